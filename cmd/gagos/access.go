@@ -0,0 +1,108 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaga951/gagos/internal/audit"
+	"github.com/gaga951/gagos/internal/ipaccess"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// Requests refused by the access rules get a 403 before authentication and
+// are written to the audit log with the action "access_denied". A client
+// that keeps retrying is recorded once per accessAuditInterval.
+
+// accessAuditInterval is how often the same refusal of a client is audited
+const accessAuditInterval = time.Minute
+
+// accessAuditMaxKeys bounds the refusals remembered for throttling
+const accessAuditMaxKeys = 10000
+
+var (
+	accessAuditMu   sync.Mutex
+	accessAuditLast = make(map[string]time.Time)
+)
+
+// trustedProxies reads GAGOS_TRUSTED_PROXIES: the proxies whose
+// X-Forwarded-For header gives the client address
+func trustedProxies() []string {
+	var proxies []string
+	for _, part := range strings.Split(getEnv("GAGOS_TRUSTED_PROXIES", ""), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			proxies = append(proxies, part)
+		}
+	}
+	return proxies
+}
+
+// accessMiddleware enforces policy. The country header is only read from
+// trusted proxies, so clients cannot pick their own country.
+func accessMiddleware(policy *ipaccess.Policy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		path := c.Path()
+		if path == "/api/health" {
+			return c.Next()
+		}
+		group := ipaccess.GroupOf(path)
+		var country string
+		if policy.CountryHeader != "" && c.App().Config().EnableTrustedProxyCheck && c.IsProxyTrusted() {
+			country = c.Get(policy.CountryHeader)
+		}
+		reason := policy.Check(group, net.ParseIP(c.IP()), country)
+		if reason == "" {
+			return c.Next()
+		}
+
+		auditAccessDenied(c, group, country, reason)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "access denied"})
+	}
+}
+
+func auditAccessDenied(c *fiber.Ctx, group, country, reason string) {
+	ip := c.IP()
+	key := ip + "|" + group + "|" + reason
+	now := time.Now()
+
+	accessAuditMu.Lock()
+	last, seen := accessAuditLast[key]
+	if seen && now.Sub(last) < accessAuditInterval {
+		accessAuditMu.Unlock()
+		return
+	}
+	if len(accessAuditLast) >= accessAuditMaxKeys {
+		for k, t := range accessAuditLast {
+			if now.Sub(t) >= accessAuditInterval {
+				delete(accessAuditLast, k)
+			}
+		}
+	}
+	accessAuditLast[key] = now
+	accessAuditMu.Unlock()
+
+	log.Warn().Str("ip", ip).Str("country", country).Str("group", group).
+		Str("reason", reason).Str("path", c.Path()).Msg("Access denied")
+
+	errMsg := reason
+	if country != "" {
+		errMsg += " (" + country + ")"
+	}
+	audit.Record(&audit.Entry{
+		Time:    now,
+		Actor:   auditActor(c),
+		Action:  "access_denied",
+		Kind:    "access",
+		Name:    group,
+		Method:  c.Method(),
+		Path:    c.OriginalURL(),
+		Status:  fiber.StatusForbidden,
+		Success: false,
+		Error:   errMsg,
+	})
+}
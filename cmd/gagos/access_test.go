@@ -0,0 +1,66 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gaga951/gagos/internal/egress"
+	"github.com/gaga951/gagos/internal/ipaccess"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAccessMiddlewareIgnoresPathCase(t *testing.T) {
+	testStorage(t)
+
+	// app.Test requests come from 0.0.0.0, outside both allow lists
+	internal, err := egress.ParseCIDRs("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &ipaccess.Policy{Groups: map[string]*ipaccess.Rules{
+		ipaccess.GroupAPI:      {AllowCIDRs: internal},
+		ipaccess.GroupWebhooks: {AllowCIDRs: internal},
+	}}
+
+	app := fiber.New()
+	app.Use(accessMiddleware(policy))
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	app.Get("/", ok)
+	app.Get("/api/v1/k8s/pods", ok)
+	app.Post("/api/v1/cicd/webhooks/:id", ok)
+
+	tests := []struct {
+		method, path string
+		status       int
+	}{
+		{"GET", "/", 200},
+		{"GET", "/api/v1/k8s/pods", 403},
+		{"GET", "/API/v1/k8s/pods", 403},
+		{"GET", "/Api/V1/K8s/Pods", 403},
+		{"POST", "/api/v1/cicd/webhooks/abc", 403},
+		{"POST", "/API/V1/CICD/Webhooks/abc", 403},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.status)
+		}
+	}
+
+	for path, group := range map[string]string{
+		"/API/v1/cicd/freestyle/webhook/x": ipaccess.GroupWebhooks,
+		"/Login":                           ipaccess.GroupAuth,
+		"/api/AUTH/login":                  ipaccess.GroupAuth,
+		"/Api":                             ipaccess.GroupAPI,
+	} {
+		if got := ipaccess.GroupOf(path); got != group {
+			t.Errorf("GroupOf(%q) = %s, want %s", path, got, group)
+		}
+	}
+}
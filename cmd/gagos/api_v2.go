@@ -0,0 +1,381 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gaga951/gagos/internal/cicd"
+	"github.com/gaga951/gagos/internal/k8s"
+	"github.com/gaga951/gagos/internal/monitoring"
+	"github.com/gofiber/fiber/v2"
+)
+
+// API v2 is dark-launched: routes are only registered when GAGOS_API_V2 is
+// set to "true". It uses plural resource names, namespaced paths, standard
+// HTTP verbs and a common pagination envelope for every list endpoint.
+
+const (
+	v2DefaultLimit = 50
+	v2MaxLimit     = 500
+)
+
+// v2Pagination describes the slice of a collection returned by a list call
+type v2Pagination struct {
+	Total      int  `json:"total"`
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	NextOffset *int `json:"next_offset"`
+}
+
+// v2ListEnvelope wraps every v2 list response
+type v2ListEnvelope struct {
+	Items      interface{}  `json:"items"`
+	Pagination v2Pagination `json:"pagination"`
+}
+
+func apiV2Enabled() bool {
+	return getEnv("GAGOS_API_V2", "false") == "true"
+}
+
+// v1DeprecationMiddleware advertises the v2 API on every v1 response
+func v1DeprecationMiddleware() fiber.Handler {
+	sunset := getEnv("GAGOS_API_V1_SUNSET", "")
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", "true")
+		c.Set("Link", "</api/v2>; rel=\"successor-version\"")
+		if sunset != "" {
+			c.Set("Sunset", sunset)
+		}
+		return c.Next()
+	}
+}
+
+// v2Paginate slices items using the limit/offset query parameters and writes the envelope
+func v2Paginate[T any](c *fiber.Ctx, items []T) error {
+	limit := c.QueryInt("limit", v2DefaultLimit)
+	if limit <= 0 {
+		limit = v2DefaultLimit
+	}
+	if limit > v2MaxLimit {
+		limit = v2MaxLimit
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	total := len(items)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	page := v2Pagination{Total: total, Limit: limit, Offset: offset}
+	if end < total {
+		next := end
+		page.NextOffset = &next
+	}
+
+	pageItems := items[offset:end]
+	if pageItems == nil {
+		pageItems = []T{}
+	}
+	return c.JSON(v2ListEnvelope{Items: pageItems, Pagination: page})
+}
+
+// v2SelectorOptions passes label/field selectors through to the API server.
+// Paging stays offset-based in v2, so limit/continue are not forwarded.
+func v2SelectorOptions(c *fiber.Ctx) k8s.ListOptions {
+	return k8s.ListOptions{
+		LabelSelector: c.Query("labelSelector"),
+		FieldSelector: c.Query("fieldSelector"),
+	}
+}
+
+// v2NamespacedList builds a paginated list handler for a namespaced resource
+func v2NamespacedList[T any](list func(ctx context.Context, namespace string) ([]T, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		items, err := list(ctx, c.Params("namespace", ""))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return v2Paginate(c, items)
+	}
+}
+
+// v2ClusterList builds a paginated list handler for a cluster-scoped resource
+func v2ClusterList[T any](list func(ctx context.Context) ([]T, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		items, err := list(ctx)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return v2Paginate(c, items)
+	}
+}
+
+// v2K8sNamespacedList wraps a k8s lister so selectors from the query reach the API server
+func v2K8sNamespacedList[T any](list func(ctx context.Context, namespace string, opts k8s.ListOptions) ([]T, string, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		opts := v2SelectorOptions(c)
+		return v2NamespacedList(func(ctx context.Context, namespace string) ([]T, error) {
+			items, _, err := list(ctx, namespace, opts)
+			return items, err
+		})(c)
+	}
+}
+
+// v2K8sClusterList is v2K8sNamespacedList for cluster-scoped resources
+func v2K8sClusterList[T any](list func(ctx context.Context, opts k8s.ListOptions) ([]T, string, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		opts := v2SelectorOptions(c)
+		return v2ClusterList(func(ctx context.Context) ([]T, error) {
+			items, _, err := list(ctx, opts)
+			return items, err
+		})(c)
+	}
+}
+
+// setupV2Routes registers the /api/v2 surface. Single-resource handlers are
+// shared with v1 since they already read :namespace and :name params.
+func setupV2Routes(app *fiber.App) {
+	v2 := app.Group("/api/v2")
+
+	// Kubernetes - cluster-scoped resources
+	k8sGroup := v2.Group("/k8s")
+	k8sGroup.Use(auditMiddleware())
+	k8sGroup.Get("/namespaces", v2K8sClusterList(k8s.ListNamespaces))
+	k8sGroup.Post("/namespaces", createNamespaceHandler)
+	k8sGroup.Get("/namespaces/:name", getNamespaceHandler)
+	k8sGroup.Delete("/namespaces/:name", deleteNamespaceHandler)
+	k8sGroup.Get("/nodes", v2K8sClusterList(k8s.ListNodes))
+	k8sGroup.Get("/nodes/:name", getNodeHandler)
+	k8sGroup.Get("/nodes/:name/events", kindEventsHandler("nodes"))
+	k8sGroup.Get("/persistentvolumes", v2K8sClusterList(k8s.ListPersistentVolumes))
+	k8sGroup.Get("/persistentvolumes/:name", getPVHandler)
+	k8sGroup.Delete("/persistentvolumes/:name", deletePVHandler)
+	k8sGroup.Get("/storageclasses", v2K8sClusterList(k8s.ListStorageClasses))
+	k8sGroup.Get("/storageclasses/:name", getStorageClassHandler)
+	k8sGroup.Patch("/storageclasses/:name", patchStorageClassHandler)
+	k8sGroup.Post("/storageclasses/:name/diff", kindDiffHandler("storageclasses"))
+	k8sGroup.Delete("/storageclasses/:name", deleteStorageClassHandler)
+
+	// Kubernetes - namespaced resources, listed across all namespaces or within one
+	namespaced := []struct {
+		plural string
+		list   fiber.Handler
+	}{
+		{"pods", v2K8sNamespacedList(k8s.ListPods)},
+		{"services", v2K8sNamespacedList(k8s.ListServices)},
+		{"deployments", v2K8sNamespacedList(k8s.ListDeployments)},
+		{"configmaps", v2K8sNamespacedList(k8s.ListConfigMaps)},
+		{"secrets", v2K8sNamespacedList(k8s.ListSecrets)},
+		{"serviceaccounts", v2K8sNamespacedList(k8s.ListServiceAccounts)},
+		{"persistentvolumeclaims", v2K8sNamespacedList(k8s.ListPersistentVolumeClaims)},
+		{"ingresses", v2K8sNamespacedList(k8s.ListIngresses)},
+		{"daemonsets", v2K8sNamespacedList(k8s.ListDaemonSets)},
+		{"statefulsets", v2K8sNamespacedList(k8s.ListStatefulSets)},
+		{"jobs", v2K8sNamespacedList(k8s.ListJobs)},
+		{"cronjobs", v2K8sNamespacedList(k8s.ListCronJobs)},
+		{"events", v2K8sNamespacedList(k8s.ListEvents)},
+		{"replicasets", v2K8sNamespacedList(k8s.ListReplicaSets)},
+		{"networkpolicies", v2K8sNamespacedList(k8s.ListNetworkPolicies)},
+		{"poddisruptionbudgets", v2K8sNamespacedList(k8s.ListPodDisruptionBudgets)},
+		{"endpoints", v2K8sNamespacedList(k8s.ListEndpoints)},
+		{"endpointslices", v2K8sNamespacedList(k8s.ListEndpointSlices)},
+	}
+	for _, r := range namespaced {
+		k8sGroup.Get("/"+r.plural, r.list)
+		k8sGroup.Get("/namespaces/:namespace/"+r.plural, r.list)
+	}
+
+	k8sGroup.Post("/pods/delete", batchDeletePodsHandler)
+
+	ns := k8sGroup.Group("/namespaces/:namespace")
+	ns.Get("/pods/:name", getPodHandler)
+	ns.Get("/pods/:name/logs", getPodLogsHandler)
+	ns.Patch("/pods/:name", patchPodHandler)
+	ns.Delete("/pods/:name", deletePodHandler)
+	ns.Post("/pods/delete", batchDeletePodsHandler)
+	ns.Get("/services/:name", getServiceHandler)
+	ns.Patch("/services/:name", patchServiceHandler)
+	ns.Delete("/services/:name", deleteServiceHandler)
+	ns.Get("/services/:name/topology", serviceTopologyHandler)
+	ns.Get("/deployments/:name", getDeploymentHandler)
+	ns.Patch("/deployments/:name", patchDeploymentHandler)
+	ns.Delete("/deployments/:name", deleteDeploymentHandler)
+	ns.Post("/deployments/:name/scale", scaleDeploymentHandler)
+	ns.Post("/deployments/:name/restart", restartDeploymentHandler)
+	ns.Get("/configmaps/:name", getConfigMapHandler)
+	ns.Patch("/configmaps/:name", patchConfigMapHandler)
+	ns.Delete("/configmaps/:name", deleteConfigMapHandler)
+	ns.Get("/secrets/:name", getSecretHandler)
+	ns.Patch("/secrets/:name", patchSecretHandler)
+	ns.Delete("/secrets/:name", deleteSecretHandler)
+	ns.Get("/serviceaccounts/:name", getServiceAccountHandler)
+	ns.Delete("/serviceaccounts/:name", deleteServiceAccountHandler)
+	ns.Get("/persistentvolumeclaims/:name", getPVCHandler)
+	ns.Patch("/persistentvolumeclaims/:name", patchPVCHandler)
+	ns.Delete("/persistentvolumeclaims/:name", deletePVCHandler)
+	ns.Get("/ingresses/:name", getIngressHandler)
+	ns.Patch("/ingresses/:name", patchIngressHandler)
+	ns.Delete("/ingresses/:name", deleteIngressHandler)
+	ns.Get("/daemonsets/:name", getDaemonSetHandler)
+	ns.Patch("/daemonsets/:name", patchDaemonSetHandler)
+	ns.Delete("/daemonsets/:name", deleteDaemonSetHandler)
+	ns.Post("/daemonsets/:name/restart", restartDaemonSetHandler)
+	ns.Get("/statefulsets/:name", getStatefulSetHandler)
+	ns.Patch("/statefulsets/:name", patchStatefulSetHandler)
+	ns.Delete("/statefulsets/:name", deleteStatefulSetHandler)
+	ns.Post("/statefulsets/:name/scale", scaleStatefulSetHandler)
+	ns.Post("/statefulsets/:name/restart", restartStatefulSetHandler)
+	ns.Get("/jobs/:name", getJobHandler)
+	ns.Delete("/jobs/:name", deleteJobHandler)
+	ns.Get("/cronjobs/:name", getCronJobHandler)
+	ns.Patch("/cronjobs/:name", patchCronJobHandler)
+	ns.Delete("/cronjobs/:name", deleteCronJobHandler)
+	ns.Post("/cronjobs/:name/trigger", triggerCronJobHandler)
+	ns.Post("/cronjobs/:name/suspend", suspendCronJobHandler)
+	ns.Post("/cronjobs/:name/resume", suspendCronJobHandler)
+	ns.Get("/replicasets/:name", getReplicaSetHandler)
+	ns.Delete("/replicasets/:name", deleteReplicaSetHandler)
+	ns.Get("/events/:name", getEventHandler)
+	ns.Get("/networkpolicies/:name", getNetworkPolicyHandler)
+	ns.Patch("/networkpolicies/:name", patchNetworkPolicyHandler)
+	ns.Delete("/networkpolicies/:name", deleteNetworkPolicyHandler)
+	ns.Get("/poddisruptionbudgets/:name", getPDBHandler)
+	ns.Patch("/poddisruptionbudgets/:name", patchPDBHandler)
+	ns.Delete("/poddisruptionbudgets/:name", deletePDBHandler)
+	ns.Post("/resources", applyHandler)
+	ns.Post("/resources/validate", validateHandler)
+	ns.Post("/:kind/:name/diff", diffResourceHandler)
+	ns.Get("/:kind/:name/events", resourceEventsHandler)
+
+	// CI/CD
+	cicdGroup := v2.Group("/cicd")
+	cicdGroup.Get("/pipelines", v2ListPipelinesHandler)
+	cicdGroup.Post("/pipelines", createPipelineHandler)
+	cicdGroup.Get("/pipelines/:id", getPipelineHandler)
+	cicdGroup.Put("/pipelines/:id", updatePipelineHandler)
+	cicdGroup.Delete("/pipelines/:id", deletePipelineHandler)
+	cicdGroup.Post("/pipelines/:id/runs", triggerPipelineHandler)
+	cicdGroup.Get("/pipelines/:id/runs", v2ListRunsHandler)
+	cicdGroup.Get("/runs", v2ListRunsHandler)
+	cicdGroup.Get("/runs/:runId", getRunHandler)
+	cicdGroup.Delete("/runs/:runId", deleteRunHandler)
+	cicdGroup.Post("/runs/:runId/cancel", cancelRunHandler)
+	cicdGroup.Get("/runs/:runId/jobs/:job/logs", getJobLogsHandler)
+	cicdGroup.Post("/runs/:runId/artifacts", uploadArtifactHandler)
+	cicdGroup.Get("/artifacts", v2ListArtifactsHandler)
+	cicdGroup.Get("/artifacts/:id", downloadArtifactHandler)
+	cicdGroup.Delete("/artifacts/:id", deleteArtifactHandler)
+	cicdGroup.Get("/freestyle-jobs", v2ListFreestyleJobsHandler)
+	cicdGroup.Post("/freestyle-jobs", createFreestyleJobHandler)
+	cicdGroup.Get("/freestyle-jobs/:id", getFreestyleJobHandler)
+	cicdGroup.Put("/freestyle-jobs/:id", updateFreestyleJobHandler)
+	cicdGroup.Delete("/freestyle-jobs/:id", deleteFreestyleJobHandler)
+	cicdGroup.Post("/freestyle-jobs/:id/builds", triggerFreestyleBuildHandler)
+	cicdGroup.Get("/freestyle-jobs/:id/builds", v2ListFreestyleBuildsHandler)
+	cicdGroup.Get("/freestyle-builds", v2ListFreestyleBuildsHandler)
+	cicdGroup.Get("/freestyle-builds/:id", getFreestyleBuildHandler)
+	cicdGroup.Delete("/freestyle-builds/:id", deleteFreestyleBuildHandler)
+	cicdGroup.Post("/freestyle-builds/:id/cancel", cancelFreestyleBuildHandler)
+	cicdGroup.Get("/freestyle-builds/:id/logs", getFreestyleBuildLogsHandler)
+	cicdGroup.Get("/ssh-hosts", v2ListSSHHostsHandler)
+	cicdGroup.Post("/ssh-hosts", createSSHHostHandler)
+	cicdGroup.Get("/ssh-hosts/:id", getSSHHostHandler)
+	cicdGroup.Put("/ssh-hosts/:id", updateSSHHostHandler)
+	cicdGroup.Delete("/ssh-hosts/:id", deleteSSHHostHandler)
+
+	// Monitoring
+	mon := v2.Group("/monitoring")
+	mon.Get("/summary", monitoringSummaryHandler)
+	mon.Get("/nodes", v2ClusterList(monitoring.GetNodeMetrics))
+	mon.Get("/pods", v2NamespacedList(monitoring.GetPodMetrics))
+	mon.Get("/namespaces/:namespace/pods", v2NamespacedList(monitoring.GetPodMetrics))
+	mon.Get("/top", monitoringTopHandler)
+	mon.Get("/namespaces/:namespace/top", monitoringTopHandler)
+	mon.Get("/resourcequotas", v2NamespacedList(monitoring.ListResourceQuotas))
+	mon.Get("/namespaces/:namespace/resourcequotas", v2NamespacedList(monitoring.ListResourceQuotas))
+	mon.Get("/limitranges", v2NamespacedList(monitoring.ListLimitRanges))
+	mon.Get("/namespaces/:namespace/limitranges", v2NamespacedList(monitoring.ListLimitRanges))
+	mon.Get("/horizontalpodautoscalers", v2NamespacedList(monitoring.ListHPAs))
+	mon.Get("/namespaces/:namespace/horizontalpodautoscalers", v2NamespacedList(monitoring.ListHPAs))
+
+	// Databases, Elasticsearch and S3, read through connection profiles
+	setupV2DatabaseRoutes(v2)
+
+	// Unknown v2 routes return JSON rather than falling through to static files
+	v2.Use(func(c *fiber.Ctx) error {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "no such v2 endpoint: " + c.Method() + " " + strings.TrimPrefix(c.Path(), "/api/v2"),
+		})
+	})
+}
+
+func v2ListPipelinesHandler(c *fiber.Ctx) error {
+	pipelines, err := cicd.ListPipelines()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, pipelines)
+}
+
+func v2ListRunsHandler(c *fiber.Ctx) error {
+	runs, err := cicd.ListRuns(c.Params("id", ""), 0)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, runs)
+}
+
+func v2ListArtifactsHandler(c *fiber.Ctx) error {
+	artifacts, err := cicd.ListArtifacts(c.Query("run_id", ""), c.Query("pipeline_id", ""))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, artifacts)
+}
+
+func v2ListFreestyleJobsHandler(c *fiber.Ctx) error {
+	jobs, err := cicd.ListFreestyleJobs()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, jobs)
+}
+
+func v2ListFreestyleBuildsHandler(c *fiber.Ctx) error {
+	var builds []*cicd.FreestyleBuild
+	var err error
+	if id := c.Params("id", ""); id != "" {
+		builds, err = cicd.ListFreestyleBuildsForJob(id)
+	} else {
+		builds, err = cicd.ListFreestyleBuilds()
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, builds)
+}
+
+func v2ListSSHHostsHandler(c *fiber.Ctx) error {
+	hosts, err := cicd.ListSSHHostsSafe()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, hosts)
+}
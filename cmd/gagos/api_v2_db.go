@@ -0,0 +1,374 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/gaga951/gagos/internal/database"
+	"github.com/gofiber/fiber/v2"
+)
+
+// In v1 every database and Elasticsearch read is a POST carrying the
+// connection config. In v2 a connection is created once (POST
+// /db/connections/{kind}, the v1 connect handlers) and its reads are GETs on
+// the profile ID, so credentials never travel in a URL and are not posted
+// again for every read. Queries, searches and writes stay POSTs.
+
+// v2Profile resolves the :id param to a profile of config type T, writing
+// the error response when it is unknown or of another kind
+func v2Profile[T any](c *fiber.Ctx) (T, bool) {
+	var zero T
+	config, ok := database.ConnectionProfile(c.Params("id"))
+	if !ok {
+		c.Status(404).JSON(fiber.Map{"error": "unknown connection profile: connect first"})
+		return zero, false
+	}
+	typed, ok := config.(T)
+	if !ok {
+		c.Status(400).JSON(fiber.Map{"error": "this endpoint does not apply to the connection's kind"})
+		return zero, false
+	}
+	return typed, true
+}
+
+// v2Param is a path parameter with its percent-encoding undone, for names
+// such as document IDs that may hold any character
+func v2Param(c *fiber.Ctx, name string) string {
+	v := c.Params(name)
+	if u, err := url.PathUnescape(v); err == nil {
+		return u
+	}
+	return v
+}
+
+// setupV2DatabaseRoutes registers /api/v2/db
+func setupV2DatabaseRoutes(v2 fiber.Router) {
+	db := v2.Group("/db")
+	db.Get("/connections", v2ListConnectionsHandler)
+	db.Post("/connections/postgres", postgresConnectHandler)
+	db.Post("/connections/mysql", mysqlConnectHandler)
+	db.Post("/connections/mssql", mssqlConnectHandler)
+	db.Post("/connections/oracle", oracleConnectHandler)
+	db.Post("/connections/redis", redisConnectHandler)
+	db.Post("/connections/elasticsearch", esConnectHandler)
+	db.Post("/connections/s3", s3ConnectHandler)
+	db.Delete("/connections/:id", forgetConnectionHandler)
+
+	conn := db.Group("/connections/:id")
+	conn.Get("/info", v2ConnectionInfoHandler)
+	conn.Get("/databases", v2ConnectionDatabasesHandler)
+
+	// Oracle
+	conn.Get("/tablespaces", v2OracleTablespacesHandler)
+
+	// Elasticsearch
+	conn.Get("/health", v2ESHealthHandler)
+	conn.Get("/stats", v2ESStatsHandler)
+	conn.Get("/nodes", v2ESNodesHandler)
+	conn.Get("/indices", v2ESIndicesHandler)
+	conn.Get("/indices/:index/mapping", v2ESIndexMappingHandler)
+	conn.Get("/indices/:index/settings", v2ESIndexSettingsHandler)
+	conn.Get("/indices/:index/documents/:docId", v2ESDocumentHandler)
+	conn.Get("/tasks", v2ESTasksHandler)
+	conn.Get("/tasks/:task", v2ESTaskHandler)
+	conn.Get("/snapshot-repositories", v2ESSnapshotRepositoriesHandler)
+	conn.Get("/snapshot-repositories/:repository/snapshots", v2ESSnapshotsHandler)
+	conn.Get("/snapshot-repositories/:repository/snapshots/:snapshot", v2ESSnapshotStatusHandler)
+
+	// S3
+	conn.Get("/buckets", v2S3BucketsHandler)
+	conn.Get("/buckets/:bucket/objects", v2S3ObjectsHandler)
+}
+
+func v2ListConnectionsHandler(c *fiber.Ctx) error {
+	return v2Paginate(c, database.ConnectionStatuses())
+}
+
+// v2ConnectionInfoHandler is the server overview of a SQL or Redis profile
+func v2ConnectionInfoHandler(c *fiber.Ctx) error {
+	config, ok := database.ConnectionProfile(c.Params("id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "unknown connection profile: connect first"})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch cfg := config.(type) {
+	case database.PostgresConfig:
+		return c.JSON(database.GetPostgresInfo(ctx, cfg))
+	case database.MySQLConfig:
+		return c.JSON(database.GetMySQLInfo(ctx, cfg))
+	case database.MSSQLConfig:
+		return c.JSON(database.GetMSSQLInfo(ctx, cfg))
+	case database.OracleConfig:
+		return c.JSON(database.GetOracleInfo(ctx, cfg))
+	case database.RedisConfig:
+		return c.JSON(database.GetRedisInfo(ctx, cfg))
+	}
+	return c.Status(400).JSON(fiber.Map{"error": "this endpoint does not apply to the connection's kind"})
+}
+
+func v2ConnectionDatabasesHandler(c *fiber.Ctx) error {
+	config, ok := database.ConnectionProfile(c.Params("id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "unknown connection profile: connect first"})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var databases []string
+	var err error
+	switch cfg := config.(type) {
+	case database.PostgresConfig:
+		databases, err = database.GetPostgresDatabases(ctx, cfg)
+	case database.MySQLConfig:
+		databases, err = database.GetMySQLDatabases(ctx, cfg)
+	case database.MSSQLConfig:
+		databases, err = database.GetMSSQLDatabases(ctx, cfg)
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "this endpoint does not apply to the connection's kind"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, databases)
+}
+
+func v2OracleTablespacesHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.OracleConfig](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tablespaces, err := database.GetOracleTablespaces(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, tablespaces)
+}
+
+func v2ESHealthHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.ESConfig](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	health, err := database.GetESClusterHealth(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(health)
+}
+
+func v2ESStatsHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.ESConfig](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stats, err := database.GetESClusterStats(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(stats)
+}
+
+func v2ESNodesHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.ESConfig](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	nodes, err := database.GetESNodes(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Set("Content-Type", "application/json")
+	return c.Send(nodes)
+}
+
+func v2ESIndicesHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.ESConfig](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	indices, err := database.ListESIndices(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, indices)
+}
+
+func v2ESIndexMappingHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.ESConfig](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	mapping, err := database.GetESIndexMapping(ctx, config, v2Param(c, "index"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Set("Content-Type", "application/json")
+	return c.Send(mapping)
+}
+
+func v2ESIndexSettingsHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.ESConfig](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	settings, err := database.GetESIndexSettings(ctx, config, v2Param(c, "index"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Set("Content-Type", "application/json")
+	return c.Send(settings)
+}
+
+func v2ESDocumentHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.ESConfig](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	doc, err := database.GetESDocument(ctx, config, v2Param(c, "index"), v2Param(c, "docId"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Set("Content-Type", "application/json")
+	return c.Send(doc)
+}
+
+func v2ESTasksHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.ESConfig](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tasks, err := database.ListESTasks(ctx, config, c.Query("actions"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, tasks)
+}
+
+func v2ESTaskHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.ESConfig](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	status, err := database.GetESTask(ctx, config, v2Param(c, "task"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(status)
+}
+
+func v2ESSnapshotRepositoriesHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.ESConfig](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	repos, err := database.ListESSnapshotRepositories(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, repos)
+}
+
+func v2ESSnapshotsHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.ESConfig](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	snapshots, err := database.ListESSnapshots(ctx, config, v2Param(c, "repository"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, snapshots)
+}
+
+func v2ESSnapshotStatusHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.ESConfig](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	status, err := database.GetESSnapshotStatus(ctx, config, v2Param(c, "repository"), v2Param(c, "snapshot"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Set("Content-Type", "application/json")
+	return c.Send(status)
+}
+
+func v2S3BucketsHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.S3Config](c)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	buckets, err := database.ListS3Buckets(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, buckets)
+}
+
+// v2S3ObjectsHandler lists up to max_keys (default 1000) objects under prefix
+func v2S3ObjectsHandler(c *fiber.Ctx) error {
+	config, ok := v2Profile[database.S3Config](c)
+	if !ok {
+		return nil
+	}
+	maxKeys := c.QueryInt("max_keys", 1000)
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	objects, err := database.ListS3Objects(ctx, config, v2Param(c, "bucket"), c.Query("prefix"), maxKeys)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return v2Paginate(c, objects)
+}
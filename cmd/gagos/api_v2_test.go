@@ -0,0 +1,134 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gaga951/gagos/internal/k8s"
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestV2DatabaseRoutes(t *testing.T) {
+	// An Elasticsearch node that answers the connection test and the reads
+	var paths []string
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/":
+			io.WriteString(w, `{"cluster_name":"test","version":{"number":"8.12.0"}}`)
+		case r.URL.Path == "/_cluster/health":
+			io.WriteString(w, `{"cluster_name":"test","status":"green"}`)
+		default:
+			io.WriteString(w, `{"_id":"a/b","found":true}`)
+		}
+	}))
+	defer es.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(es.URL, "http://"))
+	portNum, _ := strconv.Atoi(port)
+
+	app := fiber.New()
+	setupV2Routes(app)
+
+	do := func(method, path, body string) (int, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	status, out := do("POST", "/api/v2/db/connections/elasticsearch", `{"host":"`+host+`","port":`+strconv.Itoa(portNum)+`}`)
+	id, _ := out["profile_id"].(string)
+	if status != 200 || id == "" {
+		t.Fatalf("connect = %d %v", status, out)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/api/v2/db/connections/" + id + "/health", 200},
+		{"/api/v2/db/connections/" + id + "/indices/logs/documents/a%2Fb", 200},
+		{"/api/v2/db/connections/" + id + "/info", 400},
+		{"/api/v2/db/connections/" + id + "/buckets", 400},
+		{"/api/v2/db/connections/unknown/health", 404},
+	}
+	for _, tt := range tests {
+		if status, out := do("GET", tt.path, ""); status != tt.status {
+			t.Errorf("GET %s = %d %v, want %d", tt.path, status, out, tt.status)
+		}
+	}
+	// The document ID reaches Elasticsearch still escaped as one segment
+	if last := paths[len(paths)-1]; last != "/logs/_doc/a%2Fb" {
+		t.Errorf("document read went to %s", last)
+	}
+
+	status, out = do("GET", "/api/v2/db/connections", "")
+	if status != 200 || out["pagination"] == nil {
+		t.Errorf("list = %d %v", status, out)
+	}
+	if status, _ := do("DELETE", "/api/v2/db/connections/"+id, ""); status != 200 {
+		t.Errorf("delete = %d", status)
+	}
+	if status, _ := do("GET", "/api/v2/db/connections/"+id+"/health", ""); status != 404 {
+		t.Errorf("read after delete = %d, want 404", status)
+	}
+}
+
+func TestResourceEventsKind(t *testing.T) {
+	node := corev1.ObjectReference{Kind: "Node", Name: "n1"}
+	pod := corev1.ObjectReference{Kind: "Pod", Name: "n1", Namespace: "default"}
+	k8s.SetClient(fake.NewSimpleClientset(
+		&corev1.Event{ObjectMeta: metav1.ObjectMeta{Name: "node-event", Namespace: "default"}, InvolvedObject: node},
+		&corev1.Event{ObjectMeta: metav1.ObjectMeta{Name: "pod-event", Namespace: "default"}, InvolvedObject: pod},
+	), nil)
+	defer k8s.SetClient(nil, nil)
+
+	app := fiber.New()
+	setupV2Routes(app)
+	// A shared handler mounted without :kind must not guess one
+	app.Get("/unkinded/:name/events", resourceEventsHandler)
+	app.Post("/unkinded/:name/diff", diffResourceHandler)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v2/k8s/nodes/n1/events", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result k8s.EventCorrelation
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != 200 || result.Kind != "Node" || len(result.Events) != 1 {
+		t.Errorf("node events = %d %+v", resp.StatusCode, result)
+	}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/unkinded/n1/events", nil),
+		httptest.NewRequest("POST", "/unkinded/n1/diff", strings.NewReader(`{"yaml":"kind: Node"}`)),
+	} {
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != 400 {
+			t.Errorf("%s %s = %d, want 400", req.Method, req.URL.Path, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gaga951/gagos/internal/audit"
+	"github.com/gaga951/gagos/internal/auth"
+	"github.com/gaga951/gagos/internal/k8s"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Every request that changes the cluster through the k8s routes is written
+// to the audit log once its handler has run, whether it succeeded or not.
+// Patches also record the fields they changed, worked out with the same
+// dry-run diff the editor previews.
+
+// auditReadOnlySuffixes are POST routes that change nothing
+var auditReadOnlySuffixes = []string{"/diff", "/validate", "/generate", "/images/scan"}
+
+// auditVerbs are the route suffixes that name the action they perform
+var auditVerbs = map[string]bool{
+	"scale": true, "restart": true, "rollout": true, "resize": true, "delete": true,
+	"trigger": true, "suspend": true, "resume": true, "cleanup": true, "apply": true,
+	"create": true, "kustomize": true, "cp": true, "debug": true, "clone": true,
+	"rollback": true,
+}
+
+// auditSecretManifest spots a Secret in an applied manifest, as YAML or
+// inside a JSON string
+var auditSecretManifest = regexp.MustCompile(`(?m)kind"?\s*:\s*"?Secret(?:$|[^A-Za-z0-9])`)
+
+// auditSessionPrefix is how much of the session token identifies the actor
+const auditSessionPrefix = 8
+
+func auditMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		method := c.Method()
+		path := strings.TrimSuffix(c.Path(), "/")
+		var readAction string
+		if method == fiber.MethodGet || method == fiber.MethodHead || method == fiber.MethodOptions {
+			if readAction = auditSecretRead(c, path); readAction == "" {
+				return c.Next()
+			}
+		}
+		for _, suffix := range auditReadOnlySuffixes {
+			if strings.HasSuffix(path, suffix) {
+				return c.Next()
+			}
+		}
+
+		start := time.Now()
+		var diff *k8s.ResourceDiff
+		if method == fiber.MethodPatch {
+			diff = auditPatchDiff(c, path)
+		}
+
+		err := c.Next()
+
+		entry := &audit.Entry{
+			Time:     start,
+			Actor:    auditActor(c),
+			Method:   method,
+			Path:     c.OriginalURL(),
+			Status:   c.Response().StatusCode(),
+			Duration: float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if fe, ok := err.(*fiber.Error); ok {
+			entry.Status = fe.Code
+			entry.Error = fe.Message
+		} else if err != nil {
+			entry.Status = fiber.StatusInternalServerError
+			entry.Error = err.Error()
+		}
+		entry.Success = entry.Status < 400
+		if !entry.Success && entry.Error == "" {
+			var resp struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(c.Response().Body(), &resp) == nil {
+				entry.Error = resp.Error
+			}
+		}
+
+		entry.Kind, entry.Action = auditRoute(c.Route().Path, method)
+		if readAction != "" {
+			entry.Action = readAction
+		}
+		if kind := c.Params("kind"); kind != "" {
+			entry.Kind = kind
+		}
+		entry.Namespace = c.Params("namespace")
+		entry.Name = c.Params("name")
+
+		secret := strings.HasPrefix(strings.ToLower(entry.Kind), "secret")
+		if !strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+			body := c.Body()
+			if len(body) > audit.MaxBodyBytes {
+				body = body[:audit.MaxBodyBytes]
+				entry.Truncated = true
+			}
+			if !secret && !auditSecretManifest.Match(body) {
+				entry.Body = string(body)
+			}
+		}
+		if diff != nil && entry.Success {
+			for _, ch := range diff.Changes {
+				change := audit.Change{Path: ch.Path, Op: ch.Op, Old: ch.Old, New: ch.New}
+				if secret {
+					// Only which keys changed, never their values
+					change.Old, change.New = nil, nil
+				}
+				entry.Changes = append(entry.Changes, change)
+			}
+			if !secret {
+				entry.Unified = diff.Unified
+			}
+		}
+
+		audit.Record(entry)
+		return err
+	}
+}
+
+// auditPatchDiff previews what a PATCH of a resource's YAML will change.
+// The route has not matched yet, so the resource comes from the path:
+// v1 /k8s/<kind>/<namespace>/<name>, v2 /k8s/namespaces/<namespace>/<resource>/<name>,
+// or /k8s/<kind>/<name> for cluster-scoped kinds.
+func auditPatchDiff(c *fiber.Ctx, path string) *k8s.ResourceDiff {
+	_, rest, ok := strings.Cut(path, "/k8s/")
+	if !ok {
+		return nil
+	}
+	var kind, namespace, name string
+	segs := strings.Split(rest, "/")
+	switch {
+	case len(segs) == 4 && segs[0] == "namespaces":
+		namespace, kind, name = segs[1], segs[2], segs[3]
+	case len(segs) == 3:
+		kind, namespace, name = segs[0], segs[1], segs[2]
+	case len(segs) == 2:
+		kind, name = segs[0], segs[1]
+	default:
+		return nil
+	}
+
+	var req struct {
+		YAML string `json:"yaml"`
+	}
+	if json.Unmarshal(c.Body(), &req) != nil || strings.TrimSpace(req.YAML) == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	diff, err := k8s.DiffResource(ctx, kind, namespace, name, req.YAML)
+	if err != nil {
+		return nil
+	}
+	return diff
+}
+
+// auditSecretRead names the reads that show Secret values, which are
+// recorded like changes: decode for ?decode=true on a Secret, reveal for
+// ?reveal=true on one of its revisions
+func auditSecretRead(c *fiber.Ctx, path string) string {
+	_, rest, ok := strings.Cut(path, "/k8s/")
+	if !ok {
+		return ""
+	}
+	segs := strings.Split(rest, "/")
+	secret := segs[0] == "secret" || len(segs) > 2 && segs[0] == "namespaces" && segs[2] == "secrets"
+	switch {
+	case !secret:
+		return ""
+	case c.QueryBool("decode"):
+		return "decode"
+	case c.QueryBool("reveal"):
+		return "reveal"
+	}
+	return ""
+}
+
+// auditRoute names the kind and action of a matched route such as
+// /api/v1/k8s/deployment/:namespace/:name/scale
+func auditRoute(route, method string) (kind, action string) {
+	_, rest, _ := strings.Cut(route, "/k8s/")
+	segs := strings.Split(strings.Trim(rest, "/"), "/")
+	if len(segs) >= 2 && segs[0] == "namespaces" && segs[1] == ":namespace" {
+		segs = segs[2:]
+	}
+	if len(segs) > 1 || !auditVerbs[segs[0]] {
+		// /apply and the like name only the action
+		kind = segs[0]
+	}
+
+	switch method {
+	case fiber.MethodDelete:
+		return kind, "delete"
+	case fiber.MethodPatch:
+		return kind, "patch"
+	case fiber.MethodPut:
+		return kind, "update"
+	}
+	if last := segs[len(segs)-1]; auditVerbs[last] {
+		return kind, last
+	}
+	return kind, "create"
+}
+
+func auditActor(c *fiber.Ctx) audit.Actor {
+	actor := audit.Actor{IP: c.IP(), UserAgent: c.Get(fiber.HeaderUserAgent), Elevated: auth.IsElevated(c)}
+	if token := c.Cookies("gagos_session"); len(token) >= auditSessionPrefix {
+		actor.Session = token[:auditSessionPrefix]
+	}
+	return actor
+}
+
+// auditLogHandler queries the audit log.
+// Query params: since, until (RFC 3339 or a duration such as 24h), action,
+// kind, namespace, name, ip, failed=true, limit
+func auditLogHandler(c *fiber.Ctx) error {
+	filter := audit.Filter{
+		Action:    c.Query("action"),
+		Kind:      c.Query("kind"),
+		Namespace: c.Query("namespace"),
+		Name:      c.Query("name"),
+		IP:        c.Query("ip"),
+		Failed:    c.QueryBool("failed"),
+		Limit:     c.QueryInt("limit", audit.DefaultLimit),
+	}
+	var err error
+	if filter.Since, err = auditTime(c.Query("since")); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid since: " + err.Error()})
+	}
+	if filter.Until, err = auditTime(c.Query("until")); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid until: " + err.Error()})
+	}
+
+	entries, err := audit.Query(filter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"entries": entries, "count": len(entries)})
+}
+
+func getAuditEntryHandler(c *fiber.Ctx) error {
+	entry, err := audit.Get(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(entry)
+}
+
+// auditTime parses an RFC 3339 time, or a duration counted back from now
+func auditTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,81 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gaga951/gagos/internal/audit"
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+var testStorageOnce sync.Once
+
+// testStorage opens a BBolt store in a temporary directory, once for the
+// package's tests
+func testStorage(t *testing.T) {
+	t.Helper()
+	testStorageOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "gagos-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Setenv("GAGOS_STORAGE_TYPE", storage.StorageTypeBBolt)
+		os.Setenv("GAGOS_DB_PATH", filepath.Join(dir, "gagos.db"))
+		if err := storage.Init(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestAuditSecretReads(t *testing.T) {
+	testStorage(t)
+
+	app := fiber.New()
+	v1 := app.Group("/api/v1/k8s")
+	v1.Use(auditMiddleware())
+	ok := func(c *fiber.Ctx) error { return c.JSON(fiber.Map{}) }
+	v1.Get("/secret/:namespace/:name", ok)
+	v1.Get("/secret/:namespace/:name/history/:revision", ok)
+	v1.Get("/configmap/:namespace/:name", ok)
+	v2 := app.Group("/api/v2/k8s")
+	v2.Use(auditMiddleware())
+	v2.Get("/namespaces/:namespace/secrets/:name", ok)
+
+	tests := []struct {
+		path   string
+		action string // empty when the read is not recorded
+	}{
+		{"/api/v1/k8s/secret/default/db?decode=true&keys=password", "decode"},
+		{"/api/v1/k8s/secret/default/db", ""},
+		{"/api/v1/k8s/secret/default/tls/history/3?reveal=true", "reveal"},
+		{"/api/v1/k8s/configmap/default/app?decode=true", ""},
+		{"/api/v2/k8s/namespaces/prod/secrets/api?decode=true", "decode"},
+	}
+	for _, tt := range tests {
+		before, _ := audit.Query(audit.Filter{Limit: 1000})
+		if _, err := app.Test(httptest.NewRequest("GET", tt.path, nil)); err != nil {
+			t.Fatalf("GET %s: %v", tt.path, err)
+		}
+		after, _ := audit.Query(audit.Filter{Limit: 1000})
+		if tt.action == "" {
+			if len(after) != len(before) {
+				t.Errorf("GET %s was recorded", tt.path)
+			}
+			continue
+		}
+		if len(after) != len(before)+1 {
+			t.Fatalf("GET %s recorded %d entries, want 1", tt.path, len(after)-len(before))
+		}
+		e := after[0]
+		if e.Action != tt.action || e.Path != tt.path || !e.Success {
+			t.Errorf("GET %s recorded %+v", tt.path, e)
+		}
+	}
+}
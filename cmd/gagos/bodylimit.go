@@ -0,0 +1,79 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Request bodies are streamed (fiber.Config.StreamRequestBody), so fasthttp no
+// longer rejects oversized requests on its own. bodyLimitMiddleware enforces
+// the limit per route before any handler buffers or parses the body.
+
+// bodyLimitRule raises the limit for POST routes whose path ends in suffix
+type bodyLimitRule struct {
+	suffix string
+	limit  int64
+}
+
+// envMB reads a size in megabytes from key, falling back to def
+func envMB(key string, def int) int64 {
+	mb, err := strconv.Atoi(getEnv(key, strconv.Itoa(def)))
+	if err != nil || mb <= 0 {
+		mb = def
+	}
+	return int64(mb) << 20
+}
+
+// defaultBodyLimit applies to every route without a rule (GAGOS_BODY_LIMIT_MB, default 4)
+func defaultBodyLimit() int64 {
+	return envMB("GAGOS_BODY_LIMIT_MB", 4)
+}
+
+// uploadBodyLimit applies to S3 and artifact uploads (GAGOS_UPLOAD_LIMIT_MB, default 1024)
+func uploadBodyLimit() int64 {
+	return envMB("GAGOS_UPLOAD_LIMIT_MB", 1024)
+}
+
+// multipartOverhead leaves room for form fields and part headers on top of the file itself
+const multipartOverhead = 1 << 20
+
+func bodyLimitRules() []bodyLimitRule {
+	return []bodyLimitRule{
+		{suffix: "/storage/s3/object/upload", limit: uploadBodyLimit() + multipartOverhead},
+		{suffix: "/artifacts", limit: uploadBodyLimit() + multipartOverhead},
+		{suffix: "/cp", limit: podCopyMaxBytes() + multipartOverhead},
+	}
+}
+
+func bodyLimitMiddleware() fiber.Handler {
+	def := defaultBodyLimit()
+	rules := bodyLimitRules()
+
+	return func(c *fiber.Ctx) error {
+		limit := def
+		if c.Method() == fiber.MethodPost {
+			path := strings.TrimSuffix(c.Path(), "/")
+			for _, r := range rules {
+				if strings.HasSuffix(path, r.suffix) {
+					limit = r.limit
+					break
+				}
+			}
+		}
+
+		switch n := c.Request().Header.ContentLength(); {
+		case n == -1:
+			// Chunked bodies have no length to check up front
+			return c.Status(fiber.StatusLengthRequired).JSON(fiber.Map{"error": "chunked request bodies are not supported, send Content-Length"})
+		case int64(n) > limit:
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": fmt.Sprintf("request body exceeds the %d MB limit", limit>>20)})
+		}
+		return c.Next()
+	}
+}
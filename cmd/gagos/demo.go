@@ -0,0 +1,136 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"strings"
+
+	"github.com/gaga951/gagos/internal/demo"
+	"github.com/gofiber/fiber/v2"
+)
+
+// demoLocalPaths are the API reads that only touch GAGOS's own store, served
+// as usual in demo mode
+var demoLocalPaths = []string{"/api/v1/notepad", "/api/v1/preferences"}
+
+// setupDemoRoutes serves synthetic data when GAGOS_DEMO_MODE=true. The routes are
+// registered ahead of the real ones so they win; every other read would reach a
+// real cluster or backend and answers 503, and everything stays read-only.
+func setupDemoRoutes(app *fiber.App) {
+	app.Use("/api/v1", demoReadOnlyMiddleware)
+	app.Use("/api/v2", demoReadOnlyMiddleware)
+
+	k8sGroup := app.Group("/api/v1/k8s")
+	k8sGroup.Get("/namespaces", func(c *fiber.Ctx) error {
+		namespaces := demo.Namespaces()
+		return c.JSON(fiber.Map{"count": len(namespaces), "namespaces": namespaces})
+	})
+	k8sGroup.Get("/nodes", func(c *fiber.Ctx) error {
+		nodes := demo.Nodes()
+		return c.JSON(fiber.Map{"count": len(nodes), "nodes": nodes})
+	})
+	demoPods := func(c *fiber.Ctx) error {
+		namespace := c.Params("namespace", "")
+		pods := demo.Pods(namespace)
+		return c.JSON(fiber.Map{"namespace": namespace, "count": len(pods), "pods": pods})
+	}
+	k8sGroup.Get("/pods", demoPods)
+	k8sGroup.Get("/pods/:namespace", demoPods)
+	demoServices := func(c *fiber.Ctx) error {
+		namespace := c.Params("namespace", "")
+		services := demo.Services(namespace)
+		return c.JSON(fiber.Map{"namespace": namespace, "count": len(services), "services": services})
+	}
+	k8sGroup.Get("/services", demoServices)
+	k8sGroup.Get("/services/:namespace", demoServices)
+	demoDeployments := func(c *fiber.Ctx) error {
+		namespace := c.Params("namespace", "")
+		deployments := demo.Deployments(namespace)
+		return c.JSON(fiber.Map{"namespace": namespace, "count": len(deployments), "deployments": deployments})
+	}
+	k8sGroup.Get("/deployments", demoDeployments)
+	k8sGroup.Get("/deployments/:namespace", demoDeployments)
+
+	mon := app.Group("/api/v1/monitoring")
+	mon.Get("/summary", func(c *fiber.Ctx) error {
+		return c.JSON(demo.ClusterSummary())
+	})
+	mon.Get("/nodes", func(c *fiber.Ctx) error {
+		nodes := demo.NodeMetrics()
+		return c.JSON(fiber.Map{"count": len(nodes), "nodes": nodes, "metrics_available": true})
+	})
+	demoPodMetrics := func(c *fiber.Ctx) error {
+		namespace := c.Params("namespace", "")
+		pods := demo.PodMetrics(namespace)
+		return c.JSON(fiber.Map{"namespace": namespace, "count": len(pods), "pods": pods, "metrics_available": true})
+	}
+	mon.Get("/pods", demoPodMetrics)
+	mon.Get("/pods/:namespace", demoPodMetrics)
+
+	cicdGroup := app.Group("/api/v1/cicd")
+	cicdGroup.Get("/stats", func(c *fiber.Ctx) error {
+		return c.JSON(demo.Stats())
+	})
+	cicdGroup.Get("/pipelines", func(c *fiber.Ctx) error {
+		pipelines := demo.Pipelines()
+		return c.JSON(fiber.Map{"count": len(pipelines), "pipelines": pipelines})
+	})
+	cicdGroup.Get("/pipelines/:id", func(c *fiber.Ctx) error {
+		for _, p := range demo.Pipelines() {
+			if p.ID == c.Params("id") {
+				return c.JSON(p)
+			}
+		}
+		return c.Status(404).JSON(fiber.Map{"error": "pipeline not found"})
+	})
+	cicdGroup.Get("/pipelines/:id/runs", func(c *fiber.Ctx) error {
+		runs := demo.Runs(c.Params("id"))
+		return c.JSON(fiber.Map{"count": len(runs), "runs": runs})
+	})
+	cicdGroup.Get("/runs", func(c *fiber.Ctx) error {
+		runs := demo.Runs("")
+		if limit := c.QueryInt("limit", 50); limit > 0 && len(runs) > limit {
+			runs = runs[:limit]
+		}
+		return c.JSON(fiber.Map{"count": len(runs), "runs": runs})
+	})
+	cicdGroup.Get("/runs/:runId", func(c *fiber.Ctx) error {
+		for _, r := range demo.Runs("") {
+			if r.ID == c.Params("runId") {
+				return c.JSON(r)
+			}
+		}
+		return c.Status(404).JSON(fiber.Map{"error": "run not found"})
+	})
+
+	app.Get("/api/v1/*", demoUnavailable)
+	app.Get("/api/v2/*", demoUnavailable)
+}
+
+// demoUnavailable answers the reads demo mode has no synthetic data for
+func demoUnavailable(c *fiber.Ctx) error {
+	for _, prefix := range demoLocalPaths {
+		if c.Path() == prefix || strings.HasPrefix(c.Path(), prefix+"/") {
+			return c.Next()
+		}
+	}
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "not available in demo mode"})
+}
+
+// demoReadOnlyMiddleware rejects anything that could change state in demo mode.
+// The DB/ES/S3/network tools use POST for reads but reach real backends, so they are blocked too.
+func demoReadOnlyMiddleware(c *fiber.Ctx) error {
+	path := c.Path()
+	if path == "/api/v1/terminal/ws" || strings.HasSuffix(path, "/debug/ws") {
+		return c.Status(403).JSON(fiber.Map{"error": "terminal is disabled in demo mode"})
+	}
+	if c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead || c.Method() == fiber.MethodOptions {
+		return c.Next()
+	}
+	if strings.HasPrefix(path, "/api/v1/tools/") && path != "/api/v1/tools/cert/check" {
+		// Pure local helpers (base64, hash, diff, ...) are safe to use
+		return c.Next()
+	}
+	return c.Status(403).JSON(fiber.Map{"error": "GAGOS is running in read-only demo mode"})
+}
@@ -0,0 +1,50 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestDemoRoutes(t *testing.T) {
+	app := fiber.New()
+	setupDemoRoutes(app)
+	// Stand-ins for the real handlers, registered after the demo ones
+	real := func(c *fiber.Ctx) error { return c.SendString("real backend") }
+	app.Get("/api/v1/k8s/pods", real)
+	app.Get("/api/v1/k8s/secrets/:namespace", real)
+	app.Get("/api/v1/notepad/:key", real)
+	app.Get("/api/v2/k8s/pods", real)
+	app.Post("/api/v1/db/postgres/query", real)
+	app.Post("/api/v1/tools/base64/encode", real)
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"GET", "/api/v1/k8s/pods", 200},
+		{"GET", "/api/v1/monitoring/summary", 200},
+		{"GET", "/api/v1/cicd/pipelines", 200},
+		{"GET", "/api/v1/k8s/secrets/default", 503},
+		{"GET", "/api/v1/k8s/watch", 503},
+		{"GET", "/api/v2/k8s/pods", 503},
+		{"GET", "/api/v1/notepad/todo", 200},
+		{"GET", "/api/v1/terminal/ws", 403},
+		{"POST", "/api/v1/db/postgres/query", 403},
+		{"POST", "/api/v1/tools/base64/encode", 200},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.status)
+		}
+	}
+}
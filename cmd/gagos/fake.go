@@ -0,0 +1,35 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build fake
+
+package main
+
+import (
+	"os"
+
+	"github.com/gaga951/gagos/internal/fakes"
+	"github.com/rs/zerolog/log"
+)
+
+// startFakeBackends replaces the cluster, the SSH hosts and the database
+// with in-process fakes seeded with fixtures. Data is kept in
+// GAGOS_FAKE_DATA_DIR, or in a temporary directory that is removed on exit.
+func startFakeBackends() bool {
+	h, err := fakes.Start(fakes.Options{DataDir: os.Getenv("GAGOS_FAKE_DATA_DIR"), Seed: true})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to start fake backends")
+	}
+	fakeHarness = h
+	log.Warn().Str("data_dir", h.DataDir).Msg("Fake mode: Kubernetes, SSH and storage are simulated")
+	return true
+}
+
+// stopFakeBackends removes the fake backends' temporary data
+func stopFakeBackends() {
+	if fakeHarness != nil {
+		fakeHarness.Stop()
+	}
+}
+
+var fakeHarness *fakes.Harness
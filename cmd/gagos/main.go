@@ -16,24 +16,39 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"os"
 	"os/signal"
+	"path"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gaga951/gagos/internal/auth"
 	"github.com/gaga951/gagos/internal/cicd"
+	"github.com/gaga951/gagos/internal/confighistory"
 	"github.com/gaga951/gagos/internal/database"
+	"github.com/gaga951/gagos/internal/demo"
+	"github.com/gaga951/gagos/internal/egress"
+	"github.com/gaga951/gagos/internal/ipaccess"
 	"github.com/gaga951/gagos/internal/k8s"
 	"github.com/gaga951/gagos/internal/monitoring"
 	"github.com/gaga951/gagos/internal/network"
+	"github.com/gaga951/gagos/internal/servertls"
 	"github.com/gaga951/gagos/internal/storage"
+	"github.com/gaga951/gagos/internal/subsystems"
 	"github.com/gaga951/gagos/internal/terminal"
+	"github.com/gaga951/gagos/internal/usage"
 	"github.com/gaga951/gagos/internal/tools"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
@@ -59,49 +74,39 @@ func main() {
 		Str("build_time", buildTime).
 		Msg("Starting GAGOS")
 
-	// Initialize Kubernetes client
-	if err := k8s.InitClient(); err != nil {
-		log.Warn().Err(err).Msg("Failed to initialize Kubernetes client - K8s features will be unavailable")
-	} else {
-		log.Info().Msg("Kubernetes client initialized successfully")
-	}
-
 	// Initialize authentication
 	auth.Init()
 
-	// Initialize storage
-	if err := storage.Init(); err != nil {
-		log.Warn().Err(err).Msg("Failed to initialize storage - notepad will be unavailable")
-	} else {
-		log.Info().Msg("Storage initialized successfully")
+	if demo.Enabled() {
+		log.Info().Msg("Demo mode enabled - serving synthetic data, write operations are disabled")
 	}
 
-	// Initialize CI/CD scheduler
-	scheduler := cicd.InitScheduler()
-	if err := scheduler.Start(); err != nil {
-		log.Warn().Err(err).Msg("Failed to start CI/CD scheduler")
-	} else {
-		log.Info().Msg("CI/CD scheduler started")
+	if egress.Offline() {
+		log.Info().Msg("Offline mode enabled - outbound connections are limited to internal networks")
 	}
 
-	// Load notification configurations
-	if err := cicd.LoadNotificationConfigs(); err != nil {
-		log.Warn().Err(err).Msg("Failed to load notification configs")
-	} else {
-		log.Info().Msg("Notification configs loaded")
-	}
+	// Builds with the fake tag run on in-process Kubernetes, SSH and storage
+	fakeBackends = startFakeBackends()
 
-	// Initialize monitoring
-	if err := monitoring.Init(); err != nil {
-		log.Warn().Err(err).Msg("Failed to initialize monitoring")
-	} else {
-		log.Info().Msg("Monitoring initialized")
-	}
+	// Start the Kubernetes client, storage and what depends on them.
+	// Subsystems that fail are retried in the background.
+	registerSubsystems()
+	subsystems.Start()
+
+	// Keep saved database connection health current
+	database.StartConnectionRevalidator()
+
+	// Run the scheduled NFS/SMB/path mount checks
+	network.StartMountMonitors()
 
 	// Get configuration from environment
 	// Use GAGOS_SERVER_* to avoid conflict with K8s service-injected GAGOS_PORT
 	host := getEnv("GAGOS_SERVER_HOST", getEnv("GAGOS_HOST", "0.0.0.0"))
 	port := getEnv("GAGOS_SERVER_PORT", getEnv("GAGOS_PORT", "8080"))
+	tlsConfig, err := servertls.FromEnv()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid TLS configuration")
+	}
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -109,23 +114,59 @@ func main() {
 		DisableStartupMessage: false,
 		ReadTimeout:           30 * time.Second,
 		WriteTimeout:          30 * time.Second,
+		// Bodies up to BodyLimit are buffered; larger ones (uploads) are
+		// streamed and capped per route by bodyLimitMiddleware
+		BodyLimit:         int(defaultBodyLimit()),
+		StreamRequestBody: true,
+		// Behind a load balancer or ingress, the client address comes
+		// from X-Forwarded-For when the request arrives from a trusted proxy
+		ProxyHeader:             fiber.HeaderXForwardedFor,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          trustedProxies(),
+		EnableIPValidation:      true,
 	})
 
 	// Middleware
 	app.Use(recover.New())
+	app.Use(bodyLimitMiddleware())
 	app.Use(logger.New(logger.Config{
 		Format:     "${time} | ${status} | ${latency} | ${ip} | ${method} | ${path}\n",
 		TimeFormat: "2006-01-02 15:04:05",
 	}))
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
-		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
+		AllowMethods: "GET,POST,PUT,PATCH,DELETE,OPTIONS",
 		AllowHeaders: "Origin,Content-Type,Accept,Authorization",
 	}))
 
+	accessPolicy, err := ipaccess.FromEnv()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid access rules")
+	}
+	if accessPolicy != nil {
+		if accessPolicy.CountryHeader != "" && len(trustedProxies()) == 0 {
+			log.Fatal().Msg("GAGOS_GEOIP_HEADER needs GAGOS_TRUSTED_PROXIES")
+		}
+		log.Info().Interface("groups", accessPolicy.Summary()).Msg("Access rules enabled")
+		app.Use(accessMiddleware(accessPolicy))
+	}
+
+	if tlsConfig.Enabled() {
+		app.Use(hstsMiddleware())
+		if tlsConfig.ClientCA != "" {
+			auth.EnableClientCerts(tlsConfig.RequireCert)
+		}
+	}
+
 	// Authentication middleware
 	app.Use(auth.Middleware())
 
+	// Usage analytics, counted once a request got past authentication
+	if usage.Enabled() {
+		usage.Start()
+		app.Use(usageMiddleware())
+	}
+
 	// Routes
 	setupRoutes(app)
 
@@ -143,10 +184,18 @@ func main() {
 
 	// Start server
 	addr := fmt.Sprintf("%s:%s", host, port)
-	log.Info().Str("address", addr).Msg("Server listening")
-	if err := app.Listen(addr); err != nil {
+	if tlsConfig.Enabled() {
+		log.Info().Str("address", addr).Str("certificate", tlsConfig.Source()).Msg("Server listening with TLS")
+		err = listenTLS(app, addr, tlsConfig)
+	} else {
+		log.Info().Str("address", addr).Msg("Server listening")
+		err = app.Listen(addr)
+	}
+	if err != nil {
 		log.Fatal().Err(err).Msg("Server failed to start")
 	}
+	usage.Flush()
+	stopFakeBackends()
 }
 
 func setupRoutes(app *fiber.App) {
@@ -163,6 +212,8 @@ func setupRoutes(app *fiber.App) {
 	app.Get("/login", loginPageHandler)
 	app.Post("/api/auth/login", loginHandler)
 	app.Post("/api/auth/logout", logoutHandler)
+	app.Post("/api/auth/elevate", elevateHandler)
+	app.Delete("/api/auth/elevate", dropElevationHandler)
 
 	// Runtime info (public - for login page hint)
 	app.Get("/api/runtime", runtimeHandler)
@@ -176,8 +227,16 @@ func setupRoutes(app *fiber.App) {
 	})
 	app.Static("/", "/app/web/static")
 
+	// Demo mode serves synthetic data ahead of the real handlers
+	if demo.Enabled() {
+		setupDemoRoutes(app)
+	}
+
 	// API v1 group
 	v1 := app.Group("/api/v1")
+	if apiV2Enabled() {
+		v1.Use(v1DeprecationMiddleware())
+	}
 
 	// Network tools endpoints
 	net := v1.Group("/network")
@@ -193,8 +252,21 @@ func setupRoutes(app *fiber.App) {
 
 	// Kubernetes endpoints
 	k8sGroup := v1.Group("/k8s")
+	k8sGroup.Use(subsystemGate(subsystems.Kubernetes))
+	k8sGroup.Use(auditMiddleware())
 	// List endpoints
+	k8sGroup.Get("/apis", k8sAPIsHandler)
 	k8sGroup.Get("/namespaces", namespacesHandler)
+	k8sGroup.Post("/namespaces", createNamespaceHandler)
+	k8sGroup.Get("/namespace-templates", namespaceTemplatesHandler)
+	k8sGroup.Get("/search", k8sSearchHandler)
+	k8sGroup.Get("/export", k8sExportHandler)
+	k8sGroup.Post("/clone", k8sCloneHandler)
+	k8sGroup.Get("/hygiene", k8sHygieneHandler)
+	k8sGroup.Post("/hygiene/cleanup", k8sHygieneCleanupHandler)
+	k8sGroup.Get("/images", k8sImagesHandler)
+	k8sGroup.Get("/images/scan", k8sImageScanHandler)
+	k8sGroup.Post("/images/scan", k8sScanImageHandler)
 	k8sGroup.Get("/nodes", nodesHandler)
 	k8sGroup.Get("/pods", podsHandler)
 	k8sGroup.Get("/pods/:namespace", podsHandler)
@@ -216,6 +288,7 @@ func setupRoutes(app *fiber.App) {
 	k8sGroup.Get("/daemonsets", daemonSetsHandler)
 	k8sGroup.Get("/daemonsets/:namespace", daemonSetsHandler)
 	k8sGroup.Get("/statefulsets", statefulSetsHandler)
+	k8sGroup.Get("/statefulsets/orphan-pvcs", statefulSetOrphanPVCsHandler)
 	k8sGroup.Get("/statefulsets/:namespace", statefulSetsHandler)
 	k8sGroup.Get("/jobs", jobsHandler)
 	k8sGroup.Get("/jobs/:namespace", jobsHandler)
@@ -225,36 +298,65 @@ func setupRoutes(app *fiber.App) {
 	k8sGroup.Get("/events/:namespace", eventsHandler)
 	k8sGroup.Get("/replicasets", replicaSetsHandler)
 	k8sGroup.Get("/replicasets/:namespace", replicaSetsHandler)
+	k8sGroup.Get("/networkpolicies", networkPoliciesHandler)
+	k8sGroup.Get("/networkpolicies/:namespace", networkPoliciesHandler)
+	k8sGroup.Get("/pdbs", pdbsHandler)
+	k8sGroup.Get("/pdbs/:namespace", pdbsHandler)
+	k8sGroup.Get("/storageclasses", storageClassesHandler)
+	k8sGroup.Get("/leases", leasesHandler)
+	k8sGroup.Get("/leases/:namespace", leasesHandler)
+	k8sGroup.Get("/endpoints", endpointsHandler)
+	k8sGroup.Get("/endpoints/:namespace", endpointsHandler)
+	k8sGroup.Get("/endpointslices", endpointSlicesHandler)
+	k8sGroup.Get("/endpointslices/:namespace", endpointSlicesHandler)
 
 	// Single resource operations (describe/edit/delete)
 	// Pods
 	k8sGroup.Get("/pod/:namespace/:name", getPodHandler)
 	k8sGroup.Get("/pod/:namespace/:name/logs", getPodLogsHandler)
+	k8sGroup.Get("/pod/:namespace/:name/logs/download", podLogsDownloadHandler)
+	k8sGroup.Post("/pod/:namespace/:name/cp", podCopyUploadHandler)
+	k8sGroup.Get("/pod/:namespace/:name/cp", podCopyDownloadHandler)
+	k8sGroup.Post("/pod/:namespace/:name/debug", createDebugContainerHandler)
 	k8sGroup.Patch("/pod/:namespace/:name", patchPodHandler)
 	k8sGroup.Delete("/pod/:namespace/:name", deletePodHandler)
+	k8sGroup.Post("/pods/delete", batchDeletePodsHandler)
 	// Services
 	k8sGroup.Get("/service/:namespace/:name", getServiceHandler)
 	k8sGroup.Patch("/service/:namespace/:name", patchServiceHandler)
 	k8sGroup.Delete("/service/:namespace/:name", deleteServiceHandler)
+	k8sGroup.Get("/service/:namespace/:name/topology", serviceTopologyHandler)
 	// Deployments
 	k8sGroup.Get("/deployment/:namespace/:name", getDeploymentHandler)
 	k8sGroup.Patch("/deployment/:namespace/:name", patchDeploymentHandler)
 	k8sGroup.Delete("/deployment/:namespace/:name", deleteDeploymentHandler)
 	k8sGroup.Post("/deployment/:namespace/:name/scale", scaleDeploymentHandler)
 	k8sGroup.Post("/deployment/:namespace/:name/restart", restartDeploymentHandler)
+	k8sGroup.Get("/deployment/:namespace/:name/logs/download", deploymentLogsDownloadHandler)
 	// ConfigMaps
 	k8sGroup.Get("/configmap/:namespace/:name", getConfigMapHandler)
 	k8sGroup.Patch("/configmap/:namespace/:name", patchConfigMapHandler)
 	k8sGroup.Delete("/configmap/:namespace/:name", deleteConfigMapHandler)
+	k8sGroup.Get("/configmap/:namespace/:name/impact", configMapImpactHandler)
+	k8sGroup.Post("/configmap/:namespace/:name/rollout", configMapRolloutHandler)
+	k8sGroup.Get("/configmap/:namespace/:name/history", configMapHistoryHandler)
+	k8sGroup.Get("/configmap/:namespace/:name/history/:revision", configMapRevisionHandler)
+	k8sGroup.Post("/configmap/:namespace/:name/history/:revision/rollback", configMapRollbackHandler)
 	// Secrets
 	k8sGroup.Get("/secret/:namespace/:name", getSecretHandler)
 	k8sGroup.Patch("/secret/:namespace/:name", patchSecretHandler)
 	k8sGroup.Delete("/secret/:namespace/:name", deleteSecretHandler)
+	k8sGroup.Get("/secret/:namespace/:name/impact", secretImpactHandler)
+	k8sGroup.Post("/secret/:namespace/:name/rollout", secretRolloutHandler)
+	k8sGroup.Get("/secret/:namespace/:name/history", secretHistoryHandler)
+	k8sGroup.Get("/secret/:namespace/:name/history/:revision", secretRevisionHandler)
+	k8sGroup.Post("/secret/:namespace/:name/history/:revision/rollback", secretRollbackHandler)
 	// Namespaces
 	k8sGroup.Get("/namespace/:name", getNamespaceHandler)
 	k8sGroup.Delete("/namespace/:name", deleteNamespaceHandler)
 	// Nodes
 	k8sGroup.Get("/node/:name", getNodeHandler)
+	k8sGroup.Get("/node/:name/drain-preview", nodeDrainPreviewHandler)
 	// ServiceAccounts
 	k8sGroup.Get("/serviceaccount/:namespace/:name", getServiceAccountHandler)
 	k8sGroup.Delete("/serviceaccount/:namespace/:name", deleteServiceAccountHandler)
@@ -264,6 +366,9 @@ func setupRoutes(app *fiber.App) {
 	// PersistentVolumeClaims
 	k8sGroup.Get("/pvc/:namespace/:name", getPVCHandler)
 	k8sGroup.Patch("/pvc/:namespace/:name", patchPVCHandler)
+	k8sGroup.Get("/pvc/:namespace/:name/resize", pvcResizeStatusHandler)
+	k8sGroup.Post("/pvc/:namespace/:name/resize", resizePVCHandler)
+	k8sGroup.Get("/pvc/:namespace/:name/expand", pvcExpansionHandler)
 	k8sGroup.Delete("/pvc/:namespace/:name", deletePVCHandler)
 	// Ingresses
 	k8sGroup.Get("/ingress/:namespace/:name", getIngressHandler)
@@ -280,6 +385,8 @@ func setupRoutes(app *fiber.App) {
 	k8sGroup.Delete("/statefulset/:namespace/:name", deleteStatefulSetHandler)
 	k8sGroup.Post("/statefulset/:namespace/:name/scale", scaleStatefulSetHandler)
 	k8sGroup.Post("/statefulset/:namespace/:name/restart", restartStatefulSetHandler)
+	k8sGroup.Get("/statefulset/:namespace/:name/pvcs", statefulSetPVCsHandler)
+	k8sGroup.Post("/statefulset/:namespace/:name/pvcs/delete", deleteStatefulSetPVCsHandler)
 	// Jobs
 	k8sGroup.Get("/job/:namespace/:name", getJobHandler)
 	k8sGroup.Delete("/job/:namespace/:name", deleteJobHandler)
@@ -287,13 +394,84 @@ func setupRoutes(app *fiber.App) {
 	k8sGroup.Get("/cronjob/:namespace/:name", getCronJobHandler)
 	k8sGroup.Patch("/cronjob/:namespace/:name", patchCronJobHandler)
 	k8sGroup.Delete("/cronjob/:namespace/:name", deleteCronJobHandler)
+	k8sGroup.Post("/cronjob/:namespace/:name/trigger", triggerCronJobHandler)
+	k8sGroup.Post("/cronjob/:namespace/:name/suspend", suspendCronJobHandler)
+	k8sGroup.Post("/cronjob/:namespace/:name/resume", suspendCronJobHandler)
 	// ReplicaSets
 	k8sGroup.Get("/replicaset/:namespace/:name", getReplicaSetHandler)
 	k8sGroup.Delete("/replicaset/:namespace/:name", deleteReplicaSetHandler)
 	// Events
 	k8sGroup.Get("/event/:namespace/:name", getEventHandler)
-	// Create resource
-	k8sGroup.Post("/create", createResourceHandler)
+	// NetworkPolicies
+	k8sGroup.Get("/networkpolicy/:namespace/:name", getNetworkPolicyHandler)
+	k8sGroup.Patch("/networkpolicy/:namespace/:name", patchNetworkPolicyHandler)
+	k8sGroup.Delete("/networkpolicy/:namespace/:name", deleteNetworkPolicyHandler)
+	// PodDisruptionBudgets
+	k8sGroup.Get("/pdb/:namespace/:name", getPDBHandler)
+	k8sGroup.Patch("/pdb/:namespace/:name", patchPDBHandler)
+	k8sGroup.Delete("/pdb/:namespace/:name", deletePDBHandler)
+	// HorizontalPodAutoscalers
+	k8sGroup.Get("/hpa", monitoringHPAHandler)
+	k8sGroup.Get("/hpa/:namespace", monitoringHPAHandler)
+	k8sGroup.Post("/hpa/:namespace", createHPAHandler)
+	k8sGroup.Post("/hpa/:namespace/generate", generateHPAHandler)
+	k8sGroup.Get("/hpa/:namespace/:name", getHPAHandler)
+	k8sGroup.Put("/hpa/:namespace/:name", updateHPAHandler)
+	k8sGroup.Patch("/hpa/:namespace/:name", patchHPAHandler)
+	k8sGroup.Delete("/hpa/:namespace/:name", deleteHPAHandler)
+	// VerticalPodAutoscalers (requires the VPA CRDs)
+	k8sGroup.Get("/vpa", vpasHandler)
+	k8sGroup.Get("/vpa/:namespace", vpasHandler)
+	k8sGroup.Post("/vpa/:namespace", createVPAHandler)
+	k8sGroup.Get("/vpa/:namespace/:name", getVPAHandler)
+	k8sGroup.Patch("/vpa/:namespace/:name", patchVPAHandler)
+	k8sGroup.Delete("/vpa/:namespace/:name", deleteVPAHandler)
+	// StorageClasses
+	k8sGroup.Get("/storageclass/:name", getStorageClassHandler)
+	k8sGroup.Patch("/storageclass/:name", patchStorageClassHandler)
+	k8sGroup.Delete("/storageclass/:name", deleteStorageClassHandler)
+	// Leases
+	k8sGroup.Get("/lease/:namespace/:name", getLeaseHandler)
+	// Apply resources (server-side apply, multi-document YAML)
+	k8sGroup.Post("/apply", applyHandler)
+	k8sGroup.Post("/create", applyHandler) // deprecated alias
+	// Render a kustomization from Git or an uploaded bundle, optionally applying it
+	k8sGroup.Post("/kustomize", kustomizeHandler)
+	// Validate manifests (server-side dry-run with strict field validation)
+	k8sGroup.Post("/validate", validateHandler)
+	// Preview what a Patch would change
+	k8sGroup.Post("/storageclass/:name/diff", kindDiffHandler("storageclass"))
+	k8sGroup.Post("/:kind/:namespace/:name/diff", diffResourceHandler)
+	k8sGroup.Get("/node/:name/events", kindEventsHandler("node"))
+	k8sGroup.Get("/:kind/:namespace/:name/events", resourceEventsHandler)
+
+	// Debug container terminal WebSocket
+	app.Use("/api/v1/k8s/pod/:namespace/:name/debug/ws", func(c *fiber.Ctx) error {
+		if websocket.IsWebSocketUpgrade(c) {
+			return c.Next()
+		}
+		return fiber.ErrUpgradeRequired
+	})
+	app.Get("/api/v1/k8s/pod/:namespace/:name/debug/ws", websocket.New(podDebugAttachHandler))
+
+	// Guided PVC expansion WebSocket (resize, then follow it to the end)
+	app.Use("/api/v1/k8s/pvc/:namespace/:name/expand/ws", func(c *fiber.Ctx) error {
+		if websocket.IsWebSocketUpgrade(c) {
+			return c.Next()
+		}
+		return fiber.ErrUpgradeRequired
+	})
+	app.Get("/api/v1/k8s/pvc/:namespace/:name/expand/ws", websocket.New(pvcExpandWatchHandler))
+
+	// Resource watch WebSocket (live table updates)
+	app.Use("/api/v1/k8s/watch", func(c *fiber.Ctx) error {
+		if websocket.IsWebSocketUpgrade(c) {
+			return c.Next()
+		}
+		return fiber.ErrUpgradeRequired
+	})
+	app.Get("/api/v1/k8s/watch", websocket.New(k8sWatchHandler))
+	app.Get("/api/v1/k8s/watch/events", websocket.New(eventsWatchHandler))
 
 	// Docker endpoints (placeholder for future)
 	docker := v1.Group("/docker")
@@ -302,6 +480,7 @@ func setupRoutes(app *fiber.App) {
 
 	// Notepad endpoints
 	notepad := v1.Group("/notepad")
+	notepad.Use(subsystemGate(subsystems.Storage))
 	notepad.Get("/", listNotepadsHandler)
 	notepad.Get("/:key", getNotepadHandler)
 	notepad.Post("/:key", saveNotepadHandler)
@@ -309,30 +488,51 @@ func setupRoutes(app *fiber.App) {
 
 	// Desktop preferences endpoints
 	prefs := v1.Group("/preferences")
+	prefs.Use(subsystemGate(subsystems.Storage))
 	prefs.Get("/desktop", getDesktopPrefsHandler)
 	prefs.Post("/desktop", saveDesktopPrefsHandler)
 	prefs.Delete("/desktop", resetDesktopPrefsHandler)
 
 	// CI/CD endpoints
 	cicdGroup := v1.Group("/cicd")
+	cicdGroup.Use(subsystemGate(subsystems.Storage))
 	cicdGroup.Get("/stats", cicdStatsHandler)
+	cicdGroup.Get("/retention", retentionHandler)
+	cicdGroup.Post("/retention/cleanup", retentionCleanupHandler)
 	cicdGroup.Get("/sample", cicdSampleHandler)
 	cicdGroup.Get("/pipelines", listPipelinesHandler)
 	cicdGroup.Post("/pipelines", createPipelineHandler)
+	cicdGroup.Post("/pipelines/validate", validatePipelineHandler)
 	cicdGroup.Get("/pipelines/:id", getPipelineHandler)
 	cicdGroup.Put("/pipelines/:id", updatePipelineHandler)
 	cicdGroup.Delete("/pipelines/:id", deletePipelineHandler)
 	cicdGroup.Post("/pipelines/:id/trigger", triggerPipelineHandler)
+	cicdGroup.Post("/pipelines/:id/dry-run", dryRunPipelineHandler)
 	cicdGroup.Get("/pipelines/:id/runs", listPipelineRunsHandler)
+	cicdGroup.Get("/pipelines/:id/coverage", pipelineCoverageHandler)
+	cicdGroup.Get("/pipelines/:id/analytics", pipelineAnalyticsHandler)
 	cicdGroup.Get("/pipelines/:id/badge", pipelineBadgeHandler)
+	cicdGroup.Get("/pipelines/:id/webhook/tokens", webhookTokensHandler(cicd.WebhookKindPipeline))
+	cicdGroup.Post("/pipelines/:id/webhook/tokens", createWebhookTokenHandler(cicd.WebhookKindPipeline))
+	cicdGroup.Post("/pipelines/:id/webhook/tokens/:tokenId/rotate", rotateWebhookTokenHandler(cicd.WebhookKindPipeline))
+	cicdGroup.Delete("/pipelines/:id/webhook/tokens/:tokenId", revokeWebhookTokenHandler(cicd.WebhookKindPipeline))
 	cicdGroup.Get("/runs", listAllRunsHandler)
 	cicdGroup.Get("/runs/:runId", getRunHandler)
 	cicdGroup.Post("/runs/:runId/cancel", cancelRunHandler)
+	cicdGroup.Post("/runs/:runId/rerun", rerunRunHandler)
 	cicdGroup.Delete("/runs/:runId", deleteRunHandler)
 	cicdGroup.Get("/runs/:runId/jobs/:job/logs", getJobLogsHandler)
+	cicdGroup.Post("/runs/:runId/artifacts", uploadArtifactHandler)
+	cicdGroup.Get("/runs/:runId/tests", getRunTestsHandler)
+	cicdGroup.Get("/runs/:runId/coverage", getRunCoverageHandler)
+	cicdGroup.Get("/runs/:runId/timeline", getRunTimelineHandler)
 	cicdGroup.Get("/artifacts", listArtifactsHandler)
 	cicdGroup.Get("/artifacts/:id/download", downloadArtifactHandler)
 	cicdGroup.Delete("/artifacts/:id", deleteArtifactHandler)
+	cicdGroup.Get("/environments", listEnvironmentsHandler)
+	cicdGroup.Get("/environments/:name", getEnvironmentHandler)
+	cicdGroup.Get("/environments/:name/deployments", listDeploymentsHandler)
+	cicdGroup.Post("/environments/:name/rollback", rollbackEnvironmentHandler)
 
 	// Notification configuration endpoints
 	notifGroup := cicdGroup.Group("/notifications")
@@ -363,6 +563,25 @@ func setupRoutes(app *fiber.App) {
 	gitGroup.Delete("/credentials/:id", deleteGitCredentialHandler)
 	gitGroup.Post("/credentials/:id/test", testGitCredentialHandler)
 
+	// Registry Credentials endpoints
+	registryGroup := cicdGroup.Group("/registry")
+	registryGroup.Get("/credentials", listRegistryCredentialsHandler)
+	registryGroup.Post("/credentials", createRegistryCredentialHandler)
+	registryGroup.Get("/credentials/:id", getRegistryCredentialHandler)
+	registryGroup.Put("/credentials/:id", updateRegistryCredentialHandler)
+	registryGroup.Delete("/credentials/:id", deleteRegistryCredentialHandler)
+
+	// Secret Variables endpoints
+	cicdGroup.Get("/templates", listJobTemplatesHandler)
+	cicdGroup.Post("/templates", createJobTemplateHandler)
+	cicdGroup.Get("/templates/:name/:version", getJobTemplateHandler)
+	cicdGroup.Delete("/templates/:name/:version", deleteJobTemplateHandler)
+	cicdGroup.Get("/secrets", listSecretVariablesHandler)
+	cicdGroup.Post("/secrets", createSecretVariableHandler)
+	cicdGroup.Get("/secrets/:id", getSecretVariableHandler)
+	cicdGroup.Put("/secrets/:id", updateSecretVariableHandler)
+	cicdGroup.Delete("/secrets/:id", deleteSecretVariableHandler)
+
 	// Freestyle Jobs endpoints
 	freestyleGroup := cicdGroup.Group("/freestyle")
 	freestyleGroup.Get("/jobs", listFreestyleJobsHandler)
@@ -370,9 +589,14 @@ func setupRoutes(app *fiber.App) {
 	freestyleGroup.Get("/jobs/:id", getFreestyleJobHandler)
 	freestyleGroup.Put("/jobs/:id", updateFreestyleJobHandler)
 	freestyleGroup.Delete("/jobs/:id", deleteFreestyleJobHandler)
+	freestyleGroup.Get("/jobs/:id/parameters", freestyleJobParametersHandler)
 	freestyleGroup.Post("/jobs/:id/build", triggerFreestyleBuildHandler)
 	freestyleGroup.Get("/jobs/:id/builds", listJobBuildsHandler)
 	freestyleGroup.Get("/jobs/:id/badge", freestyleJobBadgeHandler)
+	freestyleGroup.Get("/jobs/:id/webhook/tokens", webhookTokensHandler(cicd.WebhookKindFreestyle))
+	freestyleGroup.Post("/jobs/:id/webhook/tokens", createWebhookTokenHandler(cicd.WebhookKindFreestyle))
+	freestyleGroup.Post("/jobs/:id/webhook/tokens/:tokenId/rotate", rotateWebhookTokenHandler(cicd.WebhookKindFreestyle))
+	freestyleGroup.Delete("/jobs/:id/webhook/tokens/:tokenId", revokeWebhookTokenHandler(cicd.WebhookKindFreestyle))
 
 	// Freestyle Builds endpoints
 	freestyleGroup.Get("/builds", listFreestyleBuildsHandler)
@@ -405,18 +629,34 @@ func setupRoutes(app *fiber.App) {
 	})
 	app.Get("/api/v1/cicd/freestyle/builds/:id/logs/stream", websocket.New(freestyleLogStreamHandler))
 
+	// Usage analytics
+	v1.Get("/admin/usage", usageReportHandler)
+	v1.Get("/admin/subsystems", listSubsystemsHandler)
+	v1.Post("/admin/subsystems/:name/reinit", reinitSubsystemHandler)
+
+	// Audit log of changes made through the k8s endpoints
+	v1.Get("/audit", auditLogHandler)
+	v1.Get("/audit/:id", getAuditEntryHandler)
+
 	// Monitoring endpoints
 	mon := v1.Group("/monitoring")
+	mon.Use(subsystemGate(subsystems.Monitoring))
 	mon.Get("/summary", monitoringSummaryHandler)
 	mon.Get("/nodes", monitoringNodesHandler)
+	mon.Get("/nodes/allocation", monitoringNodeAllocationHandler)
 	mon.Get("/pods", monitoringPodsHandler)
 	mon.Get("/pods/:namespace", monitoringPodsHandler)
+	mon.Get("/top", monitoringTopHandler)
+	mon.Get("/top/:namespace", monitoringTopHandler)
 	mon.Get("/quotas", monitoringQuotasHandler)
 	mon.Get("/quotas/:namespace", monitoringQuotasHandler)
 	mon.Get("/limitranges", monitoringLimitRangesHandler)
 	mon.Get("/limitranges/:namespace", monitoringLimitRangesHandler)
 	mon.Get("/hpa", monitoringHPAHandler)
 	mon.Get("/hpa/:namespace", monitoringHPAHandler)
+	mon.Get("/volumes", monitoringVolumesHandler)
+	mon.Get("/volumes/alerts", monitoringVolumeAlertsHandler)
+	mon.Get("/volumes/:namespace", monitoringVolumesHandler)
 
 	// Tools endpoints
 	toolsGroup := v1.Group("/tools")
@@ -438,6 +678,31 @@ func setupRoutes(app *fiber.App) {
 	// Diff
 	toolsGroup.Post("/diff", diffHandler)
 
+	// Backend rate limit / circuit breaker state
+	v1.Get("/backends", backendsHandler)
+
+	// Cached connection tests and their health
+	v1.Get("/db/connections", connectionsHandler)
+	v1.Delete("/db/connections/:id", forgetConnectionHandler)
+
+	// Schema migrations against a connection profile
+	v1.Post("/db/migrations", migrationRunHandler)
+	v1.Get("/db/migrations", listMigrationRunsHandler)
+	v1.Get("/db/migrations/:id", getMigrationRunHandler)
+	v1.Get("/db/result-policies", listResultPoliciesHandler)
+	v1.Post("/db/result-policies", saveResultPolicyHandler)
+	v1.Get("/db/result-policies/:id", getResultPolicyHandler)
+	v1.Put("/db/result-policies/:id", saveResultPolicyHandler)
+	v1.Delete("/db/result-policies/:id", deleteResultPolicyHandler)
+	v1.Post("/db/table/rows", tableRowsHandler)
+	v1.Post("/db/imports", importRunHandler)
+	v1.Get("/db/imports", listImportRunsHandler)
+	v1.Get("/db/imports/:id", getImportRunHandler)
+	v1.Get("/db/imports/:id/errors", importErrorRowsHandler)
+	v1.Post("/db/table/row", tableRowChangeHandler(database.RowInsert))
+	v1.Put("/db/table/row", tableRowChangeHandler(database.RowUpdate))
+	v1.Delete("/db/table/row", tableRowChangeHandler(database.RowDelete))
+
 	// Database Tools - PostgreSQL
 	pgGroup := v1.Group("/db/postgres")
 	pgGroup.Post("/connect", postgresConnectHandler)
@@ -454,6 +719,24 @@ func setupRoutes(app *fiber.App) {
 	redisGroup.Post("/scan", redisScanHandler)
 	redisGroup.Post("/key", redisKeyHandler)
 	redisGroup.Post("/command", redisCommandHandler)
+	redisGroup.Post("/replication", redisReplicationHandler)
+	redisGroup.Post("/replication/promote", redisPromoteHandler)
+	redisGroup.Post("/replication/repoint", redisRepointHandler)
+
+	// Memcached endpoints
+	memcachedGroup := v1.Group("/db/memcached")
+	memcachedGroup.Post("/stats", memcachedStatsHandler)
+	memcachedGroup.Post("/slabs", memcachedSlabsHandler)
+	memcachedGroup.Post("/get", memcachedGetHandler)
+	memcachedGroup.Post("/set", memcachedSetHandler)
+	memcachedGroup.Post("/delete", memcachedDeleteHandler)
+
+	// etcd endpoints
+	etcdGroup := v1.Group("/db/etcd")
+	etcdGroup.Post("/members", etcdMembersHandler)
+	etcdGroup.Post("/health", etcdHealthHandler)
+	etcdGroup.Post("/keys", etcdKeysHandler)
+	etcdGroup.Post("/key", etcdKeyHandler)
 
 	// Database Tools - MySQL/MariaDB
 	mysqlGroup := v1.Group("/db/mysql")
@@ -463,7 +746,33 @@ func setupRoutes(app *fiber.App) {
 	mysqlGroup.Post("/dump", mysqlDumpHandler)
 	mysqlGroup.Post("/databases", mysqlDatabasesHandler)
 
+	// Database Tools - SQL Server
+	mssqlGroup := v1.Group("/db/mssql")
+	mssqlGroup.Post("/connect", mssqlConnectHandler)
+	mssqlGroup.Post("/info", mssqlInfoHandler)
+	mssqlGroup.Post("/query", mssqlQueryHandler)
+	mssqlGroup.Post("/databases", mssqlDatabasesHandler)
+	mssqlGroup.Post("/alwayson", mssqlAlwaysOnHandler)
+
+	// Database Tools - Oracle
+	oracleGroup := v1.Group("/db/oracle")
+	oracleGroup.Post("/connect", oracleConnectHandler)
+	oracleGroup.Post("/info", oracleInfoHandler)
+	oracleGroup.Post("/query", oracleQueryHandler)
+	oracleGroup.Post("/tablespaces", oracleTablespacesHandler)
+
 	// S3 Storage
+	// Storage mount health
+	mountsGroup := v1.Group("/storage")
+	mountsGroup.Post("/nfs/check", nfsCheckHandler)
+	mountsGroup.Post("/smb/check", smbCheckHandler)
+	mountsGroup.Post("/path/probe", pathProbeHandler)
+	mountsGroup.Get("/monitors", mountMonitorsHandler)
+	mountsGroup.Post("/monitors", createMountMonitorHandler)
+	mountsGroup.Get("/monitors/:id", mountMonitorHandler)
+	mountsGroup.Delete("/monitors/:id", deleteMountMonitorHandler)
+	mountsGroup.Post("/monitors/:id/run", runMountMonitorHandler)
+
 	s3Group := v1.Group("/storage/s3")
 	s3Group.Post("/connect", s3ConnectHandler)
 	s3Group.Post("/buckets", s3ListBucketsHandler)
@@ -475,6 +784,20 @@ func setupRoutes(app *fiber.App) {
 	s3Group.Post("/object/download", s3DownloadHandler)
 	s3Group.Post("/object/delete", s3DeleteHandler)
 	s3Group.Post("/object/presign", s3PresignHandler)
+	s3Group.Post("/minio/info", minioInfoHandler)
+	s3Group.Post("/minio/heal", minioHealStatusHandler)
+	s3Group.Post("/minio/users", minioUsersHandler)
+	s3Group.Post("/minio/user", minioUserHandler)
+	s3Group.Post("/minio/user/add", minioAddUserHandler)
+	s3Group.Post("/minio/user/status", minioUserStatusHandler)
+	s3Group.Post("/minio/user/remove", minioRemoveUserHandler)
+	s3Group.Post("/minio/policies", minioPoliciesHandler)
+	s3Group.Post("/minio/policy", minioPolicyHandler)
+	s3Group.Post("/minio/policy/add", minioAddPolicyHandler)
+	s3Group.Post("/minio/policy/remove", minioRemovePolicyHandler)
+	s3Group.Post("/minio/policy/set", minioSetPolicyHandler)
+	s3Group.Post("/minio/quota", minioQuotaHandler)
+	s3Group.Post("/minio/quota/set", minioSetQuotaHandler)
 
 	// Elasticsearch
 	esGroup := v1.Group("/elasticsearch")
@@ -492,6 +815,19 @@ func setupRoutes(app *fiber.App) {
 	esGroup.Post("/document", esGetDocumentHandler)
 	esGroup.Post("/document/delete", esDeleteDocumentHandler)
 	esGroup.Post("/query", esQueryHandler)
+	esGroup.Post("/reindex", esReindexHandler)
+	esGroup.Post("/tasks", esTasksHandler)
+	esGroup.Post("/task", esTaskHandler)
+	esGroup.Post("/task/cancel", esCancelTaskHandler)
+	esGroup.Post("/snapshot/repositories", esSnapshotRepositoriesHandler)
+	esGroup.Post("/snapshot/repository/create", esCreateSnapshotRepositoryHandler)
+	esGroup.Post("/snapshot/repository/verify", esVerifySnapshotRepositoryHandler)
+	esGroup.Post("/snapshot/repository/delete", esDeleteSnapshotRepositoryHandler)
+	esGroup.Post("/snapshots", esSnapshotsHandler)
+	esGroup.Post("/snapshot/create", esCreateSnapshotHandler)
+	esGroup.Post("/snapshot/status", esSnapshotStatusHandler)
+	esGroup.Post("/snapshot/restore", esRestoreSnapshotHandler)
+	esGroup.Post("/snapshot/delete", esDeleteSnapshotHandler)
 
 	// WebSocket terminal endpoint
 	app.Use("/api/v1/terminal/ws", func(c *fiber.Ctx) error {
@@ -501,6 +837,11 @@ func setupRoutes(app *fiber.App) {
 		return fiber.ErrUpgradeRequired
 	})
 	app.Get("/api/v1/terminal/ws", websocket.New(terminal.HandleWebSocket))
+
+	// API v2 (dark launch, opt-in via GAGOS_API_V2=true)
+	if apiV2Enabled() {
+		setupV2Routes(app)
+	}
 }
 
 // Basic handlers
@@ -512,6 +853,15 @@ func healthHandler(c *fiber.Ctx) error {
 	})
 }
 
+// backendsHandler reports the client-side rate limit and circuit breaker
+// state for the Kubernetes API server and every database host used so far
+func backendsHandler(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"kubernetes": k8s.APIStatus(),
+		"databases":  database.BackendStatuses(),
+	})
+}
+
 func apiInfoHandler(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"name":        "GAGOS API",
@@ -651,11 +1001,25 @@ func tracerouteHandler(c *fiber.Ctx) error {
 
 // Kubernetes handlers
 
+// k8sListOptions reads the limit, continue, labelSelector and fieldSelector query params
+func k8sListOptions(c *fiber.Ctx) k8s.ListOptions {
+	limit := c.QueryInt("limit", 0)
+	if limit < 0 {
+		limit = 0
+	}
+	return k8s.ListOptions{
+		Limit:         int64(limit),
+		Continue:      c.Query("continue"),
+		LabelSelector: c.Query("labelSelector"),
+		FieldSelector: c.Query("fieldSelector"),
+	}
+}
+
 func namespacesHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	namespaces, err := k8s.ListNamespaces(ctx)
+	namespaces, cont, err := k8s.ListNamespaces(ctx, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -663,6 +1027,7 @@ func namespacesHandler(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"count":      len(namespaces),
 		"namespaces": namespaces,
+		"continue":   cont,
 	})
 }
 
@@ -670,14 +1035,15 @@ func nodesHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	nodes, err := k8s.ListNodes(ctx)
+	nodes, cont, err := k8s.ListNodes(ctx, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.JSON(fiber.Map{
-		"count": len(nodes),
-		"nodes": nodes,
+		"count":    len(nodes),
+		"nodes":    nodes,
+		"continue": cont,
 	})
 }
 
@@ -686,7 +1052,7 @@ func podsHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	pods, err := k8s.ListPods(ctx, namespace)
+	pods, cont, err := k8s.ListPods(ctx, namespace, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -695,6 +1061,7 @@ func podsHandler(c *fiber.Ctx) error {
 		"namespace": namespace,
 		"count":     len(pods),
 		"pods":      pods,
+		"continue":  cont,
 	})
 }
 
@@ -703,7 +1070,7 @@ func servicesHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	services, err := k8s.ListServices(ctx, namespace)
+	services, cont, err := k8s.ListServices(ctx, namespace, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -712,6 +1079,7 @@ func servicesHandler(c *fiber.Ctx) error {
 		"namespace": namespace,
 		"count":     len(services),
 		"services":  services,
+		"continue":  cont,
 	})
 }
 
@@ -720,7 +1088,7 @@ func deploymentsHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	deployments, err := k8s.ListDeployments(ctx, namespace)
+	deployments, cont, err := k8s.ListDeployments(ctx, namespace, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -729,6 +1097,7 @@ func deploymentsHandler(c *fiber.Ctx) error {
 		"namespace":   namespace,
 		"count":       len(deployments),
 		"deployments": deployments,
+		"continue":    cont,
 	})
 }
 
@@ -740,6 +1109,14 @@ func getPodHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if c.Query("view") == "describe" {
+		desc, err := k8s.DescribePod(ctx, namespace, name)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(desc)
+	}
+
 	detail, err := k8s.GetPod(ctx, namespace, name)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
@@ -750,23 +1127,222 @@ func getPodHandler(c *fiber.Ctx) error {
 func getPodLogsHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
-	container := c.Query("container", "")
-	tailLines := c.QueryInt("tail", 100)
+	opts := k8s.PodLogOptions{
+		Container:     c.Query("container", ""),
+		TailLines:     int64(c.QueryInt("tail", 100)),
+		Previous:      c.QueryBool("previous"),
+		AllContainers: c.QueryBool("all_containers"),
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	logs, err := k8s.GetPodLogs(ctx, namespace, name, container, int64(tailLines))
+	logs, err := k8s.GetPodLogs(ctx, namespace, name, opts)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.JSON(fiber.Map{
-		"namespace": namespace,
-		"pod":       name,
+		"namespace":      namespace,
+		"pod":            name,
+		"container":      opts.Container,
+		"previous":       opts.Previous,
+		"all_containers": opts.AllContainers,
+		"logs":           logs,
+	})
+}
+
+func podLogsDownloadHandler(c *fiber.Ctx) error {
+	return logsDownloadHandler(c, "pod")
+}
+
+func deploymentLogsDownloadHandler(c *fiber.Ctx) error {
+	return logsDownloadHandler(c, "deployment")
+}
+
+// logsDownloadHandler streams the full logs of a pod or of every pod of a
+// Deployment as a file.
+// Query params: container, previous, since_time, until_time (RFC 3339 or a
+// duration such as 2h), gzip=true
+func logsDownloadHandler(c *fiber.Ctx, kind string) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	opts := k8s.LogDownloadOptions{
+		Container: c.Query("container"),
+		Previous:  c.QueryBool("previous"),
+	}
+	var err error
+	if opts.SinceTime, err = auditTime(c.Query("since_time")); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid since_time: " + err.Error()})
+	}
+	if opts.UntilTime, err = auditTime(c.Query("until_time")); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid until_time: " + err.Error()})
+	}
+	if !opts.SinceTime.IsZero() && !opts.UntilTime.IsZero() && !opts.UntilTime.After(opts.SinceTime) {
+		return c.Status(400).JSON(fiber.Map{"error": "until_time must be after since_time"})
+	}
+	compress := c.QueryBool("gzip")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	download, err := k8s.PrepareLogDownload(ctx, kind, namespace, name, opts)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("kind", kind).Str("namespace", namespace).Str("name", name).Int("pods", download.Pods()).Str("ip", c.IP()).Msg("Logs download")
+
+	filename := fmt.Sprintf("%s-%s-%s.log", namespace, name, time.Now().UTC().Format("20060102-150405"))
+	if compress {
+		filename += ".gz"
+		c.Set("Content-Type", "application/gzip")
+	} else {
+		c.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		var out io.Writer = w
+		var gz *gzip.Writer
+		if compress {
+			gz = gzip.NewWriter(w)
+			out = gz
+		}
+		// Headers are already sent; a failure can only cut the file short
+		if err := download.Write(ctx, out); err != nil {
+			log.Warn().Err(err).Str("kind", kind).Str("namespace", namespace).Str("name", name).Msg("Logs download failed")
+		}
+		if gz != nil {
+			gz.Close()
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+// podCopyMaxBytes caps pod cp transfers in either direction (GAGOS_POD_CP_MAX_MB, default 100)
+func podCopyMaxBytes() int64 {
+	return envMB("GAGOS_POD_CP_MAX_MB", 100)
+}
+
+// Upload a file into a pod (multipart: file, path, container)
+func podCopyUploadHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	destDir := c.FormValue("path", "/tmp")
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "file is required"})
+	}
+	maxBytes := podCopyMaxBytes()
+	if file.Size > maxBytes {
+		return c.Status(413).JSON(fiber.Map{"error": fmt.Sprintf("file exceeds the %d MB limit", maxBytes>>20)})
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to open file"})
+	}
+	defer src.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	container, err := k8s.ResolveContainer(ctx, namespace, name, c.FormValue("container"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := k8s.CopyToPod(ctx, namespace, name, container, destDir, file.Filename, src, file.Size, maxBytes); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
 		"container": container,
-		"logs":      logs,
+		"path":      strings.TrimSuffix(destDir, "/") + "/" + path.Base(file.Filename),
+		"size":      file.Size,
+	})
+}
+
+// Download a file or directory from a pod as a tar archive (query: path, container)
+func podCopyDownloadHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	srcPath := c.Query("path")
+	if srcPath == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "path is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	container, err := k8s.ResolveContainer(ctx, namespace, name, c.Query("container"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var buf bytes.Buffer
+	maxBytes := podCopyMaxBytes()
+	if err := k8s.CopyFromPod(ctx, namespace, name, container, srcPath, &buf, maxBytes); err != nil {
+		if errors.Is(err, k8s.ErrCopyTooLarge) {
+			return c.Status(413).JSON(fiber.Map{"error": fmt.Sprintf("archive exceeds the %d MB limit", maxBytes>>20)})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set("Content-Type", "application/x-tar")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%s.tar\"", name, path.Base(srcPath)))
+	return c.Send(buf.Bytes())
+}
+
+// Inject an ephemeral debug container into a running pod
+func createDebugContainerHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+
+	var req struct {
+		Image   string   `json:"image"`
+		Target  string   `json:"target"`
+		Command []string `json:"command"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Image == "" {
+		req.Image = getEnv("GAGOS_DEBUG_IMAGE", "busybox:1.36")
+	}
+
+	// Pulling a large image like netshoot can take a while
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	info, err := k8s.CreateDebugContainer(ctx, namespace, name, k8s.DebugOptions{
+		Image:   req.Image,
+		Target:  req.Target,
+		Command: req.Command,
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"debug":     info,
+		"attach_ws": fmt.Sprintf("/api/v1/k8s/pod/%s/%s/debug/ws?container=%s", namespace, name, info.Container),
 	})
 }
 
+// podDebugAttachHandler attaches a terminal to a debug container over WebSocket
+func podDebugAttachHandler(c *websocket.Conn) {
+	container := c.Query("container")
+	if container == "" {
+		c.WriteJSON(terminal.WsMessage{Type: terminal.MsgTypeOutput, Data: "Error: container is required\r\n"})
+		return
+	}
+	terminal.HandlePodAttach(c, c.Params("namespace"), c.Params("name"), container)
+}
+
 func patchPodHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
@@ -790,15 +1366,94 @@ func patchPodHandler(c *fiber.Ctx) error {
 func deletePodHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
+	opts, err := podDeleteOptionsFromQuery(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := k8s.DeletePod(ctx, namespace, name); err != nil {
+	if err := k8s.DeletePod(ctx, namespace, name, opts); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.JSON(fiber.Map{"success": true, "message": "Pod deleted"})
 }
 
+// podDeleteOptionsFromQuery reads ?gracePeriodSeconds=N and ?force=true
+func podDeleteOptionsFromQuery(c *fiber.Ctx) (k8s.PodDeleteOptions, error) {
+	opts := k8s.PodDeleteOptions{Force: c.QueryBool("force", false)}
+	if v := c.Query("gracePeriodSeconds"); v != "" {
+		grace, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || grace < 0 {
+			return opts, fmt.Errorf("invalid gracePeriodSeconds %q", v)
+		}
+		opts.GracePeriodSeconds = &grace
+	}
+	return opts, nil
+}
+
+// batchDeletePodsHandler deletes or evicts every pod matching the body's
+// selectors, e.g. {"namespace":"default","reason":"Evicted"} or
+// {"phases":["Succeeded","Failed"],"dry_run":true}
+func batchDeletePodsHandler(c *fiber.Ctx) error {
+	var req struct {
+		Namespace          string   `json:"namespace"`
+		LabelSelector      string   `json:"label_selector"`
+		FieldSelector      string   `json:"field_selector"`
+		Phases             []string `json:"phases"`
+		Reason             string   `json:"reason"`
+		Evict              bool     `json:"evict"`
+		GracePeriodSeconds *int64   `json:"grace_period_seconds"`
+		Force              bool     `json:"force"`
+		DryRun             bool     `json:"dry_run"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Namespace == "" {
+		req.Namespace = c.Params("namespace")
+	}
+	if req.GracePeriodSeconds != nil && *req.GracePeriodSeconds < 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "grace_period_seconds must not be negative"})
+	}
+	if req.LabelSelector == "" && req.FieldSelector == "" && len(req.Phases) == 0 && req.Reason == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "label_selector, field_selector, phases or reason is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	results, err := k8s.DeletePods(ctx, req.Namespace, k8s.PodBatchOptions{
+		PodDeleteOptions: k8s.PodDeleteOptions{
+			GracePeriodSeconds: req.GracePeriodSeconds,
+			Force:              req.Force,
+		},
+		LabelSelector: req.LabelSelector,
+		FieldSelector: req.FieldSelector,
+		Phases:        req.Phases,
+		Reason:        req.Reason,
+		Evict:         req.Evict,
+		DryRun:        req.DryRun,
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Action == k8s.PodBatchFailed {
+			failed++
+		}
+	}
+	return c.JSON(fiber.Map{
+		"success": failed == 0,
+		"dry_run": req.DryRun,
+		"matched": len(results),
+		"failed":  failed,
+		"pods":    results,
+	})
+}
+
 // Single resource handlers - Services
 
 func getServiceHandler(c *fiber.Ctx) error {
@@ -846,6 +1501,20 @@ func deleteServiceHandler(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true, "message": "Service deleted"})
 }
 
+// Service topology: the service, its endpoints and selected pods, with diagnostics
+func serviceTopologyHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	topo, err := k8s.GetServiceTopology(ctx, namespace, name)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(topo)
+}
+
 // Single resource handlers - Deployments
 
 func getDeploymentHandler(c *fiber.Ctx) error {
@@ -854,6 +1523,14 @@ func getDeploymentHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if c.Query("view") == "describe" {
+		desc, err := k8s.DescribeDeployment(ctx, namespace, name)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(desc)
+	}
+
 	detail, err := k8s.GetDeployment(ctx, namespace, name)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
@@ -945,7 +1622,8 @@ func patchConfigMapHandler(c *fiber.Ctx) error {
 	name := c.Params("name")
 
 	var req struct {
-		YAML string `json:"yaml"`
+		YAML             string `json:"yaml"`
+		RestartConsumers bool   `json:"restart_consumers"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
@@ -954,10 +1632,43 @@ func patchConfigMapHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	snapshotConfig(ctx, c, "configmap", namespace, name, confighistory.SourceObserved)
 	if err := k8s.PatchConfigMap(ctx, namespace, name, req.YAML); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "ConfigMap updated"})
+	snapshotConfig(ctx, c, "configmap", namespace, name, confighistory.SourceEdit)
+	if !req.RestartConsumers {
+		resp := fiber.Map{"success": true, "message": "ConfigMap updated"}
+		if warning := configEditWarning(ctx, "configmap", namespace, name); warning != nil {
+			resp["warning"] = warning
+		}
+		return c.JSON(resp)
+	}
+	results, err := rolloutConfigConsumers(c, "configmap", namespace, name, nil)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "ConfigMap updated, but restarting its consumers failed: " + err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "ConfigMap updated", "rollout": results})
+}
+
+func configMapImpactHandler(c *fiber.Ctx) error {
+	return configImpactHandler(c, "configmap")
+}
+
+func configMapRolloutHandler(c *fiber.Ctx) error {
+	return configRolloutHandler(c, "configmap")
+}
+
+func configMapHistoryHandler(c *fiber.Ctx) error {
+	return configHistoryHandler(c, "configmap")
+}
+
+func configMapRevisionHandler(c *fiber.Ctx) error {
+	return configRevisionHandler(c, "configmap")
+}
+
+func configMapRollbackHandler(c *fiber.Ctx) error {
+	return configRollbackHandler(c, "configmap")
 }
 
 func deleteConfigMapHandler(c *fiber.Ctx) error {
@@ -974,9 +1685,17 @@ func deleteConfigMapHandler(c *fiber.Ctx) error {
 
 // Single resource handlers - Secrets
 
+// getSecretHandler returns a secret as YAML. With ?decode=true it also
+// returns the decoded values, of the comma separated ?keys= only when given;
+// that needs GAGOS_SECRET_DECODE=true and an elevated session, and every
+// attempt is logged.
 func getSecretHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
+	if c.QueryBool("decode", false) {
+		return decodeSecretHandler(c, namespace, name)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -987,12 +1706,41 @@ func getSecretHandler(c *fiber.Ctx) error {
 	return c.JSON(detail)
 }
 
+func decodeSecretHandler(c *fiber.Ctx, namespace, name string) error {
+	var keys []string
+	if q := c.Query("keys"); q != "" {
+		keys = strings.Split(q, ",")
+	}
+	audit := log.Info().Str("namespace", namespace).Str("name", name).Strs("keys", keys).Str("ip", c.IP())
+
+	if !k8s.SecretDecodeEnabled() {
+		audit.Str("result", "disabled").Msg("Secret decode refused")
+		return c.Status(403).JSON(fiber.Map{"error": "secret decoding is disabled (GAGOS_SECRET_DECODE)"})
+	}
+	if !auth.IsElevated(c) {
+		audit.Str("result", "not elevated").Msg("Secret decode refused")
+		return queryGuardError(c, fmt.Errorf("%w to decode a secret", database.ErrElevationRequired))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	detail, err := k8s.DecodeSecret(ctx, namespace, name, keys)
+	if err != nil {
+		audit.Err(err).Msg("Secret decode failed")
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	audit.Strs("revealed", detail.Revealed).Msg("Secret decoded")
+	return c.JSON(detail)
+}
+
 func patchSecretHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
 
 	var req struct {
-		YAML string `json:"yaml"`
+		YAML             string `json:"yaml"`
+		RestartConsumers bool   `json:"restart_consumers"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
@@ -1001,17 +1749,233 @@ func patchSecretHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	snapshotConfig(ctx, c, "secret", namespace, name, confighistory.SourceObserved)
 	if err := k8s.PatchSecret(ctx, namespace, name, req.YAML); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "Secret updated"})
+	snapshotConfig(ctx, c, "secret", namespace, name, confighistory.SourceEdit)
+	if !req.RestartConsumers {
+		resp := fiber.Map{"success": true, "message": "Secret updated"}
+		if warning := configEditWarning(ctx, "secret", namespace, name); warning != nil {
+			resp["warning"] = warning
+		}
+		return c.JSON(resp)
+	}
+	results, err := rolloutConfigConsumers(c, "secret", namespace, name, nil)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Secret updated, but restarting its consumers failed: " + err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "Secret updated", "rollout": results})
 }
 
-func deleteSecretHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace")
-	name := c.Params("name")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+func secretImpactHandler(c *fiber.Ctx) error {
+	return configImpactHandler(c, "secret")
+}
+
+func secretRolloutHandler(c *fiber.Ctx) error {
+	return configRolloutHandler(c, "secret")
+}
+
+func secretHistoryHandler(c *fiber.Ctx) error {
+	return configHistoryHandler(c, "secret")
+}
+
+func secretRevisionHandler(c *fiber.Ctx) error {
+	return configRevisionHandler(c, "secret")
+}
+
+func secretRollbackHandler(c *fiber.Ctx) error {
+	return configRollbackHandler(c, "secret")
+}
+
+// configImpactHandler lists the workloads that use a ConfigMap or Secret
+func configImpactHandler(c *fiber.Ctx, kind string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	impact, err := k8s.GetConfigImpact(ctx, kind, c.Params("namespace"), c.Params("name"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(impact)
+}
+
+// configRolloutHandler restarts the workloads that use a ConfigMap or Secret
+func configRolloutHandler(c *fiber.Ctx, kind string) error {
+	var req struct {
+		Workloads []string `json:"workloads"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		}
+	}
+
+	results, err := rolloutConfigConsumers(c, kind, c.Params("namespace"), c.Params("name"), req.Workloads)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "results": results})
+}
+
+// snapshotConfig records the current content of a ConfigMap or Secret in
+// its history. The history is best effort and never fails an edit.
+func snapshotConfig(ctx context.Context, c *fiber.Ctx, kind, namespace, name, source string) {
+	if _, err := confighistory.Snapshot(ctx, kind, namespace, name, source, c.IP()); err != nil {
+		log.Warn().Err(err).Str("kind", kind).Str("namespace", namespace).Str("name", name).Msg("Failed to record config revision")
+	}
+}
+
+// configHistoryHandler lists the revisions of a ConfigMap or Secret
+func configHistoryHandler(c *fiber.Ctx, kind string) error {
+	revisions, err := confighistory.List(kind, c.Params("namespace"), c.Params("name"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"revisions": revisions, "limit": confighistory.Limit()})
+}
+
+// configRevisionHandler shows what rolling back to a revision would change.
+// Secret values need ?reveal=true, GAGOS_SECRET_DECODE and an elevated session.
+func configRevisionHandler(c *fiber.Ctx, kind string) error {
+	revision, err := c.ParamsInt("revision")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid revision"})
+	}
+	reveal := kind == "secret" && c.QueryBool("reveal")
+	if reveal {
+		if !k8s.SecretDecodeEnabled() {
+			return c.Status(403).JSON(fiber.Map{"error": "secret decoding is disabled (GAGOS_SECRET_DECODE)"})
+		}
+		if !auth.IsElevated(c) {
+			return queryGuardError(c, fmt.Errorf("%w to reveal secret values", database.ErrElevationRequired))
+		}
+		log.Info().Str("namespace", c.Params("namespace")).Str("name", c.Params("name")).Int("revision", revision).Str("ip", c.IP()).Msg("Secret revision revealed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	diff, err := confighistory.Diff(ctx, kind, c.Params("namespace"), c.Params("name"), revision, reveal)
+	if err != nil {
+		if errors.Is(err, confighistory.ErrRevisionNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(diff)
+}
+
+// configRollbackHandler restores a ConfigMap or Secret to a revision, and
+// restarts its consumers when asked
+func configRollbackHandler(c *fiber.Ctx, kind string) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	revision, err := c.ParamsInt("revision")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid revision"})
+	}
+	var req struct {
+		RestartConsumers bool `json:"restart_consumers"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rev, err := confighistory.Rollback(ctx, kind, namespace, name, revision, c.IP())
+	if err != nil {
+		if errors.Is(err, confighistory.ErrRevisionNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("kind", kind).Str("namespace", namespace).Str("name", name).Int("revision", revision).Str("ip", c.IP()).Msg("Rolled back config")
+
+	resp := fiber.Map{"success": true, "message": fmt.Sprintf("Rolled back to revision %d", revision)}
+	if rev == nil {
+		resp["message"] = fmt.Sprintf("Already at the content of revision %d", revision)
+	} else {
+		resp["revision"] = rev.Revision
+	}
+	if req.RestartConsumers {
+		results, err := rolloutConfigConsumers(c, kind, namespace, name, nil)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Rolled back, but restarting the consumers failed: " + err.Error()})
+		}
+		resp["rollout"] = results
+	} else if warning := configEditWarning(ctx, kind, namespace, name); warning != nil {
+		resp["warning"] = warning
+	}
+	return c.JSON(resp)
+}
+
+// configEditWarning describes the workloads an edit to a ConfigMap or Secret
+// affects and how to restart them, or returns nil when nothing uses it
+func configEditWarning(ctx context.Context, kind, namespace, name string) fiber.Map {
+	impact, err := k8s.GetConfigImpact(ctx, kind, namespace, name)
+	if err != nil || len(impact.Consumers) == 0 {
+		return nil
+	}
+	var stale, restartable []string
+	for _, consumer := range impact.Consumers {
+		workload := consumer.Kind + "/" + consumer.Name
+		if consumer.NeedsRestart {
+			stale = append(stale, workload)
+		}
+		if consumer.Restartable {
+			restartable = append(restartable, workload)
+		}
+	}
+
+	message := fmt.Sprintf("%d workload(s) and %d pod(s) use this %s", len(impact.Consumers), len(impact.Pods), kind)
+	if len(stale) > 0 {
+		message += fmt.Sprintf("; %s only see the change after a restart", strings.Join(stale, ", "))
+	}
+	warning := fiber.Map{"message": message, "consumers": impact.Consumers, "pods": impact.Pods}
+	if len(restartable) > 0 {
+		warning["restart"] = fiber.Map{
+			"method":    "POST",
+			"path":      fmt.Sprintf("/api/v1/k8s/%s/%s/%s/rollout", kind, namespace, name),
+			"workloads": restartable,
+		}
+	}
+	return warning
+}
+
+func rolloutConfigConsumers(c *fiber.Ctx, kind, namespace, name string, workloads []string) ([]k8s.RolloutResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	results, err := k8s.RolloutConfigConsumers(ctx, kind, namespace, name, workloads)
+	if err != nil {
+		return nil, err
+	}
+	restarted := make([]string, 0, len(results))
+	for _, r := range results {
+		if r.Restarted {
+			restarted = append(restarted, r.Kind+"/"+r.Name)
+		}
+	}
+	log.Info().
+		Str("kind", kind).
+		Str("namespace", namespace).
+		Str("name", name).
+		Strs("restarted", restarted).
+		Str("ip", c.IP()).
+		Msg("Restarted config consumers")
+	return results, nil
+}
+
+func deleteSecretHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
 	if err := k8s.DeleteSecret(ctx, namespace, name); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
@@ -1033,6 +1997,36 @@ func getNamespaceHandler(c *fiber.Ctx) error {
 	return c.JSON(detail)
 }
 
+// createNamespaceHandler creates a namespace with an optional quota, limit
+// range and default network policy from the onboarding templates
+func createNamespaceHandler(c *fiber.Ctx) error {
+	var req k8s.NamespaceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := k8s.CreateNamespace(ctx, req)
+	if err != nil {
+		if result == nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(422).JSON(fiber.Map{"success": false, "error": err.Error(), "results": result.Results})
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"dry_run": req.DryRun,
+		"results": result.Results,
+		"yaml":    result.YAML,
+	})
+}
+
+func namespaceTemplatesHandler(c *fiber.Ctx) error {
+	return c.JSON(k8s.GetNamespaceTemplates())
+}
+
 func deleteNamespaceHandler(c *fiber.Ctx) error {
 	name := c.Params("name")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -1058,6 +2052,19 @@ func getNodeHandler(c *fiber.Ctx) error {
 	return c.JSON(detail)
 }
 
+// nodeDrainPreviewHandler reports what draining the node would do to each
+// pod, without evicting anything
+func nodeDrainPreviewHandler(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	preview, err := k8s.PreviewDrain(ctx, c.Params("name"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(preview)
+}
+
 // List handlers for additional K8s resources
 
 func configMapsHandler(c *fiber.Ctx) error {
@@ -1065,7 +2072,7 @@ func configMapsHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cms, err := k8s.ListConfigMaps(ctx, namespace)
+	cms, cont, err := k8s.ListConfigMaps(ctx, namespace, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -1074,6 +2081,7 @@ func configMapsHandler(c *fiber.Ctx) error {
 		"namespace":  namespace,
 		"count":      len(cms),
 		"configmaps": cms,
+		"continue":   cont,
 	})
 }
 
@@ -1082,7 +2090,7 @@ func secretsHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	secrets, err := k8s.ListSecrets(ctx, namespace)
+	secrets, cont, err := k8s.ListSecrets(ctx, namespace, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -1091,6 +2099,7 @@ func secretsHandler(c *fiber.Ctx) error {
 		"namespace": namespace,
 		"count":     len(secrets),
 		"secrets":   secrets,
+		"continue":  cont,
 	})
 }
 
@@ -1099,7 +2108,7 @@ func serviceAccountsHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	sas, err := k8s.ListServiceAccounts(ctx, namespace)
+	sas, cont, err := k8s.ListServiceAccounts(ctx, namespace, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -1108,6 +2117,7 @@ func serviceAccountsHandler(c *fiber.Ctx) error {
 		"namespace":       namespace,
 		"count":           len(sas),
 		"serviceaccounts": sas,
+		"continue":        cont,
 	})
 }
 
@@ -1115,14 +2125,15 @@ func pvsHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	pvs, err := k8s.ListPersistentVolumes(ctx)
+	pvs, cont, err := k8s.ListPersistentVolumes(ctx, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.JSON(fiber.Map{
-		"count": len(pvs),
-		"pvs":   pvs,
+		"count":    len(pvs),
+		"pvs":      pvs,
+		"continue": cont,
 	})
 }
 
@@ -1131,7 +2142,7 @@ func pvcsHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	pvcs, err := k8s.ListPersistentVolumeClaims(ctx, namespace)
+	pvcs, cont, err := k8s.ListPersistentVolumeClaims(ctx, namespace, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -1140,6 +2151,7 @@ func pvcsHandler(c *fiber.Ctx) error {
 		"namespace": namespace,
 		"count":     len(pvcs),
 		"pvcs":      pvcs,
+		"continue":  cont,
 	})
 }
 
@@ -1148,7 +2160,7 @@ func ingressesHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	ingresses, err := k8s.ListIngresses(ctx, namespace)
+	ingresses, cont, err := k8s.ListIngresses(ctx, namespace, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -1157,6 +2169,7 @@ func ingressesHandler(c *fiber.Ctx) error {
 		"namespace": namespace,
 		"count":     len(ingresses),
 		"ingresses": ingresses,
+		"continue":  cont,
 	})
 }
 
@@ -1165,7 +2178,7 @@ func daemonSetsHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	dss, err := k8s.ListDaemonSets(ctx, namespace)
+	dss, cont, err := k8s.ListDaemonSets(ctx, namespace, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -1174,6 +2187,7 @@ func daemonSetsHandler(c *fiber.Ctx) error {
 		"namespace":  namespace,
 		"count":      len(dss),
 		"daemonsets": dss,
+		"continue":   cont,
 	})
 }
 
@@ -1182,7 +2196,7 @@ func statefulSetsHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	sss, err := k8s.ListStatefulSets(ctx, namespace)
+	sss, cont, err := k8s.ListStatefulSets(ctx, namespace, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -1191,6 +2205,7 @@ func statefulSetsHandler(c *fiber.Ctx) error {
 		"namespace":    namespace,
 		"count":        len(sss),
 		"statefulsets": sss,
+		"continue":     cont,
 	})
 }
 
@@ -1199,7 +2214,7 @@ func jobsHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	jobs, err := k8s.ListJobs(ctx, namespace)
+	jobs, cont, err := k8s.ListJobs(ctx, namespace, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -1208,6 +2223,7 @@ func jobsHandler(c *fiber.Ctx) error {
 		"namespace": namespace,
 		"count":     len(jobs),
 		"jobs":      jobs,
+		"continue":  cont,
 	})
 }
 
@@ -1216,7 +2232,7 @@ func cronJobsHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cjs, err := k8s.ListCronJobs(ctx, namespace)
+	cjs, cont, err := k8s.ListCronJobs(ctx, namespace, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -1225,6 +2241,7 @@ func cronJobsHandler(c *fiber.Ctx) error {
 		"namespace": namespace,
 		"count":     len(cjs),
 		"cronjobs":  cjs,
+		"continue":  cont,
 	})
 }
 
@@ -1233,7 +2250,14 @@ func eventsHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	events, err := k8s.ListEvents(ctx, namespace)
+	opts := k8sListOptions(c)
+	if selector := eventFilterFromQuery(c.Query).FieldSelector(); selector != "" {
+		if opts.FieldSelector != "" {
+			selector = opts.FieldSelector + "," + selector
+		}
+		opts.FieldSelector = selector
+	}
+	events, cont, err := k8s.ListEvents(ctx, namespace, opts)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -1242,237 +2266,432 @@ func eventsHandler(c *fiber.Ctx) error {
 		"namespace": namespace,
 		"count":     len(events),
 		"events":    events,
+		"continue":  cont,
 	})
 }
 
-func replicaSetsHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace", "")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// resourceEventsHandler returns the events of a resource and of the objects it owns
+func resourceEventsHandler(c *fiber.Ctx) error {
+	return resourceEvents(c, c.Params("kind"))
+}
 
-	rss, err := k8s.ListReplicaSets(ctx, namespace)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+// kindEventsHandler serves resourceEventsHandler on routes that name the kind
+// in the path, such as the cluster-scoped node routes
+func kindEventsHandler(kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return resourceEvents(c, kind)
 	}
-
-	return c.JSON(fiber.Map{
-		"namespace":   namespace,
-		"count":       len(rss),
-		"replicasets": rss,
-	})
 }
 
-// Single resource handlers for additional K8s resources
+func resourceEvents(c *fiber.Ctx, kind string) error {
+	if kind == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "kind is required"})
+	}
 
-func getServiceAccountHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace")
-	name := c.Params("name")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	detail, err := k8s.GetServiceAccount(ctx, namespace, name)
+	result, err := k8s.CorrelatedEvents(ctx, kind, c.Params("namespace", ""), c.Params("name"))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(detail)
+	return c.JSON(result)
 }
 
-func deleteServiceAccountHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace")
-	name := c.Params("name")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// k8sSearchHandler searches cached resources by name, label, annotation and
+// reference. Query params: q, kinds=configmaps,deployments (default all but
+// events), namespace, limit
+func k8sSearchHandler(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	if err := k8s.DeleteServiceAccount(ctx, namespace, name); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	result, err := k8s.SearchResources(ctx, c.Query("q"), splitQueryList(c.Query("kinds")), c.Query("namespace"), c.QueryInt("limit"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "ServiceAccount deleted"})
+	return c.JSON(result)
 }
 
-func getPVHandler(c *fiber.Ctx) error {
-	name := c.Params("name")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// k8sExportHandler streams a tar.gz of the namespace's objects as cleaned
+// YAML; ?secrets=true includes Secrets and needs an elevated session
+func k8sExportHandler(c *fiber.Ctx) error {
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "namespace is required"})
+	}
+	opts := k8s.ExportOptions{IncludeSecrets: c.QueryBool("secrets")}
+	if opts.IncludeSecrets && !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to export Secrets", database.ErrElevationRequired))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	detail, err := k8s.GetPersistentVolume(ctx, name)
+	export, err := k8s.PrepareExport(ctx, namespace, opts)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(detail)
+	log.Info().Str("namespace", namespace).Bool("secrets", opts.IncludeSecrets).Str("ip", c.IP()).Msg("Namespace export")
+
+	filename := fmt.Sprintf("%s-%s.tar.gz", namespace, time.Now().UTC().Format("20060102-150405"))
+	c.Set("Content-Type", "application/gzip")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		// Headers are already sent; a failure can only cut the archive short
+		if err := export.Write(ctx, w); err != nil {
+			log.Warn().Err(err).Str("namespace", namespace).Msg("Namespace export failed")
+		}
+		w.Flush()
+	})
+	return nil
 }
 
-func deletePVHandler(c *fiber.Ctx) error {
-	name := c.Params("name")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// k8sAPIsHandler reports the server version, the served API groups and
+// which optional APIs GAGOS features need are present. ?refresh=true skips
+// the cache.
+func k8sAPIsHandler(c *fiber.Ctx) error {
+	apis, err := k8s.DiscoverAPIs(c.QueryBool("refresh"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(apis)
+}
+
+// k8sCloneHandler copies a workload with its ConfigMaps, Secrets and
+// Services into another namespace. Copying Secrets needs elevation.
+func k8sCloneHandler(c *fiber.Ctx) error {
+	var opts k8s.CloneOptions
+	if err := c.BodyParser(&opts); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if opts.Kind == "" || opts.Namespace == "" || opts.Name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "kind, namespace and name are required"})
+	}
+	opts.Kind = strings.ToLower(opts.Kind)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := k8s.DeletePersistentVolume(ctx, name); err != nil {
+	if !auth.IsElevated(c) {
+		secrets, err := k8s.CloneUsesSecrets(ctx, opts)
+		if err != nil {
+			if errors.Is(err, k8s.ErrClone) {
+				return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+			}
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if secrets {
+			return queryGuardError(c, fmt.Errorf("%w to copy Secrets (or set skip_secrets)", database.ErrElevationRequired))
+		}
+	}
+
+	result, err := k8s.CloneWorkload(ctx, opts)
+	if err != nil {
+		if errors.Is(err, k8s.ErrClone) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "PersistentVolume deleted"})
+	if !opts.DryRun {
+		log.Info().Str("kind", opts.Kind).Str("namespace", opts.Namespace).Str("name", opts.Name).
+			Str("target_namespace", result.TargetNamespace).Str("new_name", result.NewName).
+			Int("objects", len(result.Objects)).Str("ip", c.IP()).Msg("Cloned workload")
+	}
+	return c.JSON(result)
 }
 
-func getPVCHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace")
-	name := c.Params("name")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// k8sHygieneHandler reports unused ConfigMaps and Secrets, released PVs,
+// old failed Jobs and ReplicaSets scaled to zero
+func k8sHygieneHandler(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	detail, err := k8s.GetPersistentVolumeClaim(ctx, namespace, name)
+	report, err := k8s.GetHygieneReport(ctx, k8s.HygieneOptions{
+		Namespace:        c.Query("namespace"),
+		FailedJobAgeDays: c.QueryInt("failed_job_age_days"),
+		IncludeSystem:    c.QueryBool("include_system"),
+	})
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(detail)
+	return c.JSON(report)
 }
 
-func patchPVCHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace")
-	name := c.Params("name")
-
+// k8sHygieneCleanupHandler deletes items of a hygiene report, e.g.
+// {"items":[{"kind":"configmaps","namespace":"default","name":"old"}],"dry_run":true}
+func k8sHygieneCleanupHandler(c *fiber.Ctx) error {
 	var req struct {
-		YAML string `json:"yaml"`
+		k8s.HygieneOptions
+		Items  []k8s.HygieneItem `json:"items"`
+		DryRun bool              `json:"dry_run"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
+	if len(req.Items) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "items is required"})
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	if err := k8s.PatchPersistentVolumeClaim(ctx, namespace, name, req.YAML); err != nil {
+	results, err := k8s.CleanupHygiene(ctx, req.HygieneOptions, req.Items, req.DryRun)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "PVC updated"})
-}
-
-func deletePVCHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace")
-	name := c.Params("name")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
 
-	if err := k8s.DeletePersistentVolumeClaim(ctx, namespace, name); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	failed := 0
+	for _, r := range results {
+		if r.Action == k8s.HygieneFailed {
+			failed++
+		}
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "PVC deleted"})
+	return c.JSON(fiber.Map{
+		"success": failed == 0,
+		"dry_run": req.DryRun,
+		"failed":  failed,
+		"items":   results,
+	})
 }
 
-func getIngressHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace")
-	name := c.Params("name")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func k8sImagesHandler(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	detail, err := k8s.GetIngress(ctx, namespace, name)
+	inventory, err := k8s.ListImages(ctx, c.Query("namespace"))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(detail)
+	return c.JSON(inventory)
 }
 
-func patchIngressHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace")
-	name := c.Params("name")
+// k8sImageScanHandler returns the last stored scan of ?image=
+func k8sImageScanHandler(c *fiber.Ctx) error {
+	image := c.Query("image")
+	if image == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "image is required"})
+	}
+	scan, err := k8s.GetImageScan(image)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(scan)
+}
 
+// k8sScanImageHandler scans a running image with the configured scanner,
+// which can take minutes the first time Trivy downloads its database
+func k8sScanImageHandler(c *fiber.Ctx) error {
 	var req struct {
-		YAML string `json:"yaml"`
+		Image string `json:"image"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
+	if req.Image == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "image is required"})
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
-	if err := k8s.PatchIngress(ctx, namespace, name, req.YAML); err != nil {
+	scan, err := k8s.ScanImage(ctx, req.Image)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "Ingress updated"})
+	log.Info().Str("image", req.Image).Str("scanner", scan.Scanner).Int("vulnerabilities", len(scan.Vulnerabilities)).Str("ip", c.IP()).Msg("Image scanned")
+	return c.JSON(scan)
 }
 
-func deleteIngressHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace")
-	name := c.Params("name")
+func replicaSetsHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace", "")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := k8s.DeleteIngress(ctx, namespace, name); err != nil {
+	rss, cont, err := k8s.ListReplicaSets(ctx, namespace, k8sListOptions(c))
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "Ingress deleted"})
-}
 
-func getDaemonSetHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace")
-	name := c.Params("name")
+	return c.JSON(fiber.Map{
+		"namespace":   namespace,
+		"count":       len(rss),
+		"replicasets": rss,
+		"continue":    cont,
+	})
+}
+
+func networkPoliciesHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace", "")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	detail, err := k8s.GetDaemonSet(ctx, namespace, name)
+	policies, cont, err := k8s.ListNetworkPolicies(ctx, namespace, k8sListOptions(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(detail)
+
+	return c.JSON(fiber.Map{
+		"namespace":       namespace,
+		"count":           len(policies),
+		"networkpolicies": policies,
+		"continue":        cont,
+	})
 }
 
-func patchDaemonSetHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace")
-	name := c.Params("name")
+func pdbsHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	var req struct {
-		YAML string `json:"yaml"`
+	pdbs, cont, err := k8s.ListPodDisruptionBudgets(ctx, namespace, k8sListOptions(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+
+	return c.JSON(fiber.Map{
+		"namespace": namespace,
+		"count":     len(pdbs),
+		"pdbs":      pdbs,
+		"continue":  cont,
+	})
+}
+
+func storageClassesHandler(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	classes, cont, err := k8s.ListStorageClasses(ctx, k8sListOptions(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	return c.JSON(fiber.Map{
+		"count":          len(classes),
+		"storageclasses": classes,
+		"continue":       cont,
+	})
+}
+
+func leasesHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace", "")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := k8s.PatchDaemonSet(ctx, namespace, name, req.YAML); err != nil {
+	leases, cont, err := k8s.ListLeases(ctx, namespace, c.Query("type"), k8sListOptions(c))
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "DaemonSet updated"})
+
+	return c.JSON(fiber.Map{
+		"namespace": namespace,
+		"count":     len(leases),
+		"leases":    leases,
+		"continue":  cont,
+	})
 }
 
-func deleteDaemonSetHandler(c *fiber.Ctx) error {
+func endpointsHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	endpoints, cont, err := k8s.ListEndpoints(ctx, namespace, k8sListOptions(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"namespace": namespace,
+		"count":     len(endpoints),
+		"endpoints": endpoints,
+		"continue":  cont,
+	})
+}
+
+func endpointSlicesHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	slices, cont, err := k8s.ListEndpointSlices(ctx, namespace, k8sListOptions(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"namespace":      namespace,
+		"count":          len(slices),
+		"endpointslices": slices,
+		"continue":       cont,
+	})
+}
+
+// Single resource handlers for additional K8s resources
+
+func getServiceAccountHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := k8s.DeleteDaemonSet(ctx, namespace, name); err != nil {
+	detail, err := k8s.GetServiceAccount(ctx, namespace, name)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "DaemonSet deleted"})
+	return c.JSON(detail)
 }
 
-func restartDaemonSetHandler(c *fiber.Ctx) error {
+func deleteServiceAccountHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := k8s.RestartDaemonSet(ctx, namespace, name); err != nil {
+	if err := k8s.DeleteServiceAccount(ctx, namespace, name); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "DaemonSet restart triggered"})
+	return c.JSON(fiber.Map{"success": true, "message": "ServiceAccount deleted"})
 }
 
-func getStatefulSetHandler(c *fiber.Ctx) error {
+func getPVHandler(c *fiber.Ctx) error {
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	detail, err := k8s.GetPersistentVolume(ctx, name)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(detail)
+}
+
+func deletePVHandler(c *fiber.Ctx) error {
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := k8s.DeletePersistentVolume(ctx, name); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "PersistentVolume deleted"})
+}
+
+func getPVCHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	detail, err := k8s.GetStatefulSet(ctx, namespace, name)
+	detail, err := k8s.GetPersistentVolumeClaim(ctx, namespace, name)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.JSON(detail)
 }
 
-func patchStatefulSetHandler(c *fiber.Ctx) error {
+func patchPVCHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
 
@@ -1486,95 +2705,137 @@ func patchStatefulSetHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := k8s.PatchStatefulSet(ctx, namespace, name, req.YAML); err != nil {
+	if err := k8s.PatchPersistentVolumeClaim(ctx, namespace, name, req.YAML); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "StatefulSet updated"})
+	return c.JSON(fiber.Map{"success": true, "message": "PVC updated"})
 }
 
-func deleteStatefulSetHandler(c *fiber.Ctx) error {
+func pvcResizeStatusHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := k8s.DeleteStatefulSet(ctx, namespace, name); err != nil {
+	status, err := k8s.GetPVCResizeStatus(ctx, namespace, name)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "StatefulSet deleted"})
+	return c.JSON(status)
 }
 
-func scaleStatefulSetHandler(c *fiber.Ctx) error {
+// resizePVCHandler expands a PVC to "size"; poll GET .../resize for progress
+func resizePVCHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
 
 	var req struct {
-		Replicas int32 `json:"replicas"`
+		Size string `json:"size"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
+	if req.Size == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "size is required"})
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := k8s.ScaleStatefulSet(ctx, namespace, name, req.Replicas); err != nil {
+	status, err := k8s.ResizePVC(ctx, namespace, name, req.Size)
+	if errors.Is(err, k8s.ErrPVCNotResizable) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": fmt.Sprintf("StatefulSet scaled to %d replicas", req.Replicas)})
+	log.Info().Str("namespace", namespace).Str("name", name).Str("size", status.Requested).Str("ip", c.IP()).Msg("PVC resize requested")
+	return c.JSON(status)
 }
 
-func restartStatefulSetHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace")
-	name := c.Params("name")
+// pvcExpansionHandler reports how far a PVC expansion has got;
+// ?stuck_after=<seconds> (default 120)
+func pvcExpansionHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := k8s.RestartStatefulSet(ctx, namespace, name); err != nil {
+	stuckAfter := time.Duration(c.QueryInt("stuck_after")) * time.Second
+	report, err := k8s.GetPVCExpansion(ctx, c.Params("namespace"), c.Params("name"), stuckAfter)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "StatefulSet restart triggered"})
+	return c.JSON(report)
 }
 
-func getJobHandler(c *fiber.Ctx) error {
+// pvcExpandWatchHandler resizes a PVC when ?size is given, then streams its
+// expansion until it completes, fails or is stuck (?stuck_after=<seconds>).
+// The last message has "done": true.
+func pvcExpandWatchHandler(c *websocket.Conn) {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	stuckAfter := time.Duration(0)
+	if v, err := strconv.Atoi(c.Query("stuck_after")); err == nil && v > 0 {
+		stuckAfter = time.Duration(v) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
 	defer cancel()
 
-	detail, err := k8s.GetJob(ctx, namespace, name)
+	// Stop following as soon as the client goes away
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if size := c.Query("size"); size != "" {
+		status, err := k8s.ResizePVC(ctx, namespace, name, size)
+		if err != nil {
+			c.WriteJSON(fiber.Map{"type": "ERROR", "error": err.Error(), "done": true})
+			return
+		}
+		log.Info().Str("namespace", namespace).Str("name", name).Str("size", status.Requested).Str("ip", c.IP()).Msg("PVC resize requested")
+	}
+
+	report, err := k8s.WatchPVCExpansion(ctx, namespace, name, stuckAfter, func(r *k8s.PVCExpansion) error {
+		return c.WriteJSON(fiber.Map{"type": "PROGRESS", "expansion": r})
+	})
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		c.WriteJSON(fiber.Map{"type": "ERROR", "error": err.Error(), "done": true})
+		return
 	}
-	return c.JSON(detail)
+	c.WriteJSON(fiber.Map{"type": "RESULT", "expansion": report, "done": true})
 }
 
-func deleteJobHandler(c *fiber.Ctx) error {
+func deletePVCHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := k8s.DeleteJob(ctx, namespace, name); err != nil {
+	if err := k8s.DeletePersistentVolumeClaim(ctx, namespace, name); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "Job deleted"})
+	return c.JSON(fiber.Map{"success": true, "message": "PVC deleted"})
 }
 
-func getCronJobHandler(c *fiber.Ctx) error {
+func getIngressHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	detail, err := k8s.GetCronJob(ctx, namespace, name)
+	detail, err := k8s.GetIngress(ctx, namespace, name)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.JSON(detail)
 }
 
-func patchCronJobHandler(c *fiber.Ctx) error {
+func patchIngressHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
 
@@ -1588,2097 +2849,4966 @@ func patchCronJobHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := k8s.PatchCronJob(ctx, namespace, name, req.YAML); err != nil {
+	if err := k8s.PatchIngress(ctx, namespace, name, req.YAML); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "CronJob updated"})
+	return c.JSON(fiber.Map{"success": true, "message": "Ingress updated"})
 }
 
-func deleteCronJobHandler(c *fiber.Ctx) error {
+func deleteIngressHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := k8s.DeleteCronJob(ctx, namespace, name); err != nil {
+	if err := k8s.DeleteIngress(ctx, namespace, name); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "CronJob deleted"})
+	return c.JSON(fiber.Map{"success": true, "message": "Ingress deleted"})
 }
 
-func getReplicaSetHandler(c *fiber.Ctx) error {
+func getDaemonSetHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	detail, err := k8s.GetReplicaSet(ctx, namespace, name)
+	detail, err := k8s.GetDaemonSet(ctx, namespace, name)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.JSON(detail)
 }
 
-func deleteReplicaSetHandler(c *fiber.Ctx) error {
+func patchDaemonSetHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
+
+	var req struct {
+		YAML string `json:"yaml"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := k8s.DeleteReplicaSet(ctx, namespace, name); err != nil {
+	if err := k8s.PatchDaemonSet(ctx, namespace, name, req.YAML); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "ReplicaSet deleted"})
+	return c.JSON(fiber.Map{"success": true, "message": "DaemonSet updated"})
 }
 
-func getEventHandler(c *fiber.Ctx) error {
+func deleteDaemonSetHandler(c *fiber.Ctx) error {
 	namespace := c.Params("namespace")
 	name := c.Params("name")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	detail, err := k8s.GetEvent(ctx, namespace, name)
-	if err != nil {
+	if err := k8s.DeleteDaemonSet(ctx, namespace, name); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(detail)
+	return c.JSON(fiber.Map{"success": true, "message": "DaemonSet deleted"})
 }
 
-// Create resource handler
-func createResourceHandler(c *fiber.Ctx) error {
-	var req struct {
-		Type      string `json:"type"`
-		Namespace string `json:"namespace"`
-		YAML      string `json:"yaml"`
-	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
-	}
+func restartDaemonSetHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	if req.Type == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "resource type is required"})
-	}
-	if req.YAML == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "YAML content is required"})
-	}
-	if req.Namespace == "" {
-		req.Namespace = "default"
+	if err := k8s.RestartDaemonSet(ctx, namespace, name); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(fiber.Map{"success": true, "message": "DaemonSet restart triggered"})
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func getStatefulSetHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var err error
-	switch req.Type {
-	case "deployment":
-		err = k8s.CreateDeployment(ctx, req.Namespace, req.YAML)
-	case "service":
-		err = k8s.CreateService(ctx, req.Namespace, req.YAML)
-	case "configmap":
-		err = k8s.CreateConfigMap(ctx, req.Namespace, req.YAML)
-	case "secret":
-		err = k8s.CreateSecret(ctx, req.Namespace, req.YAML)
-	case "ingress":
-		err = k8s.CreateIngress(ctx, req.Namespace, req.YAML)
-	case "pod":
-		err = k8s.CreatePod(ctx, req.Namespace, req.YAML)
-	case "cronjob":
-		err = k8s.CreateCronJob(ctx, req.Namespace, req.YAML)
-	case "job":
-		err = k8s.CreateJob(ctx, req.Namespace, req.YAML)
-	case "pvc":
-		err = k8s.CreatePersistentVolumeClaim(ctx, req.Namespace, req.YAML)
-	case "serviceaccount":
-		err = k8s.CreateServiceAccount(ctx, req.Namespace, req.YAML)
-	case "daemonset":
-		err = k8s.CreateDaemonSet(ctx, req.Namespace, req.YAML)
-	case "statefulset":
-		err = k8s.CreateStatefulSet(ctx, req.Namespace, req.YAML)
-	default:
-		return c.Status(400).JSON(fiber.Map{"error": "unsupported resource type: " + req.Type})
-	}
-
+	detail, err := k8s.GetStatefulSet(ctx, namespace, name)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": fmt.Sprintf("%s created successfully", req.Type)})
+	return c.JSON(detail)
 }
 
-// New Network Tool handlers
-
-type TelnetRequest struct {
-	Host    string `json:"host"`
-	Port    int    `json:"port"`
-	Command string `json:"command"`
-	Timeout int    `json:"timeout"`
-}
+func patchStatefulSetHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
 
-func telnetHandler(c *fiber.Ctx) error {
-	var req TelnetRequest
+	var req struct {
+		YAML string `json:"yaml"`
+	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	if req.Host == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "host is required"})
-	}
-	if req.Port <= 0 || req.Port > 65535 {
-		return c.Status(400).JSON(fiber.Map{"error": "valid port (1-65535) is required"})
-	}
-	if req.Timeout <= 0 || req.Timeout > 30 {
-		req.Timeout = 10
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	result := network.TelnetConnect(req.Host, req.Port, req.Command, time.Duration(req.Timeout)*time.Second)
-	return c.JSON(result)
+	if err := k8s.PatchStatefulSet(ctx, namespace, name, req.YAML); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "StatefulSet updated"})
 }
 
-type WhoisRequest struct {
-	Query   string `json:"query"`
-	Timeout int    `json:"timeout"`
+func deleteStatefulSetHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := k8s.DeleteStatefulSet(ctx, namespace, name); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "StatefulSet deleted"})
 }
 
-func whoisHandler(c *fiber.Ctx) error {
-	var req WhoisRequest
+func scaleStatefulSetHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+
+	var req struct {
+		Replicas int32 `json:"replicas"`
+	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	if req.Query == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "query is required"})
-	}
-	if req.Timeout <= 0 || req.Timeout > 30 {
-		req.Timeout = 10
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	result := network.Whois(req.Query, time.Duration(req.Timeout)*time.Second)
-	return c.JSON(result)
+	if err := k8s.ScaleStatefulSet(ctx, namespace, name, req.Replicas); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "message": fmt.Sprintf("StatefulSet scaled to %d replicas", req.Replicas)})
 }
 
-type SSLCheckRequest struct {
-	Host    string `json:"host"`
-	Port    int    `json:"port"`
-	Timeout int    `json:"timeout"`
-}
+func restartStatefulSetHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-func sslCheckHandler(c *fiber.Ctx) error {
-	var req SSLCheckRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	if err := k8s.RestartStatefulSet(ctx, namespace, name); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(fiber.Map{"success": true, "message": "StatefulSet restart triggered"})
+}
 
-	if req.Host == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "host is required"})
-	}
-	if req.Port <= 0 {
-		req.Port = 443
-	}
-	if req.Timeout <= 0 || req.Timeout > 30 {
-		req.Timeout = 10
-	}
+// statefulSetPVCsHandler lists the claims of each replica and those left
+// behind by scaling down
+func statefulSetPVCsHandler(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	result := network.CheckSSL(req.Host, req.Port, time.Duration(req.Timeout)*time.Second)
+	result, err := k8s.GetStatefulSetPVCs(ctx, c.Params("namespace"), c.Params("name"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
 	return c.JSON(result)
 }
 
-type CurlRequest struct {
-	URL             string            `json:"url"`
-	Method          string            `json:"method"`
-	Headers         map[string]string `json:"headers"`
-	Body            string            `json:"body"`
-	Timeout         int               `json:"timeout"`
-	FollowRedirects bool              `json:"follow_redirects"`
-	IncludeBody     bool              `json:"include_body"`
-}
+// deleteStatefulSetPVCsHandler deletes the claims of one ordinal, e.g.
+// {"ordinal": 2, "recreate_pod": true} to give replica 2 new volumes
+func deleteStatefulSetPVCsHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
 
-func curlHandler(c *fiber.Ctx) error {
-	var req CurlRequest
+	var req struct {
+		Ordinal     *int `json:"ordinal"`
+		RecreatePod bool `json:"recreate_pod"`
+	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
+	if req.Ordinal == nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ordinal is required"})
+	}
 
-	if req.URL == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "url is required"})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	deleted, err := k8s.DeleteStatefulSetReplicaPVCs(ctx, namespace, name, *req.Ordinal, req.RecreatePod)
+	if len(deleted) > 0 {
+		log.Info().Str("namespace", namespace).Str("statefulset", name).Int("ordinal", *req.Ordinal).Strs("deleted", deleted).Str("ip", c.IP()).Msg("StatefulSet replica PVCs deleted")
 	}
-	if req.Method == "" {
-		req.Method = "GET"
+	if errors.Is(err, k8s.ErrStatefulSetPVC) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
-	if req.Timeout <= 0 || req.Timeout > 60 {
-		req.Timeout = 30
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error(), "deleted": deleted})
 	}
-
-	result := network.Curl(req.URL, req.Method, req.Headers, req.Body, time.Duration(req.Timeout)*time.Second, req.FollowRedirects, req.IncludeBody)
-	return c.JSON(result)
-}
-
-func interfacesHandler(c *fiber.Ctx) error {
-	result := network.GetNetworkInfo()
-	return c.JSON(result)
+	return c.JSON(fiber.Map{"success": true, "deleted": deleted})
 }
 
-// Docker handlers (placeholders)
-
-func containersHandler(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
-		"message": "Docker containers endpoint - coming soon",
-		"status":  "placeholder",
-	})
-}
+// statefulSetOrphanPVCsHandler finds claims left by scaled-down
+// StatefulSets; ?namespace= (default all)
+func statefulSetOrphanPVCsHandler(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-func imagesHandler(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
-		"message": "Docker images endpoint - coming soon",
-		"status":  "placeholder",
-	})
+	orphans, err := k8s.FindStatefulSetOrphanPVCs(ctx, c.Query("namespace"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"count": len(orphans), "orphans": orphans})
 }
 
-// Notepad handlers
+func getJobHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-func listNotepadsHandler(c *fiber.Ctx) error {
-	keys, err := storage.ListNotepads()
+	detail, err := k8s.GetJob(ctx, namespace, name)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	if keys == nil {
-		keys = []string{}
-	}
-	return c.JSON(fiber.Map{
-		"notepads": keys,
-		"count":    len(keys),
-	})
+	return c.JSON(detail)
 }
 
-func getNotepadHandler(c *fiber.Ctx) error {
-	key := c.Params("key")
-	if key == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "key is required"})
-	}
+func deleteJobHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	data, err := storage.GetNotepad(key)
-	if err != nil {
+	if err := k8s.DeleteJob(ctx, namespace, name); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(fiber.Map{"success": true, "message": "Job deleted"})
+}
 
-	if data == nil {
-		return c.JSON(fiber.Map{
-			"key":     key,
-			"content": "",
-		})
-	}
+func getCronJobHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	return c.JSON(fiber.Map{
-		"key":       key,
-		"content":   data.Content,
-		"updatedAt": data.UpdatedAt,
-	})
+	detail, err := k8s.GetCronJob(ctx, namespace, name)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(detail)
 }
 
-func saveNotepadHandler(c *fiber.Ctx) error {
-	key := c.Params("key")
-	if key == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "key is required"})
-	}
+func patchCronJobHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
 
 	var req struct {
-		Content string `json:"content"`
+		YAML string `json:"yaml"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	data := &storage.NotepadData{
-		Content:   req.Content,
-		UpdatedAt: time.Now().Unix(),
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	if err := storage.SaveNotepad(key, data); err != nil {
+	if err := k8s.PatchCronJob(ctx, namespace, name, req.YAML); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{
-		"success":   true,
-		"key":       key,
-		"updatedAt": data.UpdatedAt,
-	})
+	return c.JSON(fiber.Map{"success": true, "message": "CronJob updated"})
 }
 
-func deleteNotepadHandler(c *fiber.Ctx) error {
-	key := c.Params("key")
-	if key == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "key is required"})
-	}
+// triggerCronJobHandler runs a CronJob now by creating a Job from its template
+func triggerCronJobHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	if err := storage.DeleteNotepad(key); err != nil {
+	job, err := k8s.TriggerCronJob(ctx, namespace, name)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{
-		"success": true,
-		"key":     key,
-	})
+	return c.JSON(fiber.Map{"success": true, "message": "Job " + job + " created", "job": job})
 }
 
-// Desktop preferences handlers
+// suspendCronJobHandler serves both .../suspend and .../resume
+func suspendCronJobHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	suspend := strings.HasSuffix(c.Path(), "/suspend")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-func getDesktopPrefsHandler(c *fiber.Ctx) error {
-	prefs, err := storage.GetDesktopPreferences()
-	if err != nil {
+	if err := k8s.SetCronJobSuspend(ctx, namespace, name, suspend); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	if prefs == nil {
-		return c.JSON(fiber.Map{})
-	}
-
-	// Return whichever format is stored
-	result := fiber.Map{"updated_at": prefs.UpdatedAt}
-	if prefs.Slots != nil {
-		result["slots"] = prefs.Slots
-	}
-	if prefs.IconOrder != nil {
-		result["icon_order"] = prefs.IconOrder
+	message := "CronJob resumed"
+	if suspend {
+		message = "CronJob suspended"
 	}
-	if prefs.Hidden != nil {
-		result["hidden"] = prefs.Hidden
-	}
-	return c.JSON(result)
+	return c.JSON(fiber.Map{"success": true, "message": message, "suspend": suspend})
 }
 
-func saveDesktopPrefsHandler(c *fiber.Ctx) error {
-	var req struct {
-		Slots     []*string `json:"slots"`
-		IconOrder []string  `json:"icon_order"`
-		Hidden    []string  `json:"hidden"`
-	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
-	}
+func deleteCronJobHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	prefs := &storage.DesktopPreferences{
-		Slots:     req.Slots,
-		IconOrder: req.IconOrder,
-		Hidden:    req.Hidden,
-		UpdatedAt: time.Now().Unix(),
+	if err := k8s.DeleteCronJob(ctx, namespace, name); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(fiber.Map{"success": true, "message": "CronJob deleted"})
+}
 
-	if err := storage.SaveDesktopPreferences(prefs); err != nil {
+func getReplicaSetHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	detail, err := k8s.GetReplicaSet(ctx, namespace, name)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{
-		"success":    true,
-		"updated_at": prefs.UpdatedAt,
-	})
+	return c.JSON(detail)
 }
 
-func resetDesktopPrefsHandler(c *fiber.Ctx) error {
-	if err := storage.DeletePreference("desktop"); err != nil {
+func deleteReplicaSetHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := k8s.DeleteReplicaSet(ctx, namespace, name); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(fiber.Map{"success": true, "message": "ReplicaSet deleted"})
+}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-	})
+func getEventHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	detail, err := k8s.GetEvent(ctx, namespace, name)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(detail)
 }
 
-// Auth handlers
+func getNetworkPolicyHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-func loginPageHandler(c *fiber.Ctx) error {
-	return c.SendFile("/app/web/static/login.html")
+	detail, err := k8s.GetNetworkPolicy(ctx, namespace, name)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(detail)
 }
 
-func loginHandler(c *fiber.Ctx) error {
+func patchNetworkPolicyHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+
 	var req struct {
-		Password string `json:"password"`
+		YAML string `json:"yaml"`
 	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
-	}
-
-	if !auth.ValidatePassword(req.Password) {
-		return c.Status(401).JSON(fiber.Map{"error": "invalid password"})
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	token := auth.CreateSession()
-	c.Cookie(&fiber.Cookie{
-		Name:     "gagos_session",
-		Value:    token,
-		HTTPOnly: true,
-		Secure:   false, // Set true for HTTPS
-		SameSite: "Lax",
-		MaxAge:   86400, // 24 hours
-	})
-
-	return c.JSON(fiber.Map{"success": true})
-}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-func logoutHandler(c *fiber.Ctx) error {
-	token := c.Cookies("gagos_session")
-	if token != "" {
-		auth.DeleteSession(token)
+	if err := k8s.PatchNetworkPolicy(ctx, namespace, name, req.YAML); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	c.Cookie(&fiber.Cookie{
-		Name:   "gagos_session",
-		Value:  "",
-		MaxAge: -1,
-	})
-
-	return c.JSON(fiber.Map{"success": true})
+	return c.JSON(fiber.Map{"success": true, "message": "NetworkPolicy updated"})
 }
 
-func runtimeHandler(c *fiber.Ctx) error {
-	runtime := getEnv("GAGOS_RUNTIME", "docker")
-	return c.JSON(fiber.Map{
-		"runtime": runtime,
-	})
+func deleteNetworkPolicyHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := k8s.DeleteNetworkPolicy(ctx, namespace, name); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "NetworkPolicy deleted"})
 }
 
-// CI/CD handlers
+func getPDBHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-func cicdStatsHandler(c *fiber.Ctx) error {
-	stats, err := cicd.GetStats()
+	detail, err := k8s.GetPodDisruptionBudget(ctx, namespace, name)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(stats)
+	return c.JSON(detail)
 }
 
-func cicdSampleHandler(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
-		"yaml": cicd.GetSamplePipelineYAML(),
-	})
-}
+func getLeaseHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-func listPipelinesHandler(c *fiber.Ctx) error {
-	pipelines, err := cicd.ListPipelines()
+	detail, err := k8s.GetLease(ctx, namespace, name)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{
-		"count":     len(pipelines),
-		"pipelines": pipelines,
-	})
+	return c.JSON(detail)
 }
 
-func createPipelineHandler(c *fiber.Ctx) error {
-	var req cicd.CreatePipelineRequest
+func patchPDBHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+
+	var req struct {
+		YAML string `json:"yaml"`
+	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	if req.YAML == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "yaml is required"})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := k8s.PatchPodDisruptionBudget(ctx, namespace, name, req.YAML); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(fiber.Map{"success": true, "message": "PodDisruptionBudget updated"})
+}
 
-	pipeline, err := cicd.ParsePipelineYAML(req.YAML)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+func deletePDBHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := k8s.DeletePodDisruptionBudget(ctx, namespace, name); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(fiber.Map{"success": true, "message": "PodDisruptionBudget deleted"})
+}
 
-	if err := cicd.SavePipeline(pipeline); err != nil {
+func getHPAHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	detail, err := k8s.GetHPA(ctx, namespace, name)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(detail)
+}
 
-	// Register with scheduler if has cron triggers
-	if scheduler := cicd.GetScheduler(); scheduler != nil {
-		scheduler.RegisterPipeline(pipeline)
+// createHPAHandler creates an HPA from a structured spec. ?dry_run=true
+// validates it server-side and returns the resulting YAML.
+func createHPAHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+
+	var spec k8s.HPASpec
+	if err := c.BodyParser(&spec); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	return c.Status(201).JSON(fiber.Map{
-		"id":          pipeline.ID,
-		"name":        pipeline.Name,
-		"webhook_url": pipeline.Status.WebhookURL,
-		"created_at":  pipeline.CreatedAt.Format(time.RFC3339),
-	})
-}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-func getPipelineHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	pipeline, err := cicd.GetPipeline(id)
+	detail, err := k8s.CreateHPA(ctx, namespace, spec, c.QueryBool("dry_run"))
 	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(pipeline)
+	return c.JSON(detail)
 }
 
-func pipelineBadgeHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	pipeline, err := cicd.GetPipeline(id)
-	if err != nil {
-		return c.Status(404).SendString(generateBadgeSVG("not found", "#9f9f9f"))
+// generateHPAHandler builds an HPA for an existing Deployment, creating it
+// only when "create" is set
+func generateHPAHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+
+	var req struct {
+		k8s.HPASpec
+		Deployment string `json:"deployment"`
+		Create     bool   `json:"create"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Deployment == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "deployment is required"})
 	}
 
-	// Determine status and color
-	status := "no builds"
-	color := "#9f9f9f"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	// Get last run status if we have a last run
-	if pipeline.Status.LastRunID != "" {
-		run, err := cicd.GetRun(pipeline.Status.LastRunID)
-		if err == nil {
-			switch run.Status {
-			case cicd.RunStatusSucceeded:
-				status = "passing"
-				color = "#4ade80"
-			case cicd.RunStatusFailed:
-				status = "failing"
-				color = "#ef4444"
-			case cicd.RunStatusRunning:
-				status = "running"
-				color = "#fbbf24"
-			case cicd.RunStatusCancelled:
-				status = "cancelled"
-				color = "#9f9f9f"
-			default:
-				status = string(run.Status)
-			}
-		}
+	result, err := k8s.GenerateHPA(ctx, namespace, req.Deployment, req.HPASpec, req.Create)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	c.Set("Content-Type", "image/svg+xml")
-	c.Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	return c.SendString(generateBadgeSVG(status, color))
+	return c.JSON(result)
 }
 
-func generateBadgeSVG(status, color string) string {
-	labelWidth := 50
-	statusWidth := len(status)*7 + 10
-	totalWidth := labelWidth + statusWidth
+func updateHPAHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
 
-	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20">
-  <linearGradient id="b" x2="0" y2="100%%">
-    <stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
-    <stop offset="1" stop-opacity=".1"/>
-  </linearGradient>
-  <mask id="a">
-    <rect width="%d" height="20" rx="3" fill="#fff"/>
-  </mask>
-  <g mask="url(#a)">
-    <rect width="%d" height="20" fill="#555"/>
-    <rect x="%d" width="%d" height="20" fill="%s"/>
-    <rect width="%d" height="20" fill="url(#b)"/>
-  </g>
-  <g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11">
-    <text x="%d" y="15" fill="#010101" fill-opacity=".3">build</text>
-    <text x="%d" y="14">build</text>
-    <text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text>
-    <text x="%d" y="14">%s</text>
-  </g>
-</svg>`, totalWidth, totalWidth, labelWidth, labelWidth, statusWidth, color, totalWidth,
-		labelWidth/2, labelWidth/2,
-		labelWidth+statusWidth/2, status,
-		labelWidth+statusWidth/2, status)
-}
-
-func updatePipelineHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-
-	// Get existing pipeline
-	existing, err := cicd.GetPipeline(id)
-	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
-	}
-
-	var req cicd.CreatePipelineRequest
-	if err := c.BodyParser(&req); err != nil {
+	var spec k8s.HPASpec
+	if err := c.BodyParser(&spec); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	if req.YAML == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "yaml is required"})
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	// Parse the new YAML
-	newPipeline, err := cicd.ParsePipelineYAML(req.YAML)
+	detail, err := k8s.UpdateHPA(ctx, namespace, name, spec)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
-	}
-
-	// Preserve ID and status
-	newPipeline.ID = existing.ID
-	newPipeline.Status.TotalRuns = existing.Status.TotalRuns
-	newPipeline.Status.LastRunID = existing.Status.LastRunID
-	newPipeline.Status.LastRunAt = existing.Status.LastRunAt
-	newPipeline.Status.WebhookToken = existing.Status.WebhookToken
-	newPipeline.Status.WebhookURL = existing.Status.WebhookURL
-	newPipeline.CreatedAt = existing.CreatedAt
-	newPipeline.UpdatedAt = time.Now()
-
-	if err := cicd.SavePipeline(newPipeline); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	// Update scheduler
-	if scheduler := cicd.GetScheduler(); scheduler != nil {
-		scheduler.RegisterPipeline(newPipeline)
-	}
-
-	return c.JSON(fiber.Map{
-		"id":         newPipeline.ID,
-		"name":       newPipeline.Name,
-		"updated_at": newPipeline.UpdatedAt.Format(time.RFC3339),
-	})
+	return c.JSON(detail)
 }
 
-func deletePipelineHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
+func patchHPAHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
 
-	// Unregister from scheduler
-	if scheduler := cicd.GetScheduler(); scheduler != nil {
-		scheduler.UnregisterPipeline(id)
+	var req struct {
+		YAML string `json:"yaml"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	if err := cicd.DeletePipeline(id); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := k8s.PatchHPA(ctx, namespace, name, req.YAML); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{"success": true})
+	return c.JSON(fiber.Map{"success": true, "message": "HorizontalPodAutoscaler updated"})
 }
 
-func triggerPipelineHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
+func deleteHPAHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	pipeline, err := cicd.GetPipeline(id)
-	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	if err := k8s.DeleteHPA(ctx, namespace, name); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(fiber.Map{"success": true, "message": "HorizontalPodAutoscaler deleted"})
+}
 
-	var req cicd.TriggerPipelineRequest
-	c.BodyParser(&req) // Optional body
-
+func vpasHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace", "")
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	run, err := cicd.TriggerPipeline(ctx, pipeline, "manual", "", req.Variables)
+	vpas, err := k8s.ListVPAs(ctx, namespace)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.JSON(fiber.Map{
-		"run_id":     run.ID,
-		"run_number": run.RunNumber,
-		"status":     run.Status,
+		"namespace": namespace,
+		"count":     len(vpas),
+		"vpas":      vpas,
 	})
 }
 
-func listPipelineRunsHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	limit := c.QueryInt("limit", 50)
+func getVPAHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	runs, err := cicd.ListRuns(id, limit)
+	detail, err := k8s.GetVPA(ctx, namespace, name)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{
-		"count": len(runs),
-		"runs":  runs,
-	})
+	return c.JSON(detail)
 }
 
-func listAllRunsHandler(c *fiber.Ctx) error {
-	limit := c.QueryInt("limit", 50)
+func createVPAHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
 
-	runs, err := cicd.ListRuns("", limit)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	var spec k8s.VPASpec
+	if err := c.BodyParser(&spec); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	return c.JSON(fiber.Map{
-		"count": len(runs),
-		"runs":  runs,
-	})
-}
-
-func getRunHandler(c *fiber.Ctx) error {
-	runId := c.Params("runId")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	run, err := cicd.GetRun(runId)
+	vpa, err := k8s.CreateVPA(ctx, namespace, spec)
 	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(run)
+	return c.JSON(vpa)
 }
 
-func cancelRunHandler(c *fiber.Ctx) error {
-	runId := c.Params("runId")
+func patchVPAHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	var req struct {
+		YAML string `json:"yaml"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := cicd.CancelRun(ctx, runId); err != nil {
+	if err := k8s.PatchVPA(ctx, namespace, name, req.YAML); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{"success": true})
+	return c.JSON(fiber.Map{"success": true, "message": "VerticalPodAutoscaler updated"})
 }
 
-func deleteRunHandler(c *fiber.Ctx) error {
-	runId := c.Params("runId")
-
-	// Clean up artifacts
-	cicd.CleanupRunArtifacts(runId)
+func deleteVPAHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	if err := cicd.DeleteRun(runId); err != nil {
+	if err := k8s.DeleteVPA(ctx, namespace, name); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{"success": true})
+	return c.JSON(fiber.Map{"success": true, "message": "VerticalPodAutoscaler deleted"})
 }
 
-func getJobLogsHandler(c *fiber.Ctx) error {
-	runId := c.Params("runId")
-	jobName := c.Params("job")
-	tailLines := int64(c.QueryInt("tail", 1000))
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func getStorageClassHandler(c *fiber.Ctx) error {
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	logs, err := cicd.GetJobLogs(ctx, runId, jobName, tailLines)
+	detail, err := k8s.GetStorageClass(ctx, name)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{
-		"run_id": runId,
-		"job":    jobName,
-		"logs":   logs,
-	})
-}
-
-func cicdLogStreamHandler(c *websocket.Conn) {
-	runId := c.Params("runId")
-	jobName := c.Params("job")
-	cicd.StreamJobLogs(c, runId, jobName)
+	return c.JSON(detail)
 }
 
-func cicdWebhookHandler(c *fiber.Ctx) error {
-	pipelineId := c.Params("pipelineId")
-	token := c.Params("token")
+func patchStorageClassHandler(c *fiber.Ctx) error {
+	name := c.Params("name")
 
-	var payload cicd.WebhookPayload
-	c.BodyParser(&payload) // Optional
+	var req struct {
+		YAML string `json:"yaml"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
 
-	signature := c.Get("X-Hub-Signature-256", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	run, err := cicd.HandleWebhook(pipelineId, token, &payload, signature)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	if err := k8s.PatchStorageClass(ctx, name, req.YAML); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{
-		"run_id":     run.ID,
-		"run_number": run.RunNumber,
-		"status":     run.Status,
-	})
+	return c.JSON(fiber.Map{"success": true, "message": "StorageClass updated"})
 }
 
-func listArtifactsHandler(c *fiber.Ctx) error {
-	runId := c.Query("run_id", "")
-	pipelineId := c.Query("pipeline_id", "")
+func deleteStorageClassHandler(c *fiber.Ctx) error {
+	name := c.Params("name")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	artifacts, err := cicd.ListArtifacts(runId, pipelineId)
-	if err != nil {
+	if err := k8s.DeleteStorageClass(ctx, name); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{
-		"count":     len(artifacts),
-		"artifacts": artifacts,
-	})
+	return c.JSON(fiber.Map{"success": true, "message": "StorageClass deleted"})
 }
 
-func downloadArtifactHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-
-	file, artifact, err := cicd.GetArtifactFile(id)
-	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+// Apply resource handler - server-side applies one or more YAML documents
+// kustomizeHandler builds a kustomization from a Git repository (JSON body)
+// or an uploaded bundle (multipart form with a "bundle" file) and, with
+// "apply", applies the result like applyHandler
+func kustomizeHandler(c *fiber.Ctx) error {
+	var req struct {
+		Source struct {
+			URL          string `json:"url"`
+			Ref          string `json:"ref"`
+			CredentialID string `json:"credential_id"`
+		} `json:"source"`
+		Path      string `json:"path"`
+		Namespace string `json:"namespace"`
+		Apply     bool   `json:"apply"`
+		Force     bool   `json:"force"`
+		DryRun    bool   `json:"dry_run"`
 	}
-	defer file.Close()
+	var result *k8s.KustomizeResult
 
-	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", artifact.Filename))
-	c.Set("Content-Type", artifact.MimeType)
+	if file, err := c.FormFile("bundle"); err == nil {
+		req.Path = c.FormValue("path")
+		req.Namespace = c.FormValue("namespace")
+		req.Apply = c.FormValue("apply") == "true"
+		req.Force = c.FormValue("force") == "true"
+		req.DryRun = c.FormValue("dry_run") == "true"
 
-	return c.SendStream(file)
-}
+		src, err := file.Open()
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to open file"})
+		}
+		defer src.Close()
+		if result, err = k8s.KustomizeBundle(src, file.Filename, req.Path); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+	} else {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		if req.Source.URL == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "source url is required, or upload a bundle"})
+		}
+		username, password, err := gitHTTPAuth(req.Source.CredentialID)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
 
-func deleteArtifactHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		if result, err = k8s.KustomizeGit(ctx, req.Source.URL, req.Source.Ref, req.Path, username, password); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
 
-	if err := cicd.DeleteArtifact(id); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	resp := fiber.Map{
+		"success": true,
+		"yaml":    result.YAML,
+		"count":   result.Count,
+	}
+	if result.Commit != "" {
+		resp["commit"] = result.Commit
+	}
+	if result.SHA256 != "" {
+		resp["sha256"] = result.SHA256
+	}
+	if !req.Apply || result.Count == 0 {
+		return c.JSON(resp)
 	}
 
-	return c.JSON(fiber.Map{"success": true})
-}
-
-// SSH Host handlers
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-func listSSHHostsHandler(c *fiber.Ctx) error {
-	hosts, err := cicd.ListSSHHostsSafe()
+	results, err := k8s.ApplyManifests(ctx, result.YAML, k8s.ApplyOptions{
+		Namespace: req.Namespace,
+		Force:     req.Force,
+		DryRun:    req.DryRun,
+	})
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{
-		"count": len(hosts),
-		"hosts": hosts,
-	})
+
+	failed := 0
+	for _, r := range results {
+		if r.Action == k8s.ApplyFailed {
+			failed++
+		}
+	}
+	resp["success"] = failed == 0
+	resp["dry_run"] = req.DryRun
+	resp["failed"] = failed
+	resp["results"] = results
+	if failed > 0 {
+		resp["error"] = fmt.Sprintf("%d of %d objects failed to apply", failed, len(results))
+		return c.Status(422).JSON(resp)
+	}
+	return c.JSON(resp)
 }
 
-func createSSHHostHandler(c *fiber.Ctx) error {
-	var req cicd.CreateSSHHostRequest
+func applyHandler(c *fiber.Ctx) error {
+	var req struct {
+		Namespace string `json:"namespace"`
+		YAML      string `json:"yaml"`
+		Force     bool   `json:"force"`
+		DryRun    bool   `json:"dry_run"`
+	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-
-	if req.Name == "" || req.Host == "" || req.Username == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "name, host, and username are required"})
+	if strings.TrimSpace(req.YAML) == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "YAML content is required"})
 	}
-
-	if req.AuthMethod == "" {
-		req.AuthMethod = cicd.SSHAuthPassword
+	if req.Namespace == "" {
+		req.Namespace = c.Params("namespace")
 	}
 
-	host, err := cicd.CreateSSHHost(&req)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	results, err := k8s.ApplyManifests(ctx, req.YAML, k8s.ApplyOptions{
+		Namespace: req.Namespace,
+		Force:     req.Force,
+		DryRun:    req.DryRun,
+	})
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.Status(201).JSON(host.ToSafe())
-}
+	failed := 0
+	for _, r := range results {
+		if r.Action == k8s.ApplyFailed {
+			failed++
+		}
+	}
 
-func getSSHHostHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	host, err := cicd.GetSSHHost(id)
-	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	resp := fiber.Map{
+		"success": failed == 0,
+		"dry_run": req.DryRun,
+		"count":   len(results),
+		"failed":  failed,
+		"results": results,
 	}
-	return c.JSON(host.ToSafe())
+	if failed > 0 {
+		resp["error"] = fmt.Sprintf("%d of %d objects failed to apply", failed, len(results))
+		return c.Status(422).JSON(resp)
+	}
+	return c.JSON(resp)
 }
 
-func updateSSHHostHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var req cicd.UpdateSSHHostRequest
+// validateHandler dry-runs one or more YAML documents and reports problems
+// with their line numbers, without changing the cluster
+func validateHandler(c *fiber.Ctx) error {
+	var req struct {
+		Namespace string `json:"namespace"`
+		YAML      string `json:"yaml"`
+		Mode      string `json:"mode"`
+	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
+	if strings.TrimSpace(req.YAML) == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "YAML content is required"})
+	}
+	switch req.Mode {
+	case "", k8s.ValidateAuto, k8s.ValidateCreate, k8s.ValidateUpdate:
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "mode must be auto, create or update"})
+	}
+	if req.Namespace == "" {
+		req.Namespace = c.Params("namespace")
+	}
 
-	host, err := cicd.UpdateSSHHost(id, &req)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report, err := k8s.ValidateManifests(ctx, req.YAML, k8s.ValidateOptions{
+		Namespace: req.Namespace,
+		Mode:      req.Mode,
+	})
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(host.ToSafe())
+	return c.JSON(report)
 }
 
-func deleteSSHHostHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	if err := cicd.DeleteSSHHost(id); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
-	}
-	return c.JSON(fiber.Map{"success": true})
+// diffResourceHandler dry-runs the edited YAML as the Patch handlers would
+// and returns what would change on the live object
+func diffResourceHandler(c *fiber.Ctx) error {
+	return diffResource(c, c.Params("kind"))
 }
 
-func testSSHHostHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	if err := cicd.TestSSHHostConnection(id); err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"success": false,
-			"error":   err.Error(),
-		})
+// kindDiffHandler serves diffResourceHandler on routes that name the kind in
+// the path, such as the cluster-scoped storage class routes
+func kindDiffHandler(kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return diffResource(c, kind)
 	}
-	return c.JSON(fiber.Map{
-		"success": true,
-		"message": "Connection test passed",
-	})
 }
 
-func getSSHHostGroupsHandler(c *fiber.Ctx) error {
-	groups, err := cicd.GetSSHHostGroups()
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+func diffResource(c *fiber.Ctx, kind string) error {
+	if kind == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "kind is required"})
 	}
-	return c.JSON(fiber.Map{
-		"groups": groups,
-	})
-}
 
-func getSSHHostKeyHandler(c *fiber.Ctx) error {
 	var req struct {
-		Host string `json:"host"`
-		Port int    `json:"port"`
+		YAML string `json:"yaml"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-
-	if req.Host == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "host is required"})
-	}
-	if req.Port == 0 {
-		req.Port = 22
+	if strings.TrimSpace(req.YAML) == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "YAML content is required"})
 	}
 
-	keyInfo, err := cicd.GetHostFingerprint(req.Host, req.Port)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	diff, err := k8s.DiffResource(ctx, kind, c.Params("namespace"), c.Params("name"), req.YAML)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(keyInfo)
+	return c.JSON(diff)
 }
 
-// Git Credential handlers
+// New Network Tool handlers
 
-func listGitCredentialsHandler(c *fiber.Ctx) error {
-	creds, err := cicd.ListGitCredentialsSafe()
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
-	}
-	return c.JSON(fiber.Map{
-		"count":       len(creds),
-		"credentials": creds,
+type TelnetRequest struct {
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+	Command string `json:"command"`
+	Timeout int    `json:"timeout"`
+}
+
+// k8sWatchHandler streams resource events over WebSocket.
+// Query params: kinds=pods,deployments (default pods), namespaces=default,kube-system (default all)
+// The client can also send {"action":"edit"|"close", kind, namespace, name,
+// resource_version} to be told with a CONFLICT event when an object it has
+// open in the editor changes on the server.
+func k8sWatchHandler(c *websocket.Conn) {
+	kinds := splitQueryList(c.Query("kinds", "pods"))
+	namespaces := splitQueryList(c.Query("namespaces", ""))
+
+	var edits *k8s.EditWatch
+	streamWatch(c, func(ctx context.Context, events chan<- k8s.WatchEvent) error {
+		return k8s.WatchResources(ctx, kinds, namespaces, events)
+	}, func(ctx context.Context, events chan<- k8s.WatchEvent, msg []byte) error {
+		var req struct {
+			Action          string `json:"action"`
+			Kind            string `json:"kind"`
+			Namespace       string `json:"namespace"`
+			Name            string `json:"name"`
+			ResourceVersion string `json:"resource_version"`
+		}
+		if err := json.Unmarshal(msg, &req); err != nil {
+			return fmt.Errorf("invalid message")
+		}
+		if edits == nil {
+			edits = k8s.NewEditWatch(ctx, events)
+		}
+		switch req.Action {
+		case "edit":
+			return edits.Open(req.Kind, req.Namespace, req.Name, req.ResourceVersion)
+		case "close":
+			edits.Close(req.Kind, req.Namespace, req.Name)
+			return nil
+		}
+		return fmt.Errorf("action must be edit or close")
 	})
 }
 
-func createGitCredentialHandler(c *fiber.Ctx) error {
-	var req cicd.CreateGitCredentialRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
-	}
+// eventsWatchHandler streams Events over WebSocket.
+// Query params: namespaces (default all), type=Warning, kind and name of the
+// involved object, reason
+func eventsWatchHandler(c *websocket.Conn) {
+	filter := eventFilterFromQuery(c.Query)
+	filter.Namespaces = splitQueryList(c.Query("namespaces", ""))
 
-	if req.Name == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "name is required"})
-	}
+	streamWatch(c, func(ctx context.Context, events chan<- k8s.WatchEvent) error {
+		return k8s.WatchEvents(ctx, filter, events)
+	}, nil)
+}
 
-	if req.AuthMethod == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "auth_method is required"})
+// eventFilterFromQuery reads the type, kind, name and reason filters
+func eventFilterFromQuery(query func(key string, defaultValue ...string) string) k8s.EventFilter {
+	return k8s.EventFilter{
+		Types:  splitQueryList(query("type")),
+		Kind:   query("kind"),
+		Name:   query("name"),
+		Reason: query("reason"),
 	}
+}
 
-	// Validate required fields based on auth method
-	switch req.AuthMethod {
-	case cicd.GitAuthToken:
-		if req.Token == "" {
-			return c.Status(400).JSON(fiber.Map{"error": "token is required for token authentication"})
-		}
-	case cicd.GitAuthPassword:
-		if req.Username == "" || req.Password == "" {
-			return c.Status(400).JSON(fiber.Map{"error": "username and password are required for password authentication"})
-		}
-	case cicd.GitAuthSSHKey:
-		if req.PrivateKey == "" {
-			return c.Status(400).JSON(fiber.Map{"error": "private_key is required for SSH key authentication"})
+// streamWatch writes the events produced by watch to the WebSocket until the
+// client goes away or the watch fails. Messages from the client go to
+// onMessage, when set, whose errors are sent back without ending the stream.
+func streamWatch(c *websocket.Conn, watch func(ctx context.Context, events chan<- k8s.WatchEvent) error,
+	onMessage func(ctx context.Context, events chan<- k8s.WatchEvent, msg []byte) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan k8s.WatchEvent, 256)
+	msgErrs := make(chan error, 16)
+
+	// Stop watching as soon as the client goes away
+	go func() {
+		defer cancel()
+		for {
+			_, msg, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if onMessage == nil {
+				continue
+			}
+			if err := onMessage(ctx, events, msg); err != nil {
+				select {
+				case msgErrs <- err:
+				case <-ctx.Done():
+					return
+				}
+			}
 		}
-	default:
-		return c.Status(400).JSON(fiber.Map{"error": "invalid auth_method"})
-	}
+	}()
 
-	cred, err := cicd.CreateGitCredential(&req)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
-	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- watch(ctx, events)
+	}()
 
-	return c.Status(201).JSON(cred.ToSafe())
+	for {
+		select {
+		case ev := <-events:
+			if err := c.WriteJSON(ev); err != nil {
+				return
+			}
+		case err := <-msgErrs:
+			if err := c.WriteJSON(fiber.Map{"type": "ERROR", "error": err.Error()}); err != nil {
+				return
+			}
+		case err := <-errCh:
+			if err != nil {
+				c.WriteJSON(fiber.Map{"type": "ERROR", "error": err.Error()})
+			}
+			return
+		}
+	}
 }
 
-func getGitCredentialHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	cred, err := cicd.GetGitCredential(id)
-	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+// splitQueryList splits a comma-separated query value, dropping empty entries
+func splitQueryList(value string) []string {
+	var result []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
 	}
-	return c.JSON(cred.ToSafe())
+	return result
 }
 
-func updateGitCredentialHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var req cicd.UpdateGitCredentialRequest
+func telnetHandler(c *fiber.Ctx) error {
+	var req TelnetRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	cred, err := cicd.UpdateGitCredential(id, &req)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	if req.Host == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "host is required"})
+	}
+	if req.Port <= 0 || req.Port > 65535 {
+		return c.Status(400).JSON(fiber.Map{"error": "valid port (1-65535) is required"})
+	}
+	if req.Timeout <= 0 || req.Timeout > 30 {
+		req.Timeout = 10
 	}
 
-	return c.JSON(cred.ToSafe())
+	result := network.TelnetConnect(req.Host, req.Port, req.Command, time.Duration(req.Timeout)*time.Second)
+	return c.JSON(result)
 }
 
-func deleteGitCredentialHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	if err := cicd.DeleteGitCredential(id); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
-	}
-	return c.JSON(fiber.Map{"success": true})
+type WhoisRequest struct {
+	Query   string `json:"query"`
+	Timeout int    `json:"timeout"`
 }
 
-func testGitCredentialHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var req cicd.TestGitCredentialRequest
+func whoisHandler(c *fiber.Ctx) error {
+	var req WhoisRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	if req.URL == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "url is required for testing"})
+	if req.Query == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "query is required"})
 	}
-
-	if err := cicd.TestGitCredential(id, req.URL); err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"success": false,
-			"error":   err.Error(),
-		})
+	if req.Timeout <= 0 || req.Timeout > 30 {
+		req.Timeout = 10
 	}
-	return c.JSON(fiber.Map{
-		"success": true,
-		"message": "Credential test passed",
-	})
+
+	result := network.Whois(req.Query, time.Duration(req.Timeout)*time.Second)
+	return c.JSON(result)
 }
 
-// Freestyle Job handlers
+type SSLCheckRequest struct {
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+	Timeout int    `json:"timeout"`
+}
 
-func listFreestyleJobsHandler(c *fiber.Ctx) error {
-	jobs, err := cicd.ListFreestyleJobs()
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+func sslCheckHandler(c *fiber.Ctx) error {
+	var req SSLCheckRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
+
+	if req.Host == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "host is required"})
+	}
+	if req.Port <= 0 {
+		req.Port = 443
+	}
+	if req.Timeout <= 0 || req.Timeout > 30 {
+		req.Timeout = 10
+	}
+
+	result := network.CheckSSL(req.Host, req.Port, time.Duration(req.Timeout)*time.Second)
+	return c.JSON(result)
+}
+
+type CurlRequest struct {
+	URL             string            `json:"url"`
+	Method          string            `json:"method"`
+	Headers         map[string]string `json:"headers"`
+	Body            string            `json:"body"`
+	Timeout         int               `json:"timeout"`
+	FollowRedirects bool              `json:"follow_redirects"`
+	IncludeBody     bool              `json:"include_body"`
+	Proxy           string            `json:"proxy"` // proxy URL or "direct"; empty for the configured proxy
+}
+
+func curlHandler(c *fiber.Ctx) error {
+	var req CurlRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.URL == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "url is required"})
+	}
+	if req.Method == "" {
+		req.Method = "GET"
+	}
+	if req.Timeout <= 0 || req.Timeout > 60 {
+		req.Timeout = 30
+	}
+
+	result := network.Curl(req.URL, req.Method, req.Headers, req.Body, time.Duration(req.Timeout)*time.Second, req.FollowRedirects, req.IncludeBody, req.Proxy)
+	return c.JSON(result)
+}
+
+func interfacesHandler(c *fiber.Ctx) error {
+	result := network.GetNetworkInfo()
+	return c.JSON(result)
+}
+
+// Docker handlers (placeholders)
+
+func containersHandler(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
-		"count": len(jobs),
-		"jobs":  jobs,
+		"message": "Docker containers endpoint - coming soon",
+		"status":  "placeholder",
 	})
 }
 
-func createFreestyleJobHandler(c *fiber.Ctx) error {
-	var req cicd.CreateFreestyleJobRequest
+func imagesHandler(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"message": "Docker images endpoint - coming soon",
+		"status":  "placeholder",
+	})
+}
+
+// Notepad handlers
+
+func listNotepadsHandler(c *fiber.Ctx) error {
+	keys, err := storage.ListNotepads()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+	return c.JSON(fiber.Map{
+		"notepads": keys,
+		"count":    len(keys),
+	})
+}
+
+func getNotepadHandler(c *fiber.Ctx) error {
+	key := c.Params("key")
+	if key == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "key is required"})
+	}
+
+	data, err := storage.GetNotepad(key)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if data == nil {
+		return c.JSON(fiber.Map{
+			"key":     key,
+			"content": "",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"key":       key,
+		"content":   data.Content,
+		"updatedAt": data.UpdatedAt,
+	})
+}
+
+func saveNotepadHandler(c *fiber.Ctx) error {
+	key := c.Params("key")
+	if key == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "key is required"})
+	}
+
+	var req struct {
+		Content string `json:"content"`
+	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	if req.Name == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "name is required"})
+	data := &storage.NotepadData{
+		Content:   req.Content,
+		UpdatedAt: time.Now().Unix(),
 	}
 
-	job, err := cicd.CreateFreestyleJob(&req)
-	if err != nil {
+	if err := storage.SaveNotepad(key, data); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.Status(201).JSON(job)
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"key":       key,
+		"updatedAt": data.UpdatedAt,
+	})
 }
 
-func getFreestyleJobHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	job, err := cicd.GetFreestyleJob(id)
-	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+func deleteNotepadHandler(c *fiber.Ctx) error {
+	key := c.Params("key")
+	if key == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "key is required"})
 	}
-	return c.JSON(job)
+
+	if err := storage.DeleteNotepad(key); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"key":     key,
+	})
 }
 
-func freestyleJobBadgeHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	job, err := cicd.GetFreestyleJob(id)
+// Desktop preferences handlers
+
+func getDesktopPrefsHandler(c *fiber.Ctx) error {
+	prefs, err := storage.GetDesktopPreferences()
 	if err != nil {
-		return c.Status(404).SendString(generateBadgeSVG("not found", "#9f9f9f"))
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// Determine status and color
-	status := "unknown"
-	color := "#9f9f9f"
+	if prefs == nil {
+		return c.JSON(fiber.Map{})
+	}
 
-	if job.Status.LastStatus != "" {
-		switch job.Status.LastStatus {
-		case "succeeded":
-			status = "passing"
-			color = "#4ade80"
-		case "failed":
-			status = "failing"
-			color = "#ef4444"
-		case "running":
-			status = "running"
-			color = "#fbbf24"
-		case "cancelled":
-			status = "cancelled"
-			color = "#9f9f9f"
-		default:
-			status = job.Status.LastStatus
+	// Return whichever format is stored
+	result := fiber.Map{"updated_at": prefs.UpdatedAt}
+	if prefs.Slots != nil {
+		result["slots"] = prefs.Slots
+	}
+	if prefs.IconOrder != nil {
+		result["icon_order"] = prefs.IconOrder
+	}
+	if prefs.Hidden != nil {
+		result["hidden"] = prefs.Hidden
+	}
+	return c.JSON(result)
+}
+
+func saveDesktopPrefsHandler(c *fiber.Ctx) error {
+	var req struct {
+		Slots     []*string `json:"slots"`
+		IconOrder []string  `json:"icon_order"`
+		Hidden    []string  `json:"hidden"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	prefs := &storage.DesktopPreferences{
+		Slots:     req.Slots,
+		IconOrder: req.IconOrder,
+		Hidden:    req.Hidden,
+		UpdatedAt: time.Now().Unix(),
+	}
+
+	if err := storage.SaveDesktopPreferences(prefs); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"updated_at": prefs.UpdatedAt,
+	})
+}
+
+func resetDesktopPrefsHandler(c *fiber.Ctx) error {
+	if err := storage.DeletePreference("desktop"); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// Auth handlers
+
+func loginPageHandler(c *fiber.Ctx) error {
+	return c.SendFile("/app/web/static/login.html")
+}
+
+func loginHandler(c *fiber.Ctx) error {
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	if !auth.ValidatePassword(req.Password) {
+		return c.Status(401).JSON(fiber.Map{"error": "invalid password"})
+	}
+
+	token := auth.CreateSession()
+	c.Cookie(&fiber.Cookie{
+		Name:     "gagos_session",
+		Value:    token,
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: "Lax",
+		MaxAge:   86400, // 24 hours
+	})
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// elevateHandler exchanges the admin password (GAGOS_ADMIN_PASSWORD, or
+// GAGOS_PASSWORD when unset) for a short-lived elevation cookie that allows
+// write queries and other dangerous operations
+func elevateHandler(c *fiber.Ctx) error {
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	token, expiry, ok := auth.Elevate(req.Password)
+	if !ok {
+		return c.Status(401).JSON(fiber.Map{"error": "invalid password"})
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     auth.ElevationCookie,
+		Value:    token,
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: "Strict",
+		MaxAge:   int(auth.ElevationTTL.Seconds()),
+	})
+
+	return c.JSON(fiber.Map{"success": true, "expires_at": expiry})
+}
+
+func dropElevationHandler(c *fiber.Ctx) error {
+	if token := c.Cookies(auth.ElevationCookie); token != "" {
+		auth.DropElevation(token)
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:   auth.ElevationCookie,
+		Value:  "",
+		MaxAge: -1,
+	})
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// queryGuardError maps a refused passthrough query onto a response; the UI
+// prompts for elevation when elevation_required is set
+func queryGuardError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, database.ErrElevationRequired) {
+		return c.Status(403).JSON(fiber.Map{"error": err.Error(), "elevation_required": true})
+	}
+	return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+}
+
+func logoutHandler(c *fiber.Ctx) error {
+	token := c.Cookies("gagos_session")
+	if token != "" {
+		auth.DeleteSession(token)
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:   "gagos_session",
+		Value:  "",
+		MaxAge: -1,
+	})
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func runtimeHandler(c *fiber.Ctx) error {
+	runtime := getEnv("GAGOS_RUNTIME", "docker")
+	// The UI hides what an air-gapped install cannot do
+	disabled := []string{}
+	if !network.WhoisAvailable() {
+		disabled = append(disabled, "whois")
+	}
+	return c.JSON(fiber.Map{
+		"runtime":           runtime,
+		"offline":           egress.Offline(),
+		"disabled_features": disabled,
+		"proxies":           egress.ProxySettings(),
+	})
+}
+
+// CI/CD handlers
+
+func cicdStatsHandler(c *fiber.Ctx) error {
+	stats, err := cicd.GetStats()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(stats)
+}
+
+// Global retention of runs, builds and artifacts
+func retentionHandler(c *fiber.Ctx) error {
+	return c.JSON(cicd.GetRetentionConfig())
+}
+
+// Apply the retention now instead of waiting for the hourly cleanup
+func retentionCleanupHandler(c *fiber.Ctx) error {
+	scheduler := cicd.GetScheduler()
+	if scheduler == nil {
+		return c.Status(500).JSON(fiber.Map{"error": "scheduler not initialized"})
+	}
+	return c.JSON(scheduler.RunCleanup())
+}
+
+func cicdSampleHandler(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"yaml": cicd.GetSamplePipelineYAML(),
+	})
+}
+
+func listPipelinesHandler(c *fiber.Ctx) error {
+	pipelines, err := cicd.ListPipelines()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"count":     len(pipelines),
+		"pipelines": pipelines,
+	})
+}
+
+// pipelineYAMLError answers a pipeline YAML that did not parse, listing its
+// problems with their line and column
+func pipelineYAMLError(c *fiber.Ctx, err error) error {
+	resp := fiber.Map{"error": err.Error()}
+	if errs := cicd.AsPipelineErrors(err); errs != nil {
+		resp["errors"] = errs
+	}
+	return c.Status(400).JSON(resp)
+}
+
+func createPipelineHandler(c *fiber.Ctx) error {
+	var req cicd.CreatePipelineRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.YAML == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "yaml is required"})
+	}
+
+	pipeline, err := cicd.ParsePipelineYAML(req.YAML)
+	if err != nil {
+		return pipelineYAMLError(c, err)
+	}
+
+	if err := cicd.SavePipeline(pipeline); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Register with scheduler if has cron triggers
+	if scheduler := cicd.GetScheduler(); scheduler != nil {
+		scheduler.RegisterPipeline(pipeline)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"id":          pipeline.ID,
+		"name":        pipeline.Name,
+		"webhook_url": pipeline.Status.WebhookURL,
+		"created_at":  pipeline.CreatedAt.Format(time.RFC3339),
+	})
+}
+
+// validatePipelineHandler checks a pipeline YAML without saving it
+func validatePipelineHandler(c *fiber.Ctx) error {
+	var req cicd.CreatePipelineRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.YAML == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "yaml is required"})
+	}
+
+	_, errs := cicd.ValidatePipelineYAML(req.YAML)
+	if errs == nil {
+		errs = cicd.PipelineErrors{}
+	}
+	return c.JSON(fiber.Map{
+		"valid":  len(errs) == 0,
+		"errors": errs,
+	})
+}
+
+func getPipelineHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	pipeline, err := cicd.GetPipeline(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(pipeline)
+}
+
+func pipelineBadgeHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	pipeline, err := cicd.GetPipeline(id)
+	if err != nil {
+		return c.Status(404).SendString(generateBadgeSVG("not found", "#9f9f9f"))
+	}
+
+	// Determine status and color
+	status := "no builds"
+	color := "#9f9f9f"
+
+	// Get last run status if we have a last run
+	if pipeline.Status.LastRunID != "" {
+		run, err := cicd.GetRun(pipeline.Status.LastRunID)
+		if err == nil {
+			switch run.Status {
+			case cicd.RunStatusSucceeded:
+				status = "passing"
+				color = "#4ade80"
+			case cicd.RunStatusFailed:
+				status = "failing"
+				color = "#ef4444"
+			case cicd.RunStatusRunning:
+				status = "running"
+				color = "#fbbf24"
+			case cicd.RunStatusCancelled:
+				status = "cancelled"
+				color = "#9f9f9f"
+			default:
+				status = string(run.Status)
+			}
+		}
+	}
+
+	c.Set("Content-Type", "image/svg+xml")
+	c.Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	return c.SendString(generateBadgeSVG(status, color))
+}
+
+func generateBadgeSVG(status, color string) string {
+	labelWidth := 50
+	statusWidth := len(status)*7 + 10
+	totalWidth := labelWidth + statusWidth
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20">
+  <linearGradient id="b" x2="0" y2="100%%">
+    <stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
+    <stop offset="1" stop-opacity=".1"/>
+  </linearGradient>
+  <mask id="a">
+    <rect width="%d" height="20" rx="3" fill="#fff"/>
+  </mask>
+  <g mask="url(#a)">
+    <rect width="%d" height="20" fill="#555"/>
+    <rect x="%d" width="%d" height="20" fill="%s"/>
+    <rect width="%d" height="20" fill="url(#b)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11">
+    <text x="%d" y="15" fill="#010101" fill-opacity=".3">build</text>
+    <text x="%d" y="14">build</text>
+    <text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text>
+    <text x="%d" y="14">%s</text>
+  </g>
+</svg>`, totalWidth, totalWidth, labelWidth, labelWidth, statusWidth, color, totalWidth,
+		labelWidth/2, labelWidth/2,
+		labelWidth+statusWidth/2, status,
+		labelWidth+statusWidth/2, status)
+}
+
+func updatePipelineHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	// Get existing pipeline
+	existing, err := cicd.GetPipeline(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req cicd.CreatePipelineRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.YAML == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "yaml is required"})
+	}
+
+	// Parse the new YAML
+	newPipeline, err := cicd.ParsePipelineYAML(req.YAML)
+	if err != nil {
+		return pipelineYAMLError(c, err)
+	}
+
+	// Preserve ID and status
+	newPipeline.ID = existing.ID
+	newPipeline.Status.TotalRuns = existing.Status.TotalRuns
+	newPipeline.Status.LastRunID = existing.Status.LastRunID
+	newPipeline.Status.LastRunAt = existing.Status.LastRunAt
+	newPipeline.Status.WebhookToken = existing.Status.WebhookToken
+	newPipeline.Status.WebhookURL = existing.Status.WebhookURL
+	newPipeline.Status.WebhookTokens = existing.Status.WebhookTokens
+	newPipeline.CreatedAt = existing.CreatedAt
+	newPipeline.UpdatedAt = time.Now()
+
+	if err := cicd.SavePipeline(newPipeline); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Update scheduler
+	if scheduler := cicd.GetScheduler(); scheduler != nil {
+		scheduler.RegisterPipeline(newPipeline)
+	}
+
+	return c.JSON(fiber.Map{
+		"id":         newPipeline.ID,
+		"name":       newPipeline.Name,
+		"updated_at": newPipeline.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
+func deletePipelineHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	// Unregister from scheduler
+	if scheduler := cicd.GetScheduler(); scheduler != nil {
+		scheduler.UnregisterPipeline(id)
+	}
+
+	if err := cicd.DeletePipeline(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func triggerPipelineHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	pipeline, err := cicd.GetPipeline(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req cicd.TriggerPipelineRequest
+	c.BodyParser(&req) // Optional body
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	run, err := cicd.TriggerPipeline(ctx, pipeline, "manual", "", req.Variables)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"run_id":     run.ID,
+		"run_number": run.RunNumber,
+		"status":     run.Status,
+	})
+}
+
+func dryRunPipelineHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	pipeline, err := cicd.GetPipeline(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req cicd.TriggerPipelineRequest
+	c.BodyParser(&req) // Optional body
+
+	result, err := cicd.DryRunPipeline(pipeline, req.Variables)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(result)
+}
+
+func listPipelineRunsHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	limit := c.QueryInt("limit", 50)
+
+	runs, err := cicd.ListRuns(id, limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"count": len(runs),
+		"runs":  runs,
+	})
+}
+
+func listAllRunsHandler(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+
+	runs, err := cicd.ListRuns("", limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"count": len(runs),
+		"runs":  runs,
+	})
+}
+
+func getRunHandler(c *fiber.Ctx) error {
+	runId := c.Params("runId")
+
+	run, err := cicd.GetRun(runId)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(run)
+}
+
+func cancelRunHandler(c *fiber.Ctx) error {
+	runId := c.Params("runId")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := cicd.CancelRun(ctx, runId); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func rerunRunHandler(c *fiber.Ctx) error {
+	runId := c.Params("runId")
+
+	orig, err := cicd.GetRun(runId)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req cicd.RerunRequest
+	c.BodyParser(&req) // Optional body
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	run, err := cicd.RerunPipelineRun(ctx, orig, req.FailedOnly)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"run_id":     run.ID,
+		"run_number": run.RunNumber,
+		"status":     run.Status,
+		"rerun_of":   orig.ID,
+	})
+}
+
+func deleteRunHandler(c *fiber.Ctx) error {
+	runId := c.Params("runId")
+
+	if err := cicd.DeleteRun(runId); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func listEnvironmentsHandler(c *fiber.Ctx) error {
+	envs, err := cicd.ListEnvironments()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"count":        len(envs),
+		"environments": envs,
+	})
+}
+
+func getEnvironmentHandler(c *fiber.Ctx) error {
+	env, err := cicd.GetEnvironment(c.Params("name"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(env)
+}
+
+func listDeploymentsHandler(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+
+	deployments, err := cicd.ListDeployments(c.Params("name"), limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"count":       len(deployments),
+		"deployments": deployments,
+	})
+}
+
+func rollbackEnvironmentHandler(c *fiber.Ctx) error {
+	name := c.Params("name")
+
+	var req cicd.RollbackRequest
+	c.BodyParser(&req) // Optional body
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	run, target, err := cicd.RollbackEnvironment(ctx, name, req.DeploymentID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"run_id":      run.ID,
+		"run_number":  run.RunNumber,
+		"status":      run.Status,
+		"rollback_of": target.ID,
+		"version":     target.Version,
+	})
+}
+
+func getJobLogsHandler(c *fiber.Ctx) error {
+	runId := c.Params("runId")
+	jobName := c.Params("job")
+	tailLines := int64(c.QueryInt("tail", 1000))
+	attempt := c.QueryInt("attempt", 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	logs, err := cicd.GetJobLogs(ctx, runId, jobName, attempt, tailLines)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	resp := fiber.Map{
+		"run_id": runId,
+		"job":    jobName,
+		"logs":   logs,
+	}
+	if attempt > 0 {
+		resp["attempt"] = attempt
+	}
+	return c.JSON(resp)
+}
+
+func cicdLogStreamHandler(c *websocket.Conn) {
+	runId := c.Params("runId")
+	jobName := c.Params("job")
+	cicd.StreamJobLogs(c, runId, jobName)
+}
+
+func cicdWebhookHandler(c *fiber.Ctx) error {
+	pipelineId := c.Params("pipelineId")
+	token := c.Params("token")
+
+	header := func(key string) string { return c.Get(key) }
+	payload, err := cicd.ParseWebhookPayload(header, c.Body())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// GitHub, GAGOS and Gitea sign the body; GitLab sends the secret
+	signature := ""
+	for _, h := range []string{"X-Hub-Signature-256", "X-GAGOS-Signature", "X-Gitea-Signature", "X-Gitlab-Token"} {
+		if signature = c.Get(h); signature != "" {
+			break
+		}
+	}
+
+	run, err := cicd.HandleWebhook(pipelineId, token, payload, c.Body(), signature, c.IP())
+	if errors.Is(err, cicd.ErrWebhookIgnored) {
+		// Git providers mark deliveries that fail, so a filtered event is
+		// answered with success
+		return c.JSON(fiber.Map{"skipped": true, "reason": err.Error()})
+	}
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"run_id":     run.ID,
+		"run_number": run.RunNumber,
+		"status":     run.Status,
+	})
+}
+
+func listArtifactsHandler(c *fiber.Ctx) error {
+	runId := c.Query("run_id", "")
+	pipelineId := c.Query("pipeline_id", "")
+
+	artifacts, err := cicd.ListArtifacts(runId, pipelineId)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"count":     len(artifacts),
+		"artifacts": artifacts,
+	})
+}
+
+// Upload an artifact to a run (multipart: file, name, type). The file is
+// streamed to the artifact store without being held in memory. A JUnit XML
+// report (type=junit, or any .xml file that is one) is added to the run's
+// test results, and a coverage report (type=coverage) to its coverage.
+func uploadArtifactHandler(c *fiber.Ctx) error {
+	runId := c.Params("runId")
+
+	run, err := cicd.GetRun(runId)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "file is required"})
+	}
+	filename := path.Base(file.Filename)
+	if filename == "." || filename == "/" || filename == ".." {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid file name"})
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to open file"})
+	}
+	defer src.Close()
+
+	reportType := c.FormValue("type")
+	if reportType != "" && reportType != "junit" && reportType != "coverage" {
+		return c.Status(400).JSON(fiber.Map{"error": "type must be junit or coverage"})
+	}
+	junit := reportType == "junit"
+
+	artifact, err := cicd.SaveArtifact(run.ID, run.PipelineID, c.FormValue("name", filename), filename, src)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if reportType == "coverage" {
+		if _, err := cicd.IngestCoverageReport(artifact); err != nil {
+			cicd.DeleteArtifact(artifact.ID)
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+	} else if junit || strings.EqualFold(path.Ext(filename), ".xml") {
+		if _, err := cicd.IngestTestReport(artifact); err != nil {
+			if junit {
+				cicd.DeleteArtifact(artifact.ID)
+				return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+			}
+			if !errors.Is(err, cicd.ErrNotTestReport) {
+				log.Warn().Err(err).Str("artifact_id", artifact.ID).Msg("Failed to ingest test report")
+			}
+		}
+	}
+
+	return c.Status(201).JSON(artifact)
+}
+
+// Test results of a run, with its failed tests and the flaky tests of the
+// last ?history= runs of its pipeline
+func getRunTestsHandler(c *fiber.Ctx) error {
+	results, err := cicd.GetTestResults(c.Params("runId"), c.QueryInt("history", 0))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(results)
+}
+
+// Coverage of a run and the outcome of its coverage gate
+func getRunCoverageHandler(c *fiber.Ctx) error {
+	report, err := cicd.GetCoverageReport(c.Params("runId"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// Jobs of a run on its time line, with the waits between them
+func getRunTimelineHandler(c *fiber.Ctx) error {
+	timeline, err := cicd.GetRunTimeline(c.Params("runId"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(timeline)
+}
+
+// Success rate, run and job durations and queue waits of a pipeline's runs
+// in the last ?days=, overall and per ?period= (day or week)
+func pipelineAnalyticsHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if _, err := cicd.GetPipeline(id); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	analytics, err := cicd.GetPipelineAnalytics(id, c.QueryInt("days", 0), c.Query("period"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(analytics)
+}
+
+// Coverage history of a pipeline, newest first
+func pipelineCoverageHandler(c *fiber.Ctx) error {
+	reports, err := cicd.ListCoverageReports(c.Params("id"), c.QueryInt("limit", 50))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"count":    len(reports),
+		"coverage": reports,
+	})
+}
+
+func downloadArtifactHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	file, artifact, err := cicd.GetArtifactFile(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer file.Close()
+
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", artifact.Filename))
+	c.Set("Content-Type", artifact.MimeType)
+
+	return c.SendStream(file)
+}
+
+func deleteArtifactHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	if err := cicd.DeleteArtifact(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// SSH Host handlers
+
+func listSSHHostsHandler(c *fiber.Ctx) error {
+	hosts, err := cicd.ListSSHHostsSafe()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"count": len(hosts),
+		"hosts": hosts,
+	})
+}
+
+func createSSHHostHandler(c *fiber.Ctx) error {
+	var req cicd.CreateSSHHostRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.Name == "" || req.Host == "" || req.Username == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name, host, and username are required"})
+	}
+
+	if req.AuthMethod == "" {
+		req.AuthMethod = cicd.SSHAuthPassword
+	}
+
+	host, err := cicd.CreateSSHHost(&req)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(host.ToSafe())
+}
+
+func getSSHHostHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	host, err := cicd.GetSSHHost(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(host.ToSafe())
+}
+
+func updateSSHHostHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var req cicd.UpdateSSHHostRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	host, err := cicd.UpdateSSHHost(id, &req)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(host.ToSafe())
+}
+
+func deleteSSHHostHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := cicd.DeleteSSHHost(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func testSSHHostHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := cicd.TestSSHHostConnection(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Connection test passed",
+	})
+}
+
+func getSSHHostGroupsHandler(c *fiber.Ctx) error {
+	groups, err := cicd.GetSSHHostGroups()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"groups": groups,
+	})
+}
+
+func getSSHHostKeyHandler(c *fiber.Ctx) error {
+	var req struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.Host == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "host is required"})
+	}
+	if req.Port == 0 {
+		req.Port = 22
+	}
+
+	keyInfo, err := cicd.GetHostFingerprint(req.Host, req.Port)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(keyInfo)
+}
+
+// Git Credential handlers
+
+func listGitCredentialsHandler(c *fiber.Ctx) error {
+	creds, err := cicd.ListGitCredentialsSafe()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"count":       len(creds),
+		"credentials": creds,
+	})
+}
+
+func createGitCredentialHandler(c *fiber.Ctx) error {
+	var req cicd.CreateGitCredentialRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.Name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name is required"})
+	}
+
+	if req.AuthMethod == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "auth_method is required"})
+	}
+
+	// Validate required fields based on auth method
+	switch req.AuthMethod {
+	case cicd.GitAuthToken:
+		if req.Token == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "token is required for token authentication"})
+		}
+	case cicd.GitAuthPassword:
+		if req.Username == "" || req.Password == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "username and password are required for password authentication"})
+		}
+	case cicd.GitAuthSSHKey:
+		if req.PrivateKey == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "private_key is required for SSH key authentication"})
+		}
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "invalid auth_method"})
+	}
+
+	cred, err := cicd.CreateGitCredential(&req)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(cred.ToSafe())
+}
+
+func getGitCredentialHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	cred, err := cicd.GetGitCredential(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(cred.ToSafe())
+}
+
+func updateGitCredentialHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var req cicd.UpdateGitCredentialRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	cred, err := cicd.UpdateGitCredential(id, &req)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(cred.ToSafe())
+}
+
+func deleteGitCredentialHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := cicd.DeleteGitCredential(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func testGitCredentialHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var req cicd.TestGitCredentialRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.URL == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "url is required for testing"})
+	}
+
+	if err := cicd.TestGitCredential(id, req.URL); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Credential test passed",
+	})
+}
+
+// Registry Credential handlers
+
+func listRegistryCredentialsHandler(c *fiber.Ctx) error {
+	creds, err := cicd.ListRegistryCredentialsSafe()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"count":       len(creds),
+		"credentials": creds,
+	})
+}
+
+func createRegistryCredentialHandler(c *fiber.Ctx) error {
+	var req cicd.RegistryCredentialRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.Name == "" || req.Registry == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name and registry are required"})
+	}
+	if req.Username == "" || req.Password == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "username and password are required"})
+	}
+
+	cred, err := cicd.CreateRegistryCredential(&req)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(cred.ToSafe())
+}
+
+func getRegistryCredentialHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	cred, err := cicd.GetRegistryCredential(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(cred.ToSafe())
+}
+
+func updateRegistryCredentialHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var req cicd.RegistryCredentialRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	cred, err := cicd.UpdateRegistryCredential(id, &req)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(cred.ToSafe())
+}
+
+func deleteRegistryCredentialHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := cicd.DeleteRegistryCredential(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// Secret Variable handlers
+
+func listJobTemplatesHandler(c *fiber.Ctx) error {
+	templates, err := cicd.ListJobTemplates(c.Query("name"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"count":     len(templates),
+		"templates": templates,
+	})
+}
+
+func createJobTemplateHandler(c *fiber.Ctx) error {
+	var req cicd.CreateJobTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.Name == "" || req.Version == "" || req.Job == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name, version and job are required"})
+	}
+
+	template, err := cicd.CreateJobTemplate(&req)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(template)
+}
+
+func getJobTemplateHandler(c *fiber.Ctx) error {
+	template, err := cicd.GetJobTemplate(c.Params("name"), c.Params("version"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(template)
+}
+
+func deleteJobTemplateHandler(c *fiber.Ctx) error {
+	if _, err := cicd.GetJobTemplate(c.Params("name"), c.Params("version")); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := cicd.DeleteJobTemplate(c.Params("name"), c.Params("version")); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func listSecretVariablesHandler(c *fiber.Ctx) error {
+	secrets, err := cicd.ListSecretVariablesSafe(c.Query("scope"), c.Query("scope_id"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"count":   len(secrets),
+		"secrets": secrets,
+	})
+}
+
+func createSecretVariableHandler(c *fiber.Ctx) error {
+	var req cicd.SecretVariableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.Name == "" || req.Value == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name and value are required"})
+	}
+	if req.Scope == "" || req.ScopeID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "scope and scope_id are required"})
+	}
+
+	secret, err := cicd.CreateSecretVariable(&req)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(secret.ToSafe())
+}
+
+func getSecretVariableHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	secret, err := cicd.GetSecretVariable(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(secret.ToSafe())
+}
+
+func updateSecretVariableHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var req cicd.SecretVariableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	secret, err := cicd.UpdateSecretVariable(id, &req)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(secret.ToSafe())
+}
+
+func deleteSecretVariableHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := cicd.DeleteSecretVariable(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// Freestyle Job handlers
+
+func listFreestyleJobsHandler(c *fiber.Ctx) error {
+	jobs, err := cicd.ListFreestyleJobs()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"count": len(jobs),
+		"jobs":  jobs,
+	})
+}
+
+func createFreestyleJobHandler(c *fiber.Ctx) error {
+	var req cicd.CreateFreestyleJobRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.Name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name is required"})
+	}
+
+	job, err := cicd.CreateFreestyleJob(&req)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(job)
+}
+
+func getFreestyleJobHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	job, err := cicd.GetFreestyleJob(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(job)
+}
+
+func freestyleJobBadgeHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	job, err := cicd.GetFreestyleJob(id)
+	if err != nil {
+		return c.Status(404).SendString(generateBadgeSVG("not found", "#9f9f9f"))
+	}
+
+	// Determine status and color
+	status := "unknown"
+	color := "#9f9f9f"
+
+	if job.Status.LastStatus != "" {
+		switch job.Status.LastStatus {
+		case "succeeded":
+			status = "passing"
+			color = "#4ade80"
+		case "failed":
+			status = "failing"
+			color = "#ef4444"
+		case "running":
+			status = "running"
+			color = "#fbbf24"
+		case "cancelled":
+			status = "cancelled"
+			color = "#9f9f9f"
+		default:
+			status = job.Status.LastStatus
+		}
+	} else {
+		status = "no builds"
+	}
+
+	c.Set("Content-Type", "image/svg+xml")
+	c.Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	return c.SendString(generateBadgeSVG(status, color))
+}
+
+func updateFreestyleJobHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var req cicd.CreateFreestyleJobRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	job, err := cicd.UpdateFreestyleJob(id, &req)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(job)
+}
+
+func deleteFreestyleJobHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := cicd.DeleteFreestyleJob(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func freestyleJobParametersHandler(c *fiber.Ctx) error {
+	job, err := cicd.GetFreestyleJob(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	params := job.Parameters
+	if params == nil {
+		params = []cicd.BuildParameter{}
+	}
+	return c.JSON(fiber.Map{
+		"count":      len(params),
+		"parameters": params,
+	})
+}
+
+func triggerFreestyleBuildHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var req cicd.TriggerFreestyleBuildRequest
+	if form, err := c.MultipartForm(); err == nil {
+		// File parameters come as a multipart form, with the other
+		// parameters as its values
+		req.Parameters = make(map[string]string, len(form.Value))
+		for name, values := range form.Value {
+			if len(values) > 0 {
+				req.Parameters[name] = values[0]
+			}
+		}
+		req.Files = make(map[string]*multipart.FileHeader, len(form.File))
+		for name, files := range form.File {
+			if len(files) > 0 {
+				req.Files[name] = files[0]
+			}
+		}
+	} else {
+		c.BodyParser(&req) // Optional params
+	}
+
+	build, err := cicd.TriggerManualFreestyleBuild(id, &req)
+	if err != nil {
+		if errors.Is(err, cicd.ErrInvalidParameters) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(build)
+}
+
+func listJobBuildsHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	builds, err := cicd.ListFreestyleBuildsForJob(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"count":  len(builds),
+		"builds": builds,
+	})
+}
+
+// Freestyle Build handlers
+
+func listFreestyleBuildsHandler(c *fiber.Ctx) error {
+	builds, err := cicd.ListFreestyleBuilds()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"count":  len(builds),
+		"builds": builds,
+	})
+}
+
+func getFreestyleBuildHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	build, err := cicd.GetFreestyleBuild(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(build)
+}
+
+func cancelFreestyleBuildHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := cicd.CancelFreestyleBuild(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func deleteFreestyleBuildHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := cicd.DeleteFreestyleBuild(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func getFreestyleBuildLogsHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	logs, err := cicd.GetBuildLogs(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"build_id": id,
+		"logs":     logs,
+	})
+}
+
+func freestyleWebhookHandler(c *fiber.Ctx) error {
+	token := c.Params("token")
+
+	var params map[string]string
+	c.BodyParser(&params)
+
+	branch := params["branch"]
+	if branch == "" {
+		branch = strings.TrimPrefix(params["ref"], "refs/heads/")
+	}
+	job, err := cicd.UseFreestyleWebhookToken(token, branch, c.IP())
+	if job == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "invalid webhook token"})
+	}
+	if err != nil {
+		log.Warn().Str("job_name", job.Name).Str("branch", branch).Str("ip", c.IP()).Msg("Webhook token used outside its branch scope")
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Check if webhook trigger is enabled
+	webhookEnabled := false
+	for _, t := range job.Triggers {
+		if t.Type == "webhook" && t.Enabled {
+			webhookEnabled = true
+			break
+		}
+	}
+
+	if !webhookEnabled {
+		return c.Status(403).JSON(fiber.Map{"error": "webhook trigger is not enabled for this job"})
+	}
+
+	// Verify HMAC signature if secret is configured
+	if job.Status.WebhookSecret != "" {
+		signature := c.Get("X-GAGOS-Signature")
+		if signature == "" {
+			// Also check common webhook signature headers
+			signature = c.Get("X-Hub-Signature-256")
+			if signature == "" {
+				signature = c.Get("X-Signature-256")
+			}
+		}
+
+		if signature != "" {
+			body := c.Body()
+			if !cicd.VerifyWebhookSignature(body, signature, job.Status.WebhookSecret) {
+				log.Warn().
+					Str("job_id", job.ID).
+					Str("job_name", job.Name).
+					Msg("Webhook signature verification failed")
+				return c.Status(401).JSON(fiber.Map{"error": "invalid signature"})
+			}
+		}
+		// If no signature provided but secret is set, log a warning but allow
+		// This allows for gradual adoption of signature verification
+		if signature == "" {
+			log.Warn().
+				Str("job_id", job.ID).
+				Str("job_name", job.Name).
+				Msg("Webhook called without signature - consider adding X-GAGOS-Signature header")
+		}
+	}
+
+	build, err := cicd.TriggerFreestyleBuild(job.ID, "webhook", "", params)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"build_id":     build.ID,
+		"build_number": build.BuildNumber,
+		"job_name":     build.JobName,
+	})
+}
+
+// webhookTokensHandler lists the webhook tokens of a pipeline or freestyle job
+func webhookTokensHandler(kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokens, err := cicd.ListWebhookTokens(kind, c.Params("id"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{
+			"count":  len(tokens),
+			"tokens": tokens,
+		})
+	}
+}
+
+// createWebhookTokenHandler adds a webhook token, optionally limited to
+// branches
+func createWebhookTokenHandler(kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req cicd.WebhookTokenRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		token, err := cicd.CreateWebhookToken(kind, c.Params("id"), &req)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Info().Str("kind", kind).Str("id", c.Params("id")).Str("token_id", token.ID).Str("ip", c.IP()).Msg("Webhook token created")
+		return c.Status(201).JSON(token)
+	}
+}
+
+// rotateWebhookTokenHandler replaces a webhook token. The old token keeps
+// working for grace_seconds (default GAGOS_WEBHOOK_TOKEN_GRACE_HOURS).
+func rotateWebhookTokenHandler(kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req cicd.WebhookTokenRequest
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+			}
+		}
+		grace := cicd.WebhookTokenGrace()
+		if req.GraceSeconds != nil {
+			if *req.GraceSeconds < 0 {
+				return c.Status(400).JSON(fiber.Map{"error": "grace_seconds must not be negative"})
+			}
+			grace = time.Duration(*req.GraceSeconds) * time.Second
+		}
+		token, err := cicd.RotateWebhookToken(kind, c.Params("id"), c.Params("tokenId"), grace)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Info().Str("kind", kind).Str("id", c.Params("id")).Str("token_id", c.Params("tokenId")).
+			Dur("grace", grace).Str("ip", c.IP()).Msg("Webhook token rotated")
+		return c.JSON(token)
+	}
+}
+
+// revokeWebhookTokenHandler deletes a webhook token other than the primary
+func revokeWebhookTokenHandler(kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := cicd.RevokeWebhookToken(kind, c.Params("id"), c.Params("tokenId")); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Info().Str("kind", kind).Str("id", c.Params("id")).Str("token_id", c.Params("tokenId")).Str("ip", c.IP()).Msg("Webhook token revoked")
+		return c.JSON(fiber.Map{"success": true})
+	}
+}
+
+func freestyleLogStreamHandler(c *websocket.Conn) {
+	buildID := c.Params("id")
+
+	stream := cicd.GetBuildOutputStream(buildID)
+	if stream == nil {
+		// Build not running, send existing logs and close
+		logs, err := cicd.GetBuildLogs(buildID)
+		if err != nil {
+			c.WriteMessage(websocket.TextMessage, []byte("Error: "+err.Error()))
+			return
+		}
+		c.WriteMessage(websocket.TextMessage, []byte(logs))
+		return
+	}
+
+	// Subscribe to live output
+	ch := stream.Subscribe()
+	defer stream.Unsubscribe(ch)
+
+	for data := range ch {
+		if err := c.WriteMessage(websocket.TextMessage, data); err != nil {
+			break
+		}
+	}
+}
+
+// Monitoring handlers
+
+func monitoringSummaryHandler(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	summary, err := monitoring.GetClusterSummary(ctx)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(summary)
+}
+
+func monitoringNodesHandler(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	nodes, err := monitoring.GetNodeMetrics(ctx)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"count":            len(nodes),
+		"nodes":            nodes,
+		"metrics_available": monitoring.IsMetricsAvailable(),
+	})
+}
+
+func monitoringPodsHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pods, err := monitoring.GetPodMetrics(ctx, namespace)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"namespace":         namespace,
+		"count":             len(pods),
+		"pods":              pods,
+		"metrics_available": monitoring.IsMetricsAvailable(),
+	})
+}
+
+// monitoringTopHandler serves usage against requests, limits and quotas;
+// ?sort=cpu|memory|cpu_percent|memory_percent|name
+func monitoringTopHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	top, err := monitoring.GetResourceTop(ctx, namespace, c.Query("sort"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(top)
+}
+
+// monitoringNodeAllocationHandler compares each node's allocatable
+// resources with the requests, limits and usage of its pods
+func monitoringNodeAllocationHandler(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report, err := monitoring.GetNodeAllocation(ctx)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+func monitoringQuotasHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	quotas, err := monitoring.ListResourceQuotas(ctx, namespace)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"namespace": namespace,
+		"count":     len(quotas),
+		"quotas":    quotas,
+	})
+}
+
+func monitoringLimitRangesHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	limitRanges, err := monitoring.ListLimitRanges(ctx, namespace)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"namespace":    namespace,
+		"count":        len(limitRanges),
+		"limit_ranges": limitRanges,
+	})
+}
+
+func monitoringHPAHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	hpas, err := monitoring.ListHPAs(ctx, namespace)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"namespace": namespace,
+		"count":     len(hpas),
+		"hpas":      hpas,
+	})
+}
+
+// monitoringVolumesHandler serves PVC usage from the kubelet stats;
+// ?full=true keeps only volumes at or above ?threshold (default the alert threshold)
+func monitoringVolumesHandler(c *fiber.Ctx) error {
+	namespace := c.Params("namespace", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var volumes []monitoring.VolumeUsage
+	var err error
+	if c.QueryBool("full") {
+		threshold := c.QueryFloat("threshold", monitoring.VolumeAlertThreshold())
+		volumes, err = monitoring.GetNearlyFullVolumes(ctx, namespace, threshold)
+	} else {
+		volumes, err = monitoring.GetVolumeUsage(ctx, namespace)
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"namespace": namespace,
+		"count":     len(volumes),
+		"volumes":   volumes,
+	})
+}
+
+func monitoringVolumeAlertsHandler(c *fiber.Ctx) error {
+	alerts := monitoring.GetVolumeAlerts()
+	return c.JSON(fiber.Map{
+		"threshold": monitoring.VolumeAlertThreshold(),
+		"count":     len(alerts),
+		"alerts":    alerts,
+	})
+}
+
+// Tools handlers
+
+func base64EncodeHandler(c *fiber.Ctx) error {
+	var req struct {
+		Input   string `json:"input"`
+		URLSafe bool   `json:"url_safe"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	result := tools.EncodeBase64(req.Input, req.URLSafe)
+	return c.JSON(result)
+}
+
+func base64DecodeHandler(c *fiber.Ctx) error {
+	var req struct {
+		Input   string `json:"input"`
+		URLSafe bool   `json:"url_safe"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	result := tools.DecodeBase64(req.Input, req.URLSafe)
+	if result.Error != "" {
+		return c.Status(400).JSON(result)
+	}
+	return c.JSON(result)
+}
+
+func k8sSecretDecodeHandler(c *fiber.Ctx) error {
+	var req struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	result := tools.DecodeK8sSecret(req.Data)
+	return c.JSON(fiber.Map{"decoded": result})
+}
+
+func hashHandler(c *fiber.Ctx) error {
+	var req struct {
+		Input     string `json:"input"`
+		Algorithm string `json:"algorithm"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	if req.Algorithm == "" || req.Algorithm == "all" {
+		result := tools.HashAll(req.Input)
+		return c.JSON(result)
+	}
+
+	var result tools.HashResult
+	switch req.Algorithm {
+	case "md5":
+		result = tools.HashMD5(req.Input)
+	case "sha1":
+		result = tools.HashSHA1(req.Input)
+	case "sha256":
+		result = tools.HashSHA256(req.Input)
+	case "sha512":
+		result = tools.HashSHA512(req.Input)
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "unsupported algorithm"})
+	}
+	return c.JSON(result)
+}
+
+func hashCompareHandler(c *fiber.Ctx) error {
+	var req struct {
+		Hash1 string `json:"hash1"`
+		Hash2 string `json:"hash2"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	result := tools.CompareHashes(req.Hash1, req.Hash2)
+	return c.JSON(result)
+}
+
+func certCheckHandler(c *fiber.Ctx) error {
+	var req struct {
+		Host    string `json:"host"`
+		Port    int    `json:"port"`
+		Timeout int    `json:"timeout"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Host == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "host is required"})
+	}
+	if req.Port == 0 {
+		req.Port = 443
+	}
+	if req.Timeout == 0 {
+		req.Timeout = 10
+	}
+	result := tools.GetCertificateInfo(req.Host, req.Port, time.Duration(req.Timeout)*time.Second)
+	if result.Error != "" {
+		return c.Status(500).JSON(result)
+	}
+	return c.JSON(result)
+}
+
+func certParseHandler(c *fiber.Ctx) error {
+	var req struct {
+		PEM string `json:"pem"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.PEM == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "PEM data is required"})
+	}
+	result := tools.ParsePEMCertificate(req.PEM)
+	if result.Error != "" {
+		return c.Status(400).JSON(result)
+	}
+	return c.JSON(result)
+}
+
+func sshGenerateHandler(c *fiber.Ctx) error {
+	var req struct {
+		Algorithm string `json:"algorithm"`
+		BitSize   int    `json:"bit_size"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Algorithm == "" {
+		req.Algorithm = "ED25519"
+	}
+	result := tools.GenerateSSHKeyPair(req.Algorithm, req.BitSize)
+	if result.Error != "" {
+		return c.Status(500).JSON(result)
+	}
+	return c.JSON(result)
+}
+
+func sshInfoHandler(c *fiber.Ctx) error {
+	var req struct {
+		Key string `json:"key"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Key == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "key data is required"})
+	}
+	result := tools.GetSSHKeyInfo(req.Key)
+	return c.JSON(result)
+}
+
+func convertHandler(c *fiber.Ctx) error {
+	var req struct {
+		Input string `json:"input"`
+		From  string `json:"from"`
+		To    string `json:"to"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Input == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "input is required"})
+	}
+
+	var result tools.ConvertResult
+	conversion := req.From + "->" + req.To
+
+	switch conversion {
+	case "csv->json":
+		result = tools.CSVToJSON(req.Input)
+	case "json->yaml":
+		result = tools.JSONToYAML(req.Input)
+	case "yaml->json":
+		result = tools.YAMLToJSON(req.Input)
+	case "xml->json":
+		result = tools.XMLToJSON(req.Input)
+	case "toml->yaml":
+		result = tools.TOMLToYAML(req.Input)
+	case "yaml->toml":
+		result = tools.YAMLToTOML(req.Input)
+	case "properties->yaml":
+		result = tools.PropertiesToYAML(req.Input)
+	case "json->json":
+		if req.To == "minified" {
+			result = tools.MinifyJSON(req.Input)
+		} else {
+			result = tools.FormatJSON(req.Input)
+		}
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "unsupported conversion: " + conversion})
+	}
+
+	if result.Error != "" {
+		return c.Status(400).JSON(result)
+	}
+	return c.JSON(result)
+}
+
+func diffHandler(c *fiber.Ctx) error {
+	var req struct {
+		Text1 string `json:"text1"`
+		Text2 string `json:"text2"`
+		Type  string `json:"type"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	var result tools.DiffResult
+	switch req.Type {
+	case "json":
+		result = tools.JSONDiff(req.Text1, req.Text2)
+	case "yaml":
+		result = tools.YAMLDiff(req.Text1, req.Text2)
+	default:
+		result = tools.TextDiff(req.Text1, req.Text2)
+	}
+
+	if result.Error != "" {
+		return c.Status(400).JSON(result)
+	}
+	return c.JSON(result)
+}
+
+// PostgreSQL handlers
+
+// connectionsHandler lists the health of every tested connection profile
+func connectionsHandler(c *fiber.Ctx) error {
+	connections := database.ConnectionStatuses()
+	return c.JSON(fiber.Map{"count": len(connections), "connections": connections})
+}
+
+func forgetConnectionHandler(c *fiber.Ctx) error {
+	if !database.ForgetConnection(c.Params("id")) {
+		return c.Status(404).JSON(fiber.Map{"error": "connection not found"})
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "Connection forgotten"})
+}
+
+// gitHTTPAuth resolves a stored git credential to HTTP basic auth for a clone.
+// An empty id means an anonymous clone.
+func gitHTTPAuth(credentialID string) (username, password string, err error) {
+	if credentialID == "" {
+		return "", "", nil
+	}
+	cred, err := cicd.GetDecryptedGitCredential(credentialID)
+	if err != nil {
+		return "", "", err
+	}
+	switch cred.AuthMethod {
+	case cicd.GitAuthToken:
+		return "oauth2", cred.Token, nil
+	case cicd.GitAuthPassword:
+		return cred.Username, cred.Password, nil
+	default:
+		return "", "", fmt.Errorf("only token and password git credentials can be used over HTTPS")
+	}
+}
+
+// migrationRunHandler starts a migration run from a Git repository (JSON
+// body) or an uploaded bundle (multipart form with a "bundle" file)
+func migrationRunHandler(c *fiber.Ctx) error {
+	var req database.MigrationRequest
+	var set *database.MigrationSet
+
+	if file, err := c.FormFile("bundle"); err == nil {
+		req = database.MigrationRequest{
+			ProfileID: c.FormValue("profile_id"),
+			Format:    c.FormValue("format"),
+			Direction: c.FormValue("direction"),
+			DryRun:    c.FormValue("dry_run") == "true",
+		}
+		req.Target, _ = strconv.ParseInt(c.FormValue("target"), 10, 64)
+		req.Steps, _ = strconv.Atoi(c.FormValue("steps"))
+
+		src, err := file.Open()
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to open file"})
+		}
+		defer src.Close()
+		if set, err = database.LoadBundleMigrations(src, file.Filename, c.FormValue("path"), req.Format); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+	} else {
+		var body struct {
+			database.MigrationRequest
+			Source database.MigrationSource `json:"source"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+		}
+		req = body.MigrationRequest
+		if body.Source.Type != "git" {
+			return c.Status(400).JSON(fiber.Map{"error": "source type must be git, or upload a bundle"})
+		}
+
+		username, password, err := gitHTTPAuth(body.Source.CredentialID)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		if set, err = database.LoadGitMigrations(ctx, body.Source, username, password, req.Format); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	if req.ProfileID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "profile_id is required"})
+	}
+	if !req.DryRun && !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to run migrations", database.ErrElevationRequired))
+	}
+
+	run, err := database.StartMigration(req, set, c.IP())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("run", run.ID).Str("database", run.Database).Str("direction", run.Direction).
+		Bool("dry_run", run.DryRun).Str("ip", c.IP()).Msg("Migration run started")
+	return c.Status(202).JSON(run)
+}
+
+func listMigrationRunsHandler(c *fiber.Ctx) error {
+	runs, err := database.ListMigrationRuns(c.Query("profile_id"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"count": len(runs), "runs": runs})
+}
+
+func getMigrationRunHandler(c *fiber.Ctx) error {
+	run, err := database.GetMigrationRun(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(run)
+}
+
+// importRunHandler starts a CSV or XLSX import into a table from a multipart
+// form with a "file" and the request fields as form values; mapping is JSON
+func importRunHandler(c *fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "file is required"})
+	}
+	req := database.ImportRequest{
+		ProfileID: c.FormValue("profile_id"),
+		Schema:    c.FormValue("schema"),
+		Table:     c.FormValue("table"),
+		NoHeader:  c.FormValue("no_header") == "true",
+		Delimiter: c.FormValue("delimiter"),
+		Sheet:     c.FormValue("sheet"),
+		DryRun:    c.FormValue("dry_run") == "true",
+	}
+	req.BatchSize, _ = strconv.Atoi(c.FormValue("batch_size"))
+	if mapping := c.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &req.Mapping); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "mapping must be a JSON object of file column to table column"})
+		}
+	}
+
+	if req.ProfileID == "" || req.Table == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "profile_id and table are required"})
+	}
+	if !req.DryRun && !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to import into tables", database.ErrElevationRequired))
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to open file"})
+	}
+	defer src.Close()
+
+	run, err := database.StartImport(req, src, file.Filename, c.IP())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("run", run.ID).Str("database", run.Database).Str("table", run.Table).
+		Int("rows", run.TotalRows).Bool("dry_run", run.DryRun).Str("ip", c.IP()).Msg("Import started")
+	return c.Status(202).JSON(run)
+}
+
+func listImportRunsHandler(c *fiber.Ctx) error {
+	runs, err := database.ListImportRuns(c.Query("profile_id"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"count": len(runs), "runs": runs})
+}
+
+func getImportRunHandler(c *fiber.Ctx) error {
+	run, err := database.GetImportRun(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(run)
+}
+
+// importErrorRowsHandler downloads the rows an import rejected as CSV
+func importErrorRowsHandler(c *fiber.Ctx) error {
+	data, err := database.GetImportErrorRows(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	if data == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "import rejected no rows"})
+	}
+	c.Set("Content-Type", "text/csv")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-errors.csv\"", c.Params("id")))
+	return c.Send(data)
+}
+
+func listResultPoliciesHandler(c *fiber.Ctx) error {
+	policies, err := database.ListResultPolicies()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"count": len(policies), "policies": policies})
+}
+
+func getResultPolicyHandler(c *fiber.Ctx) error {
+	policy, err := database.GetResultPolicy(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(policy)
+}
+
+// saveResultPolicyHandler creates a result policy (POST) or replaces one (PUT)
+func saveResultPolicyHandler(c *fiber.Ctx) error {
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to change result policies", database.ErrElevationRequired))
+	}
+
+	var policy database.ResultPolicy
+	if err := c.BodyParser(&policy); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	policy.ID = c.Params("id")
+	if policy.ID != "" {
+		if _, err := database.GetResultPolicy(policy.ID); err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	if err := database.SaveResultPolicy(&policy, c.IP()); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("policy", policy.ID).Str("kind", policy.Kind).Str("host", policy.Host).
+		Str("database", policy.Database).Str("ip", c.IP()).Msg("Result policy saved")
+	return c.JSON(policy)
+}
+
+func deleteResultPolicyHandler(c *fiber.Ctx) error {
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to change result policies", database.ErrElevationRequired))
+	}
+	if err := database.DeleteResultPolicy(c.Params("id")); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("policy", c.Params("id")).Str("ip", c.IP()).Msg("Result policy deleted")
+	return c.JSON(fiber.Map{"success": true, "message": "Result policy deleted"})
+}
+
+// queryResultPolicy returns the result policy for a console query. Asking for
+// unmasked results skips the policy and needs elevation.
+func queryResultPolicy(c *fiber.Ctx, unmasked bool, find func() (*database.ResultPolicy, error)) (*database.ResultPolicy, error) {
+	if unmasked {
+		if !auth.IsElevated(c) {
+			return nil, fmt.Errorf("%w for unmasked results", database.ErrElevationRequired)
+		}
+		return nil, nil
+	}
+	return find()
+}
+
+// tableRowsHandler returns a filtered, sorted page of a table under the
+// connection's result policy
+func tableRowsHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.TableRequest
+		Unmasked bool `json:"unmasked"` // skip the result policy, needs elevation
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.ProfileID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "profile_id is required"})
+	}
+
+	policy, err := queryResultPolicy(c, req.Unmasked, func() (*database.ResultPolicy, error) {
+		return database.ProfileResultPolicy(req.ProfileID)
+	})
+	if err != nil {
+		return queryGuardError(c, err)
+	}
+
+	page, err := database.BrowseTable(context.Background(), req.TableRequest, policy)
+	if err != nil {
+		return queryGuardError(c, err)
+	}
+	return c.JSON(page)
+}
+
+// tableRowChangeHandler inserts, updates or deletes one row by primary key
+func tableRowChangeHandler(op string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var change database.RowChange
+		if err := c.BodyParser(&change); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+		}
+		if change.ProfileID == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "profile_id is required"})
+		}
+		if !change.DryRun && !auth.IsElevated(c) {
+			return queryGuardError(c, fmt.Errorf("%w to edit table rows", database.ErrElevationRequired))
+		}
+
+		result, err := database.ChangeRow(context.Background(), op, change)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !change.DryRun {
+			log.Info().Str("profile", change.ProfileID).Str("table", change.Table).Str("op", op).
+				Str("ip", c.IP()).Msg("Table row changed")
+		}
+		return c.JSON(result)
+	}
+}
+
+func postgresConnectHandler(c *fiber.Ctx) error {
+	var config database.PostgresConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 5432
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := database.CachedPostgresConnection(ctx, config, c.QueryBool("fresh", false))
+	return c.JSON(result)
+}
+
+func postgresInfoHandler(c *fiber.Ctx) error {
+	var config database.PostgresConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 5432
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := database.GetPostgresInfo(ctx, config)
+	return c.JSON(result)
+}
+
+func postgresQueryHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.PostgresConfig
+		Query    string `json:"query"`
+		ReadOnly bool   `json:"readonly"`
+		Unmasked bool   `json:"unmasked"` // skip the result policy, needs elevation
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Port == 0 {
+		req.Port = 5432
+	}
+
+	opts := database.QueryOptions{ReadOnly: req.ReadOnly, AllowWrite: auth.IsElevated(c)}
+	if err := database.CheckQuery(req.Query, database.DialectPostgres, opts); err != nil {
+		return queryGuardError(c, err)
+	}
+	policy, err := queryResultPolicy(c, req.Unmasked, func() (*database.ResultPolicy, error) {
+		return database.PostgresResultPolicy(req.PostgresConfig)
+	})
+	if err != nil {
+		return queryGuardError(c, err)
+	}
+	opts.Policy = policy
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result := database.ExecutePostgresQuery(ctx, req.PostgresConfig, req.Query, opts)
+	if result.Error != "" {
+		return c.Status(400).JSON(result)
+	}
+	return c.JSON(result)
+}
+
+func postgresDumpHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.PostgresConfig
+		SchemaOnly bool     `json:"schema_only"`
+		DataOnly   bool     `json:"data_only"`
+		Tables     []string `json:"tables"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Port == 0 {
+		req.Port = 5432
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	result := database.DumpPostgres(ctx, req.PostgresConfig, req.SchemaOnly, req.DataOnly, req.Tables)
+	return c.JSON(result)
+}
+
+func postgresDatabasesHandler(c *fiber.Ctx) error {
+	var config database.PostgresConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 5432
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	databases, err := database.GetPostgresDatabases(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"databases": databases})
+}
+
+// Redis handlers
+
+func redisConnectHandler(c *fiber.Ctx) error {
+	var config database.RedisConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 6379
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := database.CachedRedisConnection(ctx, config, c.QueryBool("fresh", false))
+	return c.JSON(result)
+}
+
+func redisInfoHandler(c *fiber.Ctx) error {
+	var config database.RedisConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 6379
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := database.GetRedisInfo(ctx, config)
+	return c.JSON(result)
+}
+
+func redisClusterHandler(c *fiber.Ctx) error {
+	var config database.RedisConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 6379
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := database.GetRedisClusterInfo(ctx, config)
+	return c.JSON(result)
+}
+
+func redisScanHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.RedisConfig
+		Pattern string `json:"pattern"`
+		Cursor  uint64 `json:"cursor"`
+		Count   int64  `json:"count"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Port == 0 {
+		req.Port = 6379
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := database.ScanRedisKeys(ctx, req.RedisConfig, req.Pattern, req.Cursor, req.Count)
+	return c.JSON(result)
+}
+
+func redisKeyHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.RedisConfig
+		Key string `json:"key"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Port == 0 {
+		req.Port = 6379
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := database.GetRedisKeyValue(ctx, req.RedisConfig, req.Key)
+	return c.JSON(result)
+}
+
+func redisCommandHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.RedisConfig
+		Command string `json:"command"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Port == 0 {
+		req.Port = 6379
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if !auth.IsElevated(c) {
+		if err := database.CheckRedisCommand(ctx, req.RedisConfig, req.Command); err != nil {
+			return queryGuardError(c, err)
 		}
-	} else {
-		status = "no builds"
 	}
 
-	c.Set("Content-Type", "image/svg+xml")
-	c.Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	return c.SendString(generateBadgeSVG(status, color))
+	result := database.ExecuteRedisCommand(ctx, req.RedisConfig, req.Command)
+	return c.JSON(result)
 }
 
-func updateFreestyleJobHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var req cicd.CreateFreestyleJobRequest
+// redisReplicationHandler reports replication lag of the node in the body,
+// its master and replicas; "replicas" adds host:port addresses to inspect
+func redisReplicationHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.RedisConfig
+		Replicas []string `json:"replicas"`
+	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Port == 0 {
+		req.Port = 6379
 	}
 
-	job, err := cicd.UpdateFreestyleJob(id, &req)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	result, err := database.GetRedisReplication(ctx, req.RedisConfig, req.Replicas)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(job)
+	return c.JSON(result)
 }
 
-func deleteFreestyleJobHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	if err := cicd.DeleteFreestyleJob(id); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+// redisPromoteHandler promotes the replica in the body to master; "confirm"
+// must repeat its host:port
+func redisPromoteHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.RedisConfig
+		Confirm string `json:"confirm"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Port == 0 {
+		req.Port = 6379
+	}
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to promote a Redis replica", database.ErrElevationRequired))
 	}
-	return c.JSON(fiber.Map{"success": true})
-}
 
-func triggerFreestyleBuildHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var req cicd.TriggerFreestyleBuildRequest
-	c.BodyParser(&req) // Optional params
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
 
-	build, err := cicd.TriggerFreestyleBuild(id, "manual", "", req.Parameters)
+	result, err := database.PromoteRedisReplica(ctx, req.RedisConfig, req.Confirm)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.Status(201).JSON(build)
+	log.Info().Str("node", result.After.Addr).Str("previous_master", result.Before.MasterAddr).Str("ip", c.IP()).Msg("Redis replica promoted")
+	return c.JSON(result)
 }
 
-func listJobBuildsHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	builds, err := cicd.ListFreestyleBuildsForJob(id)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+// redisRepointHandler makes "replicas" replicate from "master"; "confirm"
+// must repeat the master's host:port
+func redisRepointHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.RedisConfig
+		Master   string   `json:"master"`
+		Replicas []string `json:"replicas"`
+		Confirm  string   `json:"confirm"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Port == 0 {
+		req.Port = 6379
+	}
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to repoint Redis replicas", database.ErrElevationRequired))
 	}
-	return c.JSON(fiber.Map{
-		"count":  len(builds),
-		"builds": builds,
-	})
-}
 
-// Freestyle Build handlers
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-func listFreestyleBuildsHandler(c *fiber.Ctx) error {
-	builds, err := cicd.ListFreestyleBuilds()
+	results, err := database.RepointRedisReplicas(ctx, req.RedisConfig, req.Master, req.Replicas, req.Confirm)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	failed := 0
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+	}
+	log.Info().Str("master", req.Master).Int("replicas", len(results)-failed).Str("ip", c.IP()).Msg("Redis replicas repointed")
 	return c.JSON(fiber.Map{
-		"count":  len(builds),
-		"builds": builds,
+		"success":  failed == 0,
+		"failed":   failed,
+		"replicas": results,
 	})
 }
 
-func getFreestyleBuildHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	build, err := cicd.GetFreestyleBuild(id)
-	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
-	}
-	return c.JSON(build)
-}
+// Memcached handlers
 
-func cancelFreestyleBuildHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	if err := cicd.CancelFreestyleBuild(id); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+func memcachedStatsHandler(c *fiber.Ctx) error {
+	var config database.MemcachedConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
 	}
-	return c.JSON(fiber.Map{"success": true})
-}
-
-func deleteFreestyleBuildHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	if err := cicd.DeleteFreestyleBuild(id); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	if config.Port == 0 {
+		config.Port = 11211
 	}
-	return c.JSON(fiber.Map{"success": true})
-}
 
-func getFreestyleBuildLogsHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	logs, err := cicd.GetBuildLogs(id)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stats, err := database.GetMemcachedStats(ctx, config)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{
-		"build_id": id,
-		"logs":     logs,
-	})
+	return c.JSON(stats)
 }
 
-func freestyleWebhookHandler(c *fiber.Ctx) error {
-	token := c.Params("token")
+func memcachedSlabsHandler(c *fiber.Ctx) error {
+	var config database.MemcachedConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 11211
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	job, err := cicd.GetFreestyleJobByWebhookToken(token)
+	slabs, err := database.GetMemcachedSlabs(ctx, config)
 	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "invalid webhook token"})
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(fiber.Map{"slabs": slabs})
+}
 
-	// Check if webhook trigger is enabled
-	webhookEnabled := false
-	for _, t := range job.Triggers {
-		if t.Type == "webhook" && t.Enabled {
-			webhookEnabled = true
-			break
-		}
+func memcachedGetHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.MemcachedConfig
+		Key string `json:"key"`
 	}
-
-	if !webhookEnabled {
-		return c.Status(403).JSON(fiber.Map{"error": "webhook trigger is not enabled for this job"})
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
 	}
-
-	// Verify HMAC signature if secret is configured
-	if job.Status.WebhookSecret != "" {
-		signature := c.Get("X-GAGOS-Signature")
-		if signature == "" {
-			// Also check common webhook signature headers
-			signature = c.Get("X-Hub-Signature-256")
-			if signature == "" {
-				signature = c.Get("X-Signature-256")
-			}
-		}
-
-		if signature != "" {
-			body := c.Body()
-			if !cicd.VerifyWebhookSignature(body, signature, job.Status.WebhookSecret) {
-				log.Warn().
-					Str("job_id", job.ID).
-					Str("job_name", job.Name).
-					Msg("Webhook signature verification failed")
-				return c.Status(401).JSON(fiber.Map{"error": "invalid signature"})
-			}
-		}
-		// If no signature provided but secret is set, log a warning but allow
-		// This allows for gradual adoption of signature verification
-		if signature == "" {
-			log.Warn().
-				Str("job_id", job.ID).
-				Str("job_name", job.Name).
-				Msg("Webhook called without signature - consider adding X-GAGOS-Signature header")
-		}
+	if req.Port == 0 {
+		req.Port = 11211
 	}
 
-	var params map[string]string
-	c.BodyParser(&params)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	build, err := cicd.TriggerFreestyleBuild(job.ID, "webhook", "", params)
+	item, err := database.GetMemcachedKey(ctx, req.MemcachedConfig, req.Key)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.Status(201).JSON(fiber.Map{
-		"build_id":     build.ID,
-		"build_number": build.BuildNumber,
-		"job_name":     build.JobName,
-	})
+	return c.JSON(item)
 }
 
-func freestyleLogStreamHandler(c *websocket.Conn) {
-	buildID := c.Params("id")
-
-	stream := cicd.GetBuildOutputStream(buildID)
-	if stream == nil {
-		// Build not running, send existing logs and close
-		logs, err := cicd.GetBuildLogs(buildID)
-		if err != nil {
-			c.WriteMessage(websocket.TextMessage, []byte("Error: "+err.Error()))
-			return
+// memcachedSetHandler stores a value; "encoding":"base64" sends binary data
+func memcachedSetHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.MemcachedConfig
+		Key      string `json:"key"`
+		Value    string `json:"value"`
+		Encoding string `json:"encoding"`
+		Flags    uint32 `json:"flags"`
+		TTL      int64  `json:"ttl"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Port == 0 {
+		req.Port = 11211
+	}
+	value := []byte(req.Value)
+	if req.Encoding == "base64" {
+		var err error
+		if value, err = base64.StdEncoding.DecodeString(req.Value); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "value is not valid base64"})
 		}
-		c.WriteMessage(websocket.TextMessage, []byte(logs))
-		return
 	}
 
-	// Subscribe to live output
-	ch := stream.Subscribe()
-	defer stream.Unsubscribe(ch)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	for data := range ch {
-		if err := c.WriteMessage(websocket.TextMessage, data); err != nil {
-			break
-		}
+	if err := database.SetMemcachedKey(ctx, req.MemcachedConfig, req.Key, value, req.Flags, req.TTL); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(fiber.Map{"success": true})
 }
 
-// Monitoring handlers
+func memcachedDeleteHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.MemcachedConfig
+		Key string `json:"key"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Port == 0 {
+		req.Port = 11211
+	}
 
-func monitoringSummaryHandler(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	summary, err := monitoring.GetClusterSummary(ctx)
+	deleted, err := database.DeleteMemcachedKey(ctx, req.MemcachedConfig, req.Key)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(summary)
+	return c.JSON(fiber.Map{"success": true, "deleted": deleted})
 }
 
-func monitoringNodesHandler(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// etcd handlers
+
+func etcdMembersHandler(c *fiber.Ctx) error {
+	var config database.EtcdConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 2379
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	nodes, err := monitoring.GetNodeMetrics(ctx)
+	members, err := database.GetEtcdMembers(ctx, config)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{
-		"count":            len(nodes),
-		"nodes":            nodes,
-		"metrics_available": monitoring.IsMetricsAvailable(),
-	})
+	return c.JSON(members)
 }
 
-func monitoringPodsHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace", "")
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func etcdHealthHandler(c *fiber.Ctx) error {
+	var config database.EtcdConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 2379
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	pods, err := monitoring.GetPodMetrics(ctx, namespace)
+	endpoints, err := database.GetEtcdHealth(ctx, config)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{
-		"namespace":         namespace,
-		"count":             len(pods),
-		"pods":              pods,
-		"metrics_available": monitoring.IsMetricsAvailable(),
-	})
+	healthy := len(endpoints) > 0
+	for _, e := range endpoints {
+		healthy = healthy && e.Healthy
+	}
+	return c.JSON(fiber.Map{"healthy": healthy, "endpoints": endpoints})
 }
 
-func monitoringQuotasHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace", "")
+func etcdKeysHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.EtcdConfig
+		Prefix string `json:"prefix"`
+		Start  string `json:"start"`
+		Limit  int    `json:"limit"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Port == 0 {
+		req.Port = 2379
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	quotas, err := monitoring.ListResourceQuotas(ctx, namespace)
+	list, err := database.ListEtcdKeys(ctx, req.EtcdConfig, req.Prefix, req.Start, req.Limit)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{
-		"namespace": namespace,
-		"count":     len(quotas),
-		"quotas":    quotas,
-	})
+	return c.JSON(list)
 }
 
-func monitoringLimitRangesHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace", "")
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func etcdKeyHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.EtcdConfig
+		Key string `json:"key"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Port == 0 {
+		req.Port = 2379
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	limitRanges, err := monitoring.ListLimitRanges(ctx, namespace)
+	key, err := database.GetEtcdKey(ctx, req.EtcdConfig, req.Key)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(fiber.Map{
-		"namespace":    namespace,
-		"count":        len(limitRanges),
-		"limit_ranges": limitRanges,
-	})
+	return c.JSON(key)
 }
 
-func monitoringHPAHandler(c *fiber.Ctx) error {
-	namespace := c.Params("namespace", "")
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// MySQL handlers
 
-	hpas, err := monitoring.ListHPAs(ctx, namespace)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+func mysqlConnectHandler(c *fiber.Ctx) error {
+	var config database.MySQLConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 3306
 	}
 
-	return c.JSON(fiber.Map{
-		"namespace": namespace,
-		"count":     len(hpas),
-		"hpas":      hpas,
-	})
-}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-// Tools handlers
+	result := database.CachedMySQLConnection(ctx, config, c.QueryBool("fresh", false))
+	return c.JSON(result)
+}
 
-func base64EncodeHandler(c *fiber.Ctx) error {
-	var req struct {
-		Input   string `json:"input"`
-		URLSafe bool   `json:"url_safe"`
-	}
-	if err := c.BodyParser(&req); err != nil {
+func mysqlInfoHandler(c *fiber.Ctx) error {
+	var config database.MySQLConfig
+	if err := c.BodyParser(&config); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
 	}
-	result := tools.EncodeBase64(req.Input, req.URLSafe)
+	if config.Port == 0 {
+		config.Port = 3306
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := database.GetMySQLInfo(ctx, config)
 	return c.JSON(result)
 }
 
-func base64DecodeHandler(c *fiber.Ctx) error {
+func mysqlQueryHandler(c *fiber.Ctx) error {
 	var req struct {
-		Input   string `json:"input"`
-		URLSafe bool   `json:"url_safe"`
+		database.MySQLConfig
+		Query    string `json:"query"`
+		ReadOnly bool   `json:"readonly"`
+		Unmasked bool   `json:"unmasked"` // skip the result policy, needs elevation
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
 	}
-	result := tools.DecodeBase64(req.Input, req.URLSafe)
+	if req.Port == 0 {
+		req.Port = 3306
+	}
+
+	opts := database.QueryOptions{ReadOnly: req.ReadOnly, AllowWrite: auth.IsElevated(c)}
+	if err := database.CheckQuery(req.Query, database.DialectMySQL, opts); err != nil {
+		return queryGuardError(c, err)
+	}
+	policy, err := queryResultPolicy(c, req.Unmasked, func() (*database.ResultPolicy, error) {
+		return database.MySQLResultPolicy(req.MySQLConfig)
+	})
+	if err != nil {
+		return queryGuardError(c, err)
+	}
+	opts.Policy = policy
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result := database.ExecuteMySQLQuery(ctx, req.MySQLConfig, req.Query, opts)
 	if result.Error != "" {
 		return c.Status(400).JSON(result)
 	}
 	return c.JSON(result)
 }
 
-func k8sSecretDecodeHandler(c *fiber.Ctx) error {
+func mysqlDumpHandler(c *fiber.Ctx) error {
 	var req struct {
-		Data map[string]string `json:"data"`
+		database.MySQLConfig
+		SchemaOnly bool     `json:"schema_only"`
+		DataOnly   bool     `json:"data_only"`
+		Tables     []string `json:"tables"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
 	}
-	result := tools.DecodeK8sSecret(req.Data)
-	return c.JSON(fiber.Map{"decoded": result})
+	if req.Port == 0 {
+		req.Port = 3306
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	result := database.DumpMySQL(ctx, req.MySQLConfig, req.SchemaOnly, req.DataOnly, req.Tables)
+	return c.JSON(result)
 }
 
-func hashHandler(c *fiber.Ctx) error {
-	var req struct {
-		Input     string `json:"input"`
-		Algorithm string `json:"algorithm"`
-	}
-	if err := c.BodyParser(&req); err != nil {
+func mysqlDatabasesHandler(c *fiber.Ctx) error {
+	var config database.MySQLConfig
+	if err := c.BodyParser(&config); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
 	}
+	if config.Port == 0 {
+		config.Port = 3306
+	}
 
-	if req.Algorithm == "" || req.Algorithm == "all" {
-		result := tools.HashAll(req.Input)
-		return c.JSON(result)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	databases, err := database.GetMySQLDatabases(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(fiber.Map{"databases": databases})
+}
 
-	var result tools.HashResult
-	switch req.Algorithm {
-	case "md5":
-		result = tools.HashMD5(req.Input)
-	case "sha1":
-		result = tools.HashSHA1(req.Input)
-	case "sha256":
-		result = tools.HashSHA256(req.Input)
-	case "sha512":
-		result = tools.HashSHA512(req.Input)
-	default:
-		return c.Status(400).JSON(fiber.Map{"error": "unsupported algorithm"})
+// SQL Server handlers
+
+func mssqlConnectHandler(c *fiber.Ctx) error {
+	var config database.MSSQLConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 1433
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	result := database.CachedMSSQLConnection(ctx, config, c.QueryBool("fresh", false))
 	return c.JSON(result)
 }
 
-func hashCompareHandler(c *fiber.Ctx) error {
-	var req struct {
-		Hash1 string `json:"hash1"`
-		Hash2 string `json:"hash2"`
-	}
-	if err := c.BodyParser(&req); err != nil {
+func mssqlInfoHandler(c *fiber.Ctx) error {
+	var config database.MSSQLConfig
+	if err := c.BodyParser(&config); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
 	}
-	result := tools.CompareHashes(req.Hash1, req.Hash2)
+	if config.Port == 0 {
+		config.Port = 1433
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := database.GetMSSQLInfo(ctx, config)
 	return c.JSON(result)
 }
 
-func certCheckHandler(c *fiber.Ctx) error {
+func mssqlQueryHandler(c *fiber.Ctx) error {
 	var req struct {
-		Host    string `json:"host"`
-		Port    int    `json:"port"`
-		Timeout int    `json:"timeout"`
+		database.MSSQLConfig
+		Query    string `json:"query"`
+		ReadOnly bool   `json:"readonly"`
+		Unmasked bool   `json:"unmasked"` // skip the result policy, needs elevation
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
 	}
-	if req.Host == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "host is required"})
-	}
 	if req.Port == 0 {
-		req.Port = 443
+		req.Port = 1433
 	}
-	if req.Timeout == 0 {
-		req.Timeout = 10
+
+	opts := database.QueryOptions{ReadOnly: req.ReadOnly, AllowWrite: auth.IsElevated(c)}
+	if err := database.CheckQuery(req.Query, database.DialectMSSQL, opts); err != nil {
+		return queryGuardError(c, err)
 	}
-	result := tools.GetCertificateInfo(req.Host, req.Port, time.Duration(req.Timeout)*time.Second)
+	policy, err := queryResultPolicy(c, req.Unmasked, func() (*database.ResultPolicy, error) {
+		return database.MSSQLResultPolicy(req.MSSQLConfig)
+	})
+	if err != nil {
+		return queryGuardError(c, err)
+	}
+	opts.Policy = policy
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result := database.ExecuteMSSQLQuery(ctx, req.MSSQLConfig, req.Query, opts)
 	if result.Error != "" {
-		return c.Status(500).JSON(result)
+		return c.Status(400).JSON(result)
+	}
+	return c.JSON(result)
+}
+
+func mssqlDatabasesHandler(c *fiber.Ctx) error {
+	var config database.MSSQLConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 1433
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	databases, err := database.GetMSSQLDatabases(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"databases": databases})
+}
+
+func mssqlAlwaysOnHandler(c *fiber.Ctx) error {
+	var config database.MSSQLConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 1433
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	status, err := database.GetMSSQLAlwaysOn(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(status)
+}
+
+// Oracle handlers
+
+func oracleConnectHandler(c *fiber.Ctx) error {
+	var config database.OracleConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 1521
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	result := database.CachedOracleConnection(ctx, config, c.QueryBool("fresh", false))
+	return c.JSON(result)
+}
+
+func oracleInfoHandler(c *fiber.Ctx) error {
+	var config database.OracleConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if config.Port == 0 {
+		config.Port = 1521
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := database.GetOracleInfo(ctx, config)
 	return c.JSON(result)
 }
 
-func certParseHandler(c *fiber.Ctx) error {
+func oracleQueryHandler(c *fiber.Ctx) error {
 	var req struct {
-		PEM string `json:"pem"`
+		database.OracleConfig
+		Query    string `json:"query"`
+		ReadOnly bool   `json:"readonly"`
+		Unmasked bool   `json:"unmasked"` // skip the result policy, needs elevation
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
 	}
-	if req.PEM == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "PEM data is required"})
+	if req.Port == 0 {
+		req.Port = 1521
 	}
-	result := tools.ParsePEMCertificate(req.PEM)
+
+	opts := database.QueryOptions{ReadOnly: req.ReadOnly, AllowWrite: auth.IsElevated(c)}
+	if err := database.CheckQuery(req.Query, database.DialectOracle, opts); err != nil {
+		return queryGuardError(c, err)
+	}
+	policy, err := queryResultPolicy(c, req.Unmasked, func() (*database.ResultPolicy, error) {
+		return database.OracleResultPolicy(req.OracleConfig)
+	})
+	if err != nil {
+		return queryGuardError(c, err)
+	}
+	opts.Policy = policy
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result := database.ExecuteOracleQuery(ctx, req.OracleConfig, req.Query, opts)
 	if result.Error != "" {
 		return c.Status(400).JSON(result)
 	}
 	return c.JSON(result)
 }
 
-func sshGenerateHandler(c *fiber.Ctx) error {
-	var req struct {
-		Algorithm string `json:"algorithm"`
-		BitSize   int    `json:"bit_size"`
-	}
-	if err := c.BodyParser(&req); err != nil {
+func oracleTablespacesHandler(c *fiber.Ctx) error {
+	var config database.OracleConfig
+	if err := c.BodyParser(&config); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
 	}
-	if req.Algorithm == "" {
-		req.Algorithm = "ED25519"
+	if config.Port == 0 {
+		config.Port = 1521
 	}
-	result := tools.GenerateSSHKeyPair(req.Algorithm, req.BitSize)
-	if result.Error != "" {
-		return c.Status(500).JSON(result)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tablespaces, err := database.GetOracleTablespaces(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(result)
+	return c.JSON(fiber.Map{"tablespaces": tablespaces})
 }
 
-func sshInfoHandler(c *fiber.Ctx) error {
-	var req struct {
-		Key string `json:"key"`
+// Notification handlers
+
+func listNotificationsHandler(c *fiber.Ctx) error {
+	configs, err := cicd.ListNotificationConfigs()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	safe := make([]*cicd.NotificationConfig, 0, len(configs))
+	for _, config := range configs {
+		safe = append(safe, config.ToSafe())
 	}
-	if req.Key == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "key data is required"})
+	return c.JSON(fiber.Map{
+		"count":         len(safe),
+		"notifications": safe,
+	})
+}
+
+func createNotificationHandler(c *fiber.Ctx) error {
+	var config cicd.NotificationConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-	result := tools.GetSSHKeyInfo(req.Key)
-	return c.JSON(result)
+
+	if config.Name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name is required"})
+	}
+	if err := cicd.ValidateNotificationConfig(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := validateProxySetting(config.Proxy); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	result, err := cicd.CreateNotificationConfig(&config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(result.ToSafe())
 }
 
-func convertHandler(c *fiber.Ctx) error {
-	var req struct {
-		Input string `json:"input"`
-		From  string `json:"from"`
-		To    string `json:"to"`
+func getNotificationHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	config, err := cicd.GetNotificationConfig(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
 	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	return c.JSON(config.ToSafe())
+}
+
+func updateNotificationHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var config cicd.NotificationConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-	if req.Input == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "input is required"})
+	if err := cicd.ValidateNotificationConfig(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := validateProxySetting(config.Proxy); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	var result tools.ConvertResult
-	conversion := req.From + "->" + req.To
+	result, err := cicd.UpdateNotificationConfig(id, &config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	switch conversion {
-	case "csv->json":
-		result = tools.CSVToJSON(req.Input)
-	case "json->yaml":
-		result = tools.JSONToYAML(req.Input)
-	case "yaml->json":
-		result = tools.YAMLToJSON(req.Input)
-	case "xml->json":
-		result = tools.XMLToJSON(req.Input)
-	case "toml->yaml":
-		result = tools.TOMLToYAML(req.Input)
-	case "yaml->toml":
-		result = tools.YAMLToTOML(req.Input)
-	case "properties->yaml":
-		result = tools.PropertiesToYAML(req.Input)
-	case "json->json":
-		if req.To == "minified" {
-			result = tools.MinifyJSON(req.Input)
-		} else {
-			result = tools.FormatJSON(req.Input)
-		}
-	default:
-		return c.Status(400).JSON(fiber.Map{"error": "unsupported conversion: " + conversion})
+	return c.JSON(result.ToSafe())
+}
+
+func deleteNotificationHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := cicd.DeleteNotificationConfig(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(fiber.Map{"success": true})
+}
 
-	if result.Error != "" {
-		return c.Status(400).JSON(result)
+// validateProxySetting accepts an empty proxy setting, "direct" or a proxy URL
+func validateProxySetting(proxy string) error {
+	if proxy == "" || proxy == egress.ProxyDirect {
+		return nil
 	}
-	return c.JSON(result)
+	_, err := egress.ParseProxyURL(proxy)
+	return err
 }
 
-func diffHandler(c *fiber.Ctx) error {
+func testNotificationHandler(c *fiber.Ctx) error {
 	var req struct {
-		Text1 string `json:"text1"`
-		Text2 string `json:"text2"`
-		Type  string `json:"type"`
+		ID      string                `json:"id"` // saved config whose SMTP password an email test may use
+		Type    cicd.NotificationType `json:"type"`
+		URL     string                `json:"url"`
+		Secret  string                `json:"secret"`
+		Headers map[string]string     `json:"headers"`
+		Proxy   string                `json:"proxy"`
+		Email   *cicd.EmailConfig     `json:"email"`
 	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	var result tools.DiffResult
-	switch req.Type {
-	case "json":
-		result = tools.JSONDiff(req.Text1, req.Text2)
-	case "yaml":
-		result = tools.YAMLDiff(req.Text1, req.Text2)
-	default:
-		result = tools.TextDiff(req.Text1, req.Text2)
+	testConfig := &cicd.NotificationConfig{
+		Name:    "Test Notification",
+		Type:    req.Type,
+		Enabled: true,
+		URL:     req.URL,
+		Secret:  req.Secret,
+		Headers: req.Headers,
+		Proxy:   req.Proxy,
+		Email:   req.Email,
+	}
+	if err := cicd.ValidateNotificationConfig(testConfig); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := validateProxySetting(req.Proxy); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	if result.Error != "" {
-		return c.Status(400).JSON(result)
+	var existing *cicd.NotificationConfig
+	if req.ID != "" {
+		existing, _ = cicd.GetNotificationConfig(req.ID)
 	}
-	return c.JSON(result)
+	if err := cicd.SendTestNotification(testConfig, existing); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Test notification sent",
+	})
 }
 
-// PostgreSQL handlers
+// S3 Storage handlers
 
-func postgresConnectHandler(c *fiber.Ctx) error {
-	var config database.PostgresConfig
+func s3ConnectHandler(c *fiber.Ctx) error {
+	var config database.S3Config
 	if err := c.BodyParser(&config); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-	if config.Port == 0 {
-		config.Port = 5432
+
+	if config.Endpoint == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "endpoint is required"})
+	}
+	if config.AuthMode != database.AuthIAM && (config.AccessKeyID == "" || config.SecretAccessKey == "") {
+		return c.Status(400).JSON(fiber.Map{"error": "access key and secret key are required"})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result := database.TestPostgresConnection(ctx, config)
+	result := database.CachedS3Connection(ctx, config, c.QueryBool("fresh", false))
 	return c.JSON(result)
 }
 
-func postgresInfoHandler(c *fiber.Ctx) error {
-	var config database.PostgresConfig
+func s3ListBucketsHandler(c *fiber.Ctx) error {
+	var config database.S3Config
 	if err := c.BodyParser(&config); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-	if config.Port == 0 {
-		config.Port = 5432
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	buckets, err := database.ListS3Buckets(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"buckets": buckets})
+}
+
+func s3CreateBucketHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.S3Config
+		Bucket string `json:"bucket"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.Bucket == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "bucket name is required"})
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result := database.GetPostgresInfo(ctx, config)
-	return c.JSON(result)
+	if err := database.CreateS3Bucket(ctx, req.S3Config, req.Bucket); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"success": true, "message": "Bucket created"})
+}
+
+func s3DeleteBucketHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.S3Config
+		Bucket string `json:"bucket"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	if req.Bucket == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "bucket name is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := database.DeleteS3Bucket(ctx, req.S3Config, req.Bucket); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"success": true, "message": "Bucket deleted"})
 }
 
-func postgresQueryHandler(c *fiber.Ctx) error {
+func s3ListObjectsHandler(c *fiber.Ctx) error {
 	var req struct {
-		database.PostgresConfig
-		Query    string `json:"query"`
-		ReadOnly bool   `json:"readonly"`
+		database.S3Config
+		Bucket  string `json:"bucket"`
+		Prefix  string `json:"prefix"`
+		MaxKeys int    `json:"max_keys"`
 	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-	if req.Port == 0 {
-		req.Port = 5432
+
+	if req.Bucket == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "bucket is required"})
+	}
+
+	if req.MaxKeys <= 0 {
+		req.MaxKeys = 1000
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	result := database.ExecutePostgresQuery(ctx, req.PostgresConfig, req.Query, req.ReadOnly)
-	if result.Error != "" {
-		return c.Status(400).JSON(result)
+	objects, err := database.ListS3Objects(ctx, req.S3Config, req.Bucket, req.Prefix, req.MaxKeys)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(result)
+
+	return c.JSON(fiber.Map{"objects": objects, "bucket": req.Bucket, "prefix": req.Prefix})
 }
 
-func postgresDumpHandler(c *fiber.Ctx) error {
+func s3ObjectInfoHandler(c *fiber.Ctx) error {
 	var req struct {
-		database.PostgresConfig
-		SchemaOnly bool     `json:"schema_only"`
-		DataOnly   bool     `json:"data_only"`
-		Tables     []string `json:"tables"`
+		database.S3Config
+		Bucket string `json:"bucket"`
+		Key    string `json:"key"`
 	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
-	}
-	if req.Port == 0 {
-		req.Port = 5432
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
-	result := database.DumpPostgres(ctx, req.PostgresConfig, req.SchemaOnly, req.DataOnly, req.Tables)
-	return c.JSON(result)
-}
-
-func postgresDatabasesHandler(c *fiber.Ctx) error {
-	var config database.PostgresConfig
-	if err := c.BodyParser(&config); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
-	}
-	if config.Port == 0 {
-		config.Port = 5432
+	if req.Bucket == "" || req.Key == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "bucket and key are required"})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	databases, err := database.GetPostgresDatabases(ctx, config)
+	info, err := database.GetS3ObjectInfo(ctx, req.S3Config, req.Bucket, req.Key)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"databases": databases})
-}
 
-// Redis handlers
+	return c.JSON(info)
+}
 
-func redisConnectHandler(c *fiber.Ctx) error {
-	var config database.RedisConfig
-	if err := c.BodyParser(&config); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+func s3UploadHandler(c *fiber.Ctx) error {
+	// Get config from form fields
+	config := database.S3Config{
+		Endpoint:        c.FormValue("endpoint"),
+		Region:          c.FormValue("region"),
+		AccessKeyID:     c.FormValue("access_key_id"),
+		SecretAccessKey: c.FormValue("secret_access_key"),
+		UseSSL:          c.FormValue("use_ssl") == "true",
+		AuthMode:        c.FormValue("auth_mode"),
 	}
-	if config.Port == 0 {
-		config.Port = 6379
+	if tlsJSON := c.FormValue("tls"); tlsJSON != "" {
+		if err := json.Unmarshal([]byte(tlsJSON), &config.TLS); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid tls: " + err.Error()})
+		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	result := database.TestRedisConnection(ctx, config)
-	return c.JSON(result)
-}
+	bucket := c.FormValue("bucket")
+	prefix := c.FormValue("prefix")
 
-func redisInfoHandler(c *fiber.Ctx) error {
-	var config database.RedisConfig
-	if err := c.BodyParser(&config); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
-	}
-	if config.Port == 0 {
-		config.Port = 6379
+	if bucket == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "bucket is required"})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// Get the file from the request
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "file is required"})
+	}
 
-	result := database.GetRedisInfo(ctx, config)
-	return c.JSON(result)
-}
+	// Open the file (spooled to disk by the multipart reader, not held in memory)
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to open file"})
+	}
+	defer src.Close()
 
-func redisClusterHandler(c *fiber.Ctx) error {
-	var config database.RedisConfig
-	if err := c.BodyParser(&config); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	// Build the key (prefix + filename)
+	key := file.Filename
+	if prefix != "" {
+		key = strings.TrimSuffix(prefix, "/") + "/" + file.Filename
 	}
-	if config.Port == 0 {
-		config.Port = 6379
+
+	// Detect content type from file header
+	contentType := file.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	result := database.GetRedisClusterInfo(ctx, config)
-	return c.JSON(result)
+	if err := database.UploadS3Object(ctx, config, bucket, key, src, file.Size, contentType); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"success": true, "key": key, "size": file.Size})
 }
 
-func redisScanHandler(c *fiber.Ctx) error {
+func s3DownloadHandler(c *fiber.Ctx) error {
 	var req struct {
-		database.RedisConfig
-		Pattern string `json:"pattern"`
-		Cursor  uint64 `json:"cursor"`
-		Count   int64  `json:"count"`
+		database.S3Config
+		Bucket string `json:"bucket"`
+		Key    string `json:"key"`
 	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-	if req.Port == 0 {
-		req.Port = 6379
+
+	if req.Bucket == "" || req.Key == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "bucket and key are required"})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	result := database.ScanRedisKeys(ctx, req.RedisConfig, req.Pattern, req.Cursor, req.Count)
-	return c.JSON(result)
-}
-
-func redisKeyHandler(c *fiber.Ctx) error {
-	var req struct {
-		database.RedisConfig
-		Key string `json:"key"`
-	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
-	}
-	if req.Port == 0 {
-		req.Port = 6379
+	data, contentType, err := database.DownloadS3Object(ctx, req.S3Config, req.Bucket, req.Key)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// Extract filename from key
+	parts := strings.Split(req.Key, "/")
+	filename := parts[len(parts)-1]
 
-	result := database.GetRedisKeyValue(ctx, req.RedisConfig, req.Key)
-	return c.JSON(result)
+	c.Set("Content-Type", contentType)
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	return c.Send(data)
 }
 
-func redisCommandHandler(c *fiber.Ctx) error {
+func s3DeleteHandler(c *fiber.Ctx) error {
 	var req struct {
-		database.RedisConfig
-		Command string `json:"command"`
+		database.S3Config
+		Bucket string `json:"bucket"`
+		Key    string `json:"key"`
 	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-	if req.Port == 0 {
-		req.Port = 6379
+
+	if req.Bucket == "" || req.Key == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "bucket and key are required"})
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result := database.ExecuteRedisCommand(ctx, req.RedisConfig, req.Command)
-	return c.JSON(result)
-}
+	if err := database.DeleteS3Object(ctx, req.S3Config, req.Bucket, req.Key); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
 
-// MySQL handlers
+	return c.JSON(fiber.Map{"success": true, "message": "Object deleted"})
+}
 
-func mysqlConnectHandler(c *fiber.Ctx) error {
-	var config database.MySQLConfig
-	if err := c.BodyParser(&config); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+func s3PresignHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.S3Config
+		Bucket      string `json:"bucket"`
+		Key         string `json:"key"`
+		ExpiryHours int    `json:"expiry_hours"`
 	}
-	if config.Port == 0 {
-		config.Port = 3306
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	result := database.TestMySQLConnection(ctx, config)
-	return c.JSON(result)
-}
-
-func mysqlInfoHandler(c *fiber.Ctx) error {
-	var config database.MySQLConfig
-	if err := c.BodyParser(&config); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+	if req.Bucket == "" || req.Key == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "bucket and key are required"})
 	}
-	if config.Port == 0 {
-		config.Port = 3306
+
+	if req.ExpiryHours <= 0 {
+		req.ExpiryHours = 24
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result := database.GetMySQLInfo(ctx, config)
-	return c.JSON(result)
+	url, err := database.GetPresignedURL(ctx, req.S3Config, req.Bucket, req.Key, time.Duration(req.ExpiryHours)*time.Hour)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"url": url, "expires_in_hours": req.ExpiryHours})
 }
 
-func mysqlQueryHandler(c *fiber.Ctx) error {
+// Storage mount health handlers
+
+func nfsCheckHandler(c *fiber.Ctx) error {
 	var req struct {
-		database.MySQLConfig
-		Query    string `json:"query"`
-		ReadOnly bool   `json:"readonly"`
+		Host    string `json:"host"`
+		Export  string `json:"export"`
+		Timeout int    `json:"timeout"` // seconds
 	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-	if req.Port == 0 {
-		req.Port = 3306
+	if req.Host == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "host is required"})
+	}
+	if req.Timeout <= 0 || req.Timeout > 60 {
+		req.Timeout = 10
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	result := database.ExecuteMySQLQuery(ctx, req.MySQLConfig, req.Query, req.ReadOnly)
-	if result.Error != "" {
-		return c.Status(400).JSON(result)
-	}
+	result := network.CheckNFS(req.Host, req.Export, time.Duration(req.Timeout)*time.Second)
 	return c.JSON(result)
 }
 
-func mysqlDumpHandler(c *fiber.Ctx) error {
+func smbCheckHandler(c *fiber.Ctx) error {
 	var req struct {
-		database.MySQLConfig
-		SchemaOnly bool     `json:"schema_only"`
-		DataOnly   bool     `json:"data_only"`
-		Tables     []string `json:"tables"`
+		Host    string `json:"host"`
+		Port    int    `json:"port"`
+		Timeout int    `json:"timeout"` // seconds
 	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-	if req.Port == 0 {
-		req.Port = 3306
+	if req.Host == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "host is required"})
+	}
+	if req.Port < 0 || req.Port > 65535 {
+		return c.Status(400).JSON(fiber.Map{"error": "valid port (1-65535) is required"})
+	}
+	if req.Timeout <= 0 || req.Timeout > 60 {
+		req.Timeout = 10
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
-	result := database.DumpMySQL(ctx, req.MySQLConfig, req.SchemaOnly, req.DataOnly, req.Tables)
+	result := network.CheckSMB(req.Host, req.Port, time.Duration(req.Timeout)*time.Second)
 	return c.JSON(result)
 }
 
-func mysqlDatabasesHandler(c *fiber.Ctx) error {
-	var config database.MySQLConfig
-	if err := c.BodyParser(&config); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request"})
+func pathProbeHandler(c *fiber.Ctx) error {
+	var req struct {
+		Path string `json:"path"`
+		Size int    `json:"size"` // bytes
 	}
-	if config.Port == 0 {
-		config.Port = 3306
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	databases, err := database.GetMySQLDatabases(ctx, config)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	if req.Path == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "path is required"})
 	}
-	return c.JSON(fiber.Map{"databases": databases})
-}
 
-// Notification handlers
+	result := network.ProbePath(req.Path, req.Size)
+	return c.JSON(result)
+}
 
-func listNotificationsHandler(c *fiber.Ctx) error {
-	configs, err := cicd.ListNotificationConfigs()
+func mountMonitorsHandler(c *fiber.Ctx) error {
+	monitors, err := network.ListMountMonitors()
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{
-		"count":         len(configs),
-		"notifications": configs,
-	})
+	return c.JSON(fiber.Map{"monitors": monitors, "count": len(monitors)})
 }
 
-func createNotificationHandler(c *fiber.Ctx) error {
-	var config cicd.NotificationConfig
-	if err := c.BodyParser(&config); err != nil {
+func createMountMonitorHandler(c *fiber.Ctx) error {
+	var monitor network.MountMonitor
+	if err := c.BodyParser(&monitor); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	if config.Name == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "name is required"})
-	}
-	if config.URL == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "url is required"})
-	}
-
-	result, err := cicd.CreateNotificationConfig(&config)
+	created, err := network.CreateMountMonitor(&monitor)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.Status(201).JSON(result)
+	return c.Status(201).JSON(created)
 }
 
-func getNotificationHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	config, err := cicd.GetNotificationConfig(id)
+func mountMonitorHandler(c *fiber.Ctx) error {
+	status, err := network.GetMountMonitor(c.Params("id"))
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(config)
+	return c.JSON(status)
 }
 
-func updateNotificationHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var config cicd.NotificationConfig
-	if err := c.BodyParser(&config); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+func deleteMountMonitorHandler(c *fiber.Ctx) error {
+	if err := network.DeleteMountMonitor(c.Params("id")); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(fiber.Map{"success": true, "message": "Monitor deleted"})
+}
 
-	result, err := cicd.UpdateNotificationConfig(id, &config)
+func runMountMonitorHandler(c *fiber.Ctx) error {
+	check, err := network.RunMountMonitor(c.Params("id"))
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.JSON(result)
+	return c.JSON(check)
 }
 
-func deleteNotificationHandler(c *fiber.Ctx) error {
-	id := c.Params("id")
-	if err := cicd.DeleteNotificationConfig(id); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+// MinIO admin handlers
+
+// minioAdminError maps the MinIO availability errors to 404, the rest to 500
+func minioAdminError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, database.ErrMinIOAdminDisabled) || errors.Is(err, database.ErrNotMinIO) {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true})
+	return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 }
 
-func testNotificationHandler(c *fiber.Ctx) error {
-	var req struct {
-		URL     string            `json:"url"`
-		Secret  string            `json:"secret"`
-		Headers map[string]string `json:"headers"`
-	}
-	if err := c.BodyParser(&req); err != nil {
+func minioInfoHandler(c *fiber.Ctx) error {
+	var config database.S3Config
+	if err := c.BodyParser(&config); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	if req.URL == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "url is required"})
-	}
-
-	// Create and save a temporary test notification config
-	testConfig := &cicd.NotificationConfig{
-		Name:    "Test Notification",
-		Type:    cicd.NotificationTypeWebhook,
-		Enabled: true,
-		URL:     req.URL,
-		Secret:  req.Secret,
-		Headers: req.Headers,
-		Events:  []cicd.NotificationEvent{cicd.NotificationEventBuildSucceeded},
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	// Create the config (this also loads it into memory)
-	created, err := cicd.CreateNotificationConfig(testConfig)
+	info, err := database.GetMinIOServerInfo(ctx, config)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "failed to create test config: " + err.Error()})
-	}
-
-	// Create a test payload
-	testBuild := &cicd.FreestyleBuild{
-		ID:          "test-build",
-		JobID:       "test-job",
-		JobName:     "Test Job",
-		BuildNumber: 1,
-		Status:      cicd.RunStatusSucceeded,
-		TriggerType: "test",
+		return minioAdminError(c, err)
 	}
-
-	// Send test notification (this will use the config we just created)
-	cicd.NotifyBuildEvent(cicd.NotificationEventBuildSucceeded, testBuild)
-
-	// Clean up the test config
-	cicd.DeleteNotificationConfig(created.ID)
-
-	return c.JSON(fiber.Map{
-		"success": true,
-		"message": "Test notification sent to " + req.URL,
-	})
+	return c.JSON(info)
 }
 
-// S3 Storage handlers
-
-func s3ConnectHandler(c *fiber.Ctx) error {
+func minioHealStatusHandler(c *fiber.Ctx) error {
 	var config database.S3Config
 	if err := c.BodyParser(&config); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	if config.Endpoint == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "endpoint is required"})
-	}
-	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "access key and secret key are required"})
-	}
-
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result := database.TestS3Connection(ctx, config)
-	return c.JSON(result)
+	status, err := database.GetMinIOHealStatus(ctx, config)
+	if err != nil {
+		return minioAdminError(c, err)
+	}
+	return c.JSON(status)
 }
 
-func s3ListBucketsHandler(c *fiber.Ctx) error {
+func minioUsersHandler(c *fiber.Ctx) error {
 	var config database.S3Config
 	if err := c.BodyParser(&config); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
@@ -3687,256 +7817,282 @@ func s3ListBucketsHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	buckets, err := database.ListS3Buckets(ctx, config)
+	users, err := database.ListMinIOUsers(ctx, config)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return minioAdminError(c, err)
 	}
-
-	return c.JSON(fiber.Map{"buckets": buckets})
+	return c.JSON(fiber.Map{"users": users, "count": len(users)})
 }
 
-func s3CreateBucketHandler(c *fiber.Ctx) error {
+func minioUserHandler(c *fiber.Ctx) error {
 	var req struct {
 		database.S3Config
-		Bucket string `json:"bucket"`
+		User string `json:"user"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-
-	if req.Bucket == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "bucket name is required"})
+	if req.User == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user is required"})
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := database.CreateS3Bucket(ctx, req.S3Config, req.Bucket); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	user, err := database.GetMinIOUser(ctx, req.S3Config, req.User)
+	if err != nil {
+		return minioAdminError(c, err)
 	}
-
-	return c.JSON(fiber.Map{"success": true, "message": "Bucket created"})
+	return c.JSON(user)
 }
 
-func s3DeleteBucketHandler(c *fiber.Ctx) error {
+func minioAddUserHandler(c *fiber.Ctx) error {
 	var req struct {
 		database.S3Config
-		Bucket string `json:"bucket"`
+		User       string `json:"user"`
+		UserSecret string `json:"user_secret"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-
-	if req.Bucket == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "bucket name is required"})
+	if req.User == "" || req.UserSecret == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user and user_secret are required"})
+	}
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to manage MinIO users", database.ErrElevationRequired))
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := database.DeleteS3Bucket(ctx, req.S3Config, req.Bucket); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	if err := database.AddMinIOUser(ctx, req.S3Config, req.User, req.UserSecret); err != nil {
+		return minioAdminError(c, err)
 	}
-
-	return c.JSON(fiber.Map{"success": true, "message": "Bucket deleted"})
+	log.Info().Str("endpoint", req.Endpoint).Str("user", req.User).Str("ip", c.IP()).Msg("MinIO user added")
+	return c.JSON(fiber.Map{"success": true, "message": "User added"})
 }
 
-func s3ListObjectsHandler(c *fiber.Ctx) error {
+func minioUserStatusHandler(c *fiber.Ctx) error {
 	var req struct {
 		database.S3Config
-		Bucket  string `json:"bucket"`
-		Prefix  string `json:"prefix"`
-		MaxKeys int    `json:"max_keys"`
+		User    string `json:"user"`
+		Enabled bool   `json:"enabled"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-
-	if req.Bucket == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "bucket is required"})
+	if req.User == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user is required"})
 	}
-
-	if req.MaxKeys <= 0 {
-		req.MaxKeys = 1000
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to manage MinIO users", database.ErrElevationRequired))
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	objects, err := database.ListS3Objects(ctx, req.S3Config, req.Bucket, req.Prefix, req.MaxKeys)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	if err := database.SetMinIOUserStatus(ctx, req.S3Config, req.User, req.Enabled); err != nil {
+		return minioAdminError(c, err)
 	}
-
-	return c.JSON(fiber.Map{"objects": objects, "bucket": req.Bucket, "prefix": req.Prefix})
+	log.Info().Str("endpoint", req.Endpoint).Str("user", req.User).Bool("enabled", req.Enabled).Str("ip", c.IP()).Msg("MinIO user status changed")
+	return c.JSON(fiber.Map{"success": true, "message": "User status changed"})
 }
 
-func s3ObjectInfoHandler(c *fiber.Ctx) error {
+func minioRemoveUserHandler(c *fiber.Ctx) error {
 	var req struct {
 		database.S3Config
-		Bucket string `json:"bucket"`
-		Key    string `json:"key"`
+		User string `json:"user"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-
-	if req.Bucket == "" || req.Key == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "bucket and key are required"})
+	if req.User == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user is required"})
+	}
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to manage MinIO users", database.ErrElevationRequired))
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	info, err := database.GetS3ObjectInfo(ctx, req.S3Config, req.Bucket, req.Key)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	if err := database.RemoveMinIOUser(ctx, req.S3Config, req.User); err != nil {
+		return minioAdminError(c, err)
 	}
-
-	return c.JSON(info)
+	log.Info().Str("endpoint", req.Endpoint).Str("user", req.User).Str("ip", c.IP()).Msg("MinIO user removed")
+	return c.JSON(fiber.Map{"success": true, "message": "User removed"})
 }
 
-func s3UploadHandler(c *fiber.Ctx) error {
-	// Get config from form fields
-	config := database.S3Config{
-		Endpoint:        c.FormValue("endpoint"),
-		Region:          c.FormValue("region"),
-		AccessKeyID:     c.FormValue("access_key_id"),
-		SecretAccessKey: c.FormValue("secret_access_key"),
-		UseSSL:          c.FormValue("use_ssl") == "true",
+func minioPoliciesHandler(c *fiber.Ctx) error {
+	var config database.S3Config
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	bucket := c.FormValue("bucket")
-	prefix := c.FormValue("prefix")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	if bucket == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "bucket is required"})
+	policies, err := database.ListMinIOPolicies(ctx, config)
+	if err != nil {
+		return minioAdminError(c, err)
 	}
+	return c.JSON(fiber.Map{"policies": policies, "count": len(policies)})
+}
 
-	// Get the file from the request
-	file, err := c.FormFile("file")
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "file is required"})
+func minioPolicyHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.S3Config
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name is required"})
 	}
 
-	// Open the file
-	src, err := file.Open()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	policy, err := database.GetMinIOPolicy(ctx, req.S3Config, req.Name)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "failed to open file"})
+		return minioAdminError(c, err)
 	}
-	defer src.Close()
+	return c.JSON(fiber.Map{"name": req.Name, "policy": policy})
+}
 
-	// Read file content
-	data, err := io.ReadAll(src)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "failed to read file"})
+func minioAddPolicyHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.S3Config
+		Name   string          `json:"name"`
+		Policy json.RawMessage `json:"policy"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Name == "" || len(req.Policy) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "name and policy are required"})
+	}
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to manage MinIO policies", database.ErrElevationRequired))
 	}
 
-	// Build the key (prefix + filename)
-	key := file.Filename
-	if prefix != "" {
-		key = strings.TrimSuffix(prefix, "/") + "/" + file.Filename
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := database.AddMinIOPolicy(ctx, req.S3Config, req.Name, req.Policy); err != nil {
+		return minioAdminError(c, err)
 	}
+	log.Info().Str("endpoint", req.Endpoint).Str("policy", req.Name).Str("ip", c.IP()).Msg("MinIO policy saved")
+	return c.JSON(fiber.Map{"success": true, "message": "Policy saved"})
+}
 
-	// Detect content type from file header
-	contentType := file.Header.Get("Content-Type")
-	if contentType == "" {
-		contentType = "application/octet-stream"
+func minioRemovePolicyHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.S3Config
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name is required"})
+	}
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to manage MinIO policies", database.ErrElevationRequired))
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := database.UploadS3Object(ctx, config, bucket, key, data, contentType); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	if err := database.RemoveMinIOPolicy(ctx, req.S3Config, req.Name); err != nil {
+		return minioAdminError(c, err)
 	}
-
-	return c.JSON(fiber.Map{"success": true, "key": key, "size": len(data)})
+	log.Info().Str("endpoint", req.Endpoint).Str("policy", req.Name).Str("ip", c.IP()).Msg("MinIO policy removed")
+	return c.JSON(fiber.Map{"success": true, "message": "Policy removed"})
 }
 
-func s3DownloadHandler(c *fiber.Ctx) error {
+// minioSetPolicyHandler replaces the policies of a user, or of a group when
+// "group" is set
+func minioSetPolicyHandler(c *fiber.Ctx) error {
 	var req struct {
 		database.S3Config
-		Bucket string `json:"bucket"`
-		Key    string `json:"key"`
+		Policies []string `json:"policies"`
+		User     string   `json:"user"`
+		Group    string   `json:"group"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-
-	if req.Bucket == "" || req.Key == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "bucket and key are required"})
+	target, isGroup := req.User, false
+	if req.Group != "" {
+		target, isGroup = req.Group, true
+	}
+	if target == "" || len(req.Policies) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "policies and a user or group are required"})
+	}
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to manage MinIO policies", database.ErrElevationRequired))
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	data, contentType, err := database.DownloadS3Object(ctx, req.S3Config, req.Bucket, req.Key)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	policies := strings.Join(req.Policies, ",")
+	if err := database.SetMinIOPolicy(ctx, req.S3Config, policies, target, isGroup); err != nil {
+		return minioAdminError(c, err)
 	}
-
-	// Extract filename from key
-	parts := strings.Split(req.Key, "/")
-	filename := parts[len(parts)-1]
-
-	c.Set("Content-Type", contentType)
-	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
-	return c.Send(data)
+	log.Info().Str("endpoint", req.Endpoint).Str("target", target).Bool("group", isGroup).Str("policies", policies).Str("ip", c.IP()).Msg("MinIO policy set")
+	return c.JSON(fiber.Map{"success": true, "message": "Policy set"})
 }
 
-func s3DeleteHandler(c *fiber.Ctx) error {
+func minioQuotaHandler(c *fiber.Ctx) error {
 	var req struct {
 		database.S3Config
 		Bucket string `json:"bucket"`
-		Key    string `json:"key"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-
-	if req.Bucket == "" || req.Key == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "bucket and key are required"})
+	if req.Bucket == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "bucket is required"})
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := database.DeleteS3Object(ctx, req.S3Config, req.Bucket, req.Key); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	quota, err := database.GetMinIOBucketQuota(ctx, req.S3Config, req.Bucket)
+	if err != nil {
+		return minioAdminError(c, err)
 	}
-
-	return c.JSON(fiber.Map{"success": true, "message": "Object deleted"})
+	return c.JSON(fiber.Map{"bucket": req.Bucket, "quota": quota})
 }
 
-func s3PresignHandler(c *fiber.Ctx) error {
+// minioSetQuotaHandler sets a hard quota in bytes on a bucket; 0 clears it
+func minioSetQuotaHandler(c *fiber.Ctx) error {
 	var req struct {
 		database.S3Config
-		Bucket      string `json:"bucket"`
-		Key         string `json:"key"`
-		ExpiryHours int    `json:"expiry_hours"`
+		Bucket string `json:"bucket"`
+		Quota  uint64 `json:"quota"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
-
-	if req.Bucket == "" || req.Key == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "bucket and key are required"})
+	if req.Bucket == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "bucket is required"})
 	}
-
-	if req.ExpiryHours <= 0 {
-		req.ExpiryHours = 24
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to set a bucket quota", database.ErrElevationRequired))
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	url, err := database.GetPresignedURL(ctx, req.S3Config, req.Bucket, req.Key, time.Duration(req.ExpiryHours)*time.Hour)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	if err := database.SetMinIOBucketQuota(ctx, req.S3Config, req.Bucket, req.Quota); err != nil {
+		return minioAdminError(c, err)
 	}
-
-	return c.JSON(fiber.Map{"url": url, "expires_in_hours": req.ExpiryHours})
+	log.Info().Str("endpoint", req.Endpoint).Str("bucket", req.Bucket).Uint64("quota", req.Quota).Str("ip", c.IP()).Msg("MinIO bucket quota set")
+	return c.JSON(fiber.Map{"success": true, "message": "Quota set"})
 }
 
 // Elasticsearch handlers
@@ -3950,7 +8106,7 @@ func esConnectHandler(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	result := database.TestESConnection(ctx, config)
+	result := database.CachedESConnection(ctx, config, c.QueryBool("fresh", false))
 	return c.JSON(result)
 }
 
@@ -4237,6 +8393,11 @@ func esQueryHandler(c *fiber.Ctx) error {
 	if req.Path == "" {
 		req.Path = "/"
 	}
+	if !auth.IsElevated(c) {
+		if err := database.CheckESRequest(req.Method, req.Path); err != nil {
+			return queryGuardError(c, err)
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
@@ -4249,6 +8410,313 @@ func esQueryHandler(c *fiber.Ctx) error {
 	return c.JSON(result)
 }
 
+func esReindexHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.ESConfig
+		database.ESReindexRequest
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Source == "" || req.Dest == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "source and dest are required"})
+	}
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to reindex", database.ErrElevationRequired))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	task, err := database.StartESReindex(ctx, req.ESConfig, req.ESReindexRequest)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("source", req.Source).Str("dest", req.Dest).Str("task", task).Str("ip", c.IP()).Msg("Elasticsearch reindex started")
+	return c.JSON(fiber.Map{"success": true, "task": task})
+}
+
+// esTasksHandler lists running tasks, optionally only those whose action
+// matches actions, e.g. *reindex,*snapshot*
+func esTasksHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.ESConfig
+		Actions string `json:"actions"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tasks, err := database.ListESTasks(ctx, req.ESConfig, req.Actions)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"count": len(tasks), "tasks": tasks})
+}
+
+func esTaskHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.ESConfig
+		Task string `json:"task"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Task == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "task is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	status, err := database.GetESTask(ctx, req.ESConfig, req.Task)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(status)
+}
+
+func esCancelTaskHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.ESConfig
+		Task string `json:"task"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Task == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "task is required"})
+	}
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to cancel tasks", database.ErrElevationRequired))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := database.CancelESTask(ctx, req.ESConfig, req.Task); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("task", req.Task).Str("ip", c.IP()).Msg("Elasticsearch task cancelled")
+	return c.JSON(fiber.Map{"success": true, "message": "Task cancellation requested"})
+}
+
+func esSnapshotRepositoriesHandler(c *fiber.Ctx) error {
+	var config database.ESConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	repos, err := database.ListESSnapshotRepositories(ctx, config)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"count": len(repos), "repositories": repos})
+}
+
+func esCreateSnapshotRepositoryHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.ESConfig
+		Repository database.ESSnapshotRepository `json:"repository"`
+		Verify     *bool                         `json:"verify"` // default true
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Repository.Name == "" || req.Repository.Type == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "repository name and type are required"})
+	}
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to change snapshot repositories", database.ErrElevationRequired))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if err := database.CreateESSnapshotRepository(ctx, req.ESConfig, req.Repository, req.Verify == nil || *req.Verify); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("repository", req.Repository.Name).Str("type", req.Repository.Type).Str("ip", c.IP()).Msg("Elasticsearch snapshot repository saved")
+	return c.JSON(fiber.Map{"success": true, "message": "Repository saved"})
+}
+
+func esVerifySnapshotRepositoryHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.ESConfig
+		Repository string `json:"repository"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Repository == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "repository is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	nodes, err := database.VerifyESSnapshotRepository(ctx, req.ESConfig, req.Repository)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "nodes": nodes})
+}
+
+func esDeleteSnapshotRepositoryHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.ESConfig
+		Repository string `json:"repository"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Repository == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "repository is required"})
+	}
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to change snapshot repositories", database.ErrElevationRequired))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := database.DeleteESSnapshotRepository(ctx, req.ESConfig, req.Repository); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("repository", req.Repository).Str("ip", c.IP()).Msg("Elasticsearch snapshot repository deleted")
+	return c.JSON(fiber.Map{"success": true, "message": "Repository deleted"})
+}
+
+func esSnapshotsHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.ESConfig
+		Repository string `json:"repository"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Repository == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "repository is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	snapshots, err := database.ListESSnapshots(ctx, req.ESConfig, req.Repository)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"count": len(snapshots), "snapshots": snapshots})
+}
+
+func esCreateSnapshotHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.ESConfig
+		database.ESSnapshotRequest
+		Repository string `json:"repository"`
+		Snapshot   string `json:"snapshot"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Repository == "" || req.Snapshot == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "repository and snapshot are required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := database.CreateESSnapshot(ctx, req.ESConfig, req.Repository, req.Snapshot, req.ESSnapshotRequest); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("repository", req.Repository).Str("snapshot", req.Snapshot).Str("ip", c.IP()).Msg("Elasticsearch snapshot started")
+	return c.JSON(fiber.Map{"success": true, "message": "Snapshot started"})
+}
+
+func esSnapshotStatusHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.ESConfig
+		Repository string `json:"repository"`
+		Snapshot   string `json:"snapshot"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Repository == "" || req.Snapshot == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "repository and snapshot are required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	status, err := database.GetESSnapshotStatus(ctx, req.ESConfig, req.Repository, req.Snapshot)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Set("Content-Type", "application/json")
+	return c.Send(status)
+}
+
+func esRestoreSnapshotHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.ESConfig
+		database.ESRestoreRequest
+		Repository string `json:"repository"`
+		Snapshot   string `json:"snapshot"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Repository == "" || req.Snapshot == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "repository and snapshot are required"})
+	}
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to restore snapshots", database.ErrElevationRequired))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := database.RestoreESSnapshot(ctx, req.ESConfig, req.Repository, req.Snapshot, req.ESRestoreRequest); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("repository", req.Repository).Str("snapshot", req.Snapshot).Strs("indices", req.Indices).
+		Str("ip", c.IP()).Msg("Elasticsearch snapshot restore started")
+	return c.JSON(fiber.Map{"success": true, "message": "Restore started"})
+}
+
+func esDeleteSnapshotHandler(c *fiber.Ctx) error {
+	var req struct {
+		database.ESConfig
+		Repository string `json:"repository"`
+		Snapshot   string `json:"snapshot"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Repository == "" || req.Snapshot == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "repository and snapshot are required"})
+	}
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to delete snapshots", database.ErrElevationRequired))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if err := database.DeleteESSnapshot(ctx, req.ESConfig, req.Repository, req.Snapshot); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("repository", req.Repository).Str("snapshot", req.Snapshot).Str("ip", c.IP()).Msg("Elasticsearch snapshot deleted")
+	return c.JSON(fiber.Map{"success": true, "message": "Snapshot deleted"})
+}
+
 // Helper functions
 
 func getEnv(key, defaultValue string) string {
@@ -0,0 +1,11 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !fake
+
+package main
+
+// startFakeBackends is a no-op without the fake build tag
+func startFakeBackends() bool { return false }
+
+func stopFakeBackends() {}
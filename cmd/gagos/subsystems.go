@@ -0,0 +1,135 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gaga951/gagos/internal/auth"
+	"github.com/gaga951/gagos/internal/cicd"
+	"github.com/gaga951/gagos/internal/database"
+	"github.com/gaga951/gagos/internal/k8s"
+	"github.com/gaga951/gagos/internal/monitoring"
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/gaga951/gagos/internal/subsystems"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// k8sPingTimeout bounds the API server health check
+const k8sPingTimeout = 5 * time.Second
+
+// fakeBackends is set when the fake backends replaced the Kubernetes client
+// and storage
+var fakeBackends bool
+
+// registerSubsystems registers the subsystems that need an outside
+// dependency, dependencies first
+func registerSubsystems() {
+	subsystems.Register(subsystems.Subsystem{
+		Name:        subsystems.Kubernetes,
+		Description: "Kubernetes API client",
+		Init:        initKubernetes,
+		Check:       func() error { return k8s.Ping(k8sPingTimeout) },
+	})
+	subsystems.Register(subsystems.Subsystem{
+		Name:        subsystems.Storage,
+		Description: "Storage backend (" + getEnv("GAGOS_STORAGE_TYPE", storage.StorageTypeBBolt) + ")",
+		Init:        storage.Init,
+	})
+	subsystems.Register(subsystems.Subsystem{
+		Name:        subsystems.Scheduler,
+		Description: "CI/CD cron triggers, cleanup and queued runs",
+		DependsOn:   []string{subsystems.Storage},
+		Init:        func() error { return cicd.InitScheduler().Start() },
+	})
+	subsystems.Register(subsystems.Subsystem{
+		Name:        subsystems.Notifications,
+		Description: "CI/CD notification configs",
+		DependsOn:   []string{subsystems.Storage},
+		Init:        cicd.LoadNotificationConfigs,
+	})
+	subsystems.Register(subsystems.Subsystem{
+		Name:        subsystems.Monitoring,
+		Description: "Cluster monitoring and volume alerts",
+		DependsOn:   []string{subsystems.Kubernetes},
+		Init: func() error {
+			if err := monitoring.Init(); err != nil {
+				return err
+			}
+			monitoring.StartVolumeAlerts()
+			return nil
+		},
+	})
+}
+
+// initKubernetes creates the Kubernetes client, and enables the informer
+// cache with GAGOS_K8S_CACHE
+func initKubernetes() error {
+	if fakeBackends {
+		return nil
+	}
+	if err := k8s.InitClient(); err != nil {
+		return err
+	}
+	if getEnv("GAGOS_K8S_CACHE", "false") == "true" && !k8s.CacheEnabled() {
+		resync, err := time.ParseDuration(getEnv("GAGOS_K8S_CACHE_RESYNC", "10m"))
+		if err != nil {
+			log.Warn().Err(err).Msg("Invalid GAGOS_K8S_CACHE_RESYNC, using 10m")
+			resync = 10 * time.Minute
+		}
+		if err := k8s.InitCache(resync); err != nil {
+			log.Warn().Err(err).Msg("Failed to enable Kubernetes informer cache")
+		} else {
+			log.Info().Dur("resync", resync).Msg("Kubernetes informer cache enabled")
+		}
+	}
+	return nil
+}
+
+// subsystemGate answers 503 while a subsystem the routes need is not ready,
+// instead of letting handlers fail on a missing client
+func subsystemGate(name string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if subsystems.Ready(name) {
+			return c.Next()
+		}
+		status, _ := subsystems.Get(name)
+		resp := fiber.Map{
+			"error":     subsystems.UnavailableError(name).Error(),
+			"subsystem": name,
+		}
+		if status != nil && status.NextRetry != nil {
+			resp["next_retry"] = status.NextRetry
+			if wait := int(time.Until(*status.NextRetry).Seconds()) + 1; wait > 0 {
+				c.Set(fiber.HeaderRetryAfter, fmt.Sprint(wait))
+			}
+		}
+		return c.Status(fiber.StatusServiceUnavailable).JSON(resp)
+	}
+}
+
+func listSubsystemsHandler(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"subsystems": subsystems.List()})
+}
+
+// reinitSubsystemHandler starts a subsystem again at once, e.g. after the
+// Kubernetes API server or the database came back
+func reinitSubsystemHandler(c *fiber.Ctx) error {
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to re-initialize a subsystem", database.ErrElevationRequired))
+	}
+	name := c.Params("name")
+	status, err := subsystems.Reinit(name)
+	if errors.Is(err, subsystems.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Info().Str("subsystem", name).Str("state", string(status.State)).Str("ip", c.IP()).Msg("Subsystem re-initialized")
+	return c.JSON(status)
+}
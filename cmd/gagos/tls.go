@@ -0,0 +1,51 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"github.com/gaga951/gagos/internal/servertls"
+)
+
+// defaultHSTSMaxAge is one year, in seconds
+const defaultHSTSMaxAge = 31536000
+
+// hstsMiddleware tells browsers to use HTTPS only, for GAGOS_HSTS_MAX_AGE
+// seconds (0 disables it)
+func hstsMiddleware() fiber.Handler {
+	maxAge, err := strconv.Atoi(getEnv("GAGOS_HSTS_MAX_AGE", strconv.Itoa(defaultHSTSMaxAge)))
+	if err != nil || maxAge < 0 {
+		log.Warn().Str("value", getEnv("GAGOS_HSTS_MAX_AGE", "")).Msg("Invalid GAGOS_HSTS_MAX_AGE, using default")
+		maxAge = defaultHSTSMaxAge
+	}
+	header := fmt.Sprintf("max-age=%d", maxAge)
+	return func(c *fiber.Ctx) error {
+		if maxAge > 0 && c.Protocol() == "https" {
+			c.Set(fiber.HeaderStrictTransportSecurity, header)
+		}
+		return c.Next()
+	}
+}
+
+// listenTLS serves app on addr over TLS, with the certificate reloaded as it
+// changes
+func listenTLS(app *fiber.App, addr string, cfg *servertls.Config) error {
+	tlsConfig, err := cfg.TLSConfig(context.Background())
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen(fiber.NetworkTCP4, addr)
+	if err != nil {
+		return err
+	}
+	return app.Listener(tls.NewListener(ln, tlsConfig))
+}
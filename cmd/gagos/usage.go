@@ -0,0 +1,59 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/gaga951/gagos/internal/auth"
+	"github.com/gaga951/gagos/internal/database"
+	"github.com/gaga951/gagos/internal/usage"
+	"github.com/gofiber/fiber/v2"
+)
+
+// usageMiddleware counts each API call by its route, so that calls with
+// different parameters add up to one endpoint
+func usageMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		route := c.Route().Path
+		if route == "/api/health" {
+			return err
+		}
+		session := ""
+		if token := c.Cookies("gagos_session"); len(token) >= auditSessionPrefix {
+			session = token[:auditSessionPrefix]
+		}
+		failed := err != nil || c.Response().StatusCode() >= 400
+		usage.Record(c.Method(), route, usage.ActorID(session, c.IP()), failed)
+		return err
+	}
+}
+
+// usageReportHandler reports which features and endpoints were used over
+// the last ?days= days (default 30), by whom, and which were not used at
+// all. It names clients, so it needs an elevated session.
+func usageReportHandler(c *fiber.Ctx) error {
+	if !auth.IsElevated(c) {
+		return queryGuardError(c, fmt.Errorf("%w to view usage analytics", database.ErrElevationRequired))
+	}
+	if !usage.Enabled() {
+		return c.Status(404).JSON(fiber.Map{"error": "usage analytics are disabled (GAGOS_USAGE_ANALYTICS)"})
+	}
+
+	var known []string
+	for _, route := range c.App().GetRoutes(true) {
+		if route.Method == fiber.MethodHead || route.Method == fiber.MethodOptions {
+			continue
+		}
+		known = append(known, route.Method+" "+route.Path)
+	}
+
+	report, err := usage.GetReport(c.QueryInt("days", usage.DefaultDays), known)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
@@ -0,0 +1,231 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+// Package audit keeps a persistent record of the changes made to the
+// cluster through GAGOS: who made them, what they touched, when, and what
+// changed.
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// MaxBodyBytes is how much of a request body an entry keeps
+const MaxBodyBytes = 16 << 10
+
+// Query limits
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// pruneInterval is how often Record drops entries past the retention
+const pruneInterval = time.Hour
+
+// Actor is who made a change. GAGOS has a single shared password, so the
+// actor is the client and its session rather than a user name.
+type Actor struct {
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Session   string `json:"session,omitempty"` // first characters of the session token
+	Elevated  bool   `json:"elevated"`
+}
+
+// Change is one field a patch changed
+type Change struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Entry is one recorded action
+type Entry struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Actor     Actor     `json:"actor"`
+	Action    string    `json:"action"` // delete, patch, scale, restart, ...
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+	Body      string    `json:"body,omitempty"`
+	Truncated bool      `json:"truncated,omitempty"` // Body was cut at MaxBodyBytes
+	Changes   []Change  `json:"changes,omitempty"`
+	Unified   string    `json:"unified,omitempty"`
+	Duration  float64   `json:"duration_ms"`
+}
+
+// Filter selects entries; empty fields match everything
+type Filter struct {
+	Since     time.Time
+	Until     time.Time
+	Action    string
+	Kind      string
+	Namespace string
+	Name      string
+	IP        string
+	Failed    bool // only actions that failed
+	Limit     int
+}
+
+var (
+	pruneMu   sync.Mutex
+	lastPrune time.Time
+)
+
+// Retention is how long entries are kept (GAGOS_AUDIT_RETENTION_DAYS,
+// default 90; 0 keeps them forever)
+func Retention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("GAGOS_AUDIT_RETENTION_DAYS"))
+	if err != nil || days < 0 {
+		days = 90
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Record stores an entry, filling in its ID and time
+func Record(e *Entry) {
+	if storage.GetBackend() == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.ID = entryID(e.Time)
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	if err := storage.GetBackend().Set(storage.BucketAuditLog, e.ID, data); err != nil {
+		log.Error().Err(err).Str("action", e.Action).Str("path", e.Path).Msg("Failed to save audit entry")
+	}
+	go pruneExpired()
+}
+
+// Get returns an entry by ID
+func Get(id string) (*Entry, error) {
+	data, err := storage.GetBackend().Get(storage.BucketAuditLog, id)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("audit entry not found: %s", id)
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Query returns the entries matching f, most recent first
+func Query(f Filter) ([]Entry, error) {
+	if f.Limit <= 0 {
+		f.Limit = DefaultLimit
+	}
+	if f.Limit > MaxLimit {
+		f.Limit = MaxLimit
+	}
+
+	dataList, err := storage.GetBackend().List(storage.BucketAuditLog)
+	if err != nil {
+		return nil, err
+	}
+	entries := []Entry{}
+	for _, data := range dataList {
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			continue
+		}
+		if f.matches(&e) {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID > entries[j].ID
+	})
+	if len(entries) > f.Limit {
+		entries = entries[:f.Limit]
+	}
+	return entries, nil
+}
+
+func (f Filter) matches(e *Entry) bool {
+	switch {
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && e.Time.After(f.Until):
+		return false
+	case f.Action != "" && !strings.EqualFold(e.Action, f.Action):
+		return false
+	case f.Kind != "" && !strings.EqualFold(e.Kind, f.Kind):
+		return false
+	case f.Namespace != "" && e.Namespace != f.Namespace:
+		return false
+	case f.Name != "" && !strings.Contains(e.Name, f.Name):
+		return false
+	case f.IP != "" && e.Actor.IP != f.IP:
+		return false
+	case f.Failed && e.Success:
+		return false
+	}
+	return true
+}
+
+// entryID sorts by time: the timestamp in nanoseconds, then random bytes
+// for entries recorded in the same instant
+func entryID(t time.Time) string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%019d-%s", t.UnixNano(), hex.EncodeToString(b))
+}
+
+// pruneExpired deletes entries older than the retention, at most once per
+// pruneInterval
+func pruneExpired() {
+	retention := Retention()
+	if retention == 0 {
+		return
+	}
+	pruneMu.Lock()
+	defer pruneMu.Unlock()
+	if time.Since(lastPrune) < pruneInterval {
+		return
+	}
+	lastPrune = time.Now()
+
+	keys, err := storage.GetBackend().ListKeys(storage.BucketAuditLog)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list audit entries")
+		return
+	}
+	cutoff := fmt.Sprintf("%019d", time.Now().Add(-retention).UnixNano())
+	pruned := 0
+	for _, key := range keys {
+		if key < cutoff {
+			if err := storage.GetBackend().Delete(storage.BucketAuditLog, key); err == nil {
+				pruned++
+			}
+		}
+	}
+	if pruned > 0 {
+		log.Info().Int("entries", pruned).Msg("Pruned audit log")
+	}
+}
@@ -21,6 +21,9 @@ var (
 	sessions     = make(map[string]time.Time)
 	sessionMutex sync.RWMutex
 	sessionTTL   = 24 * time.Hour
+
+	clientCerts       bool // the TLS listener verifies client certificates
+	requireClientCert bool
 )
 
 // Init initializes the auth package with password from environment
@@ -81,16 +84,46 @@ func DeleteSession(token string) {
 	sessionMutex.Unlock()
 }
 
+// EnableClientCerts lets a verified client certificate authenticate a
+// request in place of a session. With require, every request but the health
+// check needs one.
+func EnableClientCerts(require bool) {
+	clientCerts = true
+	requireClientCert = require
+	log.Info().Bool("required", require).Msg("Client certificate authentication enabled")
+}
+
+// ClientCertSubject returns the subject of the request's verified client
+// certificate, empty when there is none
+func ClientCertSubject(c *fiber.Ctx) string {
+	state := c.Context().TLSConnectionState()
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return state.VerifiedChains[0][0].Subject.String()
+}
+
 // Middleware returns a Fiber middleware that enforces authentication
 func Middleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		path := c.Path()
+
+		if clientCerts {
+			if ClientCertSubject(c) != "" {
+				return c.Next()
+			}
+			if requireClientCert && path != "/api/health" {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "client certificate required",
+				})
+			}
+		}
+
 		// If auth is disabled, allow all requests
 		if !IsEnabled() {
 			return c.Next()
 		}
 
-		path := c.Path()
-
 		// Allow public endpoints (health checks, login, runtime info)
 		publicPaths := []string{
 			"/api/health",
@@ -141,4 +174,5 @@ func CleanupExpiredSessions() {
 			delete(sessions, token)
 		}
 	}
+	cleanupExpiredElevations(now)
 }
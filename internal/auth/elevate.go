@@ -0,0 +1,88 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"crypto/subtle"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ElevationCookie holds the token granted by Elevate
+const ElevationCookie = "gagos_elevation"
+
+// ElevationTTL is how long an elevation lasts before it must be renewed
+const ElevationTTL = 15 * time.Minute
+
+var (
+	elevations     = make(map[string]time.Time)
+	elevationMutex sync.RWMutex
+)
+
+// adminPassword guards write and destructive operations. It falls back to
+// GAGOS_PASSWORD so enabling auth alone still requires re-entering it.
+func adminPassword() string {
+	if p := os.Getenv("GAGOS_ADMIN_PASSWORD"); p != "" {
+		return p
+	}
+	return password
+}
+
+// ElevationRequired reports whether dangerous operations need an elevation.
+// With neither GAGOS_PASSWORD nor GAGOS_ADMIN_PASSWORD set there is nothing
+// to elevate with, so every caller is treated as elevated.
+func ElevationRequired() bool {
+	return adminPassword() != ""
+}
+
+// Elevate checks input against the admin password and returns a token that
+// marks the caller as elevated until expiry
+func Elevate(input string) (token string, expiry time.Time, ok bool) {
+	admin := adminPassword()
+	if admin == "" || subtle.ConstantTimeCompare([]byte(input), []byte(admin)) != 1 {
+		return "", time.Time{}, false
+	}
+	token = GenerateToken()
+	expiry = time.Now().Add(ElevationTTL)
+	elevationMutex.Lock()
+	elevations[token] = expiry
+	elevationMutex.Unlock()
+	return token, expiry, true
+}
+
+// DropElevation revokes an elevation token
+func DropElevation(token string) {
+	elevationMutex.Lock()
+	delete(elevations, token)
+	elevationMutex.Unlock()
+}
+
+// IsElevated reports whether the request carries a live elevation token
+func IsElevated(c *fiber.Ctx) bool {
+	if !ElevationRequired() {
+		return true
+	}
+	token := c.Cookies(ElevationCookie)
+	if token == "" {
+		return false
+	}
+	elevationMutex.RLock()
+	expiry, exists := elevations[token]
+	elevationMutex.RUnlock()
+	return exists && time.Now().Before(expiry)
+}
+
+// cleanupExpiredElevations is called from CleanupExpiredSessions
+func cleanupExpiredElevations(now time.Time) {
+	elevationMutex.Lock()
+	defer elevationMutex.Unlock()
+	for token, expiry := range elevations {
+		if now.After(expiry) {
+			delete(elevations, token)
+		}
+	}
+}
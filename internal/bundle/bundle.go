@@ -0,0 +1,263 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+// Package bundle fetches a directory tree to work on, either by unpacking an
+// uploaded archive or by shallow-cloning a Git repository, into a temporary
+// directory the caller removes.
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gaga951/gagos/internal/egress"
+)
+
+// MaxSize bounds an uploaded archive and what it unpacks to
+const MaxSize = 32 << 20
+
+// Extract unpacks a .zip, .tar.gz, .tgz or .tar archive into dir, keeping only
+// the regular files keep accepts (all when nil), and returns the archive's
+// SHA-256. Entries that would leave dir are refused.
+func Extract(r io.Reader, filename, dir string, keep func(name string) bool) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > MaxSize {
+		return "", fmt.Errorf("bundle is larger than %d MB", MaxSize>>20)
+	}
+	sum := sha256.Sum256(data)
+
+	x := &extractor{root: dir, keep: keep}
+	name := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		err = x.zip(data)
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(bytes.NewReader(data)); err == nil {
+			err = x.tar(gz)
+		}
+	case strings.HasSuffix(name, ".tar"):
+		err = x.tar(bytes.NewReader(data))
+	default:
+		return "", fmt.Errorf("bundle must be a .zip, .tar.gz, .tgz or .tar file")
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid bundle: %w", err)
+	}
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Join joins name to root, refusing names that leave root
+func Join(root, name string) (string, error) {
+	p := filepath.Join(root, filepath.FromSlash(name))
+	if p != root && !strings.HasPrefix(p, root+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q leaves the bundle", name)
+	}
+	return p, nil
+}
+
+type extractor struct {
+	root  string
+	keep  func(name string) bool
+	total int64
+}
+
+// write extracts one file, keeping the unpacked total under MaxSize
+func (x *extractor) write(name string, r io.Reader) error {
+	if x.keep != nil && !x.keep(name) {
+		return nil
+	}
+	p, err := Join(x.root, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, err := io.Copy(f, io.LimitReader(r, MaxSize-x.total+1))
+	x.total += n
+	if err != nil {
+		return err
+	}
+	if x.total > MaxSize {
+		return fmt.Errorf("bundle unpacks to more than %d MB", MaxSize>>20)
+	}
+	return nil
+}
+
+func (x *extractor) zip(data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = x.write(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) tar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := x.write(h.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// Clone shallow-clones rawURL at ref (the default branch when empty) into
+// dir and returns the checked out commit. Only http and https URLs are
+// cloned, after the host passes the egress policy; username and password, if
+// set, are sent as HTTP basic auth and kept out of error messages. The clone
+// goes through the git proxy when one is configured.
+func Clone(ctx context.Context, rawURL, ref, username, password, dir string) (string, error) {
+	remote, err := newGitRemote(ctx, rawURL, username, password)
+	if err != nil {
+		return "", err
+	}
+	args := append(remote.config, "clone", "--depth", "1", "--quiet")
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	args = append(args, "--", remote.url.String(), dir)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("git clone failed: %s", remote.redact(out))
+	}
+
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", nil
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// LsRemote returns the commit ref points to in the repository at rawURL,
+// the default branch when ref is empty, without cloning it. URLs, egress and
+// credentials are handled as by Clone.
+func LsRemote(ctx context.Context, rawURL, ref, username, password string) (string, error) {
+	remote, err := newGitRemote(ctx, rawURL, username, password)
+	if err != nil {
+		return "", err
+	}
+	if ref == "" {
+		ref = "HEAD"
+	} else if !strings.HasPrefix(ref, "refs/") {
+		ref = "refs/heads/" + ref
+	}
+	args := append(remote.config, "ls-remote", "--", remote.url.String(), ref)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.Output()
+	if err != nil {
+		var stderr []byte
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr = exitErr.Stderr
+		}
+		return "", fmt.Errorf("git ls-remote failed: %s", remote.redact(stderr))
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		sha, name, found := strings.Cut(line, "\t")
+		if found && name == ref {
+			return sha, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in %s", ref, rawURL)
+}
+
+// gitRemote is a repository URL checked for git to fetch from
+type gitRemote struct {
+	rawURL   string
+	url      url.URL  // with the credentials
+	config   []string // git -c options, such as the proxy
+	via      *url.URL
+	password string
+}
+
+func newGitRemote(ctx context.Context, rawURL, username, password string) (*gitRemote, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("git url must be an http or https URL")
+	}
+	if u.User != nil {
+		return nil, fmt.Errorf("git url must not contain credentials; use a git credential")
+	}
+	proxy, err := egress.ProxyFor(egress.ProxyGit, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := egress.Default().CheckVia(ctx, proxy, u); err != nil {
+		return nil, err
+	}
+
+	remote := &gitRemote{rawURL: rawURL, url: *u, password: password}
+	if username != "" || password != "" {
+		remote.url.User = url.UserPassword(username, password)
+	}
+	remote.via = proxy.For(u)
+	if remote.via != nil {
+		remote.config = []string{"-c", "http.proxy=" + remote.via.String()}
+	}
+	return remote, nil
+}
+
+// redact turns git output into an error message without the credentials
+func (r *gitRemote) redact(out []byte) string {
+	msg := strings.TrimSpace(string(out))
+	if r.password != "" {
+		msg = strings.ReplaceAll(msg, r.url.String(), r.rawURL)
+	}
+	if r.via != nil && r.via.User != nil {
+		msg = strings.ReplaceAll(msg, r.via.String(), r.via.Redacted())
+	}
+	return msg
+}
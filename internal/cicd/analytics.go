@@ -0,0 +1,341 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Pipeline analytics sum up the finished runs of a pipeline over a window:
+// how often they succeed, how long the runs and each of their jobs take, and
+// how long runs wait before they start, overall and per day or week so that
+// a job that got slower stands out.
+
+// Analytics periods
+const (
+	AnalyticsPeriodDay  = "day"
+	AnalyticsPeriodWeek = "week"
+)
+
+// Analytics window, in days
+const (
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 365
+)
+
+// DurationStats sums up durations, in milliseconds. Percentiles are nearest
+// rank.
+type DurationStats struct {
+	Count int   `json:"count"`
+	Avg   int64 `json:"avg_ms"`
+	P50   int64 `json:"p50_ms"`
+	P90   int64 `json:"p90_ms"`
+	P95   int64 `json:"p95_ms"`
+	Max   int64 `json:"max_ms"`
+}
+
+// RunOutcomes counts finished runs or jobs by status. SuccessRate is the
+// share of succeeded among succeeded and failed ones.
+type RunOutcomes struct {
+	Total       int     `json:"total"`
+	Succeeded   int     `json:"succeeded"`
+	Failed      int     `json:"failed"`
+	Cancelled   int     `json:"cancelled"`
+	SuccessRate float64 `json:"success_rate"`
+}
+
+// JobAnalytics sums up the executions of one job
+type JobAnalytics struct {
+	Name     string        `json:"name"`
+	Outcomes RunOutcomes   `json:"outcomes"`
+	Duration DurationStats `json:"duration"`
+	Retried  int           `json:"retried"` // executions that needed more than one attempt
+}
+
+// AnalyticsPeriod sums up the runs of one day or week
+type AnalyticsPeriod struct {
+	Start     time.Time                `json:"start"`
+	Outcomes  RunOutcomes              `json:"outcomes"`
+	Duration  DurationStats            `json:"duration"`
+	QueueWait DurationStats            `json:"queue_wait"`
+	Jobs      map[string]DurationStats `json:"jobs"`
+}
+
+// PipelineAnalytics sums up the finished runs of a pipeline since From
+type PipelineAnalytics struct {
+	PipelineID string            `json:"pipeline_id"`
+	From       time.Time         `json:"from"`
+	To         time.Time         `json:"to"`
+	Period     string            `json:"period"`
+	Outcomes   RunOutcomes       `json:"outcomes"`
+	Duration   DurationStats     `json:"duration"`
+	QueueWait  DurationStats     `json:"queue_wait"`
+	Jobs       []JobAnalytics    `json:"jobs"`
+	Trend      []AnalyticsPeriod `json:"trend"` // oldest first
+}
+
+// RunTimeline places the jobs of a run on its time line
+type RunTimeline struct {
+	RunID     string        `json:"run_id"`
+	Status    RunStatus     `json:"status"`
+	CreatedAt time.Time     `json:"created_at"`
+	QueueWait int64         `json:"queue_wait_ms"`
+	Duration  int64         `json:"duration_ms"`
+	Jobs      []JobTimeline `json:"jobs"`
+}
+
+// JobTimeline is a job of a run timeline. Offset is from the start of the
+// run; Wait is the time between the end of the job before it, or the start
+// of the run, and its start.
+type JobTimeline struct {
+	Name       string    `json:"name"`
+	Status     RunStatus `json:"status"`
+	Offset     int64     `json:"offset_ms"`
+	Wait       int64     `json:"wait_ms"`
+	Duration   int64     `json:"duration_ms"`
+	Attempts   int       `json:"attempts,omitempty"`
+	ReusedFrom string    `json:"reused_from,omitempty"`
+}
+
+// durationStats sums up durations, which it sorts
+func durationStats(durations []int64) DurationStats {
+	stats := DurationStats{Count: len(durations)}
+	if len(durations) == 0 {
+		return stats
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	var sum int64
+	for _, d := range durations {
+		sum += d
+	}
+	rank := func(p int) int64 {
+		i := (p*len(durations)+99)/100 - 1
+		if i < 0 {
+			i = 0
+		}
+		return durations[i]
+	}
+	stats.Avg = sum / int64(len(durations))
+	stats.P50 = rank(50)
+	stats.P90 = rank(90)
+	stats.P95 = rank(95)
+	stats.Max = durations[len(durations)-1]
+	return stats
+}
+
+// count adds a finished run or job to o
+func (o *RunOutcomes) count(status RunStatus) {
+	switch status {
+	case RunStatusSucceeded:
+		o.Succeeded++
+	case RunStatusFailed:
+		o.Failed++
+	case RunStatusCancelled:
+		o.Cancelled++
+	default:
+		return
+	}
+	o.Total++
+	if n := o.Succeeded + o.Failed; n > 0 {
+		o.SuccessRate = float64(o.Succeeded) / float64(n)
+	}
+}
+
+// runFinished tells whether a run has ended
+func runFinished(status RunStatus) bool {
+	return status == RunStatusSucceeded || status == RunStatusFailed || status == RunStatusCancelled
+}
+
+// runQueueWait is how long a run waited between being created and starting
+func runQueueWait(run *PipelineRun) (int64, bool) {
+	if run.StartedAt == nil {
+		return 0, false
+	}
+	wait := run.StartedAt.Sub(run.CreatedAt).Milliseconds()
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
+
+// jobExecuted tells whether a job of a run executed rather than being
+// skipped or reused from another run
+func jobExecuted(job *JobRun) bool {
+	return job.ReusedFrom == "" && job.StartedAt != nil && runFinished(job.Status)
+}
+
+// periodStart is the start of the day or week, from Monday, of t
+func periodStart(t time.Time, period string) time.Time {
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	if period == AnalyticsPeriodWeek {
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	return day
+}
+
+// GetPipelineAnalytics sums up the runs of a pipeline finished in the last
+// days, per day or per week
+func GetPipelineAnalytics(pipelineID string, days int, period string) (*PipelineAnalytics, error) {
+	pipeline, err := GetPipeline(pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	if days <= 0 {
+		days = defaultAnalyticsDays
+	}
+	if days > maxAnalyticsDays {
+		days = maxAnalyticsDays
+	}
+	switch period {
+	case "":
+		period = AnalyticsPeriodDay
+	case AnalyticsPeriodDay, AnalyticsPeriodWeek:
+	default:
+		return nil, fmt.Errorf("period must be %s or %s", AnalyticsPeriodDay, AnalyticsPeriodWeek)
+	}
+
+	runs, err := ListRuns(pipelineID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	analytics := &PipelineAnalytics{
+		PipelineID: pipelineID,
+		From:       now.AddDate(0, 0, -days),
+		To:         now,
+		Period:     period,
+		Jobs:       make([]JobAnalytics, 0, len(pipeline.Spec.Jobs)),
+		Trend:      make([]AnalyticsPeriod, 0),
+	}
+
+	// Jobs in the order of the spec, then jobs only earlier runs had
+	jobIndex := make(map[string]int)
+	jobDurations := make(map[string][]int64)
+	addJob := func(name string) *JobAnalytics {
+		i, ok := jobIndex[name]
+		if !ok {
+			i = len(analytics.Jobs)
+			jobIndex[name] = i
+			analytics.Jobs = append(analytics.Jobs, JobAnalytics{Name: name})
+		}
+		return &analytics.Jobs[i]
+	}
+	for _, job := range pipeline.Spec.Jobs {
+		addJob(job.Name)
+	}
+
+	type periodData struct {
+		period    AnalyticsPeriod
+		durations []int64
+		waits     []int64
+		jobs      map[string][]int64
+	}
+	periods := make(map[time.Time]*periodData)
+	var durations, waits []int64
+
+	for _, run := range runs {
+		if !runFinished(run.Status) || run.CreatedAt.Before(analytics.From) {
+			continue
+		}
+		start := periodStart(run.CreatedAt.UTC(), period)
+		p, ok := periods[start]
+		if !ok {
+			p = &periodData{period: AnalyticsPeriod{Start: start}, jobs: make(map[string][]int64)}
+			periods[start] = p
+		}
+
+		analytics.Outcomes.count(run.Status)
+		p.period.Outcomes.count(run.Status)
+		if run.Duration > 0 {
+			durations = append(durations, run.Duration)
+			p.durations = append(p.durations, run.Duration)
+		}
+		if wait, ok := runQueueWait(run); ok {
+			waits = append(waits, wait)
+			p.waits = append(p.waits, wait)
+		}
+
+		for i := range run.Jobs {
+			job := &run.Jobs[i]
+			if !jobExecuted(job) {
+				continue
+			}
+			ja := addJob(job.Name)
+			ja.Outcomes.count(job.Status)
+			if len(job.Attempts) > 1 {
+				ja.Retried++
+			}
+			if job.Duration > 0 {
+				jobDurations[job.Name] = append(jobDurations[job.Name], job.Duration)
+				p.jobs[job.Name] = append(p.jobs[job.Name], job.Duration)
+			}
+		}
+	}
+
+	analytics.Duration = durationStats(durations)
+	analytics.QueueWait = durationStats(waits)
+	for i := range analytics.Jobs {
+		analytics.Jobs[i].Duration = durationStats(jobDurations[analytics.Jobs[i].Name])
+	}
+	for _, p := range periods {
+		p.period.Duration = durationStats(p.durations)
+		p.period.QueueWait = durationStats(p.waits)
+		p.period.Jobs = make(map[string]DurationStats, len(p.jobs))
+		for name, d := range p.jobs {
+			p.period.Jobs[name] = durationStats(d)
+		}
+		analytics.Trend = append(analytics.Trend, p.period)
+	}
+	sort.Slice(analytics.Trend, func(i, j int) bool {
+		return analytics.Trend[i].Start.Before(analytics.Trend[j].Start)
+	})
+
+	return analytics, nil
+}
+
+// GetRunTimeline places the jobs of a run on its time line
+func GetRunTimeline(runID string) (*RunTimeline, error) {
+	run, err := GetRun(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := &RunTimeline{
+		RunID:     run.ID,
+		Status:    run.Status,
+		CreatedAt: run.CreatedAt,
+		Duration:  run.Duration,
+		Jobs:      make([]JobTimeline, 0, len(run.Jobs)),
+	}
+	timeline.QueueWait, _ = runQueueWait(run)
+
+	prevEnd := run.StartedAt
+	for i := range run.Jobs {
+		job := &run.Jobs[i]
+		jt := JobTimeline{
+			Name:       job.Name,
+			Status:     job.Status,
+			Duration:   job.Duration,
+			Attempts:   len(job.Attempts),
+			ReusedFrom: job.ReusedFrom,
+		}
+		if job.ReusedFrom == "" && job.StartedAt != nil {
+			if run.StartedAt != nil {
+				jt.Offset = job.StartedAt.Sub(*run.StartedAt).Milliseconds()
+			}
+			if prevEnd != nil && job.StartedAt.After(*prevEnd) {
+				jt.Wait = job.StartedAt.Sub(*prevEnd).Milliseconds()
+			}
+			if job.FinishedAt != nil {
+				prevEnd = job.FinishedAt
+			}
+		}
+		timeline.Jobs = append(timeline.Jobs, jt)
+	}
+	return timeline, nil
+}
@@ -0,0 +1,274 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// A build-image job replaces the script container with an image builder
+// that needs no privileges: Kaniko, or BuildKit in rootless mode. The
+// registry login comes from a stored registry credential, rendered into a
+// Docker config.json in a Secret that lives as long as the attempt, like
+// the credentials of a source checkout.
+
+// registryConfigKey is the key of the Docker config in the registry Secret
+const registryConfigKey = "config.json"
+
+// invalidTagChars are the characters a Docker tag may not contain
+var invalidTagChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// builderImage is the image of a builder (GAGOS_KANIKO_IMAGE,
+// GAGOS_BUILDKIT_IMAGE)
+func builderImage(builder string) string {
+	if builder == BuilderBuildKit {
+		if image := os.Getenv("GAGOS_BUILDKIT_IMAGE"); image != "" {
+			return image
+		}
+		return "moby/buildkit:v0.13.2-rootless"
+	}
+	if image := os.Getenv("GAGOS_KANIKO_IMAGE"); image != "" {
+		return image
+	}
+	return "gcr.io/kaniko-project/executor:v1.23.2"
+}
+
+// dockerConfigDir is where a builder reads its registry login
+func dockerConfigDir(builder string) string {
+	if builder == BuilderBuildKit {
+		return "/home/user/.docker"
+	}
+	return "/kaniko/.docker"
+}
+
+// validateJobKind checks a job's kind and build section
+func validateJobKind(kind string, build *BuildImageSpec) error {
+	switch kind {
+	case "", JobKindScript:
+		if build != nil {
+			return fmt.Errorf("build requires kind: %s", JobKindBuildImage)
+		}
+		return nil
+	case JobKindBuildImage:
+	default:
+		return fmt.Errorf("kind must be '%s' or '%s'", JobKindScript, JobKindBuildImage)
+	}
+
+	if build == nil {
+		return fmt.Errorf("build is required for kind: %s", JobKindBuildImage)
+	}
+	if build.Image == "" {
+		return fmt.Errorf("build.image is required")
+	}
+	if strings.Contains(build.Image, "@") {
+		return fmt.Errorf("build.image must not contain a digest")
+	}
+	if build.Builder != "" && build.Builder != BuilderKaniko && build.Builder != BuilderBuildKit {
+		return fmt.Errorf("build.builder must be '%s' or '%s'", BuilderKaniko, BuilderBuildKit)
+	}
+	for field, p := range map[string]string{"build.context": build.Context, "build.dockerfile": build.Dockerfile} {
+		if p == "" {
+			continue
+		}
+		clean := path.Clean(p)
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("%s must be a path inside the workspace", field)
+		}
+	}
+	return nil
+}
+
+// runVariables resolves the variables of a run, with RUN_NUMBER, RUN_ID,
+// PIPELINE_ID and PIPELINE_NAME, for os.Expand
+func runVariables(pipeline *Pipeline, run *PipelineRun) func(string) string {
+	return func(name string) string {
+		switch name {
+		case "RUN_NUMBER":
+			return fmt.Sprintf("%d", run.RunNumber)
+		case "RUN_ID":
+			return run.ID
+		case "PIPELINE_ID":
+			return pipeline.ID
+		case "PIPELINE_NAME":
+			return pipeline.Name
+		}
+		return run.Variables[name]
+	}
+}
+
+// imageDestinations returns the image references a build-image job pushes.
+// Tags are cleaned of characters Docker does not allow, so that a branch
+// name such as feature/login becomes the tag feature-login.
+func imageDestinations(pipeline *Pipeline, run *PipelineRun, jobSpec *JobSpec) ([]string, error) {
+	build := jobSpec.Build
+	vars := runVariables(pipeline, run)
+	image := strings.TrimSpace(os.Expand(build.Image, vars))
+	if image == "" {
+		return nil, fmt.Errorf("build.image is empty after variable expansion")
+	}
+	if last := image[strings.LastIndex(image, "/")+1:]; strings.Contains(last, ":") {
+		return nil, fmt.Errorf("build.image %s must not include a tag; use build.tags", image)
+	}
+
+	tags := build.Tags
+	if len(tags) == 0 {
+		tags = []string{"${RUN_NUMBER}"}
+	}
+	var destinations []string
+	seen := map[string]bool{}
+	for _, t := range tags {
+		tag := invalidTagChars.ReplaceAllString(os.Expand(t, vars), "-")
+		tag = strings.TrimLeft(tag, ".-")
+		if len(tag) > 128 {
+			tag = tag[:128]
+		}
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		destinations = append(destinations, image+":"+tag)
+	}
+	if len(destinations) == 0 {
+		return nil, fmt.Errorf("build.tags are all empty after variable expansion")
+	}
+	return destinations, nil
+}
+
+// applyImageBuild turns the runner container of job into the builder
+func applyImageBuild(job *batchv1.Job, pipeline *Pipeline, run *PipelineRun, jobSpec *JobSpec) {
+	build := jobSpec.Build
+	podSpec := &job.Spec.Template.Spec
+	runner := &podSpec.Containers[0]
+	destinations, _ := imageDestinations(pipeline, run, jobSpec)
+	vars := runVariables(pipeline, run)
+
+	contextDir := path.Join(runner.WorkingDir, build.Context)
+	dockerfile := build.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	dockerfilePath := path.Join(contextDir, dockerfile)
+	image := os.Expand(build.Image, vars)
+
+	argNames := make([]string, 0, len(build.BuildArgs))
+	for name := range build.BuildArgs {
+		argNames = append(argNames, name)
+	}
+	sort.Strings(argNames)
+
+	runner.Image = builderImage(build.Builder)
+	runner.Env = append(runner.Env, corev1.EnvVar{Name: "DOCKER_CONFIG", Value: dockerConfigDir(build.Builder)})
+
+	if build.Builder == BuilderBuildKit {
+		args := []string{
+			"build",
+			"--frontend", "dockerfile.v0",
+			"--local", "context=" + contextDir,
+			"--local", "dockerfile=" + path.Dir(dockerfilePath),
+			"--opt", "filename=" + path.Base(dockerfilePath),
+		}
+		for _, name := range argNames {
+			args = append(args, "--opt", "build-arg:"+name+"="+os.Expand(build.BuildArgs[name], vars))
+		}
+		if build.Target != "" {
+			args = append(args, "--opt", "target="+build.Target)
+		}
+		args = append(args, "--output", fmt.Sprintf(`type=image,"name=%s",push=true`, strings.Join(destinations, ",")))
+		if build.Cache {
+			cache := "type=registry,ref=" + image + ":buildcache"
+			args = append(args, "--export-cache", cache+",mode=max", "--import-cache", cache)
+		}
+		runner.Command = []string{"buildctl-daemonless.sh"}
+		runner.Args = args
+		runner.Env = append(runner.Env, corev1.EnvVar{Name: "BUILDKITD_FLAGS", Value: "--oci-worker-no-process-sandbox"})
+
+		// Rootless BuildKit needs its user namespaces, which the default
+		// seccomp and AppArmor profiles block
+		uid := int64(1000)
+		runner.SecurityContext = &corev1.SecurityContext{
+			RunAsUser:      &uid,
+			RunAsGroup:     &uid,
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined},
+		}
+		if job.Spec.Template.Annotations == nil {
+			job.Spec.Template.Annotations = map[string]string{}
+		}
+		job.Spec.Template.Annotations["container.apparmor.security.beta.kubernetes.io/"+runner.Name] = "unconfined"
+		return
+	}
+
+	args := []string{
+		"--context=dir://" + contextDir,
+		"--dockerfile=" + dockerfilePath,
+	}
+	for _, d := range destinations {
+		args = append(args, "--destination="+d)
+	}
+	for _, name := range argNames {
+		args = append(args, "--build-arg="+name+"="+os.Expand(build.BuildArgs[name], vars))
+	}
+	if build.Target != "" {
+		args = append(args, "--target="+build.Target)
+	}
+	if build.Cache {
+		args = append(args, "--cache=true", "--cache-repo="+image+"/cache")
+	}
+	runner.Command = []string{"/kaniko/executor"}
+	runner.Args = args
+}
+
+// addRegistryCredentials mounts the Docker config of the job's registry
+// credential into the builder. It returns the Secret holding it, nil when
+// the job pushes without a credential.
+func addRegistryCredentials(job *batchv1.Job, pipeline *Pipeline, run *PipelineRun, jobSpec *JobSpec) (*corev1.Secret, error) {
+	build := jobSpec.Build
+	if build.RegistryCredentialID == "" {
+		return nil, nil
+	}
+	cred, err := GetRegistryCredential(build.RegistryCredentialID)
+	if err != nil {
+		return nil, fmt.Errorf("registry credential: %w", err)
+	}
+	image := os.Expand(build.Image, runVariables(pipeline, run))
+	if registry := imageRegistry(image); registry != cred.Registry {
+		return nil, fmt.Errorf("registry credential %s is for %s, but %s is pushed to %s", cred.Name, cred.Registry, image, registry)
+	}
+	config, err := dockerConfigJSON(cred)
+	if err != nil {
+		return nil, fmt.Errorf("registry credential %s: %w", cred.Name, err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      job.Name + "-registry",
+			Namespace: cicdNamespace,
+			Labels:    job.Labels,
+		},
+		Data: map[string][]byte{registryConfigKey: config},
+	}
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: "registry-credentials",
+		VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+			SecretName: secret.Name,
+			Items:      []corev1.KeyToPath{{Key: registryConfigKey, Path: registryConfigKey}},
+		}},
+	})
+	runner := &podSpec.Containers[0]
+	runner.VolumeMounts = append(runner.VolumeMounts, corev1.VolumeMount{
+		Name:      "registry-credentials",
+		MountPath: dockerConfigDir(build.Builder),
+		ReadOnly:  true,
+	})
+	return secret, nil
+}
@@ -0,0 +1,299 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// A pipeline with a commitStatus section reports its runs as a commit status
+// on GitHub, GitLab or Gitea: pending when a run starts, then its result, so
+// pull requests show the build inline. The commit and repository come from
+// the webhook that triggered the run unless the section names them.
+
+// commitStatusTimeout bounds each request to the provider
+const commitStatusTimeout = 15 * time.Second
+
+// validateCommitStatus checks a pipeline's commitStatus section
+func validateCommitStatus(c *CommitStatusSpec) error {
+	if c.CredentialID == "" {
+		return fmt.Errorf("commitStatus.credentialId is required")
+	}
+	switch c.Provider {
+	case "", WebhookProviderGitHub, WebhookProviderGitLab, WebhookProviderGitea:
+	default:
+		return fmt.Errorf("commitStatus.provider must be '%s', '%s' or '%s'", WebhookProviderGitHub, WebhookProviderGitLab, WebhookProviderGitea)
+	}
+	if c.APIURL != "" {
+		if u, err := url.Parse(c.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("commitStatus.apiUrl must be an http(s) URL")
+		}
+	}
+	return nil
+}
+
+// commitStatusRequest is one status to post
+type commitStatusRequest struct {
+	provider    string
+	url         string
+	token       string
+	state       string
+	context     string
+	targetURL   string
+	description string
+}
+
+// reportCommitStatus posts the status of run to its commit. Failures are
+// logged, never failing the run.
+func reportCommitStatus(pipeline *Pipeline, run *PipelineRun) {
+	spec := pipeline.Spec.CommitStatus
+	if spec == nil {
+		return
+	}
+	req, err := buildCommitStatus(pipeline, run)
+	if err != nil {
+		log.Warn().Err(err).Str("run_id", run.ID).Str("pipeline", pipeline.Name).Msg("Failed to report commit status")
+		return
+	}
+	if req == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commitStatusTimeout)
+	defer cancel()
+	if err := postCommitStatus(ctx, req); err != nil {
+		log.Warn().Err(err).Str("run_id", run.ID).Str("pipeline", pipeline.Name).Msg("Failed to report commit status")
+		return
+	}
+	log.Debug().Str("run_id", run.ID).Str("state", req.state).Str("context", req.context).Msg("Commit status reported")
+}
+
+// buildCommitStatus renders the status of run. It returns nil when the run
+// has no commit to report on, as with a manual run.
+func buildCommitStatus(pipeline *Pipeline, run *PipelineRun) (*commitStatusRequest, error) {
+	spec := pipeline.Spec.CommitStatus
+	vars := runVariables(pipeline, run)
+	expand := func(value, fallback string) string {
+		if value == "" {
+			value = fallback
+		}
+		return strings.TrimSpace(os.Expand(value, vars))
+	}
+
+	commit := expand(spec.Commit, "${WEBHOOK_COMMIT}")
+	repository := expand(spec.Repository, "${WEBHOOK_REPOSITORY}")
+	if commit == "" || repository == "" {
+		return nil, nil
+	}
+	provider := spec.Provider
+	if provider == "" {
+		provider = run.Variables["WEBHOOK_PROVIDER"]
+	}
+	if provider == "" {
+		return nil, fmt.Errorf("commitStatus.provider is not set and the run was not triggered by a Git provider")
+	}
+
+	host, project, scheme, err := parseRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+	apiURL := strings.TrimSuffix(spec.APIURL, "/")
+	if apiURL == "" {
+		apiURL = defaultProviderAPI(provider, scheme, host)
+	}
+	req := &commitStatusRequest{
+		provider:    provider,
+		context:     expand(spec.Context, "gagos/${PIPELINE_NAME}"),
+		targetURL:   expand(spec.TargetURL, runPageURL(run.ID)),
+		description: commitStatusDescription(run),
+	}
+	switch provider {
+	case WebhookProviderGitLab:
+		req.url = fmt.Sprintf("%s/projects/%s/statuses/%s", apiURL, url.PathEscape(project), commit)
+	default:
+		req.url = fmt.Sprintf("%s/repos/%s/statuses/%s", apiURL, project, commit)
+	}
+	req.state = commitStatusState(provider, run.Status)
+
+	cred, err := GetDecryptedGitCredential(spec.CredentialID)
+	if err != nil {
+		return nil, fmt.Errorf("git credential: %w", err)
+	}
+	switch cred.AuthMethod {
+	case GitAuthToken:
+		req.token = cred.Token
+	case GitAuthPassword:
+		req.token = cred.Password
+	default:
+		return nil, fmt.Errorf("git credential %s must use token or password authentication", cred.Name)
+	}
+	return req, nil
+}
+
+// parseRepository splits a clone URL, HTTPS or SSH, into its host and
+// project path, and the scheme the provider's API is reached with
+func parseRepository(repository string) (host, project, scheme string, err error) {
+	scheme = "https"
+	if !strings.Contains(repository, "://") {
+		// scp-like SSH: git@host:owner/repo.git
+		userHost, path, found := strings.Cut(repository, ":")
+		if !found {
+			return "", "", "", fmt.Errorf("cannot parse repository %s", repository)
+		}
+		_, host, _ = strings.Cut(userHost, "@")
+		if host == "" {
+			host = userHost
+		}
+		project = path
+	} else {
+		u, parseErr := url.Parse(repository)
+		if parseErr != nil || u.Host == "" {
+			return "", "", "", fmt.Errorf("cannot parse repository %s", repository)
+		}
+		host, project = u.Host, u.Path
+		if u.Scheme == "http" {
+			scheme = "http"
+		} else if u.Scheme == "ssh" {
+			host = u.Hostname()
+		}
+	}
+	project = strings.TrimSuffix(strings.Trim(project, "/"), ".git")
+	if project == "" {
+		return "", "", "", fmt.Errorf("repository %s has no project path", repository)
+	}
+	return host, project, scheme, nil
+}
+
+// defaultProviderAPI is the API base URL of a provider hosted at host
+func defaultProviderAPI(provider, scheme, host string) string {
+	switch provider {
+	case WebhookProviderGitLab:
+		return scheme + "://" + host + "/api/v4"
+	case WebhookProviderGitea:
+		return scheme + "://" + host + "/api/v1"
+	}
+	if host == "github.com" {
+		return "https://api.github.com"
+	}
+	// GitHub Enterprise Server
+	return scheme + "://" + host + "/api/v3"
+}
+
+// runPageURL links to a run in the GAGOS UI (GAGOS_PUBLIC_URL)
+func runPageURL(runID string) string {
+	base := strings.TrimSuffix(os.Getenv("GAGOS_PUBLIC_URL"), "/")
+	if base == "" {
+		return ""
+	}
+	return base + "/?cicd_run=" + url.QueryEscape(runID)
+}
+
+// commitStatusState maps a run status to the provider's status states
+func commitStatusState(provider string, status RunStatus) string {
+	if provider == WebhookProviderGitLab {
+		switch status {
+		case RunStatusRunning:
+			return "running"
+		case RunStatusSucceeded:
+			return "success"
+		case RunStatusFailed:
+			return "failed"
+		case RunStatusCancelled:
+			return "canceled"
+		}
+		return "pending"
+	}
+	switch status {
+	case RunStatusSucceeded:
+		return "success"
+	case RunStatusFailed:
+		return "failure"
+	case RunStatusCancelled:
+		return "error"
+	}
+	return "pending"
+}
+
+// commitStatusDescription is the short text shown next to the status
+func commitStatusDescription(run *PipelineRun) string {
+	switch run.Status {
+	case RunStatusRunning, RunStatusPending:
+		return fmt.Sprintf("Run #%d is running", run.RunNumber)
+	case RunStatusSucceeded:
+		return fmt.Sprintf("Run #%d succeeded in %s", run.RunNumber, time.Duration(run.Duration)*time.Millisecond)
+	case RunStatusCancelled:
+		return fmt.Sprintf("Run #%d was cancelled", run.RunNumber)
+	}
+	for _, job := range run.Jobs {
+		if job.Status == RunStatusFailed {
+			description := fmt.Sprintf("Run #%d failed in job %s", run.RunNumber, job.Name)
+			if len(description) > 140 {
+				description = description[:140]
+			}
+			return description
+		}
+	}
+	return fmt.Sprintf("Run #%d failed", run.RunNumber)
+}
+
+// postCommitStatus sends a status to the provider's API
+func postCommitStatus(ctx context.Context, req *commitStatusRequest) error {
+	body := map[string]string{
+		"state":       req.state,
+		"context":     req.context,
+		"description": req.description,
+	}
+	if req.provider == WebhookProviderGitLab {
+		// GitLab calls the context name
+		body = map[string]string{
+			"state":       req.state,
+			"name":        req.context,
+			"description": req.description,
+		}
+	}
+	if req.targetURL != "" {
+		body["target_url"] = req.targetURL
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	switch req.provider {
+	case WebhookProviderGitLab:
+		httpReq.Header.Set("PRIVATE-TOKEN", req.token)
+	case WebhookProviderGitea:
+		httpReq.Header.Set("Authorization", "token "+req.token)
+	default:
+		httpReq.Header.Set("Authorization", "Bearer "+req.token)
+		httpReq.Header.Set("Accept", "application/vnd.github+json")
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", req.provider, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
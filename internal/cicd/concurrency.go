@@ -0,0 +1,261 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gaga951/gagos/internal/k8s"
+	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Runs of a pipeline with a concurrency section take a slot of its group
+// while they execute. When the group is full a new run stays pending in the
+// group's queue until a running run finishes; in cancel-in-progress mode the
+// oldest running run is cancelled as well, and runs still waiting in the
+// queue are cancelled in favour of the new one. A slot is only released when
+// the run's execution returns, so a cancelled run's pods are gone before the
+// next run of the group starts.
+//
+// Pipelines sharing a group may set different max values. The group then
+// allows as many runs as the smallest max among the pipelines with runs in
+// it, running or waiting, so the limit does not depend on which pipeline's
+// run arrives or finishes first.
+
+// runQueue tracks the running and queued runs of each concurrency group
+type runQueue struct {
+	mu      sync.Mutex
+	running map[string][]*queuedRun // group -> running runs, oldest first
+	waiting map[string][]*queuedRun // group -> runs waiting for a slot
+
+	// exec executes a run in its slot; executeRun when nil
+	exec func(pipeline *Pipeline, run *PipelineRun, clientset kubernetes.Interface)
+}
+
+type queuedRun struct {
+	pipeline *Pipeline
+	run      *PipelineRun
+}
+
+var (
+	resumeMu sync.Mutex
+	resumed  bool
+)
+
+var groupQueue = &runQueue{
+	running: make(map[string][]*queuedRun),
+	waiting: make(map[string][]*queuedRun),
+}
+
+// validateConcurrency checks a pipeline's concurrency section
+func validateConcurrency(c *ConcurrencySpec) error {
+	if c.Max < 0 {
+		return fmt.Errorf("concurrency.max must not be negative")
+	}
+	if c.Mode != "" && c.Mode != ConcurrencyQueue && c.Mode != ConcurrencyCancelInProgress {
+		return fmt.Errorf("concurrency.mode must be '%s' or '%s'", ConcurrencyQueue, ConcurrencyCancelInProgress)
+	}
+	if c.Group != "" && len(c.Group) > 253 {
+		return fmt.Errorf("concurrency.group is too long")
+	}
+	return nil
+}
+
+// concurrencyGroup returns the group of a run of pipeline with vars, or ""
+// when the pipeline has no concurrency limits
+func concurrencyGroup(pipeline *Pipeline, vars map[string]string) string {
+	c := pipeline.Spec.Concurrency
+	if c == nil {
+		return ""
+	}
+	group := os.Expand(c.Group, func(name string) string { return vars[name] })
+	if group == "" {
+		group = "pipeline:" + pipeline.ID
+	}
+	return group
+}
+
+// startRun executes run now, or queues it when its concurrency group is full
+func startRun(pipeline *Pipeline, run *PipelineRun, clientset kubernetes.Interface) {
+	if run.ConcurrencyGroup == "" {
+		go executeRun(pipeline, run, clientset)
+		return
+	}
+	groupQueue.admit(pipeline, run, clientset)
+}
+
+func (q *runQueue) admit(pipeline *Pipeline, run *PipelineRun, clientset kubernetes.Interface) {
+	group := run.ConcurrencyGroup
+
+	q.mu.Lock()
+	limit := q.groupLimit(group, pipeline)
+	var cancel []string
+	var superseded []*queuedRun
+	if pipeline.Spec.Concurrency.Mode == ConcurrencyCancelInProgress {
+		superseded = q.waiting[group]
+		delete(q.waiting, group)
+		if n := len(q.running[group]) - limit + 1; n > 0 {
+			for _, r := range q.running[group][:n] {
+				cancel = append(cancel, r.run.ID)
+			}
+		}
+	}
+	if len(q.running[group]) < limit && len(q.waiting[group]) == 0 {
+		q.running[group] = append(q.running[group], &queuedRun{pipeline: pipeline, run: run})
+		q.mu.Unlock()
+		go q.execute(pipeline, run, clientset)
+		return
+	}
+	q.waiting[group] = append(q.waiting[group], &queuedRun{pipeline: pipeline, run: run})
+	position := len(q.waiting[group])
+	q.mu.Unlock()
+
+	run.Queued = true
+	saveRun(run)
+	log.Info().Str("run_id", run.ID).Str("group", group).Int("position", position).Msg("Pipeline run queued")
+
+	for _, w := range superseded {
+		cancelQueuedRun(w.run, run.ID)
+	}
+	for _, id := range cancel {
+		log.Info().Str("run_id", id).Str("group", group).Str("by", run.ID).Msg("Cancelling in-progress run")
+		if err := CancelRun(context.Background(), id); err != nil {
+			log.Warn().Err(err).Str("run_id", id).Msg("Failed to cancel in-progress run")
+		}
+	}
+}
+
+// execute runs run in its group's slot and hands the slot on when it ends
+func (q *runQueue) execute(pipeline *Pipeline, run *PipelineRun, clientset kubernetes.Interface) {
+	defer q.release(run.ConcurrencyGroup, run.ID, clientset)
+	if run.Queued {
+		run.Queued = false
+		saveRun(run)
+	}
+	if q.exec != nil {
+		q.exec(pipeline, run, clientset)
+		return
+	}
+	executeRun(pipeline, run, clientset)
+}
+
+func (q *runQueue) release(group, runID string, clientset kubernetes.Interface) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	running := q.running[group]
+	for i, r := range running {
+		if r.run.ID == runID {
+			running = append(running[:i], running[i+1:]...)
+			break
+		}
+	}
+	q.running[group] = running
+
+	for len(q.waiting[group]) > 0 {
+		next := q.waiting[group][0]
+		q.waiting[group] = q.waiting[group][1:]
+		// Runs cancelled while they waited are dropped
+		if current, err := GetRun(next.run.ID); err != nil || current.Status != RunStatusPending {
+			continue
+		}
+		if len(q.running[group]) >= q.groupLimit(group, next.pipeline) {
+			q.waiting[group] = append([]*queuedRun{next}, q.waiting[group]...)
+			break
+		}
+		q.running[group] = append(q.running[group], next)
+		go q.execute(next.pipeline, next.run, clientset)
+	}
+	if len(q.running[group]) == 0 {
+		delete(q.running, group)
+	}
+	if len(q.waiting[group]) == 0 {
+		delete(q.waiting, group)
+	}
+}
+
+// groupLimit returns how many runs group allows at once: the smallest max of
+// pipeline and of the pipelines with runs running or waiting in the group.
+// q.mu must be held.
+func (q *runQueue) groupLimit(group string, pipeline *Pipeline) int {
+	limit := concurrencyMax(pipeline)
+	for _, runs := range [][]*queuedRun{q.running[group], q.waiting[group]} {
+		for _, r := range runs {
+			limit = min(limit, concurrencyMax(r.pipeline))
+		}
+	}
+	return limit
+}
+
+// concurrencyMax returns the max of a pipeline's concurrency section, 1 when
+// it is not set
+func concurrencyMax(pipeline *Pipeline) int {
+	if c := pipeline.Spec.Concurrency; c != nil && c.Max > 0 {
+		return c.Max
+	}
+	return 1
+}
+
+// cancelQueuedRun cancels a run that never started because a newer run of
+// its group replaced it
+func cancelQueuedRun(run *PipelineRun, newerID string) {
+	current, err := GetRun(run.ID)
+	if err != nil || current.Status != RunStatusPending {
+		return
+	}
+	now := time.Now()
+	current.Status = RunStatusCancelled
+	current.Queued = false
+	current.FinishedAt = &now
+	current.Error = fmt.Sprintf("superseded by run %s", newerID)
+	for i := range current.Jobs {
+		current.Jobs[i].Status = RunStatusCancelled
+	}
+	if err := saveRun(current); err != nil {
+		log.Warn().Err(err).Str("run_id", run.ID).Msg("Failed to cancel queued run")
+		return
+	}
+	NotifyPipelineRunEvent(NotificationEventRunCancelled, current, current.PipelineName)
+}
+
+// resumeQueuedRuns queues again the runs that were waiting for a slot when
+// GAGOS stopped, in the order they were created. It does so once, when the
+// Kubernetes client is available.
+func resumeQueuedRuns() {
+	clientset := k8s.GetClient()
+	if clientset == nil {
+		return
+	}
+	resumeMu.Lock()
+	defer resumeMu.Unlock()
+	if resumed {
+		return
+	}
+	resumed = true
+
+	all, err := ListRuns("", 0)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load queued runs")
+		return
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].CreatedAt.Before(all[j].CreatedAt) })
+	for _, run := range all {
+		if !run.Queued || run.Status != RunStatusPending {
+			continue
+		}
+		pipeline, err := GetPipeline(run.PipelineID)
+		if err != nil {
+			log.Warn().Err(err).Str("run_id", run.ID).Msg("Failed to load pipeline of queued run")
+			continue
+		}
+		run.Queued = false
+		run.ConcurrencyGroup = concurrencyGroup(pipeline, run.Variables)
+		startRun(pipeline, run, clientset)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gaga951/gagos/internal/k8s"
+	"github.com/gaga951/gagos/internal/storage"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testQueue is a runQueue whose runs execute until the test finishes them
+type testQueue struct {
+	*runQueue
+	t       *testing.T
+	started chan string
+	mu      sync.Mutex
+	finish  map[string]chan struct{}
+	next    int
+}
+
+// testStorage opens a bbolt store in a temporary directory for the test
+func testStorage(t *testing.T) {
+	t.Setenv("GAGOS_STORAGE_TYPE", storage.StorageTypeBBolt)
+	t.Setenv("GAGOS_DB_PATH", filepath.Join(t.TempDir(), "gagos.db"))
+	if err := storage.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { storage.Close() })
+}
+
+func newTestQueue(t *testing.T) *testQueue {
+	testStorage(t)
+	// CancelRun deletes the Jobs of the run it cancels
+	k8s.SetClient(fake.NewSimpleClientset(), nil)
+	t.Cleanup(func() { k8s.SetClient(nil, nil) })
+
+	q := &testQueue{
+		t:       t,
+		started: make(chan string, 10),
+		finish:  make(map[string]chan struct{}),
+	}
+	q.runQueue = &runQueue{
+		running: make(map[string][]*queuedRun),
+		waiting: make(map[string][]*queuedRun),
+		exec:    q.exec,
+	}
+	return q
+}
+
+func (q *testQueue) done(id string) chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.finish[id] == nil {
+		q.finish[id] = make(chan struct{})
+	}
+	return q.finish[id]
+}
+
+func (q *testQueue) exec(_ *Pipeline, run *PipelineRun, _ kubernetes.Interface) {
+	run.Status = RunStatusRunning
+	saveRun(run)
+	q.started <- run.ID
+	<-q.done(run.ID)
+	// Keep a cancellation saved while the run executed
+	saveActiveRun(run)
+	if run.Status == RunStatusRunning {
+		run.Status = RunStatusSucceeded
+		saveRun(run)
+	}
+}
+
+// admit submits a new pending run of pipeline and returns its ID
+func (q *testQueue) admit(pipeline *Pipeline) string {
+	q.next++
+	run := &PipelineRun{
+		ID:               fmt.Sprintf("run-%d", q.next),
+		PipelineID:       pipeline.ID,
+		Status:           RunStatusPending,
+		ConcurrencyGroup: concurrencyGroup(pipeline, nil),
+		CreatedAt:        time.Now(),
+	}
+	if err := saveRun(run); err != nil {
+		q.t.Fatal(err)
+	}
+	q.runQueue.admit(pipeline, run, nil)
+	return run.ID
+}
+
+// expectStarted checks that exactly the runs ids start next
+func (q *testQueue) expectStarted(ids ...string) {
+	q.t.Helper()
+	want := map[string]bool{}
+	for _, id := range ids {
+		want[id] = true
+	}
+	for range ids {
+		select {
+		case id := <-q.started:
+			if !want[id] {
+				q.t.Fatalf("%s started, want %v", id, ids)
+			}
+		case <-time.After(5 * time.Second):
+			q.t.Fatalf("timed out waiting for %v to start", ids)
+		}
+	}
+	select {
+	case id := <-q.started:
+		q.t.Fatalf("%s started as well", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// status returns the saved state of a run
+func (q *testQueue) status(id string) *PipelineRun {
+	q.t.Helper()
+	run, err := GetRun(id)
+	if err != nil {
+		q.t.Fatal(err)
+	}
+	return run
+}
+
+// wait waits until every run has released its slot
+func (q *testQueue) wait() {
+	q.t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		q.runQueue.mu.Lock()
+		idle := len(q.running) == 0 && len(q.waiting) == 0
+		q.runQueue.mu.Unlock()
+		if idle {
+			return
+		}
+		if time.Now().After(deadline) {
+			q.t.Fatal("runs never released their slots")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func concurrencyPipeline(max int, mode string) *Pipeline {
+	p := &Pipeline{ID: "p1", Name: "deploy"}
+	p.Spec.Concurrency = &ConcurrencySpec{Max: max, Mode: mode}
+	return p
+}
+
+func TestRunQueue(t *testing.T) {
+	q := newTestQueue(t)
+	pipeline := concurrencyPipeline(1, ConcurrencyQueue)
+
+	r1 := q.admit(pipeline)
+	q.expectStarted(r1)
+	r2, r3, r4 := q.admit(pipeline), q.admit(pipeline), q.admit(pipeline)
+	q.expectStarted()
+	if !q.status(r2).Queued || !q.status(r3).Queued {
+		t.Error("waiting runs are not marked queued")
+	}
+
+	// Runs take the slot in the order they arrived
+	close(q.done(r1))
+	q.expectStarted(r2)
+	if q.status(r2).Queued {
+		t.Error("started run is still marked queued")
+	}
+
+	// A run cancelled while it waits is skipped
+	if err := CancelRun(context.Background(), r3); err != nil {
+		t.Fatal(err)
+	}
+	close(q.done(r2))
+	q.expectStarted(r4)
+	close(q.done(r4))
+	q.wait()
+
+	for id, want := range map[string]RunStatus{r1: RunStatusSucceeded, r2: RunStatusSucceeded, r3: RunStatusCancelled, r4: RunStatusSucceeded} {
+		if got := q.status(id).Status; got != want {
+			t.Errorf("%s is %s, want %s", id, got, want)
+		}
+	}
+}
+
+func TestRunQueueMax(t *testing.T) {
+	q := newTestQueue(t)
+	pipeline := concurrencyPipeline(2, ConcurrencyQueue)
+
+	r1, r2 := q.admit(pipeline), q.admit(pipeline)
+	q.expectStarted(r1, r2)
+	r3 := q.admit(pipeline)
+	q.expectStarted()
+
+	close(q.done(r2))
+	q.expectStarted(r3)
+	close(q.done(r1))
+	close(q.done(r3))
+	q.wait()
+}
+
+func TestRunQueueSharedGroup(t *testing.T) {
+	q := newTestQueue(t)
+	wide := concurrencyPipeline(3, ConcurrencyQueue)
+	wide.Spec.Concurrency.Group = "deploy"
+	narrow := &Pipeline{ID: "p2", Name: "migrate"}
+	narrow.Spec.Concurrency = &ConcurrencySpec{Max: 1, Group: "deploy"}
+
+	// Whichever pipeline admits or hands on a slot, the group holds one run
+	// while the pipeline with max 1 has runs in it
+	r1 := q.admit(wide)
+	q.expectStarted(r1)
+	r2, r3 := q.admit(narrow), q.admit(wide)
+	q.expectStarted()
+
+	close(q.done(r1))
+	q.expectStarted(r2)
+	close(q.done(r2))
+	q.expectStarted(r3)
+
+	// Once only the wider pipeline has runs in the group, its max applies
+	r4 := q.admit(wide)
+	q.expectStarted(r4)
+	r5 := q.admit(narrow)
+	q.expectStarted()
+	close(q.done(r3))
+	q.expectStarted()
+	close(q.done(r4))
+	q.expectStarted(r5)
+	close(q.done(r5))
+	q.wait()
+}
+
+func TestRunQueueCancelInProgress(t *testing.T) {
+	q := newTestQueue(t)
+	pipeline := concurrencyPipeline(1, ConcurrencyCancelInProgress)
+
+	r1 := q.admit(pipeline)
+	q.expectStarted(r1)
+	r2 := q.admit(pipeline)
+	if got := q.status(r1).Status; got != RunStatusCancelled {
+		t.Errorf("in-progress run is %s, want cancelled", got)
+	}
+	r3 := q.admit(pipeline)
+	if got := q.status(r2); got.Status != RunStatusCancelled || got.Error != "superseded by run "+r3 {
+		t.Errorf("queued run is %s (%s), want superseded", got.Status, got.Error)
+	}
+
+	// The newest run waits until the cancelled one has stopped
+	q.expectStarted()
+	close(q.done(r1))
+	q.expectStarted(r3)
+	close(q.done(r3))
+	q.wait()
+
+	if got := q.status(r1).Status; got != RunStatusCancelled {
+		t.Errorf("cancelled run finished as %s", got)
+	}
+	if got := q.status(r3).Status; got != RunStatusSucceeded {
+		t.Errorf("newest run is %s", got)
+	}
+}
+
+func TestConcurrencyGroup(t *testing.T) {
+	p := &Pipeline{ID: "p1"}
+	if g := concurrencyGroup(p, nil); g != "" {
+		t.Errorf("group without concurrency = %q", g)
+	}
+	p.Spec.Concurrency = &ConcurrencySpec{}
+	if g := concurrencyGroup(p, nil); g != "pipeline:p1" {
+		t.Errorf("default group = %q", g)
+	}
+	p.Spec.Concurrency.Group = "deploy-${ENV}"
+	if g := concurrencyGroup(p, map[string]string{"ENV": "prod"}); g != "deploy-prod" {
+		t.Errorf("expanded group = %q", g)
+	}
+
+	for _, c := range []ConcurrencySpec{{Max: -1}, {Mode: "latest"}} {
+		if err := validateConcurrency(&c); err == nil {
+			t.Errorf("%+v accepted", c)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// A job publishes its coverage by uploading a Cobertura XML, LCOV or Go
+// coverprofile report as an artifact of its run. The reports a run uploads
+// add up to its coverage, which is kept per run so that a pipeline has a
+// coverage history and, with spec.coverage, a gate that fails runs whose
+// coverage is too low or fell too much.
+
+// Coverage report formats
+const (
+	CoverageFormatCobertura = "cobertura"
+	CoverageFormatLCOV      = "lcov"
+	CoverageFormatGo        = "go"
+)
+
+// coverageMu serializes the updates of a run's coverage by reports uploaded
+// at the same time
+var coverageMu sync.Mutex
+
+// CoverageGateResult is the outcome of a run's coverage gate
+type CoverageGateResult struct {
+	Passed   bool     `json:"passed"`
+	Minimum  float64  `json:"minimum,omitempty"`
+	MaxDrop  float64  `json:"max_drop,omitempty"`
+	Baseline *float64 `json:"baseline,omitempty"` // coverage of the last successful run
+	Error    string   `json:"error,omitempty"`
+}
+
+// CoverageReport is the coverage a run uploaded. Covered and Total count
+// lines, or statements for Go coverprofiles.
+type CoverageReport struct {
+	RunID      string              `json:"run_id"`
+	PipelineID string              `json:"pipeline_id"`
+	RunNumber  int                 `json:"run_number"`
+	Reports    []string            `json:"reports"` // artifact IDs
+	Formats    []string            `json:"formats"`
+	Covered    int64               `json:"covered"`
+	Total      int64               `json:"total"`
+	Percent    float64             `json:"percent"`
+	Gate       *CoverageGateResult `json:"gate,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+// ParseCoverageReport reads the covered and total lines of a coverage
+// report, telling its format from its content
+func ParseCoverageReport(r io.Reader) (format string, covered, total int64, err error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("empty coverage report")
+		}
+		if b[0] != ' ' && b[0] != '\t' && b[0] != '\r' && b[0] != '\n' {
+			break
+		}
+		br.ReadByte()
+	}
+
+	if b, _ := br.Peek(1); b[0] == '<' {
+		covered, total, err = parseCobertura(br)
+		return CoverageFormatCobertura, covered, total, err
+	}
+	if b, _ := br.Peek(5); string(b) == "mode:" {
+		covered, total, err = parseGoCoverProfile(br)
+		return CoverageFormatGo, covered, total, err
+	}
+	covered, total, err = parseLCOV(br)
+	return CoverageFormatLCOV, covered, total, err
+}
+
+// parseCobertura reads the lines-covered and lines-valid of the <coverage>
+// root of a Cobertura report
+func parseCobertura(r io.Reader) (int64, int64, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid Cobertura report: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "coverage" {
+			return 0, 0, fmt.Errorf("not a coverage report: root element is <%s>", start.Name.Local)
+		}
+		var covered, total int64 = -1, -1
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "lines-covered":
+				covered, _ = strconv.ParseInt(attr.Value, 10, 64)
+			case "lines-valid":
+				total, _ = strconv.ParseInt(attr.Value, 10, 64)
+			}
+		}
+		if covered < 0 || total < 0 || covered > total {
+			return 0, 0, fmt.Errorf("no valid lines-covered and lines-valid in Cobertura report")
+		}
+		return covered, total, nil
+	}
+}
+
+// parseLCOV adds up the LH and LF of each record of an LCOV tracefile,
+// counting its DA lines when a record has none
+func parseLCOV(r io.Reader) (int64, int64, error) {
+	var covered, total int64
+	var hit, found, daHit, daFound int64
+	hasSummary, records := false, 0
+	endRecord := func() {
+		if hasSummary {
+			covered, total = covered+hit, total+found
+		} else {
+			covered, total = covered+daHit, total+daFound
+		}
+		hit, found, daHit, daFound, hasSummary = 0, 0, 0, 0, false
+		records++
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		key, value, _ := strings.Cut(line, ":")
+		switch key {
+		case "LH":
+			hit, _ = strconv.ParseInt(value, 10, 64)
+			hasSummary = true
+		case "LF":
+			found, _ = strconv.ParseInt(value, 10, 64)
+			hasSummary = true
+		case "DA":
+			fields := strings.Split(value, ",")
+			if len(fields) < 2 {
+				continue
+			}
+			daFound++
+			if n, _ := strconv.ParseInt(fields[1], 10, 64); n > 0 {
+				daHit++
+			}
+		case "end_of_record":
+			endRecord()
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return 0, 0, fmt.Errorf("invalid LCOV report: %w", err)
+	}
+	if hasSummary || daFound > 0 {
+		endRecord()
+	}
+	if records == 0 {
+		return 0, 0, fmt.Errorf("not a coverage report: no LCOV records")
+	}
+	return covered, total, nil
+}
+
+// parseGoCoverProfile counts the covered and total statements of a Go
+// coverprofile. A block listed more than once, as with -coverpkg, counts
+// once, covered if any listing covered it.
+func parseGoCoverProfile(r io.Reader) (int64, int64, error) {
+	type block struct {
+		stmts   int64
+		covered bool
+	}
+	blocks := make(map[string]*block)
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		// file:startLine.startCol,endLine.endCol numStmt count
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return 0, 0, fmt.Errorf("invalid Go coverprofile line: %q", line)
+		}
+		stmts, err1 := strconv.ParseInt(fields[1], 10, 64)
+		count, err2 := strconv.ParseInt(fields[2], 10, 64)
+		if err1 != nil || err2 != nil {
+			return 0, 0, fmt.Errorf("invalid Go coverprofile line: %q", line)
+		}
+		b, ok := blocks[fields[0]]
+		if !ok {
+			b = &block{stmts: stmts}
+			blocks[fields[0]] = b
+		}
+		b.covered = b.covered || count > 0
+	}
+	if err := sc.Err(); err != nil {
+		return 0, 0, fmt.Errorf("invalid Go coverprofile: %w", err)
+	}
+
+	var covered, total int64
+	for _, b := range blocks {
+		total += b.stmts
+		if b.covered {
+			covered += b.stmts
+		}
+	}
+	return covered, total, nil
+}
+
+// coveragePercent is the share of total that is covered, in percent rounded
+// to two decimals
+func coveragePercent(covered, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(covered)*10000/float64(total)) / 100
+}
+
+// IngestCoverageReport parses an uploaded artifact as a coverage report and
+// adds it to the coverage of its run
+func IngestCoverageReport(artifact *ArtifactMetadata) (*CoverageReport, error) {
+	f, err := os.Open(artifact.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open report: %w", err)
+	}
+	defer f.Close()
+
+	format, covered, total, err := ParseCoverageReport(f)
+	if err != nil {
+		return nil, err
+	}
+
+	coverageMu.Lock()
+	defer coverageMu.Unlock()
+
+	report, err := GetCoverageReport(artifact.RunID)
+	if err != nil {
+		report = &CoverageReport{
+			RunID:      artifact.RunID,
+			PipelineID: artifact.PipelineID,
+			CreatedAt:  time.Now(),
+		}
+		if run, err := GetRun(artifact.RunID); err == nil {
+			report.RunNumber = run.RunNumber
+		}
+	}
+	report.Reports = append(report.Reports, artifact.ID)
+	if !containsString(report.Formats, format) {
+		report.Formats = append(report.Formats, format)
+	}
+	report.Covered += covered
+	report.Total += total
+	report.Percent = coveragePercent(report.Covered, report.Total)
+	report.UpdatedAt = time.Now()
+
+	if err := saveCoverageReport(report); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("run_id", report.RunID).
+		Str("artifact_id", artifact.ID).
+		Str("format", format).
+		Float64("percent", report.Percent).
+		Msg("Coverage report ingested")
+
+	return report, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func saveCoverageReport(report *CoverageReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if err := storage.GetBackend().Set(storage.BucketCoverage, report.RunID, data); err != nil {
+		return fmt.Errorf("failed to save coverage report: %w", err)
+	}
+	return nil
+}
+
+// GetCoverageReport retrieves the coverage of a run
+func GetCoverageReport(runID string) (*CoverageReport, error) {
+	data, err := storage.GetBackend().Get(storage.BucketCoverage, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coverage report: %w", err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("no coverage for run: %s", runID)
+	}
+
+	var report CoverageReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal coverage report: %w", err)
+	}
+	return &report, nil
+}
+
+// ListCoverageReports returns the coverage of a pipeline's runs, newest
+// first
+func ListCoverageReports(pipelineID string, limit int) ([]*CoverageReport, error) {
+	items, err := storage.GetBackend().List(storage.BucketCoverage)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*CoverageReport, 0)
+	for _, data := range items {
+		var report CoverageReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			continue
+		}
+		if pipelineID != "" && report.PipelineID != pipelineID {
+			continue
+		}
+		reports = append(reports, &report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].CreatedAt.After(reports[j].CreatedAt)
+	})
+
+	if limit > 0 && len(reports) > limit {
+		reports = reports[:limit]
+	}
+	return reports, nil
+}
+
+// deleteCoverageReport removes the coverage of a run
+func deleteCoverageReport(runID string) {
+	storage.GetBackend().Delete(storage.BucketCoverage, runID)
+}
+
+func validateCoverage(c *CoverageSpec) error {
+	if c.Minimum < 0 || c.Minimum > 100 {
+		return fmt.Errorf("coverage.minimum must be between 0 and 100")
+	}
+	if c.MaxDrop < 0 || c.MaxDrop > 100 {
+		return fmt.Errorf("coverage.maxDrop must be between 0 and 100")
+	}
+	if c.Minimum == 0 && c.MaxDrop == 0 {
+		return fmt.Errorf("coverage requires minimum or maxDrop")
+	}
+	return nil
+}
+
+// coverageBaseline is the coverage of the last successful run of the
+// pipeline before report
+func coverageBaseline(report *CoverageReport) *CoverageReport {
+	reports, err := ListCoverageReports(report.PipelineID, 0)
+	if err != nil {
+		return nil
+	}
+	for _, r := range reports {
+		if r.RunID == report.RunID || !r.CreatedAt.Before(report.CreatedAt) {
+			continue
+		}
+		if run, err := GetRun(r.RunID); err == nil && run.Status == RunStatusSucceeded {
+			return r
+		}
+	}
+	return nil
+}
+
+// checkCoverageGate applies the coverage gate of pipeline to run, whose jobs
+// passed, and records the result with the run's coverage. A run that
+// uploaded no coverage fails the gate.
+func checkCoverageGate(pipeline *Pipeline, run *PipelineRun) error {
+	spec := pipeline.Spec.Coverage
+	coverageMu.Lock()
+	defer coverageMu.Unlock()
+
+	report, err := GetCoverageReport(run.ID)
+	if err != nil {
+		return fmt.Errorf("coverage gate: no coverage report was uploaded")
+	}
+
+	result := &CoverageGateResult{Passed: true, Minimum: spec.Minimum, MaxDrop: spec.MaxDrop}
+	if spec.Minimum > 0 && report.Percent < spec.Minimum {
+		result.Passed = false
+		result.Error = fmt.Sprintf("coverage gate: coverage %.2f%% is below the minimum of %.2f%%", report.Percent, spec.Minimum)
+	}
+	if spec.MaxDrop > 0 {
+		if base := coverageBaseline(report); base != nil {
+			result.Baseline = &base.Percent
+			if drop := base.Percent - report.Percent; result.Passed && drop > spec.MaxDrop {
+				result.Passed = false
+				result.Error = fmt.Sprintf("coverage gate: coverage fell %.2f points to %.2f%% from %.2f%% in run #%d, more than the %.2f allowed",
+					drop, report.Percent, base.Percent, base.RunNumber, spec.MaxDrop)
+			}
+		}
+	}
+
+	report.Gate = result
+	if err := saveCoverageReport(report); err != nil {
+		log.Warn().Err(err).Str("run_id", run.ID).Msg("Failed to record coverage gate")
+	}
+	if !result.Passed {
+		return errors.New(result.Error)
+	}
+	return nil
+}
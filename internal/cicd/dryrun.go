@@ -0,0 +1,167 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// Dry-run job outcomes
+const (
+	DryRunWouldRun = "would_run"
+	DryRunSkipped  = "skipped"
+	DryRunInvalid  = "invalid"
+)
+
+// DryRunJob describes what would happen to a single job
+type DryRunJob struct {
+	Name        string   `json:"name"`
+	Order       int      `json:"order"` // 1-based position in execution order, 0 if not executed
+	Outcome     string   `json:"outcome"`
+	Reason      string   `json:"reason,omitempty"`
+	DependsOn   []string `json:"depends_on,omitempty"`
+	Environment string   `json:"environment,omitempty"` // environment the job deploys to
+	K8sJobName  string   `json:"k8s_job_name,omitempty"`
+	Manifest    string   `json:"manifest,omitempty"` // K8s Job YAML that would be created
+	Hosts       []string `json:"hosts,omitempty"`    // SSH hosts a job with runsOn may run on
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// DryRunResult is the simulated execution plan for a pipeline
+type DryRunResult struct {
+	PipelineID     string            `json:"pipeline_id"`
+	PipelineName   string            `json:"pipeline_name"`
+	Namespace      string            `json:"namespace"`
+	Variables      map[string]string `json:"variables"`
+	ExecutionOrder []string          `json:"execution_order"`
+	Jobs           []DryRunJob       `json:"jobs"`
+	Valid          bool              `json:"valid"`
+}
+
+// DryRunPipeline simulates a run without touching the cluster or storage. It follows
+// the same rules as executeRun: jobs run in declared order, skipIf is evaluated against
+// the merged variables, and skipped jobs satisfy dependencies.
+func DryRunPipeline(pipeline *Pipeline, vars map[string]string) (*DryRunResult, error) {
+	if pipeline == nil {
+		return nil, fmt.Errorf("pipeline is required")
+	}
+
+	mergedVars := mergeVariables(pipeline, vars)
+	run := &PipelineRun{
+		ID:           generateID("run"),
+		PipelineID:   pipeline.ID,
+		PipelineName: pipeline.Name,
+		RunNumber:    pipeline.Status.TotalRuns + 1,
+		TriggerType:  "dry-run",
+		Variables:    mergedVars,
+	}
+
+	result := &DryRunResult{
+		PipelineID:     pipeline.ID,
+		PipelineName:   pipeline.Name,
+		Namespace:      cicdNamespace,
+		Variables:      mergedVars,
+		ExecutionOrder: []string{},
+		Jobs:           make([]DryRunJob, 0, len(pipeline.Spec.Jobs)),
+		Valid:          true,
+	}
+
+	position := make(map[string]int, len(pipeline.Spec.Jobs))
+	for i, jobSpec := range pipeline.Spec.Jobs {
+		position[jobSpec.Name] = i
+	}
+
+	for i := range pipeline.Spec.Jobs {
+		jobSpec := pipeline.Spec.Jobs[i]
+		job := DryRunJob{
+			Name:        jobSpec.Name,
+			DependsOn:   jobSpec.DependsOn,
+			Environment: jobSpec.Environment,
+		}
+
+		for _, dep := range jobSpec.DependsOn {
+			if pos, ok := position[dep]; ok && pos > i {
+				job.Warnings = append(job.Warnings, fmt.Sprintf("dependency %s is declared after this job and will not have run yet", dep))
+			}
+		}
+
+		if shouldSkipJob(&jobSpec, mergedVars) {
+			job.Outcome = DryRunSkipped
+			job.Reason = fmt.Sprintf("skipIf variable %s is %q", jobSpec.SkipIf, mergedVars[jobSpec.SkipIf])
+			result.Jobs = append(result.Jobs, job)
+			continue
+		}
+
+		err := validateJobResources(&jobSpec)
+		if err == nil && jobSpec.Kind == JobKindBuildImage {
+			_, err = imageDestinations(pipeline, run, &jobSpec)
+		}
+		if err != nil {
+			job.Outcome = DryRunInvalid
+			job.Reason = err.Error()
+			result.Valid = false
+			result.Jobs = append(result.Jobs, job)
+			continue
+		}
+
+		if group := sshRunnerGroup(&jobSpec); group != "" {
+			hosts, err := sshRunnerHosts(group)
+			if err != nil {
+				job.Outcome = DryRunInvalid
+				job.Reason = err.Error()
+				result.Valid = false
+				result.Jobs = append(result.Jobs, job)
+				continue
+			}
+			for _, h := range hosts {
+				job.Hosts = append(job.Hosts, h.Name)
+			}
+			result.ExecutionOrder = append(result.ExecutionOrder, jobSpec.Name)
+			job.Order = len(result.ExecutionOrder)
+			job.Outcome = DryRunWouldRun
+			result.Jobs = append(result.Jobs, job)
+			continue
+		}
+
+		k8sJob := buildK8sJob(pipeline, run, &jobSpec)
+		k8sJob.APIVersion = "batch/v1"
+		k8sJob.Kind = "Job"
+		manifest, err := yaml.Marshal(k8sJob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render job %s: %w", jobSpec.Name, err)
+		}
+
+		result.ExecutionOrder = append(result.ExecutionOrder, jobSpec.Name)
+		job.Order = len(result.ExecutionOrder)
+		job.Outcome = DryRunWouldRun
+		job.K8sJobName = k8sJob.Name
+		job.Manifest = string(manifest)
+		result.Jobs = append(result.Jobs, job)
+	}
+
+	return result, nil
+}
+
+// validateJobResources checks resource quantities up front, since buildK8sJob
+// uses resource.MustParse and would panic on a bad value.
+func validateJobResources(jobSpec *JobSpec) error {
+	quantities := []struct{ field, value string }{
+		{"resources.limits.memory", jobSpec.Resources.Limits.Memory},
+		{"resources.limits.cpu", jobSpec.Resources.Limits.CPU},
+		{"resources.requests.memory", jobSpec.Resources.Requests.Memory},
+		{"resources.requests.cpu", jobSpec.Resources.Requests.CPU},
+	}
+	for _, q := range quantities {
+		if q.value == "" {
+			continue
+		}
+		if _, err := resource.ParseQuantity(q.value); err != nil {
+			return fmt.Errorf("invalid %s %q: %v", q.field, q.value, err)
+		}
+	}
+	return nil
+}
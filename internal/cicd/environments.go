@@ -0,0 +1,338 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gaga951/gagos/internal/k8s"
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// A job with environment: <name> deploys to that environment. Every attempt
+// to deploy is recorded once the job has run, so each environment has a
+// history, and the last successful deployment is what it currently runs. A
+// rollback starts a new run of the pipeline with the variables of an earlier
+// deployment's run in which only the deploy job is executed again.
+
+// TriggerTypeRollback is the trigger type of a run that rolls an environment
+// back
+const TriggerTypeRollback = "rollback"
+
+// maxDeploymentHistory caps the deployments kept per environment
+const maxDeploymentHistory = 100
+
+// Deployment is one run of a job that deploys to an environment
+type Deployment struct {
+	ID           string     `json:"id"`
+	Environment  string     `json:"environment"`
+	PipelineID   string     `json:"pipeline_id"`
+	PipelineName string     `json:"pipeline_name"`
+	RunID        string     `json:"run_id"`
+	RunNumber    int        `json:"run_number"`
+	JobName      string     `json:"job_name"`
+	Version      string     `json:"version"`
+	Status       RunStatus  `json:"status"`
+	TriggerType  string     `json:"trigger_type"`
+	TriggerRef   string     `json:"trigger_ref,omitempty"`
+	RollbackOf   string     `json:"rollback_of,omitempty"` // deployment a rollback went back to
+	Error        string     `json:"error,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Environment sums up the deployments to one environment
+type Environment struct {
+	Name        string      `json:"name"`
+	Current     *Deployment `json:"current,omitempty"` // last successful deployment
+	Latest      *Deployment `json:"latest,omitempty"`  // last deployment, whatever its outcome
+	Deployments int         `json:"deployments"`
+}
+
+// deploymentVersion is the version a job of run deploys: the VERSION
+// variable, else the first image the run pushed, the tag or commit it was
+// triggered by, or the run number. A rollback deploys the version it goes
+// back to.
+func deploymentVersion(run *PipelineRun) string {
+	if run.RollbackOf != "" {
+		if dep, err := GetDeployment(run.RollbackOf); err == nil {
+			return dep.Version
+		}
+	}
+	if v := run.Variables["VERSION"]; v != "" {
+		return v
+	}
+	for _, job := range run.Jobs {
+		if len(job.Images) > 0 {
+			return job.Images[0]
+		}
+	}
+	if tag := run.Variables["WEBHOOK_TAG"]; tag != "" {
+		return tag
+	}
+	if commit := firstNonEmpty(run.Variables["WEBHOOK_COMMIT"], run.Variables["SCM_COMMIT"]); commit != "" {
+		return shortSHA(commit)
+	}
+	return fmt.Sprintf("#%d", run.RunNumber)
+}
+
+// recordDeployment records the outcome of a job that deploys to an
+// environment. Failures are logged, never failing the run.
+func recordDeployment(run *PipelineRun, jobRun *JobRun, jobSpec *JobSpec) {
+	dep := &Deployment{
+		ID:           generateID("dep"),
+		Environment:  jobSpec.Environment,
+		PipelineID:   run.PipelineID,
+		PipelineName: run.PipelineName,
+		RunID:        run.ID,
+		RunNumber:    run.RunNumber,
+		JobName:      jobRun.Name,
+		Version:      deploymentVersion(run),
+		Status:       jobRun.Status,
+		TriggerType:  run.TriggerType,
+		TriggerRef:   run.TriggerRef,
+		RollbackOf:   run.RollbackOf,
+		Error:        jobRun.Error,
+		StartedAt:    jobRun.StartedAt,
+		FinishedAt:   jobRun.FinishedAt,
+		CreatedAt:    time.Now(),
+	}
+	if err := saveDeployment(dep); err != nil {
+		log.Warn().Err(err).Str("run_id", run.ID).Str("environment", dep.Environment).Msg("Failed to record deployment")
+		return
+	}
+	log.Info().
+		Str("environment", dep.Environment).
+		Str("version", dep.Version).
+		Str("status", string(dep.Status)).
+		Str("run_id", run.ID).
+		Msg("Deployment recorded")
+	pruneDeployments(dep.Environment)
+}
+
+func saveDeployment(dep *Deployment) error {
+	data, err := json.Marshal(dep)
+	if err != nil {
+		return err
+	}
+	return storage.GetBackend().Set(storage.BucketDeployments, dep.ID, data)
+}
+
+// pruneDeployments drops the oldest deployments of an environment beyond
+// maxDeploymentHistory, keeping the current one
+func pruneDeployments(environment string) {
+	deps, err := ListDeployments(environment, 0)
+	if err != nil || len(deps) <= maxDeploymentHistory {
+		return
+	}
+	current := currentDeployment(deps)
+	for _, dep := range deps[maxDeploymentHistory:] {
+		if dep != current {
+			storage.GetBackend().Delete(storage.BucketDeployments, dep.ID)
+		}
+	}
+}
+
+// GetDeployment retrieves a deployment by ID
+func GetDeployment(id string) (*Deployment, error) {
+	data, err := storage.GetBackend().Get(storage.BucketDeployments, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("deployment not found: %s", id)
+	}
+
+	var dep Deployment
+	if err := json.Unmarshal(data, &dep); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal deployment: %w", err)
+	}
+	return &dep, nil
+}
+
+// ListDeployments returns the deployments to an environment, or to all
+// environments when it is empty, newest first
+func ListDeployments(environment string, limit int) ([]*Deployment, error) {
+	items, err := storage.GetBackend().List(storage.BucketDeployments)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := make([]*Deployment, 0)
+	for _, data := range items {
+		var dep Deployment
+		if err := json.Unmarshal(data, &dep); err != nil {
+			continue
+		}
+		if environment != "" && dep.Environment != environment {
+			continue
+		}
+		deps = append(deps, &dep)
+	}
+	sort.Slice(deps, func(i, j int) bool {
+		return deps[i].CreatedAt.After(deps[j].CreatedAt)
+	})
+
+	if limit > 0 && len(deps) > limit {
+		deps = deps[:limit]
+	}
+	return deps, nil
+}
+
+// currentDeployment is the newest successful deployment of deps, which are
+// sorted newest first
+func currentDeployment(deps []*Deployment) *Deployment {
+	for _, dep := range deps {
+		if dep.Status == RunStatusSucceeded {
+			return dep
+		}
+	}
+	return nil
+}
+
+// ListEnvironments returns every environment deployed to, by name
+func ListEnvironments() ([]*Environment, error) {
+	deps, err := ListDeployments("", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string][]*Deployment)
+	for _, dep := range deps {
+		byName[dep.Environment] = append(byName[dep.Environment], dep)
+	}
+	envs := make([]*Environment, 0, len(byName))
+	for name, history := range byName {
+		envs = append(envs, &Environment{
+			Name:        name,
+			Current:     currentDeployment(history),
+			Latest:      history[0],
+			Deployments: len(history),
+		})
+	}
+	sort.Slice(envs, func(i, j int) bool {
+		return envs[i].Name < envs[j].Name
+	})
+	return envs, nil
+}
+
+// GetEnvironment sums up the deployments to one environment
+func GetEnvironment(name string) (*Environment, error) {
+	deps, err := ListDeployments(name, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(deps) == 0 {
+		return nil, fmt.Errorf("environment not found: %s", name)
+	}
+	return &Environment{
+		Name:        name,
+		Current:     currentDeployment(deps),
+		Latest:      deps[0],
+		Deployments: len(deps),
+	}, nil
+}
+
+// rollbackTarget picks the deployment a rollback goes back to: the one
+// named, or the successful deployment before the current one
+func rollbackTarget(environment, deploymentID string) (*Deployment, error) {
+	if deploymentID != "" {
+		dep, err := GetDeployment(deploymentID)
+		if err != nil {
+			return nil, err
+		}
+		if dep.Environment != environment {
+			return nil, fmt.Errorf("deployment %s is not a deployment to %s", deploymentID, environment)
+		}
+		if dep.Status != RunStatusSucceeded {
+			return nil, fmt.Errorf("deployment %s did not succeed", deploymentID)
+		}
+		return dep, nil
+	}
+
+	deps, err := ListDeployments(environment, 0)
+	if err != nil {
+		return nil, err
+	}
+	current := currentDeployment(deps)
+	if current == nil {
+		return nil, fmt.Errorf("environment %s has no successful deployment", environment)
+	}
+	for _, dep := range deps {
+		if dep.Status == RunStatusSucceeded && dep.CreatedAt.Before(current.CreatedAt) && dep.Version != current.Version {
+			return dep, nil
+		}
+	}
+	return nil, fmt.Errorf("environment %s has no earlier version to roll back to", environment)
+}
+
+// RollbackEnvironment redeploys an earlier deployment to an environment. The
+// new run uses the pipeline as it is now with the variables of the
+// deployment's run; the deploy job runs again while the other jobs reuse
+// their result from that run, or are skipped when they have none.
+func RollbackEnvironment(ctx context.Context, environment, deploymentID string) (*PipelineRun, *Deployment, error) {
+	clientset := k8s.GetClient()
+	if clientset == nil {
+		return nil, nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	target, err := rollbackTarget(environment, deploymentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	orig, err := GetRun(target.RunID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("run of deployment %s: %w", target.ID, err)
+	}
+	pipeline, err := GetPipeline(target.PipelineID)
+	if err != nil {
+		return nil, nil, err
+	}
+	deploys := false
+	for _, job := range pipeline.Spec.Jobs {
+		if job.Name == target.JobName && job.Environment == environment {
+			deploys = true
+			break
+		}
+	}
+	if !deploys {
+		return nil, nil, fmt.Errorf("job %s of pipeline %s no longer deploys to %s", target.JobName, pipeline.Name, environment)
+	}
+
+	run := newRun(pipeline, TriggerTypeRollback, fmt.Sprintf("rollback:%s@%s", environment, target.Version), orig.Variables)
+	run.RerunOf = orig.ID
+	run.RollbackOf = target.ID
+	for i := range run.Jobs {
+		if run.Jobs[i].Name == target.JobName {
+			continue
+		}
+		run.Jobs[i].Status = RunStatusSkipped
+		for _, prev := range orig.Jobs {
+			if prev.Name != run.Jobs[i].Name || prev.Status != RunStatusSucceeded {
+				continue
+			}
+			run.Jobs[i] = prev
+			if run.Jobs[i].ReusedFrom == "" {
+				run.Jobs[i].ReusedFrom = orig.ID
+			}
+			break
+		}
+	}
+
+	if err := launchRun(pipeline, run, clientset); err != nil {
+		return nil, nil, err
+	}
+	log.Info().
+		Str("environment", environment).
+		Str("version", target.Version).
+		Str("run_id", run.ID).
+		Msg("Environment rollback started")
+	return run, target, nil
+}
@@ -6,9 +6,11 @@ package cicd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -28,6 +30,12 @@ var (
 	artifactPath    string
 )
 
+// Cancellation channels of the runs executing, closed by CancelRun
+var (
+	runningRuns   = make(map[string]chan struct{})
+	runningRunsMu sync.Mutex
+)
+
 func init() {
 	cicdNamespace = os.Getenv("GAGOS_CICD_NAMESPACE")
 	if cicdNamespace == "" {
@@ -39,6 +47,11 @@ func init() {
 	}
 }
 
+// Namespace returns the namespace pipeline jobs run in (GAGOS_CICD_NAMESPACE)
+func Namespace() string {
+	return cicdNamespace
+}
+
 // TriggerPipeline creates a new pipeline run and starts execution
 func TriggerPipeline(ctx context.Context, pipeline *Pipeline, triggerType, triggerRef string, vars map[string]string) (*PipelineRun, error) {
 	clientset := k8s.GetClient()
@@ -46,31 +59,78 @@ func TriggerPipeline(ctx context.Context, pipeline *Pipeline, triggerType, trigg
 		return nil, fmt.Errorf("kubernetes client not initialized")
 	}
 
-	// Merge variables
-	mergedVars := make(map[string]string)
-	for k, v := range pipeline.Spec.Variables {
-		mergedVars[k] = v
+	run := newRun(pipeline, triggerType, triggerRef, vars)
+	if err := launchRun(pipeline, run, clientset); err != nil {
+		return nil, err
 	}
-	for k, v := range vars {
-		mergedVars[k] = v
+	return run, nil
+}
+
+// RerunPipelineRun starts a new run of a finished run's pipeline with the
+// same variables and trigger ref. With failedOnly, jobs that succeeded in
+// the original run are not executed again: the new run reuses their result.
+func RerunPipelineRun(ctx context.Context, orig *PipelineRun, failedOnly bool) (*PipelineRun, error) {
+	clientset := k8s.GetClient()
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
 	}
 
-	// Create the run
-	now := time.Now()
-	runID := generateID("run")
-	runNumber := pipeline.Status.TotalRuns + 1
+	if orig.Status == RunStatusPending || orig.Status == RunStatusRunning {
+		return nil, fmt.Errorf("run is still active")
+	}
+
+	pipeline, err := GetPipeline(orig.PipelineID)
+	if err != nil {
+		return nil, err
+	}
+
+	run := newRun(pipeline, orig.TriggerType, orig.TriggerRef, orig.Variables)
+	run.RerunOf = orig.ID
+
+	if failedOnly {
+		reused := 0
+		for i := range run.Jobs {
+			for _, prev := range orig.Jobs {
+				if prev.Name != run.Jobs[i].Name || prev.Status != RunStatusSucceeded {
+					continue
+				}
+				run.Jobs[i] = prev
+				// Point at the run that actually executed the job
+				if run.Jobs[i].ReusedFrom == "" {
+					run.Jobs[i].ReusedFrom = orig.ID
+				}
+				reused++
+				break
+			}
+		}
+		if reused == len(run.Jobs) {
+			return nil, fmt.Errorf("run has no failed or cancelled jobs to re-run")
+		}
+	}
+
+	if err := launchRun(pipeline, run, clientset); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// newRun creates a pending run of pipeline with its job runs
+func newRun(pipeline *Pipeline, triggerType, triggerRef string, vars map[string]string) *PipelineRun {
+	mergedVars := mergeVariables(pipeline, vars)
 
 	run := &PipelineRun{
-		ID:           runID,
+		ID:           generateID("run"),
 		PipelineID:   pipeline.ID,
 		PipelineName: pipeline.Name,
-		RunNumber:    runNumber,
+		RunNumber:    pipeline.Status.TotalRuns + 1,
 		Status:       RunStatusPending,
 		TriggerType:  triggerType,
 		TriggerRef:   triggerRef,
 		Variables:    mergedVars,
 		Jobs:         make([]JobRun, 0, len(pipeline.Spec.Jobs)),
-		CreatedAt:    now,
+		CreatedAt:    time.Now(),
+
+		ConcurrencyGroup: concurrencyGroup(pipeline, mergedVars),
 	}
 
 	// Initialize job runs
@@ -80,29 +140,68 @@ func TriggerPipeline(ctx context.Context, pipeline *Pipeline, triggerType, trigg
 			Status: RunStatusPending,
 		})
 	}
+	return run
+}
 
+// launchRun saves a new run, records it on its pipeline and starts it
+func launchRun(pipeline *Pipeline, run *PipelineRun, clientset kubernetes.Interface) error {
 	// Save the run
 	if err := saveRun(run); err != nil {
-		return nil, fmt.Errorf("failed to save run: %w", err)
+		return fmt.Errorf("failed to save run: %w", err)
 	}
 
 	// Update pipeline status
-	pipeline.Status.TotalRuns = runNumber
-	pipeline.Status.LastRunID = runID
+	now := run.CreatedAt
+	pipeline.Status.TotalRuns = run.RunNumber
+	pipeline.Status.LastRunID = run.ID
 	pipeline.Status.LastRunAt = &now
 	pipeline.UpdatedAt = now
 	if err := savePipeline(pipeline); err != nil {
 		log.Warn().Err(err).Msg("Failed to update pipeline status")
 	}
 
-	// Start execution in background
-	go executeRun(pipeline, run, clientset)
+	// Start execution in background, once the concurrency group has a slot
+	startRun(pipeline, run, clientset)
+	return nil
+}
 
-	return run, nil
+// mergeVariables overlays trigger-time variables on the pipeline defaults
+func mergeVariables(pipeline *Pipeline, vars map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for k, v := range pipeline.Spec.Variables {
+		merged[k] = v
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+	return merged
+}
+
+// shouldSkipJob reports whether the job's skipIf variable is set to a truthy value
+func shouldSkipJob(jobSpec *JobSpec, vars map[string]string) bool {
+	if jobSpec.SkipIf == "" {
+		return false
+	}
+	skipVal := strings.ToLower(vars[jobSpec.SkipIf])
+	return skipVal == "true" || skipVal == "1" || skipVal == "yes"
 }
 
+// MaxJobRetries caps the retries of a job
+const MaxJobRetries = 10
+
+// Retry backoff of a job: the delay doubles after each attempt up to
+// maxRetryDelay
+const (
+	defaultRetryDelay = 10 * time.Second
+	maxRetryDelay     = 10 * time.Minute
+)
+
+// errJobFailed is returned when the job's pod failed, as opposed to a
+// timeout or an API error
+var errJobFailed = errors.New("job failed")
+
 // executeRun executes all jobs in the pipeline run
-func executeRun(pipeline *Pipeline, run *PipelineRun, clientset *kubernetes.Clientset) {
+func executeRun(pipeline *Pipeline, run *PipelineRun, clientset kubernetes.Interface) {
 	ctx := context.Background()
 
 	// Mark run as running
@@ -111,16 +210,41 @@ func executeRun(pipeline *Pipeline, run *PipelineRun, clientset *kubernetes.Clie
 	run.StartedAt = &now
 	saveRun(run)
 
+	// Create cancellation channel
+	runningRunsMu.Lock()
+	runningRuns[run.ID] = make(chan struct{})
+	runningRunsMu.Unlock()
+	defer func() {
+		runningRunsMu.Lock()
+		delete(runningRuns, run.ID)
+		runningRunsMu.Unlock()
+	}()
+
 	// Send run started notification
 	NotifyPipelineRunEvent(NotificationEventRunStarted, run, pipeline.Name)
+	reportCommitStatus(pipeline, run)
 
 	log.Info().Str("run_id", run.ID).Str("pipeline", pipeline.Name).Msg("Starting pipeline run")
 
 	// Execute jobs sequentially (respecting dependencies)
 	completed := make(map[string]bool)
 	failed := false
+	cancelled := false
+
+	// Without its secret variables no job of the run can work
+	secrets, err := secretValues(SecretScopePipeline, pipeline.ID)
+	if err != nil {
+		log.Error().Err(err).Str("run_id", run.ID).Msg("Failed to load secret variables")
+		run.Error = fmt.Sprintf("secret variables: %s", err)
+		failed = true
+	}
+	run.secrets = secrets
+	run.masker = runLogMasker(secrets)
 
 	for i := range run.Jobs {
+		if !failed && runCancelled(run.ID) {
+			failed, cancelled = true, true
+		}
 		if failed {
 			run.Jobs[i].Status = RunStatusCancelled
 			continue
@@ -128,16 +252,25 @@ func executeRun(pipeline *Pipeline, run *PipelineRun, clientset *kubernetes.Clie
 
 		jobSpec := pipeline.Spec.Jobs[i]
 
+		// Jobs reused from the run being re-run count as passed
+		if run.Jobs[i].ReusedFrom != "" {
+			completed[jobSpec.Name] = true
+			continue
+		}
+
+		// Jobs a rollback does not execute again
+		if run.Jobs[i].Status == RunStatusSkipped {
+			completed[jobSpec.Name] = true
+			continue
+		}
+
 		// Check if job should be skipped via skipIf variable
-		if jobSpec.SkipIf != "" {
-			skipVal := strings.ToLower(run.Variables[jobSpec.SkipIf])
-			if skipVal == "true" || skipVal == "1" || skipVal == "yes" {
-				log.Info().Str("job", jobSpec.Name).Str("skipIf", jobSpec.SkipIf).Msg("Job skipped by variable")
-				run.Jobs[i].Status = RunStatusSkipped
-				completed[jobSpec.Name] = true // Treat as passed for dependencies
-				saveRun(run)
-				continue
-			}
+		if shouldSkipJob(&jobSpec, run.Variables) {
+			log.Info().Str("job", jobSpec.Name).Str("skipIf", jobSpec.SkipIf).Msg("Job skipped by variable")
+			run.Jobs[i].Status = RunStatusSkipped
+			completed[jobSpec.Name] = true // Treat as passed for dependencies
+			saveActiveRun(run)
+			continue
 		}
 
 		// Check dependencies (skipped jobs count as passed)
@@ -160,8 +293,11 @@ func executeRun(pipeline *Pipeline, run *PipelineRun, clientset *kubernetes.Clie
 		}
 
 		// Execute the job
-		err := executeJob(ctx, clientset, pipeline, run, &run.Jobs[i], &jobSpec)
-		if err != nil {
+		err := runJob(ctx, clientset, pipeline, run, &run.Jobs[i], &jobSpec)
+		if err != nil && runCancelled(run.ID) {
+			run.Jobs[i].Status = RunStatusCancelled
+			failed, cancelled = true, true
+		} else if err != nil {
 			log.Error().Err(err).Str("job", jobSpec.Name).Msg("Job execution failed")
 			run.Jobs[i].Status = RunStatusFailed
 			run.Jobs[i].Error = err.Error()
@@ -169,8 +305,20 @@ func executeRun(pipeline *Pipeline, run *PipelineRun, clientset *kubernetes.Clie
 		} else {
 			completed[jobSpec.Name] = true
 		}
+		if jobSpec.Environment != "" {
+			recordDeployment(run, &run.Jobs[i], &jobSpec)
+		}
 
-		saveRun(run)
+		saveActiveRun(run)
+	}
+
+	// A run whose jobs passed can still fail its coverage gate
+	if !failed && pipeline.Spec.Coverage != nil {
+		if err := checkCoverageGate(pipeline, run); err != nil {
+			log.Info().Str("run_id", run.ID).Err(err).Msg("Coverage gate failed")
+			run.Error = err.Error()
+			failed = true
+		}
 	}
 
 	// Mark run as complete
@@ -180,6 +328,15 @@ func executeRun(pipeline *Pipeline, run *PipelineRun, clientset *kubernetes.Clie
 		run.Duration = finishedAt.Sub(*run.StartedAt).Milliseconds()
 	}
 
+	// CancelRun already recorded and announced the cancellation
+	if cancelled || runCancelled(run.ID) {
+		run.Status = RunStatusCancelled
+		saveRun(run)
+		reportCommitStatus(pipeline, run)
+		log.Info().Str("run_id", run.ID).Msg("Pipeline run cancelled")
+		return
+	}
+
 	if failed {
 		run.Status = RunStatusFailed
 	} else {
@@ -196,6 +353,7 @@ func executeRun(pipeline *Pipeline, run *PipelineRun, clientset *kubernetes.Clie
 		event = NotificationEventRunSucceeded
 	}
 	NotifyPipelineRunEvent(event, run, pipeline.Name)
+	reportCommitStatus(pipeline, run)
 
 	log.Info().
 		Str("run_id", run.ID).
@@ -204,21 +362,159 @@ func executeRun(pipeline *Pipeline, run *PipelineRun, clientset *kubernetes.Clie
 		Msg("Pipeline run completed")
 }
 
-// executeJob creates and monitors a K8s Job for a pipeline job
-func executeJob(ctx context.Context, clientset *kubernetes.Clientset, pipeline *Pipeline, run *PipelineRun, jobRun *JobRun, jobSpec *JobSpec) error {
+// runJob executes a job and retries it up to jobSpec.Retries times when it
+// fails, waiting retryDelay before the first retry and twice as long before
+// each next one. Every attempt gets its own K8s Job, and the pods of failed
+// attempts are kept so their logs can still be read.
+func runJob(ctx context.Context, clientset kubernetes.Interface, pipeline *Pipeline, run *PipelineRun, jobRun *JobRun, jobSpec *JobSpec) error {
+	// An invalid spec fails the same way on every attempt
+	if err := validateJobResources(jobSpec); err != nil {
+		return err
+	}
+
+	delay := defaultRetryDelay
+	if jobSpec.RetryDelay > 0 {
+		delay = time.Duration(jobSpec.RetryDelay) * time.Second
+	}
+	for attempt := 1; ; attempt++ {
+		started := time.Now()
+		jobRun.Attempt = attempt
+		jobRun.K8sPodName = ""
+		jobRun.LogPath = ""
+		jobRun.Host = ""
+		err := executeJob(ctx, clientset, pipeline, run, jobRun, jobSpec, attempt)
+		if jobSpec.Retries == 0 {
+			return err
+		}
+
+		finished := time.Now()
+		record := JobAttempt{
+			Attempt:    attempt,
+			Status:     RunStatusSucceeded,
+			K8sJobName: jobRun.K8sJobName,
+			K8sPodName: jobRun.K8sPodName,
+			StartedAt:  &started,
+			FinishedAt: &finished,
+			Duration:   finished.Sub(started).Milliseconds(),
+			ExitCode:   jobRun.ExitCode,
+			LogPath:    jobRun.LogPath,
+			Host:       jobRun.Host,
+		}
+		if err != nil {
+			record.Status = RunStatusFailed
+			record.Error = err.Error()
+		}
+		jobRun.Attempts = append(jobRun.Attempts, record)
+		if err == nil || attempt > jobSpec.Retries {
+			return err
+		}
+
+		// A cancelled run deletes its K8s Jobs, which must not start a retry
+		if runCancelled(run.ID) {
+			return fmt.Errorf("run cancelled: %w", err)
+		}
+
+		log.Warn().Err(err).
+			Str("job", jobSpec.Name).
+			Int("attempt", attempt).
+			Dur("delay", delay).
+			Msg("Job attempt failed, retrying")
+		jobRun.Error = fmt.Sprintf("attempt %d failed: %v", attempt, err)
+		saveActiveRun(run)
+
+		// Cancelling the run ends the wait instead of starting the retry
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-runCancelChannel(run.ID):
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		if runCancelled(run.ID) {
+			return fmt.Errorf("run cancelled: %w", err)
+		}
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// executeJob creates and monitors a K8s Job for one attempt of a pipeline job
+func executeJob(ctx context.Context, clientset kubernetes.Interface, pipeline *Pipeline, run *PipelineRun, jobRun *JobRun, jobSpec *JobSpec, attempt int) error {
+	if jobSpec.RunsOn != "" {
+		return executeSSHJob(ctx, pipeline, run, jobRun, jobSpec, attempt)
+	}
+
 	// Mark job as running
 	now := time.Now()
 	jobRun.Status = RunStatusRunning
-	jobRun.StartedAt = &now
+	if jobRun.StartedAt == nil {
+		jobRun.StartedAt = &now
+	}
 
 	// Build the K8s Job
 	k8sJob := buildK8sJob(pipeline, run, jobSpec)
+	if attempt > 1 {
+		k8sJob.Name = fmt.Sprintf("%s-r%d", k8sJob.Name, attempt)
+	}
 	jobRun.K8sJobName = k8sJob.Name
 
+	// Source checkout, with its credentials in a Secret that lives as long
+	// as the attempt
+	var sourceSecret *corev1.Secret
+	if jobSpec.Source != nil {
+		secret, err := addSourceCheckout(k8sJob, run, jobSpec)
+		if err != nil {
+			return err
+		}
+		if secret != nil {
+			sourceSecret, err = clientset.CoreV1().Secrets(cicdNamespace).Create(ctx, secret, metav1.CreateOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to create source secret: %w", err)
+			}
+			defer clientset.CoreV1().Secrets(cicdNamespace).Delete(context.Background(), sourceSecret.Name, metav1.DeleteOptions{})
+		}
+	}
+
+	// Registry login of an image build, in a Secret like the source's
+	var registrySecret *corev1.Secret
+	if jobSpec.Kind == JobKindBuildImage {
+		images, err := imageDestinations(pipeline, run, jobSpec)
+		if err != nil {
+			return err
+		}
+		jobRun.Images = images
+		secret, err := addRegistryCredentials(k8sJob, pipeline, run, jobSpec)
+		if err != nil {
+			return err
+		}
+		if secret != nil {
+			registrySecret, err = clientset.CoreV1().Secrets(cicdNamespace).Create(ctx, secret, metav1.CreateOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to create registry secret: %w", err)
+			}
+			defer clientset.CoreV1().Secrets(cicdNamespace).Delete(context.Background(), registrySecret.Name, metav1.DeleteOptions{})
+		}
+	}
+
+	// Secret variables, read by the runner from a Secret like the source's
+	var variablesSecret *corev1.Secret
+	if len(run.secrets) > 0 {
+		var err error
+		variablesSecret, err = clientset.CoreV1().Secrets(cicdNamespace).Create(ctx, addSecretVariables(k8sJob, run.secrets), metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create secret variables: %w", err)
+		}
+		defer clientset.CoreV1().Secrets(cicdNamespace).Delete(context.Background(), variablesSecret.Name, metav1.DeleteOptions{})
+	}
+
 	log.Info().
 		Str("job", jobSpec.Name).
 		Str("k8s_job", k8sJob.Name).
-		Str("image", jobSpec.Image).
+		Str("image", k8sJob.Spec.Template.Spec.Containers[0].Image).
 		Msg("Creating K8s Job")
 
 	// Create the Job
@@ -226,6 +522,27 @@ func executeJob(ctx context.Context, clientset *kubernetes.Clientset, pipeline *
 	if err != nil {
 		return fmt.Errorf("failed to create k8s job: %w", err)
 	}
+	if sourceSecret != nil {
+		// Should GAGOS stop before the deferred delete, the Secret goes
+		// with the Job once its TTL expires
+		if err := ownSourceSecret(ctx, clientset, sourceSecret, createdJob); err != nil {
+			log.Warn().Err(err).Str("secret", sourceSecret.Name).Msg("Failed to set owner of source secret")
+		}
+	}
+	if registrySecret != nil {
+		if err := ownSourceSecret(ctx, clientset, registrySecret, createdJob); err != nil {
+			log.Warn().Err(err).Str("secret", registrySecret.Name).Msg("Failed to set owner of registry secret")
+		}
+	}
+	if variablesSecret != nil {
+		if err := ownSourceSecret(ctx, clientset, variablesSecret, createdJob); err != nil {
+			log.Warn().Err(err).Str("secret", variablesSecret.Name).Msg("Failed to set owner of secret variables")
+		}
+	}
+
+	// Keep the logs beyond the pod's lifetime
+	capture := captureJobLogs(clientset, run.ID, jobSpec.Name, attempt, createdJob.Name, jobSpec.Source != nil, run.masker)
+	defer func() { jobRun.LogPath = capture.finish() }()
 
 	// Watch the Job for completion
 	timeout := time.Duration(jobSpec.Timeout) * time.Second
@@ -238,8 +555,11 @@ func executeJob(ctx context.Context, clientset *kubernetes.Clientset, pipeline *
 
 	err = watchJobCompletion(watchCtx, clientset, createdJob.Name, jobRun)
 	if err != nil {
-		// Try to cleanup the job
+		// Try to cleanup the job; the pod of a failed job is kept for its logs
 		deletePolicy := metav1.DeletePropagationBackground
+		if errors.Is(err, errJobFailed) {
+			deletePolicy = metav1.DeletePropagationOrphan
+		}
 		clientset.BatchV1().Jobs(cicdNamespace).Delete(ctx, createdJob.Name, metav1.DeleteOptions{
 			PropagationPolicy: &deletePolicy,
 		})
@@ -253,6 +573,7 @@ func executeJob(ctx context.Context, clientset *kubernetes.Clientset, pipeline *
 		jobRun.Duration = finishedAt.Sub(*jobRun.StartedAt).Milliseconds()
 	}
 	jobRun.Status = RunStatusSucceeded
+	jobRun.Error = ""
 
 	// Cleanup job (leave pod for log viewing)
 	deletePolicy := metav1.DeletePropagationOrphan
@@ -399,11 +720,15 @@ func buildK8sJob(pipeline *Pipeline, run *PipelineRun, jobSpec *JobSpec) *batchv
 		}
 	}
 
+	if jobSpec.Kind == JobKindBuildImage {
+		applyImageBuild(job, pipeline, run, jobSpec)
+	}
+
 	return job
 }
 
 // watchJobCompletion watches a K8s Job until completion
-func watchJobCompletion(ctx context.Context, clientset *kubernetes.Clientset, jobName string, jobRun *JobRun) error {
+func watchJobCompletion(ctx context.Context, clientset kubernetes.Interface, jobName string, jobRun *JobRun) error {
 	// First, get the pod name
 	for {
 		select {
@@ -437,6 +762,13 @@ func watchJobCompletion(ctx context.Context, clientset *kubernetes.Clientset, jo
 	}
 	defer watcher.Stop()
 
+	// The job may have finished before the watch started
+	if job, err := clientset.BatchV1().Jobs(cicdNamespace).Get(ctx, jobName, metav1.GetOptions{}); err == nil {
+		if done, err := jobFinished(job, jobRun); done {
+			return err
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -445,29 +777,53 @@ func watchJobCompletion(ctx context.Context, clientset *kubernetes.Clientset, jo
 			if !ok {
 				return fmt.Errorf("job watch channel closed")
 			}
+			job, ok := event.Object.(*batchv1.Job)
+			if !ok || job.Name != jobName {
+				continue
+			}
+			if event.Type == watch.Deleted {
+				return fmt.Errorf("job was deleted")
+			}
 
 			if event.Type == watch.Modified || event.Type == watch.Added {
-				job, ok := event.Object.(*batchv1.Job)
-				if !ok {
-					continue
-				}
-
-				// Check for completion
-				for _, condition := range job.Status.Conditions {
-					if condition.Type == batchv1.JobComplete && condition.Status == corev1.ConditionTrue {
-						jobRun.ExitCode = 0
-						return nil
-					}
-					if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
-						jobRun.ExitCode = 1
-						return fmt.Errorf("job failed: %s", condition.Message)
-					}
+				if done, err := jobFinished(job, jobRun); done {
+					return err
 				}
 			}
 		}
 	}
 }
 
+// jobFinished reports whether a K8s Job completed, with its error when it
+// failed
+func jobFinished(job *batchv1.Job, jobRun *JobRun) (bool, error) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobComplete && condition.Status == corev1.ConditionTrue {
+			jobRun.ExitCode = 0
+			return true, nil
+		}
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			jobRun.ExitCode = 1
+			return true, fmt.Errorf("%w: %s", errJobFailed, condition.Message)
+		}
+	}
+	return false, nil
+}
+
+// runCancelled reports whether the run was cancelled while it executed
+func runCancelled(runID string) bool {
+	current, err := GetRun(runID)
+	return err == nil && current.Status == RunStatusCancelled
+}
+
+// runCancelChannel returns the cancellation channel of an executing run, nil
+// when the run is not executing here
+func runCancelChannel(runID string) <-chan struct{} {
+	runningRunsMu.Lock()
+	defer runningRunsMu.Unlock()
+	return runningRuns[runID]
+}
+
 // CancelRun cancels a running pipeline
 func CancelRun(ctx context.Context, runID string) error {
 	run, err := GetRun(runID)
@@ -514,6 +870,14 @@ func CancelRun(ctx context.Context, runID string) error {
 		return err
 	}
 
+	// Wake the executor when it waits to retry a job
+	runningRunsMu.Lock()
+	if ch, ok := runningRuns[runID]; ok {
+		close(ch)
+		delete(runningRuns, runID)
+	}
+	runningRunsMu.Unlock()
+
 	// Send cancelled notification
 	pipelineName := run.PipelineID
 	if pipeline, err := GetPipeline(run.PipelineID); err == nil {
@@ -552,6 +916,17 @@ func saveRun(run *PipelineRun) error {
 	return storage.SaveRun(run.ID, data)
 }
 
+// saveActiveRun saves the progress of an executing run, keeping the
+// cancellation CancelRun saved meanwhile instead of reviving the run
+func saveActiveRun(run *PipelineRun) error {
+	if current, err := GetRun(run.ID); err == nil && current.Status == RunStatusCancelled {
+		run.Status = RunStatusCancelled
+		run.FinishedAt = current.FinishedAt
+		run.Duration = current.Duration
+	}
+	return saveRun(run)
+}
+
 func savePipeline(pipeline *Pipeline) error {
 	data, err := json.Marshal(pipeline)
 	if err != nil {
@@ -652,13 +1027,25 @@ func SavePipeline(pipeline *Pipeline) error {
 	return savePipeline(pipeline)
 }
 
-// DeletePipeline removes a pipeline
+// DeletePipeline removes a pipeline and its secret variables
 func DeletePipeline(id string) error {
-	return storage.DeletePipeline(id)
+	if err := storage.DeletePipeline(id); err != nil {
+		return err
+	}
+	deleteScopedSecrets(SecretScopePipeline, id)
+	pruneWebhookTokenUse(WebhookKindPipeline, id, nil)
+	return nil
 }
 
-// DeleteRun removes a run
+// DeleteRun removes a run, its kept job logs, its artifacts and its test
+// and coverage results
 func DeleteRun(id string) error {
+	if run, err := GetRun(id); err == nil {
+		deleteJobLogs(run)
+	}
+	CleanupRunArtifacts(id)
+	deleteTestReport(id)
+	deleteCoverageReport(id)
 	return storage.DeleteRun(id)
 }
 
@@ -3,6 +3,7 @@ package cicd
 import (
 	"encoding/json"
 	"fmt"
+	"mime/multipart"
 	"sort"
 	"sync"
 	"time"
@@ -20,20 +21,25 @@ var (
 	// buildOutputs stores build output for streaming
 	buildOutputs   = make(map[string]*BuildOutputStream)
 	buildOutputsMu sync.RWMutex
+
+	// buildStepsMu serializes updates of build steps, which the steps of a
+	// parallel group make at the same time
+	buildStepsMu sync.Mutex
 )
 
 // BuildOutputStream handles streaming output for a build
 type BuildOutputStream struct {
-	mu         sync.RWMutex
-	output     []byte
-	listeners  []chan []byte
-	closed     bool
+	mu        sync.RWMutex
+	output    *headTailBuffer // capped at buildOutputLimit
+	listeners []chan []byte
+	closed    bool
+	masked    *maskingWriter // masks secret values ahead of output; nil when there are none
 }
 
 // NewBuildOutputStream creates a new output stream
 func NewBuildOutputStream() *BuildOutputStream {
 	return &BuildOutputStream{
-		output:    make([]byte, 0, 4096),
+		output:    newHeadTailBuffer(buildOutputLimit()),
 		listeners: make([]chan []byte, 0),
 	}
 }
@@ -46,8 +52,15 @@ func (s *BuildOutputStream) Write(p []byte) (n int, err error) {
 	if s.closed {
 		return 0, fmt.Errorf("stream closed")
 	}
+	if s.masked != nil {
+		return s.masked.Write(p)
+	}
+	return s.write(p)
+}
 
-	s.output = append(s.output, p...)
+// write keeps and sends output; the caller holds the lock
+func (s *BuildOutputStream) write(p []byte) (int, error) {
+	s.output.Write(p)
 
 	// Notify all listeners
 	for _, ch := range s.listeners {
@@ -61,6 +74,37 @@ func (s *BuildOutputStream) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// streamOutput writes to a stream whose lock is held
+type streamOutput struct {
+	s *BuildOutputStream
+}
+
+func (o streamOutput) Write(p []byte) (int, error) {
+	return o.s.write(p)
+}
+
+// setMasker masks the output written from now on
+func (s *BuildOutputStream) setMasker(masker *logMasker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if masker != nil {
+		s.masked = newMaskingWriter(streamOutput{s}, masker)
+	}
+}
+
+// mask masks text of the build, such as a step error
+func (s *BuildOutputStream) mask(text string) string {
+	if s == nil {
+		return text
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.masked == nil {
+		return text
+	}
+	return s.masked.masker.mask(text)
+}
+
 // Subscribe returns a channel that receives new output
 func (s *BuildOutputStream) Subscribe() chan []byte {
 	s.mu.Lock()
@@ -70,8 +114,8 @@ func (s *BuildOutputStream) Subscribe() chan []byte {
 	s.listeners = append(s.listeners, ch)
 
 	// Send existing output
-	if len(s.output) > 0 {
-		ch <- s.output
+	if s.output.Len() > 0 {
+		ch <- s.output.Bytes()
 	}
 
 	return ch
@@ -96,6 +140,9 @@ func (s *BuildOutputStream) Close() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.masked != nil {
+		s.masked.Flush()
+	}
 	s.closed = true
 	for _, ch := range s.listeners {
 		close(ch)
@@ -103,11 +150,12 @@ func (s *BuildOutputStream) Close() {
 	s.listeners = nil
 }
 
-// GetOutput returns all accumulated output
+// GetOutput returns the accumulated output, truncated in the middle past
+// the build output limit
 func (s *BuildOutputStream) GetOutput() []byte {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return append([]byte{}, s.output...)
+	return s.output.Bytes()
 }
 
 // generateFreestyleBuildID generates a unique ID for a freestyle build
@@ -115,8 +163,15 @@ func generateFreestyleBuildID() string {
 	return generateID("fsb")
 }
 
-// CreateFreestyleBuild creates a new build for a job
+// CreateFreestyleBuild creates a new build for a job. Besides the
+// parameters the job declares, params may carry variables of the trigger.
 func CreateFreestyleBuild(jobID string, triggerType string, triggerRef string, params map[string]string) (*FreestyleBuild, error) {
+	return createFreestyleBuild(jobID, triggerType, triggerRef, params, nil, false)
+}
+
+// createFreestyleBuild creates a new build for a job with the files of its
+// file parameters. With strict, only parameters the job declares are taken.
+func createFreestyleBuild(jobID, triggerType, triggerRef string, params map[string]string, files map[string]*multipart.FileHeader, strict bool) (*FreestyleBuild, error) {
 	job, err := GetFreestyleJob(jobID)
 	if err != nil {
 		return nil, err
@@ -126,6 +181,12 @@ func CreateFreestyleBuild(jobID string, triggerType string, triggerRef string, p
 		return nil, fmt.Errorf("job is disabled")
 	}
 
+	// Check the parameters and apply their defaults
+	params, passwords, err := resolveBuildParameters(job, params, files, strict)
+	if err != nil {
+		return nil, err
+	}
+
 	buildNum, err := GetNextBuildNumber(jobID)
 	if err != nil {
 		buildNum = 1
@@ -137,22 +198,6 @@ func CreateFreestyleBuild(jobID string, triggerType string, triggerRef string, p
 		env[k] = v
 	}
 
-	// Apply default values for missing parameters
-	for _, p := range job.Parameters {
-		if _, ok := params[p.Name]; !ok && p.DefaultValue != "" {
-			params[p.Name] = p.DefaultValue
-		}
-	}
-
-	// Validate required parameters
-	for _, p := range job.Parameters {
-		if p.Required {
-			if _, ok := params[p.Name]; !ok {
-				return nil, fmt.Errorf("required parameter missing: %s", p.Name)
-			}
-		}
-	}
-
 	// Initialize build steps from job
 	steps := make([]FreestyleBuildStep, len(job.BuildSteps))
 	for i, s := range job.BuildSteps {
@@ -160,16 +205,19 @@ func CreateFreestyleBuild(jobID string, triggerType string, triggerRef string, p
 		hostName := ""
 		if host, err := GetSSHHost(s.HostID); err == nil {
 			hostName = host.Name
+		} else if s.HostGroup != "" {
+			hostName = s.HostGroup
 		}
 
 		steps[i] = FreestyleBuildStep{
-			StepID:   s.ID,
-			Name:     s.Name,
-			Type:     s.Type,
-			HostID:   s.HostID,
-			HostName: hostName,
-			Status:   RunStatusPending,
-			ExitCode: -1,
+			StepID:    s.ID,
+			Name:      s.Name,
+			Type:      s.Type,
+			HostID:    s.HostID,
+			HostName:  hostName,
+			HostGroup: s.HostGroup,
+			Status:    RunStatusPending,
+			ExitCode:  -1,
 		}
 	}
 
@@ -187,6 +235,11 @@ func CreateFreestyleBuild(jobID string, triggerType string, triggerRef string, p
 		CreatedAt:   time.Now(),
 	}
 
+	if err := saveFileParameters(build, files); err != nil {
+		CleanupRunArtifacts(build.ID)
+		return nil, err
+	}
+
 	// Save to storage
 	data, err := json.Marshal(build)
 	if err != nil {
@@ -194,8 +247,10 @@ func CreateFreestyleBuild(jobID string, triggerType string, triggerRef string, p
 	}
 
 	if err := storage.GetBackend().Set(storage.BucketFreestyleBuilds, build.ID, data); err != nil {
+		CleanupRunArtifacts(build.ID)
 		return nil, fmt.Errorf("failed to save build: %w", err)
 	}
+	setBuildPasswords(build.ID, passwords)
 
 	log.Info().
 		Str("id", build.ID).
@@ -317,7 +372,7 @@ func CompleteFreestyleBuild(buildID string, status RunStatus, errMsg string) err
 	now := time.Now()
 	build.Status = status
 	build.FinishedAt = &now
-	build.Error = errMsg
+	build.Error = GetBuildOutputStream(buildID).mask(errMsg)
 
 	if build.StartedAt != nil {
 		build.Duration = now.Sub(*build.StartedAt).Milliseconds()
@@ -353,16 +408,26 @@ func CompleteFreestyleBuild(buildID string, status RunStatus, errMsg string) err
 		NotifyBuildEvent(event, build)
 	}
 
+	// Trigger the jobs and pipelines chained to this one
+	triggerDownstream(build)
+
 	return UpdateFreestyleBuild(build)
 }
 
 // UpdateFreestyleBuildStep updates a single step's status
 func UpdateFreestyleBuildStep(buildID string, stepID string, status RunStatus, exitCode int, output string, errMsg string) error {
+	buildStepsMu.Lock()
+	defer buildStepsMu.Unlock()
+
 	build, err := GetFreestyleBuild(buildID)
 	if err != nil {
 		return err
 	}
 
+	stream := GetBuildOutputStream(buildID)
+	output = limitStepOutput(build, stepID, stream.mask(output))
+	errMsg = stream.mask(errMsg)
+
 	now := time.Now()
 	for i := range build.Steps {
 		if build.Steps[i].StepID == stepID {
@@ -460,7 +525,7 @@ func GetBuildLogs(buildID string) (string, error) {
 		}
 	}
 
-	return logs, nil
+	return freestyleLogMasker(build.JobID).mask(logs), nil
 }
 
 // DeleteFreestyleBuild deletes a build
@@ -477,6 +542,9 @@ func DeleteFreestyleBuild(id string) error {
 	if err := storage.GetBackend().Delete(storage.BucketFreestyleBuilds, id); err != nil {
 		return fmt.Errorf("failed to delete build: %w", err)
 	}
+	if err := CleanupRunArtifacts(id); err != nil {
+		log.Warn().Err(err).Str("id", id).Msg("Failed to delete build artifacts")
+	}
 
 	log.Info().Str("id", id).Msg("Freestyle build deleted")
 	return nil
@@ -0,0 +1,216 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Post-build actions chain a freestyle job to other freestyle jobs and
+// pipelines: when a build finishes, each action whose condition its outcome
+// meets triggers its target with the parameters it lists, which may use the
+// variables of the build, such as ${BUILD_NUMBER}. A cancelled build
+// triggers nothing. The builds and runs an action starts are recorded with
+// the build, and are triggered as "upstream" with the build as their ref.
+
+// Kinds of post-build action targets
+const (
+	DownstreamKindJob      = "job"
+	DownstreamKindPipeline = "pipeline"
+)
+
+// When post-build actions trigger
+const (
+	DownstreamOnSuccess = "success"
+	DownstreamOnFailure = "failure"
+	DownstreamOnAlways  = "always" // success or failure
+)
+
+// TriggerTypeUpstream is the trigger type of builds and runs a post-build
+// action started
+const TriggerTypeUpstream = "upstream"
+
+// PostBuildAction triggers a freestyle job or pipeline when a build finishes
+type PostBuildAction struct {
+	Kind           string            `json:"kind"`                      // job or pipeline
+	Target         string            `json:"target"`                    // ID of the freestyle job or pipeline
+	On             string            `json:"on,omitempty"`              // success (default), failure or always
+	Parameters     map[string]string `json:"parameters,omitempty"`      // parameters, or pipeline variables, of the target
+	PassParameters bool              `json:"pass_parameters,omitempty"` // also pass the parameters of the build
+}
+
+// DownstreamBuild is a build or run a post-build action started, or the
+// error it failed with
+type DownstreamBuild struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	Name   string `json:"name,omitempty"`
+	ID     string `json:"id,omitempty"`     // build or run ID
+	Number int    `json:"number,omitempty"` // build or run number
+	Error  string `json:"error,omitempty"`
+}
+
+// validatePostBuildActions checks the post-build actions of a job, that
+// their targets exist and that jobs do not trigger each other in a loop
+func validatePostBuildActions(jobID string, actions []PostBuildAction) error {
+	for i, a := range actions {
+		switch a.On {
+		case "", DownstreamOnSuccess, DownstreamOnFailure, DownstreamOnAlways:
+		default:
+			return fmt.Errorf("post_build[%d].on must be %s, %s or %s", i, DownstreamOnSuccess, DownstreamOnFailure, DownstreamOnAlways)
+		}
+		if a.Target == "" {
+			return fmt.Errorf("post_build[%d].target is required", i)
+		}
+		switch a.Kind {
+		case DownstreamKindJob:
+			if a.Target == jobID {
+				return fmt.Errorf("post_build[%d] triggers the job itself", i)
+			}
+			if _, err := GetFreestyleJob(a.Target); err != nil {
+				return fmt.Errorf("post_build[%d]: %w", i, err)
+			}
+		case DownstreamKindPipeline:
+			if _, err := GetPipeline(a.Target); err != nil {
+				return fmt.Errorf("post_build[%d]: %w", i, err)
+			}
+		default:
+			return fmt.Errorf("post_build[%d].kind must be %s or %s", i, DownstreamKindJob, DownstreamKindPipeline)
+		}
+	}
+	if jobID == "" {
+		return nil
+	}
+	return checkDownstreamLoop(jobID, actions)
+}
+
+// checkDownstreamLoop reports a freestyle job that its post-build actions
+// would trigger again. Pipelines trigger no jobs, so only jobs can loop.
+func checkDownstreamLoop(jobID string, actions []PostBuildAction) error {
+	jobs, err := ListFreestyleJobs()
+	if err != nil {
+		return err
+	}
+	names := make(map[string]string, len(jobs))
+	next := make(map[string][]string, len(jobs))
+	for _, j := range jobs {
+		names[j.ID] = j.Name
+		if j.ID == jobID {
+			continue
+		}
+		for _, a := range j.PostBuild {
+			if a.Kind == DownstreamKindJob {
+				next[j.ID] = append(next[j.ID], a.Target)
+			}
+		}
+	}
+	for _, a := range actions {
+		if a.Kind == DownstreamKindJob {
+			next[jobID] = append(next[jobID], a.Target)
+		}
+	}
+
+	visited := make(map[string]bool)
+	var find func(id string) []string // the jobs from id back to jobID
+	find = func(id string) []string {
+		for _, n := range next[id] {
+			if n == jobID {
+				return []string{names[id], names[jobID]}
+			}
+			if visited[n] {
+				continue
+			}
+			visited[n] = true
+			if path := find(n); path != nil {
+				return append([]string{names[id]}, path...)
+			}
+		}
+		return nil
+	}
+	if path := find(jobID); path != nil {
+		return fmt.Errorf("post-build actions would trigger the job in a loop: %s", strings.Join(path, " -> "))
+	}
+	return nil
+}
+
+// downstreamApplies tells whether a post-build action triggers after a
+// build with status
+func downstreamApplies(on string, status RunStatus) bool {
+	switch on {
+	case "", DownstreamOnSuccess:
+		return status == RunStatusSucceeded
+	case DownstreamOnFailure:
+		return status == RunStatusFailed
+	case DownstreamOnAlways:
+		return status == RunStatusSucceeded || status == RunStatusFailed
+	}
+	return false
+}
+
+// downstreamParameters are the parameters a post-build action passes on
+func downstreamParameters(a *PostBuildAction, build *FreestyleBuild, job *FreestyleJob) map[string]string {
+	params := make(map[string]string, len(a.Parameters)+len(build.Parameters))
+	if a.PassParameters {
+		for k, v := range build.Parameters {
+			params[k] = v
+		}
+	}
+	for k, v := range a.Parameters {
+		params[k] = expandVariables(v, build, job)
+	}
+	return params
+}
+
+// triggerDownstream runs the post-build actions of a finished build and
+// records what they started with it
+func triggerDownstream(build *FreestyleBuild) {
+	job, err := GetFreestyleJob(build.JobID)
+	if err != nil || len(job.PostBuild) == 0 {
+		return
+	}
+
+	ref := fmt.Sprintf("%s #%d", build.JobName, build.BuildNumber)
+	for i := range job.PostBuild {
+		a := &job.PostBuild[i]
+		if !downstreamApplies(a.On, build.Status) {
+			continue
+		}
+		params := downstreamParameters(a, build, job)
+		d := DownstreamBuild{Kind: a.Kind, Target: a.Target}
+
+		switch a.Kind {
+		case DownstreamKindJob:
+			b, err := TriggerFreestyleBuild(a.Target, TriggerTypeUpstream, ref, params)
+			if err != nil {
+				d.Error = err.Error()
+				break
+			}
+			d.Name, d.ID, d.Number = b.JobName, b.ID, b.BuildNumber
+		case DownstreamKindPipeline:
+			pipeline, err := GetPipeline(a.Target)
+			if err != nil {
+				d.Error = err.Error()
+				break
+			}
+			d.Name = pipeline.Name
+			run, err := TriggerPipeline(context.Background(), pipeline, TriggerTypeUpstream, ref, params)
+			if err != nil {
+				d.Error = err.Error()
+				break
+			}
+			d.ID, d.Number = run.ID, run.RunNumber
+		}
+
+		if d.Error != "" {
+			log.Warn().Str("build", build.ID).Str("kind", d.Kind).Str("target", d.Target).Str("error", d.Error).Msg("Post-build action failed")
+		} else {
+			log.Info().Str("build", build.ID).Str("kind", d.Kind).Str("target", d.Name).Str("id", d.ID).Msg("Post-build action triggered")
+		}
+		build.Downstream = append(build.Downstream, d)
+	}
+}
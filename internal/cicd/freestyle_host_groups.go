@@ -0,0 +1,188 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// A build step with a host group runs on every SSH host of the group rather
+// than on a single host, for commands meant for a whole fleet. The hosts run
+// one after the other, or up to the step's concurrency at a time, in which
+// case the output of each host is kept apart and added to the log as one
+// section when it is done. The step records the exit code and output of
+// every host, and fails when any host fails.
+
+// StepHostResult is how a step on a host group went on one of its hosts
+type StepHostResult struct {
+	HostID   string    `json:"host_id"`
+	HostName string    `json:"host_name"`
+	Status   RunStatus `json:"status"`
+	ExitCode int       `json:"exit_code"`
+	Output   string    `json:"output,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// validateStepHostGroups checks the steps that run on a host group
+func validateStepHostGroups(steps []BuildStep) error {
+	for i, step := range steps {
+		if step.HostGroup == "" {
+			if step.Concurrency != 0 {
+				return fmt.Errorf("build_steps[%d].concurrency only applies to steps on a host group", i)
+			}
+			continue
+		}
+		if step.HostID != "" && step.HostID != "local" {
+			return fmt.Errorf("build_steps[%d] cannot have both a host and a host group", i)
+		}
+		if step.Type == StepTypeSCPPull || step.Type == StepTypeSFTPDownload {
+			return fmt.Errorf("build_steps[%d]: %s steps cannot run on a host group", i, step.Type)
+		}
+		if step.Concurrency < 0 {
+			return fmt.Errorf("build_steps[%d].concurrency cannot be negative", i)
+		}
+		if _, err := sshRunnerHosts(step.HostGroup); err != nil {
+			return fmt.Errorf("build_steps[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// noteWorkspaceHosts notes the hosts steps make the build's workspace on
+// before they run at the same time, as they then only read the build
+func noteWorkspaceHosts(build *FreestyleBuild, steps []BuildStep) {
+	if build.workspace == "" {
+		return
+	}
+	for _, step := range steps {
+		if step.HostGroup != "" {
+			hosts, _ := sshRunnerHosts(step.HostGroup)
+			for _, h := range hosts {
+				build.useWorkspace(h.ID)
+			}
+		} else if step.HostID != "" && step.HostID != "local" {
+			build.useWorkspace(step.HostID)
+		}
+	}
+}
+
+// executeHostGroupStep executes a step on every host of its host group
+func executeHostGroupStep(outputID string, build *FreestyleBuild, job *FreestyleJob, step *BuildStep, cancelCh <-chan struct{}) error {
+	hosts, err := sshRunnerHosts(step.HostGroup)
+	if err != nil {
+		WriteBuildOutput(outputID, []byte(fmt.Sprintf("Failed to get SSH hosts: %s\n", err)))
+		UpdateFreestyleBuildStep(build.ID, step.ID, RunStatusFailed, -1, "", err.Error())
+		return err
+	}
+
+	concurrency := step.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	concurrency = min(concurrency, len(hosts))
+	WriteBuildOutput(outputID, []byte(fmt.Sprintf("Host group: %s (%d hosts, %d at a time)\n", step.HostGroup, len(hosts), concurrency)))
+	if concurrency > 1 {
+		noteWorkspaceHosts(build, []BuildStep{*step})
+	}
+
+	results := make([]StepHostResult, len(hosts))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		select {
+		case <-cancelCh:
+			results[i] = StepHostResult{HostID: host.ID, HostName: host.Name, Status: RunStatusCancelled, ExitCode: -1}
+			continue
+		case sem <- struct{}{}:
+		}
+
+		// Hosts running one at a time write to the step's output as they go
+		hostOutputID := outputID
+		var stream *BuildOutputStream
+		if concurrency > 1 {
+			hostOutputID = outputID + "@" + host.ID
+			stream = NewBuildOutputStream()
+			buildOutputsMu.Lock()
+			buildOutputs[hostOutputID] = stream
+			buildOutputsMu.Unlock()
+		}
+
+		wg.Add(1)
+		go func(i int, host *SSHHost) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			WriteBuildOutput(hostOutputID, []byte(fmt.Sprintf("\n[%s]\n", host.Name)))
+			exitCode, output, err := executeRemoteStep(hostOutputID, build, job, step, host, cancelCh)
+			r := StepHostResult{HostID: host.ID, HostName: host.Name, Status: RunStatusSucceeded, ExitCode: exitCode, Output: output}
+			switch {
+			case err != nil:
+				r.Status, r.Error = RunStatusFailed, err.Error()
+			case exitCode != 0:
+				r.Status, r.Error = RunStatusFailed, fmt.Sprintf("Exit code: %d", exitCode)
+			}
+			results[i] = r
+
+			if stream != nil {
+				buildOutputsMu.Lock()
+				delete(buildOutputs, hostOutputID)
+				buildOutputsMu.Unlock()
+				stream.Close()
+				WriteBuildOutput(outputID, stream.GetOutput())
+			}
+		}(i, host)
+	}
+	wg.Wait()
+
+	// The step's output is that of each host in turn
+	var output strings.Builder
+	var failed []string
+	exitCode := 0
+	for _, r := range results {
+		fmt.Fprintf(&output, "[%s]\n%s", r.HostName, r.Output)
+		if r.Status == RunStatusSucceeded {
+			continue
+		}
+		failed = append(failed, r.HostName)
+		if exitCode == 0 {
+			exitCode = r.ExitCode
+		}
+	}
+	recordStepHosts(build.ID, step.ID, results)
+
+	if len(failed) > 0 {
+		err := fmt.Errorf("step failed on %d of %d hosts: %s", len(failed), len(results), strings.Join(failed, ", "))
+		WriteBuildOutput(outputID, []byte(err.Error()+"\n"))
+		UpdateFreestyleBuildStep(build.ID, step.ID, RunStatusFailed, exitCode, output.String(), err.Error())
+		return err
+	}
+	UpdateFreestyleBuildStep(build.ID, step.ID, RunStatusSucceeded, 0, output.String(), "")
+	return nil
+}
+
+// recordStepHosts stores the per-host results of a step with its build
+func recordStepHosts(buildID, stepID string, results []StepHostResult) error {
+	buildStepsMu.Lock()
+	defer buildStepsMu.Unlock()
+
+	build, err := GetFreestyleBuild(buildID)
+	if err != nil {
+		return err
+	}
+	stream := GetBuildOutputStream(buildID)
+	for i := range results {
+		results[i].Output = stream.mask(results[i].Output)
+		results[i].Error = stream.mask(results[i].Error)
+	}
+	limitHostOutputs(results)
+	for i := range build.Steps {
+		if build.Steps[i].StepID == stepID {
+			build.Steps[i].Hosts = results
+			break
+		}
+	}
+	return UpdateFreestyleBuild(build)
+}
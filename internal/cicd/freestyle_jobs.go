@@ -23,6 +23,28 @@ func generateBuildStepID() string {
 
 // CreateFreestyleJob creates a new freestyle job
 func CreateFreestyleJob(req *CreateFreestyleJobRequest) (*FreestyleJob, error) {
+	if err := validateBuildParameters(req.Parameters); err != nil {
+		return nil, err
+	}
+	if err := validatePostBuildActions("", req.PostBuild); err != nil {
+		return nil, err
+	}
+	if err := validateWorkspace(req.Workspace); err != nil {
+		return nil, err
+	}
+	if err := validateArtifactArchive(req.Archive, req.BuildSteps); err != nil {
+		return nil, err
+	}
+	if err := validateParallelSteps(req.BuildSteps); err != nil {
+		return nil, err
+	}
+	if err := validateStepHostGroups(req.BuildSteps); err != nil {
+		return nil, err
+	}
+	if err := validateTransferSteps(req.BuildSteps); err != nil {
+		return nil, err
+	}
+
 	// Assign IDs to build steps
 	for i := range req.BuildSteps {
 		if req.BuildSteps[i].ID == "" {
@@ -44,6 +66,10 @@ func CreateFreestyleJob(req *CreateFreestyleJobRequest) (*FreestyleJob, error) {
 		Environment: req.Environment,
 		BuildSteps:  req.BuildSteps,
 		Triggers:    req.Triggers,
+		Retention:   req.Retention,
+		PostBuild:   req.PostBuild,
+		Workspace:   req.Workspace,
+		Archive:     req.Archive,
 		Status: FreestyleJobStatus{
 			TotalBuilds: 0,
 		},
@@ -98,15 +124,16 @@ func GetFreestyleJob(id string) (*FreestyleJob, error) {
 	return &job, nil
 }
 
-// GetFreestyleJobByWebhookToken retrieves a job by its webhook token
+// GetFreestyleJobByWebhookToken retrieves a job by one of its webhook tokens
 func GetFreestyleJobByWebhookToken(token string) (*FreestyleJob, error) {
 	jobs, err := ListFreestyleJobs()
 	if err != nil {
 		return nil, err
 	}
 
+	now := time.Now()
 	for _, job := range jobs {
-		if job.Status.WebhookToken == token {
+		if t, _ := freestyleTokenSet(job).match(token, "", now); t != nil {
 			return job, nil
 		}
 	}
@@ -114,6 +141,37 @@ func GetFreestyleJobByWebhookToken(token string) (*FreestyleJob, error) {
 	return nil, fmt.Errorf("job not found for webhook token")
 }
 
+// UseFreestyleWebhookToken finds the job of a webhook token, records the
+// call from ip and checks the token's branch scope
+func UseFreestyleWebhookToken(token, branch, ip string) (*FreestyleJob, error) {
+	job, err := GetFreestyleJobByWebhookToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	webhookToken, err := freestyleTokenSet(job).match(token, branch, now)
+	if webhookToken == nil {
+		return nil, err
+	}
+	if useErr := recordWebhookTokenUse(WebhookKindFreestyle, job.ID, webhookToken, ip, now); useErr != nil {
+		log.Warn().Err(useErr).Str("job", job.Name).Msg("Failed to record webhook token use")
+	}
+	return job, err
+}
+
+// saveFreestyleJob writes a job to storage
+func saveFreestyleJob(job *FreestyleJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	if err := storage.GetBackend().Set(storage.BucketFreestyleJobs, job.ID, data); err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
+
 // ListFreestyleJobs returns all freestyle jobs
 func ListFreestyleJobs() ([]*FreestyleJob, error) {
 	dataList, err := storage.GetBackend().List(storage.BucketFreestyleJobs)
@@ -145,6 +203,27 @@ func UpdateFreestyleJob(id string, req *CreateFreestyleJobRequest) (*FreestyleJo
 	if err != nil {
 		return nil, err
 	}
+	if err := validateBuildParameters(req.Parameters); err != nil {
+		return nil, err
+	}
+	if err := validatePostBuildActions(id, req.PostBuild); err != nil {
+		return nil, err
+	}
+	if err := validateWorkspace(req.Workspace); err != nil {
+		return nil, err
+	}
+	if err := validateArtifactArchive(req.Archive, req.BuildSteps); err != nil {
+		return nil, err
+	}
+	if err := validateParallelSteps(req.BuildSteps); err != nil {
+		return nil, err
+	}
+	if err := validateStepHostGroups(req.BuildSteps); err != nil {
+		return nil, err
+	}
+	if err := validateTransferSteps(req.BuildSteps); err != nil {
+		return nil, err
+	}
 
 	// Update fields
 	job.Name = req.Name
@@ -153,6 +232,10 @@ func UpdateFreestyleJob(id string, req *CreateFreestyleJobRequest) (*FreestyleJo
 	job.Parameters = req.Parameters
 	job.Environment = req.Environment
 	job.Triggers = req.Triggers
+	job.Retention = req.Retention
+	job.PostBuild = req.PostBuild
+	job.Workspace = req.Workspace
+	job.Archive = req.Archive
 
 	// Update build steps with IDs
 	for i := range req.BuildSteps {
@@ -183,6 +266,7 @@ func UpdateFreestyleJob(id string, req *CreateFreestyleJobRequest) (*FreestyleJo
 		job.Status.WebhookToken = ""
 		job.Status.WebhookSecret = ""
 		job.Status.WebhookURL = ""
+		job.Status.WebhookTokens = nil
 	}
 
 	job.UpdatedAt = time.Now()
@@ -230,6 +314,8 @@ func DeleteFreestyleJob(id string) error {
 	if err := storage.GetBackend().Delete(storage.BucketFreestyleJobs, id); err != nil {
 		return fmt.Errorf("failed to delete job: %w", err)
 	}
+	deleteScopedSecrets(SecretScopeFreestyle, id)
+	pruneWebhookTokenUse(WebhookKindFreestyle, id, nil)
 
 	log.Info().Str("id", id).Msg("Freestyle job deleted")
 	return nil
@@ -0,0 +1,172 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// The output of a freestyle build is kept in memory while it runs, for the
+// live log, and the output of each step is stored with the build. A step
+// printing without end would grow both without bound, so both are capped:
+// past a cap, the first and the last half of the output are kept, with a
+// marker for how much was dropped in between. GAGOS_BUILD_OUTPUT_MAX_MB
+// (default 16) caps the output a build keeps in memory, and
+// GAGOS_BUILD_LOG_MAX_MB (default 4) the output stored with a build across
+// its steps, though each step keeps at least minStepLog of its own. 0 turns
+// a cap off. Live log viewers still see all of the output as it comes.
+
+// minStepLog is the output kept of a step once its build's stored log
+// limit is used up
+const minStepLog = 64 * 1024
+
+// buildOutputLimit is how much output a running build keeps in memory
+// (GAGOS_BUILD_OUTPUT_MAX_MB, default 16)
+func buildOutputLimit() int {
+	return envMegabytes("GAGOS_BUILD_OUTPUT_MAX_MB", 16)
+}
+
+// buildLogLimit is how much output is stored with a build
+// (GAGOS_BUILD_LOG_MAX_MB, default 4)
+func buildLogLimit() int {
+	return envMegabytes("GAGOS_BUILD_LOG_MAX_MB", 4)
+}
+
+func envMegabytes(name string, def int) int {
+	if mb, err := strconv.Atoi(os.Getenv(name)); err == nil && mb >= 0 {
+		return mb << 20
+	}
+	return def << 20
+}
+
+// truncationMarker stands for the output dropped from the middle
+func truncationMarker(dropped int64) string {
+	return fmt.Sprintf("\n... [%s of output truncated] ...\n", formatBytes(dropped))
+}
+
+// headTailBuffer keeps the first and the last bytes written to it, half of
+// its limit each, and counts the bytes dropped in between. Without a limit
+// it keeps everything.
+type headTailBuffer struct {
+	limit   int
+	head    []byte
+	tail    []byte // ring of the last bytes, allocated once the head is full
+	pos     int    // where the next byte goes in tail
+	full    bool   // tail has wrapped around
+	dropped int64
+}
+
+func newHeadTailBuffer(limit int) *headTailBuffer {
+	return &headTailBuffer{limit: limit}
+}
+
+// Write implements io.Writer
+func (b *headTailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.limit <= 0 {
+		b.head = append(b.head, p...)
+		return n, nil
+	}
+
+	if room := b.limit/2 - len(b.head); room > 0 {
+		k := min(room, len(p))
+		b.head = append(b.head, p[:k]...)
+		p = p[k:]
+	}
+	if len(p) == 0 {
+		return n, nil
+	}
+
+	if b.tail == nil {
+		b.tail = make([]byte, b.limit-b.limit/2)
+	}
+	size := len(b.tail)
+	kept := b.pos
+	if b.full {
+		kept = size
+	}
+	if len(p) >= size {
+		b.dropped += int64(kept + len(p) - size)
+		copy(b.tail, p[len(p)-size:])
+		b.pos, b.full = 0, true
+		return n, nil
+	}
+	if over := kept + len(p) - size; over > 0 {
+		b.dropped += int64(over)
+	}
+	k := copy(b.tail[b.pos:], p)
+	copy(b.tail, p[k:])
+	if b.pos+len(p) >= size {
+		b.full = true
+	}
+	b.pos = (b.pos + len(p)) % size
+	return n, nil
+}
+
+// Len is the size of what Bytes returns, without the marker
+func (b *headTailBuffer) Len() int {
+	if b.full {
+		return len(b.head) + len(b.tail)
+	}
+	return len(b.head) + b.pos
+}
+
+// Bytes returns a copy of the output kept, with a marker where output was
+// dropped
+func (b *headTailBuffer) Bytes() []byte {
+	out := make([]byte, 0, b.Len()+64)
+	out = append(out, b.head...)
+	if b.dropped > 0 {
+		out = append(out, truncationMarker(b.dropped)...)
+	}
+	if b.full {
+		out = append(out, b.tail[b.pos:]...)
+	}
+	return append(out, b.tail[:b.pos]...)
+}
+
+func (b *headTailBuffer) String() string {
+	return string(b.Bytes())
+}
+
+// truncateHeadTail keeps the first and the last half of limit bytes of s
+func truncateHeadTail(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	head := limit / 2
+	tail := limit - head
+	return s[:head] + truncationMarker(int64(len(s)-limit)) + s[len(s)-tail:]
+}
+
+// limitStepOutput truncates the output of a step to what is left of its
+// build's stored log limit after the other steps
+func limitStepOutput(build *FreestyleBuild, stepID, output string) string {
+	limit := buildLogLimit()
+	if limit <= 0 {
+		return output
+	}
+	used := 0
+	for _, step := range build.Steps {
+		if step.StepID != stepID {
+			used += len(step.Output)
+		}
+	}
+	return truncateHeadTail(output, max(limit-used, minStepLog))
+}
+
+// limitHostOutputs truncates the outputs of the hosts of a step on a host
+// group to a share each of the build's stored log limit
+func limitHostOutputs(results []StepHostResult) {
+	limit := buildLogLimit()
+	if limit <= 0 || len(results) == 0 {
+		return
+	}
+	share := max(limit/len(results), minStepLog)
+	for i := range results {
+		results[i].Output = truncateHeadTail(results[i].Output, share)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestTruncateHeadTail(t *testing.T) {
+	tests := []struct {
+		s     string
+		limit int
+		want  string
+	}{
+		{"abcdef", 0, "abcdef"},
+		{"abcdef", 6, "abcdef"},
+		{"abcdef", 4, "ab" + truncationMarker(2) + "ef"},
+		{"abcdefg", 3, "a" + truncationMarker(4) + "fg"},
+	}
+	for _, tt := range tests {
+		if got := truncateHeadTail(tt.s, tt.limit); got != tt.want {
+			t.Errorf("truncateHeadTail(%q, %d) = %q, want %q", tt.s, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestHeadTailBuffer(t *testing.T) {
+	// Whatever the write sizes, the buffer keeps what truncateHeadTail
+	// keeps of the whole output
+	rng := rand.New(rand.NewSource(1))
+	for _, limit := range []int{0, 1, 2, 7, 64, 1000} {
+		for run := 0; run < 50; run++ {
+			b := newHeadTailBuffer(limit)
+			var all strings.Builder
+			for writes := rng.Intn(20); writes > 0; writes-- {
+				p := make([]byte, rng.Intn(3*limit+5))
+				for i := range p {
+					p[i] = byte('a' + rng.Intn(26))
+				}
+				if n, err := b.Write(p); n != len(p) || err != nil {
+					t.Fatalf("Write = %d, %v", n, err)
+				}
+				all.Write(p)
+			}
+			want := truncateHeadTail(all.String(), limit)
+			if got := b.String(); got != want {
+				t.Fatalf("limit %d after %d bytes: got %q, want %q", limit, all.Len(), got, want)
+			}
+			if kept := min(all.Len(), limit); limit > 0 && b.Len() != kept {
+				t.Fatalf("limit %d: Len = %d, want %d", limit, b.Len(), kept)
+			}
+		}
+	}
+}
+
+func TestLimitStepOutput(t *testing.T) {
+	t.Setenv("GAGOS_BUILD_LOG_MAX_MB", "1")
+	build := &FreestyleBuild{Steps: []FreestyleBuildStep{
+		{StepID: "first", Output: strings.Repeat("a", 1<<20-100)},
+		{StepID: "second"},
+	}}
+	// The second step still keeps minStepLog once the first used the limit
+	out := limitStepOutput(build, "second", strings.Repeat("b", 1<<20))
+	if !strings.Contains(out, "truncated") || strings.Count(out, "b") != minStepLog {
+		t.Errorf("kept %d bytes of the second step", strings.Count(out, "b"))
+	}
+	// A step is not counted against itself
+	if out := limitStepOutput(build, "first", build.Steps[0].Output); out != build.Steps[0].Output {
+		t.Error("first step truncated within the limit")
+	}
+
+	t.Setenv("GAGOS_BUILD_LOG_MAX_MB", "0")
+	if out := limitStepOutput(build, "second", strings.Repeat("b", 2<<20)); len(out) != 2<<20 {
+		t.Error("output truncated with the limit off")
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Adjacent build steps with the same parallel group run at the same time,
+// typically the same deployment on several hosts, and the build waits for
+// all of them before going on. While they run, the output of each step is
+// kept apart; it is added to the build log as one section when the step
+// finishes, and the group ends with a summary of its steps. A step of the
+// group that fails without continue_on_error fails the build once the whole
+// group is done.
+
+// stepGroups splits the steps of a job into what runs one after the other:
+// a single step, or the steps of a parallel group
+func stepGroups(steps []BuildStep) [][]BuildStep {
+	var groups [][]BuildStep
+	for i, step := range steps {
+		if n := len(groups); n > 0 && step.Parallel != "" && step.Parallel == steps[i-1].Parallel {
+			groups[n-1] = append(groups[n-1], step)
+			continue
+		}
+		groups = append(groups, []BuildStep{step})
+	}
+	return groups
+}
+
+// validateParallelSteps checks that the steps of each parallel group are
+// next to each other
+func validateParallelSteps(steps []BuildStep) error {
+	done := make(map[string]bool)
+	for _, group := range stepGroups(steps) {
+		name := group[0].Parallel
+		if name == "" {
+			continue
+		}
+		if done[name] {
+			return fmt.Errorf("steps of parallel group %s must be next to each other", name)
+		}
+		done[name] = true
+	}
+	return nil
+}
+
+// stepOutputID is the output stream of a step of a parallel group
+func stepOutputID(buildID, stepID string) string {
+	return buildID + "/" + stepID
+}
+
+// executeParallelSteps runs the steps of a parallel group at the same time
+// and waits for all of them
+func executeParallelSteps(buildID string, build *FreestyleBuild, job *FreestyleJob, steps []BuildStep, cancelCh <-chan struct{}) error {
+	group := steps[0].Parallel
+	WriteBuildOutput(buildID, []byte(fmt.Sprintf("\n=== Parallel group %s: %d steps ===\n", group, len(steps))))
+
+	noteWorkspaceHosts(build, steps)
+
+	errs := make([]error, len(steps))
+	var wg sync.WaitGroup
+	for i := range steps {
+		step := &steps[i]
+		outputID := stepOutputID(buildID, step.ID)
+		stream := NewBuildOutputStream()
+		buildOutputsMu.Lock()
+		buildOutputs[outputID] = stream
+		buildOutputsMu.Unlock()
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = executeStep(outputID, build, job, step, cancelCh)
+
+			buildOutputsMu.Lock()
+			delete(buildOutputs, outputID)
+			buildOutputsMu.Unlock()
+			stream.Close()
+			WriteBuildOutput(buildID, stream.GetOutput())
+		}(i)
+	}
+	wg.Wait()
+
+	var summary strings.Builder
+	var failed []string
+	fmt.Fprintf(&summary, "\n=== Parallel group %s finished ===\n", group)
+	for i, step := range steps {
+		if errs[i] == nil {
+			fmt.Fprintf(&summary, "  %s: succeeded\n", step.Name)
+			continue
+		}
+		fmt.Fprintf(&summary, "  %s: failed: %s\n", step.Name, errs[i])
+		if !step.ContinueOnError {
+			failed = append(failed, fmt.Sprintf("%s: %s", step.Name, errs[i]))
+		}
+	}
+	WriteBuildOutput(buildID, []byte(summary.String()))
+
+	if len(failed) > 0 {
+		return fmt.Errorf("parallel group %s failed: %s", group, strings.Join(failed, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A freestyle job declares the parameters a build takes, each with a type.
+// They are checked when a build is triggered: required ones must be given,
+// choices must be one of the job's and booleans read as true or false. A
+// user triggering a build may only pass declared parameters; webhooks, SCM
+// polls and upstream builds also pass the variables of what triggered them.
+//
+// Password parameters are not stored with the build: like secret variables
+// they are exported ahead of each step and masked in the log. File
+// parameters are uploaded with the trigger and stored as artifacts of the
+// build; the parameter holds the path of the file on the GAGOS server, for
+// scp steps to copy to a host.
+
+// Types of build parameters
+const (
+	ParamTypeString   = "string"
+	ParamTypeChoice   = "choice"
+	ParamTypeBoolean  = "boolean"
+	ParamTypePassword = "password"
+	ParamTypeFile     = "file"
+)
+
+// ErrInvalidParameters is wrapped by the errors of parameters a build was
+// triggered with
+var ErrInvalidParameters = errors.New("invalid parameters")
+
+// buildPasswords holds the password parameters of builds that have not
+// started executing yet, by build ID
+var (
+	buildPasswords   = make(map[string]map[string]string)
+	buildPasswordsMu sync.Mutex
+)
+
+// validateBuildParameters checks the parameters a job declares
+func validateBuildParameters(params []BuildParameter) error {
+	names := make(map[string]bool, len(params))
+	for i, p := range params {
+		if !shellName.MatchString(p.Name) {
+			return fmt.Errorf("parameters[%d].name must be a valid environment variable name", i)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicate parameter: %s", p.Name)
+		}
+		names[p.Name] = true
+
+		switch p.Type {
+		case "", ParamTypeString:
+		case ParamTypeChoice:
+			if len(p.Choices) == 0 {
+				return fmt.Errorf("parameter %s of type choice needs choices", p.Name)
+			}
+			if p.DefaultValue != "" && !containsString(p.Choices, p.DefaultValue) {
+				return fmt.Errorf("default of parameter %s is not one of its choices", p.Name)
+			}
+		case ParamTypeBoolean:
+			if p.DefaultValue != "" {
+				if _, err := strconv.ParseBool(p.DefaultValue); err != nil {
+					return fmt.Errorf("default of parameter %s must be true or false", p.Name)
+				}
+			}
+		case ParamTypePassword, ParamTypeFile:
+			if p.DefaultValue != "" {
+				return fmt.Errorf("parameter %s of type %s cannot have a default", p.Name, p.Type)
+			}
+		default:
+			return fmt.Errorf("parameter %s has an unknown type %q; must be %s, %s, %s, %s or %s", p.Name, p.Type,
+				ParamTypeString, ParamTypeChoice, ParamTypeBoolean, ParamTypePassword, ParamTypeFile)
+		}
+		if p.Type != ParamTypeChoice && len(p.Choices) > 0 {
+			return fmt.Errorf("parameter %s: choices only apply to parameters of type choice", p.Name)
+		}
+	}
+	return nil
+}
+
+// resolveBuildParameters checks the parameters a build is triggered with
+// against those the job declares and fills in defaults. Password parameters
+// are returned apart. With strict, parameters the job does not declare are
+// rejected.
+func resolveBuildParameters(job *FreestyleJob, params map[string]string, files map[string]*multipart.FileHeader, strict bool) (values, passwords map[string]string, err error) {
+	values = make(map[string]string, len(params))
+	for k, v := range params {
+		values[k] = v
+	}
+	passwords = make(map[string]string)
+
+	declared := make(map[string]bool, len(job.Parameters))
+	var problems []string
+	for _, p := range job.Parameters {
+		declared[p.Name] = true
+		value, given := values[p.Name]
+		if p.Type == ParamTypeFile {
+			// Set once the file is stored with the build
+			delete(values, p.Name)
+			if given {
+				problems = append(problems, fmt.Sprintf("%s is a file and must be uploaded", p.Name))
+			} else if files[p.Name] == nil && p.Required {
+				problems = append(problems, fmt.Sprintf("required parameter missing: %s", p.Name))
+			}
+			continue
+		}
+		if !given || value == "" {
+			value = p.DefaultValue
+			if value == "" && p.Type == ParamTypeChoice {
+				value = p.Choices[0]
+			}
+		}
+		if value == "" && p.Required {
+			problems = append(problems, fmt.Sprintf("required parameter missing: %s", p.Name))
+			continue
+		}
+
+		switch p.Type {
+		case ParamTypeChoice:
+			if !containsString(p.Choices, value) {
+				problems = append(problems, fmt.Sprintf("%s must be one of %s", p.Name, strings.Join(p.Choices, ", ")))
+				continue
+			}
+		case ParamTypeBoolean:
+			b := false
+			if value != "" {
+				if b, err = strconv.ParseBool(value); err != nil {
+					problems = append(problems, fmt.Sprintf("%s must be true or false", p.Name))
+					continue
+				}
+			}
+			value = strconv.FormatBool(b)
+		case ParamTypePassword:
+			delete(values, p.Name)
+			if value != "" {
+				passwords[p.Name] = value
+			}
+			continue
+		}
+		values[p.Name] = value
+	}
+
+	var unknown []string
+	if strict {
+		for name := range values {
+			if !declared[name] {
+				unknown = append(unknown, name)
+			}
+		}
+	}
+	for name := range files {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		problems = append(problems, fmt.Sprintf("unknown parameter: %s", name))
+	}
+
+	if len(problems) > 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidParameters, strings.Join(problems, "; "))
+	}
+	return values, passwords, nil
+}
+
+// saveFileParameters stores the files a build was triggered with as its
+// artifacts and sets their parameters to where they are stored
+func saveFileParameters(build *FreestyleBuild, files map[string]*multipart.FileHeader) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fh := files[name]
+		f, err := fh.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read file parameter %s: %w", name, err)
+		}
+		artifact, err := SaveArtifact(build.ID, build.JobID, name, filepath.Base(fh.Filename), f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to store file parameter %s: %w", name, err)
+		}
+		build.Parameters[name] = artifact.Path
+	}
+	return nil
+}
+
+// setBuildPasswords keeps the password parameters of a build until it
+// executes
+func setBuildPasswords(buildID string, passwords map[string]string) {
+	if len(passwords) == 0 {
+		return
+	}
+	buildPasswordsMu.Lock()
+	defer buildPasswordsMu.Unlock()
+	buildPasswords[buildID] = passwords
+}
+
+// takeBuildPasswords returns the password parameters of a build and forgets
+// them
+func takeBuildPasswords(buildID string) map[string]string {
+	buildPasswordsMu.Lock()
+	defer buildPasswordsMu.Unlock()
+	passwords := buildPasswords[buildID]
+	delete(buildPasswords, buildID)
+	return passwords
+}
@@ -1,6 +1,9 @@
 package cicd
 
-import "time"
+import (
+	"mime/multipart"
+	"time"
+)
 
 // ============ SSH Host Types ============
 
@@ -186,6 +189,9 @@ const (
 	StepTypeScript   BuildStepType = "script"   // Execute script content
 	StepTypeSCPPush  BuildStepType = "scp_push" // Copy files TO remote
 	StepTypeSCPPull  BuildStepType = "scp_pull" // Copy files FROM remote
+
+	StepTypeSFTPUpload   BuildStepType = "sftp_upload"   // Copy a directory tree TO remote
+	StepTypeSFTPDownload BuildStepType = "sftp_download" // Copy a directory tree FROM remote
 )
 
 // BuildStep represents a single step in a freestyle job
@@ -195,18 +201,22 @@ type BuildStep struct {
 	Type            BuildStepType `json:"type"`
 	Order           int           `json:"order"`
 	HostID          string        `json:"host_id"`                     // SSH host to execute on
+	HostGroup       string        `json:"host_group,omitempty"`        // Or every host of an SSH host group
+	Concurrency     int           `json:"concurrency,omitempty"`       // Hosts of the group at a time, default 1
 	Command         string        `json:"command,omitempty"`           // For shell type
 	Script          string        `json:"script,omitempty"`            // For script type
 	LocalPath       string        `json:"local_path,omitempty"`        // For SCP
 	RemotePath      string        `json:"remote_path,omitempty"`       // For SCP
+	Patterns        []string      `json:"patterns,omitempty"`          // For SFTP, files to copy, default all
 	Timeout         int           `json:"timeout,omitempty"`           // Seconds, default 300
 	ContinueOnError bool          `json:"continue_on_error,omitempty"`
+	Parallel        string        `json:"parallel,omitempty"`          // Group of adjacent steps run at the same time
 }
 
 // BuildParameter defines a user-input parameter for job runs
 type BuildParameter struct {
 	Name         string   `json:"name"`
-	Type         string   `json:"type"` // string, boolean, choice
+	Type         string   `json:"type"` // string (default), choice, boolean, password or file
 	Description  string   `json:"description,omitempty"`
 	DefaultValue string   `json:"default_value,omitempty"`
 	Choices      []string `json:"choices,omitempty"` // For choice type
@@ -222,29 +232,41 @@ type FreestyleTrigger struct {
 
 // FreestyleJob represents a UI-configured job
 type FreestyleJob struct {
-	ID           string             `json:"id"`
-	Name         string             `json:"name"`
-	Description  string             `json:"description,omitempty"`
-	Enabled      bool               `json:"enabled"`
-	SCM          *GitSCMConfig      `json:"scm,omitempty"` // Source Code Management
-	Parameters   []BuildParameter   `json:"parameters,omitempty"`
-	Environment  map[string]string  `json:"environment,omitempty"`
-	BuildSteps   []BuildStep        `json:"build_steps"`
-	Triggers     []FreestyleTrigger `json:"triggers,omitempty"`
-	Status       FreestyleJobStatus `json:"status"`
-	CreatedAt    time.Time          `json:"created_at"`
-	UpdatedAt    time.Time          `json:"updated_at"`
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Enabled     bool                `json:"enabled"`
+	SCM         *GitSCMConfig       `json:"scm,omitempty"` // Source Code Management
+	Parameters  []BuildParameter    `json:"parameters,omitempty"`
+	Environment map[string]string   `json:"environment,omitempty"`
+	BuildSteps  []BuildStep         `json:"build_steps"`
+	Triggers    []FreestyleTrigger  `json:"triggers,omitempty"`
+	Retention   *FreestyleRetention `json:"retention,omitempty"`
+	PostBuild   []PostBuildAction   `json:"post_build,omitempty"`        // jobs and pipelines triggered when a build finishes
+	Workspace   *FreestyleWorkspace `json:"workspace,omitempty"`         // where steps run on the hosts
+	Archive     *ArtifactArchive    `json:"archive_artifacts,omitempty"` // files of the workspace kept after a build
+	Status      FreestyleJobStatus  `json:"status"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// FreestyleRetention is how long the builds of a job are kept. Zero fields
+// take the global retention.
+type FreestyleRetention struct {
+	KeepBuilds int `json:"keep_builds,omitempty"`  // most recent builds kept
+	MaxAgeDays int `json:"max_age_days,omitempty"` // older builds are deleted
 }
 
 // FreestyleJobStatus holds runtime status
 type FreestyleJobStatus struct {
-	WebhookURL    string     `json:"webhook_url,omitempty"`
-	WebhookToken  string     `json:"webhook_token,omitempty"`
-	WebhookSecret string     `json:"webhook_secret,omitempty"` // For HMAC signature verification
-	LastBuildID   string     `json:"last_build_id,omitempty"`
-	LastBuildAt   *time.Time `json:"last_build_at,omitempty"`
-	LastStatus    string     `json:"last_status,omitempty"`
-	TotalBuilds   int        `json:"total_builds"`
+	WebhookURL    string         `json:"webhook_url,omitempty"`
+	WebhookToken  string         `json:"webhook_token,omitempty"`  // primary token, the one in WebhookURL
+	WebhookTokens []WebhookToken `json:"webhook_tokens,omitempty"` // every valid token, including the primary
+	WebhookSecret string         `json:"webhook_secret,omitempty"` // For HMAC signature verification
+	LastBuildID   string         `json:"last_build_id,omitempty"`
+	LastBuildAt   *time.Time     `json:"last_build_at,omitempty"`
+	LastStatus    string         `json:"last_status,omitempty"`
+	TotalBuilds   int            `json:"total_builds"`
 }
 
 // ============ Freestyle Build Types ============
@@ -265,23 +287,39 @@ type FreestyleBuild struct {
 	FinishedAt   *time.Time           `json:"finished_at,omitempty"`
 	Duration     int64                `json:"duration_ms,omitempty"`
 	Error        string               `json:"error,omitempty"`
+	Downstream   []DownstreamBuild    `json:"downstream,omitempty"` // builds and runs its post-build actions started
+	Workspace    string               `json:"workspace,omitempty"`  // workspace on the hosts, when the job has a policy
+	Artifacts    []BuildArtifact      `json:"artifacts,omitempty"`  // files archived from the workspace
 	CreatedAt    time.Time            `json:"created_at"`
+
+	// secrets are the decrypted secret variables of the job while the build
+	// executes; masker hides them and the stored credentials in its logs.
+	// Neither is stored.
+	secrets map[string]string
+	masker  *logMasker
+
+	// workspace is the workspace steps run in when the job has a policy, and
+	// workspaceHosts the hosts it was made on
+	workspace      string
+	workspaceHosts map[string]bool
 }
 
 // FreestyleBuildStep represents execution of a single build step
 type FreestyleBuildStep struct {
-	StepID     string        `json:"step_id"`
-	Name       string        `json:"name"`
-	Type       BuildStepType `json:"type"`
-	HostID     string        `json:"host_id"`
-	HostName   string        `json:"host_name"`
-	Status     RunStatus     `json:"status"`
-	StartedAt  *time.Time    `json:"started_at,omitempty"`
-	FinishedAt *time.Time    `json:"finished_at,omitempty"`
-	Duration   int64         `json:"duration_ms,omitempty"`
-	ExitCode   int           `json:"exit_code"`
-	Output     string        `json:"output,omitempty"` // stdout+stderr
-	Error      string        `json:"error,omitempty"`
+	StepID     string           `json:"step_id"`
+	Name       string           `json:"name"`
+	Type       BuildStepType    `json:"type"`
+	HostID     string           `json:"host_id"`
+	HostName   string           `json:"host_name"`
+	HostGroup  string           `json:"host_group,omitempty"`
+	Status     RunStatus        `json:"status"`
+	StartedAt  *time.Time       `json:"started_at,omitempty"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Duration   int64            `json:"duration_ms,omitempty"`
+	ExitCode   int              `json:"exit_code"`
+	Output     string           `json:"output,omitempty"` // stdout+stderr
+	Error      string           `json:"error,omitempty"`
+	Hosts      []StepHostResult `json:"hosts,omitempty"` // each host of the host group
 }
 
 // ============ Request/Response Types ============
@@ -316,19 +354,25 @@ type UpdateSSHHostRequest struct {
 
 // CreateFreestyleJobRequest is the request body for creating a freestyle job
 type CreateFreestyleJobRequest struct {
-	Name        string             `json:"name"`
-	Description string             `json:"description,omitempty"`
-	Enabled     bool               `json:"enabled"`
-	SCM         *GitSCMConfig      `json:"scm,omitempty"`
-	Parameters  []BuildParameter   `json:"parameters,omitempty"`
-	Environment map[string]string  `json:"environment,omitempty"`
-	BuildSteps  []BuildStep        `json:"build_steps"`
-	Triggers    []FreestyleTrigger `json:"triggers,omitempty"`
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Enabled     bool                `json:"enabled"`
+	SCM         *GitSCMConfig       `json:"scm,omitempty"`
+	Parameters  []BuildParameter    `json:"parameters,omitempty"`
+	Environment map[string]string   `json:"environment,omitempty"`
+	BuildSteps  []BuildStep         `json:"build_steps"`
+	Triggers    []FreestyleTrigger  `json:"triggers,omitempty"`
+	Retention   *FreestyleRetention `json:"retention,omitempty"`
+	PostBuild   []PostBuildAction   `json:"post_build,omitempty"`
+	Workspace   *FreestyleWorkspace `json:"workspace,omitempty"`
+	Archive     *ArtifactArchive    `json:"archive_artifacts,omitempty"`
 }
 
-// TriggerFreestyleBuildRequest is the request body for triggering a build
+// TriggerFreestyleBuildRequest is the request body for triggering a build.
+// Files are the uploads of file parameters, from a multipart request.
 type TriggerFreestyleBuildRequest struct {
-	Parameters map[string]string `json:"parameters,omitempty"`
+	Parameters map[string]string                `json:"parameters,omitempty"`
+	Files      map[string]*multipart.FileHeader `json:"-"`
 }
 
 // CreateGitCredentialRequest is the request body for creating a Git credential
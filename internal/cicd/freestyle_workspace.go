@@ -0,0 +1,310 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// A freestyle build works in a workspace directory on each SSH host it runs
+// steps on. Jobs with a workspace policy run their shell and script steps in
+// it, with $WORKSPACE set to it: either a new directory for every build, or
+// one directory per job that builds reuse, so that dependency caches and
+// build outputs outlive a build. Builds of a job that reuses its workspace
+// should not run at the same time. The workspace is removed from the hosts
+// after the build when the cleanup policy says so.
+//
+// Archiving artifacts is a post-step: once the steps are done, files of the
+// workspace matching the job's patterns are copied from a host into the
+// GAGOS artifact store, and listed with the build.
+
+// Workspace policies
+const (
+	WorkspacePolicyNew   = "new"   // a new directory for every build
+	WorkspacePolicyReuse = "reuse" // one directory per job, kept between builds
+)
+
+// When workspaces are removed after a build
+const (
+	WorkspaceCleanupNever     = "never"
+	WorkspaceCleanupAlways    = "always"
+	WorkspaceCleanupOnSuccess = "on_success"
+)
+
+// defaultWorkspaceRoot is where workspaces are made on the hosts
+const defaultWorkspaceRoot = "/tmp/gagos-builds"
+
+var (
+	workspaceRootRe   = regexp.MustCompile(`^/[A-Za-z0-9._/-]*$`)
+	archivePatternRe  = regexp.MustCompile(`^[A-Za-z0-9._/*?\[\]@+,=-]+$`)
+	archiveListScript = "shopt -s globstar nullglob; for f in %s; do [ -f \"$f\" ] && printf '%%s\\n' \"$f\"; done; true"
+)
+
+// FreestyleWorkspace is where the builds of a job work on its hosts
+type FreestyleWorkspace struct {
+	Policy  string `json:"policy,omitempty"`  // new (default) or reuse
+	Root    string `json:"root,omitempty"`    // directory workspaces are made in, default /tmp/gagos-builds
+	Cleanup string `json:"cleanup,omitempty"` // never (default), always or on_success
+}
+
+// ArtifactArchive selects the files of a build's workspace kept as its
+// artifacts
+type ArtifactArchive struct {
+	HostID     string   `json:"host_id,omitempty"`     // host the files are on, default the host of the first step
+	Patterns   []string `json:"patterns"`              // globs relative to the workspace, e.g. dist/**/*.tar.gz
+	Always     bool     `json:"always,omitempty"`      // also archive the files of failed builds
+	AllowEmpty bool     `json:"allow_empty,omitempty"` // do not fail the build when no file matches
+}
+
+// BuildArtifact is a file archived from a build, downloaded from
+// /api/v1/cicd/artifacts/<id>/download
+type BuildArtifact struct {
+	ID   string `json:"id"`
+	Name string `json:"name"` // path in the workspace
+	Size int64  `json:"size"`
+}
+
+// validateWorkspace checks the workspace policy of a job
+func validateWorkspace(ws *FreestyleWorkspace) error {
+	if ws == nil {
+		return nil
+	}
+	switch ws.Policy {
+	case "", WorkspacePolicyNew, WorkspacePolicyReuse:
+	default:
+		return fmt.Errorf("workspace.policy must be %s or %s", WorkspacePolicyNew, WorkspacePolicyReuse)
+	}
+	switch ws.Cleanup {
+	case "", WorkspaceCleanupNever, WorkspaceCleanupAlways, WorkspaceCleanupOnSuccess:
+	default:
+		return fmt.Errorf("workspace.cleanup must be %s, %s or %s", WorkspaceCleanupNever, WorkspaceCleanupAlways, WorkspaceCleanupOnSuccess)
+	}
+	if ws.Root != "" && (!workspaceRootRe.MatchString(ws.Root) || hasParentElem(ws.Root) || strings.Trim(ws.Root, "/") == "") {
+		return fmt.Errorf("workspace.root must be an absolute directory other than /")
+	}
+	return nil
+}
+
+// validateArtifactArchive checks the artifacts a job archives
+func validateArtifactArchive(a *ArtifactArchive, steps []BuildStep) error {
+	if a == nil {
+		return nil
+	}
+	if len(a.Patterns) == 0 {
+		return fmt.Errorf("archive_artifacts.patterns is required")
+	}
+	for i, p := range a.Patterns {
+		if !archivePatternRe.MatchString(p) || strings.HasPrefix(p, "/") || hasParentElem(p) {
+			return fmt.Errorf("archive_artifacts.patterns[%d] must be a path relative to the workspace, with * ? [] and ** as wildcards", i)
+		}
+	}
+	hostID := archiveHostID(a, steps)
+	if hostID == "" || hostID == "local" {
+		return fmt.Errorf("archive_artifacts.host_id is required when the first step does not run on an SSH host")
+	}
+	if _, err := GetSSHHost(hostID); err != nil {
+		return fmt.Errorf("archive_artifacts: %w", err)
+	}
+	return nil
+}
+
+func hasParentElem(path string) bool {
+	for _, elem := range strings.Split(path, "/") {
+		if elem == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveHostID is the host artifacts are archived from
+func archiveHostID(a *ArtifactArchive, steps []BuildStep) string {
+	if a.HostID != "" || len(steps) == 0 {
+		return a.HostID
+	}
+	return steps[0].HostID
+}
+
+// workspacePath is the workspace of a build on its hosts
+func workspacePath(job *FreestyleJob, build *FreestyleBuild) string {
+	root, reuse := defaultWorkspaceRoot, false
+	if ws := job.Workspace; ws != nil {
+		if ws.Root != "" {
+			root = strings.TrimRight(ws.Root, "/")
+		}
+		reuse = ws.Policy == WorkspacePolicyReuse
+	}
+	if reuse {
+		return fmt.Sprintf("%s/%s/workspace", root, job.ID)
+	}
+	return fmt.Sprintf("%s/%s/%d", root, job.ID, build.BuildNumber)
+}
+
+// reusesWorkspace tells whether the builds of a job share a workspace
+func reusesWorkspace(job *FreestyleJob) bool {
+	return job.Workspace != nil && job.Workspace.Policy == WorkspacePolicyReuse
+}
+
+// useWorkspace notes that the build made its workspace on a host, for it to
+// be cleaned up there
+func (b *FreestyleBuild) useWorkspace(hostID string) {
+	if b.workspaceHosts[hostID] {
+		return
+	}
+	if b.workspaceHosts == nil {
+		b.workspaceHosts = make(map[string]bool)
+	}
+	b.workspaceHosts[hostID] = true
+}
+
+// workspacePrefix is the command prefix that makes the build's workspace and
+// changes to it, "" when the job has no workspace policy
+func workspacePrefix(build *FreestyleBuild) string {
+	if build.workspace == "" {
+		return ""
+	}
+	ws := shellQuote(build.workspace)
+	return fmt.Sprintf("mkdir -p %s && cd %s && ", ws, ws)
+}
+
+// recordBuildWorkspace stores the workspace of a build with it
+func recordBuildWorkspace(buildID, workspace string) error {
+	build, err := GetFreestyleBuild(buildID)
+	if err != nil {
+		return err
+	}
+	build.Workspace = workspace
+	return UpdateFreestyleBuild(build)
+}
+
+// cleanupWorkspaces removes the workspace of a build from the hosts it was
+// made on, when the cleanup policy of the job asks for it
+func cleanupWorkspaces(buildID string, build *FreestyleBuild, job *FreestyleJob, status RunStatus) {
+	if job.Workspace == nil || len(build.workspaceHosts) == 0 {
+		return
+	}
+	switch job.Workspace.Cleanup {
+	case WorkspaceCleanupAlways:
+	case WorkspaceCleanupOnSuccess:
+		if status != RunStatusSucceeded {
+			return
+		}
+	default:
+		return
+	}
+
+	workspace := workspacePath(job, build)
+	hostIDs := make([]string, 0, len(build.workspaceHosts))
+	for id := range build.workspaceHosts {
+		hostIDs = append(hostIDs, id)
+	}
+	sort.Strings(hostIDs)
+
+	for _, id := range hostIDs {
+		host, err := GetSSHHost(id)
+		if err != nil {
+			WriteBuildOutput(buildID, []byte(fmt.Sprintf("Failed to clean workspace: %s\n", err)))
+			continue
+		}
+		WriteBuildOutput(buildID, []byte(fmt.Sprintf("Cleaning workspace %s on %s\n", workspace, host.Name)))
+		session, err := NewSSHSession(host)
+		if err != nil {
+			WriteBuildOutput(buildID, []byte(fmt.Sprintf("Failed to clean workspace: %s\n", err)))
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		_, stderr, exitCode, err := session.ExecuteCommand(ctx, "rm -rf "+shellQuote(workspace), 2*time.Minute)
+		cancel()
+		session.Close()
+		if err != nil || exitCode != 0 {
+			msg := errorText(err, stderr)
+			WriteBuildOutput(buildID, []byte(fmt.Sprintf("Failed to clean workspace: %s\n", msg)))
+			log.Warn().Str("build", buildID).Str("host", host.Name).Str("error", msg).Msg("Failed to clean workspace")
+		}
+	}
+}
+
+// archiveArtifacts copies the files of the build's workspace that match the
+// job's patterns into the artifact store and records them with the build
+func archiveArtifacts(buildID string, build *FreestyleBuild, job *FreestyleJob) error {
+	a := job.Archive
+	WriteBuildOutput(buildID, []byte("\n--- Archive artifacts ---\n"))
+
+	host, err := GetSSHHost(archiveHostID(a, job.BuildSteps))
+	if err != nil {
+		return fmt.Errorf("archive artifacts: %w", err)
+	}
+	session, err := NewSSHSession(host)
+	if err != nil {
+		return fmt.Errorf("archive artifacts: failed to connect: %w", err)
+	}
+	defer session.Close()
+
+	// Without a workspace, patterns are relative to the SSH user's home
+	dir := build.Environment["WORKSPACE"]
+	if dir == "" {
+		dir = "."
+	}
+	list := fmt.Sprintf("cd %s && bash -c %s", shellQuote(dir), shellQuote(fmt.Sprintf(archiveListScript, strings.Join(a.Patterns, " "))))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	stdout, stderr, exitCode, err := session.ExecuteCommand(ctx, list, 2*time.Minute)
+	cancel()
+	if err != nil || exitCode != 0 {
+		return fmt.Errorf("archive artifacts: failed to list files in %s: %s", dir, errorText(err, stderr))
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, f := range strings.Split(stdout, "\n") {
+		f = strings.TrimPrefix(strings.TrimSpace(f), "./")
+		if f != "" && !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		if a.AllowEmpty {
+			WriteBuildOutput(buildID, []byte("No files to archive\n"))
+			return nil
+		}
+		return fmt.Errorf("archive artifacts: no files match %s", strings.Join(a.Patterns, ", "))
+	}
+
+	archived := make([]BuildArtifact, 0, len(files))
+	filenames := make(map[string]bool, len(files))
+	for i, f := range files {
+		content, err := session.SCPPull(dir + "/" + f)
+		if err != nil {
+			return fmt.Errorf("archive artifacts: failed to copy %s: %w", f, err)
+		}
+		// Artifacts of a build are stored in one directory
+		filename := strings.ReplaceAll(f, "/", "_")
+		if filenames[filename] {
+			filename = fmt.Sprintf("%d_%s", i, filename)
+		}
+		filenames[filename] = true
+
+		artifact, err := SaveArtifact(build.ID, job.ID, f, filename, bytes.NewReader(content))
+		if err != nil {
+			return fmt.Errorf("archive artifacts: %w", err)
+		}
+		archived = append(archived, BuildArtifact{ID: artifact.ID, Name: f, Size: artifact.Size})
+		WriteBuildOutput(buildID, []byte(fmt.Sprintf("Archived %s (%d bytes)\n", f, artifact.Size)))
+	}
+
+	stored, err := GetFreestyleBuild(buildID)
+	if err != nil {
+		return err
+	}
+	stored.Artifacts = append(stored.Artifacts, archived...)
+	return UpdateFreestyleBuild(stored)
+}
@@ -28,7 +28,7 @@ func ExecuteGitSCM(buildID string, session *SSHSession, job *FreestyleJob, build
 	WriteBuildOutput(buildID, []byte("\n=== Source Code Management ===\n"))
 
 	// Create workspace directory
-	workspace := fmt.Sprintf("/tmp/gagos-builds/%s/%d", job.ID, build.BuildNumber)
+	workspace := workspacePath(job, build)
 	WriteBuildOutput(buildID, []byte(fmt.Sprintf("Workspace: %s\n", workspace)))
 
 	// Clean workspace if configured
@@ -69,6 +69,24 @@ func ExecuteGitSCM(buildID string, session *SSHSession, job *FreestyleJob, build
 			clonePath = fmt.Sprintf("%s/%s", workspace, repoName)
 		}
 
+		// A reused workspace keeps the checkout of the last build, which is
+		// brought up to date rather than cloned again
+		if reusesWorkspace(job) && !job.SCM.CleanBefore && hasGitCheckout(session, clonePath) {
+			branch := ""
+			if len(job.SCM.Branches) > 0 {
+				branch = job.SCM.Branches[0].Specifier
+			}
+			if err := updateGitCheckout(buildID, session, repo, job.SCM, clonePath, branch); err != nil {
+				return nil, fmt.Errorf("failed to update repo %d: %w", repoNum, err)
+			}
+			if i == 0 {
+				result.Commit, result.Branch, _ = getGitInfo(session, clonePath)
+				WriteBuildOutput(buildID, []byte(fmt.Sprintf("Commit: %s\n", result.Commit)))
+				WriteBuildOutput(buildID, []byte(fmt.Sprintf("Branch: %s\n", result.Branch)))
+			}
+			continue
+		}
+
 		// Build git clone command with authentication
 		cloneCmd, err := buildGitCloneCommand(repo, job.SCM, clonePath)
 		if err != nil {
@@ -168,7 +186,12 @@ func buildSSHKeyCloneCommand(url string, cred *GitCredential, scm *GitSCMConfig,
 	}
 
 	gitCmd := fmt.Sprintf("git clone%s '%s' '%s'", cloneOpts, url, clonePath)
+	return withSSHAgent(cred, gitCmd), nil
+}
 
+// withSSHAgent wraps a git command so that it authenticates with the SSH key
+// of a credential
+func withSSHAgent(cred *GitCredential, gitCmd string) string {
 	// Wrap with SSH agent setup
 	// Note: We escape the key content and passphrase carefully
 	keyEscaped := strings.ReplaceAll(cred.PrivateKey, "'", "'\"'\"'")
@@ -196,7 +219,55 @@ eval $(ssh-agent -s) >/dev/null 2>&1
 	cmd += fmt.Sprintf(`GIT_SSH_COMMAND="ssh -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null" %s 2>&1
 `, gitCmd)
 
-	return cmd, nil
+	return cmd
+}
+
+// hasGitCheckout tells whether a directory holds a git checkout
+func hasGitCheckout(session *SSHSession, path string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, _, exitCode, err := session.ExecuteCommand(ctx, fmt.Sprintf("test -d '%s/.git'", path), 30*time.Second)
+	return err == nil && exitCode == 0
+}
+
+// updateGitCheckout fetches the branch into an existing checkout and resets
+// it to the fetched commit. Files git ignores, such as dependency caches, are
+// kept; other changes are discarded. Without a branch, the remote's default
+// branch is used.
+func updateGitCheckout(buildID string, session *SSHSession, repo GitRepository, scm *GitSCMConfig, repoPath, branchSpec string) error {
+	branch := branchName(branchSpec)
+	WriteBuildOutput(buildID, []byte(fmt.Sprintf("Updating existing checkout in %s...\n", repoPath)))
+
+	fetchOpts := " --prune"
+	if scm.CloneDepth > 0 {
+		fetchOpts += fmt.Sprintf(" --depth %d", scm.CloneDepth)
+	}
+	gitCmd := fmt.Sprintf("cd '%s' && B=%s", repoPath, shellQuote(branch))
+	gitCmd += ` && { [ -n "$B" ] || B=$(git remote show origin | sed -n 's/.*HEAD branch: //p'); }`
+	gitCmd += fmt.Sprintf(` && git fetch%s origin "$B" && git checkout -f -B "$B" FETCH_HEAD && git clean -fd`, fetchOpts)
+	if scm.Submodules {
+		gitCmd += " && git submodule update --init --recursive"
+	}
+
+	gitCmd = "sh -c " + shellQuote(gitCmd)
+	cmd := gitCmd + " 2>&1"
+	if repo.CredentialID != "" {
+		cred, err := GetDecryptedGitCredential(repo.CredentialID)
+		if err != nil {
+			return fmt.Errorf("failed to get credential: %w", err)
+		}
+		if cred.AuthMethod == GitAuthSSHKey {
+			cmd = withSSHAgent(cred, gitCmd)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	exitCode, err := session.ExecuteCommandStreaming(ctx, cmd, 10*time.Minute, GetBuildOutputStream(buildID))
+	if err != nil || exitCode != 0 {
+		return fmt.Errorf("git fetch failed with exit code %d", exitCode)
+	}
+	return nil
 }
 
 // injectTokenIntoURL injects a token into an HTTPS URL
@@ -256,16 +327,7 @@ func checkoutBranch(buildID string, session *SSHSession, repoPath, branchSpec st
 	// "*/main" -> "main"
 	// "refs/heads/main" -> "main"
 	// "origin/main" -> "main"
-	branch := branchSpec
-	if strings.HasPrefix(branch, "*/") {
-		branch = branch[2:]
-	}
-	if strings.HasPrefix(branch, "refs/heads/") {
-		branch = branch[11:]
-	}
-	if strings.HasPrefix(branch, "origin/") {
-		branch = branch[7:]
-	}
+	branch := branchName(branchSpec)
 
 	WriteBuildOutput(buildID, []byte(fmt.Sprintf("Checking out branch: %s\n", branch)))
 
@@ -284,6 +346,21 @@ func checkoutBranch(buildID string, session *SSHSession, repoPath, branchSpec st
 	return nil
 }
 
+// branchName is the branch of a branch specifier
+func branchName(branchSpec string) string {
+	branch := branchSpec
+	if strings.HasPrefix(branch, "*/") {
+		branch = branch[2:]
+	}
+	if strings.HasPrefix(branch, "refs/heads/") {
+		branch = branch[11:]
+	}
+	if strings.HasPrefix(branch, "origin/") {
+		branch = branch[7:]
+	}
+	return branch
+}
+
 // getGitInfo retrieves current commit SHA and branch name
 func getGitInfo(session *SSHSession, repoPath string) (commit, branch string, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -0,0 +1,363 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Job templates define a common job, such as running Go tests or building an
+// image, once for many pipelines. A pipeline job takes one with
+// `uses: templates/go-test@v1` and fills in its parameters under `with`;
+// fields the job sets itself replace the template's. Templates are expanded
+// when a pipeline is saved, so a version is never changed once published: a
+// change is a new version that pipelines move to when they are ready.
+
+// templateRefPrefix starts the uses reference of a job template
+const templateRefPrefix = "templates/"
+
+var (
+	templateVersionRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,31}$`)
+	templateParamRe   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	// templatePlaceholderRe finds ${{ params.<name> }} in a template's job
+	templatePlaceholderRe = regexp.MustCompile(`\$\{\{\s*params\.([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}`)
+)
+
+// TemplateParameter is a parameter of a job template
+type TemplateParameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// JobTemplate is a version of a job template. Job is the YAML of a pipeline
+// job without its name, where ${{ params.<name> }} stands for a parameter.
+type JobTemplate struct {
+	ID          string              `json:"id"` // <name>@<version>
+	Name        string              `json:"name"`
+	Version     string              `json:"version"`
+	Description string              `json:"description,omitempty"`
+	Parameters  []TemplateParameter `json:"parameters,omitempty"`
+	Job         string              `json:"job"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+// CreateJobTemplateRequest is the request body for publishing a version of a
+// job template
+type CreateJobTemplateRequest struct {
+	Name        string              `json:"name"`
+	Version     string              `json:"version"`
+	Description string              `json:"description,omitempty"`
+	Parameters  []TemplateParameter `json:"parameters,omitempty"`
+	Job         string              `json:"job"`
+}
+
+// Ref is how pipeline jobs use the template
+func (t *JobTemplate) Ref() string {
+	return templateRefPrefix + t.ID
+}
+
+func templateID(name, version string) string {
+	return name + "@" + version
+}
+
+// parseTemplateRef splits templates/<name>@<version>
+func parseTemplateRef(ref string) (name, version string, err error) {
+	rest, ok := strings.CutPrefix(ref, templateRefPrefix)
+	if ok {
+		name, version, ok = strings.Cut(rest, "@")
+	}
+	if !ok || name == "" || version == "" {
+		return "", "", fmt.Errorf("%q must be %s<name>@<version>", ref, templateRefPrefix)
+	}
+	return name, version, nil
+}
+
+// validateJobTemplate checks a template and that its job is a pipeline job
+// in which every placeholder is a parameter
+func validateJobTemplate(t *JobTemplate) error {
+	if !isValidName(t.Name) {
+		return fmt.Errorf("name must contain only alphanumeric characters, dashes, and underscores")
+	}
+	if !templateVersionRe.MatchString(t.Version) {
+		return fmt.Errorf("version must contain only alphanumeric characters, dots, dashes, and underscores")
+	}
+
+	params := make(map[string]bool, len(t.Parameters))
+	for i, p := range t.Parameters {
+		if !templateParamRe.MatchString(p.Name) {
+			return fmt.Errorf("parameters[%d].name must start with a letter or underscore and contain only alphanumeric characters, dashes, and underscores", i)
+		}
+		if params[p.Name] {
+			return fmt.Errorf("duplicate parameter: %s", p.Name)
+		}
+		if p.Required && p.Default != "" {
+			return fmt.Errorf("parameter %s is required and cannot have a default", p.Name)
+		}
+		params[p.Name] = true
+	}
+
+	if strings.TrimSpace(t.Job) == "" {
+		return fmt.Errorf("job is required")
+	}
+	for _, m := range templatePlaceholderRe.FindAllStringSubmatch(t.Job, -1) {
+		if !params[m[1]] {
+			return fmt.Errorf("job uses parameter %s, which is not declared", m[1])
+		}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(t.Job), &doc); err != nil {
+		return fmt.Errorf("job is not valid YAML: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("job must be a mapping")
+	}
+	root := doc.Content[0]
+	v := &pipelineValidator{root: root}
+	checkKnownFields(v, root, jobYAMLType, nil)
+	for _, key := range []string{"name", "uses", "with"} {
+		if n := childNode(root, key); n != nil {
+			v.errorf(at(key), "a template's job takes no %s", key)
+		}
+	}
+	if len(v.errs) > 0 {
+		return fmt.Errorf("job: %w", v.errs)
+	}
+	return nil
+}
+
+// CreateJobTemplate publishes a version of a job template
+func CreateJobTemplate(req *CreateJobTemplateRequest) (*JobTemplate, error) {
+	t := &JobTemplate{
+		ID:          templateID(req.Name, req.Version),
+		Name:        req.Name,
+		Version:     req.Version,
+		Description: req.Description,
+		Parameters:  req.Parameters,
+		Job:         req.Job,
+		CreatedAt:   time.Now(),
+	}
+	if err := validateJobTemplate(t); err != nil {
+		return nil, err
+	}
+
+	existing, err := storage.GetBackend().Get(storage.BucketJobTemplates, t.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("template %s already exists; publish a new version instead", t.ID)
+	}
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal template: %w", err)
+	}
+	if err := storage.GetBackend().Set(storage.BucketJobTemplates, t.ID, data); err != nil {
+		return nil, fmt.Errorf("failed to save template: %w", err)
+	}
+
+	log.Info().Str("template", t.ID).Msg("Job template published")
+	return t, nil
+}
+
+// GetJobTemplate retrieves a version of a job template
+func GetJobTemplate(name, version string) (*JobTemplate, error) {
+	id := templateID(name, version)
+	data, err := storage.GetBackend().Get(storage.BucketJobTemplates, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("template not found: %s", id)
+	}
+
+	var t JobTemplate
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal template: %w", err)
+	}
+	return &t, nil
+}
+
+// ListJobTemplates returns the versions of a job template, or of all of them
+// when name is empty, by name and then newest first
+func ListJobTemplates(name string) ([]*JobTemplate, error) {
+	dataList, err := storage.GetBackend().List(storage.BucketJobTemplates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	templates := make([]*JobTemplate, 0, len(dataList))
+	for _, data := range dataList {
+		var t JobTemplate
+		if err := json.Unmarshal(data, &t); err != nil {
+			log.Warn().Err(err).Msg("Failed to unmarshal job template")
+			continue
+		}
+		if name != "" && t.Name != name {
+			continue
+		}
+		templates = append(templates, &t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Name != templates[j].Name {
+			return templates[i].Name < templates[j].Name
+		}
+		return templates[i].CreatedAt.After(templates[j].CreatedAt)
+	})
+	return templates, nil
+}
+
+// DeleteJobTemplate deletes a version of a job template that no pipeline
+// uses
+func DeleteJobTemplate(name, version string) error {
+	t, err := GetJobTemplate(name, version)
+	if err != nil {
+		return err
+	}
+
+	pipelines, err := ListPipelines()
+	if err != nil {
+		return err
+	}
+	for _, p := range pipelines {
+		for _, job := range p.Spec.Jobs {
+			if job.Uses == t.Ref() {
+				return fmt.Errorf("template %s is used by pipeline %s", t.ID, p.Name)
+			}
+		}
+	}
+
+	if err := storage.GetBackend().Delete(storage.BucketJobTemplates, t.ID); err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+	log.Info().Str("template", t.ID).Msg("Job template deleted")
+	return nil
+}
+
+// params resolves the parameters a job passes to the template
+func (t *JobTemplate) params(with map[string]string) (map[string]string, error) {
+	declared := make(map[string]bool, len(t.Parameters))
+	params := make(map[string]string, len(t.Parameters))
+	var missing []string
+	for _, p := range t.Parameters {
+		declared[p.Name] = true
+		value, ok := with[p.Name]
+		switch {
+		case ok:
+			params[p.Name] = value
+		case p.Required:
+			missing = append(missing, p.Name)
+		default:
+			params[p.Name] = p.Default
+		}
+	}
+
+	var unknown []string
+	for name := range with {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%s has no parameter %s", t.Ref(), strings.Join(unknown, ", "))
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%s requires parameter %s", t.Ref(), strings.Join(missing, ", "))
+	}
+	return params, nil
+}
+
+// expand renders the template's job with the parameters. They are put in
+// the values of the parsed YAML rather than its text, so that a value cannot
+// change the structure of the job.
+func (t *JobTemplate) expand(params map[string]string) (JobYAML, error) {
+	var job JobYAML
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(t.Job), &doc); err != nil {
+		return job, err
+	}
+	substituteParams(&doc, params)
+	if err := doc.Decode(&job); err != nil {
+		return job, fmt.Errorf("%s does not render to a valid job: %w", t.Ref(), err)
+	}
+	return job, nil
+}
+
+// substituteParams puts the parameters in the scalars of a YAML document
+func substituteParams(n *yaml.Node, params map[string]string) {
+	if n.Kind == yaml.ScalarNode {
+		value := templatePlaceholderRe.ReplaceAllStringFunc(n.Value, func(m string) string {
+			return params[templatePlaceholderRe.FindStringSubmatch(m)[1]]
+		})
+		if value != n.Value {
+			n.Value = value
+			// A plain value is read as what it now is, e.g. a number
+			if n.Style == 0 {
+				n.Tag = ""
+			}
+		}
+		return
+	}
+	for _, c := range n.Content {
+		substituteParams(c, params)
+	}
+}
+
+// expandJobTemplates replaces the jobs of a pipeline that use a template by
+// the template's job, overlaid with the fields the job sets itself
+func expandJobTemplates(p *PipelineYAML, v *pipelineValidator) {
+	v.unexpanded = make(map[int]bool)
+	for i := range p.Spec.Jobs {
+		job := &p.Spec.Jobs[i]
+		jobAt := at("spec", "jobs", i)
+		if job.Uses == "" {
+			if len(job.With) > 0 {
+				v.errorf(jobAt.with("with"), "job[%d].with only applies to jobs that use a template", i)
+			}
+			continue
+		}
+
+		name, version, err := parseTemplateRef(job.Uses)
+		if err != nil {
+			v.errorf(jobAt.with("uses"), "job[%d].uses %v", i, err)
+			v.unexpanded[i] = true
+			continue
+		}
+		t, err := GetJobTemplate(name, version)
+		if err != nil {
+			v.errorf(jobAt.with("uses"), "job[%d].uses: %v", i, err)
+			v.unexpanded[i] = true
+			continue
+		}
+		params, err := t.params(job.With)
+		if err != nil {
+			v.errorf(jobAt.with("with"), "job[%d]: %v", i, err)
+			v.unexpanded[i] = true
+			continue
+		}
+		expanded, err := t.expand(params)
+		if err != nil {
+			v.errorf(jobAt.with("with"), "job[%d]: %v", i, err)
+			v.unexpanded[i] = true
+			continue
+		}
+
+		// Errors decoding the job were reported with the rest of the pipeline
+		_ = v.node(jobAt).Decode(&expanded)
+		*job = expanded
+	}
+}
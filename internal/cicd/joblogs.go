@@ -0,0 +1,286 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gaga951/gagos/internal/database"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The logs of every job attempt are captured while its pod runs and kept
+// once the attempt ends, so they can still be read after the pod is gone.
+// They are written to <GAGOS_ARTIFACT_PATH>/<run>/logs/<job>.<attempt>.log,
+// and with GAGOS_CICD_LOG_S3_BUCKET set they are moved to that bucket when
+// the attempt ends. JobRun.LogPath holds where they went, a file path or an
+// s3://bucket/key URL.
+
+// logCaptureGrace is how long a finished attempt waits for its log streams
+// to drain before they are cut off
+const logCaptureGrace = 10 * time.Second
+
+const s3LogScheme = "s3://"
+
+// logCapture follows the logs of one job attempt into a file
+type logCapture struct {
+	runID   string
+	jobName string
+	attempt int
+	path    string
+	file    *os.File
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// jobLogS3 returns the S3 settings for job logs, or nil when they are kept
+// on disk
+func jobLogS3() (*database.S3Config, string, string) {
+	bucket := os.Getenv("GAGOS_CICD_LOG_S3_BUCKET")
+	if bucket == "" {
+		return nil, "", ""
+	}
+	config := &database.S3Config{
+		Endpoint:        os.Getenv("GAGOS_CICD_LOG_S3_ENDPOINT"),
+		Region:          os.Getenv("GAGOS_CICD_LOG_S3_REGION"),
+		AccessKeyID:     os.Getenv("GAGOS_CICD_LOG_S3_ACCESS_KEY"),
+		SecretAccessKey: os.Getenv("GAGOS_CICD_LOG_S3_SECRET_KEY"),
+		UseSSL:          os.Getenv("GAGOS_CICD_LOG_S3_USE_SSL") != "false",
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "s3.amazonaws.com"
+	}
+	if config.AccessKeyID == "" {
+		config.AuthMode = database.AuthIAM
+	}
+	prefix := strings.Trim(os.Getenv("GAGOS_CICD_LOG_S3_PREFIX"), "/")
+	if prefix == "" {
+		prefix = "gagos/job-logs"
+	}
+	return config, bucket, prefix
+}
+
+// jobLogFile is the file a job attempt's logs are captured to
+func jobLogFile(runID, jobName string, attempt int) string {
+	return filepath.Join(artifactPath, runID, "logs", fmt.Sprintf("%s.%d.log", jobName, attempt))
+}
+
+// captureJobLogs starts following the logs of the pod of a K8s Job, the
+// source checkout first when the job has one, masking secret values. It
+// returns nil when the log file cannot be created; the logs are then only
+// readable from the pod.
+func captureJobLogs(clientset kubernetes.Interface, runID, jobName string, attempt int, k8sJobName string, hasSource bool, masker *logMasker) *logCapture {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := openJobLog(runID, jobName, attempt, cancel)
+	if c == nil {
+		cancel()
+		return nil
+	}
+	f := newMaskingWriter(c.file, masker)
+	containers := []string{"runner"}
+	if hasSource {
+		containers = []string{sourceContainerName, "runner"}
+	}
+	go func() {
+		defer close(c.done)
+		podName := waitForJobPod(ctx, clientset, k8sJobName)
+		if podName == "" {
+			return
+		}
+		defer f.Flush()
+		for _, container := range containers {
+			if !followContainerLogs(ctx, clientset, podName, container, f) {
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// openJobLog creates the log file of a job attempt. The caller writes to it
+// and closes done once nothing more is written; cancel stops the writing.
+func openJobLog(runID, jobName string, attempt int, cancel context.CancelFunc) *logCapture {
+	file := jobLogFile(runID, jobName, attempt)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		log.Warn().Err(err).Str("job", jobName).Msg("Failed to create job log directory")
+		return nil
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		log.Warn().Err(err).Str("job", jobName).Msg("Failed to create job log file")
+		return nil
+	}
+	return &logCapture{
+		runID:   runID,
+		jobName: jobName,
+		attempt: attempt,
+		path:    file,
+		file:    f,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+}
+
+// waitForJobPod returns the name of the pod of a K8s Job once it exists, or
+// "" when ctx ends first
+func waitForJobPod(ctx context.Context, clientset kubernetes.Interface, k8sJobName string) string {
+	for {
+		pods, err := clientset.CoreV1().Pods(cicdNamespace).List(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("job-name=%s", k8sJobName),
+		})
+		if err == nil && len(pods.Items) > 0 {
+			return pods.Items[0].Name
+		}
+		select {
+		case <-ctx.Done():
+			return ""
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// followContainerLogs copies the logs of a container to w until it exits.
+// A container that has not started yet is waited for. It reports false when
+// the container will not run, so that the containers after it are skipped.
+func followContainerLogs(ctx context.Context, clientset kubernetes.Interface, podName, container string, w io.Writer) bool {
+	for {
+		stream, err := clientset.CoreV1().Pods(cicdNamespace).GetLogs(podName, &corev1.PodLogOptions{
+			Container: container,
+			Follow:    true,
+		}).Stream(ctx)
+		if err == nil {
+			io.Copy(w, stream)
+			stream.Close()
+			return ctx.Err() == nil
+		}
+
+		pod, getErr := clientset.CoreV1().Pods(cicdNamespace).Get(ctx, podName, metav1.GetOptions{})
+		if getErr != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// finish waits for the log streams of a finished attempt, closes the file
+// and moves it to S3 when configured. It returns where the logs are kept.
+func (c *logCapture) finish() string {
+	if c == nil {
+		return ""
+	}
+	select {
+	case <-c.done:
+	case <-time.After(logCaptureGrace):
+		c.cancel()
+		<-c.done
+	}
+	c.cancel()
+	if err := c.file.Close(); err != nil {
+		log.Warn().Err(err).Str("job", c.jobName).Msg("Failed to write job log file")
+	}
+
+	config, bucket, prefix := jobLogS3()
+	if config == nil {
+		return c.path
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return c.path
+	}
+	key := path.Join(prefix, c.runID, fmt.Sprintf("%s.%d.log", c.jobName, c.attempt))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := database.UploadS3Object(ctx, *config, bucket, key, bytes.NewReader(data), int64(len(data)), "text/plain; charset=utf-8"); err != nil {
+		// The file stays readable; it is only moved when the upload succeeds
+		log.Warn().Err(err).Str("job", c.jobName).Str("bucket", bucket).Msg("Failed to upload job logs to S3")
+		return c.path
+	}
+	os.Remove(c.path)
+	return s3LogScheme + bucket + "/" + key
+}
+
+// readJobLogs reads the kept logs of a job attempt, the last tailLines lines
+// when tailLines is positive
+func readJobLogs(ctx context.Context, location string, tailLines int64) (string, error) {
+	var data []byte
+	var err error
+	if rest, ok := strings.CutPrefix(location, s3LogScheme); ok {
+		config, _, _ := jobLogS3()
+		if config == nil {
+			return "", fmt.Errorf("job logs are in S3 but GAGOS_CICD_LOG_S3_BUCKET is not set")
+		}
+		bucket, key, _ := strings.Cut(rest, "/")
+		data, _, err = database.DownloadS3Object(ctx, *config, bucket, key)
+	} else {
+		data, err = os.ReadFile(location)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read job logs: %w", err)
+	}
+
+	logs := string(data)
+	if tailLines > 0 {
+		lines := strings.SplitAfter(strings.TrimSuffix(logs, "\n"), "\n")
+		if int64(len(lines)) > tailLines {
+			logs = strings.Join(lines[int64(len(lines))-tailLines:], "")
+			if strings.HasSuffix(string(data), "\n") {
+				logs += "\n"
+			}
+		}
+	}
+	return logs, nil
+}
+
+// deleteJobLogs deletes the kept logs of a run's jobs. Jobs reused from an
+// earlier run keep that run's logs, which are left alone.
+func deleteJobLogs(run *PipelineRun) {
+	var locations []string
+	for _, job := range run.Jobs {
+		if job.ReusedFrom != "" {
+			continue
+		}
+		if job.LogPath != "" {
+			locations = append(locations, job.LogPath)
+		}
+		for _, a := range job.Attempts {
+			if a.LogPath != "" && a.LogPath != job.LogPath {
+				locations = append(locations, a.LogPath)
+			}
+		}
+	}
+
+	for _, location := range locations {
+		rest, ok := strings.CutPrefix(location, s3LogScheme)
+		if !ok {
+			os.Remove(location)
+			continue
+		}
+		config, _, _ := jobLogS3()
+		if config == nil {
+			continue
+		}
+		bucket, key, _ := strings.Cut(rest, "/")
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := database.DeleteS3Object(ctx, *config, bucket, key); err != nil {
+			log.Warn().Err(err).Str("run_id", run.ID).Str("key", key).Msg("Failed to delete job logs from S3")
+		}
+		cancel()
+	}
+	os.RemoveAll(filepath.Join(artifactPath, run.ID, "logs"))
+}
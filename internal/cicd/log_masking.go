@@ -0,0 +1,201 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gaga951/gagos/internal/storage"
+)
+
+// Secret values are replaced with *** in job and build logs before they are
+// kept or sent to a client: the secret variables of the pipeline or
+// freestyle job, and the passwords, passphrases and tokens of the stored SSH
+// hosts, Git credentials and registry credentials. Output is masked a line at
+// a time, so a value split over two writes is still caught; a value that
+// spans lines is masked line by line. Logs kept before a value was stored are
+// masked again when they are read.
+
+// maskedValue replaces a secret value in logs
+const maskedValue = "***"
+
+// minMaskedLength is the length below which a value is not masked: a very
+// short value would mask ordinary output
+const minMaskedLength = 4
+
+// maxMaskBuffer is how much output without a newline is held back before it
+// is masked and written anyway
+const maxMaskBuffer = 64 * 1024
+
+// logMasker replaces secret values in logs. A nil logMasker masks nothing.
+type logMasker struct {
+	replacer *strings.Replacer
+}
+
+// newLogMasker returns a masker for values, nil when there is nothing to mask
+func newLogMasker(values ...string) *logMasker {
+	seen := map[string]bool{}
+	var masked []string
+	add := func(v string) {
+		v = strings.TrimSpace(v)
+		if len(v) >= minMaskedLength && !seen[v] {
+			seen[v] = true
+			masked = append(masked, v)
+		}
+	}
+	for _, v := range values {
+		add(v)
+		if strings.Contains(v, "\n") {
+			for _, line := range strings.Split(v, "\n") {
+				add(line)
+			}
+		}
+	}
+	if len(masked) == 0 {
+		return nil
+	}
+	// The longest value wins where values overlap
+	sort.Slice(masked, func(i, j int) bool {
+		return len(masked[i]) > len(masked[j])
+	})
+	pairs := make([]string, 0, 2*len(masked))
+	for _, v := range masked {
+		pairs = append(pairs, v, maskedValue)
+	}
+	return &logMasker{replacer: strings.NewReplacer(pairs...)}
+}
+
+// mapValues lists the values of a map of secrets
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
+
+// mask returns s with the secret values replaced
+func (m *logMasker) mask(s string) string {
+	if m == nil {
+		return s
+	}
+	return m.replacer.Replace(s)
+}
+
+// maskingWriter masks the output written through it a line at a time.
+// Flush writes what is left of the last line.
+type maskingWriter struct {
+	w      io.Writer
+	masker *logMasker
+	buf    []byte
+}
+
+// newMaskingWriter masks what is written to w
+func newMaskingWriter(w io.Writer, masker *logMasker) *maskingWriter {
+	return &maskingWriter{w: w, masker: masker}
+}
+
+func (mw *maskingWriter) Write(p []byte) (int, error) {
+	if mw.masker == nil {
+		return mw.w.Write(p)
+	}
+	mw.buf = append(mw.buf, p...)
+	end := bytes.LastIndexByte(mw.buf, '\n') + 1
+	if end == 0 && len(mw.buf) >= maxMaskBuffer {
+		end = len(mw.buf)
+	}
+	if end > 0 {
+		if _, err := io.WriteString(mw.w, mw.masker.mask(string(mw.buf[:end]))); err != nil {
+			return 0, err
+		}
+		mw.buf = append(mw.buf[:0], mw.buf[end:]...)
+	}
+	return len(p), nil
+}
+
+// Flush masks and writes the output held back
+func (mw *maskingWriter) Flush() error {
+	if len(mw.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(mw.w, mw.masker.mask(string(mw.buf)))
+	mw.buf = mw.buf[:0]
+	return err
+}
+
+// credentialValues decrypts the passwords, passphrases and tokens of the
+// stored SSH hosts, Git credentials and registry credentials, which a job may
+// print while it connects, clones or pushes
+func credentialValues() []string {
+	if err := InitCrypto(); err != nil {
+		return nil
+	}
+	var values []string
+	decrypt := func(encrypted string) string {
+		if encrypted == "" {
+			return ""
+		}
+		value, err := Decrypt(encrypted)
+		if err != nil {
+			return ""
+		}
+		values = append(values, value)
+		return value
+	}
+
+	if hosts, err := ListSSHHosts(); err == nil {
+		for _, h := range hosts {
+			decrypt(h.Password)
+			decrypt(h.Passphrase)
+		}
+	}
+	if creds, err := ListGitCredentials(); err == nil {
+		for _, c := range creds {
+			decrypt(c.Token)
+			decrypt(c.Passphrase)
+			if password := decrypt(c.Password); password != "" {
+				// As it appears in a clone URL
+				escaped := strings.NewReplacer("@", "%40", ":", "%3A").Replace(password)
+				values = append(values, escaped)
+			}
+		}
+	}
+	if items, err := storage.GetBackend().List(storage.BucketRegistryCreds); err == nil {
+		for _, data := range items {
+			var cred RegistryCredential
+			if json.Unmarshal(data, &cred) != nil {
+				continue
+			}
+			if password := decrypt(cred.Password); password != "" {
+				// As it appears in a Docker config.json
+				values = append(values, base64.StdEncoding.EncodeToString([]byte(cred.Username+":"+password)))
+			}
+		}
+	}
+	return values
+}
+
+// runLogMasker masks secret variables and the stored credentials
+func runLogMasker(secrets map[string]string) *logMasker {
+	return newLogMasker(append(mapValues(secrets), credentialValues()...)...)
+}
+
+// pipelineLogMasker masks the secret variables of a pipeline and the stored
+// credentials, for logs read after the run
+func pipelineLogMasker(pipelineID string) *logMasker {
+	secrets, _ := secretValues(SecretScopePipeline, pipelineID)
+	return runLogMasker(secrets)
+}
+
+// freestyleLogMasker masks the secret variables of a freestyle job and the
+// stored credentials, for logs read after the build
+func freestyleLogMasker(jobID string) *logMasker {
+	secrets, _ := secretValues(SecretScopeFreestyle, jobID)
+	return runLogMasker(secrets)
+}
@@ -0,0 +1,72 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogMasker(t *testing.T) {
+	if m := newLogMasker("", "abc", "  "); m != nil {
+		t.Error("masker for values too short to mask")
+	}
+	var none *logMasker
+	if got := none.mask("token abcd"); got != "token abcd" {
+		t.Errorf("nil masker changed output: %q", got)
+	}
+
+	m := newLogMasker(" hunter2 ", "hunter2-admin", "line-one\nline-two", "hunter2")
+	tests := []struct {
+		in, want string
+	}{
+		{"password=hunter2", "password=***"},
+		{"login hunter2-admin", "login ***"},    // the longest value wins
+		{"key: line-one\nline-two", "key: ***"}, // the whole value
+		{"line-two only", "*** only"},           // one of its lines
+		{"hunter hunter2hunter2", "hunter ******"},
+		{"nothing secret", "nothing secret"},
+	}
+	for _, tt := range tests {
+		if got := m.mask(tt.in); got != tt.want {
+			t.Errorf("mask(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMaskingWriter(t *testing.T) {
+	var out strings.Builder
+	w := newMaskingWriter(&out, newLogMasker("s3cr3t-value"))
+
+	// A value split over writes is held back until its line ends
+	for _, p := range []string{"token=s3c", "r3t-va", "lue\nnext ", "line s3cr3t-value"} {
+		if n, err := w.Write([]byte(p)); n != len(p) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", p, n, err)
+		}
+	}
+	if got := out.String(); got != "token=***\n" {
+		t.Errorf("before Flush: %q", got)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "token=***\nnext line ***" {
+		t.Errorf("after Flush: %q", got)
+	}
+
+	// Output without newlines is not held back without end
+	out.Reset()
+	w.Write([]byte(strings.Repeat("x", maxMaskBuffer)))
+	if out.Len() != maxMaskBuffer {
+		t.Errorf("wrote %d bytes of a %d byte line", out.Len(), maxMaskBuffer)
+	}
+
+	// Without a masker, writes go straight through
+	out.Reset()
+	w = newMaskingWriter(&out, nil)
+	w.Write([]byte("s3cr3t-value"))
+	if got := out.String(); got != "s3cr3t-value" {
+		t.Errorf("unmasked writer: %q", got)
+	}
+}
@@ -5,22 +5,33 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/rs/zerolog/log"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 
 	"github.com/gaga951/gagos/internal/k8s"
 )
 
-// GetJobLogs retrieves logs for a specific job in a run
-func GetJobLogs(ctx context.Context, runID, jobName string, tailLines int64) (string, error) {
+// GetJobLogs retrieves logs for a specific job in a run, with the secret
+// variables of its pipeline masked. attempt selects an attempt of a job with
+// retries; 0 is the latest one.
+func GetJobLogs(ctx context.Context, runID, jobName string, attempt int, tailLines int64) (string, error) {
 	run, err := GetRun(runID)
 	if err != nil {
 		return "", err
 	}
+	logs, err := jobLogs(ctx, run, jobName, attempt, tailLines)
+	return pipelineLogMasker(run.PipelineID).mask(logs), err
+}
 
+// jobLogs reads the logs of a job attempt, kept or from its pod
+func jobLogs(ctx context.Context, run *PipelineRun, jobName string, attempt int, tailLines int64) (string, error) {
 	// Find the job
 	var jobRun *JobRun
 	for i := range run.Jobs {
@@ -34,7 +45,31 @@ func GetJobLogs(ctx context.Context, runID, jobName string, tailLines int64) (st
 		return "", fmt.Errorf("job not found: %s", jobName)
 	}
 
-	if jobRun.K8sPodName == "" {
+	podName, logPath := jobRun.K8sPodName, jobRun.LogPath
+	if attempt > 0 && attempt != jobRun.Attempt {
+		podName, logPath = "", ""
+		found := false
+		for _, a := range jobRun.Attempts {
+			if a.Attempt == attempt {
+				podName, logPath, found = a.K8sPodName, a.LogPath, true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("attempt %d not found for job %s", attempt, jobName)
+		}
+	}
+
+	// Finished attempts have kept logs; the pod may be long gone
+	if logPath != "" {
+		logs, err := readJobLogs(ctx, logPath, tailLines)
+		if err == nil || podName == "" {
+			return logs, err
+		}
+		log.Debug().Err(err).Str("run_id", run.ID).Str("job", jobName).Msg("Kept job logs unreadable, reading from pod")
+	}
+
+	if podName == "" {
 		return "", fmt.Errorf("job has not started yet")
 	}
 
@@ -43,15 +78,38 @@ func GetJobLogs(ctx context.Context, runID, jobName string, tailLines int64) (st
 		return "", fmt.Errorf("kubernetes client not initialized")
 	}
 
-	// Get logs from pod
+	// A source checkout logs in its own init container, ahead of the script
+	var logs string
+	if pod, err := clientset.CoreV1().Pods(cicdNamespace).Get(ctx, podName, metav1.GetOptions{}); err == nil {
+		for _, c := range pod.Spec.InitContainers {
+			if c.Name == sourceContainerName {
+				checkout, err := readContainerLogs(ctx, clientset, podName, sourceContainerName, tailLines)
+				if err != nil {
+					return checkout, err
+				}
+				logs = checkout
+			}
+		}
+	}
+
+	runner, err := readContainerLogs(ctx, clientset, podName, "runner", tailLines)
+	if err != nil && logs != "" {
+		// The script never ran when the checkout failed
+		return logs, nil
+	}
+	return logs + runner, err
+}
+
+// readContainerLogs reads the logs of one container of a job pod
+func readContainerLogs(ctx context.Context, clientset kubernetes.Interface, podName, container string, tailLines int64) (string, error) {
 	opts := &corev1.PodLogOptions{
-		Container: "runner",
+		Container: container,
 	}
 	if tailLines > 0 {
 		opts.TailLines = &tailLines
 	}
 
-	req := clientset.CoreV1().Pods(cicdNamespace).GetLogs(jobRun.K8sPodName, opts)
+	req := clientset.CoreV1().Pods(cicdNamespace).GetLogs(podName, opts)
 	stream, err := req.Stream(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get logs: %w", err)
@@ -107,10 +165,32 @@ func StreamJobLogs(c *websocket.Conn, runID, jobName string) {
 		sendWsError(c, fmt.Sprintf("Job not found: %s", jobName))
 		return
 	}
+	masker := pipelineLogMasker(run.PipelineID)
 
 	// Send initial status
 	sendWsStatus(c, string(jobRun.Status))
 
+	// A job on an SSH host writes its kept logs as it runs
+	if jobRun.Host != "" && jobRun.Status == RunStatusRunning && jobRun.LogPath != "" && !strings.HasPrefix(jobRun.LogPath, s3LogScheme) {
+		followJobLogFile(c, runID, jobName, jobRun.LogPath, masker)
+		return
+	}
+
+	// A finished job's kept logs are sent as they are
+	if jobRun.LogPath != "" {
+		logs, err := readJobLogs(ctx, jobRun.LogPath, 0)
+		if err == nil {
+			for _, line := range strings.Split(strings.TrimSuffix(logs, "\n"), "\n") {
+				if err := c.WriteJSON(WsMessage{Type: "log", Line: masker.mask(line), Timestamp: time.Now().Format(time.RFC3339)}); err != nil {
+					return
+				}
+			}
+			c.WriteJSON(WsMessage{Type: "complete", Status: string(jobRun.Status), ExitCode: jobRun.ExitCode})
+			return
+		}
+		log.Debug().Err(err).Str("run_id", runID).Str("job", jobName).Msg("Kept job logs unreadable, streaming from pod")
+	}
+
 	// Wait for pod to be ready
 	if jobRun.K8sPodName == "" {
 		// Poll for pod name
@@ -161,7 +241,7 @@ func StreamJobLogs(c *websocket.Conn, runID, jobName string) {
 	// Read and forward logs
 	scanner := bufio.NewScanner(stream)
 	for scanner.Scan() {
-		line := scanner.Text()
+		line := masker.mask(scanner.Text())
 		msg := WsMessage{
 			Type:      "log",
 			Line:      line,
@@ -201,6 +281,56 @@ func StreamJobLogs(c *websocket.Conn, runID, jobName string) {
 		Msg("Log stream completed")
 }
 
+// followJobLogFile streams a log file that a running job appends to, until
+// the job is no longer running
+func followJobLogFile(c *websocket.Conn, runID, jobName, logPath string, masker *logMasker) {
+	f, err := os.Open(logPath)
+	if err != nil {
+		sendWsError(c, fmt.Sprintf("Failed to open logs: %s", err))
+		return
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var partial string
+	status, exitCode := RunStatusRunning, 0
+	for {
+		line, err := reader.ReadString('\n')
+		partial += line
+		if err == nil {
+			msg := WsMessage{Type: "log", Line: masker.mask(strings.TrimSuffix(partial, "\n")), Timestamp: time.Now().Format(time.RFC3339)}
+			if err := c.WriteJSON(msg); err != nil {
+				return
+			}
+			partial = ""
+			continue
+		}
+		if status != RunStatusRunning {
+			break
+		}
+
+		// At the end of the file: see whether the job is still writing
+		run, err := GetRun(runID)
+		if err != nil {
+			sendWsError(c, err.Error())
+			return
+		}
+		for i := range run.Jobs {
+			if run.Jobs[i].Name == jobName && (run.Jobs[i].Status != RunStatusRunning || run.Jobs[i].LogPath != logPath) {
+				// Read what was written last, then stop
+				status, exitCode = run.Jobs[i].Status, run.Jobs[i].ExitCode
+			}
+		}
+		if status == RunStatusRunning {
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+	if partial != "" {
+		c.WriteJSON(WsMessage{Type: "log", Line: masker.mask(partial), Timestamp: time.Now().Format(time.RFC3339)})
+	}
+	c.WriteJSON(WsMessage{Type: "complete", Status: string(status), ExitCode: exitCode})
+}
+
 func sendWsError(c *websocket.Conn, errMsg string) {
 	msg := WsMessage{
 		Type:  "error",
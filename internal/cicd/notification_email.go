@@ -0,0 +1,372 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gaga951/gagos/internal/egress"
+)
+
+// An email notification is sent through the SMTP server of its config, to
+// the addresses it lists. Subject and body are Go templates over
+// emailTemplateData; a failed run or build adds the last lines of its log.
+// The SMTP password is stored encrypted and never returned by the API.
+// Connections follow the egress policy; the HTTP proxy settings do not apply.
+
+// TLS modes of an SMTP server
+const (
+	EmailTLSStartTLS = "starttls" // upgrade a plain connection, port 587
+	EmailTLSImplicit = "tls"      // TLS from the start, port 465
+	EmailTLSNone     = "none"     // plain connection, port 25
+)
+
+// emailLogExcerptLines is how much of the log of a failed run or build an
+// email shows
+const emailLogExcerptLines = 30
+
+const defaultEmailSubject = `[GAGOS] {{.Kind}} {{.Name}} #{{.Number}} {{.Status}}`
+
+const defaultEmailBody = `{{.Kind}} {{.Name}} #{{.Number}} {{.Status}}.
+
+Trigger:  {{.Trigger}}
+{{- if .Duration}}
+Duration: {{.Duration}}
+{{- end}}
+{{- if .URL}}
+Details:  {{.URL}}
+{{- end}}
+{{- if .Error}}
+
+Error: {{.Error}}
+{{- end}}
+{{- if .LogExcerpt}}
+
+Last lines of the log{{if .Job}} of {{.Job}}{{end}}:
+
+{{.LogExcerpt}}
+{{- end}}
+`
+
+// EmailConfig is the SMTP server and recipients of an email notification
+type EmailConfig struct {
+	Host               string   `json:"host"`
+	Port               int      `json:"port,omitempty"` // default by TLS mode
+	TLS                string   `json:"tls,omitempty"`  // starttls (default), tls or none
+	InsecureSkipVerify bool     `json:"insecure_skip_verify,omitempty"`
+	Username           string   `json:"username,omitempty"`
+	Password           string   `json:"password,omitempty"` // Encrypted; write-only
+	HasPassword        bool     `json:"has_password,omitempty"`
+	From               string   `json:"from"`
+	To                 []string `json:"to"`
+	Subject            string   `json:"subject,omitempty"` // template, default defaultEmailSubject
+	Body               string   `json:"body,omitempty"`    // template, default defaultEmailBody
+}
+
+// emailTemplateData is what the subject and body templates of an email see
+type emailTemplateData struct {
+	Event      string // e.g. run_failed
+	Kind       string // Pipeline or Freestyle job
+	Name       string
+	Number     int
+	Status     string
+	Trigger    string
+	Duration   string
+	Error      string
+	URL        string
+	Job        string // job whose log is excerpted
+	LogExcerpt string
+}
+
+// validateEmailConfig checks the SMTP settings, recipients and templates of
+// an email notification
+func validateEmailConfig(cfg *EmailConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("email is required for email notifications")
+	}
+	if cfg.Host == "" {
+		return fmt.Errorf("email.host is required")
+	}
+	switch cfg.TLS {
+	case "", EmailTLSStartTLS, EmailTLSImplicit, EmailTLSNone:
+	default:
+		return fmt.Errorf("email.tls must be %s, %s or %s", EmailTLSStartTLS, EmailTLSImplicit, EmailTLSNone)
+	}
+	if cfg.Port < 0 || cfg.Port > 65535 {
+		return fmt.Errorf("email.port is invalid")
+	}
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return fmt.Errorf("email.from: %w", err)
+	}
+	if len(cfg.To) == 0 {
+		return fmt.Errorf("email.to needs at least one address")
+	}
+	for _, to := range cfg.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("email.to %q: %w", to, err)
+		}
+	}
+	if _, err := template.New("subject").Parse(cfg.Subject); err != nil {
+		return fmt.Errorf("email.subject: %w", err)
+	}
+	if _, err := template.New("body").Parse(cfg.Body); err != nil {
+		return fmt.Errorf("email.body: %w", err)
+	}
+	return nil
+}
+
+// prepareEmailPassword encrypts a new SMTP password, or keeps the stored
+// one of existing when none is given
+func prepareEmailPassword(config, existing *NotificationConfig) error {
+	cfg := config.Email
+	if cfg == nil {
+		return nil
+	}
+	cfg.HasPassword = false
+	if cfg.Password == "" {
+		if existing != nil && existing.Email != nil {
+			cfg.Password = existing.Email.Password
+		}
+		return nil
+	}
+	if err := InitCrypto(); err != nil {
+		return fmt.Errorf("failed to initialize crypto: %w", err)
+	}
+	encrypted, err := Encrypt(cfg.Password)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt SMTP password: %w", err)
+	}
+	cfg.Password = encrypted
+	return nil
+}
+
+// emailPort is the port of the SMTP server, by default the one of its TLS
+// mode
+func emailPort(cfg *EmailConfig) int {
+	if cfg.Port != 0 {
+		return cfg.Port
+	}
+	switch cfg.TLS {
+	case EmailTLSImplicit:
+		return 465
+	case EmailTLSNone:
+		return 25
+	}
+	return 587
+}
+
+// emailTemplateDataFor describes a notification for the email templates
+func emailTemplateDataFor(payload NotificationPayload) emailTemplateData {
+	data := emailTemplateData{Event: string(payload.Event)}
+	var duration int64
+	switch {
+	case payload.PipelineRun != nil:
+		r := payload.PipelineRun
+		data.Kind, data.Name, data.Number = "Pipeline", r.PipelineName, r.RunNumber
+		data.Status, data.Trigger, data.Error, data.URL = r.Status, r.TriggerType, r.Error, r.URL
+		duration = r.Duration
+		if RunStatus(r.Status) == RunStatusFailed {
+			data.Job, data.LogExcerpt = runLogExcerpt(r.ID)
+		}
+	case payload.Build != nil:
+		b := payload.Build
+		data.Kind, data.Name, data.Number = "Freestyle job", b.JobName, b.BuildNumber
+		data.Status, data.Trigger, data.Error, data.URL = b.Status, b.TriggerType, b.Error, b.URL
+		duration = b.Duration
+		if RunStatus(b.Status) == RunStatusFailed {
+			if logs, err := GetBuildLogs(b.ID); err == nil {
+				data.LogExcerpt = lastLines(logs, emailLogExcerptLines)
+			}
+		}
+	}
+	if duration > 0 {
+		data.Duration = (time.Duration(duration) * time.Millisecond).Round(time.Second).String()
+	}
+	return data
+}
+
+// runLogExcerpt returns the first failed job of a run and the end of its log
+func runLogExcerpt(runID string) (string, string) {
+	run, err := GetRun(runID)
+	if err != nil {
+		return "", ""
+	}
+	for _, job := range run.Jobs {
+		if job.Status != RunStatusFailed {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		logs, err := GetJobLogs(ctx, runID, job.Name, 0, emailLogExcerptLines)
+		cancel()
+		if err != nil {
+			return job.Name, ""
+		}
+		return job.Name, lastLines(logs, emailLogExcerptLines)
+	}
+	return "", ""
+}
+
+// lastLines returns the last n lines of s
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderEmail renders the subject and body of an email notification
+func renderEmail(cfg *EmailConfig, payload NotificationPayload) (string, string, error) {
+	subjectTmpl, bodyTmpl := cfg.Subject, cfg.Body
+	if subjectTmpl == "" {
+		subjectTmpl = defaultEmailSubject
+	}
+	if bodyTmpl == "" {
+		bodyTmpl = defaultEmailBody
+	}
+	data := emailTemplateDataFor(payload)
+
+	var subject, body bytes.Buffer
+	t, err := template.New("subject").Parse(subjectTmpl)
+	if err != nil {
+		return "", "", fmt.Errorf("email subject: %w", err)
+	}
+	if err := t.Execute(&subject, data); err != nil {
+		return "", "", fmt.Errorf("email subject: %w", err)
+	}
+	t, err = template.New("body").Parse(bodyTmpl)
+	if err != nil {
+		return "", "", fmt.Errorf("email body: %w", err)
+	}
+	if err := t.Execute(&body, data); err != nil {
+		return "", "", fmt.Errorf("email body: %w", err)
+	}
+	// A subject is one header line
+	oneLine := strings.Join(strings.Fields(subject.String()), " ")
+	return oneLine, body.String(), nil
+}
+
+// emailMessage renders an RFC 5322 message with a quoted-printable text body
+func emailMessage(cfg *EmailConfig, subject, body string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	b.WriteString("X-Mailer: GAGOS\r\n\r\n")
+	w := quotedprintable.NewWriter(&b)
+	if _, err := w.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// sendEmailNotification sends a notification as an email through the
+// config's SMTP server
+func sendEmailNotification(config *NotificationConfig, payload NotificationPayload) error {
+	cfg := config.Email
+	if cfg == nil {
+		return fmt.Errorf("notification %s has no email settings", config.Name)
+	}
+	subject, body, err := renderEmail(cfg, payload)
+	if err != nil {
+		return err
+	}
+	msg, err := emailMessage(cfg, subject, body)
+	if err != nil {
+		return fmt.Errorf("failed to render email: %w", err)
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return fmt.Errorf("email from: %w", err)
+	}
+
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(emailPort(cfg)))
+	tlsConfig := &tls.Config{ServerName: cfg.Host, InsecureSkipVerify: cfg.InsecureSkipVerify}
+	dialer := egress.Default().Dialer(10 * time.Second)
+	var conn net.Conn
+	if cfg.TLS == EmailTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp %s: %w", addr, err)
+	}
+	defer c.Close()
+
+	if cfg.TLS == "" || cfg.TLS == EmailTLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp %s does not support STARTTLS", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("smtp %s: STARTTLS: %w", addr, err)
+		}
+	}
+	if cfg.Username != "" {
+		password := ""
+		if cfg.Password != "" {
+			if err := InitCrypto(); err != nil {
+				return fmt.Errorf("failed to initialize crypto: %w", err)
+			}
+			if password, err = Decrypt(cfg.Password); err != nil {
+				return fmt.Errorf("failed to decrypt SMTP password: %w", err)
+			}
+		}
+		// PlainAuth refuses to send the password over a plain connection
+		// to anything but localhost
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, password, cfg.Host)); err != nil {
+			return fmt.Errorf("smtp %s: authentication failed: %w", addr, err)
+		}
+	}
+
+	if err := c.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp %s: MAIL FROM: %w", addr, err)
+	}
+	for _, to := range cfg.To {
+		rcpt, _ := mail.ParseAddress(to)
+		if rcpt == nil {
+			return fmt.Errorf("email to %q is invalid", to)
+		}
+		if err := c.Rcpt(rcpt.Address); err != nil {
+			return fmt.Errorf("smtp %s: RCPT TO %s: %w", addr, rcpt.Address, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp %s: DATA: %w", addr, err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("smtp %s: %w", addr, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp %s: %w", addr, err)
+	}
+	return c.Quit()
+}
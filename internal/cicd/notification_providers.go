@@ -0,0 +1,274 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Slack, Microsoft Teams and Discord notifications are posted to the incoming
+// webhook URL of a channel, formatted for that service: a title linking to
+// the run or build in the UI when GAGOS_PUBLIC_URL is set, its status in the
+// status color, and its trigger, duration and error. Webhook notifications
+// post the NotificationPayload as it is.
+
+// maxNotificationError caps the error shown in a chat message; Discord
+// rejects longer field values
+const maxNotificationError = 1000
+
+// Status colors of chat notifications
+const (
+	notificationColorSucceeded = 0x2EB67D
+	notificationColorFailed    = 0xE01E5A
+	notificationColorCancelled = 0x9CA3AF
+	notificationColorRunning   = 0x3B82F6
+)
+
+// ValidateNotificationConfig checks the type of a notification config, empty
+// meaning webhook, and what that type needs
+func ValidateNotificationConfig(config *NotificationConfig) error {
+	switch config.Type {
+	case "", NotificationTypeWebhook, NotificationTypeSlack, NotificationTypeTeams, NotificationTypeDiscord:
+		if config.URL == "" {
+			return fmt.Errorf("url is required")
+		}
+		return nil
+	case NotificationTypeEmail:
+		return validateEmailConfig(config.Email)
+	}
+	return fmt.Errorf("type must be one of %s, %s, %s, %s or %s",
+		NotificationTypeWebhook, NotificationTypeSlack, NotificationTypeTeams, NotificationTypeDiscord, NotificationTypeEmail)
+}
+
+// buildPageURL links to a freestyle build in the GAGOS UI (GAGOS_PUBLIC_URL)
+func buildPageURL(buildID string) string {
+	base := strings.TrimSuffix(os.Getenv("GAGOS_PUBLIC_URL"), "/")
+	if base == "" {
+		return ""
+	}
+	return base + "/?cicd_build=" + url.QueryEscape(buildID)
+}
+
+// notificationField is a labelled value of a chat message
+type notificationField struct {
+	Name  string
+	Value string
+}
+
+// notificationMessage is what a chat notification says, whatever the service
+type notificationMessage struct {
+	Title     string
+	URL       string
+	LinkText  string
+	Color     int // RGB
+	Fields    []notificationField
+	Error     string
+	Timestamp time.Time
+}
+
+// notificationMessageFor sums up a payload for a chat message
+func notificationMessageFor(payload NotificationPayload) notificationMessage {
+	msg := notificationMessage{Timestamp: payload.Timestamp}
+	var status, trigger, errMsg string
+	var duration int64
+	switch {
+	case payload.PipelineRun != nil:
+		r := payload.PipelineRun
+		msg.Title = fmt.Sprintf("Pipeline %s #%d %s", r.PipelineName, r.RunNumber, r.Status)
+		msg.URL = r.URL
+		msg.LinkText = "View run"
+		status, trigger, duration, errMsg = r.Status, r.TriggerType, r.Duration, r.Error
+	case payload.Build != nil:
+		b := payload.Build
+		msg.Title = fmt.Sprintf("Freestyle job %s #%d %s", b.JobName, b.BuildNumber, b.Status)
+		msg.URL = b.URL
+		msg.LinkText = "View build"
+		status, trigger, duration, errMsg = b.Status, b.TriggerType, b.Duration, b.Error
+	default:
+		msg.Title = string(payload.Event)
+	}
+
+	switch RunStatus(status) {
+	case RunStatusSucceeded:
+		msg.Color = notificationColorSucceeded
+	case RunStatusFailed:
+		msg.Color = notificationColorFailed
+	case RunStatusCancelled:
+		msg.Color = notificationColorCancelled
+	default:
+		msg.Color = notificationColorRunning
+	}
+
+	msg.Fields = append(msg.Fields, notificationField{"Status", status})
+	if trigger != "" {
+		msg.Fields = append(msg.Fields, notificationField{"Trigger", trigger})
+	}
+	if duration > 0 {
+		msg.Fields = append(msg.Fields, notificationField{"Duration", (time.Duration(duration) * time.Millisecond).Round(time.Second).String()})
+	}
+	if len(errMsg) > maxNotificationError {
+		errMsg = errMsg[:maxNotificationError] + "..."
+	}
+	msg.Error = errMsg
+	return msg
+}
+
+// notificationBody renders the request body of a notification for the
+// config's type
+func notificationBody(t NotificationType, payload NotificationPayload) ([]byte, error) {
+	switch t {
+	case NotificationTypeSlack:
+		return json.Marshal(slackMessage(notificationMessageFor(payload)))
+	case NotificationTypeTeams:
+		return json.Marshal(teamsMessage(notificationMessageFor(payload)))
+	case NotificationTypeDiscord:
+		return json.Marshal(discordMessage(notificationMessageFor(payload)))
+	}
+	return json.Marshal(payload)
+}
+
+// slackEscape escapes the characters Slack's mrkdwn reserves
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// slackMessage renders a message for a Slack incoming webhook: Block Kit
+// blocks in an attachment that carries the status color
+func slackMessage(msg notificationMessage) map[string]interface{} {
+	title := "*" + slackEscape(msg.Title) + "*"
+	if msg.URL != "" {
+		title = fmt.Sprintf("*<%s|%s>*", msg.URL, slackEscape(msg.Title))
+	}
+	fields := make([]map[string]interface{}, 0, len(msg.Fields))
+	for _, f := range msg.Fields {
+		fields = append(fields, map[string]interface{}{
+			"type": "mrkdwn",
+			"text": fmt.Sprintf("*%s*\n%s", f.Name, slackEscape(f.Value)),
+		})
+	}
+	blocks := []map[string]interface{}{
+		{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": title}},
+		{"type": "section", "fields": fields},
+	}
+	if msg.Error != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": "```" + slackEscape(msg.Error) + "```"},
+		})
+	}
+	return map[string]interface{}{
+		"text": msg.Title,
+		"attachments": []map[string]interface{}{{
+			"color":  fmt.Sprintf("#%06X", msg.Color),
+			"blocks": blocks,
+		}},
+	}
+}
+
+// teamsMessage renders a message for a Microsoft Teams workflow webhook: an
+// Adaptive Card whose title takes the status color
+func teamsMessage(msg notificationMessage) map[string]interface{} {
+	color := "Accent"
+	switch msg.Color {
+	case notificationColorSucceeded:
+		color = "Good"
+	case notificationColorFailed:
+		color = "Attention"
+	case notificationColorCancelled:
+		color = "Default"
+	}
+	facts := make([]map[string]interface{}, 0, len(msg.Fields))
+	for _, f := range msg.Fields {
+		facts = append(facts, map[string]interface{}{"title": f.Name, "value": f.Value})
+	}
+	body := []map[string]interface{}{
+		{"type": "TextBlock", "text": msg.Title, "weight": "Bolder", "size": "Medium", "color": color, "wrap": true},
+		{"type": "FactSet", "facts": facts},
+	}
+	if msg.Error != "" {
+		body = append(body, map[string]interface{}{
+			"type": "TextBlock", "text": msg.Error, "color": "Attention", "fontType": "Monospace", "wrap": true,
+		})
+	}
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"msteams": map[string]interface{}{"width": "Full"},
+		"body":    body,
+	}
+	if msg.URL != "" {
+		card["actions"] = []map[string]interface{}{
+			{"type": "Action.OpenUrl", "title": msg.LinkText, "url": msg.URL},
+		}
+	}
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	}
+}
+
+// discordMessage renders a message for a Discord webhook: an embed in the
+// status color
+func discordMessage(msg notificationMessage) map[string]interface{} {
+	fields := make([]map[string]interface{}, 0, len(msg.Fields)+1)
+	for _, f := range msg.Fields {
+		fields = append(fields, map[string]interface{}{"name": f.Name, "value": f.Value, "inline": true})
+	}
+	if msg.Error != "" {
+		fields = append(fields, map[string]interface{}{"name": "Error", "value": "```" + msg.Error + "```"})
+	}
+	embed := map[string]interface{}{
+		"title":  msg.Title,
+		"color":  msg.Color,
+		"fields": fields,
+	}
+	if msg.URL != "" {
+		embed["url"] = msg.URL
+	}
+	if !msg.Timestamp.IsZero() {
+		embed["timestamp"] = msg.Timestamp.UTC().Format(time.RFC3339)
+	}
+	return map[string]interface{}{
+		"username": "GAGOS",
+		"embeds":   []map[string]interface{}{embed},
+	}
+}
+
+// notificationTestPayload is the payload a test notification sends: a
+// successful run of a sample pipeline
+func notificationTestPayload() NotificationPayload {
+	return NotificationPayload{
+		Event:     NotificationEventRunSucceeded,
+		Timestamp: time.Now(),
+		PipelineRun: &RunNotification{
+			ID:           "test-run",
+			PipelineID:   "test-pipeline",
+			PipelineName: "test-pipeline",
+			RunNumber:    1,
+			Status:       string(RunStatusSucceeded),
+			TriggerType:  "test",
+			Duration:     42000,
+			URL:          runPageURL("test-run"),
+		},
+	}
+}
+
+// SendTestNotification sends a sample notification formatted for the
+// config's type and reports whether it was accepted. An email test without
+// a password uses the one stored in existing, when given.
+func SendTestNotification(config, existing *NotificationConfig) error {
+	if err := prepareEmailPassword(config, existing); err != nil {
+		return err
+	}
+	return postNotification(config, notificationTestPayload())
+}
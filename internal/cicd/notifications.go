@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gaga951/gagos/internal/egress"
 	"github.com/gaga951/gagos/internal/storage"
 	"github.com/rs/zerolog/log"
 )
@@ -21,6 +22,8 @@ type NotificationType string
 const (
 	NotificationTypeWebhook NotificationType = "webhook"
 	NotificationTypeSlack   NotificationType = "slack"
+	NotificationTypeTeams   NotificationType = "teams"
+	NotificationTypeDiscord NotificationType = "discord"
 	NotificationTypeEmail   NotificationType = "email"
 )
 
@@ -44,16 +47,31 @@ type NotificationConfig struct {
 	Name        string              `json:"name"`
 	Type        NotificationType    `json:"type"`
 	Enabled     bool                `json:"enabled"`
-	Events      []NotificationEvent `json:"events"`       // Events to notify on
-	URL         string              `json:"url"`          // Webhook URL
-	Secret      string              `json:"secret"`       // For HMAC signing
-	Headers     map[string]string   `json:"headers"`      // Custom headers
-	JobIDs      []string            `json:"job_ids"`      // Filter by job IDs (empty = all)
-	PipelineIDs []string            `json:"pipeline_ids"` // Filter by pipeline IDs (empty = all)
+	Events      []NotificationEvent `json:"events"`          // Events to notify on
+	URL         string              `json:"url"`             // Webhook URL
+	Secret      string              `json:"secret"`          // For HMAC signing
+	Headers     map[string]string   `json:"headers"`         // Custom headers
+	JobIDs      []string            `json:"job_ids"`         // Filter by job IDs (empty = all)
+	PipelineIDs []string            `json:"pipeline_ids"`    // Filter by pipeline IDs (empty = all)
+	Proxy       string              `json:"proxy"`           // Proxy URL or "direct" (empty = GAGOS_PROXY_NOTIFICATIONS or GAGOS_PROXY)
+	Email       *EmailConfig        `json:"email,omitempty"` // SMTP settings of email notifications
 	CreatedAt   time.Time           `json:"created_at"`
 	UpdatedAt   time.Time           `json:"updated_at"`
 }
 
+// ToSafe returns a copy of the config without the SMTP password for API
+// responses
+func (c *NotificationConfig) ToSafe() *NotificationConfig {
+	safe := *c
+	if c.Email != nil {
+		email := *c.Email
+		email.HasPassword = email.Password != ""
+		email.Password = ""
+		safe.Email = &email
+	}
+	return &safe
+}
+
 // NotificationPayload is the webhook payload structure
 type NotificationPayload struct {
 	Event       NotificationEvent `json:"event"`
@@ -91,9 +109,28 @@ type RunNotification struct {
 var (
 	notificationConfigs   = make(map[string]*NotificationConfig)
 	notificationConfigsMu sync.RWMutex
-	httpClient            = &http.Client{Timeout: 10 * time.Second}
+	httpClient            = defaultNotificationClient()
 )
 
+// notificationClient returns a client through the notifications proxy, or
+// through proxy when it is set
+func notificationClient(proxy string) (*http.Client, error) {
+	via, err := egress.ProxyFor(egress.ProxyNotifications, proxy)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Timeout: 10 * time.Second, Transport: egress.Default().TransportVia(nil, via)}, nil
+}
+
+func defaultNotificationClient() *http.Client {
+	client, err := notificationClient("")
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid notifications proxy, sending notifications directly")
+		return &http.Client{Timeout: 10 * time.Second, Transport: egress.Default().Transport(nil)}
+	}
+	return client
+}
+
 // generateNotificationID generates a unique ID for a notification config
 func generateNotificationID() string {
 	return generateID("notif")
@@ -101,6 +138,9 @@ func generateNotificationID() string {
 
 // CreateNotificationConfig creates a new notification configuration
 func CreateNotificationConfig(config *NotificationConfig) (*NotificationConfig, error) {
+	if err := prepareEmailPassword(config, nil); err != nil {
+		return nil, err
+	}
 	config.ID = generateNotificationID()
 	config.CreatedAt = time.Now()
 	config.UpdatedAt = time.Now()
@@ -176,6 +216,9 @@ func UpdateNotificationConfig(id string, config *NotificationConfig) (*Notificat
 		return nil, err
 	}
 
+	if err := prepareEmailPassword(config, existing); err != nil {
+		return nil, err
+	}
 	config.ID = existing.ID
 	config.CreatedAt = existing.CreatedAt
 	config.UpdatedAt = time.Now()
@@ -286,10 +329,11 @@ func NotifyBuildEvent(event NotificationEvent, build *FreestyleBuild) {
 					TriggerType: build.TriggerType,
 					Duration:    build.Duration,
 					Error:       build.Error,
+					URL:         buildPageURL(build.ID),
 				},
 			}
 
-			sendWebhookNotification(config, payload)
+			sendNotification(config, payload)
 		}
 	}()
 }
@@ -354,63 +398,86 @@ func NotifyPipelineRunEvent(event NotificationEvent, run *PipelineRun, pipelineN
 					TriggerType:  run.TriggerType,
 					Duration:     duration,
 					Error:        run.Error,
+					URL:          runPageURL(run.ID),
 				},
 			}
 
-			sendWebhookNotification(config, payload)
+			sendNotification(config, payload)
 		}
 	}()
 }
 
-// sendWebhookNotification sends a webhook notification
-func sendWebhookNotification(config *NotificationConfig, payload NotificationPayload) {
-	data, err := json.Marshal(payload)
-	if err != nil {
-		log.Error().Err(err).Str("config", config.Name).Msg("Failed to marshal notification payload")
+// sendNotification sends a notification, logging its outcome
+func sendNotification(config *NotificationConfig, payload NotificationPayload) {
+	if err := postNotification(config, payload); err != nil {
+		log.Warn().Err(err).Str("config", config.Name).Str("url", config.URL).Msg("Failed to send notification")
 		return
 	}
+	log.Debug().
+		Str("config", config.Name).
+		Str("event", string(payload.Event)).
+		Msg("Notification sent")
+}
+
+// postNotification posts a notification to the config's URL, formatted for
+// its type
+func postNotification(config *NotificationConfig, payload NotificationPayload) error {
+	if config.Type == NotificationTypeEmail {
+		return sendEmailNotification(config, payload)
+	}
+
+	data, err := notificationBody(config.Type, payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
 
 	req, err := http.NewRequest(http.MethodPost, config.URL, bytes.NewReader(data))
 	if err != nil {
-		log.Error().Err(err).Str("config", config.Name).Msg("Failed to create notification request")
-		return
+		return fmt.Errorf("failed to create notification request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "GAGOS-Webhook/1.0")
-	req.Header.Set("X-GAGOS-Event", string(payload.Event))
+	if config.Type == "" || config.Type == NotificationTypeWebhook {
+		req.Header.Set("X-GAGOS-Event", string(payload.Event))
+
+		// Add HMAC signature if secret is configured
+		if config.Secret != "" {
+			signature := computeHMAC(data, config.Secret)
+			req.Header.Set("X-GAGOS-Signature", "sha256="+signature)
+		}
+	}
 
 	// Add custom headers
 	for k, v := range config.Headers {
 		req.Header.Set(k, v)
 	}
 
-	// Add HMAC signature if secret is configured
-	if config.Secret != "" {
-		signature := computeHMAC(data, config.Secret)
-		req.Header.Set("X-GAGOS-Signature", "sha256="+signature)
+	client := httpClient
+	if config.Proxy != "" {
+		if client, err = notificationClient(config.Proxy); err != nil {
+			return fmt.Errorf("invalid notification proxy: %w", err)
+		}
 	}
 
-	resp, err := httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		log.Error().Err(err).Str("config", config.Name).Str("url", config.URL).Msg("Failed to send notification")
-		return
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		log.Warn().
-			Str("config", config.Name).
-			Str("url", config.URL).
-			Int("status", resp.StatusCode).
-			Msg("Notification webhook returned error")
-	} else {
-		log.Debug().
-			Str("config", config.Name).
-			Str("event", string(payload.Event)).
-			Int("status", resp.StatusCode).
-			Msg("Notification sent")
+		return fmt.Errorf("%s notification returned HTTP %d", notificationTypeName(config.Type), resp.StatusCode)
+	}
+	return nil
+}
+
+// notificationTypeName names a notification type in messages
+func notificationTypeName(t NotificationType) string {
+	if t == "" {
+		return string(NotificationTypeWebhook)
 	}
+	return string(t)
 }
 
 // computeHMAC computes HMAC-SHA256 signature
@@ -4,106 +4,169 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"path"
 	"strings"
 	"time"
-
-	"gopkg.in/yaml.v3"
 )
 
-// ParsePipelineYAML parses and validates pipeline YAML
+// ParsePipelineYAML parses and validates pipeline YAML. Its problems are
+// returned as PipelineErrors.
 func ParsePipelineYAML(yamlContent string) (*Pipeline, error) {
-	var pipelineYAML PipelineYAML
-	if err := yaml.Unmarshal([]byte(yamlContent), &pipelineYAML); err != nil {
-		return nil, fmt.Errorf("invalid YAML syntax: %w", err)
-	}
-
-	// Validate required fields
-	if err := validatePipelineYAML(&pipelineYAML); err != nil {
-		return nil, err
+	pipelineYAML, errs := ValidatePipelineYAML(yamlContent)
+	if len(errs) > 0 {
+		return nil, errs
 	}
 
 	// Convert to Pipeline
-	pipeline := convertYAMLToPipeline(&pipelineYAML, yamlContent)
+	pipeline := convertYAMLToPipeline(pipelineYAML, yamlContent)
 
 	return pipeline, nil
 }
 
-// validatePipelineYAML validates the parsed YAML structure
-func validatePipelineYAML(p *PipelineYAML) error {
+// validatePipelineYAML validates the parsed YAML structure, reporting every
+// problem to v
+func validatePipelineYAML(p *PipelineYAML, v *pipelineValidator) {
 	// Validate apiVersion
 	if p.APIVersion != "gagos.io/v1" {
-		return fmt.Errorf("unsupported apiVersion: %s (expected gagos.io/v1)", p.APIVersion)
+		v.errorf(at("apiVersion"), "unsupported apiVersion: %s (expected gagos.io/v1)", p.APIVersion)
 	}
 
 	// Validate kind
 	if p.Kind != "Pipeline" {
-		return fmt.Errorf("invalid kind: %s (expected Pipeline)", p.Kind)
+		v.errorf(at("kind"), "invalid kind: %s (expected Pipeline)", p.Kind)
 	}
 
 	// Validate metadata
 	if p.Metadata.Name == "" {
-		return fmt.Errorf("metadata.name is required")
-	}
-
-	// Validate name format (alphanumeric, dashes, underscores)
-	if !isValidName(p.Metadata.Name) {
-		return fmt.Errorf("metadata.name must contain only alphanumeric characters, dashes, and underscores")
+		v.errorf(at("metadata", "name"), "metadata.name is required")
+	} else if !isValidName(p.Metadata.Name) {
+		// Validate name format (alphanumeric, dashes, underscores)
+		v.errorf(at("metadata", "name"), "metadata.name must contain only alphanumeric characters, dashes, and underscores")
 	}
 
 	// Validate jobs
 	if len(p.Spec.Jobs) == 0 {
-		return fmt.Errorf("at least one job is required in spec.jobs")
+		v.errorf(at("spec", "jobs"), "at least one job is required in spec.jobs")
 	}
 
+	allJobs := make(map[string]bool, len(p.Spec.Jobs))
+	for _, job := range p.Spec.Jobs {
+		allJobs[job.Name] = true
+	}
 	jobNames := make(map[string]bool)
 	for i, job := range p.Spec.Jobs {
+		jobAt := at("spec", "jobs", i)
 		if job.Name == "" {
-			return fmt.Errorf("job[%d].name is required", i)
-		}
-		if !isValidName(job.Name) {
-			return fmt.Errorf("job[%d].name must contain only alphanumeric characters, dashes, and underscores", i)
-		}
-		if jobNames[job.Name] {
-			return fmt.Errorf("duplicate job name: %s", job.Name)
+			v.errorf(jobAt.with("name"), "job[%d].name is required", i)
+		} else if !isValidName(job.Name) {
+			v.errorf(jobAt.with("name"), "job[%d].name must contain only alphanumeric characters, dashes, and underscores", i)
+		} else if jobNames[job.Name] {
+			v.errorf(jobAt.with("name"), "duplicate job name: %s", job.Name)
 		}
 		jobNames[job.Name] = true
 
-		if job.Image == "" {
-			return fmt.Errorf("job[%d].image is required", i)
+		if v.unexpanded[i] {
+			// Its template was reported, and so would be all it lacks
+			continue
+		}
+
+		if err := validateJobKind(job.Kind, (*BuildImageSpec)(job.Build)); err != nil {
+			v.errorf(jobAt.with("kind"), "job[%d].%v", i, err)
+		}
+		if job.RunsOn != "" {
+			if err := validateRunsOn(job.RunsOn); err != nil {
+				v.errorf(jobAt.with("runsOn"), "job[%d].%v", i, err)
+			}
+			if job.Kind != "" || job.Image != "" || job.Privileged || len(job.Secrets) > 0 || job.Resources != (ResourceSpecYAML{}) {
+				v.errorf(jobAt.with("runsOn"), "job[%d] runs on an SSH host, which takes no kind, image, privileged, secrets or resources", i)
+			}
+			if job.Script == "" {
+				v.errorf(jobAt.with("script"), "job[%d].script is required", i)
+			}
+		} else if job.Kind == JobKindBuildImage {
+			if job.Image != "" || job.Script != "" {
+				v.errorf(jobAt.with("kind"), "job[%d] of kind %s takes build instead of image and script", i, JobKindBuildImage)
+			}
+			if job.Privileged {
+				v.errorf(jobAt.with("privileged"), "job[%d] of kind %s does not run privileged", i, JobKindBuildImage)
+			}
+		} else {
+			if job.Image == "" {
+				v.errorf(jobAt.with("image"), "job[%d].image is required", i)
+			}
+			if job.Script == "" {
+				v.errorf(jobAt.with("script"), "job[%d].script is required", i)
+			}
+		}
+		if job.Environment != "" && !isValidName(job.Environment) {
+			v.errorf(jobAt.with("environment"), "job[%d].environment must contain only alphanumeric characters, dashes, and underscores", i)
+		}
+		if job.Retries < 0 || job.Retries > MaxJobRetries {
+			v.errorf(jobAt.with("retries"), "job[%d].retries must be between 0 and %d", i, MaxJobRetries)
 		}
-		if job.Script == "" {
-			return fmt.Errorf("job[%d].script is required", i)
+		if job.RetryDelay < 0 {
+			v.errorf(jobAt.with("retryDelay"), "job[%d].retryDelay must not be negative", i)
+		}
+		if job.Source != nil {
+			if err := validateSource((*SourceSpec)(job.Source)); err != nil {
+				v.errorf(jobAt.with("source"), "job[%d].%v", i, err)
+			}
 		}
 
 		// Validate dependsOn references
-		for _, dep := range job.DependsOn {
-			if !jobNames[dep] {
-				// Check if it's defined later
-				found := false
-				for _, j := range p.Spec.Jobs {
-					if j.Name == dep {
-						found = true
-						break
-					}
-				}
-				if !found {
-					return fmt.Errorf("job[%d] references unknown dependency: %s", i, dep)
-				}
+		for d, dep := range job.DependsOn {
+			if !allJobs[dep] {
+				v.errorf(jobAt.with("dependsOn").with(d), "job[%d] references unknown dependency: %s", i, dep)
 			}
 		}
 	}
 
+	if p.Spec.Concurrency != nil {
+		if err := validateConcurrency((*ConcurrencySpec)(p.Spec.Concurrency)); err != nil {
+			v.errorf(at("spec", "concurrency"), "%v", err)
+		}
+	}
+	if p.Spec.CommitStatus != nil {
+		if err := validateCommitStatus((*CommitStatusSpec)(p.Spec.CommitStatus)); err != nil {
+			v.errorf(at("spec", "commitStatus"), "%v", err)
+		}
+	}
+	if p.Spec.Coverage != nil {
+		if err := validateCoverage((*CoverageSpec)(p.Spec.Coverage)); err != nil {
+			v.errorf(at("spec", "coverage"), "%v", err)
+		}
+	}
+	if p.Spec.Retention != nil {
+		if err := validateRetention((*RetentionSpec)(p.Spec.Retention)); err != nil {
+			v.errorf(at("spec", "retention"), "%v", err)
+		}
+	}
+
 	// Validate triggers
 	for i, trigger := range p.Spec.Triggers {
-		if trigger.Type != "webhook" && trigger.Type != "cron" {
-			return fmt.Errorf("trigger[%d].type must be 'webhook' or 'cron'", i)
+		triggerAt := at("spec", "triggers", i)
+		if trigger.Type != "webhook" && trigger.Type != "cron" && trigger.Type != TriggerTypePollSCM {
+			v.errorf(triggerAt.with("type"), "trigger[%d].type must be 'webhook', 'cron' or '%s'", i, TriggerTypePollSCM)
 		}
 		if trigger.Type == "cron" && trigger.Schedule == "" {
-			return fmt.Errorf("trigger[%d].schedule is required for cron triggers", i)
+			v.errorf(triggerAt.with("schedule"), "trigger[%d].schedule is required for cron triggers", i)
+		}
+		if trigger.Type == TriggerTypePollSCM {
+			if err := validatePollSCM(trigger.Schedule, trigger.Repo); err != nil {
+				v.errorf(triggerAt, "trigger[%d]: %v", i, err)
+			}
+		} else if trigger.Repo != "" || trigger.Branch != "" || trigger.CredentialID != "" {
+			v.errorf(triggerAt, "trigger[%d].repo, branch and credentialId only apply to %s triggers", i, TriggerTypePollSCM)
+		}
+		if len(trigger.Branches) > 0 && trigger.Type != "webhook" {
+			v.errorf(triggerAt.with("branches"), "trigger[%d].branches only applies to webhook triggers", i)
+		}
+		for b, pattern := range trigger.Branches {
+			if _, err := path.Match(pattern, ""); err != nil {
+				v.errorf(triggerAt.with("branches").with(b), "trigger[%d].branches has an invalid pattern %q", i, pattern)
+			}
 		}
 	}
-
-	return nil
 }
 
 // isValidName checks if a name contains only valid characters
@@ -141,18 +204,48 @@ func convertYAMLToPipeline(p *PipelineYAML, yamlContent string) *Pipeline {
 			TotalRuns: 0,
 		},
 	}
+	if p.Spec.Concurrency != nil {
+		concurrency := ConcurrencySpec(*p.Spec.Concurrency)
+		pipeline.Spec.Concurrency = &concurrency
+	}
+	if p.Spec.CommitStatus != nil {
+		commitStatus := CommitStatusSpec(*p.Spec.CommitStatus)
+		pipeline.Spec.CommitStatus = &commitStatus
+	}
+	if p.Spec.Coverage != nil {
+		coverage := CoverageSpec(*p.Spec.Coverage)
+		pipeline.Spec.Coverage = &coverage
+	}
+	if p.Spec.Retention != nil {
+		retention := RetentionSpec(*p.Spec.Retention)
+		pipeline.Spec.Retention = &retention
+	}
 
 	// Convert jobs
 	for _, j := range p.Spec.Jobs {
 		job := JobSpec{
-			Name:       j.Name,
-			Image:      j.Image,
-			Workdir:    j.Workdir,
-			Script:     j.Script,
-			Timeout:    j.Timeout,
-			Privileged: j.Privileged,
-			DependsOn:  j.DependsOn,
-			SkipIf:     j.SkipIf,
+			Name:        j.Name,
+			Image:       j.Image,
+			Workdir:     j.Workdir,
+			Script:      j.Script,
+			Timeout:     j.Timeout,
+			Privileged:  j.Privileged,
+			DependsOn:   j.DependsOn,
+			SkipIf:      j.SkipIf,
+			Retries:     j.Retries,
+			RetryDelay:  j.RetryDelay,
+			RunsOn:      j.RunsOn,
+			Environment: j.Environment,
+			Uses:        j.Uses,
+		}
+		if j.Source != nil {
+			source := SourceSpec(*j.Source)
+			job.Source = &source
+		}
+		if j.Kind == JobKindBuildImage {
+			build := BuildImageSpec(*j.Build)
+			job.Kind = j.Kind
+			job.Build = &build
 		}
 
 		if job.Timeout == 0 {
@@ -207,10 +300,14 @@ func convertYAMLToPipeline(p *PipelineYAML, yamlContent string) *Pipeline {
 			enabled = *t.Enabled
 		}
 		trigger := Trigger{
-			Type:     t.Type,
-			Secret:   t.Secret,
-			Schedule: t.Schedule,
-			Enabled:  enabled,
+			Type:         t.Type,
+			Secret:       t.Secret,
+			Schedule:     t.Schedule,
+			Enabled:      enabled,
+			Branches:     t.Branches,
+			Repo:         t.Repo,
+			Branch:       t.Branch,
+			CredentialID: t.CredentialID,
 		}
 		pipeline.Spec.Triggers = append(pipeline.Spec.Triggers, trigger)
 
@@ -278,6 +375,8 @@ spec:
           --insecure \
           --skip-tls-verify
       timeout: 600
+      retries: 2         # retry a failed build twice, after 30s then 60s
+      retryDelay: 30
       resources:
         limits:
           memory: "1Gi"
@@ -0,0 +1,403 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// A pipeline YAML is checked as a whole rather than up to its first
+// problem: fields the schema does not know, values of the wrong type, the
+// rules of each section, cron schedules and job dependencies. Every problem
+// is reported with the line and column it was found at.
+
+// cronParser parses schedules the way the scheduler does, after cronSpec
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// jobYAMLType is the type pipeline jobs are read into
+var jobYAMLType = reflect.TypeOf(JobYAML{})
+
+// yamlLineRe finds the line in the errors of the YAML decoder
+var yamlLineRe = regexp.MustCompile(`^(?:yaml: )?line (\d+): `)
+
+// PipelineError is a problem of a pipeline YAML
+type PipelineError struct {
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Path    string `json:"path,omitempty"` // e.g. spec.jobs[1].image
+	Message string `json:"message"`
+}
+
+func (e *PipelineError) Error() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+// PipelineErrors is every problem found in a pipeline YAML, in the order of
+// the document
+type PipelineErrors []*PipelineError
+
+func (e PipelineErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AsPipelineErrors returns the problems of a pipeline YAML that err reports,
+// if any
+func AsPipelineErrors(err error) PipelineErrors {
+	var errs PipelineErrors
+	if errors.As(err, &errs) {
+		return errs
+	}
+	return nil
+}
+
+// yamlPath is the place of a value in a YAML document: mapping keys and
+// sequence indexes
+type yamlPath []interface{}
+
+func at(path ...interface{}) yamlPath {
+	return path
+}
+
+// with returns p extended by one key or index
+func (p yamlPath) with(elem interface{}) yamlPath {
+	return append(append(yamlPath{}, p...), elem)
+}
+
+func (p yamlPath) String() string {
+	var b strings.Builder
+	for _, elem := range p {
+		switch e := elem.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", e)
+		default:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			fmt.Fprint(&b, e)
+		}
+	}
+	return b.String()
+}
+
+// pipelineValidator collects the problems of a pipeline YAML
+type pipelineValidator struct {
+	root       *yaml.Node // top-level mapping
+	errs       PipelineErrors
+	unexpanded map[int]bool // jobs whose template could not be expanded
+}
+
+// node returns the node at path, or the deepest one on the way to it that
+// exists, so that a missing field is reported where it should be
+func (v *pipelineValidator) node(path yamlPath) *yaml.Node {
+	n := v.root
+	for _, elem := range path {
+		next := childNode(n, elem)
+		if next == nil {
+			break
+		}
+		n = next
+	}
+	return n
+}
+
+// childNode returns the value of a key of a mapping or an item of a sequence
+func childNode(n *yaml.Node, elem interface{}) *yaml.Node {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	switch e := elem.(type) {
+	case string:
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == e {
+				return n.Content[i+1]
+			}
+		}
+	case int:
+		if n.Kind == yaml.SequenceNode && e < len(n.Content) {
+			return n.Content[e]
+		}
+	}
+	return nil
+}
+
+// errorf reports a problem of the value at path
+func (v *pipelineValidator) errorf(path yamlPath, format string, args ...interface{}) {
+	v.add(v.node(path), path, fmt.Sprintf(format, args...))
+}
+
+func (v *pipelineValidator) add(n *yaml.Node, path yamlPath, msg string) {
+	err := &PipelineError{Path: path.String(), Message: msg}
+	if n != nil {
+		err.Line, err.Column = n.Line, n.Column
+	}
+	v.errs = append(v.errs, err)
+}
+
+// ValidatePipelineYAML parses a pipeline YAML and checks it, returning every
+// problem it has. The pipeline is nil when the YAML could not be read.
+func ValidatePipelineYAML(yamlContent string) (*PipelineYAML, PipelineErrors) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlContent), &doc); err != nil {
+		return nil, PipelineErrors{yamlError(err.Error(), "invalid YAML syntax: ")}
+	}
+	if len(doc.Content) == 0 {
+		return nil, PipelineErrors{{Message: "pipeline YAML is empty"}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, PipelineErrors{{Line: root.Line, Column: root.Column, Message: "pipeline YAML must be a mapping"}}
+	}
+
+	v := &pipelineValidator{root: root}
+	var p PipelineYAML
+	if err := root.Decode(&p); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, PipelineErrors{yamlError(err.Error(), "")}
+		}
+		// The decoder still sets every value it could read
+		for _, msg := range typeErr.Errors {
+			v.errs = append(v.errs, yamlError(msg, ""))
+		}
+	}
+
+	checkKnownFields(v, root, reflect.TypeOf(p), nil)
+	expandJobTemplates(&p, v)
+	validatePipelineYAML(&p, v)
+	validateSchedules(&p, v)
+	validateDependencies(&p, v)
+
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		a, b := v.errs[i], v.errs[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return &p, v.errs
+}
+
+// yamlError turns an error of the YAML decoder into a PipelineError at the
+// line it names
+func yamlError(msg, prefix string) *PipelineError {
+	err := &PipelineError{}
+	if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+		err.Line, _ = strconv.Atoi(m[1])
+		msg = msg[len(m[0]):]
+	}
+	err.Message = prefix + strings.TrimPrefix(msg, "yaml: ")
+	return err
+}
+
+// checkKnownFields reports the keys of mappings that the type they are read
+// into has no field for
+func checkKnownFields(v *pipelineValidator, n *yaml.Node, t reflect.Type, path yamlPath) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Tag == "!!merge" {
+				checkKnownFields(v, value, t, path)
+				continue
+			}
+			ft, ok := fields[key.Value]
+			if !ok {
+				msg := fmt.Sprintf("unknown field %q", key.Value)
+				if len(path) > 0 {
+					msg += " in " + path.String()
+				}
+				if s := closestField(key.Value, fields); s != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", s)
+				}
+				v.add(key, path.with(key.Value), msg)
+				continue
+			}
+			checkKnownFields(v, value, ft, path.with(key.Value))
+		}
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range n.Content {
+			checkKnownFields(v, item, t.Elem(), path.with(i))
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			checkKnownFields(v, n.Content[i+1], t.Elem(), path.with(n.Content[i].Value))
+		}
+	}
+}
+
+// yamlFields maps the YAML keys of a struct to the types of their fields
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// closestField suggests the field a mistyped key meant: one that differs
+// only in case or by at most two edits
+func closestField(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		if strings.EqualFold(name, key) {
+			return name
+		}
+		if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance of a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// validateSchedules checks the cron expressions of cron and pollSCM triggers
+func validateSchedules(p *PipelineYAML, v *pipelineValidator) {
+	for i, trigger := range p.Spec.Triggers {
+		if trigger.Schedule == "" || (trigger.Type != "cron" && trigger.Type != TriggerTypePollSCM) {
+			continue
+		}
+		if _, err := cronParser.Parse(cronSpec(trigger.Schedule)); err != nil {
+			v.errorf(at("spec", "triggers", i, "schedule"), "trigger[%d].schedule %q is not a valid cron expression: %v", i, trigger.Schedule, err)
+		}
+	}
+}
+
+// validateDependencies reports dependency cycles, and dependencies on jobs
+// declared later, which would not have run yet since jobs run in the order
+// they are declared. Unknown dependencies are reported by
+// validatePipelineYAML.
+func validateDependencies(p *PipelineYAML, v *pipelineValidator) {
+	index := make(map[string]int, len(p.Spec.Jobs))
+	for i, job := range p.Spec.Jobs {
+		if _, dup := index[job.Name]; !dup {
+			index[job.Name] = i
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(p.Spec.Jobs))
+	inCycle := make([]bool, len(p.Spec.Jobs))
+	var stack []int
+	var visit func(i int)
+	visit = func(i int) {
+		state[i] = visiting
+		stack = append(stack, i)
+		for d, dep := range p.Spec.Jobs[i].DependsOn {
+			j, ok := index[dep]
+			if !ok {
+				continue
+			}
+			switch state[j] {
+			case unvisited:
+				visit(j)
+			case visiting:
+				// The stack from j back to i is a cycle
+				names := []string{}
+				for k := len(stack) - 1; k >= 0; k-- {
+					names = append(names, p.Spec.Jobs[stack[k]].Name)
+					inCycle[stack[k]] = true
+					if stack[k] == j {
+						break
+					}
+				}
+				for l, r := 0, len(names)-1; l < r; l, r = l+1, r-1 {
+					names[l], names[r] = names[r], names[l]
+				}
+				names = append(names, p.Spec.Jobs[j].Name)
+				v.errorf(at("spec", "jobs", i, "dependsOn", d), "job[%d] has a dependency cycle: %s", i, strings.Join(names, " -> "))
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = done
+	}
+	for i := range p.Spec.Jobs {
+		if state[i] == unvisited {
+			visit(i)
+		}
+	}
+
+	for i, job := range p.Spec.Jobs {
+		if inCycle[i] {
+			continue
+		}
+		for d, dep := range job.DependsOn {
+			if j, ok := index[dep]; ok && j > i {
+				v.errorf(at("spec", "jobs", i, "dependsOn", d), "job[%d] depends on %s, which is declared after it; jobs run in the order they are declared", i, dep)
+			}
+		}
+	}
+}
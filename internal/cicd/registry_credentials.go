@@ -0,0 +1,247 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// dockerHubRegistry is the auth key Docker clients use for Docker Hub
+const dockerHubRegistry = "https://index.docker.io/v1/"
+
+// RegistryCredential is a login to a container registry that build-image
+// jobs push with. The password is stored encrypted.
+type RegistryCredential struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Registry    string    `json:"registry"` // host, e.g. ghcr.io or registry.example.com:5000
+	Username    string    `json:"username"`
+	Password    string    `json:"password,omitempty"` // Encrypted password or token
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RegistryCredentialSafe is RegistryCredential without the password for API
+// responses
+type RegistryCredentialSafe struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Registry    string    `json:"registry"`
+	Username    string    `json:"username"`
+	HasPassword bool      `json:"has_password"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ToSafe converts RegistryCredential to RegistryCredentialSafe
+func (c *RegistryCredential) ToSafe() RegistryCredentialSafe {
+	return RegistryCredentialSafe{
+		ID:          c.ID,
+		Name:        c.Name,
+		Description: c.Description,
+		Registry:    c.Registry,
+		Username:    c.Username,
+		HasPassword: c.Password != "",
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+	}
+}
+
+// RegistryCredentialRequest is the request body for creating or updating a
+// registry credential. On update, empty fields are left unchanged.
+type RegistryCredentialRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Registry    string `json:"registry"`
+	Username    string `json:"username"`
+	Password    string `json:"password,omitempty"`
+}
+
+// normalizeRegistry reduces a registry to the host, and maps the Docker Hub
+// aliases to the key Docker clients look up
+func normalizeRegistry(registry string) string {
+	registry = strings.TrimSpace(registry)
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	registry = strings.TrimSuffix(registry, "/")
+	switch registry {
+	case "docker.io", "index.docker.io", "registry-1.docker.io", "index.docker.io/v1":
+		return dockerHubRegistry
+	}
+	return registry
+}
+
+// imageRegistry returns the registry an image reference pushes to
+func imageRegistry(image string) string {
+	first, _, found := strings.Cut(image, "/")
+	if !found || !strings.ContainsAny(first, ".:") && first != "localhost" {
+		return dockerHubRegistry
+	}
+	return normalizeRegistry(first)
+}
+
+// CreateRegistryCredential stores a new registry credential
+func CreateRegistryCredential(req *RegistryCredentialRequest) (*RegistryCredential, error) {
+	if req.Name == "" || req.Registry == "" || req.Username == "" || req.Password == "" {
+		return nil, fmt.Errorf("name, registry, username and password are required")
+	}
+	if err := InitCrypto(); err != nil {
+		return nil, fmt.Errorf("failed to initialize crypto: %w", err)
+	}
+	encPassword, err := Encrypt(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	now := time.Now()
+	cred := &RegistryCredential{
+		ID:          generateID("reg"),
+		Name:        req.Name,
+		Description: req.Description,
+		Registry:    normalizeRegistry(req.Registry),
+		Username:    req.Username,
+		Password:    encPassword,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := saveRegistryCredential(cred); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("id", cred.ID).Str("name", cred.Name).Str("registry", cred.Registry).Msg("Registry credential created")
+	return cred, nil
+}
+
+// GetRegistryCredential retrieves a registry credential by ID
+func GetRegistryCredential(id string) (*RegistryCredential, error) {
+	data, err := storage.GetBackend().Get(storage.BucketRegistryCreds, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential: %w", err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("registry credential not found: %s", id)
+	}
+
+	var cred RegistryCredential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal credential: %w", err)
+	}
+	return &cred, nil
+}
+
+// ListRegistryCredentialsSafe returns all registry credentials without
+// passwords, sorted by name
+func ListRegistryCredentialsSafe() ([]RegistryCredentialSafe, error) {
+	dataList, err := storage.GetBackend().List(storage.BucketRegistryCreds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credentials: %w", err)
+	}
+
+	creds := make([]RegistryCredentialSafe, 0, len(dataList))
+	for _, data := range dataList {
+		var cred RegistryCredential
+		if err := json.Unmarshal(data, &cred); err != nil {
+			log.Warn().Err(err).Msg("Failed to unmarshal registry credential")
+			continue
+		}
+		creds = append(creds, cred.ToSafe())
+	}
+	sort.Slice(creds, func(i, j int) bool {
+		return creds[i].Name < creds[j].Name
+	})
+	return creds, nil
+}
+
+// UpdateRegistryCredential updates an existing registry credential
+func UpdateRegistryCredential(id string, req *RegistryCredentialRequest) (*RegistryCredential, error) {
+	cred, err := GetRegistryCredential(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		cred.Name = req.Name
+	}
+	if req.Description != "" {
+		cred.Description = req.Description
+	}
+	if req.Registry != "" {
+		cred.Registry = normalizeRegistry(req.Registry)
+	}
+	if req.Username != "" {
+		cred.Username = req.Username
+	}
+	if req.Password != "" {
+		if err := InitCrypto(); err != nil {
+			return nil, fmt.Errorf("failed to initialize crypto: %w", err)
+		}
+		encPassword, err := Encrypt(req.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt password: %w", err)
+		}
+		cred.Password = encPassword
+	}
+	cred.UpdatedAt = time.Now()
+
+	if err := saveRegistryCredential(cred); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("id", cred.ID).Str("name", cred.Name).Msg("Registry credential updated")
+	return cred, nil
+}
+
+// DeleteRegistryCredential deletes a registry credential
+func DeleteRegistryCredential(id string) error {
+	if _, err := GetRegistryCredential(id); err != nil {
+		return err
+	}
+	if err := storage.GetBackend().Delete(storage.BucketRegistryCreds, id); err != nil {
+		return fmt.Errorf("failed to delete credential: %w", err)
+	}
+
+	log.Info().Str("id", id).Msg("Registry credential deleted")
+	return nil
+}
+
+func saveRegistryCredential(cred *RegistryCredential) error {
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return fmt.Errorf("failed to marshal credential: %w", err)
+	}
+	if err := storage.GetBackend().Set(storage.BucketRegistryCreds, cred.ID, data); err != nil {
+		return fmt.Errorf("failed to save credential: %w", err)
+	}
+	return nil
+}
+
+// dockerConfigJSON renders a Docker config.json that logs in to the
+// credential's registry
+func dockerConfigJSON(cred *RegistryCredential) ([]byte, error) {
+	if err := InitCrypto(); err != nil {
+		return nil, fmt.Errorf("failed to initialize crypto: %w", err)
+	}
+	password, err := Decrypt(cred.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt password: %w", err)
+	}
+
+	type authEntry struct {
+		Auth string `json:"auth"`
+	}
+	auths := map[string]authEntry{
+		cred.Registry: {Auth: base64.StdEncoding.EncodeToString([]byte(cred.Username + ":" + password))},
+	}
+	return json.Marshal(map[string]any{"auths": auths})
+}
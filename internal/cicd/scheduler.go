@@ -2,6 +2,9 @@ package cicd
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -17,8 +20,10 @@ var (
 // Scheduler manages cron-based pipeline and freestyle job triggers
 type Scheduler struct {
 	cron           *cron.Cron
-	jobs           map[string]cron.EntryID // pipelineID -> entryID
-	freestyleJobs  map[string]cron.EntryID // freestyleJobID -> entryID
+	jobs           map[string]cron.EntryID   // pipelineID -> entryID
+	freestyleJobs  map[string]cron.EntryID   // freestyleJobID -> entryID
+	pipelinePolls  map[string][]cron.EntryID // pipelineID -> pollSCM entryIDs
+	freestylePolls map[string][]cron.EntryID // freestyleJobID -> pollSCM entryIDs
 	mu             sync.RWMutex
 	stopChan       chan struct{}
 }
@@ -27,10 +32,12 @@ type Scheduler struct {
 func InitScheduler() *Scheduler {
 	schedulerOnce.Do(func() {
 		scheduler = &Scheduler{
-			cron:          cron.New(cron.WithSeconds()),
-			jobs:          make(map[string]cron.EntryID),
-			freestyleJobs: make(map[string]cron.EntryID),
-			stopChan:      make(chan struct{}),
+			cron:           cron.New(cron.WithSeconds()),
+			jobs:           make(map[string]cron.EntryID),
+			freestyleJobs:  make(map[string]cron.EntryID),
+			pipelinePolls:  make(map[string][]cron.EntryID),
+			freestylePolls: make(map[string][]cron.EntryID),
+			stopChan:       make(chan struct{}),
 		}
 	})
 	return scheduler
@@ -60,6 +67,9 @@ func (s *Scheduler) Start() error {
 		log.Warn().Err(err).Msg("Failed to start cleanup scheduler")
 	}
 
+	// Queue again the runs that waited for a concurrency slot
+	resumeQueuedRuns()
+
 	// Start the cron scheduler
 	s.cron.Start()
 
@@ -93,6 +103,9 @@ func (s *Scheduler) RefreshPipelines() error {
 		s.cron.Remove(entryID)
 	}
 	s.jobs = make(map[string]cron.EntryID)
+	for id := range s.pipelinePolls {
+		s.removePollsUnsafe(s.pipelinePolls, id)
+	}
 
 	// Re-register all pipelines
 	for _, p := range pipelines {
@@ -112,6 +125,7 @@ func (s *Scheduler) RegisterPipeline(p *Pipeline) error {
 		s.cron.Remove(entryID)
 		delete(s.jobs, p.ID)
 	}
+	s.removePollsUnsafe(s.pipelinePolls, p.ID)
 
 	return s.registerPipelineUnsafe(p)
 }
@@ -119,11 +133,18 @@ func (s *Scheduler) RegisterPipeline(p *Pipeline) error {
 // registerPipelineUnsafe registers without locking (caller must hold lock)
 func (s *Scheduler) registerPipelineUnsafe(p *Pipeline) error {
 	for _, trigger := range p.Spec.Triggers {
+		if trigger.Type == TriggerTypePollSCM && trigger.Enabled {
+			pipelineID, t := p.ID, trigger
+			s.addPollUnsafe(s.pipelinePolls, p.ID, p.Name, t.Schedule, func() {
+				pollPipeline(pipelineID, t)
+			})
+			continue
+		}
 		if trigger.Type != "cron" || !trigger.Enabled || trigger.Schedule == "" {
 			continue
 		}
 
-		schedule := trigger.Schedule
+		schedule := cronSpec(trigger.Schedule)
 
 		// Create a closure for the trigger
 		pipelineID := p.ID
@@ -162,6 +183,35 @@ func (s *Scheduler) UnregisterPipeline(pipelineID string) {
 		delete(s.jobs, pipelineID)
 		log.Info().Str("pipeline_id", pipelineID).Msg("Unregistered cron trigger")
 	}
+	s.removePollsUnsafe(s.pipelinePolls, pipelineID)
+}
+
+// addPollUnsafe registers a pollSCM trigger of owner. A poll still running
+// when the schedule fires again is not started twice.
+func (s *Scheduler) addPollUnsafe(polls map[string][]cron.EntryID, owner, name, schedule string, poll func()) {
+	job := cron.NewChain(cron.SkipIfStillRunning(cron.DiscardLogger)).Then(cron.FuncJob(poll))
+	entryID, err := s.cron.AddJob(cronSpec(schedule), job)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("name", name).
+			Str("schedule", schedule).
+			Msg("Failed to register SCM poll schedule")
+		return
+	}
+	polls[owner] = append(polls[owner], entryID)
+	log.Info().
+		Str("name", name).
+		Str("schedule", schedule).
+		Msg("Registered SCM poll trigger")
+}
+
+// removePollsUnsafe removes the pollSCM triggers of owner
+func (s *Scheduler) removePollsUnsafe(polls map[string][]cron.EntryID, owner string) {
+	for _, entryID := range polls[owner] {
+		s.cron.Remove(entryID)
+	}
+	delete(polls, owner)
 }
 
 // triggerPipeline is called when a cron schedule fires
@@ -236,6 +286,9 @@ func (s *Scheduler) RefreshFreestyleJobs() error {
 		s.cron.Remove(entryID)
 	}
 	s.freestyleJobs = make(map[string]cron.EntryID)
+	for id := range s.freestylePolls {
+		s.removePollsUnsafe(s.freestylePolls, id)
+	}
 
 	// Re-register all freestyle jobs
 	for _, j := range jobs {
@@ -255,6 +308,7 @@ func (s *Scheduler) RegisterFreestyleJob(j *FreestyleJob) error {
 		s.cron.Remove(entryID)
 		delete(s.freestyleJobs, j.ID)
 	}
+	s.removePollsUnsafe(s.freestylePolls, j.ID)
 
 	return s.registerFreestyleJobUnsafe(j)
 }
@@ -266,11 +320,18 @@ func (s *Scheduler) registerFreestyleJobUnsafe(j *FreestyleJob) error {
 	}
 
 	for _, trigger := range j.Triggers {
+		if trigger.Type == TriggerTypePollSCM && trigger.Enabled && trigger.Schedule != "" {
+			jobID := j.ID
+			s.addPollUnsafe(s.freestylePolls, j.ID, j.Name, trigger.Schedule, func() {
+				pollFreestyleJob(jobID)
+			})
+			continue
+		}
 		if trigger.Type != "cron" || !trigger.Enabled || trigger.Schedule == "" {
 			continue
 		}
 
-		schedule := trigger.Schedule
+		schedule := cronSpec(trigger.Schedule)
 
 		// Create a closure for the trigger
 		jobID := j.ID
@@ -309,6 +370,7 @@ func (s *Scheduler) UnregisterFreestyleJob(jobID string) {
 		delete(s.freestyleJobs, jobID)
 		log.Info().Str("job_id", jobID).Msg("Unregistered freestyle job cron trigger")
 	}
+	s.removePollsUnsafe(s.freestylePolls, jobID)
 }
 
 // triggerFreestyleJob is called when a cron schedule fires for freestyle job
@@ -360,21 +422,34 @@ type ScheduledFreestyleJobInfo struct {
 
 // Retention policy settings
 const (
-	DefaultFreestyleBuildRetention = 50          // Keep last 50 builds per job
-	DefaultPipelineRunRetention    = 100         // Keep last 100 runs per pipeline
-	DefaultMaxRetentionDays        = 30          // Maximum age in days
-	CleanupSchedule                = "0 0 3 * * *" // Run cleanup at 3 AM daily
+	DefaultFreestyleBuildRetention = 50            // Keep last 50 builds per job
+	DefaultPipelineRunRetention    = 100           // Keep last 100 runs per pipeline
+	DefaultMaxRetentionDays        = 30            // Maximum age in days
+	CleanupSchedule                = "0 0 * * * *" // Run cleanup hourly
 )
 
-// RetentionConfig holds retention policy settings
+// RetentionConfig holds retention policy settings. Pipelines and freestyle
+// jobs may override them with their own retention.
 type RetentionConfig struct {
-	FreestyleBuildsPerJob int `json:"freestyle_builds_per_job"`
+	FreestyleBuildsPerJob   int `json:"freestyle_builds_per_job"`
 	PipelineRunsPerPipeline int `json:"pipeline_runs_per_pipeline"`
-	MaxRetentionDays      int `json:"max_retention_days"`
+	MaxRetentionDays        int `json:"max_retention_days"`       // 0 keeps them whatever their age
+	ArtifactMBPerPipeline   int `json:"artifact_mb_per_pipeline"` // 0 for no cap
+}
+
+// CleanupResult counts what a cleanup deleted
+type CleanupResult struct {
+	PipelineRuns    int   `json:"pipeline_runs"`
+	FreestyleBuilds int   `json:"freestyle_builds"`
+	Artifacts       int   `json:"artifacts"` // deleted to keep pipelines under their cap
+	ArtifactBytes   int64 `json:"artifact_bytes"`
+	Duration        int64 `json:"duration_ms"`
 }
 
 var (
-	cleanupEntryID cron.EntryID
+	cleanupEntryID  cron.EntryID
+	cleanupMu       sync.Mutex // one cleanup at a time
+	retentionMu     sync.RWMutex
 	retentionConfig = RetentionConfig{
 		FreestyleBuildsPerJob:   DefaultFreestyleBuildRetention,
 		PipelineRunsPerPipeline: DefaultPipelineRunRetention,
@@ -382,8 +457,95 @@ var (
 	}
 )
 
+// LoadRetentionConfig reads the global retention from GAGOS_CICD_KEEP_RUNS,
+// GAGOS_CICD_KEEP_BUILDS, GAGOS_CICD_RETENTION_DAYS and
+// GAGOS_CICD_ARTIFACT_QUOTA_MB, keeping the defaults of unset variables
+func LoadRetentionConfig() {
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+
+	envInt := func(name string, value *int, min int) {
+		v := os.Getenv(name)
+		if v == "" {
+			return
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < min {
+			log.Warn().Str(name, v).Msg("Invalid retention setting, keeping the default")
+			return
+		}
+		*value = n
+	}
+	envInt("GAGOS_CICD_KEEP_RUNS", &retentionConfig.PipelineRunsPerPipeline, 1)
+	envInt("GAGOS_CICD_KEEP_BUILDS", &retentionConfig.FreestyleBuildsPerJob, 1)
+	envInt("GAGOS_CICD_RETENTION_DAYS", &retentionConfig.MaxRetentionDays, 0)
+	envInt("GAGOS_CICD_ARTIFACT_QUOTA_MB", &retentionConfig.ArtifactMBPerPipeline, 0)
+}
+
+func validateRetention(r *RetentionSpec) error {
+	if r.KeepRuns < 0 {
+		return fmt.Errorf("retention.keepRuns must not be negative")
+	}
+	if r.MaxAgeDays < 0 {
+		return fmt.Errorf("retention.maxAgeDays must not be negative")
+	}
+	if r.MaxArtifactsMB < 0 {
+		return fmt.Errorf("retention.maxArtifactsMB must not be negative")
+	}
+	return nil
+}
+
+// pipelineRetention is the global retention with the overrides of pipeline,
+// which is nil for the runs of a deleted pipeline
+func pipelineRetention(pipeline *Pipeline) (keep int, cutoff time.Time, maxArtifacts int64) {
+	config := GetRetentionConfig()
+	keep, days, mb := config.PipelineRunsPerPipeline, config.MaxRetentionDays, config.ArtifactMBPerPipeline
+	if pipeline != nil && pipeline.Spec.Retention != nil {
+		r := pipeline.Spec.Retention
+		if r.KeepRuns > 0 {
+			keep = r.KeepRuns
+		}
+		if r.MaxAgeDays > 0 {
+			days = r.MaxAgeDays
+		}
+		if r.MaxArtifactsMB > 0 {
+			mb = r.MaxArtifactsMB
+		}
+	}
+	if days > 0 {
+		cutoff = time.Now().AddDate(0, 0, -days)
+	}
+	return keep, cutoff, int64(mb) << 20
+}
+
+// freestyleRetention is the global retention with the overrides of job
+func freestyleRetention(job *FreestyleJob) (keep int, cutoff time.Time) {
+	config := GetRetentionConfig()
+	keep, days := config.FreestyleBuildsPerJob, config.MaxRetentionDays
+	if r := job.Retention; r != nil {
+		if r.KeepBuilds > 0 {
+			keep = r.KeepBuilds
+		}
+		if r.MaxAgeDays > 0 {
+			days = r.MaxAgeDays
+		}
+	}
+	if days > 0 {
+		cutoff = time.Now().AddDate(0, 0, -days)
+	}
+	return keep, cutoff
+}
+
+// expired tells whether the i-th newest run or build, created at created, is
+// past its retention
+func expired(i int, created time.Time, keep int, cutoff time.Time) bool {
+	return i >= keep || (!cutoff.IsZero() && created.Before(cutoff))
+}
+
 // StartCleanupScheduler registers the cleanup job with the scheduler
 func (s *Scheduler) StartCleanupScheduler() error {
+	LoadRetentionConfig()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -405,36 +567,36 @@ func (s *Scheduler) StartCleanupScheduler() error {
 	return nil
 }
 
-// RunCleanup performs cleanup of old builds and runs
-func (s *Scheduler) RunCleanup() {
+// RunCleanup performs cleanup of old builds, runs and artifacts
+func (s *Scheduler) RunCleanup() *CleanupResult {
+	cleanupMu.Lock()
+	defer cleanupMu.Unlock()
+
 	log.Info().Msg("Starting scheduled cleanup")
 	startTime := time.Now()
-
-	var totalDeleted int
+	result := &CleanupResult{}
 
 	// Cleanup freestyle builds
 	freestyleDeleted, err := s.cleanupFreestyleBuilds()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to cleanup freestyle builds")
-	} else {
-		totalDeleted += freestyleDeleted
 	}
+	result.FreestyleBuilds = freestyleDeleted
 
-	// Cleanup pipeline runs
-	pipelineDeleted, err := s.cleanupPipelineRuns()
-	if err != nil {
+	// Cleanup pipeline runs and their artifacts
+	if err := s.cleanupPipelineRuns(result); err != nil {
 		log.Error().Err(err).Msg("Failed to cleanup pipeline runs")
-	} else {
-		totalDeleted += pipelineDeleted
 	}
 
-	duration := time.Since(startTime)
+	result.Duration = time.Since(startTime).Milliseconds()
 	log.Info().
-		Int("total_deleted", totalDeleted).
-		Int("freestyle_builds", freestyleDeleted).
-		Int("pipeline_runs", pipelineDeleted).
-		Dur("duration", duration).
+		Int("total_deleted", result.FreestyleBuilds+result.PipelineRuns+result.Artifacts).
+		Int("freestyle_builds", result.FreestyleBuilds).
+		Int("pipeline_runs", result.PipelineRuns).
+		Int("artifacts", result.Artifacts).
+		Int64("duration_ms", result.Duration).
 		Msg("Cleanup completed")
+	return result
 }
 
 // cleanupFreestyleBuilds removes old freestyle builds based on retention policy
@@ -445,7 +607,6 @@ func (s *Scheduler) cleanupFreestyleBuilds() (int, error) {
 	}
 
 	var totalDeleted int
-	cutoffTime := time.Now().AddDate(0, 0, -retentionConfig.MaxRetentionDays)
 
 	for _, job := range jobs {
 		builds, err := ListFreestyleBuildsForJob(job.ID)
@@ -455,38 +616,20 @@ func (s *Scheduler) cleanupFreestyleBuilds() (int, error) {
 		}
 
 		// Builds are already sorted newest first
+		keep, cutoff := freestyleRetention(job)
 		for i, build := range builds {
-			// Keep if within count limit
-			if i < retentionConfig.FreestyleBuildsPerJob {
+			if build.Status == RunStatusRunning || build.Status == RunStatusPending {
 				continue
 			}
-
-			// Skip running builds
-			if build.Status == RunStatusRunning {
+			if !expired(i, build.CreatedAt, keep, cutoff) {
 				continue
 			}
-
-			// Delete if beyond count limit
 			if err := DeleteFreestyleBuild(build.ID); err != nil {
 				log.Warn().Err(err).Str("build_id", build.ID).Msg("Failed to delete old build")
 			} else {
 				totalDeleted++
 			}
 		}
-
-		// Also delete builds older than max retention days (even if within count)
-		for _, build := range builds {
-			if build.Status == RunStatusRunning {
-				continue
-			}
-			if build.CreatedAt.Before(cutoffTime) {
-				if err := DeleteFreestyleBuild(build.ID); err != nil {
-					// May already be deleted, ignore error
-					continue
-				}
-				totalDeleted++
-			}
-		}
 	}
 
 	if totalDeleted > 0 {
@@ -495,65 +638,99 @@ func (s *Scheduler) cleanupFreestyleBuilds() (int, error) {
 	return totalDeleted, nil
 }
 
-// cleanupPipelineRuns removes old pipeline runs based on retention policy
-func (s *Scheduler) cleanupPipelineRuns() (int, error) {
+// cleanupPipelineRuns removes old pipeline runs based on retention policy,
+// then the oldest artifacts of pipelines over their artifact cap. Runs of
+// deleted pipelines take the global retention. The run of each
+// environment's current deployment is kept so it can still be rolled back
+// to.
+func (s *Scheduler) cleanupPipelineRuns(result *CleanupResult) error {
 	pipelines, err := ListPipelines()
 	if err != nil {
-		return 0, err
+		return err
+	}
+	runs, err := ListRuns("", 0)
+	if err != nil {
+		return err
 	}
 
-	var totalDeleted int
-	cutoffTime := time.Now().AddDate(0, 0, -retentionConfig.MaxRetentionDays)
+	byID := make(map[string]*Pipeline, len(pipelines))
+	for _, p := range pipelines {
+		byID[p.ID] = p
+	}
+	protected := make(map[string]bool)
+	if envs, err := ListEnvironments(); err == nil {
+		for _, env := range envs {
+			if env.Current != nil {
+				protected[env.Current.RunID] = true
+			}
+		}
+	}
 
-	for _, pipeline := range pipelines {
-		runs, err := ListRuns(pipeline.ID, 0)
-		if err != nil {
-			log.Warn().Err(err).Str("pipeline_id", pipeline.ID).Msg("Failed to list runs for cleanup")
+	// Runs are sorted newest first
+	active := make(map[string]bool)
+	seen := make(map[string]int)
+	for _, run := range runs {
+		i := seen[run.PipelineID]
+		seen[run.PipelineID]++
+		if run.Status == RunStatusRunning || run.Status == RunStatusPending {
+			active[run.ID] = true
 			continue
 		}
+		keep, cutoff, _ := pipelineRetention(byID[run.PipelineID])
+		if protected[run.ID] || !expired(i, run.CreatedAt, keep, cutoff) {
+			continue
+		}
+		if err := DeleteRun(run.ID); err != nil {
+			log.Warn().Err(err).Str("run_id", run.ID).Msg("Failed to delete old pipeline run")
+		} else {
+			result.PipelineRuns++
+		}
+	}
 
-		// Delete runs beyond retention count
-		for i, run := range runs {
-			// Keep if within count limit
-			if i < retentionConfig.PipelineRunsPerPipeline {
-				continue
-			}
-
-			// Skip running
-			if run.Status == RunStatusRunning {
-				continue
-			}
+	if result.PipelineRuns > 0 {
+		log.Info().Int("deleted", result.PipelineRuns).Msg("Cleaned up old pipeline runs")
+	}
 
-			// Delete if beyond count limit
-			if err := DeleteRun(run.ID); err != nil {
-				log.Warn().Err(err).Str("run_id", run.ID).Msg("Failed to delete old pipeline run")
-			} else {
-				totalDeleted++
-			}
+	for id := range seen {
+		_, _, maxArtifacts := pipelineRetention(byID[id])
+		if maxArtifacts > 0 {
+			s.capPipelineArtifacts(id, maxArtifacts, active, result)
 		}
+	}
+	return nil
+}
 
-		// Also delete runs older than max retention days
-		for _, run := range runs {
-			if run.Status == RunStatusRunning {
-				continue
-			}
-			if run.StartedAt != nil && run.StartedAt.Before(cutoffTime) {
-				if err := DeleteRun(run.ID); err != nil {
-					continue
-				}
-				totalDeleted++
-			}
-		}
+// capPipelineArtifacts deletes the oldest artifacts of a pipeline until the
+// rest fit in maxBytes. Artifacts of active runs are kept.
+func (s *Scheduler) capPipelineArtifacts(pipelineID string, maxBytes int64, active map[string]bool, result *CleanupResult) {
+	artifacts, err := ListArtifacts("", pipelineID)
+	if err != nil {
+		log.Warn().Err(err).Str("pipeline_id", pipelineID).Msg("Failed to list artifacts for cleanup")
+		return
 	}
 
-	if totalDeleted > 0 {
-		log.Info().Int("deleted", totalDeleted).Msg("Cleaned up old pipeline runs")
+	// Artifacts are sorted newest first
+	var total int64
+	for _, a := range artifacts {
+		total += a.Size
+		if total <= maxBytes || active[a.RunID] {
+			continue
+		}
+		if err := DeleteArtifact(a.ID); err != nil {
+			log.Warn().Err(err).Str("artifact_id", a.ID).Msg("Failed to delete artifact over the pipeline cap")
+			continue
+		}
+		total -= a.Size
+		result.Artifacts++
+		result.ArtifactBytes += a.Size
 	}
-	return totalDeleted, nil
 }
 
 // SetRetentionConfig updates the retention policy settings
 func SetRetentionConfig(config RetentionConfig) {
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+
 	if config.FreestyleBuildsPerJob > 0 {
 		retentionConfig.FreestyleBuildsPerJob = config.FreestyleBuildsPerJob
 	}
@@ -563,15 +740,21 @@ func SetRetentionConfig(config RetentionConfig) {
 	if config.MaxRetentionDays > 0 {
 		retentionConfig.MaxRetentionDays = config.MaxRetentionDays
 	}
+	if config.ArtifactMBPerPipeline > 0 {
+		retentionConfig.ArtifactMBPerPipeline = config.ArtifactMBPerPipeline
+	}
 	log.Info().
 		Int("freestyle_builds", retentionConfig.FreestyleBuildsPerJob).
 		Int("pipeline_runs", retentionConfig.PipelineRunsPerPipeline).
 		Int("max_days", retentionConfig.MaxRetentionDays).
+		Int("artifact_mb", retentionConfig.ArtifactMBPerPipeline).
 		Msg("Retention config updated")
 }
 
 // GetRetentionConfig returns the current retention policy settings
 func GetRetentionConfig() RetentionConfig {
+	retentionMu.RLock()
+	defer retentionMu.RUnlock()
 	return retentionConfig
 }
 
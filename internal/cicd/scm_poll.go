@@ -0,0 +1,210 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gaga951/gagos/internal/bundle"
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// A pollSCM trigger runs git ls-remote on its schedule and triggers a run
+// when the polled branch has moved, for repositories that cannot reach GAGOS
+// with a webhook. The last commit seen is kept per trigger; the first poll
+// only records it.
+
+// TriggerTypePollSCM is the trigger type that polls a Git branch
+const TriggerTypePollSCM = "pollSCM"
+
+// scmPollState is what a poll remembers of its branch
+type scmPollState struct {
+	Commit    string    `json:"commit"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// validatePollSCM checks the schedule and repository of a pollSCM trigger
+func validatePollSCM(schedule, repo string) error {
+	if schedule == "" {
+		return fmt.Errorf("schedule is required for %s triggers", TriggerTypePollSCM)
+	}
+	if repo == "" {
+		return fmt.Errorf("repo is required for %s triggers", TriggerTypePollSCM)
+	}
+	if u, err := url.Parse(repo); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("repo must be an http(s) URL")
+	}
+	return nil
+}
+
+// cronSpec adds the seconds field the scheduler expects to a standard
+// five-field cron expression
+func cronSpec(schedule string) string {
+	schedule = strings.TrimSpace(schedule)
+	if !strings.HasPrefix(schedule, "@") && len(strings.Fields(schedule)) == 5 {
+		return "0 " + schedule
+	}
+	return schedule
+}
+
+// gitCredentialAuth returns the HTTPS username and password of a Git
+// credential; empty for a public repository
+func gitCredentialAuth(credentialID string) (string, string, error) {
+	if credentialID == "" {
+		return "", "", nil
+	}
+	cred, err := GetDecryptedGitCredential(credentialID)
+	if err != nil {
+		return "", "", fmt.Errorf("git credential: %w", err)
+	}
+	switch cred.AuthMethod {
+	case GitAuthToken:
+		return "oauth2", cred.Token, nil
+	case GitAuthPassword:
+		return cred.Username, cred.Password, nil
+	}
+	return "", "", fmt.Errorf("git credential %s must use token or password authentication", cred.Name)
+}
+
+// freestyleBranch returns the branch a freestyle job builds, "" for the
+// default branch
+func freestyleBranch(scm *GitSCMConfig) string {
+	if len(scm.Branches) == 0 {
+		return ""
+	}
+	branch := scm.Branches[0].Specifier
+	branch = strings.TrimPrefix(branch, "*/")
+	branch = strings.TrimPrefix(branch, "refs/heads/")
+	branch = strings.TrimPrefix(branch, "origin/")
+	if strings.Contains(branch, "*") {
+		return ""
+	}
+	return branch
+}
+
+// pollBranch checks the branch for a new commit. It returns the commit and
+// the one seen before, and whether the branch moved since the last poll.
+func pollBranch(key, repo, branch, credentialID string) (commit, previous string, changed bool, err error) {
+	username, password, err := gitCredentialAuth(credentialID)
+	if err != nil {
+		return "", "", false, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	commit, err = bundle.LsRemote(ctx, repo, branch, username, password)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	backend := storage.GetBackend()
+	var state scmPollState
+	if data, _ := backend.Get(storage.BucketSCMPolls, key); data != nil {
+		json.Unmarshal(data, &state)
+	}
+	previous = state.Commit
+	state = scmPollState{Commit: commit, CheckedAt: time.Now()}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", "", false, err
+	}
+	if err := backend.Set(storage.BucketSCMPolls, key, data); err != nil {
+		return "", "", false, fmt.Errorf("failed to save poll state: %w", err)
+	}
+	return commit, previous, previous != "" && previous != commit, nil
+}
+
+// scmVariables are the run variables of a run triggered by a poll
+func scmVariables(repo, branch, commit, previous string) map[string]string {
+	vars := map[string]string{
+		"SCM_REPOSITORY":      repo,
+		"SCM_COMMIT":          commit,
+		"SCM_PREVIOUS_COMMIT": previous,
+	}
+	if branch != "" {
+		vars["SCM_BRANCH"] = branch
+	}
+	return vars
+}
+
+// scmTriggerRef describes the commit a poll triggered on
+func scmTriggerRef(branch, commit string) string {
+	if branch == "" {
+		branch = "HEAD"
+	}
+	return "scm:" + branch + "@" + shortSHA(commit)
+}
+
+// pollPipeline polls the repository of a pipeline's pollSCM trigger
+func pollPipeline(pipelineID string, trigger Trigger) {
+	key := fmt.Sprintf("pipeline:%s:%s@%s", pipelineID, trigger.Repo, trigger.Branch)
+	commit, previous, changed, err := pollBranch(key, trigger.Repo, trigger.Branch, trigger.CredentialID)
+	if err != nil {
+		log.Warn().Err(err).Str("pipeline_id", pipelineID).Str("repo", trigger.Repo).Msg("SCM poll failed")
+		return
+	}
+	if !changed {
+		return
+	}
+
+	pipeline, err := GetPipeline(pipelineID)
+	if err != nil {
+		log.Error().Err(err).Str("pipeline_id", pipelineID).Msg("Failed to get pipeline for SCM poll")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	vars := scmVariables(trigger.Repo, trigger.Branch, commit, previous)
+	run, err := TriggerPipeline(ctx, pipeline, "scm", scmTriggerRef(trigger.Branch, commit), vars)
+	if err != nil {
+		log.Error().Err(err).Str("pipeline", pipeline.Name).Msg("Failed to trigger pipeline from SCM poll")
+		return
+	}
+	log.Info().
+		Str("pipeline", pipeline.Name).
+		Str("run_id", run.ID).
+		Str("commit", commit).
+		Msg("Pipeline triggered by SCM change")
+}
+
+// pollFreestyleJob polls the first repository of a freestyle job
+func pollFreestyleJob(jobID string) {
+	job, err := GetFreestyleJob(jobID)
+	if err != nil {
+		log.Error().Err(err).Str("job_id", jobID).Msg("Failed to get freestyle job for SCM poll")
+		return
+	}
+	if job.SCM == nil || job.SCM.Type != "git" || len(job.SCM.Repositories) == 0 {
+		return
+	}
+	repo := job.SCM.Repositories[0]
+	branch := freestyleBranch(job.SCM)
+
+	key := fmt.Sprintf("freestyle:%s:%s@%s", jobID, repo.URL, branch)
+	commit, previous, changed, err := pollBranch(key, repo.URL, branch, repo.CredentialID)
+	if err != nil {
+		log.Warn().Err(err).Str("job", job.Name).Str("repo", repo.URL).Msg("SCM poll failed")
+		return
+	}
+	if !changed {
+		return
+	}
+
+	params := scmVariables(repo.URL, branch, commit, previous)
+	build, err := TriggerFreestyleBuild(jobID, "scm", scmTriggerRef(branch, commit), params)
+	if err != nil {
+		log.Error().Err(err).Str("job", job.Name).Msg("Failed to trigger freestyle job from SCM poll")
+		return
+	}
+	log.Info().
+		Str("job", job.Name).
+		Str("build_id", build.ID).
+		Str("commit", commit).
+		Msg("Freestyle job triggered by SCM change")
+}
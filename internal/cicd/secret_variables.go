@@ -0,0 +1,356 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Secret variables are environment variables whose values are stored
+// encrypted and belong to one pipeline or freestyle job. They are decrypted
+// only while a run executes: pipeline jobs in K8s read them from a Secret
+// that lives as long as the attempt, jobs on SSH hosts and freestyle steps
+// get them exported ahead of their script. They are never stored with the
+// run or build, and their values are masked in its logs.
+
+// Scopes of a secret variable
+const (
+	SecretScopePipeline  = "pipeline"
+	SecretScopeFreestyle = "freestyle"
+)
+
+// SecretVariable is an encrypted environment variable of a pipeline or
+// freestyle job
+type SecretVariable struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"` // environment variable name
+	Description string    `json:"description,omitempty"`
+	Scope       string    `json:"scope"`           // pipeline or freestyle
+	ScopeID     string    `json:"scope_id"`        // ID of the pipeline or freestyle job
+	Value       string    `json:"value,omitempty"` // Encrypted value
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SecretVariableSafe is SecretVariable without the value for API responses
+type SecretVariableSafe struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Scope       string    `json:"scope"`
+	ScopeID     string    `json:"scope_id"`
+	HasValue    bool      `json:"has_value"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ToSafe converts SecretVariable to SecretVariableSafe
+func (s *SecretVariable) ToSafe() SecretVariableSafe {
+	return SecretVariableSafe{
+		ID:          s.ID,
+		Name:        s.Name,
+		Description: s.Description,
+		Scope:       s.Scope,
+		ScopeID:     s.ScopeID,
+		HasValue:    s.Value != "",
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+}
+
+// SecretVariableRequest is the request body for creating or updating a
+// secret variable. On update, the scope cannot change and empty fields are
+// left unchanged.
+type SecretVariableRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Scope       string `json:"scope"`
+	ScopeID     string `json:"scope_id"`
+	Value       string `json:"value,omitempty"`
+}
+
+// validateSecretScope checks that the pipeline or freestyle job a secret
+// belongs to exists
+func validateSecretScope(scope, scopeID string) error {
+	if scopeID == "" {
+		return fmt.Errorf("scope_id is required")
+	}
+	switch scope {
+	case SecretScopePipeline:
+		if _, err := GetPipeline(scopeID); err != nil {
+			return err
+		}
+	case SecretScopeFreestyle:
+		if _, err := GetFreestyleJob(scopeID); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("scope must be '%s' or '%s'", SecretScopePipeline, SecretScopeFreestyle)
+	}
+	return nil
+}
+
+// checkSecretName checks the name of a secret, which must be unique in its
+// scope
+func checkSecretName(name, scope, scopeID, id string) error {
+	if !shellName.MatchString(name) {
+		return fmt.Errorf("name must be a valid environment variable name")
+	}
+	secrets, err := listSecretVariables(scope, scopeID)
+	if err != nil {
+		return err
+	}
+	for _, s := range secrets {
+		if s.Name == name && s.ID != id {
+			return fmt.Errorf("secret %s already exists for this %s", name, scope)
+		}
+	}
+	return nil
+}
+
+// CreateSecretVariable stores a new secret variable
+func CreateSecretVariable(req *SecretVariableRequest) (*SecretVariable, error) {
+	if req.Name == "" || req.Value == "" {
+		return nil, fmt.Errorf("name and value are required")
+	}
+	if err := validateSecretScope(req.Scope, req.ScopeID); err != nil {
+		return nil, err
+	}
+	if err := checkSecretName(req.Name, req.Scope, req.ScopeID, ""); err != nil {
+		return nil, err
+	}
+	if err := InitCrypto(); err != nil {
+		return nil, fmt.Errorf("failed to initialize crypto: %w", err)
+	}
+	encValue, err := Encrypt(req.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt value: %w", err)
+	}
+
+	now := time.Now()
+	secret := &SecretVariable{
+		ID:          generateID("sec"),
+		Name:        req.Name,
+		Description: req.Description,
+		Scope:       req.Scope,
+		ScopeID:     req.ScopeID,
+		Value:       encValue,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := saveSecretVariable(secret); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("id", secret.ID).Str("name", secret.Name).Str("scope", secret.Scope).Str("scope_id", secret.ScopeID).Msg("Secret variable created")
+	return secret, nil
+}
+
+// GetSecretVariable retrieves a secret variable by ID
+func GetSecretVariable(id string) (*SecretVariable, error) {
+	data, err := storage.GetBackend().Get(storage.BucketSecretVariables, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret: %w", err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("secret not found: %s", id)
+	}
+
+	var secret SecretVariable
+	if err := json.Unmarshal(data, &secret); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal secret: %w", err)
+	}
+	return &secret, nil
+}
+
+// listSecretVariables returns the secret variables of a scope, all of them
+// when scope is empty
+func listSecretVariables(scope, scopeID string) ([]*SecretVariable, error) {
+	dataList, err := storage.GetBackend().List(storage.BucketSecretVariables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	secrets := make([]*SecretVariable, 0, len(dataList))
+	for _, data := range dataList {
+		var secret SecretVariable
+		if err := json.Unmarshal(data, &secret); err != nil {
+			log.Warn().Err(err).Msg("Failed to unmarshal secret variable")
+			continue
+		}
+		if scope != "" && (secret.Scope != scope || secret.ScopeID != scopeID) {
+			continue
+		}
+		secrets = append(secrets, &secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Name < secrets[j].Name
+	})
+	return secrets, nil
+}
+
+// ListSecretVariablesSafe returns the secret variables of a pipeline or
+// freestyle job without values, or all of them when scope is empty
+func ListSecretVariablesSafe(scope, scopeID string) ([]SecretVariableSafe, error) {
+	secrets, err := listSecretVariables(scope, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	safe := make([]SecretVariableSafe, 0, len(secrets))
+	for _, s := range secrets {
+		safe = append(safe, s.ToSafe())
+	}
+	return safe, nil
+}
+
+// UpdateSecretVariable updates an existing secret variable
+func UpdateSecretVariable(id string, req *SecretVariableRequest) (*SecretVariable, error) {
+	secret, err := GetSecretVariable(id)
+	if err != nil {
+		return nil, err
+	}
+	if (req.Scope != "" && req.Scope != secret.Scope) || (req.ScopeID != "" && req.ScopeID != secret.ScopeID) {
+		return nil, fmt.Errorf("the scope of a secret cannot change")
+	}
+
+	if req.Name != "" && req.Name != secret.Name {
+		if err := checkSecretName(req.Name, secret.Scope, secret.ScopeID, secret.ID); err != nil {
+			return nil, err
+		}
+		secret.Name = req.Name
+	}
+	if req.Description != "" {
+		secret.Description = req.Description
+	}
+	if req.Value != "" {
+		if err := InitCrypto(); err != nil {
+			return nil, fmt.Errorf("failed to initialize crypto: %w", err)
+		}
+		encValue, err := Encrypt(req.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt value: %w", err)
+		}
+		secret.Value = encValue
+	}
+	secret.UpdatedAt = time.Now()
+
+	if err := saveSecretVariable(secret); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("id", secret.ID).Str("name", secret.Name).Msg("Secret variable updated")
+	return secret, nil
+}
+
+// DeleteSecretVariable deletes a secret variable
+func DeleteSecretVariable(id string) error {
+	if _, err := GetSecretVariable(id); err != nil {
+		return err
+	}
+	if err := storage.GetBackend().Delete(storage.BucketSecretVariables, id); err != nil {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+
+	log.Info().Str("id", id).Msg("Secret variable deleted")
+	return nil
+}
+
+// deleteScopedSecrets deletes the secret variables of a deleted pipeline or
+// freestyle job
+func deleteScopedSecrets(scope, scopeID string) {
+	secrets, err := listSecretVariables(scope, scopeID)
+	if err != nil {
+		return
+	}
+	for _, s := range secrets {
+		storage.GetBackend().Delete(storage.BucketSecretVariables, s.ID)
+	}
+}
+
+func saveSecretVariable(secret *SecretVariable) error {
+	data, err := json.Marshal(secret)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secret: %w", err)
+	}
+	if err := storage.GetBackend().Set(storage.BucketSecretVariables, secret.ID, data); err != nil {
+		return fmt.Errorf("failed to save secret: %w", err)
+	}
+	return nil
+}
+
+// secretValues decrypts the secret variables of a pipeline or freestyle job
+// into a map of name to value
+func secretValues(scope, scopeID string) (map[string]string, error) {
+	secrets, err := listSecretVariables(scope, scopeID)
+	if err != nil || len(secrets) == 0 {
+		return nil, err
+	}
+	if err := InitCrypto(); err != nil {
+		return nil, fmt.Errorf("failed to initialize crypto: %w", err)
+	}
+	values := make(map[string]string, len(secrets))
+	for _, s := range secrets {
+		value, err := Decrypt(s.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret %s: %w", s.Name, err)
+		}
+		values[s.Name] = value
+	}
+	return values, nil
+}
+
+// secretExports renders the shell exports of secret variables
+func secretExports(secrets map[string]string) string {
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "export %s=%s\n", name, shellQuote(secrets[name]))
+	}
+	return b.String()
+}
+
+// addSecretVariables gives the runner container of job the secret variables
+// as environment variables read from a Secret. It returns that Secret.
+func addSecretVariables(job *batchv1.Job, secrets map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      job.Name + "-secrets",
+			Namespace: cicdNamespace,
+			Labels:    job.Labels,
+		},
+		Data: make(map[string][]byte, len(secrets)),
+	}
+	names := make([]string, 0, len(secrets))
+	for name, value := range secrets {
+		secret.Data[name] = []byte(value)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	runner := &job.Spec.Template.Spec.Containers[0]
+	for _, name := range names {
+		runner.Env = append(runner.Env, corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+				Key:                  name,
+			}},
+		})
+	}
+	return secret
+}
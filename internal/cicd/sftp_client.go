@@ -0,0 +1,606 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// A small SFTP (version 3) client for the file transfer steps, run on the
+// sftp subsystem of an SSH session. It covers what copying directory trees
+// needs: listing, reading and writing files, making directories and setting
+// permissions and times. Requests are matched to responses by ID, so reads
+// and writes of a file are kept in flight several at a time rather than
+// waiting a round trip for every chunk.
+
+// SFTP packet types
+const (
+	sftpInit     = 1
+	sftpVersion  = 2
+	sftpOpen     = 3
+	sftpClose    = 4
+	sftpRead     = 5
+	sftpWrite    = 6
+	sftpSetstat  = 9
+	sftpOpendir  = 11
+	sftpReaddir  = 12
+	sftpMkdir    = 14
+	sftpStat     = 17
+	sftpStatus   = 101
+	sftpHandle   = 102
+	sftpData     = 103
+	sftpName     = 104
+	sftpAttrsPkt = 105
+)
+
+// SFTP open flags, status codes and attribute flags
+const (
+	sftpFlagRead  = 0x01
+	sftpFlagWrite = 0x02
+	sftpFlagCreat = 0x08
+	sftpFlagTrunc = 0x10
+
+	sftpOK         = 0
+	sftpEOF        = 1
+	sftpNoSuchFile = 2
+
+	sftpAttrSize        = 0x01
+	sftpAttrUIDGID      = 0x02
+	sftpAttrPermissions = 0x04
+	sftpAttrACModTime   = 0x08
+	sftpAttrExtended    = 0x80000000
+)
+
+const (
+	sftpChunk    = 32 * 1024 // data per read or write request, which every server accepts
+	sftpInFlight = 16        // read or write requests of a file in flight at a time
+)
+
+// File type bits of SFTP permissions
+const (
+	sftpModeType = 0170000
+	sftpModeDir  = 0040000
+	sftpModeReg  = 0100000
+)
+
+// sftpStatusError is a failed SFTP request
+type sftpStatusError struct {
+	Code uint32
+	Msg  string
+}
+
+func (e *sftpStatusError) Error() string {
+	return fmt.Sprintf("sftp: %s (code %d)", e.Msg, e.Code)
+}
+
+// sftpStatusIs reports whether err is a status of code
+func sftpStatusIs(err error, code uint32) bool {
+	var se *sftpStatusError
+	return errors.As(err, &se) && se.Code == code
+}
+
+// sftpAttrs are the attributes of a remote file
+type sftpAttrs struct {
+	Size    int64
+	Mode    uint32 // permissions including the file type bits
+	ModTime time.Time
+}
+
+func (a sftpAttrs) isDir() bool     { return a.Mode&sftpModeType == sftpModeDir }
+func (a sftpAttrs) isRegular() bool { return a.Mode&sftpModeType == sftpModeReg }
+
+type sftpPacket struct {
+	typ  byte
+	data []byte // after the request ID
+}
+
+// sftpClient is an SFTP session
+type sftpClient struct {
+	session io.Closer
+	w       io.WriteCloser
+	wmu     sync.Mutex
+
+	mu      sync.Mutex
+	nextID  uint32
+	pending map[uint32]chan sftpPacket
+	err     error // why the session ended
+}
+
+// newSFTPClient starts the sftp subsystem on session
+func newSFTPClient(session *ssh.Session) (*sftpClient, error) {
+	w, err := session.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return nil, fmt.Errorf("failed to start sftp subsystem: %w", err)
+	}
+	return startSFTP(session, w, r)
+}
+
+// startSFTP starts an SFTP session on the streams of a server
+func startSFTP(session io.Closer, w io.WriteCloser, r io.Reader) (*sftpClient, error) {
+	c := &sftpClient{session: session, w: w, pending: make(map[uint32]chan sftpPacket)}
+	if err := c.send(sftpInit, binary.BigEndian.AppendUint32(nil, 3)); err != nil {
+		return nil, err
+	}
+	typ, _, err := readSFTPPacket(r)
+	if err != nil {
+		return nil, fmt.Errorf("sftp handshake: %w", err)
+	}
+	if typ != sftpVersion {
+		return nil, fmt.Errorf("sftp handshake: unexpected packet %d", typ)
+	}
+
+	go c.receive(r)
+	return c, nil
+}
+
+// Close ends the SFTP session
+func (c *sftpClient) Close() error {
+	c.w.Close()
+	return c.session.Close()
+}
+
+func readSFTPPacket(r io.Reader) (byte, []byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:4])
+	if n < 1 || n > 256*1024+1024 {
+		return 0, nil, fmt.Errorf("sftp: bad packet length %d", n)
+	}
+	data := make([]byte, n-1)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return hdr[4], data, nil
+}
+
+// send writes a packet of type typ with payload
+func (c *sftpClient) send(typ byte, payload []byte) error {
+	pkt := binary.BigEndian.AppendUint32(make([]byte, 0, 5+len(payload)), uint32(len(payload)+1))
+	pkt = append(append(pkt, typ), payload...)
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := c.w.Write(pkt)
+	return err
+}
+
+// receive hands the responses to their requests until the session ends
+func (c *sftpClient) receive(r io.Reader) {
+	for {
+		typ, data, err := readSFTPPacket(r)
+		if err == nil && len(data) < 4 {
+			err = fmt.Errorf("sftp: short packet")
+		}
+		if err != nil {
+			c.mu.Lock()
+			c.err = fmt.Errorf("sftp session ended: %w", err)
+			for id, ch := range c.pending {
+				close(ch)
+				delete(c.pending, id)
+			}
+			c.mu.Unlock()
+			return
+		}
+		id := binary.BigEndian.Uint32(data)
+		c.mu.Lock()
+		ch := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- sftpPacket{typ: typ, data: data[4:]}
+		}
+	}
+}
+
+// start sends a request, whose response comes on the returned channel
+func (c *sftpClient) start(typ byte, payload []byte) (<-chan sftpPacket, error) {
+	ch := make(chan sftpPacket, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	if err := c.send(typ, append(binary.BigEndian.AppendUint32(nil, id), payload...)); err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, err
+	}
+	return ch, nil
+}
+
+// wait waits for the response of a request
+func (c *sftpClient) wait(ch <-chan sftpPacket) (sftpPacket, error) {
+	pkt, ok := <-ch
+	if !ok {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return pkt, c.err
+	}
+	return pkt, nil
+}
+
+// request sends a request and waits for its response
+func (c *sftpClient) request(typ byte, payload []byte) (sftpPacket, error) {
+	ch, err := c.start(typ, payload)
+	if err != nil {
+		return sftpPacket{}, err
+	}
+	return c.wait(ch)
+}
+
+// status is the error of a status response, nil for OK
+func (pkt sftpPacket) status() error {
+	if pkt.typ != sftpStatus {
+		return fmt.Errorf("sftp: unexpected packet %d", pkt.typ)
+	}
+	d := sftpDecoder{data: pkt.data}
+	code := d.uint32()
+	msg := d.string()
+	if d.err != nil {
+		return d.err
+	}
+	if code == sftpOK {
+		return nil
+	}
+	return &sftpStatusError{Code: code, Msg: msg}
+}
+
+// expect checks that a response is of type typ, or the error it carries
+func (pkt sftpPacket) expect(typ byte) error {
+	if pkt.typ == typ {
+		return nil
+	}
+	if err := pkt.status(); err != nil {
+		return err
+	}
+	return fmt.Errorf("sftp: unexpected packet %d", pkt.typ)
+}
+
+func sftpString(b []byte, s string) []byte {
+	return append(binary.BigEndian.AppendUint32(b, uint32(len(s))), s...)
+}
+
+func sftpAttrsBytes(b []byte, mode os.FileMode, mtime time.Time) []byte {
+	flags := uint32(sftpAttrPermissions)
+	if !mtime.IsZero() {
+		flags |= sftpAttrACModTime
+	}
+	b = binary.BigEndian.AppendUint32(b, flags)
+	b = binary.BigEndian.AppendUint32(b, uint32(mode.Perm()))
+	if !mtime.IsZero() {
+		b = binary.BigEndian.AppendUint32(b, uint32(mtime.Unix()))
+		b = binary.BigEndian.AppendUint32(b, uint32(mtime.Unix()))
+	}
+	return b
+}
+
+// sftpDecoder reads the fields of a response
+type sftpDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *sftpDecoder) take(n int) []byte {
+	if d.err != nil || len(d.data) < n {
+		d.err = fmt.Errorf("sftp: short packet")
+		return make([]byte, n)
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *sftpDecoder) uint32() uint32 { return binary.BigEndian.Uint32(d.take(4)) }
+func (d *sftpDecoder) uint64() uint64 { return binary.BigEndian.Uint64(d.take(8)) }
+
+func (d *sftpDecoder) string() string {
+	n := d.uint32()
+	if d.err != nil || uint32(len(d.data)) < n {
+		d.err = fmt.Errorf("sftp: short packet")
+		return ""
+	}
+	return string(d.take(int(n)))
+}
+
+func (d *sftpDecoder) attrs() sftpAttrs {
+	var a sftpAttrs
+	flags := d.uint32()
+	if flags&sftpAttrSize != 0 {
+		a.Size = int64(d.uint64())
+	}
+	if flags&sftpAttrUIDGID != 0 {
+		d.uint32()
+		d.uint32()
+	}
+	if flags&sftpAttrPermissions != 0 {
+		a.Mode = d.uint32()
+	}
+	if flags&sftpAttrACModTime != 0 {
+		d.uint32()
+		a.ModTime = time.Unix(int64(d.uint32()), 0)
+	}
+	if flags&sftpAttrExtended != 0 {
+		for n := d.uint32(); n > 0 && d.err == nil; n-- {
+			d.string()
+			d.string()
+		}
+	}
+	return a
+}
+
+// Stat returns the attributes of a file, following symbolic links
+func (c *sftpClient) Stat(p string) (sftpAttrs, error) {
+	pkt, err := c.request(sftpStat, sftpString(nil, p))
+	if err != nil {
+		return sftpAttrs{}, err
+	}
+	if err := pkt.expect(sftpAttrsPkt); err != nil {
+		return sftpAttrs{}, err
+	}
+	d := sftpDecoder{data: pkt.data}
+	a := d.attrs()
+	return a, d.err
+}
+
+// Mkdir makes a directory
+func (c *sftpClient) Mkdir(p string, mode os.FileMode) error {
+	pkt, err := c.request(sftpMkdir, sftpAttrsBytes(sftpString(nil, p), mode, time.Time{}))
+	if err != nil {
+		return err
+	}
+	return pkt.status()
+}
+
+// MkdirAll makes a directory and the parents it is missing
+func (c *sftpClient) MkdirAll(p string, mode os.FileMode) error {
+	a, err := c.Stat(p)
+	if err == nil {
+		if !a.isDir() {
+			return fmt.Errorf("%s is not a directory", p)
+		}
+		return nil
+	}
+	if !sftpStatusIs(err, sftpNoSuchFile) {
+		return err
+	}
+	if parent := path.Dir(p); parent != p && parent != "." {
+		if err := c.MkdirAll(parent, 0755); err != nil {
+			return err
+		}
+	}
+	if err := c.Mkdir(p, mode); err != nil {
+		// Made in the meantime
+		if a, statErr := c.Stat(p); statErr == nil && a.isDir() {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// Setstat sets the permissions of a file, and its modification time unless
+// mtime is zero
+func (c *sftpClient) Setstat(p string, mode os.FileMode, mtime time.Time) error {
+	pkt, err := c.request(sftpSetstat, sftpAttrsBytes(sftpString(nil, p), mode, mtime))
+	if err != nil {
+		return err
+	}
+	return pkt.status()
+}
+
+// sftpEntry is an entry of a remote directory
+type sftpEntry struct {
+	Name  string
+	Attrs sftpAttrs
+}
+
+// ReadDir lists a directory, without . and ..
+func (c *sftpClient) ReadDir(p string) ([]sftpEntry, error) {
+	handle, err := c.open(sftpOpendir, sftpString(nil, p))
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeHandle(handle)
+
+	var entries []sftpEntry
+	for {
+		pkt, err := c.request(sftpReaddir, sftpString(nil, handle))
+		if err != nil {
+			return nil, err
+		}
+		if pkt.typ == sftpStatus {
+			if err := pkt.status(); !sftpStatusIs(err, sftpEOF) {
+				return nil, err
+			}
+			return entries, nil
+		}
+		if err := pkt.expect(sftpName); err != nil {
+			return nil, err
+		}
+		d := sftpDecoder{data: pkt.data}
+		for n := d.uint32(); n > 0 && d.err == nil; n-- {
+			name := d.string()
+			d.string() // long name
+			attrs := d.attrs()
+			if name != "." && name != ".." {
+				entries = append(entries, sftpEntry{Name: name, Attrs: attrs})
+			}
+		}
+		if d.err != nil {
+			return nil, d.err
+		}
+	}
+}
+
+// open opens a file or directory and returns its handle
+func (c *sftpClient) open(typ byte, payload []byte) (string, error) {
+	pkt, err := c.request(typ, payload)
+	if err != nil {
+		return "", err
+	}
+	if err := pkt.expect(sftpHandle); err != nil {
+		return "", err
+	}
+	d := sftpDecoder{data: pkt.data}
+	handle := d.string()
+	return handle, d.err
+}
+
+func (c *sftpClient) closeHandle(handle string) error {
+	pkt, err := c.request(sftpClose, sftpString(nil, handle))
+	if err != nil {
+		return err
+	}
+	return pkt.status()
+}
+
+// ReadFile copies a remote file to w
+func (c *sftpClient) ReadFile(p string, w io.Writer) error {
+	payload := binary.BigEndian.AppendUint32(sftpString(nil, p), sftpFlagRead)
+	handle, err := c.open(sftpOpen, append(payload, 0, 0, 0, 0))
+	if err != nil {
+		return err
+	}
+	defer c.closeHandle(handle)
+
+	type read struct {
+		offset uint64
+		ch     <-chan sftpPacket
+	}
+	readAt := func(offset uint64, n uint32) (<-chan sftpPacket, error) {
+		payload := binary.BigEndian.AppendUint64(sftpString(nil, handle), offset)
+		return c.start(sftpRead, binary.BigEndian.AppendUint32(payload, n))
+	}
+	// data is the data of a read response, empty at the end of the file
+	data := func(pkt sftpPacket) ([]byte, error) {
+		if pkt.typ == sftpStatus {
+			if err := pkt.status(); !sftpStatusIs(err, sftpEOF) {
+				return nil, err
+			}
+			return nil, nil
+		}
+		if err := pkt.expect(sftpData); err != nil {
+			return nil, err
+		}
+		d := sftpDecoder{data: pkt.data}
+		b := []byte(d.string())
+		return b, d.err
+	}
+
+	// Responses are taken in order; a short read is completed before going on
+	var queue []read
+	var next uint64
+	for {
+		for len(queue) < sftpInFlight {
+			ch, err := readAt(next, sftpChunk)
+			if err != nil {
+				return err
+			}
+			queue = append(queue, read{offset: next, ch: ch})
+			next += sftpChunk
+		}
+		r := queue[0]
+		queue = queue[1:]
+		for got := uint64(0); got < sftpChunk; {
+			pkt, err := c.wait(r.ch)
+			if err != nil {
+				return err
+			}
+			b, err := data(pkt)
+			if err != nil {
+				return err
+			}
+			if len(b) == 0 {
+				return nil
+			}
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+			got += uint64(len(b))
+			if got < sftpChunk {
+				if r.ch, err = readAt(r.offset+got, uint32(sftpChunk-got)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// WriteFile creates or truncates a remote file with the given permissions
+// and copies r into it
+func (c *sftpClient) WriteFile(p string, r io.Reader, mode os.FileMode) error {
+	payload := binary.BigEndian.AppendUint32(sftpString(nil, p), sftpFlagWrite|sftpFlagCreat|sftpFlagTrunc)
+	handle, err := c.open(sftpOpen, sftpAttrsBytes(payload, mode, time.Time{}))
+	if err != nil {
+		return err
+	}
+
+	var inFlight []<-chan sftpPacket
+	waitOne := func() error {
+		pkt, err := c.wait(inFlight[0])
+		inFlight = inFlight[1:]
+		if err != nil {
+			return err
+		}
+		return pkt.status()
+	}
+
+	buf := make([]byte, sftpChunk)
+	var offset uint64
+	var writeErr error
+	for writeErr == nil {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			if len(inFlight) == sftpInFlight {
+				if writeErr = waitOne(); writeErr != nil {
+					break
+				}
+			}
+			payload := binary.BigEndian.AppendUint64(sftpString(nil, handle), offset)
+			ch, err := c.start(sftpWrite, sftpString(payload, string(buf[:n])))
+			if err != nil {
+				writeErr = err
+				break
+			}
+			inFlight = append(inFlight, ch)
+			offset += uint64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		writeErr = readErr
+	}
+	for len(inFlight) > 0 {
+		if err := waitOne(); err != nil && writeErr == nil {
+			writeErr = err
+		}
+	}
+	if err := c.closeHandle(handle); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	return writeErr
+}
@@ -0,0 +1,547 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSFTPServer is an SFTP version 3 server on the local file system,
+// enough for sftpClient. It answers every request from its own goroutine so
+// responses come back out of order, and can be made to send short reads,
+// split directory listings and refuse paths.
+type testSFTPServer struct {
+	maxRead  int             // most data per READ response, 0 for no limit
+	perBatch int             // most entries per READDIR response, 0 for no limit
+	denied   map[string]bool // paths answered with permission denied
+	failFrom int64           // writes from this offset on fail, 0 for never
+
+	r  io.Reader
+	w  io.WriteCloser
+	wm sync.Mutex
+
+	mu      sync.Mutex
+	handles map[string]*testSFTPHandle
+	next    int
+	wg      sync.WaitGroup
+}
+
+type testSFTPHandle struct {
+	file    *os.File
+	entries []os.DirEntry // what is left to list of a directory
+}
+
+const (
+	testSFTPPermissionDenied = 3
+	testSFTPFailure          = 4
+)
+
+// startTestSFTP connects an sftpClient to a new server
+func startTestSFTP(t *testing.T, srv *testSFTPServer) *sftpClient {
+	t.Helper()
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	srv.r, srv.w = sr, sw
+	srv.handles = make(map[string]*testSFTPHandle)
+	go srv.serve()
+
+	c, err := startSFTP(io.NopCloser(nil), cw, cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cw.Close()
+		sw.Close()
+	})
+	return c
+}
+
+func (s *testSFTPServer) serve() {
+	defer s.w.Close()
+	typ, _, err := readSFTPPacket(s.r)
+	if err != nil || typ != sftpInit {
+		return
+	}
+	s.send(sftpVersion, binary.BigEndian.AppendUint32(nil, 3))
+	for {
+		typ, data, err := readSFTPPacket(s.r)
+		if err != nil {
+			s.wg.Wait()
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			time.Sleep(time.Duration(rand.Intn(200)) * time.Microsecond)
+			s.handle(typ, data)
+		}()
+	}
+}
+
+func (s *testSFTPServer) send(typ byte, payload []byte) {
+	pkt := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	pkt = append(append(pkt, typ), payload...)
+	s.wm.Lock()
+	defer s.wm.Unlock()
+	s.w.Write(pkt)
+}
+
+func (s *testSFTPServer) status(id []byte, code uint32, msg string) {
+	b := binary.BigEndian.AppendUint32(append([]byte{}, id...), code)
+	b = sftpString(sftpString(b, msg), "")
+	s.send(sftpStatus, b)
+}
+
+func (s *testSFTPServer) fail(id []byte, err error) {
+	switch {
+	case os.IsNotExist(err):
+		s.status(id, sftpNoSuchFile, err.Error())
+	case os.IsPermission(err):
+		s.status(id, testSFTPPermissionDenied, err.Error())
+	default:
+		s.status(id, testSFTPFailure, err.Error())
+	}
+}
+
+func testSFTPAttrs(b []byte, fi os.FileInfo) []byte {
+	mode := uint32(fi.Mode().Perm())
+	switch {
+	case fi.IsDir():
+		mode |= sftpModeDir
+	case fi.Mode()&os.ModeSymlink != 0:
+		mode |= 0120000
+	default:
+		mode |= sftpModeReg
+	}
+	b = binary.BigEndian.AppendUint32(b, sftpAttrSize|sftpAttrPermissions|sftpAttrACModTime)
+	b = binary.BigEndian.AppendUint64(b, uint64(fi.Size()))
+	b = binary.BigEndian.AppendUint32(b, mode)
+	b = binary.BigEndian.AppendUint32(b, uint32(fi.ModTime().Unix()))
+	return binary.BigEndian.AppendUint32(b, uint32(fi.ModTime().Unix()))
+}
+
+func (s *testSFTPServer) newHandle(h *testSFTPHandle) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	name := string(rune('a'+s.next%26)) + strings.Repeat("x", s.next/26)
+	s.handles[name] = h
+	return name
+}
+
+func (s *testSFTPServer) lookup(name string) *testSFTPHandle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handles[name]
+}
+
+func (s *testSFTPServer) handle(typ byte, data []byte) {
+	id, d := data[:4], sftpDecoder{data: data[4:]}
+	switch typ {
+	case sftpStat:
+		p := d.string()
+		if s.denied[p] {
+			s.status(id, testSFTPPermissionDenied, "denied")
+			return
+		}
+		fi, err := os.Stat(p)
+		if err != nil {
+			s.fail(id, err)
+			return
+		}
+		s.send(sftpAttrsPkt, testSFTPAttrs(append([]byte{}, id...), fi))
+
+	case sftpMkdir:
+		p := d.string()
+		a := d.attrs()
+		if err := os.Mkdir(p, os.FileMode(a.Mode).Perm()); err != nil {
+			s.fail(id, err)
+			return
+		}
+		s.status(id, sftpOK, "")
+
+	case sftpSetstat:
+		p := d.string()
+		a := d.attrs()
+		if err := os.Chmod(p, os.FileMode(a.Mode).Perm()); err != nil {
+			s.fail(id, err)
+			return
+		}
+		if !a.ModTime.IsZero() {
+			if err := os.Chtimes(p, a.ModTime, a.ModTime); err != nil {
+				s.fail(id, err)
+				return
+			}
+		}
+		s.status(id, sftpOK, "")
+
+	case sftpOpen:
+		p := d.string()
+		flags := d.uint32()
+		a := d.attrs()
+		if s.denied[p] {
+			s.status(id, testSFTPPermissionDenied, "denied")
+			return
+		}
+		oflags := os.O_RDONLY
+		if flags&sftpFlagWrite != 0 {
+			oflags = os.O_WRONLY
+		}
+		if flags&sftpFlagCreat != 0 {
+			oflags |= os.O_CREATE
+		}
+		if flags&sftpFlagTrunc != 0 {
+			oflags |= os.O_TRUNC
+		}
+		f, err := os.OpenFile(p, oflags, os.FileMode(a.Mode).Perm())
+		if err != nil {
+			s.fail(id, err)
+			return
+		}
+		s.send(sftpHandle, sftpString(append([]byte{}, id...), s.newHandle(&testSFTPHandle{file: f})))
+
+	case sftpOpendir:
+		p := d.string()
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			s.fail(id, err)
+			return
+		}
+		s.send(sftpHandle, sftpString(append([]byte{}, id...), s.newHandle(&testSFTPHandle{entries: entries})))
+
+	case sftpReaddir:
+		h := s.lookup(d.string())
+		if h == nil {
+			// Requests still in flight when the client closed the handle
+			s.status(id, testSFTPFailure, "invalid handle")
+			return
+		}
+		s.mu.Lock()
+		batch := h.entries
+		if s.perBatch > 0 && len(batch) > s.perBatch {
+			batch = batch[:s.perBatch]
+		}
+		h.entries = h.entries[len(batch):]
+		s.mu.Unlock()
+		if len(batch) == 0 {
+			s.status(id, sftpEOF, "EOF")
+			return
+		}
+		b := binary.BigEndian.AppendUint32(append([]byte{}, id...), uint32(len(batch)))
+		for _, e := range batch {
+			fi, err := e.Info()
+			if err != nil {
+				s.fail(id, err)
+				return
+			}
+			b = sftpString(sftpString(b, e.Name()), "-rw-r--r-- "+e.Name())
+			b = testSFTPAttrs(b, fi)
+		}
+		s.send(sftpName, b)
+
+	case sftpRead:
+		h := s.lookup(d.string())
+		if h == nil {
+			// Requests still in flight when the client closed the handle
+			s.status(id, testSFTPFailure, "invalid handle")
+			return
+		}
+		offset := d.uint64()
+		n := int(d.uint32())
+		if s.maxRead > 0 && n > s.maxRead {
+			n = s.maxRead
+		}
+		buf := make([]byte, n)
+		got, err := h.file.ReadAt(buf, int64(offset))
+		if got == 0 && err == io.EOF {
+			s.status(id, sftpEOF, "EOF")
+			return
+		}
+		if got == 0 && err != nil {
+			s.fail(id, err)
+			return
+		}
+		s.send(sftpData, sftpString(append([]byte{}, id...), string(buf[:got])))
+
+	case sftpWrite:
+		h := s.lookup(d.string())
+		if h == nil {
+			// Requests still in flight when the client closed the handle
+			s.status(id, testSFTPFailure, "invalid handle")
+			return
+		}
+		offset := d.uint64()
+		b := d.string()
+		if s.failFrom > 0 && int64(offset) >= s.failFrom {
+			s.status(id, testSFTPFailure, "no space left on device")
+			return
+		}
+		if _, err := h.file.WriteAt([]byte(b), int64(offset)); err != nil {
+			s.fail(id, err)
+			return
+		}
+		s.status(id, sftpOK, "")
+
+	case sftpClose:
+		name := d.string()
+		s.mu.Lock()
+		h := s.handles[name]
+		delete(s.handles, name)
+		s.mu.Unlock()
+		if h != nil && h.file != nil {
+			h.file.Close()
+		}
+		s.status(id, sftpOK, "")
+
+	default:
+		s.status(id, 8, "unsupported")
+	}
+}
+
+func testRandomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(b)
+	return b
+}
+
+func TestSFTPWriteAndReadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		maxRead int
+	}{
+		{"empty", 0, 0},
+		{"one byte", 1, 0},
+		{"exact chunk", sftpChunk, 0},
+		{"chunk and a byte", sftpChunk + 1, 0},
+		{"large", 3*sftpChunk*sftpInFlight + 12345, 0},
+		{"short reads", 5*sftpChunk + 7, 1000},
+		{"short reads across chunks", 2*sftpChunk*sftpInFlight + 3, sftpChunk - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := startTestSFTP(t, &testSFTPServer{maxRead: tt.maxRead})
+			content := testRandomBytes(tt.size)
+			p := filepath.Join(dir, "file.bin")
+
+			// Short reads of the source must not shorten the file
+			if err := c.WriteFile(p, io.MultiReader(bytes.NewReader(content[:len(content)/2]), bytes.NewReader(content[len(content)/2:])), 0640); err != nil {
+				t.Fatal(err)
+			}
+			onDisk, err := os.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(onDisk, content) {
+				t.Fatalf("written %d bytes, want %d", len(onDisk), len(content))
+			}
+			if fi, _ := os.Stat(p); fi.Mode().Perm() != 0640 {
+				t.Errorf("mode = %v, want 0640", fi.Mode().Perm())
+			}
+
+			var buf bytes.Buffer
+			if err := c.ReadFile(p, &buf); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), content) {
+				t.Fatalf("read %d bytes, want %d", buf.Len(), len(content))
+			}
+
+			a, err := c.Stat(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Size != int64(tt.size) || !a.isRegular() {
+				t.Errorf("stat = %+v", a)
+			}
+		})
+	}
+}
+
+func TestSFTPWriteFileTruncates(t *testing.T) {
+	dir := t.TempDir()
+	c := startTestSFTP(t, &testSFTPServer{})
+	p := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(p, []byte("a much longer previous content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WriteFile(p, strings.NewReader("short"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(p); string(b) != "short" {
+		t.Errorf("content = %q", b)
+	}
+}
+
+func TestSFTPStatusErrors(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	os.WriteFile(secret, []byte("x"), 0600)
+	c := startTestSFTP(t, &testSFTPServer{denied: map[string]bool{secret: true}})
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name string
+		err  error
+		code uint32
+	}{
+		{"stat missing", func() error { _, err := c.Stat(missing); return err }(), sftpNoSuchFile},
+		{"read missing", c.ReadFile(missing, io.Discard), sftpNoSuchFile},
+		{"read denied", c.ReadFile(secret, io.Discard), testSFTPPermissionDenied},
+		{"write into missing dir", c.WriteFile(filepath.Join(missing, "f"), strings.NewReader("x"), 0644), sftpNoSuchFile},
+		{"list missing", func() error { _, err := c.ReadDir(missing); return err }(), sftpNoSuchFile},
+		{"setstat missing", c.Setstat(missing, 0644, time.Now()), sftpNoSuchFile},
+		{"mkdir existing", c.Mkdir(dir, 0755), testSFTPFailure},
+	}
+	for _, tt := range tests {
+		if !sftpStatusIs(tt.err, tt.code) {
+			t.Errorf("%s: error %v, want status %d", tt.name, tt.err, tt.code)
+		}
+	}
+}
+
+func TestSFTPWriteFails(t *testing.T) {
+	dir := t.TempDir()
+	srv := &testSFTPServer{failFrom: 3 * sftpChunk}
+	c := startTestSFTP(t, srv)
+
+	err := c.WriteFile(filepath.Join(dir, "big"), bytes.NewReader(testRandomBytes(10*sftpChunk)), 0644)
+	if !sftpStatusIs(err, testSFTPFailure) || !strings.Contains(err.Error(), "no space left") {
+		t.Fatalf("error = %v", err)
+	}
+	// The handle is closed once the writes in flight are answered
+	srv.mu.Lock()
+	open := len(srv.handles)
+	srv.mu.Unlock()
+	if open != 0 {
+		t.Errorf("%d handles left open", open)
+	}
+	// and the session is still usable
+	if err := c.WriteFile(filepath.Join(dir, "small"), strings.NewReader("ok"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSFTPMkdirAll(t *testing.T) {
+	dir := t.TempDir()
+	c := startTestSFTP(t, &testSFTPServer{})
+
+	deep := filepath.Join(dir, "a", "b", "c", "d")
+	if err := c.MkdirAll(deep, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(deep); err != nil || !fi.IsDir() || fi.Mode().Perm() != 0750 {
+		t.Fatalf("stat %s: %v %v", deep, fi, err)
+	}
+	// Existing directories are fine
+	if err := c.MkdirAll(deep, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "file")
+	os.WriteFile(file, nil, 0644)
+	if err := c.MkdirAll(file, 0755); err == nil || !strings.Contains(err.Error(), "not a directory") {
+		t.Errorf("MkdirAll on a file: %v", err)
+	}
+	if err := c.MkdirAll(filepath.Join(file, "sub"), 0755); err == nil {
+		t.Error("MkdirAll under a file succeeded")
+	}
+}
+
+func TestSFTPSetstat(t *testing.T) {
+	dir := t.TempDir()
+	c := startTestSFTP(t, &testSFTPServer{})
+	p := filepath.Join(dir, "f")
+	os.WriteFile(p, nil, 0644)
+
+	mtime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := c.Setstat(p, 0600, mtime); err != nil {
+		t.Fatal(err)
+	}
+	fi, _ := os.Stat(p)
+	if fi.Mode().Perm() != 0600 || !fi.ModTime().Equal(mtime) {
+		t.Errorf("mode %v mtime %v", fi.Mode().Perm(), fi.ModTime())
+	}
+}
+
+func TestSFTPWalk(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range []string{"a.txt", "sub/b.txt", "sub/deeper/c.txt", "sub/deeper/d.txt", "e.txt"} {
+		full := filepath.Join(dir, p)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(p), 0644)
+	}
+	os.Symlink(filepath.Join(dir, "a.txt"), filepath.Join(dir, "link"))
+	c := startTestSFTP(t, &testSFTPServer{perBatch: 2})
+
+	files, err := c.Walk(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		if f.Dir {
+			got = append(got, f.Path+"/")
+		} else {
+			got = append(got, f.Path)
+		}
+	}
+	sort.Strings(got)
+	want := "a.txt e.txt sub/ sub/b.txt sub/deeper/ sub/deeper/c.txt sub/deeper/d.txt"
+	if strings.Join(got, " ") != want {
+		t.Errorf("walk = %v, want %s", got, want)
+	}
+
+	if _, err := c.Walk(filepath.Join(dir, "a.txt")); err == nil {
+		t.Error("walking a file succeeded")
+	}
+}
+
+func TestSFTPSessionEnds(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	go func() {
+		// Answer the handshake, then hang up on the first request
+		readSFTPPacket(sr)
+		pkt := binary.BigEndian.AppendUint32(nil, 5)
+		sw.Write(binary.BigEndian.AppendUint32(append(pkt, sftpVersion), 3))
+		readSFTPPacket(sr)
+		sw.Close()
+		io.Copy(io.Discard, sr)
+	}()
+	c, err := startSFTP(io.NopCloser(nil), cw, cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cw.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Stat("/anything")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "sftp session ended") {
+			t.Errorf("error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request hangs after the session ended")
+	}
+	// Later requests fail at once
+	if _, err := c.Stat("/anything"); err == nil {
+		t.Errorf("request after the end: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// A job with a source gets an init container that clones the repository
+// into an emptyDir mounted at the job's workdir, so the script starts in a
+// checkout. Credentials never appear in the pod spec: they go in a Secret
+// created next to the K8s Job and owned by it, and the clone's remote is
+// reset to the plain URL so the script cannot read them from .git/config.
+
+// sourceContainerName is the init container that performs the checkout
+const sourceContainerName = "checkout"
+
+// sourceMountPath is where the init container sees the credential Secret
+const sourceMountPath = "/gagos-source"
+
+// sourceImage is the image of the checkout container (GAGOS_GIT_IMAGE)
+func sourceImage() string {
+	if image := os.Getenv("GAGOS_GIT_IMAGE"); image != "" {
+		return image
+	}
+	return "alpine/git:2.43.0"
+}
+
+// validateSource checks a job's source section
+func validateSource(src *SourceSpec) error {
+	if src.Repo == "" {
+		return fmt.Errorf("source.repo is required")
+	}
+	if src.Depth < 0 {
+		return fmt.Errorf("source.depth must not be negative")
+	}
+	if src.Path != "" {
+		clean := path.Clean(src.Path)
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("source.path must be a directory inside the workspace")
+		}
+	}
+	return nil
+}
+
+// addSourceCheckout adds the checkout init container and workspace volume
+// to job. It returns the Secret holding the clone credentials, nil when the
+// source has no credential.
+func addSourceCheckout(job *batchv1.Job, run *PipelineRun, jobSpec *JobSpec) (*corev1.Secret, error) {
+	src := jobSpec.Source
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string { return run.Variables[name] })
+	}
+	repo := expand(src.Repo)
+	branch := expand(src.Branch)
+
+	podSpec := &job.Spec.Template.Spec
+	runner := &podSpec.Containers[0]
+	workdir := runner.WorkingDir
+	cloneDir := workdir
+	if src.Path != "" {
+		cloneDir = path.Join(workdir, src.Path)
+	}
+
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name:         "workspace",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+	workspaceMount := corev1.VolumeMount{Name: "workspace", MountPath: workdir}
+	runner.VolumeMounts = append(runner.VolumeMounts, workspaceMount)
+
+	container := corev1.Container{
+		Name:         sourceContainerName,
+		Image:        sourceImage(),
+		Command:      []string{"/bin/sh", "-c"},
+		VolumeMounts: []corev1.VolumeMount{workspaceMount},
+		Env: []corev1.EnvVar{
+			{Name: "GAGOS_SOURCE_REPO", Value: repo},
+			{Name: "GAGOS_SOURCE_URL", Value: repo},
+			{Name: "GAGOS_SOURCE_BRANCH", Value: branch},
+			{Name: "GAGOS_SOURCE_DIR", Value: cloneDir},
+			{Name: "GIT_TERMINAL_PROMPT", Value: "0"},
+		},
+	}
+
+	var secret *corev1.Secret
+	if src.CredentialID != "" {
+		cred, err := GetDecryptedGitCredential(src.CredentialID)
+		if err != nil {
+			return nil, fmt.Errorf("source credential: %w", err)
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      job.Name + "-source",
+				Namespace: cicdNamespace,
+				Labels:    job.Labels,
+			},
+			Data: map[string][]byte{},
+		}
+
+		switch cred.AuthMethod {
+		case GitAuthToken, GitAuthPassword:
+			url := injectTokenIntoURL(repo, cred.Token)
+			if cred.AuthMethod == GitAuthPassword {
+				url = injectCredentialsIntoURL(repo, cred.Username, cred.Password)
+			}
+			secret.Data["url"] = []byte(url)
+			container.Env[1] = corev1.EnvVar{
+				Name: "GAGOS_SOURCE_URL",
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+					Key:                  "url",
+				}},
+			}
+
+		case GitAuthSSHKey:
+			if cred.Passphrase != "" {
+				return nil, fmt.Errorf("source credential %s: SSH keys with a passphrase are not supported", cred.Name)
+			}
+			secret.Data["ssh-privatekey"] = []byte(cred.PrivateKey)
+			mode := int32(0400)
+			podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+				Name: "source-credentials",
+				VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+					SecretName:  secret.Name,
+					DefaultMode: &mode,
+				}},
+			})
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      "source-credentials",
+				MountPath: sourceMountPath,
+				ReadOnly:  true,
+			})
+			container.Env = append(container.Env, corev1.EnvVar{
+				Name:  "GIT_SSH_COMMAND",
+				Value: "ssh -i " + sourceMountPath + "/ssh-privatekey -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null",
+			})
+
+		default:
+			return nil, fmt.Errorf("source credential %s: unsupported auth method %s", cred.Name, cred.AuthMethod)
+		}
+	}
+
+	container.Args = []string{sourceScript(src)}
+	podSpec.InitContainers = append(podSpec.InitContainers, container)
+	return secret, nil
+}
+
+// sourceScript clones GAGOS_SOURCE_URL; values that come from the pipeline
+// or its variables are passed in the environment, never in the script
+func sourceScript(src *SourceSpec) string {
+	opts := ""
+	if src.Depth > 0 {
+		opts += fmt.Sprintf(" --depth %d", src.Depth)
+	}
+	if src.Submodules {
+		opts += " --recurse-submodules"
+	}
+	return `set -e
+if [ -n "$GAGOS_SOURCE_BRANCH" ]; then set -- --branch "$GAGOS_SOURCE_BRANCH"; fi
+echo "Cloning $GAGOS_SOURCE_REPO into $GAGOS_SOURCE_DIR"
+git clone` + opts + ` "$@" "$GAGOS_SOURCE_URL" "$GAGOS_SOURCE_DIR"
+git -C "$GAGOS_SOURCE_DIR" remote set-url origin "$GAGOS_SOURCE_REPO"
+echo "Checked out $(git -C "$GAGOS_SOURCE_DIR" rev-parse HEAD)"
+`
+}
+
+// ownSourceSecret makes job the owner of its credential Secret, so that the
+// Secret is garbage collected with the job
+func ownSourceSecret(ctx context.Context, clientset kubernetes.Interface, secret *corev1.Secret, job *batchv1.Job) error {
+	secret.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: "batch/v1",
+		Kind:       "Job",
+		Name:       job.Name,
+		UID:        job.UID,
+	}}
+	_, err := clientset.CoreV1().Secrets(cicdNamespace).Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
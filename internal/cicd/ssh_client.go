@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,12 +17,63 @@ import (
 
 // SSHSession wraps an SSH client with helper methods
 type SSHSession struct {
-	client *ssh.Client
-	host   *SSHHost
+	client  *ssh.Client
+	host    *SSHHost
+	backend SSHBackend       // replaces client when set
+	pooled  *pooledSSHClient // pool entry of client, if pooled
 }
 
-// NewSSHSession creates a new SSH session to the host
+// SSHBackend runs commands in place of SSH connections, for tests and the
+// fake mode. stdin is nil when the command reads no input.
+type SSHBackend interface {
+	Run(ctx context.Context, host *SSHHost, cmd string, stdin []byte, stdout, stderr io.Writer) (exitCode int, err error)
+}
+
+var sshBackend SSHBackend
+
+// SetSSHBackend makes new sessions use backend instead of connecting; nil
+// restores SSH
+func SetSSHBackend(backend SSHBackend) {
+	sshBackend = backend
+}
+
+// NewSSHSession creates a new SSH session to the host, on a pooled
+// connection
 func NewSSHSession(host *SSHHost) (*SSHSession, error) {
+	config, err := sshClientConfig(host)
+	if err != nil {
+		return nil, err
+	}
+	if sshBackend != nil {
+		return &SSHSession{host: host, backend: sshBackend}, nil
+	}
+
+	c, err := sshClients.acquire(host, config)
+	if err != nil {
+		return nil, err
+	}
+	return &SSHSession{client: c.client, host: host, pooled: c}, nil
+}
+
+// newUnpooledSSHSession creates an SSH session on a connection of its own
+func newUnpooledSSHSession(host *SSHHost) (*SSHSession, error) {
+	config, err := sshClientConfig(host)
+	if err != nil {
+		return nil, err
+	}
+	if sshBackend != nil {
+		return &SSHSession{host: host, backend: sshBackend}, nil
+	}
+
+	client, err := dialSSH(host, config)
+	if err != nil {
+		return nil, err
+	}
+	return &SSHSession{client: client, host: host}, nil
+}
+
+// sshClientConfig is how to authenticate to a host and verify its key
+func sshClientConfig(host *SSHHost) (*ssh.ClientConfig, error) {
 	var authMethods []ssh.AuthMethod
 
 	switch host.AuthMethod {
@@ -70,38 +122,84 @@ func NewSSHSession(host *SSHHost) (*SSHSession, error) {
 		hostKeyCallback = ssh.InsecureIgnoreHostKey()
 	}
 
-	config := &ssh.ClientConfig{
+	return &ssh.ClientConfig{
 		User:            host.Username,
 		Auth:            authMethods,
 		HostKeyCallback: hostKeyCallback,
-		Timeout:         30 * time.Second,
-	}
+		Timeout:         sshConnectTimeout(),
+	}, nil
+}
 
+// dialSSH connects to a host, giving up on the SSH handshake after the
+// handshake timeout
+func dialSSH(host *SSHHost, config *ssh.ClientConfig) (*ssh.Client, error) {
 	port := host.Port
 	if port == 0 {
 		port = 22
 	}
 
-	addr := fmt.Sprintf("%s:%d", host.Host, port)
-	client, err := ssh.Dial("tcp", addr, config)
+	addr := net.JoinHostPort(host.Host, strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", addr, config.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(sshHandshakeTimeout()))
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
 	if err != nil {
+		conn.Close()
 		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
 	}
+	conn.SetDeadline(time.Time{})
 
-	return &SSHSession{client: client, host: host}, nil
+	return ssh.NewClient(c, chans, reqs), nil
 }
 
-// Close closes the SSH connection
+// Close closes the SSH session, handing a pooled connection back to the pool
 func (s *SSHSession) Close() error {
+	if s.pooled != nil {
+		sshClients.release(s.pooled)
+		s.pooled, s.client = nil, nil
+		return nil
+	}
 	if s.client != nil {
 		return s.client.Close()
 	}
 	return nil
 }
 
+// newSession opens a session on the connection. A pooled connection that
+// turns out to be broken is replaced by a new one, once.
+func (s *SSHSession) newSession() (*ssh.Session, error) {
+	session, err := s.client.NewSession()
+	if err == nil || s.pooled == nil {
+		return session, err
+	}
+
+	sshClients.retire(s.pooled)
+	sshClients.release(s.pooled)
+	config, cfgErr := sshClientConfig(s.host)
+	if cfgErr != nil {
+		s.pooled, s.client = nil, nil
+		return nil, err
+	}
+	c, dialErr := sshClients.acquire(s.host, config)
+	if dialErr != nil {
+		s.pooled, s.client = nil, nil
+		return nil, fmt.Errorf("%w; reconnecting: %v", err, dialErr)
+	}
+	s.pooled, s.client = c, c.client
+	return s.client.NewSession()
+}
+
 // ExecuteCommand runs a command and returns output
 func (s *SSHSession) ExecuteCommand(ctx context.Context, cmd string, timeout time.Duration) (stdout, stderr string, exitCode int, err error) {
-	session, err := s.client.NewSession()
+	if s.backend != nil {
+		var stdoutBuf, stderrBuf bytes.Buffer
+		exitCode, err = s.runBackend(ctx, cmd, nil, &stdoutBuf, &stderrBuf, timeout)
+		return stdoutBuf.String(), stderrBuf.String(), exitCode, err
+	}
+
+	session, err := s.newSession()
 	if err != nil {
 		return "", "", -1, fmt.Errorf("failed to create session: %w", err)
 	}
@@ -139,7 +237,11 @@ func (s *SSHSession) ExecuteCommand(ctx context.Context, cmd string, timeout tim
 
 // ExecuteCommandStreaming runs a command and streams output to a writer
 func (s *SSHSession) ExecuteCommandStreaming(ctx context.Context, cmd string, timeout time.Duration, output io.Writer) (exitCode int, err error) {
-	session, err := s.client.NewSession()
+	if s.backend != nil {
+		return s.runBackend(ctx, cmd, nil, output, output, timeout)
+	}
+
+	session, err := s.newSession()
 	if err != nil {
 		return -1, fmt.Errorf("failed to create session: %w", err)
 	}
@@ -190,9 +292,24 @@ func (s *SSHSession) ExecuteCommandStreaming(ctx context.Context, cmd string, ti
 	}
 }
 
+// runBackend runs cmd on the session's backend with the same timeout
+// handling as an SSH session
+func (s *SSHSession) runBackend(ctx context.Context, cmd string, stdin []byte, stdout, stderr io.Writer, timeout time.Duration) (int, error) {
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	exitCode, err := s.backend.Run(runCtx, s.host, cmd, stdin, stdout, stderr)
+	if err != nil && ctx.Err() == nil && runCtx.Err() == context.DeadlineExceeded {
+		return -1, fmt.Errorf("command timeout after %v", timeout)
+	}
+	if err != nil {
+		return -1, err
+	}
+	return exitCode, nil
+}
+
 // TestConnection verifies the SSH connection works
 func TestSSHConnection(host *SSHHost) error {
-	session, err := NewSSHSession(host)
+	session, err := newUnpooledSSHSession(host)
 	if err != nil {
 		return err
 	}
@@ -218,7 +335,21 @@ func TestSSHConnection(host *SSHHost) error {
 
 // SCPPush copies a local file to the remote host
 func (s *SSHSession) SCPPush(localPath, remotePath string, content []byte) error {
-	session, err := s.client.NewSession()
+	if s.backend != nil {
+		if content == nil {
+			content = []byte{}
+		}
+		exitCode, err := s.backend.Run(context.Background(), s.host, fmt.Sprintf("cat > %s", remotePath), content, io.Discard, io.Discard)
+		if err == nil && exitCode != 0 {
+			err = fmt.Errorf("exit code %d", exitCode)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+		return nil
+	}
+
+	session, err := s.newSession()
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
@@ -241,7 +372,19 @@ func (s *SSHSession) SCPPush(localPath, remotePath string, content []byte) error
 
 // SCPPull reads a file from the remote host
 func (s *SSHSession) SCPPull(remotePath string) ([]byte, error) {
-	session, err := s.client.NewSession()
+	if s.backend != nil {
+		var buf bytes.Buffer
+		exitCode, err := s.backend.Run(context.Background(), s.host, fmt.Sprintf("cat %s", remotePath), nil, &buf, io.Discard)
+		if err == nil && exitCode != 0 {
+			err = fmt.Errorf("exit code %d", exitCode)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	session, err := s.newSession()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
@@ -276,7 +419,7 @@ func GetHostFingerprint(host string, port int) (*HostKeyInfo, error) {
 		port = 22
 	}
 
-	addr := fmt.Sprintf("%s:%d", host, port)
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
 	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect: %w", err)
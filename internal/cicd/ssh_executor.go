@@ -1,7 +1,6 @@
 package cicd
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -15,6 +14,7 @@ import (
 
 // ExecuteFreestyleBuild executes a freestyle build
 func ExecuteFreestyleBuild(buildID string) {
+	passwords := takeBuildPasswords(buildID)
 	build, err := GetFreestyleBuild(buildID)
 	if err != nil {
 		log.Error().Err(err).Str("build", buildID).Msg("Failed to get build")
@@ -37,6 +37,26 @@ func ExecuteFreestyleBuild(buildID string) {
 	// Get cancellation channel
 	cancelCh := GetBuildCancelChannel(buildID)
 
+	// Secret variables of the job, kept in memory only
+	secrets, err := secretValues(SecretScopeFreestyle, job.ID)
+	if err != nil {
+		WriteBuildOutput(buildID, []byte(fmt.Sprintf("Error: failed to load secret variables: %s\n", err)))
+		CompleteFreestyleBuild(buildID, RunStatusFailed, fmt.Sprintf("secret variables: %s", err))
+		return
+	}
+	// Password parameters are handled as secrets too
+	if len(passwords) > 0 && secrets == nil {
+		secrets = make(map[string]string, len(passwords))
+	}
+	for k, v := range passwords {
+		secrets[k] = v
+	}
+	build.secrets = secrets
+	build.masker = runLogMasker(secrets)
+	if stream := GetBuildOutputStream(buildID); stream != nil {
+		stream.setMasker(build.masker)
+	}
+
 	log.Info().
 		Str("build", buildID).
 		Str("job", job.Name).
@@ -48,6 +68,15 @@ func ExecuteFreestyleBuild(buildID string) {
 	WriteBuildOutput(buildID, []byte(fmt.Sprintf("Started at: %s\n", time.Now().Format(time.RFC3339))))
 	WriteBuildOutput(buildID, []byte(fmt.Sprintf("Trigger: %s\n\n", build.TriggerType)))
 
+	// Steps run in the workspace when the job has a policy for it
+	if job.Workspace != nil {
+		build.workspace = workspacePath(job, build)
+		if build.Environment == nil {
+			build.Environment = make(map[string]string)
+		}
+		build.Environment["WORKSPACE"] = build.workspace
+	}
+
 	// Execute SCM checkout if configured
 	var scmResult *GitCloneResult
 	if job.SCM != nil && job.SCM.Type == "git" && len(job.BuildSteps) > 0 {
@@ -66,11 +95,13 @@ func ExecuteFreestyleBuild(buildID string) {
 			return
 		}
 
+		build.useWorkspace(firstHost.ID)
 		scmResult, err = ExecuteGitSCM(buildID, session, job, build)
 		session.Close()
 
 		if err != nil {
 			WriteBuildOutput(buildID, []byte(fmt.Sprintf("SCM Error: %s\n", err)))
+			cleanupWorkspaces(buildID, build, job, RunStatusFailed)
 			CompleteFreestyleBuild(buildID, RunStatusFailed, fmt.Sprintf("SCM checkout failed: %s", err))
 			return
 		}
@@ -86,20 +117,36 @@ func ExecuteFreestyleBuild(buildID string) {
 			}
 		}
 	}
+	if workspace := build.Environment["WORKSPACE"]; workspace != "" {
+		if err := recordBuildWorkspace(buildID, workspace); err != nil {
+			log.Warn().Err(err).Str("build", buildID).Msg("Failed to record build workspace")
+		}
+	}
 
 	// Execute each step
 	var buildFailed bool
 	var buildError string
 
-	for _, step := range job.BuildSteps {
+	for _, steps := range stepGroups(job.BuildSteps) {
 		select {
 		case <-cancelCh:
 			WriteBuildOutput(buildID, []byte("\n!!! Build cancelled !!!\n"))
+			cleanupWorkspaces(buildID, build, job, RunStatusCancelled)
 			CompleteFreestyleBuild(buildID, RunStatusCancelled, "Build cancelled")
 			return
 		default:
 		}
 
+		if len(steps) > 1 {
+			if err := executeParallelSteps(buildID, build, job, steps, cancelCh); err != nil {
+				buildFailed = true
+				buildError = err.Error()
+				break
+			}
+			continue
+		}
+
+		step := steps[0]
 		stepErr := executeStep(buildID, build, job, &step, cancelCh)
 		if stepErr != nil {
 			if !step.ContinueOnError {
@@ -110,11 +157,25 @@ func ExecuteFreestyleBuild(buildID string) {
 		}
 	}
 
+	// Archive the files the build made, which fails a build that succeeded
+	// so far when they cannot be
+	if job.Archive != nil && (!buildFailed || job.Archive.Always) {
+		if err := archiveArtifacts(buildID, build, job); err != nil {
+			WriteBuildOutput(buildID, []byte(fmt.Sprintf("Error: %s\n", err)))
+			if !buildFailed {
+				buildFailed = true
+				buildError = err.Error()
+			}
+		}
+	}
+
 	// Complete the build
 	if buildFailed {
+		cleanupWorkspaces(buildID, build, job, RunStatusFailed)
 		WriteBuildOutput(buildID, []byte(fmt.Sprintf("\n=== Build FAILED: %s ===\n", buildError)))
 		CompleteFreestyleBuild(buildID, RunStatusFailed, buildError)
 	} else {
+		cleanupWorkspaces(buildID, build, job, RunStatusSucceeded)
 		WriteBuildOutput(buildID, []byte(fmt.Sprintf("\n=== Build SUCCEEDED ===\n")))
 		WriteBuildOutput(buildID, []byte(fmt.Sprintf("Finished at: %s\n", time.Now().Format(time.RFC3339))))
 		CompleteFreestyleBuild(buildID, RunStatusSucceeded, "")
@@ -126,38 +187,64 @@ func ExecuteFreestyleBuild(buildID string) {
 		Msg("Freestyle build execution completed")
 }
 
-// executeStep executes a single build step
-func executeStep(buildID string, build *FreestyleBuild, job *FreestyleJob, step *BuildStep, cancelCh <-chan struct{}) error {
-	WriteBuildOutput(buildID, []byte(fmt.Sprintf("\n--- Step: %s (%s) ---\n", step.Name, step.Type)))
+// executeStep executes a single build step. Its output goes to the stream
+// outputID: the build's, or the step's own when it runs in a parallel group.
+func executeStep(outputID string, build *FreestyleBuild, job *FreestyleJob, step *BuildStep, cancelCh <-chan struct{}) error {
+	WriteBuildOutput(outputID, []byte(fmt.Sprintf("\n--- Step: %s (%s) ---\n", step.Name, step.Type)))
 
 	// Mark step as running
-	UpdateFreestyleBuildStep(buildID, step.ID, RunStatusRunning, -1, "", "")
+	UpdateFreestyleBuildStep(build.ID, step.ID, RunStatusRunning, -1, "", "")
+
+	// A step with a host group runs on each of its hosts
+	if step.HostGroup != "" {
+		return executeHostGroupStep(outputID, build, job, step, cancelCh)
+	}
 
 	// Check if this is a local execution (no host specified or "local")
 	if step.HostID == "" || step.HostID == "local" {
-		return executeLocalStep(buildID, build, job, step, cancelCh)
+		return executeLocalStep(outputID, build, job, step, cancelCh)
 	}
 
 	// Get SSH host for remote execution
 	host, err := GetSSHHost(step.HostID)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to get SSH host: %s", err)
-		WriteBuildOutput(buildID, []byte(errMsg+"\n"))
-		UpdateFreestyleBuildStep(buildID, step.ID, RunStatusFailed, -1, "", errMsg)
+		WriteBuildOutput(outputID, []byte(errMsg+"\n"))
+		UpdateFreestyleBuildStep(build.ID, step.ID, RunStatusFailed, -1, "", errMsg)
 		return err
 	}
 
-	WriteBuildOutput(buildID, []byte(fmt.Sprintf("Host: %s (%s@%s:%d)\n", host.Name, host.Username, host.Host, host.Port)))
+	exitCode, output, stepErr := executeRemoteStep(outputID, build, job, step, host, cancelCh)
+
+	// Update step status
+	if stepErr != nil {
+		UpdateFreestyleBuildStep(build.ID, step.ID, RunStatusFailed, exitCode, output, stepErr.Error())
+		return stepErr
+	}
+
+	if exitCode != 0 {
+		UpdateFreestyleBuildStep(build.ID, step.ID, RunStatusFailed, exitCode, output, fmt.Sprintf("Exit code: %d", exitCode))
+		return fmt.Errorf("step failed with exit code %d", exitCode)
+	}
+
+	UpdateFreestyleBuildStep(build.ID, step.ID, RunStatusSucceeded, exitCode, output, "")
+	return nil
+}
+
+// executeRemoteStep executes a step on an SSH host
+func executeRemoteStep(outputID string, build *FreestyleBuild, job *FreestyleJob, step *BuildStep, host *SSHHost, cancelCh <-chan struct{}) (int, string, error) {
+	WriteBuildOutput(outputID, []byte(fmt.Sprintf("Host: %s (%s@%s:%d)\n", host.Name, host.Username, host.Host, host.Port)))
 
 	// Create SSH session
 	session, err := NewSSHSession(host)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to connect: %s", err)
-		WriteBuildOutput(buildID, []byte(errMsg+"\n"))
-		UpdateFreestyleBuildStep(buildID, step.ID, RunStatusFailed, -1, "", errMsg)
-		return err
+		WriteBuildOutput(outputID, []byte(fmt.Sprintf("Failed to connect: %s\n", err)))
+		return -1, "", fmt.Errorf("failed to connect: %w", err)
 	}
 	defer session.Close()
+	if build.workspace != "" {
+		build.useWorkspace(host.ID)
+	}
 
 	// Set timeout
 	timeout := time.Duration(step.Timeout) * time.Second
@@ -183,10 +270,10 @@ func executeStep(buildID string, build *FreestyleBuild, job *FreestyleJob, step
 
 	switch step.Type {
 	case StepTypeShell:
-		exitCode, output, stepErr = executeShellStep(ctx, session, step, build, job, timeout, buildID)
+		exitCode, output, stepErr = executeShellStep(ctx, session, step, build, job, timeout, outputID)
 
 	case StepTypeScript:
-		exitCode, output, stepErr = executeScriptStep(ctx, session, step, build, job, timeout, buildID)
+		exitCode, output, stepErr = executeScriptStep(ctx, session, step, build, job, timeout, outputID)
 
 	case StepTypeSCPPush:
 		exitCode, output, stepErr = executeSCPPushStep(session, step, build)
@@ -194,28 +281,22 @@ func executeStep(buildID string, build *FreestyleBuild, job *FreestyleJob, step
 	case StepTypeSCPPull:
 		exitCode, output, stepErr = executeSCPPullStep(session, step, build)
 
-	default:
-		stepErr = fmt.Errorf("unsupported step type: %s", step.Type)
-	}
+	case StepTypeSFTPUpload:
+		exitCode, output, stepErr = executeSFTPUploadStep(ctx, session, step, build, job, outputID)
 
-	// Update step status
-	if stepErr != nil {
-		UpdateFreestyleBuildStep(buildID, step.ID, RunStatusFailed, exitCode, output, stepErr.Error())
-		return stepErr
-	}
+	case StepTypeSFTPDownload:
+		exitCode, output, stepErr = executeSFTPDownloadStep(ctx, session, step, build, job, outputID)
 
-	if exitCode != 0 {
-		UpdateFreestyleBuildStep(buildID, step.ID, RunStatusFailed, exitCode, output, fmt.Sprintf("Exit code: %d", exitCode))
-		return fmt.Errorf("step failed with exit code %d", exitCode)
+	default:
+		stepErr = fmt.Errorf("unsupported step type: %s", step.Type)
 	}
-
-	UpdateFreestyleBuildStep(buildID, step.ID, RunStatusSucceeded, exitCode, output, "")
-	return nil
+	return exitCode, output, stepErr
 }
 
-// executeLocalStep executes a step locally inside the container
-func executeLocalStep(buildID string, build *FreestyleBuild, job *FreestyleJob, step *BuildStep, cancelCh <-chan struct{}) error {
-	WriteBuildOutput(buildID, []byte("Host: local (container)\n"))
+// executeLocalStep executes a step locally inside the container, with its
+// output going to the stream outputID
+func executeLocalStep(outputID string, build *FreestyleBuild, job *FreestyleJob, step *BuildStep, cancelCh <-chan struct{}) error {
+	WriteBuildOutput(outputID, []byte("Host: local (container)\n"))
 
 	// Set timeout
 	timeout := time.Duration(step.Timeout) * time.Second
@@ -241,10 +322,10 @@ func executeLocalStep(buildID string, build *FreestyleBuild, job *FreestyleJob,
 
 	switch step.Type {
 	case StepTypeShell:
-		exitCode, output, stepErr = executeLocalShellStep(ctx, step, build, job, buildID)
+		exitCode, output, stepErr = executeLocalShellStep(ctx, step, build, job, outputID)
 
 	case StepTypeScript:
-		exitCode, output, stepErr = executeLocalScriptStep(ctx, step, build, job, buildID)
+		exitCode, output, stepErr = executeLocalScriptStep(ctx, step, build, job, outputID)
 
 	default:
 		stepErr = fmt.Errorf("step type %s not supported for local execution", step.Type)
@@ -252,16 +333,16 @@ func executeLocalStep(buildID string, build *FreestyleBuild, job *FreestyleJob,
 
 	// Update step status
 	if stepErr != nil {
-		UpdateFreestyleBuildStep(buildID, step.ID, RunStatusFailed, exitCode, output, stepErr.Error())
+		UpdateFreestyleBuildStep(build.ID, step.ID, RunStatusFailed, exitCode, output, stepErr.Error())
 		return stepErr
 	}
 
 	if exitCode != 0 {
-		UpdateFreestyleBuildStep(buildID, step.ID, RunStatusFailed, exitCode, output, fmt.Sprintf("Exit code: %d", exitCode))
+		UpdateFreestyleBuildStep(build.ID, step.ID, RunStatusFailed, exitCode, output, fmt.Sprintf("Exit code: %d", exitCode))
 		return fmt.Errorf("step failed with exit code %d", exitCode)
 	}
 
-	UpdateFreestyleBuildStep(buildID, step.ID, RunStatusSucceeded, exitCode, output, "")
+	UpdateFreestyleBuildStep(build.ID, step.ID, RunStatusSucceeded, exitCode, output, "")
 	return nil
 }
 
@@ -272,37 +353,7 @@ func executeLocalShellStep(ctx context.Context, step *BuildStep, build *Freestyl
 	WriteBuildOutput(buildID, []byte(fmt.Sprintf("$ %s\n", cmdStr)))
 
 	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", cmdStr)
-
-	// Set environment variables
-	cmd.Env = os.Environ()
-	for k, v := range build.Environment {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
-	}
-
-	// Capture output
-	var stdout, stderr bytes.Buffer
-	stream := GetBuildOutputStream(buildID)
-	if stream != nil {
-		cmd.Stdout = &streamWriter{stream: stream, buffer: &stdout}
-		cmd.Stderr = &streamWriter{stream: stream, buffer: &stderr}
-	} else {
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
-	}
-
-	err := cmd.Run()
-	output := stdout.String() + stderr.String()
-
-	exitCode := 0
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
-		} else {
-			return -1, output, err
-		}
-	}
-
-	return exitCode, output, nil
+	return runLocalCommand(cmd, build, buildID)
 }
 
 // executeLocalScriptStep executes a script locally
@@ -333,25 +384,37 @@ func executeLocalScriptStep(ctx context.Context, step *BuildStep, build *Freesty
 	WriteBuildOutput(buildID, []byte(fmt.Sprintf("Running script: %s\n", tmpFile.Name())))
 
 	cmd := exec.CommandContext(ctx, tmpFile.Name())
+	return runLocalCommand(cmd, build, buildID)
+}
 
+// runLocalCommand runs a local step's command with the build's environment
+// and secret variables, streaming its masked output
+func runLocalCommand(cmd *exec.Cmd, build *FreestyleBuild, buildID string) (int, string, error) {
 	// Set environment variables
 	cmd.Env = os.Environ()
 	for k, v := range build.Environment {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
+	for k, v := range build.secrets {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	}
 
 	// Capture output
-	var stdout, stderr bytes.Buffer
+	stdout, stderr := newHeadTailBuffer(buildLogLimit()), newHeadTailBuffer(buildLogLimit())
+	var stdoutW, stderrW io.Writer = stdout, stderr
 	stream := GetBuildOutputStream(buildID)
 	if stream != nil {
-		cmd.Stdout = &streamWriter{stream: stream, buffer: &stdout}
-		cmd.Stderr = &streamWriter{stream: stream, buffer: &stderr}
-	} else {
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
+		stdoutW = &streamWriter{stream: stream, buffer: stdout}
+		stderrW = &streamWriter{stream: stream, buffer: stderr}
 	}
+	maskedStdout := newMaskingWriter(stdoutW, build.masker)
+	maskedStderr := newMaskingWriter(stderrW, build.masker)
+	cmd.Stdout = maskedStdout
+	cmd.Stderr = maskedStderr
 
-	err = cmd.Run()
+	err := cmd.Run()
+	maskedStdout.Flush()
+	maskedStderr.Flush()
 	output := stdout.String() + stderr.String()
 
 	exitCode := 0
@@ -369,7 +432,7 @@ func executeLocalScriptStep(ctx context.Context, step *BuildStep, build *Freesty
 // streamWriter writes to both a stream and a buffer
 type streamWriter struct {
 	stream *BuildOutputStream
-	buffer *bytes.Buffer
+	buffer *headTailBuffer
 }
 
 func (w *streamWriter) Write(p []byte) (n int, err error) {
@@ -388,21 +451,28 @@ func executeShellStep(ctx context.Context, session *SSHSession, step *BuildStep,
 
 	WriteBuildOutput(buildID, []byte(fmt.Sprintf("$ %s\n", cmd)))
 
+	loadSecrets, err := pushSecretVariables(ctx, session, build, step)
+	if err != nil {
+		return -1, "", err
+	}
+	cmd = loadSecrets + workspacePrefix(build) + cmd
+
 	// Create a buffer to capture output for storage
-	var outputBuf bytes.Buffer
+	outputBuf := newHeadTailBuffer(buildLogLimit())
 
 	// Create a wrapper that writes to both output stream and captures output
 	stream := GetBuildOutputStream(buildID)
 	if stream == nil {
 		// Fallback to non-streaming
 		stdout, stderr, exitCode, err := session.ExecuteCommand(ctx, cmd, timeout)
-		output := stdout + stderr
+		output := build.masker.mask(stdout + stderr)
 		return exitCode, output, err
 	}
 
 	// Use MultiWriter to write to both stream (for live updates) and buffer (for storage)
-	multiWriter := io.MultiWriter(stream, &outputBuf)
+	multiWriter := newMaskingWriter(io.MultiWriter(stream, outputBuf), build.masker)
 	exitCode, err := session.ExecuteCommandStreaming(ctx, cmd, timeout, multiWriter)
+	multiWriter.Flush()
 	return exitCode, outputBuf.String(), err
 }
 
@@ -411,7 +481,7 @@ func executeScriptStep(ctx context.Context, session *SSHSession, step *BuildStep
 	script := expandVariables(step.Script, build, job)
 
 	// Upload script to temp file and execute
-	scriptPath := fmt.Sprintf("/tmp/gagos_script_%s.sh", build.ID)
+	scriptPath := fmt.Sprintf("/tmp/gagos_script_%s_%s.sh", build.ID, sanitizeName(step.ID))
 
 	WriteBuildOutput(buildID, []byte(fmt.Sprintf("Uploading script to %s\n", scriptPath)))
 
@@ -424,24 +494,49 @@ func executeScriptStep(ctx context.Context, session *SSHSession, step *BuildStep
 		return -1, "", fmt.Errorf("failed to upload script: %w", err)
 	}
 
+	loadSecrets, err := pushSecretVariables(ctx, session, build, step)
+	if err != nil {
+		return -1, "", err
+	}
+
 	// Make executable and run
-	cmd := fmt.Sprintf("chmod +x %s && %s; EXIT_CODE=$?; rm -f %s; exit $EXIT_CODE", scriptPath, scriptPath, scriptPath)
+	cmd := loadSecrets + workspacePrefix(build) + fmt.Sprintf("chmod +x %s && %s; EXIT_CODE=$?; rm -f %s; exit $EXIT_CODE", scriptPath, scriptPath, scriptPath)
 
 	// Create a buffer to capture output for storage
-	var outputBuf bytes.Buffer
+	outputBuf := newHeadTailBuffer(buildLogLimit())
 
 	stream := GetBuildOutputStream(buildID)
 	if stream == nil {
 		stdout, stderr, exitCode, err := session.ExecuteCommand(ctx, cmd, timeout)
-		return exitCode, stdout + stderr, err
+		return exitCode, build.masker.mask(stdout + stderr), err
 	}
 
 	// Use MultiWriter to write to both stream (for live updates) and buffer (for storage)
-	multiWriter := io.MultiWriter(stream, &outputBuf)
+	multiWriter := newMaskingWriter(io.MultiWriter(stream, outputBuf), build.masker)
 	exitCode, err := session.ExecuteCommandStreaming(ctx, cmd, timeout, multiWriter)
+	multiWriter.Flush()
 	return exitCode, outputBuf.String(), err
 }
 
+// pushSecretVariables uploads the exports of the build's secret variables to
+// a file only the SSH user can read, so that they never show in a command
+// line. It returns the command prefix that loads and removes the file, ""
+// when the job has no secret variables.
+func pushSecretVariables(ctx context.Context, session *SSHSession, build *FreestyleBuild, step *BuildStep) (string, error) {
+	if len(build.secrets) == 0 {
+		return "", nil
+	}
+	path := fmt.Sprintf("/tmp/gagos_env_%s_%s.sh", build.ID, sanitizeName(step.ID))
+	prepare := fmt.Sprintf("umask 077 && : > %s", path)
+	if _, stderr, exitCode, err := session.ExecuteCommand(ctx, prepare, 30*time.Second); err != nil || exitCode != 0 {
+		return "", fmt.Errorf("failed to prepare secret variables: %s", errorText(err, stderr))
+	}
+	if err := session.SCPPush("", path, []byte(secretExports(build.secrets))); err != nil {
+		return "", fmt.Errorf("failed to upload secret variables: %w", err)
+	}
+	return fmt.Sprintf(". %s; rm -f %s; ", path, path), nil
+}
+
 // executeSCPPushStep copies files to remote
 func executeSCPPushStep(session *SSHSession, step *BuildStep, build *FreestyleBuild) (int, string, error) {
 	// Read local file
@@ -519,3 +614,17 @@ func TriggerFreestyleBuild(jobID string, triggerType string, triggerRef string,
 
 	return build, nil
 }
+
+// TriggerManualFreestyleBuild creates and executes a build a user
+// triggered, which takes only the parameters the job declares
+func TriggerManualFreestyleBuild(jobID string, req *TriggerFreestyleBuildRequest) (*FreestyleBuild, error) {
+	build, err := createFreestyleBuild(jobID, "manual", "", req.Parameters, req.Files, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Execute build in background
+	go ExecuteFreestyleBuild(build.ID)
+
+	return build, nil
+}
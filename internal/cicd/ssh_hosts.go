@@ -226,6 +226,7 @@ func DeleteSSHHost(id string) error {
 	if err := storage.GetBackend().Delete(storage.BucketSSHHosts, id); err != nil {
 		return fmt.Errorf("failed to delete host: %w", err)
 	}
+	sshClients.closeHost(id)
 
 	log.Info().Str("id", id).Msg("SSH host deleted")
 	return nil
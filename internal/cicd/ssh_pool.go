@@ -0,0 +1,282 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// SSH connections are pooled per host: the steps of a build, and builds
+// running at the same time, open their sessions on a connection the host
+// already has instead of dialing and authenticating again, which is slow and
+// runs into sshd's MaxStartups when many steps start at once. A connection
+// carries up to GAGOS_SSH_MAX_SESSIONS sessions at a time (default 8, below
+// sshd's MaxSessions of 10); more open another connection. Connections are
+// kept alive with a keep-alive request every GAGOS_SSH_KEEPALIVE (default
+// 30s) and closed once idle for GAGOS_SSH_IDLE_TIMEOUT (default 5m). One that
+// misses a keep-alive, or cannot open a session, is dropped and dialed again.
+// Connecting gives up after GAGOS_SSH_CONNECT_TIMEOUT and the SSH handshake
+// after GAGOS_SSH_HANDSHAKE_TIMEOUT (both 30s by default).
+
+// sshEnvDuration reads a duration setting, def when unset or invalid
+func sshEnvDuration(name string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// sshConnectTimeout is how long connecting to a host may take
+// (GAGOS_SSH_CONNECT_TIMEOUT, default 30s)
+func sshConnectTimeout() time.Duration {
+	return sshEnvDuration("GAGOS_SSH_CONNECT_TIMEOUT", 30*time.Second)
+}
+
+// sshHandshakeTimeout is how long the SSH handshake and authentication may
+// take once connected (GAGOS_SSH_HANDSHAKE_TIMEOUT, default 30s)
+func sshHandshakeTimeout() time.Duration {
+	return sshEnvDuration("GAGOS_SSH_HANDSHAKE_TIMEOUT", 30*time.Second)
+}
+
+// sshKeepAlive is how often pooled connections are checked
+// (GAGOS_SSH_KEEPALIVE, default 30s)
+func sshKeepAlive() time.Duration {
+	return sshEnvDuration("GAGOS_SSH_KEEPALIVE", 30*time.Second)
+}
+
+// sshIdleTimeout is how long an unused pooled connection is kept
+// (GAGOS_SSH_IDLE_TIMEOUT, default 5m)
+func sshIdleTimeout() time.Duration {
+	return sshEnvDuration("GAGOS_SSH_IDLE_TIMEOUT", 5*time.Minute)
+}
+
+// sshMaxSessions is how many sessions a pooled connection carries at a time
+// (GAGOS_SSH_MAX_SESSIONS, default 8)
+func sshMaxSessions() int {
+	if n, err := strconv.Atoi(os.Getenv("GAGOS_SSH_MAX_SESSIONS")); err == nil && n > 0 {
+		return n
+	}
+	return 8
+}
+
+// pooledSSHClient is a pooled connection to a host
+type pooledSSHClient struct {
+	client   *ssh.Client
+	hostID   string
+	key      string    // sshConnKey of the host when dialed
+	sessions int       // sessions using the connection
+	lastUsed time.Time // when the last session ended
+	dead     bool
+}
+
+// sshPool holds the pooled connections, by host ID
+type sshPool struct {
+	mu      sync.Mutex
+	clients map[string][]*pooledSSHClient
+	dialing map[string]*sync.Mutex // one dial at a time per host
+	reaper  sync.Once
+}
+
+var sshClients = &sshPool{
+	clients: make(map[string][]*pooledSSHClient),
+	dialing: make(map[string]*sync.Mutex),
+}
+
+// sshConnKey identifies how a host is connected to, so that a connection is
+// not reused once the host's address or credentials change
+func sshConnKey(host *SSHHost) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00%s\x00%s\x00%s\x00%s\x00%s\x00%t\x00%s", host.Host, host.Port, host.Username,
+		host.AuthMethod, host.Password, host.PrivateKey, host.Passphrase, host.VerifyHostKey, host.HostFingerprint)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// take returns a connection to the host with room for a session and counts
+// the session, nil when there is none; the caller holds the lock
+func (p *sshPool) take(host *SSHHost, key string) *pooledSSHClient {
+	for _, c := range p.clients[host.ID] {
+		if !c.dead && c.key == key && c.sessions < sshMaxSessions() {
+			c.sessions++
+			return c
+		}
+	}
+	return nil
+}
+
+// acquire returns a connection to the host for a session, dialing one when
+// the pool has none to spare
+func (p *sshPool) acquire(host *SSHHost, config *ssh.ClientConfig) (*pooledSSHClient, error) {
+	p.reaper.Do(func() { go p.reap() })
+	key := sshConnKey(host)
+
+	p.mu.Lock()
+	if c := p.take(host, key); c != nil {
+		p.mu.Unlock()
+		return c, nil
+	}
+	dialing, ok := p.dialing[host.ID]
+	if !ok {
+		dialing = &sync.Mutex{}
+		p.dialing[host.ID] = dialing
+	}
+	p.mu.Unlock()
+
+	// Sessions that wait for another's dial use its connection
+	dialing.Lock()
+	defer dialing.Unlock()
+	p.mu.Lock()
+	if c := p.take(host, key); c != nil {
+		p.mu.Unlock()
+		return c, nil
+	}
+	p.mu.Unlock()
+
+	client, err := dialSSH(host, config)
+	if err != nil {
+		return nil, err
+	}
+	c := &pooledSSHClient{client: client, hostID: host.ID, key: key, sessions: 1}
+	p.mu.Lock()
+	p.clients[host.ID] = append(p.clients[host.ID], c)
+	p.mu.Unlock()
+	go p.keepAlive(c)
+
+	log.Debug().Str("host", host.Name).Msg("SSH connection opened")
+	return c, nil
+}
+
+// release hands a connection back once a session is done with it, closing
+// it when it was retired
+func (p *sshPool) release(c *pooledSSHClient) {
+	p.mu.Lock()
+	c.sessions--
+	c.lastUsed = time.Now()
+	closing := c.dead && c.sessions == 0
+	p.mu.Unlock()
+
+	if closing {
+		c.client.Close()
+	}
+}
+
+// retire takes a connection that failed to open a session out of the pool;
+// it is closed once the sessions still using it are done
+func (p *sshPool) retire(c *pooledSSHClient) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !c.dead {
+		p.remove(c)
+	}
+}
+
+// discard closes a connection and removes it from the pool
+func (p *sshPool) discard(c *pooledSSHClient) {
+	p.mu.Lock()
+	if !c.dead {
+		p.remove(c)
+	}
+	p.mu.Unlock()
+	c.client.Close()
+}
+
+// remove takes a connection out of the pool; the caller holds the lock
+func (p *sshPool) remove(c *pooledSSHClient) {
+	c.dead = true
+	clients := p.clients[c.hostID]
+	for i, other := range clients {
+		if other == c {
+			p.clients[c.hostID] = append(clients[:i:i], clients[i+1:]...)
+			break
+		}
+	}
+	if len(p.clients[c.hostID]) == 0 {
+		delete(p.clients, c.hostID)
+	}
+}
+
+// closeHost closes the pooled connections to a host, for one that is deleted
+func (p *sshPool) closeHost(hostID string) {
+	p.mu.Lock()
+	clients := p.clients[hostID]
+	for _, c := range clients {
+		c.dead = true
+	}
+	delete(p.clients, hostID)
+	delete(p.dialing, hostID)
+	p.mu.Unlock()
+
+	for _, c := range clients {
+		c.client.Close()
+	}
+}
+
+// keepAlive sends keep-alive requests on a connection until it is closed,
+// dropping it when one goes unanswered
+func (p *sshPool) keepAlive(c *pooledSSHClient) {
+	interval := sshKeepAlive()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p.mu.Lock()
+		dead := c.dead
+		p.mu.Unlock()
+		if dead {
+			return
+		}
+
+		answered := make(chan error, 1)
+		go func() {
+			_, _, err := c.client.SendRequest("keepalive@openssh.com", true, nil)
+			answered <- err
+		}()
+		var err error
+		select {
+		case err = <-answered:
+		case <-time.After(interval):
+			err = fmt.Errorf("no answer within %v", interval)
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("host", c.hostID).Msg("SSH keep-alive failed, dropping connection")
+			p.discard(c)
+			return
+		}
+	}
+}
+
+// reap closes the connections that have been idle for the idle timeout
+func (p *sshPool) reap() {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		idle := sshIdleTimeout()
+		var closing []*pooledSSHClient
+		p.mu.Lock()
+		for _, clients := range p.clients {
+			for _, c := range clients {
+				if c.sessions == 0 && time.Since(c.lastUsed) > idle {
+					closing = append(closing, c)
+				}
+			}
+		}
+		for _, c := range closing {
+			p.remove(c)
+		}
+		p.mu.Unlock()
+
+		for _, c := range closing {
+			c.client.Close()
+		}
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// A job with runsOn: ssh/<group> runs its script on one of the SSH hosts in
+// that host group instead of in a K8s Job. The script, with the job's
+// environment exported ahead of it, is uploaded to the host next to a
+// workspace directory; both are removed when the attempt ends unless the job
+// names its own workdir. Runs are spread over the group by run number, and a
+// host that cannot be reached is passed over for the next one.
+
+// runsOnSSHPrefix starts the runsOn of a job that runs on an SSH host group
+const runsOnSSHPrefix = "ssh/"
+
+// shellName matches the variable names a POSIX shell can export
+var shellName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateRunsOn checks a job's runsOn
+func validateRunsOn(runsOn string) error {
+	group, ok := strings.CutPrefix(runsOn, runsOnSSHPrefix)
+	if !ok || strings.TrimSpace(group) == "" {
+		return fmt.Errorf("runsOn must be %s<host group>", runsOnSSHPrefix)
+	}
+	return nil
+}
+
+// sshRunnerGroup returns the SSH host group a job runs on, "" for a K8s job
+func sshRunnerGroup(jobSpec *JobSpec) string {
+	group, _ := strings.CutPrefix(jobSpec.RunsOn, runsOnSSHPrefix)
+	return group
+}
+
+// sshRunnerHosts lists the hosts of an SSH host group
+func sshRunnerHosts(group string) ([]*SSHHost, error) {
+	hosts, err := ListSSHHosts()
+	if err != nil {
+		return nil, err
+	}
+	var members []*SSHHost
+	for _, h := range hosts {
+		for _, g := range h.HostGroups {
+			if g == group {
+				members = append(members, h)
+				break
+			}
+		}
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("no SSH host in host group %s", group)
+	}
+	return members, nil
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sshJobScript renders the script uploaded for a job: its environment, the
+// source checkout when the job has one, then the job's own script
+func sshJobScript(pipeline *Pipeline, run *PipelineRun, jobSpec *JobSpec, workspace string) (string, error) {
+	var b strings.Builder
+	export := func(name, value string) {
+		if shellName.MatchString(name) {
+			fmt.Fprintf(&b, "export %s=%s\n", name, shellQuote(value))
+		}
+	}
+	export("PIPELINE_ID", pipeline.ID)
+	export("PIPELINE_NAME", pipeline.Name)
+	export("RUN_ID", run.ID)
+	export("RUN_NUMBER", fmt.Sprintf("%d", run.RunNumber))
+	export("JOB_NAME", jobSpec.Name)
+	export("TRIGGER_TYPE", run.TriggerType)
+	names := make([]string, 0, len(run.Variables))
+	for name := range run.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		export(name, run.Variables[name])
+	}
+	for _, ev := range jobSpec.Env {
+		export(ev.Name, ev.Value)
+	}
+	b.WriteString(secretExports(run.secrets))
+	fmt.Fprintf(&b, "cd %s || exit 1\n", shellQuote(workspace))
+
+	if src := jobSpec.Source; src != nil {
+		expand := func(s string) string {
+			return os.Expand(s, func(name string) string { return run.Variables[name] })
+		}
+		repo := expand(src.Repo)
+		url := repo
+		if src.CredentialID != "" {
+			cred, err := GetDecryptedGitCredential(src.CredentialID)
+			if err != nil {
+				return "", fmt.Errorf("source credential: %w", err)
+			}
+			switch cred.AuthMethod {
+			case GitAuthToken:
+				url = injectTokenIntoURL(repo, cred.Token)
+			case GitAuthPassword:
+				url = injectCredentialsIntoURL(repo, cred.Username, cred.Password)
+			default:
+				return "", fmt.Errorf("source credential %s: jobs on SSH hosts clone with token or password credentials", cred.Name)
+			}
+		}
+		cloneDir := workspace
+		if src.Path != "" {
+			cloneDir = path.Join(workspace, src.Path)
+		}
+		// The checkout runs in a subshell so that its variables, the
+		// credentials among them, do not reach the job's script
+		b.WriteString("(\n")
+		fmt.Fprintf(&b, "GAGOS_SOURCE_REPO=%s\n", shellQuote(repo))
+		fmt.Fprintf(&b, "GAGOS_SOURCE_URL=%s\n", shellQuote(url))
+		fmt.Fprintf(&b, "GAGOS_SOURCE_BRANCH=%s\n", shellQuote(expand(src.Branch)))
+		fmt.Fprintf(&b, "GAGOS_SOURCE_DIR=%s\n", shellQuote(cloneDir))
+		b.WriteString("export GIT_TERMINAL_PROMPT=0\n")
+		b.WriteString(sourceScript(src))
+		b.WriteString(") || exit $?\n")
+	}
+
+	b.WriteString(jobSpec.Script)
+	b.WriteString("\n")
+	return b.String(), nil
+}
+
+// connectSSHRunner opens a session to a host of group, starting at the host
+// the run number picks
+func connectSSHRunner(group string, runNumber int) (*SSHSession, *SSHHost, error) {
+	hosts, err := sshRunnerHosts(group)
+	if err != nil {
+		return nil, nil, err
+	}
+	var lastErr error
+	for i := range hosts {
+		host := hosts[(runNumber+i)%len(hosts)]
+		session, err := NewSSHSession(host)
+		if err != nil {
+			log.Warn().Err(err).Str("host", host.Name).Str("group", group).Msg("SSH runner host unreachable, trying the next one")
+			lastErr = err
+			continue
+		}
+		return session, host, nil
+	}
+	return nil, nil, fmt.Errorf("no host in SSH host group %s could be reached: %w", group, lastErr)
+}
+
+// executeSSHJob runs one attempt of a pipeline job on an SSH host
+func executeSSHJob(ctx context.Context, pipeline *Pipeline, run *PipelineRun, jobRun *JobRun, jobSpec *JobSpec, attempt int) error {
+	now := time.Now()
+	jobRun.Status = RunStatusRunning
+	if jobRun.StartedAt == nil {
+		jobRun.StartedAt = &now
+	}
+
+	group := sshRunnerGroup(jobSpec)
+	session, host, err := connectSSHRunner(group, run.RunNumber)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	jobRun.Host = host.Name
+
+	name := fmt.Sprintf("cicd-%s-%s", run.ID[:12], sanitizeName(jobSpec.Name))
+	if attempt > 1 {
+		name = fmt.Sprintf("%s-r%d", name, attempt)
+	}
+	workspace := jobSpec.Workdir
+	keepWorkspace := workspace != ""
+	if !keepWorkspace {
+		workspace = "/tmp/" + name
+	}
+	scriptPath := "/tmp/" + name + ".sh"
+	cleanup := "rm -f " + scriptPath
+	if !keepWorkspace {
+		cleanup = "rm -rf " + scriptPath + " " + shellQuote(workspace)
+	}
+
+	script, err := sshJobScript(pipeline, run, jobSpec, workspace)
+	if err != nil {
+		return err
+	}
+	// The script may hold credentials; only the SSH user can read it
+	prepare := fmt.Sprintf("umask 077 && mkdir -p %s && : > %s", shellQuote(workspace), scriptPath)
+	if _, stderr, exitCode, err := session.ExecuteCommand(ctx, prepare, 30*time.Second); err != nil || exitCode != 0 {
+		return fmt.Errorf("failed to prepare workspace on %s: %s", host.Name, errorText(err, stderr))
+	}
+	if err := session.SCPPush("", scriptPath, []byte(script)); err != nil {
+		return fmt.Errorf("failed to upload script to %s: %w", host.Name, err)
+	}
+
+	log.Info().
+		Str("job", jobSpec.Name).
+		Str("host", host.Name).
+		Str("workspace", workspace).
+		Msg("Running job on SSH host")
+
+	// Logs go straight to the kept log file, readable while the job runs
+	var logFile io.Writer = io.Discard
+	capture := openJobLog(run.ID, jobSpec.Name, attempt, func() {})
+	if capture != nil {
+		logFile = capture.file
+		jobRun.LogPath = capture.path
+		fmt.Fprintf(logFile, "Running on %s (%s)\n", host.Name, host.Host)
+	}
+	output := newMaskingWriter(logFile, run.masker)
+	saveActiveRun(run)
+	defer func() {
+		output.Flush()
+		if capture != nil {
+			close(capture.done)
+		}
+		jobRun.LogPath = capture.finish()
+	}()
+
+	timeout := time.Duration(jobSpec.Timeout) * time.Second
+	if timeout == 0 {
+		timeout = 10 * time.Minute
+	}
+
+	// CancelRun has no K8s Job to delete here; the command is killed instead
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+				if runCancelled(run.ID) {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	cmd := fmt.Sprintf("/bin/sh %s; code=$?; %s; exit $code", scriptPath, cleanup)
+	exitCode, err := session.ExecuteCommandStreaming(runCtx, cmd, timeout, output)
+	if err != nil {
+		// A killed command leaves its files behind
+		session.ExecuteCommand(context.Background(), cleanup, 30*time.Second)
+		if runCtx.Err() != nil && ctx.Err() == nil {
+			return fmt.Errorf("run cancelled")
+		}
+		return fmt.Errorf("job on %s: %w", host.Name, err)
+	}
+	jobRun.ExitCode = exitCode
+	if exitCode != 0 {
+		return fmt.Errorf("%w: exit code %d on %s", errJobFailed, exitCode, host.Name)
+	}
+
+	finishedAt := time.Now()
+	jobRun.FinishedAt = &finishedAt
+	if jobRun.StartedAt != nil {
+		jobRun.Duration = finishedAt.Sub(*jobRun.StartedAt).Milliseconds()
+	}
+	jobRun.Status = RunStatusSucceeded
+	jobRun.Error = ""
+	return nil
+}
+
+// errorText describes a failed command by its error or its stderr
+func errorText(err error, stderr string) string {
+	if err != nil {
+		return err.Error()
+	}
+	return strings.TrimSpace(stderr)
+}
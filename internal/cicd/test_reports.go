@@ -0,0 +1,470 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// A job publishes a JUnit XML test report by uploading it as an artifact of
+// its run. The report is parsed into the test results of the run, which add
+// up every report the run published and outlive the artifact, so that the
+// results of a run can be compared with those of the pipeline's earlier runs:
+// a test that both passed and failed in recent runs is flaky.
+
+// Test case statuses
+const (
+	TestStatusPassed  = "passed"
+	TestStatusFailed  = "failed"
+	TestStatusError   = "error"
+	TestStatusSkipped = "skipped"
+)
+
+// Test trend window
+const (
+	defaultTestHistory = 20
+	maxTestHistory     = 100
+)
+
+// maxTestFailureDetails caps the failure output kept per test case
+const maxTestFailureDetails = 4000
+
+// ErrNotTestReport is returned when a file is XML but not a JUnit report
+var ErrNotTestReport = errors.New("not a JUnit XML report")
+
+// testReportsMu serializes the updates of a run's test results by reports
+// uploaded at the same time
+var testReportsMu sync.Mutex
+
+// TestCaseResult is the outcome of one test case
+type TestCaseResult struct {
+	Suite     string `json:"suite,omitempty"`
+	ClassName string `json:"classname,omitempty"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Duration  int64  `json:"duration_ms"`
+	Message   string `json:"message,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Details   string `json:"details,omitempty"`
+	Report    string `json:"report"` // artifact ID of the report
+}
+
+// key identifies a test case across runs
+func (c *TestCaseResult) key() string {
+	return c.ClassName + "\x00" + c.Name
+}
+
+// TestSummary counts the test cases of a run by status
+type TestSummary struct {
+	Total    int   `json:"total"`
+	Passed   int   `json:"passed"`
+	Failed   int   `json:"failed"`
+	Errors   int   `json:"errors"`
+	Skipped  int   `json:"skipped"`
+	Duration int64 `json:"duration_ms"`
+}
+
+// TestReport holds the test results a run published
+type TestReport struct {
+	RunID      string           `json:"run_id"`
+	PipelineID string           `json:"pipeline_id"`
+	RunNumber  int              `json:"run_number"`
+	Reports    []string         `json:"reports"` // artifact IDs
+	Summary    TestSummary      `json:"summary"`
+	Cases      []TestCaseResult `json:"cases"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// FlakyTest is a test that both passed and failed in recent runs
+type FlakyTest struct {
+	ClassName   string    `json:"classname,omitempty"`
+	Name        string    `json:"name"`
+	Runs        int       `json:"runs"`
+	Passed      int       `json:"passed"`
+	Failed      int       `json:"failed"`
+	FailureRate float64   `json:"failure_rate"`
+	Flips       int       `json:"flips"`   // changes between passing and failing
+	History     []string  `json:"history"` // status in each run that ran it, oldest first
+	LastFailed  time.Time `json:"last_failed"`
+}
+
+// TestTrendPoint is the summary of one run's tests
+type TestTrendPoint struct {
+	RunID     string      `json:"run_id"`
+	RunNumber int         `json:"run_number"`
+	Summary   TestSummary `json:"summary"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// TestResults is a run's test results compared with earlier runs of its
+// pipeline
+type TestResults struct {
+	RunID     string           `json:"run_id"`
+	Reports   []string         `json:"reports"`
+	Summary   TestSummary      `json:"summary"`
+	Failed    []TestCaseResult `json:"failed"`
+	Flaky     []FlakyTest      `json:"flaky"`
+	Trend     []TestTrendPoint `json:"trend"` // oldest first, ending with this run
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// junitSuite is a <testsuite>, or the <testsuites> around them
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	Suites []junitSuite `xml:"testsuite"`
+	Cases  []junitCase  `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure"`
+	Error     *junitProblem `xml:"error"`
+	Skipped   *junitProblem `xml:"skipped"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ParseJUnitReport reads the test cases of a JUnit XML report, whose root is
+// either <testsuites> or a single <testsuite>
+func ParseJUnitReport(r io.Reader) ([]TestCaseResult, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, ErrNotTestReport
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "testsuites" && start.Name.Local != "testsuite" {
+			return nil, ErrNotTestReport
+		}
+		var root junitSuite
+		if err := dec.DecodeElement(&root, &start); err != nil {
+			return nil, fmt.Errorf("invalid JUnit report: %w", err)
+		}
+		cases := make([]TestCaseResult, 0)
+		collectJUnitCases(&root, start.Name.Local == "testsuite", &cases)
+		return cases, nil
+	}
+}
+
+// collectJUnitCases appends the test cases of suite and its nested suites.
+// The <testsuites> root names no suite of its own.
+func collectJUnitCases(suite *junitSuite, named bool, cases *[]TestCaseResult) {
+	for _, tc := range suite.Cases {
+		result := TestCaseResult{
+			ClassName: tc.ClassName,
+			Name:      tc.Name,
+			Status:    TestStatusPassed,
+			Duration:  junitDuration(tc.Time),
+		}
+		if named {
+			result.Suite = suite.Name
+		}
+		problem := tc.Skipped
+		switch {
+		case tc.Failure != nil:
+			result.Status, problem = TestStatusFailed, tc.Failure
+		case tc.Error != nil:
+			result.Status, problem = TestStatusError, tc.Error
+		case tc.Skipped != nil:
+			result.Status = TestStatusSkipped
+		}
+		if problem != nil {
+			result.Message = problem.Message
+			result.Type = problem.Type
+			result.Details = strings.TrimSpace(problem.Text)
+			if len(result.Details) > maxTestFailureDetails {
+				result.Details = result.Details[:maxTestFailureDetails] + "..."
+			}
+		}
+		*cases = append(*cases, result)
+	}
+	for i := range suite.Suites {
+		collectJUnitCases(&suite.Suites[i], true, cases)
+	}
+}
+
+// junitDuration converts a time attribute, in seconds, to milliseconds
+func junitDuration(s string) int64 {
+	secs, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 64)
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return int64(secs * 1000)
+}
+
+// summarizeTests counts cases by status
+func summarizeTests(cases []TestCaseResult) TestSummary {
+	var sum TestSummary
+	for _, c := range cases {
+		sum.Total++
+		sum.Duration += c.Duration
+		switch c.Status {
+		case TestStatusPassed:
+			sum.Passed++
+		case TestStatusFailed:
+			sum.Failed++
+		case TestStatusError:
+			sum.Errors++
+		case TestStatusSkipped:
+			sum.Skipped++
+		}
+	}
+	return sum
+}
+
+// IngestTestReport parses an uploaded artifact as a JUnit XML report and adds
+// its test cases to the results of its run. ErrNotTestReport is returned when
+// the artifact is XML of another kind.
+func IngestTestReport(artifact *ArtifactMetadata) (*TestReport, error) {
+	f, err := os.Open(artifact.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open report: %w", err)
+	}
+	defer f.Close()
+
+	cases, err := ParseJUnitReport(f)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cases {
+		cases[i].Report = artifact.ID
+	}
+
+	testReportsMu.Lock()
+	defer testReportsMu.Unlock()
+
+	report, err := GetTestReport(artifact.RunID)
+	if err != nil {
+		report = &TestReport{
+			RunID:      artifact.RunID,
+			PipelineID: artifact.PipelineID,
+			CreatedAt:  time.Now(),
+		}
+		if run, err := GetRun(artifact.RunID); err == nil {
+			report.RunNumber = run.RunNumber
+		}
+	}
+	report.Reports = append(report.Reports, artifact.ID)
+	report.Cases = append(report.Cases, cases...)
+	report.Summary = summarizeTests(report.Cases)
+	report.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.GetBackend().Set(storage.BucketTestReports, report.RunID, data); err != nil {
+		return nil, fmt.Errorf("failed to save test report: %w", err)
+	}
+
+	log.Info().
+		Str("run_id", report.RunID).
+		Str("artifact_id", artifact.ID).
+		Int("tests", len(cases)).
+		Int("failed", report.Summary.Failed+report.Summary.Errors).
+		Msg("Test report ingested")
+
+	return report, nil
+}
+
+// GetTestReport retrieves the test results of a run
+func GetTestReport(runID string) (*TestReport, error) {
+	data, err := storage.GetBackend().Get(storage.BucketTestReports, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test report: %w", err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("no test reports for run: %s", runID)
+	}
+
+	var report TestReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal test report: %w", err)
+	}
+	return &report, nil
+}
+
+// ListTestReports returns the test results of a pipeline's runs, newest
+// first
+func ListTestReports(pipelineID string, limit int) ([]*TestReport, error) {
+	items, err := storage.GetBackend().List(storage.BucketTestReports)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*TestReport, 0)
+	for _, data := range items {
+		var report TestReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			continue
+		}
+		if pipelineID != "" && report.PipelineID != pipelineID {
+			continue
+		}
+		reports = append(reports, &report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].CreatedAt.After(reports[j].CreatedAt)
+	})
+
+	if limit > 0 && len(reports) > limit {
+		reports = reports[:limit]
+	}
+	return reports, nil
+}
+
+// deleteTestReport removes the test results of a run
+func deleteTestReport(runID string) {
+	storage.GetBackend().Delete(storage.BucketTestReports, runID)
+}
+
+// GetTestResults returns the test results of a run with its failed tests,
+// and the flaky tests and summaries of the last history runs of its pipeline
+// that published tests, up to and including this one
+func GetTestResults(runID string, history int) (*TestResults, error) {
+	report, err := GetTestReport(runID)
+	if err != nil {
+		return nil, err
+	}
+	if history <= 0 {
+		history = defaultTestHistory
+	}
+	if history > maxTestHistory {
+		history = maxTestHistory
+	}
+
+	results := &TestResults{
+		RunID:     report.RunID,
+		Reports:   report.Reports,
+		Summary:   report.Summary,
+		Failed:    make([]TestCaseResult, 0),
+		UpdatedAt: report.UpdatedAt,
+	}
+	for _, c := range report.Cases {
+		if c.Status == TestStatusFailed || c.Status == TestStatusError {
+			results.Failed = append(results.Failed, c)
+		}
+	}
+
+	all, err := ListTestReports(report.PipelineID, 0)
+	if err != nil {
+		return nil, err
+	}
+	recent := make([]*TestReport, 0, history)
+	for _, r := range all {
+		if r.CreatedAt.After(report.CreatedAt) {
+			continue
+		}
+		recent = append(recent, r)
+		if len(recent) == history {
+			break
+		}
+	}
+
+	// Oldest first
+	for i := len(recent) - 1; i >= 0; i-- {
+		r := recent[i]
+		results.Trend = append(results.Trend, TestTrendPoint{
+			RunID:     r.RunID,
+			RunNumber: r.RunNumber,
+			Summary:   r.Summary,
+			CreatedAt: r.CreatedAt,
+		})
+	}
+	results.Flaky = flakyTests(recent)
+	return results, nil
+}
+
+// flakyTests finds the tests that both passed and failed in reports, which
+// are sorted newest first, most often flipping first
+func flakyTests(reports []*TestReport) []FlakyTest {
+	byKey := make(map[string]*FlakyTest)
+	order := make([]string, 0)
+	for i := len(reports) - 1; i >= 0; i-- {
+		// A test run several times in one run counts once, failed if any
+		// attempt failed
+		statuses := make(map[string]string)
+		cases := make(map[string]TestCaseResult)
+		for _, c := range reports[i].Cases {
+			k := c.key()
+			switch c.Status {
+			case TestStatusPassed:
+				if statuses[k] == "" {
+					statuses[k] = TestStatusPassed
+				}
+			case TestStatusFailed, TestStatusError:
+				statuses[k] = TestStatusFailed
+			default:
+				continue
+			}
+			cases[k] = c
+		}
+		for k, status := range statuses {
+			t, ok := byKey[k]
+			if !ok {
+				t = &FlakyTest{ClassName: cases[k].ClassName, Name: cases[k].Name}
+				byKey[k] = t
+				order = append(order, k)
+			}
+			if n := len(t.History); n > 0 && t.History[n-1] != status {
+				t.Flips++
+			}
+			t.History = append(t.History, status)
+			t.Runs++
+			if status == TestStatusPassed {
+				t.Passed++
+			} else {
+				t.Failed++
+				t.LastFailed = reports[i].CreatedAt
+			}
+		}
+	}
+
+	flaky := make([]FlakyTest, 0)
+	for _, k := range order {
+		t := byKey[k]
+		if t.Passed == 0 || t.Failed == 0 {
+			continue
+		}
+		t.FailureRate = float64(t.Failed) / float64(t.Runs)
+		flaky = append(flaky, *t)
+	}
+	sort.Slice(flaky, func(i, j int) bool {
+		if flaky[i].Flips != flaky[j].Flips {
+			return flaky[i].Flips > flaky[j].Flips
+		}
+		if flaky[i].FailureRate != flaky[j].FailureRate {
+			return flaky[i].FailureRate > flaky[j].FailureRate
+		}
+		return flaky[i].ClassName+flaky[i].Name < flaky[j].ClassName+flaky[j].Name
+	})
+	return flaky
+}
@@ -23,29 +23,131 @@ type PipelineSpec struct {
 	Variables map[string]string `json:"variables,omitempty"`
 	Jobs      []JobSpec         `json:"jobs"`
 	Artifacts []ArtifactSpec    `json:"artifacts,omitempty"`
+	// Concurrency limits the runs that may execute at once
+	Concurrency *ConcurrencySpec `json:"concurrency,omitempty"`
+	// CommitStatus reports runs back to the Git provider
+	CommitStatus *CommitStatusSpec `json:"commitStatus,omitempty"`
+	// Coverage fails runs whose coverage is too low
+	Coverage *CoverageSpec `json:"coverage,omitempty"`
+	// Retention overrides the global retention of the pipeline's runs
+	Retention *RetentionSpec `json:"retention,omitempty"`
+}
+
+// RetentionSpec is how long the runs of a pipeline are kept. Zero fields
+// take the global retention.
+type RetentionSpec struct {
+	KeepRuns       int `json:"keepRuns,omitempty"`       // most recent runs kept
+	MaxAgeDays     int `json:"maxAgeDays,omitempty"`     // older runs are deleted
+	MaxArtifactsMB int `json:"maxArtifactsMB,omitempty"` // cap on the artifacts of its runs
+}
+
+// CoverageSpec is the coverage gate of a pipeline. A run whose jobs passed
+// fails when the coverage it uploaded is below Minimum, or more than MaxDrop
+// percentage points below that of the last successful run with coverage.
+type CoverageSpec struct {
+	Minimum float64 `json:"minimum,omitempty"` // percent
+	MaxDrop float64 `json:"maxDrop,omitempty"` // percentage points
+}
+
+// CommitStatusSpec posts the status of each run to the commit it built.
+// Repository, Commit, Context and TargetURL may reference run variables.
+type CommitStatusSpec struct {
+	CredentialID string `json:"credentialId"`         // Git credential with a token
+	Provider     string `json:"provider,omitempty"`   // github, gitlab or gitea; default WEBHOOK_PROVIDER
+	APIURL       string `json:"apiUrl,omitempty"`     // default derived from the repository host
+	Repository   string `json:"repository,omitempty"` // clone URL; default ${WEBHOOK_REPOSITORY}
+	Commit       string `json:"commit,omitempty"`       // default ${WEBHOOK_COMMIT}
+	Context      string `json:"context,omitempty"`      // default gagos/${PIPELINE_NAME}
+	TargetURL    string `json:"targetUrl,omitempty"`    // default the run in the UI under GAGOS_PUBLIC_URL
+}
+
+// Concurrency modes: what a new run does when its group is full
+const (
+	ConcurrencyQueue            = "queue"              // wait for a running run to finish
+	ConcurrencyCancelInProgress = "cancel-in-progress" // cancel the oldest running run
+)
+
+// ConcurrencySpec limits the runs of a concurrency group. Runs of every
+// pipeline naming the same group share its slots, so pipelines that deploy
+// to the same environment can be serialized. Group may reference variables
+// as ${NAME}; it defaults to the pipeline.
+type ConcurrencySpec struct {
+	Group string `json:"group,omitempty"`
+	Max   int    `json:"max,omitempty"`  // runs allowed at once; default 1
+	Mode  string `json:"mode,omitempty"` // queue (default) or cancel-in-progress
 }
 
 // Trigger defines how a pipeline can be triggered
 type Trigger struct {
-	Type     string `json:"type"` // webhook, cron
-	Secret   string `json:"secret,omitempty"`
-	Schedule string `json:"schedule,omitempty"` // cron expression
-	Enabled  bool   `json:"enabled"`
+	Type         string   `json:"type"` // webhook, cron, pollSCM
+	Secret       string   `json:"secret,omitempty"`
+	Schedule     string   `json:"schedule,omitempty"` // cron expression
+	Enabled      bool     `json:"enabled"`
+	Branches     []string `json:"branches,omitempty"`     // webhook: branch globs that trigger runs; all when empty
+	Repo         string   `json:"repo,omitempty"`         // pollSCM: HTTPS URL of the polled repository
+	Branch       string   `json:"branch,omitempty"`       // pollSCM: polled branch; the default branch when empty
+	CredentialID string   `json:"credentialId,omitempty"` // pollSCM: Git credential for private repositories
 }
 
 // JobSpec defines a single job in the pipeline
 type JobSpec struct {
-	Name       string            `json:"name"`
-	Image      string            `json:"image"`
-	Workdir    string            `json:"workdir,omitempty"`
-	Script     string            `json:"script"`
-	Env        []EnvVar          `json:"env,omitempty"`
-	Secrets    []SecretMount     `json:"secrets,omitempty"`
-	Resources  ResourceSpec      `json:"resources,omitempty"`
-	Timeout    int               `json:"timeout,omitempty"` // seconds, default 600
-	Privileged bool              `json:"privileged,omitempty"`
-	DependsOn  []string          `json:"dependsOn,omitempty"`
-	SkipIf     string            `json:"skipIf,omitempty"` // Variable name - if set to "true", job is skipped
+	Name        string          `json:"name"`
+	Image       string          `json:"image"`
+	Workdir     string          `json:"workdir,omitempty"`
+	Script      string          `json:"script"`
+	Env         []EnvVar        `json:"env,omitempty"`
+	Secrets     []SecretMount   `json:"secrets,omitempty"`
+	Resources   ResourceSpec    `json:"resources,omitempty"`
+	Timeout     int             `json:"timeout,omitempty"` // seconds, default 600
+	Privileged  bool            `json:"privileged,omitempty"`
+	DependsOn   []string        `json:"dependsOn,omitempty"`
+	SkipIf      string          `json:"skipIf,omitempty"`     // Variable name - if set to "true", job is skipped
+	Retries     int             `json:"retries,omitempty"`    // extra attempts after a failure, at most MaxJobRetries
+	RetryDelay  int             `json:"retryDelay,omitempty"` // seconds before the first retry, doubled for each next one; default 10
+	Source      *SourceSpec     `json:"source,omitempty"`
+	Kind        string          `json:"kind,omitempty"`        // script (default) or build-image
+	Build       *BuildImageSpec `json:"build,omitempty"`       // for build-image jobs
+	RunsOn      string          `json:"runsOn,omitempty"`      // ssh/<host group> runs the script on an SSH host instead of in a K8s Job
+	Environment string          `json:"environment,omitempty"` // environment the job deploys to, e.g. prod
+	Uses        string          `json:"uses,omitempty"`        // templates/<name>@<version> the job is defined from
+}
+
+// Job kinds
+const (
+	JobKindScript     = "script"      // runs the job's script in its image
+	JobKindBuildImage = "build-image" // builds and pushes a container image
+)
+
+// Image builders of build-image jobs
+const (
+	BuilderKaniko   = "kaniko"
+	BuilderBuildKit = "buildkit"
+)
+
+// BuildImageSpec builds the Dockerfile in the job's workdir, usually a source
+// checkout, and pushes the image under each tag. Image, Tags and BuildArgs
+// may reference run variables as ${NAME}.
+type BuildImageSpec struct {
+	Builder              string            `json:"builder,omitempty"`    // kaniko (default) or buildkit
+	Context              string            `json:"context,omitempty"`    // directory under the workdir; default the workdir
+	Dockerfile           string            `json:"dockerfile,omitempty"` // relative to the context; default Dockerfile
+	Image                string            `json:"image"`                // repository without tag, e.g. ghcr.io/team/app
+	Tags                 []string          `json:"tags,omitempty"`       // default ${RUN_NUMBER}
+	BuildArgs            map[string]string `json:"buildArgs,omitempty"`
+	Target               string            `json:"target,omitempty"` // multi-stage build target
+	RegistryCredentialID string            `json:"registryCredentialId,omitempty"`
+	Cache                bool              `json:"cache,omitempty"` // cache layers in the image's registry
+}
+
+// SourceSpec is a Git checkout made into the job's workdir before its script
+// runs. Repo and Branch may reference pipeline variables as ${NAME}.
+type SourceSpec struct {
+	Repo         string `json:"repo"`
+	Branch       string `json:"branch,omitempty"`       // branch or tag; the default branch when empty
+	CredentialID string `json:"credentialId,omitempty"` // Git credential to clone with
+	Depth        int    `json:"depth,omitempty"`        // shallow clone depth; 0 clones the full history
+	Submodules   bool   `json:"submodules,omitempty"`
+	Path         string `json:"path,omitempty"` // directory under the workdir to clone into
 }
 
 // EnvVar represents an environment variable
@@ -81,11 +183,12 @@ type ArtifactSpec struct {
 
 // PipelineStatus holds runtime status info
 type PipelineStatus struct {
-	WebhookURL   string     `json:"webhook_url,omitempty"`
-	WebhookToken string     `json:"webhook_token,omitempty"`
-	LastRunID    string     `json:"last_run_id,omitempty"`
-	LastRunAt    *time.Time `json:"last_run_at,omitempty"`
-	TotalRuns    int        `json:"total_runs"`
+	WebhookURL    string         `json:"webhook_url,omitempty"`
+	WebhookToken  string         `json:"webhook_token,omitempty"`  // primary token, the one in WebhookURL
+	WebhookTokens []WebhookToken `json:"webhook_tokens,omitempty"` // every valid token, including the primary
+	LastRunID     string         `json:"last_run_id,omitempty"`
+	LastRunAt     *time.Time     `json:"last_run_at,omitempty"`
+	TotalRuns     int            `json:"total_runs"`
 }
 
 // RunStatus represents the status of a pipeline run
@@ -117,11 +220,45 @@ type PipelineRun struct {
 	Duration     int64             `json:"duration_ms,omitempty"`
 	Error        string            `json:"error,omitempty"`
 	CreatedAt    time.Time         `json:"created_at"`
+	// ConcurrencyGroup is set for runs of a pipeline with concurrency
+	// limits; Queued is true while the run waits for a free slot
+	ConcurrencyGroup string `json:"concurrency_group,omitempty"`
+	Queued           bool   `json:"queued,omitempty"`
+	// RerunOf is the run this one re-runs
+	RerunOf string `json:"rerun_of,omitempty"`
+	// RollbackOf is the deployment a rollback run deploys again
+	RollbackOf string `json:"rollback_of,omitempty"`
+
+	// secrets are the decrypted secret variables of the pipeline while the
+	// run executes; masker hides them and the stored credentials in its
+	// logs. Neither is stored.
+	secrets map[string]string
+	masker  *logMasker
 }
 
 // JobRun represents a single job execution within a run
 type JobRun struct {
-	Name       string     `json:"name"`
+	Name       string       `json:"name"`
+	Status     RunStatus    `json:"status"`
+	K8sJobName string       `json:"k8s_job_name,omitempty"`
+	K8sPodName string       `json:"k8s_pod_name,omitempty"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	Duration   int64        `json:"duration_ms,omitempty"`
+	ExitCode   int          `json:"exit_code,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	Attempt    int          `json:"attempt,omitempty"`  // current or last attempt, from 1
+	Attempts   []JobAttempt `json:"attempts,omitempty"` // every attempt of a job with retries
+	ReusedFrom string       `json:"reused_from,omitempty"` // run whose result was reused instead of executing the job
+	LogPath    string       `json:"log_path,omitempty"`    // kept logs of the current or last attempt, a file or s3:// URL
+	Images     []string     `json:"images,omitempty"`      // image references a build-image job pushes
+	Host       string       `json:"host,omitempty"`        // SSH host of a job with runsOn: ssh/<group>
+}
+
+// JobAttempt is one attempt of a job with retries. Each attempt runs its own
+// K8s Job, so the logs of failed attempts stay available.
+type JobAttempt struct {
+	Attempt    int        `json:"attempt"`
 	Status     RunStatus  `json:"status"`
 	K8sJobName string     `json:"k8s_job_name,omitempty"`
 	K8sPodName string     `json:"k8s_pod_name,omitempty"`
@@ -130,6 +267,8 @@ type JobRun struct {
 	Duration   int64      `json:"duration_ms,omitempty"`
 	ExitCode   int        `json:"exit_code,omitempty"`
 	Error      string     `json:"error,omitempty"`
+	LogPath    string     `json:"log_path,omitempty"`
+	Host       string     `json:"host,omitempty"`
 }
 
 // ArtifactResult represents a collected artifact
@@ -177,29 +316,100 @@ type SpecYAML struct {
 	Variables map[string]string     `yaml:"variables,omitempty"`
 	Jobs      []JobYAML             `yaml:"jobs"`
 	Artifacts []ArtifactSpecYAML    `yaml:"artifacts,omitempty"`
+	Concurrency *ConcurrencyYAML    `yaml:"concurrency,omitempty"`
+	CommitStatus *CommitStatusYAML  `yaml:"commitStatus,omitempty"`
+	Coverage    *CoverageYAML       `yaml:"coverage,omitempty"`
+	Retention   *RetentionYAML      `yaml:"retention,omitempty"`
+}
+
+// RetentionYAML for the retention of a pipeline's runs
+type RetentionYAML struct {
+	KeepRuns       int `yaml:"keepRuns,omitempty"`
+	MaxAgeDays     int `yaml:"maxAgeDays,omitempty"`
+	MaxArtifactsMB int `yaml:"maxArtifactsMB,omitempty"`
+}
+
+// CoverageYAML for the coverage gate of a pipeline
+type CoverageYAML struct {
+	Minimum float64 `yaml:"minimum,omitempty"`
+	MaxDrop float64 `yaml:"maxDrop,omitempty"`
+}
+
+// CommitStatusYAML for a pipeline's commit status reporting
+type CommitStatusYAML struct {
+	CredentialID string `yaml:"credentialId"`
+	Provider     string `yaml:"provider,omitempty"`
+	APIURL       string `yaml:"apiUrl,omitempty"`
+	Repository   string `yaml:"repository,omitempty"`
+	Commit       string `yaml:"commit,omitempty"`
+	Context      string `yaml:"context,omitempty"`
+	TargetURL    string `yaml:"targetUrl,omitempty"`
+}
+
+// ConcurrencyYAML for the concurrency limits of a pipeline
+type ConcurrencyYAML struct {
+	Group string `yaml:"group,omitempty"`
+	Max   int    `yaml:"max,omitempty"`
+	Mode  string `yaml:"mode,omitempty"`
 }
 
 // TriggerYAML for trigger definition
 type TriggerYAML struct {
-	Type     string `yaml:"type"`
-	Secret   string `yaml:"secret,omitempty"`
-	Schedule string `yaml:"schedule,omitempty"`
-	Enabled  *bool  `yaml:"enabled,omitempty"`
+	Type         string   `yaml:"type"`
+	Secret       string   `yaml:"secret,omitempty"`
+	Schedule     string   `yaml:"schedule,omitempty"`
+	Enabled      *bool    `yaml:"enabled,omitempty"`
+	Branches     []string `yaml:"branches,omitempty"`
+	Repo         string   `yaml:"repo,omitempty"`
+	Branch       string   `yaml:"branch,omitempty"`
+	CredentialID string   `yaml:"credentialId,omitempty"`
 }
 
 // JobYAML for job definition
 type JobYAML struct {
-	Name       string            `yaml:"name"`
-	Image      string            `yaml:"image"`
-	Workdir    string            `yaml:"workdir,omitempty"`
-	Script     string            `yaml:"script"`
-	Env        []EnvVarYAML      `yaml:"env,omitempty"`
-	Secrets    []SecretMountYAML `yaml:"secrets,omitempty"`
-	Resources  ResourceSpecYAML  `yaml:"resources,omitempty"`
-	Timeout    int               `yaml:"timeout,omitempty"`
-	Privileged bool              `yaml:"privileged,omitempty"`
-	DependsOn  []string          `yaml:"dependsOn,omitempty"`
-	SkipIf     string            `yaml:"skipIf,omitempty"`
+	Name        string            `yaml:"name"`
+	Image       string            `yaml:"image"`
+	Workdir     string            `yaml:"workdir,omitempty"`
+	Script      string            `yaml:"script"`
+	Env         []EnvVarYAML      `yaml:"env,omitempty"`
+	Secrets     []SecretMountYAML `yaml:"secrets,omitempty"`
+	Resources   ResourceSpecYAML  `yaml:"resources,omitempty"`
+	Timeout     int               `yaml:"timeout,omitempty"`
+	Privileged  bool              `yaml:"privileged,omitempty"`
+	DependsOn   []string          `yaml:"dependsOn,omitempty"`
+	SkipIf      string            `yaml:"skipIf,omitempty"`
+	Retries     int               `yaml:"retries,omitempty"`
+	RetryDelay  int               `yaml:"retryDelay,omitempty"`
+	Source      *SourceYAML       `yaml:"source,omitempty"`
+	Kind        string            `yaml:"kind,omitempty"`
+	Build       *BuildImageYAML   `yaml:"build,omitempty"`
+	RunsOn      string            `yaml:"runsOn,omitempty"`
+	Environment string            `yaml:"environment,omitempty"`
+	Uses        string            `yaml:"uses,omitempty"`
+	With        map[string]string `yaml:"with,omitempty"`
+}
+
+// BuildImageYAML for a build-image job's build section
+type BuildImageYAML struct {
+	Builder              string            `yaml:"builder,omitempty"`
+	Context              string            `yaml:"context,omitempty"`
+	Dockerfile           string            `yaml:"dockerfile,omitempty"`
+	Image                string            `yaml:"image"`
+	Tags                 []string          `yaml:"tags,omitempty"`
+	BuildArgs            map[string]string `yaml:"buildArgs,omitempty"`
+	Target               string            `yaml:"target,omitempty"`
+	RegistryCredentialID string            `yaml:"registryCredentialId,omitempty"`
+	Cache                bool              `yaml:"cache,omitempty"`
+}
+
+// SourceYAML for a job's source checkout
+type SourceYAML struct {
+	Repo         string `yaml:"repo"`
+	Branch       string `yaml:"branch,omitempty"`
+	CredentialID string `yaml:"credentialId,omitempty"`
+	Depth        int    `yaml:"depth,omitempty"`
+	Submodules   bool   `yaml:"submodules,omitempty"`
+	Path         string `yaml:"path,omitempty"`
 }
 
 // EnvVarYAML for env var
@@ -260,6 +470,16 @@ type TriggerPipelineRequest struct {
 	Variables map[string]string `json:"variables,omitempty"`
 }
 
+// RerunRequest is the optional body of a re-run
+type RerunRequest struct {
+	FailedOnly bool `json:"failed_only,omitempty"` // only execute the jobs that did not succeed
+}
+
+// RollbackRequest is the optional body of an environment rollback
+type RollbackRequest struct {
+	DeploymentID string `json:"deployment_id,omitempty"` // deployment to go back to; default the one before the current
+}
+
 type TriggerPipelineResponse struct {
 	RunID     string `json:"run_id"`
 	RunNumber int    `json:"run_number"`
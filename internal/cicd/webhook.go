@@ -5,13 +5,17 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
-// WebhookPayload represents incoming webhook data
+// WebhookPayload represents incoming webhook data, sent as is or parsed from
+// a Git provider's event by ParseWebhookPayload
 type WebhookPayload struct {
 	Ref       string            `json:"ref,omitempty"`
 	Branch    string            `json:"branch,omitempty"`
@@ -19,10 +23,24 @@ type WebhookPayload struct {
 	Message   string            `json:"message,omitempty"`
 	Author    string            `json:"author,omitempty"`
 	Variables map[string]string `json:"variables,omitempty"`
+
+	Provider     string   `json:"-"` // github, gitlab or gitea
+	Event        string   `json:"-"` // push, tag or pull_request
+	Tag          string   `json:"-"`
+	BaseBranch   string   `json:"-"` // target branch of a pull request
+	PullRequest  int      `json:"-"` // pull or merge request number
+	Repository   string   `json:"-"` // clone URL
+	ChangedFiles []string `json:"-"`
+	Ignored      string   `json:"-"` // why the event does not trigger a run
 }
 
-// HandleWebhook processes an incoming webhook request
-func HandleWebhook(pipelineID, token string, payload *WebhookPayload, signature string) (*PipelineRun, error) {
+// ErrWebhookIgnored is returned for webhook events that do not trigger a run,
+// such as a ping or a push to a branch the trigger filters out
+var ErrWebhookIgnored = errors.New("webhook ignored")
+
+// HandleWebhook processes an incoming webhook request from ip. body is the
+// raw request body, which the signature is checked against.
+func HandleWebhook(pipelineID, token string, payload *WebhookPayload, body []byte, signature, ip string) (*PipelineRun, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -32,9 +50,24 @@ func HandleWebhook(pipelineID, token string, payload *WebhookPayload, signature
 		return nil, fmt.Errorf("pipeline not found: %w", err)
 	}
 
-	// Verify token
-	if pipeline.Status.WebhookToken != token {
-		return nil, fmt.Errorf("invalid webhook token")
+	// Verify the token and its branch scope, recording the use either way
+	branch := ""
+	if payload != nil {
+		branch = payload.Branch
+		if branch == "" {
+			branch = branchFromRef(payload.Ref)
+		}
+	}
+	now := time.Now()
+	webhookToken, err := pipelineTokenSet(pipeline).match(token, branch, now)
+	if webhookToken == nil {
+		return nil, err
+	}
+	if useErr := recordWebhookTokenUse(WebhookKindPipeline, pipeline.ID, webhookToken, ip, now); useErr != nil {
+		log.Warn().Err(useErr).Str("pipeline", pipeline.Name).Msg("Failed to record webhook token use")
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if webhook trigger is enabled
@@ -54,43 +87,39 @@ func HandleWebhook(pipelineID, token string, payload *WebhookPayload, signature
 
 	// Verify signature if secret is set
 	if webhookTrigger.Secret != "" && signature != "" {
-		if !verifySignature(signature, webhookTrigger.Secret, payload) {
+		if !verifySignature(signature, webhookTrigger.Secret, body) {
 			return nil, fmt.Errorf("invalid webhook signature")
 		}
 	}
 
+	if payload == nil {
+		payload = &WebhookPayload{}
+	}
+	if payload.Ignored != "" {
+		return nil, fmt.Errorf("%w: %s", ErrWebhookIgnored, payload.Ignored)
+	}
+	if !webhookTrigger.matchesBranch(payload) {
+		return nil, fmt.Errorf("%w: %s is not in the trigger's branches", ErrWebhookIgnored, payload.filterBranch())
+	}
+
 	// Build trigger ref
 	triggerRef := "webhook"
-	if payload != nil {
-		if payload.Ref != "" {
-			triggerRef = "webhook:" + payload.Ref
-		} else if payload.Branch != "" {
-			triggerRef = "webhook:" + payload.Branch
-		}
-		if payload.Commit != "" {
-			triggerRef += "@" + payload.Commit[:8]
-		}
+	if payload.Ref != "" {
+		triggerRef = "webhook:" + payload.Ref
+	} else if payload.Branch != "" {
+		triggerRef = "webhook:" + payload.Branch
+	}
+	if payload.Commit != "" {
+		triggerRef += "@" + shortSHA(payload.Commit)
 	}
 
-	// Merge variables
+	// Merge variables; those describing the event win over sent ones
 	vars := make(map[string]string)
-	if payload != nil && payload.Variables != nil {
-		for k, v := range payload.Variables {
-			vars[k] = v
-		}
-		// Add webhook-specific variables
-		if payload.Ref != "" {
-			vars["WEBHOOK_REF"] = payload.Ref
-		}
-		if payload.Branch != "" {
-			vars["WEBHOOK_BRANCH"] = payload.Branch
-		}
-		if payload.Commit != "" {
-			vars["WEBHOOK_COMMIT"] = payload.Commit
-		}
-		if payload.Author != "" {
-			vars["WEBHOOK_AUTHOR"] = payload.Author
-		}
+	for k, v := range payload.Variables {
+		vars[k] = v
+	}
+	for k, v := range payload.variables() {
+		vars[k] = v
 	}
 
 	log.Info().
@@ -114,38 +143,65 @@ func HandleWebhook(pipelineID, token string, payload *WebhookPayload, signature
 	return run, nil
 }
 
-// verifySignature verifies HMAC-SHA256 signature
-func verifySignature(signature, secret string, payload *WebhookPayload) bool {
-	// GitHub-style: sha256=<signature>
-	if len(signature) > 7 && signature[:7] == "sha256=" {
-		signature = signature[7:]
+// verifySignature checks the signature of a webhook body: a hex
+// HMAC-SHA256 of the body (GitHub and GAGOS with a sha256= prefix, Gitea
+// without) or the secret itself, which GitLab sends as X-Gitlab-Token
+func verifySignature(signature, secret string, body []byte) bool {
+	if hmac.Equal([]byte(signature), []byte(secret)) {
+		return true
 	}
+	signature = strings.TrimPrefix(signature, "sha256=")
 
-	// Create HMAC
 	mac := hmac.New(sha256.New, []byte(secret))
-	// For simplicity, we just verify the secret matches
-	// In production, you'd hash the actual request body
-	mac.Write([]byte(secret))
+	mac.Write(body)
 	expected := hex.EncodeToString(mac.Sum(nil))
+	if hmac.Equal([]byte(signature), []byte(expected)) {
+		return true
+	}
 
-	return hmac.Equal([]byte(signature), []byte(expected))
+	// Signatures made before bodies were signed were an HMAC of the secret
+	mac = hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(secret))
+	return hmac.Equal([]byte(signature), []byte(hex.EncodeToString(mac.Sum(nil))))
 }
 
-// RegenerateWebhookToken regenerates the webhook token for a pipeline
-func RegenerateWebhookToken(pipelineID string) (string, error) {
-	pipeline, err := GetPipeline(pipelineID)
-	if err != nil {
-		return "", err
+// filterBranch is the branch the trigger's branch filters are matched
+// against: the target branch of a pull request, else the pushed branch
+func (p *WebhookPayload) filterBranch() string {
+	if p.BaseBranch != "" {
+		return p.BaseBranch
 	}
+	if p.Branch != "" {
+		return p.Branch
+	}
+	if p.Tag != "" {
+		return "tag " + p.Tag
+	}
+	return branchFromRef(p.Ref)
+}
 
-	newToken := generateToken()
-	pipeline.Status.WebhookToken = newToken
-	pipeline.Status.WebhookURL = fmt.Sprintf("/api/v1/cicd/webhooks/%s/%s", pipelineID, newToken)
-	pipeline.UpdatedAt = time.Now()
-
-	if err := SavePipeline(pipeline); err != nil {
-		return "", err
+// matchesBranch reports whether the trigger's branch filters let the event
+// through. Tag pushes carry no branch and only pass without filters.
+func (t *Trigger) matchesBranch(p *WebhookPayload) bool {
+	if len(t.Branches) == 0 {
+		return true
+	}
+	if p.Tag != "" {
+		return false
 	}
+	branch := p.filterBranch()
+	for _, pattern := range t.Branches {
+		if ok, _ := path.Match(pattern, branch); ok && branch != "" {
+			return true
+		}
+	}
+	return false
+}
 
-	return newToken, nil
+// shortSHA abbreviates a commit SHA for display
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
 }
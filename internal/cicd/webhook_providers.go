@@ -0,0 +1,313 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Webhook providers whose payloads are understood
+const (
+	WebhookProviderGitHub = "github"
+	WebhookProviderGitLab = "gitlab"
+	WebhookProviderGitea  = "gitea"
+)
+
+// Webhook events a pipeline is triggered by
+const (
+	WebhookEventPush        = "push"
+	WebhookEventTag         = "tag"
+	WebhookEventPullRequest = "pull_request"
+)
+
+// zeroSHA is the commit a push that deletes a branch or tag goes to
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// providerCommit is a commit in a push payload; GitHub, GitLab and Gitea
+// share its shape
+type providerCommit struct {
+	ID       string   `json:"id"`
+	Message  string   `json:"message"`
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+	Author   struct {
+		Name     string `json:"name"`
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+// githubPush is a GitHub or Gitea push event
+type githubPush struct {
+	Ref        string           `json:"ref"`
+	After      string           `json:"after"`
+	Deleted    bool             `json:"deleted"`
+	HeadCommit *providerCommit  `json:"head_commit"`
+	Commits    []providerCommit `json:"commits"`
+	Pusher     struct {
+		Name     string `json:"name"`
+		Login    string `json:"login"`
+		Username string `json:"username"`
+	} `json:"pusher"`
+	Repository struct {
+		CloneURL string `json:"clone_url"`
+	} `json:"repository"`
+}
+
+// githubPullRequest is a GitHub or Gitea pull request event
+type githubPullRequest struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Title string `json:"title"`
+		Head  struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"pull_request"`
+	Repository struct {
+		CloneURL string `json:"clone_url"`
+	} `json:"repository"`
+}
+
+// gitlabEvent is a GitLab push, tag push or merge request event
+type gitlabEvent struct {
+	ObjectKind   string           `json:"object_kind"`
+	Ref          string           `json:"ref"`
+	After        string           `json:"after"`
+	CheckoutSHA  string           `json:"checkout_sha"`
+	UserName     string           `json:"user_name"`
+	UserUsername string           `json:"user_username"`
+	Commits      []providerCommit `json:"commits"`
+	User         struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		GitHTTPURL string `json:"git_http_url"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		IID          int    `json:"iid"`
+		Title        string `json:"title"`
+		Action       string `json:"action"`
+		SourceBranch string `json:"source_branch"`
+		TargetBranch string `json:"target_branch"`
+		OldRev       string `json:"oldrev"`
+		LastCommit   struct {
+			ID      string `json:"id"`
+			Message string `json:"message"`
+		} `json:"last_commit"`
+	} `json:"object_attributes"`
+}
+
+// ParseWebhookPayload reads a webhook request body. Push and pull request
+// events of GitHub, GitLab and Gitea are recognized by their event header;
+// any other body is read as a plain WebhookPayload. Events that should not
+// trigger a run, such as a ping or a closed pull request, come back with
+// Ignored set.
+func ParseWebhookPayload(header func(string) string, body []byte) (*WebhookPayload, error) {
+	var provider, event string
+	switch {
+	case header("X-Gitea-Event") != "":
+		provider, event = WebhookProviderGitea, header("X-Gitea-Event")
+	case header("X-Gitlab-Event") != "":
+		provider, event = WebhookProviderGitLab, header("X-Gitlab-Event")
+	case header("X-GitHub-Event") != "":
+		provider, event = WebhookProviderGitHub, header("X-GitHub-Event")
+	default:
+		// The body of a generic webhook is optional
+		payload := &WebhookPayload{}
+		if len(body) > 0 {
+			json.Unmarshal(body, payload)
+		}
+		return payload, nil
+	}
+
+	var payload *WebhookPayload
+	var err error
+	switch {
+	case provider == WebhookProviderGitLab:
+		payload, err = parseGitLabEvent(body)
+	case event == "push":
+		payload, err = parseGitHubPush(body)
+	case event == "pull_request":
+		payload, err = parseGitHubPullRequest(body)
+	default:
+		payload = &WebhookPayload{Ignored: fmt.Sprintf("%s event %s does not trigger runs", provider, event)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %s payload: %w", provider, event, err)
+	}
+	payload.Provider = provider
+	return payload, nil
+}
+
+func parseGitHubPush(body []byte) (*WebhookPayload, error) {
+	var push githubPush
+	if err := json.Unmarshal(body, &push); err != nil {
+		return nil, err
+	}
+	payload := refPayload(push.Ref, push.After)
+	if push.Deleted || push.After == zeroSHA {
+		payload.Ignored = "push deletes " + push.Ref
+		return payload, nil
+	}
+	payload.Repository = push.Repository.CloneURL
+	payload.ChangedFiles = changedFiles(push.Commits)
+	payload.Author = firstNonEmpty(push.Pusher.Login, push.Pusher.Username, push.Pusher.Name)
+	if head := push.HeadCommit; head != nil {
+		payload.Message = strings.TrimSpace(head.Message)
+		payload.Author = firstNonEmpty(head.Author.Username, head.Author.Name, payload.Author)
+	}
+	return payload, nil
+}
+
+func parseGitHubPullRequest(body []byte) (*WebhookPayload, error) {
+	var event githubPullRequest
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	pr := event.PullRequest
+	payload := &WebhookPayload{
+		Event:       WebhookEventPullRequest,
+		Ref:         fmt.Sprintf("refs/pull/%d/head", event.Number),
+		Branch:      pr.Head.Ref,
+		BaseBranch:  pr.Base.Ref,
+		Commit:      pr.Head.SHA,
+		Author:      pr.User.Login,
+		Message:     pr.Title,
+		PullRequest: event.Number,
+		Repository:  event.Repository.CloneURL,
+	}
+	switch event.Action {
+	case "opened", "reopened", "synchronize", "synchronized":
+	default:
+		payload.Ignored = "pull request action " + event.Action + " does not trigger runs"
+	}
+	return payload, nil
+}
+
+func parseGitLabEvent(body []byte) (*WebhookPayload, error) {
+	var event gitlabEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+
+	if event.ObjectKind == "merge_request" {
+		mr := event.ObjectAttributes
+		payload := &WebhookPayload{
+			Event:       WebhookEventPullRequest,
+			Ref:         fmt.Sprintf("refs/merge-requests/%d/head", mr.IID),
+			Branch:      mr.SourceBranch,
+			BaseBranch:  mr.TargetBranch,
+			Commit:      mr.LastCommit.ID,
+			Author:      event.User.Username,
+			Message:     mr.Title,
+			PullRequest: mr.IID,
+			Repository:  event.Project.GitHTTPURL,
+		}
+		switch {
+		case mr.Action == "open" || mr.Action == "reopen":
+		case mr.Action == "update" && mr.OldRev != "":
+			// An update with oldrev brought new commits; without it only
+			// the title, labels or the like changed
+		default:
+			payload.Ignored = "merge request action " + mr.Action + " does not trigger runs"
+		}
+		return payload, nil
+	}
+
+	if event.ObjectKind != "push" && event.ObjectKind != "tag_push" {
+		return &WebhookPayload{Ignored: "gitlab event " + event.ObjectKind + " does not trigger runs"}, nil
+	}
+	commit := firstNonEmpty(event.CheckoutSHA, event.After)
+	payload := refPayload(event.Ref, commit)
+	if commit == "" || commit == zeroSHA {
+		payload.Ignored = "push deletes " + event.Ref
+		return payload, nil
+	}
+	payload.Repository = event.Project.GitHTTPURL
+	payload.ChangedFiles = changedFiles(event.Commits)
+	payload.Author = firstNonEmpty(event.UserUsername, event.UserName)
+	for _, c := range event.Commits {
+		if c.ID == commit {
+			payload.Message = strings.TrimSpace(c.Message)
+			payload.Author = firstNonEmpty(c.Author.Name, payload.Author)
+		}
+	}
+	return payload, nil
+}
+
+// refPayload is the payload of a push to ref, a branch or a tag
+func refPayload(ref, commit string) *WebhookPayload {
+	payload := &WebhookPayload{Event: WebhookEventPush, Ref: ref, Commit: commit}
+	if tag, ok := strings.CutPrefix(ref, "refs/tags/"); ok {
+		payload.Event = WebhookEventTag
+		payload.Tag = tag
+	} else {
+		payload.Branch = branchFromRef(ref)
+	}
+	return payload
+}
+
+// changedFiles lists the files added, modified or removed by commits
+func changedFiles(commits []providerCommit) []string {
+	seen := map[string]bool{}
+	var files []string
+	for _, c := range commits {
+		for _, list := range [][]string{c.Added, c.Modified, c.Removed} {
+			for _, f := range list {
+				if !seen[f] {
+					seen[f] = true
+					files = append(files, f)
+				}
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// variables returns the run variables describing the webhook event
+func (p *WebhookPayload) variables() map[string]string {
+	vars := map[string]string{}
+	set := func(name, value string) {
+		if value != "" {
+			vars[name] = value
+		}
+	}
+	set("WEBHOOK_PROVIDER", p.Provider)
+	set("WEBHOOK_EVENT", p.Event)
+	set("WEBHOOK_REF", p.Ref)
+	set("WEBHOOK_BRANCH", p.Branch)
+	set("WEBHOOK_TAG", p.Tag)
+	set("WEBHOOK_BASE_BRANCH", p.BaseBranch)
+	set("WEBHOOK_COMMIT", p.Commit)
+	set("WEBHOOK_AUTHOR", p.Author)
+	set("WEBHOOK_MESSAGE", p.Message)
+	set("WEBHOOK_REPOSITORY", p.Repository)
+	set("WEBHOOK_CHANGED_FILES", strings.Join(p.ChangedFiles, "\n"))
+	if p.PullRequest > 0 {
+		vars["WEBHOOK_PR_NUMBER"] = strconv.Itoa(p.PullRequest)
+	}
+	return vars
+}
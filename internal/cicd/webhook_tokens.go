@@ -0,0 +1,446 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaga951/gagos/internal/storage"
+)
+
+// A pipeline or freestyle job can have several webhook tokens. Each may be
+// limited to some branches and records when it was last used, so that a
+// leaked token can be traced and replaced. Rotating a token issues a new one
+// and keeps the old one valid for a grace window, long enough to update the
+// Git host. The primary token is the one in the webhook URL shown in the UI.
+//
+// Token use is kept in its own bucket, one record per token, and merged into
+// the list when it is read. Saving the whole pipeline or job on every call
+// would lose counts to concurrent calls and could overwrite a spec edit made
+// in the meantime.
+
+// Owners of webhook tokens
+const (
+	WebhookKindPipeline  = "pipeline"
+	WebhookKindFreestyle = "freestyle"
+)
+
+// legacyWebhookTokenID is the ID of the token of a webhook created before
+// token lists, until it is saved with one
+const legacyWebhookTokenID = "wht-default"
+
+var (
+	errInvalidWebhookToken = errors.New("invalid webhook token")
+	errWebhookTokenScope   = errors.New("webhook token is not allowed for this branch")
+)
+
+// WebhookToken is one token that can call a webhook
+type WebhookToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name,omitempty"`
+	Token      string     `json:"token"`
+	URL        string     `json:"url"`
+	Branches   []string   `json:"branches,omitempty"` // glob patterns; empty allows every branch
+	Primary    bool       `json:"primary,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // end of the grace window of a rotated token
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	UseCount   int        `json:"use_count"`
+}
+
+// WebhookTokenRequest is the body for creating or rotating a token
+type WebhookTokenRequest struct {
+	Name     string   `json:"name"`
+	Branches []string `json:"branches"`
+	Primary  bool     `json:"primary"`
+	// GraceSeconds is how long a rotated token stays valid; default
+	// GAGOS_WEBHOOK_TOKEN_GRACE_HOURS, 0 revokes it at once
+	GraceSeconds *int `json:"grace_seconds,omitempty"`
+}
+
+// WebhookTokenGrace is how long a rotated token keeps working
+// (GAGOS_WEBHOOK_TOKEN_GRACE_HOURS, default 24)
+func WebhookTokenGrace() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("GAGOS_WEBHOOK_TOKEN_GRACE_HOURS"))
+	if err != nil || hours < 0 {
+		hours = 24
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// webhookTokenSet is the token list of a pipeline or freestyle job
+type webhookTokenSet struct {
+	primary  *string // the token in the webhook URL
+	url      *string
+	tokens   *[]WebhookToken
+	generate func() string
+	urlFor   func(token string) string
+	created  time.Time // when the primary of a webhook without a token list was made
+}
+
+func pipelineTokenSet(p *Pipeline) webhookTokenSet {
+	return webhookTokenSet{
+		primary:  &p.Status.WebhookToken,
+		url:      &p.Status.WebhookURL,
+		tokens:   &p.Status.WebhookTokens,
+		generate: generateToken,
+		urlFor: func(token string) string {
+			return fmt.Sprintf("/api/v1/cicd/webhooks/%s/%s", p.ID, token)
+		},
+		created: p.CreatedAt,
+	}
+}
+
+func freestyleTokenSet(job *FreestyleJob) webhookTokenSet {
+	return webhookTokenSet{
+		primary:  &job.Status.WebhookToken,
+		url:      &job.Status.WebhookURL,
+		tokens:   &job.Status.WebhookTokens,
+		generate: func() string { return generateID("wh") },
+		urlFor: func(token string) string {
+			return fmt.Sprintf("/api/v1/cicd/freestyle/webhook/%s", token)
+		},
+		created: job.CreatedAt,
+	}
+}
+
+// sync brings the list in line with the primary token: webhooks created
+// before token lists have only the primary, and a cleared primary (webhook
+// trigger turned off) clears the list. Expired tokens are dropped.
+func (s webhookTokenSet) sync(now time.Time) {
+	if *s.primary == "" {
+		*s.tokens = nil
+		return
+	}
+	found := false
+	kept := (*s.tokens)[:0]
+	for _, t := range *s.tokens {
+		if t.ExpiresAt != nil && now.After(*t.ExpiresAt) {
+			continue
+		}
+		t.Primary = t.Token == *s.primary
+		found = found || t.Primary
+		kept = append(kept, t)
+	}
+	*s.tokens = kept
+	if !found {
+		*s.tokens = append(*s.tokens, WebhookToken{
+			ID:        legacyWebhookTokenID,
+			Name:      "default",
+			Token:     *s.primary,
+			URL:       s.urlFor(*s.primary),
+			Primary:   true,
+			CreatedAt: s.created,
+		})
+	}
+}
+
+// match returns the token matching token, checked against branch
+func (s webhookTokenSet) match(token, branch string, now time.Time) (*WebhookToken, error) {
+	s.sync(now)
+	for i := range *s.tokens {
+		t := &(*s.tokens)[i]
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) != 1 {
+			continue
+		}
+		if !t.allows(branch) {
+			return t, errWebhookTokenScope
+		}
+		return t, nil
+	}
+	return nil, errInvalidWebhookToken
+}
+
+// allows reports whether the token may trigger a build of branch
+func (t *WebhookToken) allows(branch string) bool {
+	if len(t.Branches) == 0 {
+		return true
+	}
+	for _, pattern := range t.Branches {
+		if ok, _ := path.Match(pattern, branch); ok && branch != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookTokenUse is the stored use of one token
+type webhookTokenUse struct {
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	UseCount   int        `json:"use_count"`
+}
+
+// webhookUseMu serialises the read-modify-write of use records
+var webhookUseMu sync.Mutex
+
+func webhookUseKey(kind, ownerID, tokenID string) string {
+	return kind + "/" + ownerID + "/" + tokenID
+}
+
+// recordWebhookTokenUse records a call made with token t of a pipeline or
+// freestyle job. A token without a record yet starts from the use saved in
+// its owner before records were kept apart.
+func recordWebhookTokenUse(kind, ownerID string, t *WebhookToken, ip string, now time.Time) error {
+	webhookUseMu.Lock()
+	defer webhookUseMu.Unlock()
+
+	key := webhookUseKey(kind, ownerID, t.ID)
+	use := webhookTokenUse{LastUsedAt: t.LastUsedAt, LastUsedIP: t.LastUsedIP, UseCount: t.UseCount}
+	data, err := storage.GetBackend().Get(storage.BucketWebhookTokenUse, key)
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, &use); err != nil {
+			return fmt.Errorf("failed to unmarshal webhook token use: %w", err)
+		}
+	}
+	use.LastUsedAt = &now
+	use.LastUsedIP = ip
+	use.UseCount++
+
+	data, err = json.Marshal(use)
+	if err != nil {
+		return err
+	}
+	return storage.GetBackend().Set(storage.BucketWebhookTokenUse, key, data)
+}
+
+// withWebhookTokenUse fills in the recorded use of each token
+func withWebhookTokenUse(kind, ownerID string, tokens []WebhookToken) {
+	for i := range tokens {
+		data, err := storage.GetBackend().Get(storage.BucketWebhookTokenUse, webhookUseKey(kind, ownerID, tokens[i].ID))
+		if err != nil || data == nil {
+			continue
+		}
+		var use webhookTokenUse
+		if json.Unmarshal(data, &use) == nil {
+			tokens[i].LastUsedAt, tokens[i].LastUsedIP, tokens[i].UseCount = use.LastUsedAt, use.LastUsedIP, use.UseCount
+		}
+	}
+}
+
+// pruneWebhookTokenUse deletes the use records of an owner's tokens that are
+// not in tokens; nil deletes them all
+func pruneWebhookTokenUse(kind, ownerID string, tokens []WebhookToken) {
+	keep := map[string]bool{}
+	for _, t := range tokens {
+		keep[webhookUseKey(kind, ownerID, t.ID)] = true
+	}
+	keys, err := storage.GetBackend().ListKeys(storage.BucketWebhookTokenUse)
+	if err != nil {
+		return
+	}
+	prefix := webhookUseKey(kind, ownerID, "")
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) && !keep[key] {
+			storage.GetBackend().Delete(storage.BucketWebhookTokenUse, key)
+		}
+	}
+}
+
+func (s webhookTokenSet) add(req *WebhookTokenRequest, now time.Time) (*WebhookToken, error) {
+	for _, pattern := range req.Branches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid branch pattern %q", pattern)
+		}
+	}
+	token := s.generate()
+	*s.tokens = append(*s.tokens, WebhookToken{
+		ID:        generateID("wht"),
+		Name:      strings.TrimSpace(req.Name),
+		Token:     token,
+		URL:       s.urlFor(token),
+		Branches:  req.Branches,
+		CreatedAt: now,
+	})
+	if req.Primary {
+		s.setPrimary(token)
+	}
+	return s.find(token), nil
+}
+
+func (s webhookTokenSet) setPrimary(token string) {
+	*s.primary = token
+	*s.url = s.urlFor(token)
+	for i := range *s.tokens {
+		(*s.tokens)[i].Primary = (*s.tokens)[i].Token == token
+	}
+}
+
+func (s webhookTokenSet) find(token string) *WebhookToken {
+	for i := range *s.tokens {
+		if (*s.tokens)[i].Token == token {
+			return &(*s.tokens)[i]
+		}
+	}
+	return nil
+}
+
+func (s webhookTokenSet) index(id string) int {
+	for i, t := range *s.tokens {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// rotate replaces the token id with a new one of the same name and scope.
+// The old token expires after grace.
+func (s webhookTokenSet) rotate(id string, grace time.Duration, now time.Time) (*WebhookToken, error) {
+	i := s.index(id)
+	if i < 0 {
+		return nil, fmt.Errorf("webhook token not found: %s", id)
+	}
+	old := (*s.tokens)[i]
+	if old.ExpiresAt != nil {
+		return nil, fmt.Errorf("webhook token %s was already rotated", id)
+	}
+	created, err := s.add(&WebhookTokenRequest{Name: old.Name, Branches: old.Branches, Primary: old.Primary}, now)
+	if err != nil {
+		return nil, err
+	}
+	newToken := created.Token
+
+	i = s.index(id)
+	if grace <= 0 {
+		*s.tokens = append((*s.tokens)[:i], (*s.tokens)[i+1:]...)
+	} else {
+		expires := now.Add(grace)
+		(*s.tokens)[i].ExpiresAt = &expires
+	}
+	return s.find(newToken), nil
+}
+
+// revoke deletes the token id. The primary token can only be replaced by
+// rotating it, so that the webhook always has one.
+func (s webhookTokenSet) revoke(id string) error {
+	i := s.index(id)
+	if i < 0 {
+		return fmt.Errorf("webhook token not found: %s", id)
+	}
+	if (*s.tokens)[i].Primary {
+		return fmt.Errorf("the primary webhook token cannot be revoked; rotate it instead")
+	}
+	*s.tokens = append((*s.tokens)[:i], (*s.tokens)[i+1:]...)
+	return nil
+}
+
+// loadWebhookTokens loads the tokens of a pipeline or freestyle job, with a
+// function that saves them back
+func loadWebhookTokens(kind, id string) (webhookTokenSet, func() error, error) {
+	switch kind {
+	case WebhookKindPipeline:
+		pipeline, err := GetPipeline(id)
+		if err != nil {
+			return webhookTokenSet{}, nil, err
+		}
+		if pipeline.Status.WebhookToken == "" {
+			return webhookTokenSet{}, nil, fmt.Errorf("pipeline has no webhook trigger")
+		}
+		save := func() error {
+			pipeline.UpdatedAt = time.Now()
+			return SavePipeline(pipeline)
+		}
+		return pipelineTokenSet(pipeline), save, nil
+	case WebhookKindFreestyle:
+		job, err := GetFreestyleJob(id)
+		if err != nil {
+			return webhookTokenSet{}, nil, err
+		}
+		if job.Status.WebhookToken == "" {
+			return webhookTokenSet{}, nil, fmt.Errorf("job has no webhook trigger")
+		}
+		save := func() error {
+			job.UpdatedAt = time.Now()
+			return saveFreestyleJob(job)
+		}
+		return freestyleTokenSet(job), save, nil
+	}
+	return webhookTokenSet{}, nil, fmt.Errorf("unknown webhook kind: %s", kind)
+}
+
+// ListWebhookTokens returns the webhook tokens of a pipeline or freestyle job
+func ListWebhookTokens(kind, id string) ([]WebhookToken, error) {
+	set, _, err := loadWebhookTokens(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	set.sync(time.Now())
+	withWebhookTokenUse(kind, id, *set.tokens)
+	return *set.tokens, nil
+}
+
+// CreateWebhookToken adds a token to a pipeline or freestyle job
+func CreateWebhookToken(kind, id string, req *WebhookTokenRequest) (*WebhookToken, error) {
+	set, save, err := loadWebhookTokens(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	set.sync(now)
+	token, err := set.add(req, now)
+	if err != nil {
+		return nil, err
+	}
+	created := *token
+	if err := save(); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// RotateWebhookToken replaces a token; the old one stays valid for grace
+func RotateWebhookToken(kind, id, tokenID string, grace time.Duration) (*WebhookToken, error) {
+	set, save, err := loadWebhookTokens(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	set.sync(now)
+	token, err := set.rotate(tokenID, grace, now)
+	if err != nil {
+		return nil, err
+	}
+	rotated := *token
+	if err := save(); err != nil {
+		return nil, err
+	}
+	pruneWebhookTokenUse(kind, id, *set.tokens)
+	return &rotated, nil
+}
+
+// RevokeWebhookToken deletes a token that is not the primary one
+func RevokeWebhookToken(kind, id, tokenID string) error {
+	set, save, err := loadWebhookTokens(kind, id)
+	if err != nil {
+		return err
+	}
+	set.sync(time.Now())
+	if err := set.revoke(tokenID); err != nil {
+		return err
+	}
+	if err := save(); err != nil {
+		return err
+	}
+	pruneWebhookTokenUse(kind, id, *set.tokens)
+	return nil
+}
+
+// branchFromRef returns the branch of a Git ref such as refs/heads/main
+func branchFromRef(ref string) string {
+	return strings.TrimPrefix(ref, "refs/heads/")
+}
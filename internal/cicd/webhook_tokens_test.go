@@ -0,0 +1,121 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package cicd
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gaga951/gagos/internal/storage"
+)
+
+// webhookUse returns the use count of a token as listed
+func webhookUse(t *testing.T, kind, id, token string) int {
+	t.Helper()
+	tokens, err := ListWebhookTokens(kind, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, wt := range tokens {
+		if wt.Token == token {
+			return wt.UseCount
+		}
+	}
+	t.Fatalf("token %s not listed", token)
+	return 0
+}
+
+func TestWebhookTokenUse(t *testing.T) {
+	testStorage(t)
+
+	// The webhook trigger is off, so calls only record the token use
+	pipeline := &Pipeline{ID: "p1", Name: "deploy", CreatedAt: time.Now()}
+	pipeline.Status.WebhookToken = "pipeline-token"
+	if err := SavePipeline(pipeline); err != nil {
+		t.Fatal(err)
+	}
+	job := &FreestyleJob{ID: "j1", Name: "build", CreatedAt: time.Now()}
+	job.Status.WebhookToken = "job-token"
+	if err := saveFreestyleJob(job); err != nil {
+		t.Fatal(err)
+	}
+
+	// Recording a use leaves the pipeline and the job as they were saved
+	before, _ := storage.GetBackend().Get(storage.BucketPipelines, "p1")
+	beforeJob, _ := storage.GetBackend().Get(storage.BucketFreestyleJobs, "j1")
+	HandleWebhook("p1", "pipeline-token", nil, nil, "", "10.0.0.1")
+	if _, err := UseFreestyleWebhookToken("job-token", "", "10.0.0.2"); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := storage.GetBackend().Get(storage.BucketPipelines, "p1")
+	afterJob, _ := storage.GetBackend().Get(storage.BucketFreestyleJobs, "j1")
+	if !bytes.Equal(before, after) || !bytes.Equal(beforeJob, afterJob) {
+		t.Error("recording a webhook token use rewrote its owner")
+	}
+
+	// Concurrent calls all count, and none overwrites an edit saved meanwhile
+	const calls = 50
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-start
+			HandleWebhook("p1", "pipeline-token", nil, nil, "", "10.0.0.1")
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, err := UseFreestyleWebhookToken("job-token", "", "10.0.0.2"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	close(start)
+	for i := 0; i < calls; i++ {
+		edited, err := GetPipeline("p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		edited.Description = fmt.Sprintf("edit %d", i)
+		if err := SavePipeline(edited); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	if got := webhookUse(t, WebhookKindPipeline, "p1", "pipeline-token"); got != calls+1 {
+		t.Errorf("pipeline token used %d times, want %d", got, calls+1)
+	}
+	if got := webhookUse(t, WebhookKindFreestyle, "j1", "job-token"); got != calls+1 {
+		t.Errorf("job token used %d times, want %d", got, calls+1)
+	}
+	if p, _ := GetPipeline("p1"); p.Description != fmt.Sprintf("edit %d", calls-1) {
+		t.Errorf("pipeline edit lost: description %q", p.Description)
+	}
+
+	// A token without a record keeps the use saved with it
+	pipeline, _ = GetPipeline("p1")
+	pipeline.Status.WebhookTokens = []WebhookToken{{ID: "wht-old", Token: "old-token", UseCount: 7}}
+	pipeline.Status.WebhookToken = "old-token"
+	if err := SavePipeline(pipeline); err != nil {
+		t.Fatal(err)
+	}
+	HandleWebhook("p1", "old-token", nil, nil, "", "10.0.0.1")
+	if got := webhookUse(t, WebhookKindPipeline, "p1", "old-token"); got != 8 {
+		t.Errorf("saved token used %d times, want 8", got)
+	}
+
+	// Replacing a token drops its record
+	if _, err := RotateWebhookToken(WebhookKindPipeline, "p1", "wht-old", 0); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := storage.GetBackend().Get(storage.BucketWebhookTokenUse, webhookUseKey(WebhookKindPipeline, "p1", "wht-old")); data != nil {
+		t.Error("use of a revoked token kept")
+	}
+}
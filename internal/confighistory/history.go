@@ -0,0 +1,367 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+// Package confighistory keeps the revisions of ConfigMaps and Secrets
+// edited through GAGOS, so that an edit can be reviewed and rolled back.
+// Secret revisions are stored encrypted.
+package confighistory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gaga951/gagos/internal/cicd"
+	"github.com/gaga951/gagos/internal/k8s"
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// Where a revision came from
+const (
+	SourceObserved = "observed" // the object as found before an edit, e.g. changed outside GAGOS
+	SourceEdit     = "edit"
+	SourceRollback = "rollback"
+)
+
+// Key change operations
+const (
+	KeyAdded   = "added"
+	KeyRemoved = "removed"
+	KeyChanged = "changed"
+)
+
+// ErrRevisionNotFound is returned for a revision that is not in the history
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Revision is the content of a ConfigMap or Secret at one point
+type Revision struct {
+	Kind      string            `json:"kind"` // configmap or secret
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	Revision  int               `json:"revision"`
+	Time      time.Time         `json:"time"`
+	Source    string            `json:"source"`
+	IP        string            `json:"ip,omitempty"`
+	Keys      []string          `json:"keys"`
+	Data      map[string]string `json:"data,omitempty"`   // ConfigMap data
+	Binary    map[string][]byte `json:"binary,omitempty"` // ConfigMap binaryData
+	Encrypted string            `json:"encrypted,omitempty"`
+}
+
+// RevisionSummary describes a revision without its values
+type RevisionSummary struct {
+	Revision int       `json:"revision"`
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	IP       string    `json:"ip,omitempty"`
+	Keys     int       `json:"keys"`
+	Added    []string  `json:"added"` // against the revision before
+	Removed  []string  `json:"removed"`
+	Changed  []string  `json:"changed"`
+}
+
+// KeyChange is one key that differs between two versions. Old and New are
+// empty when the values are redacted.
+type KeyChange struct {
+	Key      string `json:"key"`
+	Op       string `json:"op"`
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+	Redacted bool   `json:"redacted,omitempty"`
+}
+
+// RevisionDiff is what rolling back to a revision would change on the live object
+type RevisionDiff struct {
+	Kind      string      `json:"kind"`
+	Namespace string      `json:"namespace"`
+	Name      string      `json:"name"`
+	Revision  int         `json:"revision"`
+	Identical bool        `json:"identical"`
+	Changes   []KeyChange `json:"changes"`
+}
+
+// Limit is how many revisions are kept per object
+// (GAGOS_CONFIG_HISTORY_LIMIT, default 20)
+func Limit() int {
+	n, err := strconv.Atoi(os.Getenv("GAGOS_CONFIG_HISTORY_LIMIT"))
+	if err != nil || n <= 0 {
+		return 20
+	}
+	return n
+}
+
+// Snapshot stores the live content of the object as a new revision, unless
+// it is the same as the latest one. Returns the new revision, or nil when
+// nothing changed.
+func Snapshot(ctx context.Context, kind, namespace, name, source, ip string) (*Revision, error) {
+	data, err := k8s.GetConfigData(ctx, kind, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := load(kind, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	next := 1
+	if n := len(revisions); n > 0 {
+		latest := revisions[n-1]
+		if prev, err := latest.content(); err == nil && sameData(prev, data) {
+			return nil, nil
+		}
+		next = latest.Revision + 1
+	}
+
+	rev := &Revision{
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+		Revision:  next,
+		Time:      time.Now(),
+		Source:    source,
+		IP:        ip,
+		Keys:      data.Keys(),
+	}
+	if kind == "secret" {
+		plain, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		if rev.Encrypted, err = cicd.Encrypt(string(plain)); err != nil {
+			return nil, fmt.Errorf("failed to encrypt secret revision: %w", err)
+		}
+	} else {
+		rev.Data, rev.Binary = data.Data, data.Binary
+	}
+
+	raw, err := json.Marshal(rev)
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.GetBackend().Set(storage.BucketConfigHistory, revisionKey(kind, namespace, name, next), raw); err != nil {
+		return nil, err
+	}
+
+	// Drop the oldest revisions past the limit
+	for i := 0; i < len(revisions)+1-Limit(); i++ {
+		old := revisions[i]
+		if err := storage.GetBackend().Delete(storage.BucketConfigHistory, revisionKey(kind, namespace, name, old.Revision)); err != nil {
+			log.Warn().Err(err).Str("kind", kind).Str("namespace", namespace).Str("name", name).Int("revision", old.Revision).Msg("Failed to prune config revision")
+		}
+	}
+	return rev, nil
+}
+
+// List returns the revisions of an object, newest first, with the keys each
+// changed
+func List(kind, namespace, name string) ([]RevisionSummary, error) {
+	revisions, err := load(kind, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]RevisionSummary, 0, len(revisions))
+	var prev *k8s.ConfigData
+	for _, rev := range revisions {
+		s := RevisionSummary{
+			Revision: rev.Revision,
+			Time:     rev.Time,
+			Source:   rev.Source,
+			IP:       rev.IP,
+			Keys:     len(rev.Keys),
+			Added:    []string{},
+			Removed:  []string{},
+			Changed:  []string{},
+		}
+		data, err := rev.content()
+		if err == nil && prev != nil {
+			for _, c := range diffData(prev, data, true) {
+				switch c.Op {
+				case KeyAdded:
+					s.Added = append(s.Added, c.Key)
+				case KeyRemoved:
+					s.Removed = append(s.Removed, c.Key)
+				case KeyChanged:
+					s.Changed = append(s.Changed, c.Key)
+				}
+			}
+		}
+		prev = data
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Revision > summaries[j].Revision
+	})
+	return summaries, nil
+}
+
+// Diff compares the live object with a revision: the changes are what a
+// rollback would do. Secret values are only included with reveal.
+func Diff(ctx context.Context, kind, namespace, name string, revision int, reveal bool) (*RevisionDiff, error) {
+	rev, err := get(kind, namespace, name, revision)
+	if err != nil {
+		return nil, err
+	}
+	target, err := rev.content()
+	if err != nil {
+		return nil, err
+	}
+	live, err := k8s.GetConfigData(ctx, kind, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	changes := diffData(live, target, kind == "secret" && !reveal)
+	return &RevisionDiff{
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+		Revision:  revision,
+		Identical: len(changes) == 0,
+		Changes:   changes,
+	}, nil
+}
+
+// Rollback sets the object's content back to a revision. The live content
+// is snapshotted first so the rollback itself can be undone. Returns the
+// revision the rollback created, or nil when the object already matched.
+func Rollback(ctx context.Context, kind, namespace, name string, revision int, ip string) (*Revision, error) {
+	rev, err := get(kind, namespace, name, revision)
+	if err != nil {
+		return nil, err
+	}
+	target, err := rev.content()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Snapshot(ctx, kind, namespace, name, SourceObserved, ip); err != nil {
+		return nil, err
+	}
+	if err := k8s.ReplaceConfigData(ctx, kind, namespace, name, target); err != nil {
+		return nil, err
+	}
+	return Snapshot(ctx, kind, namespace, name, SourceRollback, ip)
+}
+
+// content returns the data a revision holds, decrypting a Secret's
+func (r *Revision) content() (*k8s.ConfigData, error) {
+	if r.Encrypted == "" {
+		return &k8s.ConfigData{Data: r.Data, Binary: r.Binary}, nil
+	}
+	plain, err := cicd.Decrypt(r.Encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt revision %d (was GAGOS_ENCRYPTION_KEY changed?): %w", r.Revision, err)
+	}
+	var data k8s.ConfigData
+	if err := json.Unmarshal([]byte(plain), &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func revisionKey(kind, namespace, name string, revision int) string {
+	return fmt.Sprintf("%s/%s/%s/%08d", kind, namespace, name, revision)
+}
+
+// load returns the stored revisions of an object, oldest first
+func load(kind, namespace, name string) ([]Revision, error) {
+	keys, err := storage.GetBackend().ListKeys(storage.BucketConfigHistory)
+	if err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("%s/%s/%s/", kind, namespace, name)
+	var revisions []Revision
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		raw, err := storage.GetBackend().Get(storage.BucketConfigHistory, key)
+		if err != nil || raw == nil {
+			continue
+		}
+		var rev Revision
+		if err := json.Unmarshal(raw, &rev); err != nil {
+			continue
+		}
+		revisions = append(revisions, rev)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+func get(kind, namespace, name string, revision int) (*Revision, error) {
+	raw, err := storage.GetBackend().Get(storage.BucketConfigHistory, revisionKey(kind, namespace, name, revision))
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("%w: %s %s/%s revision %d", ErrRevisionNotFound, kind, namespace, name, revision)
+	}
+	var rev Revision
+	if err := json.Unmarshal(raw, &rev); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func sameData(a, b *k8s.ConfigData) bool {
+	return len(diffData(a, b, true)) == 0
+}
+
+// diffData lists the keys that differ from a to b
+func diffData(a, b *k8s.ConfigData, redact bool) []KeyChange {
+	keys := map[string]bool{}
+	for _, k := range a.Keys() {
+		keys[k] = true
+	}
+	for _, k := range b.Keys() {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	changes := []KeyChange{}
+	for _, key := range sorted {
+		old, inA := a.Value(key)
+		cur, inB := b.Value(key)
+		c := KeyChange{Key: key, Redacted: redact}
+		switch {
+		case !inA:
+			c.Op = KeyAdded
+		case !inB:
+			c.Op = KeyRemoved
+		case !bytes.Equal(old, cur):
+			c.Op = KeyChanged
+		default:
+			continue
+		}
+		if !redact {
+			c.Old, c.New = printable(old, inA), printable(cur, inB)
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// printable shows binary values as a size rather than raw bytes
+func printable(v []byte, ok bool) string {
+	if !ok {
+		return ""
+	}
+	if !utf8.Valid(v) {
+		return fmt.Sprintf("<binary, %d bytes>", len(v))
+	}
+	return string(v)
+}
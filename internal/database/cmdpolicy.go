@@ -0,0 +1,170 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Commands the Redis and Elasticsearch passthrough handlers refuse unless the
+// caller is elevated. Both lists can be replaced through the environment.
+
+// defaultRedisDeny lists commands, or "COMMAND SUBCOMMAND" pairs, that wipe
+// data, reconfigure or stop the server. EVAL and friends are included because
+// a script can call any of the others.
+var defaultRedisDeny = []string{
+	"FLUSHALL", "FLUSHDB", "SWAPDB", "SHUTDOWN", "DEBUG", "MODULE", "MONITOR",
+	"SLAVEOF", "REPLICAOF", "MIGRATE", "CLUSTER RESET", "CLUSTER FAILOVER",
+	"CONFIG SET", "CONFIG REWRITE", "CONFIG RESETSTAT",
+	"ACL SETUSER", "ACL DELUSER", "ACL LOAD",
+	"SCRIPT FLUSH", "FUNCTION FLUSH", "FUNCTION DELETE", "FUNCTION RESTORE",
+	"EVAL", "EVALSHA", "EVAL_RO", "EVALSHA_RO", "FCALL", "FCALL_RO",
+}
+
+// defaultESDeny lists "METHOD /path" globs (path.Match syntax, * matches one
+// segment). Index targets containing a wildcard are rewritten to _all before
+// matching, so "/_all" covers "*", "logs-*" and "a,b*" alike.
+var defaultESDeny = []string{
+	"DELETE /_all",
+	"POST /_all/_delete_by_query",
+	"POST /_all/_update_by_query",
+	"POST /_all/_close",
+	"PUT /_cluster/settings",
+}
+
+// defaultRedisKeysMax is the DBSIZE above which KEYS is refused
+const defaultRedisKeysMax = 10000
+
+type esRule struct {
+	method string
+	path   string
+}
+
+var (
+	cmdPolicyOnce sync.Once
+	redisDeny     map[string]bool
+	redisKeysMax  int64
+	esDeny        []esRule
+)
+
+// loadCmdPolicy reads GAGOS_REDIS_DENY_COMMANDS, GAGOS_REDIS_KEYS_MAX and
+// GAGOS_ES_DENY_REQUESTS (comma separated); an empty deny list variable keeps
+// the default
+func loadCmdPolicy() {
+	cmdPolicyOnce.Do(func() {
+		redisDeny = make(map[string]bool)
+		for _, cmd := range envList("GAGOS_REDIS_DENY_COMMANDS", defaultRedisDeny) {
+			redisDeny[strings.ToUpper(strings.Join(strings.Fields(cmd), " "))] = true
+		}
+
+		redisKeysMax = defaultRedisKeysMax
+		if n, err := strconv.ParseInt(os.Getenv("GAGOS_REDIS_KEYS_MAX"), 10, 64); err == nil && n >= 0 {
+			redisKeysMax = n
+		}
+
+		for _, rule := range envList("GAGOS_ES_DENY_REQUESTS", defaultESDeny) {
+			fields := strings.Fields(rule)
+			if len(fields) != 2 {
+				continue
+			}
+			esDeny = append(esDeny, esRule{method: strings.ToUpper(fields[0]), path: fields[1]})
+		}
+	})
+}
+
+func envList(key string, def []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	var list []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// CheckRedisCommand returns an ErrElevationRequired error when command is on
+// the deny list, or is KEYS on a database holding more than
+// GAGOS_REDIS_KEYS_MAX keys (use SCAN there instead)
+func CheckRedisCommand(ctx context.Context, config RedisConfig, command string) error {
+	loadCmdPolicy()
+
+	parts := strings.Fields(command)
+	if len(parts) == 0 {
+		return nil
+	}
+	name := strings.ToUpper(parts[0])
+	if redisDeny[name] {
+		return fmt.Errorf("%w for Redis command %s", ErrElevationRequired, name)
+	}
+	if len(parts) > 1 {
+		if sub := name + " " + strings.ToUpper(parts[1]); redisDeny[sub] {
+			return fmt.Errorf("%w for Redis command %s", ErrElevationRequired, sub)
+		}
+	}
+
+	if name == "KEYS" {
+		if redisKeysMax == 0 {
+			return fmt.Errorf("%w for KEYS, use SCAN instead", ErrElevationRequired)
+		}
+		client := newRedisClient(config)
+		defer client.Close()
+
+		size, err := client.DBSize(ctx).Result()
+		if err != nil {
+			return fmt.Errorf("KEYS refused, could not check DBSIZE: %w", err)
+		}
+		if size > redisKeysMax {
+			return fmt.Errorf("%w for KEYS on a database with %d keys (limit %d), use SCAN instead",
+				ErrElevationRequired, size, redisKeysMax)
+		}
+	}
+	return nil
+}
+
+// CheckESRequest returns an ErrElevationRequired error when method and
+// requestPath match a deny rule
+func CheckESRequest(method, requestPath string) error {
+	loadCmdPolicy()
+
+	method = strings.ToUpper(method)
+	normalized := normalizeESPath(requestPath)
+	for _, rule := range esDeny {
+		if rule.method != "*" && rule.method != method {
+			continue
+		}
+		if ok, _ := path.Match(rule.path, normalized); ok {
+			return fmt.Errorf("%w for %s %s", ErrElevationRequired, method, requestPath)
+		}
+	}
+	return nil
+}
+
+// normalizeESPath drops the query string, decodes escapes, resolves dot
+// segments and rewrites a wildcard index target to _all
+func normalizeESPath(p string) string {
+	if i := strings.IndexByte(p, '?'); i >= 0 {
+		p = p[:i]
+	}
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		p = unescaped
+	}
+	p = path.Clean("/" + p)
+
+	segments := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	if target := segments[0]; target == "_all" || (strings.Contains(target, "*") && !strings.HasPrefix(target, "_")) {
+		segments[0] = "_all"
+	}
+	return "/" + strings.Join(segments, "/")
+}
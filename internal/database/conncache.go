@@ -0,0 +1,399 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Connection tests are cached per profile, one per distinct connection
+// config, so repeated "connect" clicks do not hit the target every time.
+// Profiles are found by a hash of the config but published under a random
+// ID, so the ID reveals nothing about the credentials and changes once the
+// profile is dropped. A
+// background revalidator re-tests recently used profiles to keep their
+// health current. Credentials stay in memory only until the profile has been
+// idle for GAGOS_CONN_IDLE_TIMEOUT.
+
+// Profile health states
+const (
+	HealthHealthy   = "healthy"
+	HealthDegraded  = "degraded"  // failing, but succeeded within the last few checks
+	HealthUnhealthy = "unhealthy" // failing repeatedly or never succeeded
+)
+
+// unhealthyAfter is the number of consecutive failures that turns a
+// previously working profile from degraded to unhealthy
+const unhealthyAfter = 3
+
+// ConnectionStatus is the health of one connection profile
+type ConnectionStatus struct {
+	ID                  string     `json:"id"`
+	Kind                string     `json:"kind"`
+	Name                string     `json:"name"`
+	Health              string     `json:"health"`
+	LastChecked         time.Time  `json:"last_checked"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	ResponseTime        float64    `json:"response_time_ms,omitempty"`
+}
+
+// connResult is implemented by every *ConnectionResult type
+type connResult interface {
+	connOutcome() (ok bool, errMsg string, responseTime float64)
+}
+
+func (r PostgresConnectionResult) connOutcome() (bool, string, float64) {
+	return r.Success, r.Error, r.ResponseTime
+}
+
+func (r MySQLConnectionResult) connOutcome() (bool, string, float64) {
+	return r.Success, r.Error, r.ResponseTime
+}
+
+func (r MSSQLConnectionResult) connOutcome() (bool, string, float64) {
+	return r.Success, r.Error, r.ResponseTime
+}
+
+func (r OracleConnectionResult) connOutcome() (bool, string, float64) {
+	return r.Success, r.Error, r.ResponseTime
+}
+
+func (r RedisConnectionResult) connOutcome() (bool, string, float64) {
+	return r.Success, r.Error, r.ResponseTime
+}
+
+func (r ESConnectionResult) connOutcome() (bool, string, float64) {
+	return r.Success, r.Error, r.ResponseTime
+}
+
+func (r S3ConnectionResult) connOutcome() (bool, string, float64) {
+	return r.Success, r.Error, r.ResponseTime
+}
+
+type connProfile struct {
+	hash     string // of the kind and config, the key of profileIDs
+	status   ConnectionStatus
+	config   interface{} // the tested config, credentials included
+	result   connResult
+	test     func(ctx context.Context) connResult
+	lastUsed time.Time
+	inflight chan struct{} // closed when the running test finishes
+}
+
+var (
+	profiles   = make(map[string]*connProfile) // by ID
+	profileIDs = make(map[string]string)       // config hash -> ID
+	profilesMu sync.Mutex
+)
+
+func envDuration(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d >= 0 {
+		return d
+	}
+	return def
+}
+
+// connCacheTTL is how long a test result is served from cache (GAGOS_CONN_CACHE_TTL, default 30s)
+func connCacheTTL() time.Duration {
+	return envDuration("GAGOS_CONN_CACHE_TTL", 30*time.Second)
+}
+
+// connIdleTimeout is how long an unused profile is kept (GAGOS_CONN_IDLE_TIMEOUT, default 30m)
+func connIdleTimeout() time.Duration {
+	return envDuration("GAGOS_CONN_IDLE_TIMEOUT", 30*time.Minute)
+}
+
+// configHash identifies a config, credentials included. It stays in memory
+// and is never published.
+func configHash(kind string, config interface{}) string {
+	b, _ := json.Marshal(config)
+	sum := sha256.Sum256(append([]byte(kind+"\x00"), b...))
+	return hex.EncodeToString(sum[:])
+}
+
+// newProfileID returns a random profile ID
+func newProfileID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// cachedConnectionTest returns the cached result for the profile when it is
+// younger than the TTL and fresh is not set, otherwise runs test. Concurrent
+// callers for the same profile share one test.
+func cachedConnectionTest[T connResult](ctx context.Context, kind, name string, config interface{}, fresh bool, test func(ctx context.Context) T) (result T, id string, cached bool) {
+	hash := configHash(kind, config)
+
+	profilesMu.Lock()
+	id = profileIDs[hash]
+	p := profiles[id]
+	if p == nil {
+		id = newProfileID()
+		p = &connProfile{
+			hash:   hash,
+			status: ConnectionStatus{ID: id, Kind: kind, Name: name},
+			config: config,
+			test:   func(ctx context.Context) connResult { return test(ctx) },
+		}
+		profiles[id] = p
+		profileIDs[hash] = id
+	}
+	p.lastUsed = time.Now()
+	if !fresh && p.result != nil && time.Since(p.status.LastChecked) < connCacheTTL() {
+		r := p.result.(T)
+		profilesMu.Unlock()
+		return r, id, true
+	}
+	if wait := p.inflight; wait != nil {
+		profilesMu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			var zero T
+			return zero, id, false
+		}
+		profilesMu.Lock()
+		r, _ := p.result.(T)
+		profilesMu.Unlock()
+		return r, id, true
+	}
+	p.inflight = make(chan struct{})
+	profilesMu.Unlock()
+
+	return runConnectionTest(ctx, p).(T), id, false
+}
+
+// runConnectionTest runs p's test and records the outcome. The caller must
+// have set p.inflight while holding profilesMu.
+func runConnectionTest(ctx context.Context, p *connProfile) connResult {
+	result := p.test(ctx)
+	ok, errMsg, responseTime := result.connOutcome()
+
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	now := time.Now()
+	s := &p.status
+	s.LastChecked = now
+	s.ResponseTime = responseTime
+	if ok {
+		s.LastSuccess = &now
+		s.LastError = ""
+		s.ConsecutiveFailures = 0
+		s.Health = HealthHealthy
+	} else {
+		s.LastError = errMsg
+		s.ConsecutiveFailures++
+		if s.LastSuccess != nil && s.ConsecutiveFailures < unhealthyAfter {
+			s.Health = HealthDegraded
+		} else {
+			s.Health = HealthUnhealthy
+		}
+	}
+	p.result = result
+	close(p.inflight)
+	p.inflight = nil
+	return result
+}
+
+// ConnectionStatuses returns the health of every known profile
+func ConnectionStatuses() []ConnectionStatus {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+
+	result := make([]ConnectionStatus, 0, len(profiles))
+	for _, p := range profiles {
+		if p.result != nil {
+			result = append(result, p.status)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// profileConfig returns the config and status of a profile and marks it used
+func profileConfig(id string) (interface{}, ConnectionStatus, bool) {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	p := profiles[id]
+	if p == nil {
+		return nil, ConnectionStatus{}, false
+	}
+	p.lastUsed = time.Now()
+	return p.config, p.status, true
+}
+
+// ConnectionProfile returns the config of a profile, credentials included,
+// for reads that name a profile instead of posting the config again
+func ConnectionProfile(id string) (interface{}, bool) {
+	config, _, ok := profileConfig(id)
+	return config, ok
+}
+
+// ForgetConnection drops a profile and its credentials
+func ForgetConnection(id string) bool {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	p, ok := profiles[id]
+	if ok {
+		delete(profileIDs, p.hash)
+		delete(profiles, id)
+	}
+	return ok
+}
+
+// StartConnectionRevalidator re-tests profiles used within the idle timeout
+// every GAGOS_CONN_REVALIDATE_INTERVAL (default 60s, 0 disables) and drops
+// idle ones
+func StartConnectionRevalidator() {
+	interval := envDuration("GAGOS_CONN_REVALIDATE_INTERVAL", 60*time.Second)
+	if interval == 0 {
+		log.Info().Msg("Connection revalidation disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			revalidateConnections()
+		}
+	}()
+}
+
+func revalidateConnections() {
+	idle := connIdleTimeout()
+
+	profilesMu.Lock()
+	var due []*connProfile
+	for id, p := range profiles {
+		switch {
+		case time.Since(p.lastUsed) > idle:
+			delete(profileIDs, p.hash)
+			delete(profiles, id)
+		default:
+			due = append(due, p)
+		}
+	}
+	profilesMu.Unlock()
+
+	for _, p := range due {
+		// Skip profiles a handler is testing right now
+		profilesMu.Lock()
+		busy := p.inflight != nil
+		if !busy {
+			p.inflight = make(chan struct{})
+		}
+		profilesMu.Unlock()
+		if busy {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		runConnectionTest(ctx, p)
+		cancel()
+	}
+}
+
+// CachedPostgresConnection is TestPostgresConnection behind the profile cache
+func CachedPostgresConnection(ctx context.Context, config PostgresConfig, fresh bool) PostgresConnectionResult {
+	name := fmt.Sprintf("%s@%s/%s", config.User, hostsLabel(config.Host, config.Port, config.Hosts), config.Database)
+	r, id, cached := cachedConnectionTest(ctx, "postgres", name, config, fresh, func(ctx context.Context) PostgresConnectionResult {
+		return TestPostgresConnection(ctx, config)
+	})
+	r.ProfileID, r.Cached = id, cached
+	return r
+}
+
+// CachedMySQLConnection is TestMySQLConnection behind the profile cache
+func CachedMySQLConnection(ctx context.Context, config MySQLConfig, fresh bool) MySQLConnectionResult {
+	name := fmt.Sprintf("%s@%s/%s", config.User, hostsLabel(config.Host, config.Port, config.Hosts), config.Database)
+	r, id, cached := cachedConnectionTest(ctx, "mysql", name, config, fresh, func(ctx context.Context) MySQLConnectionResult {
+		return TestMySQLConnection(ctx, config)
+	})
+	r.ProfileID, r.Cached = id, cached
+	return r
+}
+
+// CachedMSSQLConnection is TestMSSQLConnection behind the profile cache
+func CachedMSSQLConnection(ctx context.Context, config MSSQLConfig, fresh bool) MSSQLConnectionResult {
+	host := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	if config.Instance != "" {
+		host = config.Host + `\` + config.Instance
+	}
+	name := fmt.Sprintf("%s@%s/%s", config.User, host, config.Database)
+	r, id, cached := cachedConnectionTest(ctx, "mssql", name, config, fresh, func(ctx context.Context) MSSQLConnectionResult {
+		return TestMSSQLConnection(ctx, config)
+	})
+	r.ProfileID, r.Cached = id, cached
+	return r
+}
+
+// CachedOracleConnection is TestOracleConnection behind the profile cache
+func CachedOracleConnection(ctx context.Context, config OracleConfig, fresh bool) OracleConnectionResult {
+	service := config.Service
+	if service == "" {
+		service = config.SID
+	}
+	name := fmt.Sprintf("%s@%s/%s", config.User, net.JoinHostPort(config.Host, strconv.Itoa(config.Port)), service)
+	r, id, cached := cachedConnectionTest(ctx, "oracle", name, config, fresh, func(ctx context.Context) OracleConnectionResult {
+		return TestOracleConnection(ctx, config)
+	})
+	r.ProfileID, r.Cached = id, cached
+	return r
+}
+
+// CachedRedisConnection is TestRedisConnection behind the profile cache
+func CachedRedisConnection(ctx context.Context, config RedisConfig, fresh bool) RedisConnectionResult {
+	name := fmt.Sprintf("%s/%d", config.Addr(), config.DB)
+	r, id, cached := cachedConnectionTest(ctx, "redis", name, config, fresh, func(ctx context.Context) RedisConnectionResult {
+		return TestRedisConnection(ctx, config)
+	})
+	r.ProfileID, r.Cached = id, cached
+	return r
+}
+
+// CachedESConnection is TestESConnection behind the profile cache
+func CachedESConnection(ctx context.Context, config ESConfig, fresh bool) ESConnectionResult {
+	name := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	if config.Username != "" {
+		name = config.Username + "@" + name
+	}
+	r, id, cached := cachedConnectionTest(ctx, "elasticsearch", name, config, fresh, func(ctx context.Context) ESConnectionResult {
+		return TestESConnection(ctx, config)
+	})
+	r.ProfileID, r.Cached = id, cached
+	return r
+}
+
+// CachedS3Connection is TestS3Connection behind the profile cache
+func CachedS3Connection(ctx context.Context, config S3Config, fresh bool) S3ConnectionResult {
+	name := config.AccessKeyID + "@" + config.Endpoint
+	r, id, cached := cachedConnectionTest(ctx, "s3", name, config, fresh, func(ctx context.Context) S3ConnectionResult {
+		return TestS3Connection(ctx, config)
+	})
+	r.ProfileID, r.Cached = id, cached
+	return r
+}
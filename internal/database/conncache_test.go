@@ -0,0 +1,48 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestProfileIDs(t *testing.T) {
+	connect := func(config RedisConfig) string {
+		_, id, _ := cachedConnectionTest(context.Background(), "redis", "test", config, false, func(ctx context.Context) RedisConnectionResult {
+			return RedisConnectionResult{Success: true}
+		})
+		return id
+	}
+	config := RedisConfig{Host: "redis.internal", Port: 6379, Password: "hunter2"}
+	id := connect(config)
+	t.Cleanup(func() { ForgetConnection(id) })
+
+	if again := connect(config); again != id {
+		t.Errorf("same config got a new profile %s, want %s", again, id)
+	}
+	if strings.Contains(configHash("redis", config), id) {
+		t.Error("profile ID is derived from the config hash")
+	}
+
+	config.Password = "hunter3"
+	other := connect(config)
+	t.Cleanup(func() { ForgetConnection(other) })
+	if other == id {
+		t.Error("another password reused the profile")
+	}
+
+	// A dropped profile comes back under a new ID
+	config.Password = "hunter2"
+	ForgetConnection(id)
+	if _, ok := ConnectionProfile(id); ok {
+		t.Error("forgotten profile still resolves")
+	}
+	renewed := connect(config)
+	t.Cleanup(func() { ForgetConnection(renewed) })
+	if renewed == id {
+		t.Error("profile ID reused after the profile was dropped")
+	}
+}
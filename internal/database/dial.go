@@ -0,0 +1,56 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/gaga951/gagos/internal/egress"
+	mysqldrv "github.com/go-sql-driver/mysql"
+)
+
+// All database connections are dialed through the egress policy so the
+// database tools cannot be pointed at metadata services or denied ranges.
+
+func init() {
+	mysqldrv.RegisterDialContext("tcp", func(ctx context.Context, addr string) (net.Conn, error) {
+		return egress.Default().DialContext(ctx, "tcp", addr)
+	})
+}
+
+// pqDialer adapts the egress dialer to lib/pq's Dialer and DialerContext
+type pqDialer struct{}
+
+func (pqDialer) Dial(network, address string) (net.Conn, error) {
+	return egress.Default().Dialer(0).Dial(network, address)
+}
+
+func (pqDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	return egress.Default().Dialer(timeout).Dial(network, address)
+}
+
+func (pqDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return egress.Default().DialContext(ctx, network, address)
+}
+
+// mssqlDialer is the go-mssqldb Connector.Dialer
+type mssqlDialer struct{}
+
+func (mssqlDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return egress.Default().DialContext(ctx, network, addr)
+}
+
+// oracleDialer is the go-ora OracleConnector.Dialer
+type oracleDialer struct{}
+
+func (oracleDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return egress.Default().DialContext(ctx, network, addr)
+}
+
+// redisDialer is the go-redis Options.Dialer
+func redisDialer(ctx context.Context, network, addr string) (net.Conn, error) {
+	return egress.Default().DialContext(ctx, network, addr)
+}
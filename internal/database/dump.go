@@ -0,0 +1,64 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// errDumpTooLarge is returned once a dump outgrows dumpMaxBytes
+var errDumpTooLarge = errors.New("dump too large")
+
+// dumpMaxBytes caps dump output held in memory (GAGOS_DUMP_MAX_MB, default 256).
+// Larger databases should be dumped with the native tools directly.
+func dumpMaxBytes() int64 {
+	mb, err := strconv.Atoi(os.Getenv("GAGOS_DUMP_MAX_MB"))
+	if err != nil || mb <= 0 {
+		mb = 256
+	}
+	return int64(mb) << 20
+}
+
+// cappedBuffer is a bytes.Buffer that refuses writes past limit, which makes
+// the dump process exit on a broken pipe instead of growing without bound
+type cappedBuffer struct {
+	bytes.Buffer
+	limit    int64
+	exceeded bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if int64(b.Len()+len(p)) > b.limit {
+		b.exceeded = true
+		return 0, errDumpTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// runDump runs cmd and returns its stdout, failing once it exceeds dumpMaxBytes
+func runDump(cmd *exec.Cmd) ([]byte, error) {
+	out := &cappedBuffer{limit: dumpMaxBytes()}
+	var stderr bytes.Buffer
+	cmd.Stdout = out
+	cmd.Stderr = &stderr
+
+	// The exit status of the killed process wins over the write error, so
+	// check the buffer rather than err
+	err := cmd.Run()
+	if out.exceeded {
+		return nil, fmt.Errorf("output exceeds the %d MB limit", out.limit>>20)
+	}
+	if err != nil {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -7,18 +7,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/gaga951/gagos/internal/egress"
 )
 
 // ESConfig holds Elasticsearch connection configuration
 type ESConfig struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	Username string `json:"username,omitempty"`
-	Password string `json:"password,omitempty"`
-	UseSSL   bool   `json:"use_ssl"`
+	Host     string      `json:"host"`
+	Port     int         `json:"port"`
+	Username string      `json:"username,omitempty"`
+	Password string      `json:"password,omitempty"`
+	UseSSL   bool        `json:"use_ssl"`
+	TLS      *TLSOptions `json:"tls,omitempty"`
 }
 
 // ESConnectionResult holds connection test result
@@ -28,6 +34,8 @@ type ESConnectionResult struct {
 	Version      string  `json:"version,omitempty"`
 	ResponseTime float64 `json:"response_time_ms,omitempty"`
 	Error        string  `json:"error,omitempty"`
+	ProfileID    string  `json:"profile_id,omitempty"`
+	Cached       bool    `json:"cached,omitempty"`
 }
 
 // ESClusterHealth holds cluster health info
@@ -126,22 +134,39 @@ type ESQueryResult struct {
 	Error      string          `json:"error,omitempty"`
 }
 
+// tlsMode is the TLS mode of the connection; use_ssl alone does not verify
+// the server
+func (c *ESConfig) tlsMode() string {
+	legacy := ""
+	if c.UseSSL {
+		legacy = TLSRequire
+	}
+	return c.TLS.mode(legacy)
+}
+
 func (c *ESConfig) baseURL() string {
 	scheme := "http"
-	if c.UseSSL {
+	if c.tlsMode() != TLSDisable {
 		scheme = "https"
 	}
 	return fmt.Sprintf("%s://%s:%d", scheme, c.Host, c.Port)
 }
 
-func (c *ESConfig) newClient() *http.Client {
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+func (c *ESConfig) newClient() (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	if mode := c.tlsMode(); mode != TLSDisable {
+		var err error
+		if tlsConfig, err = c.TLS.clientConfig(mode, c.Host); err != nil {
+			return nil, err
+		}
 	}
+	transport := egress.Default().Transport(&http.Transport{
+		TLSClientConfig: tlsConfig,
+	})
 	return &http.Client{
-		Transport: transport,
+		Transport: guardedTransport("elasticsearch", net.JoinHostPort(c.Host, strconv.Itoa(c.Port)), transport),
 		Timeout:   30 * time.Second,
-	}
+	}, nil
 }
 
 func (c *ESConfig) doRequest(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
@@ -156,7 +181,11 @@ func (c *ESConfig) doRequest(ctx context.Context, method, path string, body io.R
 		req.SetBasicAuth(c.Username, c.Password)
 	}
 
-	return c.newClient().Do(req)
+	client, err := c.newClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
 }
 
 // TestESConnection tests connection to Elasticsearch
@@ -400,7 +429,7 @@ func SearchESDocuments(ctx context.Context, config ESConfig, index, query string
 
 // GetESDocument gets a document by ID
 func GetESDocument(ctx context.Context, config ESConfig, index, id string) (json.RawMessage, error) {
-	resp, err := config.doRequest(ctx, "GET", "/"+index+"/_doc/"+id, nil)
+	resp, err := config.doRequest(ctx, "GET", "/"+url.PathEscape(index)+"/_doc/"+url.PathEscape(id), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -421,7 +450,7 @@ func GetESDocument(ctx context.Context, config ESConfig, index, id string) (json
 
 // DeleteESDocument deletes a document by ID
 func DeleteESDocument(ctx context.Context, config ESConfig, index, id string) error {
-	resp, err := config.doRequest(ctx, "DELETE", "/"+index+"/_doc/"+id, nil)
+	resp, err := config.doRequest(ctx, "DELETE", "/"+url.PathEscape(index)+"/_doc/"+url.PathEscape(id), nil)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,440 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Long-running Elasticsearch operations: reindexing and snapshots run as
+// cluster tasks, so they are started without waiting and followed, or
+// cancelled, through the tasks API.
+
+// esTaskID matches a task ID as the tasks API reports it, node:number
+var esTaskID = regexp.MustCompile(`^[A-Za-z0-9_-]+:[0-9]+$`)
+
+// ESReindexRequest copies documents from one index to another
+type ESReindexRequest struct {
+	Source            string          `json:"source"`
+	Dest              string          `json:"dest"`
+	Query             json.RawMessage `json:"query,omitempty"`     // restricts the documents copied
+	MaxDocs           int             `json:"max_docs,omitempty"`  // 0 copies all
+	Conflicts         string          `json:"conflicts,omitempty"` // abort (default) or proceed
+	OpType            string          `json:"op_type,omitempty"`   // create to skip documents the destination has
+	Slices            int             `json:"slices,omitempty"`
+	RequestsPerSecond float64         `json:"requests_per_second,omitempty"` // throttle, 0 for none
+}
+
+// ESTask is a running cluster task. ID is node:number, as the tasks API
+// expects it back.
+type ESTask struct {
+	ID            string          `json:"id"`
+	Action        string          `json:"action"`
+	Description   string          `json:"description,omitempty"`
+	StartTime     int64           `json:"start_time_in_millis"`
+	RunningTimeMs float64         `json:"running_time_ms"`
+	Cancellable   bool            `json:"cancellable"`
+	Cancelled     bool            `json:"cancelled,omitempty"`
+	Parent        string          `json:"parent_task_id,omitempty"`
+	Status        json.RawMessage `json:"status,omitempty"` // progress, e.g. created and total for a reindex
+}
+
+// ESTaskStatus is a task and, once completed, its response or error
+type ESTaskStatus struct {
+	Completed bool            `json:"completed"`
+	Task      ESTask          `json:"task"`
+	Response  json.RawMessage `json:"response,omitempty"`
+	Error     json.RawMessage `json:"error,omitempty"`
+}
+
+// esTaskJSON is a task as the tasks API returns it
+type esTaskJSON struct {
+	Node        string          `json:"node"`
+	ID          int64           `json:"id"`
+	Action      string          `json:"action"`
+	Description string          `json:"description"`
+	StartTime   int64           `json:"start_time_in_millis"`
+	RunningTime int64           `json:"running_time_in_nanos"`
+	Cancellable bool            `json:"cancellable"`
+	Cancelled   bool            `json:"cancelled"`
+	Parent      string          `json:"parent_task_id"`
+	Status      json.RawMessage `json:"status"`
+}
+
+func (t esTaskJSON) task() ESTask {
+	return ESTask{
+		ID:            fmt.Sprintf("%s:%d", t.Node, t.ID),
+		Action:        t.Action,
+		Description:   t.Description,
+		StartTime:     t.StartTime,
+		RunningTimeMs: float64(t.RunningTime) / 1e6,
+		Cancellable:   t.Cancellable,
+		Cancelled:     t.Cancelled,
+		Parent:        t.Parent,
+		Status:        t.Status,
+	}
+}
+
+// ESSnapshotRepository is a registered snapshot repository
+type ESSnapshotRepository struct {
+	Name     string          `json:"name"`
+	Type     string          `json:"type"` // fs, s3, gcs, azure, url, ...
+	Settings json.RawMessage `json:"settings"`
+}
+
+// ESSnapshot is one snapshot of a repository
+type ESSnapshot struct {
+	Snapshot           string   `json:"snapshot"`
+	UUID               string   `json:"uuid"`
+	State              string   `json:"state"` // IN_PROGRESS, SUCCESS, PARTIAL, FAILED
+	Indices            []string `json:"indices"`
+	IncludeGlobalState bool     `json:"include_global_state"`
+	StartTime          string   `json:"start_time,omitempty"`
+	EndTime            string   `json:"end_time,omitempty"`
+	DurationMs         int64    `json:"duration_in_millis"`
+	Shards             struct {
+		Total      int `json:"total"`
+		Failed     int `json:"failed"`
+		Successful int `json:"successful"`
+	} `json:"shards"`
+}
+
+// ESSnapshotRequest creates a snapshot. Empty Indices takes every index.
+type ESSnapshotRequest struct {
+	Indices            []string `json:"indices,omitempty"`
+	IncludeGlobalState bool     `json:"include_global_state"`
+	Partial            bool     `json:"partial,omitempty"` // snapshot indices with unavailable shards anyway
+}
+
+// ESRestoreRequest restores indices from a snapshot. Restoring onto an open
+// index fails, so either close or delete it first or rename on the way in.
+type ESRestoreRequest struct {
+	Indices            []string `json:"indices,omitempty"`
+	RenamePattern      string   `json:"rename_pattern,omitempty"`     // regex on index names, e.g. (.+)
+	RenameReplacement  string   `json:"rename_replacement,omitempty"` // e.g. restored-$1
+	IncludeGlobalState bool     `json:"include_global_state"`
+	IncludeAliases     *bool    `json:"include_aliases,omitempty"` // default true
+}
+
+// esCall sends body as JSON and decodes a 2xx response into out, either of
+// which may be nil
+func (c *ESConfig) esCall(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	resp, err := c.doRequest(ctx, method, path, reader)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func checkESName(what, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s is required", what)
+	}
+	if strings.ContainsAny(name, "/\\?#*,\" <>|") {
+		return fmt.Errorf("invalid %s %q", what, name)
+	}
+	return nil
+}
+
+func checkESTaskID(id string) error {
+	if !esTaskID.MatchString(id) {
+		return fmt.Errorf("invalid task id %q: expected node:number", id)
+	}
+	return nil
+}
+
+// StartESReindex starts a reindex task and returns its ID
+func StartESReindex(ctx context.Context, config ESConfig, req ESReindexRequest) (string, error) {
+	if req.Source == "" || req.Dest == "" {
+		return "", fmt.Errorf("source and dest are required")
+	}
+	if req.Source == req.Dest {
+		return "", fmt.Errorf("source and dest must differ")
+	}
+	if err := checkESName("dest index", req.Dest); err != nil {
+		return "", err
+	}
+	switch req.Conflicts {
+	case "", "abort", "proceed":
+	default:
+		return "", fmt.Errorf("conflicts must be abort or proceed")
+	}
+	switch req.OpType {
+	case "", "index", "create":
+	default:
+		return "", fmt.Errorf("op_type must be index or create")
+	}
+
+	source := map[string]interface{}{"index": strings.Split(req.Source, ",")}
+	if len(req.Query) > 0 {
+		source["query"] = req.Query
+	}
+	dest := map[string]interface{}{"index": req.Dest}
+	if req.OpType != "" {
+		dest["op_type"] = req.OpType
+	}
+	body := map[string]interface{}{"source": source, "dest": dest}
+	if req.Conflicts != "" {
+		body["conflicts"] = req.Conflicts
+	}
+	if req.MaxDocs > 0 {
+		body["max_docs"] = req.MaxDocs
+	}
+
+	params := url.Values{"wait_for_completion": {"false"}}
+	if req.Slices > 0 {
+		params.Set("slices", fmt.Sprint(req.Slices))
+	}
+	if req.RequestsPerSecond > 0 {
+		params.Set("requests_per_second", fmt.Sprint(req.RequestsPerSecond))
+	}
+
+	var result struct {
+		Task string `json:"task"`
+	}
+	if err := config.esCall(ctx, "POST", "/_reindex?"+params.Encode(), body, &result); err != nil {
+		return "", err
+	}
+	return result.Task, nil
+}
+
+// ListESTasks returns the running tasks whose action matches actions, a
+// comma separated list of patterns such as *reindex (all when empty),
+// oldest first
+func ListESTasks(ctx context.Context, config ESConfig, actions string) ([]ESTask, error) {
+	params := url.Values{"detailed": {"true"}, "group_by": {"none"}}
+	if actions != "" {
+		params.Set("actions", actions)
+	}
+	var result struct {
+		Tasks []esTaskJSON `json:"tasks"`
+	}
+	if err := config.esCall(ctx, "GET", "/_tasks?"+params.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+	tasks := make([]ESTask, len(result.Tasks))
+	for i, t := range result.Tasks {
+		tasks[i] = t.task()
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].StartTime < tasks[j].StartTime })
+	return tasks, nil
+}
+
+// GetESTask returns a task's progress, or its result once completed. Results
+// of tasks started without waiting are kept in the .tasks index, so this works
+// after the task finished too.
+func GetESTask(ctx context.Context, config ESConfig, id string) (*ESTaskStatus, error) {
+	if err := checkESTaskID(id); err != nil {
+		return nil, err
+	}
+	var result struct {
+		Completed bool            `json:"completed"`
+		Task      esTaskJSON      `json:"task"`
+		Response  json.RawMessage `json:"response"`
+		Error     json.RawMessage `json:"error"`
+	}
+	if err := config.esCall(ctx, "GET", "/_tasks/"+id, nil, &result); err != nil {
+		return nil, err
+	}
+	return &ESTaskStatus{
+		Completed: result.Completed,
+		Task:      result.Task.task(),
+		Response:  result.Response,
+		Error:     result.Error,
+	}, nil
+}
+
+// CancelESTask asks a cancellable task to stop. A reindex stops after the
+// current batch; documents already copied stay.
+func CancelESTask(ctx context.Context, config ESConfig, id string) error {
+	if err := checkESTaskID(id); err != nil {
+		return err
+	}
+	var result struct {
+		NodeFailures []json.RawMessage `json:"node_failures"`
+		TaskFailures []struct {
+			Reason struct {
+				Reason string `json:"reason"`
+			} `json:"reason"`
+		} `json:"task_failures"`
+	}
+	if err := config.esCall(ctx, "POST", "/_tasks/"+id+"/_cancel", nil, &result); err != nil {
+		return err
+	}
+	if len(result.TaskFailures) > 0 {
+		return fmt.Errorf("cancel failed: %s", result.TaskFailures[0].Reason.Reason)
+	}
+	if len(result.NodeFailures) > 0 {
+		return fmt.Errorf("cancel failed: %s", string(result.NodeFailures[0]))
+	}
+	return nil
+}
+
+// ListESSnapshotRepositories returns the registered repositories by name
+func ListESSnapshotRepositories(ctx context.Context, config ESConfig) ([]ESSnapshotRepository, error) {
+	var result map[string]struct {
+		Type     string          `json:"type"`
+		Settings json.RawMessage `json:"settings"`
+	}
+	if err := config.esCall(ctx, "GET", "/_snapshot", nil, &result); err != nil {
+		return nil, err
+	}
+	repos := make([]ESSnapshotRepository, 0, len(result))
+	for name, r := range result {
+		repos = append(repos, ESSnapshotRepository{Name: name, Type: r.Type, Settings: r.Settings})
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	return repos, nil
+}
+
+// CreateESSnapshotRepository registers or updates a repository. The cluster
+// checks every node can write to it unless verify is false.
+func CreateESSnapshotRepository(ctx context.Context, config ESConfig, repo ESSnapshotRepository, verify bool) error {
+	if err := checkESName("repository name", repo.Name); err != nil {
+		return err
+	}
+	if repo.Type == "" {
+		return fmt.Errorf("repository type is required")
+	}
+	body := map[string]interface{}{"type": repo.Type}
+	if len(repo.Settings) > 0 {
+		body["settings"] = repo.Settings
+	}
+	return config.esCall(ctx, "PUT", fmt.Sprintf("/_snapshot/%s?verify=%t", url.PathEscape(repo.Name), verify), body, nil)
+}
+
+// VerifyESSnapshotRepository checks every node can write to the repository
+// and returns the nodes that did
+func VerifyESSnapshotRepository(ctx context.Context, config ESConfig, name string) (json.RawMessage, error) {
+	if err := checkESName("repository name", name); err != nil {
+		return nil, err
+	}
+	var result struct {
+		Nodes json.RawMessage `json:"nodes"`
+	}
+	if err := config.esCall(ctx, "POST", "/_snapshot/"+url.PathEscape(name)+"/_verify", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Nodes, nil
+}
+
+// DeleteESSnapshotRepository unregisters a repository; its snapshots stay in
+// storage
+func DeleteESSnapshotRepository(ctx context.Context, config ESConfig, name string) error {
+	if err := checkESName("repository name", name); err != nil {
+		return err
+	}
+	return config.esCall(ctx, "DELETE", "/_snapshot/"+url.PathEscape(name), nil, nil)
+}
+
+// ListESSnapshots returns a repository's snapshots, newest first
+func ListESSnapshots(ctx context.Context, config ESConfig, repo string) ([]ESSnapshot, error) {
+	if err := checkESName("repository name", repo); err != nil {
+		return nil, err
+	}
+	var result struct {
+		Snapshots []ESSnapshot `json:"snapshots"`
+	}
+	if err := config.esCall(ctx, "GET", "/_snapshot/"+url.PathEscape(repo)+"/_all", nil, &result); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result.Snapshots, func(i, j int) bool { return result.Snapshots[i].StartTime > result.Snapshots[j].StartTime })
+	if result.Snapshots == nil {
+		result.Snapshots = []ESSnapshot{}
+	}
+	return result.Snapshots, nil
+}
+
+// CreateESSnapshot starts a snapshot without waiting for it; follow it with
+// GetESSnapshotStatus
+func CreateESSnapshot(ctx context.Context, config ESConfig, repo, name string, req ESSnapshotRequest) error {
+	if err := checkESName("repository name", repo); err != nil {
+		return err
+	}
+	if err := checkESName("snapshot name", name); err != nil {
+		return err
+	}
+	body := map[string]interface{}{"include_global_state": req.IncludeGlobalState, "partial": req.Partial}
+	if len(req.Indices) > 0 {
+		body["indices"] = strings.Join(req.Indices, ",")
+	}
+	return config.esCall(ctx, "PUT", fmt.Sprintf("/_snapshot/%s/%s?wait_for_completion=false", url.PathEscape(repo), url.PathEscape(name)), body, nil)
+}
+
+// GetESSnapshotStatus returns the per-shard progress of a snapshot
+func GetESSnapshotStatus(ctx context.Context, config ESConfig, repo, name string) (json.RawMessage, error) {
+	if err := checkESName("repository name", repo); err != nil {
+		return nil, err
+	}
+	if err := checkESName("snapshot name", name); err != nil {
+		return nil, err
+	}
+	var result json.RawMessage
+	if err := config.esCall(ctx, "GET", fmt.Sprintf("/_snapshot/%s/%s/_status", url.PathEscape(repo), url.PathEscape(name)), nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RestoreESSnapshot starts restoring a snapshot without waiting for it;
+// restored indices report progress in cluster health and index recovery
+func RestoreESSnapshot(ctx context.Context, config ESConfig, repo, name string, req ESRestoreRequest) error {
+	if err := checkESName("repository name", repo); err != nil {
+		return err
+	}
+	if err := checkESName("snapshot name", name); err != nil {
+		return err
+	}
+	if (req.RenamePattern == "") != (req.RenameReplacement == "") {
+		return fmt.Errorf("rename_pattern and rename_replacement go together")
+	}
+	body := map[string]interface{}{"include_global_state": req.IncludeGlobalState}
+	if len(req.Indices) > 0 {
+		body["indices"] = strings.Join(req.Indices, ",")
+	}
+	if req.RenamePattern != "" {
+		body["rename_pattern"] = req.RenamePattern
+		body["rename_replacement"] = req.RenameReplacement
+	}
+	if req.IncludeAliases != nil {
+		body["include_aliases"] = *req.IncludeAliases
+	}
+	return config.esCall(ctx, "POST", fmt.Sprintf("/_snapshot/%s/%s/_restore?wait_for_completion=false", url.PathEscape(repo), url.PathEscape(name)), body, nil)
+}
+
+// DeleteESSnapshot deletes a snapshot, or aborts it while in progress
+func DeleteESSnapshot(ctx context.Context, config ESConfig, repo, name string) error {
+	if err := checkESName("repository name", repo); err != nil {
+		return err
+	}
+	if err := checkESName("snapshot name", name); err != nil {
+		return err
+	}
+	return config.esCall(ctx, "DELETE", fmt.Sprintf("/_snapshot/%s/%s", url.PathEscape(repo), url.PathEscape(name)), nil, nil)
+}
@@ -0,0 +1,476 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gaga951/gagos/internal/egress"
+	"github.com/gaga951/gagos/internal/fanout"
+)
+
+// etcd is read through the JSON gateway of its v3 API (/v3/...), which every
+// etcd 3.4+ serves on the client port, so no gRPC client is needed.
+
+// Key listing limits
+const (
+	DefaultEtcdKeyLimit = 100
+	MaxEtcdKeyLimit     = 1000
+)
+
+// maxEtcdResponse bounds one gateway response
+const maxEtcdResponse = 64 << 20
+
+// EtcdConfig holds etcd connection configuration
+type EtcdConfig struct {
+	Host     string      `json:"host"`
+	Port     int         `json:"port"`
+	Username string      `json:"username,omitempty"`
+	Password string      `json:"password,omitempty"`
+	TLS      *TLSOptions `json:"tls,omitempty"`
+}
+
+func (c *EtcdConfig) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// EtcdMember is a member of the cluster
+type EtcdMember struct {
+	ID         string   `json:"id"` // hex, as etcdctl prints it
+	Name       string   `json:"name"`
+	PeerURLs   []string `json:"peer_urls"`
+	ClientURLs []string `json:"client_urls"`
+	IsLearner  bool     `json:"is_learner,omitempty"`
+	IsLeader   bool     `json:"is_leader,omitempty"`
+}
+
+// EtcdMembers is the member list as seen by the endpoint asked
+type EtcdMembers struct {
+	ClusterID string       `json:"cluster_id"`
+	Members   []EtcdMember `json:"members"`
+}
+
+// EtcdEndpointHealth is the health and status of one client URL
+type EtcdEndpointHealth struct {
+	Endpoint  string   `json:"endpoint"`
+	Healthy   bool     `json:"healthy"`
+	Took      float64  `json:"took_ms"`
+	Version   string   `json:"version,omitempty"`
+	DBSize    int64    `json:"db_size,omitempty"`
+	DBInUse   int64    `json:"db_size_in_use,omitempty"`
+	IsLeader  bool     `json:"is_leader,omitempty"`
+	RaftTerm  int64    `json:"raft_term,omitempty"`
+	RaftIndex int64    `json:"raft_index,omitempty"`
+	Errors    []string `json:"errors,omitempty"` // alarms such as NOSPACE
+	Error     string   `json:"error,omitempty"`
+}
+
+// EtcdKey is one key; Value is only set by GetEtcdKey, base64 when Encoding
+// says so because it is not valid UTF-8
+type EtcdKey struct {
+	Key            string `json:"key"`
+	Value          string `json:"value,omitempty"`
+	Encoding       string `json:"encoding,omitempty"`
+	Size           int    `json:"size,omitempty"`
+	CreateRevision int64  `json:"create_revision"`
+	ModRevision    int64  `json:"mod_revision"`
+	Version        int64  `json:"version"`
+	Lease          string `json:"lease,omitempty"` // hex lease ID
+}
+
+// EtcdKeyList is one page of keys under a prefix. Pass Next as start to get
+// the following page.
+type EtcdKeyList struct {
+	Prefix   string    `json:"prefix"`
+	Keys     []EtcdKey `json:"keys"`
+	Count    int64     `json:"count"` // keys under the prefix in total
+	More     bool      `json:"more"`
+	Next     string    `json:"next,omitempty"`
+	Revision int64     `json:"revision"`
+}
+
+// etcdInt reads the gateway's int64 and uint64 fields, which are JSON strings
+type etcdInt int64
+
+func (n *etcdInt) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v = uint64(i)
+	}
+	*n = etcdInt(v)
+	return nil
+}
+
+func (n etcdInt) hex() string {
+	return strconv.FormatUint(uint64(n), 16)
+}
+
+type etcdHeader struct {
+	ClusterID etcdInt `json:"cluster_id"`
+	MemberID  etcdInt `json:"member_id"`
+	Revision  etcdInt `json:"revision"`
+	RaftTerm  etcdInt `json:"raft_term"`
+}
+
+// etcdClient talks to one client URL
+type etcdClient struct {
+	base   string
+	config EtcdConfig
+	http   *http.Client
+	token  string
+}
+
+func newEtcdClient(config EtcdConfig, endpoint string) (*etcdClient, error) {
+	mode := config.TLS.mode("")
+	if endpoint == "" {
+		scheme := "http"
+		if mode != TLSDisable {
+			scheme = "https"
+		}
+		endpoint = fmt.Sprintf("%s://%s", scheme, config.Addr())
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid etcd endpoint %q", endpoint)
+	}
+	transport := &http.Transport{}
+	if u.Scheme == "https" {
+		if mode == TLSDisable {
+			mode = TLSRequire
+		}
+		if transport.TLSClientConfig, err = config.TLS.clientConfig(mode, u.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	return &etcdClient{
+		base:   strings.TrimRight(endpoint, "/"),
+		config: config,
+		http: &http.Client{
+			Transport: guardedTransport("etcd", u.Host, egress.Default().Transport(transport)),
+			Timeout:   30 * time.Second,
+		},
+	}, nil
+}
+
+// call POSTs body to a gateway path and decodes the response into out,
+// authenticating first when the config has a username
+func (c *etcdClient) call(ctx context.Context, path string, body, out interface{}) error {
+	if c.config.Username != "" && c.token == "" && path != "/v3/auth/authenticate" {
+		var auth struct {
+			Token string `json:"token"`
+		}
+		err := c.call(ctx, "/v3/auth/authenticate", map[string]string{"name": c.config.Username, "password": c.config.Password}, &auth)
+		if err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+		c.token = auth.Token
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxEtcdResponse))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var gwErr struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(respBody, &gwErr) == nil && (gwErr.Message != "" || gwErr.Error != "") {
+			if gwErr.Message == "" {
+				gwErr.Message = gwErr.Error
+			}
+			return fmt.Errorf("etcd: %s", gwErr.Message)
+		}
+		return fmt.Errorf("etcd returned %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+type etcdStatus struct {
+	Header      etcdHeader `json:"header"`
+	Version     string     `json:"version"`
+	DBSize      etcdInt    `json:"dbSize"`
+	DBSizeInUse etcdInt    `json:"dbSizeInUse"`
+	Leader      etcdInt    `json:"leader"`
+	RaftIndex   etcdInt    `json:"raftIndex"`
+	RaftTerm    etcdInt    `json:"raftTerm"`
+	Errors      []string   `json:"errors"`
+}
+
+// GetEtcdMembers lists the cluster members, marking the leader
+func GetEtcdMembers(ctx context.Context, config EtcdConfig) (*EtcdMembers, error) {
+	c, err := newEtcdClient(config, "")
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Header  etcdHeader `json:"header"`
+		Members []struct {
+			ID         etcdInt  `json:"ID"`
+			Name       string   `json:"name"`
+			PeerURLs   []string `json:"peerURLs"`
+			ClientURLs []string `json:"clientURLs"`
+			IsLearner  bool     `json:"isLearner"`
+		} `json:"members"`
+	}
+	if err := c.call(ctx, "/v3/cluster/member/list", map[string]interface{}{}, &resp); err != nil {
+		return nil, err
+	}
+	var status etcdStatus
+	leaderKnown := c.call(ctx, "/v3/maintenance/status", map[string]interface{}{}, &status) == nil
+
+	result := &EtcdMembers{ClusterID: resp.Header.ClusterID.hex(), Members: []EtcdMember{}}
+	for _, m := range resp.Members {
+		result.Members = append(result.Members, EtcdMember{
+			ID:         m.ID.hex(),
+			Name:       m.Name,
+			PeerURLs:   m.PeerURLs,
+			ClientURLs: m.ClientURLs,
+			IsLearner:  m.IsLearner,
+			IsLeader:   leaderKnown && m.ID == status.Leader,
+		})
+	}
+	sort.Slice(result.Members, func(i, j int) bool { return result.Members[i].Name < result.Members[j].Name })
+	return result, nil
+}
+
+// GetEtcdHealth checks every member's client URLs, like
+// `etcdctl endpoint health --cluster` and `endpoint status`. Client URLs the
+// members advertise may not be reachable from GAGOS; those report an error.
+func GetEtcdHealth(ctx context.Context, config EtcdConfig) ([]EtcdEndpointHealth, error) {
+	members, err := GetEtcdMembers(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []string
+	for _, m := range members.Members {
+		endpoints = append(endpoints, m.ClientURLs...)
+	}
+
+	results := make([]EtcdEndpointHealth, len(endpoints))
+	tasks := make(map[string]fanout.Func, len(endpoints))
+	for i, endpoint := range endpoints {
+		i, endpoint := i, endpoint
+		results[i] = EtcdEndpointHealth{Endpoint: endpoint}
+		tasks[endpoint] = func(ctx context.Context) error {
+			h := &results[i]
+			c, err := newEtcdClient(config, endpoint)
+			if err != nil {
+				h.Error = err.Error()
+				return err
+			}
+			// A linearizable read, as etcdctl endpoint health does, fails
+			// when the member has lost quorum
+			start := time.Now()
+			var status etcdStatus
+			err = c.call(ctx, "/v3/maintenance/status", map[string]interface{}{}, &status)
+			if err == nil {
+				err = c.call(ctx, "/v3/kv/range", map[string]interface{}{"key": base64.StdEncoding.EncodeToString([]byte("health"))}, nil)
+			}
+			h.Took = float64(time.Since(start).Microseconds()) / 1000.0
+			if err != nil {
+				h.Error = err.Error()
+				return err
+			}
+			h.Healthy = len(status.Errors) == 0
+			h.Version = status.Version
+			h.DBSize = int64(status.DBSize)
+			h.DBInUse = int64(status.DBSizeInUse)
+			h.IsLeader = status.Leader != 0 && status.Leader == status.Header.MemberID
+			h.RaftTerm = int64(status.RaftTerm)
+			h.RaftIndex = int64(status.RaftIndex)
+			h.Errors = status.Errors
+			return nil
+		}
+	}
+	// Per-endpoint errors are already in results
+	fanout.Run(ctx, fanout.Options{Limit: 8}, tasks)
+	return results, nil
+}
+
+type etcdKV struct {
+	Key            string  `json:"key"`
+	Value          string  `json:"value"`
+	CreateRevision etcdInt `json:"create_revision"`
+	ModRevision    etcdInt `json:"mod_revision"`
+	Version        etcdInt `json:"version"`
+	Lease          etcdInt `json:"lease"`
+}
+
+func (kv etcdKV) toKey(withValue bool) (EtcdKey, error) {
+	key, err := base64.StdEncoding.DecodeString(kv.Key)
+	if err != nil {
+		return EtcdKey{}, err
+	}
+	value, err := base64.StdEncoding.DecodeString(kv.Value)
+	if err != nil {
+		return EtcdKey{}, err
+	}
+	k := EtcdKey{
+		Key:            string(key),
+		Size:           len(value),
+		CreateRevision: int64(kv.CreateRevision),
+		ModRevision:    int64(kv.ModRevision),
+		Version:        int64(kv.Version),
+	}
+	if kv.Lease != 0 {
+		k.Lease = kv.Lease.hex()
+	}
+	if withValue {
+		if utf8.Valid(value) {
+			k.Value = string(value)
+		} else {
+			k.Value, k.Encoding = kv.Value, "base64"
+		}
+	}
+	return k, nil
+}
+
+// prefixEnd returns the range end covering every key starting with prefix,
+// "\x00" (to the end of the keyspace) for an empty prefix
+func prefixEnd(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return "\x00"
+}
+
+// ListEtcdKeys lists keys under prefix in key order, from start on when set
+func ListEtcdKeys(ctx context.Context, config EtcdConfig, prefix, start string, limit int) (*EtcdKeyList, error) {
+	if limit <= 0 {
+		limit = DefaultEtcdKeyLimit
+	}
+	if limit > MaxEtcdKeyLimit {
+		limit = MaxEtcdKeyLimit
+	}
+	from := prefix
+	if start != "" {
+		if !strings.HasPrefix(start, prefix) {
+			return nil, fmt.Errorf("start must begin with the prefix")
+		}
+		from = start
+	}
+	if from == "" {
+		from = "\x00"
+	}
+	c, err := newEtcdClient(config, "")
+	if err != nil {
+		return nil, err
+	}
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	// Values are fetched too, for their sizes; keys_only would drop them
+	var resp struct {
+		Header etcdHeader `json:"header"`
+		KVs    []etcdKV   `json:"kvs"`
+		More   bool       `json:"more"`
+	}
+	err = c.call(ctx, "/v3/kv/range", map[string]interface{}{
+		"key":         b64(from),
+		"range_end":   b64(prefixEnd(prefix)),
+		"limit":       limit,
+		"sort_order":  "ASCEND",
+		"sort_target": "KEY",
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	var count struct {
+		Count etcdInt `json:"count"`
+	}
+	err = c.call(ctx, "/v3/kv/range", map[string]interface{}{
+		"key":        b64(prefix),
+		"range_end":  b64(prefixEnd(prefix)),
+		"count_only": true,
+		"revision":   int64(resp.Header.Revision),
+	}, &count)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &EtcdKeyList{Prefix: prefix, Keys: []EtcdKey{}, Count: int64(count.Count), More: resp.More, Revision: int64(resp.Header.Revision)}
+	for _, kv := range resp.KVs {
+		k, err := kv.toKey(false)
+		if err != nil {
+			return nil, err
+		}
+		list.Keys = append(list.Keys, k)
+	}
+	if list.More && len(list.Keys) > 0 {
+		list.Next = list.Keys[len(list.Keys)-1].Key + "\x00"
+	}
+	return list, nil
+}
+
+// GetEtcdKey returns one key with its value
+func GetEtcdKey(ctx context.Context, config EtcdConfig, key string) (*EtcdKey, error) {
+	if key == "" {
+		return nil, fmt.Errorf("key is required")
+	}
+	c, err := newEtcdClient(config, "")
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		KVs []etcdKV `json:"kvs"`
+	}
+	err = c.call(ctx, "/v3/kv/range", map[string]interface{}{"key": base64.StdEncoding.EncodeToString([]byte(key))}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.KVs) == 0 {
+		return nil, fmt.Errorf("key not found: %s", key)
+	}
+	k, err := resp.KVs[0].toKey(true)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
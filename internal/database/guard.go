@@ -0,0 +1,182 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gaga951/gagos/internal/guard"
+	mysqldrv "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	mssql "github.com/microsoft/go-mssqldb"
+	go_ora "github.com/sijms/go-ora/v2"
+)
+
+// backends holds one rate limiter and circuit breaker per database host,
+// tuned via GAGOS_DB_RATE_LIMIT, GAGOS_DB_RATE_BURST,
+// GAGOS_DB_BREAKER_THRESHOLD and GAGOS_DB_BREAKER_COOLDOWN.
+var backends = guard.NewRegistry(guard.ConfigFromEnv("GAGOS_DB", guard.Config{
+	RPS:              10,
+	Burst:            20,
+	FailureThreshold: 5,
+	Cooldown:         30 * time.Second,
+}))
+
+// BackendStatuses returns the limiter and breaker state of every database
+// host contacted so far
+func BackendStatuses() []guard.Status {
+	return backends.Statuses()
+}
+
+// guardedConnector gates every new SQL connection on the host's guard
+type guardedConnector struct {
+	driver.Connector
+	guard *guard.Guard
+}
+
+func (c *guardedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := c.guard.Before(ctx); err != nil {
+		return nil, err
+	}
+	conn, err := c.Connector.Connect(ctx)
+	c.guard.After(err)
+	return conn, err
+}
+
+// openPostgres is sql.Open("postgres", ...) behind the host's guard
+func openPostgres(config PostgresConfig) (*sql.DB, error) {
+	if config.AuthMode == AuthIAM {
+		token, err := rdsAuthToken(config.Host, config.Port, config.User, config.Region)
+		if err != nil {
+			return nil, err
+		}
+		config.Password = token
+	}
+	if config.AuthMode == AuthKerberos {
+		// The server asks for GSSAPI, answered by pqGSS
+		config.Password = ""
+	}
+	dsn := config.ConnectionString()
+	if config.TLS.mode(config.SSLMode) != TLSDisable || config.AuthMode == AuthIAM {
+		params, err := config.TLS.pqTLSParams()
+		if err != nil {
+			return nil, err
+		}
+		dsn += params
+	}
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	connector.Dialer(pqDialer{})
+	key := fmt.Sprintf("postgres/%s:%d", config.Host, config.Port)
+	return sql.OpenDB(&guardedConnector{Connector: connector, guard: backends.Get(key)}), nil
+}
+
+// openMySQL is sql.Open("mysql", ...) behind the host's guard
+func openMySQL(config MySQLConfig) (*sql.DB, error) {
+	mode := config.TLS.mode("")
+	if config.AuthMode == AuthIAM {
+		token, err := rdsAuthToken(config.Host, config.Port, config.User, config.Region)
+		if err != nil {
+			return nil, err
+		}
+		config.Password = token
+		// RDS only accepts IAM tokens over TLS, sent in clear text inside it
+		if mode == TLSDisable {
+			mode = TLSRequire
+		}
+	}
+
+	dsn := config.DSN()
+	if config.AuthMode == AuthIAM {
+		dsn += "&allowCleartextPasswords=true"
+	}
+	if config.multiStatements {
+		dsn += "&multiStatements=true"
+	}
+	if mode != TLSDisable {
+		param, err := config.TLS.mysqlTLSParam(mode, config.Host)
+		if err != nil {
+			return nil, err
+		}
+		dsn += param
+	}
+	connector, err := (&mysqldrv.MySQLDriver{}).OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("mysql/%s:%d", config.Host, config.Port)
+	return sql.OpenDB(&guardedConnector{Connector: connector, guard: backends.Get(key)}), nil
+}
+
+// openMSSQL opens a SQL Server connection behind the host's guard
+func openMSSQL(config MSSQLConfig) (*sql.DB, error) {
+	cfg, err := config.connConfig()
+	if err != nil {
+		return nil, err
+	}
+	connector := mssql.NewConnectorConfig(cfg)
+	connector.Dialer = mssqlDialer{}
+	key := fmt.Sprintf("mssql/%s:%d", config.Host, config.Port)
+	return sql.OpenDB(&guardedConnector{Connector: connector, guard: backends.Get(key)}), nil
+}
+
+// openOracle opens an Oracle connection behind the host's guard
+func openOracle(config OracleConfig) (*sql.DB, error) {
+	dsn, err := config.connURL()
+	if err != nil {
+		return nil, err
+	}
+	connector := go_ora.NewConnector(dsn).(*go_ora.OracleConnector)
+	connector.Dialer(oracleDialer{})
+	key := fmt.Sprintf("oracle/%s:%d", config.Host, config.Port)
+	return sql.OpenDB(&guardedConnector{Connector: connector, guard: backends.Get(key)}), nil
+}
+
+// redisLimiter adapts a guard to go-redis' per-command Limiter hook
+type redisLimiter struct {
+	guard *guard.Guard
+}
+
+func newRedisLimiter(config RedisConfig) *redisLimiter {
+	return &redisLimiter{guard: backends.Get("redis/" + config.Addr())}
+}
+
+func (l *redisLimiter) Allow() error {
+	return l.guard.Before(context.Background())
+}
+
+func (l *redisLimiter) ReportResult(err error) {
+	// Command errors such as redis.Nil or WRONGTYPE say nothing about the
+	// server's health
+	if err != nil && !isConnectionError(err) {
+		err = nil
+	}
+	l.guard.After(err)
+}
+
+// guardedTransport routes an HTTP backend (Elasticsearch, S3) through the
+// guard for its host
+func guardedTransport(kind, host string, next http.RoundTripper) http.RoundTripper {
+	return backends.Get(kind + "/" + host).RoundTripper(next)
+}
+
+// isConnectionError reports whether err means the backend is unreachable or
+// overloaded rather than the request being bad
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
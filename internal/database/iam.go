@@ -0,0 +1,124 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// With auth_mode "iam", S3 and RDS (PostgreSQL, MySQL) connections use the AWS
+// identity GAGOS runs under instead of a pasted key or password. RDS gets a
+// 15 minute IAM auth token generated for every new connection.
+
+// Auth modes
+const (
+	AuthPassword = "password" // static keys or password (default)
+	AuthIAM      = "iam"      // AWS credential chain
+	AuthKerberos = "kerberos" // GAGOS's Kerberos identity (SQL Server, PostgreSQL)
+)
+
+// awsCredentials is the AWS credential chain: AWS_* environment variables,
+// web identity (EKS IRSA, AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN), the
+// ECS task role and the EC2 instance profile. These are GAGOS's own
+// credentials, so the metadata endpoints are reached without the egress
+// policy that blocks them for user-supplied hosts.
+var awsCredentials = credentials.NewChainCredentials([]credentials.Provider{
+	&credentials.EnvAWS{},
+	&credentials.IAM{Client: &http.Client{Transport: http.DefaultTransport, Timeout: 10 * time.Second}},
+})
+
+// rdsTokenLifetime is how long an RDS IAM auth token is accepted
+const rdsTokenLifetime = 15 * time.Minute
+
+// emptyPayloadHash is the SHA-256 of an empty body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// rdsRegion returns region, or the one in an RDS endpoint name
+// (<name>.<id>.<region>.rds.amazonaws.com), or AWS_REGION
+func rdsRegion(host, region string) string {
+	if region != "" {
+		return region
+	}
+	parts := strings.Split(host, ".")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] == "rds" {
+			return parts[i-1]
+		}
+	}
+	if r := os.Getenv("AWS_REGION"); r != "" {
+		return r
+	}
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+// rdsAuthToken generates an RDS IAM auth token for user at host:port, used as
+// the password. The database user needs the rds_iam role (PostgreSQL) or the
+// AWSAuthenticationPlugin (MySQL), and the connection must use TLS.
+func rdsAuthToken(host string, port int, user, region string) (string, error) {
+	region = rdsRegion(host, region)
+	if region == "" {
+		return "", fmt.Errorf("IAM auth needs a region: set region or AWS_REGION")
+	}
+	creds, err := awsCredentials.Get()
+	if err != nil {
+		return "", fmt.Errorf("no AWS credentials for IAM auth: %w", err)
+	}
+	if creds.AccessKeyID == "" {
+		return "", fmt.Errorf("no AWS credentials for IAM auth")
+	}
+
+	endpoint := net.JoinHostPort(host, strconv.Itoa(port))
+	query := url.Values{"Action": {"connect"}, "DBUser": {user}}
+	signed := presignV4(endpoint, query, creds, region, "rds-db", rdsTokenLifetime, time.Now().UTC())
+	return endpoint + "/?" + signed, nil
+}
+
+// presignV4 adds AWS Signature Version 4 query authentication for a GET of /
+// on host and returns the encoded query
+func presignV4(host string, query url.Values, creds credentials.Value, region, service string, expires time.Duration, now time.Time) string {
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + region + "/" + service + "/aws4_request"
+
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", creds.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if creds.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	// SigV4 wants RFC 3986 escaping, which differs from Encode only for spaces
+	canonicalQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	canonicalRequest := strings.Join([]string{
+		"GET", "/", canonicalQuery, "host:" + host + "\n", "host", emptyPayloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), now.Format("20060102"))
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	return canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,629 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// Imports load a CSV or XLSX file into an existing table as a tracked job.
+// Every value is checked against its column's type before anything is sent,
+// rows are inserted in batches, and a batch the database refuses is retried
+// row by row so only the bad rows are rejected. Rejected rows are kept with
+// their error and can be downloaded as CSV to fix and import again.
+
+// MaxImportSize bounds an uploaded import file
+const MaxImportSize = 32 << 20
+
+// Import batch sizes, and the most parameters one batch may bind, which is
+// SQL Server's limit less some headroom
+const (
+	defaultImportBatch = 500
+	maxImportBatch     = 5000
+	maxImportParams    = 2000
+)
+
+// maxImportErrors is how many errors a run keeps inline, and
+// maxImportErrorRows how many rejected rows it keeps for download
+const (
+	maxImportErrors    = 100
+	maxImportErrorRows = 10000
+)
+
+// Import run states
+const (
+	ImportRunning   = "running"
+	ImportSucceeded = "succeeded"
+	ImportFailed    = "failed"
+)
+
+// importTimeout bounds a whole import
+const importTimeout = 30 * time.Minute
+
+// maxImportRuns is how many runs List returns
+const maxImportRuns = 100
+
+// ImportRequest loads a file into a table. Mapping maps file columns, named
+// by their header or, with NoHeader, by 1-based position, to table columns;
+// when empty, file columns are matched to table columns of the same name
+// (case-insensitively), or by position with NoHeader. Unmapped file columns
+// are ignored.
+type ImportRequest struct {
+	ProfileID string            `json:"profile_id"`
+	Schema    string            `json:"schema,omitempty"`
+	Table     string            `json:"table"`
+	Mapping   map[string]string `json:"mapping,omitempty"`
+	NoHeader  bool              `json:"no_header,omitempty"`
+	Delimiter string            `json:"delimiter,omitempty"` // CSV only, default ","
+	Sheet     string            `json:"sheet,omitempty"`     // XLSX only, default the first sheet
+	BatchSize int               `json:"batch_size,omitempty"`
+	DryRun    bool              `json:"dry_run,omitempty"` // validate every row without inserting
+}
+
+// ImportError is a rejected row, by its line (CSV) or row (XLSX) number
+type ImportError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportRun is a tracked import job
+type ImportRun struct {
+	ID          string            `json:"id"`
+	ProfileID   string            `json:"profile_id"`
+	Kind        string            `json:"kind"`
+	Database    string            `json:"database"` // profile name, e.g. user@host:5432/db
+	Table       string            `json:"table"`    // schema.table
+	File        string            `json:"file"`
+	Format      string            `json:"format"` // csv or xlsx
+	SHA256      string            `json:"sha256"`
+	Mapping     map[string]string `json:"mapping"` // file column to table column, as resolved
+	DryRun      bool              `json:"dry_run"`
+	Status      string            `json:"status"`
+	TriggeredBy string            `json:"triggered_by"` // client address
+	StartedAt   time.Time         `json:"started_at"`
+	FinishedAt  *time.Time        `json:"finished_at,omitempty"`
+	TotalRows   int               `json:"total_rows"`
+	Processed   int               `json:"processed"`
+	Inserted    int               `json:"inserted"` // rows that passed validation on a dry run
+	Failed      int               `json:"failed"`
+	Errors      []ImportError     `json:"errors"` // the first maxImportErrors
+	Error       string            `json:"error,omitempty"`
+}
+
+// importRecord is one non-empty row of an import file
+type importRecord struct {
+	line   int
+	values []string
+}
+
+// importColumn is one mapped column
+type importColumn struct {
+	source   int // index in the file's rows
+	name     string
+	kind     string
+	nullable bool
+}
+
+// importFile is a parsed import file
+type importFile struct {
+	header  []string
+	records []importRecord
+}
+
+// StartImport parses the file, resolves the column mapping and starts the
+// import in the background. Errors in the file or mapping are returned
+// before a run is created.
+func StartImport(req ImportRequest, r io.Reader, filename, triggeredBy string) (*ImportRun, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxImportSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxImportSize {
+		return nil, fmt.Errorf("import file is larger than %d MB", MaxImportSize>>20)
+	}
+	sum := sha256.Sum256(data)
+
+	var format string
+	var records []importRecord
+	switch name := strings.ToLower(filename); {
+	case strings.HasSuffix(name, ".xlsx"):
+		format = "xlsx"
+		records, err = readXLSX(data, req.Sheet)
+	case strings.HasSuffix(name, ".csv"), strings.HasSuffix(name, ".txt"), strings.HasSuffix(name, ".tsv"):
+		format = "csv"
+		delimiter := req.Delimiter
+		if delimiter == "" && strings.HasSuffix(name, ".tsv") {
+			delimiter = "\t"
+		}
+		records, err = readCSV(data, delimiter)
+	default:
+		return nil, fmt.Errorf("import file must be a .csv, .tsv or .xlsx file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	file := importFile{records: records}
+	if !req.NoHeader {
+		if len(records) == 0 {
+			return nil, fmt.Errorf("import file is empty")
+		}
+		file.header, file.records = records[0].values, records[1:]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), importTimeout)
+	db, d, err := openTableTarget(ctx, req.ProfileID, true)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	m, err := readTableMeta(ctx, db, d, req.Schema, req.Table)
+	if err != nil {
+		cancel()
+		db.Close()
+		return nil, err
+	}
+	columns, mapping, err := mapImportColumns(m, file, req)
+	if err != nil {
+		cancel()
+		db.Close()
+		return nil, err
+	}
+	_, status, _ := profileConfig(req.ProfileID)
+
+	run := &ImportRun{
+		ID:          importRunID(),
+		ProfileID:   req.ProfileID,
+		Kind:        status.Kind,
+		Database:    status.Name,
+		Table:       m.schema + "." + m.table,
+		File:        filename,
+		Format:      format,
+		SHA256:      hex.EncodeToString(sum[:]),
+		Mapping:     mapping,
+		DryRun:      req.DryRun,
+		Status:      ImportRunning,
+		TriggeredBy: triggeredBy,
+		StartedAt:   time.Now(),
+		TotalRows:   len(file.records),
+		Errors:      []ImportError{},
+	}
+	saveImportRun(run)
+
+	go func() {
+		defer cancel()
+		defer db.Close()
+		runImport(ctx, db, d, m, columns, file, run, req.BatchSize)
+	}()
+	return run, nil
+}
+
+// readCSV reads every non-empty record of a CSV file. A UTF-8 byte order
+// mark, as Excel writes, is skipped.
+func readCSV(data []byte, delimiter string) ([]importRecord, error) {
+	cr := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	cr.FieldsPerRecord = -1
+	if delimiter != "" {
+		r, size := utf8.DecodeRuneInString(delimiter)
+		if size != len(delimiter) || r == '"' || r == '\r' || r == '\n' {
+			return nil, fmt.Errorf("delimiter must be a single character")
+		}
+		cr.Comma = r
+	}
+
+	var records []importRecord
+	for {
+		values, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		if len(values) == 1 && strings.TrimSpace(values[0]) == "" {
+			continue
+		}
+		records = append(records, importRecord{line: line, values: values})
+	}
+}
+
+// mapImportColumns resolves the mapping against the file and the table and
+// returns the mapped columns in table order
+func mapImportColumns(m *tableMeta, file importFile, req ImportRequest) ([]importColumn, map[string]string, error) {
+	// sources are the file's column names: the header, or 1-based positions
+	var sources []string
+	if file.header != nil {
+		for _, h := range file.header {
+			sources = append(sources, strings.TrimSpace(h))
+		}
+	} else {
+		width := 0
+		for _, r := range file.records {
+			if len(r.values) > width {
+				width = len(r.values)
+			}
+		}
+		for i := 1; i <= width; i++ {
+			sources = append(sources, strconv.Itoa(i))
+		}
+	}
+	sourceIndex := map[string]int{}
+	for i, s := range sources {
+		if _, dup := sourceIndex[s]; !dup {
+			sourceIndex[s] = i
+		}
+	}
+
+	mapping := req.Mapping
+	if len(mapping) == 0 {
+		mapping = map[string]string{}
+		for i, s := range sources {
+			if file.header == nil {
+				if i < len(m.columns) {
+					mapping[s] = m.columns[i].Name
+				}
+				continue
+			}
+			for _, c := range m.columns {
+				if strings.EqualFold(s, c.Name) {
+					mapping[s] = c.Name
+				}
+			}
+		}
+		if len(mapping) == 0 {
+			return nil, nil, fmt.Errorf("no file column matches a column of %s.%s; set a mapping", m.schema, m.table)
+		}
+	}
+
+	var columns []importColumn
+	used := map[string]string{}
+	for _, source := range sortedMapKeys(mapping) {
+		target := mapping[source]
+		if target == "" {
+			continue
+		}
+		i, ok := sourceIndex[source]
+		if !ok {
+			return nil, nil, fmt.Errorf("mapping names file column %q, which the file does not have", source)
+		}
+		if err := m.column(target); err != nil {
+			return nil, nil, err
+		}
+		if prev, dup := used[target]; dup {
+			return nil, nil, fmt.Errorf("file columns %q and %q both map to %s", prev, source, target)
+		}
+		used[target] = source
+		for _, c := range m.columns {
+			if c.Name == target {
+				columns = append(columns, importColumn{source: i, name: c.Name, kind: importKind(c.Type), nullable: c.Nullable})
+			}
+		}
+	}
+	if len(columns) == 0 {
+		return nil, nil, fmt.Errorf("mapping maps no columns")
+	}
+	order := map[string]int{}
+	for i, c := range m.columns {
+		order[c.Name] = i
+	}
+	sort.Slice(columns, func(i, j int) bool { return order[columns[i].name] < order[columns[j].name] })
+
+	resolved := map[string]string{}
+	for target, source := range used {
+		resolved[source] = target
+	}
+	return columns, resolved, nil
+}
+
+func sortedMapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Value kinds an import checks
+const (
+	importText   = "text"
+	importInt    = "int"
+	importNumber = "number"
+	importBool   = "bool"
+	importDate   = "date"
+)
+
+// importKind maps an INFORMATION_SCHEMA data type to the check its values get
+func importKind(dataType string) string {
+	t := strings.ToLower(dataType)
+	switch {
+	case t == "integer", t == "int", t == "smallint", t == "bigint", t == "tinyint", t == "mediumint":
+		return importInt
+	case strings.Contains(t, "numeric"), strings.Contains(t, "decimal"), strings.Contains(t, "money"),
+		strings.Contains(t, "float"), strings.Contains(t, "double"), t == "real":
+		return importNumber
+	case t == "boolean", t == "bool", t == "bit":
+		return importBool
+	case t == "date", strings.HasPrefix(t, "timestamp"), strings.HasPrefix(t, "datetime"), t == "smalldatetime":
+		return importDate
+	}
+	return importText
+}
+
+var importDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// convert checks a file value against the column and returns what to bind.
+// An empty value is NULL.
+func (c importColumn) convert(s string) (interface{}, error) {
+	if s == "" {
+		if !c.nullable {
+			return nil, fmt.Errorf("%s must not be empty", c.name)
+		}
+		return nil, nil
+	}
+	switch c.kind {
+	case importInt:
+		v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not an integer", c.name, s)
+		}
+		return v, nil
+	case importNumber:
+		// Bound as text so decimals keep their precision
+		s = strings.TrimSpace(s)
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return nil, fmt.Errorf("%s: %q is not a number", c.name, s)
+		}
+		return s, nil
+	case importBool:
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "true", "t", "yes", "y", "1":
+			return true, nil
+		case "false", "f", "no", "n", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%s: %q is not a boolean", c.name, s)
+	case importDate:
+		for _, layout := range importDateLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("%s: %q is not a date (use YYYY-MM-DD or YYYY-MM-DD HH:MM:SS)", c.name, s)
+	}
+	return s, nil
+}
+
+// runImport validates and inserts the rows, saving progress after every batch
+func runImport(ctx context.Context, db *sql.DB, d tableDialect, m *tableMeta, columns []importColumn, file importFile, run *ImportRun, batchSize int) {
+	var rejected [][]string
+	reject := func(rec importRecord, err error) {
+		run.Failed++
+		if len(run.Errors) < maxImportErrors {
+			run.Errors = append(run.Errors, ImportError{Row: rec.line, Error: err.Error()})
+		}
+		if len(rejected) < maxImportErrorRows {
+			rejected = append(rejected, append([]string{strconv.Itoa(rec.line), err.Error()}, rec.values...))
+		}
+	}
+	finish := func(err error) {
+		now := time.Now()
+		run.FinishedAt = &now
+		run.Status = ImportSucceeded
+		if err != nil {
+			run.Status = ImportFailed
+			run.Error = err.Error()
+		}
+		if len(rejected) > 0 {
+			saveImportErrorRows(run.ID, file.header, rejected)
+		}
+		saveImportRun(run)
+		log.Info().Str("run", run.ID).Str("table", run.Table).Str("status", run.Status).Bool("dry_run", run.DryRun).
+			Int("inserted", run.Inserted).Int("failed", run.Failed).Msg("Import finished")
+	}
+
+	if batchSize <= 0 {
+		batchSize = defaultImportBatch
+	}
+	if batchSize > maxImportBatch {
+		batchSize = maxImportBatch
+	}
+	if limit := maxImportParams / len(columns); batchSize > limit {
+		batchSize = max(limit, 1)
+	}
+
+	var batch []importRecord
+	var args [][]interface{}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		defer func() { batch, args = nil, nil }()
+		if run.DryRun {
+			run.Inserted += len(batch)
+			return nil
+		}
+		if err := insertImportRows(ctx, db, d, m, columns, args); err == nil {
+			run.Inserted += len(batch)
+			return nil
+		} else if ctx.Err() != nil {
+			return err
+		}
+		// Retry one by one so only the rows the database refuses are rejected
+		for i, rec := range batch {
+			if err := insertImportRows(ctx, db, d, m, columns, args[i:i+1]); err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+				reject(rec, err)
+				continue
+			}
+			run.Inserted++
+		}
+		return nil
+	}
+
+	for _, rec := range file.records {
+		values := make([]interface{}, len(columns))
+		var err error
+		for i, c := range columns {
+			var s string
+			if c.source < len(rec.values) {
+				s = rec.values[c.source]
+			}
+			if values[i], err = c.convert(s); err != nil {
+				break
+			}
+		}
+		run.Processed++
+		if err != nil {
+			reject(rec, err)
+		} else {
+			batch = append(batch, rec)
+			args = append(args, values)
+		}
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				finish(err)
+				return
+			}
+			saveImportRun(run)
+		}
+	}
+	if err := flush(); err != nil {
+		finish(err)
+		return
+	}
+	if run.Failed > 0 && run.Inserted == 0 {
+		finish(errors.New("every row was rejected"))
+		return
+	}
+	finish(nil)
+}
+
+// insertImportRows inserts rows in one multi-row INSERT
+func insertImportRows(ctx context.Context, db *sql.DB, d tableDialect, m *tableMeta, columns []importColumn, rows [][]interface{}) error {
+	s := &statement{d: d}
+	cols := make([]string, len(columns))
+	for i, c := range columns {
+		cols[i] = d.quote(c.name)
+	}
+	tuples := make([]string, len(rows))
+	for i, row := range rows {
+		params := make([]string, len(row))
+		for j, v := range row {
+			params[j] = s.arg(v)
+		}
+		tuples[i] = "(" + strings.Join(params, ", ") + ")"
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", m.name(d), strings.Join(cols, ", "), strings.Join(tuples, ", "))
+	_, err := db.ExecContext(ctx, query, s.args...)
+	return err
+}
+
+func importRunID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "imp-" + hex.EncodeToString(b)
+}
+
+func saveImportRun(run *ImportRun) {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return
+	}
+	if err := storage.GetBackend().Set(storage.BucketDBImports, run.ID, data); err != nil {
+		log.Error().Err(err).Str("run", run.ID).Msg("Failed to save import run")
+	}
+}
+
+// saveImportErrorRows stores the rejected rows as CSV: the row number, the
+// error, then the row as it was in the file
+func saveImportErrorRows(id string, header []string, rows [][]string) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if header != nil {
+		w.Write(append([]string{"row", "error"}, header...))
+	}
+	w.WriteAll(rows)
+	if err := storage.GetBackend().Set(storage.BucketDBImportErrors, id, buf.Bytes()); err != nil {
+		log.Error().Err(err).Str("run", id).Msg("Failed to save import error rows")
+	}
+}
+
+// GetImportRun returns a run by ID
+func GetImportRun(id string) (*ImportRun, error) {
+	data, err := storage.GetBackend().Get(storage.BucketDBImports, id)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("import run not found: %s", id)
+	}
+	var run ImportRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetImportErrorRows returns a run's rejected rows as CSV, or nil when it
+// rejected none
+func GetImportErrorRows(id string) ([]byte, error) {
+	if _, err := GetImportRun(id); err != nil {
+		return nil, err
+	}
+	return storage.GetBackend().Get(storage.BucketDBImportErrors, id)
+}
+
+// ListImportRuns returns the most recent runs, optionally of one profile,
+// without their errors
+func ListImportRuns(profileID string) ([]ImportRun, error) {
+	dataList, err := storage.GetBackend().List(storage.BucketDBImports)
+	if err != nil {
+		return nil, err
+	}
+	runs := []ImportRun{}
+	for _, data := range dataList {
+		var run ImportRun
+		if err := json.Unmarshal(data, &run); err != nil {
+			continue
+		}
+		if profileID != "" && run.ProfileID != profileID {
+			continue
+		}
+		run.Errors = nil
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	if len(runs) > maxImportRuns {
+		runs = runs[:maxImportRuns]
+	}
+	return runs, nil
+}
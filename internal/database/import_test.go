@@ -0,0 +1,113 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	got, err := readCSV([]byte("\xef\xbb\xbfid;name\n\n1;\"a;b\"\n2;c\n"), ";")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []importRecord{{1, []string{"id", "name"}}, {3, []string{"1", "a;b"}}, {4, []string{"2", "c"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %v, want %v", got, want)
+	}
+	for _, d := range []string{"ab", "\"", "\n"} {
+		if _, err := readCSV([]byte("a"), d); err == nil {
+			t.Errorf("delimiter %q accepted", d)
+		}
+	}
+}
+
+func TestMapImportColumns(t *testing.T) {
+	m := &tableMeta{
+		schema: "public",
+		table:  "users",
+		columns: []TableColumn{
+			{Name: "id", Type: "integer"},
+			{Name: "name", Type: "text", Nullable: true},
+			{Name: "born", Type: "date", Nullable: true},
+		},
+		byName: map[string]bool{"id": true, "name": true, "born": true},
+	}
+	withHeader := importFile{header: []string{"Name", " ID ", "extra"}}
+	noHeader := importFile{records: []importRecord{{1, []string{"1", "a", "2024-01-01"}}}}
+
+	tests := []struct {
+		name    string
+		file    importFile
+		mapping map[string]string
+		want    string // column:source in table order
+		errMsg  string
+	}{
+		{name: "header matched by name", file: withHeader, want: "id:1 name:0"},
+		{name: "positions without a header", file: noHeader, want: "id:0 name:1 born:2"},
+		{name: "explicit mapping", file: withHeader, mapping: map[string]string{"extra": "born", "ID": "id", "Name": ""}, want: "id:1 born:2"},
+		{name: "unknown file column", file: withHeader, mapping: map[string]string{"nope": "id"}, errMsg: "does not have"},
+		{name: "unknown table column", file: withHeader, mapping: map[string]string{"extra": "nope"}, errMsg: "unknown column"},
+		{name: "two sources for one column", file: withHeader, mapping: map[string]string{"extra": "id", "ID": "id"}, errMsg: "both map to id"},
+		{name: "nothing matches", file: importFile{header: []string{"x"}}, errMsg: "no file column matches"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, _, err := mapImportColumns(m, tt.file, ImportRequest{Mapping: tt.mapping})
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("error = %v, want %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range columns {
+				got = append(got, c.name+":"+strconv.Itoa(c.source))
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("columns = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportColumnConvert(t *testing.T) {
+	tests := []struct {
+		kind     string
+		nullable bool
+		in       string
+		want     interface{}
+		ok       bool
+	}{
+		{importInt, false, " 42 ", int64(42), true},
+		{importInt, false, "4.2", nil, false},
+		{importNumber, false, "1.50", "1.50", true},
+		{importNumber, false, "abc", nil, false},
+		{importBool, false, "Yes", true, true},
+		{importBool, false, "0", false, true},
+		{importBool, false, "maybe", nil, false},
+		{importText, true, "", nil, true},
+		{importText, false, "", nil, false},
+		{importDate, false, "2024-03-01", nil, true},
+		{importDate, false, "2024-03-01 12:00:00", nil, true},
+		{importDate, false, "01/03/2024", nil, false},
+	}
+	for _, tt := range tests {
+		c := importColumn{name: "col", kind: tt.kind, nullable: tt.nullable}
+		got, err := c.convert(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s %q: error %v", tt.kind, tt.in, err)
+			continue
+		}
+		if tt.ok && tt.want != nil && got != tt.want {
+			t.Errorf("%s %q = %v, want %v", tt.kind, tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/lib/pq"
+
+	// SQL Server's krb5 integrated authenticator
+	_ "github.com/microsoft/go-mssqldb/integratedauth/krb5"
+)
+
+// With auth_mode "kerberos", SQL Server and PostgreSQL connections log in
+// with the Kerberos identity GAGOS runs under instead of a password, the way
+// IAM auth uses its AWS identity: a keytab (GAGOS_KRB5_KEYTAB, for the
+// principal GAGOS_KRB5_PRINCIPAL as name@REALM), or else the credential cache
+// KRB5CCNAME kept fresh by kinit or a sidecar. Realms and KDCs come from
+// KRB5_CONFIG (default /etc/krb5.conf). The KDCs are GAGOS's own
+// infrastructure, so they are reached without the egress policy.
+
+// krb5Identity is where GAGOS's Kerberos credentials come from
+type krb5Identity struct {
+	configFile string
+	keytab     string
+	principal  string // without the realm
+	realm      string
+	ccache     string
+}
+
+// kerberosIdentity reads the Kerberos identity from the environment
+func kerberosIdentity() (krb5Identity, error) {
+	id := krb5Identity{configFile: os.Getenv("KRB5_CONFIG")}
+	if id.configFile == "" {
+		id.configFile = "/etc/krb5.conf"
+	}
+	if kt := os.Getenv("GAGOS_KRB5_KEYTAB"); kt != "" {
+		name, realm, ok := strings.Cut(os.Getenv("GAGOS_KRB5_PRINCIPAL"), "@")
+		if !ok || name == "" || realm == "" {
+			return id, fmt.Errorf("GAGOS_KRB5_PRINCIPAL must be name@REALM to log in with GAGOS_KRB5_KEYTAB")
+		}
+		id.keytab, id.principal, id.realm = kt, name, realm
+		return id, nil
+	}
+	id.ccache = strings.TrimPrefix(os.Getenv("KRB5CCNAME"), "FILE:")
+	if id.ccache == "" {
+		id.ccache = fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid())
+	}
+	return id, nil
+}
+
+// mssqlParams has go-mssqldb log in with the identity
+func (id krb5Identity) mssqlParams(query url.Values) {
+	query.Set("authenticator", "krb5")
+	query.Set("krb5-configfile", id.configFile)
+	if id.keytab != "" {
+		query.Set("krb5-keytabfile", id.keytab)
+		query.Set("krb5-realm", id.realm)
+	} else {
+		query.Set("krb5-credcachefile", id.ccache)
+	}
+}
+
+// Keytab clients are kept, and renew their tickets, for as long as GAGOS
+// runs; a credential cache is read again for every connection
+var (
+	krb5Clients   = make(map[krb5Identity]*client.Client)
+	krb5ClientsMu sync.Mutex
+)
+
+// client returns a Kerberos client logged in with the identity
+func (id krb5Identity) client() (*client.Client, error) {
+	cfg, err := config.Load(id.configFile)
+	if err != nil {
+		return nil, fmt.Errorf("krb5 config %s: %w", id.configFile, err)
+	}
+	if id.keytab == "" {
+		cc, err := credentials.LoadCCache(id.ccache)
+		if err != nil {
+			return nil, fmt.Errorf("credential cache %s: %w", id.ccache, err)
+		}
+		return client.NewFromCCache(cc, cfg, client.DisablePAFXFAST(true))
+	}
+
+	krb5ClientsMu.Lock()
+	defer krb5ClientsMu.Unlock()
+	if cl, ok := krb5Clients[id]; ok {
+		return cl, nil
+	}
+	kt, err := keytab.Load(id.keytab)
+	if err != nil {
+		return nil, fmt.Errorf("keytab %s: %w", id.keytab, err)
+	}
+	cl := client.NewWithKeytab(id.principal, id.realm, kt, cfg, client.DisablePAFXFAST(true))
+	if err := cl.Login(); err != nil {
+		return nil, fmt.Errorf("kerberos login as %s@%s: %w", id.principal, id.realm, err)
+	}
+	krb5Clients[id] = cl
+	return cl, nil
+}
+
+func init() {
+	pq.RegisterGSSProvider(newPQGSS)
+}
+
+// pqGSS answers PostgreSQL's GSSAPI authentication with SPNEGO tokens
+type pqGSS struct {
+	client *client.Client
+}
+
+func newPQGSS() (pq.GSS, error) {
+	id, err := kerberosIdentity()
+	if err != nil {
+		return nil, err
+	}
+	cl, err := id.client()
+	if err != nil {
+		return nil, err
+	}
+	return &pqGSS{client: cl}, nil
+}
+
+// GetInitToken asks for a ticket to service/host, with host canonicalized
+// the way the server's keytab names it
+func (g *pqGSS) GetInitToken(host, service string) ([]byte, error) {
+	if cname, err := net.LookupCNAME(host); err == nil {
+		host = strings.TrimSuffix(cname, ".")
+	}
+	return g.GetInitTokenFromSpn(service + "/" + host)
+}
+
+func (g *pqGSS) GetInitTokenFromSpn(spn string) ([]byte, error) {
+	token, err := spnego.SPNEGOClient(g.client, spn).InitSecContext()
+	if err != nil {
+		return nil, fmt.Errorf("kerberos ticket for %s: %w", spn, err)
+	}
+	return token.Marshal()
+}
+
+func (g *pqGSS) Continue(inToken []byte) (bool, []byte, error) {
+	var token spnego.SPNEGOToken
+	if err := token.Unmarshal(inToken); err != nil {
+		return true, nil, fmt.Errorf("kerberos response: %w", err)
+	}
+	if state := token.NegTokenResp.State(); state != spnego.NegStateAcceptCompleted {
+		return true, nil, fmt.Errorf("kerberos authentication not accepted (state %d)", state)
+	}
+	return true, nil, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"testing"
+)
+
+func TestKerberosIdentity(t *testing.T) {
+	t.Setenv("KRB5_CONFIG", "")
+	t.Setenv("KRB5CCNAME", "FILE:/tmp/cc")
+	t.Setenv("GAGOS_KRB5_KEYTAB", "")
+	id, err := kerberosIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.configFile != "/etc/krb5.conf" || id.ccache != "/tmp/cc" || id.keytab != "" {
+		t.Errorf("credential cache identity = %+v", id)
+	}
+
+	t.Setenv("GAGOS_KRB5_KEYTAB", "/etc/gagos.keytab")
+	t.Setenv("GAGOS_KRB5_PRINCIPAL", "gagos")
+	if _, err := kerberosIdentity(); err == nil {
+		t.Error("expected an error for a principal without a realm")
+	}
+	t.Setenv("GAGOS_KRB5_PRINCIPAL", "gagos@CORP.EXAMPLE.COM")
+	id, err = kerberosIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.principal != "gagos" || id.realm != "CORP.EXAMPLE.COM" || id.keytab != "/etc/gagos.keytab" {
+		t.Errorf("keytab identity = %+v", id)
+	}
+}
+
+func TestMSSQLKerberosParams(t *testing.T) {
+	t.Setenv("KRB5_CONFIG", "/etc/krb5.conf")
+	t.Setenv("GAGOS_KRB5_KEYTAB", "/etc/gagos.keytab")
+	t.Setenv("GAGOS_KRB5_PRINCIPAL", "gagos@CORP.EXAMPLE.COM")
+
+	config := MSSQLConfig{Host: "sql.corp.example.com", Port: 1433, User: "sa", Password: "ignored", AuthMode: AuthKerberos}
+	cfg, err := config.connConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.User != "gagos" || cfg.Password != "" {
+		t.Errorf("login = %q/%q, want the principal without a password", cfg.User, cfg.Password)
+	}
+	want := map[string]string{
+		"authenticator":   "krb5",
+		"krb5-configfile": "/etc/krb5.conf",
+		"krb5-keytabfile": "/etc/gagos.keytab",
+		"krb5-realm":      "CORP.EXAMPLE.COM",
+	}
+	for k, v := range want {
+		if cfg.Parameters[k] != v {
+			t.Errorf("parameter %s = %q, want %q", k, cfg.Parameters[k], v)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gaga951/gagos/internal/egress"
+)
+
+// A client for the memcached text protocol: stats, slab info and single-key
+// get/set/delete. SASL needs the binary protocol and is not supported.
+
+// MaxMemcachedValue is the largest value set accepts, memcached's default
+// item size limit
+const MaxMemcachedValue = 1 << 20
+
+// MemcachedConfig holds Memcached connection configuration
+type MemcachedConfig struct {
+	Host string      `json:"host"`
+	Port int         `json:"port"`
+	TLS  *TLSOptions `json:"tls,omitempty"`
+}
+
+func (c *MemcachedConfig) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// MemcachedStats holds the main server statistics; Raw has all of them
+type MemcachedStats struct {
+	Version         string            `json:"version"`
+	Uptime          int64             `json:"uptime_seconds"`
+	UptimeHuman     string            `json:"uptime_human"`
+	CurrConnections int64             `json:"curr_connections"`
+	CurrItems       int64             `json:"curr_items"`
+	Bytes           int64             `json:"bytes"`
+	LimitMaxBytes   int64             `json:"limit_maxbytes"`
+	GetHits         int64             `json:"get_hits"`
+	GetMisses       int64             `json:"get_misses"`
+	HitRate         float64           `json:"hit_rate_percent"`
+	Evictions       int64             `json:"evictions"`
+	Raw             map[string]string `json:"raw"`
+	Duration        float64           `json:"duration_ms"`
+}
+
+// MemcachedSlab joins `stats slabs` and `stats items` for one slab class
+type MemcachedSlab struct {
+	ID            int   `json:"id"`
+	ChunkSize     int64 `json:"chunk_size"`
+	ChunksPerPage int64 `json:"chunks_per_page"`
+	TotalPages    int64 `json:"total_pages"`
+	TotalChunks   int64 `json:"total_chunks"`
+	UsedChunks    int64 `json:"used_chunks"`
+	FreeChunks    int64 `json:"free_chunks"`
+	MemRequested  int64 `json:"mem_requested"`
+	Items         int64 `json:"items"`
+	OldestAge     int64 `json:"oldest_age_seconds"`
+	Evicted       int64 `json:"evicted"`
+	OutOfMemory   int64 `json:"outofmemory"`
+}
+
+// MemcachedItem is the value of one key. Value is base64 when Encoding says
+// so, because it is not valid UTF-8.
+type MemcachedItem struct {
+	Key      string `json:"key"`
+	Found    bool   `json:"found"`
+	Flags    uint32 `json:"flags,omitempty"`
+	Size     int    `json:"size,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Encoding string `json:"encoding,omitempty"` // "base64" for binary values
+}
+
+// memcachedConn is one connection speaking the text protocol
+type memcachedConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialMemcached(ctx context.Context, config MemcachedConfig) (*memcachedConn, error) {
+	dial := egress.Default().DialContext
+	if mode := config.TLS.mode(""); mode != TLSDisable {
+		cfg, err := config.TLS.clientConfig(mode, config.Host)
+		if err != nil {
+			return nil, err
+		}
+		dial = tlsDialer(cfg)
+	}
+	g := backends.Get("memcached/" + config.Addr())
+	if err := g.Before(ctx); err != nil {
+		return nil, err
+	}
+	conn, err := dial(ctx, "tcp", config.Addr())
+	g.After(err)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return &memcachedConn{conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+func (m *memcachedConn) Close() error {
+	return m.conn.Close()
+}
+
+// send writes a command line, and data as a data block when not nil
+func (m *memcachedConn) send(line string, data []byte) error {
+	buf := []byte(line + "\r\n")
+	if data != nil {
+		buf = append(append(buf, data...), '\r', '\n')
+	}
+	_, err := m.conn.Write(buf)
+	return err
+}
+
+func (m *memcachedConn) readLine() (string, error) {
+	line, err := m.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	// ERROR, CLIENT_ERROR <msg> and SERVER_ERROR <msg>
+	if line == "ERROR" || strings.HasPrefix(line, "CLIENT_ERROR ") || strings.HasPrefix(line, "SERVER_ERROR ") {
+		return "", fmt.Errorf("memcached: %s", line)
+	}
+	return line, nil
+}
+
+// stats runs a stats command and returns its STAT lines as a map
+func (m *memcachedConn) stats(args string) (map[string]string, error) {
+	if err := m.send(strings.TrimSpace("stats "+args), nil); err != nil {
+		return nil, err
+	}
+	stats := map[string]string{}
+	for {
+		line, err := m.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "END" {
+			return stats, nil
+		}
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) == 3 && fields[0] == "STAT" {
+			stats[fields[1]] = fields[2]
+		}
+	}
+}
+
+// checkMemcachedKey enforces the text protocol's key rules
+func checkMemcachedKey(key string) error {
+	if key == "" || len(key) > 250 {
+		return fmt.Errorf("key must be 1 to 250 bytes")
+	}
+	for _, r := range key {
+		if r <= ' ' || r == 0x7f {
+			return fmt.Errorf("key must not contain spaces or control characters")
+		}
+	}
+	return nil
+}
+
+// GetMemcachedStats returns the server statistics
+func GetMemcachedStats(ctx context.Context, config MemcachedConfig) (*MemcachedStats, error) {
+	start := time.Now()
+	m, err := dialMemcached(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	raw, err := m.stats("")
+	if err != nil {
+		return nil, err
+	}
+	num := func(key string) int64 {
+		n, _ := strconv.ParseInt(raw[key], 10, 64)
+		return n
+	}
+	stats := &MemcachedStats{
+		Version:         raw["version"],
+		Uptime:          num("uptime"),
+		CurrConnections: num("curr_connections"),
+		CurrItems:       num("curr_items"),
+		Bytes:           num("bytes"),
+		LimitMaxBytes:   num("limit_maxbytes"),
+		GetHits:         num("get_hits"),
+		GetMisses:       num("get_misses"),
+		Evictions:       num("evictions"),
+		Raw:             raw,
+	}
+	stats.UptimeHuman = formatUptime(stats.Uptime)
+	if total := stats.GetHits + stats.GetMisses; total > 0 {
+		stats.HitRate = float64(stats.GetHits) / float64(total) * 100
+	}
+	stats.Duration = float64(time.Since(start).Microseconds()) / 1000.0
+	return stats, nil
+}
+
+// GetMemcachedSlabs returns each slab class with its item statistics
+func GetMemcachedSlabs(ctx context.Context, config MemcachedConfig) ([]MemcachedSlab, error) {
+	m, err := dialMemcached(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	slabStats, err := m.stats("slabs")
+	if err != nil {
+		return nil, err
+	}
+	itemStats, err := m.stats("items")
+	if err != nil {
+		return nil, err
+	}
+
+	slabs := map[int]*MemcachedSlab{}
+	slab := func(id int) *MemcachedSlab {
+		if slabs[id] == nil {
+			slabs[id] = &MemcachedSlab{ID: id}
+		}
+		return slabs[id]
+	}
+	// "1:chunk_size" in stats slabs, "items:1:number" in stats items
+	for k, v := range slabStats {
+		idStr, field, ok := strings.Cut(k, ":")
+		id, err := strconv.Atoi(idStr)
+		if !ok || err != nil {
+			continue
+		}
+		n, _ := strconv.ParseInt(v, 10, 64)
+		s := slab(id)
+		switch field {
+		case "chunk_size":
+			s.ChunkSize = n
+		case "chunks_per_page":
+			s.ChunksPerPage = n
+		case "total_pages":
+			s.TotalPages = n
+		case "total_chunks":
+			s.TotalChunks = n
+		case "used_chunks":
+			s.UsedChunks = n
+		case "free_chunks":
+			s.FreeChunks = n
+		case "mem_requested":
+			s.MemRequested = n
+		}
+	}
+	for k, v := range itemStats {
+		parts := strings.SplitN(k, ":", 3)
+		if len(parts) != 3 || parts[0] != "items" {
+			continue
+		}
+		id, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		n, _ := strconv.ParseInt(v, 10, 64)
+		s := slab(id)
+		switch parts[2] {
+		case "number":
+			s.Items = n
+		case "age":
+			s.OldestAge = n
+		case "evicted":
+			s.Evicted = n
+		case "outofmemory":
+			s.OutOfMemory = n
+		}
+	}
+
+	result := make([]MemcachedSlab, 0, len(slabs))
+	for _, s := range slabs {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// GetMemcachedKey returns the value of key; Found is false when it is absent
+func GetMemcachedKey(ctx context.Context, config MemcachedConfig, key string) (*MemcachedItem, error) {
+	if err := checkMemcachedKey(key); err != nil {
+		return nil, err
+	}
+	m, err := dialMemcached(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	if err := m.send("get "+key, nil); err != nil {
+		return nil, err
+	}
+	item := &MemcachedItem{Key: key}
+	for {
+		line, err := m.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "END" {
+			return item, nil
+		}
+		// VALUE <key> <flags> <bytes>
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] != "VALUE" {
+			return nil, fmt.Errorf("unexpected reply: %q", line)
+		}
+		flags, _ := strconv.ParseUint(fields[2], 10, 32)
+		size, err := strconv.Atoi(fields[3])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("unexpected reply: %q", line)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(m.r, data); err != nil {
+			return nil, err
+		}
+		data = data[:size]
+		item.Found, item.Flags, item.Size = true, uint32(flags), size
+		if utf8.Valid(data) {
+			item.Value = string(data)
+		} else {
+			item.Value, item.Encoding = base64.StdEncoding.EncodeToString(data), "base64"
+		}
+	}
+}
+
+// SetMemcachedKey stores value under key. ttl is in seconds, 0 for none.
+func SetMemcachedKey(ctx context.Context, config MemcachedConfig, key string, value []byte, flags uint32, ttl int64) error {
+	if err := checkMemcachedKey(key); err != nil {
+		return err
+	}
+	if len(value) > MaxMemcachedValue {
+		return fmt.Errorf("value must not exceed %d bytes", MaxMemcachedValue)
+	}
+	if ttl < 0 {
+		return fmt.Errorf("ttl must not be negative")
+	}
+	m, err := dialMemcached(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.send(fmt.Sprintf("set %s %d %d %d", key, flags, ttl, len(value)), value); err != nil {
+		return err
+	}
+	line, err := m.readLine()
+	if err != nil {
+		return err
+	}
+	if line != "STORED" {
+		return fmt.Errorf("not stored: %s", line)
+	}
+	return nil
+}
+
+// DeleteMemcachedKey deletes key and reports whether it existed
+func DeleteMemcachedKey(ctx context.Context, config MemcachedConfig, key string) (bool, error) {
+	if err := checkMemcachedKey(key); err != nil {
+		return false, err
+	}
+	m, err := dialMemcached(ctx, config)
+	if err != nil {
+		return false, err
+	}
+	defer m.Close()
+
+	if err := m.send("delete "+key, nil); err != nil {
+		return false, err
+	}
+	line, err := m.readLine()
+	if err != nil {
+		return false, err
+	}
+	switch line {
+	case "DELETED":
+		return true, nil
+	case "NOT_FOUND":
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected reply: %q", line)
+}
@@ -0,0 +1,761 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// Migrations run a directory of versioned SQL files against a connection
+// profile as a tracked job. Both goose and golang-migrate layouts are read, and
+// the applied versions are kept in the tool's own table (goose_db_version or
+// schema_migrations) so either tool can take over from GAGOS.
+
+// Migration formats
+const (
+	MigrationFormatGoose   = "goose"   // <version>_<name>.sql with -- +goose Up and Down sections
+	MigrationFormatMigrate = "migrate" // <version>_<name>.up.sql and .down.sql (golang-migrate)
+)
+
+// Migration run and step states
+const (
+	MigrationPending   = "pending"
+	MigrationRunning   = "running"
+	MigrationSucceeded = "succeeded"
+	MigrationFailed    = "failed"
+	MigrationPlanned   = "planned" // dry runs
+	MigrationSkipped   = "skipped" // not reached after a failure
+)
+
+// migrationTimeout bounds a whole run
+const migrationTimeout = 30 * time.Minute
+
+// maxMigrationRuns is how many runs List returns
+const maxMigrationRuns = 100
+
+// Migration is one version of a migration directory
+type Migration struct {
+	Version int64  `json:"version"`
+	Name    string `json:"name"`
+	Up      string `json:"-"`
+	Down    string `json:"-"`
+	NoTx    bool   `json:"no_transaction,omitempty"` // goose NO TRANSACTION
+}
+
+// MigrationSource is where a migration directory came from
+type MigrationSource struct {
+	Type         string `json:"type"` // git or bundle
+	URL          string `json:"url,omitempty"`
+	Ref          string `json:"ref,omitempty"`
+	Path         string `json:"path,omitempty"` // directory inside the repository or bundle
+	CredentialID string `json:"credential_id,omitempty"`
+	Commit       string `json:"commit,omitempty"`
+	File         string `json:"file,omitempty"`   // bundle file name
+	SHA256       string `json:"sha256,omitempty"` // bundle checksum
+}
+
+// MigrationSet is a loaded migration directory
+type MigrationSet struct {
+	Format     string
+	Source     MigrationSource
+	Migrations []Migration
+}
+
+// MigrationRequest selects what a run does
+type MigrationRequest struct {
+	ProfileID string `json:"profile_id"`
+	Format    string `json:"format,omitempty"`    // goose or migrate, detected when empty
+	Direction string `json:"direction,omitempty"` // up (default) or down
+	Target    int64  `json:"target,omitempty"`    // up: last version to apply; down: version to roll back to
+	Steps     int    `json:"steps,omitempty"`     // down without target: migrations to roll back, default 1
+	DryRun    bool   `json:"dry_run,omitempty"`
+}
+
+// MigrationStep is one migration applied or rolled back by a run
+type MigrationStep struct {
+	Version    int64      `json:"version"`
+	Name       string     `json:"name"`
+	Direction  string     `json:"direction"`
+	Status     string     `json:"status"`
+	SQL        string     `json:"sql,omitempty"` // dry runs only
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	DurationMs float64    `json:"duration_ms"`
+	Log        []string   `json:"log,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// MigrationRun is a tracked migration job
+type MigrationRun struct {
+	ID            string          `json:"id"`
+	ProfileID     string          `json:"profile_id"`
+	Kind          string          `json:"kind"`
+	Database      string          `json:"database"` // profile name, e.g. user@host:5432/db
+	Source        MigrationSource `json:"source"`
+	Format        string          `json:"format"`
+	Direction     string          `json:"direction"`
+	DryRun        bool            `json:"dry_run"`
+	Status        string          `json:"status"`
+	TriggeredBy   string          `json:"triggered_by"` // client address
+	StartedAt     time.Time       `json:"started_at"`
+	FinishedAt    *time.Time      `json:"finished_at,omitempty"`
+	VersionBefore int64           `json:"version_before"`
+	VersionAfter  int64           `json:"version_after"`
+	Steps         []MigrationStep `json:"steps"`
+	Error         string          `json:"error,omitempty"`
+}
+
+var (
+	// migratingProfiles holds the profiles a run is migrating, so two runs
+	// never change the same database at once
+	migratingProfiles   = make(map[string]bool)
+	migratingProfilesMu sync.Mutex
+
+	gooseFile   = regexp.MustCompile(`^(\d+)_(.+)\.sql$`)
+	migrateFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+)
+
+// LoadMigrations reads the migration files of dir. An empty format is
+// detected: any .up.sql file means golang-migrate, otherwise goose.
+func LoadMigrations(dir, format string) (string, []Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	if format == "" {
+		format = MigrationFormatGoose
+		for _, e := range entries {
+			if strings.HasSuffix(e.Name(), ".up.sql") {
+				format = MigrationFormatMigrate
+				break
+			}
+		}
+	}
+	if format != MigrationFormatGoose && format != MigrationFormatMigrate {
+		return "", nil, fmt.Errorf("unknown migration format %q (goose or migrate)", format)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		var m []string
+		if format == MigrationFormatMigrate {
+			m = migrateFile.FindStringSubmatch(e.Name())
+		} else if !strings.HasSuffix(e.Name(), ".up.sql") && !strings.HasSuffix(e.Name(), ".down.sql") {
+			m = gooseFile.FindStringSubmatch(e.Name())
+		}
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return "", nil, err
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] || format == MigrationFormatGoose {
+			return "", nil, fmt.Errorf("duplicate migration version %d", version)
+		}
+		switch {
+		case format == MigrationFormatGoose:
+			if err := parseGooseFile(mig, string(data)); err != nil {
+				return "", nil, fmt.Errorf("%s: %w", e.Name(), err)
+			}
+		case m[3] == "up":
+			mig.Up = string(data)
+		default:
+			mig.Down = string(data)
+		}
+	}
+	if len(byVersion) == 0 {
+		return "", nil, fmt.Errorf("no %s migrations found in %s", format, filepath.Base(dir))
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return format, migrations, nil
+}
+
+// parseGooseFile splits a goose file into its Up and Down sections. Statement
+// blocks are kept whole; each section is sent as one batch.
+func parseGooseFile(m *Migration, data string) error {
+	var up, down strings.Builder
+	var section *strings.Builder
+	for _, line := range strings.SplitAfter(data, "\n") {
+		annotation := strings.TrimSpace(line)
+		if strings.HasPrefix(annotation, "-- +goose ") {
+			switch strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(annotation, "-- +goose "))) {
+			case "UP":
+				section = &up
+			case "DOWN":
+				section = &down
+			case "NO TRANSACTION":
+				m.NoTx = true
+			}
+			continue
+		}
+		if section != nil {
+			section.WriteString(line)
+		}
+	}
+	if section == nil {
+		return fmt.Errorf("missing -- +goose Up annotation")
+	}
+	m.Up, m.Down = up.String(), down.String()
+	return nil
+}
+
+// migrationDialect holds what differs between the supported databases
+type migrationDialect struct {
+	kind        string
+	trueLit     string
+	falseLit    string
+	tableExists string // query with one %s for the table name
+	createGoose string
+	createMig   string
+}
+
+var migrationDialects = map[string]migrationDialect{
+	"postgres": {
+		kind: "postgres", trueLit: "TRUE", falseLit: "FALSE",
+		tableExists: "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = '%s'",
+		createGoose: "CREATE TABLE IF NOT EXISTS goose_db_version (id serial PRIMARY KEY, version_id bigint NOT NULL, is_applied boolean NOT NULL, tstamp timestamp DEFAULT now())",
+		createMig:   "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)",
+	},
+	"mysql": {
+		kind: "mysql", trueLit: "TRUE", falseLit: "FALSE",
+		tableExists: "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = '%s'",
+		createGoose: "CREATE TABLE IF NOT EXISTS goose_db_version (id serial, version_id bigint NOT NULL, is_applied boolean NOT NULL, tstamp timestamp NULL DEFAULT now(), PRIMARY KEY (id))",
+		createMig:   "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)",
+	},
+	"mssql": {
+		kind: "mssql", trueLit: "1", falseLit: "0",
+		tableExists: "SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = SCHEMA_NAME() AND TABLE_NAME = '%s'",
+		createGoose: "IF OBJECT_ID(N'goose_db_version', N'U') IS NULL CREATE TABLE goose_db_version (id int IDENTITY(1,1) PRIMARY KEY, version_id bigint NOT NULL, is_applied bit NOT NULL, tstamp datetime NULL DEFAULT CURRENT_TIMESTAMP)",
+		createMig:   "IF OBJECT_ID(N'schema_migrations', N'U') IS NULL CREATE TABLE schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty bit NOT NULL)",
+	},
+}
+
+// openMigrationTarget opens the primary of a profile's database
+func openMigrationTarget(ctx context.Context, profileID string) (*sql.DB, ConnectionStatus, error) {
+	config, status, ok := profileConfig(profileID)
+	if !ok {
+		return nil, status, fmt.Errorf("unknown connection profile %q: connect to the database first", profileID)
+	}
+	var db *sql.DB
+	var err error
+	switch c := config.(type) {
+	case PostgresConfig:
+		db, _, err = openPostgresFor(ctx, c, true)
+	case MySQLConfig:
+		c.multiStatements = true
+		db, _, err = openMySQLFor(ctx, c, true)
+	case MSSQLConfig:
+		db, err = openMSSQL(c)
+	default:
+		err = fmt.Errorf("migrations need a PostgreSQL, MySQL or SQL Server profile, not %s", status.Kind)
+	}
+	return db, status, err
+}
+
+// migrationState is the version table of one database
+type migrationState struct {
+	db      *sql.DB
+	dialect migrationDialect
+	format  string
+	applied map[int64]bool
+	current int64
+	dirty   bool
+}
+
+// readMigrationState reads the applied versions. The version table is only
+// created when create is set, so dry runs leave the database untouched.
+func readMigrationState(ctx context.Context, db *sql.DB, dialect migrationDialect, format string, create bool) (*migrationState, error) {
+	s := &migrationState{db: db, dialect: dialect, format: format, applied: map[int64]bool{}}
+	table := "schema_migrations"
+	if format == MigrationFormatGoose {
+		table = "goose_db_version"
+	}
+
+	var exists int
+	if err := db.QueryRowContext(ctx, fmt.Sprintf(dialect.tableExists, table)).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		if !create {
+			return s, nil
+		}
+		create := dialect.createMig
+		if format == MigrationFormatGoose {
+			create = dialect.createGoose
+		}
+		if _, err := db.ExecContext(ctx, create); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", table, err)
+		}
+		if format == MigrationFormatGoose {
+			// goose starts every table with version 0
+			if err := s.record(ctx, db, 0, true); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	}
+
+	if format == MigrationFormatMigrate {
+		err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations").Scan(&s.current, &s.dirty)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	// The latest row of a version decides whether it is applied
+	rows, err := db.QueryContext(ctx, "SELECT version_id, is_applied FROM goose_db_version ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	seen := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		var applied bool
+		if err := rows.Scan(&version, &applied); err != nil {
+			return nil, err
+		}
+		if seen[version] {
+			continue
+		}
+		seen[version] = true
+		if applied && version > 0 {
+			s.applied[version] = true
+			if version > s.current {
+				s.current = version
+			}
+		}
+	}
+	return s, rows.Err()
+}
+
+// sqlExecer is a *sql.DB or *sql.Tx
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// record stores version as applied (goose) or as the current version
+// (golang-migrate)
+func (s *migrationState) record(ctx context.Context, ex sqlExecer, version int64, applied bool) error {
+	if s.format == MigrationFormatGoose {
+		query := fmt.Sprintf("INSERT INTO goose_db_version (version_id, is_applied) VALUES (%d, %s)", version, s.dialect.trueLit)
+		if !applied {
+			query = fmt.Sprintf("DELETE FROM goose_db_version WHERE version_id = %d", version)
+		}
+		_, err := ex.ExecContext(ctx, query)
+		return err
+	}
+
+	dirty := s.dialect.falseLit
+	if !applied {
+		dirty = s.dialect.trueLit
+	}
+	if _, err := ex.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+	if version <= 0 {
+		return nil
+	}
+	_, err := ex.ExecContext(ctx, fmt.Sprintf("INSERT INTO schema_migrations (version, dirty) VALUES (%d, %s)", version, dirty))
+	return err
+}
+
+// plan returns the migrations to run, in order
+func (s *migrationState) plan(req MigrationRequest, migrations []Migration) ([]Migration, error) {
+	if s.dirty {
+		return nil, fmt.Errorf("database is dirty at version %d: fix it by hand, then force the version", s.current)
+	}
+	isApplied := func(v int64) bool {
+		if s.format == MigrationFormatGoose {
+			return s.applied[v]
+		}
+		return v <= s.current
+	}
+
+	var plan []Migration
+	if req.Direction == "down" {
+		steps := req.Steps
+		if steps <= 0 {
+			steps = 1
+		}
+		for i := len(migrations) - 1; i >= 0; i-- {
+			m := migrations[i]
+			if !isApplied(m.Version) {
+				continue
+			}
+			if req.Target > 0 && m.Version <= req.Target {
+				break
+			}
+			if req.Target == 0 && len(plan) == steps {
+				break
+			}
+			plan = append(plan, m)
+		}
+		return plan, nil
+	}
+
+	for _, m := range migrations {
+		if isApplied(m.Version) {
+			continue
+		}
+		if req.Target > 0 && m.Version > req.Target {
+			break
+		}
+		if m.Version < s.current {
+			// goose refuses these by default, and golang-migrate cannot record them
+			return nil, fmt.Errorf("migration %d_%s is older than the current version %d", m.Version, m.Name, s.current)
+		}
+		plan = append(plan, m)
+	}
+	return plan, nil
+}
+
+// versionBefore returns the version a database is at after rolling back m
+func (s *migrationState) versionBefore(m Migration, migrations []Migration) int64 {
+	var prev int64
+	for _, other := range migrations {
+		if other.Version >= m.Version {
+			break
+		}
+		if s.format == MigrationFormatMigrate || s.applied[other.Version] {
+			prev = other.Version
+		}
+	}
+	return prev
+}
+
+// StartMigration validates req and starts a run against the profile in the
+// background. The returned run is already saved.
+func StartMigration(req MigrationRequest, set *MigrationSet, triggeredBy string) (*MigrationRun, error) {
+	if req.Direction == "" {
+		req.Direction = "up"
+	}
+	if req.Direction != "up" && req.Direction != "down" {
+		return nil, fmt.Errorf("direction must be up or down")
+	}
+	if req.Format != "" && req.Format != set.Format {
+		return nil, fmt.Errorf("migrations are in %s format, not %s", set.Format, req.Format)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	db, status, err := openMigrationTarget(ctx, req.ProfileID)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if !req.DryRun {
+		migratingProfilesMu.Lock()
+		busy := migratingProfiles[req.ProfileID]
+		migratingProfiles[req.ProfileID] = true
+		migratingProfilesMu.Unlock()
+		if busy {
+			cancel()
+			db.Close()
+			return nil, fmt.Errorf("a migration is already running against %s", status.Name)
+		}
+	}
+
+	run := &MigrationRun{
+		ID:          migrationRunID(),
+		ProfileID:   req.ProfileID,
+		Kind:        status.Kind,
+		Database:    status.Name,
+		Source:      set.Source,
+		Format:      set.Format,
+		Direction:   req.Direction,
+		DryRun:      req.DryRun,
+		Status:      MigrationRunning,
+		TriggeredBy: triggeredBy,
+		StartedAt:   time.Now(),
+		Steps:       []MigrationStep{},
+	}
+	saveMigrationRun(run)
+
+	go func() {
+		defer cancel()
+		defer db.Close()
+		if !req.DryRun {
+			defer func() {
+				migratingProfilesMu.Lock()
+				delete(migratingProfiles, req.ProfileID)
+				migratingProfilesMu.Unlock()
+			}()
+		}
+		runMigrations(ctx, db, run, req, set.Migrations)
+	}()
+	return run, nil
+}
+
+// runMigrations plans and executes a run, saving it after every step
+func runMigrations(ctx context.Context, db *sql.DB, run *MigrationRun, req MigrationRequest, migrations []Migration) {
+	finish := func(err error) {
+		now := time.Now()
+		run.FinishedAt = &now
+		run.Status = MigrationSucceeded
+		if err != nil {
+			run.Status = MigrationFailed
+			run.Error = err.Error()
+		}
+		saveMigrationRun(run)
+		log.Info().Str("run", run.ID).Str("database", run.Database).Str("status", run.Status).
+			Bool("dry_run", run.DryRun).Int("steps", len(run.Steps)).Msg("Migration run finished")
+	}
+
+	state, err := readMigrationState(ctx, db, migrationDialects[run.Kind], run.Format, !run.DryRun)
+	if err != nil {
+		finish(fmt.Errorf("failed to read migration versions: %w", err))
+		return
+	}
+	run.VersionBefore, run.VersionAfter = state.current, state.current
+
+	plan, err := state.plan(req, migrations)
+	if err != nil {
+		finish(err)
+		return
+	}
+	for _, m := range plan {
+		step := MigrationStep{Version: m.Version, Name: m.Name, Direction: req.Direction, Status: MigrationPending, Log: []string{}}
+		if run.DryRun {
+			step.Status = MigrationPlanned
+			step.SQL = m.Up
+			if req.Direction == "down" {
+				step.SQL = m.Down
+			}
+		}
+		run.Steps = append(run.Steps, step)
+	}
+	if run.DryRun || len(plan) == 0 {
+		finish(nil)
+		return
+	}
+	saveMigrationRun(run)
+
+	for i, m := range plan {
+		step := &run.Steps[i]
+		now := time.Now()
+		step.StartedAt = &now
+		step.Status = MigrationRunning
+		saveMigrationRun(run)
+
+		err := state.apply(ctx, step, m, migrations)
+		step.DurationMs = float64(time.Since(now).Microseconds()) / 1000
+		if err != nil {
+			step.Status = MigrationFailed
+			step.Error = err.Error()
+			for j := i + 1; j < len(run.Steps); j++ {
+				run.Steps[j].Status = MigrationSkipped
+			}
+			finish(fmt.Errorf("%d_%s failed: %w", m.Version, m.Name, err))
+			return
+		}
+		step.Status = MigrationSucceeded
+		if req.Direction == "down" {
+			run.VersionAfter = state.versionBefore(m, migrations)
+		} else {
+			run.VersionAfter = m.Version
+		}
+	}
+	finish(nil)
+}
+
+// apply runs one migration and records it. goose migrations run in a
+// transaction unless marked NO TRANSACTION; golang-migrate ones mark the
+// version dirty first, as the tool does, and stay dirty if they fail.
+func (s *migrationState) apply(ctx context.Context, step *MigrationStep, m Migration, migrations []Migration) error {
+	logf := func(format string, args ...interface{}) {
+		step.Log = append(step.Log, time.Now().Format("15:04:05.000")+" "+fmt.Sprintf(format, args...))
+	}
+	body, version, applied := m.Up, m.Version, true
+	if step.Direction == "down" {
+		body, version, applied = m.Down, s.versionBefore(m, migrations), false
+	}
+	if strings.TrimSpace(body) == "" {
+		return fmt.Errorf("no %s migration", step.Direction)
+	}
+	batches := migrationBatches(s.dialect, body)
+	logf("%s %d_%s: %d batch(es)", step.Direction, m.Version, m.Name, len(batches))
+
+	if s.format == MigrationFormatMigrate {
+		if err := s.record(ctx, s.db, version, false); err != nil {
+			return fmt.Errorf("failed to mark version %d dirty: %w", version, err)
+		}
+		logf("marked version %d dirty", version)
+		if err := execBatches(ctx, s.db, batches, logf); err != nil {
+			return err
+		}
+		if err := s.record(ctx, s.db, version, true); err != nil {
+			return err
+		}
+		logf("version is now %d", version)
+		return nil
+	}
+
+	if m.NoTx {
+		logf("running without a transaction")
+		if err := execBatches(ctx, s.db, batches, logf); err != nil {
+			return err
+		}
+		if err := s.record(ctx, s.db, m.Version, applied); err != nil {
+			return err
+		}
+	} else {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := execBatches(ctx, tx, batches, logf); err != nil {
+			tx.Rollback()
+			logf("rolled back")
+			return err
+		}
+		if err := s.record(ctx, tx, m.Version, applied); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	if applied {
+		s.applied[m.Version] = true
+	} else {
+		delete(s.applied, m.Version)
+	}
+	logf("recorded version %d as %s", m.Version, map[bool]string{true: "applied", false: "rolled back"}[applied])
+	return nil
+}
+
+// migrationBatches splits a SQL Server migration on GO lines; other
+// databases take the whole body in one batch
+func migrationBatches(dialect migrationDialect, body string) []string {
+	if dialect.kind != "mssql" {
+		return []string{body}
+	}
+	var batches []string
+	var batch strings.Builder
+	for _, line := range strings.SplitAfter(body, "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), "GO") {
+			if strings.TrimSpace(batch.String()) != "" {
+				batches = append(batches, batch.String())
+			}
+			batch.Reset()
+			continue
+		}
+		batch.WriteString(line)
+	}
+	if strings.TrimSpace(batch.String()) != "" {
+		batches = append(batches, batch.String())
+	}
+	return batches
+}
+
+func execBatches(ctx context.Context, ex sqlExecer, batches []string, logf func(string, ...interface{})) error {
+	for i, batch := range batches {
+		start := time.Now()
+		result, err := ex.ExecContext(ctx, batch)
+		if err != nil {
+			logf("batch %d failed: %v", i+1, err)
+			return err
+		}
+		affected, _ := result.RowsAffected()
+		logf("batch %d done in %s, %d row(s) affected", i+1, time.Since(start).Round(time.Millisecond), affected)
+	}
+	return nil
+}
+
+func migrationRunID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "mig-" + hex.EncodeToString(b)
+}
+
+func saveMigrationRun(run *MigrationRun) {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return
+	}
+	if err := storage.GetBackend().Set(storage.BucketDBMigrations, run.ID, data); err != nil {
+		log.Error().Err(err).Str("run", run.ID).Msg("Failed to save migration run")
+	}
+}
+
+// GetMigrationRun returns a run by ID
+func GetMigrationRun(id string) (*MigrationRun, error) {
+	data, err := storage.GetBackend().Get(storage.BucketDBMigrations, id)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("migration run not found: %s", id)
+	}
+	var run MigrationRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListMigrationRuns returns the most recent runs, optionally of one profile,
+// without step logs
+func ListMigrationRuns(profileID string) ([]MigrationRun, error) {
+	dataList, err := storage.GetBackend().List(storage.BucketDBMigrations)
+	if err != nil {
+		return nil, err
+	}
+	runs := []MigrationRun{}
+	for _, data := range dataList {
+		var run MigrationRun
+		if err := json.Unmarshal(data, &run); err != nil {
+			continue
+		}
+		if profileID != "" && run.ProfileID != profileID {
+			continue
+		}
+		for i := range run.Steps {
+			run.Steps[i].Log = nil
+			run.Steps[i].SQL = ""
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	if len(runs) > maxMigrationRuns {
+		runs = runs[:maxMigrationRuns]
+	}
+	return runs, nil
+}
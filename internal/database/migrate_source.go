@@ -0,0 +1,61 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gaga951/gagos/internal/bundle"
+)
+
+// LoadGitMigrations clones src.URL at src.Ref (the default branch when empty)
+// and loads the migrations under src.Path. See bundle.Clone for which URLs
+// are accepted; username and password are never stored.
+func LoadGitMigrations(ctx context.Context, src MigrationSource, username, password, format string) (*MigrationSet, error) {
+	dir, err := os.MkdirTemp("", "gagos-migrations-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if src.Commit, err = bundle.Clone(ctx, src.URL, src.Ref, username, password, dir); err != nil {
+		return nil, err
+	}
+	return loadMigrationSet(dir, src, format)
+}
+
+// LoadBundleMigrations unpacks an uploaded .zip, .tar.gz, .tgz or .tar bundle
+// and loads the migrations under path. Only .sql files are extracted.
+func LoadBundleMigrations(r io.Reader, filename, path, format string) (*MigrationSet, error) {
+	dir, err := os.MkdirTemp("", "gagos-migrations-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	src := MigrationSource{Type: "bundle", File: filepath.Base(filename), Path: path}
+	src.SHA256, err = bundle.Extract(r, filename, dir, func(name string) bool {
+		return strings.HasSuffix(strings.ToLower(name), ".sql")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loadMigrationSet(dir, src, format)
+}
+
+func loadMigrationSet(root string, src MigrationSource, format string) (*MigrationSet, error) {
+	dir, err := bundle.Join(root, src.Path)
+	if err != nil {
+		return nil, err
+	}
+	format, migrations, err := LoadMigrations(dir, format)
+	if err != nil {
+		return nil, err
+	}
+	return &MigrationSet{Format: format, Source: src, Migrations: migrations}, nil
+}
@@ -0,0 +1,556 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/minio/minio-go/v7/pkg/signer"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// A client for the MinIO admin API (/minio/admin/v3), the one `mc admin`
+// uses. It only runs against endpoints detected as MinIO, and
+// GAGOS_MINIO_ADMIN=false turns it off altogether.
+
+var (
+	ErrMinIOAdminDisabled = errors.New("MinIO admin features are disabled (GAGOS_MINIO_ADMIN=false)")
+	ErrNotMinIO           = errors.New("endpoint is not a MinIO server")
+)
+
+const minioAdminPrefix = "/minio/admin/v3/"
+
+// MinIOAdminEnabled reports whether the MinIO admin features are on
+func MinIOAdminEnabled() bool {
+	return os.Getenv("GAGOS_MINIO_ADMIN") != "false"
+}
+
+// minioEndpoints remembers the endpoints already detected as MinIO
+var minioEndpoints sync.Map
+
+// DetectMinIO reports whether the endpoint is a MinIO server, from its
+// unauthenticated liveness probe
+func DetectMinIO(ctx context.Context, config S3Config) bool {
+	if _, ok := minioEndpoints.Load(config.Endpoint); ok {
+		return true
+	}
+	transport, secure, err := s3Transport(config)
+	if err != nil {
+		return false
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s3BaseURL(config.Endpoint, secure)+"/minio/health/live", nil)
+	if err != nil {
+		return false
+	}
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Server"), "MinIO") {
+		return false
+	}
+	minioEndpoints.Store(config.Endpoint, true)
+	return true
+}
+
+func s3BaseURL(endpoint string, secure bool) string {
+	if secure {
+		return "https://" + endpoint
+	}
+	return "http://" + endpoint
+}
+
+// minioAdmin signs admin API requests with the connection's keys
+type minioAdmin struct {
+	client               *http.Client
+	base                 string
+	region               string
+	accessKey, secretKey string
+	sessionToken         string
+}
+
+func newMinIOAdmin(ctx context.Context, config S3Config) (*minioAdmin, error) {
+	if !MinIOAdminEnabled() {
+		return nil, ErrMinIOAdminDisabled
+	}
+	if !DetectMinIO(ctx, config) {
+		return nil, ErrNotMinIO
+	}
+	transport, secure, err := s3Transport(config)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := s3Credentials(config).Get()
+	if err != nil {
+		return nil, err
+	}
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &minioAdmin{
+		client:       &http.Client{Transport: transport},
+		base:         s3BaseURL(config.Endpoint, secure) + minioAdminPrefix,
+		region:       region,
+		accessKey:    creds.AccessKeyID,
+		secretKey:    creds.SecretAccessKey,
+		sessionToken: creds.SessionToken,
+	}, nil
+}
+
+// call runs one admin API request and returns the response body
+func (a *minioAdmin) call(ctx context.Context, method, path string, query url.Values, body []byte) ([]byte, error) {
+	u := a.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	req.ContentLength = int64(len(body))
+	req = signer.SignV4(*req, a.accessKey, a.secretKey, a.sessionToken, a.region)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return nil, fmt.Errorf("minio: %s: %s", apiErr.Code, apiErr.Message)
+		}
+		return nil, fmt.Errorf("minio: %s", resp.Status)
+	}
+	return data, nil
+}
+
+func (a *minioAdmin) callJSON(ctx context.Context, method, path string, query url.Values, body []byte, out interface{}) error {
+	data, err := a.call(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// MinIOServerInfo is the deployment summary of `mc admin info`
+type MinIOServerInfo struct {
+	Mode         string `json:"mode"`
+	DeploymentID string `json:"deploymentID"`
+	Region       string `json:"region,omitempty"`
+	Buckets      struct {
+		Count uint64 `json:"count"`
+	} `json:"buckets"`
+	Objects struct {
+		Count uint64 `json:"count"`
+	} `json:"objects"`
+	Usage struct {
+		Size uint64 `json:"size"`
+	} `json:"usage"`
+	Backend struct {
+		Type             string `json:"backendType"`
+		OnlineDisks      int    `json:"onlineDisks"`
+		OfflineDisks     int    `json:"offlineDisks"`
+		StandardSCParity int    `json:"standardSCParity"`
+		TotalSets        []int  `json:"totalSets,omitempty"`
+	} `json:"backend"`
+	Servers []MinIOServer `json:"servers"`
+}
+
+// MinIOServer is one node of the deployment
+type MinIOServer struct {
+	State    string       `json:"state"`
+	Endpoint string       `json:"endpoint"`
+	Uptime   int64        `json:"uptime"`
+	Version  string       `json:"version"`
+	CommitID string       `json:"commitID,omitempty"`
+	Pool     int          `json:"poolNumber"`
+	Drives   []MinIODrive `json:"drives"`
+}
+
+// MinIODrive is one drive of a node
+type MinIODrive struct {
+	Endpoint       string `json:"endpoint"`
+	State          string `json:"state"`
+	Healing        bool   `json:"healing,omitempty"`
+	TotalSpace     uint64 `json:"totalspace"`
+	UsedSpace      uint64 `json:"usedspace"`
+	AvailableSpace uint64 `json:"availspace"`
+}
+
+// GetMinIOServerInfo returns the deployment's nodes, drives and usage
+func GetMinIOServerInfo(ctx context.Context, config S3Config) (*MinIOServerInfo, error) {
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	var info MinIOServerInfo
+	if err := a.callJSON(ctx, http.MethodGet, "info", nil, nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// MinIOHealStatus is the state of background healing. Sets is passed
+// through as MinIO reports it.
+type MinIOHealStatus struct {
+	OfflineEndpoints []string        `json:"offline_nodes"`
+	ScannedItems     uint64          `json:"scanned_items_count"`
+	HealDisks        []string        `json:"heal_disks"`
+	Sets             json.RawMessage `json:"sets,omitempty"`
+}
+
+// GetMinIOHealStatus returns the background healing status
+func GetMinIOHealStatus(ctx context.Context, config S3Config) (*MinIOHealStatus, error) {
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	var status MinIOHealStatus
+	if err := a.callJSON(ctx, http.MethodPost, "background-heal/status", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// MinIOUser is a user of MinIO's internal identity provider
+type MinIOUser struct {
+	AccessKey  string   `json:"access_key"`
+	PolicyName string   `json:"policy_name,omitempty"`
+	Status     string   `json:"status"`
+	MemberOf   []string `json:"member_of,omitempty"`
+}
+
+// minioUserInfo is the admin API's user record
+type minioUserInfo struct {
+	SecretKey  string   `json:"secretKey,omitempty"`
+	PolicyName string   `json:"policyName,omitempty"`
+	Status     string   `json:"status"`
+	MemberOf   []string `json:"memberOf,omitempty"`
+}
+
+// ListMinIOUsers lists the users, sorted by access key
+func ListMinIOUsers(ctx context.Context, config S3Config) ([]MinIOUser, error) {
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	data, err := a.call(ctx, http.MethodGet, "list-users", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The user list is encrypted with the caller's secret key
+	if data, err = madminDecrypt(a.secretKey, data); err != nil {
+		return nil, err
+	}
+	var users map[string]minioUserInfo
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, err
+	}
+	result := make([]MinIOUser, 0, len(users))
+	for key, u := range users {
+		result = append(result, MinIOUser{AccessKey: key, PolicyName: u.PolicyName, Status: u.Status, MemberOf: u.MemberOf})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].AccessKey < result[j].AccessKey })
+	return result, nil
+}
+
+// GetMinIOUser returns one user
+func GetMinIOUser(ctx context.Context, config S3Config, accessKey string) (*MinIOUser, error) {
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	var u minioUserInfo
+	if err := a.callJSON(ctx, http.MethodGet, "user-info", url.Values{"accessKey": {accessKey}}, nil, &u); err != nil {
+		return nil, err
+	}
+	return &MinIOUser{AccessKey: accessKey, PolicyName: u.PolicyName, Status: u.Status, MemberOf: u.MemberOf}, nil
+}
+
+// AddMinIOUser creates a user, or changes the secret key of an existing one
+func AddMinIOUser(ctx context.Context, config S3Config, accessKey, secretKey string) error {
+	if accessKey == "" || secretKey == "" {
+		return fmt.Errorf("access key and secret key are required")
+	}
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(minioUserInfo{SecretKey: secretKey, Status: "enabled"})
+	if err != nil {
+		return err
+	}
+	if body, err = madminEncrypt(a.secretKey, body); err != nil {
+		return err
+	}
+	_, err = a.call(ctx, http.MethodPut, "add-user", url.Values{"accessKey": {accessKey}}, body)
+	return err
+}
+
+// SetMinIOUserStatus enables or disables a user
+func SetMinIOUserStatus(ctx context.Context, config S3Config, accessKey string, enabled bool) error {
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return err
+	}
+	status := "disabled"
+	if enabled {
+		status = "enabled"
+	}
+	_, err = a.call(ctx, http.MethodPut, "set-user-status", url.Values{"accessKey": {accessKey}, "status": {status}}, nil)
+	return err
+}
+
+// RemoveMinIOUser deletes a user
+func RemoveMinIOUser(ctx context.Context, config S3Config, accessKey string) error {
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return err
+	}
+	_, err = a.call(ctx, http.MethodDelete, "remove-user", url.Values{"accessKey": {accessKey}}, nil)
+	return err
+}
+
+// SetMinIOPolicy sets the policies of a user or group; policies is a comma
+// separated list and replaces what was attached before
+func SetMinIOPolicy(ctx context.Context, config S3Config, policies, userOrGroup string, isGroup bool) error {
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return err
+	}
+	query := url.Values{
+		"policyName":  {policies},
+		"userOrGroup": {userOrGroup},
+		"isGroup":     {fmt.Sprint(isGroup)},
+	}
+	_, err = a.call(ctx, http.MethodPut, "set-user-or-group-policy", query, nil)
+	return err
+}
+
+// ListMinIOPolicies returns the canned policies by name
+func ListMinIOPolicies(ctx context.Context, config S3Config) (map[string]json.RawMessage, error) {
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	policies := map[string]json.RawMessage{}
+	if err := a.callJSON(ctx, http.MethodGet, "list-canned-policies", nil, nil, &policies); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// GetMinIOPolicy returns the document of one canned policy
+func GetMinIOPolicy(ctx context.Context, config S3Config, name string) (json.RawMessage, error) {
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	data, err := a.call(ctx, http.MethodGet, "info-canned-policy", url.Values{"name": {name}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(data), nil
+}
+
+// AddMinIOPolicy creates or replaces a canned policy
+func AddMinIOPolicy(ctx context.Context, config S3Config, name string, policy json.RawMessage) error {
+	if !json.Valid(policy) {
+		return fmt.Errorf("policy must be a JSON document")
+	}
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return err
+	}
+	_, err = a.call(ctx, http.MethodPut, "add-canned-policy", url.Values{"name": {name}}, policy)
+	return err
+}
+
+// RemoveMinIOPolicy deletes a canned policy
+func RemoveMinIOPolicy(ctx context.Context, config S3Config, name string) error {
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return err
+	}
+	_, err = a.call(ctx, http.MethodDelete, "remove-canned-policy", url.Values{"name": {name}}, nil)
+	return err
+}
+
+// MinIOBucketQuota is a bucket's hard quota in bytes; 0 means none
+type MinIOBucketQuota struct {
+	Quota uint64 `json:"quota"`
+	Type  string `json:"quotatype,omitempty"`
+}
+
+// GetMinIOBucketQuota returns the quota of a bucket
+func GetMinIOBucketQuota(ctx context.Context, config S3Config, bucket string) (*MinIOBucketQuota, error) {
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	var quota MinIOBucketQuota
+	if err := a.callJSON(ctx, http.MethodGet, "get-bucket-quota", url.Values{"bucket": {bucket}}, nil, &quota); err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// SetMinIOBucketQuota sets a hard quota on a bucket; 0 clears it
+func SetMinIOBucketQuota(ctx context.Context, config S3Config, bucket string, quota uint64) error {
+	a, err := newMinIOAdmin(ctx, config)
+	if err != nil {
+		return err
+	}
+	// Newer servers read size, older ones quota
+	body, err := json.Marshal(map[string]interface{}{"quota": quota, "size": quota, "quotatype": "hard"})
+	if err != nil {
+		return err
+	}
+	_, err = a.call(ctx, http.MethodPut, "set-bucket-quota", url.Values{"bucket": {bucket}}, body)
+	return err
+}
+
+// The admin API encrypts credentials in transit with the caller's secret
+// key: salt (32) | algorithm (1) | nonce (8) | sio stream. The stream is
+// sealed in 16 KiB fragments whose nonce ends in a little endian sequence
+// number; sequence 0 authenticates the (empty) associated data, and each
+// fragment's additional data is a flag byte, 0x80 on the last, followed by
+// that tag.
+const (
+	madminArgon2idAESGCM   = 0x00
+	madminArgon2idChaCha20 = 0x01
+	madminPBKDF2AESGCM     = 0x02
+
+	madminSaltSize     = 32
+	madminNonceSize    = 8
+	madminFragmentSize = 1 << 14
+)
+
+func madminAEAD(id byte, password string, salt []byte) (cipher.AEAD, error) {
+	switch id {
+	case madminArgon2idAESGCM, madminArgon2idChaCha20:
+		key := argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32)
+		if id == madminArgon2idChaCha20 {
+			return chacha20poly1305.New(key)
+		}
+		return newAESGCM(key)
+	case madminPBKDF2AESGCM:
+		return newAESGCM(pbkdf2.Key([]byte(password), salt, 8192, 32, sha256.New))
+	}
+	return nil, fmt.Errorf("minio: unknown encryption algorithm %d", id)
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// madminStream returns the per-fragment nonce function and additional data
+func madminStream(aead cipher.AEAD, prefix []byte) (func(seq uint32) []byte, []byte) {
+	nonce := func(seq uint32) []byte {
+		n := make([]byte, aead.NonceSize())
+		copy(n, prefix)
+		binary.LittleEndian.PutUint32(n[len(n)-4:], seq)
+		return n
+	}
+	ad := aead.Seal([]byte{0x00}, nonce(0), nil, nil)
+	return nonce, ad
+}
+
+func madminEncrypt(password string, data []byte) ([]byte, error) {
+	salt := make([]byte, madminSaltSize)
+	prefix := make([]byte, madminNonceSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	aead, err := madminAEAD(madminArgon2idAESGCM, password, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, ad := madminStream(aead, prefix)
+
+	out := append(append(append([]byte{}, salt...), madminArgon2idAESGCM), prefix...)
+	seq := uint32(1)
+	for {
+		n := min(len(data), madminFragmentSize)
+		last := n == len(data)
+		if last {
+			ad[0] = 0x80
+		}
+		out = aead.Seal(out, nonce(seq), data[:n], ad)
+		if last {
+			return out, nil
+		}
+		data = data[n:]
+		seq++
+	}
+}
+
+func madminDecrypt(password string, data []byte) ([]byte, error) {
+	if len(data) < madminSaltSize+1+madminNonceSize {
+		return nil, fmt.Errorf("minio: encrypted response too short")
+	}
+	salt, id := data[:madminSaltSize], data[madminSaltSize]
+	prefix := data[madminSaltSize+1 : madminSaltSize+1+madminNonceSize]
+	data = data[madminSaltSize+1+madminNonceSize:]
+	aead, err := madminAEAD(id, password, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, ad := madminStream(aead, prefix)
+
+	var out []byte
+	fragment := madminFragmentSize + aead.Overhead()
+	for seq := uint32(1); ; seq++ {
+		n := min(len(data), fragment)
+		last := n == len(data)
+		if last {
+			ad[0] = 0x80
+		}
+		if out, err = aead.Open(out, nonce(seq), data[:n], ad); err != nil {
+			return nil, fmt.Errorf("minio: could not decrypt response: %w", err)
+		}
+		if last {
+			return out, nil
+		}
+		data = data[n:]
+	}
+}
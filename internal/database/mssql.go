@@ -0,0 +1,520 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// MSSQLConfig represents SQL Server connection configuration
+type MSSQLConfig struct {
+	Host           string      `json:"host"`
+	Port           int         `json:"port"`
+	Instance       string      `json:"instance,omitempty"` // named instance, resolved through SQL Browser
+	User           string      `json:"user"`
+	Password       string      `json:"password"`
+	Database       string      `json:"database"`
+	ReadOnlyIntent bool        `json:"read_only_intent,omitempty"` // ApplicationIntent=ReadOnly, routes to a readable secondary
+	TLS            *TLSOptions `json:"tls,omitempty"`
+	AuthMode       string      `json:"auth_mode,omitempty"` // password (default) or kerberos
+}
+
+// connConfig builds the driver configuration. Without a TLS block the
+// driver default applies: only the login is encrypted.
+func (c *MSSQLConfig) connConfig() (msdsn.Config, error) {
+	query := url.Values{}
+	query.Set("database", c.Database)
+	query.Set("app name", "GAGOS")
+	query.Set("dial timeout", "10")
+	if c.ReadOnlyIntent {
+		query.Set("ApplicationIntent", "ReadOnly")
+	}
+	user := url.UserPassword(c.User, c.Password)
+	if c.AuthMode == AuthKerberos {
+		id, err := kerberosIdentity()
+		if err != nil {
+			return msdsn.Config{}, err
+		}
+		id.mssqlParams(query)
+		// A keytab logs in as its principal, a credential cache as its own
+		user = nil
+		if id.keytab != "" {
+			user = url.User(id.principal)
+		}
+	}
+	u := &url.URL{
+		Scheme:   "sqlserver",
+		User:     user,
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		RawQuery: query.Encode(),
+	}
+	if c.Instance != "" {
+		// The instance's port comes from SQL Browser
+		u.Host = c.Host
+		u.Path = "/" + c.Instance
+	}
+	cfg, err := msdsn.Parse(u.String())
+	if err != nil {
+		return cfg, err
+	}
+
+	if mode := c.TLS.mode(""); mode != TLSDisable {
+		tlsConfig, err := c.TLS.clientConfig(mode, c.Host)
+		if err != nil {
+			return cfg, err
+		}
+		// SQL Server expects one TDS packet per TLS record
+		tlsConfig.DynamicRecordSizingDisabled = true
+		cfg.Encryption = msdsn.EncryptionRequired
+		cfg.TLSConfig = tlsConfig
+	}
+	return cfg, nil
+}
+
+// MSSQLConnectionResult represents connection test result
+type MSSQLConnectionResult struct {
+	Success      bool    `json:"success"`
+	Version      string  `json:"version,omitempty"`
+	Edition      string  `json:"edition,omitempty"`
+	ResponseTime float64 `json:"response_time_ms,omitempty"`
+	Error        string  `json:"error,omitempty"`
+	ProfileID    string  `json:"profile_id,omitempty"`
+	Cached       bool    `json:"cached,omitempty"`
+}
+
+// MSSQLInfo represents database information
+type MSSQLInfo struct {
+	Version        string       `json:"version"`
+	Edition        string       `json:"edition"`
+	ServerName     string       `json:"server_name"`
+	DatabaseSize   string       `json:"database_size"`
+	TableCount     int          `json:"table_count"`
+	Uptime         int64        `json:"uptime_seconds"`
+	UptimeHuman    string       `json:"uptime_human"`
+	Connections    int          `json:"connections"`
+	MaxConnections int          `json:"max_connections"`
+	HadrEnabled    bool         `json:"hadr_enabled"` // Always On availability groups enabled
+	Tables         []MSSQLTable `json:"tables,omitempty"`
+	Error          string       `json:"error,omitempty"`
+}
+
+// MSSQLTable represents table information
+type MSSQLTable struct {
+	Schema    string `json:"schema"`
+	Name      string `json:"name"`
+	RowCount  int64  `json:"row_count"`
+	DataSize  string `json:"data_size"`
+	IndexSize string `json:"index_size"`
+}
+
+// MSSQLQueryResult represents query execution result
+type MSSQLQueryResult struct {
+	Columns       []string        `json:"columns,omitempty"`
+	Rows          [][]interface{} `json:"rows,omitempty"`
+	RowsAffected  int64           `json:"rows_affected"`
+	Duration      float64         `json:"duration_ms"`
+	Error         string          `json:"error,omitempty"`
+	Truncated     bool            `json:"truncated,omitempty"`      // more rows than the row cap
+	MaskedColumns []string        `json:"masked_columns,omitempty"` // columns masked by the result policy
+}
+
+// MSSQLAlwaysOn is the Always On availability group state seen from the
+// connected replica
+type MSSQLAlwaysOn struct {
+	Enabled bool                     `json:"enabled"`
+	Groups  []MSSQLAvailabilityGroup `json:"groups"`
+}
+
+// MSSQLAvailabilityGroup is one availability group
+type MSSQLAvailabilityGroup struct {
+	Name                  string            `json:"name"`
+	PrimaryReplica        string            `json:"primary_replica"`
+	SynchronizationHealth string            `json:"synchronization_health"`
+	Replicas              []MSSQLAGReplica  `json:"replicas"`
+	Databases             []MSSQLAGDatabase `json:"databases"`
+}
+
+// MSSQLAGReplica is one replica of an availability group. Role and state are
+// only known for the local replica unless connected to the primary.
+type MSSQLAGReplica struct {
+	Server                string `json:"server"`
+	Role                  string `json:"role"`
+	AvailabilityMode      string `json:"availability_mode"`
+	FailoverMode          string `json:"failover_mode"`
+	ConnectedState        string `json:"connected_state"`
+	SynchronizationHealth string `json:"synchronization_health"`
+}
+
+// MSSQLAGDatabase is one database on one replica of an availability group
+type MSSQLAGDatabase struct {
+	Database              string `json:"database"`
+	Replica               string `json:"replica"`
+	SynchronizationState  string `json:"synchronization_state"`
+	SynchronizationHealth string `json:"synchronization_health"`
+	LogSendQueueKB        *int64 `json:"log_send_queue_kb,omitempty"`
+	RedoQueueKB           *int64 `json:"redo_queue_kb,omitempty"`
+}
+
+// TestMSSQLConnection tests a SQL Server connection
+func TestMSSQLConnection(ctx context.Context, config MSSQLConfig) MSSQLConnectionResult {
+	start := time.Now()
+
+	db, err := openMSSQL(config)
+	if err != nil {
+		return MSSQLConnectionResult{
+			Success: false,
+			Error:   "Failed to open connection: " + err.Error(),
+		}
+	}
+	defer db.Close()
+
+	db.SetConnMaxLifetime(10 * time.Second)
+	db.SetMaxOpenConns(1)
+
+	var version, edition string
+	err = db.QueryRowContext(ctx, "SELECT CAST(SERVERPROPERTY('ProductVersion') AS nvarchar(128)), CAST(SERVERPROPERTY('Edition') AS nvarchar(128))").Scan(&version, &edition)
+	if err != nil {
+		return MSSQLConnectionResult{
+			Success: false,
+			Error:   "Failed to query: " + err.Error(),
+		}
+	}
+
+	return MSSQLConnectionResult{
+		Success:      true,
+		Version:      version,
+		Edition:      edition,
+		ResponseTime: float64(time.Since(start).Microseconds()) / 1000.0,
+	}
+}
+
+// GetMSSQLInfo retrieves database information
+func GetMSSQLInfo(ctx context.Context, config MSSQLConfig) MSSQLInfo {
+	db, err := openMSSQL(config)
+	if err != nil {
+		return MSSQLInfo{Error: "Failed to connect: " + err.Error()}
+	}
+	defer db.Close()
+
+	info := MSSQLInfo{}
+
+	// Get version and edition
+	var hadr int
+	if err := db.QueryRowContext(ctx, `
+		SELECT
+			CAST(SERVERPROPERTY('ProductVersion') AS nvarchar(128)),
+			CAST(SERVERPROPERTY('Edition') AS nvarchar(128)),
+			COALESCE(@@SERVERNAME, ''),
+			COALESCE(CAST(SERVERPROPERTY('IsHadrEnabled') AS int), 0),
+			@@MAX_CONNECTIONS
+	`).Scan(&info.Version, &info.Edition, &info.ServerName, &hadr, &info.MaxConnections); err != nil {
+		return MSSQLInfo{Error: err.Error()}
+	}
+	info.HadrEnabled = hadr == 1
+
+	// Get database size
+	db.QueryRowContext(ctx, `
+		SELECT CONCAT(CAST(SUM(size) * 8 / 1024.0 AS decimal(18, 2)), ' MB')
+		FROM sys.database_files
+	`).Scan(&info.DatabaseSize)
+
+	// Get table count
+	db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sys.tables").Scan(&info.TableCount)
+
+	// Uptime and sessions need VIEW SERVER STATE
+	var started time.Time
+	if err := db.QueryRowContext(ctx, "SELECT sqlserver_start_time FROM sys.dm_os_sys_info").Scan(&started); err == nil {
+		info.Uptime = int64(time.Since(started).Seconds())
+		info.UptimeHuman = formatMySQLUptime(info.Uptime)
+	}
+	db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sys.dm_exec_sessions WHERE is_user_process = 1").Scan(&info.Connections)
+
+	// Get tables info
+	rows, err := db.QueryContext(ctx, `
+		SELECT TOP 50
+			s.name,
+			t.name,
+			COALESCE(SUM(CASE WHEN p.index_id IN (0, 1) THEN p.row_count END), 0),
+			CONCAT(CAST(SUM(CASE WHEN p.index_id IN (0, 1) THEN p.used_page_count ELSE 0 END) * 8 / 1024.0 AS decimal(18, 2)), ' MB'),
+			CONCAT(CAST(SUM(CASE WHEN p.index_id > 1 THEN p.used_page_count ELSE 0 END) * 8 / 1024.0 AS decimal(18, 2)), ' MB')
+		FROM sys.tables t
+		JOIN sys.schemas s ON s.schema_id = t.schema_id
+		LEFT JOIN sys.dm_db_partition_stats p ON p.object_id = t.object_id
+		GROUP BY s.name, t.name
+		ORDER BY SUM(p.used_page_count) DESC
+	`)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var t MSSQLTable
+			rows.Scan(&t.Schema, &t.Name, &t.RowCount, &t.DataSize, &t.IndexSize)
+			info.Tables = append(info.Tables, t)
+		}
+	}
+
+	return info
+}
+
+// ExecuteMSSQLQuery executes a SQL query under the same rules as
+// ExecuteMySQLQuery. SQL Server has no read-only transactions, so reads that
+// must not write run in a transaction that is always rolled back.
+func ExecuteMSSQLQuery(ctx context.Context, config MSSQLConfig, query string, opts QueryOptions) MSSQLQueryResult {
+	start := time.Now()
+
+	query = strings.TrimSpace(query)
+	kind, readTx, err := checkStatement(query, DialectMSSQL, opts)
+	if err != nil {
+		return MSSQLQueryResult{Error: err.Error()}
+	}
+
+	db, err := openMSSQL(config)
+	if err != nil {
+		return MSSQLQueryResult{Error: "Failed to connect: " + err.Error()}
+	}
+	defer db.Close()
+
+	// There is no statement timeout setting; cancelling the context sends
+	// an attention that stops the statement on the server
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+
+	runner, done, err := beginRolledBack(ctx, db, readTx)
+	if err != nil {
+		return MSSQLQueryResult{Error: "Failed to connect: " + err.Error()}
+	}
+	defer done()
+
+	if kind == StatementRead {
+		rows, err := runner.QueryContext(ctx, query)
+		if err != nil {
+			return MSSQLQueryResult{
+				Error:    err.Error(),
+				Duration: float64(time.Since(start).Microseconds()) / 1000.0,
+			}
+		}
+		defer rows.Close()
+
+		cols, _ := rows.Columns()
+		types, _ := rows.ColumnTypes()
+		result := MSSQLQueryResult{
+			Columns: cols,
+			Rows:    make([][]interface{}, 0),
+		}
+
+		for rows.Next() {
+			if len(result.Rows) >= opts.Policy.maxRows() {
+				result.Truncated = true
+				break
+			}
+
+			values := make([]interface{}, len(cols))
+			valuePtrs := make([]interface{}, len(cols))
+			for i := range values {
+				valuePtrs[i] = &values[i]
+			}
+
+			rows.Scan(valuePtrs...)
+
+			row := make([]interface{}, len(cols))
+			for i, v := range values {
+				row[i] = mssqlValue(v, types[i].DatabaseTypeName())
+			}
+			result.Rows = append(result.Rows, row)
+		}
+		result.MaskedColumns = opts.Policy.apply(cols, result.Rows)
+
+		result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
+		return result
+	}
+
+	// Execute non-SELECT query
+	res, err := runner.ExecContext(ctx, query)
+	if err != nil {
+		return MSSQLQueryResult{
+			Error:    err.Error(),
+			Duration: float64(time.Since(start).Microseconds()) / 1000.0,
+		}
+	}
+
+	affected, _ := res.RowsAffected()
+	return MSSQLQueryResult{
+		RowsAffected: affected,
+		Duration:     float64(time.Since(start).Microseconds()) / 1000.0,
+	}
+}
+
+// beginRolledBack pins a connection from db and, when readTx is set, opens a
+// transaction on it that done rolls back
+func beginRolledBack(ctx context.Context, db *sql.DB, readTx bool) (runner sqlRunner, done func(), err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !readTx {
+		return conn, func() { conn.Close() }, nil
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return tx, func() {
+		tx.Rollback()
+		conn.Close()
+	}, nil
+}
+
+// mssqlValue converts a scanned value for JSON output
+func mssqlValue(v interface{}, typeName string) interface{} {
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+	if typeName == "UNIQUEIDENTIFIER" {
+		var id mssql.UniqueIdentifier
+		if err := id.Scan(b); err == nil {
+			return id.String()
+		}
+	}
+	return string(b)
+}
+
+// GetMSSQLDatabases lists all user databases
+func GetMSSQLDatabases(ctx context.Context, config MSSQLConfig) ([]string, error) {
+	connConfig := config
+	connConfig.Database = "master"
+
+	db, err := openMSSQL(connConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// database_id 1-4 are master, tempdb, model and msdb
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sys.databases WHERE database_id > 4 ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var databases []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		databases = append(databases, name)
+	}
+	return databases, nil
+}
+
+// GetMSSQLAlwaysOn returns the availability groups, their replicas and the
+// synchronization state of their databases. It needs VIEW SERVER STATE.
+func GetMSSQLAlwaysOn(ctx context.Context, config MSSQLConfig) (*MSSQLAlwaysOn, error) {
+	db, err := openMSSQL(config)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	result := &MSSQLAlwaysOn{Groups: []MSSQLAvailabilityGroup{}}
+	var hadr int
+	if err := db.QueryRowContext(ctx, "SELECT COALESCE(CAST(SERVERPROPERTY('IsHadrEnabled') AS int), 0)").Scan(&hadr); err != nil {
+		return nil, err
+	}
+	if hadr != 1 {
+		return result, nil
+	}
+	result.Enabled = true
+
+	groups := map[string]*MSSQLAvailabilityGroup{}
+	var order []string
+	rows, err := db.QueryContext(ctx, `
+		SELECT ag.name, COALESCE(gs.primary_replica, ''), COALESCE(gs.synchronization_health_desc, '')
+		FROM sys.availability_groups ag
+		LEFT JOIN sys.dm_hadr_availability_group_states gs ON gs.group_id = ag.group_id
+		ORDER BY ag.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		g := &MSSQLAvailabilityGroup{Replicas: []MSSQLAGReplica{}, Databases: []MSSQLAGDatabase{}}
+		if err := rows.Scan(&g.Name, &g.PrimaryReplica, &g.SynchronizationHealth); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		groups[g.Name] = g
+		order = append(order, g.Name)
+	}
+	rows.Close()
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT ag.name, ar.replica_server_name, COALESCE(rs.role_desc, ''),
+			ar.availability_mode_desc, ar.failover_mode_desc,
+			COALESCE(rs.connected_state_desc, ''), COALESCE(rs.synchronization_health_desc, '')
+		FROM sys.availability_replicas ar
+		JOIN sys.availability_groups ag ON ag.group_id = ar.group_id
+		LEFT JOIN sys.dm_hadr_availability_replica_states rs ON rs.replica_id = ar.replica_id
+		ORDER BY ag.name, ar.replica_server_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var group string
+		var r MSSQLAGReplica
+		if err := rows.Scan(&group, &r.Server, &r.Role, &r.AvailabilityMode, &r.FailoverMode, &r.ConnectedState, &r.SynchronizationHealth); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if g := groups[group]; g != nil {
+			g.Replicas = append(g.Replicas, r)
+		}
+	}
+	rows.Close()
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT ag.name, COALESCE(DB_NAME(drs.database_id), ''), ar.replica_server_name,
+			COALESCE(drs.synchronization_state_desc, ''), COALESCE(drs.synchronization_health_desc, ''),
+			drs.log_send_queue_size, drs.redo_queue_size
+		FROM sys.dm_hadr_database_replica_states drs
+		JOIN sys.availability_groups ag ON ag.group_id = drs.group_id
+		JOIN sys.availability_replicas ar ON ar.replica_id = drs.replica_id
+		ORDER BY ag.name, 2, ar.replica_server_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var group string
+		var d MSSQLAGDatabase
+		var logSend, redo sql.NullInt64
+		if err := rows.Scan(&group, &d.Database, &d.Replica, &d.SynchronizationState, &d.SynchronizationHealth, &logSend, &redo); err != nil {
+			return nil, err
+		}
+		if logSend.Valid {
+			d.LogSendQueueKB = &logSend.Int64
+		}
+		if redo.Valid {
+			d.RedoQueueKB = &redo.Int64
+		}
+		if g := groups[group]; g != nil {
+			g.Databases = append(g.Databases, d)
+		}
+	}
+
+	for _, name := range order {
+		result.Groups = append(result.Groups, *groups[name])
+	}
+	return result, nil
+}
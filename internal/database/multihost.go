@@ -0,0 +1,193 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gaga951/gagos/internal/fanout"
+)
+
+// PostgreSQL and MySQL configs may list several hosts with primary/replica
+// roles. Reads can be sent to a replica and every operation fails over to the
+// next candidate host when one cannot be reached.
+
+// Host roles
+const (
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
+
+// Query targets
+const (
+	TargetAuto    = "auto"    // reads to a replica when one answers, writes to the primary
+	TargetPrimary = "primary" // everything to the primary
+	TargetReplica = "replica" // reads only, to a replica
+)
+
+// failoverTimeout bounds how long one candidate host may take to answer
+const failoverTimeout = 5 * time.Second
+
+// DBHost is one server of a multi-host PostgreSQL or MySQL deployment
+type DBHost struct {
+	Host string `json:"host"`
+	Port int    `json:"port,omitempty"` // defaults to the config's port
+	Role string `json:"role,omitempty"` // primary (default) or replica
+}
+
+func (h DBHost) Addr() string {
+	return net.JoinHostPort(h.Host, strconv.Itoa(h.Port))
+}
+
+// HostStatus is the state of one configured host as seen by a connection test
+type HostStatus struct {
+	Host      string `json:"host"`
+	Port      int    `json:"port"`
+	Role      string `json:"role"`
+	Reachable bool   `json:"reachable"`
+	ReadOnly  bool   `json:"read_only"` // in recovery (PostgreSQL) or read_only (MySQL)
+	Error     string `json:"error,omitempty"`
+}
+
+// resolveHosts returns the configured hosts, or the single host/port pair
+// when no list is given
+func resolveHosts(host string, port int, hosts []DBHost) []DBHost {
+	if len(hosts) == 0 {
+		return []DBHost{{Host: host, Port: port, Role: RolePrimary}}
+	}
+	resolved := make([]DBHost, 0, len(hosts))
+	for _, h := range hosts {
+		if h.Port == 0 {
+			h.Port = port
+		}
+		if h.Role == "" {
+			h.Role = RolePrimary
+		}
+		resolved = append(resolved, h)
+	}
+	return resolved
+}
+
+// hostsLabel describes the configured hosts for display
+func hostsLabel(host string, port int, hosts []DBHost) string {
+	resolved := resolveHosts(host, port, hosts)
+	addrs := make([]string, 0, len(resolved))
+	for _, h := range resolved {
+		addrs = append(addrs, h.Addr())
+	}
+	return strings.Join(addrs, ",")
+}
+
+// targetHosts orders the candidate hosts for an operation. Writes only go to
+// primaries; reads with TargetAuto prefer replicas and fall back to primaries.
+func targetHosts(hosts []DBHost, target string, write bool) ([]DBHost, error) {
+	var primaries, replicas []DBHost
+	for _, h := range hosts {
+		switch h.Role {
+		case RolePrimary:
+			primaries = append(primaries, h)
+		case RoleReplica:
+			replicas = append(replicas, h)
+		default:
+			return nil, fmt.Errorf("host %s: role must be primary or replica", h.Addr())
+		}
+	}
+
+	switch target {
+	case "", TargetAuto:
+		if write {
+			break
+		}
+		return append(replicas, primaries...), nil
+	case TargetPrimary:
+	case TargetReplica:
+		if write {
+			return nil, fmt.Errorf("writes cannot run on a replica, use target primary")
+		}
+		if len(replicas) == 0 {
+			return nil, fmt.Errorf("no replica host configured")
+		}
+		return replicas, nil
+	default:
+		return nil, fmt.Errorf("target must be auto, primary or replica")
+	}
+
+	if len(primaries) == 0 {
+		return nil, fmt.Errorf("no primary host configured")
+	}
+	return primaries, nil
+}
+
+// openFailover opens the first candidate that answers and, for writes, is not
+// read-only according to readOnlyQuery. A single candidate is opened as is.
+func openFailover(ctx context.Context, hosts []DBHost, write bool, readOnlyQuery string, open func(DBHost) (*sql.DB, error)) (*sql.DB, DBHost, error) {
+	if len(hosts) == 1 {
+		db, err := open(hosts[0])
+		return db, hosts[0], err
+	}
+
+	var failures []string
+	for _, h := range hosts {
+		db, err := open(h)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", h.Addr(), err))
+			continue
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, failoverTimeout)
+		err = db.PingContext(checkCtx)
+		if err == nil && write {
+			var readOnly bool
+			if err = db.QueryRowContext(checkCtx, readOnlyQuery).Scan(&readOnly); err == nil && readOnly {
+				err = fmt.Errorf("server is read-only")
+			}
+		}
+		cancel()
+		if err == nil {
+			return db, h, nil
+		}
+
+		db.Close()
+		failures = append(failures, fmt.Sprintf("%s: %v", h.Addr(), err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, DBHost{}, fmt.Errorf("no usable host (%s)", strings.Join(failures, "; "))
+}
+
+// checkHosts tests every configured host concurrently
+func checkHosts(ctx context.Context, hosts []DBHost, readOnlyQuery string, open func(DBHost) (*sql.DB, error)) []HostStatus {
+	statuses := make([]HostStatus, len(hosts))
+	tasks := make(map[string]fanout.Func, len(hosts))
+	for i, h := range hosts {
+		i, h := i, h
+		statuses[i] = HostStatus{Host: h.Host, Port: h.Port, Role: h.Role}
+		tasks[strconv.Itoa(i)] = func(ctx context.Context) error {
+			db, err := open(h)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			if err := db.QueryRowContext(ctx, readOnlyQuery).Scan(&statuses[i].ReadOnly); err != nil {
+				return err
+			}
+			statuses[i].Reachable = true
+			return nil
+		}
+	}
+
+	for key, err := range fanout.Run(ctx, fanout.Options{Timeout: failoverTimeout}, tasks) {
+		i, _ := strconv.Atoi(key)
+		statuses[i].Error = err.Error()
+	}
+	return statuses
+}
@@ -8,16 +8,24 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gaga951/gagos/internal/egress"
 	_ "github.com/go-sql-driver/mysql"
 )
 
 // MySQLConfig represents MySQL/MariaDB connection configuration
 type MySQLConfig struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	User     string `json:"user"`
-	Password string `json:"password"`
-	Database string `json:"database"`
+	Host     string      `json:"host"`
+	Port     int         `json:"port"`
+	User     string      `json:"user"`
+	Password string      `json:"password"`
+	Database string      `json:"database"`
+	Hosts    []DBHost    `json:"hosts,omitempty"`  // multi-host deployment; Host/Port are used when empty
+	Target   string      `json:"target,omitempty"` // auto (default), primary or replica
+	TLS      *TLSOptions `json:"tls,omitempty"`
+	AuthMode string      `json:"auth_mode,omitempty"` // password (default) or iam for an RDS IAM token
+	Region   string      `json:"region,omitempty"`    // AWS region for IAM auth, from the host name when empty
+
+	multiStatements bool // allow several statements per Exec, for migrations
 }
 
 func (c *MySQLConfig) DSN() string {
@@ -25,13 +33,36 @@ func (c *MySQLConfig) DSN() string {
 		c.User, c.Password, c.Host, c.Port, c.Database)
 }
 
+// mysqlReadOnlyQuery tells a read-only replica from a writable primary
+const mysqlReadOnlyQuery = "SELECT @@global.read_only"
+
+// openMySQLFor opens the host config.Target selects for a read or write,
+// failing over to the next candidate when a host does not answer
+func openMySQLFor(ctx context.Context, config MySQLConfig, write bool) (*sql.DB, DBHost, error) {
+	hosts, err := targetHosts(resolveHosts(config.Host, config.Port, config.Hosts), config.Target, write)
+	if err != nil {
+		return nil, DBHost{}, err
+	}
+	return openFailover(ctx, hosts, write, mysqlReadOnlyQuery, config.openHost)
+}
+
+// openHost opens one host of a multi-host config
+func (c MySQLConfig) openHost(h DBHost) (*sql.DB, error) {
+	c.Host, c.Port = h.Host, h.Port
+	return openMySQL(c)
+}
+
 // MySQLConnectionResult represents connection test result
 type MySQLConnectionResult struct {
-	Success      bool    `json:"success"`
-	Version      string  `json:"version,omitempty"`
-	ServerType   string  `json:"server_type,omitempty"`
-	ResponseTime float64 `json:"response_time_ms,omitempty"`
-	Error        string  `json:"error,omitempty"`
+	Success      bool         `json:"success"`
+	Version      string       `json:"version,omitempty"`
+	ServerType   string       `json:"server_type,omitempty"`
+	ResponseTime float64      `json:"response_time_ms,omitempty"`
+	Error        string       `json:"error,omitempty"`
+	Host         string       `json:"host,omitempty"`  // host that answered
+	Hosts        []HostStatus `json:"hosts,omitempty"` // every host of a multi-host config
+	ProfileID    string       `json:"profile_id,omitempty"`
+	Cached       bool         `json:"cached,omitempty"`
 }
 
 // MySQLInfo represents database information
@@ -60,11 +91,14 @@ type MySQLTable struct {
 
 // MySQLQueryResult represents query execution result
 type MySQLQueryResult struct {
-	Columns      []string        `json:"columns,omitempty"`
-	Rows         [][]interface{} `json:"rows,omitempty"`
-	RowsAffected int64           `json:"rows_affected"`
-	Duration     float64         `json:"duration_ms"`
-	Error        string          `json:"error,omitempty"`
+	Columns       []string        `json:"columns,omitempty"`
+	Rows          [][]interface{} `json:"rows,omitempty"`
+	RowsAffected  int64           `json:"rows_affected"`
+	Duration      float64         `json:"duration_ms"`
+	Error         string          `json:"error,omitempty"`
+	Truncated     bool            `json:"truncated,omitempty"`      // more rows than the row cap
+	MaskedColumns []string        `json:"masked_columns,omitempty"` // columns masked by the result policy
+	Host          string          `json:"host,omitempty"`           // host the statement ran on
 }
 
 // MySQLDumpResult represents dump operation result
@@ -80,12 +114,16 @@ type MySQLDumpResult struct {
 func TestMySQLConnection(ctx context.Context, config MySQLConfig) MySQLConnectionResult {
 	start := time.Now()
 
-	db, err := sql.Open("mysql", config.DSN())
+	db, host, err := openMySQLFor(ctx, config, false)
 	if err != nil {
-		return MySQLConnectionResult{
+		result := MySQLConnectionResult{
 			Success: false,
 			Error:   "Failed to open connection: " + err.Error(),
 		}
+		if len(config.Hosts) > 1 {
+			result.Hosts = checkHosts(ctx, resolveHosts(config.Host, config.Port, config.Hosts), mysqlReadOnlyQuery, config.openHost)
+		}
+		return result
 	}
 	defer db.Close()
 
@@ -106,17 +144,22 @@ func TestMySQLConnection(ctx context.Context, config MySQLConfig) MySQLConnectio
 		serverType = "MariaDB"
 	}
 
-	return MySQLConnectionResult{
+	result := MySQLConnectionResult{
 		Success:      true,
 		Version:      version,
 		ServerType:   serverType,
+		Host:         host.Addr(),
 		ResponseTime: float64(time.Since(start).Microseconds()) / 1000.0,
 	}
+	if len(config.Hosts) > 1 {
+		result.Hosts = checkHosts(ctx, resolveHosts(config.Host, config.Port, config.Hosts), mysqlReadOnlyQuery, config.openHost)
+	}
+	return result
 }
 
 // GetMySQLInfo retrieves database information
 func GetMySQLInfo(ctx context.Context, config MySQLConfig) MySQLInfo {
-	db, err := sql.Open("mysql", config.DSN())
+	db, _, err := openMySQLFor(ctx, config, false)
 	if err != nil {
 		return MySQLInfo{Error: "Failed to connect: " + err.Error()}
 	}
@@ -197,28 +240,39 @@ func GetMySQLInfo(ctx context.Context, config MySQLConfig) MySQLInfo {
 	return info
 }
 
-// ExecuteMySQLQuery executes a SQL query
-func ExecuteMySQLQuery(ctx context.Context, config MySQLConfig, query string, readonly bool) MySQLQueryResult {
+// ExecuteMySQLQuery executes a SQL query. Each statement is classified first:
+// anything but a read is refused unless opts.AllowWrite is set and
+// opts.ReadOnly is not, and reads run in a read-only transaction unless the
+// caller may write. A server-side statement timeout is always applied.
+func ExecuteMySQLQuery(ctx context.Context, config MySQLConfig, query string, opts QueryOptions) MySQLQueryResult {
 	start := time.Now()
 
-	db, err := sql.Open("mysql", config.DSN())
+	query = strings.TrimSpace(query)
+	kind, readTx, err := checkStatement(query, DialectMySQL, opts)
+	if err != nil {
+		return MySQLQueryResult{Error: err.Error()}
+	}
+
+	db, host, err := openMySQLFor(ctx, config, kind != StatementRead)
 	if err != nil {
 		return MySQLQueryResult{Error: "Failed to connect: " + err.Error()}
 	}
 	defer db.Close()
 
-	query = strings.TrimSpace(query)
-	isSelect := strings.HasPrefix(strings.ToUpper(query), "SELECT") ||
-		strings.HasPrefix(strings.ToUpper(query), "SHOW") ||
-		strings.HasPrefix(strings.ToUpper(query), "DESCRIBE") ||
-		strings.HasPrefix(strings.ToUpper(query), "EXPLAIN")
+	runner, done, err := beginGuarded(ctx, db, readTx)
+	if err != nil {
+		return MySQLQueryResult{Error: "Failed to connect: " + err.Error()}
+	}
+	defer done()
 
-	if readonly && !isSelect {
-		return MySQLQueryResult{Error: "Only SELECT queries allowed in read-only mode"}
+	// max_execution_time only covers SELECT; MariaDB calls it
+	// max_statement_time and counts in seconds. Both are best effort.
+	if _, err := runner.ExecContext(ctx, fmt.Sprintf("SET SESSION max_execution_time = %d", opts.timeout().Milliseconds())); err != nil {
+		runner.ExecContext(ctx, fmt.Sprintf("SET SESSION max_statement_time = %g", opts.timeout().Seconds()))
 	}
 
-	if isSelect {
-		rows, err := db.QueryContext(ctx, query)
+	if kind == StatementRead {
+		rows, err := runner.QueryContext(ctx, query)
 		if err != nil {
 			return MySQLQueryResult{
 				Error:    err.Error(),
@@ -232,9 +286,15 @@ func ExecuteMySQLQuery(ctx context.Context, config MySQLConfig, query string, re
 			Columns:  cols,
 			Rows:     make([][]interface{}, 0),
 			Duration: 0,
+			Host:     host.Addr(),
 		}
 
 		for rows.Next() {
+			if len(result.Rows) >= opts.Policy.maxRows() {
+				result.Truncated = true
+				break
+			}
+
 			values := make([]interface{}, len(cols))
 			valuePtrs := make([]interface{}, len(cols))
 			for i := range values {
@@ -252,18 +312,15 @@ func ExecuteMySQLQuery(ctx context.Context, config MySQLConfig, query string, re
 				}
 			}
 			result.Rows = append(result.Rows, row)
-
-			if len(result.Rows) >= 1000 {
-				break // Limit rows
-			}
 		}
+		result.MaskedColumns = opts.Policy.apply(cols, result.Rows)
 
 		result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
 		return result
 	}
 
 	// Execute non-SELECT query
-	res, err := db.ExecContext(ctx, query)
+	res, err := runner.ExecContext(ctx, query)
 	if err != nil {
 		return MySQLQueryResult{
 			Error:    err.Error(),
@@ -275,6 +332,7 @@ func ExecuteMySQLQuery(ctx context.Context, config MySQLConfig, query string, re
 	return MySQLQueryResult{
 		RowsAffected: affected,
 		Duration:     float64(time.Since(start).Microseconds()) / 1000.0,
+		Host:         host.Addr(),
 	}
 }
 
@@ -282,6 +340,20 @@ func ExecuteMySQLQuery(ctx context.Context, config MySQLConfig, query string, re
 func DumpMySQL(ctx context.Context, config MySQLConfig, schemaOnly bool, dataOnly bool, tables []string) MySQLDumpResult {
 	start := time.Now()
 
+	// mysqldump gets a single host, so pick one that answers first
+	if len(config.Hosts) > 0 {
+		db, host, err := openMySQLFor(ctx, config, false)
+		if err != nil {
+			return MySQLDumpResult{
+				Success:  false,
+				Error:    err.Error(),
+				Duration: float64(time.Since(start).Microseconds()) / 1000.0,
+			}
+		}
+		db.Close()
+		config.Host, config.Port = host.Host, host.Port
+	}
+
 	args := []string{
 		"-h", config.Host,
 		"-P", fmt.Sprintf("%d", config.Port),
@@ -302,9 +374,18 @@ func DumpMySQL(ctx context.Context, config MySQLConfig, schemaOnly bool, dataOnl
 		}
 	}
 
+	// mysqldump dials on its own, so check the target up front
+	if err := egress.Default().CheckHost(ctx, config.Host, config.Port); err != nil {
+		return MySQLDumpResult{
+			Success:  false,
+			Error:    err.Error(),
+			Duration: float64(time.Since(start).Microseconds()) / 1000.0,
+		}
+	}
+
 	cmd := exec.CommandContext(ctx, "mysqldump", args...)
 
-	output, err := cmd.Output()
+	output, err := runDump(cmd)
 	if err != nil {
 		return MySQLDumpResult{
 			Success:  false,
@@ -326,7 +407,7 @@ func GetMySQLDatabases(ctx context.Context, config MySQLConfig) ([]string, error
 	connConfig := config
 	connConfig.Database = "information_schema"
 
-	db, err := sql.Open("mysql", connConfig.DSN())
+	db, _, err := openMySQLFor(ctx, connConfig, false)
 	if err != nil {
 		return nil, err
 	}
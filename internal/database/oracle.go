@@ -0,0 +1,335 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	go_ora "github.com/sijms/go-ora/v2"
+)
+
+// OracleConfig represents Oracle Database connection configuration
+type OracleConfig struct {
+	Host     string      `json:"host"`
+	Port     int         `json:"port"`
+	Service  string      `json:"service"`       // service name, e.g. ORCLPDB1
+	SID      string      `json:"sid,omitempty"` // used when there is no service name
+	User     string      `json:"user"`
+	Password string      `json:"password"`
+	TLS      *TLSOptions `json:"tls,omitempty"`
+}
+
+// connURL builds the go-ora connection URL. go-ora runs the TLS handshake
+// itself and verifies the server against the system roots under the host
+// name, so a TLS block may only choose require or verify-full.
+func (c *OracleConfig) connURL() (string, error) {
+	options := map[string]string{"PROGRAM": "GAGOS"}
+	if c.Service == "" && c.SID != "" {
+		options["SID"] = c.SID
+	}
+	switch mode := c.TLS.mode(""); mode {
+	case TLSDisable:
+	case TLSRequire, TLSVerifyFull:
+		if c.TLS.CACert != "" || c.TLS.ClientCert != "" || c.TLS.ClientKey != "" || c.TLS.ServerName != "" || c.TLS.Secret != nil {
+			return "", fmt.Errorf("Oracle TLS takes no certificates or server name: the server is verified against the system roots")
+		}
+		options["SSL"] = "true"
+		options["SSL VERIFY"] = strconv.FormatBool(mode == TLSVerifyFull)
+	default:
+		return "", fmt.Errorf("Oracle TLS mode must be disable, require or verify-full")
+	}
+	return go_ora.BuildUrl(c.Host, c.Port, c.Service, c.User, c.Password, options), nil
+}
+
+// OracleConnectionResult represents connection test result
+type OracleConnectionResult struct {
+	Success      bool    `json:"success"`
+	Version      string  `json:"version,omitempty"`
+	ResponseTime float64 `json:"response_time_ms,omitempty"`
+	Error        string  `json:"error,omitempty"`
+	ProfileID    string  `json:"profile_id,omitempty"`
+	Cached       bool    `json:"cached,omitempty"`
+}
+
+// OracleInfo represents database information. The instance, database and
+// session figures need SELECT on the V$ views and stay empty without it.
+type OracleInfo struct {
+	Version      string        `json:"version"`
+	InstanceName string        `json:"instance_name"`
+	HostName     string        `json:"host_name"`
+	DatabaseName string        `json:"database_name"`
+	OpenMode     string        `json:"open_mode"`
+	DatabaseRole string        `json:"database_role"` // PRIMARY, PHYSICAL STANDBY, ...
+	Uptime       int64         `json:"uptime_seconds"`
+	UptimeHuman  string        `json:"uptime_human"`
+	Sessions     int           `json:"sessions"`
+	MaxSessions  int           `json:"max_sessions"`
+	TableCount   int           `json:"table_count"` // tables owned by the connected user
+	Tables       []OracleTable `json:"tables,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// OracleTable represents table information. RowCount is from the last
+// statistics gathered on the table.
+type OracleTable struct {
+	Name     string `json:"name"`
+	RowCount int64  `json:"row_count"`
+	Size     string `json:"size"`
+}
+
+// OracleTablespace is the space used in one tablespace. SizeBytes is the
+// size the data files may grow to, counting autoextend.
+type OracleTablespace struct {
+	Name        string  `json:"name"`
+	Status      string  `json:"status"`   // ONLINE, OFFLINE or READ ONLY
+	Contents    string  `json:"contents"` // PERMANENT, TEMPORARY or UNDO
+	UsedBytes   int64   `json:"used_bytes"`
+	SizeBytes   int64   `json:"size_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// OracleQueryResult represents query execution result
+type OracleQueryResult struct {
+	Columns       []string        `json:"columns,omitempty"`
+	Rows          [][]interface{} `json:"rows,omitempty"`
+	RowsAffected  int64           `json:"rows_affected"`
+	Duration      float64         `json:"duration_ms"`
+	Error         string          `json:"error,omitempty"`
+	Truncated     bool            `json:"truncated,omitempty"`      // more rows than the row cap
+	MaskedColumns []string        `json:"masked_columns,omitempty"` // columns masked by the result policy
+}
+
+// TestOracleConnection tests an Oracle connection
+func TestOracleConnection(ctx context.Context, config OracleConfig) OracleConnectionResult {
+	start := time.Now()
+
+	db, err := openOracle(config)
+	if err != nil {
+		return OracleConnectionResult{
+			Success: false,
+			Error:   "Failed to open connection: " + err.Error(),
+		}
+	}
+	defer db.Close()
+
+	db.SetConnMaxLifetime(10 * time.Second)
+	db.SetMaxOpenConns(1)
+
+	var version string
+	err = db.QueryRowContext(ctx, "SELECT banner FROM v$version WHERE ROWNUM = 1").Scan(&version)
+	if err != nil {
+		return OracleConnectionResult{
+			Success: false,
+			Error:   "Failed to query: " + err.Error(),
+		}
+	}
+
+	return OracleConnectionResult{
+		Success:      true,
+		Version:      version,
+		ResponseTime: float64(time.Since(start).Microseconds()) / 1000.0,
+	}
+}
+
+// GetOracleInfo retrieves database information
+func GetOracleInfo(ctx context.Context, config OracleConfig) OracleInfo {
+	db, err := openOracle(config)
+	if err != nil {
+		return OracleInfo{Error: "Failed to connect: " + err.Error()}
+	}
+	defer db.Close()
+
+	info := OracleInfo{}
+
+	if err := db.QueryRowContext(ctx, "SELECT banner FROM v$version WHERE ROWNUM = 1").Scan(&info.Version); err != nil {
+		return OracleInfo{Error: err.Error()}
+	}
+
+	// Instance, database and sessions need SELECT on the V$ views
+	if err := db.QueryRowContext(ctx, `
+		SELECT instance_name, host_name, ROUND((SYSDATE - startup_time) * 86400)
+		FROM v$instance
+	`).Scan(&info.InstanceName, &info.HostName, &info.Uptime); err == nil {
+		info.UptimeHuman = formatMySQLUptime(info.Uptime)
+	}
+	db.QueryRowContext(ctx, "SELECT name, open_mode, database_role FROM v$database").Scan(&info.DatabaseName, &info.OpenMode, &info.DatabaseRole)
+	db.QueryRowContext(ctx, "SELECT COUNT(*) FROM v$session WHERE type = 'USER'").Scan(&info.Sessions)
+	db.QueryRowContext(ctx, "SELECT TO_NUMBER(value) FROM v$parameter WHERE name = 'sessions'").Scan(&info.MaxSessions)
+
+	// Get table count
+	db.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_tables").Scan(&info.TableCount)
+
+	// Get tables info
+	rows, err := db.QueryContext(ctx, `
+		SELECT * FROM (
+			SELECT t.table_name, COALESCE(t.num_rows, 0),
+				TO_CHAR(COALESCE(SUM(s.bytes), 0) / 1048576, 'FM999999990.00') || ' MB'
+			FROM user_tables t
+			LEFT JOIN user_segments s ON s.segment_name = t.table_name AND s.segment_type LIKE 'TABLE%'
+			GROUP BY t.table_name, t.num_rows
+			ORDER BY COALESCE(SUM(s.bytes), 0) DESC
+		) WHERE ROWNUM <= 50
+	`)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var t OracleTable
+			rows.Scan(&t.Name, &t.RowCount, &t.Size)
+			info.Tables = append(info.Tables, t)
+		}
+	}
+
+	return info
+}
+
+// GetOracleTablespaces returns the space used in each tablespace, fullest
+// first. It needs SELECT on DBA_TABLESPACE_USAGE_METRICS and DBA_TABLESPACES,
+// e.g. through SELECT_CATALOG_ROLE.
+func GetOracleTablespaces(ctx context.Context, config OracleConfig) ([]OracleTablespace, error) {
+	db, err := openOracle(config)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT m.tablespace_name, t.status, t.contents,
+			m.used_space * t.block_size, m.tablespace_size * t.block_size, m.used_percent
+		FROM dba_tablespace_usage_metrics m
+		JOIN dba_tablespaces t ON t.tablespace_name = m.tablespace_name
+		ORDER BY m.used_percent DESC, m.tablespace_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tablespaces := []OracleTablespace{}
+	for rows.Next() {
+		var t OracleTablespace
+		if err := rows.Scan(&t.Name, &t.Status, &t.Contents, &t.UsedBytes, &t.SizeBytes, &t.UsedPercent); err != nil {
+			return nil, err
+		}
+		tablespaces = append(tablespaces, t)
+	}
+	return tablespaces, rows.Err()
+}
+
+// ExecuteOracleQuery executes a SQL query under the same rules as
+// ExecutePostgresQuery. go-ora cannot open a read-only transaction itself, so
+// reads that must not write start theirs with SET TRANSACTION READ ONLY and
+// it is always rolled back.
+func ExecuteOracleQuery(ctx context.Context, config OracleConfig, query string, opts QueryOptions) OracleQueryResult {
+	start := time.Now()
+
+	query = strings.TrimSpace(query)
+	kind, readTx, err := checkStatement(query, DialectOracle, opts)
+	if err != nil {
+		return OracleQueryResult{Error: err.Error()}
+	}
+	if kind == StatementRead {
+		// Oracle rejects the terminator that SQL tools accept; PL/SQL blocks
+		// need theirs, but they never classify as reads
+		query = strings.TrimRight(query, "; \t\r\n")
+	}
+
+	db, err := openOracle(config)
+	if err != nil {
+		return OracleQueryResult{Error: "Failed to connect: " + err.Error()}
+	}
+	defer db.Close()
+
+	// There is no statement timeout setting; cancelling the context sends
+	// a break that stops the statement on the server
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+
+	runner, done, err := beginRolledBack(ctx, db, readTx)
+	if err != nil {
+		return OracleQueryResult{Error: "Failed to connect: " + err.Error()}
+	}
+	defer done()
+	if readTx {
+		if _, err := runner.ExecContext(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+			return OracleQueryResult{Error: "Failed to start a read-only transaction: " + err.Error()}
+		}
+	}
+
+	if kind == StatementRead {
+		rows, err := runner.QueryContext(ctx, query)
+		if err != nil {
+			return OracleQueryResult{
+				Error:    err.Error(),
+				Duration: float64(time.Since(start).Microseconds()) / 1000.0,
+			}
+		}
+		defer rows.Close()
+
+		cols, _ := rows.Columns()
+		types, _ := rows.ColumnTypes()
+		result := OracleQueryResult{
+			Columns: cols,
+			Rows:    make([][]interface{}, 0),
+		}
+
+		for rows.Next() {
+			if len(result.Rows) >= opts.Policy.maxRows() {
+				result.Truncated = true
+				break
+			}
+
+			values := make([]interface{}, len(cols))
+			valuePtrs := make([]interface{}, len(cols))
+			for i := range values {
+				valuePtrs[i] = &values[i]
+			}
+
+			rows.Scan(valuePtrs...)
+
+			row := make([]interface{}, len(cols))
+			for i, v := range values {
+				row[i] = oracleValue(v, types[i].DatabaseTypeName())
+			}
+			result.Rows = append(result.Rows, row)
+		}
+		result.MaskedColumns = opts.Policy.apply(cols, result.Rows)
+
+		result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
+		return result
+	}
+
+	// Execute non-SELECT query
+	res, err := runner.ExecContext(ctx, query)
+	if err != nil {
+		return OracleQueryResult{
+			Error:    err.Error(),
+			Duration: float64(time.Since(start).Microseconds()) / 1000.0,
+		}
+	}
+
+	affected, _ := res.RowsAffected()
+	return OracleQueryResult{
+		RowsAffected: affected,
+		Duration:     float64(time.Since(start).Microseconds()) / 1000.0,
+	}
+}
+
+// oracleValue converts a scanned value for JSON output; binary columns are
+// shown as hex
+func oracleValue(v interface{}, typeName string) interface{} {
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+	switch typeName {
+	case "RAW", "LongRaw", "OCIBlobLocator":
+		return hex.EncodeToString(b)
+	}
+	return string(b)
+}
@@ -0,0 +1,69 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	go_ora "github.com/sijms/go-ora/v2"
+)
+
+func TestOracleConnURL(t *testing.T) {
+	config := OracleConfig{Host: "ora.internal", Port: 1521, Service: "ORCLPDB1", User: "app", Password: "p@ss:w/rd?&"}
+	dsn, err := config.connURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := go_ora.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.UserID != "app" || cfg.Password != config.Password || cfg.ServiceName != "ORCLPDB1" || cfg.SID != "" {
+		t.Errorf("login = %q/%q service %q sid %q", cfg.UserID, cfg.Password, cfg.ServiceName, cfg.SID)
+	}
+	if len(cfg.Servers) != 1 || cfg.Servers[0].Addr != "ora.internal" || cfg.Servers[0].Port != 1521 {
+		t.Errorf("servers = %+v", cfg.Servers)
+	}
+	if cfg.SSL {
+		t.Error("TLS on without a TLS block")
+	}
+
+	// The SID is only used without a service name
+	config.Service, config.SID = "", "ORCL"
+	if dsn, err = config.connURL(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = go_ora.ParseConfig(dsn); err != nil || cfg.SID != "ORCL" {
+		t.Errorf("sid = %q, %v", cfg.SID, err)
+	}
+
+	for mode, verify := range map[string]bool{TLSRequire: false, TLSVerifyFull: true} {
+		config.TLS = &TLSOptions{Mode: mode}
+		if dsn, err = config.connURL(); err != nil {
+			t.Fatal(err)
+		}
+		if cfg, err = go_ora.ParseConfig(dsn); err != nil || !cfg.SSL || cfg.SSLVerify != verify {
+			t.Errorf("%s: ssl %v verify %v, %v", mode, cfg.SSL, cfg.SSLVerify, err)
+		}
+	}
+
+	// go-ora builds its own tls.Config, so nothing else can be honoured
+	for _, tls := range []*TLSOptions{{Mode: TLSVerifyCA}, {CACert: "pem"}, {Mode: TLSRequire, ServerName: "other"}} {
+		config.TLS = tls
+		if _, err := config.connURL(); err == nil {
+			t.Errorf("TLS block %+v accepted", tls)
+		}
+	}
+}
+
+func TestOracleDialsThroughEgress(t *testing.T) {
+	// Link-local addresses are denied by the default egress policy
+	t.Setenv("GAGOS_EGRESS_ALLOW_LINK_LOCAL", "")
+	r := TestOracleConnection(context.Background(), OracleConfig{Host: "169.254.169.254", Port: 1521, Service: "x", User: "u", Password: "p"})
+	if r.Success || !strings.Contains(r.Error, "egress policy") {
+		t.Errorf("connection to the metadata address = %+v", r)
+	}
+}
@@ -11,34 +11,64 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gaga951/gagos/internal/egress"
 	_ "github.com/lib/pq"
 )
 
 // PostgresConfig represents PostgreSQL connection configuration
 type PostgresConfig struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	User     string `json:"user"`
-	Password string `json:"password"`
-	Database string `json:"database"`
-	SSLMode  string `json:"ssl_mode"`
+	Host     string      `json:"host"`
+	Port     int         `json:"port"`
+	User     string      `json:"user"`
+	Password string      `json:"password"`
+	Database string      `json:"database"`
+	SSLMode  string      `json:"ssl_mode"`
+	Hosts    []DBHost    `json:"hosts,omitempty"`     // multi-host deployment; Host/Port are used when empty
+	Target   string      `json:"target,omitempty"`    // auto (default), primary or replica
+	TLS      *TLSOptions `json:"tls,omitempty"`       // its mode takes precedence over SSLMode
+	AuthMode string      `json:"auth_mode,omitempty"` // password (default), iam for an RDS IAM token or kerberos
+	Region   string      `json:"region,omitempty"`    // AWS region for IAM auth, from the host name when empty
 }
 
 func (c *PostgresConfig) ConnectionString() string {
-	sslMode := c.SSLMode
-	if sslMode == "" {
-		sslMode = "disable"
+	sslMode := c.TLS.mode(c.SSLMode)
+	if c.AuthMode == AuthIAM && sslMode == TLSDisable {
+		// RDS only accepts IAM tokens over TLS
+		sslMode = TLSRequire
 	}
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		c.Host, c.Port, c.User, c.Password, c.Database, sslMode)
+		c.Host, c.Port, c.User, pqQuote(c.Password), c.Database, sslMode)
+}
+
+// pgReadOnlyQuery tells a standby from a primary
+const pgReadOnlyQuery = "SELECT pg_is_in_recovery()"
+
+// openPostgresFor opens the host config.Target selects for a read or write,
+// failing over to the next candidate when a host does not answer
+func openPostgresFor(ctx context.Context, config PostgresConfig, write bool) (*sql.DB, DBHost, error) {
+	hosts, err := targetHosts(resolveHosts(config.Host, config.Port, config.Hosts), config.Target, write)
+	if err != nil {
+		return nil, DBHost{}, err
+	}
+	return openFailover(ctx, hosts, write, pgReadOnlyQuery, config.openHost)
+}
+
+// openHost opens one host of a multi-host config
+func (c PostgresConfig) openHost(h DBHost) (*sql.DB, error) {
+	c.Host, c.Port = h.Host, h.Port
+	return openPostgres(c)
 }
 
 // PostgresConnectionResult represents connection test result
 type PostgresConnectionResult struct {
-	Success      bool    `json:"success"`
-	Version      string  `json:"version,omitempty"`
-	ResponseTime float64 `json:"response_time_ms,omitempty"`
-	Error        string  `json:"error,omitempty"`
+	Success      bool         `json:"success"`
+	Version      string       `json:"version,omitempty"`
+	ResponseTime float64      `json:"response_time_ms,omitempty"`
+	Error        string       `json:"error,omitempty"`
+	Host         string       `json:"host,omitempty"`  // host that answered
+	Hosts        []HostStatus `json:"hosts,omitempty"` // every host of a multi-host config
+	ProfileID    string       `json:"profile_id,omitempty"`
+	Cached       bool         `json:"cached,omitempty"`
 }
 
 // PostgresInfo represents database information
@@ -64,11 +94,14 @@ type PostgresTable struct {
 
 // PostgresQueryResult represents query execution result
 type PostgresQueryResult struct {
-	Columns      []string        `json:"columns,omitempty"`
-	Rows         [][]interface{} `json:"rows,omitempty"`
-	RowsAffected int64           `json:"rows_affected"`
-	Duration     float64         `json:"duration_ms"`
-	Error        string          `json:"error,omitempty"`
+	Columns       []string        `json:"columns,omitempty"`
+	Rows          [][]interface{} `json:"rows,omitempty"`
+	RowsAffected  int64           `json:"rows_affected"`
+	Duration      float64         `json:"duration_ms"`
+	Error         string          `json:"error,omitempty"`
+	Truncated     bool            `json:"truncated,omitempty"`      // more rows than the row cap
+	MaskedColumns []string        `json:"masked_columns,omitempty"` // columns masked by the result policy
+	Host          string          `json:"host,omitempty"`           // host the statement ran on
 }
 
 // PostgresDumpResult represents dump operation result
@@ -84,12 +117,16 @@ type PostgresDumpResult struct {
 func TestPostgresConnection(ctx context.Context, config PostgresConfig) PostgresConnectionResult {
 	start := time.Now()
 
-	db, err := sql.Open("postgres", config.ConnectionString())
+	db, host, err := openPostgresFor(ctx, config, false)
 	if err != nil {
-		return PostgresConnectionResult{
+		result := PostgresConnectionResult{
 			Success: false,
 			Error:   "Failed to open connection: " + err.Error(),
 		}
+		if len(config.Hosts) > 1 {
+			result.Hosts = checkHosts(ctx, resolveHosts(config.Host, config.Port, config.Hosts), pgReadOnlyQuery, config.openHost)
+		}
+		return result
 	}
 	defer db.Close()
 
@@ -105,16 +142,21 @@ func TestPostgresConnection(ctx context.Context, config PostgresConfig) Postgres
 		}
 	}
 
-	return PostgresConnectionResult{
+	result := PostgresConnectionResult{
 		Success:      true,
 		Version:      version,
+		Host:         host.Addr(),
 		ResponseTime: float64(time.Since(start).Microseconds()) / 1000.0,
 	}
+	if len(config.Hosts) > 1 {
+		result.Hosts = checkHosts(ctx, resolveHosts(config.Host, config.Port, config.Hosts), pgReadOnlyQuery, config.openHost)
+	}
+	return result
 }
 
 // GetPostgresInfo retrieves database information
 func GetPostgresInfo(ctx context.Context, config PostgresConfig) PostgresInfo {
-	db, err := sql.Open("postgres", config.ConnectionString())
+	db, _, err := openPostgresFor(ctx, config, false)
 	if err != nil {
 		return PostgresInfo{Error: "Failed to connect: " + err.Error()}
 	}
@@ -162,27 +204,43 @@ func GetPostgresInfo(ctx context.Context, config PostgresConfig) PostgresInfo {
 	return info
 }
 
-// ExecutePostgresQuery executes a SQL query
-func ExecutePostgresQuery(ctx context.Context, config PostgresConfig, query string, readonly bool) PostgresQueryResult {
+// ExecutePostgresQuery executes a SQL query. Each statement is classified first:
+// anything but a read is refused unless opts.AllowWrite is set and
+// opts.ReadOnly is not, and reads run in a read-only transaction unless the
+// caller may write. A server-side statement timeout is always applied.
+func ExecutePostgresQuery(ctx context.Context, config PostgresConfig, query string, opts QueryOptions) PostgresQueryResult {
 	start := time.Now()
 
-	db, err := sql.Open("postgres", config.ConnectionString())
+	query = strings.TrimSpace(query)
+	kind, readTx, err := checkStatement(query, DialectPostgres, opts)
+	if err != nil {
+		return PostgresQueryResult{Error: err.Error()}
+	}
+
+	db, host, err := openPostgresFor(ctx, config, kind != StatementRead)
 	if err != nil {
 		return PostgresQueryResult{Error: "Failed to connect: " + err.Error()}
 	}
 	defer db.Close()
 
-	query = strings.TrimSpace(query)
-	isSelect := strings.HasPrefix(strings.ToUpper(query), "SELECT") ||
-		strings.HasPrefix(strings.ToUpper(query), "SHOW") ||
-		strings.HasPrefix(strings.ToUpper(query), "EXPLAIN")
+	runner, done, err := beginGuarded(ctx, db, readTx)
+	if err != nil {
+		return PostgresQueryResult{Error: "Failed to connect: " + err.Error()}
+	}
+	defer done()
 
-	if readonly && !isSelect {
-		return PostgresQueryResult{Error: "Only SELECT queries allowed in read-only mode"}
+	// SET LOCAL only lasts for the read-only transaction; on a pinned
+	// connection a plain SET lasts until the connection is closed
+	setTimeout := fmt.Sprintf("SET statement_timeout = %d", opts.timeout().Milliseconds())
+	if readTx {
+		setTimeout = fmt.Sprintf("SET LOCAL statement_timeout = %d", opts.timeout().Milliseconds())
+	}
+	if _, err := runner.ExecContext(ctx, setTimeout); err != nil {
+		return PostgresQueryResult{Error: "Failed to set statement timeout: " + err.Error()}
 	}
 
-	if isSelect {
-		rows, err := db.QueryContext(ctx, query)
+	if kind == StatementRead {
+		rows, err := runner.QueryContext(ctx, query)
 		if err != nil {
 			return PostgresQueryResult{
 				Error:    err.Error(),
@@ -196,9 +254,15 @@ func ExecutePostgresQuery(ctx context.Context, config PostgresConfig, query stri
 			Columns:  cols,
 			Rows:     make([][]interface{}, 0),
 			Duration: 0,
+			Host:     host.Addr(),
 		}
 
 		for rows.Next() {
+			if len(result.Rows) >= opts.Policy.maxRows() {
+				result.Truncated = true
+				break
+			}
+
 			values := make([]interface{}, len(cols))
 			valuePtrs := make([]interface{}, len(cols))
 			for i := range values {
@@ -216,18 +280,15 @@ func ExecutePostgresQuery(ctx context.Context, config PostgresConfig, query stri
 				}
 			}
 			result.Rows = append(result.Rows, row)
-
-			if len(result.Rows) >= 1000 {
-				break // Limit rows
-			}
 		}
+		result.MaskedColumns = opts.Policy.apply(cols, result.Rows)
 
 		result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
 		return result
 	}
 
 	// Execute non-SELECT query
-	res, err := db.ExecContext(ctx, query)
+	res, err := runner.ExecContext(ctx, query)
 	if err != nil {
 		return PostgresQueryResult{
 			Error:    err.Error(),
@@ -239,6 +300,7 @@ func ExecutePostgresQuery(ctx context.Context, config PostgresConfig, query stri
 	return PostgresQueryResult{
 		RowsAffected: affected,
 		Duration:     float64(time.Since(start).Microseconds()) / 1000.0,
+		Host:         host.Addr(),
 	}
 }
 
@@ -246,6 +308,20 @@ func ExecutePostgresQuery(ctx context.Context, config PostgresConfig, query stri
 func DumpPostgres(ctx context.Context, config PostgresConfig, schemaOnly bool, dataOnly bool, tables []string) PostgresDumpResult {
 	start := time.Now()
 
+	// pg_dump gets a single host, so pick one that answers first
+	if len(config.Hosts) > 0 {
+		db, host, err := openPostgresFor(ctx, config, false)
+		if err != nil {
+			return PostgresDumpResult{
+				Success:  false,
+				Error:    err.Error(),
+				Duration: float64(time.Since(start).Microseconds()) / 1000.0,
+			}
+		}
+		db.Close()
+		config.Host, config.Port = host.Host, host.Port
+	}
+
 	args := []string{
 		"-h", config.Host,
 		"-p", fmt.Sprintf("%d", config.Port),
@@ -266,10 +342,19 @@ func DumpPostgres(ctx context.Context, config PostgresConfig, schemaOnly bool, d
 		}
 	}
 
+	// pg_dump dials on its own, so check the target up front
+	if err := egress.Default().CheckHost(ctx, config.Host, config.Port); err != nil {
+		return PostgresDumpResult{
+			Success:  false,
+			Error:    err.Error(),
+			Duration: float64(time.Since(start).Microseconds()) / 1000.0,
+		}
+	}
+
 	cmd := exec.CommandContext(ctx, "pg_dump", args...)
 	cmd.Env = append(cmd.Env, fmt.Sprintf("PGPASSWORD=%s", config.Password))
 
-	output, err := cmd.Output()
+	output, err := runDump(cmd)
 	if err != nil {
 		return PostgresDumpResult{
 			Success:  false,
@@ -292,7 +377,7 @@ func GetPostgresDatabases(ctx context.Context, config PostgresConfig) ([]string,
 	connConfig := config
 	connConfig.Database = "postgres"
 
-	db, err := sql.Open("postgres", connConfig.ConnectionString())
+	db, _, err := openPostgresFor(ctx, connConfig, false)
 	if err != nil {
 		return nil, err
 	}
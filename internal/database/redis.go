@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 	"time"
@@ -12,11 +13,12 @@ import (
 
 // RedisConfig represents Redis connection configuration
 type RedisConfig struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	Password string `json:"password"`
-	DB       int    `json:"db"`
-	UseTLS   bool   `json:"use_tls"`
+	Host     string      `json:"host"`
+	Port     int         `json:"port"`
+	Password string      `json:"password"`
+	DB       int         `json:"db"`
+	UseTLS   bool        `json:"use_tls"`
+	TLS      *TLSOptions `json:"tls,omitempty"`
 }
 
 func (c *RedisConfig) Addr() string {
@@ -30,6 +32,8 @@ type RedisConnectionResult struct {
 	Mode         string  `json:"mode,omitempty"`
 	ResponseTime float64 `json:"response_time_ms,omitempty"`
 	Error        string  `json:"error,omitempty"`
+	ProfileID    string  `json:"profile_id,omitempty"`
+	Cached       bool    `json:"cached,omitempty"`
 }
 
 // RedisInfo represents Redis server information
@@ -137,15 +141,36 @@ type RedisCommandResult struct {
 	Error    string      `json:"error,omitempty"`
 }
 
-// TestRedisConnection tests Redis connection
-func TestRedisConnection(ctx context.Context, config RedisConfig) RedisConnectionResult {
-	start := time.Now()
-
-	client := redis.NewClient(&redis.Options{
+// newRedisClient creates a client for config. A TLS setup error is returned
+// by the dialer, so it surfaces as the first command's error.
+func newRedisClient(config RedisConfig) *redis.Client {
+	dialer := redisDialer
+	legacy := ""
+	if config.UseTLS {
+		legacy = TLSRequire
+	}
+	if mode := config.TLS.mode(legacy); mode != TLSDisable {
+		cfg, err := config.TLS.clientConfig(mode, config.Host)
+		if err != nil {
+			dialer = func(context.Context, string, string) (net.Conn, error) { return nil, err }
+		} else {
+			dialer = tlsDialer(cfg)
+		}
+	}
+	return redis.NewClient(&redis.Options{
 		Addr:     config.Addr(),
 		Password: config.Password,
 		DB:       config.DB,
+		Limiter:  newRedisLimiter(config),
+		Dialer:   dialer,
 	})
+}
+
+// TestRedisConnection tests Redis connection
+func TestRedisConnection(ctx context.Context, config RedisConfig) RedisConnectionResult {
+	start := time.Now()
+
+	client := newRedisClient(config)
 	defer client.Close()
 
 	pong, err := client.Ping(ctx).Result()
@@ -178,11 +203,7 @@ func TestRedisConnection(ctx context.Context, config RedisConfig) RedisConnectio
 
 // GetRedisInfo retrieves Redis server information
 func GetRedisInfo(ctx context.Context, config RedisConfig) RedisInfo {
-	client := redis.NewClient(&redis.Options{
-		Addr:     config.Addr(),
-		Password: config.Password,
-		DB:       config.DB,
-	})
+	client := newRedisClient(config)
 	defer client.Close()
 
 	info := RedisInfo{}
@@ -244,11 +265,7 @@ func GetRedisInfo(ctx context.Context, config RedisConfig) RedisInfo {
 
 // GetRedisClusterInfo retrieves cluster information
 func GetRedisClusterInfo(ctx context.Context, config RedisConfig) RedisClusterInfo {
-	client := redis.NewClient(&redis.Options{
-		Addr:     config.Addr(),
-		Password: config.Password,
-		DB:       config.DB,
-	})
+	client := newRedisClient(config)
 	defer client.Close()
 
 	// Check if cluster is enabled
@@ -288,11 +305,7 @@ func GetRedisClusterInfo(ctx context.Context, config RedisConfig) RedisClusterIn
 
 // ScanRedisKeys scans keys matching a pattern
 func ScanRedisKeys(ctx context.Context, config RedisConfig, pattern string, cursor uint64, count int64) RedisScanResult {
-	client := redis.NewClient(&redis.Options{
-		Addr:     config.Addr(),
-		Password: config.Password,
-		DB:       config.DB,
-	})
+	client := newRedisClient(config)
 	defer client.Close()
 
 	if pattern == "" {
@@ -340,11 +353,7 @@ func ScanRedisKeys(ctx context.Context, config RedisConfig, pattern string, curs
 
 // GetRedisKeyValue retrieves a key's value
 func GetRedisKeyValue(ctx context.Context, config RedisConfig, key string) RedisKeyInfo {
-	client := redis.NewClient(&redis.Options{
-		Addr:     config.Addr(),
-		Password: config.Password,
-		DB:       config.DB,
-	})
+	client := newRedisClient(config)
 	defer client.Close()
 
 	info := RedisKeyInfo{Key: key}
@@ -394,11 +403,7 @@ func GetRedisKeyValue(ctx context.Context, config RedisConfig, key string) Redis
 func ExecuteRedisCommand(ctx context.Context, config RedisConfig, command string) RedisCommandResult {
 	start := time.Now()
 
-	client := redis.NewClient(&redis.Options{
-		Addr:     config.Addr(),
-		Password: config.Password,
-		DB:       config.DB,
-	})
+	client := newRedisClient(config)
 	defer client.Close()
 
 	// Parse command
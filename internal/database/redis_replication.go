@@ -0,0 +1,321 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gaga951/gagos/internal/fanout"
+)
+
+// Manual failover for master/replica setups without Sentinel: inspect
+// replication, promote the most up-to-date replica, then repoint the others
+// (and the old master, once back) at it. Every node is reached with the
+// credentials and TLS settings of the profile the request was made with.
+
+// RedisReplicationNode is the replication state of one node. Offset is
+// master_repl_offset on a master and slave_repl_offset on a replica; LagBytes
+// is how far a replica's offset trails its master's.
+type RedisReplicationNode struct {
+	Addr           string `json:"addr"`
+	Role           string `json:"role,omitempty"` // master or slave
+	MasterAddr     string `json:"master_addr,omitempty"`
+	LinkStatus     string `json:"link_status,omitempty"`
+	LastIOSeconds  int64  `json:"last_io_seconds_ago,omitempty"`
+	SyncInProgress bool   `json:"sync_in_progress,omitempty"`
+	ReplID         string `json:"replid,omitempty"`
+	Offset         int64  `json:"offset"`
+	LagBytes       int64  `json:"lag_bytes,omitempty"`
+	LagSeconds     int64  `json:"lag_seconds,omitempty"` // as the master reports it
+	ReadOnly       bool   `json:"read_only,omitempty"`
+	Priority       int    `json:"priority,omitempty"` // 0 means never promote
+	Replicas       int    `json:"replicas,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// RedisReplication describes a master and its replicas. Candidate is the
+// replica best suited for promotion: not syncing, priority above 0 and the
+// highest offset, ties going to the lower priority value.
+type RedisReplication struct {
+	Master    *RedisReplicationNode  `json:"master,omitempty"`
+	Replicas  []RedisReplicationNode `json:"replicas"`
+	Candidate string                 `json:"candidate,omitempty"`
+	Warnings  []string               `json:"warnings,omitempty"`
+}
+
+// RedisPromoteResult is the outcome of promoting a replica
+type RedisPromoteResult struct {
+	Before RedisReplicationNode `json:"before"`
+	After  RedisReplicationNode `json:"after"`
+}
+
+// RedisRepointResult is the outcome of repointing one replica
+type RedisRepointResult struct {
+	Addr           string `json:"addr"`
+	PreviousMaster string `json:"previous_master,omitempty"`
+	Success        bool   `json:"success"`
+	Error          string `json:"error,omitempty"`
+}
+
+// redisAt returns config aimed at another node
+func redisAt(config RedisConfig, addr string) (RedisConfig, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return config, fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	config.Port, err = strconv.Atoi(port)
+	if err != nil || config.Port <= 0 || config.Port > 65535 {
+		return config, fmt.Errorf("invalid port in %q", addr)
+	}
+	config.Host = host
+	return config, nil
+}
+
+func redisNodeAddr(config RedisConfig) string {
+	return net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+}
+
+// redisReplicationInfo reads INFO replication of one node. replicas are the
+// addresses the node reports as its connected replicas, with their lag.
+func redisReplicationInfo(ctx context.Context, config RedisConfig) (node RedisReplicationNode, replicas map[string]int64, err error) {
+	node.Addr = redisNodeAddr(config)
+	client := newRedisClient(config)
+	defer client.Close()
+
+	info, err := client.Info(ctx, "replication").Result()
+	if err != nil {
+		node.Error = err.Error()
+		return node, nil, err
+	}
+	node.Role = parseRedisInfoValue(info, "role")
+	node.ReplID = parseRedisInfoValue(info, "master_replid")
+	replicas = map[string]int64{}
+	if node.Role == "master" {
+		node.Offset, _ = strconv.ParseInt(parseRedisInfoValue(info, "master_repl_offset"), 10, 64)
+		n, _ := strconv.Atoi(parseRedisInfoValue(info, "connected_slaves"))
+		for i := 0; i < n; i++ {
+			// slave0:ip=10.0.0.2,port=6379,state=online,offset=123,lag=0
+			fields := map[string]string{}
+			for _, kv := range strings.Split(parseRedisInfoValue(info, fmt.Sprintf("slave%d", i)), ",") {
+				if k, v, ok := strings.Cut(kv, "="); ok {
+					fields[k] = v
+				}
+			}
+			if fields["ip"] != "" && fields["port"] != "" {
+				lag, _ := strconv.ParseInt(fields["lag"], 10, 64)
+				replicas[net.JoinHostPort(fields["ip"], fields["port"])] = lag
+			}
+		}
+		node.Replicas = len(replicas)
+		return node, replicas, nil
+	}
+
+	node.MasterAddr = net.JoinHostPort(parseRedisInfoValue(info, "master_host"), parseRedisInfoValue(info, "master_port"))
+	node.LinkStatus = parseRedisInfoValue(info, "master_link_status")
+	node.LastIOSeconds, _ = strconv.ParseInt(parseRedisInfoValue(info, "master_last_io_seconds_ago"), 10, 64)
+	node.SyncInProgress = parseRedisInfoValue(info, "master_sync_in_progress") == "1"
+	node.Offset, _ = strconv.ParseInt(parseRedisInfoValue(info, "slave_repl_offset"), 10, 64)
+	node.ReadOnly = parseRedisInfoValue(info, "slave_read_only") == "1"
+	priority := parseRedisInfoValue(info, "slave_priority")
+	if priority == "" {
+		priority = parseRedisInfoValue(info, "replica_priority")
+	}
+	node.Priority, _ = strconv.Atoi(priority)
+	return node, replicas, nil
+}
+
+// GetRedisReplication inspects the replication the node in config belongs
+// to: its master when it is a replica, and every replica the master reports
+// plus the extra addresses given, which is how replicas announcing an
+// unreachable address or a master that is down can still be inspected.
+func GetRedisReplication(ctx context.Context, config RedisConfig, extra []string) (*RedisReplication, error) {
+	self, selfReplicas, err := redisReplicationInfo(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RedisReplication{Replicas: []RedisReplicationNode{}}
+	lagSeconds := selfReplicas
+	addrs := map[string]bool{}
+	if self.Role == "master" {
+		result.Master = &self
+	} else {
+		addrs[self.Addr] = true
+		masterConfig, err := redisAt(config, self.MasterAddr)
+		if err != nil {
+			return nil, err
+		}
+		master, masterReplicas, err := redisReplicationInfo(ctx, masterConfig)
+		result.Master = &master
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("master %s is unreachable: %v", master.Addr, err))
+		} else if master.Role != "master" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s replicates from %s, which is itself a replica", self.Addr, master.Addr))
+		}
+		lagSeconds = masterReplicas
+	}
+	for addr := range lagSeconds {
+		addrs[addr] = true
+	}
+	for _, addr := range extra {
+		if _, err := redisAt(config, addr); err != nil {
+			return nil, err
+		}
+		addrs[addr] = true
+	}
+	if result.Master != nil {
+		delete(addrs, result.Master.Addr)
+	}
+
+	nodes := make([]RedisReplicationNode, 0, len(addrs))
+	tasks := make(map[string]fanout.Func, len(addrs))
+	for addr := range addrs {
+		i := len(nodes)
+		nodes = append(nodes, RedisReplicationNode{Addr: addr})
+		if addr == self.Addr {
+			nodes[i] = self
+			continue
+		}
+		nodeConfig, _ := redisAt(config, addr)
+		tasks[addr] = func(ctx context.Context) error {
+			node, _, err := redisReplicationInfo(ctx, nodeConfig)
+			nodes[i] = node
+			return err
+		}
+	}
+	// Unreachable replicas keep their error in nodes
+	fanout.Run(ctx, fanout.Options{Limit: 8}, tasks)
+
+	var best *RedisReplicationNode
+	for i := range nodes {
+		node := &nodes[i]
+		if lag, ok := lagSeconds[node.Addr]; ok {
+			node.LagSeconds = lag
+		}
+		if node.Error != "" {
+			continue
+		}
+		if node.Role == "master" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s is a master too", node.Addr))
+			continue
+		}
+		if result.Master != nil && result.Master.Error == "" {
+			node.LagBytes = max(result.Master.Offset-node.Offset, 0)
+			if node.MasterAddr != result.Master.Addr {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%s replicates from %s, not %s", node.Addr, node.MasterAddr, result.Master.Addr))
+			}
+		}
+		if node.Priority == 0 || node.SyncInProgress {
+			continue
+		}
+		// With the master down its offset is unknown, so compare replica
+		// offsets directly: the highest has received the most
+		if best == nil || node.Offset > best.Offset || (node.Offset == best.Offset && node.Priority < best.Priority) {
+			best = node
+		}
+	}
+	if best != nil {
+		result.Candidate = best.Addr
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Addr < nodes[j].Addr })
+	result.Replicas = nodes
+	return result, nil
+}
+
+// PromoteRedisReplica turns the replica in config into a master with
+// REPLICAOF NO ONE. confirm must repeat the node's host:port.
+func PromoteRedisReplica(ctx context.Context, config RedisConfig, confirm string) (*RedisPromoteResult, error) {
+	addr := redisNodeAddr(config)
+	if confirm != addr {
+		return nil, fmt.Errorf("confirm must be %s to promote it", addr)
+	}
+	before, _, err := redisReplicationInfo(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if before.Role != "slave" {
+		return nil, fmt.Errorf("%s is not a replica (role %s)", addr, before.Role)
+	}
+
+	if err := replicaOf(ctx, config, "NO", "ONE"); err != nil {
+		return nil, err
+	}
+	after, _, err := redisReplicationInfo(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("promoted, but reading its state failed: %w", err)
+	}
+	return &RedisPromoteResult{Before: before, After: after}, nil
+}
+
+// RepointRedisReplicas makes each node in replicas replicate from master,
+// which must be a master already. A repointed replica drops its data and
+// resyncs unless it shares the new master's replication history. confirm
+// must repeat the new master's host:port.
+func RepointRedisReplicas(ctx context.Context, config RedisConfig, master string, replicas []string, confirm string) ([]RedisRepointResult, error) {
+	masterConfig, err := redisAt(config, master)
+	if err != nil {
+		return nil, err
+	}
+	master = redisNodeAddr(masterConfig)
+	if confirm != master {
+		return nil, fmt.Errorf("confirm must be %s to repoint replicas to it", master)
+	}
+	if len(replicas) == 0 {
+		return nil, fmt.Errorf("at least one replica is required")
+	}
+	node, _, err := redisReplicationInfo(ctx, masterConfig)
+	if err != nil {
+		return nil, fmt.Errorf("new master %s is unreachable: %w", master, err)
+	}
+	if node.Role != "master" {
+		return nil, fmt.Errorf("%s is not a master; promote it first", master)
+	}
+
+	results := make([]RedisRepointResult, len(replicas))
+	tasks := make(map[string]fanout.Func, len(replicas))
+	for i, addr := range replicas {
+		i, addr := i, addr
+		results[i] = RedisRepointResult{Addr: addr}
+		replicaConfig, err := redisAt(config, addr)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if redisNodeAddr(replicaConfig) == master {
+			results[i].Error = "is the new master"
+			continue
+		}
+		tasks[addr] = func(ctx context.Context) error {
+			if before, _, err := redisReplicationInfo(ctx, replicaConfig); err == nil && before.Role == "slave" {
+				results[i].PreviousMaster = before.MasterAddr
+			}
+			err := replicaOf(ctx, replicaConfig, masterConfig.Host, strconv.Itoa(masterConfig.Port))
+			if err != nil {
+				results[i].Error = err.Error()
+			} else {
+				results[i].Success = true
+			}
+			return err
+		}
+	}
+	// Per-replica errors are already in results
+	fanout.Run(ctx, fanout.Options{Limit: 8}, tasks)
+	return results, nil
+}
+
+// replicaOf runs REPLICAOF, or SLAVEOF on servers older than Redis 5
+func replicaOf(ctx context.Context, config RedisConfig, host, port string) error {
+	client := newRedisClient(config)
+	defer client.Close()
+	err := client.Do(ctx, "REPLICAOF", host, port).Err()
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "unknown command") {
+		err = client.Do(ctx, "SLAVEOF", host, port).Err()
+	}
+	return err
+}
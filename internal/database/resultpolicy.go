@@ -0,0 +1,292 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gaga951/gagos/internal/storage"
+)
+
+// Result policies limit what the SQL query console returns for a connection:
+// how many rows, how long a cell may be and which columns are masked. They are
+// stored server side and matched against the target of every query, so a
+// client cannot opt out by editing its connection config; only an elevated
+// caller can ask for unmasked results.
+
+// DefaultMaxRows is the row cap of a query without a policy
+const DefaultMaxRows = 1000
+
+// DefaultMaskColumns are the column patterns masked when a policy does not
+// list its own
+var DefaultMaskColumns = []string{"*password*", "*passwd*", "*secret*", "*token*", "*email*"}
+
+// maskedValue replaces a masked cell
+const maskedValue = "****"
+
+// ResultPolicy applies to queries against the matching connections. Empty
+// Host and Database, and a zero Port, match any.
+type ResultPolicy struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Kind          string    `json:"kind"` // postgres, mysql, mssql or oracle
+	Host          string    `json:"host,omitempty"`
+	Port          int       `json:"port,omitempty"`
+	Database      string    `json:"database,omitempty"`
+	MaxRows       int       `json:"max_rows,omitempty"`        // 0 keeps DefaultMaxRows, which is also the ceiling
+	MaxCellLength int       `json:"max_cell_length,omitempty"` // characters kept of a string cell, 0 for no limit
+	MaskColumns   []string  `json:"mask_columns"`              // case-insensitive globs, DefaultMaskColumns when null
+	UpdatedAt     time.Time `json:"updated_at"`
+	UpdatedBy     string    `json:"updated_by,omitempty"`
+}
+
+func resultPolicyID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "rp-" + hex.EncodeToString(b)
+}
+
+func (p *ResultPolicy) validate() error {
+	switch p.Kind {
+	case "postgres", "mysql", "mssql", "oracle":
+	default:
+		return fmt.Errorf("kind must be postgres, mysql, mssql or oracle")
+	}
+	if p.MaxRows < 0 || p.MaxCellLength < 0 {
+		return fmt.Errorf("max_rows and max_cell_length must not be negative")
+	}
+	for i, pattern := range p.MaskColumns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("invalid mask pattern %q", p.MaskColumns[i])
+		}
+		p.MaskColumns[i] = pattern
+	}
+	return nil
+}
+
+// SaveResultPolicy creates the policy, or replaces it when its ID exists
+func SaveResultPolicy(p *ResultPolicy, updatedBy string) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	if p.ID == "" {
+		p.ID = resultPolicyID()
+	}
+	p.UpdatedAt, p.UpdatedBy = time.Now(), updatedBy
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return storage.GetBackend().Set(storage.BucketDBResultPolicy, p.ID, data)
+}
+
+func GetResultPolicy(id string) (*ResultPolicy, error) {
+	data, err := storage.GetBackend().Get(storage.BucketDBResultPolicy, id)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("result policy not found: %s", id)
+	}
+	var p ResultPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func DeleteResultPolicy(id string) error {
+	return storage.GetBackend().Delete(storage.BucketDBResultPolicy, id)
+}
+
+// ListResultPolicies returns every policy, ordered by kind and name
+func ListResultPolicies() ([]ResultPolicy, error) {
+	dataList, err := storage.GetBackend().List(storage.BucketDBResultPolicy)
+	if err != nil {
+		return nil, err
+	}
+	policies := make([]ResultPolicy, 0, len(dataList))
+	for _, data := range dataList {
+		var p ResultPolicy
+		if err := json.Unmarshal(data, &p); err == nil {
+			policies = append(policies, p)
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		if policies[i].Kind != policies[j].Kind {
+			return policies[i].Kind < policies[j].Kind
+		}
+		return policies[i].Name < policies[j].Name
+	})
+	return policies, nil
+}
+
+// FindResultPolicy returns the most specific policy matching a connection,
+// or nil. hosts are every host the connection may use; a policy with a host
+// applies when any of them matches.
+func FindResultPolicy(kind string, hosts []DBHost, database string) (*ResultPolicy, error) {
+	policies, err := ListResultPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	var best *ResultPolicy
+	bestScore := -1
+	for i := range policies {
+		p := &policies[i]
+		if p.Kind != kind || (p.Database != "" && p.Database != database) {
+			continue
+		}
+		if p.Host != "" || p.Port != 0 {
+			matched := false
+			for _, h := range hosts {
+				if (p.Host == "" || strings.EqualFold(p.Host, h.Host)) && (p.Port == 0 || p.Port == h.Port) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		score := 0
+		for _, set := range []bool{p.Host != "", p.Port != 0, p.Database != ""} {
+			if set {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best, nil
+}
+
+// PostgresResultPolicy is FindResultPolicy for a PostgreSQL connection
+func PostgresResultPolicy(config PostgresConfig) (*ResultPolicy, error) {
+	return FindResultPolicy("postgres", resolveHosts(config.Host, config.Port, config.Hosts), config.Database)
+}
+
+// MySQLResultPolicy is FindResultPolicy for a MySQL connection
+func MySQLResultPolicy(config MySQLConfig) (*ResultPolicy, error) {
+	return FindResultPolicy("mysql", resolveHosts(config.Host, config.Port, config.Hosts), config.Database)
+}
+
+// MSSQLResultPolicy is FindResultPolicy for a SQL Server connection
+func MSSQLResultPolicy(config MSSQLConfig) (*ResultPolicy, error) {
+	return FindResultPolicy("mssql", []DBHost{{Host: config.Host, Port: config.Port}}, config.Database)
+}
+
+// OracleResultPolicy is FindResultPolicy for an Oracle connection; the
+// policy's database is the service name
+func OracleResultPolicy(config OracleConfig) (*ResultPolicy, error) {
+	return FindResultPolicy("oracle", []DBHost{{Host: config.Host, Port: config.Port}}, config.Service)
+}
+
+// ProfileResultPolicy is FindResultPolicy for a connection profile
+func ProfileResultPolicy(profileID string) (*ResultPolicy, error) {
+	config, _, ok := profileConfig(profileID)
+	if !ok {
+		return nil, fmt.Errorf("unknown connection profile %q: connect to the database first", profileID)
+	}
+	switch c := config.(type) {
+	case PostgresConfig:
+		return PostgresResultPolicy(c)
+	case MySQLConfig:
+		return MySQLResultPolicy(c)
+	case MSSQLConfig:
+		return MSSQLResultPolicy(c)
+	case OracleConfig:
+		return OracleResultPolicy(c)
+	}
+	return nil, nil
+}
+
+// maxRows is the row cap under the policy
+func (p *ResultPolicy) maxRows() int {
+	if p == nil || p.MaxRows == 0 || p.MaxRows > DefaultMaxRows {
+		return DefaultMaxRows
+	}
+	return p.MaxRows
+}
+
+// maskPatterns are the column patterns the policy masks
+func (p *ResultPolicy) maskPatterns() []string {
+	if p.MaskColumns == nil {
+		return DefaultMaskColumns
+	}
+	return p.MaskColumns
+}
+
+// masks reports whether the policy masks column
+func (p *ResultPolicy) masks(column string) bool {
+	if p == nil {
+		return false
+	}
+	name := strings.ToLower(column)
+	for _, pattern := range p.maskPatterns() {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// apply masks and truncates rows in place and returns the masked columns
+func (p *ResultPolicy) apply(columns []string, rows [][]interface{}) []string {
+	if p == nil {
+		return nil
+	}
+
+	var masked []string
+	maskCol := make([]bool, len(columns))
+	for i, col := range columns {
+		if p.masks(col) {
+			maskCol[i] = true
+			masked = append(masked, col)
+		}
+	}
+
+	for _, row := range rows {
+		for i, v := range row {
+			if v == nil {
+				continue
+			}
+			if i < len(maskCol) && maskCol[i] {
+				row[i] = maskValue(v)
+				continue
+			}
+			if s, ok := v.(string); ok && p.MaxCellLength > 0 && utf8.RuneCountInString(s) > p.MaxCellLength {
+				row[i] = string([]rune(s)[:p.MaxCellLength]) + "…"
+			}
+		}
+	}
+	return masked
+}
+
+// maskValue hides a cell, keeping the first letter and domain of an email
+// address so rows stay distinguishable
+func maskValue(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return maskedValue
+	}
+	at := strings.LastIndexByte(s, '@')
+	if at <= 0 || strings.ContainsAny(s, " \t\n") {
+		return maskedValue
+	}
+	first, _ := utf8.DecodeRuneInString(s)
+	return string(first) + maskedValue + s[at:]
+}
@@ -1,23 +1,27 @@
 package database
 
 import (
-	"bytes"
 	"context"
 	"io"
+	"net"
+	"net/http"
 	"strings"
 	"time"
 
+	"github.com/gaga951/gagos/internal/egress"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
 // S3Config holds configuration for connecting to S3-compatible storage
 type S3Config struct {
-	Endpoint        string `json:"endpoint"`
-	Region          string `json:"region"`
-	AccessKeyID     string `json:"access_key_id"`
-	SecretAccessKey string `json:"secret_access_key"`
-	UseSSL          bool   `json:"use_ssl"`
+	Endpoint        string      `json:"endpoint"`
+	Region          string      `json:"region"`
+	AccessKeyID     string      `json:"access_key_id"`
+	SecretAccessKey string      `json:"secret_access_key"`
+	UseSSL          bool        `json:"use_ssl"`
+	TLS             *TLSOptions `json:"tls,omitempty"`
+	AuthMode        string      `json:"auth_mode,omitempty"` // password (access keys, default) or iam
 }
 
 // S3ConnectionResult holds the result of a connection test
@@ -26,6 +30,9 @@ type S3ConnectionResult struct {
 	Buckets      []string `json:"buckets,omitempty"`
 	ResponseTime float64  `json:"response_time_ms,omitempty"`
 	Error        string   `json:"error,omitempty"`
+	ProfileID    string   `json:"profile_id,omitempty"`
+	Cached       bool     `json:"cached,omitempty"`
+	MinIO        bool     `json:"minio,omitempty"` // MinIO admin features are available
 }
 
 // S3Bucket represents a bucket in S3
@@ -55,13 +62,52 @@ type S3ObjectInfo struct {
 
 // createS3Client creates a new MinIO client for S3 operations
 func createS3Client(config S3Config) (*minio.Client, error) {
+	transport, secure, err := s3Transport(config)
+	if err != nil {
+		return nil, err
+	}
 	return minio.New(config.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, ""),
-		Secure: config.UseSSL,
-		Region: config.Region,
+		Creds:     s3Credentials(config),
+		Secure:    secure,
+		Region:    config.Region,
+		Transport: transport,
 	})
 }
 
+// s3Transport builds the guarded HTTP transport for the endpoint and reports
+// whether it speaks TLS
+func s3Transport(config S3Config) (http.RoundTripper, bool, error) {
+	// use_ssl on its own verifies against the system roots
+	legacy := ""
+	if config.UseSSL {
+		legacy = TLSVerifyFull
+	}
+	mode := config.TLS.mode(legacy)
+	secure := mode != TLSDisable
+
+	transport, err := minio.DefaultTransport(secure)
+	if err != nil {
+		return nil, false, err
+	}
+	if secure && config.TLS != nil {
+		host, _, err := net.SplitHostPort(config.Endpoint)
+		if err != nil {
+			host = config.Endpoint
+		}
+		if transport.TLSClientConfig, err = config.TLS.clientConfig(mode, host); err != nil {
+			return nil, false, err
+		}
+	}
+	return guardedTransport("s3", config.Endpoint, egress.Default().Transport(transport)), secure, nil
+}
+
+func s3Credentials(config S3Config) *credentials.Credentials {
+	if config.AuthMode == AuthIAM {
+		return awsCredentials
+	}
+	return credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, "")
+}
+
 // TestS3Connection tests connectivity to S3-compatible storage
 func TestS3Connection(ctx context.Context, config S3Config) S3ConnectionResult {
 	start := time.Now()
@@ -92,6 +138,7 @@ func TestS3Connection(ctx context.Context, config S3Config) S3ConnectionResult {
 		Success:      true,
 		Buckets:      bucketNames,
 		ResponseTime: float64(time.Since(start).Milliseconds()),
+		MinIO:        MinIOAdminEnabled() && DetectMinIO(ctx, config),
 	}
 }
 
@@ -243,8 +290,8 @@ func GetS3ObjectInfo(ctx context.Context, config S3Config, bucket, key string) (
 	}, nil
 }
 
-// UploadS3Object uploads data to S3
-func UploadS3Object(ctx context.Context, config S3Config, bucket, key string, data []byte, contentType string) error {
+// UploadS3Object streams size bytes from r to S3
+func UploadS3Object(ctx context.Context, config S3Config, bucket, key string, r io.Reader, size int64, contentType string) error {
 	client, err := createS3Client(config)
 	if err != nil {
 		return err
@@ -254,8 +301,7 @@ func UploadS3Object(ctx context.Context, config S3Config, bucket, key string, da
 		contentType = "application/octet-stream"
 	}
 
-	reader := bytes.NewReader(data)
-	_, err = client.PutObject(ctx, bucket, key, reader, int64(len(data)), minio.PutObjectOptions{
+	_, err = client.PutObject(ctx, bucket, key, r, size, minio.PutObjectOptions{
 		ContentType: contentType,
 	})
 
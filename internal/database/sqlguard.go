@@ -0,0 +1,364 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// StatementKind classifies a SQL statement by what it can change
+type StatementKind int
+
+const (
+	StatementRead  StatementKind = iota // SELECT, SHOW, EXPLAIN, ...
+	StatementWrite                      // INSERT, UPDATE, DELETE, CALL, unknown
+	StatementDDL                        // CREATE, ALTER, DROP, TRUNCATE, GRANT, ...
+)
+
+func (k StatementKind) String() string {
+	switch k {
+	case StatementRead:
+		return "read"
+	case StatementDDL:
+		return "ddl"
+	default:
+		return "write"
+	}
+}
+
+// QueryOptions controls how a passthrough query is run
+type QueryOptions struct {
+	ReadOnly   bool          // reject anything that is not a read
+	AllowWrite bool          // caller may run DML/DDL; otherwise reads only
+	Timeout    time.Duration // per-statement timeout, 0 means StatementTimeout()
+	Policy     *ResultPolicy // row cap, cell truncation and masking of results, nil for none
+}
+
+// StatementTimeout is the server-side statement timeout for passthrough
+// queries (GAGOS_SQL_STATEMENT_TIMEOUT, default 30s)
+func StatementTimeout() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("GAGOS_SQL_STATEMENT_TIMEOUT")); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Second
+}
+
+func (o QueryOptions) timeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return StatementTimeout()
+}
+
+// SQLDialect selects the comment and quoting rules used by ClassifySQL
+type SQLDialect int
+
+const (
+	DialectPostgres SQLDialect = iota
+	DialectMySQL
+	DialectMSSQL  // T-SQL
+	DialectOracle // also q'[...]' quoting
+)
+
+// Errors returned by CheckQuery
+var (
+	ErrReadOnlyQuery     = errors.New("only read queries allowed in read-only mode")
+	ErrElevationRequired = errors.New("elevated permission required")
+)
+
+// CheckQuery reports whether query may run under opts, without running it
+func CheckQuery(query string, dialect SQLDialect, opts QueryOptions) error {
+	_, _, err := checkStatement(query, dialect, opts)
+	return err
+}
+
+// checkStatement classifies query and decides whether it may run under opts.
+// readTx is true when it must run inside a read-only transaction.
+func checkStatement(query string, dialect SQLDialect, opts QueryOptions) (kind StatementKind, readTx bool, err error) {
+	kind = ClassifySQL(query, dialect)
+	switch {
+	case kind == StatementRead:
+		// A read can still call a function with side effects; the read-only
+		// transaction makes the database reject those
+		return kind, opts.ReadOnly || !opts.AllowWrite, nil
+	case opts.ReadOnly:
+		return kind, true, fmt.Errorf("%w (statement is %s)", ErrReadOnlyQuery, kind)
+	case !opts.AllowWrite:
+		return kind, true, fmt.Errorf("%w for %s statements", ErrElevationRequired, kind)
+	}
+	return kind, false, nil
+}
+
+// sqlRunner is what the query paths need from *sql.Conn and *sql.Tx
+type sqlRunner interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// beginGuarded pins a connection from db and, when readTx is set, opens a
+// read-only transaction on it. Writes run outside a transaction because
+// statements like VACUUM or CREATE INDEX CONCURRENTLY refuse to run in one.
+// done releases everything and must be called after the rows are read.
+func beginGuarded(ctx context.Context, db *sql.DB, readTx bool) (runner sqlRunner, done func(), err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !readTx {
+		return conn, func() { conn.Close() }, nil
+	}
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return tx, func() {
+		tx.Rollback()
+		conn.Close()
+	}, nil
+}
+
+var readKeywords = map[string]bool{
+	"SELECT": true, "SHOW": true, "EXPLAIN": true, "DESCRIBE": true, "DESC": true,
+	"VALUES": true, "TABLE": true, "WITH": true,
+}
+
+var ddlKeywords = map[string]bool{
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "RENAME": true,
+	"GRANT": true, "REVOKE": true, "DENY": true, "COMMENT": true, "REINDEX": true, "CLUSTER": true,
+	"VACUUM": true, "ANALYZE": true, "OPTIMIZE": true, "REPAIR": true,
+}
+
+// writeKeywords make a statement that starts with a read keyword a write,
+// e.g. WITH x AS (DELETE ...) or SELECT ... INTO new_table
+var writeKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "REPLACE": true,
+	"INTO": true, "UPSERT": true,
+}
+
+// ClassifySQL returns the most dangerous kind among the statements in query.
+// Comments and quoted strings are ignored; anything not recognised as a read
+// or DDL counts as a write.
+func ClassifySQL(query string, dialect SQLDialect) StatementKind {
+	kind := StatementRead
+	for _, stmt := range splitStatements(query, dialect) {
+		k := classifyStatement(stmt)
+		if dialect == DialectMSSQL {
+			k = classifyTSQL(stmt, k)
+		}
+		// EXPLAIN PLAN stores the plan in PLAN_TABLE
+		if dialect == DialectOracle && len(stmt) > 0 && stmt[0] == "EXPLAIN" && k < StatementWrite {
+			k = StatementWrite
+		}
+		if k > kind {
+			kind = k
+		}
+	}
+	return kind
+}
+
+// tsqlDDLKeywords and tsqlWriteKeywords can start a new statement anywhere in
+// T-SQL, which does not need semicolons between statements
+var (
+	tsqlDDLKeywords   = map[string]bool{"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "GRANT": true, "REVOKE": true, "DENY": true}
+	tsqlWriteKeywords = map[string]bool{"EXEC": true, "EXECUTE": true}
+)
+
+// classifyTSQL raises kind when a later word of a T-SQL batch begins a
+// statement of its own, as in SELECT 1 DROP TABLE t
+func classifyTSQL(words []string, kind StatementKind) StatementKind {
+	for _, w := range words[1:] {
+		switch {
+		case tsqlDDLKeywords[w]:
+			return StatementDDL
+		case tsqlWriteKeywords[w] || writeKeywords[w]:
+			if kind < StatementWrite {
+				kind = StatementWrite
+			}
+		}
+	}
+	return kind
+}
+
+func classifyStatement(words []string) StatementKind {
+	if len(words) == 0 {
+		return StatementRead
+	}
+	first := strings.TrimSuffix(words[0], "(")
+	switch first {
+	case "SHOW", "DESCRIBE", "DESC":
+		return StatementRead
+	case "EXPLAIN":
+		// Plain EXPLAIN only plans; EXPLAIN ANALYZE executes the statement
+		analyze := false
+		for _, w := range words[1:] {
+			if w == "ANALYZE" || w == "ANALYSE" {
+				analyze = true
+			}
+		}
+		if !analyze {
+			return StatementRead
+		}
+	}
+	switch {
+	case ddlKeywords[first]:
+		return StatementDDL
+	case readKeywords[first]:
+		for _, w := range words[1:] {
+			if writeKeywords[w] {
+				return StatementWrite
+			}
+		}
+		return StatementRead
+	}
+	return StatementWrite
+}
+
+// splitStatements tokenises query into upper-cased bare words per statement,
+// skipping comments, string literals, quoted identifiers and $$ bodies
+func splitStatements(query string, dialect SQLDialect) [][]string {
+	mysql := dialect == DialectMySQL
+	postgres := dialect == DialectPostgres
+	var (
+		stmts [][]string
+		words []string
+		word  strings.Builder
+	)
+	r := []rune(query)
+	i := 0
+	// Words directly followed by "(" are function calls, e.g. REPLACE(s, a, b),
+	// and get a "(" suffix so they never match a keyword
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := strings.ToUpper(word.String())
+		j := i
+		for j < len(r) && unicode.IsSpace(r[j]) {
+			j++
+		}
+		if j < len(r) && r[j] == '(' {
+			w += "("
+		}
+		words = append(words, w)
+		word.Reset()
+	}
+
+	for ; i < len(r); i++ {
+		c := r[i]
+		switch {
+		case c == '-' && i+1 < len(r) && r[i+1] == '-', mysql && c == '#':
+			flush()
+			for i < len(r) && r[i] != '\n' {
+				i++
+			}
+		case mysql && c == '/' && i+2 < len(r) && r[i+1] == '*' && r[i+2] == '!':
+			// MySQL runs the contents of /*! ... */ comments, so only drop
+			// the marker and optional version number
+			flush()
+			i += 2
+			for i+1 < len(r) && unicode.IsDigit(r[i+1]) {
+				i++
+			}
+		case c == '/' && i+1 < len(r) && r[i+1] == '*':
+			flush()
+			i += 2
+			for i+1 < len(r) && !(r[i] == '*' && r[i+1] == '/') {
+				i++
+			}
+			i++
+		case dialect == DialectOracle && c == '\'' && i+1 < len(r) &&
+			(strings.EqualFold(word.String(), "Q") || strings.EqualFold(word.String(), "NQ")):
+			// Alternative quoting: q'[...]', q'{...}', q'(...)', q'<...>' or
+			// q'X...X' for any other delimiter X
+			flush()
+			end := r[i+1]
+			switch end {
+			case '[':
+				end = ']'
+			case '{':
+				end = '}'
+			case '(':
+				end = ')'
+			case '<':
+				end = '>'
+			}
+			i += 2
+			for i < len(r) && !(r[i] == end && i+1 < len(r) && r[i+1] == '\'') {
+				i++
+			}
+			i++
+		case c == '\'' || c == '"' || (mysql && c == '`'):
+			// Backslash escapes apply in MySQL strings and Postgres E'' strings
+			escapes := (mysql && c != '`') || (postgres && c == '\'' && strings.EqualFold(word.String(), "E"))
+			flush()
+			i++
+			for i < len(r) {
+				if escapes && r[i] == '\\' {
+					i += 2
+					continue
+				}
+				if r[i] == c {
+					// Doubled quotes escape themselves
+					if i+1 < len(r) && r[i+1] == c {
+						i += 2
+						continue
+					}
+					break
+				}
+				i++
+			}
+		case dialect == DialectMSSQL && c == '[':
+			// Bracketed identifiers, with ]] escaping ]
+			flush()
+			for i++; i < len(r); i++ {
+				if r[i] == ']' {
+					if i+1 < len(r) && r[i+1] == ']' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case postgres && c == '$' && word.Len() == 0:
+			// Dollar quoting: $$...$$ or $tag$...$tag$
+			end := i + 1
+			for end < len(r) && (unicode.IsLetter(r[end]) || unicode.IsDigit(r[end]) || r[end] == '_') {
+				end++
+			}
+			if end >= len(r) || r[end] != '$' {
+				continue
+			}
+			tag := string(r[i : end+1])
+			rest := string(r[end+1:])
+			if idx := strings.Index(rest, tag); idx >= 0 {
+				i = end + len([]rune(rest[:idx])) + len([]rune(tag))
+			} else {
+				i = len(r)
+			}
+		case c == ';':
+			flush()
+			if len(words) > 0 {
+				stmts = append(stmts, words)
+				words = nil
+			}
+		case unicode.IsLetter(c) || c == '_' || (word.Len() > 0 && unicode.IsDigit(c)):
+			word.WriteRune(c)
+		default:
+			flush()
+		}
+	}
+	flush()
+	if len(words) > 0 {
+		stmts = append(stmts, words)
+	}
+	return stmts
+}
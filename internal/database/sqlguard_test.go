@@ -0,0 +1,110 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifySQL(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect SQLDialect
+		query   string
+		want    StatementKind
+	}{
+		{"empty", DialectPostgres, "", StatementRead},
+		{"select", DialectPostgres, "SELECT 1", StatementRead},
+		{"lower case", DialectPostgres, "select * from t", StatementRead},
+		{"show", DialectPostgres, "SHOW search_path", StatementRead},
+		{"update", DialectPostgres, "UPDATE t SET a = 1", StatementWrite},
+		{"call", DialectPostgres, "CALL refresh()", StatementWrite},
+		{"unknown", DialectPostgres, "LOCK TABLE t", StatementWrite},
+		{"vacuum", DialectPostgres, "VACUUM t", StatementDDL},
+		{"second statement", DialectPostgres, "SELECT 1; DROP TABLE t", StatementDDL},
+		{"trailing semicolons", DialectPostgres, "SELECT 1;;", StatementRead},
+		{"writable CTE", DialectPostgres, "WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d", StatementWrite},
+		{"select into", DialectPostgres, "SELECT * INTO copy FROM t", StatementWrite},
+		{"function named like a keyword", DialectPostgres, "SELECT replace(name, 'a', 'b') FROM t", StatementRead},
+		{"explain", DialectPostgres, "EXPLAIN SELECT 1", StatementRead},
+		{"explain analyze", DialectPostgres, "EXPLAIN ANALYZE DELETE FROM t", StatementWrite},
+		{"explain plans a write", DialectPostgres, "EXPLAIN DELETE FROM t", StatementRead},
+		{"explain analyse option", DialectPostgres, "EXPLAIN (ANALYSE) UPDATE t SET a = 1", StatementWrite},
+		{"explain analyze a read", DialectPostgres, "EXPLAIN ANALYZE SELECT 1", StatementRead},
+		{"line comment", DialectPostgres, "-- DROP TABLE t\nSELECT 1", StatementRead},
+		{"block comment", DialectPostgres, "/* DELETE FROM t; */ SELECT 1", StatementRead},
+		{"string literal", DialectPostgres, "SELECT 'x; DROP TABLE t'", StatementRead},
+		{"doubled quote", DialectPostgres, "SELECT 'it''s; DROP TABLE t'", StatementRead},
+		{"quoted identifier", DialectPostgres, `SELECT "delete" FROM t`, StatementRead},
+		{"standard string ends at backslash quote", DialectPostgres, `SELECT 'a\'; DROP TABLE t; --'`, StatementDDL},
+		{"escape string", DialectPostgres, `SELECT E'a\'; DROP TABLE t'`, StatementRead},
+		{"dollar quoting", DialectPostgres, "SELECT $$; DROP TABLE t$$", StatementRead},
+		{"tagged dollar quoting", DialectPostgres, "SELECT $fn$ $$; DELETE FROM t $fn$", StatementRead},
+		{"unterminated dollar quote", DialectPostgres, "SELECT $x$ DROP TABLE t", StatementRead},
+		{"positional parameter", DialectPostgres, "SELECT $1; DROP TABLE t", StatementDDL},
+
+		{"mysql hash comment", DialectMySQL, "SELECT 1 # ; DROP TABLE t", StatementRead},
+		{"mysql executable comment", DialectMySQL, "SELECT 1; /*!50000 DROP TABLE t */", StatementDDL},
+		{"mysql backslash escape", DialectMySQL, `SELECT 'a\'; DROP TABLE t'`, StatementRead},
+		{"mysql backtick identifier", DialectMySQL, "SELECT `delete` FROM t", StatementRead},
+		{"mysql replace into", DialectMySQL, "REPLACE INTO t VALUES (1)", StatementWrite},
+		{"mysql optimize", DialectMySQL, "OPTIMIZE TABLE t", StatementDDL},
+		{"hash is not a comment in postgres", DialectPostgres, "SELECT 1 # 2; DROP TABLE t", StatementDDL},
+
+		{"tsql batch without semicolon", DialectMSSQL, "SELECT 1 DROP TABLE t", StatementDDL},
+		{"tsql exec", DialectMSSQL, "SELECT 1 EXEC sp_who", StatementWrite},
+		{"tsql deny", DialectMSSQL, "DENY SELECT ON t TO u", StatementDDL},
+		{"tsql bracketed identifier", DialectMSSQL, "SELECT [drop] FROM t", StatementRead},
+		{"tsql escaped bracket", DialectMSSQL, "SELECT [a]]; drop] FROM t", StatementRead},
+		{"tsql keyword in string", DialectMSSQL, "SELECT 'DROP TABLE t'", StatementRead},
+
+		{"oracle q-quote", DialectOracle, "SELECT q'[it's; DROP TABLE t]' FROM dual", StatementRead},
+		{"oracle q-quote with any delimiter", DialectOracle, "SELECT NQ'#a'; DROP TABLE t#' FROM dual", StatementRead},
+		{"oracle q-quote ends at its delimiter", DialectOracle, "SELECT q'(a)' FROM dual; DROP TABLE t", StatementDDL},
+		{"q is no quote prefix in postgres", DialectPostgres, "SELECT q'[a'; DROP TABLE t; --]'", StatementDDL},
+		{"oracle explain plan", DialectOracle, "EXPLAIN PLAN FOR SELECT 1 FROM dual", StatementWrite},
+		{"oracle plsql block", DialectOracle, "BEGIN NULL; END;", StatementWrite},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifySQL(tt.query, tt.dialect); got != tt.want {
+				t.Errorf("ClassifySQL(%q) = %s, want %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckStatement(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		opts   QueryOptions
+		readTx bool
+		err    error
+	}{
+		{"read", "SELECT 1", QueryOptions{}, true, nil},
+		{"read with write allowed", "SELECT 1", QueryOptions{AllowWrite: true}, false, nil},
+		{"read in read-only mode", "SELECT 1", QueryOptions{ReadOnly: true, AllowWrite: true}, true, nil},
+		{"write", "DELETE FROM t", QueryOptions{}, true, ErrElevationRequired},
+		{"ddl", "DROP TABLE t", QueryOptions{}, true, ErrElevationRequired},
+		{"write allowed", "DELETE FROM t", QueryOptions{AllowWrite: true}, false, nil},
+		{"ddl allowed", "DROP TABLE t", QueryOptions{AllowWrite: true}, false, nil},
+		{"write in read-only mode", "DELETE FROM t", QueryOptions{ReadOnly: true, AllowWrite: true}, true, ErrReadOnlyQuery},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, readTx, err := checkStatement(tt.query, DialectPostgres, tt.opts)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+			if readTx != tt.readTx {
+				t.Errorf("readTx = %v, want %v", readTx, tt.readTx)
+			}
+			if err := CheckQuery(tt.query, DialectPostgres, tt.opts); !errors.Is(err, tt.err) {
+				t.Errorf("CheckQuery error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
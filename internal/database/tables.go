@@ -0,0 +1,603 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The table browser pages through one table of a connection profile with
+// column filters and sorting, and edits single rows by primary key. Every
+// identifier is checked against the table's columns in INFORMATION_SCHEMA and
+// every value is a bound parameter, so no caller input is spliced into SQL.
+
+// Column filter operators
+const (
+	FilterEq         = "eq"
+	FilterNe         = "ne"
+	FilterLt         = "lt"
+	FilterLte        = "lte"
+	FilterGt         = "gt"
+	FilterGte        = "gte"
+	FilterContains   = "contains"    // case-insensitive substring of the value as text
+	FilterStartsWith = "starts_with" // case-insensitive prefix of the value as text
+	FilterIsNull     = "is_null"
+	FilterNotNull    = "not_null"
+)
+
+var filterOperators = map[string]string{
+	FilterEq: "=", FilterNe: "<>", FilterLt: "<", FilterLte: "<=", FilterGt: ">", FilterGte: ">=",
+}
+
+// defaultPageSize is the page size when a request sets none
+const defaultPageSize = 50
+
+// ColumnFilter restricts the rows to those where Column matches Value under Op
+type ColumnFilter struct {
+	Column string      `json:"column"`
+	Op     string      `json:"op"`
+	Value  interface{} `json:"value"`
+}
+
+// ColumnSort orders the rows by Column
+type ColumnSort struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc"`
+}
+
+// TableRequest selects a page of a table. Schema defaults to the
+// connection's current schema (the database on MySQL).
+type TableRequest struct {
+	ProfileID string         `json:"profile_id"`
+	Schema    string         `json:"schema"`
+	Table     string         `json:"table"`
+	Filters   []ColumnFilter `json:"filters"`
+	Sort      []ColumnSort   `json:"sort"`
+	Limit     int            `json:"limit"`
+	Offset    int            `json:"offset"`
+}
+
+// TableColumn describes one column of a browsed table
+type TableColumn struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Nullable   bool   `json:"nullable"`
+	PrimaryKey bool   `json:"primary_key"`
+}
+
+// TablePage is one page of rows with the table's columns
+type TablePage struct {
+	Schema        string          `json:"schema"`
+	Table         string          `json:"table"`
+	Columns       []TableColumn   `json:"columns"`
+	PrimaryKey    []string        `json:"primary_key"` // empty when the table has none, and rows cannot be edited
+	Rows          [][]interface{} `json:"rows"`
+	Total         int64           `json:"total"` // rows matching the filters
+	Limit         int             `json:"limit"`
+	Offset        int             `json:"offset"`
+	MaskedColumns []string        `json:"masked_columns,omitempty"`
+	Duration      float64         `json:"duration_ms"`
+}
+
+// RowChange inserts, updates or deletes one row. Key holds the full primary
+// key of the row to update or delete; Values the columns to insert or set.
+type RowChange struct {
+	ProfileID string                 `json:"profile_id"`
+	Schema    string                 `json:"schema"`
+	Table     string                 `json:"table"`
+	Key       map[string]interface{} `json:"key"`
+	Values    map[string]interface{} `json:"values"`
+	DryRun    bool                   `json:"dry_run"` // return the statement without running it
+}
+
+// RowChangeResult is the statement run for a RowChange
+type RowChangeResult struct {
+	Statement    string        `json:"statement"`
+	Args         []interface{} `json:"args"`
+	RowsAffected int64         `json:"rows_affected"`
+	DryRun       bool          `json:"dry_run"`
+}
+
+// tableDialect holds the SQL differences the table browser cares about
+type tableDialect struct {
+	currentSchema string
+	quoteOpen     string
+	quoteClose    string
+	placeholder   func(n int) string
+	castText      string // CAST of a column to text, with one %s
+	like          string // case-insensitive LIKE
+	offsetFetch   bool   // pages with OFFSET ... FETCH, which needs an ORDER BY, instead of LIMIT
+	begin         func(ctx context.Context, db *sql.DB, readTx bool) (sqlRunner, func(), error)
+}
+
+var tableDialects = map[SQLDialect]tableDialect{
+	DialectPostgres: {
+		currentSchema: "SELECT current_schema()",
+		quoteOpen:     `"`, quoteClose: `"`,
+		placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+		castText:    "CAST(%s AS TEXT)",
+		like:        "ILIKE",
+		begin:       beginGuarded,
+	},
+	DialectMySQL: {
+		currentSchema: "SELECT DATABASE()",
+		quoteOpen:     "`", quoteClose: "`",
+		placeholder: func(int) string { return "?" },
+		castText:    "CAST(%s AS CHAR)",
+		like:        "LIKE",
+		begin:       beginGuarded,
+	},
+	DialectMSSQL: {
+		currentSchema: "SELECT SCHEMA_NAME()",
+		quoteOpen:     "[", quoteClose: "]",
+		placeholder: func(n int) string { return fmt.Sprintf("@p%d", n) },
+		castText:    "CAST(%s AS NVARCHAR(MAX))",
+		like:        "LIKE",
+		offsetFetch: true,
+		begin:       beginRolledBack,
+	},
+}
+
+// quote quotes an identifier, doubling any closing quote inside it
+func (d tableDialect) quote(name string) string {
+	return d.quoteOpen + strings.ReplaceAll(name, d.quoteClose, d.quoteClose+d.quoteClose) + d.quoteClose
+}
+
+// openTableTarget opens the database of a connection profile, the primary
+// when write is set
+func openTableTarget(ctx context.Context, profileID string, write bool) (*sql.DB, tableDialect, error) {
+	config, status, ok := profileConfig(profileID)
+	if !ok {
+		return nil, tableDialect{}, fmt.Errorf("unknown connection profile %q: connect to the database first", profileID)
+	}
+	var db *sql.DB
+	var err error
+	var dialect SQLDialect
+	switch c := config.(type) {
+	case PostgresConfig:
+		dialect = DialectPostgres
+		db, _, err = openPostgresFor(ctx, c, write)
+	case MySQLConfig:
+		dialect = DialectMySQL
+		db, _, err = openMySQLFor(ctx, c, write)
+	case MSSQLConfig:
+		dialect = DialectMSSQL
+		db, err = openMSSQL(c)
+	default:
+		err = fmt.Errorf("the table browser needs a PostgreSQL, MySQL or SQL Server profile, not %s", status.Kind)
+	}
+	return db, tableDialects[dialect], err
+}
+
+// tableMeta is a table's columns and primary key
+type tableMeta struct {
+	schema  string
+	table   string
+	columns []TableColumn
+	byName  map[string]bool
+	key     []string
+}
+
+func (m *tableMeta) name(d tableDialect) string {
+	return d.quote(m.schema) + "." + d.quote(m.table)
+}
+
+func (m *tableMeta) column(name string) error {
+	if !m.byName[name] {
+		return fmt.Errorf("unknown column %q in %s.%s", name, m.schema, m.table)
+	}
+	return nil
+}
+
+// readTableMeta looks the table up in INFORMATION_SCHEMA
+func readTableMeta(ctx context.Context, runner sqlRunner, d tableDialect, schema, table string) (*tableMeta, error) {
+	if table == "" {
+		return nil, fmt.Errorf("table is required")
+	}
+	if schema == "" {
+		rows, err := runner.QueryContext(ctx, d.currentSchema)
+		if err != nil {
+			return nil, err
+		}
+		var current sql.NullString
+		if rows.Next() {
+			rows.Scan(&current)
+		}
+		rows.Close()
+		if schema = current.String; schema == "" {
+			return nil, fmt.Errorf("schema is required: the connection has no current schema")
+		}
+	}
+
+	m := &tableMeta{schema: schema, table: table, byName: map[string]bool{}}
+	rows, err := runner.QueryContext(ctx, fmt.Sprintf(
+		"SELECT COLUMN_NAME, DATA_TYPE, IS_NULLABLE FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = %s AND TABLE_NAME = %s ORDER BY ORDINAL_POSITION",
+		d.placeholder(1), d.placeholder(2)), schema, table)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var col TableColumn
+		var nullable string
+		if err := rows.Scan(&col.Name, &col.Type, &nullable); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		col.Nullable = nullable == "YES"
+		m.columns = append(m.columns, col)
+		m.byName[col.Name] = true
+	}
+	rows.Close()
+	if len(m.columns) == 0 {
+		return nil, fmt.Errorf("table %s.%s not found", schema, table)
+	}
+
+	rows, err = runner.QueryContext(ctx, fmt.Sprintf(
+		`SELECT k.COLUMN_NAME FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS t
+		JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE k
+		  ON k.CONSTRAINT_NAME = t.CONSTRAINT_NAME AND k.TABLE_SCHEMA = t.TABLE_SCHEMA AND k.TABLE_NAME = t.TABLE_NAME
+		WHERE t.CONSTRAINT_TYPE = 'PRIMARY KEY' AND t.TABLE_SCHEMA = %s AND t.TABLE_NAME = %s
+		ORDER BY k.ORDINAL_POSITION`, d.placeholder(1), d.placeholder(2)), schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		m.key = append(m.key, name)
+	}
+	for i := range m.columns {
+		for _, k := range m.key {
+			if m.columns[i].Name == k {
+				m.columns[i].PrimaryKey = true
+			}
+		}
+	}
+	return m, rows.Err()
+}
+
+// statement builds SQL with numbered parameters
+type statement struct {
+	d    tableDialect
+	args []interface{}
+}
+
+func (s *statement) arg(v interface{}) string {
+	s.args = append(s.args, jsonValue(v))
+	return s.d.placeholder(len(s.args))
+}
+
+// jsonValue turns a whole JSON number back into an integer, so it binds as
+// one instead of as a float
+func jsonValue(v interface{}) interface{} {
+	if f, ok := v.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return int64(f)
+	}
+	return v
+}
+
+// escapeLike escapes the LIKE wildcards in s for ESCAPE '!'
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_", "[", "![").Replace(s)
+}
+
+// where builds the WHERE clause of the filters
+func (s *statement) where(m *tableMeta, filters []ColumnFilter) (string, error) {
+	var conds []string
+	for _, f := range filters {
+		if err := m.column(f.Column); err != nil {
+			return "", err
+		}
+		col := s.d.quote(f.Column)
+		switch f.Op {
+		case FilterIsNull:
+			conds = append(conds, col+" IS NULL")
+		case FilterNotNull:
+			conds = append(conds, col+" IS NOT NULL")
+		case FilterContains, FilterStartsWith:
+			pattern := escapeLike(fmt.Sprint(f.Value)) + "%"
+			if f.Op == FilterContains {
+				pattern = "%" + pattern
+			}
+			conds = append(conds, fmt.Sprintf("%s %s %s ESCAPE '!'", fmt.Sprintf(s.d.castText, col), s.d.like, s.arg(pattern)))
+		default:
+			op, ok := filterOperators[f.Op]
+			if !ok {
+				return "", fmt.Errorf("unknown filter operator %q", f.Op)
+			}
+			if f.Value == nil {
+				return "", fmt.Errorf("filter on %s needs a value; use is_null or not_null for NULL", f.Column)
+			}
+			conds = append(conds, fmt.Sprintf("%s %s %s", col, op, s.arg(f.Value)))
+		}
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), nil
+}
+
+// BrowseTable returns one page of a table. Filtering or sorting on a column
+// the policy masks is refused, since it would reveal the values.
+func BrowseTable(ctx context.Context, req TableRequest, policy *ResultPolicy) (*TablePage, error) {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, StatementTimeout())
+	defer cancel()
+
+	db, d, err := openTableTarget(ctx, req.ProfileID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	runner, done, err := d.begin(ctx, db, true)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	m, err := readTableMeta(ctx, runner, d, req.Schema, req.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range req.Filters {
+		if policy.masks(f.Column) {
+			return nil, fmt.Errorf("%w to filter on masked column %s", ErrElevationRequired, f.Column)
+		}
+	}
+	for _, o := range req.Sort {
+		if policy.masks(o.Column) {
+			return nil, fmt.Errorf("%w to sort on masked column %s", ErrElevationRequired, o.Column)
+		}
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if max := policy.maxRows(); limit > max {
+		limit = max
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+
+	s := &statement{d: d}
+	where, err := s.where(m, req.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	count, err := runner.QueryContext(ctx, "SELECT COUNT(*) FROM "+m.name(d)+where, s.args...)
+	if err != nil {
+		return nil, err
+	}
+	if count.Next() {
+		count.Scan(&total)
+	}
+	count.Close()
+
+	var order []string
+	for _, o := range req.Sort {
+		if err := m.column(o.Column); err != nil {
+			return nil, err
+		}
+		dir := "ASC"
+		if o.Desc {
+			dir = "DESC"
+		}
+		order = append(order, s.d.quote(o.Column)+" "+dir)
+	}
+	// Page by the primary key when nothing else orders the rows, so pages
+	// do not overlap
+	if len(order) == 0 {
+		for _, k := range m.key {
+			order = append(order, d.quote(k))
+		}
+	}
+
+	cols := make([]string, len(m.columns))
+	for i, c := range m.columns {
+		cols[i] = d.quote(c.Name)
+	}
+	query := "SELECT " + strings.Join(cols, ", ") + " FROM " + m.name(d) + where
+	if d.offsetFetch {
+		if len(order) == 0 {
+			order = []string{"(SELECT NULL)"}
+		}
+		query += fmt.Sprintf(" ORDER BY %s OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", strings.Join(order, ", "), req.Offset, limit)
+	} else {
+		if len(order) > 0 {
+			query += " ORDER BY " + strings.Join(order, ", ")
+		}
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, req.Offset)
+	}
+
+	rows, err := runner.QueryContext(ctx, query, s.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	types, _ := rows.ColumnTypes()
+
+	page := &TablePage{
+		Schema:     m.schema,
+		Table:      m.table,
+		Columns:    m.columns,
+		PrimaryKey: m.key,
+		Rows:       make([][]interface{}, 0),
+		Total:      total,
+		Limit:      limit,
+		Offset:     req.Offset,
+	}
+	if page.PrimaryKey == nil {
+		page.PrimaryKey = []string{}
+	}
+	names := make([]string, len(m.columns))
+	for i, c := range m.columns {
+		names[i] = c.Name
+	}
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			values[i] = mssqlValue(v, types[i].DatabaseTypeName())
+		}
+		page.Rows = append(page.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	page.MaskedColumns = policy.apply(names, page.Rows)
+	page.Duration = float64(time.Since(start).Microseconds()) / 1000.0
+	return page, nil
+}
+
+// Row change operations
+const (
+	RowInsert = "insert"
+	RowUpdate = "update"
+	RowDelete = "delete"
+)
+
+// ChangeRow inserts, updates or deletes exactly one row. Updates and deletes
+// need the full primary key and are rolled back unless they match one row.
+func ChangeRow(ctx context.Context, op string, change RowChange) (*RowChangeResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, StatementTimeout())
+	defer cancel()
+
+	db, d, err := openTableTarget(ctx, change.ProfileID, true)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	m, err := readTableMeta(ctx, db, d, change.Schema, change.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &statement{d: d}
+	var query string
+	switch op {
+	case RowInsert:
+		if len(change.Values) == 0 {
+			return nil, fmt.Errorf("values are required")
+		}
+		var cols, params []string
+		for _, name := range sortedKeys(change.Values) {
+			if err := m.column(name); err != nil {
+				return nil, err
+			}
+			cols = append(cols, d.quote(name))
+			params = append(params, s.arg(change.Values[name]))
+		}
+		query = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", m.name(d), strings.Join(cols, ", "), strings.Join(params, ", "))
+	case RowUpdate, RowDelete:
+		if op == RowUpdate {
+			if len(change.Values) == 0 {
+				return nil, fmt.Errorf("values are required")
+			}
+			var sets []string
+			for _, name := range sortedKeys(change.Values) {
+				if err := m.column(name); err != nil {
+					return nil, err
+				}
+				sets = append(sets, d.quote(name)+" = "+s.arg(change.Values[name]))
+			}
+			query = fmt.Sprintf("UPDATE %s SET %s", m.name(d), strings.Join(sets, ", "))
+		} else {
+			query = "DELETE FROM " + m.name(d)
+		}
+		where, err := s.keyWhere(m, change.Key)
+		if err != nil {
+			return nil, err
+		}
+		query += where
+	default:
+		return nil, fmt.Errorf("unknown row operation %q", op)
+	}
+
+	result := &RowChangeResult{Statement: query, Args: s.args, DryRun: change.DryRun}
+	if change.DryRun {
+		return result, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, query, s.args...)
+	if err != nil {
+		return nil, err
+	}
+	result.RowsAffected, _ = res.RowsAffected()
+	if op != RowInsert && result.RowsAffected != 1 {
+		matched := result.RowsAffected
+		// MySQL counts changed rather than matched rows, so an update that
+		// sets the current values reports 0
+		if matched == 0 && op == RowUpdate {
+			k := &statement{d: d}
+			where, _ := k.keyWhere(m, change.Key)
+			if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+m.name(d)+where, k.args...).Scan(&matched); err != nil {
+				return nil, err
+			}
+		}
+		if matched != 1 {
+			return nil, fmt.Errorf("%s matched %d rows, expected 1; nothing was changed", op, matched)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// keyWhere builds the WHERE clause selecting one row by its full primary key
+func (s *statement) keyWhere(m *tableMeta, key map[string]interface{}) (string, error) {
+	if len(m.key) == 0 {
+		return "", fmt.Errorf("%s.%s has no primary key; edit it with SQL instead", m.schema, m.table)
+	}
+	if len(key) != len(m.key) {
+		return "", fmt.Errorf("key must hold exactly the primary key columns: %s", strings.Join(m.key, ", "))
+	}
+	conds := make([]string, 0, len(m.key))
+	for _, k := range m.key {
+		v, ok := key[k]
+		if !ok || v == nil {
+			return "", fmt.Errorf("key must hold exactly the primary key columns: %s", strings.Join(m.key, ", "))
+		}
+		conds = append(conds, s.d.quote(k)+" = "+s.arg(v))
+	}
+	return " WHERE " + strings.Join(conds, " AND "), nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
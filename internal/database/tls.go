@@ -0,0 +1,257 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gaga951/gagos/internal/egress"
+	"github.com/gaga951/gagos/internal/k8s"
+	mysqldrv "github.com/go-sql-driver/mysql"
+)
+
+// Every connector accepts the same TLS block: a CA bundle, a client
+// certificate and key as PEM, or a reference to a Kubernetes Secret holding
+// them, plus the verification mode.
+
+// TLS modes, named after PostgreSQL's sslmode values
+const (
+	TLSDisable    = "disable"
+	TLSRequire    = "require"     // encrypt, do not verify the server
+	TLSVerifyCA   = "verify-ca"   // verify the server certificate chain
+	TLSVerifyFull = "verify-full" // verify the chain and the server name
+)
+
+// TLSSecretRef points at a Secret holding the TLS material, such as a
+// kubernetes.io/tls Secret or one issued by cert-manager
+type TLSSecretRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	CAKey     string `json:"ca_key,omitempty"`   // default ca.crt
+	CertKey   string `json:"cert_key,omitempty"` // default tls.crt
+	KeyKey    string `json:"key_key,omitempty"`  // default tls.key
+}
+
+// TLSOptions configures TLS for a database or storage connection. PEM fields
+// given inline take precedence over the same item from Secret.
+type TLSOptions struct {
+	Mode       string        `json:"mode,omitempty"`
+	CACert     string        `json:"ca_cert,omitempty"`
+	ClientCert string        `json:"client_cert,omitempty"`
+	ClientKey  string        `json:"client_key,omitempty"`
+	ServerName string        `json:"server_name,omitempty"` // name checked by verify-full instead of the host
+	Secret     *TLSSecretRef `json:"secret,omitempty"`
+}
+
+// tlsMaterial is the PEM data of a TLSOptions with the Secret resolved
+type tlsMaterial struct {
+	ca, cert, key []byte
+}
+
+// mode returns the TLS mode to use. legacy is the connector's older setting
+// (ssl_mode, use_tls, use_ssl); a TLS block without a mode verifies fully.
+func (o *TLSOptions) mode(legacy string) string {
+	switch {
+	case o != nil && o.Mode != "":
+		return o.Mode
+	case legacy != "":
+		return legacy
+	case o != nil:
+		return TLSVerifyFull
+	}
+	return TLSDisable
+}
+
+// material resolves the PEM data, reading the Secret when one is referenced
+func (o *TLSOptions) material() (tlsMaterial, error) {
+	var m tlsMaterial
+	if o == nil {
+		return m, nil
+	}
+
+	if ref := o.Secret; ref != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		data, err := k8s.SecretData(ctx, ref.Namespace, ref.Name)
+		if err != nil {
+			return m, fmt.Errorf("TLS secret %s/%s: %w", ref.Namespace, ref.Name, err)
+		}
+		m.ca = data[keyOr(ref.CAKey, "ca.crt")]
+		m.cert = data[keyOr(ref.CertKey, "tls.crt")]
+		m.key = data[keyOr(ref.KeyKey, "tls.key")]
+	}
+
+	if o.CACert != "" {
+		m.ca = []byte(o.CACert)
+	}
+	if o.ClientCert != "" {
+		m.cert = []byte(o.ClientCert)
+	}
+	if o.ClientKey != "" {
+		m.key = []byte(o.ClientKey)
+	}
+	if (len(m.cert) == 0) != (len(m.key) == 0) {
+		return m, fmt.Errorf("TLS client certificate and key must be given together")
+	}
+	return m, nil
+}
+
+func keyOr(key, def string) string {
+	if key == "" {
+		return def
+	}
+	return key
+}
+
+// clientConfig builds the tls.Config for mode against host
+func (o *TLSOptions) clientConfig(mode, host string) (*tls.Config, error) {
+	m, err := o.material()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: host}
+	if o != nil && o.ServerName != "" {
+		cfg.ServerName = o.ServerName
+	}
+	if len(m.ca) > 0 {
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(m.ca) {
+			return nil, fmt.Errorf("no certificate found in the TLS CA bundle")
+		}
+	}
+	if len(m.cert) > 0 {
+		cert, err := tls.X509KeyPair(m.cert, m.key)
+		if err != nil {
+			return nil, fmt.Errorf("TLS client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	switch mode {
+	case TLSRequire:
+		cfg.InsecureSkipVerify = true
+	case TLSVerifyCA:
+		// crypto/tls always checks the name, so the chain is verified by hand
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = verifyChain(cfg.RootCAs)
+	case TLSVerifyFull:
+	default:
+		return nil, fmt.Errorf("TLS mode must be disable, require, verify-ca or verify-full")
+	}
+	return cfg, nil
+}
+
+// verifyChain checks the server certificate against roots (the system pool
+// when nil) without checking its name
+func verifyChain(roots *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("server sent no certificate")
+		}
+		opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+		var leaf *x509.Certificate
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			if i == 0 {
+				leaf = cert
+			} else {
+				opts.Intermediates.AddCert(cert)
+			}
+		}
+		_, err := leaf.Verify(opts)
+		return err
+	}
+}
+
+// tlsHash identifies a TLS configuration for one host
+func tlsHash(o *TLSOptions, mode, host string) string {
+	b, _ := json.Marshal(o)
+	sum := sha256.Sum256([]byte(mode + "\x00" + host + "\x00" + string(b)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// pqTLSParams returns the lib/pq connection string parameters carrying the
+// TLS material. lib/pq only takes inline PEM together with a client
+// certificate, so a CA bundle on its own is written to a temporary file; it
+// holds nothing secret.
+func (o *TLSOptions) pqTLSParams() (string, error) {
+	m, err := o.material()
+	if err != nil {
+		return "", err
+	}
+
+	var params []string
+	switch {
+	case len(m.cert) > 0:
+		params = append(params, "sslinline=true", "sslcert="+pqQuote(string(m.cert)), "sslkey="+pqQuote(string(m.key)))
+		if len(m.ca) > 0 {
+			params = append(params, "sslrootcert="+pqQuote(string(m.ca)))
+		}
+	case len(m.ca) > 0:
+		sum := sha256.Sum256(m.ca)
+		path := filepath.Join(os.TempDir(), "gagos-ca-"+hex.EncodeToString(sum[:8])+".pem")
+		if _, err := os.Stat(path); err != nil {
+			if err := os.WriteFile(path, m.ca, 0o644); err != nil {
+				return "", fmt.Errorf("writing TLS CA bundle: %w", err)
+			}
+		}
+		params = append(params, "sslrootcert="+pqQuote(path))
+	}
+	if len(params) == 0 {
+		return "", nil
+	}
+	return " " + strings.Join(params, " "), nil
+}
+
+// pqQuote quotes a connection string value for lib/pq
+func pqQuote(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+// mysqlTLSParam registers the TLS configuration with the MySQL driver and
+// returns the DSN parameter selecting it. Registrations are keyed by a hash of
+// the configuration, so reconnecting reuses the same name.
+func (o *TLSOptions) mysqlTLSParam(mode, host string) (string, error) {
+	cfg, err := o.clientConfig(mode, host)
+	if err != nil {
+		return "", err
+	}
+	name := "gagos-" + tlsHash(o, mode, host)
+	if err := mysqldrv.RegisterTLSConfig(name, cfg); err != nil {
+		return "", err
+	}
+	return "&tls=" + name, nil
+}
+
+// tlsDialer dials through the egress policy and runs the TLS handshake
+func tlsDialer(cfg *tls.Config) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := egress.Default().DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
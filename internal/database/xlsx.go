@@ -0,0 +1,279 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A minimal reader for the first (or a named) worksheet of an .xlsx file:
+// enough for table imports, which only need each cell's displayed value.
+// Formulas are read from their cached results.
+
+// maxXLSXPart bounds one unpacked part of a workbook
+const maxXLSXPart = 128 << 20
+
+// maxXLSXColumns is Excel's column limit (XFD)
+const maxXLSXColumns = 16384
+
+// xlsxDateFormats are the built-in number formats that show dates and times
+var xlsxDateFormats = map[int]bool{14: true, 15: true, 16: true, 17: true, 18: true, 19: true, 20: true, 21: true, 22: true, 45: true, 46: true, 47: true}
+
+// xlsxFormatLiteral matches the quoted, escaped and bracketed parts of a
+// number format, which do not make it a date format
+var xlsxFormatLiteral = regexp.MustCompile(`"[^"]*"|\\.|\[[^\]]*\]`)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRels struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []struct {
+		T    string `xml:"t"`
+		Runs []struct {
+			T string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string `xml:"r,attr"`
+			T      string `xml:"t,attr"`
+			S      int    `xml:"s,attr"`
+			V      string `xml:"v"`
+			Inline struct {
+				T    string `xml:"t"`
+				Runs []struct {
+					T string `xml:"t"`
+				} `xml:"r"`
+			} `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX returns the rows of a worksheet, the first when sheet is empty,
+// each with its 1-based row number. Empty rows are skipped.
+func readXLSX(data []byte, sheet string) ([]importRecord, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not an xlsx file: %w", err)
+	}
+	parts := map[string]*zip.File{}
+	for _, f := range zr.File {
+		parts[f.Name] = f
+	}
+	part := func(name string, v interface{}) error {
+		f, ok := parts[name]
+		if !ok {
+			return nil
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		b, err := io.ReadAll(io.LimitReader(rc, maxXLSXPart+1))
+		if err != nil {
+			return err
+		}
+		if len(b) > maxXLSXPart {
+			return fmt.Errorf("%s unpacks to more than %d MB", name, maxXLSXPart>>20)
+		}
+		return xml.Unmarshal(b, v)
+	}
+
+	var wb xlsxWorkbook
+	var rels xlsxRels
+	if err := part("xl/workbook.xml", &wb); err != nil {
+		return nil, fmt.Errorf("invalid workbook: %w", err)
+	}
+	if err := part("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, fmt.Errorf("invalid workbook: %w", err)
+	}
+	if len(wb.Sheets) == 0 {
+		return nil, fmt.Errorf("workbook has no sheets")
+	}
+	rid := wb.Sheets[0].RID
+	if sheet != "" {
+		rid = ""
+		for _, s := range wb.Sheets {
+			if s.Name == sheet {
+				rid = s.RID
+			}
+		}
+		if rid == "" {
+			return nil, fmt.Errorf("workbook has no sheet %q", sheet)
+		}
+	}
+	var sheetPart string
+	for _, r := range rels.Relationships {
+		if r.ID == rid {
+			if strings.HasPrefix(r.Target, "/") {
+				sheetPart = strings.TrimPrefix(r.Target, "/")
+			} else {
+				sheetPart = path.Join("xl", r.Target)
+			}
+		}
+	}
+	if _, ok := parts[sheetPart]; !ok {
+		return nil, fmt.Errorf("invalid workbook: worksheet %q is missing", sheetPart)
+	}
+
+	var sst xlsxSharedStrings
+	var styles xlsxStyles
+	var ws xlsxSheet
+	if err := part("xl/sharedStrings.xml", &sst); err != nil {
+		return nil, fmt.Errorf("invalid workbook: %w", err)
+	}
+	if err := part("xl/styles.xml", &styles); err != nil {
+		return nil, fmt.Errorf("invalid workbook: %w", err)
+	}
+	if err := part(sheetPart, &ws); err != nil {
+		return nil, fmt.Errorf("invalid worksheet: %w", err)
+	}
+
+	strs := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		s := si.T
+		for _, r := range si.Runs {
+			s += r.T
+		}
+		strs[i] = s
+	}
+	customDate := map[int]bool{}
+	for _, f := range styles.NumFmts {
+		code := strings.ToLower(xlsxFormatLiteral.ReplaceAllString(f.Code, ""))
+		customDate[f.ID] = strings.ContainsAny(code, "ymdhs") && !strings.Contains(code, "0")
+	}
+	isDate := func(style int) bool {
+		if style < 0 || style >= len(styles.CellXfs) {
+			return false
+		}
+		id := styles.CellXfs[style].NumFmtID
+		return xlsxDateFormats[id] || customDate[id]
+	}
+
+	var records []importRecord
+	next := 1
+	for _, row := range ws.Rows {
+		line := row.R
+		if line == 0 {
+			line = next
+		}
+		next = line + 1
+
+		var values []string
+		col := 0
+		empty := true
+		for _, c := range row.Cells {
+			if c.R != "" {
+				col = xlsxColumn(c.R)
+			}
+			if col >= maxXLSXColumns {
+				return nil, fmt.Errorf("row %d: cell %s is beyond the last column", line, c.R)
+			}
+			var v string
+			switch c.T {
+			case "s":
+				i, err := strconv.Atoi(c.V)
+				if err != nil || i < 0 || i >= len(strs) {
+					return nil, fmt.Errorf("row %d: invalid shared string %q", line, c.V)
+				}
+				v = strs[i]
+			case "inlineStr":
+				v = c.Inline.T
+				for _, r := range c.Inline.Runs {
+					v += r.T
+				}
+			case "b":
+				v = "false"
+				if c.V == "1" {
+					v = "true"
+				}
+			case "", "n":
+				v = c.V
+				if v != "" && isDate(c.S) {
+					if serial, err := strconv.ParseFloat(v, 64); err == nil {
+						v = xlsxDate(serial)
+					}
+				}
+			default: // str (formula text), e (error), d (ISO date)
+				v = c.V
+			}
+			for len(values) < col {
+				values = append(values, "")
+			}
+			values = append(values, v)
+			if v != "" {
+				empty = false
+			}
+			col++
+		}
+		if !empty {
+			records = append(records, importRecord{line: line, values: values})
+		}
+	}
+	return records, nil
+}
+
+// xlsxColumn returns the 0-based column of a cell reference such as "AB12"
+func xlsxColumn(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A') + 1
+		if col > maxXLSXColumns {
+			break
+		}
+	}
+	return col - 1
+}
+
+// xlsxDate converts a date serial (days since 1899-12-30) to the layout the
+// import parses, dropping the time of day when there is none
+func xlsxDate(serial float64) string {
+	days, frac := math.Modf(serial)
+	t := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).
+		AddDate(0, 0, int(days)).
+		Add(time.Duration(math.Round(frac*86400)) * time.Second)
+	if frac == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,197 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testXLSXWorkbook = `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Data" r:id="rId1"/><sheet name="Other" r:id="rId2"/></sheets></workbook>`
+
+const testXLSXRels = `<Relationships>
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Target="/xl/worksheets/sheet2.xml"/></Relationships>`
+
+const testXLSXStyles = `<styleSheet>
+<numFmts><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd"/><numFmt numFmtId="165" formatCode="&quot;day&quot; 0.00"/></numFmts>
+<cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="22"/><xf numFmtId="164"/><xf numFmtId="165"/></cellXfs></styleSheet>`
+
+// testXLSX zips a workbook with the given sheet data for the first sheet
+// and parts added or replaced by extra
+func testXLSX(t *testing.T, sheetData string, extra map[string]string) []byte {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml":            testXLSXWorkbook,
+		"xl/_rels/workbook.xml.rels": testXLSXRels,
+		"xl/styles.xml":              testXLSXStyles,
+		"xl/worksheets/sheet1.xml":   "<worksheet><sheetData>" + sheetData + "</sheetData></worksheet>",
+		"xl/worksheets/sheet2.xml":   `<worksheet><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>second</t></is></c></row></sheetData></worksheet>`,
+	}
+	for name, body := range extra {
+		parts[name] = body
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range parts {
+		if body == "" {
+			continue
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadXLSX(t *testing.T) {
+	shared := map[string]string{"xl/sharedStrings.xml": `<sst>
+<si><t>id</t></si><si><t>name</t></si><si><r><t>Ada </t></r><r><t>Lovelace</t></r></si></sst>`}
+
+	tests := []struct {
+		name   string
+		sheet  string
+		data   string
+		extra  map[string]string
+		want   []importRecord
+		errMsg string
+	}{
+		{
+			name:  "shared strings and rich text",
+			data:  `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row><row r="2"><c r="A2"><v>7</v></c><c r="B2" t="s"><v>2</v></c></row>`,
+			extra: shared,
+			want:  []importRecord{{1, []string{"id", "name"}}, {2, []string{"7", "Ada Lovelace"}}},
+		},
+		{
+			name: "inline strings",
+			data: `<row r="1"><c r="A1" t="inlineStr"><is><t>plain</t></is></c><c r="B1" t="inlineStr"><is><r><t>ri</t></r><r><t>ch</t></r></is></c></row>`,
+			want: []importRecord{{1, []string{"plain", "rich"}}},
+		},
+		{
+			name: "booleans, formulas and errors",
+			data: `<row r="1"><c r="A1" t="b"><v>1</v></c><c r="B1" t="b"><v>0</v></c><c r="C1" t="str"><f>A1&amp;"x"</f><v>TRUEx</v></c><c r="D1" t="e"><v>#DIV/0!</v></c></row>`,
+			want: []importRecord{{1, []string{"true", "false", "TRUEx", "#DIV/0!"}}},
+		},
+		{
+			name: "date styles",
+			data: `<row r="1"><c r="A1" s="1"><v>45352</v></c><c r="B1" s="2"><v>45352.5</v></c><c r="C1" s="3"><v>1</v></c><c r="D1" s="4"><v>45352</v></c><c r="E1" s="0"><v>45352</v></c><c r="F1" s="9"><v>45352</v></c></row>`,
+			want: []importRecord{{1, []string{"2024-03-01", "2024-03-01 12:00:00", "1899-12-31", "45352", "45352", "45352"}}},
+		},
+		{
+			name: "sparse cells",
+			data: `<row r="1"><c r="B1"><v>2</v></c><c r="E1"><v>5</v></c></row><row r="3"><c r="AA3"><v>27</v></c></row>`,
+			want: []importRecord{
+				{1, []string{"", "2", "", "", "5"}},
+				{3, append(make([]string, 26), "27")},
+			},
+		},
+		{
+			name: "cells and rows without references",
+			data: `<row><c><v>1</v></c><c><v>2</v></c></row><row r="4"><c r="B4"><v>3</v></c><c><v>4</v></c></row><row><c><v>5</v></c></row>`,
+			want: []importRecord{{1, []string{"1", "2"}}, {4, []string{"", "3", "4"}}, {5, []string{"5"}}},
+		},
+		{
+			name: "empty rows are skipped",
+			data: `<row r="1"><c r="A1"><v>1</v></c></row><row r="2"></row><row r="3"><c r="A3" t="inlineStr"><is><t></t></is></c></row><row r="4"><c r="A4"><v>4</v></c></row>`,
+			want: []importRecord{{1, []string{"1"}}, {4, []string{"4"}}},
+		},
+		{
+			name:  "named sheet",
+			sheet: "Other",
+			want:  []importRecord{{1, []string{"second"}}},
+		},
+		{
+			name:   "unknown sheet",
+			sheet:  "Nope",
+			errMsg: `no sheet "Nope"`,
+		},
+		{
+			name:   "shared string out of range",
+			data:   `<row r="1"><c r="A1" t="s"><v>3</v></c></row>`,
+			extra:  shared,
+			errMsg: "invalid shared string",
+		},
+		{
+			name:   "column beyond XFD",
+			data:   `<row r="1"><c r="ZZZZZZZ1"><v>1</v></c></row>`,
+			errMsg: "beyond the last column",
+		},
+		{
+			name:   "missing worksheet",
+			extra:  map[string]string{"xl/worksheets/sheet1.xml": ""},
+			errMsg: "worksheet \"xl/worksheets/sheet1.xml\" is missing",
+		},
+		{
+			name:   "no sheets",
+			extra:  map[string]string{"xl/workbook.xml": "<workbook/>"},
+			errMsg: "no sheets",
+		},
+		{
+			name:   "malformed worksheet",
+			extra:  map[string]string{"xl/worksheets/sheet1.xml": "<worksheet><sheetData><row>"},
+			errMsg: "invalid worksheet",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readXLSX(testXLSX(t, tt.data, tt.extra), tt.sheet)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("error = %v, want %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadXLSXMalformedZip(t *testing.T) {
+	if _, err := readXLSX([]byte("id,name\n1,a\n"), ""); err == nil || !strings.Contains(err.Error(), "not an xlsx file") {
+		t.Errorf("csv as xlsx: %v", err)
+	}
+
+	// A part that unpacks past the limit is refused: a zip bomb of spaces
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range map[string]string{"xl/workbook.xml": testXLSXWorkbook, "xl/_rels/workbook.xml.rels": testXLSXRels} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(body))
+	}
+	w, _ := zw.Create("xl/worksheets/sheet1.xml")
+	w.Write([]byte("<worksheet>"))
+	spaces := bytes.Repeat([]byte(" "), 1<<20)
+	for i := 0; i <= maxXLSXPart>>20; i++ {
+		w.Write(spaces)
+	}
+	zw.Close()
+	if buf.Len() > 1<<20 {
+		t.Fatalf("bomb is %d bytes compressed", buf.Len())
+	}
+	if _, err := readXLSX(buf.Bytes(), ""); err == nil || !strings.Contains(err.Error(), "unpacks to more than") {
+		t.Errorf("oversized part: %v", err)
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AZ1": 51, "XFD1048576": 16383} {
+		if got := xlsxColumn(ref); got != want {
+			t.Errorf("xlsxColumn(%q) = %d, want %d", ref, got, want)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+// Package demo generates realistic synthetic data so GAGOS can be evaluated
+// (and the UI developed) without a cluster, metrics-server or credentials.
+package demo
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/gaga951/gagos/internal/cicd"
+	"github.com/gaga951/gagos/internal/k8s"
+	"github.com/gaga951/gagos/internal/monitoring"
+)
+
+// Enabled reports whether demo mode is switched on (GAGOS_DEMO_MODE=true)
+func Enabled() bool {
+	return os.Getenv("GAGOS_DEMO_MODE") == "true"
+}
+
+// startedAt anchors all generated timestamps so ages look consistent between calls
+var startedAt = time.Now().Add(-37 * 24 * time.Hour)
+
+type demoNode struct {
+	name   string
+	role   string
+	cpu    int64 // millicores
+	memory int64 // bytes
+}
+
+type demoApp struct {
+	namespace string
+	name      string
+	image     string
+	replicas  int32
+	port      int32
+	cpu       int64 // millicores per replica
+	memory    int64 // bytes per replica
+}
+
+var nodes = []demoNode{
+	{"cp-1", "control-plane", 4000, 8 << 30},
+	{"worker-1", "worker", 8000, 32 << 30},
+	{"worker-2", "worker", 8000, 32 << 30},
+	{"worker-3", "worker", 8000, 32 << 30},
+	{"worker-4", "worker", 16000, 64 << 30},
+}
+
+var apps = []demoApp{
+	{"kube-system", "coredns", "registry.k8s.io/coredns/coredns:v1.11.1", 2, 53, 12, 24 << 20},
+	{"kube-system", "metrics-server", "registry.k8s.io/metrics-server/metrics-server:v0.7.0", 1, 443, 8, 32 << 20},
+	{"monitoring", "prometheus", "prom/prometheus:v2.49.1", 1, 9090, 450, 2 << 30},
+	{"monitoring", "grafana", "grafana/grafana:10.3.1", 1, 3000, 35, 180 << 20},
+	{"web", "frontend", "nginx:1.25-alpine", 3, 80, 20, 48 << 20},
+	{"web", "api-gateway", "envoyproxy/envoy:v1.29.0", 2, 8080, 90, 120 << 20},
+	{"payments", "payments-api", "ghcr.io/acme/payments-api:2.14.3", 3, 8080, 210, 380 << 20},
+	{"payments", "ledger-worker", "ghcr.io/acme/ledger-worker:2.14.3", 2, 9000, 140, 256 << 20},
+	{"data", "postgres", "postgres:16", 1, 5432, 320, 1536 << 20},
+	{"data", "redis", "redis:7.2", 1, 6379, 45, 210 << 20},
+	{"default", "hello-world", "nginxdemos/hello:latest", 1, 80, 2, 12 << 20},
+}
+
+var namespaces = []string{"default", "kube-system", "monitoring", "web", "payments", "data"}
+
+// hash returns a stable pseudo-random value for a string key
+func hash(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// suffix builds a kubernetes-looking random suffix that never changes for a key
+func suffix(key string, n int) string {
+	const alphabet = "bcdfghjklmnpqrstvwxz2456789"
+	v := hash(key)
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = alphabet[v%uint32(len(alphabet))]
+		v = v*1103515245 + 12345
+	}
+	return string(out)
+}
+
+// wave returns a slowly varying factor in [1-amp, 1+amp] so metrics look alive
+func wave(key string, amp float64) float64 {
+	phase := float64(hash(key)%360) * math.Pi / 180
+	t := float64(time.Now().Unix()) / 90
+	return 1 + amp*math.Sin(t+phase)
+}
+
+func ageOf(t time.Time) string {
+	d := time.Since(t)
+	if d.Hours() >= 24 {
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	if d.Hours() >= 1 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
+
+func createdAt(key string) time.Time {
+	return startedAt.Add(time.Duration(hash(key)%(30*24)) * time.Hour)
+}
+
+type demoPod struct {
+	app  demoApp
+	name string
+	node string
+}
+
+func pods() []demoPod {
+	var result []demoPod
+	for _, a := range apps {
+		rs := suffix(a.namespace+a.name, 10)
+		for i := int32(0); i < a.replicas; i++ {
+			name := fmt.Sprintf("%s-%s-%s", a.name, rs, suffix(fmt.Sprintf("%s%d", a.name, i), 5))
+			node := nodes[1+int(hash(name))%(len(nodes)-1)].name
+			if a.namespace == "kube-system" {
+				node = nodes[int(hash(name))%len(nodes)].name
+			}
+			result = append(result, demoPod{app: a, name: name, node: node})
+		}
+	}
+	return result
+}
+
+func inNamespace(filter, namespace string) bool {
+	return filter == "" || filter == namespace
+}
+
+// Namespaces returns the synthetic namespaces
+func Namespaces() []k8s.NamespaceInfo {
+	var result []k8s.NamespaceInfo
+	for _, ns := range namespaces {
+		created := createdAt("ns/" + ns)
+		result = append(result, k8s.NamespaceInfo{
+			Name:      ns,
+			Status:    "Active",
+			Labels:    map[string]string{"kubernetes.io/metadata.name": ns},
+			CreatedAt: created.Format(time.RFC3339),
+			Age:       ageOf(created),
+		})
+	}
+	return result
+}
+
+// Nodes returns the synthetic nodes
+func Nodes() []k8s.NodeInfo {
+	var result []k8s.NodeInfo
+	for i, n := range nodes {
+		created := startedAt.Add(time.Duration(i) * time.Hour)
+		result = append(result, k8s.NodeInfo{
+			Name:             n.name,
+			Status:           "Ready",
+			Roles:            []string{n.role},
+			InternalIP:       fmt.Sprintf("10.0.0.%d", 10+i),
+			OS:               "Ubuntu 22.04.4 LTS",
+			KernelVersion:    "5.15.0-94-generic",
+			ContainerRuntime: "containerd://1.7.13",
+			KubeletVersion:   "v1.29.2",
+			Labels:           map[string]string{"kubernetes.io/hostname": n.name},
+			CreatedAt:        created.Format(time.RFC3339),
+			Age:              ageOf(created),
+		})
+	}
+	return result
+}
+
+// Pods returns synthetic pods, optionally filtered by namespace
+func Pods(namespace string) []k8s.PodInfo {
+	var result []k8s.PodInfo
+	for i, p := range pods() {
+		if !inNamespace(namespace, p.app.namespace) {
+			continue
+		}
+		created := createdAt("pod/" + p.name)
+		restarts := int32(hash(p.name) % 4)
+		if restarts < 3 {
+			restarts = 0
+		}
+		result = append(result, k8s.PodInfo{
+			Name:      p.name,
+			Namespace: p.app.namespace,
+			Status:    "Running",
+			Ready:     "1/1",
+			Restarts:  restarts,
+			Node:      p.node,
+			IP:        fmt.Sprintf("10.244.%d.%d", 1+int(hash(p.node))%4, 10+i),
+			Labels:    map[string]string{"app": p.app.name},
+			CreatedAt: created.Format(time.RFC3339),
+			Age:       ageOf(created),
+			Containers: []k8s.ContainerInfo{{
+				Name:         p.app.name,
+				Image:        p.app.image,
+				Ready:        true,
+				RestartCount: restarts,
+				State:        "running",
+			}},
+		})
+	}
+	return result
+}
+
+// Deployments returns synthetic deployments, optionally filtered by namespace
+func Deployments(namespace string) []k8s.DeploymentInfo {
+	var result []k8s.DeploymentInfo
+	for _, a := range apps {
+		if !inNamespace(namespace, a.namespace) {
+			continue
+		}
+		created := createdAt("deploy/" + a.name)
+		result = append(result, k8s.DeploymentInfo{
+			Name:      a.name,
+			Namespace: a.namespace,
+			Ready:     fmt.Sprintf("%d/%d", a.replicas, a.replicas),
+			UpToDate:  a.replicas,
+			Available: a.replicas,
+			Labels:    map[string]string{"app": a.name},
+			CreatedAt: created.Format(time.RFC3339),
+			Age:       ageOf(created),
+		})
+	}
+	return result
+}
+
+// Services returns one synthetic ClusterIP service per app
+func Services(namespace string) []k8s.ServiceInfo {
+	var result []k8s.ServiceInfo
+	for i, a := range apps {
+		if !inNamespace(namespace, a.namespace) {
+			continue
+		}
+		created := createdAt("svc/" + a.name)
+		result = append(result, k8s.ServiceInfo{
+			Name:      a.name,
+			Namespace: a.namespace,
+			Type:      "ClusterIP",
+			ClusterIP: fmt.Sprintf("10.96.%d.%d", 1+i/200, 10+i),
+			Ports: []k8s.ServicePort{{
+				Port:       a.port,
+				TargetPort: fmt.Sprintf("%d", a.port),
+				Protocol:   "TCP",
+			}},
+			Selector:  map[string]string{"app": a.name},
+			CreatedAt: created.Format(time.RFC3339),
+			Age:       ageOf(created),
+		})
+	}
+	return result
+}
+
+// NodeMetrics returns synthetic node usage that drifts slowly over time
+func NodeMetrics() []monitoring.NodeMetrics {
+	usage := make(map[string][2]int64)
+	count := make(map[string]int)
+	for _, pm := range PodMetrics("") {
+		u := usage[pm.Node]
+		usage[pm.Node] = [2]int64{u[0] + pm.CPUUsage, u[1] + pm.MemoryUsage}
+		count[pm.Node]++
+	}
+
+	var result []monitoring.NodeMetrics
+	for _, n := range nodes {
+		// System daemons account for a baseline on every node
+		cpu := usage[n.name][0] + int64(180*wave("sys"+n.name, 0.2))
+		mem := usage[n.name][1] + int64(float64(900<<20)*wave("sysm"+n.name, 0.05))
+		result = append(result, monitoring.NodeMetrics{
+			Name:           n.name,
+			CPUUsage:       cpu,
+			CPUCapacity:    n.cpu,
+			CPUPercent:     float64(cpu) / float64(n.cpu) * 100,
+			MemoryUsage:    mem,
+			MemoryCapacity: n.memory,
+			MemoryPercent:  float64(mem) / float64(n.memory) * 100,
+			PodCount:       count[n.name],
+			PodCapacity:    110,
+			Conditions:     []string{"Ready"},
+			Roles:          []string{n.role},
+		})
+	}
+	return result
+}
+
+// PodMetrics returns synthetic per-pod usage, optionally filtered by namespace
+func PodMetrics(namespace string) []monitoring.PodMetrics {
+	var result []monitoring.PodMetrics
+	for _, p := range pods() {
+		if !inNamespace(namespace, p.app.namespace) {
+			continue
+		}
+		cpu := int64(float64(p.app.cpu) * wave(p.name, 0.35))
+		mem := int64(float64(p.app.memory) * wave(p.name+"m", 0.08))
+		result = append(result, monitoring.PodMetrics{
+			Name:        p.name,
+			Namespace:   p.app.namespace,
+			CPUUsage:    cpu,
+			MemoryUsage: mem,
+			Containers:  []monitoring.ContainerMetrics{{Name: p.app.name, CPUUsage: cpu, MemoryUsage: mem}},
+			Node:        p.node,
+			Status:      "Running",
+		})
+	}
+	return result
+}
+
+// ClusterSummary aggregates the synthetic node metrics
+func ClusterSummary() *monitoring.ClusterSummary {
+	summary := &monitoring.ClusterSummary{Timestamp: time.Now()}
+	for _, n := range NodeMetrics() {
+		summary.TotalNodes++
+		summary.ReadyNodes++
+		summary.TotalCPUMillicores += n.CPUCapacity
+		summary.UsedCPUMillicores += n.CPUUsage
+		summary.TotalMemoryBytes += n.MemoryCapacity
+		summary.UsedMemoryBytes += n.MemoryUsage
+		summary.TotalPods += n.PodCount
+		summary.RunningPods += n.PodCount
+	}
+	if summary.TotalCPUMillicores > 0 {
+		summary.CPUPercent = float64(summary.UsedCPUMillicores) / float64(summary.TotalCPUMillicores) * 100
+	}
+	if summary.TotalMemoryBytes > 0 {
+		summary.MemoryPercent = float64(summary.UsedMemoryBytes) / float64(summary.TotalMemoryBytes) * 100
+	}
+	return summary
+}
+
+var pipelineDefs = []struct {
+	name string
+	jobs []string
+}{
+	{"payments-api", []string{"lint", "test", "build", "deploy-staging"}},
+	{"frontend", []string{"install", "test", "build"}},
+	{"nightly-backup", []string{"dump", "upload"}},
+}
+
+// Pipelines returns synthetic CI/CD pipelines
+func Pipelines() []*cicd.Pipeline {
+	var result []*cicd.Pipeline
+	for i, def := range pipelineDefs {
+		created := createdAt("pipeline/" + def.name)
+		p := &cicd.Pipeline{
+			ID:          fmt.Sprintf("pipe-demo%04d", i+1),
+			Name:        def.name,
+			Description: "Demo pipeline for " + def.name,
+			CreatedAt:   created,
+			UpdatedAt:   created,
+		}
+		for _, j := range def.jobs {
+			p.Spec.Jobs = append(p.Spec.Jobs, cicd.JobSpec{Name: j, Image: "alpine:3.19", Script: "echo " + j})
+		}
+		runs := Runs(p.ID)
+		p.Status.TotalRuns = len(runs)
+		if len(runs) > 0 {
+			p.Status.LastRunID = runs[0].ID
+			p.Status.LastRunAt = runs[0].StartedAt
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// Runs returns synthetic runs (newest first), optionally filtered by pipeline ID
+func Runs(pipelineID string) []*cicd.PipelineRun {
+	var result []*cicd.PipelineRun
+	now := time.Now().Truncate(time.Hour)
+	for i, def := range pipelineDefs {
+		id := fmt.Sprintf("pipe-demo%04d", i+1)
+		if pipelineID != "" && pipelineID != id {
+			continue
+		}
+		for n := 1; n <= 12; n++ {
+			key := fmt.Sprintf("%s#%d", def.name, n)
+			started := now.Add(-time.Duration(12-n)*6*time.Hour - time.Duration(hash(key)%300)*time.Second)
+			status := cicd.RunStatusSucceeded
+			if hash(key)%7 == 0 {
+				status = cicd.RunStatusFailed
+			}
+
+			run := &cicd.PipelineRun{
+				ID:           fmt.Sprintf("run-%s", suffix(key, 12)),
+				PipelineID:   id,
+				PipelineName: def.name,
+				RunNumber:    n,
+				Status:       status,
+				TriggerType:  []string{"manual", "webhook", "cron"}[hash(key)%3],
+				CreatedAt:    started,
+			}
+
+			cursor := started
+			for j, jobName := range def.jobs {
+				jobStatus := cicd.RunStatusSucceeded
+				if status == cicd.RunStatusFailed && j == len(def.jobs)-1 {
+					jobStatus = cicd.RunStatusFailed
+				}
+				d := time.Duration(20+hash(key+jobName)%160) * time.Second
+				jobStart, jobEnd := cursor, cursor.Add(d)
+				run.Jobs = append(run.Jobs, cicd.JobRun{
+					Name:       jobName,
+					Status:     jobStatus,
+					StartedAt:  &jobStart,
+					FinishedAt: &jobEnd,
+					Duration:   d.Milliseconds(),
+				})
+				cursor = jobEnd
+			}
+			finished := cursor
+			run.StartedAt = &started
+			run.FinishedAt = &finished
+			run.Duration = finished.Sub(started).Milliseconds()
+			if status == cicd.RunStatusFailed {
+				run.Error = "job " + def.jobs[len(def.jobs)-1] + " failed with exit code 1"
+			}
+			result = append(result, run)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result
+}
+
+// Stats summarises the synthetic CI/CD history
+func Stats() *cicd.CICDStats {
+	stats := &cicd.CICDStats{TotalPipelines: len(pipelineDefs)}
+	dayAgo := time.Now().Add(-24 * time.Hour)
+	for _, r := range Runs("") {
+		stats.TotalRuns++
+		if r.CreatedAt.Before(dayAgo) {
+			continue
+		}
+		switch r.Status {
+		case cicd.RunStatusSucceeded:
+			stats.Succeeded24h++
+		case cicd.RunStatusFailed:
+			stats.Failed24h++
+		}
+	}
+	return stats
+}
@@ -0,0 +1,264 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+// Package egress decides which destinations the network, database and webhook
+// tools may connect to. Checks run on the resolved IP inside the dialer, so a
+// hostname that resolves (or re-resolves) to a blocked address is caught too.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrBlocked is wrapped by every error caused by the egress policy
+var ErrBlocked = errors.New("destination blocked by egress policy")
+
+// ErrOffline is wrapped by the errors of features that need the internet
+// when GAGOS_OFFLINE is set
+var ErrOffline = errors.New("not available in offline mode")
+
+// defaultDeny covers link-local ranges, which is where cloud metadata
+// services live (169.254.169.254, fd00:ec2::254). Opt out with
+// GAGOS_EGRESS_ALLOW_LINK_LOCAL=true.
+var defaultDeny = []string{
+	"169.254.0.0/16",
+	"fe80::/10",
+	"fd00:ec2::254/128",
+}
+
+// internalRanges are what an offline install may still reach: private,
+// carrier-grade NAT and loopback addresses, which covers the pod and service
+// networks of a cluster
+var internalRanges = []string{
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"::1/128",
+	"fc00::/7",
+}
+
+// Offline reports whether GAGOS_OFFLINE is set, for air-gapped installs.
+// The default policy then only allows internal ranges and
+// GAGOS_EGRESS_ALLOW_CIDRS, and features that need public servers, such as
+// whois, are disabled unless pointed at an internal mirror.
+func Offline() bool {
+	return os.Getenv("GAGOS_OFFLINE") == "true"
+}
+
+// Policy is a set of CIDR and port rules. Deny rules win over allow rules;
+// an empty allow list allows everything not denied.
+type Policy struct {
+	AllowCIDRs []*net.IPNet
+	DenyCIDRs  []*net.IPNet
+	AllowPorts map[int]bool
+	DenyPorts  map[int]bool
+}
+
+var (
+	defaultPolicy *Policy
+	defaultOnce   sync.Once
+)
+
+// Default returns the process-wide policy built from the environment:
+// GAGOS_EGRESS_ALLOW_CIDRS, GAGOS_EGRESS_DENY_CIDRS, GAGOS_EGRESS_ALLOW_PORTS,
+// GAGOS_EGRESS_DENY_PORTS (comma separated), GAGOS_EGRESS_ALLOW_LINK_LOCAL
+// and GAGOS_OFFLINE.
+func Default() *Policy {
+	defaultOnce.Do(func() {
+		p, err := FromEnv()
+		if err != nil {
+			// Fail closed on the link-local defaults rather than refusing to start
+			log.Error().Err(err).Msg("Invalid egress policy, using defaults")
+			p = &Policy{DenyCIDRs: mustParseCIDRs(defaultDeny)}
+			if Offline() {
+				p.AllowCIDRs = mustParseCIDRs(internalRanges)
+			}
+		}
+		defaultPolicy = p
+	})
+	return defaultPolicy
+}
+
+// FromEnv parses the policy from GAGOS_EGRESS_* environment variables
+func FromEnv() (*Policy, error) {
+	p := &Policy{}
+	var err error
+
+	if p.AllowCIDRs, err = ParseCIDRs(os.Getenv("GAGOS_EGRESS_ALLOW_CIDRS")); err != nil {
+		return nil, fmt.Errorf("GAGOS_EGRESS_ALLOW_CIDRS: %w", err)
+	}
+	if Offline() {
+		p.AllowCIDRs = append(p.AllowCIDRs, mustParseCIDRs(internalRanges)...)
+	}
+	if p.DenyCIDRs, err = ParseCIDRs(os.Getenv("GAGOS_EGRESS_DENY_CIDRS")); err != nil {
+		return nil, fmt.Errorf("GAGOS_EGRESS_DENY_CIDRS: %w", err)
+	}
+	if os.Getenv("GAGOS_EGRESS_ALLOW_LINK_LOCAL") != "true" {
+		p.DenyCIDRs = append(p.DenyCIDRs, mustParseCIDRs(defaultDeny)...)
+	}
+	if p.AllowPorts, err = parsePorts(os.Getenv("GAGOS_EGRESS_ALLOW_PORTS")); err != nil {
+		return nil, fmt.Errorf("GAGOS_EGRESS_ALLOW_PORTS: %w", err)
+	}
+	if p.DenyPorts, err = parsePorts(os.Getenv("GAGOS_EGRESS_DENY_PORTS")); err != nil {
+		return nil, fmt.Errorf("GAGOS_EGRESS_DENY_PORTS: %w", err)
+	}
+	return p, nil
+}
+
+// ParseCIDRs accepts comma separated CIDRs or bare IPs
+func ParseCIDRs(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			if ip := net.ParseIP(part); ip != nil && ip.To4() != nil {
+				part += "/32"
+			} else {
+				part += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func mustParseCIDRs(cidrs []string) []*net.IPNet {
+	nets, err := ParseCIDRs(strings.Join(cidrs, ","))
+	if err != nil {
+		panic(err)
+	}
+	return nets
+}
+
+func parsePorts(s string) (map[int]bool, error) {
+	var ports map[int]bool
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		port, err := strconv.Atoi(part)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", part)
+		}
+		if ports == nil {
+			ports = make(map[int]bool)
+		}
+		ports[port] = true
+	}
+	return ports, nil
+}
+
+// Check returns an ErrBlocked error when ip:port is not allowed. A port of 0
+// skips the port rules (ICMP, traceroute).
+func (p *Policy) Check(ip net.IP, port int) error {
+	for _, n := range p.DenyCIDRs {
+		if n.Contains(ip) {
+			return fmt.Errorf("%w: %s is in denied range %s", ErrBlocked, ip, n)
+		}
+	}
+	if len(p.AllowCIDRs) > 0 {
+		allowed := false
+		for _, n := range p.AllowCIDRs {
+			if n.Contains(ip) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %s is not in an allowed range", ErrBlocked, ip)
+		}
+	}
+	if port > 0 {
+		if p.DenyPorts[port] {
+			return fmt.Errorf("%w: port %d is denied", ErrBlocked, port)
+		}
+		if len(p.AllowPorts) > 0 && !p.AllowPorts[port] {
+			return fmt.Errorf("%w: port %d is not allowed", ErrBlocked, port)
+		}
+	}
+	return nil
+}
+
+// CheckHost resolves host and checks every address it resolves to. Use it for
+// tools that connect outside a Go dialer, such as pg_dump or mysqldump.
+func (p *Policy) CheckHost(ctx context.Context, host string, port int) error {
+	if ip := net.ParseIP(host); ip != nil {
+		return p.Check(ip, port)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if err := p.Check(addr.IP, port); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// control is a net.Dialer Control hook that runs after name resolution, right
+// before each connect
+func (p *Policy) control(network, address string, _ syscall.RawConn) error {
+	if strings.HasPrefix(network, "unix") {
+		return nil
+	}
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: unresolved address %q", ErrBlocked, host)
+	}
+	port, _ := strconv.Atoi(portStr)
+	return p.Check(ip, port)
+}
+
+// Dialer returns a net.Dialer that enforces the policy
+func (p *Policy) Dialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   p.control,
+	}
+}
+
+// DialContext dials through the policy with no timeout beyond ctx
+func (p *Policy) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return p.Dialer(0).DialContext(ctx, network, address)
+}
+
+// Transport points base (or a clone of http.DefaultTransport) at the policy
+// dialer. Proxies from the environment are dropped so they cannot be used to
+// bypass the policy.
+func (p *Policy) Transport(base *http.Transport) *http.Transport {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	base.Proxy = nil
+	base.DialContext = p.DialContext
+	return base
+}
@@ -0,0 +1,184 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package egress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	nets, err := ParseCIDRs(" 10.0.0.0/8, 192.168.1.5,,::1 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, n := range nets {
+		got = append(got, n.String())
+	}
+	if want := []string{"10.0.0.0/8", "192.168.1.5/32", "::1/128"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCIDRs = %v, want %v", got, want)
+	}
+	if nets, err := ParseCIDRs(""); nets != nil || err != nil {
+		t.Errorf("empty list = %v, %v", nets, err)
+	}
+	for _, bad := range []string{"example.com", "10.0.0.0/33", "10.0.0.0/8,nope"} {
+		if _, err := ParseCIDRs(bad); err == nil {
+			t.Errorf("ParseCIDRs(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	for _, key := range []string{"GAGOS_EGRESS_ALLOW_CIDRS", "GAGOS_EGRESS_DENY_CIDRS", "GAGOS_EGRESS_ALLOW_PORTS",
+		"GAGOS_EGRESS_DENY_PORTS", "GAGOS_EGRESS_ALLOW_LINK_LOCAL", "GAGOS_OFFLINE"} {
+		t.Setenv(key, "")
+	}
+	metadata := net.ParseIP("169.254.169.254")
+
+	p, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Check(metadata, 80); !errors.Is(err, ErrBlocked) {
+		t.Errorf("metadata service allowed by default: %v", err)
+	}
+	if err := p.Check(net.ParseIP("93.184.216.34"), 443); err != nil {
+		t.Errorf("public address blocked by default: %v", err)
+	}
+
+	t.Setenv("GAGOS_EGRESS_ALLOW_LINK_LOCAL", "true")
+	t.Setenv("GAGOS_EGRESS_DENY_PORTS", "25, 465")
+	if p, err = FromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Check(metadata, 80); err != nil {
+		t.Errorf("link-local blocked after opting out: %v", err)
+	}
+	if err := p.Check(metadata, 465); !errors.Is(err, ErrBlocked) {
+		t.Errorf("denied port allowed: %v", err)
+	}
+
+	for key, value := range map[string]string{
+		"GAGOS_EGRESS_ALLOW_CIDRS": "10.0.0.0/40",
+		"GAGOS_EGRESS_DENY_CIDRS":  "nope",
+		"GAGOS_EGRESS_ALLOW_PORTS": "0",
+		"GAGOS_EGRESS_DENY_PORTS":  "http",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := FromEnv(); err == nil {
+				t.Errorf("%s=%q accepted", key, value)
+			}
+		})
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	p := &Policy{
+		AllowCIDRs: mustParseCIDRs([]string{"10.0.0.0/8", "fc00::/7"}),
+		DenyCIDRs:  mustParseCIDRs([]string{"10.1.0.0/16"}),
+		AllowPorts: map[int]bool{443: true, 5432: true},
+		DenyPorts:  map[int]bool{5432: true},
+	}
+	tests := []struct {
+		ip      string
+		port    int
+		allowed bool
+	}{
+		{"10.2.3.4", 443, true},
+		{"fd00::1", 443, true},
+		{"10.1.2.3", 443, false},    // deny range wins over the allow range
+		{"192.168.1.1", 443, false}, // outside the allow list
+		{"10.2.3.4", 80, false},     // port not in the allow list
+		{"10.2.3.4", 5432, false},   // denied port wins over the allowed one
+		{"10.2.3.4", 0, true},       // no port, no port rules
+		{"10.1.2.3", 0, false},
+	}
+	for _, tt := range tests {
+		err := p.Check(net.ParseIP(tt.ip), tt.port)
+		if tt.allowed && err != nil {
+			t.Errorf("Check(%s, %d) = %v, want allowed", tt.ip, tt.port, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrBlocked) {
+			t.Errorf("Check(%s, %d) = %v, want blocked", tt.ip, tt.port, err)
+		}
+	}
+
+	// An empty policy allows everything
+	if err := (&Policy{}).Check(net.ParseIP("8.8.8.8"), 53); err != nil {
+		t.Errorf("empty policy: %v", err)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	p := &Policy{DenyCIDRs: mustParseCIDRs([]string{"127.0.0.0/8", "::1/128"})}
+	ctx := context.Background()
+	if err := p.CheckHost(ctx, "127.0.0.1", 5432); !errors.Is(err, ErrBlocked) {
+		t.Errorf("IP literal: %v", err)
+	}
+	if err := p.CheckHost(ctx, "localhost", 5432); !errors.Is(err, ErrBlocked) {
+		t.Errorf("name resolving to loopback: %v", err)
+	}
+	if err := p.CheckHost(ctx, "192.0.2.1", 5432); err != nil {
+		t.Errorf("allowed IP literal: %v", err)
+	}
+}
+
+func TestDialer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	open := &Policy{}
+	conn, err := open.Dialer(time.Second).Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("allowed dial: %v", err)
+	}
+	conn.Close()
+
+	// The check runs on the resolved address, so a name is no way around it
+	closed := &Policy{DenyCIDRs: mustParseCIDRs([]string{"127.0.0.0/8", "::1/128"})}
+	for _, addr := range []string{ln.Addr().String(), net.JoinHostPort("localhost", port)} {
+		if _, err := closed.Dialer(time.Second).Dial("tcp", addr); !errors.Is(err, ErrBlocked) {
+			t.Errorf("dial %s = %v, want blocked", addr, err)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: (&Policy{}).Transport(nil), Timeout: 5 * time.Second}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("allowed request: %v", err)
+	}
+	resp.Body.Close()
+
+	deny := &Policy{DenyCIDRs: mustParseCIDRs([]string{"127.0.0.0/8"})}
+	client = &http.Client{Transport: deny.Transport(nil), Timeout: 5 * time.Second}
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrBlocked) {
+		t.Errorf("request = %v, want blocked", err)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package egress
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Outbound HTTP can go through a proxy, for installs behind a corporate
+// egress proxy. GAGOS_PROXY sets one for everything, GAGOS_PROXY_<SUBSYSTEM>
+// overrides it for a subsystem and GAGOS_NO_PROXY lists the destinations
+// that skip it, with the syntax of NO_PROXY. The HTTP_PROXY variables of the
+// environment are still ignored, so that the proxy is a deliberate choice.
+//
+// The proxy itself must pass the policy like any destination. Destinations
+// reached through it are checked against the deny ranges and port rules
+// only: the allow list is for direct connections, and a name the proxy
+// resolves may not resolve here at all.
+
+// Subsystems with their own proxy setting
+const (
+	ProxyCurl          = "curl"          // the curl network tool
+	ProxyGit           = "git"           // Git clones
+	ProxyRegistry      = "registry"      // image scans: trivy and the scanner webhook
+	ProxyNotifications = "notifications" // notification webhooks
+)
+
+// ProxySubsystems lists the subsystems with a proxy setting
+var ProxySubsystems = []string{ProxyCurl, ProxyGit, ProxyRegistry, ProxyNotifications}
+
+// ProxyDirect as a proxy setting turns the proxy off
+const ProxyDirect = "direct"
+
+// Proxy is the proxy of a subsystem. A nil Proxy connects directly.
+type Proxy struct {
+	url    *url.URL
+	choose func(*url.URL) (*url.URL, error)
+}
+
+// ProxyFor returns the proxy of subsystem: override when set, else
+// GAGOS_PROXY_<SUBSYSTEM>, else GAGOS_PROXY. A setting of "direct", or none
+// at all, gives a nil Proxy.
+func ProxyFor(subsystem, override string) (*Proxy, error) {
+	setting := strings.TrimSpace(override)
+	if setting == "" {
+		setting = strings.TrimSpace(os.Getenv("GAGOS_PROXY_" + strings.ToUpper(subsystem)))
+	}
+	if setting == "" {
+		setting = strings.TrimSpace(os.Getenv("GAGOS_PROXY"))
+	}
+	if setting == "" || setting == ProxyDirect {
+		return nil, nil
+	}
+
+	u, err := ParseProxyURL(setting)
+	if err != nil {
+		return nil, err
+	}
+	cfg := httpproxy.Config{
+		HTTPProxy:  u.String(),
+		HTTPSProxy: u.String(),
+		NoProxy:    os.Getenv("GAGOS_NO_PROXY"),
+	}
+	return &Proxy{url: u, choose: cfg.ProxyFunc()}, nil
+}
+
+// ProxySettings returns the proxy of each subsystem without passwords, for
+// display
+func ProxySettings() map[string]string {
+	settings := make(map[string]string, len(ProxySubsystems))
+	for _, subsystem := range ProxySubsystems {
+		proxy, err := ProxyFor(subsystem, "")
+		if err != nil {
+			settings[subsystem] = err.Error()
+			continue
+		}
+		settings[subsystem] = proxy.String()
+	}
+	return settings
+}
+
+// ParseProxyURL checks a proxy setting: an http, https, socks5 or socks5h
+// URL with a host
+func ParseProxyURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: expected a URL such as http://proxy:3128", redactProxy(s))
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy %q: scheme must be http, https, socks5 or socks5h", redactProxy(s))
+	}
+	return u, nil
+}
+
+// redactProxy keeps proxy credentials out of error messages
+func redactProxy(s string) string {
+	if u, err := url.Parse(s); err == nil {
+		return u.Redacted()
+	}
+	return "<unparsable>"
+}
+
+// URL returns the proxy URL, nil for direct connections
+func (x *Proxy) URL() *url.URL {
+	if x == nil {
+		return nil
+	}
+	return x.url
+}
+
+// For returns the proxy to use for target, nil when target is reached
+// directly because there is no proxy or GAGOS_NO_PROXY matches it
+func (x *Proxy) For(target *url.URL) *url.URL {
+	if x == nil {
+		return nil
+	}
+	u, err := x.choose(target)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+// String is the proxy URL without its password, or "direct"
+func (x *Proxy) String() string {
+	if x == nil {
+		return ProxyDirect
+	}
+	return x.url.Redacted()
+}
+
+// TransportVia is Transport through proxy; a nil proxy is the same as
+// Transport.
+func (p *Policy) TransportVia(base *http.Transport, proxy *Proxy) *http.Transport {
+	base = p.Transport(base)
+	if proxy == nil {
+		return base
+	}
+	base.Proxy = func(req *http.Request) (*url.URL, error) {
+		via := proxy.For(req.URL)
+		if via == nil {
+			return nil, nil
+		}
+		if err := p.checkProxied(req.Context(), req.URL.Hostname(), urlPort(req.URL)); err != nil {
+			return nil, err
+		}
+		return via, nil
+	}
+	return base
+}
+
+// CheckVia is CheckHost for a tool that connects outside Go, such as git,
+// with proxy: the proxy is checked like a direct destination and target
+// like a proxied one.
+func (p *Policy) CheckVia(ctx context.Context, proxy *Proxy, target *url.URL) error {
+	via := proxy.For(target)
+	if via == nil {
+		return p.CheckHost(ctx, target.Hostname(), urlPort(target))
+	}
+	if err := p.CheckHost(ctx, via.Hostname(), urlPort(via)); err != nil {
+		return fmt.Errorf("proxy %s: %w", via.Redacted(), err)
+	}
+	return p.checkProxied(ctx, target.Hostname(), urlPort(target))
+}
+
+// checkProxied checks a destination sent through a proxy against the deny
+// ranges, when it resolves here, and the port rules
+func (p *Policy) checkProxied(ctx context.Context, host string, port int) error {
+	deny := &Policy{DenyCIDRs: p.DenyCIDRs, AllowPorts: p.AllowPorts, DenyPorts: p.DenyPorts}
+	if ip := net.ParseIP(host); ip != nil {
+		return deny.Check(ip, port)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		// The proxy resolves the name; only the port can be checked
+		return deny.Check(nil, port)
+	}
+	for _, addr := range addrs {
+		if err := deny.Check(addr.IP, port); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// urlPort is the port of u, or the default port of its scheme
+func urlPort(u *url.URL) int {
+	if p := u.Port(); p != "" {
+		port, _ := strconv.Atoi(p)
+		return port
+	}
+	switch u.Scheme {
+	case "http":
+		return 80
+	case "socks5", "socks5h":
+		return 1080
+	}
+	return 443
+}
+
+// ProxyEnv returns the environment that makes a child process such as
+// trivy use proxy, nil for a nil proxy
+func ProxyEnv(proxy *Proxy) []string {
+	if proxy == nil {
+		return nil
+	}
+	u := proxy.url.String()
+	noProxy := os.Getenv("GAGOS_NO_PROXY")
+	return []string{
+		"HTTP_PROXY=" + u, "HTTPS_PROXY=" + u, "NO_PROXY=" + noProxy,
+		"http_proxy=" + u, "https_proxy=" + u, "no_proxy=" + noProxy,
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+// Package fakes runs GAGOS against in-process backends: a client-go fake
+// clientset instead of a cluster, an in-memory SSH executor instead of real
+// hosts, and a BBolt database in a temporary directory. It backs the fake
+// mode of the server (built with -tags fake) and lets tests drive the HTTP
+// handlers, pipelines and freestyle builds without any infrastructure.
+//
+// Storage and the Kubernetes and SSH clients are process-wide, so only one
+// Harness may run at a time.
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gaga951/gagos/internal/cicd"
+	"github.com/gaga951/gagos/internal/k8s"
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+// Options configure a Harness
+type Options struct {
+	// DataDir holds the database; a temporary directory that Stop removes
+	// when empty
+	DataDir string
+	// Seed adds the fixture cluster objects and the fixture SSH host,
+	// pipeline and freestyle job
+	Seed bool
+	// Objects are added to the fake cluster, after the fixtures
+	Objects []runtime.Object
+}
+
+// Harness is a running set of fake backends
+type Harness struct {
+	Kubernetes *fake.Clientset
+	SSH        *SSH
+	DataDir    string
+
+	mu       sync.Mutex
+	jobFails func(job *batchv1.Job) bool
+	tempDir  bool
+	cancel   context.CancelFunc
+}
+
+// Start initializes storage in the data directory and installs the fake
+// Kubernetes and SSH backends
+func Start(opts Options) (*Harness, error) {
+	h := &Harness{DataDir: opts.DataDir}
+	if h.DataDir == "" {
+		dir, err := os.MkdirTemp("", "gagos-fake-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+		h.DataDir = dir
+		h.tempDir = true
+	}
+
+	os.Setenv("GAGOS_STORAGE_TYPE", storage.StorageTypeBBolt)
+	os.Setenv("GAGOS_DB_PATH", filepath.Join(h.DataDir, "gagos.db"))
+	if err := storage.Init(); err != nil {
+		h.removeDataDir()
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	var objects []runtime.Object
+	if opts.Seed {
+		objects = append(objects, KubernetesFixtures()...)
+	}
+	objects = append(objects, opts.Objects...)
+	h.Kubernetes = fake.NewSimpleClientset(objects...)
+	k8s.SetClient(h.Kubernetes, &rest.Config{Host: "https://fake.invalid"})
+
+	h.SSH = NewSSH()
+	cicd.SetSSHBackend(h.SSH)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	watcher, err := h.Kubernetes.BatchV1().Jobs(metav1.NamespaceAll).Watch(ctx, metav1.ListOptions{})
+	if err != nil {
+		h.Stop()
+		return nil, fmt.Errorf("failed to watch jobs: %w", err)
+	}
+	go h.runJobs(ctx, watcher)
+
+	if opts.Seed {
+		if err := SeedStorage(); err != nil {
+			h.Stop()
+			return nil, fmt.Errorf("failed to seed storage: %w", err)
+		}
+	}
+	return h, nil
+}
+
+// Stop uninstalls the fake backends and closes storage. It is safe on a
+// Harness whose Start failed part way.
+func (h *Harness) Stop() {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	cicd.SetSSHBackend(nil)
+	k8s.SetClient(nil, nil)
+	if err := storage.Close(); err != nil {
+		log.Warn().Err(err).Msg("Failed to close fake storage")
+	}
+	h.removeDataDir()
+}
+
+func (h *Harness) removeDataDir() {
+	if h.tempDir {
+		os.RemoveAll(h.DataDir)
+	}
+}
+
+// FailJobs decides which pipeline Jobs fail; nil lets every Job succeed
+func (h *Harness) FailJobs(fails func(job *batchv1.Job) bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.jobFails = fails
+}
+
+// runJobs plays the Job controller and the kubelet: every Job created in
+// the fake cluster gets a finished pod and a Complete or Failed condition
+func (h *Harness) runJobs(ctx context.Context, watcher watch.Interface) {
+	defer watcher.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return
+			}
+			job, isJob := event.Object.(*batchv1.Job)
+			if event.Type != watch.Added || !isJob {
+				continue
+			}
+			go h.finishJob(ctx, job.DeepCopy())
+		}
+	}
+}
+
+func (h *Harness) finishJob(ctx context.Context, job *batchv1.Job) {
+	h.mu.Lock()
+	failed := h.jobFails != nil && h.jobFails(job)
+	h.mu.Unlock()
+
+	labels := map[string]string{}
+	for k, v := range job.Spec.Template.Labels {
+		labels[k] = v
+	}
+	labels["job-name"] = job.Name
+	phase, condition, reason := corev1.PodSucceeded, batchv1.JobComplete, ""
+	if failed {
+		phase, condition, reason = corev1.PodFailed, batchv1.JobFailed, "BackoffLimitExceeded"
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      job.Name + "-fake",
+			Namespace: job.Namespace,
+			Labels:    labels,
+		},
+		Spec:   job.Spec.Template.Spec,
+		Status: corev1.PodStatus{Phase: phase},
+	}
+	if _, err := h.Kubernetes.CoreV1().Pods(job.Namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		log.Debug().Err(err).Str("job", job.Name).Msg("Fake job controller failed to create pod")
+	}
+
+	now := metav1.Now()
+	job.Status.StartTime = &now
+	job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{
+		Type:               condition,
+		Status:             corev1.ConditionTrue,
+		Reason:             reason,
+		LastTransitionTime: now,
+	})
+	if failed {
+		job.Status.Failed = 1
+	} else {
+		job.Status.Succeeded = 1
+		job.Status.CompletionTime = &now
+	}
+	if _, err := h.Kubernetes.BatchV1().Jobs(job.Namespace).UpdateStatus(ctx, job, metav1.UpdateOptions{}); err != nil {
+		log.Debug().Err(err).Str("job", job.Name).Msg("Fake job controller failed to finish job")
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package fakes
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaga951/gagos/internal/cicd"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// startHarness starts seeded fake backends for one test
+func startHarness(t *testing.T) *Harness {
+	t.Helper()
+	h, err := Start(Options{Seed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(h.Stop)
+	return h
+}
+
+// waitFor polls until done returns true
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func finished(s cicd.RunStatus) bool {
+	return s == cicd.RunStatusSucceeded || s == cicd.RunStatusFailed || s == cicd.RunStatusCancelled
+}
+
+func TestPipelineRun(t *testing.T) {
+	h := startHarness(t)
+	pipelines, err := cicd.ListPipelines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pipeline *cicd.Pipeline
+	for _, p := range pipelines {
+		if p.Name == FixturePipeline {
+			pipeline = p
+		}
+	}
+	if pipeline == nil {
+		t.Fatal("fixture pipeline was not seeded")
+	}
+
+	run := func() *cicd.PipelineRun {
+		started, err := cicd.TriggerPipeline(context.Background(), pipeline, "manual", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var run *cicd.PipelineRun
+		waitFor(t, "the pipeline run", func() bool {
+			run, err = cicd.GetRun(started.ID)
+			return err == nil && finished(run.Status)
+		})
+		return run
+	}
+
+	r := run()
+	if r.Status != cicd.RunStatusSucceeded {
+		t.Fatalf("run status %s: %s", r.Status, r.Error)
+	}
+	for _, j := range r.Jobs {
+		if j.Status != cicd.RunStatusSucceeded || j.K8sJobName == "" || j.K8sPodName != j.K8sJobName+"-fake" {
+			t.Errorf("job %s: %+v", j.Name, j)
+		}
+	}
+
+	// A failed Job fails the run and cancels the jobs depending on it
+	h.FailJobs(func(job *batchv1.Job) bool { return strings.Contains(job.Name, "build") })
+	r = run()
+	if r.Status != cicd.RunStatusFailed {
+		t.Fatalf("run status %s, want failed", r.Status)
+	}
+	for _, j := range r.Jobs {
+		want := cicd.RunStatusFailed
+		if j.Name == "test" {
+			want = cicd.RunStatusCancelled
+		}
+		if j.Status != want {
+			t.Errorf("job %s is %s, want %s", j.Name, j.Status, want)
+		}
+	}
+}
+
+func TestFreestyleBuild(t *testing.T) {
+	h := startHarness(t)
+	jobs, err := cicd.ListFreestyleJobs()
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("%d freestyle jobs, error %v", len(jobs), err)
+	}
+
+	build := func() *cicd.FreestyleBuild {
+		started, err := cicd.TriggerManualFreestyleBuild(jobs[0].ID, &cicd.TriggerFreestyleBuildRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b *cicd.FreestyleBuild
+		waitFor(t, "the freestyle build", func() bool {
+			b, err = cicd.GetFreestyleBuild(started.ID)
+			return err == nil && finished(b.Status)
+		})
+		return b
+	}
+
+	b := build()
+	if b.Status != cicd.RunStatusSucceeded {
+		t.Fatalf("build status %s: %s", b.Status, b.Error)
+	}
+	if len(b.Steps) != 2 || !strings.Contains(b.Steps[0].Output, "hello") {
+		t.Errorf("steps = %+v", b.Steps)
+	}
+	var ran []string
+	for _, c := range h.SSH.Commands() {
+		if c.Host != "fake-host.invalid" {
+			t.Errorf("command ran on %s", c.Host)
+		}
+		ran = append(ran, c.Cmd)
+	}
+	if !strings.Contains(strings.Join(ran, "\n"), "echo uploaded") {
+		t.Errorf("commands = %q", ran)
+	}
+
+	// A failing step fails the build
+	h.SSH.Handle("", func(_ *cicd.SSHHost, cmd string, _, stderr io.Writer) int {
+		if strings.Contains(cmd, "echo uploaded") {
+			io.WriteString(stderr, "upload failed")
+			return 3
+		}
+		return 0
+	})
+	b = build()
+	if b.Status != cicd.RunStatusFailed || b.Steps[1].Status != cicd.RunStatusFailed || b.Steps[1].ExitCode != 3 {
+		t.Errorf("build %s, steps %+v", b.Status, b.Steps)
+	}
+}
+
+func TestStartFails(t *testing.T) {
+	// A data directory that is a file cannot hold the database
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Start(Options{DataDir: file}); err == nil {
+		t.Fatal("Start succeeded")
+	}
+	// Stop must not panic on a Harness that never finished starting
+	(&Harness{DataDir: file}).Stop()
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Stop removed a data directory it did not create: %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package fakes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gaga951/gagos/internal/cicd"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Names of the seeded fixtures
+const (
+	FixtureNamespace    = "demo"
+	FixtureDeployment   = "web"
+	FixtureSSHHost      = "fake-host"
+	FixturePipeline     = "hello-pipeline"
+	FixtureFreestyleJob = "hello-freestyle"
+)
+
+// fixturePipelineYAML is a two-job pipeline that runs on the fake cluster
+const fixturePipelineYAML = `apiVersion: gagos.io/v1
+kind: Pipeline
+metadata:
+  name: ` + FixturePipeline + `
+  description: Seeded by the fake backends
+spec:
+  variables:
+    GREETING: hello
+  jobs:
+    - name: build
+      image: alpine:3.19
+      script: echo "$GREETING from build"
+    - name: test
+      image: alpine:3.19
+      script: echo "$GREETING from test"
+      dependsOn: [build]
+`
+
+// KubernetesFixtures returns a small cluster: two ready nodes, the demo and
+// CI/CD namespaces, and a web deployment with its pods and service
+func KubernetesFixtures() []runtime.Object {
+	objects := []runtime.Object{
+		namespace("default"),
+		namespace("kube-system"),
+		namespace(FixtureNamespace),
+		node("node-1"),
+		node("node-2"),
+	}
+	if ns := cicd.Namespace(); ns != "default" {
+		objects = append(objects, namespace(ns))
+	}
+
+	labels := map[string]string{"app": FixtureDeployment}
+	replicas := int32(2)
+	objects = append(objects,
+		&appsv1.Deployment{
+			ObjectMeta: meta(FixtureDeployment, FixtureNamespace, labels),
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec:       webPodSpec(""),
+				},
+			},
+			Status: appsv1.DeploymentStatus{
+				Replicas:          replicas,
+				ReadyReplicas:     replicas,
+				AvailableReplicas: replicas,
+				UpdatedReplicas:   replicas,
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: meta(FixtureDeployment, FixtureNamespace, labels),
+			Spec: corev1.ServiceSpec{
+				Type:      corev1.ServiceTypeClusterIP,
+				ClusterIP: "10.96.0.10",
+				Selector:  labels,
+				Ports:     []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+			},
+		},
+	)
+	for i := 1; i <= int(replicas); i++ {
+		objects = append(objects, &corev1.Pod{
+			ObjectMeta: meta(fmt.Sprintf("%s-%d", FixtureDeployment, i), FixtureNamespace, labels),
+			Spec:       webPodSpec(fmt.Sprintf("node-%d", i)),
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				PodIP: fmt.Sprintf("10.244.%d.10", i),
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+				},
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "web", Image: "nginx:1.25", Ready: true},
+				},
+			},
+		})
+	}
+	return objects
+}
+
+// meta names a fixture object created a day ago
+func meta(name, namespace string, labels map[string]string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:              name,
+		Namespace:         namespace,
+		Labels:            labels,
+		CreationTimestamp: metav1.NewTime(time.Now().Add(-24 * time.Hour)),
+	}
+}
+
+func namespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: meta(name, "", nil),
+		Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+	}
+}
+
+func node(name string) *corev1.Node {
+	capacity := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("4"),
+		corev1.ResourceMemory: resource.MustParse("8Gi"),
+		corev1.ResourcePods:   resource.MustParse("110"),
+	}
+	return &corev1.Node{
+		ObjectMeta: meta(name, "", map[string]string{"kubernetes.io/hostname": name}),
+		Status: corev1.NodeStatus{
+			Capacity:    capacity,
+			Allocatable: capacity,
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			},
+			NodeInfo: corev1.NodeSystemInfo{
+				KubeletVersion:          "v1.29.0",
+				OSImage:                 "Fake Linux",
+				ContainerRuntimeVersion: "containerd://1.7.0",
+			},
+		},
+	}
+}
+
+func webPodSpec(nodeName string) corev1.PodSpec {
+	return corev1.PodSpec{
+		NodeName: nodeName,
+		Containers: []corev1.Container{{
+			Name:  "web",
+			Image: "nginx:1.25",
+			Ports: []corev1.ContainerPort{{ContainerPort: 8080}},
+		}},
+	}
+}
+
+// SeedStorage adds the fixture SSH host, pipeline and freestyle job, unless
+// they already exist in a reused data directory
+func SeedStorage() error {
+	hosts, err := cicd.ListSSHHosts()
+	if err != nil {
+		return err
+	}
+	var host *cicd.SSHHost
+	for _, h := range hosts {
+		if h.Name == FixtureSSHHost {
+			host = h
+		}
+	}
+	if host == nil {
+		host, err = cicd.CreateSSHHost(&cicd.CreateSSHHostRequest{
+			Name:        FixtureSSHHost,
+			Host:        "fake-host.invalid",
+			Port:        22,
+			Username:    "deploy",
+			AuthMethod:  cicd.SSHAuthPassword,
+			Password:    "fake-password",
+			Description: "In-memory host of the fake backends",
+		})
+		if err != nil {
+			return fmt.Errorf("ssh host: %w", err)
+		}
+	}
+
+	pipelines, err := cicd.ListPipelines()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, p := range pipelines {
+		found = found || p.Name == FixturePipeline
+	}
+	if !found {
+		pipeline, err := cicd.ParsePipelineYAML(fixturePipelineYAML)
+		if err != nil {
+			return fmt.Errorf("pipeline: %w", err)
+		}
+		if err := cicd.SavePipeline(pipeline); err != nil {
+			return fmt.Errorf("pipeline: %w", err)
+		}
+	}
+
+	jobs, err := cicd.ListFreestyleJobs()
+	if err != nil {
+		return err
+	}
+	found = false
+	for _, j := range jobs {
+		found = found || j.Name == FixtureFreestyleJob
+	}
+	if !found {
+		_, err := cicd.CreateFreestyleJob(&cicd.CreateFreestyleJobRequest{
+			Name:        FixtureFreestyleJob,
+			Description: "Seeded by the fake backends",
+			Enabled:     true,
+			Environment: map[string]string{"GREETING": "hello"},
+			BuildSteps: []cicd.BuildStep{
+				{Name: "greet", Type: cicd.StepTypeShell, HostID: host.ID, Command: "echo $GREETING"},
+				{Name: "upload", Type: cicd.StepTypeShell, HostID: host.ID, Command: "echo uploaded"},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("freestyle job: %w", err)
+		}
+	}
+	return nil
+}
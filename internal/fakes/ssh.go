@@ -0,0 +1,135 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaga951/gagos/internal/cicd"
+)
+
+// SSH is an in-memory SSH backend. Every command is recorded and answered by
+// the most recently added handler whose prefix matches it. Without one,
+// "cat > path" and "cat path" write and read a file system kept per host,
+// "echo" prints its arguments, and any other command succeeds silently.
+type SSH struct {
+	mu       sync.Mutex
+	files    map[string]map[string][]byte // host -> path -> content
+	commands []Command
+	handlers []sshHandler
+}
+
+// Command is a command run through the fake SSH backend
+type Command struct {
+	Host  string    `json:"host"`
+	Cmd   string    `json:"cmd"`
+	Stdin []byte    `json:"stdin,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// HandlerFunc answers a command by writing its output and returning its
+// exit code
+type HandlerFunc func(host *cicd.SSHHost, cmd string, stdout, stderr io.Writer) int
+
+type sshHandler struct {
+	prefix string
+	fn     HandlerFunc
+}
+
+// NewSSH returns an empty fake SSH backend
+func NewSSH() *SSH {
+	return &SSH{files: make(map[string]map[string][]byte)}
+}
+
+// Handle answers the commands starting with prefix with fn
+func (s *SSH) Handle(prefix string, fn HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, sshHandler{prefix: prefix, fn: fn})
+}
+
+// Respond answers the commands starting with prefix with a fixed output and
+// exit code
+func (s *SSH) Respond(prefix, output string, exitCode int) {
+	s.Handle(prefix, func(_ *cicd.SSHHost, _ string, stdout, _ io.Writer) int {
+		io.WriteString(stdout, output)
+		return exitCode
+	})
+}
+
+// Commands returns the commands run so far, oldest first
+func (s *SSH) Commands() []Command {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Command(nil), s.commands...)
+}
+
+// WriteFile puts a file on a host
+func (s *SSH) WriteFile(host, path string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeFile(host, path, data)
+}
+
+// File returns a file of a host
+func (s *SSH) File(host, path string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.files[host][path]
+	return data, ok
+}
+
+func (s *SSH) writeFile(host, path string, data []byte) {
+	if s.files[host] == nil {
+		s.files[host] = make(map[string][]byte)
+	}
+	s.files[host][path] = append([]byte(nil), data...)
+}
+
+// Run implements cicd.SSHBackend
+func (s *SSH) Run(ctx context.Context, host *cicd.SSHHost, cmd string, stdin []byte, stdout, stderr io.Writer) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return -1, err
+	}
+
+	s.mu.Lock()
+	s.commands = append(s.commands, Command{Host: host.Host, Cmd: cmd, Stdin: stdin, Time: time.Now()})
+	var fn HandlerFunc
+	for i := len(s.handlers) - 1; i >= 0; i-- {
+		if strings.HasPrefix(cmd, s.handlers[i].prefix) {
+			fn = s.handlers[i].fn
+			break
+		}
+	}
+	if fn != nil {
+		s.mu.Unlock()
+		return fn(host, cmd, stdout, stderr), nil
+	}
+	defer s.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(cmd, "cat > "):
+		s.writeFile(host.Host, strings.TrimSpace(strings.TrimPrefix(cmd, "cat > ")), stdin)
+		return 0, nil
+	case strings.HasPrefix(cmd, "cat "):
+		path := strings.TrimSpace(strings.TrimPrefix(cmd, "cat "))
+		data, ok := s.files[host.Host][path]
+		if !ok {
+			fmt.Fprintf(stderr, "cat: %s: No such file or directory\n", path)
+			return 1, nil
+		}
+		stdout.Write(data)
+		return 0, nil
+	case cmd == "echo" || strings.HasPrefix(cmd, "echo "):
+		args := strings.TrimSpace(strings.TrimPrefix(cmd, "echo"))
+		fmt.Fprintln(stdout, strings.Trim(args, `'"`))
+		return 0, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+// Package fanout runs independent fetches concurrently with a concurrency cap.
+// Unlike errgroup, one failing or slow task never cancels its siblings, so
+// handlers can return whatever succeeded alongside per-task errors.
+package fanout
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLimit is used when Options.Limit is not set
+const DefaultLimit = 4
+
+// Func is a single fetch. It should store its own result and honour ctx.
+type Func func(ctx context.Context) error
+
+// Options controls a fan-out run
+type Options struct {
+	Limit   int           // max tasks running at once, <= 0 means DefaultLimit
+	Timeout time.Duration // per-task timeout, 0 means only the parent ctx applies
+}
+
+// Errors maps task name to error for the tasks that failed
+type Errors map[string]error
+
+func (e Errors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %v", name, e[name]))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Err returns e as an error, or nil when no task failed. Use this rather than
+// assigning Errors to an error variable directly, which is never nil.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Run starts every task, at most opts.Limit at a time, and waits for all of
+// them. It returns nil when every task succeeded.
+func Run(ctx context.Context, opts Options, tasks map[string]Func) Errors {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs Errors
+		sem  = make(chan struct{}, limit)
+	)
+
+	for name, task := range tasks {
+		name, task := name, task
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				mu.Lock()
+				if errs == nil {
+					errs = Errors{}
+				}
+				errs[name] = ctx.Err()
+				mu.Unlock()
+				return
+			}
+
+			taskCtx := ctx
+			if opts.Timeout > 0 {
+				var cancel context.CancelFunc
+				taskCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+				defer cancel()
+			}
+
+			if err := task(taskCtx); err != nil {
+				mu.Lock()
+				if errs == nil {
+					errs = Errors{}
+				}
+				errs[name] = err
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return errs
+}
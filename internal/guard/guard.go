@@ -0,0 +1,229 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+// Package guard protects outbound backends (the Kubernetes API server,
+// databases, object stores) with a client-side rate limit and a circuit
+// breaker, so a runaway UI or script cannot overwhelm them through GAGOS.
+package guard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrOpen is returned while a backend's circuit breaker is open
+var ErrOpen = errors.New("circuit breaker open")
+
+// Breaker states
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+// Config controls a guard. Zero RPS disables rate limiting and a zero
+// FailureThreshold disables the breaker.
+type Config struct {
+	RPS              float64
+	Burst            int
+	FailureThreshold int           // consecutive failures that open the breaker
+	Cooldown         time.Duration // how long the breaker stays open before a trial call
+}
+
+// ConfigFromEnv reads <prefix>_RATE_LIMIT, <prefix>_RATE_BURST,
+// <prefix>_BREAKER_THRESHOLD and <prefix>_BREAKER_COOLDOWN over the given defaults.
+func ConfigFromEnv(prefix string, defaults Config) Config {
+	cfg := defaults
+	if v, err := strconv.ParseFloat(os.Getenv(prefix+"_RATE_LIMIT"), 64); err == nil && v >= 0 {
+		cfg.RPS = v
+	}
+	if v, err := strconv.Atoi(os.Getenv(prefix + "_RATE_BURST")); err == nil && v > 0 {
+		cfg.Burst = v
+	}
+	if v, err := strconv.Atoi(os.Getenv(prefix + "_BREAKER_THRESHOLD")); err == nil && v >= 0 {
+		cfg.FailureThreshold = v
+	}
+	if v, err := time.ParseDuration(os.Getenv(prefix + "_BREAKER_COOLDOWN")); err == nil && v > 0 {
+		cfg.Cooldown = v
+	}
+	return cfg
+}
+
+// Guard is the limiter and breaker for a single backend
+type Guard struct {
+	name    string
+	cfg     Config
+	limiter *rate.Limiter
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	trial    bool // a half-open trial call is in flight
+}
+
+// New creates a guard for the named backend
+func New(name string, cfg Config) *Guard {
+	g := &Guard{name: name, cfg: cfg, state: StateClosed}
+	if cfg.RPS > 0 {
+		burst := cfg.Burst
+		if burst <= 0 {
+			burst = int(cfg.RPS) + 1
+		}
+		g.limiter = rate.NewLimiter(rate.Limit(cfg.RPS), burst)
+	}
+	return g
+}
+
+// Before must be called ahead of each backend call. It fails fast while the
+// breaker is open and otherwise waits for a rate limit token.
+func (g *Guard) Before(ctx context.Context) error {
+	g.mu.Lock()
+	if g.cfg.FailureThreshold > 0 && g.state == StateOpen {
+		if time.Since(g.openedAt) < g.cfg.Cooldown {
+			g.mu.Unlock()
+			return fmt.Errorf("%s: %w, retry after %s", g.name, ErrOpen, g.cfg.Cooldown-time.Since(g.openedAt).Round(time.Second))
+		}
+		g.state = StateHalfOpen
+	}
+	trial := false
+	if g.state == StateHalfOpen {
+		if g.trial {
+			g.mu.Unlock()
+			return fmt.Errorf("%s: %w, trial call in progress", g.name, ErrOpen)
+		}
+		g.trial, trial = true, true
+	}
+	g.mu.Unlock()
+
+	if g.limiter != nil {
+		if err := g.limiter.Wait(ctx); err != nil {
+			// No call was made, so there is no outcome to record; only give
+			// up the trial slot
+			if trial {
+				g.mu.Lock()
+				g.trial = false
+				g.mu.Unlock()
+			}
+			return fmt.Errorf("%s: rate limit: %w", g.name, err)
+		}
+	}
+	return nil
+}
+
+// After records the outcome of a call started with Before. Pass nil for
+// success and only backend-health errors (not e.g. a bad query) as failures.
+func (g *Guard) After(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.trial = false
+	if err == nil {
+		g.failures = 0
+		g.state = StateClosed
+		return
+	}
+
+	g.failures++
+	if g.cfg.FailureThreshold > 0 && (g.state == StateHalfOpen || g.failures >= g.cfg.FailureThreshold) {
+		g.state = StateOpen
+		g.openedAt = time.Now()
+	}
+}
+
+// Status is a point-in-time view of a guard
+type Status struct {
+	Name     string  `json:"name"`
+	State    string  `json:"state"`
+	Failures int     `json:"failures"`
+	RPS      float64 `json:"rps"`
+	Burst    int     `json:"burst"`
+}
+
+// Status returns the guard's current state
+func (g *Guard) Status() Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	state := g.state
+	if state == StateOpen && time.Since(g.openedAt) >= g.cfg.Cooldown {
+		state = StateHalfOpen
+	}
+	return Status{Name: g.name, State: state, Failures: g.failures, RPS: g.cfg.RPS, Burst: g.cfg.Burst}
+}
+
+// RoundTripper wraps next so every HTTP request goes through the guard.
+// Transport errors and 5xx/429 responses count as failures.
+func (g *Guard) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if err := g.Before(req.Context()); err != nil {
+			return nil, err
+		}
+		resp, err := next.RoundTrip(req)
+		switch {
+		case err != nil:
+			g.After(err)
+		case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+			g.After(fmt.Errorf("HTTP %d", resp.StatusCode))
+		default:
+			g.After(nil)
+		}
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// Registry hands out one guard per key (e.g. "postgres/db.internal:5432")
+type Registry struct {
+	cfg    Config
+	mu     sync.Mutex
+	guards map[string]*Guard
+}
+
+// NewRegistry creates a registry whose guards all share cfg
+func NewRegistry(cfg Config) *Registry {
+	return &Registry{cfg: cfg, guards: make(map[string]*Guard)}
+}
+
+// Get returns the guard for key, creating it on first use
+func (r *Registry) Get(key string) *Guard {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g, ok := r.guards[key]
+	if !ok {
+		g = New(key, r.cfg)
+		r.guards[key] = g
+	}
+	return g
+}
+
+// Statuses returns the state of every guard, sorted by name
+func (r *Registry) Statuses() []Status {
+	r.mu.Lock()
+	guards := make([]*Guard, 0, len(r.guards))
+	for _, g := range r.guards {
+		guards = append(guards, g)
+	}
+	r.mu.Unlock()
+
+	statuses := make([]Status, 0, len(guards))
+	for _, g := range guards {
+		statuses = append(statuses, g.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
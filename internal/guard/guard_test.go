@@ -0,0 +1,70 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package guard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	g := New("test", Config{FailureThreshold: 2, Cooldown: 20 * time.Millisecond})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := g.Before(ctx); err != nil {
+			t.Fatal(err)
+		}
+		g.After(errors.New("connection refused"))
+	}
+	if err := g.Before(ctx); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Before on an open breaker = %v", err)
+	}
+
+	// After the cooldown one trial call goes through at a time
+	time.Sleep(30 * time.Millisecond)
+	if err := g.Before(ctx); err != nil {
+		t.Fatalf("trial call: %v", err)
+	}
+	if err := g.Before(ctx); !errors.Is(err, ErrOpen) {
+		t.Errorf("second call during the trial = %v", err)
+	}
+	g.After(nil)
+	if s := g.Status(); s.State != StateClosed || s.Failures != 0 {
+		t.Errorf("after a successful trial: %+v", s)
+	}
+}
+
+func TestBreakerStaysOpenWhenLimiterWaitFails(t *testing.T) {
+	g := New("test", Config{RPS: 1000, Burst: 1, FailureThreshold: 1, Cooldown: 20 * time.Millisecond})
+	if err := g.Before(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	g.After(errors.New("connection refused"))
+	time.Sleep(30 * time.Millisecond)
+
+	// A caller that gives up waiting for a token made no call, so the
+	// breaker must not count it as a success
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		if err := g.Before(cancelled); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Before with a cancelled context = %v", err)
+		}
+	}
+	if s := g.Status(); s.State == StateClosed || s.Failures != 1 {
+		t.Fatalf("breaker after failed waits: %+v", s)
+	}
+
+	// and the trial slot is free again
+	if err := g.Before(context.Background()); err != nil {
+		t.Fatalf("trial call: %v", err)
+	}
+	g.After(errors.New("still down"))
+	if s := g.Status(); s.State != StateOpen {
+		t.Errorf("failed trial left the breaker %s", s.State)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ipaccess restricts who can reach GAGOS by client address and
+// country. Rules are set per route group, so that for example webhooks stay
+// open to a Git host while the API only answers office ranges.
+package ipaccess
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/gaga951/gagos/internal/egress"
+)
+
+// Route groups with their own rules
+const (
+	GroupUI       = "ui"       // the web UI and its static files
+	GroupAuth     = "auth"     // the login page and /api/auth
+	GroupAPI      = "api"      // the rest of /api
+	GroupWebhooks = "webhooks" // pipeline and freestyle webhooks
+)
+
+// Groups lists the route groups
+var Groups = []string{GroupUI, GroupAuth, GroupAPI, GroupWebhooks}
+
+// webhookPrefixes are the paths of the token-authenticated webhooks
+var webhookPrefixes = []string{
+	"/api/v1/cicd/webhooks/",
+	"/api/v1/cicd/freestyle/webhook/",
+}
+
+// Rules restricts one route group. Deny entries win over allow entries, and
+// an empty allow list allows everything not denied.
+type Rules struct {
+	AllowCIDRs     []*net.IPNet
+	DenyCIDRs      []*net.IPNet
+	AllowCountries map[string]bool
+	DenyCountries  map[string]bool
+}
+
+// Policy holds the rules of each route group
+type Policy struct {
+	Groups map[string]*Rules
+	// CountryHeader is the request header carrying the client's ISO 3166
+	// country code, set by a GeoIP-aware proxy or CDN in front of GAGOS
+	CountryHeader string
+}
+
+// FromEnv reads GAGOS_ACCESS_{ALLOW,DENY}_{CIDRS,COUNTRIES} for every group,
+// overridden per group by GAGOS_ACCESS_<GROUP>_{ALLOW,DENY}_{CIDRS,COUNTRIES},
+// and GAGOS_GEOIP_HEADER. It returns a nil Policy when nothing is set.
+func FromEnv() (*Policy, error) {
+	p := &Policy{
+		Groups:        make(map[string]*Rules, len(Groups)),
+		CountryHeader: strings.TrimSpace(os.Getenv("GAGOS_GEOIP_HEADER")),
+	}
+	for _, group := range Groups {
+		rules, err := rulesFromEnv(group)
+		if err != nil {
+			return nil, err
+		}
+		if rules != nil {
+			p.Groups[group] = rules
+		}
+	}
+	if len(p.Groups) == 0 {
+		return nil, nil
+	}
+	for group, rules := range p.Groups {
+		if (len(rules.AllowCountries) > 0 || len(rules.DenyCountries) > 0) && p.CountryHeader == "" {
+			return nil, fmt.Errorf("country rules for %s need GAGOS_GEOIP_HEADER", group)
+		}
+	}
+	return p, nil
+}
+
+func rulesFromEnv(group string) (*Rules, error) {
+	rules := &Rules{}
+	var err error
+	var name string
+
+	name, rules.AllowCIDRs, err = cidrSetting(group, "ALLOW_CIDRS")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	name, rules.DenyCIDRs, err = cidrSetting(group, "DENY_CIDRS")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	rules.AllowCountries = parseCountries(setting(group, "ALLOW_COUNTRIES"))
+	rules.DenyCountries = parseCountries(setting(group, "DENY_COUNTRIES"))
+
+	if len(rules.AllowCIDRs) == 0 && len(rules.DenyCIDRs) == 0 &&
+		len(rules.AllowCountries) == 0 && len(rules.DenyCountries) == 0 {
+		return nil, nil
+	}
+	return rules, nil
+}
+
+// setting returns the group's own variable, else the one for all groups
+func setting(group, suffix string) string {
+	if v, ok := os.LookupEnv("GAGOS_ACCESS_" + strings.ToUpper(group) + "_" + suffix); ok {
+		return v
+	}
+	return os.Getenv("GAGOS_ACCESS_" + suffix)
+}
+
+func cidrSetting(group, suffix string) (string, []*net.IPNet, error) {
+	name := "GAGOS_ACCESS_" + strings.ToUpper(group) + "_" + suffix
+	if _, ok := os.LookupEnv(name); !ok {
+		name = "GAGOS_ACCESS_" + suffix
+	}
+	nets, err := egress.ParseCIDRs(os.Getenv(name))
+	return name, nets, err
+}
+
+// parseCountries accepts comma separated two-letter country codes
+func parseCountries(s string) map[string]bool {
+	countries := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.ToUpper(strings.TrimSpace(part)); part != "" {
+			countries[part] = true
+		}
+	}
+	return countries
+}
+
+// GroupOf returns the route group of a request path. Routing ignores case,
+// so the path is matched in lower case.
+func GroupOf(path string) string {
+	path = strings.ToLower(path)
+	for _, prefix := range webhookPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return GroupWebhooks
+		}
+	}
+	switch {
+	case path == "/login" || strings.HasPrefix(path, "/api/auth/"):
+		return GroupAuth
+	case path == "/api" || strings.HasPrefix(path, "/api/"):
+		return GroupAPI
+	}
+	return GroupUI
+}
+
+// Check returns why a client is refused access to group, or "" when it is
+// allowed. country is empty when it is unknown; an unknown country never
+// matches an allow list.
+func (p *Policy) Check(group string, ip net.IP, country string) string {
+	if p == nil {
+		return ""
+	}
+	rules := p.Groups[group]
+	if rules == nil {
+		return ""
+	}
+	country = strings.ToUpper(country)
+
+	if ip == nil && (len(rules.AllowCIDRs) > 0 || len(rules.DenyCIDRs) > 0) {
+		return "unknown client address"
+	}
+	if containsIP(rules.DenyCIDRs, ip) {
+		return "address denied"
+	}
+	if len(rules.AllowCIDRs) > 0 && !containsIP(rules.AllowCIDRs, ip) {
+		return "address not allowed"
+	}
+	if country != "" && rules.DenyCountries[country] {
+		return "country denied"
+	}
+	if len(rules.AllowCountries) > 0 && !rules.AllowCountries[country] {
+		if country == "" {
+			return "country unknown"
+		}
+		return "country not allowed"
+	}
+	return ""
+}
+
+// Summary describes the rules of each restricted group, for logs
+func (p *Policy) Summary() map[string]string {
+	summary := make(map[string]string, len(p.Groups))
+	for group, rules := range p.Groups {
+		var parts []string
+		if len(rules.AllowCIDRs) > 0 {
+			parts = append(parts, fmt.Sprintf("allow %d ranges", len(rules.AllowCIDRs)))
+		}
+		if len(rules.DenyCIDRs) > 0 {
+			parts = append(parts, fmt.Sprintf("deny %d ranges", len(rules.DenyCIDRs)))
+		}
+		if len(rules.AllowCountries) > 0 {
+			parts = append(parts, fmt.Sprintf("allow %d countries", len(rules.AllowCountries)))
+		}
+		if len(rules.DenyCountries) > 0 {
+			parts = append(parts, fmt.Sprintf("deny %d countries", len(rules.DenyCountries)))
+		}
+		summary[group] = strings.Join(parts, ", ")
+	}
+	return summary
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
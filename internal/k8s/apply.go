@@ -0,0 +1,222 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// FieldManager is the server-side apply field manager used for all GAGOS writes
+const FieldManager = "gagos"
+
+// Apply actions reported per object, mirroring kubectl apply output
+const (
+	ApplyCreated    = "created"
+	ApplyConfigured = "configured"
+	ApplyUnchanged  = "unchanged"
+	ApplyFailed     = "failed"
+)
+
+// ApplyOptions controls a server-side apply request
+type ApplyOptions struct {
+	Namespace string // default namespace for namespaced objects without one
+	Force     bool   // take ownership of conflicting fields
+	DryRun    bool   // validate on the server without persisting
+}
+
+// ApplyResult is the outcome for a single object in a manifest
+type ApplyResult struct {
+	APIVersion string `json:"api_version"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	Action     string `json:"action"`
+	Error      string `json:"error,omitempty"`
+}
+
+var (
+	dynamicClient dynamic.Interface
+	restMapper    *restmapper.DeferredDiscoveryRESTMapper
+	dynamicMu     sync.Mutex
+)
+
+// getDynamic lazily builds the dynamic client and a discovery-backed REST mapper
+func getDynamic() (dynamic.Interface, *restmapper.DeferredDiscoveryRESTMapper, error) {
+	if clientset == nil || restConfig == nil {
+		return nil, nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	dynamicMu.Lock()
+	defer dynamicMu.Unlock()
+
+	if dynamicClient == nil {
+		dc, err := dynamic.NewForConfig(restConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create dynamic client: %w", err)
+		}
+		dynamicClient = dc
+		restMapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+	}
+	return dynamicClient, restMapper, nil
+}
+
+// DecodeManifests splits multi-document YAML (or JSON) into unstructured objects,
+// skipping empty documents and expanding v1 List kinds.
+func DecodeManifests(content string) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader([]byte(content)), 4096)
+
+	var objects []*unstructured.Unstructured
+	for i := 0; ; i++ {
+		var raw map[string]interface{}
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("document %d: invalid YAML: %w", i+1, err)
+		}
+		if len(raw) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: raw}
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+			return nil, fmt.Errorf("document %d: apiVersion and kind are required", i+1)
+		}
+
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, fmt.Errorf("document %d: %w", i+1, err)
+			}
+			for j := range list.Items {
+				objects = append(objects, &list.Items[j])
+			}
+			continue
+		}
+		objects = append(objects, obj)
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no objects found in manifest")
+	}
+	return objects, nil
+}
+
+// ApplyManifests server-side applies every object in a multi-document manifest.
+// Objects are applied in order; a failure on one object does not stop the rest.
+func ApplyManifests(ctx context.Context, content string, opts ApplyOptions) ([]ApplyResult, error) {
+	dc, mapper, err := getDynamic()
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := DecodeManifests(content)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ApplyResult, 0, len(objects))
+	for _, obj := range objects {
+		results = append(results, applyObject(ctx, dc, mapper, obj, opts))
+	}
+	return results, nil
+}
+
+func applyObject(ctx context.Context, dc dynamic.Interface, mapper *restmapper.DeferredDiscoveryRESTMapper, obj *unstructured.Unstructured, opts ApplyOptions) ApplyResult {
+	result := ApplyResult{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Name:       obj.GetName(),
+		Namespace:  obj.GetNamespace(),
+		Action:     ApplyFailed,
+	}
+
+	if obj.GetName() == "" {
+		result.Error = "metadata.name is required"
+		return result
+	}
+
+	resource, err := resourceFor(dc, mapper, obj, opts.Namespace)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Namespace = obj.GetNamespace()
+
+	existingVersion := ""
+	existing, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err == nil {
+		existingVersion = existing.GetResourceVersion()
+	} else if !apierrors.IsNotFound(err) {
+		result.Error = err.Error()
+		return result
+	}
+
+	// Server-side apply rejects these fields on input
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+
+	applyOpts := metav1.ApplyOptions{FieldManager: FieldManager, Force: opts.Force}
+	if opts.DryRun {
+		applyOpts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	applied, err := resource.Apply(ctx, obj.GetName(), obj, applyOpts)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	switch {
+	case existingVersion == "":
+		result.Action = ApplyCreated
+	case applied.GetResourceVersion() == existingVersion:
+		result.Action = ApplyUnchanged
+	default:
+		result.Action = ApplyConfigured
+	}
+	return result
+}
+
+// resourceFor resolves obj's REST mapping and returns the dynamic client for
+// it, defaulting the namespace of namespaced objects and clearing it on
+// cluster-scoped ones
+func resourceFor(dc dynamic.Interface, mapper *restmapper.DeferredDiscoveryRESTMapper, obj *unstructured.Unstructured, defaultNamespace string) (dynamic.ResourceInterface, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		// The CRD may have been created earlier in this same manifest
+		mapper.Reset()
+		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unknown resource type: %v", err)
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		obj.SetNamespace("")
+		return dc.Resource(mapping.Resource), nil
+	}
+	if obj.GetNamespace() == "" {
+		if defaultNamespace == "" {
+			defaultNamespace = "default"
+		}
+		obj.SetNamespace(defaultNamespace)
+	}
+	return dc.Resource(mapping.Resource).Namespace(obj.GetNamespace()), nil
+}
@@ -0,0 +1,472 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// HPASpec is the structured form of a HorizontalPodAutoscaler used to create
+// and edit one. Utilization targets are percentages of the pods' requests.
+type HPASpec struct {
+	Name                   string `json:"name"`
+	TargetKind             string `json:"target_kind"` // Deployment (default), StatefulSet or ReplicaSet
+	TargetName             string `json:"target_name"`
+	MinReplicas            int32  `json:"min_replicas"`
+	MaxReplicas            int32  `json:"max_replicas"`
+	TargetCPU              *int32 `json:"target_cpu_percent,omitempty"`
+	TargetMemory           *int32 `json:"target_memory_percent,omitempty"`
+	ScaleDownStabilization *int32 `json:"scale_down_stabilization_seconds,omitempty"`
+}
+
+// GeneratedHPA is an HPA generated for a workload, with what to check before
+// relying on it
+type GeneratedHPA struct {
+	YAML     string   `json:"yaml"`
+	Warnings []string `json:"warnings"`
+	Created  bool     `json:"created"`
+}
+
+// hpaTargetKinds are the scale targets an HPASpec accepts, with their API version
+var hpaTargetKinds = map[string]string{
+	"Deployment":  "apps/v1",
+	"StatefulSet": "apps/v1",
+	"ReplicaSet":  "apps/v1",
+}
+
+// apply sets the fields of s on hpa, leaving any other metrics and behavior alone
+func (s HPASpec) apply(hpa *autoscalingv2.HorizontalPodAutoscaler) error {
+	if s.TargetKind == "" {
+		s.TargetKind = "Deployment"
+	}
+	apiVersion, ok := hpaTargetKinds[s.TargetKind]
+	if !ok {
+		return fmt.Errorf("unsupported target kind %q", s.TargetKind)
+	}
+	if s.TargetName == "" {
+		return fmt.Errorf("target_name is required")
+	}
+	if s.MinReplicas < 1 {
+		s.MinReplicas = 1
+	}
+	if s.MaxReplicas < s.MinReplicas {
+		return fmt.Errorf("max_replicas must be at least min_replicas (%d)", s.MinReplicas)
+	}
+
+	hpa.Spec.ScaleTargetRef = autoscalingv2.CrossVersionObjectReference{
+		APIVersion: apiVersion,
+		Kind:       s.TargetKind,
+		Name:       s.TargetName,
+	}
+	hpa.Spec.MinReplicas = &s.MinReplicas
+	hpa.Spec.MaxReplicas = s.MaxReplicas
+	setResourceMetric(hpa, corev1.ResourceCPU, s.TargetCPU)
+	setResourceMetric(hpa, corev1.ResourceMemory, s.TargetMemory)
+	if len(hpa.Spec.Metrics) == 0 {
+		return fmt.Errorf("set target_cpu_percent or target_memory_percent")
+	}
+	if s.ScaleDownStabilization != nil {
+		if hpa.Spec.Behavior == nil {
+			hpa.Spec.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{}
+		}
+		if hpa.Spec.Behavior.ScaleDown == nil {
+			hpa.Spec.Behavior.ScaleDown = &autoscalingv2.HPAScalingRules{}
+		}
+		hpa.Spec.Behavior.ScaleDown.StabilizationWindowSeconds = s.ScaleDownStabilization
+	}
+	return nil
+}
+
+// setResourceMetric sets, or with a nil target removes, the average
+// utilization metric of a resource
+func setResourceMetric(hpa *autoscalingv2.HorizontalPodAutoscaler, resource corev1.ResourceName, target *int32) {
+	metrics := hpa.Spec.Metrics[:0]
+	for _, m := range hpa.Spec.Metrics {
+		if m.Type != autoscalingv2.ResourceMetricSourceType || m.Resource == nil || m.Resource.Name != resource {
+			metrics = append(metrics, m)
+		}
+	}
+	if target != nil {
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: resource,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: target,
+				},
+			},
+		})
+	}
+	hpa.Spec.Metrics = metrics
+}
+
+func hpaDetail(hpa *autoscalingv2.HorizontalPodAutoscaler) (*ResourceDetail, error) {
+	hpa.ManagedFields = nil
+	hpa.APIVersion, hpa.Kind = "autoscaling/v2", "HorizontalPodAutoscaler"
+	yamlBytes, err := yaml.Marshal(hpa)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceDetail{
+		Kind:      "HorizontalPodAutoscaler",
+		Name:      hpa.Name,
+		Namespace: hpa.Namespace,
+		YAML:      string(yamlBytes),
+	}, nil
+}
+
+// ========== HorizontalPodAutoscaler ==========
+
+func GetHPA(ctx context.Context, namespace, name string) (*ResourceDetail, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	hpa, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return hpaDetail(hpa)
+}
+
+// CreateHPA creates an HPA from spec, named after its target when spec has
+// no name. With dryRun the server validates it without persisting.
+func CreateHPA(ctx context.Context, namespace string, spec HPASpec, dryRun bool) (*ResourceDetail, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+	if err := spec.apply(hpa); err != nil {
+		return nil, err
+	}
+	hpa.Name, hpa.Namespace = spec.Name, namespace
+	if hpa.Name == "" {
+		hpa.Name = spec.TargetName
+	}
+
+	opts := metav1.CreateOptions{FieldManager: FieldManager}
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	created, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Create(ctx, hpa, opts)
+	if err != nil {
+		return nil, err
+	}
+	return hpaDetail(created)
+}
+
+// UpdateHPA applies spec to an existing HPA. Metrics other than CPU and
+// memory utilization, and scale-up behavior, are kept.
+func UpdateHPA(ctx context.Context, namespace, name string, spec HPASpec) (*ResourceDetail, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	client := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace)
+	hpa, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if err := spec.apply(hpa); err != nil {
+		return nil, err
+	}
+	updated, err := client.Update(ctx, hpa, metav1.UpdateOptions{FieldManager: FieldManager})
+	if err != nil {
+		return nil, err
+	}
+	return hpaDetail(updated)
+}
+
+func PatchHPA(ctx context.Context, namespace, name string, yamlContent string) error {
+	if clientset == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+
+	jsonBytes, err := yaml.YAMLToJSON([]byte(yamlContent))
+	if err != nil {
+		return fmt.Errorf("invalid YAML: %w", err)
+	}
+
+	_, err = clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Patch(ctx, name, types.StrategicMergePatchType, jsonBytes, metav1.PatchOptions{})
+	return err
+}
+
+func DeleteHPA(ctx context.Context, namespace, name string) error {
+	if clientset == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+	return clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// GenerateHPA builds an HPA for a Deployment. Unset fields are derived from
+// the Deployment: min_replicas from its replicas, max_replicas twice that and
+// a 80% CPU target. It is created only when create is set.
+func GenerateHPA(ctx context.Context, namespace, deployment string, spec HPASpec, create bool) (*GeneratedHPA, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	dep, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deployment, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GeneratedHPA{Warnings: []string{}}
+	spec.TargetKind, spec.TargetName = "Deployment", dep.Name
+	if spec.MinReplicas == 0 {
+		spec.MinReplicas = 1
+		if dep.Spec.Replicas != nil && *dep.Spec.Replicas > 1 {
+			spec.MinReplicas = *dep.Spec.Replicas
+		}
+	}
+	if spec.MaxReplicas == 0 {
+		spec.MaxReplicas = spec.MinReplicas * 2
+		if spec.MaxReplicas < 2 {
+			spec.MaxReplicas = 2
+		}
+	}
+	if spec.TargetCPU == nil && spec.TargetMemory == nil {
+		cpu := int32(80)
+		spec.TargetCPU = &cpu
+	}
+
+	for _, c := range dep.Spec.Template.Spec.Containers {
+		if _, ok := c.Resources.Requests[corev1.ResourceCPU]; !ok && spec.TargetCPU != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("container %s has no CPU request; CPU utilization cannot be computed", c.Name))
+		}
+		if _, ok := c.Resources.Requests[corev1.ResourceMemory]; !ok && spec.TargetMemory != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("container %s has no memory request; memory utilization cannot be computed", c.Name))
+		}
+	}
+	if existing, err := hpaFor(ctx, namespace, "Deployment", dep.Name); err == nil && existing != "" {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("HPA %s already targets this Deployment", existing))
+	}
+
+	// A server-side dry run fills in defaults and validates the result
+	detail, err := CreateHPA(ctx, namespace, spec, !create)
+	if err != nil {
+		return nil, err
+	}
+	result.YAML, result.Created = detail.YAML, create
+	return result, nil
+}
+
+// hpaFor returns the name of an HPA that scales kind/name, if any
+func hpaFor(ctx context.Context, namespace, kind, name string) (string, error) {
+	hpas, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+	for _, h := range hpas.Items {
+		if h.Spec.ScaleTargetRef.Kind == kind && h.Spec.ScaleTargetRef.Name == name {
+			return h.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// ========== VerticalPodAutoscaler ==========
+
+// vpaGVR is the VerticalPodAutoscaler resource, served only when the VPA
+// CRDs are installed
+var vpaGVR = schema.GroupVersionResource{Group: "autoscaling.k8s.io", Version: "v1", Resource: "verticalpodautoscalers"}
+
+// VPA update modes
+var vpaUpdateModes = map[string]bool{"Off": true, "Initial": true, "Recreate": true, "Auto": true}
+
+// VPAInfo summarizes a VerticalPodAutoscaler and its recommendations
+type VPAInfo struct {
+	Name            string              `json:"name"`
+	Namespace       string              `json:"namespace"`
+	TargetKind      string              `json:"target_kind"`
+	TargetName      string              `json:"target_name"`
+	UpdateMode      string              `json:"update_mode"`
+	Recommendations []VPARecommendation `json:"recommendations"`
+	CreatedAt       string              `json:"created_at"`
+	Age             string              `json:"age"`
+}
+
+// VPARecommendation is the recommended requests of one container
+type VPARecommendation struct {
+	Container  string            `json:"container"`
+	Target     map[string]string `json:"target"`
+	LowerBound map[string]string `json:"lower_bound,omitempty"`
+	UpperBound map[string]string `json:"upper_bound,omitempty"`
+}
+
+// VPASpec is the structured form of a VerticalPodAutoscaler used to create one
+type VPASpec struct {
+	Name       string `json:"name"`
+	TargetKind string `json:"target_kind"` // Deployment (default), StatefulSet, DaemonSet, ...
+	TargetName string `json:"target_name"`
+	UpdateMode string `json:"update_mode"` // Off (default), Initial, Recreate or Auto
+}
+
+// vpaClient returns the VerticalPodAutoscaler client, with a clear error
+// when the VPA CRDs are not installed
+func vpaClient(namespace string) (dynamic.ResourceInterface, error) {
+	dc, mapper, err := getDynamic()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mapper.KindFor(vpaGVR); err != nil {
+		return nil, fmt.Errorf("VerticalPodAutoscaler is not installed in this cluster")
+	}
+	return dc.Resource(vpaGVR).Namespace(namespace), nil
+}
+
+func ListVPAs(ctx context.Context, namespace string) ([]VPAInfo, error) {
+	client, err := vpaClient(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := client.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := []VPAInfo{}
+	for _, item := range list.Items {
+		result = append(result, vpaToInfo(&item))
+	}
+	return result, nil
+}
+
+func vpaToInfo(obj *unstructured.Unstructured) VPAInfo {
+	info := VPAInfo{
+		Name:            obj.GetName(),
+		Namespace:       obj.GetNamespace(),
+		Recommendations: []VPARecommendation{},
+		CreatedAt:       obj.GetCreationTimestamp().Format(time.RFC3339),
+		Age:             formatAge(obj.GetCreationTimestamp().Time),
+	}
+	info.TargetKind, _, _ = unstructured.NestedString(obj.Object, "spec", "targetRef", "kind")
+	info.TargetName, _, _ = unstructured.NestedString(obj.Object, "spec", "targetRef", "name")
+	info.UpdateMode, _, _ = unstructured.NestedString(obj.Object, "spec", "updatePolicy", "updateMode")
+	if info.UpdateMode == "" {
+		info.UpdateMode = "Auto"
+	}
+
+	recs, _, _ := unstructured.NestedSlice(obj.Object, "status", "recommendation", "containerRecommendations")
+	for _, r := range recs {
+		rec, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		c := VPARecommendation{}
+		c.Container, _, _ = unstructured.NestedString(rec, "containerName")
+		c.Target, _, _ = unstructured.NestedStringMap(rec, "target")
+		c.LowerBound, _, _ = unstructured.NestedStringMap(rec, "lowerBound")
+		c.UpperBound, _, _ = unstructured.NestedStringMap(rec, "upperBound")
+		info.Recommendations = append(info.Recommendations, c)
+	}
+	return info
+}
+
+func GetVPA(ctx context.Context, namespace, name string) (*ResourceDetail, error) {
+	client, err := vpaClient(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	unstructured.RemoveNestedField(obj.Object, "metadata", "managedFields")
+	yamlBytes, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceDetail{
+		Kind:      "VerticalPodAutoscaler",
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+		YAML:      string(yamlBytes),
+	}, nil
+}
+
+// CreateVPA creates a VPA for a workload, named after it when spec has no
+// name. The default update mode, Off, only records recommendations.
+func CreateVPA(ctx context.Context, namespace string, spec VPASpec) (*VPAInfo, error) {
+	client, err := vpaClient(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.TargetName == "" {
+		return nil, fmt.Errorf("target_name is required")
+	}
+	if spec.TargetKind == "" {
+		spec.TargetKind = "Deployment"
+	}
+	if spec.UpdateMode == "" {
+		spec.UpdateMode = "Off"
+	}
+	if !vpaUpdateModes[spec.UpdateMode] {
+		return nil, fmt.Errorf("update_mode must be Off, Initial, Recreate or Auto")
+	}
+	if spec.Name == "" {
+		spec.Name = spec.TargetName
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "autoscaling.k8s.io/v1",
+		"kind":       "VerticalPodAutoscaler",
+		"metadata":   map[string]interface{}{"name": spec.Name, "namespace": namespace},
+		"spec": map[string]interface{}{
+			"targetRef": map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       spec.TargetKind,
+				"name":       spec.TargetName,
+			},
+			"updatePolicy": map[string]interface{}{"updateMode": spec.UpdateMode},
+		},
+	}}
+	created, err := client.Create(ctx, obj, metav1.CreateOptions{FieldManager: FieldManager})
+	if err != nil {
+		return nil, err
+	}
+	info := vpaToInfo(created)
+	return &info, nil
+}
+
+// PatchVPA applies the YAML as a JSON merge patch; custom resources do not
+// support strategic merge
+func PatchVPA(ctx context.Context, namespace, name string, yamlContent string) error {
+	client, err := vpaClient(namespace)
+	if err != nil {
+		return err
+	}
+
+	jsonBytes, err := yaml.YAMLToJSON([]byte(yamlContent))
+	if err != nil {
+		return fmt.Errorf("invalid YAML: %w", err)
+	}
+
+	_, err = client.Patch(ctx, name, types.MergePatchType, jsonBytes, metav1.PatchOptions{FieldManager: FieldManager})
+	return err
+}
+
+func DeleteVPA(ctx context.Context, namespace, name string) error {
+	client, err := vpaClient(namespace)
+	if err != nil {
+		return err
+	}
+	return client.Delete(ctx, name, metav1.DeleteOptions{})
+}
@@ -0,0 +1,167 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// How long a list call waits for a cold informer before falling back to the API server
+const cacheSyncTimeout = 5 * time.Second
+
+var (
+	cacheEnabled bool
+	cacheResync  time.Duration
+)
+
+// InitCache enables serving list calls from the shared informer cache. Informers
+// are started lazily per kind on first use and resync every resync period.
+// Must be called after InitClient and before the first list or watch.
+func InitCache(resync time.Duration) error {
+	if clientset == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+	if resync < 0 {
+		return fmt.Errorf("resync period must not be negative")
+	}
+
+	informerMu.Lock()
+	defer informerMu.Unlock()
+	if informerFactory != nil {
+		return fmt.Errorf("informer factory already started")
+	}
+	cacheEnabled = true
+	cacheResync = resync
+	return nil
+}
+
+// CacheEnabled reports whether list calls may be served from the informer cache
+func CacheEnabled() bool {
+	return cacheEnabled
+}
+
+// cacheContinuePrefix marks continue tokens handed out by the cache. They
+// name the last key returned; the API server's own tokens are opaque and
+// only it can resume them.
+const cacheContinuePrefix = "gagos-cache:"
+
+func cacheContinueToken(key string) string {
+	return cacheContinuePrefix + base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// cacheContinueKey returns the key a cache continue token resumes after
+func cacheContinueKey(token string) (string, bool) {
+	encoded, ok := strings.CutPrefix(token, cacheContinuePrefix)
+	if !ok {
+		return "", false
+	}
+	key, err := base64.RawURLEncoding.DecodeString(encoded)
+	return string(key), err == nil
+}
+
+// listFromCache returns the converted objects of kind from the informer
+// cache, a page of them when opts has a limit, and the continue token for
+// the next page. ok is false when the request must go to the API server
+// instead: the cache is off, the caller uses a field selector or resumes an
+// API server list, or the informer has not synced in time.
+func listFromCache(ctx context.Context, kind, namespace string, opts ListOptions) (items []interface{}, next string, ok bool) {
+	if !cacheEnabled || opts.FieldSelector != "" {
+		return nil, "", false
+	}
+	var after string
+	if opts.Continue != "" {
+		if after, ok = cacheContinueKey(opts.Continue); !ok {
+			return nil, "", false
+		}
+	}
+	wk, known := watchKinds[kind]
+	if !known {
+		return nil, "", false
+	}
+
+	selector := labels.Everything()
+	if opts.LabelSelector != "" {
+		parsed, err := labels.Parse(opts.LabelSelector)
+		if err != nil {
+			// Let the API server report the bad selector
+			return nil, "", false
+		}
+		selector = parsed
+	}
+
+	factory := sharedInformerFactory()
+	informer := wk.informer(factory)
+	factory.Start(informerStop)
+
+	syncCtx, cancel := context.WithTimeout(ctx, cacheSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), informer.HasSynced) {
+		return nil, "", false
+	}
+
+	var objs []interface{}
+	if namespace != "" {
+		var err error
+		objs, err = informer.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+		if err != nil {
+			return nil, "", false
+		}
+	} else {
+		objs = informer.GetIndexer().List()
+	}
+
+	type keyed struct {
+		key  string
+		info interface{}
+	}
+	matched := make([]keyed, 0, len(objs))
+	for _, obj := range objs {
+		accessor, err := meta.Accessor(obj)
+		if err != nil || !selector.Matches(labels.Set(accessor.GetLabels())) {
+			continue
+		}
+		key := accessor.GetNamespace() + "/" + accessor.GetName()
+		if after != "" && key <= after {
+			continue
+		}
+		info, converted := wk.convert(obj)
+		if !converted {
+			continue
+		}
+		matched = append(matched, keyed{key: key, info: info})
+	}
+
+	// Match the API server's ordering, which is its storage keys': namespace/name
+	sort.Slice(matched, func(i, j int) bool { return matched[i].key < matched[j].key })
+
+	if opts.Limit > 0 && int64(len(matched)) > opts.Limit {
+		matched = matched[:opts.Limit]
+		next = cacheContinueToken(matched[len(matched)-1].key)
+	}
+	items = make([]interface{}, len(matched))
+	for i, m := range matched {
+		items[i] = m.info
+	}
+	return items, next, true
+}
+
+// cachedAs converts cache results back to the typed Info slice
+func cachedAs[T any](items []interface{}) []T {
+	var result []T
+	for _, item := range items {
+		if v, ok := item.(T); ok {
+			result = append(result, v)
+		}
+	}
+	return result
+}
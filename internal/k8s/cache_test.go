@@ -0,0 +1,109 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testCachePods lists pods through ListPods and returns namespace/name
+// of each, following continue tokens until the last page
+func testCachePods(t *testing.T, namespace string, opts ListOptions) []string {
+	t.Helper()
+	var names []string
+	for pages := 0; ; pages++ {
+		pods, next, err := ListPods(context.Background(), namespace, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opts.Limit > 0 && int64(len(pods)) > opts.Limit {
+			t.Fatalf("page of %d pods, limit %d", len(pods), opts.Limit)
+		}
+		for _, p := range pods {
+			names = append(names, p.Namespace+"/"+p.Name)
+		}
+		if next == "" {
+			return names
+		}
+		if pages > 20 {
+			t.Fatal("continue tokens never end")
+		}
+		opts.Continue = next
+	}
+}
+
+func TestListFromCacheMatchesAPIServer(t *testing.T) {
+	pod := func(namespace, name, app string) runtime.Object {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: namespace, Labels: map[string]string{"app": app},
+		}}
+	}
+	// Added out of order; the fake clientset, like the API server, lists
+	// in namespace/name order
+	SetClient(fake.NewSimpleClientset(
+		pod("prod", "web-1", "web"),
+		pod("default", "web-2", "web"),
+		pod("kube-system", "dns", "dns"),
+		pod("default", "db-1", "db"),
+		pod("prod", "api", "api"),
+		pod("default", "web-1", "web"),
+	), nil)
+	stop := make(chan struct{})
+	defer func() {
+		close(stop)
+		informerMu.Lock()
+		informerFactory = nil
+		informerMu.Unlock()
+		cacheEnabled = false
+		SetClient(nil, nil)
+	}()
+
+	type listing struct {
+		namespace string
+		selector  string
+	}
+	listings := []listing{
+		{"", ""},
+		{"", "app=web"},
+		{"", "app!=web"},
+		{"", "app in (web,db)"},
+		{"default", ""},
+		{"default", "app=web"},
+		{"prod", "app=nope"},
+	}
+	fromAPI := map[listing][]string{}
+	for _, l := range listings {
+		fromAPI[l] = testCachePods(t, l.namespace, ListOptions{LabelSelector: l.selector})
+	}
+
+	informerStop = stop
+	if err := InitCache(0); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range listings {
+		want := fromAPI[l]
+		for _, limit := range []int64{0, 1, 2, 4} {
+			got := testCachePods(t, l.namespace, ListOptions{LabelSelector: l.selector, Limit: limit})
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%q %q limit %d: cache %v, API server %v", l.namespace, l.selector, limit, got, want)
+			}
+		}
+	}
+
+	// A continue token from the API server goes back to the API server
+	if _, _, ok := listFromCache(context.Background(), "pods", "", ListOptions{Continue: "eyJ2IjoibWV0YS5rOHMuaW8vdjEifQ"}); ok {
+		t.Error("cache resumed an API server continue token")
+	}
+	// and so does a field selector
+	if _, _, ok := listFromCache(context.Background(), "pods", "", ListOptions{FieldSelector: "status.phase=Running"}); ok {
+		t.Error("cache answered a field selector")
+	}
+}
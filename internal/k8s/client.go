@@ -10,6 +10,14 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/gaga951/gagos/internal/guard"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -17,8 +25,12 @@ import (
 )
 
 var (
-	clientset  *kubernetes.Clientset
+	clientset  kubernetes.Interface
 	restConfig *rest.Config
+
+	// apiGuard trips when the API server keeps failing so callers fail fast
+	// instead of piling more requests onto it
+	apiGuard *guard.Guard
 )
 
 func InitClient() error {
@@ -39,23 +51,88 @@ func InitClient() error {
 		}
 	}
 
-	clientset, err = kubernetes.NewForConfig(restConfig)
+	// Client-side throttling is done by client-go itself; the guard only
+	// carries the circuit breaker. Tune with GAGOS_K8S_RATE_LIMIT,
+	// GAGOS_K8S_RATE_BURST, GAGOS_K8S_BREAKER_THRESHOLD and GAGOS_K8S_BREAKER_COOLDOWN.
+	guardCfg := guard.ConfigFromEnv("GAGOS_K8S", guard.Config{
+		RPS:              20,
+		Burst:            40,
+		FailureThreshold: 5,
+		Cooldown:         30 * time.Second,
+	})
+	restConfig.QPS = float32(guardCfg.RPS)
+	restConfig.Burst = guardCfg.Burst
+	guardCfg.RPS = 0
+	apiGuard = guard.New("kubernetes", guardCfg)
+	restConfig.Wrap(apiGuard.RoundTripper)
+
+	client, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
 		return fmt.Errorf("failed to create k8s client: %w", err)
 	}
+	clientset = client
 
 	return nil
 }
 
-func GetClient() *kubernetes.Clientset {
+// SetClient replaces the client, e.g. with a fake clientset for tests and
+// the fake mode
+func SetClient(client kubernetes.Interface, config *rest.Config) {
+	clientset = client
+	restConfig = config
+}
+
+func GetClient() kubernetes.Interface {
 	return clientset
 }
 
+// CoreREST returns the core/v1 REST client of client for raw requests such
+// as exec, attach and node proxies. A fake clientset has none.
+func CoreREST(client kubernetes.Interface) (*rest.RESTClient, error) {
+	if client == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	rc, ok := client.CoreV1().RESTClient().(*rest.RESTClient)
+	if !ok || rc == nil {
+		return nil, fmt.Errorf("kubernetes client does not support raw requests")
+	}
+	return rc, nil
+}
+
+// APIStatus returns the API server circuit breaker state, or nil before InitClient
+func APIStatus() *guard.Status {
+	if apiGuard == nil {
+		return nil
+	}
+	status := apiGuard.Status()
+	status.RPS = float64(restConfig.QPS)
+	status.Burst = restConfig.Burst
+	return &status
+}
+
 // GetConfig returns the rest.Config for creating additional clients (e.g., metrics)
 func GetConfig() *rest.Config {
 	return restConfig
 }
 
+// ListOptions narrows and pages a list call. The zero value lists everything.
+// When Limit is set the returned continue token fetches the next page.
+type ListOptions struct {
+	Limit         int64
+	Continue      string
+	LabelSelector string
+	FieldSelector string
+}
+
+func (o ListOptions) toListOptions() metav1.ListOptions {
+	return metav1.ListOptions{
+		Limit:         o.Limit,
+		Continue:      o.Continue,
+		LabelSelector: o.LabelSelector,
+		FieldSelector: o.FieldSelector,
+	}
+}
+
 type NamespaceInfo struct {
 	Name      string            `json:"name"`
 	Status    string            `json:"status"`
@@ -64,28 +141,36 @@ type NamespaceInfo struct {
 	Age       string            `json:"age"`
 }
 
-func ListNamespaces(ctx context.Context) ([]NamespaceInfo, error) {
+func ListNamespaces(ctx context.Context, opts ListOptions) ([]NamespaceInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	if items, next, ok := listFromCache(ctx, "namespaces", "", opts); ok {
+		return cachedAs[NamespaceInfo](items), next, nil
 	}
 
-	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []NamespaceInfo
-	for _, ns := range namespaces.Items {
-		result = append(result, NamespaceInfo{
-			Name:      ns.Name,
-			Status:    string(ns.Status.Phase),
-			Labels:    ns.Labels,
-			CreatedAt: ns.CreationTimestamp.Format(time.RFC3339),
-			Age:       formatAge(ns.CreationTimestamp.Time),
-		})
+	for i := range namespaces.Items {
+		result = append(result, namespaceToInfo(&namespaces.Items[i]))
 	}
 
-	return result, nil
+	return result, namespaces.Continue, nil
+}
+
+func namespaceToInfo(ns *corev1.Namespace) NamespaceInfo {
+	return NamespaceInfo{
+		Name:      ns.Name,
+		Status:    string(ns.Status.Phase),
+		Labels:    ns.Labels,
+		CreatedAt: ns.CreationTimestamp.Format(time.RFC3339),
+		Age:       formatAge(ns.CreationTimestamp.Time),
+	}
 }
 
 type PodInfo struct {
@@ -110,61 +195,69 @@ type ContainerInfo struct {
 	State        string `json:"state"`
 }
 
-func ListPods(ctx context.Context, namespace string) ([]PodInfo, error) {
+func ListPods(ctx context.Context, namespace string, opts ListOptions) ([]PodInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
 	}
 
-	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if items, next, ok := listFromCache(ctx, "pods", namespace, opts); ok {
+		return cachedAs[PodInfo](items), next, nil
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []PodInfo
-	for _, pod := range pods.Items {
-		var containers []ContainerInfo
-		var totalRestarts int32
-		readyCount := 0
-
-		for _, cs := range pod.Status.ContainerStatuses {
-			state := "unknown"
-			if cs.State.Running != nil {
-				state = "running"
-			} else if cs.State.Waiting != nil {
-				state = cs.State.Waiting.Reason
-			} else if cs.State.Terminated != nil {
-				state = cs.State.Terminated.Reason
-			}
+	for i := range pods.Items {
+		result = append(result, podToInfo(&pods.Items[i]))
+	}
 
-			containers = append(containers, ContainerInfo{
-				Name:         cs.Name,
-				Image:        cs.Image,
-				Ready:        cs.Ready,
-				RestartCount: cs.RestartCount,
-				State:        state,
-			})
-			totalRestarts += cs.RestartCount
-			if cs.Ready {
-				readyCount++
-			}
+	return result, pods.Continue, nil
+}
+
+func podToInfo(pod *corev1.Pod) PodInfo {
+	var containers []ContainerInfo
+	var totalRestarts int32
+	readyCount := 0
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		state := "unknown"
+		if cs.State.Running != nil {
+			state = "running"
+		} else if cs.State.Waiting != nil {
+			state = cs.State.Waiting.Reason
+		} else if cs.State.Terminated != nil {
+			state = cs.State.Terminated.Reason
 		}
 
-		result = append(result, PodInfo{
-			Name:       pod.Name,
-			Namespace:  pod.Namespace,
-			Status:     string(pod.Status.Phase),
-			Ready:      fmt.Sprintf("%d/%d", readyCount, len(pod.Spec.Containers)),
-			Restarts:   totalRestarts,
-			Node:       pod.Spec.NodeName,
-			IP:         pod.Status.PodIP,
-			Labels:     pod.Labels,
-			CreatedAt:  pod.CreationTimestamp.Format(time.RFC3339),
-			Age:        formatAge(pod.CreationTimestamp.Time),
-			Containers: containers,
+		containers = append(containers, ContainerInfo{
+			Name:         cs.Name,
+			Image:        cs.Image,
+			Ready:        cs.Ready,
+			RestartCount: cs.RestartCount,
+			State:        state,
 		})
+		totalRestarts += cs.RestartCount
+		if cs.Ready {
+			readyCount++
+		}
 	}
 
-	return result, nil
+	return PodInfo{
+		Name:       pod.Name,
+		Namespace:  pod.Namespace,
+		Status:     string(pod.Status.Phase),
+		Ready:      fmt.Sprintf("%d/%d", readyCount, len(pod.Spec.Containers)),
+		Restarts:   totalRestarts,
+		Node:       pod.Spec.NodeName,
+		IP:         pod.Status.PodIP,
+		Labels:     pod.Labels,
+		CreatedAt:  pod.CreationTimestamp.Format(time.RFC3339),
+		Age:        formatAge(pod.CreationTimestamp.Time),
+		Containers: containers,
+	}
 }
 
 type ServiceInfo struct {
@@ -188,51 +281,59 @@ type ServicePort struct {
 	Protocol   string `json:"protocol"`
 }
 
-func ListServices(ctx context.Context, namespace string) ([]ServiceInfo, error) {
+func ListServices(ctx context.Context, namespace string, opts ListOptions) ([]ServiceInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	if items, next, ok := listFromCache(ctx, "services", namespace, opts); ok {
+		return cachedAs[ServiceInfo](items), next, nil
 	}
 
-	services, err := clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	services, err := clientset.CoreV1().Services(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []ServiceInfo
-	for _, svc := range services.Items {
-		var ports []ServicePort
-		for _, p := range svc.Spec.Ports {
-			ports = append(ports, ServicePort{
-				Name:       p.Name,
-				Port:       p.Port,
-				TargetPort: p.TargetPort.String(),
-				NodePort:   p.NodePort,
-				Protocol:   string(p.Protocol),
-			})
-		}
+	for i := range services.Items {
+		result = append(result, serviceToInfo(&services.Items[i]))
+	}
 
-		externalIP := ""
-		if len(svc.Spec.ExternalIPs) > 0 {
-			externalIP = svc.Spec.ExternalIPs[0]
-		} else if svc.Spec.Type == "LoadBalancer" && len(svc.Status.LoadBalancer.Ingress) > 0 {
-			externalIP = svc.Status.LoadBalancer.Ingress[0].IP
-		}
+	return result, services.Continue, nil
+}
 
-		result = append(result, ServiceInfo{
-			Name:       svc.Name,
-			Namespace:  svc.Namespace,
-			Type:       string(svc.Spec.Type),
-			ClusterIP:  svc.Spec.ClusterIP,
-			ExternalIP: externalIP,
-			Ports:      ports,
-			Labels:     svc.Labels,
-			Selector:   svc.Spec.Selector,
-			CreatedAt:  svc.CreationTimestamp.Format(time.RFC3339),
-			Age:        formatAge(svc.CreationTimestamp.Time),
+func serviceToInfo(svc *corev1.Service) ServiceInfo {
+	var ports []ServicePort
+	for _, p := range svc.Spec.Ports {
+		ports = append(ports, ServicePort{
+			Name:       p.Name,
+			Port:       p.Port,
+			TargetPort: p.TargetPort.String(),
+			NodePort:   p.NodePort,
+			Protocol:   string(p.Protocol),
 		})
 	}
 
-	return result, nil
+	externalIP := ""
+	if len(svc.Spec.ExternalIPs) > 0 {
+		externalIP = svc.Spec.ExternalIPs[0]
+	} else if svc.Spec.Type == "LoadBalancer" && len(svc.Status.LoadBalancer.Ingress) > 0 {
+		externalIP = svc.Status.LoadBalancer.Ingress[0].IP
+	}
+
+	return ServiceInfo{
+		Name:       svc.Name,
+		Namespace:  svc.Namespace,
+		Type:       string(svc.Spec.Type),
+		ClusterIP:  svc.Spec.ClusterIP,
+		ExternalIP: externalIP,
+		Ports:      ports,
+		Labels:     svc.Labels,
+		Selector:   svc.Spec.Selector,
+		CreatedAt:  svc.CreationTimestamp.Format(time.RFC3339),
+		Age:        formatAge(svc.CreationTimestamp.Time),
+	}
 }
 
 type DeploymentInfo struct {
@@ -246,31 +347,43 @@ type DeploymentInfo struct {
 	Age        string            `json:"age"`
 }
 
-func ListDeployments(ctx context.Context, namespace string) ([]DeploymentInfo, error) {
+func ListDeployments(ctx context.Context, namespace string, opts ListOptions) ([]DeploymentInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
 	}
 
-	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if items, next, ok := listFromCache(ctx, "deployments", namespace, opts); ok {
+		return cachedAs[DeploymentInfo](items), next, nil
+	}
+
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []DeploymentInfo
-	for _, dep := range deployments.Items {
-		result = append(result, DeploymentInfo{
-			Name:      dep.Name,
-			Namespace: dep.Namespace,
-			Ready:     fmt.Sprintf("%d/%d", dep.Status.ReadyReplicas, *dep.Spec.Replicas),
-			UpToDate:  dep.Status.UpdatedReplicas,
-			Available: dep.Status.AvailableReplicas,
-			Labels:    dep.Labels,
-			CreatedAt: dep.CreationTimestamp.Format(time.RFC3339),
-			Age:       formatAge(dep.CreationTimestamp.Time),
-		})
+	for i := range deployments.Items {
+		result = append(result, deploymentToInfo(&deployments.Items[i]))
 	}
 
-	return result, nil
+	return result, deployments.Continue, nil
+}
+
+func deploymentToInfo(dep *appsv1.Deployment) DeploymentInfo {
+	replicas := int32(0)
+	if dep.Spec.Replicas != nil {
+		replicas = *dep.Spec.Replicas
+	}
+	return DeploymentInfo{
+		Name:      dep.Name,
+		Namespace: dep.Namespace,
+		Ready:     fmt.Sprintf("%d/%d", dep.Status.ReadyReplicas, replicas),
+		UpToDate:  dep.Status.UpdatedReplicas,
+		Available: dep.Status.AvailableReplicas,
+		Labels:    dep.Labels,
+		CreatedAt: dep.CreationTimestamp.Format(time.RFC3339),
+		Age:       formatAge(dep.CreationTimestamp.Time),
+	}
 }
 
 type NodeInfo struct {
@@ -288,71 +401,79 @@ type NodeInfo struct {
 	Age              string            `json:"age"`
 }
 
-func ListNodes(ctx context.Context) ([]NodeInfo, error) {
+func ListNodes(ctx context.Context, opts ListOptions) ([]NodeInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
 	}
 
-	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if items, next, ok := listFromCache(ctx, "nodes", "", opts); ok {
+		return cachedAs[NodeInfo](items), next, nil
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []NodeInfo
-	for _, node := range nodes.Items {
-		status := "Unknown"
-		for _, cond := range node.Status.Conditions {
-			if cond.Type == "Ready" {
-				if cond.Status == "True" {
-					status = "Ready"
-				} else {
-					status = "NotReady"
-				}
-				break
+	for i := range nodes.Items {
+		result = append(result, nodeToInfo(&nodes.Items[i]))
+	}
+
+	return result, nodes.Continue, nil
+}
+
+func nodeToInfo(node *corev1.Node) NodeInfo {
+	status := "Unknown"
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == "Ready" {
+			if cond.Status == "True" {
+				status = "Ready"
+			} else {
+				status = "NotReady"
 			}
+			break
 		}
+	}
 
-		var roles []string
-		for label := range node.Labels {
-			if label == "node-role.kubernetes.io/master" || label == "node-role.kubernetes.io/control-plane" {
-				roles = append(roles, "control-plane")
-			}
-			if label == "node-role.kubernetes.io/worker" {
-				roles = append(roles, "worker")
-			}
+	var roles []string
+	for label := range node.Labels {
+		if label == "node-role.kubernetes.io/master" || label == "node-role.kubernetes.io/control-plane" {
+			roles = append(roles, "control-plane")
 		}
-		if len(roles) == 0 {
-			roles = []string{"worker"}
+		if label == "node-role.kubernetes.io/worker" {
+			roles = append(roles, "worker")
 		}
+	}
+	if len(roles) == 0 {
+		roles = []string{"worker"}
+	}
 
-		internalIP := ""
-		externalIP := ""
-		for _, addr := range node.Status.Addresses {
-			if addr.Type == "InternalIP" {
-				internalIP = addr.Address
-			}
-			if addr.Type == "ExternalIP" {
-				externalIP = addr.Address
-			}
+	internalIP := ""
+	externalIP := ""
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == "InternalIP" {
+			internalIP = addr.Address
+		}
+		if addr.Type == "ExternalIP" {
+			externalIP = addr.Address
 		}
-
-		result = append(result, NodeInfo{
-			Name:             node.Name,
-			Status:           status,
-			Roles:            roles,
-			InternalIP:       internalIP,
-			ExternalIP:       externalIP,
-			OS:               node.Status.NodeInfo.OSImage,
-			KernelVersion:    node.Status.NodeInfo.KernelVersion,
-			ContainerRuntime: node.Status.NodeInfo.ContainerRuntimeVersion,
-			KubeletVersion:   node.Status.NodeInfo.KubeletVersion,
-			Labels:           node.Labels,
-			CreatedAt:        node.CreationTimestamp.Format(time.RFC3339),
-			Age:              formatAge(node.CreationTimestamp.Time),
-		})
 	}
 
-	return result, nil
+	return NodeInfo{
+		Name:             node.Name,
+		Status:           status,
+		Roles:            roles,
+		InternalIP:       internalIP,
+		ExternalIP:       externalIP,
+		OS:               node.Status.NodeInfo.OSImage,
+		KernelVersion:    node.Status.NodeInfo.KernelVersion,
+		ContainerRuntime: node.Status.NodeInfo.ContainerRuntimeVersion,
+		KubeletVersion:   node.Status.NodeInfo.KubeletVersion,
+		Labels:           node.Labels,
+		CreatedAt:        node.CreationTimestamp.Format(time.RFC3339),
+		Age:              formatAge(node.CreationTimestamp.Time),
+	}
 }
 
 func formatAge(t time.Time) string {
@@ -383,28 +504,36 @@ type ConfigMapInfo struct {
 	Age       string            `json:"age"`
 }
 
-func ListConfigMaps(ctx context.Context, namespace string) ([]ConfigMapInfo, error) {
+func ListConfigMaps(ctx context.Context, namespace string, opts ListOptions) ([]ConfigMapInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	if items, next, ok := listFromCache(ctx, "configmaps", namespace, opts); ok {
+		return cachedAs[ConfigMapInfo](items), next, nil
 	}
 
-	cms, err := clientset.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+	cms, err := clientset.CoreV1().ConfigMaps(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []ConfigMapInfo
-	for _, cm := range cms.Items {
-		result = append(result, ConfigMapInfo{
-			Name:      cm.Name,
-			Namespace: cm.Namespace,
-			Data:      len(cm.Data),
-			Labels:    cm.Labels,
-			CreatedAt: cm.CreationTimestamp.Format(time.RFC3339),
-			Age:       formatAge(cm.CreationTimestamp.Time),
-		})
+	for i := range cms.Items {
+		result = append(result, configMapToInfo(&cms.Items[i]))
+	}
+	return result, cms.Continue, nil
+}
+
+func configMapToInfo(cm *corev1.ConfigMap) ConfigMapInfo {
+	return ConfigMapInfo{
+		Name:      cm.Name,
+		Namespace: cm.Namespace,
+		Data:      len(cm.Data),
+		Labels:    cm.Labels,
+		CreatedAt: cm.CreationTimestamp.Format(time.RFC3339),
+		Age:       formatAge(cm.CreationTimestamp.Time),
 	}
-	return result, nil
 }
 
 type SecretInfo struct {
@@ -417,29 +546,37 @@ type SecretInfo struct {
 	Age       string            `json:"age"`
 }
 
-func ListSecrets(ctx context.Context, namespace string) ([]SecretInfo, error) {
+func ListSecrets(ctx context.Context, namespace string, opts ListOptions) ([]SecretInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	if items, next, ok := listFromCache(ctx, "secrets", namespace, opts); ok {
+		return cachedAs[SecretInfo](items), next, nil
 	}
 
-	secrets, err := clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	secrets, err := clientset.CoreV1().Secrets(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []SecretInfo
-	for _, s := range secrets.Items {
-		result = append(result, SecretInfo{
-			Name:      s.Name,
-			Namespace: s.Namespace,
-			Type:      string(s.Type),
-			Data:      len(s.Data),
-			Labels:    s.Labels,
-			CreatedAt: s.CreationTimestamp.Format(time.RFC3339),
-			Age:       formatAge(s.CreationTimestamp.Time),
-		})
+	for i := range secrets.Items {
+		result = append(result, secretToInfo(&secrets.Items[i]))
+	}
+	return result, secrets.Continue, nil
+}
+
+func secretToInfo(s *corev1.Secret) SecretInfo {
+	return SecretInfo{
+		Name:      s.Name,
+		Namespace: s.Namespace,
+		Type:      string(s.Type),
+		Data:      len(s.Data),
+		Labels:    s.Labels,
+		CreatedAt: s.CreationTimestamp.Format(time.RFC3339),
+		Age:       formatAge(s.CreationTimestamp.Time),
 	}
-	return result, nil
 }
 
 type ServiceAccountInfo struct {
@@ -451,28 +588,32 @@ type ServiceAccountInfo struct {
 	Age       string            `json:"age"`
 }
 
-func ListServiceAccounts(ctx context.Context, namespace string) ([]ServiceAccountInfo, error) {
+func ListServiceAccounts(ctx context.Context, namespace string, opts ListOptions) ([]ServiceAccountInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
 	}
 
-	sas, err := clientset.CoreV1().ServiceAccounts(namespace).List(ctx, metav1.ListOptions{})
+	sas, err := clientset.CoreV1().ServiceAccounts(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []ServiceAccountInfo
-	for _, sa := range sas.Items {
-		result = append(result, ServiceAccountInfo{
-			Name:      sa.Name,
-			Namespace: sa.Namespace,
-			Secrets:   len(sa.Secrets),
-			Labels:    sa.Labels,
-			CreatedAt: sa.CreationTimestamp.Format(time.RFC3339),
-			Age:       formatAge(sa.CreationTimestamp.Time),
-		})
+	for i := range sas.Items {
+		result = append(result, serviceAccountToInfo(&sas.Items[i]))
+	}
+	return result, sas.Continue, nil
+}
+
+func serviceAccountToInfo(sa *corev1.ServiceAccount) ServiceAccountInfo {
+	return ServiceAccountInfo{
+		Name:      sa.Name,
+		Namespace: sa.Namespace,
+		Secrets:   len(sa.Secrets),
+		Labels:    sa.Labels,
+		CreatedAt: sa.CreationTimestamp.Format(time.RFC3339),
+		Age:       formatAge(sa.CreationTimestamp.Time),
 	}
-	return result, nil
 }
 
 type PVInfo struct {
@@ -488,47 +629,55 @@ type PVInfo struct {
 	Age             string            `json:"age"`
 }
 
-func ListPersistentVolumes(ctx context.Context) ([]PVInfo, error) {
+func ListPersistentVolumes(ctx context.Context, opts ListOptions) ([]PVInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	if items, next, ok := listFromCache(ctx, "pvs", "", opts); ok {
+		return cachedAs[PVInfo](items), next, nil
 	}
 
-	pvs, err := clientset.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	pvs, err := clientset.CoreV1().PersistentVolumes().List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []PVInfo
-	for _, pv := range pvs.Items {
-		var accessModes []string
-		for _, am := range pv.Spec.AccessModes {
-			accessModes = append(accessModes, string(am))
-		}
+	for i := range pvs.Items {
+		result = append(result, pvToInfo(&pvs.Items[i]))
+	}
+	return result, pvs.Continue, nil
+}
 
-		claim := ""
-		if pv.Spec.ClaimRef != nil {
-			claim = pv.Spec.ClaimRef.Namespace + "/" + pv.Spec.ClaimRef.Name
-		}
+func pvToInfo(pv *corev1.PersistentVolume) PVInfo {
+	var accessModes []string
+	for _, am := range pv.Spec.AccessModes {
+		accessModes = append(accessModes, string(am))
+	}
 
-		capacity := ""
-		if qty, ok := pv.Spec.Capacity["storage"]; ok {
-			capacity = qty.String()
-		}
+	claim := ""
+	if pv.Spec.ClaimRef != nil {
+		claim = pv.Spec.ClaimRef.Namespace + "/" + pv.Spec.ClaimRef.Name
+	}
 
-		result = append(result, PVInfo{
-			Name:          pv.Name,
-			Capacity:      capacity,
-			AccessModes:   accessModes,
-			ReclaimPolicy: string(pv.Spec.PersistentVolumeReclaimPolicy),
-			Status:        string(pv.Status.Phase),
-			Claim:         claim,
-			StorageClass:  pv.Spec.StorageClassName,
-			Labels:        pv.Labels,
-			CreatedAt:     pv.CreationTimestamp.Format(time.RFC3339),
-			Age:           formatAge(pv.CreationTimestamp.Time),
-		})
+	capacity := ""
+	if qty, ok := pv.Spec.Capacity["storage"]; ok {
+		capacity = qty.String()
+	}
+
+	return PVInfo{
+		Name:          pv.Name,
+		Capacity:      capacity,
+		AccessModes:   accessModes,
+		ReclaimPolicy: string(pv.Spec.PersistentVolumeReclaimPolicy),
+		Status:        string(pv.Status.Phase),
+		Claim:         claim,
+		StorageClass:  pv.Spec.StorageClassName,
+		Labels:        pv.Labels,
+		CreatedAt:     pv.CreationTimestamp.Format(time.RFC3339),
+		Age:           formatAge(pv.CreationTimestamp.Time),
 	}
-	return result, nil
 }
 
 type PVCInfo struct {
@@ -544,47 +693,55 @@ type PVCInfo struct {
 	Age          string            `json:"age"`
 }
 
-func ListPersistentVolumeClaims(ctx context.Context, namespace string) ([]PVCInfo, error) {
+func ListPersistentVolumeClaims(ctx context.Context, namespace string, opts ListOptions) ([]PVCInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	if items, next, ok := listFromCache(ctx, "pvcs", namespace, opts); ok {
+		return cachedAs[PVCInfo](items), next, nil
 	}
 
-	pvcs, err := clientset.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	pvcs, err := clientset.CoreV1().PersistentVolumeClaims(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []PVCInfo
-	for _, pvc := range pvcs.Items {
-		var accessModes []string
-		for _, am := range pvc.Spec.AccessModes {
-			accessModes = append(accessModes, string(am))
-		}
+	for i := range pvcs.Items {
+		result = append(result, pvcToInfo(&pvcs.Items[i]))
+	}
+	return result, pvcs.Continue, nil
+}
 
-		capacity := ""
-		if qty, ok := pvc.Status.Capacity["storage"]; ok {
-			capacity = qty.String()
-		}
+func pvcToInfo(pvc *corev1.PersistentVolumeClaim) PVCInfo {
+	var accessModes []string
+	for _, am := range pvc.Spec.AccessModes {
+		accessModes = append(accessModes, string(am))
+	}
 
-		storageClass := ""
-		if pvc.Spec.StorageClassName != nil {
-			storageClass = *pvc.Spec.StorageClassName
-		}
+	capacity := ""
+	if qty, ok := pvc.Status.Capacity["storage"]; ok {
+		capacity = qty.String()
+	}
 
-		result = append(result, PVCInfo{
-			Name:         pvc.Name,
-			Namespace:    pvc.Namespace,
-			Status:       string(pvc.Status.Phase),
-			Volume:       pvc.Spec.VolumeName,
-			Capacity:     capacity,
-			AccessModes:  accessModes,
-			StorageClass: storageClass,
-			Labels:       pvc.Labels,
-			CreatedAt:    pvc.CreationTimestamp.Format(time.RFC3339),
-			Age:          formatAge(pvc.CreationTimestamp.Time),
-		})
+	storageClass := ""
+	if pvc.Spec.StorageClassName != nil {
+		storageClass = *pvc.Spec.StorageClassName
+	}
+
+	return PVCInfo{
+		Name:         pvc.Name,
+		Namespace:    pvc.Namespace,
+		Status:       string(pvc.Status.Phase),
+		Volume:       pvc.Spec.VolumeName,
+		Capacity:     capacity,
+		AccessModes:  accessModes,
+		StorageClass: storageClass,
+		Labels:       pvc.Labels,
+		CreatedAt:    pvc.CreationTimestamp.Format(time.RFC3339),
+		Age:          formatAge(pvc.CreationTimestamp.Time),
 	}
-	return result, nil
 }
 
 type IngressInfo struct {
@@ -598,51 +755,59 @@ type IngressInfo struct {
 	Age        string            `json:"age"`
 }
 
-func ListIngresses(ctx context.Context, namespace string) ([]IngressInfo, error) {
+func ListIngresses(ctx context.Context, namespace string, opts ListOptions) ([]IngressInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
 	}
 
-	ingresses, err := clientset.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+	if items, next, ok := listFromCache(ctx, "ingresses", namespace, opts); ok {
+		return cachedAs[IngressInfo](items), next, nil
+	}
+
+	ingresses, err := clientset.NetworkingV1().Ingresses(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []IngressInfo
-	for _, ing := range ingresses.Items {
-		var hosts []string
-		for _, rule := range ing.Spec.Rules {
-			if rule.Host != "" {
-				hosts = append(hosts, rule.Host)
-			}
-		}
+	for i := range ingresses.Items {
+		result = append(result, ingressToInfo(&ingresses.Items[i]))
+	}
+	return result, ingresses.Continue, nil
+}
 
-		class := ""
-		if ing.Spec.IngressClassName != nil {
-			class = *ing.Spec.IngressClassName
+func ingressToInfo(ing *networkingv1.Ingress) IngressInfo {
+	var hosts []string
+	for _, rule := range ing.Spec.Rules {
+		if rule.Host != "" {
+			hosts = append(hosts, rule.Host)
 		}
+	}
 
-		address := ""
-		if len(ing.Status.LoadBalancer.Ingress) > 0 {
-			if ing.Status.LoadBalancer.Ingress[0].IP != "" {
-				address = ing.Status.LoadBalancer.Ingress[0].IP
-			} else {
-				address = ing.Status.LoadBalancer.Ingress[0].Hostname
-			}
+	class := ""
+	if ing.Spec.IngressClassName != nil {
+		class = *ing.Spec.IngressClassName
+	}
+
+	address := ""
+	if len(ing.Status.LoadBalancer.Ingress) > 0 {
+		if ing.Status.LoadBalancer.Ingress[0].IP != "" {
+			address = ing.Status.LoadBalancer.Ingress[0].IP
+		} else {
+			address = ing.Status.LoadBalancer.Ingress[0].Hostname
 		}
+	}
 
-		result = append(result, IngressInfo{
-			Name:      ing.Name,
-			Namespace: ing.Namespace,
-			Class:     class,
-			Hosts:     hosts,
-			Address:   address,
-			Labels:    ing.Labels,
-			CreatedAt: ing.CreationTimestamp.Format(time.RFC3339),
-			Age:       formatAge(ing.CreationTimestamp.Time),
-		})
+	return IngressInfo{
+		Name:      ing.Name,
+		Namespace: ing.Namespace,
+		Class:     class,
+		Hosts:     hosts,
+		Address:   address,
+		Labels:    ing.Labels,
+		CreatedAt: ing.CreationTimestamp.Format(time.RFC3339),
+		Age:       formatAge(ing.CreationTimestamp.Time),
 	}
-	return result, nil
 }
 
 type DaemonSetInfo struct {
@@ -659,40 +824,48 @@ type DaemonSetInfo struct {
 	Age             string            `json:"age"`
 }
 
-func ListDaemonSets(ctx context.Context, namespace string) ([]DaemonSetInfo, error) {
+func ListDaemonSets(ctx context.Context, namespace string, opts ListOptions) ([]DaemonSetInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	if items, next, ok := listFromCache(ctx, "daemonsets", namespace, opts); ok {
+		return cachedAs[DaemonSetInfo](items), next, nil
 	}
 
-	dss, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	dss, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []DaemonSetInfo
-	for _, ds := range dss.Items {
-		nodeSelector := ""
-		if len(ds.Spec.Template.Spec.NodeSelector) > 0 {
-			for k, v := range ds.Spec.Template.Spec.NodeSelector {
-				nodeSelector += k + "=" + v + " "
-			}
+	for i := range dss.Items {
+		result = append(result, daemonSetToInfo(&dss.Items[i]))
+	}
+	return result, dss.Continue, nil
+}
+
+func daemonSetToInfo(ds *appsv1.DaemonSet) DaemonSetInfo {
+	nodeSelector := ""
+	if len(ds.Spec.Template.Spec.NodeSelector) > 0 {
+		for k, v := range ds.Spec.Template.Spec.NodeSelector {
+			nodeSelector += k + "=" + v + " "
 		}
+	}
 
-		result = append(result, DaemonSetInfo{
-			Name:         ds.Name,
-			Namespace:    ds.Namespace,
-			Desired:      ds.Status.DesiredNumberScheduled,
-			Current:      ds.Status.CurrentNumberScheduled,
-			Ready:        ds.Status.NumberReady,
-			UpToDate:     ds.Status.UpdatedNumberScheduled,
-			Available:    ds.Status.NumberAvailable,
-			NodeSelector: nodeSelector,
-			Labels:       ds.Labels,
-			CreatedAt:    ds.CreationTimestamp.Format(time.RFC3339),
-			Age:          formatAge(ds.CreationTimestamp.Time),
-		})
+	return DaemonSetInfo{
+		Name:         ds.Name,
+		Namespace:    ds.Namespace,
+		Desired:      ds.Status.DesiredNumberScheduled,
+		Current:      ds.Status.CurrentNumberScheduled,
+		Ready:        ds.Status.NumberReady,
+		UpToDate:     ds.Status.UpdatedNumberScheduled,
+		Available:    ds.Status.NumberAvailable,
+		NodeSelector: nodeSelector,
+		Labels:       ds.Labels,
+		CreatedAt:    ds.CreationTimestamp.Format(time.RFC3339),
+		Age:          formatAge(ds.CreationTimestamp.Time),
 	}
-	return result, nil
 }
 
 type StatefulSetInfo struct {
@@ -705,33 +878,41 @@ type StatefulSetInfo struct {
 	Age        string            `json:"age"`
 }
 
-func ListStatefulSets(ctx context.Context, namespace string) ([]StatefulSetInfo, error) {
+func ListStatefulSets(ctx context.Context, namespace string, opts ListOptions) ([]StatefulSetInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	if items, next, ok := listFromCache(ctx, "statefulsets", namespace, opts); ok {
+		return cachedAs[StatefulSetInfo](items), next, nil
 	}
 
-	sss, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	sss, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []StatefulSetInfo
-	for _, ss := range sss.Items {
-		replicas := int32(0)
-		if ss.Spec.Replicas != nil {
-			replicas = *ss.Spec.Replicas
-		}
-		result = append(result, StatefulSetInfo{
-			Name:      ss.Name,
-			Namespace: ss.Namespace,
-			Ready:     fmt.Sprintf("%d/%d", ss.Status.ReadyReplicas, replicas),
-			Replicas:  replicas,
-			Labels:    ss.Labels,
-			CreatedAt: ss.CreationTimestamp.Format(time.RFC3339),
-			Age:       formatAge(ss.CreationTimestamp.Time),
-		})
+	for i := range sss.Items {
+		result = append(result, statefulSetToInfo(&sss.Items[i]))
+	}
+	return result, sss.Continue, nil
+}
+
+func statefulSetToInfo(ss *appsv1.StatefulSet) StatefulSetInfo {
+	replicas := int32(0)
+	if ss.Spec.Replicas != nil {
+		replicas = *ss.Spec.Replicas
+	}
+	return StatefulSetInfo{
+		Name:      ss.Name,
+		Namespace: ss.Namespace,
+		Ready:     fmt.Sprintf("%d/%d", ss.Status.ReadyReplicas, replicas),
+		Replicas:  replicas,
+		Labels:    ss.Labels,
+		CreatedAt: ss.CreationTimestamp.Format(time.RFC3339),
+		Age:       formatAge(ss.CreationTimestamp.Time),
 	}
-	return result, nil
 }
 
 type JobInfo struct {
@@ -745,48 +926,56 @@ type JobInfo struct {
 	Age          string            `json:"age"`
 }
 
-func ListJobs(ctx context.Context, namespace string) ([]JobInfo, error) {
+func ListJobs(ctx context.Context, namespace string, opts ListOptions) ([]JobInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	if items, next, ok := listFromCache(ctx, "jobs", namespace, opts); ok {
+		return cachedAs[JobInfo](items), next, nil
 	}
 
-	jobs, err := clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	jobs, err := clientset.BatchV1().Jobs(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []JobInfo
-	for _, job := range jobs.Items {
-		completions := int32(1)
-		if job.Spec.Completions != nil {
-			completions = *job.Spec.Completions
-		}
+	for i := range jobs.Items {
+		result = append(result, jobToInfo(&jobs.Items[i]))
+	}
+	return result, jobs.Continue, nil
+}
 
-		status := "Running"
-		if job.Status.Succeeded > 0 && job.Status.Succeeded >= completions {
-			status = "Complete"
-		} else if job.Status.Failed > 0 {
-			status = "Failed"
-		}
+func jobToInfo(job *batchv1.Job) JobInfo {
+	completions := int32(1)
+	if job.Spec.Completions != nil {
+		completions = *job.Spec.Completions
+	}
 
-		duration := "-"
-		if job.Status.StartTime != nil && job.Status.CompletionTime != nil {
-			d := job.Status.CompletionTime.Sub(job.Status.StartTime.Time)
-			duration = d.Round(time.Second).String()
-		}
+	status := "Running"
+	if job.Status.Succeeded > 0 && job.Status.Succeeded >= completions {
+		status = "Complete"
+	} else if job.Status.Failed > 0 {
+		status = "Failed"
+	}
 
-		result = append(result, JobInfo{
-			Name:        job.Name,
-			Namespace:   job.Namespace,
-			Completions: fmt.Sprintf("%d/%d", job.Status.Succeeded, completions),
-			Duration:    duration,
-			Status:      status,
-			Labels:      job.Labels,
-			CreatedAt:   job.CreationTimestamp.Format(time.RFC3339),
-			Age:         formatAge(job.CreationTimestamp.Time),
-		})
+	duration := "-"
+	if job.Status.StartTime != nil && job.Status.CompletionTime != nil {
+		d := job.Status.CompletionTime.Sub(job.Status.StartTime.Time)
+		duration = d.Round(time.Second).String()
+	}
+
+	return JobInfo{
+		Name:        job.Name,
+		Namespace:   job.Namespace,
+		Completions: fmt.Sprintf("%d/%d", job.Status.Succeeded, completions),
+		Duration:    duration,
+		Status:      status,
+		Labels:      job.Labels,
+		CreatedAt:   job.CreationTimestamp.Format(time.RFC3339),
+		Age:         formatAge(job.CreationTimestamp.Time),
 	}
-	return result, nil
 }
 
 type CronJobInfo struct {
@@ -801,41 +990,49 @@ type CronJobInfo struct {
 	Age           string            `json:"age"`
 }
 
-func ListCronJobs(ctx context.Context, namespace string) ([]CronJobInfo, error) {
+func ListCronJobs(ctx context.Context, namespace string, opts ListOptions) ([]CronJobInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
 	}
 
-	cjs, err := clientset.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if items, next, ok := listFromCache(ctx, "cronjobs", namespace, opts); ok {
+		return cachedAs[CronJobInfo](items), next, nil
+	}
+
+	cjs, err := clientset.BatchV1().CronJobs(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []CronJobInfo
-	for _, cj := range cjs.Items {
-		lastSchedule := "-"
-		if cj.Status.LastScheduleTime != nil {
-			lastSchedule = formatAge(cj.Status.LastScheduleTime.Time) + " ago"
-		}
+	for i := range cjs.Items {
+		result = append(result, cronJobToInfo(&cjs.Items[i]))
+	}
+	return result, cjs.Continue, nil
+}
 
-		suspend := false
-		if cj.Spec.Suspend != nil {
-			suspend = *cj.Spec.Suspend
-		}
+func cronJobToInfo(cj *batchv1.CronJob) CronJobInfo {
+	lastSchedule := "-"
+	if cj.Status.LastScheduleTime != nil {
+		lastSchedule = formatAge(cj.Status.LastScheduleTime.Time) + " ago"
+	}
 
-		result = append(result, CronJobInfo{
-			Name:         cj.Name,
-			Namespace:    cj.Namespace,
-			Schedule:     cj.Spec.Schedule,
-			Suspend:      suspend,
-			Active:       len(cj.Status.Active),
-			LastSchedule: lastSchedule,
-			Labels:       cj.Labels,
-			CreatedAt:    cj.CreationTimestamp.Format(time.RFC3339),
-			Age:          formatAge(cj.CreationTimestamp.Time),
-		})
+	suspend := false
+	if cj.Spec.Suspend != nil {
+		suspend = *cj.Spec.Suspend
+	}
+
+	return CronJobInfo{
+		Name:         cj.Name,
+		Namespace:    cj.Namespace,
+		Schedule:     cj.Spec.Schedule,
+		Suspend:      suspend,
+		Active:       len(cj.Status.Active),
+		LastSchedule: lastSchedule,
+		Labels:       cj.Labels,
+		CreatedAt:    cj.CreationTimestamp.Format(time.RFC3339),
+		Age:          formatAge(cj.CreationTimestamp.Time),
 	}
-	return result, nil
 }
 
 type EventInfo struct {
@@ -851,32 +1048,40 @@ type EventInfo struct {
 	Age       string `json:"age"`
 }
 
-func ListEvents(ctx context.Context, namespace string) ([]EventInfo, error) {
+func ListEvents(ctx context.Context, namespace string, opts ListOptions) ([]EventInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	if items, next, ok := listFromCache(ctx, "events", namespace, opts); ok {
+		return cachedAs[EventInfo](items), next, nil
 	}
 
-	events, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	events, err := clientset.CoreV1().Events(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []EventInfo
-	for _, e := range events.Items {
-		result = append(result, EventInfo{
-			Name:      e.Name,
-			Namespace: e.Namespace,
-			Type:      e.Type,
-			Reason:    e.Reason,
-			Object:    e.InvolvedObject.Kind + "/" + e.InvolvedObject.Name,
-			Message:   e.Message,
-			Count:     e.Count,
-			FirstSeen: e.FirstTimestamp.Format(time.RFC3339),
-			LastSeen:  e.LastTimestamp.Format(time.RFC3339),
-			Age:       formatAge(e.LastTimestamp.Time),
-		})
+	for i := range events.Items {
+		result = append(result, eventToInfo(&events.Items[i]))
+	}
+	return result, events.Continue, nil
+}
+
+func eventToInfo(e *corev1.Event) EventInfo {
+	return EventInfo{
+		Name:      e.Name,
+		Namespace: e.Namespace,
+		Type:      e.Type,
+		Reason:    e.Reason,
+		Object:    e.InvolvedObject.Kind + "/" + e.InvolvedObject.Name,
+		Message:   e.Message,
+		Count:     e.Count,
+		FirstSeen: e.FirstTimestamp.Format(time.RFC3339),
+		LastSeen:  e.LastTimestamp.Format(time.RFC3339),
+		Age:       formatAge(e.LastTimestamp.Time),
 	}
-	return result, nil
 }
 
 type ReplicaSetInfo struct {
@@ -890,32 +1095,340 @@ type ReplicaSetInfo struct {
 	Age       string            `json:"age"`
 }
 
-func ListReplicaSets(ctx context.Context, namespace string) ([]ReplicaSetInfo, error) {
+func ListReplicaSets(ctx context.Context, namespace string, opts ListOptions) ([]ReplicaSetInfo, string, error) {
 	if clientset == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
 	}
 
-	rss, err := clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if items, next, ok := listFromCache(ctx, "replicasets", namespace, opts); ok {
+		return cachedAs[ReplicaSetInfo](items), next, nil
+	}
+
+	rss, err := clientset.AppsV1().ReplicaSets(namespace).List(ctx, opts.toListOptions())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var result []ReplicaSetInfo
-	for _, rs := range rss.Items {
-		desired := int32(0)
-		if rs.Spec.Replicas != nil {
-			desired = *rs.Spec.Replicas
+	for i := range rss.Items {
+		result = append(result, replicaSetToInfo(&rss.Items[i]))
+	}
+	return result, rss.Continue, nil
+}
+
+func replicaSetToInfo(rs *appsv1.ReplicaSet) ReplicaSetInfo {
+	desired := int32(0)
+	if rs.Spec.Replicas != nil {
+		desired = *rs.Spec.Replicas
+	}
+	return ReplicaSetInfo{
+		Name:      rs.Name,
+		Namespace: rs.Namespace,
+		Desired:   desired,
+		Current:   rs.Status.Replicas,
+		Ready:     rs.Status.ReadyReplicas,
+		Labels:    rs.Labels,
+		CreatedAt: rs.CreationTimestamp.Format(time.RFC3339),
+		Age:       formatAge(rs.CreationTimestamp.Time),
+	}
+}
+
+type NetworkPolicyInfo struct {
+	Name         string            `json:"name"`
+	Namespace    string            `json:"namespace"`
+	PodSelector  string            `json:"pod_selector"`
+	PolicyTypes  []string          `json:"policy_types"`
+	IngressRules int               `json:"ingress_rules"`
+	EgressRules  int               `json:"egress_rules"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	CreatedAt    string            `json:"created_at"`
+	Age          string            `json:"age"`
+}
+
+func ListNetworkPolicies(ctx context.Context, namespace string, opts ListOptions) ([]NetworkPolicyInfo, string, error) {
+	if clientset == nil {
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	if items, next, ok := listFromCache(ctx, "networkpolicies", namespace, opts); ok {
+		return cachedAs[NetworkPolicyInfo](items), next, nil
+	}
+
+	nps, err := clientset.NetworkingV1().NetworkPolicies(namespace).List(ctx, opts.toListOptions())
+	if err != nil {
+		return nil, "", err
+	}
+
+	var result []NetworkPolicyInfo
+	for i := range nps.Items {
+		result = append(result, networkPolicyToInfo(&nps.Items[i]))
+	}
+	return result, nps.Continue, nil
+}
+
+func networkPolicyToInfo(np *networkingv1.NetworkPolicy) NetworkPolicyInfo {
+	var policyTypes []string
+	for _, pt := range np.Spec.PolicyTypes {
+		policyTypes = append(policyTypes, string(pt))
+	}
+
+	// An empty selector selects every pod in the namespace
+	podSelector := metav1.FormatLabelSelector(&np.Spec.PodSelector)
+	if podSelector == "<none>" {
+		podSelector = "<all pods>"
+	}
+
+	return NetworkPolicyInfo{
+		Name:         np.Name,
+		Namespace:    np.Namespace,
+		PodSelector:  podSelector,
+		PolicyTypes:  policyTypes,
+		IngressRules: len(np.Spec.Ingress),
+		EgressRules:  len(np.Spec.Egress),
+		Labels:       np.Labels,
+		CreatedAt:    np.CreationTimestamp.Format(time.RFC3339),
+		Age:          formatAge(np.CreationTimestamp.Time),
+	}
+}
+
+type PodDisruptionBudgetInfo struct {
+	Name               string            `json:"name"`
+	Namespace          string            `json:"namespace"`
+	MinAvailable       string            `json:"min_available,omitempty"`
+	MaxUnavailable     string            `json:"max_unavailable,omitempty"`
+	Selector           string            `json:"selector"`
+	CurrentHealthy     int32             `json:"current_healthy"`
+	DesiredHealthy     int32             `json:"desired_healthy"`
+	ExpectedPods       int32             `json:"expected_pods"`
+	DisruptionsAllowed int32             `json:"disruptions_allowed"`
+	Labels             map[string]string `json:"labels,omitempty"`
+	CreatedAt          string            `json:"created_at"`
+	Age                string            `json:"age"`
+}
+
+func ListPodDisruptionBudgets(ctx context.Context, namespace string, opts ListOptions) ([]PodDisruptionBudgetInfo, string, error) {
+	if clientset == nil {
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	if items, next, ok := listFromCache(ctx, "pdbs", namespace, opts); ok {
+		return cachedAs[PodDisruptionBudgetInfo](items), next, nil
+	}
+
+	pdbs, err := clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, opts.toListOptions())
+	if err != nil {
+		return nil, "", err
+	}
+
+	var result []PodDisruptionBudgetInfo
+	for i := range pdbs.Items {
+		result = append(result, pdbToInfo(&pdbs.Items[i]))
+	}
+	return result, pdbs.Continue, nil
+}
+
+func pdbToInfo(pdb *policyv1.PodDisruptionBudget) PodDisruptionBudgetInfo {
+	minAvailable := ""
+	if pdb.Spec.MinAvailable != nil {
+		minAvailable = pdb.Spec.MinAvailable.String()
+	}
+	maxUnavailable := ""
+	if pdb.Spec.MaxUnavailable != nil {
+		maxUnavailable = pdb.Spec.MaxUnavailable.String()
+	}
+
+	return PodDisruptionBudgetInfo{
+		Name:               pdb.Name,
+		Namespace:          pdb.Namespace,
+		MinAvailable:       minAvailable,
+		MaxUnavailable:     maxUnavailable,
+		Selector:           metav1.FormatLabelSelector(pdb.Spec.Selector),
+		CurrentHealthy:     pdb.Status.CurrentHealthy,
+		DesiredHealthy:     pdb.Status.DesiredHealthy,
+		ExpectedPods:       pdb.Status.ExpectedPods,
+		DisruptionsAllowed: pdb.Status.DisruptionsAllowed,
+		Labels:             pdb.Labels,
+		CreatedAt:          pdb.CreationTimestamp.Format(time.RFC3339),
+		Age:                formatAge(pdb.CreationTimestamp.Time),
+	}
+}
+
+type StorageClassInfo struct {
+	Name                 string            `json:"name"`
+	Provisioner          string            `json:"provisioner"`
+	ReclaimPolicy        string            `json:"reclaim_policy"`
+	VolumeBindingMode    string            `json:"volume_binding_mode"`
+	AllowVolumeExpansion bool              `json:"allow_volume_expansion"`
+	IsDefault            bool              `json:"is_default"`
+	Labels               map[string]string `json:"labels,omitempty"`
+	CreatedAt            string            `json:"created_at"`
+	Age                  string            `json:"age"`
+}
+
+func ListStorageClasses(ctx context.Context, opts ListOptions) ([]StorageClassInfo, string, error) {
+	if clientset == nil {
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	if items, next, ok := listFromCache(ctx, "storageclasses", "", opts); ok {
+		return cachedAs[StorageClassInfo](items), next, nil
+	}
+
+	scs, err := clientset.StorageV1().StorageClasses().List(ctx, opts.toListOptions())
+	if err != nil {
+		return nil, "", err
+	}
+
+	var result []StorageClassInfo
+	for i := range scs.Items {
+		result = append(result, storageClassToInfo(&scs.Items[i]))
+	}
+	return result, scs.Continue, nil
+}
+
+// defaultStorageClassAnnotation marks the cluster's default StorageClass
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+func storageClassToInfo(sc *storagev1.StorageClass) StorageClassInfo {
+	// The API server defaults both fields, but older objects may lack them
+	reclaimPolicy := string(corev1.PersistentVolumeReclaimDelete)
+	if sc.ReclaimPolicy != nil {
+		reclaimPolicy = string(*sc.ReclaimPolicy)
+	}
+	bindingMode := string(storagev1.VolumeBindingImmediate)
+	if sc.VolumeBindingMode != nil {
+		bindingMode = string(*sc.VolumeBindingMode)
+	}
+
+	return StorageClassInfo{
+		Name:                 sc.Name,
+		Provisioner:          sc.Provisioner,
+		ReclaimPolicy:        reclaimPolicy,
+		VolumeBindingMode:    bindingMode,
+		AllowVolumeExpansion: sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion,
+		IsDefault:            sc.Annotations[defaultStorageClassAnnotation] == "true",
+		Labels:               sc.Labels,
+		CreatedAt:            sc.CreationTimestamp.Format(time.RFC3339),
+		Age:                  formatAge(sc.CreationTimestamp.Time),
+	}
+}
+
+type EndpointsInfo struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Ready     []string          `json:"ready"`
+	NotReady  []string          `json:"not_ready"`
+	Ports     []string          `json:"ports"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	CreatedAt string            `json:"created_at"`
+	Age       string            `json:"age"`
+}
+
+func ListEndpoints(ctx context.Context, namespace string, opts ListOptions) ([]EndpointsInfo, string, error) {
+	if clientset == nil {
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	eps, err := clientset.CoreV1().Endpoints(namespace).List(ctx, opts.toListOptions())
+	if err != nil {
+		return nil, "", err
+	}
+
+	var result []EndpointsInfo
+	for i := range eps.Items {
+		result = append(result, endpointsToInfo(&eps.Items[i]))
+	}
+	return result, eps.Continue, nil
+}
+
+func endpointsToInfo(ep *corev1.Endpoints) EndpointsInfo {
+	var ready, notReady, ports []string
+	for _, subset := range ep.Subsets {
+		for _, addr := range subset.Addresses {
+			ready = append(ready, addr.IP)
 		}
-		result = append(result, ReplicaSetInfo{
-			Name:      rs.Name,
-			Namespace: rs.Namespace,
-			Desired:   desired,
-			Current:   rs.Status.Replicas,
-			Ready:     rs.Status.ReadyReplicas,
-			Labels:    rs.Labels,
-			CreatedAt: rs.CreationTimestamp.Format(time.RFC3339),
-			Age:       formatAge(rs.CreationTimestamp.Time),
-		})
+		for _, addr := range subset.NotReadyAddresses {
+			notReady = append(notReady, addr.IP)
+		}
+		for _, p := range subset.Ports {
+			ports = append(ports, fmt.Sprintf("%d/%s", p.Port, p.Protocol))
+		}
+	}
+
+	return EndpointsInfo{
+		Name:      ep.Name,
+		Namespace: ep.Namespace,
+		Ready:     ready,
+		NotReady:  notReady,
+		Ports:     ports,
+		Labels:    ep.Labels,
+		CreatedAt: ep.CreationTimestamp.Format(time.RFC3339),
+		Age:       formatAge(ep.CreationTimestamp.Time),
+	}
+}
+
+type EndpointSliceInfo struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Service     string            `json:"service"`
+	AddressType string            `json:"address_type"`
+	Endpoints   int               `json:"endpoints"`
+	Ready       int               `json:"ready"`
+	Ports       []string          `json:"ports"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	CreatedAt   string            `json:"created_at"`
+	Age         string            `json:"age"`
+}
+
+func ListEndpointSlices(ctx context.Context, namespace string, opts ListOptions) ([]EndpointSliceInfo, string, error) {
+	if clientset == nil {
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	slices, err := clientset.DiscoveryV1().EndpointSlices(namespace).List(ctx, opts.toListOptions())
+	if err != nil {
+		return nil, "", err
+	}
+
+	var result []EndpointSliceInfo
+	for i := range slices.Items {
+		result = append(result, endpointSliceToInfo(&slices.Items[i]))
+	}
+	return result, slices.Continue, nil
+}
+
+func endpointSliceToInfo(es *discoveryv1.EndpointSlice) EndpointSliceInfo {
+	ready := 0
+	for _, ep := range es.Endpoints {
+		// A nil ready condition means unknown and is treated as ready
+		if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+			ready++
+		}
+	}
+
+	var ports []string
+	for _, p := range es.Ports {
+		port := "<all>"
+		if p.Port != nil {
+			port = fmt.Sprintf("%d", *p.Port)
+		}
+		protocol := corev1.ProtocolTCP
+		if p.Protocol != nil {
+			protocol = *p.Protocol
+		}
+		ports = append(ports, port+"/"+string(protocol))
+	}
+
+	return EndpointSliceInfo{
+		Name:        es.Name,
+		Namespace:   es.Namespace,
+		Service:     es.Labels[discoveryv1.LabelServiceName],
+		AddressType: string(es.AddressType),
+		Endpoints:   len(es.Endpoints),
+		Ready:       ready,
+		Ports:       ports,
+		Labels:      es.Labels,
+		CreatedAt:   es.CreationTimestamp.Format(time.RFC3339),
+		Age:         formatAge(es.CreationTimestamp.Time),
 	}
-	return result, nil
 }
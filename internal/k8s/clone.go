@@ -0,0 +1,496 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// A clone copies a workload, with the ConfigMaps and Secrets its pods use
+// and the Services that select them, into another namespace or under a new
+// name. The copies keep their spec but none of the state or identity of the
+// originals, and each is annotated with where it came from.
+
+// ErrClone is returned for a clone request that cannot be carried out
+var ErrClone = errors.New("cannot clone workload")
+
+// ClonedFromAnnotation names the object a copy was made from, as namespace/name
+const ClonedFromAnnotation = "gagos.io/cloned-from"
+
+// What a clone did with each object
+const (
+	CloneCreated     = "created"
+	CloneWouldCreate = "would-create" // dry run
+	CloneExists      = "exists"       // already in the target namespace; left as is
+	CloneFailed      = "failed"
+)
+
+// CloneOptions describes a clone
+type CloneOptions struct {
+	Kind            string            `json:"kind"` // deployment, statefulset or daemonset
+	Namespace       string            `json:"namespace"`
+	Name            string            `json:"name"`
+	TargetNamespace string            `json:"target_namespace"`
+	NewName         string            `json:"new_name"`    // defaults to name
+	NameSuffix      string            `json:"name_suffix"` // appended to the copied ConfigMaps, Secrets and Services
+	Labels          map[string]string `json:"labels"`      // added to every copy and to the selectors
+	Replicas        *int32            `json:"replicas"`    // defaults to the original's
+	SkipConfigMaps  bool              `json:"skip_configmaps"`
+	SkipSecrets     bool              `json:"skip_secrets"`
+	SkipServices    bool              `json:"skip_services"`
+	DryRun          bool              `json:"dry_run"`
+}
+
+// ClonedObject is one object a clone copied
+type ClonedObject struct {
+	Kind   string `json:"kind"`
+	Source string `json:"source"` // name in the source namespace
+	Name   string `json:"name"`   // name in the target namespace
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CloneResult is what a clone did
+type CloneResult struct {
+	Kind            string         `json:"kind"`
+	Namespace       string         `json:"namespace"`
+	Name            string         `json:"name"`
+	TargetNamespace string         `json:"target_namespace"`
+	NewName         string         `json:"new_name"`
+	DryRun          bool           `json:"dry_run"`
+	Objects         []ClonedObject `json:"objects"`
+	Warnings        []string       `json:"warnings"`
+}
+
+// CloneUsesSecrets reports whether a clone would copy Secrets, so that the
+// caller can ask for elevation first
+func CloneUsesSecrets(ctx context.Context, opts CloneOptions) (bool, error) {
+	sameNamespace := opts.TargetNamespace == "" || opts.TargetNamespace == opts.Namespace
+	if opts.SkipSecrets || sameNamespace && opts.NameSuffix == "" {
+		return false, nil
+	}
+	_, template, err := cloneSource(ctx, opts)
+	if err != nil {
+		return false, err
+	}
+	_, secrets := podSpecConfigRefs(&template.Spec)
+	return len(secrets) > 0, nil
+}
+
+// CloneWorkload copies a Deployment, StatefulSet or DaemonSet and what it
+// depends on. ConfigMaps and Secrets that already exist in the target are
+// used as they are; the workload itself must not exist yet.
+func CloneWorkload(ctx context.Context, opts CloneOptions) (*CloneResult, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	if opts.TargetNamespace == "" {
+		opts.TargetNamespace = opts.Namespace
+	}
+	if opts.NewName == "" {
+		opts.NewName = opts.Name
+	}
+	sameNamespace := opts.TargetNamespace == opts.Namespace
+	if sameNamespace && opts.NewName == opts.Name {
+		return nil, fmt.Errorf("%w: a clone in the same namespace needs a new_name", ErrClone)
+	}
+	if sameNamespace && len(opts.Labels) == 0 {
+		// Otherwise both workloads would claim the same pods
+		return nil, fmt.Errorf("%w: a clone in the same namespace needs labels to tell its pods apart", ErrClone)
+	}
+	if errs := validateLabels(opts.Labels); errs != "" {
+		return nil, fmt.Errorf("%w: %s", ErrClone, errs)
+	}
+
+	obj, template, err := cloneSource(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := clientset.CoreV1().Namespaces().Get(ctx, opts.TargetNamespace, metav1.GetOptions{}); err != nil {
+		return nil, fmt.Errorf("target namespace: %w", err)
+	}
+	if exists, err := cloneTargetExists(ctx, opts); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, fmt.Errorf("%w: %s %s/%s already exists", ErrClone, opts.Kind, opts.TargetNamespace, opts.NewName)
+	}
+
+	result := &CloneResult{
+		Kind:            opts.Kind,
+		Namespace:       opts.Namespace,
+		Name:            opts.Name,
+		TargetNamespace: opts.TargetNamespace,
+		NewName:         opts.NewName,
+		DryRun:          opts.DryRun,
+		Objects:         []ClonedObject{},
+		Warnings:        []string{},
+	}
+	createOpts := metav1.CreateOptions{}
+	if opts.DryRun {
+		createOpts.DryRun = []string{metav1.DryRunAll}
+	}
+	record := func(kind, source, name string, err error) {
+		o := ClonedObject{Kind: kind, Source: source, Name: name, Action: CloneCreated}
+		switch {
+		case apierrors.IsAlreadyExists(err):
+			o.Action = CloneExists
+		case err != nil:
+			o.Action = CloneFailed
+			o.Error = err.Error()
+		case opts.DryRun:
+			o.Action = CloneWouldCreate
+		}
+		result.Objects = append(result.Objects, o)
+	}
+	// In the same namespace the originals are shared unless a suffix is given
+	copyDeps := !sameNamespace || opts.NameSuffix != ""
+	rename := func(name string) string { return name + opts.NameSuffix }
+
+	configMaps, secrets := podSpecConfigRefs(&template.Spec)
+	cmNames, secretNames := map[string]string{}, map[string]string{}
+	if copyDeps && !opts.SkipConfigMaps {
+		for _, name := range configMaps {
+			cm, err := clientset.CoreV1().ConfigMaps(opts.Namespace).Get(ctx, name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("ConfigMap %s is referenced but does not exist", name))
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			cmNames[name] = rename(name)
+			cm.ObjectMeta = cloneMeta(cm.ObjectMeta, cmNames[name], opts)
+			_, err = clientset.CoreV1().ConfigMaps(opts.TargetNamespace).Create(ctx, cm, createOpts)
+			record("configmap", name, cmNames[name], err)
+		}
+	}
+	if copyDeps && !opts.SkipSecrets {
+		for _, name := range secrets {
+			secret, err := clientset.CoreV1().Secrets(opts.Namespace).Get(ctx, name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Secret %s is referenced but does not exist", name))
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if secret.Type == corev1.SecretTypeServiceAccountToken {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Secret %s is a service account token and was not copied", name))
+				continue
+			}
+			secretNames[name] = rename(name)
+			secret.ObjectMeta = cloneMeta(secret.ObjectMeta, secretNames[name], opts)
+			_, err = clientset.CoreV1().Secrets(opts.TargetNamespace).Create(ctx, secret, createOpts)
+			record("secret", name, secretNames[name], err)
+		}
+	}
+	if !copyDeps && len(configMaps)+len(secrets) > 0 {
+		result.Warnings = append(result.Warnings, "ConfigMaps and Secrets are shared with the original; set name_suffix to copy them")
+	}
+
+	serviceNames := map[string]string{}
+	if copyDeps && !opts.SkipServices {
+		services, err := clientset.CoreV1().Services(opts.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range services.Items {
+			svc := &services.Items[i]
+			if len(svc.Spec.Selector) == 0 || !labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(template.Labels)) {
+				continue
+			}
+			serviceNames[svc.Name] = rename(svc.Name)
+			_, err := clientset.CoreV1().Services(opts.TargetNamespace).Create(ctx, cloneService(svc, serviceNames[svc.Name], opts), createOpts)
+			record("service", svc.Name, serviceNames[svc.Name], err)
+		}
+	}
+
+	// The pods of a copy are told apart from the original's by the new labels
+	template.ObjectMeta = metav1.ObjectMeta{
+		Labels:      mergeLabels(template.Labels, opts.Labels),
+		Annotations: template.Annotations,
+	}
+	renamePodSpecConfigRefs(&template.Spec, cmNames, secretNames)
+	for _, v := range template.Spec.Volumes {
+		if v.PersistentVolumeClaim != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("volume %s uses PVC %s, which is not copied", v.Name, v.PersistentVolumeClaim.ClaimName))
+		}
+	}
+	if sa := template.Spec.ServiceAccountName; sa != "" && sa != "default" && !sameNamespace {
+		if _, err := clientset.CoreV1().ServiceAccounts(opts.TargetNamespace).Get(ctx, sa, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("service account %s does not exist in %s; pods will not start until it does", sa, opts.TargetNamespace))
+		}
+	}
+
+	switch src := obj.(type) {
+	case *appsv1.Deployment:
+		d := &appsv1.Deployment{ObjectMeta: cloneMeta(src.ObjectMeta, opts.NewName, opts), Spec: src.Spec}
+		d.Spec.Template = *template
+		d.Spec.Selector = cloneSelector(src.Spec.Selector, opts.Labels)
+		if opts.Replicas != nil {
+			d.Spec.Replicas = opts.Replicas
+		}
+		_, err = clientset.AppsV1().Deployments(opts.TargetNamespace).Create(ctx, d, createOpts)
+	case *appsv1.StatefulSet:
+		s := &appsv1.StatefulSet{ObjectMeta: cloneMeta(src.ObjectMeta, opts.NewName, opts), Spec: src.Spec}
+		s.Spec.Template = *template
+		s.Spec.Selector = cloneSelector(src.Spec.Selector, opts.Labels)
+		if opts.Replicas != nil {
+			s.Spec.Replicas = opts.Replicas
+		}
+		if renamed, ok := serviceNames[s.Spec.ServiceName]; ok {
+			s.Spec.ServiceName = renamed
+		}
+		for i := range s.Spec.VolumeClaimTemplates {
+			t := &s.Spec.VolumeClaimTemplates[i]
+			t.ObjectMeta = metav1.ObjectMeta{Name: t.Name, Labels: t.Labels, Annotations: t.Annotations}
+			t.Status = corev1.PersistentVolumeClaimStatus{}
+		}
+		_, err = clientset.AppsV1().StatefulSets(opts.TargetNamespace).Create(ctx, s, createOpts)
+	case *appsv1.DaemonSet:
+		d := &appsv1.DaemonSet{ObjectMeta: cloneMeta(src.ObjectMeta, opts.NewName, opts), Spec: src.Spec}
+		d.Spec.Template = *template
+		d.Spec.Selector = cloneSelector(src.Spec.Selector, opts.Labels)
+		_, err = clientset.AppsV1().DaemonSets(opts.TargetNamespace).Create(ctx, d, createOpts)
+	}
+	record(opts.Kind, opts.Name, opts.NewName, err)
+	return result, nil
+}
+
+// cloneSource reads the workload to clone and returns it with a copy of its
+// pod template
+func cloneSource(ctx context.Context, opts CloneOptions) (interface{}, *corev1.PodTemplateSpec, error) {
+	if clientset == nil {
+		return nil, nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	switch opts.Kind {
+	case "deployment":
+		d, err := clientset.AppsV1().Deployments(opts.Namespace).Get(ctx, opts.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return d, d.Spec.Template.DeepCopy(), nil
+	case "statefulset":
+		s, err := clientset.AppsV1().StatefulSets(opts.Namespace).Get(ctx, opts.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Spec.Template.DeepCopy(), nil
+	case "daemonset":
+		d, err := clientset.AppsV1().DaemonSets(opts.Namespace).Get(ctx, opts.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return d, d.Spec.Template.DeepCopy(), nil
+	}
+	return nil, nil, fmt.Errorf("%w: unsupported kind %q", ErrClone, opts.Kind)
+}
+
+func cloneTargetExists(ctx context.Context, opts CloneOptions) (bool, error) {
+	var err error
+	switch opts.Kind {
+	case "deployment":
+		_, err = clientset.AppsV1().Deployments(opts.TargetNamespace).Get(ctx, opts.NewName, metav1.GetOptions{})
+	case "statefulset":
+		_, err = clientset.AppsV1().StatefulSets(opts.TargetNamespace).Get(ctx, opts.NewName, metav1.GetOptions{})
+	case "daemonset":
+		_, err = clientset.AppsV1().DaemonSets(opts.TargetNamespace).Get(ctx, opts.NewName, metav1.GetOptions{})
+	}
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// cloneMeta keeps the labels and the user's annotations of an object and
+// drops everything the server set
+func cloneMeta(src metav1.ObjectMeta, name string, opts CloneOptions) metav1.ObjectMeta {
+	annotations := map[string]string{}
+	for key, value := range src.Annotations {
+		if !exportSkipAnnotation(key) {
+			annotations[key] = value
+		}
+	}
+	annotations[ClonedFromAnnotation] = src.Namespace + "/" + src.Name
+	return metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   opts.TargetNamespace,
+		Labels:      mergeLabels(src.Labels, opts.Labels),
+		Annotations: annotations,
+	}
+}
+
+// cloneService copies a Service without its allocated addresses and node
+// ports, selecting the pods of the copy
+func cloneService(svc *corev1.Service, name string, opts CloneOptions) *corev1.Service {
+	clone := &corev1.Service{ObjectMeta: cloneMeta(svc.ObjectMeta, name, opts), Spec: *svc.Spec.DeepCopy()}
+	clone.Spec.Selector = mergeLabels(svc.Spec.Selector, opts.Labels)
+	if clone.Spec.ClusterIP != corev1.ClusterIPNone {
+		clone.Spec.ClusterIP = ""
+		clone.Spec.ClusterIPs = nil
+	}
+	clone.Spec.ExternalIPs = nil
+	clone.Spec.LoadBalancerIP = ""
+	clone.Spec.HealthCheckNodePort = 0
+	for i := range clone.Spec.Ports {
+		clone.Spec.Ports[i].NodePort = 0
+	}
+	return clone
+}
+
+func cloneSelector(selector *metav1.LabelSelector, extra map[string]string) *metav1.LabelSelector {
+	clone := selector.DeepCopy()
+	if clone == nil {
+		clone = &metav1.LabelSelector{}
+	}
+	clone.MatchLabels = mergeLabels(clone.MatchLabels, extra)
+	return clone
+}
+
+func mergeLabels(base, extra map[string]string) map[string]string {
+	if len(base) == 0 && len(extra) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(extra))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
+
+func validateLabels(l map[string]string) string {
+	if _, err := labels.ValidatedSelectorFromSet(l); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// podSpecConfigRefs returns the ConfigMaps and Secrets a pod spec uses,
+// sorted
+func podSpecConfigRefs(spec *corev1.PodSpec) (configMaps, secrets []string) {
+	cms, secs := map[string]bool{}, map[string]bool{}
+	for _, v := range spec.Volumes {
+		if v.ConfigMap != nil {
+			cms[v.ConfigMap.Name] = true
+		}
+		if v.Secret != nil {
+			secs[v.Secret.SecretName] = true
+		}
+		if v.Projected != nil {
+			for _, src := range v.Projected.Sources {
+				if src.ConfigMap != nil {
+					cms[src.ConfigMap.Name] = true
+				}
+				if src.Secret != nil {
+					secs[src.Secret.Name] = true
+				}
+			}
+		}
+	}
+	for _, s := range spec.ImagePullSecrets {
+		secs[s.Name] = true
+	}
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range containers {
+		for _, from := range c.EnvFrom {
+			if from.ConfigMapRef != nil {
+				cms[from.ConfigMapRef.Name] = true
+			}
+			if from.SecretRef != nil {
+				secs[from.SecretRef.Name] = true
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				cms[env.ValueFrom.ConfigMapKeyRef.Name] = true
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				secs[env.ValueFrom.SecretKeyRef.Name] = true
+			}
+		}
+	}
+	for name := range cms {
+		configMaps = append(configMaps, name)
+	}
+	for name := range secs {
+		secrets = append(secrets, name)
+	}
+	sort.Strings(configMaps)
+	sort.Strings(secrets)
+	return configMaps, secrets
+}
+
+// renamePodSpecConfigRefs points a pod spec at the renamed copies of its
+// ConfigMaps and Secrets
+func renamePodSpecConfigRefs(spec *corev1.PodSpec, configMaps, secrets map[string]string) {
+	renamed := func(names map[string]string, name string) string {
+		if n, ok := names[name]; ok {
+			return n
+		}
+		return name
+	}
+	for i := range spec.Volumes {
+		v := &spec.Volumes[i]
+		if v.ConfigMap != nil {
+			v.ConfigMap.Name = renamed(configMaps, v.ConfigMap.Name)
+		}
+		if v.Secret != nil {
+			v.Secret.SecretName = renamed(secrets, v.Secret.SecretName)
+		}
+		if v.Projected != nil {
+			for j := range v.Projected.Sources {
+				src := &v.Projected.Sources[j]
+				if src.ConfigMap != nil {
+					src.ConfigMap.Name = renamed(configMaps, src.ConfigMap.Name)
+				}
+				if src.Secret != nil {
+					src.Secret.Name = renamed(secrets, src.Secret.Name)
+				}
+			}
+		}
+	}
+	for i := range spec.ImagePullSecrets {
+		spec.ImagePullSecrets[i].Name = renamed(secrets, spec.ImagePullSecrets[i].Name)
+	}
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for i := range containers {
+			c := &containers[i]
+			for j := range c.EnvFrom {
+				if c.EnvFrom[j].ConfigMapRef != nil {
+					c.EnvFrom[j].ConfigMapRef.Name = renamed(configMaps, c.EnvFrom[j].ConfigMapRef.Name)
+				}
+				if c.EnvFrom[j].SecretRef != nil {
+					c.EnvFrom[j].SecretRef.Name = renamed(secrets, c.EnvFrom[j].SecretRef.Name)
+				}
+			}
+			for j := range c.Env {
+				from := c.Env[j].ValueFrom
+				if from == nil {
+					continue
+				}
+				if from.ConfigMapKeyRef != nil {
+					from.ConfigMapKeyRef.Name = renamed(configMaps, from.ConfigMapKeyRef.Name)
+				}
+				if from.SecretKeyRef != nil {
+					from.SecretKeyRef.Name = renamed(secrets, from.SecretKeyRef.Name)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConfigData is the content of a ConfigMap or Secret. A ConfigMap's data is
+// in Data and its binaryData in Binary; a Secret's data is all in Binary.
+type ConfigData struct {
+	Data   map[string]string `json:"data,omitempty"`
+	Binary map[string][]byte `json:"binary,omitempty"`
+}
+
+// Keys returns every key, sorted
+func (d *ConfigData) Keys() []string {
+	keys := make([]string, 0, len(d.Data)+len(d.Binary))
+	for k := range d.Data {
+		keys = append(keys, k)
+	}
+	for k := range d.Binary {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Value returns a key's value and whether it is set
+func (d *ConfigData) Value(key string) ([]byte, bool) {
+	if v, ok := d.Data[key]; ok {
+		return []byte(v), true
+	}
+	v, ok := d.Binary[key]
+	return v, ok
+}
+
+// GetConfigData returns the content of a ConfigMap or Secret. kind is
+// "configmap" or "secret".
+func GetConfigData(ctx context.Context, kind, namespace, name string) (*ConfigData, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	switch kind {
+	case "configmap":
+		cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &ConfigData{Data: cm.Data, Binary: cm.BinaryData}, nil
+	case "secret":
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &ConfigData{Binary: secret.Data}, nil
+	}
+	return nil, fmt.Errorf("unsupported kind: %s", kind)
+}
+
+// ReplaceConfigData sets the whole content of a ConfigMap or Secret, so
+// keys missing from data are removed. Labels, annotations and the rest of
+// the object are kept.
+func ReplaceConfigData(ctx context.Context, kind, namespace, name string, data *ConfigData) error {
+	if clientset == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+
+	switch kind {
+	case "configmap":
+		cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		cm.Data, cm.BinaryData = data.Data, data.Binary
+		_, err = clientset.CoreV1().ConfigMaps(namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	case "secret":
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		// stringData is write-only and would be merged over data
+		secret.Data, secret.StringData = data.Binary, nil
+		_, err = clientset.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	}
+	return fmt.Errorf("unsupported kind: %s", kind)
+}
@@ -0,0 +1,166 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// DebugOptions describes the ephemeral container to inject
+type DebugOptions struct {
+	Image   string   // e.g. busybox:1.36 or nicolaka/netshoot
+	Target  string   // container whose process namespace to share (optional)
+	Command []string // defaults to sh
+}
+
+// DebugContainerInfo is returned once the ephemeral container is running
+type DebugContainerInfo struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Image     string `json:"image"`
+	Target    string `json:"target,omitempty"`
+}
+
+// CreateDebugContainer adds an ephemeral container to a running pod and waits
+// for it to start, like `kubectl debug -it --image=... pod`.
+func CreateDebugContainer(ctx context.Context, namespace, podName string, opts DebugOptions) (*DebugContainerInfo, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	if opts.Image == "" {
+		return nil, fmt.Errorf("image is required")
+	}
+	if len(opts.Command) == 0 {
+		opts.Command = []string{"sh"}
+	}
+
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		return nil, fmt.Errorf("pod %s is %s, not Running", podName, pod.Status.Phase)
+	}
+	if opts.Target != "" {
+		found := false
+		for _, c := range pod.Spec.Containers {
+			if c.Name == opts.Target {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("target container %q not found in pod %s", opts.Target, podName)
+		}
+	}
+
+	name := "debugger-" + rand.String(5)
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                     name,
+			Image:                    opts.Image,
+			Command:                  opts.Command,
+			ImagePullPolicy:          corev1.PullIfNotPresent,
+			Stdin:                    true,
+			TTY:                      true,
+			TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+		},
+		TargetContainerName: opts.Target,
+	})
+
+	if _, err := clientset.CoreV1().Pods(namespace).UpdateEphemeralContainers(ctx, podName, pod, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to add ephemeral container: %w", err)
+	}
+
+	if err := waitForEphemeralContainer(ctx, namespace, podName, name); err != nil {
+		return nil, err
+	}
+
+	return &DebugContainerInfo{
+		Namespace: namespace,
+		Pod:       podName,
+		Container: name,
+		Image:     opts.Image,
+		Target:    opts.Target,
+	}, nil
+}
+
+// waitForEphemeralContainer polls until the container is running or has failed
+func waitForEphemeralContainer(ctx context.Context, namespace, podName, container string) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name != container {
+				continue
+			}
+			if status.State.Running != nil {
+				return nil
+			}
+			if t := status.State.Terminated; t != nil {
+				return fmt.Errorf("debug container exited: %s (exit code %d)", t.Reason, t.ExitCode)
+			}
+			if w := status.State.Waiting; w != nil && (w.Reason == "ErrImagePull" || w.Reason == "ImagePullBackOff" || w.Reason == "InvalidImageName") {
+				return fmt.Errorf("debug container cannot start: %s: %s", w.Reason, w.Message)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for debug container %s to start", container)
+		case <-ticker.C:
+		}
+	}
+}
+
+// AttachContainer attaches a TTY to a running container's stdin/stdout.
+// sizes may be nil; the stream ends when the container exits or ctx is done.
+func AttachContainer(ctx context.Context, namespace, podName, container string, stdin io.Reader, stdout io.Writer, sizes remotecommand.TerminalSizeQueue) error {
+	if clientset == nil || restConfig == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+	rc, err := CoreREST(clientset)
+	if err != nil {
+		return err
+	}
+
+	req := rc.Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("attach").
+		VersionedParams(&corev1.PodAttachOptions{
+			Container: container,
+			Stdin:     true,
+			Stdout:    true,
+			TTY:       true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(restConfig, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create executor: %w", err)
+	}
+
+	return executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:             stdin,
+		Stdout:            stdout,
+		Tty:               true,
+		TerminalSizeQueue: sizes,
+	})
+}
@@ -0,0 +1,327 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ResourceDescription is a kubectl describe style view of a resource, for
+// detail panes that should not have to parse YAML
+type ResourceDescription struct {
+	Kind        string               `json:"kind"`
+	Name        string               `json:"name"`
+	Namespace   string               `json:"namespace,omitempty"`
+	Created     string               `json:"created"`
+	Age         string               `json:"age"`
+	Status      string               `json:"status"`
+	Labels      map[string]string    `json:"labels"`
+	Annotations map[string]string    `json:"annotations"`
+	Owners      []OwnerInfo          `json:"owners"` // controller chain, nearest first
+	Conditions  []ConditionInfo      `json:"conditions"`
+	Containers  []DescribeContainer  `json:"containers"`
+	Volumes     []DescribeVolume     `json:"volumes"`
+	Tolerations []DescribeToleration `json:"tolerations"`
+	Events      []EventInfo          `json:"events"`
+	Details     map[string]string    `json:"details"` // kind specific fields, e.g. node or strategy
+}
+
+// OwnerInfo is one link of an owner chain
+type OwnerInfo struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// ConditionInfo is a status condition
+type ConditionInfo struct {
+	Type           string `json:"type"`
+	Status         string `json:"status"`
+	Reason         string `json:"reason,omitempty"`
+	Message        string `json:"message,omitempty"`
+	LastTransition string `json:"last_transition,omitempty"`
+}
+
+// DescribeContainer summarizes a container. State, Ready and Restarts are
+// only set for pods.
+type DescribeContainer struct {
+	Name     string `json:"name"`
+	Image    string `json:"image"`
+	Init     bool   `json:"init,omitempty"`
+	State    string `json:"state,omitempty"`
+	Ready    bool   `json:"ready"`
+	Restarts int32  `json:"restarts"`
+}
+
+// DescribeVolume is a volume and where it is mounted
+type DescribeVolume struct {
+	Name   string          `json:"name"`
+	Type   string          `json:"type"`
+	Source string          `json:"source,omitempty"`
+	Mounts []DescribeMount `json:"mounts"`
+}
+
+// DescribeMount is a volume mount in one container
+type DescribeMount struct {
+	Container string `json:"container"`
+	Path      string `json:"path"`
+	SubPath   string `json:"sub_path,omitempty"`
+	ReadOnly  bool   `json:"read_only"`
+}
+
+// DescribeToleration is a pod toleration
+type DescribeToleration struct {
+	Key      string `json:"key,omitempty"`
+	Operator string `json:"operator,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Effect   string `json:"effect,omitempty"`
+	Seconds  *int64 `json:"seconds,omitempty"`
+}
+
+// DescribePod returns the describe view of a pod
+func DescribePod(ctx context.Context, namespace, name string) (*ResourceDescription, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	d := newDescription("Pod", pod.ObjectMeta)
+	d.Status = podToInfo(pod).Status
+	d.Details["node"] = pod.Spec.NodeName
+	d.Details["pod_ip"] = pod.Status.PodIP
+	d.Details["qos_class"] = string(pod.Status.QOSClass)
+	d.Details["service_account"] = pod.Spec.ServiceAccountName
+	d.Details["priority_class"] = pod.Spec.PriorityClassName
+	for _, c := range pod.Status.Conditions {
+		d.Conditions = append(d.Conditions, ConditionInfo{
+			Type:           string(c.Type),
+			Status:         string(c.Status),
+			Reason:         c.Reason,
+			Message:        c.Message,
+			LastTransition: formatTime(c.LastTransitionTime),
+		})
+	}
+
+	statuses := map[string]corev1.ContainerStatus{}
+	for _, s := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		statuses[s.Name] = s
+	}
+	for _, c := range describeContainers(pod.Spec) {
+		if s, ok := statuses[c.Name]; ok {
+			c.State = containerState(s.State)
+			c.Ready = s.Ready
+			c.Restarts = s.RestartCount
+		}
+		d.Containers = append(d.Containers, c)
+	}
+	describePodSpec(d, pod.Spec)
+
+	d.Owners = ownerChain(ctx, namespace, pod.ObjectMeta)
+	if err := describeEvents(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DescribeDeployment returns the describe view of a deployment. Volumes,
+// containers and tolerations come from the pod template; events include those
+// of the deployment's ReplicaSets and Pods.
+func DescribeDeployment(ctx context.Context, namespace, name string) (*ResourceDescription, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	dep, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	d := newDescription("Deployment", dep.ObjectMeta)
+	info := deploymentToInfo(dep)
+	d.Status = info.Ready + " ready"
+	d.Details["strategy"] = string(dep.Spec.Strategy.Type)
+	d.Details["selector"] = metav1.FormatLabelSelector(dep.Spec.Selector)
+	d.Details["updated"] = fmt.Sprintf("%d", dep.Status.UpdatedReplicas)
+	d.Details["available"] = fmt.Sprintf("%d", dep.Status.AvailableReplicas)
+	d.Details["service_account"] = dep.Spec.Template.Spec.ServiceAccountName
+	for _, c := range dep.Status.Conditions {
+		d.Conditions = append(d.Conditions, ConditionInfo{
+			Type:           string(c.Type),
+			Status:         string(c.Status),
+			Reason:         c.Reason,
+			Message:        c.Message,
+			LastTransition: formatTime(c.LastTransitionTime),
+		})
+	}
+	d.Containers = describeContainers(dep.Spec.Template.Spec)
+	describePodSpec(d, dep.Spec.Template.Spec)
+
+	d.Owners = ownerChain(ctx, namespace, dep.ObjectMeta)
+	if err := describeEvents(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func newDescription(kind string, meta metav1.ObjectMeta) *ResourceDescription {
+	return &ResourceDescription{
+		Kind:        kind,
+		Name:        meta.Name,
+		Namespace:   meta.Namespace,
+		Created:     formatTime(meta.CreationTimestamp),
+		Age:         formatAge(meta.CreationTimestamp.Time),
+		Labels:      meta.Labels,
+		Annotations: meta.Annotations,
+		Owners:      []OwnerInfo{},
+		Conditions:  []ConditionInfo{},
+		Containers:  []DescribeContainer{},
+		Volumes:     []DescribeVolume{},
+		Tolerations: []DescribeToleration{},
+		Events:      []EventInfo{},
+		Details:     map[string]string{},
+	}
+}
+
+func describeEvents(ctx context.Context, d *ResourceDescription) error {
+	events, err := CorrelatedEvents(ctx, d.Kind, d.Namespace, d.Name)
+	if err != nil {
+		return err
+	}
+	d.Events = events.Events
+	return nil
+}
+
+func describeContainers(spec corev1.PodSpec) []DescribeContainer {
+	containers := []DescribeContainer{}
+	for _, c := range spec.InitContainers {
+		containers = append(containers, DescribeContainer{Name: c.Name, Image: c.Image, Init: true})
+	}
+	for _, c := range spec.Containers {
+		containers = append(containers, DescribeContainer{Name: c.Name, Image: c.Image})
+	}
+	return containers
+}
+
+// describePodSpec fills in the volumes, with their mounts, and tolerations
+func describePodSpec(d *ResourceDescription, spec corev1.PodSpec) {
+	mounts := map[string][]DescribeMount{}
+	for _, c := range append(spec.InitContainers, spec.Containers...) {
+		for _, m := range c.VolumeMounts {
+			mounts[m.Name] = append(mounts[m.Name], DescribeMount{
+				Container: c.Name,
+				Path:      m.MountPath,
+				SubPath:   m.SubPath,
+				ReadOnly:  m.ReadOnly,
+			})
+		}
+	}
+	for _, v := range spec.Volumes {
+		volType, source := volumeSource(v.VolumeSource)
+		vol := DescribeVolume{Name: v.Name, Type: volType, Source: source, Mounts: mounts[v.Name]}
+		if vol.Mounts == nil {
+			vol.Mounts = []DescribeMount{}
+		}
+		d.Volumes = append(d.Volumes, vol)
+	}
+
+	for _, t := range spec.Tolerations {
+		d.Tolerations = append(d.Tolerations, DescribeToleration{
+			Key:      t.Key,
+			Operator: string(t.Operator),
+			Value:    t.Value,
+			Effect:   string(t.Effect),
+			Seconds:  t.TolerationSeconds,
+		})
+	}
+}
+
+// volumeSource returns the type of a volume and what it points at
+func volumeSource(v corev1.VolumeSource) (string, string) {
+	switch {
+	case v.ConfigMap != nil:
+		return "ConfigMap", v.ConfigMap.Name
+	case v.Secret != nil:
+		return "Secret", v.Secret.SecretName
+	case v.PersistentVolumeClaim != nil:
+		return "PersistentVolumeClaim", v.PersistentVolumeClaim.ClaimName
+	case v.EmptyDir != nil:
+		return "EmptyDir", string(v.EmptyDir.Medium)
+	case v.HostPath != nil:
+		return "HostPath", v.HostPath.Path
+	case v.Projected != nil:
+		return "Projected", fmt.Sprintf("%d sources", len(v.Projected.Sources))
+	case v.DownwardAPI != nil:
+		return "DownwardAPI", ""
+	case v.CSI != nil:
+		return "CSI", v.CSI.Driver
+	case v.NFS != nil:
+		return "NFS", v.NFS.Server + ":" + v.NFS.Path
+	case v.Ephemeral != nil:
+		return "Ephemeral", ""
+	}
+	return "Other", ""
+}
+
+func containerState(s corev1.ContainerState) string {
+	switch {
+	case s.Running != nil:
+		return "Running"
+	case s.Waiting != nil:
+		return "Waiting: " + s.Waiting.Reason
+	case s.Terminated != nil:
+		return "Terminated: " + s.Terminated.Reason
+	}
+	return ""
+}
+
+// ownerChain follows controller references up from meta: a Pod's ReplicaSet
+// and its Deployment, or a Job and its CronJob. Owners of kinds it cannot look
+// up end the chain.
+func ownerChain(ctx context.Context, namespace string, meta metav1.ObjectMeta) []OwnerInfo {
+	chain := []OwnerInfo{}
+	ref := metav1.GetControllerOfNoCopy(&meta)
+	for ref != nil && len(chain) < 10 {
+		chain = append(chain, OwnerInfo{Kind: ref.Kind, Name: ref.Name})
+
+		var owner metav1.Object
+		var err error
+		switch ref.Kind {
+		case "ReplicaSet":
+			owner, err = clientset.AppsV1().ReplicaSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		case "Job":
+			owner, err = clientset.BatchV1().Jobs(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		case "Deployment":
+			owner, err = clientset.AppsV1().Deployments(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		case "StatefulSet":
+			owner, err = clientset.AppsV1().StatefulSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		case "DaemonSet":
+			owner, err = clientset.AppsV1().DaemonSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		case "CronJob":
+			owner, err = clientset.BatchV1().CronJobs(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		default:
+			return chain
+		}
+		if err != nil {
+			// The owner may already be gone; keep what was found
+			return chain
+		}
+		ref = metav1.GetControllerOf(owner)
+	}
+	return chain
+}
+
+func formatTime(t metav1.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,284 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gaga951/gagos/internal/tools"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// diffContext is the number of unchanged lines kept around each hunk
+const diffContext = 3
+
+// diffKinds maps the kind names used in v1 resource URLs to their API
+// resource; these are the kinds with a Patch handler
+var diffKinds = map[string]struct {
+	gvr        schema.GroupVersionResource
+	namespaced bool
+}{
+	"pod":           {schema.GroupVersionResource{Version: "v1", Resource: "pods"}, true},
+	"service":       {schema.GroupVersionResource{Version: "v1", Resource: "services"}, true},
+	"configmap":     {schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, true},
+	"secret":        {schema.GroupVersionResource{Version: "v1", Resource: "secrets"}, true},
+	"pvc":           {schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumeclaims"}, true},
+	"deployment":    {schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, true},
+	"daemonset":     {schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}, true},
+	"statefulset":   {schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}, true},
+	"cronjob":       {schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"}, true},
+	"ingress":       {schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}, true},
+	"networkpolicy": {schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"}, true},
+	"pdb":           {schema.GroupVersionResource{Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"}, true},
+	"hpa":           {schema.GroupVersionResource{Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"}, true},
+	"storageclass":  {schema.GroupVersionResource{Group: "storage.k8s.io", Version: "v1", Resource: "storageclasses"}, false},
+}
+
+// Field change operations
+const (
+	FieldAdded   = "added"
+	FieldRemoved = "removed"
+	FieldChanged = "changed"
+)
+
+// FieldChange is one leaf that differs between the live and the edited object
+type FieldChange struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// ResourceDiff is what a patch would change on a live object
+type ResourceDiff struct {
+	Kind      string        `json:"kind"`
+	Name      string        `json:"name"`
+	Namespace string        `json:"namespace,omitempty"`
+	Identical bool          `json:"identical"`
+	Additions int           `json:"additions"`
+	Deletions int           `json:"deletions"`
+	Changes   []FieldChange `json:"changes"`
+	Unified   string        `json:"unified"`
+}
+
+// DiffResource previews a Patch handler call: the edited YAML is sent as a
+// dry-run strategic merge patch, exactly like the real patch, and the result
+// is compared with the live object. status, managedFields, resourceVersion
+// and generation are left out of the comparison.
+func DiffResource(ctx context.Context, kind, namespace, name, yamlContent string) (*ResourceDiff, error) {
+	dc, _, err := getDynamic()
+	if err != nil {
+		return nil, err
+	}
+
+	target, ok := diffKinds[strings.ToLower(kind)]
+	if !ok {
+		// v2 routes use the plural resource name
+		for _, k := range diffKinds {
+			if k.gvr.Resource == strings.ToLower(kind) {
+				target, ok = k, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unsupported kind %q", kind)
+	}
+	var resource dynamic.ResourceInterface = dc.Resource(target.gvr)
+	if target.namespaced {
+		resource = dc.Resource(target.gvr).Namespace(namespace)
+	} else {
+		namespace = ""
+	}
+
+	patch, err := yaml.YAMLToJSON([]byte(yamlContent))
+	if err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+
+	live, err := resource.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	patched, err := resource.Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{
+		DryRun: []string{metav1.DryRunAll},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("patch would fail: %w", err)
+	}
+
+	before, after := diffable(live), diffable(patched)
+	beforeYAML, err := yaml.Marshal(before)
+	if err != nil {
+		return nil, err
+	}
+	afterYAML, err := yaml.Marshal(after)
+	if err != nil {
+		return nil, err
+	}
+
+	text := tools.TextDiff(string(beforeYAML), string(afterYAML))
+	result := &ResourceDiff{
+		Kind:      live.GetKind(),
+		Name:      name,
+		Namespace: namespace,
+		Identical: text.Identical,
+		Additions: text.Additions,
+		Deletions: text.Deletions,
+		Changes:   []FieldChange{},
+	}
+	diffFields("", before, after, &result.Changes)
+	if !text.Identical {
+		label := strings.ToLower(result.Kind) + "/" + name
+		if namespace != "" {
+			label = namespace + "/" + label
+		}
+		result.Unified = unifiedDiff("live/"+label, "edited/"+label, text.DiffLines)
+	}
+	return result, nil
+}
+
+// diffable strips the fields the API server maintains itself
+func diffable(obj *unstructured.Unstructured) map[string]interface{} {
+	o := obj.DeepCopy()
+	unstructured.RemoveNestedField(o.Object, "status")
+	unstructured.RemoveNestedField(o.Object, "metadata", "managedFields")
+	unstructured.RemoveNestedField(o.Object, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(o.Object, "metadata", "generation")
+	return o.Object
+}
+
+// diffFields appends a change for every leaf that differs. Lists of equal
+// length are compared item by item; otherwise the whole list is reported.
+func diffFields(path string, before, after interface{}, changes *[]FieldChange) {
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+
+	switch b := before.(type) {
+	case map[string]interface{}:
+		a, ok := after.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool, len(b)+len(a))
+		for k := range b {
+			keys[k] = true
+		}
+		for k := range a {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			child := k
+			if path != "" {
+				child = path + "." + k
+			}
+			bv, inBefore := b[k]
+			av, inAfter := a[k]
+			switch {
+			case !inBefore:
+				*changes = append(*changes, FieldChange{Path: child, Op: FieldAdded, New: av})
+			case !inAfter:
+				*changes = append(*changes, FieldChange{Path: child, Op: FieldRemoved, Old: bv})
+			default:
+				diffFields(child, bv, av, changes)
+			}
+		}
+		return
+	case []interface{}:
+		a, ok := after.([]interface{})
+		if !ok || len(a) != len(b) {
+			break
+		}
+		for i := range b {
+			diffFields(path+"["+strconv.Itoa(i)+"]", b[i], a[i], changes)
+		}
+		return
+	}
+
+	*changes = append(*changes, FieldChange{Path: path, Op: FieldChanged, Old: before, New: after})
+}
+
+// unifiedDiff renders diff lines as a unified diff with hunk headers
+func unifiedDiff(fromFile, toFile string, lines []tools.DiffLine) string {
+	type numbered struct {
+		tools.DiffLine
+		oldLine, newLine int // line numbers before this line
+	}
+	all := make([]numbered, len(lines))
+	oldLine, newLine := 0, 0
+	for i, l := range lines {
+		all[i] = numbered{l, oldLine, newLine}
+		if l.Type != "add" {
+			oldLine++
+		}
+		if l.Type != "delete" {
+			newLine++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromFile, toFile)
+	for i := 0; i < len(all); {
+		if all[i].Type == "unchanged" {
+			i++
+			continue
+		}
+
+		// Grow the hunk until more than 2*diffContext unchanged lines follow
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(all); j++ {
+			if all[j].Type != "unchanged" {
+				end = j
+			} else if j-end > 2*diffContext {
+				break
+			}
+		}
+		stop := end + diffContext + 1
+		if stop > len(all) {
+			stop = len(all)
+		}
+
+		var body strings.Builder
+		oldCount, newCount := 0, 0
+		for _, l := range all[start:stop] {
+			switch l.Type {
+			case "add":
+				body.WriteString("+" + l.Content + "\n")
+				newCount++
+			case "delete":
+				body.WriteString("-" + l.Content + "\n")
+				oldCount++
+			default:
+				body.WriteString(" " + l.Content + "\n")
+				oldCount++
+				newCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", all[start].oldLine+1, oldCount, all[start].newLine+1, newCount)
+		sb.WriteString(body.String())
+		i = stop
+	}
+	return sb.String()
+}
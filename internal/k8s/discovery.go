@@ -0,0 +1,197 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// API discovery tells the UI what the cluster serves, so that features
+// depending on an optional API, such as metrics-server or the VPA CRDs, can
+// be hidden instead of failing when used. Discovery takes a request per API
+// group, so the result is cached for a few minutes.
+
+// discoveryTTL is how long a discovery result is reused
+const discoveryTTL = 5 * time.Minute
+
+// ServerVersionInfo is the API server's build
+type ServerVersionInfo struct {
+	GitVersion string `json:"git_version"`
+	Major      string `json:"major"`
+	Minor      string `json:"minor"`
+	Platform   string `json:"platform"`
+	GoVersion  string `json:"go_version"`
+	BuildDate  string `json:"build_date"`
+}
+
+// APIGroupInfo is an API group and the versions the server serves
+type APIGroupInfo struct {
+	Name             string   `json:"name"` // empty for the core group
+	PreferredVersion string   `json:"preferred_version"`
+	Versions         []string `json:"versions"`
+}
+
+// APIFeature is a GAGOS feature that needs an API the cluster may not serve
+type APIFeature struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Group       string `json:"group"`
+	Version     string `json:"version"`
+	Resource    string `json:"resource"`
+	Available   bool   `json:"available"`
+	Reason      string `json:"reason,omitempty"` // why it is not available
+}
+
+// APIDiscovery is what the cluster serves and which features it supports
+type APIDiscovery struct {
+	ServerVersion ServerVersionInfo `json:"server_version"`
+	Groups        []APIGroupInfo    `json:"groups"`
+	Features      []APIFeature      `json:"features"`
+	FailedGroups  map[string]string `json:"failed_groups,omitempty"` // group/version -> error, e.g. an unavailable aggregated API
+	Timestamp     time.Time         `json:"timestamp"`
+}
+
+// optionalAPIs are the APIs GAGOS features depend on that a cluster may
+// not serve: add-ons, CRDs, and APIs only recent Kubernetes versions have
+var optionalAPIs = []APIFeature{
+	{Name: "metrics", Description: "CPU and memory usage (metrics-server)", Group: "metrics.k8s.io", Version: "v1beta1", Resource: "nodes"},
+	{Name: "vpa", Description: "VerticalPodAutoscaler recommendations", Group: "autoscaling.k8s.io", Version: "v1", Resource: "verticalpodautoscalers"},
+	{Name: "hpa", Description: "HorizontalPodAutoscalers (autoscaling/v2)", Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"},
+	{Name: "cronjobs", Description: "CronJobs (batch/v1)", Group: "batch", Version: "v1", Resource: "cronjobs"},
+	{Name: "endpointslices", Description: "EndpointSlices", Group: "discovery.k8s.io", Version: "v1", Resource: "endpointslices"},
+	{Name: "pdbs", Description: "PodDisruptionBudgets and drain preview (policy/v1)", Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"},
+	{Name: "eviction", Description: "Node drain through the eviction API", Version: "v1", Resource: "pods/eviction"},
+	{Name: "debug", Description: "Ephemeral debug containers", Version: "v1", Resource: "pods/ephemeralcontainers"},
+	{Name: "leases", Description: "Leases and leader election", Group: "coordination.k8s.io", Version: "v1", Resource: "leases"},
+	{Name: "ingresses", Description: "Ingresses (networking.k8s.io/v1)", Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+	{Name: "networkpolicies", Description: "NetworkPolicies", Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+	{Name: "storageclasses", Description: "StorageClasses", Group: "storage.k8s.io", Version: "v1", Resource: "storageclasses"},
+}
+
+var (
+	discoveryMu     sync.Mutex
+	discoveryCached *APIDiscovery
+)
+
+// DiscoverAPIs returns the server version, the served API groups and the
+// availability of each optional API. refresh skips the cache.
+func DiscoverAPIs(refresh bool) (*APIDiscovery, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	discoveryMu.Lock()
+	defer discoveryMu.Unlock()
+	if !refresh && discoveryCached != nil && time.Since(discoveryCached.Timestamp) < discoveryTTL {
+		return discoveryCached, nil
+	}
+
+	client := clientset.Discovery()
+	version, err := client.ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server version: %w", err)
+	}
+	result := &APIDiscovery{
+		ServerVersion: ServerVersionInfo{
+			GitVersion: version.GitVersion,
+			Major:      version.Major,
+			Minor:      version.Minor,
+			Platform:   version.Platform,
+			GoVersion:  version.GoVersion,
+			BuildDate:  version.BuildDate,
+		},
+		Timestamp: time.Now(),
+	}
+
+	// An aggregated API that is down, typically metrics-server, fails its
+	// own group only; the rest of the discovery is still good
+	groups, resourceLists, err := client.ServerGroupsAndResources()
+	var failed *discovery.ErrGroupDiscoveryFailed
+	if err != nil && !errors.As(err, &failed) {
+		return nil, fmt.Errorf("failed to discover APIs: %w", err)
+	}
+	if failed != nil {
+		result.FailedGroups = make(map[string]string, len(failed.Groups))
+		for gv, gvErr := range failed.Groups {
+			result.FailedGroups[gv.String()] = gvErr.Error()
+		}
+	}
+
+	result.Groups = make([]APIGroupInfo, 0, len(groups))
+	for _, g := range groups {
+		info := APIGroupInfo{Name: g.Name, PreferredVersion: g.PreferredVersion.Version}
+		for _, v := range g.Versions {
+			info.Versions = append(info.Versions, v.Version)
+		}
+		result.Groups = append(result.Groups, info)
+	}
+	sort.Slice(result.Groups, func(i, j int) bool { return result.Groups[i].Name < result.Groups[j].Name })
+
+	served := make(map[schema.GroupVersion]map[string]bool, len(resourceLists))
+	for _, list := range resourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		resources := make(map[string]bool, len(list.APIResources))
+		for _, r := range list.APIResources {
+			resources[r.Name] = true
+		}
+		served[gv] = resources
+	}
+
+	result.Features = make([]APIFeature, 0, len(optionalAPIs))
+	for _, f := range optionalAPIs {
+		gv := schema.GroupVersion{Group: f.Group, Version: f.Version}
+		switch {
+		case served[gv][f.Resource]:
+			f.Available = true
+		case failed != nil && failed.Groups[gv] != nil:
+			f.Reason = fmt.Sprintf("%s is registered but not responding: %v", gv, failed.Groups[gv])
+		case served[gv] != nil:
+			f.Reason = fmt.Sprintf("%s does not serve %s", gv, f.Resource)
+		default:
+			f.Reason = fmt.Sprintf("%s is not served by this cluster", groupVersionName(gv))
+		}
+		result.Features = append(result.Features, f)
+	}
+
+	discoveryCached = result
+	return result, nil
+}
+
+// Ping checks that the API server answers within timeout
+func Ping(timeout time.Duration) error {
+	if clientset == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var err error
+	if client, ok := clientset.Discovery().(*discovery.DiscoveryClient); ok {
+		err = client.RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+	} else {
+		// A fake clientset has no REST client
+		_, err = clientset.Discovery().ServerVersion()
+	}
+	if err != nil {
+		return fmt.Errorf("API server unreachable: %w", err)
+	}
+	return nil
+}
+
+func groupVersionName(gv schema.GroupVersion) string {
+	if gv.Group == "" {
+		return "core/" + gv.Version
+	}
+	return gv.String()
+}
@@ -0,0 +1,230 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// A drain preview works out what `kubectl drain` would do to each pod on a
+// node without evicting anything. PodDisruptionBudgets are checked against
+// the disruptions they allow right now, so the preview is only as good as
+// the moment it was taken.
+
+// What a drain does to a pod
+const (
+	DrainEvict        = "evict"
+	DrainBlockedByPDB = "blocked-by-pdb"   // eviction is refused until the budget allows it
+	DrainUnmanaged    = "unmanaged"        // no controller recreates it; needs --force
+	DrainDaemonSet    = "daemonset"        // left running; needs --ignore-daemonsets
+	DrainMirror       = "mirror"           // static pod, managed by the kubelet
+	DrainCompleted    = "delete-completed" // finished pods are deleted
+)
+
+// mirrorPodAnnotation marks the API copy of a static pod
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// DrainPod is what a drain would do to one pod
+type DrainPod struct {
+	Namespace    string   `json:"namespace"`
+	Name         string   `json:"name"`
+	Controller   string   `json:"controller,omitempty"` // Kind/name of the owning controller
+	Phase        string   `json:"phase"`
+	Action       string   `json:"action"`
+	PDBs         []string `json:"pdbs,omitempty"`          // namespace/name of the budgets covering the pod
+	LocalStorage bool     `json:"local_storage,omitempty"` // emptyDir data is lost; needs --delete-emptydir-data
+	Reason       string   `json:"reason,omitempty"`
+}
+
+// DrainPDB is a budget that covers pods on the node
+type DrainPDB struct {
+	Namespace          string `json:"namespace"`
+	Name               string `json:"name"`
+	DisruptionsAllowed int32  `json:"disruptions_allowed"`
+	PodsOnNode         int    `json:"pods_on_node"`
+	Blocked            int    `json:"blocked"`
+}
+
+// DrainPreview is the simulated drain of a node
+type DrainPreview struct {
+	Node        string         `json:"node"`
+	Cordoned    bool           `json:"cordoned"`
+	Pods        []DrainPod     `json:"pods"`
+	PDBs        []DrainPDB     `json:"pdbs"`
+	Counts      map[string]int `json:"counts"`       // pods per action
+	Blockers    []string       `json:"blockers"`     // why a plain `kubectl drain` would stop
+	CanComplete bool           `json:"can_complete"` // every pod can leave now with the needed flags
+}
+
+// PreviewDrain simulates draining a node: which pods would be evicted, which
+// are held by a PodDisruptionBudget, which have no controller, and which a
+// drain leaves alone
+func PreviewDrain(ctx context.Context, nodeName string) (*DrainPreview, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	node, err := clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	pdbs, err := clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PodDisruptionBudgets: %w", err)
+	}
+
+	preview := &DrainPreview{
+		Node:     nodeName,
+		Cordoned: node.Spec.Unschedulable,
+		Pods:     []DrainPod{},
+		PDBs:     []DrainPDB{},
+		Counts:   map[string]int{},
+		Blockers: []string{},
+	}
+
+	sort.Slice(pods.Items, func(i, j int) bool {
+		a, b := pods.Items[i], pods.Items[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	// Disruptions each budget has given to pods on the node so far
+	budgets := map[string]*DrainPDB{}
+	used := map[string]int32{}
+	var budgetOrder []string
+	unmanaged, daemonsets, localStorage := 0, 0, 0
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		dp := DrainPod{Namespace: pod.Namespace, Name: pod.Name, Phase: string(pod.Status.Phase)}
+		controller := metav1.GetControllerOf(pod)
+		if controller != nil {
+			dp.Controller = controller.Kind + "/" + controller.Name
+		}
+		for _, v := range pod.Spec.Volumes {
+			if v.EmptyDir != nil {
+				dp.LocalStorage = true
+			}
+		}
+
+		switch {
+		case pod.Annotations[mirrorPodAnnotation] != "":
+			dp.Action = DrainMirror
+			dp.Reason = "static pods are managed by the kubelet and stay on the node"
+		case pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed:
+			dp.Action = DrainCompleted
+		case controller != nil && controller.Kind == "DaemonSet":
+			dp.Action = DrainDaemonSet
+			dp.Reason = "DaemonSet pods are ignored and keep running"
+			daemonsets++
+		default:
+			dp.Action = DrainEvict
+			if controller == nil {
+				dp.Action = DrainUnmanaged
+				dp.Reason = "no controller will recreate it; the pod is deleted for good"
+				unmanaged++
+			}
+			if dp.LocalStorage {
+				localStorage++
+			}
+			for j := range pdbs.Items {
+				pdb := &pdbs.Items[j]
+				if !pdbCovers(pdb, pod) {
+					continue
+				}
+				key := pdb.Namespace + "/" + pdb.Name
+				dp.PDBs = append(dp.PDBs, key)
+				budget, ok := budgets[key]
+				if !ok {
+					budget = &DrainPDB{Namespace: pdb.Namespace, Name: pdb.Name, DisruptionsAllowed: pdb.Status.DisruptionsAllowed}
+					budgets[key] = budget
+					budgetOrder = append(budgetOrder, key)
+				}
+				budget.PodsOnNode++
+				if pdbSkipsPod(pdb, pod) {
+					continue
+				}
+				if used[key] >= budget.DisruptionsAllowed {
+					budget.Blocked++
+					dp.Action = DrainBlockedByPDB
+					dp.Reason = fmt.Sprintf("%s allows %d disruption(s) now", key, pdb.Status.DisruptionsAllowed)
+				} else {
+					used[key]++
+				}
+			}
+			if len(dp.PDBs) > 1 {
+				dp.Action = DrainBlockedByPDB
+				dp.Reason = "covered by more than one PodDisruptionBudget, which the eviction API refuses"
+			}
+		}
+		preview.Counts[dp.Action]++
+		preview.Pods = append(preview.Pods, dp)
+	}
+	for _, key := range budgetOrder {
+		preview.PDBs = append(preview.PDBs, *budgets[key])
+	}
+
+	if daemonsets > 0 {
+		preview.Blockers = append(preview.Blockers, fmt.Sprintf("%d DaemonSet-managed pod(s): use --ignore-daemonsets", daemonsets))
+	}
+	if unmanaged > 0 {
+		preview.Blockers = append(preview.Blockers, fmt.Sprintf("%d pod(s) without a controller: use --force to delete them", unmanaged))
+	}
+	if localStorage > 0 {
+		preview.Blockers = append(preview.Blockers, fmt.Sprintf("%d pod(s) with emptyDir volumes: use --delete-emptydir-data, their data is lost", localStorage))
+	}
+	if n := preview.Counts[DrainBlockedByPDB]; n > 0 {
+		preview.Blockers = append(preview.Blockers, fmt.Sprintf("%d pod(s) held by PodDisruptionBudgets: the drain waits until replacements are ready elsewhere", n))
+	}
+	preview.CanComplete = preview.Counts[DrainBlockedByPDB] == 0
+	return preview, nil
+}
+
+// pdbCovers reports whether the budget's selector matches the pod
+func pdbCovers(pdb *policyv1.PodDisruptionBudget, pod *corev1.Pod) bool {
+	if pdb.Namespace != pod.Namespace || pdb.Spec.Selector == nil {
+		return false
+	}
+	// In policy/v1 an empty selector matches every pod in the namespace
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(pod.Labels))
+}
+
+// pdbSkipsPod reports whether the eviction API lets the pod go without
+// using up the budget: an unready pod under the AlwaysAllow policy, or
+// one that is not running yet
+func pdbSkipsPod(pdb *policyv1.PodDisruptionBudget, pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodPending {
+		return true
+	}
+	policy := pdb.Spec.UnhealthyPodEvictionPolicy
+	return policy != nil && *policy == policyv1.AlwaysAllow && !podReady(pod)
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
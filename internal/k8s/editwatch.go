@@ -0,0 +1,265 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// An edit watch follows the objects a client has open in the YAML editor,
+// on the same informers as WatchResources, and sends a CONFLICT event when
+// one of them changes on the server. Only changes to the object itself
+// count: status updates and bookkeeping such as managedFields do not, so a
+// Deployment rolling out its pods does not raise a conflict.
+
+// WatchEventConflict is sent when an object open in the editor changed
+const WatchEventConflict = "CONFLICT"
+
+// MaxEditWatches caps the objects one client can have open
+const MaxEditWatches = 50
+
+// EditConflict is the object of a CONFLICT event
+type EditConflict struct {
+	EditingVersion  string `json:"editing_version"`            // the version the editor loaded
+	ResourceVersion string `json:"resource_version,omitempty"` // the version now on the server
+	Deleted         bool   `json:"deleted,omitempty"`
+	Manager         string `json:"manager,omitempty"` // field manager of the latest change, e.g. kubectl-edit
+	ChangedAt       string `json:"changed_at,omitempty"`
+}
+
+// EditWatch tracks the objects one client has open for editing
+type EditWatch struct {
+	ctx    context.Context
+	events chan<- WatchEvent
+
+	mu       sync.Mutex
+	open     map[string]*editSession
+	handlers map[string]cache.ResourceEventHandlerRegistration
+}
+
+type editSession struct {
+	loaded      string // resourceVersion the editor loaded
+	fingerprint string // content last seen, empty until the object is seen
+}
+
+// NewEditWatch sends the conflicts of the objects opened with Open into
+// events until ctx is cancelled
+func NewEditWatch(ctx context.Context, events chan<- WatchEvent) *EditWatch {
+	w := &EditWatch{
+		ctx:      ctx,
+		events:   events,
+		open:     make(map[string]*editSession),
+		handlers: make(map[string]cache.ResourceEventHandlerRegistration),
+	}
+	go func() {
+		<-ctx.Done()
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		factory := sharedInformerFactory()
+		for kind, handle := range w.handlers {
+			watchKinds[kind].informer(factory).RemoveEventHandler(handle)
+		}
+		w.handlers = nil
+	}()
+	return w
+}
+
+// Open starts following an object loaded at resourceVersion. kind is a
+// watch kind; the singular form used by the resource routes is accepted.
+// If the object has changed since it was loaded, a conflict is sent at once.
+func (w *EditWatch) Open(kind, namespace, name, resourceVersion string) error {
+	if clientset == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+	kind, ok := watchKindName(kind)
+	if !ok {
+		return fmt.Errorf("unsupported kind: %s", kind)
+	}
+	if name == "" || resourceVersion == "" {
+		return fmt.Errorf("name and resource_version are required")
+	}
+
+	w.mu.Lock()
+	if w.handlers == nil {
+		w.mu.Unlock()
+		return w.ctx.Err()
+	}
+	key := editKey(kind, namespace, name)
+	if _, exists := w.open[key]; !exists && len(w.open) >= MaxEditWatches {
+		w.mu.Unlock()
+		return fmt.Errorf("at most %d objects can be open for editing", MaxEditWatches)
+	}
+	w.open[key] = &editSession{loaded: resourceVersion}
+
+	factory := sharedInformerFactory()
+	informer := watchKinds[kind].informer(factory)
+	if _, registered := w.handlers[kind]; !registered {
+		// A new handler is replayed the objects in the cache, which checks
+		// this one too
+		handle, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { w.check(kind, obj, false) },
+			UpdateFunc: func(_, obj interface{}) { w.check(kind, obj, false) },
+			DeleteFunc: func(obj interface{}) { w.check(kind, obj, true) },
+		})
+		if err != nil {
+			delete(w.open, key)
+			w.mu.Unlock()
+			return fmt.Errorf("failed to register %s watch: %w", kind, err)
+		}
+		w.handlers[kind] = handle
+		w.mu.Unlock()
+		factory.Start(informerStop)
+		return nil
+	}
+	w.mu.Unlock()
+
+	if obj, exists, err := informer.GetStore().GetByKey(cacheKey(namespace, name)); err == nil && exists {
+		w.check(kind, obj, false)
+	}
+	return nil
+}
+
+// Close stops following an object, e.g. before the editor saves it
+func (w *EditWatch) Close(kind, namespace, name string) {
+	kind, _ = watchKindName(kind)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.open, editKey(kind, namespace, name))
+}
+
+// check sends a conflict when obj is open and its content moved on from
+// what the editor has
+func (w *EditWatch) check(kind string, obj interface{}, deleted bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	key := editKey(kind, accessor.GetNamespace(), accessor.GetName())
+
+	w.mu.Lock()
+	session, ok := w.open[key]
+	if !ok {
+		w.mu.Unlock()
+		return
+	}
+	conflict := EditConflict{EditingVersion: session.loaded}
+	if deleted {
+		delete(w.open, key)
+		conflict.Deleted = true
+	} else {
+		fingerprint := editFingerprint(obj)
+		first := session.fingerprint == ""
+		changed := session.fingerprint != fingerprint
+		session.fingerprint = fingerprint
+		if first && accessor.GetResourceVersion() == session.loaded || !first && !changed {
+			w.mu.Unlock()
+			return
+		}
+		conflict.ResourceVersion = accessor.GetResourceVersion()
+		conflict.Manager, conflict.ChangedAt = latestManager(accessor.GetManagedFields())
+	}
+	w.mu.Unlock()
+
+	select {
+	case w.events <- WatchEvent{
+		Type:      WatchEventConflict,
+		Kind:      kind,
+		Namespace: accessor.GetNamespace(),
+		Name:      accessor.GetName(),
+		Object:    conflict,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}:
+	case <-w.ctx.Done():
+	}
+}
+
+// editFingerprint hashes an object without its status and the metadata the
+// server changes on its own
+func editFingerprint(obj interface{}) string {
+	ro, ok := obj.(runtime.Object)
+	if !ok {
+		return ""
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ro)
+	if err != nil {
+		return ""
+	}
+	// Type meta is set on some decoded objects and not on others
+	for _, field := range []string{"status", "kind", "apiVersion"} {
+		delete(content, field)
+	}
+	if md, ok := content["metadata"].(map[string]interface{}); ok {
+		for _, field := range []string{"resourceVersion", "managedFields", "generation"} {
+			delete(md, field)
+		}
+	}
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// latestManager returns the field manager that last changed the object
+// outside its status
+func latestManager(fields []metav1.ManagedFieldsEntry) (string, string) {
+	var manager string
+	var latest time.Time
+	for _, f := range fields {
+		if f.Subresource == "status" || f.Time == nil {
+			continue
+		}
+		if f.Time.Time.After(latest) || f.Time.Time.Equal(latest) {
+			manager, latest = f.Manager, f.Time.Time
+		}
+	}
+	if manager == "" {
+		return "", ""
+	}
+	return manager, latest.UTC().Format(time.RFC3339)
+}
+
+// watchKindName maps the singular kind of the resource routes, such as
+// deployment or ingress, to its watch kind
+func watchKindName(kind string) (string, bool) {
+	kind = strings.ToLower(kind)
+	candidates := []string{kind, kind + "s", kind + "es"}
+	if strings.HasSuffix(kind, "y") {
+		candidates = append(candidates, strings.TrimSuffix(kind, "y")+"ies")
+	}
+	for _, c := range candidates {
+		if _, ok := watchKinds[c]; ok {
+			return c, true
+		}
+	}
+	return kind, false
+}
+
+func editKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// cacheKey is the informer store key of an object
+func cacheKey(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
@@ -0,0 +1,248 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// eventKinds maps the kind names used in URLs and filters to the Kind found
+// in an event's involvedObject. Unknown kinds are used as given, so custom
+// resources still match by their exact Kind.
+var eventKinds = map[string]string{
+	"pod": "Pod", "pods": "Pod",
+	"deployment": "Deployment", "deployments": "Deployment",
+	"replicaset": "ReplicaSet", "replicasets": "ReplicaSet",
+	"statefulset": "StatefulSet", "statefulsets": "StatefulSet",
+	"daemonset": "DaemonSet", "daemonsets": "DaemonSet",
+	"job": "Job", "jobs": "Job",
+	"cronjob": "CronJob", "cronjobs": "CronJob",
+	"service": "Service", "services": "Service",
+	"endpoints": "Endpoints",
+	"configmap": "ConfigMap", "configmaps": "ConfigMap",
+	"secret": "Secret", "secrets": "Secret",
+	"ingress": "Ingress", "ingresses": "Ingress",
+	"pvc": "PersistentVolumeClaim", "pvcs": "PersistentVolumeClaim", "persistentvolumeclaims": "PersistentVolumeClaim",
+	"pv": "PersistentVolume", "pvs": "PersistentVolume", "persistentvolumes": "PersistentVolume",
+	"hpa": "HorizontalPodAutoscaler", "horizontalpodautoscalers": "HorizontalPodAutoscaler",
+	"node": "Node", "nodes": "Node",
+	"namespace": "Namespace", "namespaces": "Namespace",
+}
+
+// clusterEventKinds are the kinds whose events are not tied to their own namespace
+var clusterEventKinds = map[string]bool{"Node": true, "PersistentVolume": true, "Namespace": true}
+
+func eventKind(kind string) string {
+	if k, ok := eventKinds[strings.ToLower(kind)]; ok {
+		return k
+	}
+	return kind
+}
+
+// EventFilter selects events by namespace, type and involved object. Empty
+// fields match everything.
+type EventFilter struct {
+	Namespaces []string
+	Types      []string // Normal, Warning
+	Kind       string   // involved object kind, e.g. pod or Pod
+	Name       string   // involved object name
+	Reason     string
+}
+
+// Matches reports whether e passes the filter
+func (f EventFilter) Matches(e *corev1.Event) bool {
+	if len(f.Namespaces) > 0 && !containsFold(f.Namespaces, e.Namespace) {
+		return false
+	}
+	if len(f.Types) > 0 && !containsFold(f.Types, e.Type) {
+		return false
+	}
+	if f.Kind != "" && eventKind(f.Kind) != e.InvolvedObject.Kind {
+		return false
+	}
+	if f.Name != "" && f.Name != e.InvolvedObject.Name {
+		return false
+	}
+	return f.Reason == "" || f.Reason == e.Reason
+}
+
+// FieldSelector is the server-side equivalent of the filter, leaving out the
+// namespaces. Several types cannot be expressed and are not included.
+func (f EventFilter) FieldSelector() string {
+	set := fields.Set{}
+	if len(f.Types) == 1 {
+		set["type"] = f.Types[0]
+	}
+	if f.Kind != "" {
+		set["involvedObject.kind"] = eventKind(f.Kind)
+	}
+	if f.Name != "" {
+		set["involvedObject.name"] = f.Name
+	}
+	if f.Reason != "" {
+		set["reason"] = f.Reason
+	}
+	if len(set) == 0 {
+		return ""
+	}
+	return fields.SelectorFromSet(set).String()
+}
+
+func containsFold(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchEvents streams the events matching filter, cluster-wide unless the
+// filter names namespaces, until ctx is cancelled. Events already in the
+// informer cache are replayed as ADDED first.
+func WatchEvents(ctx context.Context, filter EventFilter, events chan<- WatchEvent) error {
+	if clientset == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+	return watchInformers(ctx, []string{"events"}, func(_ string, obj interface{}) bool {
+		e, ok := obj.(*corev1.Event)
+		return ok && filter.Matches(e)
+	}, events)
+}
+
+// EventCorrelation holds the events of a resource and of the objects it owns
+type EventCorrelation struct {
+	Kind      string      `json:"kind"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Related   []string    `json:"related"` // owned objects as Kind/name
+	Warnings  int         `json:"warnings"`
+	Events    []EventInfo `json:"events"`
+}
+
+// CorrelatedEvents returns the events of a resource together with those of
+// the objects it owns: a Deployment's ReplicaSets and Pods, a CronJob's Jobs
+// and their Pods, the Pods of a StatefulSet, DaemonSet or Job. Events are
+// sorted most recent first.
+func CorrelatedEvents(ctx context.Context, kind, namespace, name string) (*EventCorrelation, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	kind = eventKind(kind)
+	if clusterEventKinds[kind] {
+		namespace = ""
+	}
+	objects := map[string]bool{kind + "/" + name: true}
+	result := &EventCorrelation{Kind: kind, Name: name, Namespace: namespace, Related: []string{}, Events: []EventInfo{}}
+
+	if namespace != "" {
+		related, err := ownedObjects(ctx, namespace, kind, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range related {
+			objects[key] = true
+		}
+		result.Related = related
+	}
+
+	opts := metav1.ListOptions{}
+	if namespace == "" {
+		// Cluster-scoped objects are looked up across namespaces
+		opts.FieldSelector = EventFilter{Kind: kind, Name: name}.FieldSelector()
+	}
+	list, err := clientset.CoreV1().Events(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []*corev1.Event
+	for i := range list.Items {
+		e := &list.Items[i]
+		if objects[e.InvolvedObject.Kind+"/"+e.InvolvedObject.Name] {
+			matched = append(matched, e)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return eventLastSeen(matched[i]).After(eventLastSeen(matched[j]))
+	})
+	for _, e := range matched {
+		if e.Type == corev1.EventTypeWarning {
+			result.Warnings++
+		}
+		result.Events = append(result.Events, eventToInfo(e))
+	}
+	return result, nil
+}
+
+// ownedObjects walks ownerReferences down from kind/name and returns the
+// owned ReplicaSets, Jobs and Pods as Kind/name
+func ownedObjects(ctx context.Context, namespace, kind, name string) ([]string, error) {
+	owners := map[string]bool{kind + "/" + name: true}
+	var related []string
+	add := func(childKind string, meta metav1.ObjectMeta) {
+		for _, ref := range meta.OwnerReferences {
+			if owners[ref.Kind+"/"+ref.Name] {
+				key := childKind + "/" + meta.Name
+				owners[key] = true
+				related = append(related, key)
+				return
+			}
+		}
+	}
+
+	// Owners come before what they own: Deployment > ReplicaSet > Pod and
+	// CronJob > Job > Pod
+	switch kind {
+	case "Deployment":
+		rs, err := clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs.Items {
+			add("ReplicaSet", r.ObjectMeta)
+		}
+	case "CronJob":
+		jobs, err := clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, j := range jobs.Items {
+			add("Job", j.ObjectMeta)
+		}
+	case "ReplicaSet", "StatefulSet", "DaemonSet", "Job":
+	default:
+		return related, nil
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pods.Items {
+		add("Pod", p.ObjectMeta)
+	}
+	return related, nil
+}
+
+// eventLastSeen is when the event last occurred; events.k8s.io events only
+// set eventTime
+func eventLastSeen(e *corev1.Event) time.Time {
+	switch {
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	}
+	return e.CreationTimestamp.Time
+}
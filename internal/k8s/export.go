@@ -0,0 +1,258 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// A namespace export is every object a user created in the namespace, as
+// YAML that can be applied to another cluster. Objects a controller owns
+// are left out, since their owner recreates them, and so is everything the
+// server fills in.
+
+// exportSkipResources are namespaced resources that hold runtime state or
+// are derived from other objects
+var exportSkipResources = map[string]bool{
+	"events":                          true,
+	"events.events.k8s.io":            true,
+	"endpoints":                       true,
+	"endpointslices.discovery.k8s.io": true,
+	"leases.coordination.k8s.io":      true,
+	"pods.metrics.k8s.io":             true,
+	"controllerrevisions.apps":        true,
+}
+
+// exportSkipAnnotations are set by the server or kubectl, not by the user
+var exportSkipAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"deployment.kubernetes.io/revision",
+	"pv.kubernetes.io/",
+	"volume.kubernetes.io/",
+	"volume.beta.kubernetes.io/storage-provisioner",
+	"control-plane.alpha.kubernetes.io/leader",
+}
+
+// jobControllerLabels are added to a Job's selector and pod template by the
+// Job controller and name the uid of the Job they were made for
+var jobControllerLabels = []string{"controller-uid", "batch.kubernetes.io/controller-uid", "job-name", "batch.kubernetes.io/job-name"}
+
+// ExportOptions selects what a namespace export includes
+type ExportOptions struct {
+	IncludeSecrets bool
+}
+
+// NamespaceExport is a namespace export ready to be written
+type NamespaceExport struct {
+	Namespace string
+	client    dynamic.Interface
+	resources []schema.GroupVersionResource
+}
+
+// PrepareExport checks the namespace exists and discovers the resources to
+// export, so that errors come before any of the archive is written
+func PrepareExport(ctx context.Context, namespace string, opts ExportOptions) (*NamespaceExport, error) {
+	dc, _, err := getDynamic()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err != nil {
+		return nil, err
+	}
+
+	lists, err := clientset.Discovery().ServerPreferredNamespacedResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("failed to discover resources: %w", err)
+	}
+	// A group that failed discovery (often an unavailable metrics API) is skipped
+
+	export := &NamespaceExport{Namespace: namespace, client: dc}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			if strings.Contains(r.Name, "/") || !containsFold(r.Verbs, "list") {
+				continue
+			}
+			gvr := gv.WithResource(r.Name)
+			if exportSkipResources[exportResourceName(gvr)] {
+				continue
+			}
+			if gvr.Group == "" && gvr.Resource == "secrets" && !opts.IncludeSecrets {
+				continue
+			}
+			export.resources = append(export.resources, gvr)
+		}
+	}
+	sort.Slice(export.resources, func(i, j int) bool {
+		return exportResourceName(export.resources[i]) < exportResourceName(export.resources[j])
+	})
+	return export, nil
+}
+
+// Write streams the export to w as a tar.gz with one file per object,
+// <namespace>/<resource>/<name>.yaml. Resources that cannot be listed are
+// recorded in <namespace>/export-errors.txt rather than failing the export.
+func (e *NamespaceExport) Write(ctx context.Context, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	var failures []string
+	for _, gvr := range e.resources {
+		name := exportResourceName(gvr)
+		opts := metav1.ListOptions{Limit: 500}
+		for {
+			list, err := e.client.Resource(gvr).Namespace(e.Namespace).List(ctx, opts)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+				break
+			}
+			for i := range list.Items {
+				obj := &list.Items[i]
+				if !exportable(gvr, obj) {
+					continue
+				}
+				cleanExportObject(gvr, obj)
+				data, err := yaml.Marshal(obj.Object)
+				if err != nil {
+					failures = append(failures, fmt.Sprintf("%s/%s: %v", name, obj.GetName(), err))
+					continue
+				}
+				if err := writeTarFile(tw, fmt.Sprintf("%s/%s/%s.yaml", e.Namespace, name, obj.GetName()), data, now); err != nil {
+					return err
+				}
+			}
+			if list.GetContinue() == "" {
+				break
+			}
+			opts.Continue = list.GetContinue()
+		}
+	}
+
+	if len(failures) > 0 {
+		data := []byte(strings.Join(failures, "\n") + "\n")
+		if err := writeTarFile(tw, e.Namespace+"/export-errors.txt", data, now); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// exportResourceName is resource.group, or the resource for the core group
+func exportResourceName(gvr schema.GroupVersionResource) string {
+	if gvr.Group == "" {
+		return gvr.Resource
+	}
+	return gvr.Resource + "." + gvr.Group
+}
+
+// exportable leaves out objects recreated by a controller or by Kubernetes
+func exportable(gvr schema.GroupVersionResource, obj *unstructured.Unstructured) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Controller != nil && *ref.Controller {
+			return false
+		}
+	}
+	if gvr.Group != "" {
+		return true
+	}
+	switch gvr.Resource {
+	case "configmaps":
+		return obj.GetName() != "kube-root-ca.crt"
+	case "secrets":
+		t, _, _ := unstructured.NestedString(obj.Object, "type")
+		return t != "kubernetes.io/service-account-token"
+	}
+	return true
+}
+
+// cleanExportObject drops status, server-set metadata and the fields that
+// tie an object to the cluster it was read from
+func cleanExportObject(gvr schema.GroupVersionResource, obj *unstructured.Unstructured) {
+	delete(obj.Object, "status")
+	for _, field := range []string{"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields", "selfLink", "ownerReferences", "deletionTimestamp", "deletionGracePeriodSeconds"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	if annotations := obj.GetAnnotations(); len(annotations) > 0 {
+		for key := range annotations {
+			if exportSkipAnnotation(key) {
+				delete(annotations, key)
+			}
+		}
+		if len(annotations) == 0 {
+			unstructured.RemoveNestedField(obj.Object, "metadata", "annotations")
+		} else {
+			obj.SetAnnotations(annotations)
+		}
+	}
+
+	switch exportResourceName(gvr) {
+	case "services":
+		// Headless services keep clusterIP: None; other addresses are
+		// allocated again by the target cluster
+		if ip, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP"); ip != "None" {
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+		}
+	case "persistentvolumeclaims":
+		unstructured.RemoveNestedField(obj.Object, "spec", "volumeName")
+	case "pods":
+		unstructured.RemoveNestedField(obj.Object, "spec", "nodeName")
+	case "jobs.batch":
+		if manual, _, _ := unstructured.NestedBool(obj.Object, "spec", "manualSelector"); !manual {
+			unstructured.RemoveNestedField(obj.Object, "spec", "selector")
+			for _, label := range jobControllerLabels {
+				unstructured.RemoveNestedField(obj.Object, "spec", "template", "metadata", "labels", label)
+			}
+		}
+	}
+}
+
+// exportSkipAnnotation reports whether key is one of exportSkipAnnotations,
+// or falls under one that ends in a slash
+func exportSkipAnnotation(key string) bool {
+	for _, skip := range exportSkipAnnotations {
+		if key == skip || strings.HasSuffix(skip, "/") && strings.HasPrefix(key, skip) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
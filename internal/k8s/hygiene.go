@@ -0,0 +1,325 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gaga951/gagos/internal/fanout"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The hygiene report finds objects a cluster accumulates and nobody cleans
+// up: ConfigMaps and Secrets nothing uses, released volumes, old failed Jobs
+// and ReplicaSets scaled to zero. It is conservative: objects with an owner,
+// or created by Kubernetes or Helm for their own use, are never reported.
+
+// Kinds the hygiene report covers
+const (
+	HygieneConfigMaps  = "configmaps"
+	HygieneSecrets     = "secrets"
+	HygienePVs         = "pvs"
+	HygieneJobs        = "jobs"
+	HygieneReplicaSets = "replicasets"
+)
+
+// DefaultFailedJobAgeDays is how long a failed Job is kept before reported
+const DefaultFailedJobAgeDays = 7
+
+// systemNamespaces are skipped unless asked for; their controllers read
+// ConfigMaps and Secrets directly rather than through pods
+var systemNamespaces = map[string]bool{"kube-system": true, "kube-public": true, "kube-node-lease": true}
+
+// unreportedSecretTypes are managed by Kubernetes or Helm
+var unreportedSecretTypes = map[corev1.SecretType]bool{
+	corev1.SecretTypeServiceAccountToken: true,
+	corev1.SecretTypeBootstrapToken:      true,
+	"helm.sh/release.v1":                 true,
+}
+
+// HygieneOptions scopes a hygiene report
+type HygieneOptions struct {
+	Namespace        string `json:"namespace,omitempty"` // all namespaces when empty
+	FailedJobAgeDays int    `json:"failed_job_age_days,omitempty"`
+	IncludeSystem    bool   `json:"include_system,omitempty"` // also scan kube-system, kube-public and kube-node-lease
+}
+
+// HygieneItem is an object the report suggests deleting
+type HygieneItem struct {
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace,omitempty"`
+	Name      string    `json:"name"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+func (i HygieneItem) key() string {
+	return i.Kind + "/" + i.Namespace + "/" + i.Name
+}
+
+// HygieneReport lists the cleanup candidates, grouped by kind in Counts
+type HygieneReport struct {
+	Items       []HygieneItem  `json:"items"`
+	Counts      map[string]int `json:"counts"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+// Cleanup actions reported per item
+const (
+	HygieneDeleted = "deleted"
+	HygieneMatched = "matched" // dry run
+	HygieneSkipped = "skipped" // no longer in the report
+	HygieneFailed  = "failed"
+)
+
+// HygieneResult is the outcome of cleaning up one item
+type HygieneResult struct {
+	HygieneItem
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// GetHygieneReport scans for cleanup candidates
+func GetHygieneReport(ctx context.Context, opts HygieneOptions) (*HygieneReport, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	if opts.FailedJobAgeDays <= 0 {
+		opts.FailedJobAgeDays = DefaultFailedJobAgeDays
+	}
+	ns := opts.Namespace
+	skip := func(namespace string) bool {
+		return !opts.IncludeSystem && systemNamespaces[namespace]
+	}
+	list := metav1.ListOptions{}
+	report := &HygieneReport{Items: []HygieneItem{}, Counts: map[string]int{}, GeneratedAt: time.Now()}
+	add := func(kind string, meta metav1.ObjectMeta, reason string) {
+		report.Items = append(report.Items, HygieneItem{
+			Kind:      kind,
+			Namespace: meta.Namespace,
+			Name:      meta.Name,
+			Reason:    reason,
+			CreatedAt: meta.CreationTimestamp.Time,
+		})
+		report.Counts[kind]++
+	}
+
+	// Everything a pod, pod template, ingress or service account uses, as
+	// namespace/kind/name
+	used := map[string]bool{}
+	use := func(namespace string, obj interface{}) {
+		for _, ref := range objectReferences(obj) {
+			used[namespace+"/"+ref] = true
+		}
+	}
+	pods, err := clientset.CoreV1().Pods(ns).List(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		use(pods.Items[i].Namespace, &pods.Items[i])
+	}
+	deployments, err := clientset.AppsV1().Deployments(ns).List(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		use(deployments.Items[i].Namespace, &deployments.Items[i])
+	}
+	statefulSets, err := clientset.AppsV1().StatefulSets(ns).List(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		use(statefulSets.Items[i].Namespace, &statefulSets.Items[i])
+	}
+	daemonSets, err := clientset.AppsV1().DaemonSets(ns).List(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		use(daemonSets.Items[i].Namespace, &daemonSets.Items[i])
+	}
+	cronJobs, err := clientset.BatchV1().CronJobs(ns).List(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cronJobs.Items {
+		use(cronJobs.Items[i].Namespace, &cronJobs.Items[i])
+	}
+	jobs, err := clientset.BatchV1().Jobs(ns).List(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs.Items {
+		use(jobs.Items[i].Namespace, &jobs.Items[i])
+	}
+	ingresses, err := clientset.NetworkingV1().Ingresses(ns).List(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ingresses.Items {
+		use(ingresses.Items[i].Namespace, &ingresses.Items[i])
+	}
+	serviceAccounts, err := clientset.CoreV1().ServiceAccounts(ns).List(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	for _, sa := range serviceAccounts.Items {
+		for _, s := range sa.Secrets {
+			used[sa.Namespace+"/secret/"+s.Name] = true
+		}
+		for _, s := range sa.ImagePullSecrets {
+			used[sa.Namespace+"/secret/"+s.Name] = true
+		}
+	}
+
+	configMaps, err := clientset.CoreV1().ConfigMaps(ns).List(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	for _, cm := range configMaps.Items {
+		// kube-root-ca.crt is published into every namespace and mounted
+		// through projected service account volumes
+		if skip(cm.Namespace) || len(cm.OwnerReferences) > 0 || cm.Name == "kube-root-ca.crt" || used[cm.Namespace+"/configmap/"+cm.Name] {
+			continue
+		}
+		add(HygieneConfigMaps, cm.ObjectMeta, "not used by any pod or workload")
+	}
+
+	secrets, err := clientset.CoreV1().Secrets(ns).List(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range secrets.Items {
+		if skip(s.Namespace) || len(s.OwnerReferences) > 0 || unreportedSecretTypes[s.Type] || used[s.Namespace+"/secret/"+s.Name] {
+			continue
+		}
+		add(HygieneSecrets, s.ObjectMeta, "not used by any pod, workload, ingress or service account")
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -opts.FailedJobAgeDays)
+	for _, job := range jobs.Items {
+		if skip(job.Namespace) || len(job.OwnerReferences) > 0 {
+			continue
+		}
+		for _, cond := range job.Status.Conditions {
+			if cond.Type == "Failed" && cond.Status == corev1.ConditionTrue && cond.LastTransitionTime.Time.Before(cutoff) {
+				add(HygieneJobs, job.ObjectMeta, fmt.Sprintf("failed %s ago: %s", formatAge(cond.LastTransitionTime.Time), cond.Reason))
+				break
+			}
+		}
+	}
+
+	replicaSets, err := clientset.AppsV1().ReplicaSets(ns).List(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	for _, rs := range replicaSets.Items {
+		if skip(rs.Namespace) || rs.Spec.Replicas == nil || *rs.Spec.Replicas != 0 || rs.Status.Replicas != 0 {
+			continue
+		}
+		reason := "scaled to zero"
+		for _, owner := range rs.OwnerReferences {
+			if owner.Kind == "Deployment" {
+				reason = fmt.Sprintf("old revision of deployment %s; revisionHistoryLimit keeps these for rollback", owner.Name)
+			}
+		}
+		add(HygieneReplicaSets, rs.ObjectMeta, reason)
+	}
+
+	// PersistentVolumes are cluster-scoped, so only a cluster-wide report
+	// includes them
+	if ns == "" {
+		pvs, err := clientset.CoreV1().PersistentVolumes().List(ctx, list)
+		if err != nil {
+			return nil, err
+		}
+		for _, pv := range pvs.Items {
+			switch pv.Status.Phase {
+			case corev1.VolumeReleased:
+				add(HygienePVs, pv.ObjectMeta, fmt.Sprintf("released: its claim was deleted; reclaim policy %s", pv.Spec.PersistentVolumeReclaimPolicy))
+			case corev1.VolumeFailed:
+				add(HygienePVs, pv.ObjectMeta, "failed: "+pv.Status.Message)
+			}
+		}
+	}
+
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		if report.Items[i].Kind != report.Items[j].Kind {
+			return report.Items[i].Kind < report.Items[j].Kind
+		}
+		return report.Items[i].Namespace+"/"+report.Items[i].Name < report.Items[j].Namespace+"/"+report.Items[j].Name
+	})
+	return report, nil
+}
+
+// CleanupHygiene deletes the given items. The report is computed again with
+// opts first and items no longer in it are skipped, so an object that came
+// into use since the report was read is never deleted.
+func CleanupHygiene(ctx context.Context, opts HygieneOptions, items []HygieneItem, dryRun bool) ([]HygieneResult, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("at least one item is required")
+	}
+	report, err := GetHygieneReport(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]HygieneItem, len(report.Items))
+	for _, item := range report.Items {
+		current[item.key()] = item
+	}
+
+	results := make([]HygieneResult, len(items))
+	tasks := make(map[string]fanout.Func, len(items))
+	for i, item := range items {
+		i, item := i, item
+		results[i] = HygieneResult{HygieneItem: item, Action: HygieneMatched}
+		found, ok := current[item.key()]
+		if !ok {
+			results[i].Action = HygieneSkipped
+			results[i].Error = "no longer in the hygiene report"
+			continue
+		}
+		results[i].HygieneItem = found
+		if dryRun {
+			continue
+		}
+		tasks[item.key()] = func(ctx context.Context) error {
+			err := deleteHygieneItem(ctx, item)
+			if err != nil {
+				results[i].Action = HygieneFailed
+				results[i].Error = err.Error()
+			} else {
+				results[i].Action = HygieneDeleted
+			}
+			return err
+		}
+	}
+	// Per-item errors are already in results
+	fanout.Run(ctx, fanout.Options{Limit: 8}, tasks)
+	return results, nil
+}
+
+func deleteHygieneItem(ctx context.Context, item HygieneItem) error {
+	background := metav1.DeletePropagationBackground
+	opts := metav1.DeleteOptions{PropagationPolicy: &background}
+	switch item.Kind {
+	case HygieneConfigMaps:
+		return clientset.CoreV1().ConfigMaps(item.Namespace).Delete(ctx, item.Name, opts)
+	case HygieneSecrets:
+		return clientset.CoreV1().Secrets(item.Namespace).Delete(ctx, item.Name, opts)
+	case HygienePVs:
+		return clientset.CoreV1().PersistentVolumes().Delete(ctx, item.Name, opts)
+	case HygieneJobs:
+		return clientset.BatchV1().Jobs(item.Namespace).Delete(ctx, item.Name, opts)
+	case HygieneReplicaSets:
+		return clientset.AppsV1().ReplicaSets(item.Namespace).Delete(ctx, item.Name, opts)
+	}
+	return fmt.Errorf("unsupported kind: %s", item.Kind)
+}
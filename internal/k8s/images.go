@@ -0,0 +1,153 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImageInfo aggregates the containers running one image reference. Digests
+// are the image IDs the kubelets resolved it to; more than one means nodes
+// pulled a mutable tag at different times.
+type ImageInfo struct {
+	Image        string            `json:"image"`
+	Registry     string            `json:"registry"`
+	Repository   string            `json:"repository"`
+	Tag          string            `json:"tag,omitempty"`
+	Digest       string            `json:"digest,omitempty"` // when the reference pins one
+	Mutable      bool              `json:"mutable"`          // untagged or :latest, and not pinned by digest
+	Pods         int               `json:"pods"`
+	Containers   int               `json:"containers"`
+	Namespaces   []string          `json:"namespaces"`
+	PullPolicies []string          `json:"pull_policies"`
+	Digests      []string          `json:"digests,omitempty"`
+	Scan         *ImageScanSummary `json:"scan,omitempty"` // last stored scan, if any
+}
+
+// ImageInventory lists the images of running pods
+type ImageInventory struct {
+	Namespace string      `json:"namespace,omitempty"`
+	Images    []ImageInfo `json:"images"`
+	Count     int         `json:"count"`
+	Pods      int         `json:"pods"`
+}
+
+// ListImages aggregates the images of all containers, init containers
+// included, of pods that have not finished
+func ListImages(ctx context.Context, namespace string) (*ImageInventory, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	byImage := map[string]*ImageInfo{}
+	namespaces := map[string]map[string]bool{}
+	policies := map[string]map[string]bool{}
+	digests := map[string]map[string]bool{}
+	inventory := &ImageInventory{Namespace: namespace, Images: []ImageInfo{}}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		inventory.Pods++
+		imageIDs := map[string]string{}
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, s := range statuses {
+			imageIDs[s.Name] = s.ImageID
+		}
+
+		inPod := map[string]bool{}
+		containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+		for _, c := range containers {
+			info, ok := byImage[c.Image]
+			if !ok {
+				registry, repository, tag, digest := parseImageRef(c.Image)
+				info = &ImageInfo{
+					Image:      c.Image,
+					Registry:   registry,
+					Repository: repository,
+					Tag:        tag,
+					Digest:     digest,
+					Mutable:    digest == "" && (tag == "" || tag == "latest"),
+				}
+				byImage[c.Image] = info
+				namespaces[c.Image] = map[string]bool{}
+				policies[c.Image] = map[string]bool{}
+				digests[c.Image] = map[string]bool{}
+			}
+			info.Containers++
+			if !inPod[c.Image] {
+				inPod[c.Image] = true
+				info.Pods++
+			}
+			namespaces[c.Image][pod.Namespace] = true
+			if c.ImagePullPolicy != "" {
+				policies[c.Image][string(c.ImagePullPolicy)] = true
+			}
+			if id := imageIDs[c.Name]; id != "" {
+				if i := strings.LastIndex(id, "@"); i >= 0 {
+					id = id[i+1:]
+				}
+				digests[c.Image][id] = true
+			}
+		}
+	}
+
+	for image, info := range byImage {
+		info.Namespaces = sortedSet(namespaces[image])
+		info.PullPolicies = sortedSet(policies[image])
+		info.Digests = sortedSet(digests[image])
+		info.Scan = storedImageScanSummary(image)
+		inventory.Images = append(inventory.Images, *info)
+	}
+	sort.Slice(inventory.Images, func(i, j int) bool {
+		if inventory.Images[i].Containers != inventory.Images[j].Containers {
+			return inventory.Images[i].Containers > inventory.Images[j].Containers
+		}
+		return inventory.Images[i].Image < inventory.Images[j].Image
+	})
+	inventory.Count = len(inventory.Images)
+	return inventory, nil
+}
+
+// parseImageRef splits an image reference the way the container runtimes
+// read it: the first path component is a registry only when it has a dot or
+// port or is localhost, and Docker Hub images without one live in library/
+func parseImageRef(image string) (registry, repository, tag, digest string) {
+	ref := image
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref, digest = ref[:i], ref[i+1:]
+	}
+	if i := strings.LastIndex(ref, ":"); i >= 0 && !strings.Contains(ref[i:], "/") {
+		ref, tag = ref[:i], ref[i+1:]
+	}
+	registry = "docker.io"
+	if i := strings.Index(ref, "/"); i >= 0 {
+		if first := ref[:i]; strings.ContainsAny(first, ".:") || first == "localhost" {
+			registry, ref = first, ref[i+1:]
+		}
+	}
+	if registry == "docker.io" && !strings.Contains(ref, "/") {
+		ref = "library/" + ref
+	}
+	return registry, ref, tag, digest
+}
+
+func sortedSet(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
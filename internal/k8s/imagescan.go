@@ -0,0 +1,304 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaga951/gagos/internal/egress"
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// Image scans run through an ImageScanner: the trivy CLI, optionally against
+// a Trivy server (GAGOS_TRIVY_SERVER), or a webhook (GAGOS_IMAGE_SCANNER_URL)
+// fronting any other registry scanner. The last scan of each image is stored
+// and shown in the image inventory.
+
+// Vulnerability severities, as Trivy reports them
+const (
+	SeverityCritical = "CRITICAL"
+	SeverityHigh     = "HIGH"
+	SeverityMedium   = "MEDIUM"
+	SeverityLow      = "LOW"
+	SeverityUnknown  = "UNKNOWN"
+)
+
+// maxScanResponse bounds a scanner's JSON report
+const maxScanResponse = 64 << 20
+
+// imageRefPattern keeps image references from being read as scanner flags
+var imageRefPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/:@+-]*$`)
+
+// ImageVulnerability is one finding of a scan
+type ImageVulnerability struct {
+	ID               string `json:"id"`
+	Package          string `json:"package"`
+	InstalledVersion string `json:"installed_version,omitempty"`
+	FixedVersion     string `json:"fixed_version,omitempty"`
+	Severity         string `json:"severity"`
+	Title            string `json:"title,omitempty"`
+}
+
+// ImageScanSummary counts the findings of a scan by severity
+type ImageScanSummary struct {
+	Scanner    string         `json:"scanner"`
+	ScannedAt  time.Time      `json:"scanned_at"`
+	Severities map[string]int `json:"severities"`
+	Fixable    int            `json:"fixable"` // findings with a fixed version
+}
+
+// ImageScan is the stored result of scanning one image
+type ImageScan struct {
+	Image string `json:"image"`
+	ImageScanSummary
+	Vulnerabilities []ImageVulnerability `json:"vulnerabilities"`
+}
+
+// ImageScanner scans an image for known vulnerabilities
+type ImageScanner interface {
+	Name() string
+	Scan(ctx context.Context, image string) ([]ImageVulnerability, error)
+}
+
+var (
+	imageScannerMu sync.RWMutex
+	imageScanner   ImageScanner
+	scannerFromEnv sync.Once
+)
+
+// SetImageScanner replaces the scanner chosen from the environment
+func SetImageScanner(s ImageScanner) {
+	scannerFromEnv.Do(func() {})
+	imageScannerMu.Lock()
+	imageScanner = s
+	imageScannerMu.Unlock()
+}
+
+// GetImageScanner returns the configured scanner, or nil when there is none:
+// the webhook if GAGOS_IMAGE_SCANNER_URL is set, else trivy if it is on PATH
+func GetImageScanner() ImageScanner {
+	scannerFromEnv.Do(func() {
+		var s ImageScanner
+		proxy, err := egress.ProxyFor(egress.ProxyRegistry, "")
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid registry proxy, image scans connect directly")
+		}
+		if url := os.Getenv("GAGOS_IMAGE_SCANNER_URL"); url != "" {
+			s = &webhookScanner{url: url, client: &http.Client{
+				Transport: egress.Default().TransportVia(http.DefaultTransport.(*http.Transport).Clone(), proxy),
+			}}
+		} else if path, err := exec.LookPath("trivy"); err == nil {
+			s = &trivyScanner{path: path, server: os.Getenv("GAGOS_TRIVY_SERVER"), proxy: proxy}
+		}
+		imageScannerMu.Lock()
+		imageScanner = s
+		imageScannerMu.Unlock()
+	})
+	imageScannerMu.RLock()
+	defer imageScannerMu.RUnlock()
+	return imageScanner
+}
+
+// ScanImage scans an image that a pod is running and stores the result
+func ScanImage(ctx context.Context, image string) (*ImageScan, error) {
+	if !imageRefPattern.MatchString(image) {
+		return nil, fmt.Errorf("invalid image reference: %q", image)
+	}
+	scanner := GetImageScanner()
+	if scanner == nil {
+		return nil, fmt.Errorf("no image scanner configured: install trivy or set GAGOS_IMAGE_SCANNER_URL")
+	}
+	inventory, err := ListImages(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	running := false
+	for _, info := range inventory.Images {
+		running = running || info.Image == image
+	}
+	if !running {
+		return nil, fmt.Errorf("image is not running in the cluster: %s", image)
+	}
+
+	vulns, err := scanner.Scan(ctx, image)
+	if err != nil {
+		return nil, fmt.Errorf("%s scan failed: %w", scanner.Name(), err)
+	}
+	scan := &ImageScan{
+		Image: image,
+		ImageScanSummary: ImageScanSummary{
+			Scanner:    scanner.Name(),
+			ScannedAt:  time.Now(),
+			Severities: map[string]int{},
+		},
+		Vulnerabilities: vulns,
+	}
+	if scan.Vulnerabilities == nil {
+		scan.Vulnerabilities = []ImageVulnerability{}
+	}
+	for i, v := range scan.Vulnerabilities {
+		sev := strings.ToUpper(v.Severity)
+		if sev == "" {
+			sev = SeverityUnknown
+		}
+		scan.Vulnerabilities[i].Severity = sev
+		scan.Severities[sev]++
+		if v.FixedVersion != "" {
+			scan.Fixable++
+		}
+	}
+
+	data, err := json.Marshal(scan)
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.GetBackend().Set(storage.BucketImageScans, image, data); err != nil {
+		return nil, fmt.Errorf("failed to save scan: %w", err)
+	}
+	return scan, nil
+}
+
+// GetImageScan returns the last stored scan of an image
+func GetImageScan(image string) (*ImageScan, error) {
+	data, err := storage.GetBackend().Get(storage.BucketImageScans, image)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("image has not been scanned: %s", image)
+	}
+	var scan ImageScan
+	if err := json.Unmarshal(data, &scan); err != nil {
+		return nil, err
+	}
+	return &scan, nil
+}
+
+func storedImageScanSummary(image string) *ImageScanSummary {
+	if storage.GetBackend() == nil {
+		return nil
+	}
+	scan, err := GetImageScan(image)
+	if err != nil {
+		return nil
+	}
+	return &scan.ImageScanSummary
+}
+
+// trivyScanner runs `trivy image`, in client mode when server is set. Image
+// pulls and database downloads go through proxy when it is set.
+type trivyScanner struct {
+	path   string
+	server string
+	proxy  *egress.Proxy
+}
+
+func (s *trivyScanner) Name() string { return "trivy" }
+
+func (s *trivyScanner) Scan(ctx context.Context, image string) ([]ImageVulnerability, error) {
+	args := []string{"image", "--quiet", "--format", "json", "--scanners", "vuln"}
+	if s.server != "" {
+		args = append(args, "--server", s.server)
+	} else if egress.Offline() {
+		// Scan against the databases already in the trivy cache
+		args = append(args, "--offline-scan", "--skip-db-update", "--skip-java-db-update")
+	}
+	args = append(args, image)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.path, args...)
+	if env := egress.ProxyEnv(s.proxy); env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	var report struct {
+		Results []struct {
+			Vulnerabilities []struct {
+				VulnerabilityID  string
+				PkgName          string
+				InstalledVersion string
+				FixedVersion     string
+				Severity         string
+				Title            string
+			}
+		}
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		return nil, fmt.Errorf("invalid trivy report: %w", err)
+	}
+	var vulns []ImageVulnerability
+	for _, r := range report.Results {
+		for _, v := range r.Vulnerabilities {
+			vulns = append(vulns, ImageVulnerability{
+				ID:               v.VulnerabilityID,
+				Package:          v.PkgName,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+				Severity:         v.Severity,
+				Title:            v.Title,
+			})
+		}
+	}
+	return vulns, nil
+}
+
+// webhookScanner posts {"image": "..."} and expects
+// {"vulnerabilities": [...]} in the ImageVulnerability format back
+type webhookScanner struct {
+	url    string
+	client *http.Client
+}
+
+func (s *webhookScanner) Name() string { return "webhook" }
+
+func (s *webhookScanner) Scan(ctx context.Context, image string) ([]ImageVulnerability, error) {
+	body, err := json.Marshal(map[string]string{"image": image})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxScanResponse))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("scanner returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	var report struct {
+		Vulnerabilities []ImageVulnerability `json:"vulnerabilities"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("invalid scanner response: %w", err)
+	}
+	return report.Vulnerabilities, nil
+}
@@ -0,0 +1,185 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gaga951/gagos/internal/bundle"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+// KustomizeResult is a rendered kustomization
+type KustomizeResult struct {
+	YAML   string `json:"yaml"`
+	Count  int    `json:"count"`
+	Commit string `json:"commit,omitempty"` // Git sources only
+	SHA256 string `json:"sha256,omitempty"` // uploaded bundles only
+}
+
+// KustomizeGit clones url at ref and builds the kustomization at path in it
+func KustomizeGit(ctx context.Context, url, ref, path, username, password string) (*KustomizeResult, error) {
+	dir, err := os.MkdirTemp("", "gagos-kustomize-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	commit, err := bundle.Clone(ctx, url, ref, username, password, dir)
+	if err != nil {
+		return nil, err
+	}
+	result, err := buildBundle(dir, path)
+	if err != nil {
+		return nil, err
+	}
+	result.Commit = commit
+	return result, nil
+}
+
+// KustomizeBundle unpacks an uploaded archive and builds the kustomization at
+// path in it
+func KustomizeBundle(r io.Reader, filename, path string) (*KustomizeResult, error) {
+	dir, err := os.MkdirTemp("", "gagos-kustomize-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	sum, err := bundle.Extract(r, filename, dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	result, err := buildBundle(dir, path)
+	if err != nil {
+		return nil, err
+	}
+	result.SHA256 = sum
+	return result, nil
+}
+
+func buildBundle(root, path string) (*KustomizeResult, error) {
+	dir, err := bundle.Join(root, path)
+	if err != nil {
+		return nil, err
+	}
+	rel, _ := filepath.Rel(root, dir)
+	return BuildKustomization(root, rel)
+}
+
+// BuildKustomization renders the kustomization at path under root with the
+// kustomize SDK, as `kustomize build` would. The build runs on an in-memory
+// copy of root, so nothing outside it can be referenced, and remote bases are
+// refused so every fetch goes through the egress policy.
+func BuildKustomization(root, path string) (*KustomizeResult, error) {
+	fs, err := memoryCopy(root)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join("/", filepath.ToSlash(path))
+	if err := checkLocalKustomization(fs, dir, map[string]bool{}); err != nil {
+		return nil, err
+	}
+
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fs, dir)
+	if err != nil {
+		return nil, fmt.Errorf("kustomize build failed: %w", err)
+	}
+	out, err := resources.AsYaml()
+	if err != nil {
+		return nil, err
+	}
+	return &KustomizeResult{YAML: string(out), Count: resources.Size()}, nil
+}
+
+// memoryCopy loads the files under root, except .git, into an in-memory
+// filesystem rooted at /
+func memoryCopy(root string) (filesys.FileSystem, error) {
+	fs := filesys.MakeFsInMemory()
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return fs.WriteFile("/"+filepath.ToSlash(rel), data)
+	})
+	return fs, err
+}
+
+// checkLocalKustomization refuses the kustomization in dir, or one it
+// includes, when it references a remote base or component, which kustomize
+// would git clone on its own
+func checkLocalKustomization(fs filesys.FileSystem, dir string, seen map[string]bool) error {
+	if seen[dir] {
+		return nil
+	}
+	seen[dir] = true
+
+	var data []byte
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		if b, err := fs.ReadFile(filepath.Join(dir, name)); err == nil {
+			data = b
+			break
+		}
+	}
+	if data == nil {
+		return nil // a plain resource file or a missing directory; kustomize reports the latter
+	}
+
+	var k struct {
+		Resources  []string `json:"resources"`
+		Bases      []string `json:"bases"`
+		Components []string `json:"components"`
+	}
+	if err := yaml.Unmarshal(data, &k); err != nil {
+		return fmt.Errorf("%s: %w", dir, err)
+	}
+	for _, ref := range append(append(k.Resources, k.Bases...), k.Components...) {
+		if isRemoteRef(ref) {
+			return fmt.Errorf("remote kustomize reference %q is not supported; vendor it into the bundle", ref)
+		}
+		if sub := filepath.Join(dir, ref); fs.IsDir(sub) {
+			if err := checkLocalKustomization(fs, sub, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isRemoteRef reports whether a kustomization entry is a URL or Git
+// repository rather than a local path
+func isRemoteRef(ref string) bool {
+	return strings.Contains(ref, "://") ||
+		strings.HasPrefix(ref, "git@") ||
+		strings.HasPrefix(ref, "github.com/") ||
+		strings.HasPrefix(ref, "gitlab.com/") ||
+		strings.HasPrefix(ref, "bitbucket.org/") ||
+		strings.Contains(ref, "?ref=") ||
+		strings.Contains(ref, "//") && !strings.HasPrefix(ref, "/")
+}
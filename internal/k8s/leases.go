@@ -0,0 +1,149 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// What a Lease is used for
+const (
+	LeaseLeaderElection    = "leader-election"
+	LeaseNodeHeartbeat     = "node-heartbeat"
+	LeaseAPIServerIdentity = "apiserver-identity"
+)
+
+// apiServerIdentityLabel marks the Leases API servers hold in kube-system
+const apiServerIdentityLabel = "apiserver.kubernetes.io/identity"
+
+type LeaseInfo struct {
+	Name                 string            `json:"name"`
+	Namespace            string            `json:"namespace"`
+	Type                 string            `json:"type"`
+	Holder               string            `json:"holder"`
+	LeaseDurationSeconds int32             `json:"lease_duration_seconds"`
+	AcquireTime          string            `json:"acquire_time,omitempty"`
+	RenewTime            string            `json:"renew_time,omitempty"`
+	SinceRenew           string            `json:"since_renew,omitempty"`
+	Transitions          int32             `json:"transitions"`
+	Expired              bool              `json:"expired"` // not renewed within its duration, so up for grabs
+	Labels               map[string]string `json:"labels,omitempty"`
+	CreatedAt            string            `json:"created_at"`
+	Age                  string            `json:"age"`
+}
+
+// ListLeases lists Leases, optionally only those of one type
+func ListLeases(ctx context.Context, namespace, leaseType string, opts ListOptions) ([]LeaseInfo, string, error) {
+	if clientset == nil {
+		return nil, "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	var result []LeaseInfo
+	cont := ""
+	if items, next, ok := listFromCache(ctx, "leases", namespace, opts); ok {
+		result = cachedAs[LeaseInfo](items)
+		cont = next
+	} else {
+		leases, err := clientset.CoordinationV1().Leases(namespace).List(ctx, opts.toListOptions())
+		if err != nil {
+			return nil, "", err
+		}
+		for i := range leases.Items {
+			result = append(result, leaseToInfo(&leases.Items[i]))
+		}
+		cont = leases.Continue
+	}
+
+	if leaseType == "" {
+		return result, cont, nil
+	}
+	var filtered []LeaseInfo
+	for _, l := range result {
+		if l.Type == leaseType {
+			filtered = append(filtered, l)
+		}
+	}
+	return filtered, cont, nil
+}
+
+func leaseToInfo(lease *coordinationv1.Lease) LeaseInfo {
+	info := LeaseInfo{
+		Name:      lease.Name,
+		Namespace: lease.Namespace,
+		Type:      LeaseLeaderElection,
+		Labels:    lease.Labels,
+		CreatedAt: lease.CreationTimestamp.Format(time.RFC3339),
+		Age:       formatAge(lease.CreationTimestamp.Time),
+	}
+	switch {
+	case lease.Namespace == "kube-node-lease":
+		info.Type = LeaseNodeHeartbeat
+	case lease.Labels[apiServerIdentityLabel] != "":
+		info.Type = LeaseAPIServerIdentity
+	}
+
+	spec := lease.Spec
+	if spec.HolderIdentity != nil {
+		info.Holder = *spec.HolderIdentity
+	}
+	if spec.LeaseDurationSeconds != nil {
+		info.LeaseDurationSeconds = *spec.LeaseDurationSeconds
+	}
+	if spec.LeaseTransitions != nil {
+		info.Transitions = *spec.LeaseTransitions
+	}
+	if spec.AcquireTime != nil {
+		info.AcquireTime = spec.AcquireTime.Format(time.RFC3339)
+	}
+	if spec.RenewTime != nil {
+		info.RenewTime = spec.RenewTime.Format(time.RFC3339)
+		info.SinceRenew = formatAge(spec.RenewTime.Time)
+		expiry := spec.RenewTime.Add(time.Duration(info.LeaseDurationSeconds) * time.Second)
+		info.Expired = time.Now().After(expiry)
+	}
+	// A released or never-held lease has no holder and is free to take
+	if info.Holder == "" {
+		info.Expired = true
+	}
+	return info
+}
+
+// LeaseDetail is a Lease's summary with its YAML
+type LeaseDetail struct {
+	ResourceDetail
+	Lease LeaseInfo `json:"lease"`
+}
+
+func GetLease(ctx context.Context, namespace, name string) (*LeaseDetail, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	lease, err := clientset.CoordinationV1().Leases(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	lease.ManagedFields = nil
+	yamlBytes, err := yaml.Marshal(lease)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LeaseDetail{
+		ResourceDetail: ResourceDetail{
+			Kind:      "Lease",
+			Name:      lease.Name,
+			Namespace: lease.Namespace,
+			YAML:      string(yamlBytes),
+		},
+		Lease: leaseToInfo(lease),
+	}, nil
+}
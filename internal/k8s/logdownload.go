@@ -0,0 +1,193 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// A log download streams the full logs of one pod, or of every pod of a
+// Deployment, container after container. Nothing is held in memory, so
+// there is no tail limit as with GetPodLogs. Each line keeps its timestamp
+// and is prefixed like `kubectl logs --prefix`.
+
+// LogDownloadOptions selects the logs to download
+type LogDownloadOptions struct {
+	Container string    // every container when empty
+	Previous  bool      // logs of the previous instance of each container
+	SinceTime time.Time // zero for the start of the log
+	UntilTime time.Time // zero for the end of the log
+}
+
+// LogDownload is a download whose pods and containers have been resolved,
+// so that errors are reported before the response starts
+type LogDownload struct {
+	namespace string
+	opts      LogDownloadOptions
+	sources   []logSource
+}
+
+type logSource struct {
+	pod       string
+	container string
+}
+
+// PrepareLogDownload resolves the containers to read. kind is "pod" or
+// "deployment".
+func PrepareLogDownload(ctx context.Context, kind, namespace, name string, opts LogDownloadOptions) (*LogDownload, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	var pods []corev1.Pod
+	switch kind {
+	case "pod":
+		pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, *pod)
+	case "deployment":
+		deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+		if err != nil {
+			return nil, err
+		}
+		list, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, err
+		}
+		pods = list.Items
+		sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	default:
+		return nil, fmt.Errorf("unsupported kind: %s", kind)
+	}
+
+	download := &LogDownload{namespace: namespace, opts: opts}
+	for _, pod := range pods {
+		for _, container := range podContainerNames(&pod) {
+			if opts.Container == "" || opts.Container == container {
+				download.sources = append(download.sources, logSource{pod: pod.Name, container: container})
+			}
+		}
+	}
+	if len(download.sources) == 0 {
+		if opts.Container != "" {
+			return nil, fmt.Errorf("no container %s in %s %s/%s", opts.Container, kind, namespace, name)
+		}
+		return nil, fmt.Errorf("no pods found for %s %s/%s", kind, namespace, name)
+	}
+	return download, nil
+}
+
+// Pods returns how many pods the download reads
+func (d *LogDownload) Pods() int {
+	pods := map[string]bool{}
+	for _, s := range d.sources {
+		pods[s.pod] = true
+	}
+	return len(pods)
+}
+
+// Write streams the logs to w. A container whose logs cannot be read, such
+// as an init container that has not run, gets an error line instead; only
+// a failure to write stops the download.
+func (d *LogDownload) Write(ctx context.Context, w io.Writer) error {
+	for _, src := range d.sources {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		prefix := fmt.Sprintf("[pod/%s/%s] ", src.pod, src.container)
+		if err := d.writeContainer(ctx, w, src, prefix); err != nil {
+			var readErr *logReadError
+			if !errors.As(err, &readErr) {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%serror reading logs: %v\n", prefix, readErr.err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// logReadError is a failure to read a container's logs, as opposed to a
+// failure to write them
+type logReadError struct{ err error }
+
+func (e *logReadError) Error() string { return e.err.Error() }
+
+func (d *LogDownload) writeContainer(ctx context.Context, w io.Writer, src logSource, prefix string) error {
+	logOpts := &corev1.PodLogOptions{Container: src.container, Previous: d.opts.Previous, Timestamps: true}
+	if !d.opts.SinceTime.IsZero() {
+		logOpts.SinceTime = &metav1.Time{Time: d.opts.SinceTime}
+	}
+	stream, err := clientset.CoreV1().Pods(d.namespace).GetLogs(src.pod, logOpts).Stream(ctx)
+	if err != nil {
+		return &logReadError{err}
+	}
+	defer stream.Close()
+
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			if !d.opts.UntilTime.IsZero() && logLineAfter(line, d.opts.UntilTime) {
+				// Lines come in time order, the rest is later still
+				return nil
+			}
+			if !strings.HasSuffix(line, "\n") {
+				line += "\n"
+			}
+			if _, werr := io.WriteString(w, prefix+line); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return &logReadError{err}
+		}
+	}
+}
+
+// logLineAfter reports whether a line read with timestamps is later than t.
+// A line without a timestamp is never later.
+func logLineAfter(line string, t time.Time) bool {
+	stamp, _, ok := strings.Cut(line, " ")
+	if !ok {
+		return false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, stamp)
+	return err == nil && ts.After(t)
+}
+
+// podContainerNames lists the init, regular and ephemeral containers of a pod
+func podContainerNames(pod *corev1.Pod) []string {
+	var names []string
+	for _, c := range pod.Spec.InitContainers {
+		names = append(names, c.Name)
+	}
+	for _, c := range pod.Spec.Containers {
+		names = append(names, c.Name)
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		names = append(names, c.Name)
+	}
+	return names
+}
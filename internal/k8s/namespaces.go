@@ -0,0 +1,297 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// Namespace onboarding creates a namespace together with a ResourceQuota,
+// LimitRange and default-deny NetworkPolicy picked from the templates below,
+// so a team gets a bounded project in one call.
+
+// quotaTemplates are the ResourceQuota hard limits per size
+var quotaTemplates = map[string]map[string]string{
+	"small": {
+		"requests.cpu": "2", "requests.memory": "4Gi",
+		"limits.cpu": "4", "limits.memory": "8Gi",
+		"pods": "20", "persistentvolumeclaims": "5", "requests.storage": "50Gi",
+		"services.loadbalancers": "0", "services.nodeports": "0",
+	},
+	"medium": {
+		"requests.cpu": "8", "requests.memory": "16Gi",
+		"limits.cpu": "16", "limits.memory": "32Gi",
+		"pods": "50", "persistentvolumeclaims": "20", "requests.storage": "200Gi",
+		"services.loadbalancers": "1", "services.nodeports": "0",
+	},
+	"large": {
+		"requests.cpu": "32", "requests.memory": "64Gi",
+		"limits.cpu": "64", "limits.memory": "128Gi",
+		"pods": "200", "persistentvolumeclaims": "50", "requests.storage": "1Ti",
+		"services.loadbalancers": "2", "services.nodeports": "2",
+	},
+}
+
+// LimitRangeTemplate is a container's default request and limit, and the
+// largest limit it may set
+type LimitRangeTemplate struct {
+	DefaultRequest map[string]string `json:"default_request"`
+	Default        map[string]string `json:"default"`
+	Max            map[string]string `json:"max,omitempty"`
+}
+
+// limitRangeTemplates are the LimitRange container defaults per name. Pods
+// need requests and limits once a quota covers them, so these fill them in
+// for containers that set none.
+var limitRangeTemplates = map[string]LimitRangeTemplate{
+	"default": {
+		DefaultRequest: map[string]string{"cpu": "100m", "memory": "128Mi"},
+		Default:        map[string]string{"cpu": "500m", "memory": "512Mi"},
+	},
+	"strict": {
+		DefaultRequest: map[string]string{"cpu": "50m", "memory": "64Mi"},
+		Default:        map[string]string{"cpu": "250m", "memory": "256Mi"},
+		Max:            map[string]string{"cpu": "2", "memory": "4Gi"},
+	},
+}
+
+// networkPolicyTemplates describe the default NetworkPolicies
+var networkPolicyTemplates = map[string]string{
+	"deny-all-ingress":     "Deny all ingress to the namespace's pods",
+	"allow-same-namespace": "Deny ingress except from pods in the same namespace",
+}
+
+// NamespaceTemplates lists what CreateNamespace can provision
+type NamespaceTemplates struct {
+	Quotas          map[string]map[string]string  `json:"quotas"`
+	LimitRanges     map[string]LimitRangeTemplate `json:"limit_ranges"`
+	NetworkPolicies map[string]string             `json:"network_policies"`
+}
+
+// GetNamespaceTemplates returns the built-in onboarding templates
+func GetNamespaceTemplates() NamespaceTemplates {
+	return NamespaceTemplates{
+		Quotas:          quotaTemplates,
+		LimitRanges:     limitRangeTemplates,
+		NetworkPolicies: networkPolicyTemplates,
+	}
+}
+
+// NamespaceRequest creates a namespace with optional guard rails. Template
+// names select from GetNamespaceTemplates; empty ones are skipped.
+type NamespaceRequest struct {
+	Name          string            `json:"name"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+	Quota         string            `json:"quota,omitempty"`
+	QuotaHard     map[string]string `json:"quota_hard,omitempty"` // added to or overriding the quota template
+	LimitRange    string            `json:"limit_range,omitempty"`
+	NetworkPolicy string            `json:"network_policy,omitempty"`
+	DryRun        bool              `json:"dry_run"`
+}
+
+// NamespaceResult is the outcome of CreateNamespace. YAML holds the rendered
+// objects.
+type NamespaceResult struct {
+	Results []ApplyResult `json:"results"`
+	YAML    string        `json:"yaml"`
+}
+
+// CreateNamespace creates the namespace and then its quota, limit range and
+// network policy. If one of those fails the namespace is deleted again, so a
+// retry starts clean. An existing namespace is never touched.
+//
+// A dry run validates the namespace on the server; the other objects cannot be
+// dry-run in a namespace that does not exist yet and are only rendered.
+func CreateNamespace(ctx context.Context, req NamespaceRequest) (*NamespaceResult, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	ns, objects, err := renderNamespace(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &NamespaceResult{Results: []ApplyResult{}}
+	var docs []string
+	for _, obj := range append([]runtime.Object{ns}, objects...) {
+		b, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, string(b))
+	}
+	result.YAML = strings.Join(docs, "---\n")
+
+	opts := metav1.CreateOptions{FieldManager: FieldManager}
+	if req.DryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	if _, err := clientset.CoreV1().Namespaces().Create(ctx, ns, opts); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("namespace %s already exists", req.Name)
+		}
+		return nil, err
+	}
+	result.Results = append(result.Results, ApplyResult{APIVersion: "v1", Kind: "Namespace", Name: req.Name, Action: ApplyCreated})
+
+	for _, obj := range objects {
+		r := ApplyResult{Namespace: req.Name, Action: ApplyCreated}
+		var err error
+		switch o := obj.(type) {
+		case *corev1.ResourceQuota:
+			r.APIVersion, r.Kind, r.Name = "v1", "ResourceQuota", o.Name
+			if !req.DryRun {
+				_, err = clientset.CoreV1().ResourceQuotas(req.Name).Create(ctx, o, opts)
+			}
+		case *corev1.LimitRange:
+			r.APIVersion, r.Kind, r.Name = "v1", "LimitRange", o.Name
+			if !req.DryRun {
+				_, err = clientset.CoreV1().LimitRanges(req.Name).Create(ctx, o, opts)
+			}
+		case *networkingv1.NetworkPolicy:
+			r.APIVersion, r.Kind, r.Name = "networking.k8s.io/v1", "NetworkPolicy", o.Name
+			if !req.DryRun {
+				_, err = clientset.NetworkingV1().NetworkPolicies(req.Name).Create(ctx, o, opts)
+			}
+		}
+		if err != nil {
+			r.Action, r.Error = ApplyFailed, err.Error()
+			result.Results = append(result.Results, r)
+			if delErr := clientset.CoreV1().Namespaces().Delete(ctx, req.Name, metav1.DeleteOptions{}); delErr != nil {
+				return result, fmt.Errorf("%s %s failed: %v; deleting namespace %s also failed: %v", r.Kind, r.Name, err, req.Name, delErr)
+			}
+			return result, fmt.Errorf("%s %s failed, namespace %s was deleted again: %v", r.Kind, r.Name, req.Name, err)
+		}
+		result.Results = append(result.Results, r)
+	}
+	return result, nil
+}
+
+// renderNamespace builds the namespace and the objects selected by req
+func renderNamespace(req NamespaceRequest) (*corev1.Namespace, []runtime.Object, error) {
+	if errs := validation.IsDNS1123Label(req.Name); len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid namespace name %q: %s", req.Name, strings.Join(errs, "; "))
+	}
+
+	ns := &corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        req.Name,
+			Labels:      req.Labels,
+			Annotations: req.Annotations,
+		},
+	}
+	var objects []runtime.Object
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: req.Name, Labels: map[string]string{"app.kubernetes.io/managed-by": FieldManager}}
+	}
+
+	if req.Quota != "" || len(req.QuotaHard) > 0 {
+		hard := map[string]string{}
+		if req.Quota != "" {
+			tmpl, ok := quotaTemplates[req.Quota]
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown quota template %q (have %s)", req.Quota, templateNames(quotaTemplates))
+			}
+			for k, v := range tmpl {
+				hard[k] = v
+			}
+		}
+		for k, v := range req.QuotaHard {
+			hard[k] = v
+		}
+		list, err := resourceList(hard)
+		if err != nil {
+			return nil, nil, fmt.Errorf("quota: %w", err)
+		}
+		objects = append(objects, &corev1.ResourceQuota{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ResourceQuota"},
+			ObjectMeta: meta("default-quota"),
+			Spec:       corev1.ResourceQuotaSpec{Hard: list},
+		})
+	}
+
+	if req.LimitRange != "" {
+		tmpl, ok := limitRangeTemplates[req.LimitRange]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown limit range template %q (have %s)", req.LimitRange, templateNames(limitRangeTemplates))
+		}
+		item := corev1.LimitRangeItem{Type: corev1.LimitTypeContainer}
+		var err error
+		if item.DefaultRequest, err = resourceList(tmpl.DefaultRequest); err != nil {
+			return nil, nil, err
+		}
+		if item.Default, err = resourceList(tmpl.Default); err != nil {
+			return nil, nil, err
+		}
+		if len(tmpl.Max) > 0 {
+			if item.Max, err = resourceList(tmpl.Max); err != nil {
+				return nil, nil, err
+			}
+		}
+		objects = append(objects, &corev1.LimitRange{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "LimitRange"},
+			ObjectMeta: meta("default-limits"),
+			Spec:       corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{item}},
+		})
+	}
+
+	if req.NetworkPolicy != "" {
+		policy := &networkingv1.NetworkPolicy{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+			ObjectMeta: meta(req.NetworkPolicy),
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			},
+		}
+		switch req.NetworkPolicy {
+		case "deny-all-ingress":
+		case "allow-same-namespace":
+			policy.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}},
+			}}
+		default:
+			return nil, nil, fmt.Errorf("unknown network policy template %q (have %s)", req.NetworkPolicy, templateNames(networkPolicyTemplates))
+		}
+		objects = append(objects, policy)
+	}
+
+	return ns, objects, nil
+}
+
+func resourceList(values map[string]string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	for name, value := range values {
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity %q for %s", value, name)
+		}
+		list[corev1.ResourceName(name)] = q
+	}
+	return list, nil
+}
+
+func templateNames[T any](templates map[string]T) string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
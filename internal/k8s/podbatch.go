@@ -0,0 +1,140 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gaga951/gagos/internal/fanout"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Batch actions reported per pod
+const (
+	PodBatchDeleted = "deleted"
+	PodBatchEvicted = "evicted"
+	PodBatchMatched = "matched" // dry run
+	PodBatchFailed  = "failed"
+)
+
+// PodBatchOptions selects pods for a batch delete or eviction. At least one of
+// the selectors or filters must be set so a batch can never hit every pod.
+type PodBatchOptions struct {
+	PodDeleteOptions
+	LabelSelector string
+	FieldSelector string
+	Phases        []string // e.g. Failed, Succeeded
+	Reason        string   // status.reason, e.g. Evicted
+	Evict         bool     // use the eviction API, which honours PodDisruptionBudgets
+	DryRun        bool     // only report what would be affected
+}
+
+// PodBatchResult is the outcome for a single pod
+type PodBatchResult struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Phase     string `json:"phase"`
+	Reason    string `json:"reason,omitempty"`
+	Action    string `json:"action"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DeletePods deletes or evicts every pod in namespace (all namespaces when
+// empty) matching opts, like clearing Evicted or Completed pods in one go
+func DeletePods(ctx context.Context, namespace string, opts PodBatchOptions) ([]PodBatchResult, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	if opts.LabelSelector == "" && opts.FieldSelector == "" && len(opts.Phases) == 0 && opts.Reason == "" {
+		return nil, fmt.Errorf("a label selector, field selector, phase or reason is required")
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: opts.LabelSelector,
+		FieldSelector: opts.FieldSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []*corev1.Pod
+	for i := range pods.Items {
+		if podMatchesBatch(&pods.Items[i], opts) {
+			matched = append(matched, &pods.Items[i])
+		}
+	}
+
+	results := make([]PodBatchResult, len(matched))
+	tasks := make(map[string]fanout.Func, len(matched))
+	for i, pod := range matched {
+		i, pod := i, pod
+		results[i] = PodBatchResult{
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+			Phase:     string(pod.Status.Phase),
+			Reason:    pod.Status.Reason,
+			Action:    PodBatchMatched,
+		}
+		if opts.DryRun {
+			continue
+		}
+		tasks[pod.Namespace+"/"+pod.Name] = func(ctx context.Context) error {
+			err := deleteOrEvictPod(ctx, pod, opts)
+			if err != nil {
+				results[i].Action = PodBatchFailed
+				results[i].Error = err.Error()
+			} else if opts.Evict {
+				results[i].Action = PodBatchEvicted
+			} else {
+				results[i].Action = PodBatchDeleted
+			}
+			return err
+		}
+	}
+	// Per-pod errors are already in results
+	fanout.Run(ctx, fanout.Options{Limit: 8}, tasks)
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Namespace+"/"+results[i].Name < results[j].Namespace+"/"+results[j].Name
+	})
+	return results, nil
+}
+
+func podMatchesBatch(pod *corev1.Pod, opts PodBatchOptions) bool {
+	if len(opts.Phases) > 0 {
+		found := false
+		for _, phase := range opts.Phases {
+			if strings.EqualFold(phase, string(pod.Status.Phase)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if opts.Reason != "" && !strings.EqualFold(opts.Reason, pod.Status.Reason) {
+		return false
+	}
+	return true
+}
+
+func deleteOrEvictPod(ctx context.Context, pod *corev1.Pod, opts PodBatchOptions) error {
+	deleteOpts := opts.toDeleteOptions()
+	if !opts.Evict {
+		return clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, deleteOpts)
+	}
+	return clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+		DeleteOptions: &deleteOpts,
+	})
+}
@@ -0,0 +1,195 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// ErrCopyTooLarge is returned when a pod copy exceeds the caller's size limit
+var ErrCopyTooLarge = errors.New("copy exceeds size limit")
+
+// defaultContainerAnnotation is the annotation kubectl uses to pick a container
+const defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+
+// ResolveContainer validates container against the pod spec. When container is
+// empty it picks the kubectl default-container annotation or the first container.
+func ResolveContainer(ctx context.Context, namespace, podName, container string) (string, error) {
+	if clientset == nil {
+		return "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		return "", fmt.Errorf("pod %s is %s, not Running", podName, pod.Status.Phase)
+	}
+
+	if container == "" {
+		container = pod.Annotations[defaultContainerAnnotation]
+	}
+	if container == "" && len(pod.Spec.Containers) > 0 {
+		return pod.Spec.Containers[0].Name, nil
+	}
+
+	names := make([]string, 0, len(pod.Spec.Containers)+len(pod.Spec.EphemeralContainers))
+	for _, c := range pod.Spec.Containers {
+		if c.Name == container {
+			return container, nil
+		}
+		names = append(names, c.Name)
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		if c.Name == container {
+			return container, nil
+		}
+		names = append(names, c.Name)
+	}
+	return "", fmt.Errorf("container %q not found in pod %s (available: %s)", container, podName, strings.Join(names, ", "))
+}
+
+// ExecInPod runs command in a container and wires up the given streams.
+// stdin may be nil. The command must exit zero for a nil error.
+func ExecInPod(ctx context.Context, namespace, podName, container string, command []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if clientset == nil || restConfig == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+	rc, err := CoreREST(clientset)
+	if err != nil {
+		return err
+	}
+
+	req := rc.Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    stdout != nil,
+			Stderr:    stderr != nil,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(restConfig, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create executor: %w", err)
+	}
+
+	return executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+}
+
+// limitedWriter fails once more than limit bytes have been written
+type limitedWriter struct {
+	w       io.Writer
+	limit   int64
+	written int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.written+int64(len(p)) > l.limit {
+		return 0, ErrCopyTooLarge
+	}
+	n, err := l.w.Write(p)
+	l.written += int64(n)
+	return n, err
+}
+
+// CopyFromPod tars srcPath (a file or directory) inside the container and
+// writes the archive to w, like `kubectl cp pod:src .`. The container needs tar.
+func CopyFromPod(ctx context.Context, namespace, podName, container, srcPath string, w io.Writer, maxBytes int64) error {
+	srcPath = path.Clean(srcPath)
+	if !path.IsAbs(srcPath) {
+		return fmt.Errorf("source path must be absolute")
+	}
+	dir, base := path.Split(srcPath)
+	if base == "" {
+		// Copying "/" itself
+		dir, base = "/", "."
+	}
+
+	var stderr bytes.Buffer
+	out := &limitedWriter{w: w, limit: maxBytes}
+	err := ExecInPod(ctx, namespace, podName, container, []string{"tar", "cf", "-", "-C", dir, base}, nil, out, &stderr)
+	if errors.Is(err, ErrCopyTooLarge) || out.written > maxBytes {
+		return ErrCopyTooLarge
+	}
+	if err != nil {
+		return execError(err, &stderr)
+	}
+	return nil
+}
+
+// CopyToPod writes r as destDir/name inside the container, like
+// `kubectl cp file pod:destDir/`. size must be the exact length of r.
+func CopyToPod(ctx context.Context, namespace, podName, container, destDir, name string, r io.Reader, size, maxBytes int64) error {
+	destDir = path.Clean(destDir)
+	if !path.IsAbs(destDir) {
+		return fmt.Errorf("destination path must be absolute")
+	}
+	name = path.Base(name)
+	if name == "." || name == "/" || name == ".." {
+		return fmt.Errorf("invalid file name")
+	}
+	if size > maxBytes {
+		return ErrCopyTooLarge
+	}
+
+	// Stream a single-file tar archive into `tar xf` in the container
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    size,
+			ModTime: time.Now(),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.CopyN(tw, r, size); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(tw.Close())
+	}()
+
+	var stderr bytes.Buffer
+	err := ExecInPod(ctx, namespace, podName, container, []string{"tar", "xmf", "-", "-C", destDir}, pr, nil, &stderr)
+	pr.Close()
+	if err != nil {
+		return execError(err, &stderr)
+	}
+	return nil
+}
+
+// execError prefers the command's stderr over the generic exit status error
+func execError(err error, stderr *bytes.Buffer) error {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return err
+}
@@ -0,0 +1,105 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gaga951/gagos/internal/fanout"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// In all-containers mode the logs of each container are read with
+// timestamps and merged into one stream ordered by time. Each line keeps
+// the prefix `kubectl logs --all-containers --prefix` would give it.
+
+// containerLogLine is one line of a container's log
+type containerLogLine struct {
+	time  time.Time
+	seq   int // position in the container's log, for lines with equal times
+	text  string
+	order int // position of the container in the pod spec
+}
+
+// getAllContainerLogs merges the logs of the init, regular and ephemeral
+// containers of a pod. Containers without logs, such as init containers
+// that have not run or containers that never restarted when Previous is
+// set, are left out; it fails only when none has logs.
+func getAllContainerLogs(ctx context.Context, namespace, name string, opts PodLogOptions) (string, error) {
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	containers := podContainerNames(pod)
+
+	lines := make([][]containerLogLine, len(containers))
+	tasks := make(map[string]fanout.Func, len(containers))
+	for i, container := range containers {
+		i, container := i, container
+		tasks[container] = func(ctx context.Context) error {
+			logOpts := &corev1.PodLogOptions{Container: container, Previous: opts.Previous, Timestamps: true}
+			if opts.TailLines > 0 {
+				logOpts.TailLines = &opts.TailLines
+			}
+			raw, err := clientset.CoreV1().Pods(namespace).GetLogs(name, logOpts).DoRaw(ctx)
+			if err != nil {
+				return err
+			}
+			lines[i] = parseContainerLog(string(raw), fmt.Sprintf("[pod/%s/%s] ", name, container), i)
+			return nil
+		}
+	}
+	errs := fanout.Run(ctx, fanout.Options{Limit: 8}, tasks)
+	if len(errs) == len(containers) && len(containers) > 0 {
+		return "", fmt.Errorf("no container has logs: %w", errs.Err())
+	}
+
+	var merged []containerLogLine
+	for _, l := range lines {
+		merged = append(merged, l...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		a, b := merged[i], merged[j]
+		if !a.time.Equal(b.time) {
+			return a.time.Before(b.time)
+		}
+		if a.order != b.order {
+			return a.order < b.order
+		}
+		return a.seq < b.seq
+	})
+
+	var out strings.Builder
+	for _, l := range merged {
+		out.WriteString(l.text)
+		out.WriteByte('\n')
+	}
+	return out.String(), nil
+}
+
+// parseContainerLog splits a log read with timestamps into prefixed lines.
+// A line without a timestamp takes the time of the line before it.
+func parseContainerLog(raw, prefix string, order int) []containerLogLine {
+	raw = strings.TrimSuffix(raw, "\n")
+	if raw == "" {
+		return nil
+	}
+	var lines []containerLogLine
+	var last time.Time
+	for i, line := range strings.Split(raw, "\n") {
+		text := line
+		if stamp, rest, ok := strings.Cut(line, " "); ok {
+			if t, err := time.Parse(time.RFC3339Nano, stamp); err == nil {
+				last, text = t, rest
+			}
+		}
+		lines = append(lines, containerLogLine{time: last, seq: i, text: prefix + text, order: order})
+	}
+	return lines
+}
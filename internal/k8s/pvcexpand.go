@@ -0,0 +1,195 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Expanding a CSI volume happens in two steps: the external resizer grows
+// the volume, then the kubelet grows the file system on the node that
+// mounts it. The expansion report follows both and tells a resize that is
+// still moving from one that has stopped.
+
+// How a PVC expansion ended up
+const (
+	ExpansionComplete      = "complete"
+	ExpansionInProgress    = "in-progress"
+	ExpansionWaitingForPod = "waiting-for-pod" // the file system grows when a pod mounts the volume
+	ExpansionStuck         = "stuck"
+	ExpansionFailed        = "failed"
+)
+
+// DefaultExpansionStuckAfter is how long a resize may sit in one state
+// before it is reported stuck
+const DefaultExpansionStuckAfter = 2 * time.Minute
+
+// pvcExpansionPoll is how often WatchPVCExpansion reads the PVC
+const pvcExpansionPoll = 2 * time.Second
+
+// PVCExpansion is a PVC's resize status with what it means for the user
+type PVCExpansion struct {
+	PVCResizeStatus
+	Outcome   string      `json:"outcome"`
+	Hint      string      `json:"hint,omitempty"`
+	MountedBy []string    `json:"mounted_by"` // running pods using the claim
+	Events    []EventInfo `json:"events"`     // the PVC's events, most recent first
+	Elapsed   float64     `json:"elapsed_seconds,omitempty"`
+}
+
+// GetPVCExpansion reports the expansion of a PVC. A state is stuck when its
+// condition has not changed for stuckAfter; a pending resize has no
+// condition yet, so it is only reported stuck by WatchPVCExpansion.
+func GetPVCExpansion(ctx context.Context, namespace, name string, stuckAfter time.Duration) (*PVCExpansion, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	return pvcExpansion(ctx, namespace, name, stuckAfter, time.Time{})
+}
+
+// WatchPVCExpansion follows a PVC's expansion and calls progress each time
+// its state, conditions, pods or events change. It returns once the
+// expansion is complete, failed or stuck, or with the last report when ctx
+// ends first.
+func WatchPVCExpansion(ctx context.Context, namespace, name string, stuckAfter time.Duration, progress func(*PVCExpansion) error) (*PVCExpansion, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(pvcExpansionPoll)
+	defer ticker.Stop()
+	var last *PVCExpansion
+	for {
+		report, err := pvcExpansion(ctx, namespace, name, stuckAfter, start)
+		if err != nil {
+			if ctx.Err() != nil && last != nil {
+				return last, nil
+			}
+			return nil, err
+		}
+		report.Elapsed = time.Since(start).Seconds()
+		if last == nil || expansionChanged(last, report) {
+			if err := progress(report); err != nil {
+				return report, err
+			}
+		}
+		last = report
+		switch report.Outcome {
+		case ExpansionComplete, ExpansionFailed, ExpansionStuck:
+			return report, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return last, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// pvcExpansion builds the report. pendingSince is when the watch started;
+// a pending resize older than stuckAfter from then is stuck.
+func pvcExpansion(ctx context.Context, namespace, name string, stuckAfter time.Duration, pendingSince time.Time) (*PVCExpansion, error) {
+	if stuckAfter <= 0 {
+		stuckAfter = DefaultExpansionStuckAfter
+	}
+	pvc, err := clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	allow, err := storageClassAllowsExpansion(ctx, pvcStorageClass(pvc))
+	if err != nil {
+		return nil, err
+	}
+	report := &PVCExpansion{PVCResizeStatus: *pvcResizeStatus(pvc, allow), MountedBy: []string{}, Events: []EventInfo{}}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == name {
+				report.MountedBy = append(report.MountedBy, pod.Name)
+				break
+			}
+		}
+	}
+
+	events, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: EventFilter{Kind: "PersistentVolumeClaim", Name: name}.FieldSelector(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events.Items, func(i, j int) bool {
+		return eventLastSeen(&events.Items[i]).After(eventLastSeen(&events.Items[j]))
+	})
+	for i := range events.Items {
+		report.Events = append(report.Events, eventToInfo(&events.Items[i]))
+	}
+
+	// How long the PVC has been in its current resize condition
+	var inState time.Duration
+	for _, c := range pvc.Status.Conditions {
+		if c.Status == corev1.ConditionTrue && (c.Type == corev1.PersistentVolumeClaimResizing || c.Type == corev1.PersistentVolumeClaimFileSystemResizePending) {
+			inState = time.Since(c.LastTransitionTime.Time)
+		}
+	}
+
+	switch report.State {
+	case ResizeNone:
+		report.Outcome = ExpansionComplete
+	case ResizeFailed:
+		report.Outcome = ExpansionFailed
+		report.Hint = "the resize failed; check the events and the CSI driver's resizer logs"
+		if report.ResourceStatus == string(corev1.PersistentVolumeClaimNodeResizeFailed) {
+			report.Hint = "the file system resize failed on the node; check the events and the kubelet logs"
+		}
+	case ResizeFileSystemResizePending:
+		switch {
+		case len(report.MountedBy) == 0:
+			report.Outcome = ExpansionWaitingForPod
+			report.Hint = "the volume has grown; start a pod that mounts it to grow the file system"
+		case inState > stuckAfter:
+			report.Outcome = ExpansionStuck
+			report.Hint = fmt.Sprintf("the file system has not grown while mounted by %s; if the CSI driver does not support online expansion, restart the pod", report.MountedBy[0])
+		default:
+			report.Outcome = ExpansionInProgress
+		}
+	case ResizeInProgress:
+		report.Outcome = ExpansionInProgress
+		if inState > stuckAfter {
+			report.Outcome = ExpansionStuck
+			report.Hint = "the volume has been resizing for " + inState.Round(time.Second).String() + "; check the events and the CSI driver's resizer logs"
+		}
+	default:
+		report.Outcome = ExpansionInProgress
+		if !pendingSince.IsZero() && time.Since(pendingSince) > stuckAfter {
+			report.Outcome = ExpansionStuck
+			report.Hint = "no resizer has picked up the request; check that the CSI driver runs an external-resizer"
+		}
+	}
+	return report, nil
+}
+
+// expansionChanged reports whether b is worth sending after a
+func expansionChanged(a, b *PVCExpansion) bool {
+	return a.State != b.State || a.Outcome != b.Outcome || a.Capacity != b.Capacity ||
+		!reflect.DeepEqual(a.Conditions, b.Conditions) ||
+		!reflect.DeepEqual(a.MountedBy, b.MountedBy) ||
+		len(a.Events) != len(b.Events) ||
+		(len(b.Events) > 0 && (a.Events[0].Name != b.Events[0].Name || a.Events[0].Count != b.Events[0].Count))
+}
@@ -0,0 +1,182 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Where a PVC resize is
+const (
+	ResizeNone                    = "none"     // capacity matches the request
+	ResizePending                 = "pending"  // requested, not picked up yet
+	ResizeInProgress              = "resizing" // the volume is being expanded
+	ResizeFileSystemResizePending = "filesystem-resize-pending"
+	ResizeFailed                  = "failed"
+)
+
+// ErrPVCNotResizable is returned when a PVC cannot be resized as asked
+var ErrPVCNotResizable = errors.New("PVC cannot be resized")
+
+// betaStorageClassAnnotation predates spec.storageClassName
+const betaStorageClassAnnotation = "volume.beta.kubernetes.io/storage-class"
+
+type PVCCondition struct {
+	Type               string `json:"type"`
+	Status             string `json:"status"`
+	Reason             string `json:"reason,omitempty"`
+	Message            string `json:"message,omitempty"`
+	LastTransitionTime string `json:"last_transition_time,omitempty"`
+}
+
+// PVCResizeStatus is a PVC's requested and actual size and how far the
+// resize between them has got
+type PVCResizeStatus struct {
+	Namespace      string         `json:"namespace"`
+	Name           string         `json:"name"`
+	StorageClass   string         `json:"storage_class"`
+	AllowExpansion bool           `json:"allow_expansion"`
+	Requested      string         `json:"requested"`
+	Capacity       string         `json:"capacity"`
+	State          string         `json:"state"`
+	ResourceStatus string         `json:"resource_status,omitempty"` // status.allocatedResourceStatuses[storage], when the cluster sets it
+	Conditions     []PVCCondition `json:"conditions"`
+}
+
+// GetPVCResizeStatus returns the resize progress of a PVC
+func GetPVCResizeStatus(ctx context.Context, namespace, name string) (*PVCResizeStatus, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	pvc, err := clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	allow, err := storageClassAllowsExpansion(ctx, pvcStorageClass(pvc))
+	if err != nil {
+		return nil, err
+	}
+	return pvcResizeStatus(pvc, allow), nil
+}
+
+// ResizePVC raises a bound PVC's storage request to size after checking its
+// StorageClass allows expansion. Volumes cannot shrink.
+func ResizePVC(ctx context.Context, namespace, name, size string) (*PVCResizeStatus, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	newSize, err := resource.ParseQuantity(size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid size %q: %v", ErrPVCNotResizable, size, err)
+	}
+	pvc, err := clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if pvc.Status.Phase != corev1.ClaimBound {
+		return nil, fmt.Errorf("%w: it is %s, only bound claims can be resized", ErrPVCNotResizable, pvc.Status.Phase)
+	}
+	className := pvcStorageClass(pvc)
+	if className == "" {
+		return nil, fmt.Errorf("%w: it has no StorageClass", ErrPVCNotResizable)
+	}
+	allow, err := storageClassAllowsExpansion(ctx, className)
+	if err != nil {
+		return nil, err
+	}
+	if !allow {
+		return nil, fmt.Errorf("%w: StorageClass %s does not allow volume expansion", ErrPVCNotResizable, className)
+	}
+	current := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if newSize.Cmp(current) <= 0 {
+		return nil, fmt.Errorf("%w: new size %s must be larger than the current request %s", ErrPVCNotResizable, newSize.String(), current.String())
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"resources": map[string]interface{}{
+				"requests": map[string]string{"storage": newSize.String()},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	pvc, err = clientset.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pvcResizeStatus(pvc, allow), nil
+}
+
+func pvcStorageClass(pvc *corev1.PersistentVolumeClaim) string {
+	if pvc.Spec.StorageClassName != nil {
+		return *pvc.Spec.StorageClassName
+	}
+	return pvc.Annotations[betaStorageClassAnnotation]
+}
+
+func storageClassAllowsExpansion(ctx context.Context, name string) (bool, error) {
+	if name == "" {
+		return false, nil
+	}
+	sc, err := clientset.StorageV1().StorageClasses().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("StorageClass %s: %w", name, err)
+	}
+	return sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion, nil
+}
+
+func pvcResizeStatus(pvc *corev1.PersistentVolumeClaim, allow bool) *PVCResizeStatus {
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	status := &PVCResizeStatus{
+		Namespace:      pvc.Namespace,
+		Name:           pvc.Name,
+		StorageClass:   pvcStorageClass(pvc),
+		AllowExpansion: allow,
+		Requested:      requested.String(),
+		Capacity:       capacity.String(),
+		ResourceStatus: string(pvc.Status.AllocatedResourceStatuses[corev1.ResourceStorage]),
+		Conditions:     []PVCCondition{},
+	}
+
+	conditions := map[corev1.PersistentVolumeClaimConditionType]bool{}
+	for _, c := range pvc.Status.Conditions {
+		status.Conditions = append(status.Conditions, PVCCondition{
+			Type:               string(c.Type),
+			Status:             string(c.Status),
+			Reason:             c.Reason,
+			Message:            c.Message,
+			LastTransitionTime: c.LastTransitionTime.Format(time.RFC3339),
+		})
+		conditions[c.Type] = c.Status == corev1.ConditionTrue
+	}
+
+	switch {
+	case status.ResourceStatus == string(corev1.PersistentVolumeClaimControllerResizeFailed),
+		status.ResourceStatus == string(corev1.PersistentVolumeClaimNodeResizeFailed):
+		status.State = ResizeFailed
+	case conditions[corev1.PersistentVolumeClaimFileSystemResizePending]:
+		status.State = ResizeFileSystemResizePending
+	case conditions[corev1.PersistentVolumeClaimResizing]:
+		status.State = ResizeInProgress
+	case capacity.Cmp(requested) >= 0:
+		status.State = ResizeNone
+	default:
+		status.State = ResizePending
+	}
+	return status
+}
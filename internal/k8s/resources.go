@@ -2,13 +2,15 @@ package k8s
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
+	"unicode/utf8"
 
-	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/yaml"
@@ -65,13 +67,28 @@ func PatchPod(ctx context.Context, namespace, name string, yamlContent string) e
 	return err
 }
 
+// PodDeleteOptions controls how pods are deleted
+type PodDeleteOptions struct {
+	GracePeriodSeconds *int64 // nil uses the pod's terminationGracePeriodSeconds
+	Force              bool   // delete immediately, like kubectl delete --force --grace-period=0
+}
+
+func (o PodDeleteOptions) toDeleteOptions() metav1.DeleteOptions {
+	opts := metav1.DeleteOptions{GracePeriodSeconds: o.GracePeriodSeconds}
+	if o.Force {
+		zero := int64(0)
+		opts.GracePeriodSeconds = &zero
+	}
+	return opts
+}
+
 // DeletePod deletes a pod
-func DeletePod(ctx context.Context, namespace, name string) error {
+func DeletePod(ctx context.Context, namespace, name string, opts PodDeleteOptions) error {
 	if clientset == nil {
 		return fmt.Errorf("kubernetes client not initialized")
 	}
 
-	return clientset.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	return clientset.CoreV1().Pods(namespace).Delete(ctx, name, opts.toDeleteOptions())
 }
 
 // GetService returns a single service's details as YAML
@@ -250,6 +267,117 @@ func GetSecret(ctx context.Context, namespace, name string) (*ResourceDetail, er
 	}, nil
 }
 
+// SecretData returns a secret's decoded data
+func SecretData(ctx context.Context, namespace, name string) (map[string][]byte, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
+
+// SecretDecodeEnabled reports whether secrets may be decoded server-side,
+// which GAGOS_SECRET_DECODE=true turns on
+func SecretDecodeEnabled() bool {
+	return os.Getenv("GAGOS_SECRET_DECODE") == "true"
+}
+
+// SecretValue is one decoded key of a secret. Value is base64 when Encoding
+// says so, because it is not valid UTF-8, and empty when Redacted.
+type SecretValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value,omitempty"`
+	Encoding string `json:"encoding,omitempty"` // "base64" for binary values
+	Size     int    `json:"size"`
+	Redacted bool   `json:"redacted,omitempty"`
+}
+
+// DecodedSecret is a secret with its data decoded
+type DecodedSecret struct {
+	ResourceDetail
+	Type     string        `json:"type"`
+	Data     []SecretValue `json:"data"`
+	Revealed []string      `json:"revealed"`
+}
+
+// DecodeSecret returns a secret with the values of keys decoded, or of all
+// keys when keys is empty; the others are redacted
+func DecodeSecret(ctx context.Context, namespace, name string, keys []string) (*DecodedSecret, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	reveal := map[string]bool{}
+	for _, k := range keys {
+		if _, ok := secret.Data[k]; !ok {
+			return nil, fmt.Errorf("secret %s/%s has no key %q", namespace, name, k)
+		}
+		reveal[k] = true
+	}
+
+	// The YAML keeps the values base64 encoded, as stored, and leaves out
+	// the keys that are not revealed, along with the last applied
+	// configuration that would carry them too
+	secret.ManagedFields = nil
+	shown := secret.DeepCopy()
+	if len(keys) > 0 {
+		for key := range shown.Data {
+			if !reveal[key] {
+				delete(shown.Data, key)
+			}
+		}
+		for key := range shown.StringData {
+			if !reveal[key] {
+				delete(shown.StringData, key)
+			}
+		}
+		delete(shown.Annotations, corev1.LastAppliedConfigAnnotation)
+	}
+	yamlBytes, err := yaml.Marshal(shown)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DecodedSecret{
+		ResourceDetail: ResourceDetail{
+			Kind:      "Secret",
+			Name:      secret.Name,
+			Namespace: secret.Namespace,
+			YAML:      string(yamlBytes),
+		},
+		Type:     string(secret.Type),
+		Data:     []SecretValue{},
+		Revealed: []string{},
+	}
+	for key, data := range secret.Data {
+		v := SecretValue{Key: key, Size: len(data)}
+		switch {
+		case len(keys) > 0 && !reveal[key]:
+			v.Redacted = true
+		case utf8.Valid(data):
+			v.Value = string(data)
+		default:
+			v.Value, v.Encoding = base64.StdEncoding.EncodeToString(data), "base64"
+		}
+		if !v.Redacted {
+			result.Revealed = append(result.Revealed, key)
+		}
+		result.Data = append(result.Data, v)
+	}
+	sort.Slice(result.Data, func(i, j int) bool { return result.Data[i].Key < result.Data[j].Key })
+	sort.Strings(result.Revealed)
+	return result, nil
+}
+
 // PatchSecret updates a secret with the provided YAML
 func PatchSecret(ctx context.Context, namespace, name string, yamlContent string) error {
 	if clientset == nil {
@@ -333,21 +461,29 @@ func DeleteNamespace(ctx context.Context, name string) error {
 	return clientset.CoreV1().Namespaces().Delete(ctx, name, metav1.DeleteOptions{})
 }
 
+// PodLogOptions selects the logs GetPodLogs returns
+type PodLogOptions struct {
+	Container     string
+	TailLines     int64 // per container; 0 for all
+	Previous      bool  // logs of the previous instance, e.g. before a crash
+	AllContainers bool  // every container, merged by time; Container is ignored
+}
+
 // GetPodLogs returns logs from a pod
-func GetPodLogs(ctx context.Context, namespace, name, container string, tailLines int64) (string, error) {
+func GetPodLogs(ctx context.Context, namespace, name string, opts PodLogOptions) (string, error) {
 	if clientset == nil {
 		return "", fmt.Errorf("kubernetes client not initialized")
 	}
-
-	opts := &corev1.PodLogOptions{}
-	if container != "" {
-		opts.Container = container
+	if opts.AllContainers {
+		return getAllContainerLogs(ctx, namespace, name, opts)
 	}
-	if tailLines > 0 {
-		opts.TailLines = &tailLines
+
+	logOpts := &corev1.PodLogOptions{Container: opts.Container, Previous: opts.Previous}
+	if opts.TailLines > 0 {
+		logOpts.TailLines = &opts.TailLines
 	}
 
-	req := clientset.CoreV1().Pods(namespace).GetLogs(name, opts)
+	req := clientset.CoreV1().Pods(namespace).GetLogs(name, logOpts)
 	result, err := req.DoRaw(ctx)
 	if err != nil {
 		return "", err
@@ -805,6 +941,67 @@ func PatchCronJob(ctx context.Context, namespace, name string, yamlContent strin
 	return err
 }
 
+// TriggerCronJob creates a Job from the CronJob's jobTemplate, like
+// kubectl create job --from=cronjob/<name>, and returns the Job's name. The
+// Job is owned by the CronJob, so it is cleaned up with it.
+func TriggerCronJob(ctx context.Context, namespace, name string) (string, error) {
+	if clientset == nil {
+		return "", fmt.Errorf("kubernetes client not initialized")
+	}
+
+	cj, err := clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	annotations := map[string]string{"cronjob.kubernetes.io/instantiate": "manual"}
+	for k, v := range cj.Spec.JobTemplate.Annotations {
+		annotations[k] = v
+	}
+
+	// Job names end up in the job-name label, which is capped at 63
+	// characters including the 5 generated ones
+	prefix := name
+	if len(prefix) > 50 {
+		prefix = prefix[:50]
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName:    prefix + "-manual-",
+			Namespace:       namespace,
+			Labels:          cj.Spec.JobTemplate.Labels,
+			Annotations:     annotations,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(cj, batchv1.SchemeGroupVersion.WithKind("CronJob"))},
+		},
+		Spec: cj.Spec.JobTemplate.Spec,
+	}
+
+	created, err := clientset.BatchV1().Jobs(namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return "", err
+	}
+	return created.Name, nil
+}
+
+// SetCronJobSuspend suspends or resumes a CronJob's schedule. Jobs that are
+// already running are not affected.
+func SetCronJobSuspend(ctx context.Context, namespace, name string, suspend bool) error {
+	if clientset == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"suspend": suspend},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = clientset.BatchV1().CronJobs(namespace).Patch(ctx, name, types.MergePatchType, patchBytes, metav1.PatchOptions{})
+	return err
+}
+
 func DeleteCronJob(ctx context.Context, namespace, name string) error {
 	if clientset == nil {
 		return fmt.Errorf("kubernetes client not initialized")
@@ -871,184 +1068,145 @@ func GetEvent(ctx context.Context, namespace, name string) (*ResourceDetail, err
 	}, nil
 }
 
-// ========== Create Functions ==========
+// ========== NetworkPolicy ==========
 
-// CreateDeployment creates a new Deployment from YAML
-func CreateDeployment(ctx context.Context, namespace string, yamlContent string) error {
+func GetNetworkPolicy(ctx context.Context, namespace, name string) (*ResourceDetail, error) {
 	if clientset == nil {
-		return fmt.Errorf("kubernetes client not initialized")
-	}
-
-	var deployment appsv1.Deployment
-	if err := yaml.Unmarshal([]byte(yamlContent), &deployment); err != nil {
-		return fmt.Errorf("invalid YAML: %w", err)
+		return nil, fmt.Errorf("kubernetes client not initialized")
 	}
 
-	_, err := clientset.AppsV1().Deployments(namespace).Create(ctx, &deployment, metav1.CreateOptions{})
-	return err
-}
-
-// CreateService creates a new Service from YAML
-func CreateService(ctx context.Context, namespace string, yamlContent string) error {
-	if clientset == nil {
-		return fmt.Errorf("kubernetes client not initialized")
+	np, err := clientset.NetworkingV1().NetworkPolicies(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
 	}
 
-	var svc corev1.Service
-	if err := yaml.Unmarshal([]byte(yamlContent), &svc); err != nil {
-		return fmt.Errorf("invalid YAML: %w", err)
+	np.ManagedFields = nil
+	yamlBytes, err := yaml.Marshal(np)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err := clientset.CoreV1().Services(namespace).Create(ctx, &svc, metav1.CreateOptions{})
-	return err
+	return &ResourceDetail{
+		Kind:      "NetworkPolicy",
+		Name:      np.Name,
+		Namespace: np.Namespace,
+		YAML:      string(yamlBytes),
+	}, nil
 }
 
-// CreateConfigMap creates a new ConfigMap from YAML
-func CreateConfigMap(ctx context.Context, namespace string, yamlContent string) error {
+func PatchNetworkPolicy(ctx context.Context, namespace, name string, yamlContent string) error {
 	if clientset == nil {
 		return fmt.Errorf("kubernetes client not initialized")
 	}
 
-	var cm corev1.ConfigMap
-	if err := yaml.Unmarshal([]byte(yamlContent), &cm); err != nil {
+	jsonBytes, err := yaml.YAMLToJSON([]byte(yamlContent))
+	if err != nil {
 		return fmt.Errorf("invalid YAML: %w", err)
 	}
 
-	_, err := clientset.CoreV1().ConfigMaps(namespace).Create(ctx, &cm, metav1.CreateOptions{})
+	_, err = clientset.NetworkingV1().NetworkPolicies(namespace).Patch(ctx, name, types.StrategicMergePatchType, jsonBytes, metav1.PatchOptions{})
 	return err
 }
 
-// CreateSecret creates a new Secret from YAML
-func CreateSecret(ctx context.Context, namespace string, yamlContent string) error {
+func DeleteNetworkPolicy(ctx context.Context, namespace, name string) error {
 	if clientset == nil {
 		return fmt.Errorf("kubernetes client not initialized")
 	}
-
-	var secret corev1.Secret
-	if err := yaml.Unmarshal([]byte(yamlContent), &secret); err != nil {
-		return fmt.Errorf("invalid YAML: %w", err)
-	}
-
-	_, err := clientset.CoreV1().Secrets(namespace).Create(ctx, &secret, metav1.CreateOptions{})
-	return err
+	return clientset.NetworkingV1().NetworkPolicies(namespace).Delete(ctx, name, metav1.DeleteOptions{})
 }
 
-// CreateIngress creates a new Ingress from YAML
-func CreateIngress(ctx context.Context, namespace string, yamlContent string) error {
-	if clientset == nil {
-		return fmt.Errorf("kubernetes client not initialized")
-	}
+// ========== PodDisruptionBudget ==========
 
-	var ing networkingv1.Ingress
-	if err := yaml.Unmarshal([]byte(yamlContent), &ing); err != nil {
-		return fmt.Errorf("invalid YAML: %w", err)
+func GetPodDisruptionBudget(ctx context.Context, namespace, name string) (*ResourceDetail, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
 	}
 
-	_, err := clientset.NetworkingV1().Ingresses(namespace).Create(ctx, &ing, metav1.CreateOptions{})
-	return err
-}
-
-// CreatePod creates a new Pod from YAML
-func CreatePod(ctx context.Context, namespace string, yamlContent string) error {
-	if clientset == nil {
-		return fmt.Errorf("kubernetes client not initialized")
+	pdb, err := clientset.PolicyV1().PodDisruptionBudgets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
 	}
 
-	var pod corev1.Pod
-	if err := yaml.Unmarshal([]byte(yamlContent), &pod); err != nil {
-		return fmt.Errorf("invalid YAML: %w", err)
+	pdb.ManagedFields = nil
+	yamlBytes, err := yaml.Marshal(pdb)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err := clientset.CoreV1().Pods(namespace).Create(ctx, &pod, metav1.CreateOptions{})
-	return err
+	return &ResourceDetail{
+		Kind:      "PodDisruptionBudget",
+		Name:      pdb.Name,
+		Namespace: pdb.Namespace,
+		YAML:      string(yamlBytes),
+	}, nil
 }
 
-// CreateCronJob creates a new CronJob from YAML
-func CreateCronJob(ctx context.Context, namespace string, yamlContent string) error {
+func PatchPodDisruptionBudget(ctx context.Context, namespace, name string, yamlContent string) error {
 	if clientset == nil {
 		return fmt.Errorf("kubernetes client not initialized")
 	}
 
-	var cj batchv1.CronJob
-	if err := yaml.Unmarshal([]byte(yamlContent), &cj); err != nil {
+	jsonBytes, err := yaml.YAMLToJSON([]byte(yamlContent))
+	if err != nil {
 		return fmt.Errorf("invalid YAML: %w", err)
 	}
 
-	_, err := clientset.BatchV1().CronJobs(namespace).Create(ctx, &cj, metav1.CreateOptions{})
+	_, err = clientset.PolicyV1().PodDisruptionBudgets(namespace).Patch(ctx, name, types.StrategicMergePatchType, jsonBytes, metav1.PatchOptions{})
 	return err
 }
 
-// CreateJob creates a new Job from YAML
-func CreateJob(ctx context.Context, namespace string, yamlContent string) error {
+func DeletePodDisruptionBudget(ctx context.Context, namespace, name string) error {
 	if clientset == nil {
 		return fmt.Errorf("kubernetes client not initialized")
 	}
-
-	var job batchv1.Job
-	if err := yaml.Unmarshal([]byte(yamlContent), &job); err != nil {
-		return fmt.Errorf("invalid YAML: %w", err)
-	}
-
-	_, err := clientset.BatchV1().Jobs(namespace).Create(ctx, &job, metav1.CreateOptions{})
-	return err
+	return clientset.PolicyV1().PodDisruptionBudgets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
 }
 
-// CreatePersistentVolumeClaim creates a new PVC from YAML
-func CreatePersistentVolumeClaim(ctx context.Context, namespace string, yamlContent string) error {
-	if clientset == nil {
-		return fmt.Errorf("kubernetes client not initialized")
-	}
+// ========== StorageClass ==========
 
-	var pvc corev1.PersistentVolumeClaim
-	if err := yaml.Unmarshal([]byte(yamlContent), &pvc); err != nil {
-		return fmt.Errorf("invalid YAML: %w", err)
+func GetStorageClass(ctx context.Context, name string) (*ResourceDetail, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
 	}
 
-	_, err := clientset.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, &pvc, metav1.CreateOptions{})
-	return err
-}
-
-// CreateServiceAccount creates a new ServiceAccount from YAML
-func CreateServiceAccount(ctx context.Context, namespace string, yamlContent string) error {
-	if clientset == nil {
-		return fmt.Errorf("kubernetes client not initialized")
+	sc, err := clientset.StorageV1().StorageClasses().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
 	}
 
-	var sa corev1.ServiceAccount
-	if err := yaml.Unmarshal([]byte(yamlContent), &sa); err != nil {
-		return fmt.Errorf("invalid YAML: %w", err)
+	sc.ManagedFields = nil
+	yamlBytes, err := yaml.Marshal(sc)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err := clientset.CoreV1().ServiceAccounts(namespace).Create(ctx, &sa, metav1.CreateOptions{})
-	return err
+	return &ResourceDetail{
+		Kind: "StorageClass",
+		Name: sc.Name,
+		YAML: string(yamlBytes),
+	}, nil
 }
 
-// CreateDaemonSet creates a new DaemonSet from YAML
-func CreateDaemonSet(ctx context.Context, namespace string, yamlContent string) error {
+// PatchStorageClass updates a StorageClass. Most fields (provisioner,
+// parameters, reclaimPolicy) are immutable; labels, annotations such as the
+// default-class marker and allowVolumeExpansion can be changed.
+func PatchStorageClass(ctx context.Context, name string, yamlContent string) error {
 	if clientset == nil {
 		return fmt.Errorf("kubernetes client not initialized")
 	}
 
-	var ds appsv1.DaemonSet
-	if err := yaml.Unmarshal([]byte(yamlContent), &ds); err != nil {
+	jsonBytes, err := yaml.YAMLToJSON([]byte(yamlContent))
+	if err != nil {
 		return fmt.Errorf("invalid YAML: %w", err)
 	}
 
-	_, err := clientset.AppsV1().DaemonSets(namespace).Create(ctx, &ds, metav1.CreateOptions{})
+	_, err = clientset.StorageV1().StorageClasses().Patch(ctx, name, types.StrategicMergePatchType, jsonBytes, metav1.PatchOptions{})
 	return err
 }
 
-// CreateStatefulSet creates a new StatefulSet from YAML
-func CreateStatefulSet(ctx context.Context, namespace string, yamlContent string) error {
+func DeleteStorageClass(ctx context.Context, name string) error {
 	if clientset == nil {
 		return fmt.Errorf("kubernetes client not initialized")
 	}
-
-	var ss appsv1.StatefulSet
-	if err := yaml.Unmarshal([]byte(yamlContent), &ss); err != nil {
-		return fmt.Errorf("invalid YAML: %w", err)
-	}
-
-	_, err := clientset.AppsV1().StatefulSets(namespace).Create(ctx, &ss, metav1.CreateOptions{})
-	return err
+	return clientset.StorageV1().StorageClasses().Delete(ctx, name, metav1.DeleteOptions{})
 }
@@ -0,0 +1,73 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDecodeSecretRedactsYAML(t *testing.T) {
+	SetClient(fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "db",
+			Namespace: "default",
+			Annotations: map[string]string{
+				corev1.LastAppliedConfigAnnotation: `{"data":{"password":"aHVudGVyMg=="}}`,
+			},
+		},
+		Data: map[string][]byte{
+			"username": []byte("admin"),
+			"password": []byte("hunter2"),
+			"cert":     {0xff, 0x00},
+		},
+	}), nil)
+	defer SetClient(nil, nil)
+
+	tests := []struct {
+		name     string
+		keys     []string
+		revealed []string
+		hidden   []string
+	}{
+		{"all keys", nil, []string{"cert", "password", "username"}, nil},
+		{"one key", []string{"username"}, []string{"username"}, []string{"password", "cert"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeSecret(context.Background(), "default", "db", tt.keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got.Revealed, ",") != strings.Join(tt.revealed, ",") {
+				t.Errorf("revealed = %v, want %v", got.Revealed, tt.revealed)
+			}
+			for _, v := range got.Data {
+				if v.Key == "cert" && !v.Redacted && v.Encoding != "base64" {
+					t.Errorf("binary value not base64 encoded: %+v", v)
+				}
+			}
+			for _, key := range tt.hidden {
+				if strings.Contains(got.YAML, key+":") {
+					t.Errorf("YAML carries redacted key %q:\n%s", key, got.YAML)
+				}
+			}
+			if len(tt.keys) > 0 {
+				if strings.Contains(got.YAML, base64.StdEncoding.EncodeToString([]byte("hunter2"))) {
+					t.Errorf("YAML carries a redacted value:\n%s", got.YAML)
+				}
+			}
+		})
+	}
+
+	if _, err := DecodeSecret(context.Background(), "default", "db", []string{"missing"}); err == nil {
+		t.Error("expected an error for a missing key")
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gaga951/gagos/internal/fanout"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Editing a ConfigMap or Secret does nothing to the pods that use it:
+// environment variables are read once at start, and subPath mounts never
+// see updates. The rollout impact lists the workloads that consume one and
+// can restart them so the edit takes effect.
+
+// ConfigConsumer is a workload that uses a ConfigMap or Secret
+type ConfigConsumer struct {
+	Kind         string   `json:"kind"` // deployment, statefulset, daemonset, cronjob, job or pod
+	Name         string   `json:"name"`
+	Usages       []string `json:"usages"`
+	NeedsRestart bool     `json:"needs_restart"` // some usage never sees edits without a restart
+	Restartable  bool     `json:"restartable"`   // a rollout can restart it
+}
+
+// ConfigPod is a running pod that uses a ConfigMap or Secret
+type ConfigPod struct {
+	Name     string   `json:"name"`
+	Phase    string   `json:"phase"`
+	Workload string   `json:"workload,omitempty"` // kind/name of the consumer it belongs to
+	Usages   []string `json:"usages"`
+}
+
+// ConfigImpact is the set of workloads and pods an edit to a ConfigMap or
+// Secret affects
+type ConfigImpact struct {
+	Kind      string           `json:"kind"` // configmap or secret
+	Name      string           `json:"name"`
+	Namespace string           `json:"namespace"`
+	Consumers []ConfigConsumer `json:"consumers"`
+	Pods      []ConfigPod      `json:"pods"`
+}
+
+// restartableKinds are the consumers a rollout restarts; CronJobs and Jobs
+// pick up an edit with their next pod
+var restartableKinds = map[string]bool{"deployment": true, "statefulset": true, "daemonset": true}
+
+// RolloutResult is the outcome of restarting one consumer
+type RolloutResult struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Restarted bool   `json:"restarted"`
+	Error     string `json:"error,omitempty"`
+}
+
+// GetConfigImpact lists the workloads in namespace that mount, envFrom or
+// reference a key of the given ConfigMap or Secret, and the pods using it
+// now. Pods without a controller are listed as consumers of their own.
+// kind is "configmap" or "secret".
+func GetConfigImpact(ctx context.Context, kind, namespace, name string) (*ConfigImpact, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	if kind != "configmap" && kind != "secret" {
+		return nil, fmt.Errorf("unsupported kind: %s", kind)
+	}
+
+	impact := &ConfigImpact{Kind: kind, Name: name, Namespace: namespace, Consumers: []ConfigConsumer{}, Pods: []ConfigPod{}}
+	add := func(workload, workloadName string, spec *corev1.PodSpec) {
+		usages, restart := configUsages(spec, kind, name)
+		if len(usages) > 0 {
+			impact.Consumers = append(impact.Consumers, ConfigConsumer{
+				Kind:         workload,
+				Name:         workloadName,
+				Usages:       usages,
+				NeedsRestart: restart,
+				Restartable:  restartableKinds[workload],
+			})
+		}
+	}
+
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		add("deployment", d.Name, &d.Spec.Template.Spec)
+	}
+	statefulsets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range statefulsets.Items {
+		s := &statefulsets.Items[i]
+		add("statefulset", s.Name, &s.Spec.Template.Spec)
+	}
+	daemonsets, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range daemonsets.Items {
+		d := &daemonsets.Items[i]
+		add("daemonset", d.Name, &d.Spec.Template.Spec)
+	}
+	cronjobs, err := clientset.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range cronjobs.Items {
+		cj := &cronjobs.Items[i]
+		add("cronjob", cj.Name, &cj.Spec.JobTemplate.Spec.Template.Spec)
+	}
+	jobs, err := clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	jobOwners := map[string]string{}
+	for i := range jobs.Items {
+		j := &jobs.Items[i]
+		// Jobs of a CronJob are covered by the CronJob; finished ones no longer run
+		if ref := metav1.GetControllerOf(j); ref != nil {
+			if ref.Kind == "CronJob" {
+				jobOwners[j.Name] = "cronjob/" + ref.Name
+			}
+		} else if j.Status.CompletionTime == nil {
+			add("job", j.Name, &j.Spec.Template.Spec)
+		}
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		usages, _ := configUsages(&pod.Spec, kind, name)
+		if len(usages) == 0 {
+			continue
+		}
+		cp := ConfigPod{Name: pod.Name, Phase: string(pod.Status.Phase), Workload: podWorkload(pod, jobOwners), Usages: usages}
+		if cp.Workload == "" {
+			add("pod", pod.Name, &pod.Spec)
+			cp.Workload = "pod/" + pod.Name
+		}
+		impact.Pods = append(impact.Pods, cp)
+	}
+	sort.Slice(impact.Pods, func(i, j int) bool {
+		return impact.Pods[i].Name < impact.Pods[j].Name
+	})
+
+	sort.Slice(impact.Consumers, func(i, j int) bool {
+		a, b := impact.Consumers[i], impact.Consumers[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return impact, nil
+}
+
+// RolloutConfigConsumers restarts the consumers of a ConfigMap or Secret.
+// With workloads ("deployment/web", ...) only those are restarted, and each
+// must still be a restartable consumer; otherwise every restartable
+// consumer is.
+func RolloutConfigConsumers(ctx context.Context, kind, namespace, name string, workloads []string) ([]RolloutResult, error) {
+	impact, err := GetConfigImpact(ctx, kind, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []ConfigConsumer
+	for _, c := range impact.Consumers {
+		if c.Restartable {
+			targets = append(targets, c)
+		}
+	}
+	if len(workloads) > 0 {
+		consumers := make(map[string]ConfigConsumer, len(impact.Consumers))
+		for _, c := range impact.Consumers {
+			consumers[c.Kind+"/"+c.Name] = c
+		}
+		targets = nil
+		for _, w := range workloads {
+			c, ok := consumers[w]
+			if !ok {
+				return nil, fmt.Errorf("%s does not use %s/%s", w, kind, name)
+			}
+			if !c.Restartable {
+				return nil, fmt.Errorf("%s cannot be restarted; it picks up the change with its next pod", w)
+			}
+			targets = append(targets, c)
+		}
+	}
+
+	results := make([]RolloutResult, len(targets))
+	tasks := make(map[string]fanout.Func, len(targets))
+	for i, c := range targets {
+		i, c := i, c
+		results[i] = RolloutResult{Kind: c.Kind, Name: c.Name}
+		tasks[c.Kind+"/"+c.Name] = func(ctx context.Context) error {
+			var err error
+			switch c.Kind {
+			case "deployment":
+				err = RestartDeployment(ctx, namespace, c.Name)
+			case "statefulset":
+				err = RestartStatefulSet(ctx, namespace, c.Name)
+			case "daemonset":
+				err = RestartDaemonSet(ctx, namespace, c.Name)
+			}
+			if err != nil {
+				results[i].Error = err.Error()
+			} else {
+				results[i].Restarted = true
+			}
+			return err
+		}
+	}
+	// Per-workload errors are already in results
+	fanout.Run(ctx, fanout.Options{Limit: 8}, tasks)
+	return results, nil
+}
+
+// podWorkload names the consumer a pod belongs to, as kind/name: the
+// Deployment of its ReplicaSet, the CronJob of its Job (from jobOwners), or
+// its controller. Empty for a pod without a controller.
+func podWorkload(pod *corev1.Pod, jobOwners map[string]string) string {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return ""
+	}
+	switch ref.Kind {
+	case "ReplicaSet":
+		// <deployment>-<pod-template-hash>
+		if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(ref.Name, "-"+hash) {
+			return "deployment/" + strings.TrimSuffix(ref.Name, "-"+hash)
+		}
+	case "Job":
+		if owner, ok := jobOwners[ref.Name]; ok {
+			return owner
+		}
+	}
+	return strings.ToLower(ref.Kind) + "/" + ref.Name
+}
+
+// configUsages describes how spec uses the ConfigMap or Secret, and whether
+// any of those usages only picks up an edit when the pods restart
+func configUsages(spec *corev1.PodSpec, kind, name string) ([]string, bool) {
+	var usages []string
+	restart := false
+	seen := map[string]bool{}
+	use := func(usage string, needsRestart bool) {
+		if !seen[usage] {
+			seen[usage] = true
+			usages = append(usages, usage)
+		}
+		restart = restart || needsRestart
+	}
+
+	volumes := map[string]bool{}
+	for _, v := range spec.Volumes {
+		switch {
+		case kind == "configmap" && v.ConfigMap != nil && v.ConfigMap.Name == name,
+			kind == "secret" && v.Secret != nil && v.Secret.SecretName == name:
+			volumes[v.Name] = true
+		case v.Projected != nil:
+			for _, src := range v.Projected.Sources {
+				if kind == "configmap" && src.ConfigMap != nil && src.ConfigMap.Name == name ||
+					kind == "secret" && src.Secret != nil && src.Secret.Name == name {
+					volumes[v.Name] = true
+				}
+			}
+		}
+	}
+	if kind == "secret" {
+		for _, s := range spec.ImagePullSecrets {
+			if s.Name == name {
+				// Only read when an image is pulled
+				use("imagePullSecret", false)
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range containers {
+		for _, m := range c.VolumeMounts {
+			if !volumes[m.Name] {
+				continue
+			}
+			if m.SubPath != "" || m.SubPathExpr != "" {
+				use(fmt.Sprintf("volume %s (subPath) in %s", m.Name, c.Name), true)
+			} else {
+				use(fmt.Sprintf("volume %s in %s", m.Name, c.Name), false)
+			}
+		}
+		for _, from := range c.EnvFrom {
+			if kind == "configmap" && from.ConfigMapRef != nil && from.ConfigMapRef.Name == name ||
+				kind == "secret" && from.SecretRef != nil && from.SecretRef.Name == name {
+				use("envFrom in "+c.Name, true)
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if kind == "configmap" && env.ValueFrom.ConfigMapKeyRef != nil && env.ValueFrom.ConfigMapKeyRef.Name == name {
+				use(fmt.Sprintf("env %s in %s (key %s)", env.Name, c.Name, env.ValueFrom.ConfigMapKeyRef.Key), true)
+			}
+			if kind == "secret" && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == name {
+				use(fmt.Sprintf("env %s in %s (key %s)", env.Name, c.Name, env.ValueFrom.SecretKeyRef.Key), true)
+			}
+		}
+	}
+	return usages, restart
+}
@@ -0,0 +1,305 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Search limits
+const (
+	DefaultSearchLimit = 200
+	MaxSearchLimit     = 1000
+)
+
+// Fields a search hit can match on
+const (
+	SearchFieldName       = "name"
+	SearchFieldLabel      = "label"
+	SearchFieldAnnotation = "annotation"
+	SearchFieldReference  = "reference"
+)
+
+// lastAppliedAnnotation holds a copy of the whole object, so matching its
+// value would match nearly everything
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// SearchMatch is one field of a resource that matched the query. Value is
+// the label or annotation value, or the referenced object as kind/name.
+type SearchMatch struct {
+	Field string `json:"field"`
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// SearchHit is a resource that matched the query
+type SearchHit struct {
+	Kind      string        `json:"kind"` // as in WatchableKinds
+	Namespace string        `json:"namespace,omitempty"`
+	Name      string        `json:"name"`
+	Matches   []SearchMatch `json:"matches"`
+}
+
+// SearchResult holds the hits of a search. Unsynced lists kinds skipped
+// because their cache did not fill in time, usually for lack of RBAC.
+type SearchResult struct {
+	Query     string      `json:"query"`
+	Hits      []SearchHit `json:"hits"`
+	Count     int         `json:"count"`
+	Truncated bool        `json:"truncated"`
+	Unsynced  []string    `json:"unsynced,omitempty"`
+}
+
+// SearchResources finds resources of the given kinds (every watchable kind
+// but events and leases when empty) whose name, labels, annotations or the names of
+// objects they reference (ConfigMaps, Secrets, PVCs, ServiceAccounts and, for
+// Ingresses, Services) contain query, case-insensitively. It reads the shared informer caches, starting the
+// ones not running yet.
+func SearchResources(ctx context.Context, query string, kinds []string, namespace string, limit int) (*SearchResult, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	q := strings.ToLower(strings.TrimSpace(query))
+	if len(q) < 2 {
+		return nil, fmt.Errorf("query must be at least 2 characters")
+	}
+	if len(kinds) == 0 {
+		for _, kind := range WatchableKinds() {
+			// Both change every few seconds and hold nothing worth finding
+			if kind != "events" && kind != "leases" {
+				kinds = append(kinds, kind)
+			}
+		}
+	}
+	for _, kind := range kinds {
+		if _, ok := watchKinds[kind]; !ok {
+			return nil, fmt.Errorf("unsupported kind: %s", kind)
+		}
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	factory := sharedInformerFactory()
+	informers := make([]cache.SharedIndexInformer, len(kinds))
+	for i, kind := range kinds {
+		informers[i] = watchKinds[kind].informer(factory)
+	}
+	factory.Start(informerStop)
+
+	// One deadline for every kind, so a kind that never syncs costs the
+	// search cacheSyncTimeout once rather than per kind
+	syncCtx, cancel := context.WithTimeout(ctx, cacheSyncTimeout)
+	defer cancel()
+
+	result := &SearchResult{Query: query, Hits: []SearchHit{}}
+	for i, kind := range kinds {
+		if !cache.WaitForCacheSync(syncCtx.Done(), informers[i].HasSynced) {
+			result.Unsynced = append(result.Unsynced, kind)
+			continue
+		}
+		objs := informers[i].GetIndexer().List()
+		if namespace != "" {
+			objs, _ = informers[i].GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+		}
+
+		var hits []SearchHit
+		for _, obj := range objs {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				continue
+			}
+			if matches := searchObject(q, accessor, obj); len(matches) > 0 {
+				hits = append(hits, SearchHit{Kind: kind, Namespace: accessor.GetNamespace(), Name: accessor.GetName(), Matches: matches})
+			}
+		}
+		sort.Slice(hits, func(i, j int) bool {
+			if hits[i].Namespace != hits[j].Namespace {
+				return hits[i].Namespace < hits[j].Namespace
+			}
+			return hits[i].Name < hits[j].Name
+		})
+		result.Hits = append(result.Hits, hits...)
+	}
+
+	// Exact name matches first, then by kind as requested
+	sort.SliceStable(result.Hits, func(i, j int) bool {
+		return strings.EqualFold(result.Hits[i].Name, q) && !strings.EqualFold(result.Hits[j].Name, q)
+	})
+	if len(result.Hits) > limit {
+		result.Hits, result.Truncated = result.Hits[:limit], true
+	}
+	result.Count = len(result.Hits)
+	return result, nil
+}
+
+// searchObject returns the fields of obj that contain q
+func searchObject(q string, accessor metav1.Object, obj interface{}) []SearchMatch {
+	var matches []SearchMatch
+	contains := func(s string) bool { return strings.Contains(strings.ToLower(s), q) }
+
+	if contains(accessor.GetName()) {
+		matches = append(matches, SearchMatch{Field: SearchFieldName})
+	}
+	for _, k := range sortedStringKeys(accessor.GetLabels()) {
+		if v := accessor.GetLabels()[k]; contains(k) || contains(v) {
+			matches = append(matches, SearchMatch{Field: SearchFieldLabel, Key: k, Value: v})
+		}
+	}
+	for _, k := range sortedStringKeys(accessor.GetAnnotations()) {
+		v := accessor.GetAnnotations()[k]
+		if contains(k) || (k != lastAppliedAnnotation && contains(v)) {
+			if k == lastAppliedAnnotation {
+				v = ""
+			}
+			matches = append(matches, SearchMatch{Field: SearchFieldAnnotation, Key: k, Value: v})
+		}
+	}
+	for _, ref := range objectReferences(obj) {
+		if contains(ref[strings.IndexByte(ref, '/')+1:]) {
+			matches = append(matches, SearchMatch{Field: SearchFieldReference, Value: ref})
+		}
+	}
+	return matches
+}
+
+// objectReferences returns the objects obj uses by name, as kind/name
+func objectReferences(obj interface{}) []string {
+	var spec *corev1.PodSpec
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		spec = &o.Spec
+	case *appsv1.Deployment:
+		spec = &o.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		spec = &o.Spec.Template.Spec
+	case *appsv1.DaemonSet:
+		spec = &o.Spec.Template.Spec
+	case *appsv1.ReplicaSet:
+		spec = &o.Spec.Template.Spec
+	case *batchv1.Job:
+		spec = &o.Spec.Template.Spec
+	case *batchv1.CronJob:
+		spec = &o.Spec.JobTemplate.Spec.Template.Spec
+	case *networkingv1.Ingress:
+		return ingressReferences(o)
+	case *corev1.PersistentVolumeClaim:
+		if o.Spec.VolumeName != "" {
+			return []string{"persistentvolume/" + o.Spec.VolumeName}
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	seen := map[string]bool{}
+	var refs []string
+	add := func(kind, name string) {
+		if ref := kind + "/" + name; name != "" && !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	add("serviceaccount", spec.ServiceAccountName)
+	for _, s := range spec.ImagePullSecrets {
+		add("secret", s.Name)
+	}
+	for _, v := range spec.Volumes {
+		switch {
+		case v.ConfigMap != nil:
+			add("configmap", v.ConfigMap.Name)
+		case v.Secret != nil:
+			add("secret", v.Secret.SecretName)
+		case v.PersistentVolumeClaim != nil:
+			add("persistentvolumeclaim", v.PersistentVolumeClaim.ClaimName)
+		case v.Projected != nil:
+			for _, src := range v.Projected.Sources {
+				if src.ConfigMap != nil {
+					add("configmap", src.ConfigMap.Name)
+				}
+				if src.Secret != nil {
+					add("secret", src.Secret.Name)
+				}
+			}
+		}
+	}
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range containers {
+		for _, from := range c.EnvFrom {
+			if from.ConfigMapRef != nil {
+				add("configmap", from.ConfigMapRef.Name)
+			}
+			if from.SecretRef != nil {
+				add("secret", from.SecretRef.Name)
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				add("configmap", env.ValueFrom.ConfigMapKeyRef.Name)
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				add("secret", env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+	return refs
+}
+
+// ingressReferences returns the Services and TLS Secrets an Ingress uses
+func ingressReferences(ing *networkingv1.Ingress) []string {
+	seen := map[string]bool{}
+	var refs []string
+	add := func(ref string) {
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	backend := func(b *networkingv1.IngressBackend) {
+		if b != nil && b.Service != nil {
+			add("service/" + b.Service.Name)
+		}
+	}
+	backend(ing.Spec.DefaultBackend)
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for i := range rule.HTTP.Paths {
+			backend(&rule.HTTP.Paths[i].Backend)
+		}
+	}
+	for _, tls := range ing.Spec.TLS {
+		if tls.SecretName != "" {
+			add("secret/" + tls.SecretName)
+		}
+	}
+	return refs
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
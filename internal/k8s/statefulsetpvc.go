@@ -0,0 +1,304 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// A StatefulSet names the claims of its replicas
+// <volumeClaimTemplate>-<statefulset>-<ordinal> and, unless its retention
+// policy says otherwise, keeps them when it is scaled down. These helpers
+// match claims to replicas so that leftovers can be found and a replica's
+// storage can be replaced.
+
+// ErrStatefulSetPVC is returned when a replica's claims cannot be deleted as asked
+var ErrStatefulSetPVC = errors.New("cannot delete StatefulSet PVCs")
+
+// StatefulSetClaim is one claim of a StatefulSet replica
+type StatefulSetClaim struct {
+	Template     string `json:"template"`
+	Name         string `json:"name"`
+	Exists       bool   `json:"exists"`
+	Status       string `json:"status,omitempty"`
+	Capacity     string `json:"capacity,omitempty"`
+	StorageClass string `json:"storage_class,omitempty"`
+	VolumeName   string `json:"volume_name,omitempty"`
+	Terminating  bool   `json:"terminating,omitempty"`
+}
+
+// StatefulSetReplicaClaims are the claims of one ordinal
+type StatefulSetReplicaClaims struct {
+	Ordinal  int                `json:"ordinal"`
+	Pod      string             `json:"pod"`
+	PodPhase string             `json:"pod_phase,omitempty"` // empty when the pod does not exist
+	Claims   []StatefulSetClaim `json:"claims"`
+}
+
+// StatefulSetPVCs lists the claims of every ordinal a StatefulSet has now
+// and of those left behind by scaling down
+type StatefulSetPVCs struct {
+	Namespace     string                     `json:"namespace"`
+	Name          string                     `json:"name"`
+	Replicas      int32                      `json:"replicas"`
+	Templates     []string                   `json:"templates"`
+	WhenScaled    string                     `json:"when_scaled"`  // Retain or Delete
+	WhenDeleted   string                     `json:"when_deleted"` // Retain or Delete
+	ReplicaClaims []StatefulSetReplicaClaims `json:"replica_claims"`
+	Orphans       []StatefulSetOrphan        `json:"orphans"`
+}
+
+// StatefulSetOrphan is a claim of an ordinal beyond the current replicas
+type StatefulSetOrphan struct {
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	StatefulSet string `json:"statefulset"`
+	Template    string `json:"template"`
+	Ordinal     int    `json:"ordinal"`
+	Status      string `json:"status"`
+	Capacity    string `json:"capacity,omitempty"`
+	InUse       bool   `json:"in_use"` // a pod still mounts it, e.g. while scaling down
+}
+
+// GetStatefulSetPVCs returns the claims of each replica of a StatefulSet and
+// the orphaned claims of ordinals it was scaled down from
+func GetStatefulSetPVCs(ctx context.Context, namespace, name string) (*StatefulSetPVCs, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	sts, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pvcs, err := clientset.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]*corev1.PersistentVolumeClaim, len(pvcs.Items))
+	for i := range pvcs.Items {
+		claims[pvcs.Items[i].Name] = &pvcs.Items[i]
+	}
+	podPhases := make(map[string]string, len(pods.Items))
+	for _, pod := range pods.Items {
+		podPhases[pod.Name] = string(pod.Status.Phase)
+	}
+
+	result := &StatefulSetPVCs{
+		Namespace:     namespace,
+		Name:          name,
+		Replicas:      stsReplicas(sts),
+		Templates:     []string{},
+		WhenScaled:    string(appsv1.RetainPersistentVolumeClaimRetentionPolicyType),
+		WhenDeleted:   string(appsv1.RetainPersistentVolumeClaimRetentionPolicyType),
+		ReplicaClaims: []StatefulSetReplicaClaims{},
+		Orphans:       stsOrphans(sts, pvcs.Items, pods.Items),
+	}
+	if policy := sts.Spec.PersistentVolumeClaimRetentionPolicy; policy != nil {
+		if policy.WhenScaled != "" {
+			result.WhenScaled = string(policy.WhenScaled)
+		}
+		if policy.WhenDeleted != "" {
+			result.WhenDeleted = string(policy.WhenDeleted)
+		}
+	}
+	for _, t := range sts.Spec.VolumeClaimTemplates {
+		result.Templates = append(result.Templates, t.Name)
+	}
+
+	start := stsOrdinalStart(sts)
+	for ordinal := start; ordinal < start+int(result.Replicas); ordinal++ {
+		replica := StatefulSetReplicaClaims{
+			Ordinal: ordinal,
+			Pod:     fmt.Sprintf("%s-%d", name, ordinal),
+			Claims:  []StatefulSetClaim{},
+		}
+		replica.PodPhase = podPhases[replica.Pod]
+		for _, t := range result.Templates {
+			claim := StatefulSetClaim{Template: t, Name: stsClaimName(t, name, ordinal)}
+			if pvc, ok := claims[claim.Name]; ok {
+				claim.Exists = true
+				claim.Status = string(pvc.Status.Phase)
+				if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+					claim.Capacity = capacity.String()
+				}
+				claim.StorageClass = pvcStorageClass(pvc)
+				claim.VolumeName = pvc.Spec.VolumeName
+				claim.Terminating = pvc.DeletionTimestamp != nil
+			}
+			replica.Claims = append(replica.Claims, claim)
+		}
+		result.ReplicaClaims = append(result.ReplicaClaims, replica)
+	}
+	return result, nil
+}
+
+// FindStatefulSetOrphanPVCs returns the claims left behind by scaling down
+// every StatefulSet in namespace ("" for all)
+func FindStatefulSetOrphanPVCs(ctx context.Context, namespace string) ([]StatefulSetOrphan, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	statefulsets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pvcs, err := clientset.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	orphans := []StatefulSetOrphan{}
+	for i := range statefulsets.Items {
+		orphans = append(orphans, stsOrphans(&statefulsets.Items[i], pvcs.Items, pods.Items)...)
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		a, b := orphans[i], orphans[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return orphans, nil
+}
+
+// DeleteStatefulSetReplicaPVCs deletes the claims of one ordinal. For an
+// ordinal the StatefulSet still runs, recreatePod must be set: the pod is
+// deleted too so the controller recreates it with new, empty volumes.
+// Returns the claims and pod deleted.
+func DeleteStatefulSetReplicaPVCs(ctx context.Context, namespace, name string, ordinal int, recreatePod bool) ([]string, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	sts, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(sts.Spec.VolumeClaimTemplates) == 0 {
+		return nil, fmt.Errorf("%w: %s has no volumeClaimTemplates", ErrStatefulSetPVC, name)
+	}
+	start := stsOrdinalStart(sts)
+	if ordinal < start {
+		return nil, fmt.Errorf("%w: ordinals start at %d", ErrStatefulSetPVC, start)
+	}
+	active := ordinal < start+int(stsReplicas(sts))
+	if active && !recreatePod {
+		return nil, fmt.Errorf("%w: replica %d is running; set recreate_pod to replace its storage", ErrStatefulSetPVC, ordinal)
+	}
+
+	var deleted []string
+	for _, t := range sts.Spec.VolumeClaimTemplates {
+		claim := stsClaimName(t.Name, name, ordinal)
+		// A claim still mounted stays Terminating until the pod below is gone
+		err := clientset.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, claim, metav1.DeleteOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete PVC %s: %w", claim, err)
+		}
+		deleted = append(deleted, "persistentvolumeclaim/"+claim)
+	}
+	if active {
+		pod := fmt.Sprintf("%s-%d", name, ordinal)
+		err := clientset.CoreV1().Pods(namespace).Delete(ctx, pod, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return deleted, fmt.Errorf("failed to delete pod %s: %w", pod, err)
+		}
+		if err == nil {
+			deleted = append(deleted, "pod/"+pod)
+		}
+	}
+	return deleted, nil
+}
+
+// stsOrphans returns the claims in pvcs of sts's templates whose ordinal is
+// outside the replicas it runs now
+func stsOrphans(sts *appsv1.StatefulSet, pvcs []corev1.PersistentVolumeClaim, pods []corev1.Pod) []StatefulSetOrphan {
+	inUse := map[string]bool{}
+	for _, pod := range pods {
+		if pod.Namespace != sts.Namespace {
+			continue
+		}
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil {
+				inUse[v.PersistentVolumeClaim.ClaimName] = true
+			}
+		}
+	}
+
+	start := stsOrdinalStart(sts)
+	end := start + int(stsReplicas(sts))
+	orphans := []StatefulSetOrphan{}
+	for i := range pvcs {
+		pvc := &pvcs[i]
+		if pvc.Namespace != sts.Namespace {
+			continue
+		}
+		for _, t := range sts.Spec.VolumeClaimTemplates {
+			prefix := t.Name + "-" + sts.Name + "-"
+			if !strings.HasPrefix(pvc.Name, prefix) {
+				continue
+			}
+			ordinal, err := strconv.Atoi(strings.TrimPrefix(pvc.Name, prefix))
+			if err != nil || ordinal < 0 || (ordinal >= start && ordinal < end) {
+				continue
+			}
+			orphan := StatefulSetOrphan{
+				Namespace:   pvc.Namespace,
+				Name:        pvc.Name,
+				StatefulSet: sts.Name,
+				Template:    t.Name,
+				Ordinal:     ordinal,
+				Status:      string(pvc.Status.Phase),
+				InUse:       inUse[pvc.Name],
+			}
+			if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+				orphan.Capacity = capacity.String()
+			}
+			orphans = append(orphans, orphan)
+		}
+	}
+	return orphans
+}
+
+func stsClaimName(template, statefulset string, ordinal int) string {
+	return fmt.Sprintf("%s-%s-%d", template, statefulset, ordinal)
+}
+
+func stsReplicas(sts *appsv1.StatefulSet) int32 {
+	if sts.Spec.Replicas == nil {
+		return 1
+	}
+	return *sts.Spec.Replicas
+}
+
+// stsOrdinalStart is spec.ordinals.start, 0 unless set
+func stsOrdinalStart(sts *appsv1.StatefulSet) int {
+	if sts.Spec.Ordinals == nil {
+		return 0
+	}
+	return int(sts.Spec.Ordinals.Start)
+}
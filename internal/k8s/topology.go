@@ -0,0 +1,222 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gaga951/gagos/internal/fanout"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ServiceTopology joins a Service with its EndpointSlices and selected pods
+// to explain why it does or does not have healthy backends
+type ServiceTopology struct {
+	Service        ServiceInfo        `json:"service"`
+	Endpoints      []TopologyEndpoint `json:"endpoints"`
+	Pods           []TopologyPod      `json:"pods"`
+	ReadyEndpoints int                `json:"ready_endpoints"`
+	Healthy        bool               `json:"healthy"`
+	Problems       []string           `json:"problems,omitempty"`
+}
+
+// TopologyEndpoint is one address from the Service's EndpointSlices
+type TopologyEndpoint struct {
+	Address     string `json:"address"`
+	Pod         string `json:"pod,omitempty"`
+	Node        string `json:"node,omitempty"`
+	Zone        string `json:"zone,omitempty"`
+	Ready       bool   `json:"ready"`
+	Serving     bool   `json:"serving"`
+	Terminating bool   `json:"terminating"`
+}
+
+// TopologyPod is a pod matched by the Service's selector
+type TopologyPod struct {
+	Name        string `json:"name"`
+	Phase       string `json:"phase"`
+	IP          string `json:"ip"`
+	Node        string `json:"node"`
+	Ready       bool   `json:"ready"`
+	Restarts    int32  `json:"restarts"`
+	InEndpoints bool   `json:"in_endpoints"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// GetServiceTopology returns the Service, its endpoints and the pods its
+// selector matches, plus a list of detected problems
+func GetServiceTopology(ctx context.Context, namespace, name string) (*ServiceTopology, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	svc, err := clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		slices *discoveryv1.EndpointSliceList
+		pods   *corev1.PodList
+	)
+	tasks := map[string]fanout.Func{
+		"endpointslices": func(ctx context.Context) error {
+			var err error
+			slices, err = clientset.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{
+				LabelSelector: discoveryv1.LabelServiceName + "=" + name,
+			})
+			return err
+		},
+	}
+	if len(svc.Spec.Selector) > 0 {
+		tasks["pods"] = func(ctx context.Context) error {
+			var err error
+			pods, err = clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+				LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+			})
+			return err
+		}
+	}
+	if errs := fanout.Run(ctx, fanout.Options{}, tasks); errs != nil {
+		return nil, errs
+	}
+
+	topo := &ServiceTopology{Service: serviceToInfo(svc)}
+
+	endpointIPs := make(map[string]bool)
+	for _, slice := range slices.Items {
+		for _, ep := range slice.Endpoints {
+			e := TopologyEndpoint{
+				Ready:       ep.Conditions.Ready == nil || *ep.Conditions.Ready,
+				Serving:     ep.Conditions.Serving == nil || *ep.Conditions.Serving,
+				Terminating: ep.Conditions.Terminating != nil && *ep.Conditions.Terminating,
+			}
+			if len(ep.Addresses) > 0 {
+				e.Address = ep.Addresses[0]
+			}
+			if ep.TargetRef != nil && ep.TargetRef.Kind == "Pod" {
+				e.Pod = ep.TargetRef.Name
+			}
+			if ep.NodeName != nil {
+				e.Node = *ep.NodeName
+			}
+			if ep.Zone != nil {
+				e.Zone = *ep.Zone
+			}
+			if e.Ready {
+				topo.ReadyEndpoints++
+			}
+			endpointIPs[e.Address] = true
+			topo.Endpoints = append(topo.Endpoints, e)
+		}
+	}
+	sort.Slice(topo.Endpoints, func(i, j int) bool { return topo.Endpoints[i].Address < topo.Endpoints[j].Address })
+
+	if pods != nil {
+		for i := range pods.Items {
+			topo.Pods = append(topo.Pods, topologyPod(&pods.Items[i], endpointIPs))
+		}
+	}
+
+	topo.Problems = diagnoseService(svc, topo, pods)
+	topo.Healthy = svc.Spec.Type == corev1.ServiceTypeExternalName || topo.ReadyEndpoints > 0
+	return topo, nil
+}
+
+func topologyPod(pod *corev1.Pod, endpointIPs map[string]bool) TopologyPod {
+	tp := TopologyPod{
+		Name:        pod.Name,
+		Phase:       string(pod.Status.Phase),
+		IP:          pod.Status.PodIP,
+		Node:        pod.Spec.NodeName,
+		InEndpoints: pod.Status.PodIP != "" && endpointIPs[pod.Status.PodIP],
+	}
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			tp.Ready = cond.Status == corev1.ConditionTrue
+			if !tp.Ready && cond.Message != "" {
+				tp.Reason = cond.Message
+			}
+		}
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		tp.Restarts += cs.RestartCount
+		// A waiting reason such as CrashLoopBackOff says more than the condition message
+		if !cs.Ready && cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+			tp.Reason = cs.Name + ": " + cs.State.Waiting.Reason
+		}
+	}
+	if pod.DeletionTimestamp != nil {
+		tp.Reason = "terminating"
+	}
+	return tp
+}
+
+// diagnoseService lists the usual reasons a Service has no healthy backends
+func diagnoseService(svc *corev1.Service, topo *ServiceTopology, pods *corev1.PodList) []string {
+	var problems []string
+
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		return nil
+	}
+	if len(svc.Spec.Selector) == 0 {
+		if len(topo.Endpoints) == 0 {
+			problems = append(problems, "service has no selector and no manually managed endpoints")
+		}
+		return problems
+	}
+
+	if pods == nil || len(pods.Items) == 0 {
+		problems = append(problems, fmt.Sprintf("selector %s matches no pods in namespace %s",
+			labels.SelectorFromSet(svc.Spec.Selector).String(), svc.Namespace))
+		return problems
+	}
+
+	readyPods := 0
+	for _, p := range topo.Pods {
+		if p.Ready {
+			readyPods++
+		}
+	}
+	if readyPods == 0 {
+		problems = append(problems, fmt.Sprintf("none of the %d selected pods are ready", len(topo.Pods)))
+	}
+	if readyPods > 0 && topo.ReadyEndpoints == 0 {
+		problems = append(problems, "pods are ready but no ready endpoints exist; check the endpoint slice controller")
+	}
+
+	// Named target ports must exist on the selected pods' containers
+	for _, sp := range svc.Spec.Ports {
+		if sp.TargetPort.Type != intstr.String {
+			continue
+		}
+		for i := range pods.Items {
+			if !podHasNamedPort(&pods.Items[i], sp.TargetPort.StrVal) {
+				problems = append(problems, fmt.Sprintf("port %d targets named port %q, which pod %s does not expose",
+					sp.Port, sp.TargetPort.StrVal, pods.Items[i].Name))
+				break
+			}
+		}
+	}
+
+	return problems
+}
+
+func podHasNamedPort(pod *corev1.Pod, name string) bool {
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,396 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// Validation modes, choosing which dry-run request is made per object
+const (
+	ValidateAuto   = "auto"   // update when the object exists, create otherwise
+	ValidateCreate = "create" // the object must not exist yet
+	ValidateUpdate = "update" // the object must exist
+)
+
+// Validation error types
+const (
+	ValidationSyntax      = "syntax"       // not parseable as YAML
+	ValidationSchema      = "schema"       // unknown, duplicate or mistyped fields
+	ValidationInvalid     = "invalid"      // rejected by the API server's validation
+	ValidationUnknownKind = "unknown_kind" // apiVersion/kind not served by the cluster
+	ValidationConflict    = "conflict"     // already exists on create
+	ValidationNotFound    = "not_found"    // missing on update, or missing namespace
+	ValidationForbidden   = "forbidden"
+	ValidationFailed      = "failed" // any other error
+)
+
+// ValidateOptions controls a manifest validation request
+type ValidateOptions struct {
+	Namespace string // default namespace for namespaced objects without one
+	Mode      string // ValidateAuto (default), ValidateCreate or ValidateUpdate
+}
+
+// ValidationError is one problem found in a manifest. Line is 1-based in the
+// submitted content and 0 when it could not be located.
+type ValidationError struct {
+	Document int    `json:"document"`
+	Line     int    `json:"line,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Type     string `json:"type"`
+	Message  string `json:"message"`
+}
+
+// ValidationResult is the outcome for a single object in a manifest
+type ValidationResult struct {
+	Document   int               `json:"document"`
+	Line       int               `json:"line"`
+	APIVersion string            `json:"api_version"`
+	Kind       string            `json:"kind"`
+	Name       string            `json:"name"`
+	Namespace  string            `json:"namespace,omitempty"`
+	Operation  string            `json:"operation,omitempty"` // dry-run request made: create or update
+	Valid      bool              `json:"valid"`
+	Errors     []ValidationError `json:"errors,omitempty"`
+}
+
+// ValidationReport holds every object's result plus all errors, including
+// those that could not be tied to an object, ordered by line
+type ValidationReport struct {
+	Valid   bool               `json:"valid"`
+	Results []ValidationResult `json:"results"`
+	Errors  []ValidationError  `json:"errors"`
+}
+
+var (
+	yamlLineRe    = regexp.MustCompile(`line (\d+)`)
+	strictFieldRe = regexp.MustCompile(`(unknown|duplicate) field "([^"]+)"`)
+	typeFieldRe   = regexp.MustCompile(`Go struct field ([\w.\[\]]+) of type`)
+)
+
+// ValidateManifests checks a multi-document manifest without persisting it:
+// YAML syntax locally, then each object through a server-side dry-run create
+// or update with strict field validation. Problems are returned in the report;
+// the error is only set when the cluster cannot be reached at all.
+func ValidateManifests(ctx context.Context, content string, opts ValidateOptions) (*ValidationReport, error) {
+	dc, mapper, err := getDynamic()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ValidationReport{Results: []ValidationResult{}, Errors: []ValidationError{}}
+
+	// Documents before a syntax error are still validated
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(content)))
+	for doc := 1; ; doc++ {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if !errors.Is(err, io.EOF) {
+				report.Errors = append(report.Errors, ValidationError{
+					Document: doc,
+					Line:     yamlErrorLine(err),
+					Type:     ValidationSyntax,
+					Message:  err.Error(),
+				})
+			}
+			break
+		}
+		if len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+			continue
+		}
+
+		root := node.Content[0]
+		objects, docErr := decodeNode(root, doc)
+		if docErr != nil {
+			report.Errors = append(report.Errors, *docErr)
+			continue
+		}
+		for _, o := range objects {
+			report.Results = append(report.Results, validateObject(ctx, dc, mapper, o, opts))
+		}
+	}
+
+	for _, r := range report.Results {
+		report.Errors = append(report.Errors, r.Errors...)
+	}
+	sort.SliceStable(report.Errors, func(i, j int) bool {
+		a, b := report.Errors[i], report.Errors[j]
+		if a.Document != b.Document {
+			return a.Document < b.Document
+		}
+		return a.Line < b.Line
+	})
+	report.Valid = len(report.Errors) == 0
+	return report, nil
+}
+
+// manifestObject is one object of a manifest with the YAML it came from, so
+// server errors can be mapped back to lines
+type manifestObject struct {
+	obj      *unstructured.Unstructured
+	root     *yaml.Node
+	prefix   string // field path of the object within root, set for List items
+	document int
+}
+
+func decodeNode(root *yaml.Node, doc int) ([]manifestObject, *ValidationError) {
+	fail := func(field, msg string) *ValidationError {
+		return &ValidationError{Document: doc, Line: fieldLine(root, field), Field: field, Type: ValidationSchema, Message: msg}
+	}
+
+	if root.Kind != yaml.MappingNode {
+		return nil, fail("", "document is not an object")
+	}
+	var raw map[string]interface{}
+	if err := root.Decode(&raw); err != nil {
+		e := fail("", err.Error())
+		if line := yamlErrorLine(err); line > 0 {
+			e.Line = line
+		}
+		return nil, e
+	}
+	for _, field := range []string{"apiVersion", "kind"} {
+		if s, _ := raw[field].(string); s == "" {
+			return nil, fail(field, field+" is required")
+		}
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fail("", err.Error())
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(data); err != nil {
+		return nil, fail("", err.Error())
+	}
+
+	if !obj.IsList() {
+		return []manifestObject{{obj: obj, root: root, document: doc}}, nil
+	}
+	list, err := obj.ToList()
+	if err != nil {
+		return nil, fail("items", err.Error())
+	}
+	objects := make([]manifestObject, 0, len(list.Items))
+	for i := range list.Items {
+		objects = append(objects, manifestObject{obj: &list.Items[i], root: root, prefix: fmt.Sprintf("items[%d]", i), document: doc})
+	}
+	return objects, nil
+}
+
+func validateObject(ctx context.Context, dc dynamic.Interface, mapper *restmapper.DeferredDiscoveryRESTMapper, m manifestObject, opts ValidateOptions) ValidationResult {
+	obj := m.obj
+	result := ValidationResult{
+		Document:   m.document,
+		Line:       fieldLine(m.root, m.prefix),
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Name:       obj.GetName(),
+		Namespace:  obj.GetNamespace(),
+	}
+	fail := func(typ, field, msg string) ValidationResult {
+		result.Errors = append(result.Errors, m.error(typ, field, msg))
+		return result
+	}
+
+	if obj.GetName() == "" && obj.GetGenerateName() == "" {
+		return fail(ValidationInvalid, "metadata.name", "metadata.name is required")
+	}
+
+	resource, err := resourceFor(dc, mapper, obj, opts.Namespace)
+	if err != nil {
+		return fail(ValidationUnknownKind, "kind", err.Error())
+	}
+	result.Namespace = obj.GetNamespace()
+
+	var existing *unstructured.Unstructured
+	if obj.GetName() != "" {
+		existing, err = resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			existing = nil
+		} else if err != nil {
+			result.Errors = append(result.Errors, m.apiErrors(err)...)
+			return result
+		}
+	}
+
+	op := opts.Mode
+	if op == "" || op == ValidateAuto {
+		op = ValidateCreate
+		if existing != nil {
+			op = ValidateUpdate
+		}
+	}
+	result.Operation = op
+
+	// Fields the server manages itself would only produce noise
+	obj.SetManagedFields(nil)
+	switch op {
+	case ValidateCreate:
+		obj.SetResourceVersion("")
+		_, err = resource.Create(ctx, obj, metav1.CreateOptions{
+			DryRun:          []string{metav1.DryRunAll},
+			FieldManager:    FieldManager,
+			FieldValidation: metav1.FieldValidationStrict,
+		})
+	case ValidateUpdate:
+		if existing == nil {
+			return fail(ValidationNotFound, "metadata.name", fmt.Sprintf("%s %q does not exist", obj.GetKind(), obj.GetName()))
+		}
+		// Validate the content, not how stale the editor's copy is
+		obj.SetResourceVersion(existing.GetResourceVersion())
+		_, err = resource.Update(ctx, obj, metav1.UpdateOptions{
+			DryRun:          []string{metav1.DryRunAll},
+			FieldManager:    FieldManager,
+			FieldValidation: metav1.FieldValidationStrict,
+		})
+	}
+	if err != nil {
+		result.Errors = append(result.Errors, m.apiErrors(err)...)
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+func (m manifestObject) error(typ, field, msg string) ValidationError {
+	path := field
+	if m.prefix != "" {
+		path = strings.TrimSuffix(m.prefix+"."+field, ".")
+	}
+	return ValidationError{Document: m.document, Line: fieldLine(m.root, path), Field: field, Type: typ, Message: msg}
+}
+
+// apiErrors turns an API server rejection into one error per offending field
+func (m manifestObject) apiErrors(err error) []ValidationError {
+	var statusErr apierrors.APIStatus
+	if !errors.As(err, &statusErr) {
+		return []ValidationError{m.error(ValidationFailed, "", err.Error())}
+	}
+	status := statusErr.Status()
+
+	typ := ValidationFailed
+	switch status.Reason {
+	case metav1.StatusReasonInvalid:
+		typ = ValidationInvalid
+	case metav1.StatusReasonBadRequest:
+		typ = ValidationSchema
+	case metav1.StatusReasonAlreadyExists, metav1.StatusReasonConflict:
+		typ = ValidationConflict
+	case metav1.StatusReasonNotFound:
+		typ = ValidationNotFound
+	case metav1.StatusReasonForbidden, metav1.StatusReasonUnauthorized:
+		typ = ValidationForbidden
+	}
+
+	var errs []ValidationError
+	// Strict field validation lists every unknown or duplicate field in the message
+	for _, match := range strictFieldRe.FindAllStringSubmatch(status.Message, -1) {
+		errs = append(errs, m.error(ValidationSchema, match[2], match[0]))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if status.Details != nil {
+		for _, cause := range status.Details.Causes {
+			errs = append(errs, m.error(typ, cause.Field, cause.Message))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// Type mismatches name the field as Kind.path.to.field
+	field := ""
+	if match := typeFieldRe.FindStringSubmatch(status.Message); match != nil {
+		if i := strings.IndexByte(match[1], '.'); i >= 0 {
+			field = match[1][i+1:]
+		}
+	}
+	return []ValidationError{m.error(typ, field, status.Message)}
+}
+
+func yamlErrorLine(err error) int {
+	if match := yamlLineRe.FindStringSubmatch(err.Error()); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return line
+	}
+	return 0
+}
+
+// fieldLine returns the line of the deepest node along a field path such as
+// spec.template.spec.containers[0].image or metadata.labels[app.kubernetes.io/name]
+func fieldLine(root *yaml.Node, path string) int {
+	node, line := root, root.Line
+	for _, seg := range splitFieldPath(path) {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == seg {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if idx, err := strconv.Atoi(seg); err == nil && idx >= 0 && idx < len(node.Content) {
+				next = node.Content[idx]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line
+}
+
+func splitFieldPath(path string) []string {
+	var segments []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			segments = append(segments, current.String())
+			current.Reset()
+		}
+	}
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '.':
+			flush()
+		case '[':
+			flush()
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				current.WriteString(path[i+1:])
+				i = len(path)
+				continue
+			}
+			segments = append(segments, path[i+1:i+end])
+			i += end
+		default:
+			current.WriteByte(path[i])
+		}
+	}
+	flush()
+	return segments
+}
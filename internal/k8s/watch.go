@@ -0,0 +1,428 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Watch event types sent to subscribers
+const (
+	WatchEventAdded    = "ADDED"
+	WatchEventModified = "MODIFIED"
+	WatchEventDeleted  = "DELETED"
+)
+
+// WatchEvent is a single add/update/delete notification for a watched resource.
+// Object carries the same Info struct returned by the matching list endpoint.
+type WatchEvent struct {
+	Type      string      `json:"type"`
+	Kind      string      `json:"kind"`
+	Namespace string      `json:"namespace,omitempty"`
+	Name      string      `json:"name"`
+	Object    interface{} `json:"object"`
+	Timestamp string      `json:"timestamp"`
+}
+
+// watchKind ties a resource kind to its shared informer and Info converter
+type watchKind struct {
+	informer func(f informers.SharedInformerFactory) cache.SharedIndexInformer
+	convert  func(obj interface{}) (interface{}, bool)
+}
+
+var watchKinds = map[string]watchKind{
+	"pods": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().Pods().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*corev1.Pod)
+			if !ok {
+				return nil, false
+			}
+			return podToInfo(o), true
+		},
+	},
+	"services": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().Services().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*corev1.Service)
+			if !ok {
+				return nil, false
+			}
+			return serviceToInfo(o), true
+		},
+	},
+	"deployments": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Apps().V1().Deployments().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*appsv1.Deployment)
+			if !ok {
+				return nil, false
+			}
+			return deploymentToInfo(o), true
+		},
+	},
+	"statefulsets": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Apps().V1().StatefulSets().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*appsv1.StatefulSet)
+			if !ok {
+				return nil, false
+			}
+			return statefulSetToInfo(o), true
+		},
+	},
+	"daemonsets": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Apps().V1().DaemonSets().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*appsv1.DaemonSet)
+			if !ok {
+				return nil, false
+			}
+			return daemonSetToInfo(o), true
+		},
+	},
+	"replicasets": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Apps().V1().ReplicaSets().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*appsv1.ReplicaSet)
+			if !ok {
+				return nil, false
+			}
+			return replicaSetToInfo(o), true
+		},
+	},
+	"jobs": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Batch().V1().Jobs().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*batchv1.Job)
+			if !ok {
+				return nil, false
+			}
+			return jobToInfo(o), true
+		},
+	},
+	"cronjobs": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Batch().V1().CronJobs().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*batchv1.CronJob)
+			if !ok {
+				return nil, false
+			}
+			return cronJobToInfo(o), true
+		},
+	},
+	"configmaps": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().ConfigMaps().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*corev1.ConfigMap)
+			if !ok {
+				return nil, false
+			}
+			return configMapToInfo(o), true
+		},
+	},
+	"secrets": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().Secrets().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*corev1.Secret)
+			if !ok {
+				return nil, false
+			}
+			return secretToInfo(o), true
+		},
+	},
+	"ingresses": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Networking().V1().Ingresses().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*networkingv1.Ingress)
+			if !ok {
+				return nil, false
+			}
+			return ingressToInfo(o), true
+		},
+	},
+	"pvcs": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().PersistentVolumeClaims().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*corev1.PersistentVolumeClaim)
+			if !ok {
+				return nil, false
+			}
+			return pvcToInfo(o), true
+		},
+	},
+	"events": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().Events().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*corev1.Event)
+			if !ok {
+				return nil, false
+			}
+			return eventToInfo(o), true
+		},
+	},
+	"nodes": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().Nodes().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*corev1.Node)
+			if !ok {
+				return nil, false
+			}
+			return nodeToInfo(o), true
+		},
+	},
+	"namespaces": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().Namespaces().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*corev1.Namespace)
+			if !ok {
+				return nil, false
+			}
+			return namespaceToInfo(o), true
+		},
+	},
+	"pvs": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().PersistentVolumes().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*corev1.PersistentVolume)
+			if !ok {
+				return nil, false
+			}
+			return pvToInfo(o), true
+		},
+	},
+	"networkpolicies": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Networking().V1().NetworkPolicies().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*networkingv1.NetworkPolicy)
+			if !ok {
+				return nil, false
+			}
+			return networkPolicyToInfo(o), true
+		},
+	},
+	"pdbs": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Policy().V1().PodDisruptionBudgets().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*policyv1.PodDisruptionBudget)
+			if !ok {
+				return nil, false
+			}
+			return pdbToInfo(o), true
+		},
+	},
+	"storageclasses": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Storage().V1().StorageClasses().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*storagev1.StorageClass)
+			if !ok {
+				return nil, false
+			}
+			return storageClassToInfo(o), true
+		},
+	},
+	"leases": {
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Coordination().V1().Leases().Informer()
+		},
+		convert: func(obj interface{}) (interface{}, bool) {
+			o, ok := obj.(*coordinationv1.Lease)
+			if !ok {
+				return nil, false
+			}
+			return leaseToInfo(o), true
+		},
+	},
+}
+
+var (
+	informerFactory informers.SharedInformerFactory
+	informerMu      sync.Mutex
+	informerStop    = make(chan struct{})
+)
+
+// WatchableKinds returns the resource kinds accepted by WatchResources
+func WatchableKinds() []string {
+	kinds := make([]string, 0, len(watchKinds))
+	for k := range watchKinds {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// sharedInformerFactory lazily creates the process-wide informer factory.
+// Informers are only started for kinds somebody actually watches or lists
+// through the cache.
+func sharedInformerFactory() informers.SharedInformerFactory {
+	informerMu.Lock()
+	defer informerMu.Unlock()
+	if informerFactory == nil {
+		informerFactory = informers.NewSharedInformerFactory(clientset, cacheResync)
+	}
+	return informerFactory
+}
+
+// SharedInformerFactory returns the process-wide informer factory so other
+// packages can register handlers on the same caches. Nil if k8s is unavailable.
+func SharedInformerFactory() informers.SharedInformerFactory {
+	if clientset == nil {
+		return nil
+	}
+	return sharedInformerFactory()
+}
+
+// StartInformers starts any informers requested from the factory since the last call
+func StartInformers() {
+	if clientset == nil {
+		return
+	}
+	sharedInformerFactory().Start(informerStop)
+}
+
+// WatchResources streams add/update/delete events for the given kinds into events
+// until ctx is cancelled. An empty namespaces list means all namespaces. Objects
+// already in the informer cache are replayed as ADDED events first.
+func WatchResources(ctx context.Context, kinds []string, namespaces []string, events chan<- WatchEvent) error {
+	if clientset == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+	if len(kinds) == 0 {
+		return fmt.Errorf("at least one kind is required")
+	}
+	for _, kind := range kinds {
+		if _, ok := watchKinds[kind]; !ok {
+			return fmt.Errorf("unsupported kind: %s", kind)
+		}
+	}
+
+	nsFilter := make(map[string]bool)
+	for _, ns := range namespaces {
+		if ns != "" {
+			nsFilter[ns] = true
+		}
+	}
+	return watchInformers(ctx, kinds, func(namespace string, _ interface{}) bool {
+		return len(nsFilter) == 0 || namespace == "" || nsFilter[namespace]
+	}, events)
+}
+
+// watchInformers streams the events of the given kinds' informers that
+// accept lets through until ctx is cancelled
+func watchInformers(ctx context.Context, kinds []string, accept func(namespace string, obj interface{}) bool, events chan<- WatchEvent) error {
+	factory := sharedInformerFactory()
+
+	type registration struct {
+		informer cache.SharedIndexInformer
+		handle   cache.ResourceEventHandlerRegistration
+	}
+	var registrations []registration
+	defer func() {
+		for _, r := range registrations {
+			r.informer.RemoveEventHandler(r.handle)
+		}
+	}()
+
+	for _, kind := range kinds {
+		kind := kind
+		wk := watchKinds[kind]
+		informer := wk.informer(factory)
+
+		emit := func(eventType string, obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			info, ok := wk.convert(obj)
+			if !ok {
+				return
+			}
+			meta, err := cache.MetaNamespaceKeyFunc(obj)
+			if err != nil {
+				return
+			}
+			namespace, name, _ := cache.SplitMetaNamespaceKey(meta)
+			if !accept(namespace, obj) {
+				return
+			}
+			select {
+			case events <- WatchEvent{
+				Type:      eventType,
+				Kind:      kind,
+				Namespace: namespace,
+				Name:      name,
+				Object:    info,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			}:
+			case <-ctx.Done():
+			}
+		}
+
+		handle, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { emit(WatchEventAdded, obj) },
+			UpdateFunc: func(_, obj interface{}) { emit(WatchEventModified, obj) },
+			DeleteFunc: func(obj interface{}) { emit(WatchEventDeleted, obj) },
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register %s watch: %w", kind, err)
+		}
+		registrations = append(registrations, registration{informer: informer, handle: handle})
+	}
+
+	factory.Start(informerStop)
+
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,382 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gaga951/gagos/internal/fanout"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// The node allocation report is `kubectl describe node` for every node at
+// once: allocatable resources against the requests and limits of the pods
+// scheduled there, and against actual usage. CPU and memory usage come
+// from metrics-server, ephemeral storage usage from the kubelet stats
+// summary of the node's root file system.
+
+// Allocation thresholds, in percent of allocatable
+const (
+	allocationFullPercent   = 90 // little room left for new pods
+	allocationUsageHigh     = 90
+	allocationOvercommitted = 100 // limits above allocatable
+)
+
+// pressureConditions are the node conditions that report trouble when True
+var pressureConditions = []corev1.NodeConditionType{
+	corev1.NodeMemoryPressure,
+	corev1.NodeDiskPressure,
+	corev1.NodePIDPressure,
+	corev1.NodeNetworkUnavailable,
+}
+
+// ResourceAllocation is one resource of a node. CPU is in millicores,
+// memory and ephemeral storage in bytes, pods in pods. Usage is omitted
+// when it is not known.
+type ResourceAllocation struct {
+	Capacity         int64    `json:"capacity"`
+	Allocatable      int64    `json:"allocatable"`
+	Requested        int64    `json:"requested"`
+	Limits           int64    `json:"limits"`
+	Usage            *int64   `json:"usage,omitempty"`
+	RequestedPercent *float64 `json:"requested_percent,omitempty"`
+	LimitsPercent    *float64 `json:"limits_percent,omitempty"`
+	UsagePercent     *float64 `json:"usage_percent,omitempty"`
+}
+
+// AllocationResources are the resources the report covers
+type AllocationResources struct {
+	CPU              ResourceAllocation `json:"cpu"`
+	Memory           ResourceAllocation `json:"memory"`
+	Pods             ResourceAllocation `json:"pods"`
+	EphemeralStorage ResourceAllocation `json:"ephemeral_storage"`
+}
+
+// NodeConditionInfo is a node condition as kubectl describe shows it
+type NodeConditionInfo struct {
+	Type               string `json:"type"`
+	Status             string `json:"status"`
+	Reason             string `json:"reason,omitempty"`
+	Message            string `json:"message,omitempty"`
+	LastTransitionTime string `json:"last_transition_time,omitempty"`
+}
+
+// NodeAllocation is a node's allocatable resources against what its pods
+// request and use
+type NodeAllocation struct {
+	Name          string              `json:"name"`
+	Roles         []string            `json:"roles,omitempty"`
+	Ready         bool                `json:"ready"`
+	Unschedulable bool                `json:"unschedulable"`
+	Pressure      []string            `json:"pressure"` // pressure conditions that are True
+	Conditions    []NodeConditionInfo `json:"conditions"`
+	Resources     AllocationResources `json:"resources"`
+	Flags         []string            `json:"flags,omitempty"`
+}
+
+// NodeAllocationReport covers every node and the cluster total
+type NodeAllocationReport struct {
+	MetricsAvailable bool                `json:"metrics_available"` // CPU and memory usage
+	StatsAvailable   bool                `json:"stats_available"`   // ephemeral storage usage, for at least one node
+	Nodes            []NodeAllocation    `json:"nodes"`
+	Total            AllocationResources `json:"total"`
+	Timestamp        time.Time           `json:"timestamp"`
+}
+
+// GetNodeAllocation returns the allocation of every node, sorted by name
+func GetNodeAllocation(ctx context.Context) (*NodeAllocationReport, error) {
+	if k8sClient == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	// Nodes and pods are required; metrics are optional
+	var (
+		nodes       *corev1.NodeList
+		pods        *corev1.PodList
+		nodeMetrics *metricsv1beta1.NodeMetricsList
+	)
+	tasks := map[string]fanout.Func{
+		"nodes": func(ctx context.Context) (err error) {
+			nodes, err = k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+			return err
+		},
+		"pods": func(ctx context.Context) (err error) {
+			pods, err = k8sClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+			return err
+		},
+	}
+	if metricsClient != nil {
+		tasks["metrics"] = func(ctx context.Context) (err error) {
+			nodeMetrics, err = metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+			return err
+		}
+	}
+	errs := fanout.Run(ctx, fanout.Options{}, tasks)
+	if err := errs["nodes"]; err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	if err := errs["pods"]; err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	// Root file system usage of each node, where the kubelet answers
+	var mu sync.Mutex
+	fsUsed := make(map[string]int64)
+	statTasks := make(map[string]fanout.Func, len(nodes.Items))
+	for _, node := range nodes.Items {
+		nodeName := node.Name
+		statTasks[nodeName] = func(ctx context.Context) error {
+			summary, err := nodeStatsSummary(ctx, nodeName)
+			if err != nil {
+				return err
+			}
+			if summary.Node.Fs != nil && summary.Node.Fs.UsedBytes != nil {
+				mu.Lock()
+				fsUsed[nodeName] = int64(*summary.Node.Fs.UsedBytes)
+				mu.Unlock()
+			}
+			return nil
+		}
+	}
+	if statErrs := fanout.Run(ctx, fanout.Options{Limit: 8}, statTasks); len(statErrs) > 0 {
+		log.Debug().Err(statErrs.Err()).Msg("Kubelet stats unavailable for some nodes - ephemeral storage usage missing")
+	}
+
+	// Requests and limits of the pods holding resources on each node
+	type podTotals struct {
+		requests, limits corev1.ResourceList
+		count            int64
+	}
+	byNode := make(map[string]*podTotals)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		t := byNode[pod.Spec.NodeName]
+		if t == nil {
+			t = &podTotals{requests: corev1.ResourceList{}, limits: corev1.ResourceList{}}
+			byNode[pod.Spec.NodeName] = t
+		}
+		t.count++
+		addResources(t.requests, podResources(pod, func(r corev1.ResourceRequirements) corev1.ResourceList { return r.Requests }))
+		addResources(t.limits, podResources(pod, func(r corev1.ResourceRequirements) corev1.ResourceList { return r.Limits }))
+	}
+
+	usage := make(map[string]corev1.ResourceList)
+	if nodeMetrics != nil {
+		for _, nm := range nodeMetrics.Items {
+			usage[nm.Name] = nm.Usage
+		}
+	}
+
+	report := &NodeAllocationReport{
+		MetricsAvailable: nodeMetrics != nil,
+		StatsAvailable:   len(fsUsed) > 0,
+		Nodes:            make([]NodeAllocation, 0, len(nodes.Items)),
+		Timestamp:        time.Now(),
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		totals := byNode[node.Name]
+		if totals == nil {
+			totals = &podTotals{requests: corev1.ResourceList{}, limits: corev1.ResourceList{}}
+		}
+
+		na := NodeAllocation{
+			Name:          node.Name,
+			Roles:         nodeRoles(node),
+			Unschedulable: node.Spec.Unschedulable,
+			Pressure:      []string{},
+			Conditions:    []NodeConditionInfo{},
+		}
+		for _, cond := range node.Status.Conditions {
+			na.Conditions = append(na.Conditions, NodeConditionInfo{
+				Type:               string(cond.Type),
+				Status:             string(cond.Status),
+				Reason:             cond.Reason,
+				Message:            cond.Message,
+				LastTransitionTime: formatConditionTime(cond.LastTransitionTime),
+			})
+			if cond.Type == corev1.NodeReady {
+				na.Ready = cond.Status == corev1.ConditionTrue
+			}
+			for _, p := range pressureConditions {
+				if cond.Type == p && cond.Status == corev1.ConditionTrue {
+					na.Pressure = append(na.Pressure, string(cond.Type))
+				}
+			}
+		}
+
+		cpu := resourceAllocation(node, totals.requests, totals.limits, corev1.ResourceCPU)
+		memory := resourceAllocation(node, totals.requests, totals.limits, corev1.ResourceMemory)
+		storage := resourceAllocation(node, totals.requests, totals.limits, corev1.ResourceEphemeralStorage)
+		podCount := ResourceAllocation{
+			Capacity:    node.Status.Capacity.Pods().Value(),
+			Allocatable: node.Status.Allocatable.Pods().Value(),
+			Requested:   totals.count,
+		}
+		podCount.Usage = &totals.count
+		if u, ok := usage[node.Name]; ok {
+			cpuUsage, memUsage := u.Cpu().MilliValue(), u.Memory().Value()
+			cpu.Usage, memory.Usage = &cpuUsage, &memUsage
+		}
+		if used, ok := fsUsed[node.Name]; ok {
+			storage.Usage = &used
+		}
+		na.Resources = AllocationResources{CPU: cpu, Memory: memory, Pods: podCount, EphemeralStorage: storage}
+		na.Resources.setPercents()
+		na.Flags = allocationFlags(&na.Resources)
+
+		report.Total.add(&na.Resources, i == 0)
+		report.Nodes = append(report.Nodes, na)
+	}
+	report.Total.setPercents()
+	sort.Slice(report.Nodes, func(i, j int) bool { return report.Nodes[i].Name < report.Nodes[j].Name })
+	return report, nil
+}
+
+// podResources is what a pod reserves on its node, counted the way the
+// scheduler does: the containers added up, or the largest init container
+// when that is more, plus the pod overhead. Init containers that keep
+// running as sidecars count with the containers.
+func podResources(pod *corev1.Pod, of func(corev1.ResourceRequirements) corev1.ResourceList) corev1.ResourceList {
+	total := corev1.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		addResources(total, of(c.Resources))
+	}
+
+	sidecars := corev1.ResourceList{}
+	initPeak := corev1.ResourceList{}
+	for _, c := range pod.Spec.InitContainers {
+		if c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			addResources(total, of(c.Resources))
+			addResources(sidecars, of(c.Resources))
+			continue
+		}
+		// An init container runs next to the sidecars started before it
+		running := sidecars.DeepCopy()
+		addResources(running, of(c.Resources))
+		maxResources(initPeak, running)
+	}
+	maxResources(total, initPeak)
+
+	addResources(total, pod.Spec.Overhead)
+	return total
+}
+
+func addResources(total, add corev1.ResourceList) {
+	for name, q := range add {
+		cur := total[name]
+		cur.Add(q)
+		total[name] = cur
+	}
+}
+
+// maxResources raises each resource of total to the one in other when higher
+func maxResources(total, other corev1.ResourceList) {
+	for name, q := range other {
+		if cur, ok := total[name]; !ok || q.Cmp(cur) > 0 {
+			total[name] = q.DeepCopy()
+		}
+	}
+}
+
+func resourceAllocation(node *corev1.Node, requests, limits corev1.ResourceList, name corev1.ResourceName) ResourceAllocation {
+	value := func(list corev1.ResourceList) int64 {
+		q, ok := list[name]
+		if !ok {
+			return 0
+		}
+		if name == corev1.ResourceCPU {
+			return q.MilliValue()
+		}
+		return q.Value()
+	}
+	return ResourceAllocation{
+		Capacity:    value(node.Status.Capacity),
+		Allocatable: value(node.Status.Allocatable),
+		Requested:   value(requests),
+		Limits:      value(limits),
+	}
+}
+
+func (r *ResourceAllocation) setPercent() {
+	r.RequestedPercent = percentOf(r.Requested, r.Allocatable)
+	if r.Limits > 0 {
+		r.LimitsPercent = percentOf(r.Limits, r.Allocatable)
+	}
+	if r.Usage != nil {
+		r.UsagePercent = percentOf(*r.Usage, r.Allocatable)
+	}
+}
+
+func (a *AllocationResources) setPercents() {
+	a.CPU.setPercent()
+	a.Memory.setPercent()
+	a.Pods.setPercent()
+	a.EphemeralStorage.setPercent()
+}
+
+// add sums a node into the cluster total. The total usage is only set when
+// every node has one.
+func (a *AllocationResources) add(node *AllocationResources, first bool) {
+	sum := func(total *ResourceAllocation, r *ResourceAllocation) {
+		total.Capacity += r.Capacity
+		total.Allocatable += r.Allocatable
+		total.Requested += r.Requested
+		total.Limits += r.Limits
+		switch {
+		case r.Usage == nil:
+			total.Usage = nil
+		case first:
+			u := *r.Usage
+			total.Usage = &u
+		case total.Usage != nil:
+			*total.Usage += *r.Usage
+		}
+	}
+	sum(&a.CPU, &node.CPU)
+	sum(&a.Memory, &node.Memory)
+	sum(&a.Pods, &node.Pods)
+	sum(&a.EphemeralStorage, &node.EphemeralStorage)
+}
+
+// allocationFlags names the resources running out on a node, such as
+// cpu_requests_full, memory_overcommitted or ephemeral_storage_usage_high
+func allocationFlags(a *AllocationResources) []string {
+	var flags []string
+	check := func(resource string, r *ResourceAllocation) {
+		if r.RequestedPercent != nil && *r.RequestedPercent >= allocationFullPercent {
+			flags = append(flags, resource+"_requests_full")
+		}
+		if r.LimitsPercent != nil && *r.LimitsPercent > allocationOvercommitted {
+			flags = append(flags, resource+"_overcommitted")
+		}
+		if r.UsagePercent != nil && *r.UsagePercent >= allocationUsageHigh {
+			flags = append(flags, resource+"_usage_high")
+		}
+	}
+	check("cpu", &a.CPU)
+	check("memory", &a.Memory)
+	check("ephemeral_storage", &a.EphemeralStorage)
+	if a.Pods.RequestedPercent != nil && *a.Pods.RequestedPercent >= allocationFullPercent {
+		flags = append(flags, "pods_full")
+	}
+	return flags
+}
+
+func formatConditionTime(t metav1.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -12,45 +12,51 @@ import (
 
 var (
 	metricsClient *metricsv.Clientset
-	k8sClient     *kubernetes.Clientset
+	k8sClient     kubernetes.Interface
 	costConfig    CostConfig
 	configMu      sync.RWMutex
-	initOnce      sync.Once
-	initErr       error
+	initMu        sync.Mutex
+	initialized   bool
 )
 
-// Init initializes the monitoring package
+// Init initializes the monitoring package. It takes the current Kubernetes
+// client, so calling it again after the client was re-created picks up the
+// new one.
 func Init() error {
-	initOnce.Do(func() {
-		// Get K8s client
-		k8sClient = k8s.GetClient()
-		if k8sClient == nil {
-			initErr = fmt.Errorf("kubernetes client not initialized")
-			return
-		}
+	initMu.Lock()
+	defer initMu.Unlock()
 
-		// Get REST config for metrics client
-		config := k8s.GetConfig()
-		if config == nil {
-			initErr = fmt.Errorf("kubernetes config not available")
-			return
-		}
+	// Get K8s client
+	client := k8s.GetClient()
+	if client == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
 
-		// Create metrics client
-		var err error
-		metricsClient, err = metricsv.NewForConfig(config)
-		if err != nil {
-			log.Warn().Err(err).Msg("Failed to create metrics client - metrics will be unavailable")
-			// Don't fail - other monitoring features can still work
-		} else {
-			log.Info().Msg("Metrics client initialized")
-		}
+	// Get REST config for metrics client
+	config := k8s.GetConfig()
+	if config == nil {
+		return fmt.Errorf("kubernetes config not available")
+	}
 
-		// Initialize default cost config
-		costConfig = DefaultCostConfig()
-	})
+	// Create metrics client
+	metrics, err := metricsv.NewForConfig(config)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to create metrics client - metrics will be unavailable")
+		// Don't fail - other monitoring features can still work
+		metrics = nil
+	} else {
+		log.Info().Msg("Metrics client initialized")
+	}
+	k8sClient, metricsClient = client, metrics
 
-	return initErr
+	// Initialize default cost config
+	if !initialized {
+		configMu.Lock()
+		costConfig = DefaultCostConfig()
+		configMu.Unlock()
+		initialized = true
+	}
+	return nil
 }
 
 // GetMetricsClient returns the metrics client
@@ -59,7 +65,7 @@ func GetMetricsClient() *metricsv.Clientset {
 }
 
 // GetK8sClient returns the kubernetes client
-func GetK8sClient() *kubernetes.Clientset {
+func GetK8sClient() kubernetes.Interface {
 	return k8sClient
 }
 
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/gaga951/gagos/internal/fanout"
+	"github.com/rs/zerolog/log"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 )
 
 // GetNodeMetrics retrieves resource metrics for all nodes
@@ -15,47 +18,64 @@ func GetNodeMetrics(ctx context.Context) ([]NodeMetrics, error) {
 		return nil, fmt.Errorf("kubernetes client not initialized")
 	}
 
-	// Get node list for capacity info
-	nodes, err := k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
-	if err != nil {
+	// Fetch nodes, pods and node metrics concurrently; only nodes are required
+	var (
+		nodes           *corev1.NodeList
+		pods            *corev1.PodList
+		nodeMetricsList *metricsv1beta1.NodeMetricsList
+	)
+	tasks := map[string]fanout.Func{
+		"nodes": func(ctx context.Context) (err error) {
+			nodes, err = k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+			return err
+		},
+		"pods": func(ctx context.Context) (err error) {
+			pods, err = k8sClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+			return err
+		},
+	}
+	if metricsClient != nil {
+		tasks["metrics"] = func(ctx context.Context) (err error) {
+			nodeMetricsList, err = metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+			return err
+		}
+	}
+	errs := fanout.Run(ctx, fanout.Options{}, tasks)
+	if err := errs["nodes"]; err != nil {
 		return nil, fmt.Errorf("failed to list nodes: %w", err)
 	}
-
-	// Get pods to count per node
-	pods, err := k8sClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list pods: %w", err)
+	if err := errs["pods"]; err != nil {
+		log.Warn().Err(err).Msg("Failed to list pods for node metrics - pod counts unavailable")
 	}
 
 	// Count pods per node
 	podCountByNode := make(map[string]int)
-	for _, pod := range pods.Items {
-		if pod.Spec.NodeName != "" && pod.Status.Phase == corev1.PodRunning {
-			podCountByNode[pod.Spec.NodeName]++
+	if pods != nil {
+		for _, pod := range pods.Items {
+			if pod.Spec.NodeName != "" && pod.Status.Phase == corev1.PodRunning {
+				podCountByNode[pod.Spec.NodeName]++
+			}
 		}
 	}
 
-	// Try to get metrics from metrics-server
+	// Use metrics-server data when available
 	var nodeMetricsMap map[string]struct {
 		CPUUsage    int64
 		MemoryUsage int64
 	}
 
-	if metricsClient != nil {
-		nodeMetricsList, err := metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
-		if err == nil {
-			nodeMetricsMap = make(map[string]struct {
+	if nodeMetricsList != nil {
+		nodeMetricsMap = make(map[string]struct {
+			CPUUsage    int64
+			MemoryUsage int64
+		})
+		for _, nm := range nodeMetricsList.Items {
+			nodeMetricsMap[nm.Name] = struct {
 				CPUUsage    int64
 				MemoryUsage int64
-			})
-			for _, nm := range nodeMetricsList.Items {
-				nodeMetricsMap[nm.Name] = struct {
-					CPUUsage    int64
-					MemoryUsage int64
-				}{
-					CPUUsage:    nm.Usage.Cpu().MilliValue(),
-					MemoryUsage: nm.Usage.Memory().Value(),
-				}
+			}{
+				CPUUsage:    nm.Usage.Cpu().MilliValue(),
+				MemoryUsage: nm.Usage.Memory().Value(),
 			}
 		}
 	}
@@ -70,19 +90,7 @@ func GetNodeMetrics(ctx context.Context) ([]NodeMetrics, error) {
 			}
 		}
 
-		// Get roles
-		var roles []string
-		for label := range node.Labels {
-			if label == "node-role.kubernetes.io/master" || label == "node-role.kubernetes.io/control-plane" {
-				roles = append(roles, "control-plane")
-			}
-			if label == "node-role.kubernetes.io/worker" {
-				roles = append(roles, "worker")
-			}
-		}
-		if len(roles) == 0 {
-			roles = []string{"worker"}
-		}
+		roles := nodeRoles(&node)
 
 		// Get capacity
 		cpuCapacity := node.Status.Capacity.Cpu().MilliValue()
@@ -126,45 +134,74 @@ func GetNodeMetrics(ctx context.Context) ([]NodeMetrics, error) {
 	return result, nil
 }
 
+// nodeRoles returns control-plane and/or worker from the node-role labels
+func nodeRoles(node *corev1.Node) []string {
+	var roles []string
+	for label := range node.Labels {
+		if label == "node-role.kubernetes.io/master" || label == "node-role.kubernetes.io/control-plane" {
+			roles = append(roles, "control-plane")
+		}
+		if label == "node-role.kubernetes.io/worker" {
+			roles = append(roles, "worker")
+		}
+	}
+	if len(roles) == 0 {
+		roles = []string{"worker"}
+	}
+	return roles
+}
+
 // GetPodMetrics retrieves resource metrics for pods
 func GetPodMetrics(ctx context.Context, namespace string) ([]PodMetrics, error) {
 	if k8sClient == nil {
 		return nil, fmt.Errorf("kubernetes client not initialized")
 	}
 
-	// Get pod list
-	pods, err := k8sClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
-	if err != nil {
+	// Fetch pods and pod metrics concurrently; metrics are optional
+	var (
+		pods           *corev1.PodList
+		podMetricsList *metricsv1beta1.PodMetricsList
+	)
+	tasks := map[string]fanout.Func{
+		"pods": func(ctx context.Context) (err error) {
+			pods, err = k8sClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+			return err
+		},
+	}
+	if metricsClient != nil {
+		tasks["metrics"] = func(ctx context.Context) (err error) {
+			podMetricsList, err = metricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
+			return err
+		}
+	}
+	if err := fanout.Run(ctx, fanout.Options{}, tasks)["pods"]; err != nil {
 		return nil, fmt.Errorf("failed to list pods: %w", err)
 	}
 
-	// Try to get metrics from metrics-server
+	// Use metrics-server data when available
 	var podMetricsMap map[string]map[string]struct {
 		CPUUsage    int64
 		MemoryUsage int64
 	}
 
-	if metricsClient != nil {
-		podMetricsList, err := metricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
-		if err == nil {
-			podMetricsMap = make(map[string]map[string]struct {
+	if podMetricsList != nil {
+		podMetricsMap = make(map[string]map[string]struct {
+			CPUUsage    int64
+			MemoryUsage int64
+		})
+		for _, pm := range podMetricsList.Items {
+			key := pm.Namespace + "/" + pm.Name
+			podMetricsMap[key] = make(map[string]struct {
 				CPUUsage    int64
 				MemoryUsage int64
 			})
-			for _, pm := range podMetricsList.Items {
-				key := pm.Namespace + "/" + pm.Name
-				podMetricsMap[key] = make(map[string]struct {
+			for _, container := range pm.Containers {
+				podMetricsMap[key][container.Name] = struct {
 					CPUUsage    int64
 					MemoryUsage int64
-				})
-				for _, container := range pm.Containers {
-					podMetricsMap[key][container.Name] = struct {
-						CPUUsage    int64
-						MemoryUsage int64
-					}{
-						CPUUsage:    container.Usage.Cpu().MilliValue(),
-						MemoryUsage: container.Usage.Memory().Value(),
-					}
+				}{
+					CPUUsage:    container.Usage.Cpu().MilliValue(),
+					MemoryUsage: container.Usage.Memory().Value(),
 				}
 			}
 		}
@@ -221,15 +258,39 @@ func GetClusterSummary(ctx context.Context) (*ClusterSummary, error) {
 		return nil, fmt.Errorf("kubernetes client not initialized")
 	}
 
-	// Get nodes
-	nodes, err := k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	// Served from watch events once the tracker has synced; list directly until then
+	startSummaryTracker()
+	if s, ok := summary.snapshot(); ok {
+		return s, nil
 	}
 
-	// Get pods
-	pods, err := k8sClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
-	if err != nil {
+	// Fetch nodes, pods and node metrics concurrently; metrics are optional
+	var (
+		nodes       *corev1.NodeList
+		pods        *corev1.PodList
+		nodeMetrics *metricsv1beta1.NodeMetricsList
+	)
+	tasks := map[string]fanout.Func{
+		"nodes": func(ctx context.Context) (err error) {
+			nodes, err = k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+			return err
+		},
+		"pods": func(ctx context.Context) (err error) {
+			pods, err = k8sClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+			return err
+		},
+	}
+	if metricsClient != nil {
+		tasks["metrics"] = func(ctx context.Context) (err error) {
+			nodeMetrics, err = metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+			return err
+		}
+	}
+	errs := fanout.Run(ctx, fanout.Options{}, tasks)
+	if err := errs["nodes"]; err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	if err := errs["pods"]; err != nil {
 		return nil, fmt.Errorf("failed to list pods: %w", err)
 	}
 
@@ -260,13 +321,10 @@ func GetClusterSummary(ctx context.Context) (*ClusterSummary, error) {
 
 	// Get used CPU/Memory from metrics
 	var usedCPU, usedMem int64
-	if metricsClient != nil {
-		nodeMetrics, err := metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
-		if err == nil {
-			for _, nm := range nodeMetrics.Items {
-				usedCPU += nm.Usage.Cpu().MilliValue()
-				usedMem += nm.Usage.Memory().Value()
-			}
+	if nodeMetrics != nil {
+		for _, nm := range nodeMetrics.Items {
+			usedCPU += nm.Usage.Cpu().MilliValue()
+			usedMem += nm.Usage.Memory().Value()
 		}
 	}
 
@@ -0,0 +1,264 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package monitoring
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gaga951/gagos/internal/k8s"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// summaryResyncInterval rebuilds the counters from the informer caches to correct any drift
+	summaryResyncInterval = 5 * time.Minute
+	// summaryUsageInterval matches the metrics-server scrape resolution
+	summaryUsageInterval = 15 * time.Second
+)
+
+// nodeCapacity is the part of a node the cluster summary needs
+type nodeCapacity struct {
+	ready  bool
+	cpu    int64
+	memory int64
+}
+
+// summaryTracker keeps the cluster summary up to date from node and pod watch
+// events, so GetClusterSummary does not have to list the whole cluster per call.
+type summaryTracker struct {
+	mu          sync.RWMutex
+	synced      bool
+	nodes       map[string]nodeCapacity
+	pods        map[string]bool // namespace/name -> running
+	runningPods int
+	usedCPU     int64
+	usedMem     int64
+}
+
+var (
+	summary     = &summaryTracker{nodes: map[string]nodeCapacity{}, pods: map[string]bool{}}
+	summaryOnce sync.Once
+)
+
+// startSummaryTracker registers node/pod handlers on the shared informers and
+// starts the resync and usage polling loops. Safe to call more than once.
+func startSummaryTracker() {
+	summaryOnce.Do(func() {
+		factory := k8s.SharedInformerFactory()
+		if factory == nil {
+			return
+		}
+		nodeInformer := factory.Core().V1().Nodes().Informer()
+		podInformer := factory.Core().V1().Pods().Informer()
+
+		_, err := nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { summary.setNode(obj) },
+			UpdateFunc: func(_, obj interface{}) { summary.setNode(obj) },
+			DeleteFunc: func(obj interface{}) { summary.deleteNode(obj) },
+		})
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to watch nodes for cluster summary")
+			return
+		}
+		_, err = podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { summary.setPod(obj) },
+			UpdateFunc: func(_, obj interface{}) { summary.setPod(obj) },
+			DeleteFunc: func(obj interface{}) { summary.deletePod(obj) },
+		})
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to watch pods for cluster summary")
+			return
+		}
+		k8s.StartInformers()
+
+		go func() {
+			stop := make(chan struct{})
+			if !cache.WaitForCacheSync(stop, nodeInformer.HasSynced, podInformer.HasSynced) {
+				return
+			}
+			summary.resync(nodeInformer.GetStore().List(), podInformer.GetStore().List())
+			summary.refreshUsage()
+			log.Info().Msg("Cluster summary is now maintained from watch events")
+
+			resync := time.NewTicker(summaryResyncInterval)
+			usage := time.NewTicker(summaryUsageInterval)
+			defer resync.Stop()
+			defer usage.Stop()
+			for {
+				select {
+				case <-resync.C:
+					summary.resync(nodeInformer.GetStore().List(), podInformer.GetStore().List())
+				case <-usage.C:
+					summary.refreshUsage()
+				}
+			}
+		}()
+	})
+}
+
+func (t *summaryTracker) setNode(obj interface{}) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	t.nodes[node.Name] = toNodeCapacity(node)
+	t.mu.Unlock()
+}
+
+func (t *summaryTracker) deleteNode(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	delete(t.nodes, node.Name)
+	t.mu.Unlock()
+}
+
+func (t *summaryTracker) setPod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	key := pod.Namespace + "/" + pod.Name
+	running := pod.Status.Phase == corev1.PodRunning
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if wasRunning, exists := t.pods[key]; exists && wasRunning {
+		t.runningPods--
+	}
+	if running {
+		t.runningPods++
+	}
+	t.pods[key] = running
+}
+
+func (t *summaryTracker) deletePod(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	key := pod.Namespace + "/" + pod.Name
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if wasRunning, exists := t.pods[key]; exists {
+		if wasRunning {
+			t.runningPods--
+		}
+		delete(t.pods, key)
+	}
+}
+
+// resync rebuilds all counters from the informer stores
+func (t *summaryTracker) resync(nodeObjs, podObjs []interface{}) {
+	nodes := make(map[string]nodeCapacity, len(nodeObjs))
+	for _, obj := range nodeObjs {
+		if node, ok := obj.(*corev1.Node); ok {
+			nodes[node.Name] = toNodeCapacity(node)
+		}
+	}
+	pods := make(map[string]bool, len(podObjs))
+	running := 0
+	for _, obj := range podObjs {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			isRunning := pod.Status.Phase == corev1.PodRunning
+			pods[pod.Namespace+"/"+pod.Name] = isRunning
+			if isRunning {
+				running++
+			}
+		}
+	}
+
+	t.mu.Lock()
+	t.nodes = nodes
+	t.pods = pods
+	t.runningPods = running
+	t.synced = true
+	t.mu.Unlock()
+}
+
+// refreshUsage polls metrics-server for node usage; usage cannot be watched
+func (t *summaryTracker) refreshUsage() {
+	if metricsClient == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	nodeMetrics, err := metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to refresh node usage for cluster summary")
+		return
+	}
+	var usedCPU, usedMem int64
+	for _, nm := range nodeMetrics.Items {
+		usedCPU += nm.Usage.Cpu().MilliValue()
+		usedMem += nm.Usage.Memory().Value()
+	}
+
+	t.mu.Lock()
+	t.usedCPU = usedCPU
+	t.usedMem = usedMem
+	t.mu.Unlock()
+}
+
+// snapshot returns the current summary, or false until the first full sync
+func (t *summaryTracker) snapshot() (*ClusterSummary, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if !t.synced {
+		return nil, false
+	}
+
+	s := &ClusterSummary{
+		TotalNodes:        len(t.nodes),
+		UsedCPUMillicores: t.usedCPU,
+		UsedMemoryBytes:   t.usedMem,
+		TotalPods:         len(t.pods),
+		RunningPods:       t.runningPods,
+		Timestamp:         time.Now(),
+	}
+	for _, n := range t.nodes {
+		if n.ready {
+			s.ReadyNodes++
+		}
+		s.TotalCPUMillicores += n.cpu
+		s.TotalMemoryBytes += n.memory
+	}
+	if s.TotalCPUMillicores > 0 {
+		s.CPUPercent = float64(s.UsedCPUMillicores) / float64(s.TotalCPUMillicores) * 100
+	}
+	if s.TotalMemoryBytes > 0 {
+		s.MemoryPercent = float64(s.UsedMemoryBytes) / float64(s.TotalMemoryBytes) * 100
+	}
+	return s, true
+}
+
+func toNodeCapacity(node *corev1.Node) nodeCapacity {
+	c := nodeCapacity{
+		cpu:    node.Status.Capacity.Cpu().MilliValue(),
+		memory: node.Status.Capacity.Memory().Value(),
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady && cond.Status == corev1.ConditionTrue {
+			c.ready = true
+			break
+		}
+	}
+	return c
+}
@@ -0,0 +1,284 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gaga951/gagos/internal/fanout"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// Provisioning thresholds, as usage in percent of the request or limit
+const (
+	overProvisionedPercent  = 20  // using less than this share of the request
+	underProvisionedPercent = 100 // using more than requested
+	nearLimitPercent        = 90  // close to throttling (CPU) or OOM kill (memory)
+)
+
+// quotaResources are the quota entries that compare with pod requests/limits
+var quotaResources = map[corev1.ResourceName]bool{
+	corev1.ResourceCPU:            true,
+	corev1.ResourceMemory:         true,
+	corev1.ResourceRequestsCPU:    true,
+	corev1.ResourceRequestsMemory: true,
+	corev1.ResourceLimitsCPU:      true,
+	corev1.ResourceLimitsMemory:   true,
+}
+
+// GetResourceTop returns per-pod usage against requests and limits plus
+// per-namespace totals against quotas. sortBy is one of cpu (default),
+// memory, cpu_percent, memory_percent or name.
+func GetResourceTop(ctx context.Context, namespace, sortBy string) (*ResourceTop, error) {
+	if k8sClient == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	// Pods are required; metrics and quotas are optional
+	var (
+		pods           *corev1.PodList
+		podMetricsList *metricsv1beta1.PodMetricsList
+		quotas         *corev1.ResourceQuotaList
+	)
+	tasks := map[string]fanout.Func{
+		"pods": func(ctx context.Context) (err error) {
+			pods, err = k8sClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+			return err
+		},
+		"quotas": func(ctx context.Context) (err error) {
+			quotas, err = k8sClient.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+			return err
+		},
+	}
+	if metricsClient != nil {
+		tasks["metrics"] = func(ctx context.Context) (err error) {
+			podMetricsList, err = metricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
+			return err
+		}
+	}
+	if err := fanout.Run(ctx, fanout.Options{}, tasks)["pods"]; err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	usage := make(map[string]map[string]corev1.ResourceList)
+	if podMetricsList != nil {
+		for _, pm := range podMetricsList.Items {
+			containers := make(map[string]corev1.ResourceList, len(pm.Containers))
+			for _, c := range pm.Containers {
+				containers[c.Name] = c.Usage
+			}
+			usage[pm.Namespace+"/"+pm.Name] = containers
+		}
+	}
+	metricsAvailable := podMetricsList != nil
+
+	top := &ResourceTop{MetricsAvailable: metricsAvailable, Timestamp: time.Now()}
+	namespaces := make(map[string]*NamespaceTop)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		// Finished pods hold no resources and no longer count against quotas
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		pt := podTop(pod, usage[pod.Namespace+"/"+pod.Name], metricsAvailable)
+		top.Pods = append(top.Pods, pt)
+
+		ns := namespaces[pod.Namespace]
+		if ns == nil {
+			ns = &NamespaceTop{Namespace: pod.Namespace}
+			namespaces[pod.Namespace] = ns
+		}
+		ns.PodCount++
+		ns.CPUUsage += pt.CPUUsage
+		ns.CPURequest += pt.CPURequest
+		ns.CPULimit += pt.CPULimit
+		ns.MemoryUsage += pt.MemoryUsage
+		ns.MemoryRequest += pt.MemoryRequest
+		ns.MemoryLimit += pt.MemoryLimit
+	}
+
+	if quotas != nil {
+		for _, quota := range quotas.Items {
+			ns := namespaces[quota.Namespace]
+			if ns == nil {
+				ns = &NamespaceTop{Namespace: quota.Namespace}
+				namespaces[quota.Namespace] = ns
+			}
+			ns.Quotas = append(ns.Quotas, computeQuotaUsage(&quota)...)
+		}
+	}
+
+	for _, ns := range namespaces {
+		// Pods without limits leave the summed limits short, so namespaces
+		// are only compared with their requests
+		if metricsAvailable {
+			ns.Flags = append(ns.Flags, provisioningFlags("cpu", ns.CPUUsage, ns.CPURequest, 0)...)
+			ns.Flags = append(ns.Flags, provisioningFlags("memory", ns.MemoryUsage, ns.MemoryRequest, 0)...)
+		}
+		for _, q := range ns.Quotas {
+			if q.Status != "ok" {
+				ns.Flags = append(ns.Flags, "quota_"+q.Status+":"+q.Resource)
+			}
+		}
+		top.Namespaces = append(top.Namespaces, *ns)
+	}
+	sort.Slice(top.Namespaces, func(i, j int) bool { return top.Namespaces[i].Namespace < top.Namespaces[j].Namespace })
+
+	sortPodTop(top.Pods, sortBy)
+	return top, nil
+}
+
+func podTop(pod *corev1.Pod, usage map[string]corev1.ResourceList, metricsAvailable bool) PodTop {
+	pt := PodTop{
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		Node:      pod.Spec.NodeName,
+	}
+
+	var missingCPURequest, missingCPULimit, missingMemoryRequest, missingMemoryLimit bool
+	for _, c := range pod.Spec.Containers {
+		ct := ContainerTop{
+			Name:          c.Name,
+			CPURequest:    c.Resources.Requests.Cpu().MilliValue(),
+			CPULimit:      c.Resources.Limits.Cpu().MilliValue(),
+			MemoryRequest: c.Resources.Requests.Memory().Value(),
+			MemoryLimit:   c.Resources.Limits.Memory().Value(),
+		}
+		if u, ok := usage[c.Name]; ok {
+			ct.CPUUsage = u.Cpu().MilliValue()
+			ct.MemoryUsage = u.Memory().Value()
+		}
+		missingCPURequest = missingCPURequest || ct.CPURequest == 0
+		missingCPULimit = missingCPULimit || ct.CPULimit == 0
+		missingMemoryRequest = missingMemoryRequest || ct.MemoryRequest == 0
+		missingMemoryLimit = missingMemoryLimit || ct.MemoryLimit == 0
+
+		pt.CPUUsage += ct.CPUUsage
+		pt.CPURequest += ct.CPURequest
+		pt.CPULimit += ct.CPULimit
+		pt.MemoryUsage += ct.MemoryUsage
+		pt.MemoryRequest += ct.MemoryRequest
+		pt.MemoryLimit += ct.MemoryLimit
+		pt.Containers = append(pt.Containers, ct)
+	}
+
+	// A container without a limit makes the pod's limit unbounded
+	if missingCPULimit {
+		pt.CPULimit = 0
+	}
+	if missingMemoryLimit {
+		pt.MemoryLimit = 0
+	}
+
+	pt.CPURequestPercent = percentOf(pt.CPUUsage, pt.CPURequest)
+	pt.CPULimitPercent = percentOf(pt.CPUUsage, pt.CPULimit)
+	pt.MemoryRequestPercent = percentOf(pt.MemoryUsage, pt.MemoryRequest)
+	pt.MemoryLimitPercent = percentOf(pt.MemoryUsage, pt.MemoryLimit)
+
+	if missingCPURequest {
+		pt.Flags = append(pt.Flags, "no_cpu_request")
+	}
+	if missingMemoryRequest {
+		pt.Flags = append(pt.Flags, "no_memory_request")
+	}
+	if missingMemoryLimit {
+		pt.Flags = append(pt.Flags, "no_memory_limit")
+	}
+	if metricsAvailable && pod.Status.Phase == corev1.PodRunning {
+		pt.Flags = append(pt.Flags, provisioningFlags("cpu", pt.CPUUsage, pt.CPURequest, pt.CPULimit)...)
+		pt.Flags = append(pt.Flags, provisioningFlags("memory", pt.MemoryUsage, pt.MemoryRequest, pt.MemoryLimit)...)
+	}
+	return pt
+}
+
+// provisioningFlags compares usage with request and limit for one resource
+func provisioningFlags(resource string, usage, request, limit int64) []string {
+	var flags []string
+	if p := percentOf(usage, request); p != nil {
+		if *p < overProvisionedPercent {
+			flags = append(flags, resource+"_over_provisioned")
+		} else if *p > underProvisionedPercent {
+			flags = append(flags, resource+"_under_provisioned")
+		}
+	}
+	if p := percentOf(usage, limit); p != nil && *p >= nearLimitPercent {
+		flags = append(flags, resource+"_near_limit")
+	}
+	return flags
+}
+
+func percentOf(value, of int64) *float64 {
+	if of <= 0 {
+		return nil
+	}
+	p := float64(value) / float64(of) * 100
+	return &p
+}
+
+// computeQuotaUsage returns the CPU and memory entries of a quota, comparing
+// CPU in millicores so fractional cores are not rounded up
+func computeQuotaUsage(quota *corev1.ResourceQuota) []ResourceQuotaUsage {
+	var result []ResourceQuotaUsage
+	for resource, hardQty := range quota.Status.Hard {
+		if !quotaResources[resource] {
+			continue
+		}
+		usedQty := quota.Status.Used[resource]
+		hardVal, usedVal := hardQty.Value(), usedQty.Value()
+		if strings.HasSuffix(string(resource), "cpu") {
+			hardVal, usedVal = hardQty.MilliValue(), usedQty.MilliValue()
+		}
+
+		percent := float64(0)
+		if hardVal > 0 {
+			percent = float64(usedVal) / float64(hardVal) * 100
+		}
+		status := "ok"
+		if percent >= 90 {
+			status = "critical"
+		} else if percent >= 75 {
+			status = "warning"
+		}
+
+		result = append(result, ResourceQuotaUsage{
+			Resource: quota.Name + "/" + string(resource),
+			Hard:     hardQty.String(),
+			Used:     usedQty.String(),
+			Percent:  percent,
+			Status:   status,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Resource < result[j].Resource })
+	return result
+}
+
+func sortPodTop(pods []PodTop, sortBy string) {
+	deref := func(p *float64) float64 {
+		if p == nil {
+			return -1
+		}
+		return *p
+	}
+	var less func(a, b PodTop) bool
+	switch sortBy {
+	case "memory":
+		less = func(a, b PodTop) bool { return a.MemoryUsage > b.MemoryUsage }
+	case "cpu_percent":
+		less = func(a, b PodTop) bool { return deref(a.CPURequestPercent) > deref(b.CPURequestPercent) }
+	case "memory_percent":
+		less = func(a, b PodTop) bool { return deref(a.MemoryRequestPercent) > deref(b.MemoryRequestPercent) }
+	case "name":
+		less = func(a, b PodTop) bool { return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name }
+	default:
+		less = func(a, b PodTop) bool { return a.CPUUsage > b.CPUUsage }
+	}
+	sort.SliceStable(pods, func(i, j int) bool { return less(pods[i], pods[j]) })
+}
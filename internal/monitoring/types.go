@@ -108,6 +108,60 @@ type HPAInfo struct {
 	Age             string   `json:"age"`
 }
 
+// ResourceTop joins metrics-server usage with pod requests/limits and
+// namespace quotas
+type ResourceTop struct {
+	MetricsAvailable bool           `json:"metrics_available"`
+	Pods             []PodTop       `json:"pods"`
+	Namespaces       []NamespaceTop `json:"namespaces"`
+	Timestamp        time.Time      `json:"timestamp"`
+}
+
+// PodTop is a pod's usage next to its requests and limits. Percentages are
+// usage relative to the request or limit and are omitted when it is unset.
+type PodTop struct {
+	Name                 string         `json:"name"`
+	Namespace            string         `json:"namespace"`
+	Node                 string         `json:"node"`
+	CPUUsage             int64          `json:"cpu_usage_millicores"`
+	CPURequest           int64          `json:"cpu_request_millicores"`
+	CPULimit             int64          `json:"cpu_limit_millicores"`
+	CPURequestPercent    *float64       `json:"cpu_request_percent,omitempty"`
+	CPULimitPercent      *float64       `json:"cpu_limit_percent,omitempty"`
+	MemoryUsage          int64          `json:"memory_usage_bytes"`
+	MemoryRequest        int64          `json:"memory_request_bytes"`
+	MemoryLimit          int64          `json:"memory_limit_bytes"`
+	MemoryRequestPercent *float64       `json:"memory_request_percent,omitempty"`
+	MemoryLimitPercent   *float64       `json:"memory_limit_percent,omitempty"`
+	Containers           []ContainerTop `json:"containers"`
+	Flags                []string       `json:"flags,omitempty"`
+}
+
+// ContainerTop is a container's usage next to its requests and limits
+type ContainerTop struct {
+	Name          string `json:"name"`
+	CPUUsage      int64  `json:"cpu_usage_millicores"`
+	CPURequest    int64  `json:"cpu_request_millicores"`
+	CPULimit      int64  `json:"cpu_limit_millicores"`
+	MemoryUsage   int64  `json:"memory_usage_bytes"`
+	MemoryRequest int64  `json:"memory_request_bytes"`
+	MemoryLimit   int64  `json:"memory_limit_bytes"`
+}
+
+// NamespaceTop totals a namespace's pods and compares them with its quotas
+type NamespaceTop struct {
+	Namespace     string               `json:"namespace"`
+	PodCount      int                  `json:"pod_count"`
+	CPUUsage      int64                `json:"cpu_usage_millicores"`
+	CPURequest    int64                `json:"cpu_request_millicores"`
+	CPULimit      int64                `json:"cpu_limit_millicores"`
+	MemoryUsage   int64                `json:"memory_usage_bytes"`
+	MemoryRequest int64                `json:"memory_request_bytes"`
+	MemoryLimit   int64                `json:"memory_limit_bytes"`
+	Quotas        []ResourceQuotaUsage `json:"quotas,omitempty"`
+	Flags         []string             `json:"flags,omitempty"`
+}
+
 // CostConfig holds pricing configuration
 type CostConfig struct {
 	CPUCostPerHour    float64 `json:"cpu_cost_per_hour"`    // $ per vCPU-hour
@@ -0,0 +1,323 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gaga951/gagos/internal/fanout"
+	"github.com/gaga951/gagos/internal/k8s"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PVC usage comes from the kubelet stats summary of every node, read
+// through the API server's node proxy. Only mounted volumes have stats;
+// a PVC no running pod mounts is reported without usage.
+
+// VolumeUsage is a PVC's requested size against what its file system holds
+type VolumeUsage struct {
+	Namespace      string  `json:"namespace"`
+	Name           string  `json:"name"`
+	StorageClass   string  `json:"storage_class,omitempty"`
+	Requested      string  `json:"requested"`
+	Capacity       string  `json:"capacity,omitempty"` // status.capacity
+	Mounted        bool    `json:"mounted"`
+	Pod            string  `json:"pod,omitempty"`
+	Node           string  `json:"node,omitempty"`
+	CapacityBytes  int64   `json:"capacity_bytes,omitempty"` // file system size reported by the kubelet
+	UsedBytes      int64   `json:"used_bytes,omitempty"`
+	AvailableBytes int64   `json:"available_bytes,omitempty"`
+	Percent        float64 `json:"percent"`
+	Inodes         int64   `json:"inodes,omitempty"`
+	InodesUsed     int64   `json:"inodes_used,omitempty"`
+	InodesPercent  float64 `json:"inodes_percent"`
+	Status         string  `json:"status"` // ok, warning, critical, unknown
+}
+
+// VolumeAlert is a PVC above the alert threshold
+type VolumeAlert struct {
+	Namespace     string    `json:"namespace"`
+	Name          string    `json:"name"`
+	Percent       float64   `json:"percent"`
+	InodesPercent float64   `json:"inodes_percent"`
+	Since         time.Time `json:"since"`
+	LastSeen      time.Time `json:"last_seen"`
+}
+
+// kubeletSummary is the part of /stats/summary the volume and node
+// allocation reports read
+type kubeletSummary struct {
+	Node struct {
+		Fs *struct {
+			CapacityBytes *uint64 `json:"capacityBytes"`
+			UsedBytes     *uint64 `json:"usedBytes"`
+		} `json:"fs"`
+	} `json:"node"`
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Volume []struct {
+			Name           string  `json:"name"`
+			CapacityBytes  *uint64 `json:"capacityBytes"`
+			UsedBytes      *uint64 `json:"usedBytes"`
+			AvailableBytes *uint64 `json:"availableBytes"`
+			Inodes         *uint64 `json:"inodes"`
+			InodesUsed     *uint64 `json:"inodesUsed"`
+			PVCRef         *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+// GetVolumeUsage returns the usage of every PVC in namespace ("" for all),
+// fullest first
+func GetVolumeUsage(ctx context.Context, namespace string) ([]VolumeUsage, error) {
+	if k8sClient == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	pvcs, err := k8sClient.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PVCs: %w", err)
+	}
+	nodes, err := k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var mu sync.Mutex
+	stats := map[string]VolumeUsage{} // namespace/name -> kubelet stats
+	tasks := make(map[string]fanout.Func, len(nodes.Items))
+	for _, node := range nodes.Items {
+		nodeName := node.Name
+		tasks[nodeName] = func(ctx context.Context) error {
+			summary, err := nodeStatsSummary(ctx, nodeName)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, pod := range summary.Pods {
+				for _, v := range pod.Volume {
+					if v.PVCRef == nil || v.CapacityBytes == nil || v.UsedBytes == nil {
+						continue
+					}
+					key := v.PVCRef.Namespace + "/" + v.PVCRef.Name
+					if _, ok := stats[key]; ok {
+						continue // the same volume mounted by several pods
+					}
+					stats[key] = VolumeUsage{
+						Mounted:        true,
+						Pod:            pod.PodRef.Name,
+						Node:           nodeName,
+						CapacityBytes:  int64(*v.CapacityBytes),
+						UsedBytes:      int64(*v.UsedBytes),
+						AvailableBytes: int64(deref(v.AvailableBytes)),
+						Inodes:         int64(deref(v.Inodes)),
+						InodesUsed:     int64(deref(v.InodesUsed)),
+					}
+				}
+			}
+			return nil
+		}
+	}
+	// A node that cannot be read leaves its volumes without usage
+	for node, err := range fanout.Run(ctx, fanout.Options{Limit: 8}, tasks) {
+		log.Debug().Err(err).Str("node", node).Msg("Failed to read kubelet stats summary")
+	}
+
+	result := make([]VolumeUsage, 0, len(pvcs.Items))
+	for _, pvc := range pvcs.Items {
+		usage := stats[pvc.Namespace+"/"+pvc.Name]
+		usage.Namespace = pvc.Namespace
+		usage.Name = pvc.Name
+		if pvc.Spec.StorageClassName != nil {
+			usage.StorageClass = *pvc.Spec.StorageClassName
+		}
+		requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		usage.Requested = requested.String()
+		if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+			usage.Capacity = capacity.String()
+		}
+		usage.Status = "unknown"
+		if usage.Mounted {
+			if usage.CapacityBytes > 0 {
+				usage.Percent = float64(usage.UsedBytes) / float64(usage.CapacityBytes) * 100
+			}
+			if usage.Inodes > 0 {
+				usage.InodesPercent = float64(usage.InodesUsed) / float64(usage.Inodes) * 100
+			}
+			usage.Status = volumeStatus(max(usage.Percent, usage.InodesPercent))
+		}
+		result = append(result, usage)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if pa, pb := max(a.Percent, a.InodesPercent), max(b.Percent, b.InodesPercent); pa != pb {
+			return pa > pb
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return result, nil
+}
+
+// GetNearlyFullVolumes returns the mounted PVCs whose space or inode usage is
+// at least threshold percent
+func GetNearlyFullVolumes(ctx context.Context, namespace string, threshold float64) ([]VolumeUsage, error) {
+	volumes, err := GetVolumeUsage(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	full := []VolumeUsage{}
+	for _, v := range volumes {
+		if v.Mounted && max(v.Percent, v.InodesPercent) >= threshold {
+			full = append(full, v)
+		}
+	}
+	return full, nil
+}
+
+func nodeStatsSummary(ctx context.Context, node string) (*kubeletSummary, error) {
+	rc, err := k8s.CoreREST(k8sClient)
+	if err != nil {
+		return nil, err
+	}
+	body, err := rc.Get().
+		Resource("nodes").Name(node).SubResource("proxy").Suffix("stats/summary").
+		DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var summary kubeletSummary
+	if err := json.Unmarshal(body, &summary); err != nil {
+		return nil, fmt.Errorf("invalid stats summary: %w", err)
+	}
+	return &summary, nil
+}
+
+func deref(v *uint64) uint64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// volumeStatus uses the same thresholds as quota usage
+func volumeStatus(percent float64) string {
+	switch {
+	case percent >= 90:
+		return "critical"
+	case percent >= 75:
+		return "warning"
+	}
+	return "ok"
+}
+
+var (
+	volumeAlerts   = map[string]*VolumeAlert{} // namespace/name -> alert
+	volumeAlertsMu sync.Mutex
+	volumeAlertsOn sync.Once
+)
+
+// VolumeAlertThreshold is the usage percent at which a PVC raises an alert,
+// from GAGOS_PVC_ALERT_THRESHOLD (default 85)
+func VolumeAlertThreshold() float64 {
+	if v, err := strconv.ParseFloat(os.Getenv("GAGOS_PVC_ALERT_THRESHOLD"), 64); err == nil && v > 0 {
+		return v
+	}
+	return 85
+}
+
+// StartVolumeAlerts checks PVC usage every GAGOS_PVC_ALERT_INTERVAL
+// (default 5m, 0 disables) and logs volumes that cross the alert threshold
+// and those that drop back below it. Safe to call more than once.
+func StartVolumeAlerts() {
+	interval := 5 * time.Minute
+	if d, err := time.ParseDuration(os.Getenv("GAGOS_PVC_ALERT_INTERVAL")); err == nil && d >= 0 {
+		interval = d
+	}
+	if interval == 0 || k8sClient == nil {
+		return
+	}
+	volumeAlertsOn.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				checkVolumeAlerts()
+				<-ticker.C
+			}
+		}()
+	})
+}
+
+func checkVolumeAlerts() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	threshold := VolumeAlertThreshold()
+	full, err := GetNearlyFullVolumes(ctx, "", threshold)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to check PVC usage")
+		return
+	}
+
+	now := time.Now()
+	current := make(map[string]bool, len(full))
+	volumeAlertsMu.Lock()
+	defer volumeAlertsMu.Unlock()
+	for _, v := range full {
+		key := v.Namespace + "/" + v.Name
+		current[key] = true
+		alert, ok := volumeAlerts[key]
+		if !ok {
+			alert = &VolumeAlert{Namespace: v.Namespace, Name: v.Name, Since: now}
+			volumeAlerts[key] = alert
+			log.Warn().
+				Str("namespace", v.Namespace).
+				Str("pvc", v.Name).
+				Float64("percent", v.Percent).
+				Float64("inodes_percent", v.InodesPercent).
+				Float64("threshold", threshold).
+				Msg("PVC nearly full")
+		}
+		alert.Percent, alert.InodesPercent, alert.LastSeen = v.Percent, v.InodesPercent, now
+	}
+	for key, alert := range volumeAlerts {
+		if !current[key] {
+			delete(volumeAlerts, key)
+			log.Info().Str("namespace", alert.Namespace).Str("pvc", alert.Name).Msg("PVC no longer nearly full")
+		}
+	}
+}
+
+// GetVolumeAlerts returns the PVCs above the alert threshold at the last
+// check, oldest alert first
+func GetVolumeAlerts() []VolumeAlert {
+	volumeAlertsMu.Lock()
+	defer volumeAlertsMu.Unlock()
+	alerts := make([]VolumeAlert, 0, len(volumeAlerts))
+	for _, a := range volumeAlerts {
+		alerts = append(alerts, *a)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Since.Before(alerts[j].Since) })
+	return alerts
+}
@@ -0,0 +1,432 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package network
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/gaga951/gagos/internal/storage"
+)
+
+// Mount health: read/write probes of mounted paths, and monitors that run
+// the NFS, SMB and path checks on a schedule and keep their recent results.
+
+// Kinds of mount monitor
+const (
+	MountNFS  = "nfs"
+	MountSMB  = "smb"
+	MountPath = "path"
+)
+
+const (
+	DefaultMountInterval = 5 * time.Minute
+	MinMountInterval     = 30 * time.Second
+	mountHistorySize     = 50
+	DefaultProbeSize     = 4 << 10
+	MaxProbeSize         = 16 << 20
+)
+
+// ProbePathsAllowed returns the directories, from GAGOS_STORAGE_PROBE_PATHS,
+// under which path probes may write. None are allowed by default.
+func ProbePathsAllowed() []string {
+	var roots []string
+	for _, p := range strings.Split(os.Getenv("GAGOS_STORAGE_PROBE_PATHS"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			roots = append(roots, filepath.Clean(p))
+		}
+	}
+	return roots
+}
+
+func probePathAllowed(path string) bool {
+	for _, root := range ProbePathsAllowed() {
+		if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return true
+		}
+	}
+	return false
+}
+
+// PathProbeResult times writing, reading back and removing a test file
+type PathProbeResult struct {
+	Path     string  `json:"path"`
+	Size     int     `json:"size"`
+	OK       bool    `json:"ok"`
+	WriteMs  float64 `json:"write_ms,omitempty"` // write and fsync
+	ReadMs   float64 `json:"read_ms,omitempty"`
+	RemoveMs float64 `json:"remove_ms,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_ms"`
+}
+
+// ProbePath writes size random bytes to a temporary file in the directory
+// path, syncs it, reads it back and removes it. The path must be under one
+// of ProbePathsAllowed.
+func ProbePath(path string, size int) PathProbeResult {
+	start := time.Now()
+	if size <= 0 {
+		size = DefaultProbeSize
+	}
+	path = filepath.Clean(path)
+	result := PathProbeResult{Path: path, Size: size}
+	fail := func(format string, args ...interface{}) PathProbeResult {
+		result.Error = fmt.Sprintf(format, args...)
+		result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
+		return result
+	}
+
+	if !filepath.IsAbs(path) || !probePathAllowed(path) {
+		return fail("path is not under GAGOS_STORAGE_PROBE_PATHS")
+	}
+	if size > MaxProbeSize {
+		return fail("size must not exceed %d bytes", MaxProbeSize)
+	}
+	if info, err := os.Stat(path); err != nil {
+		return fail("%v", err)
+	} else if !info.IsDir() {
+		return fail("%s is not a directory", path)
+	}
+
+	data := make([]byte, size)
+	rand.Read(data)
+
+	t := time.Now()
+	f, err := os.CreateTemp(path, ".gagos-probe-*")
+	if err != nil {
+		return fail("create failed: %v", err)
+	}
+	name := f.Name()
+	defer os.Remove(name)
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fail("write failed: %v", err)
+	}
+	result.WriteMs = float64(time.Since(t).Microseconds()) / 1000.0
+
+	t = time.Now()
+	read, err := os.ReadFile(name)
+	if err != nil {
+		return fail("read failed: %v", err)
+	}
+	if !bytes.Equal(read, data) {
+		return fail("read back %d bytes that differ from what was written", len(read))
+	}
+	result.ReadMs = float64(time.Since(t).Microseconds()) / 1000.0
+
+	t = time.Now()
+	if err := os.Remove(name); err != nil {
+		return fail("remove failed: %v", err)
+	}
+	result.RemoveMs = float64(time.Since(t).Microseconds()) / 1000.0
+
+	result.OK = true
+	result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
+	return result
+}
+
+// MountMonitor is a check run on a schedule. Host and Export (NFS), Host and
+// Port (SMB) or Path (path) describe the target.
+type MountMonitor struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Kind            string    `json:"kind"`
+	Host            string    `json:"host,omitempty"`
+	Port            int       `json:"port,omitempty"`
+	Export          string    `json:"export,omitempty"`
+	Path            string    `json:"path,omitempty"`
+	IntervalSeconds int       `json:"interval_seconds"`
+	TimeoutSeconds  int       `json:"timeout_seconds"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// MountCheck is one run of a monitor
+type MountCheck struct {
+	Time     time.Time   `json:"time"`
+	OK       bool        `json:"ok"`
+	Error    string      `json:"error,omitempty"`
+	Duration float64     `json:"duration_ms"`
+	Result   interface{} `json:"result"`
+}
+
+// MountMonitorStatus is a monitor with its latest checks, newest first
+type MountMonitorStatus struct {
+	MountMonitor
+	Healthy *bool        `json:"healthy"` // nil until it has run
+	Checks  []MountCheck `json:"checks"`
+}
+
+var (
+	mountHistory   = map[string][]MountCheck{}
+	mountHistoryMu sync.Mutex
+)
+
+// validate fills in defaults and checks the monitor describes a target
+func (m *MountMonitor) validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch m.Kind {
+	case MountNFS:
+		if m.Host == "" {
+			return fmt.Errorf("host is required")
+		}
+	case MountSMB:
+		if m.Host == "" {
+			return fmt.Errorf("host is required")
+		}
+		if m.Port == 0 {
+			m.Port = 445
+		}
+	case MountPath:
+		if !filepath.IsAbs(m.Path) || !probePathAllowed(filepath.Clean(m.Path)) {
+			return fmt.Errorf("path must be absolute and under GAGOS_STORAGE_PROBE_PATHS")
+		}
+	default:
+		return fmt.Errorf("kind must be nfs, smb or path")
+	}
+	if m.IntervalSeconds == 0 {
+		m.IntervalSeconds = int(DefaultMountInterval.Seconds())
+	}
+	if m.IntervalSeconds < int(MinMountInterval.Seconds()) {
+		return fmt.Errorf("interval must be at least %s", MinMountInterval)
+	}
+	if m.TimeoutSeconds <= 0 || m.TimeoutSeconds > 60 {
+		m.TimeoutSeconds = 10
+	}
+	return nil
+}
+
+// CreateMountMonitor stores a new monitor; it first runs on the next tick
+func CreateMountMonitor(m *MountMonitor) (*MountMonitor, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	m.ID = "mount-" + hex.EncodeToString(id)
+	m.CreatedAt = time.Now()
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.GetBackend().Set(storage.BucketMountMonitors, m.ID, data); err != nil {
+		return nil, fmt.Errorf("failed to save mount monitor: %w", err)
+	}
+	log.Info().Str("id", m.ID).Str("name", m.Name).Str("kind", m.Kind).Msg("Mount monitor created")
+	return m, nil
+}
+
+// DeleteMountMonitor removes a monitor and its history
+func DeleteMountMonitor(id string) error {
+	if err := storage.GetBackend().Delete(storage.BucketMountMonitors, id); err != nil {
+		return fmt.Errorf("failed to delete mount monitor: %w", err)
+	}
+	mountHistoryMu.Lock()
+	delete(mountHistory, id)
+	mountHistoryMu.Unlock()
+	log.Info().Str("id", id).Msg("Mount monitor deleted")
+	return nil
+}
+
+func getMountMonitor(id string) (*MountMonitor, error) {
+	data, err := storage.GetBackend().Get(storage.BucketMountMonitors, id)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("mount monitor not found: %s", id)
+	}
+	var m MountMonitor
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func listMountMonitors() ([]MountMonitor, error) {
+	dataList, err := storage.GetBackend().List(storage.BucketMountMonitors)
+	if err != nil {
+		return nil, err
+	}
+	monitors := make([]MountMonitor, 0, len(dataList))
+	for _, data := range dataList {
+		var m MountMonitor
+		if err := json.Unmarshal(data, &m); err != nil {
+			log.Warn().Err(err).Msg("Failed to unmarshal mount monitor")
+			continue
+		}
+		monitors = append(monitors, m)
+	}
+	sort.Slice(monitors, func(i, j int) bool { return monitors[i].Name < monitors[j].Name })
+	return monitors, nil
+}
+
+func mountStatus(m MountMonitor) MountMonitorStatus {
+	mountHistoryMu.Lock()
+	checks := append([]MountCheck{}, mountHistory[m.ID]...)
+	mountHistoryMu.Unlock()
+	status := MountMonitorStatus{MountMonitor: m, Checks: checks}
+	if len(checks) > 0 {
+		status.Healthy = &checks[0].OK
+	}
+	return status
+}
+
+// ListMountMonitors returns the monitors with their latest check only
+func ListMountMonitors() ([]MountMonitorStatus, error) {
+	monitors, err := listMountMonitors()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]MountMonitorStatus, 0, len(monitors))
+	for _, m := range monitors {
+		status := mountStatus(m)
+		if len(status.Checks) > 1 {
+			status.Checks = status.Checks[:1]
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+// GetMountMonitor returns a monitor with its check history
+func GetMountMonitor(id string) (*MountMonitorStatus, error) {
+	m, err := getMountMonitor(id)
+	if err != nil {
+		return nil, err
+	}
+	status := mountStatus(*m)
+	return &status, nil
+}
+
+// RunMountMonitor runs a monitor now and records the result
+func RunMountMonitor(id string) (*MountCheck, error) {
+	m, err := getMountMonitor(id)
+	if err != nil {
+		return nil, err
+	}
+	check := runMountCheck(*m)
+	return &check, nil
+}
+
+// runMountCheck runs the monitor's check, records it and logs when the
+// monitor turns unhealthy or recovers
+func runMountCheck(m MountMonitor) MountCheck {
+	timeout := time.Duration(m.TimeoutSeconds) * time.Second
+	check := MountCheck{Time: time.Now()}
+	switch m.Kind {
+	case MountNFS:
+		r := CheckNFS(m.Host, m.Export, timeout)
+		check.OK = r.Reachable && r.Error == "" && (m.Export == "" || r.Mountable || r.MountdPort == 0)
+		check.Error, check.Duration, check.Result = r.Error, r.Duration, r
+		if check.Error == "" && !check.OK {
+			check.Error = "export not mountable: " + r.MountStatus
+		}
+	case MountSMB:
+		r := CheckSMB(m.Host, m.Port, timeout)
+		check.OK = r.Reachable && r.Error == ""
+		check.Error, check.Duration, check.Result = r.Error, r.Duration, r
+	case MountPath:
+		r := probePathWithTimeout(m.Path, timeout)
+		check.OK = r.OK
+		check.Error, check.Duration, check.Result = r.Error, r.Duration, r
+	}
+
+	mountHistoryMu.Lock()
+	previous := mountHistory[m.ID]
+	mountHistory[m.ID] = append([]MountCheck{check}, previous...)
+	if len(mountHistory[m.ID]) > mountHistorySize {
+		mountHistory[m.ID] = mountHistory[m.ID][:mountHistorySize]
+	}
+	mountHistoryMu.Unlock()
+
+	wasOK := len(previous) == 0 || previous[0].OK
+	switch {
+	case wasOK && !check.OK:
+		log.Warn().Str("id", m.ID).Str("name", m.Name).Str("kind", m.Kind).Str("error", check.Error).Msg("Mount check failing")
+	case !wasOK && check.OK:
+		log.Info().Str("id", m.ID).Str("name", m.Name).Str("kind", m.Kind).Msg("Mount check recovered")
+	}
+	return check
+}
+
+// probePathWithTimeout gives up waiting on a probe after timeout; a hung
+// mount blocks the probe itself in the kernel, so it is left running
+func probePathWithTimeout(path string, timeout time.Duration) PathProbeResult {
+	done := make(chan PathProbeResult, 1)
+	go func() { done <- ProbePath(path, DefaultProbeSize) }()
+	select {
+	case r := <-done:
+		return r
+	case <-time.After(timeout):
+		return PathProbeResult{
+			Path:     path,
+			Size:     DefaultProbeSize,
+			Error:    fmt.Sprintf("no response within %s, the mount may be hung", timeout),
+			Duration: float64(timeout.Microseconds()) / 1000.0,
+		}
+	}
+}
+
+// StartMountMonitors runs due monitors every 10 seconds
+func StartMountMonitors() {
+	if storage.GetBackend() == nil {
+		log.Warn().Msg("Mount monitors disabled: storage unavailable")
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+		running := map[string]bool{}
+		var mu sync.Mutex
+		for range ticker.C {
+			monitors, err := listMountMonitors()
+			if err != nil {
+				log.Warn().Err(err).Msg("Failed to list mount monitors")
+				continue
+			}
+			for _, m := range monitors {
+				mountHistoryMu.Lock()
+				last := mountHistory[m.ID]
+				mountHistoryMu.Unlock()
+				if len(last) > 0 && time.Since(last[0].Time) < time.Duration(m.IntervalSeconds)*time.Second {
+					continue
+				}
+				mu.Lock()
+				busy := running[m.ID]
+				running[m.ID] = true
+				mu.Unlock()
+				if busy {
+					continue
+				}
+				m := m
+				go func() {
+					runMountCheck(m)
+					mu.Lock()
+					delete(running, m.ID)
+					mu.Unlock()
+				}()
+			}
+		}
+	}()
+}
@@ -0,0 +1,424 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/gaga951/gagos/internal/egress"
+)
+
+// NFS checks speak ONC RPC over TCP: the portmapper finds mountd, mountd
+// lists the exports (showmount -e) and answers a trial mount, and a NULL
+// call on the NFS port shows which versions the server speaks.
+
+const (
+	rpcPortmapper = 100000
+	rpcNFS        = 100003
+	rpcMount      = 100005
+
+	mountProcMnt    = 1
+	mountProcUmnt   = 3
+	mountProcExport = 5
+
+	rpcAuthNone = 0
+	rpcAuthSys  = 1
+)
+
+// NFSExport is one line of showmount -e
+type NFSExport struct {
+	Path   string   `json:"path"`
+	Groups []string `json:"groups,omitempty"` // clients allowed, empty for everyone
+}
+
+// NFSCheckResult reports whether an NFS server and an export are usable
+type NFSCheckResult struct {
+	Host        string      `json:"host"`
+	Reachable   bool        `json:"reachable"`             // the NFS port answered
+	Versions    []int       `json:"versions,omitempty"`    // NFS versions the server speaks
+	MountdPort  int         `json:"mountd_port,omitempty"` // 0 for NFSv4-only servers
+	Exports     []NFSExport `json:"exports,omitempty"`
+	Export      string      `json:"export,omitempty"`
+	Exported    bool        `json:"exported,omitempty"`  // export is in the export list
+	Mountable   bool        `json:"mountable,omitempty"` // mountd granted a trial mount of export
+	MountStatus string      `json:"mount_status,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	Duration    float64     `json:"duration_ms"`
+}
+
+// CheckNFS probes host's NFS service and, when export is set, whether it
+// can be mounted. NFSv4-only servers have no mountd, so for them only the
+// NFS port is checked.
+func CheckNFS(host, export string, timeout time.Duration) NFSCheckResult {
+	start := time.Now()
+	result := NFSCheckResult{Host: host, Export: export}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result.Versions, result.Error = nfsVersions(ctx, host)
+	result.Reachable = result.Error == ""
+
+	port, err := rpcGetPort(ctx, host, rpcMount, 3)
+	if err != nil || port == 0 {
+		if result.Reachable && export != "" {
+			result.MountStatus = "no mountd; NFSv4-only servers cannot be checked for exports"
+		}
+		result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
+		return result
+	}
+	result.MountdPort = port
+
+	if result.Exports, err = nfsExports(ctx, host, port); err != nil {
+		result.Error = fmt.Sprintf("Export list failed: %v", err)
+	}
+	if export != "" {
+		for _, e := range result.Exports {
+			if e.Path == export {
+				result.Exported = true
+			}
+		}
+		result.Mountable, result.MountStatus = nfsTrialMount(ctx, host, port, export)
+	}
+
+	result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
+	return result
+}
+
+// nfsVersions sends NULL calls to port 2049; a version mismatch reply names
+// the range the server supports
+func nfsVersions(ctx context.Context, host string) ([]int, string) {
+	conn, err := rpcDial(ctx, host, 2049, false)
+	if err != nil {
+		return nil, fmt.Sprintf("Connection failed: %v", err)
+	}
+	defer conn.Close()
+
+	var versions []int
+	for _, v := range []uint32{3, 4} {
+		_, err := rpcCall(conn, rpcNFS, v, 0, rpcAuthNone, nil)
+		var mismatch *rpcMismatchError
+		switch {
+		case err == nil:
+			versions = append(versions, int(v))
+		case errors.As(err, &mismatch):
+			versions = versions[:0]
+			for i := mismatch.low; i <= mismatch.high && i <= 4; i++ {
+				versions = append(versions, int(i))
+			}
+			return versions, ""
+		default:
+			return versions, fmt.Sprintf("NFS NULL call failed: %v", err)
+		}
+	}
+	return versions, ""
+}
+
+// rpcGetPort asks the portmapper for the TCP port of a program
+func rpcGetPort(ctx context.Context, host string, prog, vers uint32) (int, error) {
+	conn, err := rpcDial(ctx, host, 111, false)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var args xdrWriter
+	args.uint32(prog)
+	args.uint32(vers)
+	args.uint32(syscall.IPPROTO_TCP)
+	args.uint32(0)
+	reply, err := rpcCall(conn, rpcPortmapper, 2, 3, rpcAuthNone, args.buf)
+	if err != nil {
+		return 0, err
+	}
+	port, err := reply.uint32()
+	return int(port), err
+}
+
+func nfsExports(ctx context.Context, host string, port int) ([]NFSExport, error) {
+	conn, err := rpcDial(ctx, host, port, false)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	reply, err := rpcCall(conn, rpcMount, 3, mountProcExport, rpcAuthNone, nil)
+	if err != nil {
+		return nil, err
+	}
+	var exports []NFSExport
+	for {
+		more, err := reply.uint32()
+		if err != nil {
+			return nil, err
+		}
+		if more == 0 {
+			return exports, nil
+		}
+		var e NFSExport
+		if e.Path, err = reply.string(); err != nil {
+			return nil, err
+		}
+		for {
+			more, err := reply.uint32()
+			if err != nil {
+				return nil, err
+			}
+			if more == 0 {
+				break
+			}
+			group, err := reply.string()
+			if err != nil {
+				return nil, err
+			}
+			e.Groups = append(e.Groups, group)
+		}
+		exports = append(exports, e)
+	}
+}
+
+// mountStatNames names the mountstat3 codes
+var mountStatNames = map[uint32]string{
+	0:     "ok",
+	1:     "not owner",
+	2:     "no such file or directory",
+	5:     "I/O error",
+	13:    "permission denied",
+	20:    "not a directory",
+	22:    "invalid argument",
+	63:    "filename too long",
+	10004: "operation not supported",
+	10006: "server fault",
+}
+
+// nfsTrialMount asks mountd to mount export as root and unmounts it again.
+// Servers with the default "secure" option only accept mounts from ports
+// below 1024, which need root or CAP_NET_BIND_SERVICE here.
+func nfsTrialMount(ctx context.Context, host string, port int, export string) (bool, string) {
+	conn, err := rpcDial(ctx, host, port, true)
+	if err != nil {
+		return false, fmt.Sprintf("connection failed: %v", err)
+	}
+	defer conn.Close()
+
+	var args xdrWriter
+	args.string(export)
+	reply, err := rpcCall(conn, rpcMount, 3, mountProcMnt, rpcAuthSys, args.buf)
+	if err != nil {
+		return false, err.Error()
+	}
+	code, err := reply.uint32()
+	if err != nil {
+		return false, err.Error()
+	}
+	if code != 0 {
+		status, ok := mountStatNames[code]
+		if !ok {
+			status = fmt.Sprintf("error %d", code)
+		}
+		if code == 1 || code == 13 {
+			if local, ok := conn.LocalAddr().(*net.TCPAddr); ok && local.Port >= 1024 {
+				status += " (the export may require a privileged source port)"
+			}
+		}
+		return false, status
+	}
+	rpcCall(conn, rpcMount, 3, mountProcUmnt, rpcAuthSys, args.buf)
+	return true, "ok"
+}
+
+// rpcDial connects to host:port, from a port below 1024 when reserved is
+// set and this process may bind one
+func rpcDial(ctx context.Context, host string, port int, reserved bool) (net.Conn, error) {
+	address := net.JoinHostPort(host, fmt.Sprint(port))
+	dialer := *egress.Default().Dialer(0)
+	if reserved {
+		for local := 1023; local >= 1000; local-- {
+			dialer.LocalAddr = &net.TCPAddr{Port: local}
+			conn, err := dialer.DialContext(ctx, "tcp", address)
+			if err == nil {
+				return rpcDeadline(ctx, conn), nil
+			}
+			if !errors.Is(err, syscall.EADDRINUSE) {
+				break
+			}
+		}
+		dialer.LocalAddr = nil
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return rpcDeadline(ctx, conn), nil
+}
+
+func rpcDeadline(ctx context.Context, conn net.Conn) net.Conn {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return conn
+}
+
+// rpcMismatchError is a PROG_MISMATCH reply with the supported versions
+type rpcMismatchError struct {
+	low, high uint32
+}
+
+func (e *rpcMismatchError) Error() string {
+	return fmt.Sprintf("program version mismatch (server supports %d-%d)", e.low, e.high)
+}
+
+var rpcAcceptErrors = map[uint32]string{
+	1: "program unavailable",
+	3: "procedure unavailable",
+	4: "garbage arguments",
+	5: "system error",
+}
+
+// rpcCall sends one call in a single record and returns the reader
+// positioned at the procedure's results
+func rpcCall(conn net.Conn, prog, vers, proc, auth uint32, args []byte) (*xdrReader, error) {
+	xid := rand.Uint32()
+	var call xdrWriter
+	call.uint32(0) // record mark, filled in below
+	call.uint32(xid)
+	call.uint32(0) // CALL
+	call.uint32(2) // RPC version
+	call.uint32(prog)
+	call.uint32(vers)
+	call.uint32(proc)
+	if auth == rpcAuthSys {
+		var cred xdrWriter
+		cred.uint32(uint32(time.Now().Unix()))
+		cred.string("gagos")
+		cred.uint32(0) // uid
+		cred.uint32(0) // gid
+		cred.uint32(0) // no supplementary groups
+		call.uint32(rpcAuthSys)
+		call.opaque(cred.buf)
+	} else {
+		call.uint32(rpcAuthNone)
+		call.uint32(0)
+	}
+	call.uint32(rpcAuthNone) // verifier
+	call.uint32(0)
+	call.buf = append(call.buf, args...)
+	binary.BigEndian.PutUint32(call.buf, 0x80000000|uint32(len(call.buf)-4))
+	if _, err := conn.Write(call.buf); err != nil {
+		return nil, err
+	}
+
+	// Replies may span several record fragments
+	var reply []byte
+	for {
+		var mark [4]byte
+		if _, err := io.ReadFull(conn, mark[:]); err != nil {
+			return nil, err
+		}
+		n := binary.BigEndian.Uint32(mark[:])
+		if len(reply)+int(n&0x7fffffff) > 1<<20 {
+			return nil, fmt.Errorf("RPC reply too large")
+		}
+		frag := make([]byte, n&0x7fffffff)
+		if _, err := io.ReadFull(conn, frag); err != nil {
+			return nil, err
+		}
+		reply = append(reply, frag...)
+		if n&0x80000000 != 0 {
+			break
+		}
+	}
+
+	r := &xdrReader{buf: reply}
+	gotXID, _ := r.uint32()
+	msgType, _ := r.uint32()
+	replyStat, err := r.uint32()
+	if err != nil || gotXID != xid || msgType != 1 {
+		return nil, fmt.Errorf("malformed RPC reply")
+	}
+	if replyStat != 0 {
+		if reason, _ := r.uint32(); reason == 1 {
+			return nil, fmt.Errorf("RPC authentication rejected")
+		}
+		return nil, fmt.Errorf("RPC call denied")
+	}
+	r.uint32() // verifier flavor
+	if _, err := r.opaque(); err != nil {
+		return nil, err
+	}
+	acceptStat, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+	switch acceptStat {
+	case 0:
+		return r, nil
+	case 2:
+		low, _ := r.uint32()
+		high, _ := r.uint32()
+		return nil, &rpcMismatchError{low: low, high: high}
+	}
+	if msg, ok := rpcAcceptErrors[acceptStat]; ok {
+		return nil, fmt.Errorf("RPC %s", msg)
+	}
+	return nil, fmt.Errorf("RPC error %d", acceptStat)
+}
+
+// xdrWriter and xdrReader cover the XDR types the calls above need
+type xdrWriter struct {
+	buf []byte
+}
+
+func (w *xdrWriter) uint32(v uint32) {
+	w.buf = binary.BigEndian.AppendUint32(w.buf, v)
+}
+
+func (w *xdrWriter) opaque(b []byte) {
+	w.uint32(uint32(len(b)))
+	w.buf = append(w.buf, b...)
+	w.buf = append(w.buf, make([]byte, (4-len(b)%4)%4)...)
+}
+
+func (w *xdrWriter) string(s string) {
+	w.opaque([]byte(s))
+}
+
+type xdrReader struct {
+	buf []byte
+}
+
+func (r *xdrReader) uint32() (uint32, error) {
+	if len(r.buf) < 4 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	v := binary.BigEndian.Uint32(r.buf)
+	r.buf = r.buf[4:]
+	return v, nil
+}
+
+func (r *xdrReader) opaque() ([]byte, error) {
+	n, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+	padded := int(n) + (4-int(n)%4)%4
+	if n > 1<<20 || len(r.buf) < padded {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[padded:]
+	return b, nil
+}
+
+func (r *xdrReader) string() (string, error) {
+	b, err := r.opaque()
+	return string(b), err
+}
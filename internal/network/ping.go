@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gaga951/gagos/internal/egress"
 	"golang.org/x/net/icmp"
 	"golang.org/x/net/ipv4"
 	"golang.org/x/net/ipv6"
@@ -62,6 +63,11 @@ func Ping(host string, count int, timeout time.Duration) PingResult {
 	}
 	result.IP = targetIP.String()
 
+	if err := egress.Default().Check(targetIP, 0); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
 	// Try ICMP first, fall back to TCP connectivity check
 	icmpSuccess := false
 
@@ -127,7 +133,7 @@ func Ping(host string, count int, timeout time.Duration) PingResult {
 		for i := 0; i < count; i++ {
 			for _, port := range ports {
 				start := time.Now()
-				conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", targetIP.String(), port), timeout/time.Duration(count))
+				conn, err := egress.Default().Dialer(timeout/time.Duration(count)).Dial("tcp", net.JoinHostPort(targetIP.String(), strconv.Itoa(port)))
 				rtt := time.Since(start)
 
 				if err == nil {
@@ -339,8 +345,8 @@ func CheckPort(host string, port int, timeout time.Duration) PortCheckResult {
 		Protocol: "tcp",
 	}
 
-	address := fmt.Sprintf("%s:%d", host, port)
-	conn, err := net.DialTimeout("tcp", address, timeout)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := egress.Default().Dialer(timeout).Dial("tcp", address)
 	result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
 
 	if err != nil {
@@ -463,6 +469,11 @@ func Traceroute(host string, maxHops int, timeout time.Duration) TracerouteResul
 	}
 	result.TargetIP = targetIP.String()
 
+	if err := egress.Default().Check(targetIP, 0); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
 	// Try tracepath first (doesn't need raw sockets), fallback to traceroute
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
@@ -663,7 +674,8 @@ func HTTPCheck(url string, timeout time.Duration, followRedirects bool) HTTPChec
 	}
 
 	client := &http.Client{
-		Timeout: timeout,
+		Timeout:   timeout,
+		Transport: egress.Default().Transport(nil),
 	}
 
 	if !followRedirects {
@@ -0,0 +1,134 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/gaga951/gagos/internal/egress"
+)
+
+// SMBCheckResult is the outcome of an SMB2 negotiate
+type SMBCheckResult struct {
+	Host            string  `json:"host"`
+	Port            int     `json:"port"`
+	Reachable       bool    `json:"reachable"`
+	Dialect         string  `json:"dialect,omitempty"`
+	SigningRequired bool    `json:"signing_required"`
+	ServerGUID      string  `json:"server_guid,omitempty"`
+	ServerTime      string  `json:"server_time,omitempty"`
+	MaxReadSize     uint32  `json:"max_read_size,omitempty"`
+	MaxWriteSize    uint32  `json:"max_write_size,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	Duration        float64 `json:"duration_ms"`
+}
+
+// smbDialects are offered in the negotiate request. 3.1.1 is left out
+// because it needs negotiate contexts; servers that speak it accept 3.0.2.
+var smbDialects = map[uint16]string{
+	0x0202: "2.0.2",
+	0x0210: "2.1",
+	0x0300: "3.0",
+	0x0302: "3.0.2",
+}
+
+// CheckSMB sends an SMB2 NEGOTIATE and reports the dialect and security
+// mode the server picks. It does not log in, so share permissions are not
+// checked.
+func CheckSMB(host string, port int, timeout time.Duration) SMBCheckResult {
+	start := time.Now()
+	if port == 0 {
+		port = 445
+	}
+	result := SMBCheckResult{Host: host, Port: port}
+
+	conn, err := egress.Default().Dialer(timeout).Dial("tcp", net.JoinHostPort(host, fmt.Sprint(port)))
+	if err != nil {
+		result.Error = fmt.Sprintf("Connection failed: %v", err)
+		result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
+		return result
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	result.Reachable = true
+
+	if err := smbNegotiate(conn, &result); err != nil {
+		result.Error = err.Error()
+	}
+	result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
+	return result
+}
+
+func smbNegotiate(conn net.Conn, result *SMBCheckResult) error {
+	// SMB2 header: protocol id, header size, command 0 (NEGOTIATE), one
+	// credit requested; the rest is zero for the first message
+	header := make([]byte, 64)
+	copy(header, "\xfeSMB")
+	binary.LittleEndian.PutUint16(header[4:], 64)
+	binary.LittleEndian.PutUint16(header[18:], 1)
+
+	dialects := []uint16{0x0202, 0x0210, 0x0300, 0x0302}
+	body := make([]byte, 36, 36+2*len(dialects))
+	binary.LittleEndian.PutUint16(body[0:], 36) // structure size
+	binary.LittleEndian.PutUint16(body[2:], uint16(len(dialects)))
+	binary.LittleEndian.PutUint16(body[4:], 1) // signing enabled
+	copy(body[12:28], "gagos-smb-check\x00")   // client GUID
+	for _, d := range dialects {
+		body = binary.LittleEndian.AppendUint16(body, d)
+	}
+
+	msg := append(header, body...)
+	// Direct TCP transport: a zero byte and a 24-bit length
+	frame := binary.BigEndian.AppendUint32(nil, uint32(len(msg)))
+	if _, err := conn.Write(append(frame, msg...)); err != nil {
+		return fmt.Errorf("negotiate failed: %w", err)
+	}
+
+	var length [4]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return fmt.Errorf("no negotiate response: %w", err)
+	}
+	n := binary.BigEndian.Uint32(length[:]) & 0xffffff
+	if n < 4 || n > 1<<16 {
+		return fmt.Errorf("unexpected negotiate response of %d bytes", n)
+	}
+	resp := make([]byte, n)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return fmt.Errorf("negotiate response cut short: %w", err)
+	}
+	if string(resp[:4]) == "\xffSMB" {
+		return fmt.Errorf("server only speaks SMB1")
+	}
+	if string(resp[:4]) != "\xfeSMB" {
+		return fmt.Errorf("not an SMB server")
+	}
+	if len(resp) < 64+65 {
+		return fmt.Errorf("negotiate response cut short")
+	}
+	if status := binary.LittleEndian.Uint32(resp[8:]); status != 0 {
+		return fmt.Errorf("negotiate refused (NTSTATUS 0x%08x)", status)
+	}
+
+	r := resp[64:]
+	result.SigningRequired = binary.LittleEndian.Uint16(r[2:])&0x02 != 0
+	dialect := binary.LittleEndian.Uint16(r[4:])
+	if result.Dialect = smbDialects[dialect]; result.Dialect == "" {
+		result.Dialect = fmt.Sprintf("0x%04x", dialect)
+	}
+	g := r[8:24]
+	result.ServerGUID = fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(g[0:]), binary.LittleEndian.Uint16(g[4:]), binary.LittleEndian.Uint16(g[6:]), g[8:10], g[10:16])
+	result.MaxReadSize = binary.LittleEndian.Uint32(r[32:])
+	result.MaxWriteSize = binary.LittleEndian.Uint32(r[36:])
+	// FILETIME: 100ns intervals since 1601
+	const epochDelta = 116444736000000000
+	if ft := binary.LittleEndian.Uint64(r[40:]); ft > epochDelta {
+		result.ServerTime = time.Unix(0, int64(ft-epochDelta)*100).UTC().Format(time.RFC3339)
+	}
+	return nil
+}
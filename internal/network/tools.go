@@ -10,8 +10,12 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/gaga951/gagos/internal/egress"
 )
 
 // Telnet - TCP connection with send/receive
@@ -31,8 +35,8 @@ func TelnetConnect(host string, port int, command string, timeout time.Duration)
 		Port: port,
 	}
 
-	address := fmt.Sprintf("%s:%d", host, port)
-	conn, err := net.DialTimeout("tcp", address, timeout)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := egress.Default().Dialer(timeout).Dial("tcp", address)
 	if err != nil {
 		result.Error = fmt.Sprintf("Connection failed: %v", err)
 		result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
@@ -100,6 +104,12 @@ type WhoisResult struct {
 	Duration float64 `json:"duration_ms"`
 }
 
+// WhoisAvailable reports whether whois can reach a server: always, unless
+// offline without GAGOS_WHOIS_SERVER
+func WhoisAvailable() bool {
+	return !egress.Offline() || os.Getenv("GAGOS_WHOIS_SERVER") != ""
+}
+
 func Whois(query string, timeout time.Duration) WhoisResult {
 	start := time.Now()
 	result := WhoisResult{
@@ -141,9 +151,23 @@ func Whois(query string, timeout time.Duration) WhoisResult {
 		server = "whois.arin.net"
 	}
 
+	// An internal whois mirror replaces the public servers, which an
+	// offline install cannot reach
+	if mirror := os.Getenv("GAGOS_WHOIS_SERVER"); mirror != "" {
+		server = mirror
+	} else if egress.Offline() {
+		result.Error = fmt.Sprintf("whois is %v: set GAGOS_WHOIS_SERVER to an internal server", egress.ErrOffline)
+		result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
+		return result
+	}
+
 	result.Server = server
 
-	conn, err := net.DialTimeout("tcp", server+":43", timeout)
+	address := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		address = net.JoinHostPort(server, "43")
+	}
+	conn, err := egress.Default().Dialer(timeout).Dial("tcp", address)
 	if err != nil {
 		result.Error = fmt.Sprintf("Connection to %s failed: %v", server, err)
 		result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
@@ -214,7 +238,7 @@ func CheckSSL(host string, port int, timeout time.Duration) SSLCertResult {
 
 	address := fmt.Sprintf("%s:%d", host, port)
 
-	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", address, &tls.Config{
+	conn, err := tls.DialWithDialer(egress.Default().Dialer(timeout), "tcp", address, &tls.Config{
 		InsecureSkipVerify: true, // We want to check the cert even if invalid
 	})
 	if err != nil {
@@ -268,11 +292,14 @@ type CurlResult struct {
 	RedirectURL   string              `json:"redirect_url,omitempty"`
 	TLSVersion    string              `json:"tls_version,omitempty"`
 	Protocol      string              `json:"protocol,omitempty"`
+	Proxy         string              `json:"proxy,omitempty"` // proxy the request went through
 	Duration      float64             `json:"duration_ms"`
 	Error         string              `json:"error,omitempty"`
 }
 
-func Curl(url string, method string, headers map[string]string, body string, timeout time.Duration, followRedirects bool, includeBody bool) CurlResult {
+// Curl sends an HTTP request. proxy overrides the curl proxy setting: a
+// proxy URL, "direct", or empty for GAGOS_PROXY_CURL or GAGOS_PROXY.
+func Curl(url string, method string, headers map[string]string, body string, timeout time.Duration, followRedirects bool, includeBody bool, proxy string) CurlResult {
 	start := time.Now()
 	result := CurlResult{
 		URL:     url,
@@ -285,11 +312,18 @@ func Curl(url string, method string, headers map[string]string, body string, tim
 		result.Method = method
 	}
 
+	via, err := egress.ProxyFor(egress.ProxyCurl, proxy)
+	if err != nil {
+		result.Error = err.Error()
+		result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
+		return result
+	}
+
 	client := &http.Client{
 		Timeout: timeout,
-		Transport: &http.Transport{
+		Transport: egress.Default().TransportVia(&http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: false},
-		},
+		}, via),
 	}
 
 	if !followRedirects {
@@ -320,6 +354,10 @@ func Curl(url string, method string, headers map[string]string, body string, tim
 		req.Header.Set("User-Agent", "GAGOS/1.0 (Network Multi-Tool)")
 	}
 
+	if u := via.For(req.URL); u != nil {
+		result.Proxy = u.Redacted()
+	}
+
 	resp, err := client.Do(req)
 	result.Duration = float64(time.Since(start).Microseconds()) / 1000.0
 
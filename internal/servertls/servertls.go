@@ -0,0 +1,219 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+// Package servertls serves GAGOS over TLS. The certificate comes from files
+// (GAGOS_TLS_CERT and GAGOS_TLS_KEY) or from a kubernetes.io/tls Secret
+// (GAGOS_TLS_SECRET), and is reloaded when it changes, so that a certificate
+// renewed by cert-manager is picked up without a restart. With
+// GAGOS_TLS_CLIENT_CA, client certificates signed by that CA are verified
+// and authenticate API requests.
+package servertls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/gaga951/gagos/internal/k8s"
+)
+
+// reloadInterval is how often the certificate source is checked for changes
+const reloadInterval = time.Minute
+
+// Config is the TLS setup read from the environment
+type Config struct {
+	CertFile    string
+	KeyFile     string
+	Secret      string // namespace/name of a kubernetes.io/tls Secret
+	ClientCA    string // PEM bundle that client certificates are verified against
+	RequireCert bool   // reject API requests without a verified client certificate
+}
+
+// FromEnv reads GAGOS_TLS_CERT, GAGOS_TLS_KEY, GAGOS_TLS_SECRET,
+// GAGOS_TLS_CLIENT_CA and GAGOS_TLS_CLIENT_AUTH
+func FromEnv() (*Config, error) {
+	cfg := &Config{
+		CertFile: os.Getenv("GAGOS_TLS_CERT"),
+		KeyFile:  os.Getenv("GAGOS_TLS_KEY"),
+		Secret:   os.Getenv("GAGOS_TLS_SECRET"),
+		ClientCA: os.Getenv("GAGOS_TLS_CLIENT_CA"),
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, fmt.Errorf("GAGOS_TLS_CERT and GAGOS_TLS_KEY must be set together")
+	}
+	if cfg.CertFile != "" && cfg.Secret != "" {
+		return nil, fmt.Errorf("set either GAGOS_TLS_CERT/GAGOS_TLS_KEY or GAGOS_TLS_SECRET, not both")
+	}
+	if cfg.Secret != "" && !strings.Contains(cfg.Secret, "/") {
+		return nil, fmt.Errorf("GAGOS_TLS_SECRET must be namespace/name")
+	}
+	switch mode := os.Getenv("GAGOS_TLS_CLIENT_AUTH"); mode {
+	case "", "verify":
+	case "require":
+		cfg.RequireCert = true
+	default:
+		return nil, fmt.Errorf("GAGOS_TLS_CLIENT_AUTH must be verify or require, got %q", mode)
+	}
+	if cfg.ClientCA == "" && cfg.RequireCert {
+		return nil, fmt.Errorf("GAGOS_TLS_CLIENT_AUTH=require needs GAGOS_TLS_CLIENT_CA")
+	}
+	if cfg.ClientCA != "" && !cfg.Enabled() {
+		return nil, fmt.Errorf("GAGOS_TLS_CLIENT_CA needs a server certificate")
+	}
+	return cfg, nil
+}
+
+// Enabled reports whether a certificate source is configured
+func (c *Config) Enabled() bool {
+	return c.CertFile != "" || c.Secret != ""
+}
+
+// Source describes where the certificate comes from, for logs
+func (c *Config) Source() string {
+	if c.Secret != "" {
+		return "secret " + c.Secret
+	}
+	return c.CertFile
+}
+
+// reloader holds the current certificate and refreshes it
+type reloader struct {
+	cfg *Config
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	version string // file modification times or the Secret's resourceVersion
+}
+
+// TLSConfig loads the certificate and returns a tls.Config that serves it.
+// The certificate is reloaded in the background until ctx is cancelled.
+func (c *Config) TLSConfig(ctx context.Context) (*tls.Config, error) {
+	r := &reloader{cfg: c}
+	if err := r.reload(ctx); err != nil {
+		return nil, err
+	}
+	go r.run(ctx)
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.cert, nil
+		},
+	}
+	if c.ClientCA != "" {
+		pem, err := os.ReadFile(c.ClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.ClientCA)
+		}
+		tlsConfig.ClientCAs = pool
+		// Required certificates are enforced per request, so that health
+		// probes without one still get through
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+func (r *reloader) run(ctx context.Context) {
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.reload(ctx); err != nil {
+				log.Error().Err(err).Str("source", r.cfg.Source()).Msg("Failed to reload TLS certificate, keeping the current one")
+			}
+		}
+	}
+}
+
+// reload loads the certificate when its source changed
+func (r *reloader) reload(ctx context.Context) error {
+	var certPEM, keyPEM []byte
+	var version string
+	if r.cfg.Secret != "" {
+		clientset := k8s.GetClient()
+		if clientset == nil {
+			return fmt.Errorf("kubernetes client not initialized")
+		}
+		namespace, name, _ := strings.Cut(r.cfg.Secret, "/")
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get TLS secret: %w", err)
+		}
+		version = secret.ResourceVersion
+		if version == r.loadedVersion() {
+			return nil
+		}
+		certPEM, keyPEM = secret.Data["tls.crt"], secret.Data["tls.key"]
+		if len(certPEM) == 0 || len(keyPEM) == 0 {
+			return fmt.Errorf("secret %s has no tls.crt or tls.key", r.cfg.Secret)
+		}
+	} else {
+		certInfo, err := os.Stat(r.cfg.CertFile)
+		if err != nil {
+			return err
+		}
+		keyInfo, err := os.Stat(r.cfg.KeyFile)
+		if err != nil {
+			return err
+		}
+		version = certInfo.ModTime().String() + "/" + keyInfo.ModTime().String()
+		if version == r.loadedVersion() {
+			return nil
+		}
+		if certPEM, err = os.ReadFile(r.cfg.CertFile); err != nil {
+			return err
+		}
+		if keyPEM, err = os.ReadFile(r.cfg.KeyFile); err != nil {
+			return err
+		}
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("invalid TLS certificate: %w", err)
+	}
+	if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil {
+		cert.Leaf = leaf
+	}
+
+	r.mu.Lock()
+	first := r.cert == nil
+	r.cert, r.version = &cert, version
+	r.mu.Unlock()
+
+	event := log.Info().Str("source", r.cfg.Source())
+	if cert.Leaf != nil {
+		event = event.Str("subject", cert.Leaf.Subject.String()).Time("not_after", cert.Leaf.NotAfter)
+	}
+	if first {
+		event.Msg("TLS certificate loaded")
+	} else {
+		event.Msg("TLS certificate reloaded")
+	}
+	return nil
+}
+
+func (r *reloader) loadedVersion() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.version
+}
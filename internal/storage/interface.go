@@ -37,6 +37,23 @@ const (
 	BucketFreestyleBuilds = "freestyle_builds"
 	BucketNotifications   = "notifications"
 	BucketGitCredentials  = "git_credentials"
+	BucketDBMigrations    = "db_migrations"
+	BucketDBResultPolicy  = "db_result_policies"
+	BucketDBImports       = "db_imports"
+	BucketDBImportErrors  = "db_import_errors"
+	BucketImageScans      = "k8s_image_scans"
+	BucketMountMonitors   = "mount_monitors"
+	BucketAuditLog        = "audit_log"
+	BucketConfigHistory   = "config_history"
+	BucketUsage           = "usage"
+	BucketRegistryCreds   = "registry_credentials"
+	BucketSCMPolls        = "cicd_scm_polls"
+	BucketDeployments     = "cicd_deployments"
+	BucketSecretVariables = "cicd_secret_variables"
+	BucketTestReports     = "cicd_test_reports"
+	BucketCoverage        = "cicd_coverage"
+	BucketJobTemplates    = "cicd_job_templates"
+	BucketWebhookTokenUse = "cicd_webhook_token_use"
 )
 
 // AllBuckets returns all bucket names
@@ -44,6 +61,10 @@ func AllBuckets() []string {
 	return []string{
 		BucketNotepad, BucketPipelines, BucketRuns, BucketArtifacts, BucketPreferences,
 		BucketSSHHosts, BucketFreestyleJobs, BucketFreestyleBuilds, BucketNotifications,
-		BucketGitCredentials,
+		BucketGitCredentials, BucketDBMigrations, BucketDBResultPolicy, BucketDBImports,
+		BucketDBImportErrors, BucketImageScans, BucketMountMonitors, BucketAuditLog,
+		BucketConfigHistory, BucketUsage, BucketRegistryCreds, BucketSCMPolls,
+		BucketDeployments, BucketSecretVariables, BucketTestReports, BucketCoverage,
+		BucketJobTemplates, BucketWebhookTokenUse,
 	}
 }
@@ -0,0 +1,302 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+// Package subsystems starts the parts of GAGOS that depend on something
+// outside the process, such as the Kubernetes API server or the storage
+// backend, and tracks whether each one is usable. A subsystem that fails to
+// start is retried with backoff, so GAGOS recovers when the dependency comes
+// back instead of needing a restart. A subsystem only starts once the
+// subsystems it depends on are ready.
+package subsystems
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Names of the subsystems registered by the server
+const (
+	Kubernetes    = "kubernetes"
+	Storage       = "storage"
+	Scheduler     = "cicd-scheduler"
+	Notifications = "notifications"
+	Monitoring    = "monitoring"
+)
+
+// State of a subsystem
+type State string
+
+const (
+	StateWaiting  State = "waiting"  // a dependency is not ready yet
+	StateStarting State = "starting" // Init is running
+	StateReady    State = "ready"
+	StateFailed   State = "failed" // retried at NextRetry
+)
+
+// Retry backoff and health checking
+const (
+	minRetryDelay = 2 * time.Second
+	maxRetryDelay = time.Minute
+	checkInterval = 30 * time.Second
+)
+
+// ErrNotFound is returned for an unknown subsystem name
+var ErrNotFound = errors.New("subsystem not found")
+
+// Subsystem is a part of GAGOS with a fallible start
+type Subsystem struct {
+	Name        string
+	Description string
+	DependsOn   []string
+	// Init starts the subsystem. It is called again after a failure, and
+	// on a manual re-init, so it must be safe to repeat.
+	Init func() error
+	// Check reports whether a ready subsystem still works; optional. A
+	// failed check marks the subsystem failed and retries Init.
+	Check func() error
+}
+
+// Status is the state of a subsystem
+type Status struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	DependsOn   []string   `json:"depends_on,omitempty"`
+	State       State      `json:"state"`
+	Error       string     `json:"error,omitempty"`
+	Attempts    int        `json:"attempts"`
+	LastAttempt *time.Time `json:"last_attempt,omitempty"`
+	NextRetry   *time.Time `json:"next_retry,omitempty"`
+	ReadySince  *time.Time `json:"ready_since,omitempty"`
+}
+
+type entry struct {
+	Subsystem
+	status   Status
+	delay    time.Duration
+	failures int // since the subsystem was last ready
+	timer    *time.Timer
+	initMu   sync.Mutex // serializes Init calls
+}
+
+var (
+	mu      sync.Mutex
+	entries []*entry
+	byName  = make(map[string]*entry)
+	checker sync.Once
+)
+
+// Register adds a subsystem. Dependencies must be registered first.
+func Register(s Subsystem) {
+	mu.Lock()
+	defer mu.Unlock()
+	e := &entry{
+		Subsystem: s,
+		status: Status{
+			Name:        s.Name,
+			Description: s.Description,
+			DependsOn:   s.DependsOn,
+			State:       StateWaiting,
+		},
+		delay: minRetryDelay,
+	}
+	entries = append(entries, e)
+	byName[s.Name] = e
+}
+
+// Start makes a first attempt at every subsystem in registration order and
+// starts the health checks. Subsystems that fail are retried in the
+// background.
+func Start() {
+	mu.Lock()
+	list := append([]*entry(nil), entries...)
+	mu.Unlock()
+	for _, e := range list {
+		attempt(e, false)
+	}
+	checker.Do(func() { go checkLoop() })
+}
+
+// Ready reports whether the subsystem is ready
+func Ready(name string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	e := byName[name]
+	return e != nil && e.status.State == StateReady
+}
+
+// Get returns the status of a subsystem
+func Get(name string) (*Status, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	e := byName[name]
+	if e == nil {
+		return nil, ErrNotFound
+	}
+	status := e.status
+	return &status, nil
+}
+
+// List returns the status of every subsystem in registration order
+func List() []Status {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]Status, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e.status)
+	}
+	return list
+}
+
+// Reinit starts a subsystem again now, also when it is ready, and resets
+// its retry backoff
+func Reinit(name string) (*Status, error) {
+	mu.Lock()
+	e := byName[name]
+	if e != nil {
+		e.delay = minRetryDelay
+	}
+	mu.Unlock()
+	if e == nil {
+		return nil, ErrNotFound
+	}
+	attempt(e, true)
+	return Get(name)
+}
+
+// attempt runs Init unless the subsystem is ready and force is false
+func attempt(e *entry, force bool) {
+	e.initMu.Lock()
+	defer e.initMu.Unlock()
+
+	mu.Lock()
+	if e.status.State == StateReady && !force {
+		mu.Unlock()
+		return
+	}
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	e.status.NextRetry = nil
+	var waiting []string
+	for _, dep := range e.DependsOn {
+		if d := byName[dep]; d == nil || d.status.State != StateReady {
+			waiting = append(waiting, dep)
+		}
+	}
+	if len(waiting) > 0 {
+		// Started again by the dependency once it is ready
+		e.status.State = StateWaiting
+		e.status.Error = "waiting for " + strings.Join(waiting, ", ")
+		mu.Unlock()
+		return
+	}
+	now := time.Now()
+	e.status.State = StateStarting
+	e.status.Attempts++
+	e.status.LastAttempt = &now
+	mu.Unlock()
+
+	err := e.Init()
+	if err == nil && e.Check != nil {
+		err = e.Check()
+	}
+
+	if err != nil {
+		fail(e, err)
+		return
+	}
+
+	mu.Lock()
+	readySince := time.Now()
+	e.status.State = StateReady
+	e.status.Error = ""
+	e.status.ReadySince = &readySince
+	e.delay = minRetryDelay
+	e.failures = 0
+	var dependents []*entry
+	for _, d := range entries {
+		for _, dep := range d.DependsOn {
+			if dep == e.Name && d.status.State != StateReady {
+				dependents = append(dependents, d)
+			}
+		}
+	}
+	mu.Unlock()
+	log.Info().Str("subsystem", e.Name).Msg("Subsystem ready")
+
+	for _, d := range dependents {
+		go attempt(d, false)
+	}
+}
+
+// fail marks a subsystem failed and schedules a retry. The caller holds
+// e.initMu.
+func fail(e *entry, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	delay := e.delay
+	next := time.Now().Add(delay)
+	e.status.State = StateFailed
+	e.status.Error = err.Error()
+	e.status.ReadySince = nil
+	e.status.NextRetry = &next
+	e.delay *= 2
+	if e.delay > maxRetryDelay {
+		e.delay = maxRetryDelay
+	}
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	e.timer = time.AfterFunc(delay, func() { attempt(e, false) })
+
+	// Only the first failure is a warning, so that a dependency that is
+	// down for good does not flood the log
+	e.failures++
+	event := log.Debug()
+	if e.failures == 1 {
+		event = log.Warn()
+	}
+	event.Err(err).Str("subsystem", e.Name).Dur("retry_in", delay).Msg("Subsystem unavailable")
+}
+
+// checkLoop runs the health checks of ready subsystems
+func checkLoop() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		mu.Lock()
+		var ready []*entry
+		for _, e := range entries {
+			if e.status.State == StateReady && e.Check != nil {
+				ready = append(ready, e)
+			}
+		}
+		mu.Unlock()
+
+		for _, e := range ready {
+			if err := e.Check(); err != nil {
+				e.initMu.Lock()
+				fail(e, err)
+				e.initMu.Unlock()
+			}
+		}
+	}
+}
+
+// UnavailableError describes why a subsystem cannot serve a request
+func UnavailableError(name string) error {
+	status, err := Get(name)
+	if err != nil {
+		return err
+	}
+	if status.Error != "" {
+		return fmt.Errorf("%s is unavailable: %s", name, status.Error)
+	}
+	return fmt.Errorf("%s is %s", name, status.State)
+}
@@ -0,0 +1,100 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+package terminal
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/gaga951/gagos/internal/k8s"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// sizeQueue feeds resize messages to the remote TTY
+type sizeQueue chan remotecommand.TerminalSize
+
+func (q sizeQueue) Next() *remotecommand.TerminalSize {
+	size, ok := <-q
+	if !ok {
+		return nil
+	}
+	return &size
+}
+
+// wsWriter sends everything written to it as output messages
+type wsWriter struct {
+	mu sync.Mutex
+	c  *websocket.Conn
+}
+
+func (w *wsWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.c.WriteJSON(WsMessage{Type: MsgTypeOutput, Data: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// HandlePodAttach bridges the WebSocket terminal protocol to a container TTY
+// via the pods/attach subresource. Used for ephemeral debug containers.
+func HandlePodAttach(c *websocket.Conn, namespace, pod, container string) {
+	log.Info().
+		Str("remote", c.RemoteAddr().String()).
+		Str("pod", namespace+"/"+pod).
+		Str("container", container).
+		Msg("Pod attach WebSocket connected")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stdinReader, stdinWriter := io.Pipe()
+	sizes := make(sizeQueue, 1)
+	sizes <- remotecommand.TerminalSize{Width: 80, Height: 24}
+	out := &wsWriter{c: c}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := k8s.AttachContainer(ctx, namespace, pod, container, stdinReader, out, sizes); err != nil {
+			log.Debug().Err(err).Msg("Pod attach ended with error")
+			out.Write([]byte("\r\nError: " + err.Error() + "\r\n"))
+		} else {
+			out.Write([]byte("\r\nSession ended\r\n"))
+		}
+		// Unblock the read loop below
+		c.Close()
+	}()
+
+	for {
+		var msg WsMessage
+		if err := c.ReadJSON(&msg); err != nil {
+			break
+		}
+
+		switch msg.Type {
+		case MsgTypeInput:
+			if _, err := stdinWriter.Write([]byte(msg.Data)); err != nil {
+				log.Debug().Err(err).Msg("Attach stdin write error")
+			}
+		case MsgTypeResize:
+			if msg.Cols > 0 && msg.Rows > 0 {
+				select {
+				case sizes <- remotecommand.TerminalSize{Width: uint16(msg.Cols), Height: uint16(msg.Rows)}:
+				default:
+					// Drop the resize if the previous one has not been consumed yet
+				}
+			}
+		}
+	}
+
+	cancel()
+	stdinWriter.Close()
+	<-done
+	close(sizes)
+	log.Info().Str("remote", c.RemoteAddr().String()).Msg("Pod attach WebSocket disconnected")
+}
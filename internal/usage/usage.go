@@ -0,0 +1,401 @@
+// Copyright 2024-2026 GAGOS Project
+// SPDX-License-Identifier: Apache-2.0
+
+// Package usage counts which features and endpoints of GAGOS are used, how
+// often and by which clients, so that operators can see what is adopted and
+// what could be retired. Counts are kept per day in the local database and
+// never leave it. With GAGOS_USAGE_ANONYMIZE, clients are recorded as
+// pseudonyms instead of IPs and session prefixes.
+package usage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaga951/gagos/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// flushInterval is how often counts are written to storage
+const flushInterval = time.Minute
+
+// dateFormat is the key of a day's counts
+const dateFormat = "2006-01-02"
+
+// Query limits
+const (
+	DefaultDays = 30
+	MaxDays     = 365
+)
+
+// EndpointStats counts the calls of one endpoint
+type EndpointStats struct {
+	Requests int64     `json:"requests"`
+	Errors   int64     `json:"errors"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// ActorStats counts the calls of one client
+type ActorStats struct {
+	Requests int64            `json:"requests"`
+	LastSeen time.Time        `json:"last_seen"`
+	Features map[string]int64 `json:"features"`
+}
+
+// Day holds the counts of one day
+type Day struct {
+	Date      string                    `json:"date"`
+	Endpoints map[string]*EndpointStats `json:"endpoints"` // "METHOD /route/:param"
+	Actors    map[string]*ActorStats    `json:"actors"`
+}
+
+func newDay(date string) *Day {
+	return &Day{Date: date, Endpoints: map[string]*EndpointStats{}, Actors: map[string]*ActorStats{}}
+}
+
+var (
+	mu      sync.Mutex
+	pending = map[string]*Day{}
+	salt    = randomSalt()
+	started sync.Once
+)
+
+// Enabled reports whether usage is recorded (GAGOS_USAGE_ANALYTICS, default
+// true)
+func Enabled() bool {
+	return os.Getenv("GAGOS_USAGE_ANALYTICS") != "false"
+}
+
+// Retention is how long daily counts are kept (GAGOS_USAGE_RETENTION_DAYS,
+// default 180; 0 keeps them forever)
+func Retention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("GAGOS_USAGE_RETENTION_DAYS"))
+	if err != nil || days < 0 {
+		days = 180
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Start writes counts to storage in the background
+func Start() {
+	started.Do(func() {
+		go func() {
+			ticker := time.NewTicker(flushInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				Flush()
+				prune()
+			}
+		}()
+	})
+}
+
+// ActorID identifies a client by its session prefix, else its IP. With
+// GAGOS_USAGE_ANONYMIZE=true it is a pseudonym that is stable until restart.
+func ActorID(session, ip string) string {
+	actor := ip
+	if session != "" {
+		actor = "session:" + session
+	}
+	if os.Getenv("GAGOS_USAGE_ANONYMIZE") == "true" {
+		sum := sha256.Sum256([]byte(salt + actor))
+		return "anon:" + hex.EncodeToString(sum[:6])
+	}
+	return actor
+}
+
+// FeatureOf returns the feature of an API route: the first segment after
+// the API version, and the second for db and storage (e.g. db/postgres).
+// Routes outside /api have no feature.
+func FeatureOf(route string) string {
+	rest, ok := strings.CutPrefix(route, "/api/")
+	if !ok {
+		return ""
+	}
+	if rest == "v1" || rest == "v2" {
+		return ""
+	}
+	if strings.HasPrefix(rest, "v1/") || strings.HasPrefix(rest, "v2/") {
+		rest = rest[3:]
+	}
+	parts := strings.Split(strings.Trim(rest, "/"), "/")
+	if parts[0] == "" {
+		return ""
+	}
+	if (parts[0] == "db" || parts[0] == "storage") && len(parts) > 1 && !strings.HasPrefix(parts[1], ":") {
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
+
+// Record counts a call of route by actor
+func Record(method, route, actor string, failed bool) {
+	feature := FeatureOf(route)
+	if feature == "" {
+		return
+	}
+	now := time.Now()
+	date := now.Format(dateFormat)
+	endpoint := method + " " + route
+
+	mu.Lock()
+	defer mu.Unlock()
+	day := pending[date]
+	if day == nil {
+		day = newDay(date)
+		pending[date] = day
+	}
+	stats := day.Endpoints[endpoint]
+	if stats == nil {
+		stats = &EndpointStats{}
+		day.Endpoints[endpoint] = stats
+	}
+	stats.Requests++
+	if failed {
+		stats.Errors++
+	}
+	stats.LastUsed = now
+
+	if actor != "" {
+		a := day.Actors[actor]
+		if a == nil {
+			a = &ActorStats{Features: map[string]int64{}}
+			day.Actors[actor] = a
+		}
+		a.Requests++
+		a.LastSeen = now
+		a.Features[feature]++
+	}
+}
+
+// Flush adds the counts recorded since the last flush to storage
+func Flush() {
+	if storage.GetBackend() == nil {
+		return
+	}
+	mu.Lock()
+	days := pending
+	pending = map[string]*Day{}
+	mu.Unlock()
+
+	for date, counts := range days {
+		day, err := loadDay(date)
+		if err != nil {
+			log.Error().Err(err).Str("date", date).Msg("Failed to load usage counts")
+			continue
+		}
+		day.merge(counts)
+		data, err := json.Marshal(day)
+		if err != nil {
+			continue
+		}
+		if err := storage.GetBackend().Set(storage.BucketUsage, date, data); err != nil {
+			log.Error().Err(err).Str("date", date).Msg("Failed to save usage counts")
+		}
+	}
+}
+
+func loadDay(date string) (*Day, error) {
+	data, err := storage.GetBackend().Get(storage.BucketUsage, date)
+	if err != nil {
+		return nil, err
+	}
+	day := newDay(date)
+	if data != nil {
+		if err := json.Unmarshal(data, day); err != nil {
+			return nil, err
+		}
+	}
+	return day, nil
+}
+
+func (d *Day) merge(o *Day) {
+	for endpoint, s := range o.Endpoints {
+		stats := d.Endpoints[endpoint]
+		if stats == nil {
+			stats = &EndpointStats{}
+			d.Endpoints[endpoint] = stats
+		}
+		stats.Requests += s.Requests
+		stats.Errors += s.Errors
+		if s.LastUsed.After(stats.LastUsed) {
+			stats.LastUsed = s.LastUsed
+		}
+	}
+	for actor, s := range o.Actors {
+		a := d.Actors[actor]
+		if a == nil {
+			a = &ActorStats{Features: map[string]int64{}}
+			d.Actors[actor] = a
+		}
+		a.Requests += s.Requests
+		if s.LastSeen.After(a.LastSeen) {
+			a.LastSeen = s.LastSeen
+		}
+		for feature, n := range s.Features {
+			a.Features[feature] += n
+		}
+	}
+}
+
+// prune deletes the days past the retention
+func prune() {
+	retention := Retention()
+	if retention == 0 || storage.GetBackend() == nil {
+		return
+	}
+	keys, err := storage.GetBackend().ListKeys(storage.BucketUsage)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-retention).Format(dateFormat)
+	for _, key := range keys {
+		if key < cutoff {
+			storage.GetBackend().Delete(storage.BucketUsage, key)
+		}
+	}
+}
+
+// EndpointUsage is the use of one endpoint over a report's window
+type EndpointUsage struct {
+	Endpoint string    `json:"endpoint"`
+	Requests int64     `json:"requests"`
+	Errors   int64     `json:"errors"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// FeatureUsage is the use of one feature over a report's window
+type FeatureUsage struct {
+	Feature   string          `json:"feature"`
+	Requests  int64           `json:"requests"`
+	Errors    int64           `json:"errors"`
+	Actors    int             `json:"actors"`
+	LastUsed  time.Time       `json:"last_used"`
+	Endpoints []EndpointUsage `json:"endpoints"`
+}
+
+// ActorUsage is the activity of one client over a report's window
+type ActorUsage struct {
+	Actor    string           `json:"actor"`
+	Requests int64            `json:"requests"`
+	LastSeen time.Time        `json:"last_seen"`
+	Features map[string]int64 `json:"features"`
+}
+
+// Report summarizes usage over the last days
+type Report struct {
+	Since    string         `json:"since"`
+	Until    string         `json:"until"`
+	Requests int64          `json:"requests"`
+	Features []FeatureUsage `json:"features"`
+	Actors   []ActorUsage   `json:"actors"`
+	// Known endpoints and features that were not called in the window
+	UnusedEndpoints []string `json:"unused_endpoints"`
+	UnusedFeatures  []string `json:"unused_features"`
+}
+
+// GetReport returns the usage of the last days. known lists every
+// endpoint ("METHOD /route") that could have been called.
+func GetReport(days int, known []string) (*Report, error) {
+	if days <= 0 {
+		days = DefaultDays
+	}
+	if days > MaxDays {
+		days = MaxDays
+	}
+	Flush()
+
+	now := time.Now()
+	since := now.AddDate(0, 0, -(days - 1)).Format(dateFormat)
+	total := newDay("")
+	if storage.GetBackend() != nil {
+		dataList, err := storage.GetBackend().List(storage.BucketUsage)
+		if err != nil {
+			return nil, err
+		}
+		for _, data := range dataList {
+			day := newDay("")
+			if err := json.Unmarshal(data, day); err != nil || day.Date < since {
+				continue
+			}
+			total.merge(day)
+		}
+	}
+
+	report := &Report{
+		Since:           since,
+		Until:           now.Format(dateFormat),
+		Features:        []FeatureUsage{},
+		Actors:          []ActorUsage{},
+		UnusedEndpoints: []string{},
+		UnusedFeatures:  []string{},
+	}
+
+	features := map[string]*FeatureUsage{}
+	for endpoint, s := range total.Endpoints {
+		_, route, _ := strings.Cut(endpoint, " ")
+		name := FeatureOf(route)
+		f := features[name]
+		if f == nil {
+			f = &FeatureUsage{Feature: name}
+			features[name] = f
+		}
+		f.Requests += s.Requests
+		f.Errors += s.Errors
+		if s.LastUsed.After(f.LastUsed) {
+			f.LastUsed = s.LastUsed
+		}
+		f.Endpoints = append(f.Endpoints, EndpointUsage{Endpoint: endpoint, Requests: s.Requests, Errors: s.Errors, LastUsed: s.LastUsed})
+		report.Requests += s.Requests
+	}
+	for actor, a := range total.Actors {
+		report.Actors = append(report.Actors, ActorUsage{Actor: actor, Requests: a.Requests, LastSeen: a.LastSeen, Features: a.Features})
+		for name := range a.Features {
+			if f := features[name]; f != nil {
+				f.Actors++
+			}
+		}
+	}
+	for _, f := range features {
+		sort.Slice(f.Endpoints, func(i, j int) bool { return f.Endpoints[i].Requests > f.Endpoints[j].Requests })
+		report.Features = append(report.Features, *f)
+	}
+	sort.Slice(report.Features, func(i, j int) bool { return report.Features[i].Requests > report.Features[j].Requests })
+	sort.Slice(report.Actors, func(i, j int) bool { return report.Actors[i].Requests > report.Actors[j].Requests })
+
+	unusedFeatures := map[string]bool{}
+	for _, endpoint := range known {
+		if total.Endpoints[endpoint] != nil {
+			continue
+		}
+		_, route, _ := strings.Cut(endpoint, " ")
+		name := FeatureOf(route)
+		if name == "" {
+			continue
+		}
+		report.UnusedEndpoints = append(report.UnusedEndpoints, endpoint)
+		if features[name] == nil {
+			unusedFeatures[name] = true
+		}
+	}
+	for name := range unusedFeatures {
+		report.UnusedFeatures = append(report.UnusedFeatures, name)
+	}
+	sort.Strings(report.UnusedEndpoints)
+	sort.Strings(report.UnusedFeatures)
+	return report, nil
+}
+
+func randomSalt() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
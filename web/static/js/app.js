@@ -29,9 +29,9 @@ import {
     loadCronJobsForNamespace, loadConfigMapsForNamespace, loadSecretsForNamespace,
     loadIngressesForNamespace, loadPVCsForNamespace, loadEventsForNamespace,
     toggleEditMode, enableEditMode, disableEditMode, showModal, closeModal,
-    describeResource, decodeDescribedSecret, editResource, saveResourceEdit, showDeleteModal, confirmDelete,
-    viewPodLogs, refreshLogs, showScaleModal, confirmScale, showRestartModal, confirmRestart,
-    openCreateModal, loadResourceTemplate, createResource,
+    describeResource, decodeDescribedSecret, showDescribedEvents, editResource, saveResourceEdit, showDeleteModal, confirmDelete,
+    viewPodLogs, refreshLogs, showScaleModal, confirmScale, showRestartModal, confirmRestart, triggerCronJob, setCronJobSuspend,
+    openCreateModal, loadResourceTemplate, createResource, validateResourceYaml, selectEditorLine, previewResourceEdit,
     toggleAutoRefresh, updateRefreshInterval
 } from './kubernetes.js';
 import { initTerminal, reconnectTerminal } from './terminal.js';
@@ -39,6 +39,7 @@ import {
     showCicdTab, loadCicdData, loadCicdStats, loadCicdPipelines, loadCicdRuns, loadCicdArtifacts,
     loadSamplePipeline, validatePipeline, createPipeline, triggerPipeline, viewPipeline,
     deletePipeline, clonePipeline, cancelRun, retryPipelineRun, viewRunJobs, closeCicdLogModal, deleteArtifact,
+    viewEnvironmentHistory, rollbackEnvironment,
     copyPipelineBadge, copyFreestyleJobBadge,
     showAddSSHHostModal, closeSSHHostModal, updateAuthFields, saveSSHHost,
     showCreateFreestyleJobModal, closeFreestyleJobModal, saveFreestyleJob, addBuildStep,
@@ -48,7 +49,7 @@ import {
 import { initNotepad, addNotepadTab, closeNotepadTab, saveNotepadContent, switchNotepadTab, renameNotepadTab } from './notepad.js';
 import {
     showMonitoringTab, loadMonitoringData, loadMonitoringSummary, loadMonitoringNodes,
-    loadMonitoringPods, loadMonitoringQuotas, loadMonitoringHPA
+    loadMonitoringPods, loadMonitoringTop, loadMonitoringQuotas, loadMonitoringHPA
 } from './monitoring.js';
 import {
     showDevToolsTab, doBase64Encode, doBase64Decode, decodeK8sSecret, generateHashes,
@@ -58,7 +59,8 @@ import {
 import {
     showPgTab, pgConnect, pgLoadInfo, pgExecuteQuery, pgDump, pgCopyDump, pgDownloadDump,
     showRedisTab, redisConnect, redisLoadInfo, redisScanKeys, redisGetKey, redisExecCommand, redisLoadCluster,
-    showMysqlTab, mysqlConnect, mysqlLoadInfo, mysqlExecuteQuery, mysqlDump, mysqlCopyDump, mysqlDownloadDump
+    showMysqlTab, mysqlConnect, mysqlLoadInfo, mysqlExecuteQuery, mysqlDump, mysqlCopyDump, mysqlDownloadDump,
+    showMssqlTab, mssqlConnect, mssqlLoadInfo, mssqlExecuteQuery, mssqlLoadAlwaysOn
 } from './database.js';
 import {
     showS3Tab, s3Connect, s3LoadBuckets, s3CreateBucket, s3DeleteBucket, s3SelectBucket,
@@ -71,6 +73,7 @@ import {
     esRefreshIndex, esViewMapping, esViewSettings, esSelectIndex, esSearchDocuments,
     esViewDocument, esDeleteDocument, esExecuteQuery, esCloseModal, esCopyModalContent
 } from './elasticsearch.js';
+import { renderSavedConnections, useSavedConnection, forgetSavedConnection, renderTlsOptions, loadPemFile } from './connections.js';
 import { escapeHtml, formatDuration, formatSize, formatTime } from './utils.js';
 
 // Set up circular dependency helpers
@@ -145,6 +148,7 @@ window.showModal = showModal;
 window.closeModal = closeModal;
 window.describeResource = describeResource;
 window.decodeDescribedSecret = decodeDescribedSecret;
+window.showDescribedEvents = showDescribedEvents;
 window.editResource = editResource;
 window.saveResourceEdit = saveResourceEdit;
 window.showDeleteModal = showDeleteModal;
@@ -155,9 +159,14 @@ window.showScaleModal = showScaleModal;
 window.confirmScale = confirmScale;
 window.showRestartModal = showRestartModal;
 window.confirmRestart = confirmRestart;
+window.triggerCronJob = triggerCronJob;
+window.setCronJobSuspend = setCronJobSuspend;
 window.openCreateModal = openCreateModal;
 window.loadResourceTemplate = loadResourceTemplate;
 window.createResource = createResource;
+window.validateResourceYaml = validateResourceYaml;
+window.selectEditorLine = selectEditorLine;
+window.previewResourceEdit = previewResourceEdit;
 window.toggleAutoRefresh = toggleAutoRefresh;
 window.updateRefreshInterval = updateRefreshInterval;
 
@@ -176,6 +185,8 @@ window.clonePipeline = clonePipeline;
 window.cancelRun = cancelRun;
 window.retryPipelineRun = retryPipelineRun;
 window.viewRunJobs = viewRunJobs;
+window.viewEnvironmentHistory = viewEnvironmentHistory;
+window.rollbackEnvironment = rollbackEnvironment;
 window.closeCicdLogModal = closeCicdLogModal;
 window.deleteArtifact = deleteArtifact;
 window.copyPipelineBadge = copyPipelineBadge;
@@ -208,6 +219,7 @@ window.renameNotepadTab = renameNotepadTab;
 // Monitoring
 window.showMonitoringTab = showMonitoringTab;
 window.loadMonitoringPods = loadMonitoringPods;
+window.loadMonitoringTop = loadMonitoringTop;
 window.loadMonitoringQuotas = loadMonitoringQuotas;
 window.loadMonitoringHPA = loadMonitoringHPA;
 
@@ -253,6 +265,12 @@ window.mysqlDump = mysqlDump;
 window.mysqlCopyDump = mysqlCopyDump;
 window.mysqlDownloadDump = mysqlDownloadDump;
 
+// Database - SQL Server
+window.showMssqlTab = showMssqlTab;
+window.mssqlConnect = mssqlConnect;
+window.mssqlExecuteQuery = mssqlExecuteQuery;
+window.mssqlLoadAlwaysOn = mssqlLoadAlwaysOn;
+
 // S3 Storage
 window.showS3Tab = showS3Tab;
 window.s3Connect = s3Connect;
@@ -291,6 +309,11 @@ window.esExecuteQuery = esExecuteQuery;
 window.esCloseModal = esCloseModal;
 window.esCopyModalContent = esCopyModalContent;
 
+// Saved connections
+window.useSavedConnection = useSavedConnection;
+window.forgetSavedConnection = forgetSavedConnection;
+window.loadPemFile = loadPemFile;
+
 // Utility
 window.escapeHtml = escapeHtml;
 
@@ -308,6 +331,10 @@ document.addEventListener('DOMContentLoaded', () => {
     checkHealth();
     setInterval(checkHealth, 30000);
 
+    // Saved database connections and their health
+    ['postgres', 'mysql', 'mssql', 'redis', 'elasticsearch', 's3'].forEach(renderSavedConnections);
+    ['pg', 'mysql', 'mssql', 'redis', 'es', 's3'].forEach(renderTlsOptions);
+
     // Set up context menu for desktop
     document.getElementById('desktop').addEventListener('contextmenu', showDesktopContextMenu);
     document.addEventListener('click', (e) => {
@@ -323,5 +350,18 @@ document.addEventListener('DOMContentLoaded', () => {
         }
     });
 
+    // Links to a run or build, such as a commit status's details link
+    const params = new URLSearchParams(window.location.search);
+    const runId = params.get('cicd_run');
+    if (runId) {
+        openWindow('cicd');
+        viewRunJobs(runId);
+    }
+    const buildId = params.get('cicd_build');
+    if (buildId) {
+        openWindow('cicd');
+        window.openBuildConsole(buildId);
+    }
+
     console.log('GAGOS initialized successfully');
 });
@@ -26,6 +26,7 @@ export function showCicdTab(tabId) {
     if (tabId === 'overview') loadCicdStats();
     else if (tabId === 'pipelines') loadCicdPipelines();
     else if (tabId === 'runs') loadCicdRuns();
+    else if (tabId === 'environments') loadCicdEnvironments();
     else if (tabId === 'artifacts') loadCicdArtifacts();
     else if (tabId === 'ssh-hosts') loadSSHHosts();
     else if (tabId === 'git-credentials') loadGitCredentials();
@@ -191,8 +192,11 @@ function renderRunsTable() {
                 <button class="row-action-btn logs" onclick="viewRunJobs('${run.id}')" title="View Jobs">
                     <svg fill="none" stroke="currentColor" stroke-width="2" viewBox="0 0 24 24" style="width:14px;height:14px;"><path stroke-linecap="round" stroke-linejoin="round" d="M4 6h16M4 12h16M4 18h7"/></svg>
                 </button>
-                ${canRetry ? `<button class="row-action-btn" style="background:rgba(251,191,36,0.2);color:#fbbf24;" onclick="retryPipelineRun('${run.pipeline_id}')" title="Retry">
+                ${canRetry ? `<button class="row-action-btn" style="background:rgba(251,191,36,0.2);color:#fbbf24;" onclick="retryPipelineRun('${run.id}')" title="Re-run">
                     <svg fill="none" stroke="currentColor" stroke-width="2" viewBox="0 0 24 24" style="width:14px;height:14px;"><path stroke-linecap="round" stroke-linejoin="round" d="M4 4v5h.582m15.356 2A8.001 8.001 0 004.582 9m0 0H9m11 11v-5h-.581m0 0a8.003 8.003 0 01-15.357-2m15.357 2H15"/></svg>
+                </button>
+                <button class="row-action-btn" style="background:rgba(251,191,36,0.2);color:#fbbf24;" onclick="retryPipelineRun('${run.id}', true)" title="Re-run failed jobs">
+                    <svg fill="none" stroke="currentColor" stroke-width="2" viewBox="0 0 24 24" style="width:14px;height:14px;"><path stroke-linecap="round" stroke-linejoin="round" d="M12 8v4m0 4h.01M4 4v5h.582m15.356 2A8.001 8.001 0 004.582 9m0 0H9"/></svg>
                 </button>` : ''}
                 ${canCancel ? `<button class="row-action-btn delete" onclick="cancelRun('${run.id}')" title="Cancel">
                     <svg fill="none" stroke="currentColor" stroke-width="2" viewBox="0 0 24 24" style="width:14px;height:14px;"><path stroke-linecap="round" stroke-linejoin="round" d="M6 18L18 6M6 6l12 12"/></svg>
@@ -202,6 +206,99 @@ function renderRunsTable() {
     });
 }
 
+export async function loadCicdEnvironments() {
+    try {
+        const r = await fetch(`${API_BASE}/cicd/environments`);
+        const d = await r.json();
+        renderEnvironmentsTable(d.environments || []);
+    } catch (e) {
+        console.error('Failed to load environments:', e);
+    }
+}
+
+function renderEnvironmentsTable(envs) {
+    const tbody = document.getElementById('cicd-environments-tbody');
+    tbody.innerHTML = '';
+    if (envs.length === 0) {
+        tbody.innerHTML = '<tr><td colspan="6" style="text-align:center;color:#6a6a7a;padding:30px;">No deployments yet. Set <code>environment</code> on a pipeline job to track its deployments.</td></tr>';
+        return;
+    }
+    envs.forEach(env => {
+        const current = env.current;
+        const latest = env.latest;
+        tbody.innerHTML += `<tr>
+            <td><strong>${escapeHtml(env.name)}</strong></td>
+            <td>${current ? escapeHtml(current.version) : '<span style="color:#6a6a7a;">-</span>'}</td>
+            <td>${current ? `${escapeHtml(current.pipeline_name)} #${current.run_number}` : '-'}</td>
+            <td>${current && current.finished_at ? formatTime(current.finished_at) : '-'}</td>
+            <td class="${getRunStatusClass(latest.status)}">${latest.status}</td>
+            <td class="action-cell">
+                <button class="row-action-btn logs" onclick="viewEnvironmentHistory('${escapeHtml(env.name)}')" title="History">
+                    <svg fill="none" stroke="currentColor" stroke-width="2" viewBox="0 0 24 24" style="width:14px;height:14px;"><path stroke-linecap="round" stroke-linejoin="round" d="M4 6h16M4 12h16M4 18h7"/></svg>
+                </button>
+                ${current ? `<button class="row-action-btn" style="background:rgba(251,191,36,0.2);color:#fbbf24;" onclick="rollbackEnvironment('${escapeHtml(env.name)}')" title="Roll back to the previous version">
+                    <svg fill="none" stroke="currentColor" stroke-width="2" viewBox="0 0 24 24" style="width:14px;height:14px;"><path stroke-linecap="round" stroke-linejoin="round" d="M3 10h10a8 8 0 018 8v2M3 10l6 6m-6-6l6-6"/></svg>
+                </button>` : ''}
+            </td>
+        </tr>`;
+    });
+}
+
+export async function viewEnvironmentHistory(name) {
+    try {
+        const r = await fetch(`${API_BASE}/cicd/environments/${encodeURIComponent(name)}/deployments?limit=50`);
+        const d = await r.json();
+        const deployments = d.deployments || [];
+        const currentId = (deployments.find(dep => dep.status === 'succeeded') || {}).id;
+        document.getElementById('cicd-env-history-title').textContent = `${name} history`;
+        const tbody = document.getElementById('cicd-env-history-tbody');
+        tbody.innerHTML = '';
+        deployments.forEach(dep => {
+            const canRollback = dep.status === 'succeeded' && dep.id !== currentId;
+            tbody.innerHTML += `<tr>
+                <td>${escapeHtml(dep.version)}${dep.id === currentId ? ' <span style="color:#4ade80;font-size:11px;">current</span>' : ''}</td>
+                <td class="${getRunStatusClass(dep.status)}">${dep.status}</td>
+                <td>${escapeHtml(dep.pipeline_name)} / ${escapeHtml(dep.job_name)}</td>
+                <td>#${dep.run_number}</td>
+                <td>${dep.trigger_type}</td>
+                <td>${dep.finished_at ? formatTime(dep.finished_at) : '-'}</td>
+                <td class="action-cell">
+                    <button class="row-action-btn logs" onclick="viewRunJobs('${dep.run_id}')" title="View Jobs">
+                        <svg fill="none" stroke="currentColor" stroke-width="2" viewBox="0 0 24 24" style="width:14px;height:14px;"><path stroke-linecap="round" stroke-linejoin="round" d="M4 6h16M4 12h16M4 18h7"/></svg>
+                    </button>
+                    ${canRollback ? `<button class="row-action-btn" style="background:rgba(251,191,36,0.2);color:#fbbf24;" onclick="rollbackEnvironment('${escapeHtml(name)}', '${dep.id}')" title="Roll back to this version">
+                        <svg fill="none" stroke="currentColor" stroke-width="2" viewBox="0 0 24 24" style="width:14px;height:14px;"><path stroke-linecap="round" stroke-linejoin="round" d="M3 10h10a8 8 0 018 8v2M3 10l6 6m-6-6l6-6"/></svg>
+                    </button>` : ''}
+                </td>
+            </tr>`;
+        });
+        document.getElementById('cicd-env-history').style.display = 'block';
+    } catch (e) {
+        alert('Failed to load history: ' + e.message);
+    }
+}
+
+export async function rollbackEnvironment(name, deploymentId = '') {
+    if (!confirm(deploymentId ? `Roll ${name} back to this version?` : `Roll ${name} back to the previous version?`)) return;
+    try {
+        const r = await fetch(`${API_BASE}/cicd/environments/${encodeURIComponent(name)}/rollback`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ deployment_id: deploymentId })
+        });
+        const d = await r.json();
+        if (d.error) {
+            alert('Error: ' + d.error);
+        } else {
+            alert(`Run #${d.run_number} started, deploying ${d.version}`);
+            loadCicdEnvironments();
+            loadCicdStats();
+        }
+    } catch (e) {
+        alert('Failed to roll back: ' + e.message);
+    }
+}
+
 export function getRunStatusClass(status) {
     switch (status) {
         case 'succeeded': return 'status-running';
@@ -430,15 +527,19 @@ export async function cancelRun(runId) {
     }
 }
 
-export async function retryPipelineRun(pipelineId) {
-    if (!confirm('Retry this pipeline?')) return;
+export async function retryPipelineRun(runId, failedOnly = false) {
+    if (!confirm(failedOnly ? 'Re-run the failed jobs of this run?' : 'Re-run this run with the same variables?')) return;
     try {
-        const r = await fetch(`${API_BASE}/cicd/pipelines/${pipelineId}/trigger`, { method: 'POST' });
+        const r = await fetch(`${API_BASE}/cicd/runs/${runId}/rerun`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ failed_only: failedOnly })
+        });
         const d = await r.json();
         if (d.error) {
             alert('Error: ' + d.error);
         } else {
-            alert(`Pipeline re-triggered! Run #${d.run_number}`);
+            alert(`Run #${d.run_number} started`);
             loadCicdRuns();
             loadCicdStats();
         }
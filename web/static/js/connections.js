@@ -0,0 +1,168 @@
+// Saved Connections for GAGOS database windows
+// Connection settings are kept in localStorage without secrets; their health
+// comes from the server's connection profile cache.
+
+import { API_BASE } from './app.js';
+import { escapeHtml, formatTime } from './utils.js';
+
+const STORAGE_KEY = 'gagos_connections';
+const MAX_SAVED = 20;
+
+// Form inputs per connection kind, keyed by config field
+const FORMS = {
+    postgres: { host: 'pg-host', port: 'pg-port', user: 'pg-user', database: 'pg-database', ssl_mode: 'pg-sslmode', target: 'pg-target' },
+    mysql: { host: 'mysql-host', port: 'mysql-port', user: 'mysql-user', database: 'mysql-database', target: 'mysql-target' },
+    mssql: { host: 'mssql-host', port: 'mssql-port', instance: 'mssql-instance', user: 'mssql-user', database: 'mssql-database', read_only_intent: 'mssql-readonly-intent' },
+    redis: { host: 'redis-host', port: 'redis-port', db: 'redis-db', use_tls: 'redis-tls' },
+    elasticsearch: { host: 'es-host', port: 'es-port', username: 'es-username', use_ssl: 'es-ssl' },
+    s3: { endpoint: 's3-endpoint', region: 's3-region', access_key_id: 's3-access-key', use_ssl: 's3-ssl' }
+};
+
+const HEALTH_COLORS = { healthy: '#4ade80', degraded: '#fbbf24', unhealthy: '#ef4444' };
+
+function loadSaved() {
+    try {
+        return JSON.parse(localStorage.getItem(STORAGE_KEY)) || [];
+    } catch (e) {
+        return [];
+    }
+}
+
+function connectionLabel(kind, f) {
+    if (kind === 's3') return `${f.access_key_id || ''}@${f.endpoint || ''}`;
+    const user = f.user || f.username;
+    const target = `${f.host || ''}:${f.port || ''}`;
+    const suffix = f.database ? '/' + f.database : (kind === 'redis' ? '/' + (f.db || 0) : '');
+    return (user ? user + '@' : '') + target + suffix;
+}
+
+// rememberConnection stores the non-secret fields of a successful connection
+export function rememberConnection(kind, profileId, config) {
+    if (!profileId) return;
+    const fields = {};
+    Object.keys(FORMS[kind] || {}).forEach(k => { fields[k] = config[k]; });
+
+    const saved = loadSaved().filter(s => s.id !== profileId &&
+        !(s.kind === kind && connectionLabel(kind, s.fields) === connectionLabel(kind, fields)));
+    saved.unshift({ kind, id: profileId, fields });
+    localStorage.setItem(STORAGE_KEY, JSON.stringify(saved.slice(0, MAX_SAVED)));
+    renderSavedConnections(kind);
+}
+
+export function useSavedConnection(kind, index) {
+    const entry = loadSaved().filter(s => s.kind === kind)[index];
+    if (!entry) return;
+    Object.entries(FORMS[kind]).forEach(([field, inputId]) => {
+        const input = document.getElementById(inputId);
+        if (!input || entry.fields[field] === undefined) return;
+        if (input.type === 'checkbox') input.checked = !!entry.fields[field];
+        else input.value = entry.fields[field];
+    });
+}
+
+export async function forgetSavedConnection(kind, id) {
+    localStorage.setItem(STORAGE_KEY, JSON.stringify(loadSaved().filter(s => s.id !== id)));
+    try {
+        await fetch(`${API_BASE}/db/connections/${encodeURIComponent(id)}`, { method: 'DELETE' });
+    } catch (e) {
+        // The server forgets idle profiles on its own
+    }
+    renderSavedConnections(kind);
+}
+
+// renderSavedConnections lists the saved connections of one kind with the
+// health the server last recorded for them
+export async function renderSavedConnections(kind) {
+    const container = document.getElementById(`${kind}-saved-conns`);
+    if (!container) return;
+
+    const saved = loadSaved().filter(s => s.kind === kind);
+    if (saved.length === 0) {
+        container.innerHTML = '';
+        return;
+    }
+
+    let statuses = {};
+    try {
+        const r = await fetch(`${API_BASE}/db/connections`);
+        const d = await r.json();
+        (d.connections || []).forEach(s => { statuses[s.id] = s; });
+    } catch (e) {
+        statuses = {};
+    }
+
+    container.innerHTML = saved.map((s, i) => {
+        const st = statuses[s.id];
+        const color = st ? HEALTH_COLORS[st.health] || '#8a8a9a' : '#8a8a9a';
+        const title = st
+            ? `${st.health} - checked ${formatTime(st.last_checked)}${st.last_error ? '\n' + st.last_error : ''}`
+            : 'Not checked since the server started';
+        const lastSuccess = st && st.last_success ? 'ok ' + formatTime(st.last_success) : (st ? 'never connected' : '');
+        return `<div style="display:flex;align-items:center;gap:6px;background:#1e1e2e;border:1px solid #3a3a4e;border-radius:4px;padding:4px 8px;font-size:12px;">
+            <span title="${escapeHtml(title)}" style="width:8px;height:8px;border-radius:50%;background:${color};"></span>
+            <span style="color:#e0e0e0;cursor:pointer;" onclick="useSavedConnection('${kind}', ${i})">${escapeHtml(connectionLabel(kind, s.fields))}</span>
+            <span style="color:#8a8a9a;">${escapeHtml(lastSuccess)}</span>
+            <button onclick="forgetSavedConnection('${kind}', '${escapeHtml(s.id)}')" title="Forget" style="background:none;border:none;color:#8a8a9a;cursor:pointer;">&times;</button>
+        </div>`;
+    }).join('');
+}
+
+// TLS options, shared by every connection form. PEM data is never saved.
+const TLS_INPUT_STYLE = 'background:#0d0d14;color:#e0e0e0;border:1px solid #3a3a4e;border-radius:4px;padding:6px;font-size:11px;';
+
+export function renderTlsOptions(prefix) {
+    const container = document.getElementById(`${prefix}-tls-options`);
+    if (!container) return;
+
+    const pem = (id, label) => `<label style="display:flex;flex-direction:column;gap:4px;flex:1;min-width:180px;">
+            <span>${label} <input type="file" accept=".pem,.crt,.key" onchange="loadPemFile(this, '${prefix}-tls-${id}')" style="font-size:11px;"></span>
+            <textarea id="${prefix}-tls-${id}" rows="3" placeholder="-----BEGIN ...-----" style="${TLS_INPUT_STYLE}font-family:monospace;resize:vertical;"></textarea>
+        </label>`;
+    container.innerHTML = `<details style="color:#8a8a9a;font-size:12px;">
+        <summary style="cursor:pointer;">TLS</summary>
+        <div style="display:flex;gap:10px;flex-wrap:wrap;align-items:flex-start;margin-top:8px;">
+            <select id="${prefix}-tls-mode" style="${TLS_INPUT_STYLE}">
+                <option value="">Default</option>
+                <option value="disable">Disabled</option>
+                <option value="require">Encrypt only</option>
+                <option value="verify-ca">Verify CA</option>
+                <option value="verify-full">Verify CA and host</option>
+            </select>
+            <input type="text" id="${prefix}-tls-secret" placeholder="Secret (namespace/name)" title="Secret with ca.crt, tls.crt and tls.key" style="${TLS_INPUT_STYLE}width:180px;">
+            <input type="text" id="${prefix}-tls-server-name" placeholder="Server name" title="Name to verify instead of the host" style="${TLS_INPUT_STYLE}width:140px;">
+        </div>
+        <div style="display:flex;gap:10px;flex-wrap:wrap;margin-top:8px;">
+            ${pem('ca', 'CA bundle')}
+            ${pem('cert', 'Client certificate')}
+            ${pem('key', 'Client key')}
+        </div>
+    </details>`;
+}
+
+export function loadPemFile(input, targetId) {
+    const file = input.files[0];
+    if (!file) return;
+    const reader = new FileReader();
+    reader.onload = () => { document.getElementById(targetId).value = reader.result; };
+    reader.readAsText(file);
+}
+
+// tlsOptions returns the TLS block of a form's config, or undefined when
+// nothing is set
+export function tlsOptions(prefix) {
+    const value = id => (document.getElementById(`${prefix}-tls-${id}`)?.value || '').trim();
+    const tls = {
+        mode: value('mode'),
+        ca_cert: value('ca'),
+        client_cert: value('cert'),
+        client_key: value('key'),
+        server_name: value('server-name')
+    };
+    const secret = value('secret');
+    if (secret) {
+        const [namespace, name] = secret.includes('/') ? secret.split('/') : ['default', secret];
+        tls.secret = { namespace, name };
+    }
+    Object.keys(tls).forEach(k => { if (!tls[k]) delete tls[k]; });
+    return Object.keys(tls).length > 0 ? tls : undefined;
+}
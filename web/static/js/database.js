@@ -1,8 +1,31 @@
-// Database Module for GAGOS (PostgreSQL, Redis, MySQL)
+// Database Module for GAGOS (PostgreSQL, Redis, MySQL, SQL Server)
 
 import { API_BASE } from './app.js';
 import { saveState } from './state.js';
-import { escapeHtml } from './utils.js';
+import { escapeHtml, fetchElevated } from './utils.js';
+import { rememberConnection, tlsOptions } from './connections.js';
+
+// withReplicas adds the replicas typed as "host:port, host:port" and the
+// query target to a PostgreSQL or MySQL config
+function withReplicas(config, prefix) {
+    const replicas = (document.getElementById(`${prefix}-replicas`)?.value || '')
+        .split(',').map(r => r.trim()).filter(Boolean);
+    if (replicas.length > 0) {
+        config.hosts = [{ host: config.host, port: config.port, role: 'primary' }].concat(replicas.map(r => {
+            const [host, port] = r.split(':');
+            return { host, port: parseInt(port) || config.port, role: 'replica' };
+        }));
+    }
+    config.target = document.getElementById(`${prefix}-target`)?.value || 'auto';
+}
+
+// policyNote describes what the connection's result policy did to a result
+function policyNote(d) {
+    const notes = [];
+    if (d.truncated) notes.push('truncated at row cap');
+    if (d.masked_columns?.length) notes.push('masked: ' + d.masked_columns.map(escapeHtml).join(', '));
+    return notes.length ? ` · <span style="color:#f59e0b;">${notes.join(' · ')}</span>` : '';
+}
 
 // ========== PostgreSQL Functions ==========
 
@@ -27,8 +50,12 @@ export async function pgConnect() {
         user: document.getElementById('pg-user').value || 'postgres',
         password: document.getElementById('pg-password').value || '',
         database: document.getElementById('pg-database').value || 'postgres',
-        ssl_mode: document.getElementById('pg-sslmode').value || 'disable'
+        ssl_mode: document.getElementById('pg-sslmode').value || 'disable',
+        auth_mode: document.getElementById('pg-iam').checked ? 'iam' :
+            document.getElementById('pg-krb').checked ? 'kerberos' : 'password',
+        tls: tlsOptions('pg')
     };
+    withReplicas(pgConfig, 'pg');
 
     btn.disabled = true;
     btn.textContent = 'Connecting...';
@@ -44,6 +71,7 @@ export async function pgConnect() {
 
         if (d.success) {
             pgConnected = true;
+            rememberConnection('postgres', d.profile_id, pgConfig);
             status.innerHTML = `<span style="color:#4ade80;">Connected to PostgreSQL ${d.version ? '(' + d.version.split(' ')[0] + ' ' + d.version.split(' ')[1] + ')' : ''}</span>`;
             btn.textContent = 'Reconnect';
             pgLoadInfo();
@@ -155,7 +183,7 @@ export async function pgExecuteQuery() {
     output.innerHTML = '<div style="color:#60a5fa;">Executing...</div>';
 
     try {
-        const r = await fetch(`${API_BASE}/db/postgres/query`, {
+        const r = await fetchElevated(`${API_BASE}/db/postgres/query`, {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify({ ...pgConfig, query, readonly })
@@ -169,14 +197,14 @@ export async function pgExecuteQuery() {
 
         if (d.columns && d.rows) {
             output.innerHTML = `
-                <div style="color:#6b7280;font-size:11px;margin-bottom:8px;">${d.rows.length} row(s) in ${d.duration_ms?.toFixed(2) || 0}ms</div>
+                <div style="color:#6b7280;font-size:11px;margin-bottom:8px;">${d.rows.length} row(s) in ${d.duration_ms?.toFixed(2) || 0}ms${d.host ? ' on ' + escapeHtml(d.host) : ''}${policyNote(d)}</div>
                 <table class="db-table">
                     <thead><tr>${d.columns.map(c => `<th>${escapeHtml(c)}</th>`).join('')}</tr></thead>
                     <tbody>${d.rows.map(row => `<tr>${row.map(v => `<td>${v === null ? '<span style="color:#6b7280;">NULL</span>' : escapeHtml(String(v))}</td>`).join('')}</tr>`).join('')}</tbody>
                 </table>
             `;
         } else {
-            output.innerHTML = `<div style="color:#4ade80;">${d.rows_affected} row(s) affected</div><div style="color:#6b7280;font-size:11px;margin-top:4px;">Duration: ${d.duration_ms?.toFixed(2) || 0}ms</div>`;
+            output.innerHTML = `<div style="color:#4ade80;">${d.rows_affected} row(s) affected${d.host ? ' on ' + escapeHtml(d.host) : ''}</div><div style="color:#6b7280;font-size:11px;margin-top:4px;">Duration: ${d.duration_ms?.toFixed(2) || 0}ms</div>`;
         }
     } catch (e) {
         output.innerHTML = `<div style="color:#ef4444;">Error: ${e.message}</div>`;
@@ -256,7 +284,8 @@ export async function redisConnect() {
         port: parseInt(document.getElementById('redis-port').value) || 6379,
         password: document.getElementById('redis-password').value || '',
         db: parseInt(document.getElementById('redis-db').value) || 0,
-        use_tls: document.getElementById('redis-tls').checked
+        use_tls: document.getElementById('redis-tls').checked,
+        tls: tlsOptions('redis')
     };
 
     btn.disabled = true;
@@ -273,6 +302,7 @@ export async function redisConnect() {
 
         if (d.success) {
             redisConnected = true;
+            rememberConnection('redis', d.profile_id, redisConfig);
             status.innerHTML = `<span style="color:#4ade80;">Connected to Redis ${d.version || ''} (${d.mode || 'standalone'})</span>`;
             btn.textContent = 'Reconnect';
             redisLoadInfo();
@@ -447,7 +477,7 @@ export async function redisExecCommand() {
     output.innerHTML = '<div style="color:#60a5fa;">Executing...</div>';
 
     try {
-        const r = await fetch(`${API_BASE}/db/redis/command`, {
+        const r = await fetchElevated(`${API_BASE}/db/redis/command`, {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify({ ...redisConfig, command })
@@ -558,8 +588,11 @@ export async function mysqlConnect() {
         port: parseInt(document.getElementById('mysql-port').value) || 3306,
         user: document.getElementById('mysql-user').value || 'root',
         password: document.getElementById('mysql-password').value || '',
-        database: document.getElementById('mysql-database').value || ''
+        database: document.getElementById('mysql-database').value || '',
+        auth_mode: document.getElementById('mysql-iam').checked ? 'iam' : 'password',
+        tls: tlsOptions('mysql')
     };
+    withReplicas(mysqlConfig, 'mysql');
 
     btn.disabled = true;
     btn.textContent = 'Connecting...';
@@ -575,6 +608,7 @@ export async function mysqlConnect() {
 
         if (d.success) {
             mysqlConnected = true;
+            rememberConnection('mysql', d.profile_id, mysqlConfig);
             status.innerHTML = `<span style="color:#4ade80;">Connected to ${d.server_type || 'MySQL'} ${d.version || ''}</span>`;
             btn.textContent = 'Reconnect';
             mysqlLoadInfo();
@@ -692,7 +726,7 @@ export async function mysqlExecuteQuery() {
     output.innerHTML = '<div style="color:#60a5fa;">Executing...</div>';
 
     try {
-        const r = await fetch(`${API_BASE}/db/mysql/query`, {
+        const r = await fetchElevated(`${API_BASE}/db/mysql/query`, {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify({ ...mysqlConfig, query, readonly })
@@ -706,14 +740,14 @@ export async function mysqlExecuteQuery() {
 
         if (d.columns && d.rows) {
             output.innerHTML = `
-                <div style="color:#6b7280;font-size:11px;margin-bottom:8px;">${d.rows.length} row(s) in ${d.duration_ms?.toFixed(2) || 0}ms</div>
+                <div style="color:#6b7280;font-size:11px;margin-bottom:8px;">${d.rows.length} row(s) in ${d.duration_ms?.toFixed(2) || 0}ms${d.host ? ' on ' + escapeHtml(d.host) : ''}${policyNote(d)}</div>
                 <table class="db-table">
                     <thead><tr>${d.columns.map(c => `<th>${escapeHtml(c)}</th>`).join('')}</tr></thead>
                     <tbody>${d.rows.map(row => `<tr>${row.map(v => `<td>${v === null ? '<span style="color:#6b7280;">NULL</span>' : escapeHtml(String(v))}</td>`).join('')}</tr>`).join('')}</tbody>
                 </table>
             `;
         } else {
-            output.innerHTML = `<div style="color:#4ade80;">${d.rows_affected} row(s) affected</div><div style="color:#6b7280;font-size:11px;margin-top:4px;">Duration: ${d.duration_ms?.toFixed(2) || 0}ms</div>`;
+            output.innerHTML = `<div style="color:#4ade80;">${d.rows_affected} row(s) affected${d.host ? ' on ' + escapeHtml(d.host) : ''}</div><div style="color:#6b7280;font-size:11px;margin-top:4px;">Duration: ${d.duration_ms?.toFixed(2) || 0}ms</div>`;
         }
     } catch (e) {
         output.innerHTML = `<div style="color:#ef4444;">Error: ${e.message}</div>`;
@@ -770,3 +804,223 @@ export function mysqlDownloadDump() {
     a.click();
     URL.revokeObjectURL(url);
 }
+
+// ========== SQL Server Functions ==========
+
+let mssqlConnected = false;
+let mssqlConfig = {};
+
+export function showMssqlTab(tabId) {
+    document.querySelectorAll('#window-mssql .tab-content').forEach(t => t.classList.remove('active'));
+    document.querySelectorAll('#window-mssql .tab-btn').forEach(b => b.classList.remove('active'));
+    document.getElementById('mssql-tab-' + tabId).classList.add('active');
+    event.target.classList.add('active');
+    if (tabId === 'alwayson') mssqlLoadAlwaysOn();
+    saveState();
+}
+
+export async function mssqlConnect() {
+    const btn = document.querySelector('#window-mssql .conn-form button');
+    const status = document.getElementById('mssql-conn-status');
+
+    mssqlConfig = {
+        host: document.getElementById('mssql-host').value || 'localhost',
+        port: parseInt(document.getElementById('mssql-port').value) || 1433,
+        instance: document.getElementById('mssql-instance').value || '',
+        user: document.getElementById('mssql-user').value || 'sa',
+        password: document.getElementById('mssql-password').value || '',
+        database: document.getElementById('mssql-database').value || '',
+        read_only_intent: document.getElementById('mssql-readonly-intent').checked,
+        auth_mode: document.getElementById('mssql-krb').checked ? 'kerberos' : 'password',
+        tls: tlsOptions('mssql')
+    };
+
+    btn.disabled = true;
+    btn.textContent = 'Connecting...';
+    status.innerHTML = '<span style="color:#60a5fa;">Testing connection...</span>';
+
+    try {
+        const r = await fetch(`${API_BASE}/db/mssql/connect`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(mssqlConfig)
+        });
+        const d = await r.json();
+
+        if (d.success) {
+            mssqlConnected = true;
+            rememberConnection('mssql', d.profile_id, mssqlConfig);
+            status.innerHTML = `<span style="color:#4ade80;">Connected to ${escapeHtml(d.edition || 'SQL Server')}</span>`;
+            btn.textContent = 'Reconnect';
+            mssqlLoadInfo();
+        } else {
+            mssqlConnected = false;
+            status.innerHTML = `<span style="color:#ef4444;">Failed: ${escapeHtml(d.error)}</span>`;
+            btn.textContent = 'Connect';
+        }
+    } catch (e) {
+        status.innerHTML = `<span style="color:#ef4444;">Error: ${e.message}</span>`;
+        btn.textContent = 'Connect';
+    }
+    btn.disabled = false;
+}
+
+export async function mssqlLoadInfo() {
+    if (!mssqlConnected) return;
+    const container = document.getElementById('mssql-info-content');
+    container.innerHTML = '<div style="color:#60a5fa;">Loading...</div>';
+
+    try {
+        const r = await fetch(`${API_BASE}/db/mssql/info`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(mssqlConfig)
+        });
+        const d = await r.json();
+
+        if (d.error) {
+            container.innerHTML = `<div style="color:#ef4444;">${escapeHtml(d.error)}</div>`;
+            return;
+        }
+
+        let tablesHtml = '';
+        if (d.tables && d.tables.length > 0) {
+            tablesHtml = `
+                <div class="info-section">
+                    <h4>Tables (Top ${d.tables.length})</h4>
+                    <table class="db-table">
+                        <thead><tr><th>Schema</th><th>Name</th><th>Rows</th><th>Data Size</th><th>Index Size</th></tr></thead>
+                        <tbody>
+                            ${d.tables.map(t => `<tr><td>${escapeHtml(t.schema)}</td><td>${escapeHtml(t.name)}</td><td>${t.row_count.toLocaleString()}</td><td>${t.data_size}</td><td>${t.index_size}</td></tr>`).join('')}
+                        </tbody>
+                    </table>
+                </div>
+            `;
+        }
+
+        container.innerHTML = `
+            <div class="db-stats-grid">
+                <div class="db-stat-card">
+                    <div class="stat-label">Server</div>
+                    <div class="stat-value">${escapeHtml(d.server_name || 'N/A')}</div>
+                </div>
+                <div class="db-stat-card">
+                    <div class="stat-label">Edition</div>
+                    <div class="stat-value" style="font-size:12px;">${escapeHtml(d.edition || 'N/A')}</div>
+                </div>
+                <div class="db-stat-card">
+                    <div class="stat-label">Database Size</div>
+                    <div class="stat-value">${d.database_size || 'N/A'}</div>
+                </div>
+                <div class="db-stat-card">
+                    <div class="stat-label">Tables</div>
+                    <div class="stat-value">${d.table_count || 0}</div>
+                </div>
+                <div class="db-stat-card">
+                    <div class="stat-label">Connections</div>
+                    <div class="stat-value">${d.connections || 0} / ${d.max_connections || 0}</div>
+                </div>
+                <div class="db-stat-card">
+                    <div class="stat-label">Uptime</div>
+                    <div class="stat-value">${d.uptime_human || 'N/A'}</div>
+                </div>
+                <div class="db-stat-card">
+                    <div class="stat-label">Always On</div>
+                    <div class="stat-value">${d.hadr_enabled ? 'Enabled' : 'Disabled'}</div>
+                </div>
+            </div>
+            <div style="color:#6b7280;font-size:11px;margin-top:8px;white-space:pre-wrap;">${escapeHtml(d.version || '')}</div>
+            ${tablesHtml}
+        `;
+    } catch (e) {
+        container.innerHTML = `<div style="color:#ef4444;">Error: ${e.message}</div>`;
+    }
+}
+
+export async function mssqlExecuteQuery() {
+    if (!mssqlConnected) {
+        alert('Please connect first');
+        return;
+    }
+    const query = document.getElementById('mssql-query-input').value.trim();
+    if (!query) return;
+
+    const readonly = document.getElementById('mssql-readonly').checked;
+    const output = document.getElementById('mssql-query-output');
+    output.innerHTML = '<div style="color:#60a5fa;">Executing...</div>';
+
+    try {
+        const r = await fetchElevated(`${API_BASE}/db/mssql/query`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ ...mssqlConfig, query, readonly })
+        });
+        const d = await r.json();
+
+        if (d.error) {
+            output.innerHTML = `<div style="color:#ef4444;">Error: ${escapeHtml(d.error)}</div><div style="color:#6b7280;font-size:11px;margin-top:4px;">Duration: ${d.duration_ms?.toFixed(2) || 0}ms</div>`;
+            return;
+        }
+
+        if (d.columns && d.rows) {
+            output.innerHTML = `
+                <div style="color:#6b7280;font-size:11px;margin-bottom:8px;">${d.rows.length} row(s) in ${d.duration_ms?.toFixed(2) || 0}ms${policyNote(d)}</div>
+                <table class="db-table">
+                    <thead><tr>${d.columns.map(c => `<th>${escapeHtml(c)}</th>`).join('')}</tr></thead>
+                    <tbody>${d.rows.map(row => `<tr>${row.map(v => `<td>${v === null ? '<span style="color:#6b7280;">NULL</span>' : escapeHtml(String(v))}</td>`).join('')}</tr>`).join('')}</tbody>
+                </table>
+            `;
+        } else {
+            output.innerHTML = `<div style="color:#4ade80;">${d.rows_affected} row(s) affected</div><div style="color:#6b7280;font-size:11px;margin-top:4px;">Duration: ${d.duration_ms?.toFixed(2) || 0}ms</div>`;
+        }
+    } catch (e) {
+        output.innerHTML = `<div style="color:#ef4444;">Error: ${e.message}</div>`;
+    }
+}
+
+export async function mssqlLoadAlwaysOn() {
+    if (!mssqlConnected) return;
+    const container = document.getElementById('mssql-alwayson-content');
+    container.innerHTML = '<div style="color:#60a5fa;">Loading...</div>';
+
+    try {
+        const r = await fetch(`${API_BASE}/db/mssql/alwayson`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(mssqlConfig)
+        });
+        const d = await r.json();
+
+        if (d.error) {
+            container.innerHTML = `<div style="color:#ef4444;">${escapeHtml(d.error)}</div>`;
+            return;
+        }
+        if (!d.enabled) {
+            container.innerHTML = '<div style="color:#8a8a9a;">Always On availability groups are not enabled on this server</div>';
+            return;
+        }
+        if (!d.groups || d.groups.length === 0) {
+            container.innerHTML = '<div style="color:#8a8a9a;">No availability groups</div>';
+            return;
+        }
+
+        const healthColor = h => h === 'HEALTHY' ? '#4ade80' : (h === 'PARTIALLY_HEALTHY' ? '#fbbf24' : '#ef4444');
+        const queue = v => v === undefined || v === null ? '-' : `${v.toLocaleString()} KB`;
+        container.innerHTML = d.groups.map(g => `
+            <div class="info-section">
+                <h4>${escapeHtml(g.name)} <span style="color:${healthColor(g.synchronization_health)};font-size:12px;">${escapeHtml(g.synchronization_health)}</span></h4>
+                <div style="color:#8a8a9a;font-size:12px;margin-bottom:8px;">Primary: ${escapeHtml(g.primary_replica || 'unknown')}</div>
+                <table class="db-table">
+                    <thead><tr><th>Replica</th><th>Role</th><th>Availability</th><th>Failover</th><th>Connected</th><th>Health</th></tr></thead>
+                    <tbody>${g.replicas.map(rep => `<tr><td>${escapeHtml(rep.server)}</td><td>${escapeHtml(rep.role)}</td><td>${escapeHtml(rep.availability_mode)}</td><td>${escapeHtml(rep.failover_mode)}</td><td>${escapeHtml(rep.connected_state)}</td><td style="color:${healthColor(rep.synchronization_health)};">${escapeHtml(rep.synchronization_health)}</td></tr>`).join('')}</tbody>
+                </table>
+                <table class="db-table" style="margin-top:8px;">
+                    <thead><tr><th>Database</th><th>Replica</th><th>State</th><th>Health</th><th>Log Send Queue</th><th>Redo Queue</th></tr></thead>
+                    <tbody>${g.databases.map(db => `<tr><td>${escapeHtml(db.database)}</td><td>${escapeHtml(db.replica)}</td><td>${escapeHtml(db.synchronization_state)}</td><td style="color:${healthColor(db.synchronization_health)};">${escapeHtml(db.synchronization_health)}</td><td>${queue(db.log_send_queue_kb)}</td><td>${queue(db.redo_queue_kb)}</td></tr>`).join('')}</tbody>
+                </table>
+            </div>
+        `).join('');
+    } catch (e) {
+        container.innerHTML = `<div style="color:#ef4444;">Error: ${e.message}</div>`;
+    }
+}
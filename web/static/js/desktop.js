@@ -16,6 +16,7 @@ export const DESKTOP_ICONS = [
     { id: 'postgres', name: 'PostgreSQL', color: '#336791', svg: '<path stroke-linecap="round" stroke-linejoin="round" stroke-width="1.5" d="M4 7v10c0 2.21 3.582 4 8 4s8-1.79 8-4V7M4 7c0 2.21 3.582 4 8 4s8-1.79 8-4M4 7c0-2.21 3.582-4 8-4s8 1.79 8 4m0 5c0 2.21-3.582 4-8 4s-8-1.79-8-4"></path>' },
     { id: 'redis', name: 'Redis', color: '#dc382d', svg: '<path stroke-linecap="round" stroke-linejoin="round" stroke-width="1.5" d="M5 12h14M5 12l4-4m-4 4l4 4M19 12l-4-4m4 4l-4 4"></path><path stroke-linecap="round" stroke-linejoin="round" stroke-width="1.5" d="M12 5v14"></path>' },
     { id: 'mysql', name: 'MySQL', color: '#00758f', svg: '<path stroke-linecap="round" stroke-linejoin="round" stroke-width="1.5" d="M4 7v10c0 2.21 3.582 4 8 4s8-1.79 8-4V7"></path><path stroke-linecap="round" stroke-linejoin="round" stroke-width="1.5" d="M20 7c0 2.21-3.582 4-8 4S4 9.21 4 7s3.582-4 8-4 8 1.79 8 4z"></path><path stroke-linecap="round" stroke-linejoin="round" stroke-width="1.5" d="M4 12c0 2.21 3.582 4 8 4s8-1.79 8-4"></path>' },
+    { id: 'mssql', name: 'SQL Server', color: '#cc2927', svg: '<path stroke-linecap="round" stroke-linejoin="round" stroke-width="1.5" d="M4 7v10c0 2.21 3.582 4 8 4s8-1.79 8-4V7"></path><path stroke-linecap="round" stroke-linejoin="round" stroke-width="1.5" d="M20 7c0 2.21-3.582 4-8 4S4 9.21 4 7s3.582-4 8-4 8 1.79 8 4z"></path><path stroke-linecap="round" stroke-linejoin="round" stroke-width="1.5" d="M4 12c0 2.21 3.582 4 8 4s8-1.79 8-4"></path>' },
     { id: 's3', name: 'S3 Storage', color: '#ff9900', svg: '<path stroke-linecap="round" stroke-linejoin="round" stroke-width="1.5" d="M5 8h14M5 8a2 2 0 110-4h14a2 2 0 110 4M5 8v10a2 2 0 002 2h10a2 2 0 002-2V8m-9 4h4"/>' },
     { id: 'elasticsearch', name: 'Elasticsearch', color: '#f59e0b', svg: '<path stroke-linecap="round" stroke-linejoin="round" stroke-width="1.5" d="M21 21l-6-6m2-5a7 7 0 11-14 0 7 7 0 0114 0z"/>' }
 ];
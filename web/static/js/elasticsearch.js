@@ -1,7 +1,8 @@
 // Elasticsearch Module for GAGOS
 
 import { API_BASE } from './app.js';
-import { escapeHtml, formatSize } from './utils.js';
+import { escapeHtml, fetchElevated, formatSize } from './utils.js';
+import { rememberConnection, tlsOptions } from './connections.js';
 
 let esConnected = false;
 let esConfig = {};
@@ -50,7 +51,7 @@ export async function esConnect() {
 
     statusEl.innerHTML = '<span style="color:#60a5fa;">Connecting...</span>';
 
-    esConfig = { host, port, username, password, use_ssl: useSSL };
+    esConfig = { host, port, username, password, use_ssl: useSSL, tls: tlsOptions('es') };
 
     try {
         const resp = await fetch(`${API_BASE}/elasticsearch/connect`, {
@@ -62,6 +63,7 @@ export async function esConnect() {
 
         if (data.success) {
             esConnected = true;
+            rememberConnection('elasticsearch', data.profile_id, esConfig);
             statusEl.innerHTML = `<span style="color:#4ade80;">Connected to ${escapeHtml(data.cluster_name)} (v${data.version}) - ${data.response_time_ms.toFixed(0)}ms</span>`;
             esLoadClusterInfo();
             esLoadIndices();
@@ -480,7 +482,7 @@ export async function esExecuteQuery() {
             bodyJson = JSON.parse(body);
         }
 
-        const resp = await fetch(`${API_BASE}/elasticsearch/query`, {
+        const resp = await fetchElevated(`${API_BASE}/elasticsearch/query`, {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify({ ...esConfig, method, path, body: bodyJson })
@@ -2,7 +2,7 @@
 // Improved UI with tabbed interface
 
 import { API_BASE } from './app.js';
-import { formatDuration, formatTime } from './utils.js';
+import { escapeHtml, formatDuration, formatTime } from './utils.js';
 import { getRunStatusClass } from './cicd.js';
 
 let sshHosts = [];
@@ -23,6 +23,8 @@ let jobEnabled = true;
 let webhookEnabled = false;
 let cronEnabled = false;
 let cronSchedule = '';
+let pollEnabled = false;
+let pollSchedule = '';
 
 // SCM state
 let scmType = 'none';
@@ -458,6 +460,8 @@ function getFreestyleTriggerIcons(triggers) {
             icons += '<span title="Webhook" style="color:#22d3ee;margin-right:8px;">&#128279;</span>';
         } else if (t.type === 'cron' && t.enabled) {
             icons += `<span title="Cron: ${t.schedule}" style="color:#a78bfa;margin-right:8px;">&#9200;</span>`;
+        } else if (t.type === 'pollSCM' && t.enabled) {
+            icons += `<span title="Poll SCM: ${t.schedule}" style="color:#4ade80;margin-right:8px;">&#128260;</span>`;
         }
     });
     return icons || '<span style="color:#6a6a7a;">Manual only</span>';
@@ -477,6 +481,8 @@ export async function showCreateFreestyleJobModal() {
     webhookEnabled = false;
     cronEnabled = false;
     cronSchedule = '';
+    pollEnabled = false;
+    pollSchedule = '';
 
     // Reset SCM state
     scmType = 'none';
@@ -586,6 +592,10 @@ function saveCurrentTabData() {
         if (webhookEl) webhookEnabled = webhookEl.checked;
         if (cronEnabledEl) cronEnabled = cronEnabledEl.checked;
         if (cronScheduleEl) cronSchedule = cronScheduleEl.value;
+        const pollEnabledEl = document.getElementById('freestyle-job-poll-enabled');
+        const pollScheduleEl = document.getElementById('freestyle-job-poll');
+        if (pollEnabledEl) pollEnabled = pollEnabledEl.checked;
+        if (pollScheduleEl) pollSchedule = pollScheduleEl.value;
     }
 }
 
@@ -884,6 +894,7 @@ function renderStepsTab() {
     const hostOptions = sshHosts.map(h =>
         `<option value="${h.id}">${h.name} (${h.host})</option>`
     ).join('');
+    const hostGroups = [...new Set(sshHosts.flatMap(h => h.host_groups || []))].sort();
 
     let stepsHtml = '';
 
@@ -899,8 +910,12 @@ function renderStepsTab() {
                 'shell': '💻 Shell Command',
                 'script': '📜 Script',
                 'scp_push': '📤 SCP Push (to remote)',
-                'scp_pull': '📥 SCP Pull (from remote)'
+                'scp_pull': '📥 SCP Pull (from remote)',
+                'sftp_upload': '📁 SFTP Upload (directory to remote)',
+                'sftp_download': '📂 SFTP Download (directory from remote)'
             };
+            const isCopy = step.type === 'scp_push' || step.type === 'scp_pull';
+            const isSftp = step.type === 'sftp_upload' || step.type === 'sftp_download';
 
             const selectedHost = sshHosts.find(h => h.id === step.host_id);
             const hostName = selectedHost ? selectedHost.name : 'Select host...';
@@ -934,10 +949,13 @@ function renderStepsTab() {
                             <label style="display:block;font-size:11px;color:#8a8a9a;margin-bottom:6px;text-transform:uppercase;font-weight:600;">Host</label>
                             <select onchange="updateStep(${i}, 'host_id', this.value)"
                                 style="width:100%;padding:10px;background:rgba(20,20,30,0.8);border:1px solid rgba(255,255,255,0.15);border-radius:6px;color:#fff;font-size:13px;">
-                                <option value="local" ${step.host_id === 'local' || step.host_id === '' ? 'selected' : ''}>🖥️ Local (container)</option>
+                                <option value="local" ${!step.host_group && (step.host_id === 'local' || step.host_id === '') ? 'selected' : ''}>🖥️ Local (container)</option>
                                 ${sshHosts.map(h => `<option value="${h.id}" ${step.host_id === h.id ? 'selected' : ''}>${h.name} (${h.host})</option>`).join('')}
+                                ${hostGroups.length > 0 ? `<optgroup label="Host groups (every host)">
+                                    ${hostGroups.map(g => `<option value="group:${escapeHtml(g)}" ${step.host_group === g ? 'selected' : ''}>👥 ${escapeHtml(g)}</option>`).join('')}
+                                </optgroup>` : ''}
                             </select>
-                            <small style="color:#6a6a7a;font-size:11px;margin-top:4px;display:block;">${step.host_id && step.host_id !== 'local' ? 'Execute on remote SSH host' : 'Execute inside GAGOS container'}</small>
+                            <small style="color:#6a6a7a;font-size:11px;margin-top:4px;display:block;">${step.host_group ? 'Execute on every host of the group' : step.host_id && step.host_id !== 'local' ? 'Execute on remote SSH host' : 'Execute inside GAGOS container'}</small>
                         </div>
                         <div>
                             <label style="display:block;font-size:11px;color:#8a8a9a;margin-bottom:6px;text-transform:uppercase;font-weight:600;">Type</label>
@@ -945,27 +963,39 @@ function renderStepsTab() {
                                 style="width:100%;padding:10px;background:rgba(20,20,30,0.8);border:1px solid rgba(255,255,255,0.15);border-radius:6px;color:#fff;font-size:13px;">
                                 <option value="shell" ${step.type === 'shell' ? 'selected' : ''}>💻 Shell Command</option>
                                 <option value="script" ${step.type === 'script' ? 'selected' : ''}>📜 Script</option>
-                                ${step.host_id && step.host_id !== 'local' ? `
+                                ${step.host_group || (step.host_id && step.host_id !== 'local') ? `
                                 <option value="scp_push" ${step.type === 'scp_push' ? 'selected' : ''}>📤 SCP Push (to remote)</option>
+                                <option value="sftp_upload" ${step.type === 'sftp_upload' ? 'selected' : ''}>📁 SFTP Upload (directory to remote)</option>
+                                ` : ''}
+                                ${!step.host_group && step.host_id && step.host_id !== 'local' ? `
                                 <option value="scp_pull" ${step.type === 'scp_pull' ? 'selected' : ''}>📥 SCP Pull (from remote)</option>
+                                <option value="sftp_download" ${step.type === 'sftp_download' ? 'selected' : ''}>📂 SFTP Download (directory from remote)</option>
                                 ` : ''}
                             </select>
                         </div>
                     </div>
 
-                    ${step.type === 'scp_push' || step.type === 'scp_pull' ? `
+                    ${isCopy || isSftp ? `
                     <div style="display:grid;grid-template-columns:1fr 1fr;gap:16px;margin-bottom:16px;">
                         <div>
                             <label style="display:block;font-size:11px;color:#8a8a9a;margin-bottom:6px;text-transform:uppercase;font-weight:600;">Local Path</label>
-                            <input type="text" value="${step.local_path || ''}" onchange="updateStep(${i}, 'local_path', this.value)" placeholder="/local/path/file.txt"
+                            <input type="text" value="${step.local_path || ''}" onchange="updateStep(${i}, 'local_path', this.value)" placeholder="${isSftp ? '/local/path/dir' : '/local/path/file.txt'}"
                                 style="width:100%;padding:10px;background:rgba(20,20,30,0.8);border:1px solid rgba(255,255,255,0.15);border-radius:6px;color:#fff;font-size:13px;font-family:monospace;">
                         </div>
                         <div>
                             <label style="display:block;font-size:11px;color:#8a8a9a;margin-bottom:6px;text-transform:uppercase;font-weight:600;">Remote Path</label>
-                            <input type="text" value="${step.remote_path || ''}" onchange="updateStep(${i}, 'remote_path', this.value)" placeholder="/remote/path/file.txt"
+                            <input type="text" value="${step.remote_path || ''}" onchange="updateStep(${i}, 'remote_path', this.value)" placeholder="${isSftp ? '/remote/path/dir' : '/remote/path/file.txt'}"
                                 style="width:100%;padding:10px;background:rgba(20,20,30,0.8);border:1px solid rgba(255,255,255,0.15);border-radius:6px;color:#fff;font-size:13px;font-family:monospace;">
                         </div>
                     </div>
+                    ${isSftp ? `
+                    <div style="margin-bottom:16px;">
+                        <label style="display:block;font-size:11px;color:#8a8a9a;margin-bottom:6px;text-transform:uppercase;font-weight:600;">Patterns</label>
+                        <input type="text" value="${escapeHtml((step.patterns || []).join(', '))}" onchange="updateStep(${i}, 'patterns', this.value.split(',').map(p => p.trim()).filter(p => p))" placeholder="all files, or e.g. **/*.js, static/**"
+                            style="width:100%;padding:10px;background:rgba(20,20,30,0.8);border:1px solid rgba(255,255,255,0.15);border-radius:6px;color:#fff;font-size:13px;font-family:monospace;">
+                        <small style="color:#6a6a7a;font-size:11px;margin-top:4px;display:block;">Globs relative to the source directory; ** matches any number of directories</small>
+                    </div>
+                    ` : ''}
                     ` : `
                     <div style="margin-bottom:16px;">
                         <label style="display:block;font-size:11px;color:#8a8a9a;margin-bottom:6px;text-transform:uppercase;font-weight:600;">${step.type === 'script' ? 'Script Content' : 'Command'}</label>
@@ -986,6 +1016,19 @@ function renderStepsTab() {
                                 style="width:16px;height:16px;accent-color:#22d3ee;">
                             <span style="font-size:12px;color:#8a8a9a;">Continue on error</span>
                         </label>
+                        ${step.host_group ? `
+                        <div style="display:flex;align-items:center;gap:8px;">
+                            <label style="font-size:12px;color:#8a8a9a;">Hosts at a time:</label>
+                            <input type="number" min="1" value="${step.concurrency || 1}" onchange="updateStep(${i}, 'concurrency', parseInt(this.value) || 1)"
+                                style="width:60px;padding:6px 10px;background:rgba(20,20,30,0.8);border:1px solid rgba(255,255,255,0.15);border-radius:4px;color:#fff;font-size:13px;">
+                        </div>
+                        ` : ''}
+                        <div style="display:flex;align-items:center;gap:8px;">
+                            <label style="font-size:12px;color:#8a8a9a;">Parallel group:</label>
+                            <input type="text" value="${step.parallel || ''}" onchange="updateStep(${i}, 'parallel', this.value.trim())" placeholder="none"
+                                title="Adjacent steps in the same group run at the same time"
+                                style="width:120px;padding:6px 10px;background:rgba(20,20,30,0.8);border:1px solid rgba(255,255,255,0.15);border-radius:4px;color:#fff;font-size:13px;">
+                        </div>
                     </div>
                 </div>
             </div>`;
@@ -1022,22 +1065,40 @@ export function addBuildStep() {
 
 export function updateStep(index, field, value) {
     if (buildSteps[index]) {
+        // A host group is picked from the host list
+        if (field === 'host_id') {
+            if (value.startsWith('group:')) {
+                buildSteps[index].host_group = value.slice('group:'.length);
+                value = '';
+            } else {
+                delete buildSteps[index].host_group;
+                delete buildSteps[index].concurrency;
+            }
+        }
         buildSteps[index][field] = value;
 
         // When host changes, re-render to update available step types
         if (field === 'host_id') {
             // If switching to local, reset SCP types to shell (SCP requires remote host)
-            if (value === 'local' || value === '') {
-                const currentType = buildSteps[index].type;
-                if (currentType === 'scp_push' || currentType === 'scp_pull') {
+            const currentType = buildSteps[index].type;
+            if ((value === 'local' || value === '') && !buildSteps[index].host_group) {
+                if (['scp_push', 'scp_pull', 'sftp_upload', 'sftp_download'].includes(currentType)) {
                     buildSteps[index].type = 'shell';
                 }
+            } else if (buildSteps[index].host_group && (currentType === 'scp_pull' || currentType === 'sftp_download')) {
+                buildSteps[index].type = 'shell';
+            }
+            if (buildSteps[index].type === 'shell') {
+                delete buildSteps[index].patterns;
             }
             renderJobModal();
         }
 
         // Re-render if type changed to update UI
         if (field === 'type') {
+            if (value !== 'sftp_upload' && value !== 'sftp_download') {
+                delete buildSteps[index].patterns;
+            }
             renderJobModal();
         }
     }
@@ -1095,6 +1156,8 @@ function renderParamsTab() {
                             <option value="string" ${param.type === 'string' ? 'selected' : ''}>Text (String)</option>
                             <option value="boolean" ${param.type === 'boolean' ? 'selected' : ''}>Checkbox (Boolean)</option>
                             <option value="choice" ${param.type === 'choice' ? 'selected' : ''}>Dropdown (Choice)</option>
+                            <option value="password" ${param.type === 'password' ? 'selected' : ''}>Password (masked)</option>
+                            <option value="file" ${param.type === 'file' ? 'selected' : ''}>File Upload</option>
                         </select>
                     </div>
                     <div>
@@ -1259,6 +1322,31 @@ function renderTriggersTab() {
             </div>
         </div>
 
+        <div style="background:rgba(30,30,40,0.5);border:1px solid rgba(255,255,255,0.1);border-radius:8px;padding:20px;margin-top:16px;">
+            <div style="display:flex;align-items:center;gap:12px;margin-bottom:16px;">
+                <span style="font-size:24px;">🔄</span>
+                <div style="flex:1;">
+                    <h4 style="margin:0;color:#e0e0e0;font-size:15px;">Poll SCM</h4>
+                    <p style="margin:4px 0 0;color:#6a6a7a;font-size:12px;">Check the Git branch on a schedule and build when it has new commits</p>
+                </div>
+                <label style="display:flex;align-items:center;gap:8px;cursor:pointer;">
+                    <input type="checkbox" id="freestyle-job-poll-enabled" ${pollEnabled ? 'checked' : ''} onchange="togglePoll(this.checked)"
+                        style="width:20px;height:20px;accent-color:#22d3ee;">
+                    <span style="font-size:13px;color:#8a8a9a;">Enable</span>
+                </label>
+            </div>
+
+            <div id="poll-settings" style="display:${pollEnabled ? 'block' : 'none'};">
+                <label style="display:block;font-size:11px;color:#8a8a9a;margin-bottom:8px;text-transform:uppercase;">Polling Schedule</label>
+                <input type="text" id="freestyle-job-poll" value="${pollSchedule}" placeholder="*/5 * * * *"
+                    style="width:100%;padding:12px;background:rgba(20,20,30,0.8);border:1px solid rgba(255,255,255,0.15);border-radius:6px;color:#fff;font-size:14px;font-family:monospace;box-sizing:border-box;">
+                <div style="margin-top:10px;font-size:11px;color:#6a6a7a;">
+                    Polls the first repository and branch of the Source Code tab with <code style="background:rgba(255,255,255,0.1);padding:2px 6px;border-radius:3px;">git ls-remote</code>.
+                    The repository must be HTTPS; private ones need a token or password credential.
+                </div>
+            </div>
+        </div>
+
         <div style="margin-top:16px;padding:16px;background:rgba(34,211,238,0.1);border:1px solid rgba(34,211,238,0.2);border-radius:8px;">
             <div style="display:flex;align-items:center;gap:10px;">
                 <span style="font-size:18px;">💡</span>
@@ -1299,6 +1387,11 @@ export function toggleCron(enabled) {
     document.getElementById('cron-settings').style.display = enabled ? 'block' : 'none';
 }
 
+export function togglePoll(enabled) {
+    pollEnabled = enabled;
+    document.getElementById('poll-settings').style.display = enabled ? 'block' : 'none';
+}
+
 export function setCronPreset(value) {
     cronSchedule = value;
     cronEnabled = true;
@@ -1348,6 +1441,14 @@ export async function saveFreestyleJob(e) {
         environment: {}
     };
 
+    // Keep the settings this form does not edit
+    if (editingJob) {
+        data.retention = editingJob.retention;
+        data.post_build = editingJob.post_build;
+        data.workspace = editingJob.workspace;
+        data.archive_artifacts = editingJob.archive_artifacts;
+    }
+
     // Add SCM configuration if Git is enabled
     if (scmType === 'git') {
         data.scm = {
@@ -1376,6 +1477,10 @@ export async function saveFreestyleJob(e) {
         data.triggers.push({ type: 'cron', schedule: cronSchedule, enabled: true });
     }
 
+    if (pollEnabled && pollSchedule) {
+        data.triggers.push({ type: 'pollSCM', schedule: pollSchedule, enabled: true });
+    }
+
     // Validation
     if (!data.name) {
         alert('Job name is required');
@@ -1389,13 +1494,13 @@ export async function saveFreestyleJob(e) {
         return;
     }
 
-    // Check if SCP steps have remote hosts (SCP requires SSH)
+    // Check if SCP and SFTP steps have remote hosts (they require SSH)
     const scpStepsWithoutHost = buildSteps.filter(s =>
-        (s.type === 'scp_push' || s.type === 'scp_pull') &&
-        (!s.host_id || s.host_id === 'local')
+        ['scp_push', 'scp_pull', 'sftp_upload', 'sftp_download'].includes(s.type) &&
+        !s.host_group && (!s.host_id || s.host_id === 'local')
     );
     if (scpStepsWithoutHost.length > 0) {
-        alert(`SCP step "${scpStepsWithoutHost[0].name || 'unnamed'}" requires a remote SSH host.`);
+        alert(`${scpStepsWithoutHost[0].type.startsWith('sftp') ? 'SFTP' : 'SCP'} step "${scpStepsWithoutHost[0].name || 'unnamed'}" requires a remote SSH host.`);
         switchJobTab('steps');
         return;
     }
@@ -1441,6 +1546,9 @@ export async function editFreestyleJob(id) {
     webhookEnabled = webhookTrigger?.enabled || false;
     cronEnabled = cronTrigger?.enabled || false;
     cronSchedule = cronTrigger?.schedule || '';
+    const pollTrigger = triggers.find(t => t.type === 'pollSCM');
+    pollEnabled = pollTrigger?.enabled || false;
+    pollSchedule = pollTrigger?.schedule || '';
 
     // Initialize SCM state from job
     if (job.scm && job.scm.type === 'git') {
@@ -1557,10 +1665,17 @@ function showRunJobModal(job) {
                 </select>
                 ${p.description ? `<small style="color:#6a6a7a;margin-top:4px;display:block;">${p.description}</small>` : ''}
             </div>`;
+        } else if (p.type === 'file') {
+            return `<div style="margin-bottom:16px;">
+                <label style="display:block;margin-bottom:6px;font-weight:500;color:#e0e0e0;">${p.name}${p.required ? ' *' : ''}</label>
+                <input type="file" id="run-param-${i}" ${p.required ? 'required' : ''}
+                    style="width:100%;padding:10px;background:rgba(20,20,30,0.8);border:1px solid rgba(255,255,255,0.15);border-radius:6px;color:#fff;font-size:14px;">
+                ${p.description ? `<small style="color:#6a6a7a;margin-top:4px;display:block;">${p.description}</small>` : ''}
+            </div>`;
         } else {
             return `<div style="margin-bottom:16px;">
                 <label style="display:block;margin-bottom:6px;font-weight:500;color:#e0e0e0;">${p.name}${p.required ? ' *' : ''}</label>
-                <input type="text" id="run-param-${i}" value="${p.default_value || ''}" ${p.required ? 'required' : ''}
+                <input type="${p.type === 'password' ? 'password' : 'text'}" id="run-param-${i}" value="${p.default_value || ''}" ${p.required ? 'required' : ''}
                     style="width:100%;padding:10px;background:rgba(20,20,30,0.8);border:1px solid rgba(255,255,255,0.15);border-radius:6px;color:#fff;font-size:14px;">
                 ${p.description ? `<small style="color:#6a6a7a;margin-top:4px;display:block;">${p.description}</small>` : ''}
             </div>`;
@@ -1602,11 +1717,14 @@ function showRunJobModal(job) {
 
 window.submitJobRun = async function(jobId, parameters) {
     const params = {};
+    const files = {};
     parameters.forEach((p, i) => {
         const el = document.getElementById(`run-param-${i}`);
         if (el) {
             if (p.type === 'boolean') {
                 params[p.name] = el.checked ? 'true' : 'false';
+            } else if (p.type === 'file') {
+                if (el.files.length > 0) files[p.name] = el.files[0];
             } else {
                 params[p.name] = el.value;
             }
@@ -1614,16 +1732,24 @@ window.submitJobRun = async function(jobId, parameters) {
     });
 
     document.getElementById('run-job-modal').remove();
-    await triggerJobBuild(jobId, params);
+    await triggerJobBuild(jobId, params, files);
 };
 
-async function triggerJobBuild(jobId, parameters) {
+async function triggerJobBuild(jobId, parameters, files = {}) {
     try {
-        const r = await fetch(`${API_BASE}/cicd/freestyle/jobs/${jobId}/build`, {
+        let request = {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify({ parameters })
-        });
+        };
+        // File parameters are uploaded as a multipart form
+        if (Object.keys(files).length > 0) {
+            const form = new FormData();
+            Object.entries(parameters).forEach(([name, value]) => form.append(name, value));
+            Object.entries(files).forEach(([name, file]) => form.append(name, file));
+            request = { method: 'POST', body: form };
+        }
+        const r = await fetch(`${API_BASE}/cicd/freestyle/jobs/${jobId}/build`, request);
         const d = await r.json();
         if (d.error) {
             alert('Error: ' + d.error);
@@ -1844,6 +1970,7 @@ window.updateParameter = updateParameter;
 window.removeParameter = removeParameter;
 window.toggleWebhook = toggleWebhook;
 window.toggleCron = toggleCron;
+window.togglePoll = togglePoll;
 window.setCronPreset = setCronPreset;
 window.updateCronPreview = updateCronPreview;
 window.copyWebhookUrl = copyWebhookUrl;
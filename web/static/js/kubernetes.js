@@ -99,6 +99,44 @@ spec:
             port:
               number: 80`,
 
+    networkpolicy: `apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-from-frontend
+spec:
+  podSelector:
+    matchLabels:
+      app: my-app
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - podSelector:
+        matchLabels:
+          app: frontend
+    ports:
+    - protocol: TCP
+      port: 80`,
+
+    pdb: `apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: my-pdb
+spec:
+  minAvailable: 1
+  selector:
+    matchLabels:
+      app: my-app`,
+
+    storageclass: `apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: my-storageclass
+provisioner: kubernetes.io/no-provisioner
+reclaimPolicy: Delete
+volumeBindingMode: WaitForFirstConsumer
+allowVolumeExpansion: true`,
+
     pod: `apiVersion: v1
 kind: Pod
 metadata:
@@ -479,7 +517,7 @@ export async function loadCronJobs() {
         if (d.cronjobs?.length > 0) {
             d.cronjobs.forEach(cj => {
                 const suspendClass = cj.suspend ? 'status-pending' : '';
-                tbody.innerHTML += `<tr><td style="font-family:monospace;font-size:12px">${cj.name}</td><td>${cj.namespace}</td><td style="font-family:monospace;font-size:11px">${cj.schedule}</td><td class="${suspendClass}">${cj.suspend?'Yes':'No'}</td><td>${cj.active}</td><td>${cj.last_schedule||'-'}</td><td>${cj.age||'-'}</td>${getActionButtons('cronjob', cj.namespace, cj.name)}</tr>`;
+                tbody.innerHTML += `<tr><td style="font-family:monospace;font-size:12px">${cj.name}</td><td>${cj.namespace}</td><td style="font-family:monospace;font-size:11px">${cj.schedule}</td><td class="${suspendClass}">${cj.suspend?'Yes':'No'}</td><td>${cj.active}</td><td>${cj.last_schedule||'-'}</td><td>${cj.age||'-'}</td>${getActionButtons('cronjob', cj.namespace, cj.name, { suspended: cj.suspend })}</tr>`;
             });
         } else {
             tbody.innerHTML = '<tr><td colspan="8" style="text-align:center;color:#6a6a7a">No cronjobs found</td></tr>';
@@ -585,26 +623,64 @@ export function loadPVCsForNamespace() {
     loadPVCs();
 }
 
-export async function loadEvents() {
-    try {
-        const url = selectedNamespace ? `${API_BASE}/k8s/events/${selectedNamespace}` : `${API_BASE}/k8s/events`;
-        const r = await fetch(url);
-        const d = await r.json();
-        const tbody = document.getElementById('events-tbody');
-        if (!tbody) return;
-        tbody.innerHTML = '';
-        if (d.events?.length > 0) {
-            // Sort by last seen, most recent first
-            const events = d.events.sort((a, b) => new Date(b.last_seen) - new Date(a.last_seen));
-            events.slice(0, 100).forEach(e => {
-                const typeClass = e.type === 'Warning' ? 'status-pending' : e.type === 'Normal' ? '' : 'status-failed';
-                const msgShort = e.message?.length > 60 ? e.message.substring(0, 60) + '...' : e.message;
-                tbody.innerHTML += `<tr><td class="${typeClass}">${e.type}</td><td>${e.reason}</td><td style="font-size:11px">${e.object}</td><td style="font-size:11px" title="${e.message}">${msgShort||'-'}</td><td>${e.count||1}</td><td>${e.age||'-'}</td>${getActionButtons('event', e.namespace, e.name)}</tr>`;
-            });
-        } else {
-            tbody.innerHTML = '<tr><td colspan="7" style="text-align:center;color:#6a6a7a">No events found</td></tr>';
+// Events are streamed over a WebSocket with the namespace and filters of the
+// Events tab; the stream is only reopened when those change.
+let eventsWs = null;
+let eventsWsQuery = null;
+const streamedEvents = new Map();
+let eventsRenderTimer = null;
+
+export function loadEvents(force = false) {
+    const params = new URLSearchParams();
+    if (selectedNamespace) params.set('namespaces', selectedNamespace);
+    if (document.getElementById('event-warnings-only')?.checked) params.set('type', 'Warning');
+    const kind = document.getElementById('event-kind-filter')?.value.trim();
+    if (kind) params.set('kind', kind);
+    const name = document.getElementById('event-name-filter')?.value.trim();
+    if (name) params.set('name', name);
+    const query = params.toString();
+
+    if (!force && eventsWs && eventsWs.readyState <= WebSocket.OPEN && query === eventsWsQuery) return;
+    if (eventsWs) {
+        eventsWs.onclose = null;
+        eventsWs.close();
+    }
+    eventsWsQuery = query;
+    streamedEvents.clear();
+    renderEvents();
+
+    const wsProtocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
+    eventsWs = new WebSocket(`${wsProtocol}//${window.location.host}${API_BASE}/k8s/watch/events?${query}`);
+    eventsWs.onmessage = (msg) => {
+        const ev = JSON.parse(msg.data);
+        if (ev.type === 'ERROR') {
+            console.error('Events stream:', ev.error);
+            return;
         }
-    } catch (e) { console.error(e); }
+        const key = `${ev.namespace}/${ev.name}`;
+        if (ev.type === 'DELETED') streamedEvents.delete(key);
+        else streamedEvents.set(key, ev.object);
+        // The initial replay arrives in a burst, render once it settles
+        clearTimeout(eventsRenderTimer);
+        eventsRenderTimer = setTimeout(renderEvents, 200);
+    };
+    eventsWs.onclose = () => { eventsWs = null; };
+}
+
+function renderEvents() {
+    const tbody = document.getElementById('events-tbody');
+    if (!tbody) return;
+    if (streamedEvents.size === 0) {
+        tbody.innerHTML = '<tr><td colspan="7" style="text-align:center;color:#6a6a7a">No events found</td></tr>';
+        return;
+    }
+    // Sort by last seen, most recent first
+    const events = [...streamedEvents.values()].sort((a, b) => new Date(b.last_seen) - new Date(a.last_seen));
+    tbody.innerHTML = events.slice(0, 100).map(e => {
+        const typeClass = e.type === 'Warning' ? 'status-pending' : e.type === 'Normal' ? '' : 'status-failed';
+        const msgShort = e.message?.length > 60 ? e.message.substring(0, 60) + '...' : e.message;
+        return `<tr><td class="${typeClass}">${e.type}</td><td>${e.reason}</td><td style="font-size:11px">${e.object}</td><td style="font-size:11px" title="${e.message}">${msgShort||'-'}</td><td>${e.count||1}</td><td>${e.age||'-'}</td>${getActionButtons('event', e.namespace, e.name)}</tr>`;
+    }).join('');
 }
 
 export function loadEventsForNamespace() {
@@ -679,7 +755,7 @@ function updateTimerDisplay() {
 }
 
 // Generate action buttons based on edit mode state
-export function getActionButtons(resourceType, namespace, name) {
+export function getActionButtons(resourceType, namespace, name, opts = {}) {
     const describeBtn = `<button class="row-action-btn describe" onclick="describeResource('${resourceType}', '${namespace}', '${name}')">Describe</button>`;
 
     if (!editModeEnabled) {
@@ -697,12 +773,95 @@ export function getActionButtons(resourceType, namespace, name) {
         actions += `<button class="row-action-btn restart" onclick="showRestartModal('${namespace}', '${name}')">Restart</button>`;
     }
 
+    if (resourceType === 'cronjob') {
+        actions += `<button class="row-action-btn restart" onclick="triggerCronJob('${namespace}', '${name}')">Run Now</button>`;
+        actions += opts.suspended
+            ? `<button class="row-action-btn scale" onclick="setCronJobSuspend('${namespace}', '${name}', false)">Resume</button>`
+            : `<button class="row-action-btn scale" onclick="setCronJobSuspend('${namespace}', '${name}', true)">Suspend</button>`;
+    }
+
     actions += `<button class="row-action-btn edit" onclick="editResource('${resourceType}', '${namespace}', '${name}')">Edit</button>`;
     actions += `<button class="row-action-btn delete" onclick="showDeleteModal('${resourceType}', '${namespace}', '${name}')">Delete</button>`;
 
     return `<td class="action-cell">${actions}</td>`;
 }
 
+// showDescribedEvents lists the events of the described resource and of the
+// objects it owns
+export async function showDescribedEvents() {
+    const { type, namespace, name } = currentResource;
+    const output = document.getElementById('describe-events');
+    output.style.display = '';
+    output.innerHTML = '<span style="color:#8a8a9a;">Loading events...</span>';
+    try {
+        const url = namespace
+            ? `${API_BASE}/k8s/${type}/${namespace}/${name}/events`
+            : `${API_BASE}/k8s/${type}/${name}/events`;
+        const r = await fetch(url);
+        const d = await r.json();
+        if (d.error) {
+            output.innerHTML = `<span style="color:#ef4444;">${escapeHtml(d.error)}</span>`;
+            return;
+        }
+        if (!d.events?.length) {
+            output.innerHTML = '<span style="color:#8a8a9a;">No events</span>';
+            return;
+        }
+        const related = d.related?.length ? `<div style="color:#8a8a9a;margin-bottom:6px;">Including ${d.related.length} owned object(s)</div>` : '';
+        output.innerHTML = related + d.events.map(e => {
+            const color = e.type === 'Warning' ? '#fbbf24' : '#8a8a9a';
+            return `<div style="margin-bottom:4px;"><span style="color:${color};">${escapeHtml(e.type)}</span> ${escapeHtml(e.reason)} <span style="color:#60a5fa;">${escapeHtml(e.object)}</span> ${escapeHtml(e.message)} <span style="color:#6a6a7a;">(${e.count || 1}x, ${escapeHtml(e.age || '-')})</span></div>`;
+        }).join('');
+    } catch (e) {
+        output.innerHTML = `<span style="color:#ef4444;">Error loading events: ${escapeHtml(e.message)}</span>`;
+    }
+}
+
+// renderDescribeDetail shows the structured describe view of a pod or
+// deployment above its YAML
+async function renderDescribeDetail(url) {
+    const detail = document.getElementById('describe-detail');
+    try {
+        const r = await fetch(url);
+        const d = await r.json();
+        if (d.error) return;
+
+        const section = (label, rows) => rows.length
+            ? `<div style="margin-top:10px;"><div style="color:#8a8a9a;font-weight:600;margin-bottom:4px;">${label}</div>${rows.join('')}</div>`
+            : '';
+        const row = html => `<div style="margin-bottom:2px;">${html}</div>`;
+        const muted = text => `<span style="color:#8a8a9a;">${escapeHtml(text)}</span>`;
+
+        const summary = [
+            `<b>Status:</b> ${escapeHtml(d.status || '-')}`,
+            `<b>Age:</b> ${escapeHtml(d.age || '-')}`,
+            ...Object.entries(d.details || {}).filter(([, v]) => v).map(([k, v]) => `<b>${escapeHtml(k.replace(/_/g, ' '))}:</b> ${escapeHtml(v)}`)
+        ].join(' &nbsp; ');
+        const owners = d.owners?.length
+            ? row(`<b>Owned by:</b> ${d.owners.map(o => escapeHtml(`${o.kind}/${o.name}`)).join(' &larr; ')}`)
+            : '';
+
+        detail.innerHTML = row(summary) + owners +
+            section('Conditions', (d.conditions || []).map(c => {
+                const color = c.status === 'True' ? '#4ade80' : '#fbbf24';
+                return row(`<span style="color:${color};">${escapeHtml(c.type)}=${escapeHtml(c.status)}</span> ${muted([c.reason, c.message].filter(Boolean).join(': '))}`);
+            })) +
+            section('Containers', (d.containers || []).map(c =>
+                row(`${escapeHtml(c.name)}${c.init ? ' ' + muted('(init)') : ''} ${muted(c.image)}${c.state ? ` ${escapeHtml(c.state)}, ${c.restarts} restart(s)` : ''}`))) +
+            section('Volumes', (d.volumes || []).map(v =>
+                row(`${escapeHtml(v.name)} ${muted(`${v.type}${v.source ? ' ' + v.source : ''}`)}${v.mounts.map(m => ` &rarr; ${escapeHtml(m.container)}:${escapeHtml(m.path)}${m.read_only ? ' (ro)' : ''}`).join('')}`))) +
+            section('Tolerations', (d.tolerations || []).map(t =>
+                row(escapeHtml(`${t.key || '*'} ${t.operator || 'Equal'} ${t.value || ''} ${t.effect || 'all effects'}${t.seconds != null ? ` for ${t.seconds}s` : ''}`)))) +
+            section(`Events (${d.events?.length || 0})`, (d.events || []).slice(0, 10).map(e => {
+                const color = e.type === 'Warning' ? '#fbbf24' : '#8a8a9a';
+                return row(`<span style="color:${color};">${escapeHtml(e.reason)}</span> <span style="color:#60a5fa;">${escapeHtml(e.object)}</span> ${escapeHtml(e.message)} ${muted(`(${e.age || '-'})`)}`);
+            }));
+        detail.style.display = '';
+    } catch (e) {
+        // The YAML below is still shown
+    }
+}
+
 // Modal helpers
 export function showModal(modalId) {
     document.getElementById(modalId).classList.add('active');
@@ -732,6 +891,9 @@ export async function describeResource(resourceType, namespace, name) {
     decodeBtn.style.display = 'none';
     decodedOutput.style.display = 'none';
     decodedOutput.textContent = '';
+    document.getElementById('describe-detail').style.display = 'none';
+document.getElementById('describe-events').style.display = 'none';
+    document.getElementById('describe-events-btn').style.display = resourceType !== 'event' && (namespace || resourceType === 'node') ? '' : 'none';
     showModal('describe-modal');
 
     try {
@@ -745,6 +907,9 @@ export async function describeResource(resourceType, namespace, name) {
         if (resourceType === 'secret') {
             decodeBtn.style.display = '';
         }
+        if (resourceType === 'pod' || resourceType === 'deployment') {
+            renderDescribeDetail(`${url}?view=describe`);
+        }
     } catch (e) {
         yaml.textContent = 'Error loading resource: ' + e.message;
     }
@@ -818,6 +983,7 @@ export async function editResource(resourceType, namespace, name) {
 
     title.textContent = `Edit ${resourceType}: ${name}`;
     editor.value = 'Loading...';
+    document.getElementById('edit-validation').innerHTML = '';
     showModal('edit-modal');
 
     try {
@@ -858,6 +1024,81 @@ export async function saveResourceEdit() {
     }
 }
 
+// previewResourceEdit shows what saving the edited YAML would change
+export async function previewResourceEdit() {
+    const output = document.getElementById('edit-validation');
+    const { type, namespace, name } = currentResource;
+    output.innerHTML = '<span style="color:#60a5fa;">Computing diff...</span>';
+
+    try {
+        const url = namespace
+            ? `${API_BASE}/k8s/${type}/${namespace}/${name}/diff`
+            : `${API_BASE}/k8s/${type}/${name}/diff`;
+        const r = await fetch(url, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ yaml: document.getElementById('edit-yaml-editor').value })
+        });
+        const d = await r.json();
+        if (!r.ok) {
+            output.innerHTML = `<span style="color:#ef4444;">${escapeHtml(d.error || 'Diff failed')}</span>`;
+            return;
+        }
+        if (d.identical) {
+            output.innerHTML = '<span style="color:#8a8a9a;">No changes</span>';
+            return;
+        }
+        const colors = { '+': '#4ade80', '-': '#ef4444', '@': '#60a5fa' };
+        const lines = d.unified.split('\n').slice(2).map(l =>
+            `<div style="color:${colors[l[0]] || '#8a8a9a'};white-space:pre;">${escapeHtml(l)}</div>`).join('');
+        output.innerHTML = `<div style="color:#e0e0e0;margin-bottom:4px;">${d.changes.length} field(s) change: +${d.additions} -${d.deletions} lines</div>
+            <div style="max-height:200px;overflow:auto;font-family:monospace;background:#0d0d14;padding:6px;border-radius:4px;">${lines}</div>`;
+    } catch (e) {
+        output.innerHTML = `<span style="color:#ef4444;">Error: ${escapeHtml(e.message)}</span>`;
+    }
+}
+
+// validateResourceYaml dry-runs the editor content on the server and lists
+// problems by line; clicking one selects that line in the editor
+export async function validateResourceYaml(editorId, outputId, mode, namespace) {
+    const editor = document.getElementById(editorId);
+    const output = document.getElementById(outputId);
+    output.innerHTML = '<span style="color:#60a5fa;">Validating...</span>';
+
+    try {
+        const r = await fetch(`${API_BASE}/k8s/validate`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ yaml: editor.value, mode, namespace: namespace || currentResource.namespace || '' })
+        });
+        const d = await r.json();
+        if (!r.ok) {
+            output.innerHTML = `<span style="color:#ef4444;">${escapeHtml(d.error || 'Validation failed')}</span>`;
+            return;
+        }
+        if (d.valid) {
+            const ops = (d.results || []).map(o => `${o.kind.toLowerCase()}/${o.name || '(generated)'} ${o.operation}`).join(', ');
+            output.innerHTML = `<span style="color:#4ade80;">Valid: ${escapeHtml(ops)} would succeed</span>`;
+            return;
+        }
+        output.innerHTML = d.errors.map(e => `<div style="color:#ef4444;${e.line ? 'cursor:pointer;' : ''}" ${e.line ? `onclick="selectEditorLine('${editorId}', ${e.line})"` : ''}>
+            ${e.line ? 'Line ' + e.line + ': ' : ''}<span style="color:#8a8a9a;">[${escapeHtml(e.type)}]</span> ${e.field ? escapeHtml(e.field) + ': ' : ''}${escapeHtml(e.message)}
+        </div>`).join('');
+    } catch (e) {
+        output.innerHTML = `<span style="color:#ef4444;">Error: ${escapeHtml(e.message)}</span>`;
+    }
+}
+
+export function selectEditorLine(editorId, line) {
+    const editor = document.getElementById(editorId);
+    const lines = editor.value.split('\n');
+    const start = lines.slice(0, line - 1).reduce((n, l) => n + l.length + 1, 0);
+    editor.focus();
+    editor.setSelectionRange(start, start + (lines[line - 1] || '').length);
+    const lineHeight = editor.scrollHeight / Math.max(lines.length, 1);
+    editor.scrollTop = Math.max(0, (line - 3) * lineHeight);
+}
+
 // Delete resource
 export function showDeleteModal(resourceType, namespace, name) {
     currentResource = { type: resourceType, namespace, name };
@@ -985,6 +1226,38 @@ export async function confirmRestart() {
     }
 }
 
+// CronJob actions
+export async function triggerCronJob(namespace, name) {
+    if (!confirm(`Run CronJob ${name} now?`)) return;
+    try {
+        const r = await fetch(`${API_BASE}/k8s/cronjob/${namespace}/${name}/trigger`, { method: 'POST' });
+        const d = await r.json();
+        if (d.success) {
+            alert(d.message);
+            loadJobs();
+            loadCronJobs();
+        } else {
+            alert('Error: ' + (d.error || 'Unknown error'));
+        }
+    } catch (e) {
+        alert('Error triggering cronjob: ' + e.message);
+    }
+}
+
+export async function setCronJobSuspend(namespace, name, suspend) {
+    try {
+        const r = await fetch(`${API_BASE}/k8s/cronjob/${namespace}/${name}/${suspend ? 'suspend' : 'resume'}`, { method: 'POST' });
+        const d = await r.json();
+        if (d.success) {
+            loadCronJobs();
+        } else {
+            alert('Error: ' + (d.error || 'Unknown error'));
+        }
+    } catch (e) {
+        alert('Error updating cronjob: ' + e.message);
+    }
+}
+
 // Create Resource
 export async function openCreateModal() {
     // Load namespaces into the dropdown
@@ -1002,6 +1275,7 @@ export async function openCreateModal() {
     // Reset form
     document.getElementById('create-resource-type').value = '';
     document.getElementById('create-yaml-editor').value = '';
+    document.getElementById('create-validation').innerHTML = '';
     showModal('create-modal');
 }
 
@@ -1031,18 +1305,21 @@ export async function createResource() {
     }
 
     try {
-        const r = await fetch(`${API_BASE}/k8s/create`, {
+        const r = await fetch(`${API_BASE}/k8s/apply`, {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify({ type, namespace, yaml })
+            body: JSON.stringify({ namespace, yaml })
         });
         const d = await r.json();
+        const summary = (d.results || [])
+            .map(o => `${o.kind.toLowerCase()}/${o.name} ${o.action}${o.error ? ': ' + o.error : ''}`)
+            .join('\n');
         if (d.success) {
             closeModal('create-modal');
             loadK8sData();
-            alert('Resource created successfully!');
+            alert(summary || 'Resource applied successfully!');
         } else {
-            alert('Error: ' + (d.error || 'Unknown error'));
+            alert('Error: ' + (d.error || 'Unknown error') + (summary ? '\n\n' + summary : ''));
         }
     } catch (e) {
         alert('Error creating resource: ' + e.message);
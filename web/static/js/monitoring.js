@@ -12,6 +12,7 @@ export function showMonitoringTab(tabId) {
     if (tabId === 'overview') loadMonitoringSummary();
     else if (tabId === 'nodes') loadMonitoringNodes();
     else if (tabId === 'pods') loadMonitoringPods();
+    else if (tabId === 'top') loadMonitoringTop();
     else if (tabId === 'quotas') loadMonitoringQuotas();
     else if (tabId === 'hpa') loadMonitoringHPA();
 
@@ -28,7 +29,7 @@ export async function populateMonitoringNamespaces() {
         const r = await fetch(`${API_BASE}/k8s/namespaces`);
         const d = await r.json();
         if (d.namespaces) {
-            const selects = ['mon-pods-ns', 'mon-quotas-ns', 'mon-hpa-ns'];
+            const selects = ['mon-pods-ns', 'mon-top-ns', 'mon-quotas-ns', 'mon-hpa-ns'];
             selects.forEach(id => {
                 const sel = document.getElementById(id);
                 if (!sel) return;
@@ -182,6 +183,64 @@ export async function loadMonitoringPods() {
     }
 }
 
+function formatTopCell(used, request, limit, percent, fmt) {
+    const req = request ? fmt(request) : '-';
+    const lim = limit ? fmt(limit) : '-';
+    const pct = percent != null ? ` <span style="color:${getUsageColor(percent)};">(${percent.toFixed(0)}%)</span>` : '';
+    return `${fmt(used)} / ${req} / ${lim}${pct}`;
+}
+
+function formatTopFlags(flags) {
+    return (flags || []).map(f => {
+        const color = f.includes('under_provisioned') || f.includes('near_limit') || f.startsWith('quota_critical') ? '#ef4444'
+            : (f.includes('over_provisioned') ? '#60a5fa' : '#fbbf24');
+        return `<span style="background:#1e1e2e;color:${color};padding:2px 6px;border-radius:4px;font-size:11px;margin:1px;display:inline-block;">${f}</span>`;
+    }).join('');
+}
+
+export async function loadMonitoringTop() {
+    const ns = document.getElementById('mon-top-ns').value;
+    const sort = document.getElementById('mon-top-sort').value;
+    const cpuFmt = v => `${v}m`;
+    const memFmt = v => `${(v / (1024*1024)).toFixed(0)}Mi`;
+    try {
+        const url = ns ? `${API_BASE}/monitoring/top/${ns}` : `${API_BASE}/monitoring/top`;
+        const r = await fetch(`${url}?sort=${sort}`);
+        const d = await r.json();
+        const tbody = document.getElementById('mon-top-tbody');
+        const nsContainer = document.getElementById('mon-top-namespaces');
+
+        nsContainer.innerHTML = (d.namespaces || []).map(n => `
+            <div style="background:#2a2a3e;border-radius:8px;padding:10px;">
+                <div style="display:flex;justify-content:space-between;margin-bottom:6px;">
+                    <span style="color:#e0e0e0;font-weight:500;">${n.namespace}</span>
+                    <span style="color:#8a8a9a;font-size:12px;">${n.pod_count} pods</span>
+                </div>
+                <div style="color:#8a8a9a;font-size:12px;">CPU ${formatTopCell(n.cpu_usage_millicores, n.cpu_request_millicores, 0, null, cpuFmt)}</div>
+                <div style="color:#8a8a9a;font-size:12px;">Mem ${formatTopCell(n.memory_usage_bytes, n.memory_request_bytes, 0, null, memFmt)}</div>
+                <div style="margin-top:4px;">${formatTopFlags(n.flags)}</div>
+            </div>
+        `).join('');
+
+        if (!d.pods || d.pods.length === 0) {
+            tbody.innerHTML = '<tr><td colspan="5" style="text-align:center;color:#8a8a9a;">No pods found</td></tr>';
+            return;
+        }
+
+        tbody.innerHTML = d.pods.map(p => `
+            <tr>
+                <td style="font-weight:500;">${p.name}</td>
+                <td><span style="background:rgba(102,126,234,0.2);padding:2px 8px;border-radius:4px;font-size:12px;">${p.namespace}</span></td>
+                <td>${formatTopCell(p.cpu_usage_millicores, p.cpu_request_millicores, p.cpu_limit_millicores, p.cpu_request_percent, cpuFmt)}</td>
+                <td>${formatTopCell(p.memory_usage_bytes, p.memory_request_bytes, p.memory_limit_bytes, p.memory_request_percent, memFmt)}</td>
+                <td>${formatTopFlags(p.flags)}</td>
+            </tr>
+        `).join('');
+    } catch (e) {
+        console.error('Failed to load top:', e);
+    }
+}
+
 export async function loadMonitoringQuotas() {
     const ns = document.getElementById('mon-quotas-ns').value;
     try {
@@ -199,6 +199,7 @@ export async function runCurl() {
     const body = document.getElementById('curl-body').value;
     const followRedirects = document.getElementById('curl-follow').checked;
     const includeBody = document.getElementById('curl-body-include').checked;
+    const proxy = document.getElementById('curl-proxy').value.trim();
     const result = document.getElementById('curl-result');
 
     if (!url) { result.textContent = 'Please enter a URL'; return; }
@@ -220,7 +221,7 @@ export async function runCurl() {
         const r = await fetch(`${API_BASE}/network/curl`, {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify({ url, method, headers, body, follow_redirects: followRedirects, include_body: includeBody, timeout: 30 })
+            body: JSON.stringify({ url, method, headers, body, follow_redirects: followRedirects, include_body: includeBody, proxy, timeout: 30 })
         });
         const d = await r.json();
         if (d.error) { result.textContent = 'Error: ' + d.error; result.className = 'output-box error'; return; }
@@ -233,6 +234,7 @@ export async function runCurl() {
         out += `Content-Type:  ${d.content_type || '-'}\n`;
         out += `Content-Len:   ${d.content_length || '-'}\n`;
         if (d.redirect_url) out += `Redirect:      ${d.redirect_url}\n`;
+        if (d.proxy) out += `Proxy:         ${d.proxy}\n`;
         out += `Duration:      ${(d.duration_ms||0).toFixed(2)}ms\n`;
 
         if (d.headers && Object.keys(d.headers).length > 0) {
@@ -7,6 +7,14 @@ let notificationConfigs = [];
 let editingNotificationId = null;
 let selectedEvents = [];
 
+const NOTIFICATION_TYPES = [
+    { value: 'webhook', label: 'Webhook', hint: '{"event": "...", "timestamp": "...", "build": {...}}' },
+    { value: 'slack', label: 'Slack', hint: 'Slack incoming webhook, Block Kit message' },
+    { value: 'teams', label: 'Microsoft Teams', hint: 'Teams workflow webhook, Adaptive Card' },
+    { value: 'discord', label: 'Discord', hint: 'Discord channel webhook, embed' },
+    { value: 'email', label: 'Email (SMTP)', hint: 'Plain text email, with the end of the log of a failed run' }
+];
+
 const ALL_EVENTS = [
     { value: 'build_started', label: 'Build Started', group: 'Freestyle' },
     { value: 'build_succeeded', label: 'Build Succeeded', group: 'Freestyle' },
@@ -135,22 +143,37 @@ function renderNotificationForm(data) {
                 <input type="text" id="notification-name" required placeholder="My Build Alerts" value="${escapeHtml(data.name || '')}"
                     style="width:100%;padding:10px 12px;background:#1a1a2e;border:1px solid #3a3a4e;border-radius:6px;color:#fff;font-size:14px;">
             </div>
+            <div class="form-group" style="margin-bottom:12px;">
+                <label style="font-size:12px;color:#8a8a9a;margin-bottom:6px;display:block;">Type</label>
+                <select id="notification-type" onchange="updateNotificationTypeHint()"
+                    style="width:100%;padding:10px 12px;background:#1a1a2e;border:1px solid #3a3a4e;border-radius:6px;color:#fff;font-size:14px;">
+                    ${NOTIFICATION_TYPES.map(t => `<option value="${t.value}" ${(data.type || 'webhook') === t.value ? 'selected' : ''}>${t.label}</option>`).join('')}
+                </select>
+            </div>
+            <div id="notification-webhook-fields" style="display:${data.type === 'email' ? 'none' : 'block'};">
             <div class="form-group" style="margin-bottom:12px;">
                 <label style="font-size:12px;color:#8a8a9a;margin-bottom:6px;display:block;">Webhook URL <span style="color:#ef4444;">*</span></label>
-                <input type="url" id="notification-url" required placeholder="https://example.com/webhook" value="${escapeHtml(data.url || '')}"
+                <input type="url" id="notification-url" placeholder="https://example.com/webhook" value="${escapeHtml(data.url || '')}"
                     style="width:100%;padding:10px 12px;background:#1a1a2e;border:1px solid #3a3a4e;border-radius:6px;color:#fff;font-size:14px;">
             </div>
             <div class="form-group" style="margin-bottom:0;">
                 <label style="font-size:12px;color:#8a8a9a;margin-bottom:6px;display:block;">
                     Secret
-                    <span style="font-size:11px;color:#6a6a7a;margin-left:6px;">(optional, for HMAC signature)</span>
+                    <span style="font-size:11px;color:#6a6a7a;margin-left:6px;">(optional, for HMAC signature of webhook payloads)</span>
                 </label>
                 <input type="text" id="notification-secret" placeholder="hmac-secret-key" value="${escapeHtml(data.secret || '')}"
                     style="width:100%;padding:10px 12px;background:#1a1a2e;border:1px solid #3a3a4e;border-radius:6px;color:#fff;font-size:14px;font-family:monospace;">
             </div>
-            <select id="notification-type" style="display:none;">
-                <option value="webhook" ${data.type === 'webhook' || !data.type ? 'selected' : ''}>Webhook</option>
-            </select>
+            <div class="form-group" style="margin-top:12px;margin-bottom:0;">
+                <label style="font-size:12px;color:#8a8a9a;margin-bottom:6px;display:block;">
+                    Proxy
+                    <span style="font-size:11px;color:#6a6a7a;margin-left:6px;">(optional, e.g. http://proxy:3128 or "direct")</span>
+                </label>
+                <input type="text" id="notification-proxy" placeholder="configured proxy" value="${escapeHtml(data.proxy || '')}"
+                    style="width:100%;padding:10px 12px;background:#1a1a2e;border:1px solid #3a3a4e;border-radius:6px;color:#fff;font-size:14px;font-family:monospace;">
+            </div>
+            </div>
+            ${renderEmailFields(data.email || {}, data.type === 'email')}
         </div>
 
         <!-- Events Section -->
@@ -224,14 +247,128 @@ function renderNotificationForm(data) {
             <!-- Payload Info -->
             <div style="background:rgba(139,92,246,0.05);border:1px solid rgba(139,92,246,0.2);border-radius:8px;padding:14px;">
                 <div style="font-size:11px;color:#8b5cf6;text-transform:uppercase;font-weight:600;margin-bottom:6px;">Payload Format</div>
-                <code style="font-size:10px;color:#8a8a9a;display:block;line-height:1.4;">
-                    {"event": "...", "timestamp": "...", "build": {...}}
+                <code id="notification-payload-hint" style="font-size:10px;color:#8a8a9a;display:block;line-height:1.4;">
+                    ${escapeHtml(notificationTypeHint(data.type))}
                 </code>
             </div>
         </div>
     `;
 }
 
+function renderEmailFields(email, visible) {
+    const inputStyle = 'width:100%;padding:10px 12px;background:#1a1a2e;border:1px solid #3a3a4e;border-radius:6px;color:#fff;font-size:14px;';
+    const labelStyle = 'font-size:12px;color:#8a8a9a;margin-bottom:6px;display:block;';
+    const tls = email.tls || 'starttls';
+    return `
+        <div id="notification-email-fields" style="display:${visible ? 'block' : 'none'};">
+            <div style="display:grid;grid-template-columns:2fr 1fr 1fr;gap:10px;margin-bottom:12px;">
+                <div>
+                    <label style="${labelStyle}">SMTP Host <span style="color:#ef4444;">*</span></label>
+                    <input type="text" id="notification-email-host" placeholder="smtp.example.com" value="${escapeHtml(email.host || '')}" style="${inputStyle}">
+                </div>
+                <div>
+                    <label style="${labelStyle}">Port</label>
+                    <input type="number" id="notification-email-port" placeholder="587" value="${email.port || ''}" style="${inputStyle}">
+                </div>
+                <div>
+                    <label style="${labelStyle}">TLS</label>
+                    <select id="notification-email-tls" style="${inputStyle}">
+                        <option value="starttls" ${tls === 'starttls' ? 'selected' : ''}>STARTTLS</option>
+                        <option value="tls" ${tls === 'tls' ? 'selected' : ''}>TLS</option>
+                        <option value="none" ${tls === 'none' ? 'selected' : ''}>None</option>
+                    </select>
+                </div>
+            </div>
+            <div style="display:grid;grid-template-columns:1fr 1fr;gap:10px;margin-bottom:12px;">
+                <div>
+                    <label style="${labelStyle}">Username</label>
+                    <input type="text" id="notification-email-username" value="${escapeHtml(email.username || '')}" style="${inputStyle}">
+                </div>
+                <div>
+                    <label style="${labelStyle}">Password</label>
+                    <input type="password" id="notification-email-password" placeholder="${email.has_password ? 'unchanged' : ''}" style="${inputStyle}">
+                </div>
+            </div>
+            <div class="form-group" style="margin-bottom:12px;">
+                <label style="${labelStyle}">From <span style="color:#ef4444;">*</span></label>
+                <input type="text" id="notification-email-from" placeholder="GAGOS <gagos@example.com>" value="${escapeHtml(email.from || '')}" style="${inputStyle}">
+            </div>
+            <div class="form-group" style="margin-bottom:12px;">
+                <label style="${labelStyle}">To <span style="color:#ef4444;">*</span> <span style="font-size:11px;color:#6a6a7a;margin-left:6px;">(comma-separated)</span></label>
+                <input type="text" id="notification-email-to" placeholder="team@example.com" value="${escapeHtml((email.to || []).join(', '))}" style="${inputStyle}">
+            </div>
+            <div class="form-group" style="margin-bottom:12px;">
+                <label style="${labelStyle}">Subject <span style="font-size:11px;color:#6a6a7a;margin-left:6px;">(optional template)</span></label>
+                <input type="text" id="notification-email-subject" placeholder="[GAGOS] {{.Kind}} {{.Name}} #{{.Number}} {{.Status}}" value="${escapeHtml(email.subject || '')}" style="${inputStyle}font-family:monospace;">
+            </div>
+            <div class="form-group" style="margin-bottom:0;">
+                <label style="${labelStyle}">Body <span style="font-size:11px;color:#6a6a7a;margin-left:6px;">(optional template)</span></label>
+                <textarea id="notification-email-body" rows="4" placeholder="Default: status, trigger, duration, link, error and log excerpt" style="${inputStyle}font-family:monospace;resize:vertical;">${escapeHtml(email.body || '')}</textarea>
+            </div>
+        </div>
+    `;
+}
+
+function readEmailFields() {
+    const port = parseInt(document.getElementById('notification-email-port').value, 10);
+    return {
+        host: document.getElementById('notification-email-host').value.trim(),
+        port: port || undefined,
+        tls: document.getElementById('notification-email-tls').value,
+        username: document.getElementById('notification-email-username').value.trim() || undefined,
+        password: document.getElementById('notification-email-password').value || undefined,
+        from: document.getElementById('notification-email-from').value.trim(),
+        to: document.getElementById('notification-email-to').value.split(',').map(s => s.trim()).filter(Boolean),
+        subject: document.getElementById('notification-email-subject').value.trim() || undefined,
+        body: document.getElementById('notification-email-body').value || undefined
+    };
+}
+
+function notificationTypeHint(type) {
+    const t = NOTIFICATION_TYPES.find(t => t.value === (type || 'webhook'));
+    return t ? t.hint : '';
+}
+
+window.updateNotificationTypeHint = function() {
+    const type = document.getElementById('notification-type').value;
+    document.getElementById('notification-payload-hint').textContent = notificationTypeHint(type);
+    document.getElementById('notification-webhook-fields').style.display = type === 'email' ? 'none' : 'block';
+    document.getElementById('notification-email-fields').style.display = type === 'email' ? 'block' : 'none';
+};
+
+window.testNotification = async function() {
+    const type = document.getElementById('notification-type').value;
+    const url = document.getElementById('notification-url').value.trim();
+    const secret = document.getElementById('notification-secret').value.trim();
+    const proxy = document.getElementById('notification-proxy').value.trim();
+    const email = type === 'email' ? readEmailFields() : undefined;
+    if (type !== 'email' && !url) {
+        alert('Webhook URL is required');
+        return;
+    }
+
+    try {
+        const r = await fetch(`${API_BASE}/cicd/notifications/test`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({
+                id: editingNotificationId || undefined,
+                type, url, email,
+                secret: secret || undefined,
+                proxy: proxy || undefined
+            })
+        });
+        const d = await r.json();
+        if (d.error) {
+            alert('Test failed: ' + d.error);
+            return;
+        }
+        alert(d.message || 'Test notification sent');
+    } catch (e) {
+        alert('Failed to send test notification: ' + e.message);
+    }
+};
+
 window.updateSelectedEvents = function() {
     selectedEvents = [];
     document.querySelectorAll('.event-checkbox:checked').forEach(cb => {
@@ -252,6 +389,7 @@ export async function saveNotification(event) {
     const type = document.getElementById('notification-type').value;
     const url = document.getElementById('notification-url').value.trim();
     const secret = document.getElementById('notification-secret').value.trim();
+    const proxy = document.getElementById('notification-proxy').value.trim();
     const enabled = document.getElementById('notification-enabled').checked;
 
     // Get selected events
@@ -261,7 +399,7 @@ export async function saveNotification(event) {
         alert('Name is required');
         return;
     }
-    if (!url) {
+    if (type !== 'email' && !url) {
         alert('Webhook URL is required');
         return;
     }
@@ -275,6 +413,8 @@ export async function saveNotification(event) {
         type,
         url,
         secret: secret || undefined,
+        proxy: proxy || undefined,
+        email: type === 'email' ? readEmailFields() : undefined,
         events: selectedEvents,
         enabled
     };
@@ -4,6 +4,7 @@
 import { API_BASE } from './app.js';
 import { saveState } from './state.js';
 import { escapeHtml, formatSize, formatTime } from './utils.js';
+import { rememberConnection, tlsOptions } from './connections.js';
 
 let s3Connected = false;
 let s3Config = {};
@@ -44,10 +45,12 @@ export async function s3Connect() {
         region: document.getElementById('s3-region').value || 'us-east-1',
         access_key_id: document.getElementById('s3-access-key').value,
         secret_access_key: document.getElementById('s3-secret-key').value,
-        use_ssl: document.getElementById('s3-ssl').checked
+        use_ssl: document.getElementById('s3-ssl').checked,
+        auth_mode: document.getElementById('s3-iam').checked ? 'iam' : 'password',
+        tls: tlsOptions('s3')
     };
 
-    if (!s3Config.access_key_id || !s3Config.secret_access_key) {
+    if (s3Config.auth_mode !== 'iam' && (!s3Config.access_key_id || !s3Config.secret_access_key)) {
         status.innerHTML = '<span style="color:#ef4444;">Access key and secret key are required</span>';
         return;
     }
@@ -66,6 +69,7 @@ export async function s3Connect() {
 
         if (d.success) {
             s3Connected = true;
+            rememberConnection('s3', d.profile_id, s3Config);
             status.innerHTML = `<span style="color:#4ade80;">Connected (${d.response_time_ms?.toFixed(0) || 0}ms) - ${d.buckets?.length || 0} buckets</span>`;
             btn.textContent = 'Reconnect';
             s3LoadBuckets();
@@ -361,6 +365,8 @@ export async function s3HandleFileSelect() {
             formData.append('access_key_id', s3Config.access_key_id);
             formData.append('secret_access_key', s3Config.secret_access_key);
             formData.append('use_ssl', s3Config.use_ssl);
+            formData.append('auth_mode', s3Config.auth_mode);
+            if (s3Config.tls) formData.append('tls', JSON.stringify(s3Config.tls));
             formData.append('bucket', currentBucket);
             formData.append('prefix', currentPrefix);
 
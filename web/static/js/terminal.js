@@ -17,7 +17,7 @@ export function initTerminal() {
 
     // Check if xterm libraries are loaded
     if (typeof Terminal === 'undefined') {
-        container.innerHTML = '<div style="color:#ef4444;padding:20px;">Error: xterm.js library failed to load. Check your internet connection.</div>';
+        container.innerHTML = '<div style="color:#ef4444;padding:20px;">Error: xterm.js library failed to load. Run `make assets` when serving web/static from a source checkout.</div>';
         return;
     }
     if (typeof FitAddon === 'undefined') {
@@ -26,3 +26,23 @@ export function formatTime(isoStr) {
     const d = new Date(isoStr);
     return d.toLocaleString();
 }
+
+// fetchElevated behaves like fetch, but when the server refuses with
+// elevation_required it asks for the admin password, elevates the session
+// for a few minutes and retries once
+export async function fetchElevated(url, options) {
+    const r = await fetch(url, options);
+    if (r.status !== 403) return r;
+    const d = await r.clone().json().catch(() => ({}));
+    if (!d.elevation_required) return r;
+
+    const password = prompt(`${d.error}.\nEnter the admin password to continue:`);
+    if (!password) return r;
+    const e = await fetch('/api/auth/elevate', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ password })
+    });
+    if (!e.ok) return e;
+    return fetch(url, options);
+}